@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+)
+
+var (
+	caEmailFlag    string
+	caNameFlag     string
+	caPasswordFlag string
+)
+
+var createAdminCmd = &cobra.Command{
+	Use:   "create-admin",
+	Short: "Create an admin account or grant admin to an existing one",
+	Long: `create-admin is the recovery path for an install without a usable
+admin. When an account with --email exists it becomes an admin, and its
+password is replaced if --password is given. Otherwise a new admin account is
+created; without --password a random one is generated, printed once, and must
+be changed on first login.
+
+The password can also be passed in IMMICH_ADMIN_PASSWORD to keep it out of
+the shell history.`,
+	RunE: runCreateAdmin,
+}
+
+func init() {
+	createAdminCmd.Flags().StringVar(&caEmailFlag, "email", "", "email of the admin account")
+	createAdminCmd.Flags().StringVar(&caNameFlag, "name", "", "name of a new admin account (default \"Admin\")")
+	createAdminCmd.Flags().StringVar(&caPasswordFlag, "password", "", "password to set on the account")
+	_ = createAdminCmd.MarkFlagRequired("email")
+
+	rootCmd.AddCommand(createAdminCmd)
+}
+
+func runCreateAdmin(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	password := caPasswordFlag
+	if password == "" {
+		password = os.Getenv("IMMICH_ADMIN_PASSWORD")
+	}
+
+	database, err := db.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to close database connection")
+		}
+	}()
+
+	if cfg.Database.AutoMigrate {
+		if err := db.RunMigrations(ctx, database.DB()); err != nil {
+			return fmt.Errorf("run migrations: %w", err)
+		}
+	}
+
+	result, err := auth.NewService(cfg.Auth, database.Queries).CreateAdmin(ctx, caEmailFlag, caNameFlag, password)
+	if err != nil {
+		return err
+	}
+
+	if !result.Created {
+		fmt.Fprintf(os.Stderr, "create-admin: %s is now an admin (user %s)\n", caEmailFlag, result.UserID)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "create-admin: created admin %s (user %s)\n", caEmailFlag, result.UserID)
+	if result.Password != "" {
+		fmt.Fprintf(os.Stdout, "%s\n", result.Password)
+		fmt.Fprintln(os.Stderr, "create-admin: the generated password above must be changed on first login")
+	}
+	return nil
+}
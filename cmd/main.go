@@ -50,8 +50,11 @@ var serveCmd = &cobra.Command{
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Run database migrations",
-	Long:  `Apply database migrations to set up or update the database schema.`,
-	RunE:  runMigrations,
+	Long: `Apply database migrations to set up or update the database schema.
+
+Use "migrate status" to see which migrations are applied, and "migrate down"
+or "migrate to" to roll back after a failed upgrade.`,
+	RunE: runMigrations,
 }
 
 func init() {
@@ -86,6 +89,12 @@ func initConfig() {
 	} else {
 		logrus.SetFormatter(&logrus.TextFormatter{})
 	}
+
+	output, err := cfg.Logging.Writer()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open log output")
+	}
+	logrus.SetOutput(output)
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
@@ -107,7 +116,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Connect to database
-	database, err := db.New(ctx, cfg.Database.URL)
+	database, err := db.NewWithReplica(ctx, cfg.Database.URL, cfg.Database.ReplicaURL)
 	if err != nil {
 		if embeddedPG != nil {
 			_ = embeddedPG.Stop()
@@ -209,6 +218,9 @@ func runServer(cmd *cobra.Command, args []string) error {
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Error("Failed to start draining the server")
+	}
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logrus.WithError(err).Error("Failed to shutdown HTTP server gracefully")
 	}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/immich-go-backend/internal/db"
+)
+
+var migrateYesFlag bool
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending database migrations",
+	Long: `status lists every migration with whether it has been applied. It only
+reads the migrations table, inside a read-only transaction, so it is safe to
+run against a production database.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateStatus,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Roll back the last N database migrations",
+	Long: `down rolls back the last N applied migrations (default 1), newest first.
+Rolling back drops the tables and columns the migrations added, with their
+data, so it requires --yes. Nothing is rolled back when one of the
+migrations cannot be reverted.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMigrateDown,
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version>",
+	Short: "Apply or roll back database migrations until version is the newest applied",
+	Long: `to applies the pending migrations up to version, or rolls back every
+applied migration newer than version. Rolling back requires --yes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMigrateTo,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{migrateDownCmd, migrateToCmd} {
+		cmd.Flags().BoolVar(&migrateYesFlag, "yes", false,
+			"confirm rolling back migrations, which deletes the data they added")
+	}
+
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateToCmd)
+}
+
+func runMigrateStatus(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	database, err := db.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeDatabase(database)
+
+	statuses, err := db.GetMigrationStatus(ctx, database.DB())
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+
+	return writeMigrationStatus(cmd.OutOrStdout(), statuses)
+}
+
+func writeMigrationStatus(out io.Writer, statuses []db.MigrationStatus) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT\tREVERSIBLE")
+	for _, s := range statuses {
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state = "applied"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+		}
+		reversible := "no"
+		if s.Reversible {
+			reversible = "yes"
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt, reversible)
+	}
+	return w.Flush()
+}
+
+func runMigrateDown(cmd *cobra.Command, args []string) error {
+	steps := 1
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid number of migrations %q", args[0])
+		}
+		steps = n
+	}
+	if !migrateYesFlag {
+		return fmt.Errorf("rolling back %d migration(s) deletes the data they added; re-run with --yes to confirm", steps)
+	}
+
+	ctx := context.Background()
+
+	database, err := db.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeDatabase(database)
+
+	if err := db.MigrateDown(ctx, database.DB(), steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+
+	logrus.Infof("Rolled back %d migration(s)", steps)
+	return nil
+}
+
+func runMigrateTo(cmd *cobra.Command, args []string) error {
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		return fmt.Errorf("invalid migration version %q", args[0])
+	}
+
+	ctx := context.Background()
+
+	database, err := db.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeDatabase(database)
+
+	rollbacks, err := db.RollbackCount(ctx, database.DB(), version)
+	if err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+	if rollbacks > 0 && !migrateYesFlag {
+		return fmt.Errorf("migrating to %03d rolls back %d migration(s) and deletes the data they added; re-run with --yes to confirm", version, rollbacks)
+	}
+
+	if err := db.MigrateTo(ctx, database.DB(), version); err != nil {
+		return fmt.Errorf("failed to migrate to %03d: %w", version, err)
+	}
+
+	logrus.Infof("Database is at migration %03d", version)
+	return nil
+}
+
+func closeDatabase(database *db.Conn) {
+	if err := database.Close(); err != nil {
+		logrus.WithError(err).Error("Failed to close database connection")
+	}
+}
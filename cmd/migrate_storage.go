@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+	"github.com/denysvitali/immich-go-backend/internal/storagemigration"
+)
+
+var (
+	msFromFlag         string
+	msToFlag           string
+	msFromPrefixFlag   string
+	msToPrefixFlag     string
+	msConcurrencyFlag  int
+	msProgressInterval time.Duration
+	msDeleteSourceFlag bool
+)
+
+var migrateStorageCmd = &cobra.Command{
+	Use:   "migrate-storage",
+	Short: "Copy all asset files to another storage backend",
+	Long: `migrate-storage copies every original, thumbnail, encoded video and
+sidecar tracked in the database from one storage backend to another. Both
+backends are configured in the storage section of the config file; --from
+and --to pick which one to read from and which one to write to.
+
+Every copy is verified against a checksum of the source before it is
+recorded, and an interrupted migration resumes with the files that are left.
+When --from-prefix and --to-prefix differ, the stored paths starting with
+--from-prefix are rewritten once every file has been verified.
+
+Source files are never deleted unless --delete-source is given, and only
+after the whole migration has verified.`,
+	RunE: runMigrateStorage,
+}
+
+func init() {
+	migrateStorageCmd.Flags().StringVar(&msFromFlag, "from", "",
+		"storage backend to copy from (local, s3 or rclone)")
+	migrateStorageCmd.Flags().StringVar(&msToFlag, "to", "",
+		"storage backend to copy to (local, s3 or rclone)")
+	migrateStorageCmd.Flags().StringVar(&msFromPrefixFlag, "from-prefix", "",
+		"path prefix to replace in the stored paths")
+	migrateStorageCmd.Flags().StringVar(&msToPrefixFlag, "to-prefix", "",
+		"path prefix that replaces --from-prefix")
+	migrateStorageCmd.Flags().IntVar(&msConcurrencyFlag, "concurrency", 4,
+		"number of files copied at once")
+	migrateStorageCmd.Flags().DurationVar(&msProgressInterval, "progress-interval", 10*time.Second,
+		"how often to report progress")
+	migrateStorageCmd.Flags().BoolVar(&msDeleteSourceFlag, "delete-source", false,
+		"delete the source files once every copy has been verified")
+	_ = migrateStorageCmd.MarkFlagRequired("from")
+	_ = migrateStorageCmd.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(migrateStorageCmd)
+}
+
+// openStorageBackend opens the configured storage with its backend replaced
+// by name.
+func openStorageBackend(config storage.StorageConfig, name string) (storage.StorageBackend, error) {
+	config.Backend = name
+	if err := storage.ValidateStorageConfig(config); err != nil {
+		return nil, err
+	}
+
+	return storage.NewStorageBackend(config)
+}
+
+func runMigrateStorage(cmd *cobra.Command, args []string) error {
+	from := strings.ToLower(msFromFlag)
+	to := strings.ToLower(msToFlag)
+	if from == to {
+		return fmt.Errorf("--from and --to must name different backends, both are %q", from)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	source, err := openStorageBackend(cfg.Storage, from)
+	if err != nil {
+		return fmt.Errorf("failed to open source storage: %w", err)
+	}
+	defer source.Close()
+
+	destination, err := openStorageBackend(cfg.Storage, to)
+	if err != nil {
+		return fmt.Errorf("failed to open destination storage: %w", err)
+	}
+	defer destination.Close()
+
+	database, err := db.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to close database connection")
+		}
+	}()
+
+	if cfg.Database.AutoMigrate {
+		if err := db.RunMigrations(ctx, database.DB()); err != nil {
+			return fmt.Errorf("run migrations: %w", err)
+		}
+	}
+
+	migrator := storagemigration.New(database.Queries, source, destination, storagemigration.Options{
+		From:             from,
+		To:               to,
+		FromPrefix:       msFromPrefixFlag,
+		ToPrefix:         msToPrefixFlag,
+		Concurrency:      msConcurrencyFlag,
+		ProgressInterval: msProgressInterval,
+	})
+
+	progress, err := migrator.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("storage migration incomplete, source files kept: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr,
+		"migrate-storage: %d files verified (%d copied, %d already migrated, %d missing from the source), %d bytes copied\n",
+		progress.Copied+progress.Skipped, progress.Copied, progress.Skipped, progress.Missing, progress.Bytes,
+	)
+
+	if !msDeleteSourceFlag {
+		logrus.Info("Source files kept; re-run with --delete-source to remove them")
+		return nil
+	}
+
+	deleted, err := migrator.DeleteSource(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete source files after %d deletions: %w", deleted, err)
+	}
+	fmt.Fprintf(os.Stderr, "migrate-storage: deleted %d source files\n", deleted)
+
+	return nil
+}
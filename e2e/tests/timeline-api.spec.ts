@@ -35,6 +35,14 @@ type TimelineBucket = {
   count: number;
 };
 
+type TimelineDaysPage = {
+  days: Array<{
+    date: string;
+    assets: Array<{ id: string; type: string; thumbhash?: string; ratio: number; localDateTime: string }>;
+  }>;
+  nextCursor?: string;
+};
+
 type CalendarHeatmap = {
   from: string;
   to: string;
@@ -80,10 +88,39 @@ test('timeline frontend endpoints expose bucket summaries and columnar assets',
   expect(bucketBody.originalFileName[assetIndex]).toBe(asset.originalFileName);
   expect(bucketBody.originalPath[assetIndex]).toBe(asset.originalPath);
   expect(bucketBody.ratio[assetIndex]).toBeGreaterThan(0);
-  expect(bucketBody.thumbhash[assetIndex]).toBeNull();
+  // Set once the thumbnails are generated, which may not have happened yet
+  expect([null, expect.any(String)]).toContainEqual(bucketBody.thumbhash[assetIndex]);
   expect(bucketBody.type[assetIndex]).toBe('IMAGE');
 });
 
+test('timeline days endpoint pages days with the grid layout of their assets', async ({ request }) => {
+  const user = await signUpAdmin(request, 'timeline-days', 'E2E Timeline Days User');
+  const newer = await uploadAsset(request, user, '2026-06-16T12:00:00Z');
+  const older = await uploadAsset(request, user, '2026-06-15T08:30:00Z');
+
+  const first = await request.get('/api/timeline/days?limit=1', { headers: user.headers });
+  await expectOk(first);
+  const firstBody = (await first.json()) as TimelineDaysPage;
+
+  expect(firstBody.days).toHaveLength(1);
+  expect(firstBody.days[0].date).toBe('2026-06-16');
+  expect(firstBody.days[0].assets).toHaveLength(1);
+  expect(firstBody.days[0].assets[0]).toMatchObject({ id: newer.id, type: 'IMAGE' });
+  expect(firstBody.days[0].assets[0].ratio).toBeGreaterThan(0);
+  expect(firstBody.nextCursor).toBe('2026-06-16');
+
+  const second = await request.get(`/api/timeline/days?limit=1&cursor=${firstBody.nextCursor}`, {
+    headers: user.headers,
+  });
+  await expectOk(second);
+  const secondBody = (await second.json()) as TimelineDaysPage;
+
+  expect(secondBody.days).toHaveLength(1);
+  expect(secondBody.days[0].date).toBe('2026-06-15');
+  expect(secondBody.days[0].assets.map((asset) => asset.id)).toEqual([older.id]);
+  expect(secondBody.nextCursor).toBeUndefined();
+});
+
 test('calendar heatmap endpoints expose taken-date activity for users and admins', async ({ request }) => {
   const user = await signUpAdmin(request, 'calendar-heatmap', 'E2E Calendar User');
   await uploadAsset(request, user, '2026-06-15T08:30:00Z');
@@ -693,6 +693,48 @@ test.describe('albums', () => {
     expect(marker.lat).toBeCloseTo(37.7749, 4);
     expect(marker.lon).toBeCloseTo(-122.4194, 4);
   });
+
+  test('album statistics count the album assets by contributor', async ({ request }) => {
+    const admin = await signUpAdmin(request, 'album-statistics', 'Album Statistics');
+    const upload = await request.post('/api/assets', {
+      headers: admin.headers,
+      multipart: {
+        assetData: {
+          name: 'e2e-stats.jpg',
+          mimeType: 'image/jpeg',
+          buffer: gpsJpeg,
+        },
+        deviceAssetId: uniqueId('stats-asset'),
+        deviceId: 'e2e-device',
+        fileCreatedAt: '2026-07-01T12:00:00.000Z',
+        fileModifiedAt: '2026-07-01T12:00:00.000Z',
+      },
+    });
+    expect(upload.status()).toBe(201);
+    const asset = await upload.json();
+
+    const createAlbum = await request.post('/api/albums', {
+      headers: admin.headers,
+      data: {
+        albumName: `Statistics Album ${uniqueId('album')}`,
+        assetIds: [asset.id],
+      },
+    });
+    await expectOk(createAlbum);
+    const album = await createAlbum.json();
+
+    const response = await request.get(`/api/albums/${album.id}/statistics`, {
+      headers: admin.headers,
+    });
+    await expectOk(response);
+    const statistics = await response.json();
+    expect(statistics).toMatchObject({
+      assetCount: 1,
+      images: 1,
+      videos: 0,
+      contributors: [{ userId: admin.userId, name: 'Album Statistics', assetCount: 1 }],
+    });
+  });
 });
 
 test.describe('activities', () => {
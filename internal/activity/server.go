@@ -34,13 +34,9 @@ func NewServer(queries *sqlc.Queries) *Server {
 // GetActivities gets activities for albums/assets
 func (s *Server) GetActivities(ctx context.Context, request *immichv1.GetActivitiesRequest) (*immichv1.GetActivitiesResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "invalid user ID")
+		return nil, err
 	}
 
 	// Parse album ID
@@ -150,18 +146,11 @@ func (s *Server) GetActivities(ctx context.Context, request *immichv1.GetActivit
 // CreateActivity creates a new activity (comment/like)
 func (s *Server) CreateActivity(ctx context.Context, request *immichv1.CreateActivityRequest) (*immichv1.ActivityResponseDto, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := uuid.Parse(claims.UserID)
+	userUUID, err := auth.UserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "invalid user ID")
+		return nil, err
 	}
-
-	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
+	userID := uuid.UUID(userUUID.Bytes)
 
 	// Parse album ID
 	if request.AlbumId == "" {
@@ -212,7 +201,7 @@ func (s *Server) CreateActivity(ctx context.Context, request *immichv1.CreateAct
 			AssetID: assetUUID,
 		})
 		if err == nil {
-			return s.activityResponse(ctx, existing, claims)
+			return s.activityResponse(ctx, existing)
 		}
 		if !errors.Is(err, pgx.ErrNoRows) {
 			return nil, grpcutil.SanitizedInternal(ctx, "failed to check existing activity", err)
@@ -231,19 +220,15 @@ func (s *Server) CreateActivity(ctx context.Context, request *immichv1.CreateAct
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to create activity", err)
 	}
 
-	return s.activityResponse(ctx, createdActivity, claims)
+	return s.activityResponse(ctx, createdActivity)
 }
 
 // GetActivityStatistics gets statistics for activities
 func (s *Server) GetActivityStatistics(ctx context.Context, request *immichv1.GetActivityStatisticsRequest) (*immichv1.ActivityStatisticsResponseDto, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "invalid user ID")
+		return nil, err
 	}
 
 	// Parse album ID
@@ -287,9 +272,9 @@ func (s *Server) GetActivityStatistics(ctx context.Context, request *immichv1.Ge
 // DeleteActivity deletes an activity
 func (s *Server) DeleteActivity(ctx context.Context, request *immichv1.DeleteActivityRequest) (*emptypb.Empty, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse activity ID
@@ -307,11 +292,7 @@ func (s *Server) DeleteActivity(ctx context.Context, request *immichv1.DeleteAct
 	}
 
 	// Verify the user owns this activity
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "invalid user ID")
-	}
-	if activity.UserId.Bytes != userID {
+	if activity.UserId != userID {
 		return nil, status.Error(codes.PermissionDenied, "not authorized to delete this activity")
 	}
 
@@ -348,9 +329,9 @@ func (s *Server) requireAlbumAccess(ctx context.Context, userID uuid.UUID, album
 	return status.Error(codes.PermissionDenied, "not authorized to access this album")
 }
 
-func (s *Server) activityResponse(ctx context.Context, activity sqlc.Activity, claims *auth.Claims) (*immichv1.ActivityResponseDto, error) {
+func (s *Server) activityResponse(ctx context.Context, activity sqlc.Activity) (*immichv1.ActivityResponseDto, error) {
 	user, err := s.queries.GetUserByID(ctx, activity.UserId)
-	if err != nil {
+	if claims, ok := auth.GetClaimsFromStdContext(ctx); err != nil && ok {
 		user.Email = claims.Email
 		user.Name = claims.Email
 	}
@@ -112,7 +112,7 @@ func TestCreateActivityValidation(t *testing.T) {
 			name:     "invalid user id in claims",
 			ctx:      authedContext("not-a-uuid"),
 			request:  &immichv1.CreateActivityRequest{AlbumId: uuid.NewString()},
-			wantCode: codes.Internal,
+			wantCode: codes.Unauthenticated,
 		},
 		{
 			name:     "missing album id",
@@ -0,0 +1,323 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+var (
+	ErrReassignAssetNotFound = errors.New("asset not found")
+	ErrReassignOwnerNotFound = errors.New("new owner not found")
+	ErrReassignSameOwner     = errors.New("asset already belongs to this user")
+	ErrReassignExternal      = errors.New("assets of an external library cannot be reassigned")
+	ErrReassignStacked       = errors.New("stacked assets cannot be reassigned, unstack them first")
+	ErrReassignDuplicate     = errors.New("the new owner already has this asset")
+)
+
+// Asset files kept next to the original are on the main storage; all other
+// asset files are derivatives.
+var mainStorageAssetFileTypes = map[string]bool{
+	"original_backup": true,
+	"sidecar_backup":  true,
+}
+
+// TxRunner runs database work in a transaction.
+type TxRunner interface {
+	InTx(ctx context.Context, fn func(*sqlc.Queries) error) error
+}
+
+// SetTxRunner enables the operations that need a transaction, such as
+// ReassignAsset.
+func (s *Service) SetTxRunner(tx TxRunner) {
+	s.tx = tx
+}
+
+// ReassignAssetRequest hands an asset to another user. Its album and tag
+// links are kept unless StripAssociations is set.
+type ReassignAssetRequest struct {
+	AssetID           uuid.UUID
+	NewOwnerID        uuid.UUID
+	StripAssociations bool
+}
+
+// ReassignAssetResult describes a reassignment. AssetIDs has the asset and
+// the motion part of a live photo, which moves with it.
+type ReassignAssetResult struct {
+	AssetIDs          []uuid.UUID
+	PreviousOwnerID   uuid.UUID
+	OwnerID           uuid.UUID
+	MovedFiles        int
+	SizeBytes         int64
+	RemovedAlbumLinks int64
+	RemovedTagLinks   int64
+}
+
+// fileMove is a stored file of a reassigned asset that moves to the storage
+// of the new owner.
+type fileMove struct {
+	store    *storage.Service
+	from, to string
+}
+
+// ReassignAsset makes another user the owner of an uploaded asset. Files in
+// the storage of the old owner move to that of the new one, and the quota
+// usage of both is updated. The database changes are rolled back, and moved
+// files moved back, when any file fails to move.
+func (s *Service) ReassignAsset(ctx context.Context, req ReassignAssetRequest) (*ReassignAssetResult, error) {
+	ctx, span := tracer.Start(ctx, "admin.reassign_asset",
+		trace.WithAttributes(
+			attribute.String("asset_id", req.AssetID.String()),
+			attribute.String("new_owner_id", req.NewOwnerID.String()),
+			attribute.Bool("strip_associations", req.StripAssociations),
+		))
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		s.operationDuration.Record(ctx, time.Since(start).Seconds(),
+			metric.WithAttributes(attribute.String("operation", "reassign_asset")))
+		s.operationCounter.Add(ctx, 1,
+			metric.WithAttributes(attribute.String("operation", "reassign_asset")))
+	}()
+
+	if s.tx == nil || s.storage == nil {
+		return nil, errors.New("asset reassignment is not available")
+	}
+
+	group, err := s.reassignableAssets(ctx, req.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	oldOwner := uuid.UUID(group[0].OwnerId.Bytes)
+	if oldOwner == req.NewOwnerID {
+		return nil, ErrReassignSameOwner
+	}
+	newOwner := pgtype.UUID{Bytes: req.NewOwnerID, Valid: true}
+	if _, err := s.db.GetUser(ctx, newOwner); errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReassignOwnerNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get new owner: %w", err)
+	}
+
+	result := &ReassignAssetResult{PreviousOwnerID: oldOwner, OwnerID: req.NewOwnerID}
+	var moved []fileMove
+	err = s.tx.InTx(ctx, func(q *sqlc.Queries) error {
+		var moves []fileMove
+		for _, asset := range group {
+			duplicate, err := q.OwnerHasUploadedAsset(ctx, sqlc.OwnerHasUploadedAssetParams{
+				OwnerId:       newOwner,
+				Checksum:      asset.Checksum,
+				DeviceId:      asset.DeviceId,
+				DeviceAssetId: asset.DeviceAssetId,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to check for duplicates: %w", err)
+			}
+			if duplicate {
+				return ErrReassignDuplicate
+			}
+
+			assetMoves, err := s.reassignAssetRows(ctx, q, asset, oldOwner, req)
+			if err != nil {
+				return err
+			}
+			moves = append(moves, assetMoves...)
+
+			if req.StripAssociations {
+				albums, err := q.RemoveAssetFromAllAlbums(ctx, asset.ID)
+				if err != nil {
+					return fmt.Errorf("failed to remove asset from albums: %w", err)
+				}
+				tags, err := q.RemoveAllTagsFromAsset(ctx, asset.ID)
+				if err != nil {
+					return fmt.Errorf("failed to remove asset tags: %w", err)
+				}
+				result.RemovedAlbumLinks += albums
+				result.RemovedTagLinks += tags
+			}
+
+			size, err := assetFileSize(ctx, q, asset.ID)
+			if err != nil {
+				return err
+			}
+			result.SizeBytes += size
+			result.AssetIDs = append(result.AssetIDs, uuid.UUID(asset.ID.Bytes))
+		}
+
+		if err := q.AdjustUserQuotaUsage(ctx, sqlc.AdjustUserQuotaUsageParams{
+			Delta: -result.SizeBytes,
+			ID:    group[0].OwnerId,
+		}); err != nil {
+			return fmt.Errorf("failed to update quota usage: %w", err)
+		}
+		if err := q.AdjustUserQuotaUsage(ctx, sqlc.AdjustUserQuotaUsageParams{
+			Delta: result.SizeBytes,
+			ID:    newOwner,
+		}); err != nil {
+			return fmt.Errorf("failed to update quota usage: %w", err)
+		}
+
+		// Files move last, so that nothing is left to roll back but the
+		// transaction when one fails.
+		for _, move := range moves {
+			exists, err := move.store.Exists(ctx, move.from)
+			if err != nil {
+				return fmt.Errorf("failed to check %s: %w", move.from, err)
+			}
+			if !exists {
+				continue
+			}
+			if err := move.store.Move(ctx, move.from, move.to); err != nil {
+				return fmt.Errorf("failed to move %s: %w", move.from, err)
+			}
+			moved = append(moved, move)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		undoFileMoves(ctx, moved)
+		return nil, err
+	}
+
+	result.MovedFiles = len(moved)
+	return result, nil
+}
+
+// reassignableAssets returns the asset and the motion part of a live photo,
+// unless the asset cannot be reassigned.
+func (s *Service) reassignableAssets(ctx context.Context, assetID uuid.UUID) ([]sqlc.Asset, error) {
+	asset, err := s.db.GetAsset(ctx, pgtype.UUID{Bytes: assetID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReassignAssetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset: %w", err)
+	}
+	group := []sqlc.Asset{asset}
+
+	if asset.LivePhotoVideoId.Valid {
+		video, err := s.db.GetAsset(ctx, asset.LivePhotoVideoId)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get live photo video: %w", err)
+		}
+		if err == nil && video.OwnerId == asset.OwnerId {
+			group = append(group, video)
+		}
+	}
+
+	for _, asset := range group {
+		if asset.LibraryId.Valid || asset.IsExternal {
+			return nil, ErrReassignExternal
+		}
+		if asset.StackId.Valid {
+			return nil, ErrReassignStacked
+		}
+	}
+	return group, nil
+}
+
+// reassignAssetRows hands the rows of an asset to the new owner and returns
+// the files that move with them.
+func (s *Service) reassignAssetRows(ctx context.Context, q *sqlc.Queries, asset sqlc.Asset, oldOwner uuid.UUID, req ReassignAssetRequest) ([]fileMove, error) {
+	var moves []fileMove
+	relink := func(store *storage.Service, path string) string {
+		newPath, ok := reassignedPath(path, oldOwner, req.NewOwnerID)
+		if ok {
+			moves = append(moves, fileMove{store: store, from: path, to: newPath})
+		}
+		return newPath
+	}
+	relinkText := func(store *storage.Service, path pgtype.Text) pgtype.Text {
+		if !path.Valid || path.String == "" {
+			return path
+		}
+		return pgtype.Text{String: relink(store, path.String), Valid: true}
+	}
+
+	if err := q.ReassignAsset(ctx, sqlc.ReassignAssetParams{
+		ID:               asset.ID,
+		OwnerId:          pgtype.UUID{Bytes: req.NewOwnerID, Valid: true},
+		OriginalPath:     relink(s.storage, asset.OriginalPath),
+		SidecarPath:      relinkText(s.storage, asset.SidecarPath),
+		EncodedVideoPath: relinkText(s.storage.Derivatives(), asset.EncodedVideoPath),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reassign asset: %w", err)
+	}
+
+	files, err := q.GetAssetFiles(ctx, asset.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset files: %w", err)
+	}
+	for _, file := range files {
+		store := s.storage.Derivatives()
+		if mainStorageAssetFileTypes[file.Type] {
+			store = s.storage
+		}
+		path := relink(store, file.Path)
+		if path == file.Path {
+			continue
+		}
+		if err := q.UpdateAssetFilePath(ctx, sqlc.UpdateAssetFilePathParams{ID: file.ID, Path: path}); err != nil {
+			return nil, fmt.Errorf("failed to update asset file path: %w", err)
+		}
+	}
+
+	if err := q.DetachAssetFacesFromPeople(ctx, asset.ID); err != nil {
+		return nil, fmt.Errorf("failed to detach faces: %w", err)
+	}
+	return moves, nil
+}
+
+// assetFileSize returns the size of the original of an asset, or 0 when its
+// metadata has not been extracted.
+func assetFileSize(ctx context.Context, q *sqlc.Queries, assetID pgtype.UUID) (int64, error) {
+	exif, err := q.GetExifByAssetId(ctx, assetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get asset metadata: %w", err)
+	}
+	return exif.FileSizeInByte.Int64, nil
+}
+
+// reassignedPath moves a path in the storage of one user to that of another.
+// Paths outside the storage of the old owner stay as they are.
+func reassignedPath(path string, from, to uuid.UUID) (string, bool) {
+	prefix := "users/" + from.String() + "/"
+	if !strings.HasPrefix(path, prefix) {
+		return path, false
+	}
+	return "users/" + to.String() + "/" + strings.TrimPrefix(path, prefix), true
+}
+
+// undoFileMoves moves files back after the reassignment was rolled back.
+// Files that fail to move back are logged, as their rows no longer point to
+// them.
+func undoFileMoves(ctx context.Context, moved []fileMove) {
+	ctx = context.WithoutCancel(ctx)
+	for i := len(moved) - 1; i >= 0; i-- {
+		move := moved[i]
+		if err := move.store.Move(ctx, move.to, move.from); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"from": move.to,
+				"to":   move.from,
+			}).Error("Failed to move file back after a failed asset reassignment")
+		}
+	}
+}
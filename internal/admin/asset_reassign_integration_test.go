@@ -0,0 +1,164 @@
+//go:build integration
+// +build integration
+
+package admin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+type reassignTestEnv struct {
+	tdb     *testdb.TestDB
+	service *Service
+	root    string
+	from    uuid.UUID
+	to      uuid.UUID
+}
+
+func newReassignTestEnv(t *testing.T) *reassignTestEnv {
+	t.Helper()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(context.Background(), tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	root := t.TempDir()
+	store, err := storage.NewService(storage.StorageConfig{
+		Backend: "local",
+		Local:   storage.LocalConfig{RootPath: root, FileMode: "0644", DirMode: "0755"},
+	})
+	require.NoError(t, err)
+	service, err := NewService(conn.Queries, &config.Config{}, store)
+	require.NoError(t, err)
+	service.SetTxRunner(conn)
+
+	return &reassignTestEnv{
+		tdb:     tdb,
+		service: service,
+		root:    root,
+		from:    tdb.CreateTestUser(t, "reassign-from@example.com"),
+		to:      tdb.CreateTestUser(t, "reassign-to@example.com"),
+	}
+}
+
+// createAsset creates an asset of the old owner with an original and a
+// thumbnail in its storage, and returns it with their paths.
+func (e *reassignTestEnv) createAsset(t *testing.T, name string) (pgtype.UUID, string, string) {
+	t.Helper()
+	ctx := context.Background()
+	assetID := pgtype.UUID{Bytes: e.tdb.CreateTestAsset(t, e.from, name), Valid: true}
+	originalPath := "users/" + e.from.String() + "/2024/05/01/" + name + ".jpg"
+	thumbnailPath := "users/" + e.from.String() + "/thumbs/" + name + "_thumbnail.webp"
+
+	_, err := e.tdb.Pool.Exec(ctx, `UPDATE assets SET "originalPath" = $2 WHERE id = $1`, assetID, originalPath)
+	require.NoError(t, err)
+	_, err = e.tdb.Pool.Exec(ctx, `INSERT INTO exif ("assetId", "fileSizeInByte") VALUES ($1, 1000)`, assetID)
+	require.NoError(t, err)
+	_, err = e.tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{AssetId: assetID, Type: "thumbnail", Path: thumbnailPath})
+	require.NoError(t, err)
+	for _, path := range []string{originalPath, thumbnailPath} {
+		require.NoError(t, e.service.storage.UploadBytes(ctx, path, []byte(name), "image/jpeg"))
+	}
+	return assetID, originalPath, thumbnailPath
+}
+
+func (e *reassignTestEnv) quotaUsage(t *testing.T, userID uuid.UUID) int64 {
+	t.Helper()
+	user, err := e.tdb.Queries.GetUser(context.Background(), pgtype.UUID{Bytes: userID, Valid: true})
+	require.NoError(t, err)
+	return user.QuotaUsageInBytes
+}
+
+// TestReassignAssetMovesFilesAndQuota reassigns an asset that is in an album
+// and checks its files, quota usage and album link follow the flag.
+func TestReassignAssetMovesFilesAndQuota(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	env := newReassignTestEnv(t)
+	ctx := context.Background()
+
+	assetID, originalPath, thumbnailPath := env.createAsset(t, "beach")
+	_, err := env.tdb.Pool.Exec(ctx, `UPDATE users SET "quotaUsageInBytes" = 1500 WHERE id = $1`, env.from)
+	require.NoError(t, err)
+	album, err := env.tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{
+		OwnerId:   pgtype.UUID{Bytes: env.from, Valid: true},
+		AlbumName: "Holidays",
+	})
+	require.NoError(t, err)
+	require.NoError(t, env.tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: assetID}))
+
+	result, err := env.service.ReassignAsset(ctx, ReassignAssetRequest{
+		AssetID:           assetID.Bytes,
+		NewOwnerID:        env.to,
+		StripAssociations: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{assetID.Bytes}, result.AssetIDs)
+	assert.Equal(t, 2, result.MovedFiles)
+	assert.Equal(t, int64(1000), result.SizeBytes)
+	assert.Equal(t, int64(1), result.RemovedAlbumLinks)
+
+	asset, err := env.tdb.Queries.GetAsset(ctx, assetID)
+	require.NoError(t, err)
+	assert.Equal(t, env.to, uuid.UUID(asset.OwnerId.Bytes))
+	assert.Equal(t, "users/"+env.to.String()+"/2024/05/01/beach.jpg", asset.OriginalPath)
+	files, err := env.tdb.Queries.GetAssetFiles(ctx, assetID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "users/"+env.to.String()+"/thumbs/beach_thumbnail.webp", files[0].Path)
+
+	for _, path := range []string{originalPath, thumbnailPath} {
+		assert.NoFileExists(t, filepath.Join(env.root, path))
+	}
+	assert.FileExists(t, filepath.Join(env.root, asset.OriginalPath))
+	assert.FileExists(t, filepath.Join(env.root, files[0].Path))
+
+	assert.Equal(t, int64(500), env.quotaUsage(t, env.from))
+	assert.Equal(t, int64(1000), env.quotaUsage(t, env.to))
+
+	_, err = env.service.ReassignAsset(ctx, ReassignAssetRequest{AssetID: assetID.Bytes, NewOwnerID: env.to})
+	assert.ErrorIs(t, err, ErrReassignSameOwner)
+}
+
+// TestReassignAssetRollsBackWhenAFileFailsToMove blocks the destination of the
+// thumbnail, and checks the original is moved back and no row changed.
+func TestReassignAssetRollsBackWhenAFileFailsToMove(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	env := newReassignTestEnv(t)
+	ctx := context.Background()
+
+	assetID, originalPath, thumbnailPath := env.createAsset(t, "forest")
+	blocked := filepath.Join(env.root, "users", env.to.String(), "thumbs")
+	require.NoError(t, os.MkdirAll(filepath.Dir(blocked), 0o755))
+	require.NoError(t, os.WriteFile(blocked, []byte("not a directory"), 0o644))
+
+	_, err := env.service.ReassignAsset(ctx, ReassignAssetRequest{AssetID: assetID.Bytes, NewOwnerID: env.to})
+	require.Error(t, err)
+
+	asset, err := env.tdb.Queries.GetAsset(ctx, assetID)
+	require.NoError(t, err)
+	assert.Equal(t, env.from, uuid.UUID(asset.OwnerId.Bytes))
+	assert.Equal(t, originalPath, asset.OriginalPath)
+	files, err := env.tdb.Queries.GetAssetFiles(ctx, assetID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, thumbnailPath, files[0].Path)
+
+	assert.FileExists(t, filepath.Join(env.root, originalPath))
+	assert.FileExists(t, filepath.Join(env.root, thumbnailPath))
+	assert.NoFileExists(t, filepath.Join(env.root, "users", env.to.String(), "2024", "05", "01", "forest.jpg"))
+	assert.Equal(t, int64(0), env.quotaUsage(t, env.to))
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// ReassignAsset makes another user the owner of an asset (admin function)
+func (s *Server) ReassignAsset(ctx context.Context, request *immichv1.ReassignAssetRequest) (*immichv1.ReassignAssetResponseDto, error) {
+	// Require admin privileges
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "admin privileges required")
+	}
+
+	assetID, err := uuid.Parse(request.GetAssetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid asset ID")
+	}
+	newOwnerID, err := uuid.Parse(request.GetNewOwnerId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid new owner ID")
+	}
+
+	result, err := s.service.ReassignAsset(ctx, ReassignAssetRequest{
+		AssetID:           assetID,
+		NewOwnerID:        newOwnerID,
+		StripAssociations: request.GetStripAssociations(),
+	})
+	switch {
+	case errors.Is(err, ErrReassignAssetNotFound), errors.Is(err, ErrReassignOwnerNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrReassignSameOwner):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrReassignExternal), errors.Is(err, ErrReassignStacked):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrReassignDuplicate):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to reassign asset", err)
+	}
+
+	assetIDs := make([]string, len(result.AssetIDs))
+	for i, id := range result.AssetIDs {
+		assetIDs[i] = id.String()
+	}
+	return &immichv1.ReassignAssetResponseDto{
+		AssetIds:          assetIDs,
+		PreviousOwnerId:   result.PreviousOwnerID.String(),
+		OwnerId:           result.OwnerID.String(),
+		MovedFiles:        int32(result.MovedFiles),
+		SizeBytes:         result.SizeBytes,
+		RemovedAlbumLinks: result.RemovedAlbumLinks,
+		RemovedTagLinks:   result.RemovedTagLinks,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReassignedPath(t *testing.T) {
+	from := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	to := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	path, ok := reassignedPath("users/"+from.String()+"/2024/05/01/ab/cd/photo.jpg", from, to)
+	assert.True(t, ok)
+	assert.Equal(t, "users/"+to.String()+"/2024/05/01/ab/cd/photo.jpg", path)
+
+	for _, kept := range []string{
+		"/mnt/photos/photo.jpg",
+		"users/" + to.String() + "/photo.jpg",
+		"users/" + from.String() + "-other/photo.jpg",
+		"thumbs/" + from.String() + "/photo.jpeg",
+	} {
+		path, ok := reassignedPath(kept, from, to)
+		assert.False(t, ok, kept)
+		assert.Equal(t, kept, path)
+	}
+}
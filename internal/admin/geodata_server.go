@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/jobs"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// ImportGeodata queues an import of the reverse geocoding dataset, after
+// which the place names of geotagged assets are updated. GetGeodataImport
+// reports how many changed.
+func (s *Server) ImportGeodata(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	if s.service.config == nil || s.service.config.Geodata.Dir == "" {
+		return nil, status.Error(codes.FailedPrecondition, "no geodata directory is configured, set GEODATA_DIR")
+	}
+	if err := s.jobService.EnqueueGeodataImport(ctx); err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to queue geodata import", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetGeodataImport returns the report of the latest geodata import.
+func (s *Server) GetGeodataImport(ctx context.Context, _ *emptypb.Empty) (*immichv1.GeodataImportResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	report, err := s.jobService.GetGeodataImport(ctx)
+	if errors.Is(err, jobs.ErrNoGeodataImport) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get geodata import", err)
+	}
+	return &immichv1.GeodataImportResponseDto{
+		StartedAt:  timestamppb.New(report.StartedAt),
+		FinishedAt: timestamppb.New(report.FinishedAt),
+		Places:     int32(report.Places),
+		LastUpdate: report.LastUpdate,
+		Assets:     int32(report.Assets),
+		Updated:    int32(report.Updated),
+	}, nil
+}
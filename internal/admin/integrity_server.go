@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"slices"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/integrity"
+	"github.com/denysvitali/immich-go-backend/internal/jobs"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
@@ -17,15 +20,8 @@ import (
 
 const integrityReportCSVHeader = "id,type,path\n"
 
-var integrityReportTypes = map[string]struct{}{
-	integrityTypeChecksumMismatch: {},
-	integrityTypeMissingFile:      {},
-	integrityTypeUntrackedFile:    {},
-}
-
 func validIntegrityReportType(reportType string) bool {
-	_, ok := integrityReportTypes[reportType]
-	return ok
+	return slices.Contains(integrity.Types, reportType)
 }
 
 func requireIntegrityReportType(reportType string) error {
@@ -61,7 +57,7 @@ func (s *Server) GetIntegrityReport(ctx context.Context, request *immichv1.GetIn
 
 	report, err := s.integrityReport(ctx)
 	if err != nil {
-		return nil, grpcutil.SanitizedInternal(ctx, "failed to build integrity report", err)
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to load integrity report", err)
 	}
 
 	items, nextCursor, err := paginateIntegrityItems(report.itemsByType(request.GetType()), request.GetCursor(), request.GetLimit())
@@ -75,9 +71,9 @@ func (s *Server) GetIntegrityReport(ctx context.Context, request *immichv1.GetIn
 	}, nil
 }
 
-// DeleteIntegrityReport deletes a flagged report item. Report items are derived
-// from the current scan and are not persisted, so this remains non-destructive
-// until explicit repair/delete semantics exist.
+// DeleteIntegrityReport deletes a flagged report item. Report items are
+// replaced by every integrity scan, so this remains non-destructive until
+// explicit repair/delete semantics exist.
 func (s *Server) DeleteIntegrityReport(ctx context.Context, request *immichv1.DeleteIntegrityReportRequest) (*emptypb.Empty, error) {
 	if err := requireIntegrityAdmin(ctx); err != nil {
 		return nil, err
@@ -100,11 +96,11 @@ func (s *Server) GetIntegrityReportFile(ctx context.Context, request *immichv1.G
 
 	report, err := s.integrityReport(ctx)
 	if err != nil {
-		return nil, grpcutil.SanitizedInternal(ctx, "failed to build integrity report", err)
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to load integrity report", err)
 	}
 
 	item, ok := report.findItem(request.GetId())
-	if !ok || item.Type == integrityTypeMissingFile || s.service == nil || s.service.storage == nil {
+	if !ok || item.Type == integrity.TypeMissingFile || item.Type == integrity.TypeMissingThumbnail || s.service == nil || s.service.storage == nil {
 		return nil, status.Error(codes.NotFound, "integrity report item not found")
 	}
 
@@ -138,7 +134,7 @@ func (s *Server) GetIntegrityReportCsv(ctx context.Context, request *immichv1.Ge
 
 	report, err := s.integrityReport(ctx)
 	if err != nil {
-		return nil, grpcutil.SanitizedInternal(ctx, "failed to build integrity report", err)
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to load integrity report", err)
 	}
 
 	return &immichv1.IntegrityReportFileResponse{
@@ -156,20 +152,43 @@ func (s *Server) GetIntegrityReportSummary(ctx context.Context, _ *emptypb.Empty
 
 	report, err := s.integrityReport(ctx)
 	if err != nil {
-		return nil, grpcutil.SanitizedInternal(ctx, "failed to build integrity report", err)
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to load integrity report", err)
 	}
 
 	summary := report.summary()
 	return &immichv1.IntegrityReportSummaryResponseDto{
-		ChecksumMismatch: summary[integrityTypeChecksumMismatch],
-		MissingFile:      summary[integrityTypeMissingFile],
-		UntrackedFile:    summary[integrityTypeUntrackedFile],
+		ChecksumMismatch: summary[integrity.TypeChecksumMismatch],
+		MissingFile:      summary[integrity.TypeMissingFile],
+		UntrackedFile:    summary[integrity.TypeUntrackedFile],
+		MissingThumbnail: summary[integrity.TypeMissingThumbnail],
 	}, nil
 }
 
+// StartIntegrityScan queues an integrity scan. The scan runs in the
+// background and replaces the integrity report when it finishes; admins are
+// notified of the outcome.
+func (s *Server) StartIntegrityScan(ctx context.Context, request *immichv1.StartIntegrityScanRequest) (*emptypb.Empty, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if s.jobService == nil {
+		return nil, status.Error(codes.Unavailable, "job service is not available")
+	}
+
+	payload := jobs.IntegrityScanPayload{VerifyThumbnails: request.GetVerifyThumbnails()}
+	if request.VerifyThumbnails == nil && s.service != nil && s.service.config != nil {
+		payload.VerifyThumbnails = s.service.config.Integrity.VerifyThumbnails
+	}
+	if err := s.jobService.EnqueueIntegrityScan(ctx, payload); err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to queue integrity scan", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
 func (s *Server) integrityReport(ctx context.Context) (*integrityReport, error) {
 	if s == nil || s.service == nil {
 		return newIntegrityReport(nil), nil
 	}
-	return s.service.buildIntegrityReport(ctx)
+	return s.service.loadIntegrityReport(ctx)
 }
@@ -21,6 +21,7 @@ func TestValidIntegrityReportType(t *testing.T) {
 	assert.True(t, validIntegrityReportType("untracked_file"))
 	assert.True(t, validIntegrityReportType("missing_file"))
 	assert.True(t, validIntegrityReportType("checksum_mismatch"))
+	assert.True(t, validIntegrityReportType("missing_thumbnail"))
 	assert.False(t, validIntegrityReportType(""))
 	assert.False(t, validIntegrityReportType("unknown"))
 }
@@ -96,3 +97,15 @@ func TestIntegrityReportItemOperationsRejectInvalidID(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, codes.InvalidArgument, status.Code(err))
 }
+
+func TestStartIntegrityScanRequiresJobService(t *testing.T) {
+	srv := &Server{}
+
+	_, err := srv.StartIntegrityScan(context.Background(), &immichv1.StartIntegrityScanRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = srv.StartIntegrityScan(adminContext(), &immichv1.StartIntegrityScanRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
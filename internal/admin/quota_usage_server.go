@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/jobs"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// SyncQuotaUsage queues a recomputation of every user's quota usage. The
+// sync runs in the background; GetQuotaUsageSync reports what it corrected.
+func (s *Server) SyncQuotaUsage(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.jobService.EnqueueQuotaUsageSync(ctx); err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to queue quota usage sync", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetQuotaUsageSync returns the report of the latest quota usage sync.
+func (s *Server) GetQuotaUsageSync(ctx context.Context, _ *emptypb.Empty) (*immichv1.QuotaUsageSyncResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	report, err := s.jobService.GetQuotaUsageSync(ctx)
+	if errors.Is(err, jobs.ErrNoQuotaUsageSync) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get quota usage sync", err)
+	}
+	return quotaUsageSyncToDto(report), nil
+}
+
+func quotaUsageSyncToDto(report *jobs.QuotaUsageSync) *immichv1.QuotaUsageSyncResponseDto {
+	corrections := make([]*immichv1.QuotaUsageCorrectionDto, len(report.Corrections))
+	for i, correction := range report.Corrections {
+		corrections[i] = &immichv1.QuotaUsageCorrectionDto{
+			UserId: correction.UserID.String(),
+			Stored: correction.Stored,
+			Actual: correction.Actual,
+		}
+	}
+	return &immichv1.QuotaUsageSyncResponseDto{
+		StartedAt:   timestamppb.New(report.StartedAt),
+		FinishedAt:  timestamppb.New(report.FinishedAt),
+		Users:       int32(report.Users),
+		Corrections: corrections,
+		Skipped:     int32(report.Skipped),
+	}
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/jobs"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// ReindexAssets starts re-running the requested processing steps for every
+// asset matching the filters. The response has the estimated work; the jobs
+// are queued in the background.
+func (s *Server) ReindexAssets(ctx context.Context, request *immichv1.ReindexAssetsRequest) (*immichv1.ReindexStatusResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+
+	steps := make([]jobs.ReindexStep, len(request.GetSteps()))
+	for i, step := range request.GetSteps() {
+		steps[i] = jobs.ReindexStep(step)
+	}
+
+	var filter jobs.ReindexFilter
+	if request.UserId != nil {
+		id, err := uuid.Parse(request.GetUserId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+		}
+		filter.UserID = &id
+	}
+	if request.LibraryId != nil {
+		id, err := uuid.Parse(request.GetLibraryId())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid library ID")
+		}
+		filter.LibraryID = &id
+	}
+	if request.TakenAfter != nil {
+		takenAfter := request.GetTakenAfter().AsTime()
+		filter.TakenAfter = &takenAfter
+	}
+	if request.TakenBefore != nil {
+		takenBefore := request.GetTakenBefore().AsTime()
+		filter.TakenBefore = &takenBefore
+	}
+	if filter.TakenAfter != nil && filter.TakenBefore != nil && !filter.TakenAfter.Before(*filter.TakenBefore) {
+		return nil, status.Error(codes.InvalidArgument, "taken_after must be before taken_before")
+	}
+
+	run, err := s.jobService.EnqueueReindex(ctx, steps, filter)
+	switch {
+	case errors.Is(err, jobs.ErrInvalidReindexStep):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, jobs.ErrReindexInProgress):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to start reindex", err)
+	}
+
+	return reindexStatusToDto(&jobs.ReindexStatus{ReindexRun: *run}), nil
+}
+
+// GetReindexStatus returns the progress of the latest re-index run.
+func (s *Server) GetReindexStatus(ctx context.Context, _ *emptypb.Empty) (*immichv1.ReindexStatusResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	return s.reindexStatus(ctx)
+}
+
+// PauseReindex stops processing of re-index jobs until ResumeReindex.
+func (s *Server) PauseReindex(ctx context.Context, _ *emptypb.Empty) (*immichv1.ReindexStatusResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.jobService.PauseReindex(ctx); err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to pause reindex", err)
+	}
+	return s.reindexStatus(ctx)
+}
+
+// ResumeReindex resumes processing of re-index jobs.
+func (s *Server) ResumeReindex(ctx context.Context, _ *emptypb.Empty) (*immichv1.ReindexStatusResponseDto, error) {
+	if err := s.requireAdminJobs(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.jobService.ResumeReindex(ctx); err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to resume reindex", err)
+	}
+	return s.reindexStatus(ctx)
+}
+
+func (s *Server) requireAdminJobs(ctx context.Context) error {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return err
+	}
+	if s.jobService == nil {
+		return status.Error(codes.Unavailable, "job service is not available")
+	}
+	return nil
+}
+
+func (s *Server) reindexStatus(ctx context.Context) (*immichv1.ReindexStatusResponseDto, error) {
+	reindex, err := s.jobService.GetReindexStatus(ctx)
+	if errors.Is(err, jobs.ErrNoReindexRun) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get reindex status", err)
+	}
+	return reindexStatusToDto(reindex), nil
+}
+
+func reindexStatusToDto(reindex *jobs.ReindexStatus) *immichv1.ReindexStatusResponseDto {
+	steps := make([]string, len(reindex.Steps))
+	for i, step := range reindex.Steps {
+		steps[i] = string(step)
+	}
+	dto := &immichv1.ReindexStatusResponseDto{
+		Id:              reindex.ID,
+		Steps:           steps,
+		EstimatedAssets: reindex.EstimatedAssets,
+		EstimatedJobs:   reindex.EstimatedJobs,
+		EnqueuedJobs:    reindex.EnqueuedJobs,
+		PendingJobs:     reindex.Pending,
+		ActiveJobs:      reindex.Active,
+		CompletedJobs:   reindex.Completed,
+		FailedJobs:      reindex.Failed,
+		Paused:          reindex.Paused,
+		Enqueuing:       reindex.EnqueuedAt == nil,
+		StartedAt:       timestamppb.New(reindex.StartedAt),
+	}
+	if reindex.EnqueuedAt != nil {
+		dto.EnqueuedAt = timestamppb.New(*reindex.EnqueuedAt)
+	}
+	return dto
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	"github.com/denysvitali/immich-go-backend/internal/jobs"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/systemmetadata"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/grpc/codes"
@@ -177,22 +179,13 @@ func (s *Server) CreateUserAdmin(ctx context.Context, request *immichv1.CreateUs
 		return nil, status.Error(codes.PermissionDenied, "admin privileges required")
 	}
 
-	// Validate request
-	if request.GetEmail() == "" {
-		return nil, status.Error(codes.InvalidArgument, "email is required")
-	}
-	if request.GetName() == "" {
-		return nil, status.Error(codes.InvalidArgument, "name is required")
-	}
-	if request.GetPassword() == "" {
-		return nil, status.Error(codes.InvalidArgument, "password is required")
-	}
-
 	// Convert request
 	req := CreateUserAdminRequest{
-		Email:    request.GetEmail(),
-		Name:     request.GetName(),
-		Password: request.GetPassword(),
+		Email:                 request.GetEmail(),
+		Name:                  request.GetName(),
+		Password:              request.GetPassword(),
+		Notify:                request.Notify,
+		SendPasswordSetupLink: request.GetSendPasswordSetupLink(),
 	}
 	if request.QuotaSizeInBytes != nil {
 		quota := request.GetQuotaSizeInBytes()
@@ -206,14 +199,30 @@ func (s *Server) CreateUserAdmin(ctx context.Context, request *immichv1.CreateUs
 		label := request.GetStorageLabel()
 		req.StorageLabel = &label
 	}
+	if err := grpcutil.Validate(ctx, "invalid user", req); err != nil {
+		return nil, err
+	}
 
 	// Call service
 	response, err := s.service.CreateUserAdmin(ctx, req)
 	if err != nil {
+		if errors.Is(err, systemmetadata.ErrNotOnboarded) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to create user", err)
 	}
+	s.publishWebhookEvent(ctx, webhooks.EventUserCreated,
+		webhooks.UserData(response.ID, response.Email, response.Name, response.IsAdmin))
 
-	return s.convertToProtoUser(response), nil
+	user := s.convertToProtoUser(response)
+	user.WelcomeEmailSent = &response.WelcomeEmailSent
+	if response.TemporaryPassword != "" {
+		user.TemporaryPassword = &response.TemporaryPassword
+	}
+	if response.PasswordSetupURL != "" {
+		user.PasswordSetupUrl = &response.PasswordSetupURL
+	}
+	return user, nil
 }
 
 // GetUserAdmin retrieves a user by ID (admin function)
@@ -275,6 +284,9 @@ func (s *Server) UpdateUserAdmin(ctx context.Context, request *immichv1.UpdateUs
 		label := request.GetStorageLabel()
 		req.StorageLabel = &label
 	}
+	if err := grpcutil.Validate(ctx, "invalid user", req); err != nil {
+		return nil, err
+	}
 
 	// Call service
 	response, err := s.service.UpdateUserAdmin(ctx, request.GetId(), req)
@@ -298,15 +310,49 @@ func (s *Server) DeleteUserAdmin(ctx context.Context, request *immichv1.DeleteUs
 		force = request.GetForce()
 	}
 
+	// Permanent deletion runs as a background job
+	if force && s.jobService == nil {
+		return nil, status.Error(codes.Unavailable, "job service is not available")
+	}
+
 	// Call service
 	response, err := s.service.DeleteUserAdmin(ctx, request.GetId(), force)
 	if err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to delete user", err)
 	}
 
+	if force {
+		// Deleting again re-enqueues the job if this fails
+		if err := s.jobService.EnqueueUserDeletion(ctx, uuid.MustParse(response.ID)); err != nil {
+			return nil, grpcutil.SanitizedInternal(ctx, "failed to schedule user deletion", err)
+		}
+	}
+
 	return s.convertToProtoUser(response), nil
 }
 
+// GetUserDeletionPreviewAdmin counts what permanently deleting a user removes (admin function)
+func (s *Server) GetUserDeletionPreviewAdmin(ctx context.Context, request *immichv1.GetUserDeletionPreviewAdminRequest) (*immichv1.UserDeletionPreviewResponseDto, error) {
+	// Require admin privileges
+	_, err := auth.RequireAdmin(ctx)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "admin privileges required")
+	}
+
+	// Call service
+	response, err := s.service.GetUserDeletionPreview(ctx, request.GetId())
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to preview user deletion", err)
+	}
+
+	return &immichv1.UserDeletionPreviewResponseDto{
+		AssetCount:      response.AssetCount,
+		TotalSizeBytes:  response.TotalSizeBytes,
+		AlbumCount:      response.AlbumCount,
+		SharedLinkCount: response.SharedLinkCount,
+	}, nil
+}
+
 // RestoreUserAdmin restores a soft-deleted user (admin function)
 func (s *Server) RestoreUserAdmin(ctx context.Context, request *immichv1.RestoreUserAdminRequest) (*immichv1.UserAdminResponseDto, error) {
 	// Require admin privileges
@@ -468,11 +514,15 @@ func (s *Server) convertToProtoUser(user *UserAdminResponseDto) *immichv1.UserAd
 	}
 
 	if user.QuotaSizeInBytes != nil {
-		protoUser.QuotaSizeInBytes = *user.QuotaSizeInBytes
+		protoUser.QuotaSizeInBytes = user.QuotaSizeInBytes
 	}
 
 	if user.StorageLabel != nil {
-		protoUser.StorageLabel = *user.StorageLabel
+		protoUser.StorageLabel = user.StorageLabel
+	}
+
+	if user.ScheduledDeletionAt != nil {
+		protoUser.ScheduledDeletionAt = timestamppb.New(*user.ScheduledDeletionAt)
 	}
 
 	return protoUser
@@ -0,0 +1,49 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// The server has no service: invalid requests must fail before reaching it.
+
+func TestCreateUserAdminValidatesRequest(t *testing.T) {
+	srv := &Server{}
+	quota := int64(-1)
+
+	_, err := srv.CreateUserAdmin(adminContext(), &immichv1.CreateUserAdminRequest{
+		Email:            "not-an-email",
+		QuotaSizeInBytes: &quota,
+	})
+	require.Error(t, err)
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, []grpcutil.FieldViolation{
+		{Field: "email", Description: "email must be an email"},
+		{Field: "name", Description: "name should not be empty"},
+		{Field: "quotaSizeInBytes", Description: "quotaSizeInBytes must not be less than 0"},
+	}, grpcutil.FieldViolations(st))
+}
+
+func TestUpdateUserAdminValidatesRequest(t *testing.T) {
+	srv := &Server{}
+	name := ""
+
+	_, err := srv.UpdateUserAdmin(adminContext(), &immichv1.UpdateUserAdminRequest{
+		Id:   "00000000-0000-0000-0000-000000000001",
+		Name: &name,
+	})
+	require.Error(t, err)
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, []grpcutil.FieldViolation{
+		{Field: "name", Description: "name should not be empty"},
+	}, grpcutil.FieldViolations(st))
+}
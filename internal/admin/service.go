@@ -13,7 +13,9 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
+	"github.com/denysvitali/immich-go-backend/internal/systemmetadata"
 	"github.com/denysvitali/immich-go-backend/internal/telemetry"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.opentelemetry.io/otel/attribute"
@@ -36,6 +38,9 @@ type Service struct {
 	config  *config.Config
 	storage *storage.Service
 	email   emailSender
+	tx      TxRunner
+
+	webhooks *webhooks.Service
 
 	// Metrics
 	operationCounter  metric.Int64Counter
@@ -67,6 +72,7 @@ func NewService(queries *sqlc.Queries, cfg *config.Config, storageSvc *storage.S
 		config:            cfg,
 		storage:           storageSvc,
 		email:             smtpEmailSender{},
+		webhooks:          webhooks.NewService(queries),
 		operationCounter:  operationCounter,
 		operationDuration: operationDuration,
 	}, nil
@@ -429,12 +435,33 @@ func (s *Service) CreateUserAdmin(ctx context.Context, req CreateUserAdminReques
 	}()
 
 	// Validate input
-	if req.Email == "" || req.Name == "" || req.Password == "" {
-		return nil, fmt.Errorf("email, name, and password are required")
+	if req.Email == "" || req.Name == "" {
+		return nil, fmt.Errorf("email and name are required")
+	}
+
+	// Users are only invited once the admin has finished first-run setup,
+	// so their files land under the configured storage template.
+	onboarded, err := systemmetadata.AdminOnboarded(ctx, s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check onboarding state: %w", err)
+	}
+	if !onboarded {
+		return nil, systemmetadata.ErrNotOnboarded
+	}
+
+	// Without a password the user gets a generated one they have to change
+	password := req.Password
+	generated := password == ""
+	if generated {
+		if password, err = generateTemporaryPassword(); err != nil {
+			return nil, err
+		}
+		shouldChange := true
+		req.ShouldChangePassword = &shouldChange
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -477,7 +504,9 @@ func (s *Service) CreateUserAdmin(ctx context.Context, req CreateUserAdminReques
 		}
 	}
 
-	return s.convertUserToDto(&user), nil
+	dto := s.convertUserToDto(&user)
+	s.welcomeUser(ctx, req, user, password, generated, dto)
+	return dto, nil
 }
 
 // GetUserAdmin retrieves a user by ID (admin function)
@@ -612,8 +641,9 @@ func (s *Service) DeleteUserAdmin(ctx context.Context, userID string, force bool
 	}
 	userUUID := pgtype.UUID{Bytes: uid, Valid: true}
 
-	// Get user before deletion for return
-	user, err := s.db.GetUserByID(ctx, userUUID)
+	// Get user before deletion for return; a soft-deleted user can still be
+	// deleted permanently
+	user, err := s.db.GetUserIncludingDeleted(ctx, userUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -625,29 +655,70 @@ func (s *Service) DeleteUserAdmin(ctx context.Context, userID string, force bool
 	}
 
 	// Perform deletion based on force flag
+	var deleteAt *time.Time
 	if force {
-		// Hard delete - permanently remove from database
-		err = s.db.HardDeleteUser(ctx, userUUID)
+		// Hard delete - hide the user now; the user deletion job removes
+		// their files and rows. This also cuts a grace period short.
+		err = s.db.MarkUserForRemoval(ctx, userUUID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to hard delete user: %w", err)
+			return nil, fmt.Errorf("failed to mark user for removal: %w", err)
+		}
+		if err := s.cancelUserDeletion(ctx, userUUID); err != nil {
+			return nil, err
 		}
 	} else {
-		// Soft delete - set deletedAt timestamp
+		// Soft delete - set deletedAt timestamp and remove the user for good
+		// once the grace period ends, unless the account is restored first
 		err = s.db.SoftDeleteUser(ctx, userUUID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to soft delete user: %w", err)
 		}
+		scheduled, err := s.scheduleUserDeletion(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		deleteAt = &scheduled
 	}
 
 	// Return the user data as it was before deletion
 	dto := s.convertUserToDto(&user)
-	if !force {
+	if dto.DeletedAt == nil {
 		now := time.Now()
 		dto.DeletedAt = &now
 	}
+	dto.ScheduledDeletionAt = deleteAt
 	return dto, nil
 }
 
+// GetUserDeletionPreview counts what permanently deleting a user would remove
+func (s *Service) GetUserDeletionPreview(ctx context.Context, userID string) (*UserDeletionPreviewDto, error) {
+	ctx, span := tracer.Start(ctx, "admin.get_user_deletion_preview",
+		trace.WithAttributes(attribute.String("user_id", userID)))
+	defer span.End()
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	userUUID := pgtype.UUID{Bytes: uid, Valid: true}
+
+	if _, err := s.db.GetUserIncludingDeleted(ctx, userUUID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	summary, err := s.db.GetUserDeletionSummary(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize user data: %w", err)
+	}
+
+	return &UserDeletionPreviewDto{
+		AssetCount:      summary.AssetCount,
+		TotalSizeBytes:  summary.TotalSizeBytes,
+		AlbumCount:      summary.AlbumCount,
+		SharedLinkCount: summary.SharedLinkCount,
+	}, nil
+}
+
 // RestoreUserAdmin restores a soft-deleted user (admin function)
 func (s *Service) RestoreUserAdmin(ctx context.Context, userID string) (*UserAdminResponseDto, error) {
 	ctx, span := tracer.Start(ctx, "admin.restore_user_admin",
@@ -674,6 +745,9 @@ func (s *Service) RestoreUserAdmin(ctx context.Context, userID string) (*UserAdm
 	if err != nil {
 		return nil, fmt.Errorf("failed to restore user: %w", err)
 	}
+	if err := s.cancelUserDeletion(ctx, userUUID); err != nil {
+		return nil, err
+	}
 
 	return s.convertUserToDto(&user), nil
 }
@@ -719,26 +793,15 @@ func (s *Service) GetUserStatisticsAdmin(ctx context.Context, userID string) (*U
 		return nil, fmt.Errorf("failed to count videos: %w", err)
 	}
 
-	// Calculate total storage usage by getting all user assets and summing file sizes
-	var totalUsage int64
-	userAssets, err := s.db.GetUserAssets(ctx, sqlc.GetUserAssetsParams{
-		OwnerId: userUUID,
-		Limit:   pgtype.Int4{Int32: 10000, Valid: true},
-		Offset:  pgtype.Int4{Int32: 0, Valid: true},
-	})
-	if err == nil {
-		for _, asset := range userAssets {
-			// Get exif data for file size
-			exif, err := s.db.GetExifByAssetId(ctx, asset.ID)
-			if err == nil && exif.FileSizeInByte.Valid {
-				totalUsage += exif.FileSizeInByte.Int64
-			}
-		}
+	// Sum the file sizes in the database rather than loading every asset
+	stats, err := s.db.GetAssetStatsByUser(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
 	}
 
 	return &UserStatisticsResponseDto{
 		Photos: int32(photoCount),
-		Usage:  totalUsage,
+		Usage:  stats.TotalSize,
 		Videos: int32(videoCount),
 	}, nil
 }
@@ -807,23 +870,30 @@ type SearchUsersAdminResponse struct {
 }
 
 type CreateUserAdminRequest struct {
-	Email                string
-	Name                 string
+	Email string `validate:"required,email"`
+	Name  string `validate:"required,max=255"`
+	// Password is generated when empty, and must be changed on first login
 	Password             string
-	QuotaSizeInBytes     *int64
+	QuotaSizeInBytes     *int64 `validate:"omitnil,min=0"`
 	ShouldChangePassword *bool
-	StorageLabel         *string
+	StorageLabel         *string `validate:"omitnil,max=255"`
+	// Notify sends the user a welcome email when SMTP is enabled; unset
+	// means true
+	Notify *bool
+	// SendPasswordSetupLink adds a one-time link to choose a password to the
+	// welcome email
+	SendPasswordSetupLink bool
 }
 
 type UpdateUserAdminRequest struct {
 	AvatarColor          *UserAvatarColor
-	Email                *string
+	Email                *string `validate:"omitnil,email"`
 	IsAdmin              *bool
-	Name                 *string
-	Password             *string
-	QuotaSizeInBytes     *int64
+	Name                 *string `validate:"omitnil,min=1,max=255"`
+	Password             *string `validate:"omitnil,min=1"`
+	QuotaSizeInBytes     *int64  `validate:"omitnil,min=0"`
 	ShouldChangePassword *bool
-	StorageLabel         *string
+	StorageLabel         *string `validate:"omitnil,max=255"`
 }
 
 type UserAdminResponseDto struct {
@@ -841,6 +911,17 @@ type UserAdminResponseDto struct {
 	ShouldChangePassword bool
 	StorageLabel         *string
 	UpdatedAt            time.Time
+	// ScheduledDeletionAt is when a deleted user is removed for good
+	ScheduledDeletionAt *time.Time
+	// WelcomeEmailSent reports whether a created user was sent a welcome
+	// email
+	WelcomeEmailSent bool
+	// TemporaryPassword is the generated password of a created user, set
+	// only when no welcome email passed it on
+	TemporaryPassword string
+	// PasswordSetupURL is the password link of a created user, set only
+	// when no welcome email passed it on
+	PasswordSetupURL string
 }
 
 type UserStatisticsResponseDto struct {
@@ -849,6 +930,13 @@ type UserStatisticsResponseDto struct {
 	Videos int32
 }
 
+type UserDeletionPreviewDto struct {
+	AssetCount      int64
+	TotalSizeBytes  int64
+	AlbumCount      int64
+	SharedLinkCount int64
+}
+
 type UserAvatarColor int32
 
 const (
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// RunStorageGarbageCollection reports storage files that no database row
+// refers to and, when dry_run is explicitly false, deletes them. Files newer
+// than the grace period are kept because their row may not be written yet.
+func (s *Server) RunStorageGarbageCollection(ctx context.Context, request *immichv1.StorageGarbageCollectionRequest) (*immichv1.StorageGarbageCollectionResponseDto, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	gracePeriod := storageGCDefaultGracePeriod
+	if request.GracePeriodHours != nil {
+		gracePeriod = time.Duration(request.GetGracePeriodHours()) * time.Hour
+		if gracePeriod < storageGCMinGracePeriod {
+			return nil, status.Errorf(codes.InvalidArgument, "grace period must be at least %d hour", int(storageGCMinGracePeriod.Hours()))
+		}
+	}
+	dryRun := request.DryRun == nil || request.GetDryRun()
+
+	result, err := s.service.CollectStorageGarbage(ctx, dryRun, gracePeriod)
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to collect storage garbage", err)
+	}
+
+	reported := result.Orphans
+	if len(reported) > storageGCMaxReportedOrphans {
+		reported = reported[:storageGCMaxReportedOrphans]
+	}
+	orphans := make([]*immichv1.StorageOrphanDto, len(reported))
+	for i, orphan := range reported {
+		orphans[i] = &immichv1.StorageOrphanDto{
+			Path:       orphan.Path,
+			Size:       orphan.Size,
+			ModifiedAt: timestamppb.New(orphan.ModTime),
+		}
+	}
+
+	return &immichv1.StorageGarbageCollectionResponseDto{
+		DryRun:           result.DryRun,
+		GracePeriodHours: int32(gracePeriod.Hours()),
+		OrphanCount:      int64(len(result.Orphans)),
+		ReclaimableBytes: result.ReclaimableBytes,
+		RecentCount:      result.RecentCount,
+		DeletedCount:     result.DeletedCount,
+		DeletedBytes:     result.DeletedBytes,
+		FailedCount:      result.FailedCount,
+		Orphans:          orphans,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/denysvitali/immich-go-backend/internal/integrity"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+const (
+	// storageGCDefaultGracePeriod protects files whose database row is not
+	// written yet, such as direct S3 uploads that are confirmed later.
+	storageGCDefaultGracePeriod = 24 * time.Hour
+	storageGCMinGracePeriod     = time.Hour
+
+	storageGCMaxReportedOrphans = 1000
+)
+
+// storageGCProtectedPrefixes are never collected: database backups are
+// tracked in their own table, not by path.
+var storageGCProtectedPrefixes = []string{"backups/"}
+
+type storageGCStorage interface {
+	List(ctx context.Context, prefix string, recursive bool) ([]storage.FileInfo, error)
+	Delete(ctx context.Context, path string) error
+}
+
+type storageGCOrphan struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+type storageGCResult struct {
+	DryRun           bool
+	GracePeriod      time.Duration
+	Orphans          []storageGCOrphan
+	ReclaimableBytes int64
+	RecentCount      int64
+	DeletedCount     int64
+	DeletedBytes     int64
+	FailedCount      int64
+}
+
+// CollectStorageGarbage finds storage files that no asset, asset file, person
+// or user refers to and that are older than the grace period. Unless dryRun
+// is false it only reports them.
+func (s *Service) CollectStorageGarbage(ctx context.Context, dryRun bool, gracePeriod time.Duration) (*storageGCResult, error) {
+	if s == nil || s.db == nil || s.storage == nil {
+		return &storageGCResult{DryRun: dryRun, GracePeriod: gracePeriod}, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "admin.storage_gc",
+		trace.WithAttributes(
+			attribute.Bool("dry_run", dryRun),
+			attribute.String("grace_period", gracePeriod.String()),
+		))
+	defer span.End()
+
+	// List storage before reading the referenced paths, so a file whose row
+	// is written in between is seen as referenced rather than orphaned.
+	files, err := s.storage.List(ctx, "", true)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("list storage files: %w", err)
+	}
+
+	referenced, err := s.db.GetReferencedStoragePaths(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("list referenced storage paths: %w", err)
+	}
+
+	result := findStorageOrphans(files, referenced, time.Now().Add(-gracePeriod))
+	result.DryRun = dryRun
+	result.GracePeriod = gracePeriod
+	if !dryRun {
+		deleteStorageOrphans(ctx, s.storage, result)
+	}
+
+	span.SetAttributes(
+		attribute.Int("storage_gc.orphans", len(result.Orphans)),
+		attribute.Int64("storage_gc.reclaimable_bytes", result.ReclaimableBytes),
+		attribute.Int64("storage_gc.deleted", result.DeletedCount),
+		attribute.Int64("storage_gc.failed", result.FailedCount),
+	)
+
+	return result, nil
+}
+
+// findStorageOrphans returns the files not in referenced that were last
+// modified before cutoff, sorted by path. Files without a modification time
+// cannot be aged and are kept.
+func findStorageOrphans(files []storage.FileInfo, referenced []string, cutoff time.Time) *storageGCResult {
+	tracked := make(map[string]struct{}, len(referenced))
+	for _, p := range referenced {
+		addTrackedPath(tracked, strings.TrimPrefix(p, "/"))
+	}
+
+	result := &storageGCResult{}
+	for _, file := range files {
+		p := strings.TrimSpace(file.Path)
+		if file.IsDir || p == "" || integrity.IsIgnoredStoragePath(p) || isStorageGCProtectedPath(p) {
+			continue
+		}
+		if _, ok := tracked[p]; ok {
+			continue
+		}
+		if file.ModTime.IsZero() || !file.ModTime.Before(cutoff) {
+			result.RecentCount++
+			continue
+		}
+
+		result.Orphans = append(result.Orphans, storageGCOrphan{Path: p, Size: file.Size, ModTime: file.ModTime})
+		result.ReclaimableBytes += file.Size
+	}
+
+	sort.Slice(result.Orphans, func(i, j int) bool {
+		return result.Orphans[i].Path < result.Orphans[j].Path
+	})
+
+	return result
+}
+
+func addTrackedPath(paths map[string]struct{}, path string) {
+	path = strings.TrimSpace(path)
+	if path != "" {
+		paths[path] = struct{}{}
+	}
+}
+
+func isStorageGCProtectedPath(p string) bool {
+	for _, prefix := range storageGCProtectedPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteStorageOrphans deletes every orphan of result, carrying on past
+// failures so one unreadable file does not block the rest.
+func deleteStorageOrphans(ctx context.Context, storageSvc storageGCStorage, result *storageGCResult) {
+	for i, orphan := range result.Orphans {
+		if ctx.Err() != nil {
+			result.FailedCount += int64(len(result.Orphans) - i)
+			return
+		}
+		if err := storageSvc.Delete(ctx, orphan.Path); err != nil {
+			trace.SpanFromContext(ctx).RecordError(err, trace.WithAttributes(attribute.String("path", orphan.Path)))
+			result.FailedCount++
+			continue
+		}
+		result.DeletedCount++
+		result.DeletedBytes += orphan.Size
+	}
+}
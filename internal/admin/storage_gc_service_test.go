@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeStorageGCStorage struct {
+	deleted []string
+	fail    map[string]bool
+}
+
+func (s *fakeStorageGCStorage) List(context.Context, string, bool) ([]storage.FileInfo, error) {
+	return nil, nil
+}
+
+func (s *fakeStorageGCStorage) Delete(_ context.Context, path string) error {
+	if s.fail[path] {
+		return errors.New("permission denied")
+	}
+	s.deleted = append(s.deleted, path)
+	return nil
+}
+
+func TestFindStorageOrphansHonorsGracePeriodAndReferences(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	cutoff := now.Add(-storageGCDefaultGracePeriod)
+
+	result := findStorageOrphans([]storage.FileInfo{
+		{Path: "assets/u/tracked.jpg", Size: 10, ModTime: old},
+		{Path: "assets/u/thumbnails/tracked_preview.jpg", Size: 20, ModTime: old},
+		{Path: "assets/u/orphan.jpg", Size: 300, ModTime: old},
+		{Path: "assets/u/aborted.mov", Size: 4000, ModTime: old},
+		{Path: "assets/u/direct-upload.jpg", Size: 50000, ModTime: now.Add(-time.Minute)},
+		{Path: "assets/u/unknown-age.jpg", Size: 7},
+		{Path: "assets/u", IsDir: true, ModTime: old},
+		{Path: "backups/immich-db-backup.sql.gz", Size: 99, ModTime: old},
+		{Path: "upload/.immich", Size: 1, ModTime: old},
+	}, []string{
+		"/assets/u/tracked.jpg",
+		"assets/u/thumbnails/tracked_preview.jpg",
+	}, cutoff)
+
+	require.Len(t, result.Orphans, 2)
+	assert.Equal(t, "assets/u/aborted.mov", result.Orphans[0].Path)
+	assert.Equal(t, "assets/u/orphan.jpg", result.Orphans[1].Path)
+	assert.EqualValues(t, 4300, result.ReclaimableBytes)
+	assert.EqualValues(t, 2, result.RecentCount, "files inside the grace period or without a modification time are kept")
+}
+
+func TestDeleteStorageOrphansContinuesPastFailures(t *testing.T) {
+	fake := &fakeStorageGCStorage{fail: map[string]bool{"b.jpg": true}}
+	result := &storageGCResult{Orphans: []storageGCOrphan{
+		{Path: "a.jpg", Size: 1},
+		{Path: "b.jpg", Size: 2},
+		{Path: "c.jpg", Size: 4},
+	}}
+
+	deleteStorageOrphans(context.Background(), fake, result)
+
+	assert.Equal(t, []string{"a.jpg", "c.jpg"}, fake.deleted)
+	assert.EqualValues(t, 2, result.DeletedCount)
+	assert.EqualValues(t, 5, result.DeletedBytes)
+	assert.EqualValues(t, 1, result.FailedCount)
+}
+
+func TestRunStorageGarbageCollectionDefaultsToDryRun(t *testing.T) {
+	srv := &Server{}
+
+	resp, err := srv.RunStorageGarbageCollection(adminContext(), &immichv1.StorageGarbageCollectionRequest{})
+	require.NoError(t, err)
+	assert.True(t, resp.GetDryRun())
+	assert.EqualValues(t, 24, resp.GetGracePeriodHours())
+
+	hours := int32(0)
+	_, err = srv.RunStorageGarbageCollection(adminContext(), &immichv1.StorageGarbageCollectionRequest{GracePeriodHours: &hours})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = srv.RunStorageGarbageCollection(context.Background(), &immichv1.StorageGarbageCollectionRequest{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+	"github.com/denysvitali/immich-go-backend/internal/users"
+)
+
+// defaultUserDeleteDelay is the grace period of a deleted account when the
+// admin settings have none.
+const defaultUserDeleteDelay = 7 * 24 * time.Hour
+
+// AccountRestorePath is where the link in the deletion email points; the
+// token is passed as the token query parameter.
+const AccountRestorePath = "/account/restore"
+
+// scheduleUserDeletion starts the grace period of a soft-deleted user and
+// tells them how to restore their account. Failing to send the email does
+// not fail the deletion.
+func (s *Service) scheduleUserDeletion(ctx context.Context, user sqlc.User) (time.Time, error) {
+	cfg := s.systemConfig(ctx)
+	deleteAt := time.Now().Add(userDeleteDelay(cfg)).UTC()
+
+	token, hash, err := users.NewRestoreToken()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := s.db.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+		UserId:           user.ID,
+		DeleteAt:         pgtype.Timestamptz{Time: deleteAt, Valid: true},
+		RestoreTokenHash: hash,
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule user deletion: %w", err)
+	}
+
+	if err := s.sendDeletionScheduledEmail(ctx, cfg, user, deleteAt, token); err != nil {
+		logrus.WithError(err).WithField("user_id", uuid.UUID(user.ID.Bytes).String()).
+			Warn("Failed to send the account deletion email")
+	}
+	return deleteAt, nil
+}
+
+// cancelUserDeletion drops the scheduled deletion of a user, if any.
+func (s *Service) cancelUserDeletion(ctx context.Context, userID pgtype.UUID) error {
+	if err := s.db.DeleteUserDeletionSchedule(ctx, userID); err != nil {
+		return fmt.Errorf("failed to cancel scheduled user deletion: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) systemConfig(ctx context.Context) systemconfig.Dto {
+	cfg, err := systemconfig.NewService(s.db).GetConfigDto(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load system config, using defaults")
+	}
+	return cfg
+}
+
+func userDeleteDelay(cfg systemconfig.Dto) time.Duration {
+	if cfg.User.DeleteDelay <= 0 {
+		return defaultUserDeleteDelay
+	}
+	return time.Duration(cfg.User.DeleteDelay) * 24 * time.Hour
+}
+
+func (s *Service) sendDeletionScheduledEmail(ctx context.Context, cfg systemconfig.Dto, user sqlc.User, deleteAt time.Time, token string) error {
+	if !cfg.Notifications.SMTP.Enabled {
+		return nil
+	}
+	baseURL, err := s.externalBaseURL(cfg)
+	if err != nil {
+		return err
+	}
+
+	link := baseURL + AccountRestorePath + "?token=" + url.QueryEscape(token)
+	html, text := renderDeletionScheduledEmail(user.Name, deleteAt, link)
+	return s.sendNotificationEmail(ctx, cfg, user.Email, "Your Immich account is scheduled for deletion", html, text)
+}
+
+// externalBaseURL returns the URL links in emails point to.
+func (s *Service) externalBaseURL(cfg systemconfig.Dto) (string, error) {
+	baseURL := strings.TrimRight(cfg.Server.ExternalDomain, "/")
+	if baseURL == "" {
+		baseURL = s.config.ExternalURL("")
+	}
+	if baseURL == "" {
+		return "", fmt.Errorf("no external domain is configured to link to")
+	}
+	return baseURL, nil
+}
+
+// sendNotificationEmail sends an email through the SMTP settings of the
+// system config.
+func (s *Service) sendNotificationEmail(ctx context.Context, cfg systemconfig.Dto, to, subject, html, text string) error {
+	smtp := cfg.Notifications.SMTP
+	if s.email == nil {
+		s.email = smtpEmailSender{}
+	}
+	return s.email.Send(ctx, emailMessage{
+		From:      smtp.From,
+		ReplyTo:   firstNonEmpty(smtp.ReplyTo, smtp.From),
+		To:        to,
+		Subject:   subject,
+		HTML:      html,
+		Text:      text,
+		MessageID: fmt.Sprintf("<%s@immich-go>", uuid.NewString()),
+		Transport: SMTPTransport{
+			Host:       smtp.Transport.Host,
+			Port:       smtp.Transport.Port,
+			Username:   smtp.Transport.Username,
+			Password:   smtp.Transport.Password,
+			IgnoreCert: smtp.Transport.IgnoreCert,
+			Secure:     smtp.Transport.Secure,
+		},
+	})
+}
+
+func renderDeletionScheduledEmail(name string, deleteAt time.Time, link string) (string, string) {
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Your Immich account was deleted by an administrator. Your photos, videos and albums will be removed permanently on %s.\n\n"+
+		"If this was a mistake, restore your account before then.",
+		firstNonEmpty(name, "Immich User"), deleteAt.Format("January 2, 2006 15:04 MST"))
+	return emailPreviewHTML(body, "Restore account", link), body + "\n" + link + "\n"
+}
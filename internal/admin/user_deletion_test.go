@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+)
+
+func TestUserDeleteDelay(t *testing.T) {
+	var cfg systemconfig.Dto
+	assert.Equal(t, defaultUserDeleteDelay, userDeleteDelay(cfg))
+
+	cfg.User.DeleteDelay = 30
+	assert.Equal(t, 30*24*time.Hour, userDeleteDelay(cfg))
+}
+
+func TestRenderDeletionScheduledEmail(t *testing.T) {
+	deleteAt := time.Date(2025, time.March, 4, 10, 30, 0, 0, time.UTC)
+	link := "https://photos.example.com/account/restore?token=abc"
+
+	html, text := renderDeletionScheduledEmail("Jane <Doe>", deleteAt, link)
+
+	assert.Contains(t, text, "Hi Jane <Doe>,")
+	assert.Contains(t, text, "March 4, 2025 10:30 UTC")
+	assert.Contains(t, text, link)
+	assert.Contains(t, html, "Jane &lt;Doe&gt;")
+	assert.Contains(t, html, "Restore account")
+	assert.Contains(t, html, link)
+}
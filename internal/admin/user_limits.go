@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+var (
+	ErrUserLimitsUserNotFound = errors.New("user not found")
+	ErrInvalidUserLimit       = errors.New("limits must not be negative")
+)
+
+// UserLimitDto is the usage of one per-user limit. Limit is the one that
+// applies to the user, 0 when it is unlimited; Override is set when an admin
+// overrode the configured limit.
+type UserLimitDto struct {
+	Count    int64
+	Limit    int32
+	Override *int32
+}
+
+// Reached reports whether the user cannot create another item.
+func (l UserLimitDto) Reached() bool {
+	return l.Limit > 0 && l.Count >= int64(l.Limit)
+}
+
+// UserLimitsDto is the usage of the shared link and API key limits of a
+// user. Only shared links that have not expired count.
+type UserLimitsDto struct {
+	SharedLinks UserLimitDto
+	APIKeys     UserLimitDto
+}
+
+// GetUserLimits returns the limits that apply to a user and how much of
+// them they use.
+func (s *Service) GetUserLimits(ctx context.Context, userID pgtype.UUID) (*UserLimitsDto, error) {
+	return s.GetUserLimitsTx(ctx, s.db, userID)
+}
+
+// GetUserLimitsTx is GetUserLimits reading through q, so a transaction that
+// holds the user's LockUserLimits lock counts what it is about to add to.
+func (s *Service) GetUserLimitsTx(ctx context.Context, q *sqlc.Queries, userID pgtype.UUID) (*UserLimitsDto, error) {
+	overrides, err := q.GetUserLimits(ctx, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user limits: %w", err)
+	}
+	sharedLinks, err := q.CountSharedLinks(ctx, sqlc.CountSharedLinksParams{
+		UserID:  userID,
+		Expired: pgtype.Bool{Bool: false, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count shared links: %w", err)
+	}
+	apiKeys, err := q.CountApiKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	return &UserLimitsDto{
+		SharedLinks: userLimit(sharedLinks, s.config.Limits.MaxSharedLinksPerUser, overrides.MaxSharedLinks),
+		APIKeys:     userLimit(apiKeys, s.config.Limits.MaxAPIKeysPerUser, overrides.MaxApiKeys),
+	}, nil
+}
+
+// UpdateUserLimits overrides the limits of a user. A nil limit falls back
+// to the configured one.
+func (s *Service) UpdateUserLimits(ctx context.Context, userID pgtype.UUID, maxSharedLinks, maxAPIKeys *int32) (*UserLimitsDto, error) {
+	if (maxSharedLinks != nil && *maxSharedLinks < 0) || (maxAPIKeys != nil && *maxAPIKeys < 0) {
+		return nil, ErrInvalidUserLimit
+	}
+	if _, err := s.db.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserLimitsUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if _, err := s.db.UpsertUserLimits(ctx, sqlc.UpsertUserLimitsParams{
+		UserId:         userID,
+		MaxSharedLinks: optionalInt4(maxSharedLinks),
+		MaxApiKeys:     optionalInt4(maxAPIKeys),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update user limits: %w", err)
+	}
+	return s.GetUserLimits(ctx, userID)
+}
+
+func userLimit(count int64, configured int, override pgtype.Int4) UserLimitDto {
+	limit := UserLimitDto{Count: count, Limit: int32(configured)}
+	if override.Valid {
+		limit.Limit = override.Int32
+		limit.Override = &override.Int32
+	}
+	return limit
+}
+
+func optionalInt4(v *int32) pgtype.Int4 {
+	if v == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *v, Valid: true}
+}
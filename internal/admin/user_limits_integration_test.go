@@ -0,0 +1,82 @@
+//go:build integration
+// +build integration
+
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/util"
+)
+
+func TestIntegration_UserLimits(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Limits.MaxSharedLinksPerUser = 2
+	cfg.Limits.MaxAPIKeysPerUser = 5
+	service, err := NewService(tdb.Queries, cfg, nil)
+	require.NoError(t, err)
+
+	userID := pgtype.UUID{Bytes: tdb.CreateTestUser(t, "limits@example.com"), Valid: true}
+	for i, expiresAt := range []pgtype.Timestamptz{
+		{},
+		{Time: time.Now().Add(time.Hour), Valid: true},
+		{Time: time.Now().Add(-time.Hour), Valid: true},
+	} {
+		_, err := tdb.Queries.CreateSharedLink(ctx, sqlc.CreateSharedLinkParams{
+			UserId:    userID,
+			Key:       []byte{byte(i)},
+			Type:      "INDIVIDUAL",
+			ExpiresAt: expiresAt,
+		})
+		require.NoError(t, err)
+	}
+	_, err = tdb.Queries.CreateApiKey(ctx, sqlc.CreateApiKeyParams{
+		Name:        "key",
+		Key:         "hash",
+		UserId:      userID,
+		Permissions: []string{},
+	})
+	require.NoError(t, err)
+
+	limits, err := service.GetUserLimits(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), limits.SharedLinks.Count, "expired links do not count")
+	assert.Equal(t, int32(2), limits.SharedLinks.Limit)
+	assert.True(t, limits.SharedLinks.Reached())
+	assert.Equal(t, int64(1), limits.APIKeys.Count)
+	assert.Equal(t, int32(5), limits.APIKeys.Limit)
+	assert.Nil(t, limits.APIKeys.Override)
+
+	limits, err = service.UpdateUserLimits(ctx, userID, util.Ptr(int32(3)), util.Ptr(int32(0)))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), limits.SharedLinks.Limit)
+	assert.False(t, limits.SharedLinks.Reached())
+	assert.Equal(t, int32(0), limits.APIKeys.Limit)
+	require.NotNil(t, limits.APIKeys.Override)
+
+	// Leaving a limit unset falls back to the configured one
+	limits, err = service.UpdateUserLimits(ctx, userID, nil, util.Ptr(int32(1)))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), limits.SharedLinks.Limit)
+	assert.Nil(t, limits.SharedLinks.Override)
+	assert.True(t, limits.APIKeys.Reached())
+
+	_, err = service.UpdateUserLimits(ctx, userID, util.Ptr(int32(-1)), nil)
+	assert.ErrorIs(t, err, ErrInvalidUserLimit)
+	_, err = service.UpdateUserLimits(ctx, pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, nil, nil)
+	assert.ErrorIs(t, err, ErrUserLimitsUserNotFound)
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// GetUserLimitsAdmin gets the shared link and API key limits of a user and
+// their usage (admin function)
+func (s *Server) GetUserLimitsAdmin(ctx context.Context, request *immichv1.GetUserLimitsAdminRequest) (*immichv1.UserLimitsResponseDto, error) {
+	// Require admin privileges
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "admin privileges required")
+	}
+
+	userID, err := uuid.Parse(request.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	limits, err := s.service.GetUserLimits(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get user limits", err)
+	}
+	return userLimitsToDto(limits), nil
+}
+
+// UpdateUserLimitsAdmin overrides the shared link and API key limits of a
+// user (admin function)
+func (s *Server) UpdateUserLimitsAdmin(ctx context.Context, request *immichv1.UpdateUserLimitsAdminRequest) (*immichv1.UserLimitsResponseDto, error) {
+	// Require admin privileges
+	if _, err := auth.RequireAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "admin privileges required")
+	}
+
+	userID, err := uuid.Parse(request.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	limits, err := s.service.UpdateUserLimits(ctx, pgtype.UUID{Bytes: userID, Valid: true}, request.MaxSharedLinks, request.MaxApiKeys)
+	switch {
+	case errors.Is(err, ErrUserLimitsUserNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrInvalidUserLimit):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to update user limits", err)
+	}
+	return userLimitsToDto(limits), nil
+}
+
+func userLimitsToDto(limits *UserLimitsDto) *immichv1.UserLimitsResponseDto {
+	return &immichv1.UserLimitsResponseDto{
+		SharedLinks: userLimitToDto(limits.SharedLinks),
+		ApiKeys:     userLimitToDto(limits.APIKeys),
+	}
+}
+
+func userLimitToDto(limit UserLimitDto) *immichv1.UserLimitDto {
+	return &immichv1.UserLimitDto{
+		Count:    limit.Count,
+		Limit:    limit.Limit,
+		Override: limit.Override,
+	}
+}
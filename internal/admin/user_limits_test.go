@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserLimit(t *testing.T) {
+	limit := userLimit(8, 10, pgtype.Int4{})
+	assert.Equal(t, int32(10), limit.Limit)
+	assert.Nil(t, limit.Override)
+	assert.False(t, limit.Reached())
+
+	limit = userLimit(8, 10, pgtype.Int4{Int32: 8, Valid: true})
+	assert.Equal(t, int32(8), limit.Limit)
+	assert.Equal(t, int32(8), *limit.Override)
+	assert.True(t, limit.Reached())
+
+	limit = userLimit(1000, 10, pgtype.Int4{Int32: 0, Valid: true})
+	assert.Equal(t, int32(0), limit.Limit)
+	assert.False(t, limit.Reached(), "0 does not limit")
+}
@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
+)
+
+// GetWebhookSubscriptions lists the webhook subscriptions with the status of
+// their latest delivery.
+func (s *Server) GetWebhookSubscriptions(ctx context.Context, _ *emptypb.Empty) (*immichv1.GetWebhookSubscriptionsResponse, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	subscriptions, err := s.service.webhooks.List(ctx)
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to list webhook subscriptions", err)
+	}
+	response := &immichv1.GetWebhookSubscriptionsResponse{
+		Subscriptions: make([]*immichv1.WebhookSubscriptionDto, len(subscriptions)),
+	}
+	for i := range subscriptions {
+		response.Subscriptions[i] = webhookSubscriptionToDto(&subscriptions[i])
+	}
+	return response, nil
+}
+
+// CreateWebhookSubscription subscribes an endpoint to server events. The
+// response carries the signing secret, which is not returned again.
+func (s *Server) CreateWebhookSubscription(ctx context.Context, request *immichv1.CreateWebhookSubscriptionRequest) (*immichv1.WebhookSubscriptionDto, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	subscription, err := s.service.webhooks.Create(ctx, webhooks.CreateParams{
+		URL:     request.GetUrl(),
+		Events:  eventTypes(request.GetEvents()),
+		Secret:  request.GetSecret(),
+		Enabled: request.Enabled == nil || request.GetEnabled(),
+	})
+	if err != nil {
+		return nil, webhookSubscriptionError(ctx, "failed to create webhook subscription", err)
+	}
+	dto := webhookSubscriptionToDto(subscription)
+	dto.Secret = &subscription.Secret
+	return dto, nil
+}
+
+// GetWebhookSubscription returns a webhook subscription with the status of
+// its latest delivery.
+func (s *Server) GetWebhookSubscription(ctx context.Context, request *immichv1.GetWebhookSubscriptionRequest) (*immichv1.WebhookSubscriptionDto, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := webhookSubscriptionID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	subscription, err := s.service.webhooks.Get(ctx, id)
+	if err != nil {
+		return nil, webhookSubscriptionError(ctx, "failed to get webhook subscription", err)
+	}
+	return webhookSubscriptionToDto(subscription), nil
+}
+
+// UpdateWebhookSubscription changes the fields of a webhook subscription
+// that are set.
+func (s *Server) UpdateWebhookSubscription(ctx context.Context, request *immichv1.UpdateWebhookSubscriptionRequest) (*immichv1.WebhookSubscriptionDto, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := webhookSubscriptionID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	params := webhooks.UpdateParams{
+		URL:     request.Url,
+		Secret:  request.Secret,
+		Enabled: request.Enabled,
+	}
+	if len(request.GetEvents()) > 0 {
+		params.Events = eventTypes(request.GetEvents())
+	}
+	subscription, err := s.service.webhooks.Update(ctx, id, params)
+	if err != nil {
+		return nil, webhookSubscriptionError(ctx, "failed to update webhook subscription", err)
+	}
+	return webhookSubscriptionToDto(subscription), nil
+}
+
+// DeleteWebhookSubscription deletes a webhook subscription. Queued
+// deliveries to it are dropped.
+func (s *Server) DeleteWebhookSubscription(ctx context.Context, request *immichv1.DeleteWebhookSubscriptionRequest) (*emptypb.Empty, error) {
+	if err := requireIntegrityAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := webhookSubscriptionID(request.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.service.webhooks.Delete(ctx, id); err != nil {
+		return nil, webhookSubscriptionError(ctx, "failed to delete webhook subscription", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// publishWebhookEvent queues an event for the webhook subscriptions. Without
+// a job service, which delivers them, events are dropped.
+func (s *Server) publishWebhookEvent(ctx context.Context, eventType webhooks.EventType, data map[string]any) {
+	if s.jobService == nil {
+		return
+	}
+	if err := s.jobService.PublishWebhookEvent(ctx, eventType, data); err != nil {
+		logrus.WithError(err).WithField("event_type", eventType).Warn("Failed to publish webhook event")
+	}
+}
+
+func webhookSubscriptionID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid webhook subscription ID")
+	}
+	return parsed, nil
+}
+
+func webhookSubscriptionError(ctx context.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, webhooks.ErrSubscriptionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, webhooks.ErrInvalidSubscription):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return grpcutil.SanitizedInternal(ctx, message, err)
+	}
+}
+
+func eventTypes(events []string) []webhooks.EventType {
+	result := make([]webhooks.EventType, len(events))
+	for i, event := range events {
+		result[i] = webhooks.EventType(event)
+	}
+	return result
+}
+
+func webhookSubscriptionToDto(subscription *sqlc.WebhookSubscription) *immichv1.WebhookSubscriptionDto {
+	dto := &immichv1.WebhookSubscriptionDto{
+		Id:             uuid.UUID(subscription.ID.Bytes).String(),
+		Url:            subscription.Url,
+		Events:         subscription.Events,
+		Enabled:        subscription.Enabled,
+		CreatedAt:      timestamppb.New(subscription.CreatedAt.Time),
+		UpdatedAt:      timestamppb.New(subscription.UpdatedAt.Time),
+		LastDeliveryAt: optionalTimestamp(subscription.LastDeliveryAt),
+		LastSuccessAt:  optionalTimestamp(subscription.LastSuccessAt),
+		FailureCount:   subscription.FailureCount,
+	}
+	if subscription.LastStatusCode.Valid {
+		dto.LastStatusCode = &subscription.LastStatusCode.Int32
+	}
+	if subscription.LastError.Valid {
+		dto.LastError = &subscription.LastError.String
+	}
+	return dto
+}
+
+func optionalTimestamp(value pgtype.Timestamptz) *timestamppb.Timestamp {
+	if !value.Valid {
+		return nil
+	}
+	return timestamppb.New(value.Time)
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+	"github.com/denysvitali/immich-go-backend/internal/users"
+)
+
+// PasswordSetupPath is where the password link in the welcome email points;
+// the token is passed as the token query parameter.
+const PasswordSetupPath = "/account/set-password"
+
+// passwordSetupTokenTTL is how long the password link of a new user works.
+const passwordSetupTokenTTL = 7 * 24 * time.Hour
+
+// generateTemporaryPassword returns the password of a user created without
+// one.
+func generateTemporaryPassword() (string, error) {
+	random := make([]byte, 18)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// welcomeUser tells a user created by an admin how to sign in. The welcome
+// email is sent when SMTP is enabled and the admin did not opt out; when it
+// is not sent, the credentials the admin has to pass on are set on dto
+// instead. Failing to send the email does not fail the user creation.
+func (s *Service) welcomeUser(ctx context.Context, req CreateUserAdminRequest, user sqlc.User, password string, generated bool, dto *UserAdminResponseDto) {
+	cfg := s.systemConfig(ctx)
+	log := logrus.WithField("user_id", dto.ID)
+
+	var link string
+	if req.SendPasswordSetupLink {
+		var err error
+		if link, err = s.createPasswordSetupLink(ctx, cfg, user.ID); err != nil {
+			log.WithError(err).Warn("Failed to create the password link of a new user")
+		}
+	}
+
+	if (req.Notify == nil || *req.Notify) && cfg.Notifications.SMTP.Enabled {
+		err := s.sendWelcomeEmail(ctx, cfg, user, password, link)
+		if err == nil {
+			dto.WelcomeEmailSent = true
+			return
+		}
+		log.WithError(err).Warn("Failed to send the welcome email")
+	}
+
+	if generated {
+		dto.TemporaryPassword = password
+	}
+	dto.PasswordSetupURL = link
+}
+
+func (s *Service) createPasswordSetupLink(ctx context.Context, cfg systemconfig.Dto, userID pgtype.UUID) (string, error) {
+	baseURL, err := s.externalBaseURL(cfg)
+	if err != nil {
+		return "", err
+	}
+	token, hash, err := users.NewPasswordSetupToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.db.CreatePasswordSetupToken(ctx, sqlc.CreatePasswordSetupTokenParams{
+		UserId:    userID,
+		TokenHash: hash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(passwordSetupTokenTTL), Valid: true},
+	}); err != nil {
+		return "", fmt.Errorf("failed to store password setup token: %w", err)
+	}
+	return baseURL + PasswordSetupPath + "?token=" + url.QueryEscape(token), nil
+}
+
+func (s *Service) sendWelcomeEmail(ctx context.Context, cfg systemconfig.Dto, user sqlc.User, password, link string) error {
+	baseURL, err := s.externalBaseURL(cfg)
+	if err != nil {
+		return err
+	}
+	html, text := renderWelcomeEmail(cfg.Templates.Email.WelcomeTemplate, baseURL, user.Name, user.Email, password, link)
+	return s.sendNotificationEmail(ctx, cfg, user.Email, "Welcome to Immich", html, text)
+}
+
+// renderWelcomeEmail renders the welcome email, using the custom template
+// of the system config when one is set. With a password link the email
+// points to it instead of the login page, and the default body leaves out
+// the password.
+func renderWelcomeEmail(customTemplate, baseURL, name, email, password, link string) (string, string) {
+	name = firstNonEmpty(name, "Immich User")
+	variables := map[string]string{
+		"baseUrl":     baseURL,
+		"displayName": name,
+		"username":    email,
+		"password":    password,
+	}
+
+	actionText, actionURL := "Login", baseURL
+	body := fmt.Sprintf("Hey %s!\n\nA new account has been created for you.\nUsername: %s\nPassword: %s", name, email, password)
+	if link != "" {
+		actionText, actionURL = "Set password", link
+		body = fmt.Sprintf("Hey %s!\n\nA new account has been created for you.\nUsername: %s\n\n"+
+			"Choose your password with the link below within %d days, then sign in at %s.",
+			name, email, int(passwordSetupTokenTTL.Hours()/24), baseURL)
+	}
+	if customTemplate != "" {
+		body = replaceTemplateTags(customTemplate, variables)
+	}
+	return emailPreviewHTML(body, actionText, actionURL), body + "\n\n" + actionURL + "\n"
+}
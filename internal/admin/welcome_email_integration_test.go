@@ -0,0 +1,85 @@
+//go:build integration
+// +build integration
+
+package admin
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/systemmetadata"
+	"github.com/denysvitali/immich-go-backend/internal/users"
+)
+
+func TestIntegration_CreateUserAdminWithoutEmail(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	_, err := tdb.Queries.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   systemmetadata.AdminOnboardingKey,
+		Value: []byte("true"),
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.Server.ExternalDomain = "https://photos.example.com"
+	service, err := NewService(tdb.Queries, cfg, nil)
+	require.NoError(t, err)
+	sender := &fakeEmailSender{}
+	service.email = sender
+
+	created, err := service.CreateUserAdmin(ctx, CreateUserAdminRequest{
+		Email:                 "welcome@example.com",
+		Name:                  "Welcome",
+		SendPasswordSetupLink: true,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, sender.sendCalls, "SMTP is disabled")
+	assert.False(t, created.WelcomeEmailSent)
+	assert.NotEmpty(t, created.TemporaryPassword, "the generated password is returned to the admin")
+	assert.True(t, created.ShouldChangePassword)
+	require.NotEmpty(t, created.PasswordSetupURL)
+
+	link, err := url.Parse(created.PasswordSetupURL)
+	require.NoError(t, err)
+	assert.Equal(t, PasswordSetupPath, link.Path)
+	token := link.Query().Get("token")
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	userService, err := users.NewService(conn.Queries, cfg)
+	require.NoError(t, err)
+	userService.SetTxRunner(conn)
+	_, err = userService.SetPasswordWithToken(ctx, token, "short")
+	assert.Error(t, err, "the password must meet the requirements")
+	user, err := userService.SetPasswordWithToken(ctx, token, "a-new-password")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, user.ID.String())
+	_, err = userService.SetPasswordWithToken(ctx, token, "another-password")
+	assert.Error(t, err, "the link works once")
+
+	updated, err := tdb.Queries.GetUserByID(ctx, pgtype.UUID{Bytes: uuid.MustParse(created.ID), Valid: true})
+	require.NoError(t, err)
+	assert.False(t, updated.ShouldChangePassword)
+
+	withPassword, err := service.CreateUserAdmin(ctx, CreateUserAdminRequest{
+		Email:    "chosen@example.com",
+		Name:     "Chosen",
+		Password: "chosen-password",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, withPassword.TemporaryPassword, "the admin already knows the password")
+	assert.Empty(t, withPassword.PasswordSetupURL)
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+)
+
+func TestRenderWelcomeEmail(t *testing.T) {
+	html, text := renderWelcomeEmail("", "https://photos.example.com", "Jane <Doe>", "jane@example.com", "s3cret", "")
+
+	assert.Contains(t, text, "Hey Jane <Doe>!")
+	assert.Contains(t, text, "Username: jane@example.com")
+	assert.Contains(t, text, "Password: s3cret")
+	assert.Contains(t, html, "Jane &lt;Doe&gt;")
+	assert.Contains(t, html, `<a href="https://photos.example.com">Login</a>`)
+}
+
+func TestRenderWelcomeEmailWithPasswordLink(t *testing.T) {
+	link := "https://photos.example.com/account/set-password?token=abc"
+	html, text := renderWelcomeEmail("", "https://photos.example.com", "Jane", "jane@example.com", "s3cret", link)
+
+	assert.NotContains(t, text, "s3cret", "the password is not needed with a link")
+	assert.Contains(t, text, "within 7 days")
+	assert.Contains(t, text, link)
+	assert.Contains(t, html, "Set password")
+	assert.Contains(t, html, link)
+}
+
+func TestRenderWelcomeEmailUsesCustomTemplate(t *testing.T) {
+	_, text := renderWelcomeEmail("Welcome {displayName}, sign in at {baseUrl} as {username} with {password}.",
+		"https://photos.example.com", "", "jane@example.com", "s3cret", "")
+
+	assert.Contains(t, text, "Welcome Immich User, sign in at https://photos.example.com as jane@example.com with s3cret.")
+}
+
+func TestSendWelcomeEmail(t *testing.T) {
+	service := newTemplateTestService(t)
+	sender := &fakeEmailSender{}
+	service.email = sender
+
+	var cfg systemconfig.Dto
+	cfg.Server.ExternalDomain = "https://photos.example.com/"
+	cfg.Notifications.SMTP = systemconfig.SMTPDto{
+		Enabled: true,
+		From:    "Immich <noreply@example.com>",
+		Transport: systemconfig.SMTPTransportDto{
+			Host: "smtp.example.com",
+			Port: 587,
+		},
+	}
+	cfg.Templates.Email.WelcomeTemplate = "Hi {displayName}, your password is {password}."
+
+	user := sqlc.User{Email: "jane@example.com", Name: "Jane"}
+	require.NoError(t, service.sendWelcomeEmail(context.Background(), cfg, user, "s3cret", ""))
+
+	assert.Equal(t, 1, sender.sendCalls)
+	assert.Equal(t, "jane@example.com", sender.sent.To)
+	assert.Equal(t, "Immich <noreply@example.com>", sender.sent.ReplyTo)
+	assert.Equal(t, "Welcome to Immich", sender.sent.Subject)
+	assert.Equal(t, "smtp.example.com", sender.sent.Transport.Host)
+	assert.Contains(t, sender.sent.Text, "Hi Jane, your password is s3cret.")
+	assert.Contains(t, sender.sent.HTML, `<a href="https://photos.example.com">Login</a>`)
+}
+
+func TestSendWelcomeEmailRequiresExternalDomain(t *testing.T) {
+	service := newTemplateTestService(t)
+	sender := &fakeEmailSender{}
+	service.email = sender
+
+	var cfg systemconfig.Dto
+	cfg.Notifications.SMTP.Enabled = true
+	assert.Error(t, service.sendWelcomeEmail(context.Background(), cfg, sqlc.User{Email: "jane@example.com"}, "s3cret", ""))
+	assert.Zero(t, sender.sendCalls)
+}
@@ -65,16 +65,10 @@ func (s *Service) CreateAPIKey(ctx context.Context, userID uuid.UUID, name strin
 		return nil, "", err
 	}
 
-	// Hash the key for storage
-	hashedKey, err := s.HashAPIKey(rawKey)
-	if err != nil {
-		return nil, "", err
-	}
-
-	// Store in database
+	// Store in database, hashed the way ValidateAPIKey looks keys up
 	apiKey, err := s.db.CreateApiKey(ctx, sqlc.CreateApiKeyParams{
 		Name:        name,
-		Key:         hashedKey,
+		Key:         hashAPIKey(rawKey),
 		UserId:      pgtype.UUID{Bytes: userID, Valid: true},
 		Permissions: []string{}, // Default permissions - can be expanded later
 	})
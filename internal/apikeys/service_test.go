@@ -78,8 +78,11 @@ func TestIntegration_CreateAPIKey(t *testing.T) {
 	assert.Equal(t, "My API Key", apiKey.Name)
 	assert.True(t, apiKey.ID.Valid)
 
-	// The raw key should be verifiable against the stored hash
-	assert.True(t, service.VerifyAPIKey(rawKey, apiKey.Key))
+	// The raw key should authenticate as the stored key
+	validated, err := service.ValidateAPIKey(ctx, rawKey)
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ID, validated.ID)
+	assert.NotEqual(t, rawKey, apiKey.Key, "keys are stored hashed")
 }
 
 func TestIntegration_GetAPIKeysByUser(t *testing.T) {
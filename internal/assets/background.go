@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultProcessingTimeout is used when the configuration sets none.
+	defaultProcessingTimeout = 10 * time.Minute
+	// maxProcessingAttempts bounds how often processing of an asset is
+	// started when it keeps timing out.
+	maxProcessingAttempts = 3
+	// processingRetryDelay is the wait before processing that timed out is
+	// started again. It doubles with each further attempt.
+	processingRetryDelay = 30 * time.Second
+)
+
+// backgroundRunner runs work in goroutines that are tied to the lifetime of
+// the service: each run times out, and Close cancels the runs and waits for
+// them to return.
+type backgroundRunner struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newBackgroundRunner(timeout time.Duration) *backgroundRunner {
+	if timeout <= 0 {
+		timeout = defaultProcessingTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundRunner{ctx: ctx, cancel: cancel, timeout: timeout}
+}
+
+// Go runs fn in a goroutine after delay. The context of fn keeps the values
+// of parent, but not its cancellation: it is cancelled when the timeout
+// passes or the runner is closed. Go reports false when the runner is closed
+// and fn will not run.
+func (r *backgroundRunner) Go(parent context.Context, delay time.Duration, fn func(ctx context.Context)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), r.timeout)
+		defer cancel()
+		stop := context.AfterFunc(r.ctx, cancel)
+		defer stop()
+		fn(ctx)
+	}()
+	return true
+}
+
+// Close cancels the running work, drops the work waiting to run and waits
+// for the running work to return.
+func (r *backgroundRunner) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+}
@@ -0,0 +1,57 @@
+package assets
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type backgroundTestKey struct{}
+
+func TestBackgroundRunner_Timeout(t *testing.T) {
+	runner := newBackgroundRunner(20 * time.Millisecond)
+	defer runner.Close()
+
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), backgroundTestKey{}, "value"))
+	cancelParent()
+
+	done := make(chan error, 1)
+	require.True(t, runner.Go(parent, 0, func(ctx context.Context) {
+		assert.Equal(t, "value", ctx.Value(backgroundTestKey{}), "values of the parent are kept")
+		<-ctx.Done()
+		done <- ctx.Err()
+	}))
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "the parent's cancellation is not")
+	case <-time.After(5 * time.Second):
+		t.Fatal("background work did not time out")
+	}
+}
+
+func TestBackgroundRunner_Close(t *testing.T) {
+	runner := newBackgroundRunner(time.Hour)
+
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	require.True(t, runner.Go(context.Background(), 0, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+	}))
+	var delayedRan atomic.Bool
+	require.True(t, runner.Go(context.Background(), time.Hour, func(context.Context) {
+		delayedRan.Store(true)
+	}))
+	<-started
+
+	runner.Close()
+	assert.True(t, cancelled.Load(), "Close waits for running work to be cancelled")
+	assert.False(t, delayedRan.Load(), "work waiting to run is dropped")
+	assert.False(t, runner.Go(context.Background(), 0, func(context.Context) {}))
+}
@@ -0,0 +1,161 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+const (
+	// blurhashMaxSize is the largest width and height a BlurHash is computed
+	// from. Its few components cannot hold more detail, so larger images are
+	// shrunk first.
+	blurhashMaxSize = 32
+	// blurhashComponentsX and blurhashComponentsY are the number of
+	// horizontal and vertical components a BlurHash is encoded with.
+	blurhashComponentsX = 4
+	blurhashComponentsY = 3
+)
+
+// blurhashDigits is the base 83 alphabet BlurHash strings are written in.
+const blurhashDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// GenerateBlurHash decodes an image and returns its BlurHash, a placeholder
+// clients can paint while the thumbnail loads.
+func (g *ThumbnailGenerator) GenerateBlurHash(ctx context.Context, reader io.Reader) (string, error) {
+	_, span := tracer.Start(ctx, "thumbnails.generate_blurhash")
+	defer span.End()
+
+	img, format, err := image.Decode(reader)
+	if err != nil {
+		span.RecordError(err)
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	span.SetAttributes(attribute.String("format", format))
+	return BlurHash(img), nil
+}
+
+// StoreBlurHash records the BlurHash of the encoded small thumbnail of an
+// asset. Videos get theirs from the frame their thumbnails were taken from.
+func StoreBlurHash(ctx context.Context, db *sqlc.Queries, generator *ThumbnailGenerator, assetID pgtype.UUID, thumbnail []byte) error {
+	hash, err := generator.GenerateBlurHash(ctx, bytes.NewReader(thumbnail))
+	if err != nil {
+		return err
+	}
+	if err := db.SetAssetBlurhash(ctx, sqlc.SetAssetBlurhashParams{
+		ID:       assetID,
+		Blurhash: pgtype.Text{String: hash, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to store blurhash: %w", err)
+	}
+	return nil
+}
+
+// BlurHash encodes img as a BlurHash (https://blurha.sh) of 4x3 components.
+// Transparency is ignored.
+func BlurHash(img image.Image) string {
+	if img.Bounds().Dx() > blurhashMaxSize || img.Bounds().Dy() > blurhashMaxSize {
+		img = imaging.Fit(img, blurhashMaxSize, blurhashMaxSize, imaging.Linear)
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return ""
+	}
+
+	// Linear RGB of every pixel
+	pixels := make([][3]float64, 0, w*h)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, [3]float64{srgbToLinear(p.R), srgbToLinear(p.G), srgbToLinear(p.B)})
+		}
+	}
+
+	factors := make([][3]float64, 0, blurhashComponentsX*blurhashComponentsY)
+	for cy := 0; cy < blurhashComponentsY; cy++ {
+		for cx := 0; cx < blurhashComponentsX; cx++ {
+			normalisation := 2.0
+			if cx == 0 && cy == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < h; y++ {
+				fy := math.Cos(math.Pi * float64(cy) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := normalisation * math.Cos(math.Pi*float64(cx)*float64(x)/float64(w)) * fy
+					pixel := pixels[x+y*w]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := 1 / float64(w*h)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+	dc, ac := factors[0], factors[1:]
+
+	hash := make([]byte, 0, 6+2*len(ac))
+	hash = appendBase83(hash, (blurhashComponentsX-1)+(blurhashComponentsY-1)*9, 1)
+
+	var actualMax float64
+	for _, factor := range ac {
+		for _, v := range factor {
+			actualMax = math.Max(actualMax, math.Abs(v))
+		}
+	}
+	quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+	maximumValue := float64(quantisedMax+1) / 166
+	hash = appendBase83(hash, quantisedMax, 1)
+
+	hash = appendBase83(hash, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+	for _, factor := range ac {
+		quantised := 0
+		for _, v := range factor {
+			q := int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+			quantised = quantised*19 + q
+		}
+		hash = appendBase83(hash, quantised, 2)
+	}
+	return string(hash)
+}
+
+// appendBase83 appends value to hash as length base 83 digits.
+func appendBase83(hash []byte, value, length int) []byte {
+	for i := 1; i <= length; i++ {
+		digit := value / int(math.Pow(83, float64(length-i))) % 83
+		hash = append(hash, blurhashDigits[digit])
+	}
+	return hash
+}
+
+func srgbToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,73 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uniformTestImage returns an image of a single colour.
+func uniformTestImage(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestBlurHashMatchesReferenceEncoder(t *testing.T) {
+	gradient := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 16), G: uint8(y * 32), B: 128, A: 255})
+		}
+	}
+
+	// Hashes of the same images with 4x3 components by the reference
+	// TypeScript encoder
+	tests := []struct {
+		name string
+		img  image.Image
+		want string
+	}{
+		{"black", uniformTestImage(4, 3, color.NRGBA{A: 255}), "L00000fQfQfQfQfQfQfQfQfQfQfQ"},
+		{"white", uniformTestImage(4, 3, color.NRGBA{R: 255, G: 255, B: 255, A: 255}), "L~TSUA~qfQ~q~q%MfQ%MfQfQfQfQ"},
+		{"landscape", thumbhashTestImage(8, 6, false), "LHGRxk-W,6-=yE-TyMI[vbr_rrrN"},
+		{"portrait", thumbhashTestImage(6, 8, false), "LMGRxk_LNisKXnm-N4O%#daiRFOa"},
+		{"gradient", gradient, "LsGuj*2@wxozu^R-jtjIf7fQfQfQ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, BlurHash(tt.img))
+		})
+	}
+}
+
+func TestBlurHashShrinksLargeImages(t *testing.T) {
+	hash := BlurHash(thumbhashTestImage(400, 200, false))
+	assert.Len(t, hash, 28, "4x3 components take 28 characters")
+	assert.Equal(t, "L", hash[:1])
+	for _, c := range hash {
+		assert.True(t, strings.ContainsRune(blurhashDigits, c), "unexpected character %q", c)
+	}
+}
+
+func TestGenerateBlurHash(t *testing.T) {
+	generator := NewThumbnailGenerator()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, thumbhashTestImage(8, 6, false)))
+	hash, err := generator.GenerateBlurHash(context.Background(), &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "LHGRxk-W,6-=yE-TyMI[vbr_rrrN", hash)
+
+	_, err = generator.GenerateBlurHash(context.Background(), strings.NewReader("not an image"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package assets
+
+import (
+	"crypto/sha1" //nolint:gosec // SHA-1 is the Immich checksum convention, not a security boundary.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Asset checksums identify file content for deduplication.
+//
+// Wire format: clients send the digest of the original file either as hex
+// (40 characters for SHA-1, 64 for SHA-256) or as standard base64 (28 and 44
+// characters). The Immich web and mobile apps send SHA-1, which is the
+// default; SHA-256 must be declared by the client or is inferred from the
+// digest length when no algorithm is given.
+//
+// Storage: assets.checksum holds the bytes of the lowercase hex digest and
+// assets."checksumAlgorithm" names the algorithm. Two assets are duplicates
+// only when both the algorithm and the digest match.
+
+// ChecksumAlgorithm names the hash function of an asset checksum.
+type ChecksumAlgorithm string
+
+const (
+	// ChecksumSHA1 is what Immich clients send, and what uploads and library
+	// imports compute so their checksums match.
+	ChecksumSHA1 ChecksumAlgorithm = "sha1"
+
+	// ChecksumSHA256 is accepted from clients that declare it.
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+
+	// DefaultChecksumAlgorithm is used to compute checksums on the server.
+	DefaultChecksumAlgorithm = ChecksumSHA1
+)
+
+// ErrInvalidChecksum is returned for checksums that are not a valid digest of
+// a supported algorithm.
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+// ParseChecksumAlgorithm parses a client-declared algorithm. An empty value
+// returns an empty algorithm, meaning "infer from the digest".
+func ParseChecksumAlgorithm(value string) (ChecksumAlgorithm, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return "", nil
+	case "sha1", "sha-1":
+		return ChecksumSHA1, nil
+	case "sha256", "sha-256":
+		return ChecksumSHA256, nil
+	}
+	return "", fmt.Errorf("%w: unsupported checksum algorithm %q", ErrInvalidChecksum, value)
+}
+
+// Size returns the digest length in bytes.
+func (a ChecksumAlgorithm) Size() int {
+	switch a {
+	case ChecksumSHA1:
+		return sha1.Size
+	case ChecksumSHA256:
+		return sha256.Size
+	}
+	return 0
+}
+
+func (a ChecksumAlgorithm) newHash() hash.Hash {
+	if a == ChecksumSHA256 {
+		return sha256.New()
+	}
+	return sha1.New() //nolint:gosec // Immich checksum convention.
+}
+
+// Checksum is a file digest together with the algorithm that produced it.
+type Checksum struct {
+	Algorithm ChecksumAlgorithm
+	Digest    []byte
+}
+
+// Hex returns the lowercase hex digest.
+func (c Checksum) Hex() string {
+	return hex.EncodeToString(c.Digest)
+}
+
+// Stored returns the value kept in assets.checksum.
+func (c Checksum) Stored() []byte {
+	return []byte(c.Hex())
+}
+
+// ParseChecksum decodes a hex or base64 digest sent by a client. When
+// algorithm is empty it is inferred from the digest length; otherwise the
+// length must match it.
+func ParseChecksum(value string, algorithm ChecksumAlgorithm) (Checksum, error) {
+	value = strings.TrimSpace(value)
+	digest, err := hex.DecodeString(value)
+	if err != nil {
+		digest, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(digest) == 0 {
+		return Checksum{}, fmt.Errorf("%w: expected a hex or base64 digest", ErrInvalidChecksum)
+	}
+
+	if algorithm == "" {
+		switch len(digest) {
+		case sha1.Size:
+			algorithm = ChecksumSHA1
+		case sha256.Size:
+			algorithm = ChecksumSHA256
+		default:
+			return Checksum{}, fmt.Errorf("%w: %d-byte digest is neither SHA-1 nor SHA-256", ErrInvalidChecksum, len(digest))
+		}
+	}
+	if size := algorithm.Size(); size == 0 || len(digest) != size {
+		return Checksum{}, fmt.Errorf("%w: %s digest must be %d bytes, got %d", ErrInvalidChecksum, algorithm, size, len(digest))
+	}
+
+	return Checksum{Algorithm: algorithm, Digest: digest}, nil
+}
+
+// ComputeChecksum hashes r with the given algorithm.
+func ComputeChecksum(r io.Reader, algorithm ChecksumAlgorithm) (Checksum, error) {
+	h := algorithm.newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		return Checksum{}, fmt.Errorf("failed to read data for checksum: %w", err)
+	}
+	return Checksum{Algorithm: algorithm, Digest: h.Sum(nil)}, nil
+}
+
+// SumChecksum hashes data with the given algorithm.
+func SumChecksum(data []byte, algorithm ChecksumAlgorithm) Checksum {
+	h := algorithm.newHash()
+	h.Write(data)
+	return Checksum{Algorithm: algorithm, Digest: h.Sum(nil)}
+}
@@ -0,0 +1,79 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // Immich asset checksum convention.
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseChecksum verifies the hex and base64 wire formats, algorithm
+// inference from the digest length and length validation.
+func TestParseChecksum(t *testing.T) {
+	data := []byte("hello, immich")
+	sha1Sum := sha1.Sum(data) //nolint:gosec // test fixture
+	sha256Sum := sha256.Sum256(data)
+
+	tests := []struct {
+		name      string
+		value     string
+		algorithm ChecksumAlgorithm
+		want      ChecksumAlgorithm
+		digest    []byte
+		wantErr   bool
+	}{
+		{name: "sha1 hex", value: hex.EncodeToString(sha1Sum[:]), want: ChecksumSHA1, digest: sha1Sum[:]},
+		{name: "sha1 uppercase hex", value: " " + strings.ToUpper(hex.EncodeToString(sha1Sum[:])) + " ", want: ChecksumSHA1, digest: sha1Sum[:]},
+		{name: "sha1 base64", value: base64.StdEncoding.EncodeToString(sha1Sum[:]), want: ChecksumSHA1, digest: sha1Sum[:]},
+		{name: "sha256 hex", value: hex.EncodeToString(sha256Sum[:]), want: ChecksumSHA256, digest: sha256Sum[:]},
+		{name: "declared sha256 base64", value: base64.StdEncoding.EncodeToString(sha256Sum[:]), algorithm: ChecksumSHA256, want: ChecksumSHA256, digest: sha256Sum[:]},
+		{name: "declared sha1 with sha256 digest", value: hex.EncodeToString(sha256Sum[:]), algorithm: ChecksumSHA1, wantErr: true},
+		{name: "unknown length", value: "abcdef123456", wantErr: true},
+		{name: "not a digest", value: "not-a-checksum", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseChecksum(tc.value, tc.algorithm)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidChecksum)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got.Algorithm)
+			assert.Equal(t, tc.digest, got.Digest)
+			assert.Equal(t, []byte(hex.EncodeToString(tc.digest)), got.Stored())
+		})
+	}
+}
+
+func TestParseChecksumAlgorithm(t *testing.T) {
+	for value, want := range map[string]ChecksumAlgorithm{"": "", "sha1": ChecksumSHA1, "SHA-1": ChecksumSHA1, "sha256": ChecksumSHA256} {
+		got, err := ParseChecksumAlgorithm(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	_, err := ParseChecksumAlgorithm("md5")
+	assert.ErrorIs(t, err, ErrInvalidChecksum)
+}
+
+// TestComputeChecksumMatchesClients verifies the server computes the SHA-1
+// the Immich clients send, so uploads and library imports deduplicate.
+func TestComputeChecksumMatchesClients(t *testing.T) {
+	data := []byte("hello, immich")
+	clientSum := sha1.Sum(data) //nolint:gosec // test fixture
+
+	got, err := ComputeChecksum(bytes.NewReader(data), DefaultChecksumAlgorithm)
+	require.NoError(t, err)
+	parsed, err := ParseChecksum(base64.StdEncoding.EncodeToString(clientSum[:]), "")
+	require.NoError(t, err)
+	assert.Equal(t, parsed, got)
+	assert.Equal(t, got, SumChecksum(data, DefaultChecksumAlgorithm))
+}
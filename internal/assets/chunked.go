@@ -0,0 +1,400 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// Resumable uploads send the file of an upload session in chunks, each with
+// its own checksum, so a chunk lost or damaged on the way is sent again on
+// its own rather than the whole file. The session records the size and
+// checksum of the whole file and which chunks arrived, so a client that
+// lost its connection asks where to continue. Chunks are staged under the
+// upload temp directory, named after their offset, until the session is
+// finished.
+
+var (
+	// ErrUploadSessionNotFound is returned for an asset without an upload
+	// session, or one of another user.
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+
+	// ErrInvalidUploadSession is wrapped by the errors of a session that
+	// cannot be opened as requested.
+	ErrInvalidUploadSession = errors.New("invalid upload session")
+
+	// ErrChunkChecksumMismatch is returned for a chunk whose content does
+	// not match the checksum sent with it. The chunk is dropped and can be
+	// sent again.
+	ErrChunkChecksumMismatch = errors.New("chunk checksum mismatch")
+
+	// ErrChunkOverlap is returned for a chunk that overlaps one received at
+	// another offset. A chunk sent again starts where the one it replaces
+	// started.
+	ErrChunkOverlap = errors.New("chunk overlaps a received chunk")
+
+	// ErrUploadIncomplete is returned when finishing an upload whose chunks
+	// leave a gap.
+	ErrUploadIncomplete = errors.New("upload incomplete")
+
+	// ErrUploadChecksumMismatch is returned when the assembled file does
+	// not match the checksum the session was opened with. Its chunks are
+	// dropped and the upload has to start over.
+	ErrUploadChecksumMismatch = errors.New("upload checksum mismatch")
+)
+
+const chunkSuffix = ".part"
+
+// UploadSession is a resumable upload in progress.
+type UploadSession struct {
+	AssetID   uuid.UUID
+	TotalSize int64
+	Checksum  Checksum
+	// Received are the byte ranges of the file that arrived, in order, with
+	// adjacent ranges merged.
+	Received []ByteRange
+}
+
+// ByteRange is the bytes from Start up to, not including, End.
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// ReceivedBytes is how much of the file arrived without gaps, which is
+// where a resumed upload continues.
+func (u *UploadSession) ReceivedBytes() int64 {
+	if len(u.Received) == 0 || u.Received[0].Start > 0 {
+		return 0
+	}
+	return u.Received[0].End
+}
+
+// CreateUploadSession creates the asset of an upload whose file is sent in
+// chunks. The size and checksum of the whole file are required.
+func (s *Service) CreateUploadSession(ctx context.Context, req UploadRequest) (*UploadSession, error) {
+	ctx, span := tracer.Start(ctx, "assets.create_upload_session",
+		trace.WithAttributes(
+			attribute.String("user_id", req.UserID.String()),
+			attribute.Int64("size", req.Size),
+		))
+	defer span.End()
+
+	if s.config.Storage.S3.DirectUpload {
+		return nil, fmt.Errorf("%w: direct uploads are sent to storage, not in chunks", ErrInvalidUploadSession)
+	}
+	if req.Size <= 0 {
+		return nil, fmt.Errorf("%w: the size of the file is required", ErrInvalidUploadSession)
+	}
+	if maxSize := s.config.Storage.Upload.MaxFileSize; maxSize > 0 && req.Size > maxSize {
+		return nil, fmt.Errorf("%w: the file is larger than the maximum file size of %d bytes", ErrInvalidUploadSession, maxSize)
+	}
+	if req.Checksum == "" {
+		return nil, fmt.Errorf("%w: the checksum of the file is required", ErrInvalidUploadSession)
+	}
+	checksum, err := ParseChecksum(req.Checksum, req.ChecksumAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.InitiateUpload(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if _, err := s.db.CreateUploadSession(ctx, sqlc.CreateUploadSessionParams{
+		AssetId:           pgtype.UUID{Bytes: upload.AssetID, Valid: true},
+		OwnerId:           pgtype.UUID{Bytes: req.UserID, Valid: true},
+		TotalSize:         req.Size,
+		Checksum:          checksum.Hex(),
+		ChecksumAlgorithm: string(checksum.Algorithm),
+	}); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+	return &UploadSession{AssetID: upload.AssetID, TotalSize: req.Size, Checksum: checksum}, nil
+}
+
+// GetUploadSession returns the upload session of an asset of userID.
+func (s *Service) GetUploadSession(ctx context.Context, assetID, userID uuid.UUID) (*UploadSession, error) {
+	session, chunks, err := s.uploadSession(ctx, assetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toUploadSession(session, chunks), nil
+}
+
+// UploadChunk stages the part of an upload's file starting at offset,
+// checking it against checksum first, and returns the session with it.
+// Chunks can arrive in any order; a chunk sent again replaces the one
+// staged for the same offset.
+func (s *Service) UploadChunk(ctx context.Context, assetID, userID uuid.UUID, offset int64, reader io.Reader, checksum string) (*UploadSession, error) {
+	ctx, span := tracer.Start(ctx, "assets.upload_chunk",
+		trace.WithAttributes(
+			attribute.String("asset_id", assetID.String()),
+			attribute.Int64("offset", offset),
+		))
+	defer span.End()
+
+	session, chunks, err := s.uploadSession(ctx, assetID, userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	dir := s.chunkDir(assetID)
+	size, err := stageChunk(dir, offset, reader, checksum, session.TotalSize, stagedChunksOf(dir, chunks))
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	if err := s.db.UpsertUploadSessionChunk(ctx, sqlc.UpsertUploadSessionChunkParams{
+		AssetId: session.AssetId,
+		Start:   offset,
+		Size:    size,
+	}); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to record upload chunk: %w", err)
+	}
+	if chunks, err = s.db.GetUploadSessionChunks(ctx, session.AssetId); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("failed to get upload chunks: %w", err)
+	}
+	return toUploadSession(session, chunks), nil
+}
+
+// FinishUploadSession assembles the staged chunks of an upload, checks the
+// file against the checksum the session was opened with, and only then
+// stores it, ends the session and starts processing like CompleteUpload.
+// When storing fails the chunks are kept and finishing can be tried again.
+func (s *Service) FinishUploadSession(ctx context.Context, assetID, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "assets.finish_upload_session",
+		trace.WithAttributes(
+			attribute.String("asset_id", assetID.String()),
+		))
+	defer span.End()
+
+	session, chunks, err := s.uploadSession(ctx, assetID, userID)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	asset, err := s.db.GetAssetByID(ctx, session.AssetId)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to get asset: %w", err)
+	}
+
+	dir := s.chunkDir(assetID)
+	file, checksum, err := assembleChunks(dir, stagedChunksOf(dir, chunks), session.TotalSize, ChecksumAlgorithm(session.ChecksumAlgorithm))
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	if checksum.Hex() != session.Checksum {
+		err := fmt.Errorf("%w: got %s, expected %s", ErrUploadChecksumMismatch, checksum.Hex(), session.Checksum)
+		span.RecordError(err)
+		if dropErr := s.db.DeleteUploadSessionChunks(ctx, session.AssetId); dropErr != nil {
+			return fmt.Errorf("failed to drop upload chunks: %w", dropErr)
+		}
+		s.removeChunks(assetID)
+		return err
+	}
+
+	if err := s.storeOriginal(ctx, &asset, file); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if err := s.db.DeleteUploadSession(ctx, session.AssetId); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to end upload session: %w", err)
+	}
+	s.removeChunks(assetID)
+	return s.activateUpload(ctx, assetID, asset)
+}
+
+// uploadSession returns the upload session of an asset of userID with the
+// chunks it received.
+func (s *Service) uploadSession(ctx context.Context, assetID, userID uuid.UUID) (sqlc.UploadSession, []sqlc.UploadSessionChunk, error) {
+	session, err := s.db.GetUploadSession(ctx, sqlc.GetUploadSessionParams{
+		AssetId: pgtype.UUID{Bytes: assetID, Valid: true},
+		OwnerId: pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.UploadSession{}, nil, ErrUploadSessionNotFound
+	}
+	if err != nil {
+		return sqlc.UploadSession{}, nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+	chunks, err := s.db.GetUploadSessionChunks(ctx, session.AssetId)
+	if err != nil {
+		return sqlc.UploadSession{}, nil, fmt.Errorf("failed to get upload chunks: %w", err)
+	}
+	return session, chunks, nil
+}
+
+func toUploadSession(session sqlc.UploadSession, chunks []sqlc.UploadSessionChunk) *UploadSession {
+	upload := &UploadSession{
+		AssetID:   uuid.UUID(session.AssetId.Bytes),
+		TotalSize: session.TotalSize,
+		Checksum:  Checksum{Algorithm: ChecksumAlgorithm(session.ChecksumAlgorithm)},
+	}
+	if checksum, err := ParseChecksum(session.Checksum, upload.Checksum.Algorithm); err == nil {
+		upload.Checksum = checksum
+	}
+	for _, chunk := range chunks {
+		end := chunk.Start + chunk.Size
+		if last := len(upload.Received) - 1; last >= 0 && chunk.Start <= upload.Received[last].End {
+			upload.Received[last].End = max(upload.Received[last].End, end)
+			continue
+		}
+		upload.Received = append(upload.Received, ByteRange{Start: chunk.Start, End: end})
+	}
+	return upload
+}
+
+func (s *Service) chunkDir(assetID uuid.UUID) string {
+	tempDir := s.config.Storage.Upload.TempDir
+	if tempDir == "" {
+		tempDir = os.TempDir()
+	}
+	return filepath.Join(tempDir, "chunks", assetID.String())
+}
+
+func (s *Service) removeChunks(assetID uuid.UUID) {
+	if err := os.RemoveAll(s.chunkDir(assetID)); err != nil {
+		s.logger.Warn("Failed to remove upload chunks", zap.String("asset_id", assetID.String()), zap.Error(err))
+	}
+}
+
+type stagedChunk struct {
+	path   string
+	offset int64
+	size   int64
+}
+
+func chunkPath(dir string, offset int64) string {
+	return filepath.Join(dir, strconv.FormatInt(offset, 10)+chunkSuffix)
+}
+
+// stagedChunksOf returns where the chunks a session received are staged
+// in dir.
+func stagedChunksOf(dir string, chunks []sqlc.UploadSessionChunk) []stagedChunk {
+	staged := make([]stagedChunk, len(chunks))
+	for i, chunk := range chunks {
+		staged[i] = stagedChunk{path: chunkPath(dir, chunk.Start), offset: chunk.Start, size: chunk.Size}
+	}
+	return staged
+}
+
+// stageChunk writes the chunk at offset of a file of totalSize bytes into
+// dir if it matches checksum and overlaps none of the staged chunks at
+// other offsets. It returns the size of the chunk.
+func stageChunk(dir string, offset int64, reader io.Reader, checksum string, totalSize int64, staged []stagedChunk) (int64, error) {
+	if offset < 0 || offset >= totalSize {
+		return 0, fmt.Errorf("invalid chunk offset %d", offset)
+	}
+	expected, err := ParseChecksum(checksum, "")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return 0, fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "incoming-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hash := expected.Algorithm.newHash()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(reader, totalSize-offset+1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	if offset+size > totalSize {
+		return 0, fmt.Errorf("chunk ends past the end of the %d byte file", totalSize)
+	}
+	if got := (Checksum{Algorithm: expected.Algorithm, Digest: hash.Sum(nil)}); got.Hex() != expected.Hex() {
+		return 0, fmt.Errorf("%w at offset %d: got %s, expected %s", ErrChunkChecksumMismatch, offset, got.Hex(), expected.Hex())
+	}
+	for _, chunk := range staged {
+		if chunk.offset != offset && offset < chunk.offset+chunk.size && chunk.offset < offset+size {
+			return 0, fmt.Errorf("%w: bytes %d to %d were received at offset %d", ErrChunkOverlap, chunk.offset, chunk.offset+chunk.size-1, chunk.offset)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), chunkPath(dir, offset)); err != nil {
+		return 0, fmt.Errorf("failed to stage chunk: %w", err)
+	}
+	return size, nil
+}
+
+// assembleChunks joins the chunks, in order, into one file of totalSize
+// bytes in dir, rewound, and hashes it with algorithm. The chunks must
+// follow each other exactly from the start of the file to its end.
+func assembleChunks(dir string, chunks []stagedChunk, totalSize int64, algorithm ChecksumAlgorithm) (*os.File, Checksum, error) {
+	var next int64
+	for _, chunk := range chunks {
+		switch {
+		case chunk.offset > next:
+			return nil, Checksum{}, fmt.Errorf("%w: bytes %d to %d are missing", ErrUploadIncomplete, next, chunk.offset-1)
+		case chunk.offset < next:
+			return nil, Checksum{}, fmt.Errorf("%w: the chunk at offset %d overlaps the one before", ErrUploadIncomplete, chunk.offset)
+		}
+		next += chunk.size
+	}
+	if next < totalSize {
+		return nil, Checksum{}, fmt.Errorf("%w: bytes %d to %d are missing", ErrUploadIncomplete, next, totalSize-1)
+	}
+
+	file, err := os.CreateTemp(dir, "assembled-*")
+	if err != nil {
+		return nil, Checksum{}, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	fail := func(err error) (*os.File, Checksum, error) {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, Checksum{}, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	hash := algorithm.newHash()
+	out := io.MultiWriter(file, hash)
+	for _, chunk := range chunks {
+		part, err := os.Open(chunk.path)
+		if err != nil {
+			return fail(err)
+		}
+		_, err = io.Copy(out, part)
+		_ = part.Close()
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	return file, Checksum{Algorithm: algorithm, Digest: hash.Sum(nil)}, nil
+}
@@ -0,0 +1,115 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestStageChunk(t *testing.T) {
+	dir := t.TempDir()
+	chunk := []byte("first chunk of the file")
+	checksum := SumChecksum(chunk, ChecksumSHA1).Hex()
+
+	_, err := stageChunk(dir, 0, bytes.NewReader([]byte("damaged chunk of the file")), checksum, 100, nil)
+	assert.ErrorIs(t, err, ErrChunkChecksumMismatch)
+	_, err = os.Stat(chunkPath(dir, 0))
+	assert.ErrorIs(t, err, os.ErrNotExist, "a damaged chunk is not kept")
+
+	size, err := stageChunk(dir, 0, bytes.NewReader(chunk), checksum, 100, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(chunk)), size)
+	staged, err := os.ReadFile(chunkPath(dir, 0))
+	require.NoError(t, err)
+	assert.Equal(t, chunk, staged)
+
+	_, err = stageChunk(dir, 0, bytes.NewReader(chunk), "not a checksum", 100, nil)
+	assert.ErrorIs(t, err, ErrInvalidChecksum)
+	_, err = stageChunk(dir, 10, bytes.NewReader(chunk), checksum, 20, nil)
+	assert.Error(t, err, "the chunk ends past the end of the file")
+	_, err = stageChunk(dir, 100, bytes.NewReader(chunk), checksum, 100, nil)
+	assert.Error(t, err, "the chunk starts past the end of the file")
+}
+
+func TestStageChunk_Overlap(t *testing.T) {
+	dir := t.TempDir()
+	chunk := []byte("0123456789")
+	checksum := SumChecksum(chunk, ChecksumSHA1).Hex()
+	received := []stagedChunk{{path: chunkPath(dir, 10), offset: 10, size: 10}}
+
+	_, err := stageChunk(dir, 5, bytes.NewReader(chunk), checksum, 100, received)
+	assert.ErrorIs(t, err, ErrChunkOverlap)
+	_, err = stageChunk(dir, 15, bytes.NewReader(chunk), checksum, 100, received)
+	assert.ErrorIs(t, err, ErrChunkOverlap)
+
+	_, err = stageChunk(dir, 10, bytes.NewReader(chunk), checksum, 100, received)
+	assert.NoError(t, err, "a chunk sent again replaces the one at its offset")
+	_, err = stageChunk(dir, 0, bytes.NewReader(chunk), checksum, 100, received)
+	assert.NoError(t, err, "adjacent chunks do not overlap")
+}
+
+func TestAssembleChunks(t *testing.T) {
+	dir := t.TempDir()
+	parts := [][]byte{[]byte("one "), []byte("two "), []byte("three")}
+	const total = int64(len("one two three"))
+	var staged []stagedChunk
+	var offset int64
+	for _, part := range parts {
+		size, err := stageChunk(dir, offset, bytes.NewReader(part), SumChecksum(part, ChecksumSHA256).Hex(), total, staged)
+		require.NoError(t, err)
+		staged = append(staged, stagedChunk{path: chunkPath(dir, offset), offset: offset, size: size})
+		offset += size
+	}
+
+	_, _, err := assembleChunks(dir, []stagedChunk{staged[0], staged[2]}, total, ChecksumSHA1)
+	assert.ErrorIs(t, err, ErrUploadIncomplete, "the middle chunk is missing")
+	_, _, err = assembleChunks(dir, staged[:2], total, ChecksumSHA1)
+	assert.ErrorIs(t, err, ErrUploadIncomplete, "the last chunk is missing")
+	_, _, err = assembleChunks(dir, nil, total, ChecksumSHA1)
+	assert.ErrorIs(t, err, ErrUploadIncomplete)
+
+	file, checksum, err := assembleChunks(dir, staged, total, ChecksumSHA1)
+	require.NoError(t, err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "one two three", string(data))
+	assert.Equal(t, SumChecksum([]byte("one two three"), ChecksumSHA1), checksum)
+
+	overlapping := append([]stagedChunk{staged[0], {path: staged[0].path, offset: 2, size: staged[0].size}}, staged[1:]...)
+	_, _, err = assembleChunks(dir, overlapping, total, ChecksumSHA1)
+	assert.ErrorIs(t, err, ErrUploadIncomplete, "chunks overlap")
+}
+
+func TestToUploadSession(t *testing.T) {
+	checksum := SumChecksum([]byte("file"), ChecksumSHA1)
+	session := sqlc.UploadSession{
+		AssetId:           pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		TotalSize:         40,
+		Checksum:          checksum.Hex(),
+		ChecksumAlgorithm: string(ChecksumSHA1),
+	}
+
+	upload := toUploadSession(session, nil)
+	assert.Equal(t, checksum, upload.Checksum)
+	assert.Empty(t, upload.Received)
+	assert.Zero(t, upload.ReceivedBytes())
+
+	upload = toUploadSession(session, []sqlc.UploadSessionChunk{
+		{Start: 0, Size: 10},
+		{Start: 10, Size: 5},
+		{Start: 30, Size: 10},
+	})
+	assert.Equal(t, []ByteRange{{Start: 0, End: 15}, {Start: 30, End: 40}}, upload.Received)
+	assert.Equal(t, int64(15), upload.ReceivedBytes(), "the upload resumes at the first gap")
+
+	upload = toUploadSession(session, []sqlc.UploadSessionChunk{{Start: 10, Size: 10}})
+	assert.Zero(t, upload.ReceivedBytes(), "nothing arrived from the start of the file")
+}
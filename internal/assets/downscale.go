@@ -0,0 +1,201 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"mime"
+	"path"
+
+	"github.com/disintegration/imaging"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// downscaleJPEGQuality is high on purpose: the downscaled image stands in
+// for the original.
+const downscaleJPEGQuality = 92
+
+// ErrDownscaleUnsupported is returned for images over the size limit whose
+// format cannot be re-encoded. They are stored as uploaded.
+var ErrDownscaleUnsupported = errors.New("image format cannot be downscaled")
+
+// ImageSizeLimit caps the size of uploaded images. Zero fields do not limit.
+type ImageSizeLimit struct {
+	MaxMegapixels float64
+	MaxDimension  int
+}
+
+// Enabled reports whether the limit caps anything.
+func (l ImageSizeLimit) Enabled() bool {
+	return l.MaxMegapixels > 0 || l.MaxDimension > 0
+}
+
+// fit returns the largest size with the aspect ratio of width x height
+// within the limit, and false when width x height already is.
+func (l ImageSizeLimit) fit(width, height int) (int, int, bool) {
+	if width <= 0 || height <= 0 {
+		return width, height, false
+	}
+	scale := 1.0
+	if l.MaxDimension > 0 {
+		scale = math.Min(scale, float64(l.MaxDimension)/float64(max(width, height)))
+	}
+	if l.MaxMegapixels > 0 {
+		scale = math.Min(scale, math.Sqrt(l.MaxMegapixels*1e6/float64(width*height)))
+	}
+	if scale >= 1 {
+		return width, height, false
+	}
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale)), true
+}
+
+// DownscaledImage is an uploaded image scaled down to the size limit.
+type DownscaledImage struct {
+	Data           []byte
+	Width          int
+	Height         int
+	OriginalWidth  int
+	OriginalHeight int
+}
+
+// DownscaleImage scales an image down to fit the limit, keeping its format.
+// A JPEG keeps its EXIF, XMP and ICC segments, and its pixels are not
+// rotated so the EXIF orientation still applies. It returns nil when the
+// image fits, or its size cannot be read.
+func DownscaleImage(data []byte, limit ImageSizeLimit) (*DownscaledImage, error) {
+	if !limit.Enabled() {
+		return nil, nil
+	}
+	cfg, formatName, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil
+	}
+	width, height, ok := limit.fit(cfg.Width, cfg.Height)
+	if !ok {
+		return nil, nil
+	}
+	format, err := imaging.FormatFromExtension(formatName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDownscaleUnsupported, formatName)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	var buf bytes.Buffer
+	resized := imaging.Resize(img, width, height, imaging.Lanczos)
+	if err := imaging.Encode(&buf, resized, format, imaging.JPEGQuality(downscaleJPEGQuality)); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	out := buf.Bytes()
+	if format == imaging.JPEG {
+		out = insertJPEGSegments(out, jpegMetadataSegments(data))
+	}
+	return &DownscaledImage{
+		Data:           out,
+		Width:          width,
+		Height:         height,
+		OriginalWidth:  cfg.Width,
+		OriginalHeight: cfg.Height,
+	}, nil
+}
+
+// KeepDownscaledOriginal stores the uploaded file of an image downscaled to
+// originalPath next to it, where metadata write-back keeps its backups, and
+// returns its path.
+func (s *Service) KeepDownscaledOriginal(ctx context.Context, originalPath string, uploaded []byte) (string, error) {
+	backupPath := originalPath + writeBackBackupSuffix
+	if err := s.storage.UploadBytes(ctx, backupPath, uploaded, mime.TypeByExtension(path.Ext(originalPath))); err != nil {
+		return "", fmt.Errorf("failed to keep downscaled original: %w", err)
+	}
+	return backupPath, nil
+}
+
+// RecordDownscale records that the asset was downscaled on upload. The
+// uploaded file kept at backupPath, if any, becomes the asset's
+// original_backup, which the asset checksum identifies.
+func (s *Service) RecordDownscale(ctx context.Context, assetID pgtype.UUID, downscaled *DownscaledImage, backupPath string) error {
+	if backupPath != "" {
+		if _, err := s.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+			AssetId: assetID,
+			Type:    assetFileTypeOriginalBackup,
+			Path:    backupPath,
+		}); err != nil {
+			return fmt.Errorf("failed to record downscaled original: %w", err)
+		}
+	}
+	if err := s.db.CreateAssetDownscale(ctx, sqlc.CreateAssetDownscaleParams{
+		AssetId:        assetID,
+		OriginalWidth:  int32(downscaled.OriginalWidth),
+		OriginalHeight: int32(downscaled.OriginalHeight),
+		Width:          int32(downscaled.Width),
+		Height:         int32(downscaled.Height),
+		OriginalKept:   backupPath != "",
+	}); err != nil {
+		return fmt.Errorf("failed to record downscale: %w", err)
+	}
+	return nil
+}
+
+const (
+	jpegMarkerSOI  = 0xD8
+	jpegMarkerSOS  = 0xDA
+	jpegMarkerAPP1 = 0xE1 // EXIF and XMP
+	jpegMarkerAPP2 = 0xE2 // ICC profile
+)
+
+// jpegMetadataSegments returns the APP1 and APP2 segments of a JPEG, marker
+// included, in file order.
+func jpegMetadataSegments(data []byte) [][]byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != jpegMarkerSOI {
+		return nil
+	}
+	var segments [][]byte
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return segments
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte before a marker.
+			i++
+			continue
+		}
+		if marker == jpegMarkerSOS {
+			return segments
+		}
+		end := i + 2 + (int(data[i+2])<<8 | int(data[i+3]))
+		if end > len(data) {
+			return segments
+		}
+		if marker == jpegMarkerAPP1 || marker == jpegMarkerAPP2 {
+			segments = append(segments, data[i:end])
+		}
+		i = end
+	}
+	return segments
+}
+
+// insertJPEGSegments puts segments right after the start of a JPEG.
+func insertJPEGSegments(data []byte, segments [][]byte) []byte {
+	if len(segments) == 0 || len(data) < 2 {
+		return data
+	}
+	size := len(data)
+	for _, segment := range segments {
+		size += len(segment)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, data[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
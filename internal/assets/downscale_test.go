@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageSizeLimitFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         ImageSizeLimit
+		width, height int
+		wantW, wantH  int
+		wantScaled    bool
+	}{
+		{"no limit", ImageSizeLimit{}, 8000, 2000, 8000, 2000, false},
+		{"fits", ImageSizeLimit{MaxDimension: 8000, MaxMegapixels: 16}, 8000, 2000, 8000, 2000, false},
+		{"longest edge", ImageSizeLimit{MaxDimension: 4000}, 16000, 2000, 4000, 500, true},
+		{"portrait edge", ImageSizeLimit{MaxDimension: 1000}, 1500, 3000, 500, 1000, true},
+		{"megapixels", ImageSizeLimit{MaxMegapixels: 4}, 4000, 4000, 2000, 2000, true},
+		{"tighter of both", ImageSizeLimit{MaxDimension: 1000, MaxMegapixels: 4}, 4000, 4000, 1000, 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, scaled := tt.limit.fit(tt.width, tt.height)
+			assert.Equal(t, tt.wantScaled, scaled)
+			assert.Equal(t, tt.wantW, w)
+			assert.Equal(t, tt.wantH, h)
+		})
+	}
+}
+
+func TestDownscaleImageKeepsJPEGMetadata(t *testing.T) {
+	exifSegment := append([]byte{0xFF, 0xE1, 0x00, 0x10}, []byte("Exif\x00\x00testdata")...)
+	original := createTestJPEG(400, 200)
+	original = insertJPEGSegments(original, [][]byte{exifSegment})
+
+	downscaled, err := DownscaleImage(original, ImageSizeLimit{MaxDimension: 100})
+	require.NoError(t, err)
+	require.NotNil(t, downscaled)
+	assert.Equal(t, 400, downscaled.OriginalWidth)
+	assert.Equal(t, 200, downscaled.OriginalHeight)
+	assert.Equal(t, 100, downscaled.Width)
+	assert.Equal(t, 50, downscaled.Height)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(downscaled.Data))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 100, cfg.Width)
+	assert.Equal(t, [][]byte{exifSegment}, jpegMetadataSegments(downscaled.Data))
+}
+
+func TestDownscaleImageKeepsFormat(t *testing.T) {
+	downscaled, err := DownscaleImage(createTestPNG(300, 300), ImageSizeLimit{MaxMegapixels: 0.01})
+	require.NoError(t, err)
+	require.NotNil(t, downscaled)
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(downscaled.Data))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 100, cfg.Width)
+	assert.Equal(t, 100, cfg.Height)
+}
+
+func TestDownscaleImageLeavesOtherImagesAlone(t *testing.T) {
+	small := createTestJPEG(100, 100)
+
+	downscaled, err := DownscaleImage(small, ImageSizeLimit{})
+	require.NoError(t, err)
+	assert.Nil(t, downscaled, "no limit configured")
+
+	downscaled, err = DownscaleImage(small, ImageSizeLimit{MaxDimension: 100})
+	require.NoError(t, err)
+	assert.Nil(t, downscaled, "fits the limit")
+
+	downscaled, err = DownscaleImage([]byte("not an image"), ImageSizeLimit{MaxDimension: 10})
+	require.NoError(t, err)
+	assert.Nil(t, downscaled, "size cannot be read")
+}
@@ -0,0 +1,339 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+// UseExiftool reports whether cfg selects exiftool for metadata extraction.
+// It is off when EXIF extraction is disabled. cfg may be nil.
+func UseExiftool(cfg *config.Config) bool {
+	return cfg != nil && cfg.Features.EXIFExtractionEnabled && cfg.Metadata.Extractor == "exiftool"
+}
+
+// WithExiftool makes the extractor read images and videos with exiftool,
+// which knows far more tags and formats than the native readers. Files
+// exiftool fails on, and every file while it is not installed, are read
+// with the native readers instead.
+func (e *MetadataExtractor) WithExiftool(enabled bool) *MetadataExtractor {
+	e.exiftool = enabled
+	return e
+}
+
+// exiftoolReadArgs prints the tags AssetMetadata holds as JSON. -n leaves
+// values unconverted: numbers stay numbers, GPS coordinates are decimal
+// degrees and durations are seconds.
+var exiftoolReadArgs = []string{
+	"-json", "-n", "-quiet",
+	"-Make", "-Model", "-LensModel",
+	"-FNumber", "-FocalLength", "-ISO", "-ExposureTime",
+	"-ImageWidth", "-ImageHeight", "-Duration",
+	"-GPSLatitude", "-GPSLatitudeRef", "-GPSLongitude", "-GPSLongitudeRef", "-GPSDateTime",
+	"-SubSecDateTimeOriginal", "-DateTimeOriginal", "-OffsetTimeOriginal", "-CreationDate", "-CreateDate",
+	"-ImageDescription", "-Description", "-Keywords", "-Subject",
+}
+
+// exiftoolTags are the tags exiftool printed for one file.
+type exiftoolTags map[string]any
+
+// extractWithExiftool reads the metadata of a file with exiftool, falling
+// back to the native readers when exiftool is missing or fails on the file.
+func (e *MetadataExtractor) extractWithExiftool(ctx context.Context, reader io.Reader, assetType AssetType, metadata *AssetMetadata) error {
+	ctx, span := tracer.Start(ctx, "metadata.extract_exiftool")
+	defer span.End()
+
+	exiftool, err := exec.LookPath("exiftool")
+	if err != nil {
+		span.SetAttributes(attribute.String("status", "exiftool_not_found"))
+		return e.extractNative(ctx, reader, assetType, metadata)
+	}
+
+	tmpFile, err := os.CreateTemp("", "metadata-*.tmp")
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+	if _, err := io.Copy(tmpFile, reader); err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("failed to write to temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	tags, err := readExiftoolTags(ctx, exiftool, tmpFile.Name())
+	if err != nil {
+		// The native readers may still get something out of the file
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("status", "exiftool_failed"))
+		file, openErr := os.Open(tmpFile.Name())
+		if openErr != nil {
+			return fmt.Errorf("failed to reopen temp file: %w", openErr)
+		}
+		defer file.Close()
+		return e.extractNative(ctx, file, assetType, metadata)
+	}
+
+	span.SetAttributes(attribute.String("status", "success"))
+	e.applyExiftoolTags(tags, assetType, metadata)
+	return nil
+}
+
+// readExiftoolTags runs exiftool on a local file.
+func readExiftoolTags(ctx context.Context, exiftool, local string) (exiftoolTags, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, exiftool, append(exiftoolReadArgs, local)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("exiftool failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseExiftoolOutput(stdout.Bytes())
+}
+
+// parseExiftoolOutput returns the tags of the single file in exiftool's
+// JSON output.
+func parseExiftoolOutput(output []byte) (exiftoolTags, error) {
+	var files []exiftoolTags
+	if err := json.Unmarshal(output, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse exiftool output: %w", err)
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("exiftool returned %d files, expected one", len(files))
+	}
+	if message, ok := files[0].string("Error"); ok {
+		return nil, errors.New(message)
+	}
+	return files[0], nil
+}
+
+// applyExiftoolTags fills metadata from the tags exiftool printed.
+func (e *MetadataExtractor) applyExiftoolTags(tags exiftoolTags, assetType AssetType, metadata *AssetMetadata) {
+	metadata.Make = tags.stringPtr("Make")
+	metadata.Model = tags.stringPtr("Model")
+	metadata.LensModel = tags.stringPtr("LensModel")
+	metadata.Description = tags.stringPtr("ImageDescription", "Description")
+	metadata.Keywords = tags.strings("Keywords", "Subject")
+
+	if width, ok := tags.float("ImageWidth"); ok && width > 0 && width <= math.MaxInt32 {
+		w32 := int32(width)
+		metadata.Width = &w32
+	}
+	if height, ok := tags.float("ImageHeight"); ok && height > 0 && height <= math.MaxInt32 {
+		h32 := int32(height)
+		metadata.Height = &h32
+	}
+	if assetType == AssetTypeImage && metadata.Width != nil && metadata.Height != nil &&
+		(int(*metadata.Width) > e.limits.MaxDimension || int(*metadata.Height) > e.limits.MaxDimension) {
+		metadata.exceedsDecodeLimit = true
+		metadata.warn(fmt.Sprintf("image is %dx%d, larger than the %d pixel decode limit",
+			*metadata.Width, *metadata.Height, e.limits.MaxDimension))
+	}
+	if assetType == AssetTypeVideo {
+		if duration, ok := tags.float("Duration"); ok && duration > 0 {
+			metadata.Duration = &duration
+		}
+	}
+
+	if fNumber, ok := tags.float("FNumber"); ok && fNumber > 0 {
+		metadata.FNumber = &fNumber
+	}
+	if focalLength, ok := tags.float("FocalLength"); ok && focalLength > 0 {
+		metadata.FocalLength = &focalLength
+	}
+	if iso, ok := tags.float("ISO"); ok && iso > 0 && iso <= math.MaxInt32 {
+		iso32 := int32(iso)
+		metadata.ISO = &iso32
+	}
+	if exposure, ok := tags.float("ExposureTime"); ok && exposure > 0 {
+		exposureTime := formatExposureTime(exposure)
+		metadata.ExposureTime = &exposureTime
+	}
+
+	if lat, lon, ok := tags.gpsPosition(); ok {
+		metadata.Latitude = &lat
+		metadata.Longitude = &lon
+	}
+
+	if dateTaken, timeZone := exiftoolCaptureTime(tags, assetType, e.location); dateTaken != nil {
+		metadata.DateTaken = dateTaken
+		metadata.TimeZone = timeZone
+	}
+}
+
+// exiftoolCaptureTime resolves the capture instant and timezone like
+// captureTime does for native EXIF data. The QuickTime CreateDate of videos
+// is UTC, while the CreateDate of images is a wall-clock time.
+func exiftoolCaptureTime(tags exiftoolTags, assetType AssetType, fallback *time.Location) (*time.Time, *string) {
+	for _, name := range []string{"SubSecDateTimeOriginal", "DateTimeOriginal", "CreationDate", "CreateDate"} {
+		value, ok := tags.string(name)
+		if !ok {
+			continue
+		}
+		wallClock, zone, ok := parseExiftoolDate(value)
+		if !ok {
+			continue
+		}
+		if zone == "" && name == "DateTimeOriginal" {
+			zone, _ = tags.string("OffsetTimeOriginal")
+		}
+		if loc, ok := ParseTimeZone(zone); ok {
+			t := reinterpretInLocation(wallClock, loc)
+			tz := loc.String()
+			return &t, &tz
+		}
+		if name == "CreateDate" && assetType == AssetTypeVideo {
+			return &wallClock, nil
+		}
+		if gpsTime, ok := tags.gpsTime(); ok {
+			if loc, ok := offsetFromGPS(wallClock, gpsTime); ok {
+				t := reinterpretInLocation(wallClock, loc)
+				tz := loc.String()
+				return &t, &tz
+			}
+		}
+		t := reinterpretInLocation(wallClock, orUTC(fallback))
+		return &t, nil
+	}
+	return nil, nil
+}
+
+// parseExiftoolDate splits an exiftool date such as
+// "2023:05:01 14:00:00.123+02:00" into its wall clock, as a UTC time, and
+// its zone, which is empty when the date has none.
+func parseExiftoolDate(value string) (time.Time, string, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < len(exifDateTimeLayout) {
+		return time.Time{}, "", false
+	}
+	end := len(value)
+	if i := strings.IndexAny(value[len(exifDateTimeLayout):], "+-Z"); i >= 0 {
+		end = len(exifDateTimeLayout) + i
+	}
+	// time.Parse accepts the fractional seconds the layout leaves out
+	wallClock, err := time.Parse(exifDateTimeLayout, value[:end])
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return wallClock, value[end:], true
+}
+
+// formatExposureTime formats an exposure time in seconds like cameras do:
+// "1/250" below a second, "2" or "2.5" above.
+func formatExposureTime(seconds float64) string {
+	if seconds < 1 {
+		return fmt.Sprintf("1/%d", int(math.Round(1/seconds)))
+	}
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
+
+// string returns the first of names holding a non-empty value.
+func (t exiftoolTags) string(names ...string) (string, bool) {
+	for _, name := range names {
+		if value, ok := exiftoolString(t[name]); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// exiftoolString returns a printed value as text. Values that look like
+// numbers are printed as numbers.
+func exiftoolString(value any) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		value = strings.TrimSpace(value)
+		return value, value != ""
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func (t exiftoolTags) stringPtr(names ...string) *string {
+	value, ok := t.string(names...)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// strings returns the values of the first of names that is set, which
+// exiftool prints as a list when there are several.
+func (t exiftoolTags) strings(names ...string) []string {
+	for _, name := range names {
+		switch value := t[name].(type) {
+		case []any:
+			var values []string
+			for _, item := range value {
+				if s, ok := exiftoolString(item); ok {
+					values = append(values, s)
+				}
+			}
+			if len(values) > 0 {
+				return values
+			}
+		default:
+			if s, ok := t.string(name); ok {
+				return []string{s}
+			}
+		}
+	}
+	return nil
+}
+
+// float returns the number under name. Some files hold numbers as text.
+func (t exiftoolTags) float(name string) (float64, bool) {
+	switch value := t[name].(type) {
+	case float64:
+		return value, !math.IsNaN(value) && !math.IsInf(value, 0)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+// gpsPosition returns the GPS position in signed decimal degrees. The
+// hemisphere references are applied in case the coordinates are unsigned.
+func (t exiftoolTags) gpsPosition() (float64, float64, bool) {
+	lat, latOK := t.float("GPSLatitude")
+	lon, lonOK := t.float("GPSLongitude")
+	if !latOK || !lonOK || (lat == 0 && lon == 0) {
+		return 0, 0, false
+	}
+	if ref, _ := t.string("GPSLatitudeRef"); strings.EqualFold(ref, "S") && lat > 0 {
+		lat = -lat
+	}
+	if ref, _ := t.string("GPSLongitudeRef"); strings.EqualFold(ref, "W") && lon > 0 {
+		lon = -lon
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// gpsTime returns the UTC instant recorded by the GPS receiver.
+func (t exiftoolTags) gpsTime() (time.Time, bool) {
+	value, ok := t.string("GPSDateTime")
+	if !ok {
+		return time.Time{}, false
+	}
+	gpsTime, _, ok := parseExiftoolDate(value)
+	return gpsTime, ok
+}
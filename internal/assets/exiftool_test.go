@@ -0,0 +1,208 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+// iPhoneExiftoolOutput is what exiftool -json -n prints for an iPhone photo
+// taken in the southern and western hemispheres.
+const iPhoneExiftoolOutput = `[{
+  "SourceFile": "/tmp/metadata-1.tmp",
+  "Make": "Apple",
+  "Model": "iPhone 15 Pro",
+  "LensModel": "iPhone 15 Pro back triple camera 6.765mm f/1.78",
+  "FNumber": 1.78,
+  "FocalLength": 6.765,
+  "ISO": 80,
+  "ExposureTime": 0.008,
+  "ImageWidth": 4032,
+  "ImageHeight": 3024,
+  "GPSLatitude": 22.9068,
+  "GPSLatitudeRef": "S",
+  "GPSLongitude": -43.1729,
+  "GPSLongitudeRef": "W",
+  "SubSecDateTimeOriginal": "2024:02:10 17:45:12.345-03:00",
+  "DateTimeOriginal": "2024:02:10 17:45:12",
+  "Keywords": ["beach", 2024],
+  "ImageDescription": "Sunset"
+}]`
+
+func TestApplyExiftoolTags(t *testing.T) {
+	tags, err := parseExiftoolOutput([]byte(iPhoneExiftoolOutput))
+	require.NoError(t, err)
+
+	metadata := &AssetMetadata{}
+	NewMetadataExtractor().applyExiftoolTags(tags, AssetTypeImage, metadata)
+
+	assert.Equal(t, "Apple", *metadata.Make)
+	assert.Equal(t, "iPhone 15 Pro", *metadata.Model)
+	assert.Equal(t, "iPhone 15 Pro back triple camera 6.765mm f/1.78", *metadata.LensModel)
+	assert.Equal(t, 1.78, *metadata.FNumber)
+	assert.Equal(t, 6.765, *metadata.FocalLength)
+	assert.Equal(t, int32(80), *metadata.ISO)
+	assert.Equal(t, "1/125", *metadata.ExposureTime)
+	assert.Equal(t, int32(4032), *metadata.Width)
+	assert.Equal(t, int32(3024), *metadata.Height)
+	assert.Equal(t, -22.9068, *metadata.Latitude, "the hemisphere applies to unsigned coordinates")
+	assert.Equal(t, -43.1729, *metadata.Longitude)
+	assert.Equal(t, time.Date(2024, time.February, 10, 20, 45, 12, 345e6, time.UTC), *metadata.DateTaken, "subseconds are kept")
+	assert.Equal(t, "UTC-3", *metadata.TimeZone)
+	assert.Equal(t, []string{"beach", "2024"}, metadata.Keywords)
+	assert.Equal(t, "Sunset", *metadata.Description)
+	assert.Nil(t, metadata.Duration, "images have no duration")
+	assert.False(t, metadata.exceedsDecodeLimit)
+}
+
+func TestApplyExiftoolTags_DecodeLimit(t *testing.T) {
+	tags := exiftoolTags{"ImageWidth": 40000.0, "ImageHeight": 30000.0}
+	metadata := &AssetMetadata{}
+	NewMetadataExtractor().applyExiftoolTags(tags, AssetTypeImage, metadata)
+	assert.True(t, metadata.exceedsDecodeLimit)
+	assert.Len(t, metadata.Warnings, 1)
+}
+
+func TestParseExiftoolOutput_Error(t *testing.T) {
+	_, err := parseExiftoolOutput([]byte(`[{"SourceFile": "a.tmp", "Error": "File format error"}]`))
+	assert.ErrorContains(t, err, "File format error")
+	_, err = parseExiftoolOutput([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestExiftoolCaptureTime(t *testing.T) {
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.NoError(t, err)
+	tests := []struct {
+		name      string
+		tags      exiftoolTags
+		assetType AssetType
+		want      time.Time
+		timeZone  string
+	}{
+		{
+			name: "offset tag",
+			tags: exiftoolTags{"DateTimeOriginal": "2023:05:01 14:00:00", "OffsetTimeOriginal": "+09:00"},
+			want: time.Date(2023, time.May, 1, 5, 0, 0, 0, time.UTC), timeZone: "UTC+9",
+		},
+		{
+			name: "GPS time",
+			tags: exiftoolTags{"DateTimeOriginal": "2023:05:01 14:00:00", "GPSDateTime": "2023:05:01 12:00:03Z"},
+			want: time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC), timeZone: "UTC+2",
+		},
+		{
+			name: "wall clock in the default timezone",
+			tags: exiftoolTags{"DateTimeOriginal": "2023:01:01 12:00:00"},
+			want: time.Date(2023, time.January, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "Apple video creation date",
+			tags:      exiftoolTags{"CreationDate": "2023:05:01 14:00:00+02:00", "CreateDate": "2023:05:01 12:00:00"},
+			assetType: AssetTypeVideo,
+			want:      time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC), timeZone: "UTC+2",
+		},
+		{
+			name:      "QuickTime create date is UTC",
+			tags:      exiftoolTags{"CreateDate": "2023:05:01 12:00:00"},
+			assetType: AssetTypeVideo,
+			want:      time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "unset dates are skipped",
+			tags: exiftoolTags{"DateTimeOriginal": "0000:00:00 00:00:00", "CreateDate": "2023:01:01 12:00:00"},
+			want: time.Date(2023, time.January, 1, 11, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assetType := tt.assetType
+			if assetType == "" {
+				assetType = AssetTypeImage
+			}
+			taken, timeZone := exiftoolCaptureTime(tt.tags, assetType, zurich)
+			require.NotNil(t, taken)
+			assert.Equal(t, tt.want, *taken)
+			if tt.timeZone == "" {
+				assert.Nil(t, timeZone)
+			} else {
+				require.NotNil(t, timeZone)
+				assert.Equal(t, tt.timeZone, *timeZone)
+			}
+		})
+	}
+
+	taken, _ := exiftoolCaptureTime(exiftoolTags{}, AssetTypeImage, zurich)
+	assert.Nil(t, taken)
+}
+
+func TestFormatExposureTime(t *testing.T) {
+	assert.Equal(t, "1/250", formatExposureTime(0.004))
+	assert.Equal(t, "1/3", formatExposureTime(0.3333))
+	assert.Equal(t, "2", formatExposureTime(2))
+	assert.Equal(t, "2.5", formatExposureTime(2.5))
+}
+
+func TestUseExiftool(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Metadata.Extractor = "exiftool"
+	assert.False(t, UseExiftool(cfg), "EXIF extraction is disabled")
+	cfg.Features.EXIFExtractionEnabled = true
+	assert.True(t, UseExiftool(cfg))
+	cfg.Metadata.Extractor = "native"
+	assert.False(t, UseExiftool(cfg))
+	assert.False(t, UseExiftool(nil))
+}
+
+// fakeExiftool puts an exiftool that runs script first in PATH.
+func fakeExiftool(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "exiftool"), []byte("#!/bin/sh\n"+script+"\n"), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExtractMetadata_Exiftool(t *testing.T) {
+	fakeExiftool(t, "cat <<'EOF'\n"+iPhoneExiftoolOutput+"\nEOF")
+	imgBytes := createTestJPEG(64, 48)
+
+	meta, err := NewMetadataExtractor().WithExiftool(true).ExtractMetadata(
+		context.Background(), bytes.NewReader(imgBytes), "photo.jpg", "image/jpeg", int64(len(imgBytes)),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, meta.LensModel)
+	assert.Equal(t, "iPhone 15 Pro back triple camera 6.765mm f/1.78", *meta.LensModel)
+	require.NotNil(t, meta.TimeZone)
+	assert.Equal(t, "UTC-3", *meta.TimeZone)
+}
+
+func TestExtractMetadata_ExiftoolFallsBackToNative(t *testing.T) {
+	imgBytes := jpegWithExifDate(createTestJPEG(64, 48), "2017:07:22 22:14:34")
+	want := time.Date(2017, time.July, 22, 22, 14, 34, 0, time.UTC)
+
+	for name, script := range map[string]string{
+		"not installed": "",
+		"failing":       "echo 'Error: File format error' >&2; exit 1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if script == "" {
+				t.Setenv("PATH", t.TempDir())
+			} else {
+				fakeExiftool(t, script)
+			}
+			meta, err := NewMetadataExtractor().WithExiftool(true).ExtractMetadata(
+				context.Background(), bytes.NewReader(imgBytes), "dated.jpg", "image/jpeg", int64(len(imgBytes)),
+			)
+			require.NoError(t, err)
+			require.NotNil(t, meta.DateTaken, "the native extractor reads the date")
+			assert.Equal(t, want, *meta.DateTaken)
+		})
+	}
+}
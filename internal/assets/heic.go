@@ -0,0 +1,90 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// heifConvertCommand is the libheif tool used to decode HEIC/HEIF images,
+// which the standard library cannot read.
+const heifConvertCommand = "heif-convert"
+
+// FullsizeQuality is the JPEG quality of converted full-size copies.
+const FullsizeQuality = 90
+
+// ErrHEICDecoderNotFound is returned when heif-convert is not in PATH.
+var ErrHEICDecoderNotFound = errors.New("heif-convert not found in PATH")
+
+// webIncompatibleMimeTypes lists image types browsers cannot display, which
+// viewers need converted to JPEG.
+var webIncompatibleMimeTypes = map[string]bool{
+	"image/heic":          true,
+	"image/heif":          true,
+	"image/heic-sequence": true,
+	"image/heif-sequence": true,
+}
+
+// NeedsWebConversion reports whether originals of mimeType must be
+// converted before a browser can display them.
+func NeedsWebConversion(mimeType string) bool {
+	return webIncompatibleMimeTypes[strings.ToLower(mimeType)]
+}
+
+// HEICDecoderAvailable reports whether heif-convert is present in PATH.
+func HEICDecoderAvailable() bool {
+	_, err := exec.LookPath(heifConvertCommand)
+	return err == nil
+}
+
+// ConvertHEICToJPEG decodes the primary image of a HEIC/HEIF file and
+// encodes it as a full-resolution JPEG.
+func ConvertHEICToJPEG(ctx context.Context, data []byte, quality int) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "assets.convert_heic",
+		trace.WithAttributes(attribute.Int("input_size", len(data))))
+	defer span.End()
+
+	heifConvert, err := exec.LookPath(heifConvertCommand)
+	if err != nil {
+		return nil, ErrHEICDecoderNotFound
+	}
+
+	dir, err := os.MkdirTemp("", "heic-convert-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir for HEIC conversion: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "input.heic")
+	if err := os.WriteFile(inputPath, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write HEIC input: %w", err)
+	}
+	outputPath := filepath.Join(dir, "output.jpg")
+
+	cmd := exec.CommandContext(ctx, heifConvert, "-q", fmt.Sprintf("%d", quality), inputPath, outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		span.SetAttributes(attribute.String("heif_convert_output", string(output)))
+		span.RecordError(err)
+		return nil, fmt.Errorf("heif-convert failed: %w", err)
+	}
+
+	jpegData, err := os.ReadFile(outputPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// Files holding several top-level images get numbered outputs;
+		// the first one is the primary image.
+		jpegData, err = os.ReadFile(filepath.Join(dir, "output-1.jpg"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converted JPEG: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("output_size", len(jpegData)))
+	return jpegData, nil
+}
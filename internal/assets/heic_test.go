@@ -0,0 +1,29 @@
+package assets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNeedsWebConversion(t *testing.T) {
+	assert.True(t, NeedsWebConversion("image/heic"))
+	assert.True(t, NeedsWebConversion("IMAGE/HEIF"))
+	assert.False(t, NeedsWebConversion("image/jpeg"))
+	assert.False(t, NeedsWebConversion("video/quicktime"))
+}
+
+func TestConvertHEICToJPEGWithoutDecoder(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	assert.False(t, HEICDecoderAvailable())
+	_, err := ConvertHEICToJPEG(context.Background(), []byte("heic"), FullsizeQuality)
+	assert.ErrorIs(t, err, ErrHEICDecoderNotFound)
+}
+
+func TestFullsizePathIsJPEG(t *testing.T) {
+	path := NewThumbnailGenerator().GetThumbnailPath("library/user/IMG_0001.HEIC", ThumbnailTypeFullsize)
+
+	assert.Equal(t, "library/user/thumbnails/IMG_0001_fullsize.jpg", path)
+}
@@ -6,9 +6,12 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"image"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -17,19 +20,98 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 var tracer = otel.Tracer("immich-go-backend/assets")
 
+// MetadataLimits bounds the work spent extracting the metadata of one file.
+type MetadataLimits struct {
+	// Concurrency is the number of files extracted at the same time.
+	Concurrency int
+	// MaxBytes is the largest image read into memory to parse its EXIF data.
+	MaxBytes int64
+	// MaxDimension is the largest image width or height accepted for
+	// decoding. Larger images keep their metadata but are flagged, so the
+	// pipeline does not decode a decompression bomb into memory.
+	MaxDimension int
+	// Timeout bounds the extraction of one file.
+	Timeout time.Duration
+}
+
+// DefaultMetadataLimits returns the limits used when none are configured.
+func DefaultMetadataLimits() MetadataLimits {
+	return MetadataLimits{
+		Concurrency:  4,
+		MaxBytes:     256 << 20,
+		MaxDimension: 30000,
+		Timeout:      time.Minute,
+	}
+}
+
+// MetadataLimitsFromConfig returns the configured metadata limits, using the
+// defaults for unset values. cfg may be nil.
+func MetadataLimitsFromConfig(cfg *config.Config) MetadataLimits {
+	if cfg == nil {
+		return DefaultMetadataLimits()
+	}
+	return MetadataLimits{
+		Concurrency:  cfg.Metadata.Concurrency,
+		MaxBytes:     cfg.Metadata.MaxBytes,
+		MaxDimension: cfg.Metadata.MaxDimension,
+		Timeout:      cfg.Metadata.Timeout,
+	}
+}
+
 // MetadataExtractor handles extraction of metadata from various file types
-type MetadataExtractor struct{}
+type MetadataExtractor struct {
+	limits   MetadataLimits
+	slots    chan struct{}
+	location *time.Location
+	exiftool bool
+}
 
-// NewMetadataExtractor creates a new metadata extractor
+// NewMetadataExtractor creates a new metadata extractor with the default limits
 func NewMetadataExtractor() *MetadataExtractor {
-	return &MetadataExtractor{}
+	return NewMetadataExtractorWithLimits(DefaultMetadataLimits())
+}
+
+// NewMetadataExtractorWithLimits creates a metadata extractor that enforces
+// limits. Zero or negative limits fall back to the defaults.
+func NewMetadataExtractorWithLimits(limits MetadataLimits) *MetadataExtractor {
+	defaults := DefaultMetadataLimits()
+	if limits.Concurrency <= 0 {
+		limits.Concurrency = defaults.Concurrency
+	}
+	if limits.MaxBytes <= 0 {
+		limits.MaxBytes = defaults.MaxBytes
+	}
+	if limits.MaxDimension <= 0 {
+		limits.MaxDimension = defaults.MaxDimension
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = defaults.Timeout
+	}
+
+	return &MetadataExtractor{
+		limits:   limits,
+		slots:    make(chan struct{}, limits.Concurrency),
+		location: time.UTC,
+	}
 }
 
-// ExtractMetadata extracts metadata from a file
+// WithDefaultLocation sets the location EXIF wall-clock dates without an
+// offset are interpreted in. A nil location means UTC.
+func (e *MetadataExtractor) WithDefaultLocation(loc *time.Location) *MetadataExtractor {
+	e.location = orUTC(loc)
+	return e
+}
+
+// ExtractMetadata extracts metadata from a file. It never fails the upload:
+// when a file breaks a limit or extraction times out, the basic file metadata
+// is returned with the reason in Warnings.
 func (e *MetadataExtractor) ExtractMetadata(ctx context.Context, reader io.Reader, filename string, contentType string, size int64) (*AssetMetadata, error) {
 	ctx, span := tracer.Start(ctx, "metadata.extract",
 		trace.WithAttributes(
@@ -50,22 +132,60 @@ func (e *MetadataExtractor) ExtractMetadata(ctx context.Context, reader io.Reade
 	// Determine asset type from content type
 	assetType := e.getAssetTypeFromContentType(contentType)
 	span.SetAttributes(attribute.String("asset_type", string(assetType)))
+	if assetType != AssetTypeImage && assetType != AssetTypeVideo {
+		return metadata, nil
+	}
 
-	// Extract metadata based on file type
-	switch assetType {
-	case AssetTypeImage:
-		if err := e.extractImageMetadata(ctx, reader, metadata); err != nil {
+	select {
+	case e.slots <- struct{}{}:
+	case <-ctx.Done():
+		metadata.warn("metadata extraction cancelled while waiting for a free slot")
+		span.SetAttributes(attribute.String("status", "cancelled"))
+		return metadata, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.limits.Timeout)
+	defer cancel()
+
+	// Extract into a copy, so a timed-out extraction that is still running
+	// cannot change the metadata handed back to the caller.
+	extracted := *metadata
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-e.slots }()
+
+		if e.exiftool {
+			done <- e.extractWithExiftool(ctx, reader, assetType, &extracted)
+		} else {
+			done <- e.extractNative(ctx, reader, assetType, &extracted)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
 			span.RecordError(err)
 			// Don't fail the entire operation for metadata extraction errors
 			// Just log and continue
 		}
-	case AssetTypeVideo:
-		if err := e.extractVideoMetadata(ctx, reader, metadata); err != nil {
-			span.RecordError(err)
+		for _, warning := range extracted.Warnings {
+			span.AddEvent("metadata.limit", trace.WithAttributes(attribute.String("warning", warning)))
 		}
+		return &extracted, nil
+	case <-ctx.Done():
+		metadata.warn(fmt.Sprintf("metadata extraction stopped after %s", e.limits.Timeout))
+		span.SetAttributes(attribute.String("status", "timeout"))
+		return metadata, nil
 	}
+}
 
-	return metadata, nil
+// extractNative extracts metadata based on file type, with goexif for
+// images and ffprobe for videos.
+func (e *MetadataExtractor) extractNative(ctx context.Context, reader io.Reader, assetType AssetType, metadata *AssetMetadata) error {
+	if assetType == AssetTypeImage {
+		return e.extractImageMetadata(ctx, reader, metadata)
+	}
+	return e.extractVideoMetadata(ctx, reader, metadata)
 }
 
 // getAssetTypeFromContentType determines asset type from MIME type
@@ -89,8 +209,31 @@ func (e *MetadataExtractor) extractImageMetadata(ctx context.Context, reader io.
 	_, span := tracer.Start(ctx, "metadata.extract_image")
 	defer span.End()
 
+	if metadata.Size > e.limits.MaxBytes {
+		metadata.warn(fmt.Sprintf("image is larger than %d bytes, EXIF data not read", e.limits.MaxBytes))
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, e.limits.MaxBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > e.limits.MaxBytes {
+		metadata.warn(fmt.Sprintf("image is larger than %d bytes, EXIF data not read", e.limits.MaxBytes))
+		return nil
+	}
+
+	// The header holds the real dimensions; checking them here keeps
+	// decompression bombs away from the thumbnail decoder.
+	header, _, headerErr := image.DecodeConfig(bytes.NewReader(data))
+	if headerErr == nil &&
+		(header.Width > e.limits.MaxDimension || header.Height > e.limits.MaxDimension) {
+		metadata.exceedsDecodeLimit = true
+		metadata.warn(fmt.Sprintf("image is %dx%d, larger than the %d pixel decode limit",
+			header.Width, header.Height, e.limits.MaxDimension))
+	}
+
 	// Try to extract EXIF data
-	x, err := exif.Decode(reader)
+	x, err := exif.Decode(bytes.NewReader(data))
 	if err != nil {
 		// Not all images have EXIF data, this is not an error
 		span.SetAttributes(attribute.Bool("has_exif", false))
@@ -142,6 +285,13 @@ func (e *MetadataExtractor) extractImageMetadata(ctx context.Context, reader io.
 		}
 	}
 
+	// EXIF keeps the size the camera wrote, which is stale once the image
+	// was downscaled on upload; the header has the size of the stored pixels.
+	if headerErr == nil && metadata.Width != nil && metadata.Height != nil {
+		w32, h32 := int32(header.Width), int32(header.Height)
+		metadata.Width, metadata.Height = &w32, &h32
+	}
+
 	// Extract camera settings
 	if fNumber, err := x.Get(exif.FNumber); err == nil {
 		if num, denom, err := fNumber.Rat2(0); err == nil && denom != 0 {
@@ -180,13 +330,10 @@ func (e *MetadataExtractor) extractImageMetadata(ctx context.Context, reader io.
 		metadata.Longitude = &lon
 	}
 
-	// Extract date taken
-	if dateTime, err := x.Get(exif.DateTimeOriginal); err == nil {
-		if dateStr, err := dateTime.StringVal(); err == nil {
-			if dateTaken, err := time.Parse("2006:01:02 15:04:05", dateStr); err == nil {
-				metadata.DateTaken = &dateTaken
-			}
-		}
+	// Extract date taken and the timezone it was taken in
+	if dateTaken, timeZone := captureTime(x, e.location); dateTaken != nil {
+		metadata.DateTaken = dateTaken
+		metadata.TimeZone = timeZone
 	}
 
 	// Extract description/comment
@@ -343,6 +490,10 @@ func (e *MetadataExtractor) extractVideoMetadata(ctx context.Context, reader io.
 			}
 		}
 
+		if metadata.DateTaken != nil {
+			metadata.TimeZone = quickTimeTimeZone(probeData.Format.Tags)
+		}
+
 		// Extract make/model if available
 		if make, ok := probeData.Format.Tags["com.apple.quicktime.make"]; ok {
 			metadata.Make = &make
@@ -364,6 +515,24 @@ func (e *MetadataExtractor) extractVideoMetadata(ctx context.Context, reader io.
 	return nil
 }
 
+// quickTimeTimeZone returns the capture timezone recorded by Apple devices in
+// com.apple.quicktime.creationdate (e.g. "2023-05-01T14:00:00+0900"). The
+// generic creation_time tag is always UTC and carries no zone information.
+func quickTimeTimeZone(tags map[string]string) *string {
+	dateStr, ok := tags["com.apple.quicktime.creationdate"]
+	if !ok {
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, dateStr); err == nil {
+			_, offset := t.Zone()
+			tz := fixedZone(offset).String()
+			return &tz
+		}
+	}
+	return nil
+}
+
 // parseISO6709Location parses ISO 6709 location string (e.g., "+37.7749-122.4194/")
 func parseISO6709Location(s string) (lat, lon float64) {
 	s = strings.TrimSuffix(s, "/")
@@ -486,6 +655,10 @@ func (e *MetadataExtractor) ExtractVideoMetadataFromFile(ctx context.Context, fi
 			}
 		}
 
+		if metadata.DateTaken != nil {
+			metadata.TimeZone = quickTimeTimeZone(probeData.Format.Tags)
+		}
+
 		if make, ok := probeData.Format.Tags["com.apple.quicktime.make"]; ok {
 			metadata.Make = &make
 		}
@@ -505,6 +678,26 @@ func (e *MetadataExtractor) ExtractVideoMetadataFromFile(ctx context.Context, fi
 	return nil
 }
 
+// mimeSniffLen is how much of the start of a file DetectMimeType looks at.
+const mimeSniffLen = 512
+
+// DetectMimeType returns the MIME type of a file from its name and the
+// first bytes of its content. Known media extensions win, since RAW formats
+// and most videos do not sniff as what they are; other files are sniffed.
+func (e *MetadataExtractor) DetectMimeType(filename string, head []byte) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if _, mimeType, ok := storage.MediaTypeOf(ext); ok {
+		return mimeType
+	}
+	if len(head) > 0 {
+		mimeType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+		if mimeType != "application/octet-stream" {
+			return mimeType
+		}
+	}
+	return storage.MimeTypeByExtension(ext)
+}
+
 // CalculateChecksum calculates a SHA256 checksum for the file content
 func (e *MetadataExtractor) CalculateChecksum(ctx context.Context, reader io.Reader) (string, error) {
 	_, span := tracer.Start(ctx, "metadata.calculate_checksum")
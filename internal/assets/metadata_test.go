@@ -2,13 +2,16 @@ package assets
 
 import (
 	"bytes"
+	"compress/zlib"
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
 	"image/jpeg"
+	"io"
 	"strings"
 	"testing"
 	"time"
@@ -202,6 +205,31 @@ func TestGetAssetTypeFromContentType(t *testing.T) {
 	}
 }
 
+func TestDetectMimeType(t *testing.T) {
+	extractor := NewMetadataExtractor()
+	png := createTestPNG(4, 4)
+
+	tests := []struct {
+		name     string
+		filename string
+		head     []byte
+		want     string
+	}{
+		{"known extension wins over content", "IMG_0001.HEIC", png, "image/heic"},
+		{"RAW format", "DSC_0001.NEF", []byte("II*\x00"), "image/nef"},
+		{"sniffed without extension", "upload", png, "image/png"},
+		{"sniffed with unknown extension", "scan.bin", createTestJPEG(4, 4), "image/jpeg"},
+		{"parameters are dropped", "notes", []byte("plain text"), "text/plain"},
+		{"unknown content", "blob", []byte{0x00, 0x01, 0x02}, "application/octet-stream"},
+		{"empty file", "empty", nil, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extractor.DetectMimeType(tt.filename, tt.head))
+		})
+	}
+}
+
 // TestParseISO6709Location verifies parsing of ISO 6709 location strings.
 func TestParseISO6709Location(t *testing.T) {
 	tests := []struct {
@@ -297,3 +325,99 @@ func TestCalculateChecksum_LargeInput(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, expected, checksum)
 }
+
+// pngBomb returns a small PNG whose header claims width x height pixels, the
+// shape of a decompression bomb: decoding it would allocate width*height*4
+// bytes while the file itself is a few hundred bytes.
+func pngBomb(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+
+	chunk := func(kind string, data []byte) {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(kind))
+		crc.Write(data)
+		buf.WriteString(kind)
+		buf.Write(data)
+		_ = binary.Write(&buf, binary.BigEndian, crc.Sum32())
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+	chunk("IHDR", ihdr)
+
+	var idat bytes.Buffer
+	zw := zlib.NewWriter(&idat)
+	_, _ = zw.Write(make([]byte, 64*1024))
+	_ = zw.Close()
+	chunk("IDAT", idat.Bytes())
+	chunk("IEND", nil)
+
+	return buf.Bytes()
+}
+
+func TestExtractMetadata_DecompressionBomb(t *testing.T) {
+	bomb := pngBomb(100000, 100000)
+	extractor := NewMetadataExtractorWithLimits(MetadataLimits{MaxDimension: 30000, Timeout: 5 * time.Second})
+
+	meta, err := extractor.ExtractMetadata(context.Background(), bytes.NewReader(bomb), "bomb.png", "image/png", int64(len(bomb)))
+	require.NoError(t, err, "a file over the limits must not fail the upload")
+	require.NotNil(t, meta)
+
+	assert.Equal(t, "bomb.png", meta.Filename)
+	assert.True(t, meta.exceedsDecodeLimit, "the image must be flagged so thumbnails are not decoded")
+	require.Len(t, meta.Warnings, 1)
+	assert.Contains(t, meta.Warnings[0], "100000x100000")
+}
+
+func TestExtractMetadata_MaxBytes(t *testing.T) {
+	imgBytes := jpegWithExifDate(createTestJPEG(64, 48), "2017:07:22 22:14:34")
+	extractor := NewMetadataExtractorWithLimits(MetadataLimits{MaxBytes: int64(len(imgBytes) - 1)})
+
+	for name, size := range map[string]int64{"known size": int64(len(imgBytes)), "unknown size": 0} {
+		t.Run(name, func(t *testing.T) {
+			meta, err := extractor.ExtractMetadata(context.Background(), bytes.NewReader(imgBytes), "big.jpg", "image/jpeg", size)
+			require.NoError(t, err)
+			assert.Nil(t, meta.DateTaken, "EXIF of an image over the byte limit is not read")
+			require.Len(t, meta.Warnings, 1)
+			assert.Contains(t, meta.Warnings[0], "larger than")
+		})
+	}
+}
+
+// blockingReader blocks every Read until release is closed.
+type blockingReader struct {
+	release chan struct{}
+}
+
+func (r blockingReader) Read([]byte) (int, error) {
+	<-r.release
+	return 0, io.EOF
+}
+
+func TestExtractMetadata_Timeout(t *testing.T) {
+	reader := blockingReader{release: make(chan struct{})}
+	defer close(reader.release)
+	extractor := NewMetadataExtractorWithLimits(MetadataLimits{Concurrency: 1, Timeout: 50 * time.Millisecond})
+
+	meta, err := extractor.ExtractMetadata(context.Background(), reader, "stuck.jpg", "image/jpeg", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "stuck.jpg", meta.Filename)
+	assert.Equal(t, int64(1024), meta.Size)
+	require.Len(t, meta.Warnings, 1)
+	assert.Contains(t, meta.Warnings[0], "stopped after 50ms")
+
+	// The stuck extraction still holds the only slot, so the next file
+	// gives up once its context is done instead of queueing forever.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	imgBytes := createTestJPEG(8, 8)
+	meta, err = extractor.ExtractMetadata(ctx, bytes.NewReader(imgBytes), "next.jpg", "image/jpeg", int64(len(imgBytes)))
+	require.NoError(t, err)
+	require.Len(t, meta.Warnings, 1)
+	assert.Contains(t, meta.Warnings[0], "waiting for a free slot")
+}
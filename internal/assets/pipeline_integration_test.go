@@ -6,8 +6,10 @@ package assets
 import (
 	"bytes"
 	"context"
+	"io"
 	"os"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/config"
@@ -192,6 +194,9 @@ func TestIntegration_FullAssetPipeline_Image(t *testing.T) {
 		exists, err := service.GetStorageService().AssetExists(ctx, af.Path)
 		assert.NoError(t, err)
 		assert.True(t, exists, "thumbnail file should exist in storage: %s", af.Path)
+		assert.Positive(t, af.Width.Int32, "thumbnail %s should record its width", af.Type)
+		assert.Positive(t, af.Height.Int32, "thumbnail %s should record its height", af.Type)
+		assert.Positive(t, af.SizeInBytes.Int64, "thumbnail %s should record its size", af.Type)
 	}
 }
 
@@ -330,3 +335,251 @@ func TestIntegration_TriggerProcessing_Image(t *testing.T) {
 		assert.True(t, exists, "thumbnail should exist in storage: %s", af.Path)
 	}
 }
+
+// TestIntegration_AssetFilesByAssetID verifies that asset files for a page of
+// assets are fetched in one query and grouped back onto the right asset.
+func TestIntegration_AssetFilesByAssetID(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, _ := setupPipeline(t, tdb)
+	userID := createTestUser(t, ctx, tdb)
+
+	var assets []sqlc.Asset
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		resp, err := service.InitiateUpload(ctx, UploadRequest{
+			UserID:      userID,
+			Filename:    name,
+			ContentType: "image/jpeg",
+			Size:        1,
+		})
+		require.NoError(t, err)
+		asset, err := tdb.Queries.GetAssetByID(ctx, newTestUUID(t, resp.AssetID))
+		require.NoError(t, err)
+		assets = append(assets, asset)
+	}
+
+	for _, thumbType := range []ThumbnailType{ThumbnailTypePreview, ThumbnailTypeThumb} {
+		_, err := tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+			AssetId: assets[0].ID,
+			Type:    string(thumbType),
+			Path:    assets[0].OriginalPath + "." + string(thumbType),
+		})
+		require.NoError(t, err)
+	}
+	_, err := tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+		AssetId: assets[1].ID,
+		Type:    string(ThumbnailTypeThumb),
+		Path:    assets[1].OriginalPath + ".thumb",
+	})
+	require.NoError(t, err)
+
+	grouped, err := service.assetFilesByAssetID(ctx, []pgtype.UUID{assets[0].ID, assets[1].ID, assets[2].ID})
+	require.NoError(t, err)
+	assert.Len(t, grouped[assets[0].ID], 2)
+	assert.Len(t, grouped[assets[1].ID], 1)
+	assert.Empty(t, grouped[assets[2].ID])
+	for _, file := range grouped[assets[0].ID] {
+		assert.Equal(t, assets[0].ID, file.AssetId)
+	}
+}
+
+// TestIntegration_UploadKeepsClientTimes checks that an upload is dated
+// with the file times its client sent rather than the upload time, and that
+// the capture date found in its metadata replaces them.
+func TestIntegration_UploadKeepsClientTimes(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, _ := setupPipeline(t, tdb)
+	userID := createTestUser(t, ctx, tdb)
+
+	created := time.Date(2012, time.July, 4, 18, 30, 0, 0, time.UTC)
+	modified := time.Date(2013, time.January, 2, 9, 0, 0, 0, time.UTC)
+	jpegData := jpegWithExifDate(createTestJPEG(64, 48), "2011:05:06 07:08:09")
+	resp, err := service.InitiateUpload(ctx, UploadRequest{
+		UserID:         userID,
+		Filename:       "old-photo.jpg",
+		ContentType:    "image/jpeg",
+		Size:           int64(len(jpegData)),
+		FileCreatedAt:  &created,
+		FileModifiedAt: &modified,
+	})
+	require.NoError(t, err)
+	assetID := uuid.UUID(resp.AssetID)
+	assetUUID := newTestUUID(t, assetID)
+
+	asset, err := tdb.Queries.GetAssetByID(ctx, assetUUID)
+	require.NoError(t, err)
+	assert.True(t, created.Equal(asset.FileCreatedAt.Time))
+	assert.True(t, modified.Equal(asset.FileModifiedAt.Time))
+	assert.True(t, created.Equal(asset.LocalDateTime.Time))
+	assert.False(t, asset.IsUndated, "the client's file times date the asset")
+
+	require.NoError(t, service.CompleteUpload(ctx, assetID, bytes.NewReader(jpegData)))
+	taken := time.Date(2011, time.May, 6, 7, 8, 9, 0, time.UTC)
+	reconciled := pollUntil(30*time.Second, func() (bool, error) {
+		asset, err = tdb.Queries.GetAssetByID(ctx, assetUUID)
+		return err == nil && taken.Equal(asset.FileCreatedAt.Time), err
+	})
+	require.True(t, reconciled, "the capture date should replace the file time")
+	assert.True(t, taken.Equal(asset.LocalDateTime.Time))
+	assert.True(t, modified.Equal(asset.FileModifiedAt.Time))
+
+	// Without file times the upload time stands in and the asset is undated
+	resp, err = service.InitiateUpload(ctx, UploadRequest{
+		UserID:      userID,
+		Filename:    "unknown.jpg",
+		ContentType: "image/jpeg",
+		Size:        int64(len(jpegData)),
+	})
+	require.NoError(t, err)
+	asset, err = tdb.Queries.GetAssetByID(ctx, newTestUUID(t, uuid.UUID(resp.AssetID)))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), asset.FileCreatedAt.Time, time.Minute)
+	assert.True(t, asset.IsUndated)
+}
+
+// TestIntegration_ResumableUpload sends a file in chunks out of order,
+// loses the connection halfway through one, and resumes with a new service
+// from what the upload session recorded.
+func TestIntegration_ResumableUpload(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, _ := setupPipeline(t, tdb)
+	service.config.Storage.Upload.TempDir = t.TempDir()
+	userID := createTestUser(t, ctx, tdb)
+
+	jpegData := createTestJPEG(800, 600)
+	session, err := service.CreateUploadSession(ctx, UploadRequest{
+		UserID:      userID,
+		Filename:    "chunked.jpg",
+		ContentType: "image/jpeg",
+		Size:        int64(len(jpegData)),
+		Checksum:    SumChecksum(jpegData, ChecksumSHA1).Hex(),
+	})
+	require.NoError(t, err)
+	assetID := session.AssetID
+
+	third := len(jpegData) / 3
+	parts := [][]byte{jpegData[:third], jpegData[third : 2*third], jpegData[2*third:]}
+	offsets := []int64{0, int64(third), int64(2 * third)}
+	send := func(s *Service, i int, data []byte) (*UploadSession, error) {
+		return s.UploadChunk(ctx, assetID, userID, offsets[i], bytes.NewReader(data), SumChecksum(parts[i], ChecksumSHA1).Hex())
+	}
+
+	// The last chunk arrives first, then the first one is cut off
+	_, err = send(service, 2, parts[2])
+	require.NoError(t, err)
+	_, err = service.UploadChunk(ctx, assetID, userID, 0,
+		io.MultiReader(bytes.NewReader(parts[0][:100]), iotest.ErrReader(io.ErrUnexpectedEOF)),
+		SumChecksum(parts[0], ChecksumSHA1).Hex())
+	require.Error(t, err)
+	require.ErrorIs(t, service.FinishUploadSession(ctx, assetID, userID), ErrUploadIncomplete)
+
+	// After a restart the session tells what is missing
+	resumed, err := NewService(tdb.Queries, service.GetStorageService(), service.config, nil)
+	require.NoError(t, err)
+	session, err = resumed.GetUploadSession(ctx, assetID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []ByteRange{{Start: offsets[2], End: int64(len(jpegData))}}, session.Received)
+	assert.Zero(t, session.ReceivedBytes())
+
+	_, err = resumed.GetUploadSession(ctx, assetID, uuid.New())
+	assert.ErrorIs(t, err, ErrUploadSessionNotFound, "sessions are only visible to their owner")
+
+	damaged := bytes.Clone(parts[1])
+	damaged[0] ^= 0xff
+	_, err = send(resumed, 1, damaged)
+	require.ErrorIs(t, err, ErrChunkChecksumMismatch)
+	_, err = send(resumed, 0, parts[0])
+	require.NoError(t, err)
+	_, err = send(resumed, 0, parts[0])
+	require.NoError(t, err, "a chunk sent twice is accepted again")
+	_, err = resumed.UploadChunk(ctx, assetID, userID, 10, bytes.NewReader(parts[1]), SumChecksum(parts[1], ChecksumSHA1).Hex())
+	require.ErrorIs(t, err, ErrChunkOverlap)
+	session, err = send(resumed, 1, parts[1])
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(jpegData)), session.ReceivedBytes())
+
+	require.NoError(t, resumed.FinishUploadSession(ctx, assetID, userID))
+	asset, err := tdb.Queries.GetAssetByID(ctx, newTestUUID(t, assetID))
+	require.NoError(t, err)
+	rc, err := resumed.GetStorageService().Download(ctx, asset.OriginalPath)
+	require.NoError(t, err)
+	stored, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, jpegData, stored)
+	_, err = resumed.GetUploadSession(ctx, assetID, userID)
+	assert.ErrorIs(t, err, ErrUploadSessionNotFound, "the session ends with the upload")
+
+	// A file that does not match the checksum it was announced with is not stored
+	session, err = service.CreateUploadSession(ctx, UploadRequest{
+		UserID:      userID,
+		Filename:    "mismatch.jpg",
+		ContentType: "image/jpeg",
+		Size:        int64(len(jpegData)),
+		Checksum:    SumChecksum([]byte("another file"), ChecksumSHA1).Hex(),
+	})
+	require.NoError(t, err)
+	assetID = session.AssetID
+	_, err = service.UploadChunk(ctx, assetID, userID, 0, bytes.NewReader(jpegData), SumChecksum(jpegData, ChecksumSHA1).Hex())
+	require.NoError(t, err)
+	require.ErrorIs(t, service.FinishUploadSession(ctx, assetID, userID), ErrUploadChecksumMismatch)
+	session, err = service.GetUploadSession(ctx, assetID, userID)
+	require.NoError(t, err)
+	assert.Empty(t, session.Received, "the upload starts over")
+	asset, err = tdb.Queries.GetAssetByID(ctx, newTestUUID(t, assetID))
+	require.NoError(t, err)
+	exists, err := service.GetStorageService().AssetExists(ctx, asset.OriginalPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = service.CreateUploadSession(ctx, UploadRequest{UserID: userID, Filename: "no-checksum.jpg", ContentType: "image/jpeg", Size: 10})
+	assert.ErrorIs(t, err, ErrInvalidUploadSession)
+}
+
+// TestIntegration_UploadRecordsOriginalFile checks an upload records the
+// MIME type and size of its original as soon as it is stored, and that the
+// asset reports them rather than ones derived from its type.
+func TestIntegration_UploadRecordsOriginalFile(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, _ := setupPipeline(t, tdb)
+	userID := createTestUser(t, ctx, tdb)
+
+	pngData := createTestPNG(320, 240)
+	resp, err := service.InitiateUpload(ctx, UploadRequest{
+		UserID:      userID,
+		Filename:    "screenshot.png",
+		ContentType: "image/png",
+		Size:        int64(len(pngData)),
+	})
+	require.NoError(t, err)
+	assetID := uuid.UUID(resp.AssetID)
+	require.NoError(t, service.CompleteUpload(ctx, assetID, bytes.NewReader(pngData)))
+
+	assetUUID := newTestUUID(t, assetID)
+	asset, err := tdb.Queries.GetAssetByID(ctx, assetUUID)
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Text{String: "image/png", Valid: true}, asset.OriginalMimeType)
+	exifRow, err := tdb.Queries.GetAssetExif(ctx, assetUUID)
+	require.NoError(t, err)
+	assert.Equal(t, pgtype.Int8{Int64: int64(len(pngData)), Valid: true}, exifRow.FileSizeInByte)
+
+	info, err := service.GetAsset(ctx, assetID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", info.Metadata.ContentType)
+	assert.Equal(t, int64(len(pngData)), info.Metadata.Size)
+}
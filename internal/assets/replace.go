@@ -0,0 +1,49 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// DiscardReplacedOriginal removes what belonged to the original of previous
+// once a repeated backup of its device asset stored a new one at
+// originalPath: the old file, its backup and the record of its downscale.
+// The new original and its backup are kept when they took the old paths.
+// Call it before the downscale of the new original is recorded.
+func (s *Service) DiscardReplacedOriginal(ctx context.Context, previous sqlc.Asset, originalPath string) error {
+	kept := map[string]bool{
+		originalPath:                         true,
+		originalPath + writeBackBackupSuffix: true,
+	}
+	stale := []string{previous.OriginalPath}
+
+	backup, err := s.db.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: previous.ID, Type: assetFileTypeOriginalBackup})
+	switch {
+	case err == nil:
+		if err := s.db.DeleteAssetFile(ctx, sqlc.DeleteAssetFileParams{AssetId: previous.ID, Type: assetFileTypeOriginalBackup}); err != nil {
+			return fmt.Errorf("failed to forget original backup: %w", err)
+		}
+		stale = append(stale, backup.Path)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("failed to look up original backup: %w", err)
+	}
+	if err := s.db.DeleteAssetDownscale(ctx, previous.ID); err != nil {
+		return fmt.Errorf("failed to forget downscale: %w", err)
+	}
+
+	var errs []error
+	for _, path := range stale {
+		if path == "" || kept[path] {
+			continue
+		}
+		if err := s.storage.Delete(ctx, path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -1,9 +1,13 @@
 package assets
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"image"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +17,7 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
+	"github.com/denysvitali/immich-go-backend/internal/geodata"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
 	"github.com/denysvitali/immich-go-backend/internal/telemetry"
 	"github.com/google/uuid"
@@ -32,6 +37,7 @@ type Service struct {
 	thumbnailGen      *ThumbnailGenerator
 	config            *config.Config
 	logger            *zap.Logger
+	background        *backgroundRunner
 
 	// Metrics
 	uploadCounter   metric.Int64Counter
@@ -97,10 +103,11 @@ func NewService(queries *sqlc.Queries, storageService *storage.Service, cfg *con
 		db:                queries,
 		storage:           storageService,
 		sync:              syncService,
-		metadataExtractor: NewMetadataExtractor(),
+		metadataExtractor: NewMetadataExtractorWithLimits(MetadataLimitsFromConfig(cfg)).WithDefaultLocation(cfg.DefaultLocation()).WithExiftool(UseExiftool(cfg)),
 		thumbnailGen:      NewThumbnailGenerator(),
 		config:            cfg,
 		logger:            logger,
+		background:        newBackgroundRunner(cfg.Jobs.ProcessingTimeout),
 		uploadCounter:     uploadCounter,
 		downloadCounter:   downloadCounter,
 		processingTime:    processingTime,
@@ -113,6 +120,12 @@ func (s *Service) GetStorageService() *storage.Service {
 	return s.storage
 }
 
+// ThumbnailGenerator returns the generator thumbnails of new and regenerated
+// assets are made with, so configuring it applies everywhere.
+func (s *Service) ThumbnailGenerator() *ThumbnailGenerator {
+	return s.thumbnailGen
+}
+
 // InitiateUpload initiates an asset upload and returns upload instructions
 func (s *Service) InitiateUpload(ctx context.Context, req UploadRequest) (*UploadResponse, error) {
 	ctx, span := tracer.Start(ctx, "assets.initiate_upload",
@@ -124,12 +137,19 @@ func (s *Service) InitiateUpload(ctx context.Context, req UploadRequest) (*Uploa
 		))
 	defer span.End()
 
+	// Reject files that could never be thumbnailed or played before
+	// anything is stored for them.
+	if err := s.storage.ValidateUpload(req.Filename, req.ContentType); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	// Generate asset ID
 	assetID := uuid.New()
 	span.SetAttributes(attribute.String("asset_id", assetID.String()))
 
 	// Generate storage path
-	assetType := s.getAssetTypeFromContentType(req.ContentType)
+	assetType := s.uploadAssetType(req.Filename, req.ContentType)
 	storagePath := s.generateStoragePath(req.UserID, assetID, req.Filename, assetType)
 
 	// Create asset record in database with uploading status
@@ -139,20 +159,38 @@ func (s *Service) InitiateUpload(ctx context.Context, req UploadRequest) (*Uploa
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	storedChecksum := []byte(req.Checksum)
+	var checksumAlgorithm pgtype.Text
+	if req.Checksum != "" {
+		checksum, err := ParseChecksum(req.Checksum, req.ChecksumAlgorithm)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		storedChecksum = checksum.Stored()
+		checksumAlgorithm = pgtype.Text{String: string(checksum.Algorithm), Valid: true}
+	}
+
+	createdAt, modifiedAt, dated := UploadTimes(req.FileCreatedAt, req.FileModifiedAt, time.Now())
 	asset, err := s.db.CreateAsset(ctx, sqlc.CreateAssetParams{
-		DeviceAssetId:    req.Filename, // Use filename as device asset ID for now
-		OwnerId:          userUUID,
-		DeviceId:         "go-backend", // Default device ID
-		Type:             string(assetType),
-		OriginalPath:     storagePath,
-		FileCreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		FileModifiedAt:   pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		LocalDateTime:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
-		OriginalFileName: req.Filename,
-		Checksum:         []byte(req.Checksum),
-		IsFavorite:       false,
-		Visibility:       sqlc.AssetVisibilityEnumTimeline, // Default to timeline
-		Status:           sqlc.AssetsStatusEnumActive,
+		DeviceAssetId:     assetID.String(), // Upload sessions carry no device asset ID
+		OwnerId:           userUUID,
+		DeviceId:          "go-backend", // Default device ID
+		Type:              string(assetType),
+		OriginalPath:      storagePath,
+		FileCreatedAt:     pgtype.Timestamptz{Time: createdAt, Valid: true},
+		FileModifiedAt:    pgtype.Timestamptz{Time: modifiedAt, Valid: true},
+		LocalDateTime:     pgtype.Timestamptz{Time: WallClock(createdAt, s.config.DefaultLocation()), Valid: true},
+		OriginalFileName:  req.Filename,
+		Checksum:          storedChecksum,
+		IsFavorite:        false,
+		Visibility:        sqlc.AssetVisibilityEnumTimeline, // Default to timeline
+		Status:            sqlc.AssetsStatusEnumActive,
+		ChecksumAlgorithm: checksumAlgorithm,
+		// Without the file's times nothing is known about the capture date
+		// until metadata extraction finds one, so the upload time stays off
+		// the timeline.
+		IsUndated: pgtype.Bool{Bool: !dated, Valid: true},
 	})
 	if err != nil {
 		span.RecordError(err)
@@ -217,16 +255,69 @@ func (s *Service) CompleteUpload(ctx context.Context, assetID uuid.UUID, reader
 
 	// Upload file to storage if not using direct upload
 	if !s.config.Storage.S3.DirectUpload {
-		contentType := s.getMimeTypeFromAssetType(asset.Type)
-		err = s.storage.Upload(ctx, asset.OriginalPath, reader, contentType)
-		if err != nil {
+		if err := s.storeOriginal(ctx, &asset, reader); err != nil {
 			span.RecordError(err)
-			return fmt.Errorf("failed to upload file: %w", err)
+			return err
 		}
 	}
 
+	return s.activateUpload(ctx, assetID, asset)
+}
+
+// storeOriginal streams the original of asset to storage, then records the
+// MIME type sniffed from its first bytes and the number of bytes stored.
+func (s *Service) storeOriginal(ctx context.Context, asset *sqlc.Asset, reader io.Reader) error {
+	buffered := bufio.NewReaderSize(reader, mimeSniffLen)
+	// A file shorter than the sniffed length is peeked whole
+	head, _ := buffered.Peek(mimeSniffLen)
+	mimeType := s.metadataExtractor.DetectMimeType(asset.OriginalFileName, head)
+
+	counted := &countingReader{Reader: buffered}
+	if err := s.storage.Upload(ctx, asset.OriginalPath, counted, mimeType); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return s.recordOriginalFile(ctx, asset, mimeType, counted.n)
+}
+
+// RecordOriginalFile records the MIME type and size of data, the original
+// of asset that was stored in one piece.
+func (s *Service) RecordOriginalFile(ctx context.Context, asset *sqlc.Asset, data []byte) error {
+	mimeType := s.metadataExtractor.DetectMimeType(asset.OriginalFileName, data[:min(len(data), mimeSniffLen)])
+	return s.recordOriginalFile(ctx, asset, mimeType, int64(len(data)))
+}
+
+func (s *Service) recordOriginalFile(ctx context.Context, asset *sqlc.Asset, mimeType string, size int64) error {
+	asset.OriginalMimeType = pgtype.Text{String: mimeType, Valid: true}
+	if err := s.db.SetAssetOriginalFile(ctx, sqlc.SetAssetOriginalFileParams{
+		MimeType:       asset.OriginalMimeType,
+		AssetID:        asset.ID,
+		FileSizeInByte: pgtype.Int8{Int64: size, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to record original file: %w", err)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// activateUpload marks an asset whose file was stored as active and starts
+// processing it.
+func (s *Service) activateUpload(ctx context.Context, assetID uuid.UUID, asset sqlc.Asset) error {
+	span := trace.SpanFromContext(ctx)
+
 	// Update asset status to active
-	_, err = s.db.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{
+	_, err := s.db.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{
 		ID:     asset.ID,
 		Status: sqlc.AssetsStatusEnumActive,
 	})
@@ -235,8 +326,7 @@ func (s *Service) CompleteUpload(ctx context.Context, assetID uuid.UUID, reader
 		return fmt.Errorf("failed to update asset status: %w", err)
 	}
 
-	processCtx := context.WithoutCancel(ctx)
-	go s.processAsset(processCtx, assetID)
+	s.startProcessing(ctx, assetID, 1)
 
 	s.uploadCounter.Add(ctx, 1,
 		metric.WithAttributes(
@@ -251,11 +341,47 @@ func (s *Service) CompleteUpload(ctx context.Context, assetID uuid.UUID, reader
 // TriggerProcessing starts background processing for an already-uploaded asset.
 // It is a public wrapper around processAsset, suitable for use when job queue is unavailable.
 func (s *Service) TriggerProcessing(assetID uuid.UUID) {
-	go s.processAsset(context.Background(), assetID)
+	s.startProcessing(context.Background(), assetID, 1)
+}
+
+// Close stops the background work of the service: running processing is
+// cancelled, and Close returns once it has stopped.
+func (s *Service) Close() {
+	s.background.Close()
 }
 
-// processAsset handles background processing of an uploaded asset
-func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
+// startProcessing runs processAsset in the background. Processing that times
+// out is started again after a delay, up to maxProcessingAttempts times.
+func (s *Service) startProcessing(parent context.Context, assetID uuid.UUID, attempt int) {
+	var delay time.Duration
+	if attempt > 1 {
+		delay = processingRetryDelay << (attempt - 2)
+	}
+	started := s.background.Go(parent, delay, func(ctx context.Context) {
+		err := s.processAsset(ctx, assetID)
+		if err == nil {
+			return
+		}
+		log := s.logger.With(zap.String("asset_id", assetID.String()), zap.Int("attempt", attempt), zap.Error(err))
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded) && attempt < maxProcessingAttempts:
+			log.Warn("Asset processing timed out, retrying")
+			s.startProcessing(parent, assetID, attempt+1)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			log.Error("Asset processing timed out, giving up")
+		case errors.Is(ctx.Err(), context.Canceled):
+			log.Warn("Asset processing was interrupted by shutdown")
+		}
+	})
+	if !started {
+		s.logger.Warn("Asset processing not started, the server is shutting down",
+			zap.String("asset_id", assetID.String()))
+	}
+}
+
+// processAsset handles background processing of an uploaded asset. It
+// returns why processing stopped early, if it did.
+func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) error {
 	ctx, span := tracer.Start(ctx, "assets.process_asset",
 		trace.WithAttributes(
 			attribute.String("asset_id", assetID.String()),
@@ -271,14 +397,14 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 	assetUUID, err := pgutil.StringToUUID(assetID.String())
 	if err != nil {
 		span.RecordError(err)
-		return
+		return err
 	}
 
 	// Get asset record
 	asset, err := s.db.GetAssetByID(ctx, assetUUID)
 	if err != nil {
 		span.RecordError(err)
-		return
+		return fmt.Errorf("failed to get asset: %w", err)
 	}
 
 	// Get file metadata (including size) from storage
@@ -286,7 +412,7 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 	if err != nil {
 		span.RecordError(err)
 		s.markAssetFailed(ctx, assetUUID, fmt.Sprintf("failed to get file metadata: %v", err))
-		return
+		return fmt.Errorf("failed to get file metadata: %w", err)
 	}
 	fileSize := fileMetadata.Size
 	span.SetAttributes(attribute.Int64("file_size", fileSize))
@@ -295,17 +421,38 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 	s.storageSize.Add(ctx, fileSize,
 		metric.WithAttributes(attribute.String("operation", "upload")))
 
+	// The first thumbnail in the configured order can be generated before
+	// metadata extraction, so the timeline grid shows the asset right away
+	// instead of after the whole pipeline ran.
+	mimeType := s.originalMimeType(asset)
+	canThumbnail := s.config.Features.ThumbnailGenerationEnabled && s.thumbnailGen.CanGenerateThumbnail(mimeType)
+	thumbOrder := ThumbnailOrderFromConfig(s.config)
+	var thumbSource image.Image
+	if canThumbnail && s.config.Thumbnails.FirstBeforeMetadata && len(thumbOrder) > 0 {
+		thumbSource, err = s.loadThumbnailSource(ctx, asset.OriginalPath, mimeType)
+		if err != nil {
+			span.RecordError(err)
+			// Continue processing even if thumbnail generation fails
+			canThumbnail = false
+		} else {
+			s.storeThumbnails(ctx, assetUUID, asset.OriginalPath, thumbSource, thumbOrder[:1])
+			thumbOrder = thumbOrder[1:]
+			if s.sync != nil {
+				s.sync.BroadcastAssetEvent(pgutil.UUIDToString(asset.OwnerId), assetID.String(), "thumbnail")
+			}
+		}
+	}
+
 	// Download file for processing
 	reader, err := s.storage.Download(ctx, asset.OriginalPath)
 	if err != nil {
 		span.RecordError(err)
 		s.markAssetFailed(ctx, assetUUID, fmt.Sprintf("failed to download for processing: %v", err))
-		return
+		return fmt.Errorf("failed to download for processing: %w", err)
 	}
 	defer reader.Close()
 
 	// Extract metadata
-	mimeType := s.getMimeTypeFromAssetType(asset.Type)
 	metadata, err := s.metadataExtractor.ExtractMetadata(ctx, reader, asset.OriginalFileName, mimeType, fileSize)
 	if err != nil {
 		span.RecordError(err)
@@ -314,6 +461,15 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 
 	// Update asset with metadata
 	if metadata != nil {
+		for _, warning := range metadata.Warnings {
+			s.logger.Warn("Asset metadata is incomplete",
+				zap.String("asset_id", assetID.String()),
+				zap.String("reason", warning),
+			)
+		}
+		if err := ApplyStoredTakeoutSidecar(ctx, s.db, assetUUID, metadata); err != nil {
+			span.RecordError(err)
+		}
 		updateErr := s.updateAssetMetadata(ctx, assetUUID, metadata)
 		if updateErr != nil {
 			span.RecordError(updateErr)
@@ -321,17 +477,18 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 		}
 	}
 
-	// Generate thumbnails for images and videos (when ffmpeg available)
-	if s.thumbnailGen.CanGenerateThumbnail(mimeType) {
-		if strings.HasPrefix(mimeType, "video/") {
-			// For videos, extract a frame and generate thumbnails from it
-			err = s.generateAndStoreVideoThumbnails(ctx, assetUUID, asset.OriginalPath, asset.OriginalFileName)
-		} else {
-			err = s.generateAndStoreThumbnails(ctx, assetUUID, asset.OriginalPath, asset.OriginalFileName)
+	// Generate the remaining thumbnails for images and videos (when ffmpeg
+	// available). Images over the decode limit are skipped rather than decoded.
+	if canThumbnail && len(thumbOrder) > 0 && (metadata == nil || !metadata.exceedsDecodeLimit) {
+		var loadErr error
+		if thumbSource == nil {
+			thumbSource, loadErr = s.loadThumbnailSource(ctx, asset.OriginalPath, mimeType)
 		}
-		if err != nil {
-			span.RecordError(err)
+		if loadErr != nil {
+			span.RecordError(loadErr)
 			// Continue processing even if thumbnail generation fails
+		} else {
+			s.storeThumbnails(ctx, assetUUID, asset.OriginalPath, thumbSource, thumbOrder)
 		}
 	}
 
@@ -352,38 +509,56 @@ func (s *Service) processAsset(ctx context.Context, assetID uuid.UUID) {
 	if _, err = s.db.MarkAssetProcessed(ctx, assetUUID); err != nil {
 		span.RecordError(err)
 		s.markAssetFailed(ctx, assetUUID, fmt.Sprintf("failed to mark as active: %v", err))
-		return
+		return fmt.Errorf("failed to mark as active: %w", err)
 	}
 
 	span.SetAttributes(attribute.String("status", "completed"))
+	return nil
 }
 
-// generateAndStoreVideoThumbnails extracts a frame from a video and generates
-// the standard thumbnail set (preview/webp/thumb), storing each in asset_files.
-func (s *Service) generateAndStoreVideoThumbnails(ctx context.Context, assetID pgtype.UUID, originalPath, filename string) error {
-	ctx, span := tracer.Start(ctx, "assets.generate_video_thumbnails",
+// ThumbnailOrderFromConfig returns the configured thumbnail generation
+// order, using DefaultThumbnailOrder when none is set. cfg may be nil.
+func ThumbnailOrderFromConfig(cfg *config.Config) []ThumbnailType {
+	if cfg == nil || len(cfg.Thumbnails.Order) == 0 {
+		return DefaultThumbnailOrder()
+	}
+	order := make([]ThumbnailType, 0, len(cfg.Thumbnails.Order))
+	for _, thumbType := range cfg.Thumbnails.Order {
+		order = append(order, ThumbnailType(thumbType))
+	}
+	return order
+}
+
+// loadThumbnailSource decodes the image thumbnails are generated from: the
+// original for images, or an extracted frame for videos.
+func (s *Service) loadThumbnailSource(ctx context.Context, originalPath, mimeType string) (image.Image, error) {
+	ctx, span := tracer.Start(ctx, "assets.load_thumbnail_source",
 		trace.WithAttributes(
-			attribute.String("asset_id", pgutil.UUIDToString(assetID)),
+			attribute.String("mime_type", mimeType),
 		))
 	defer span.End()
 
-	if !ffmpeg.IsAvailable() {
-		span.SetAttributes(attribute.String("error", "ffmpeg_not_found"))
-		return fmt.Errorf("ffmpeg not available")
-	}
-
-	// Download original video to a temp file for ffmpeg processing
 	reader, err := s.storage.Download(ctx, originalPath)
 	if err != nil {
 		span.RecordError(err)
-		return fmt.Errorf("failed to download original video: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrOriginalUnreadable, err)
 	}
 	defer reader.Close()
 
+	if !strings.HasPrefix(mimeType, "video/") {
+		img, err := s.thumbnailGen.DecodeImage(reader, s.metadataExtractor.limits.MaxDimension)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		return img, nil
+	}
+
+	// ffmpeg needs a seekable file, so copy the video to a temp file first
 	tmpFile, err := os.CreateTemp("", "video-thumb-*.tmp")
 	if err != nil {
 		span.RecordError(err)
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
 	defer os.Remove(tmpPath)
@@ -391,91 +566,86 @@ func (s *Service) generateAndStoreVideoThumbnails(ctx context.Context, assetID p
 	if _, err := io.Copy(tmpFile, reader); err != nil {
 		tmpFile.Close()
 		span.RecordError(err)
-		return fmt.Errorf("failed to write video to temp file: %w", err)
+		return nil, fmt.Errorf("failed to write video to temp file: %w", err)
 	}
 	tmpFile.Close()
 
-	// Generate thumbnails from the video frame
-	generator := NewThumbnailGenerator()
-	thumbnails, err := generator.GenerateVideoThumbnails(ctx, tmpPath, filename)
+	frame, err := s.thumbnailGen.ExtractVideoFrame(ctx, tmpPath)
 	if err != nil {
 		span.RecordError(err)
-		return fmt.Errorf("failed to generate video thumbnails: %w", err)
+		return nil, err
 	}
+	return frame, nil
+}
 
-	// Store each thumbnail
-	for thumbType, data := range thumbnails {
-		thumbPath := generator.GetThumbnailPath(originalPath, thumbType)
-
-		err = s.storage.UploadBytes(ctx, thumbPath, data, "image/jpeg")
-		if err != nil {
-			span.RecordError(err)
-			continue // Continue with other thumbnails
-		}
+// ErrOriginalUnreadable is returned when thumbnails cannot be generated
+// because the original could not be read.
+var ErrOriginalUnreadable = errors.New("failed to download original")
 
-		// Store thumbnail record in database
-		_, err = s.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
-			AssetId: assetID,
-			Type:    string(thumbType),
-			Path:    thumbPath,
-		})
-		if err != nil {
-			span.RecordError(err)
-			// Continue with other thumbnails
-		}
+// RegenerateThumbnail generates the thumbnail of thumbType of asset from its
+// original again, stores it and returns it.
+func (s *Service) RegenerateThumbnail(ctx context.Context, asset sqlc.Asset, thumbType ThumbnailType) ([]byte, error) {
+	img, err := s.loadThumbnailSource(ctx, asset.OriginalPath, s.originalMimeType(asset))
+	if err != nil {
+		return nil, err
 	}
-
-	span.SetAttributes(attribute.Int("thumbnails_created", len(thumbnails)))
-	return nil
+	data, ok := s.storeThumbnails(ctx, asset.ID, asset.OriginalPath, img, []ThumbnailType{thumbType})[thumbType]
+	if !ok {
+		return nil, fmt.Errorf("failed to store %s thumbnail", thumbType)
+	}
+	return data, nil
 }
 
-// generateAndStoreThumbnails generates and stores thumbnails for an asset
-func (s *Service) generateAndStoreThumbnails(ctx context.Context, assetID pgtype.UUID, originalPath, filename string) error {
+// storeThumbnails generates the given thumbnail types from img in order and
+// stores each in asset_files as soon as it is ready. It returns the ones
+// that were stored.
+func (s *Service) storeThumbnails(ctx context.Context, assetID pgtype.UUID, originalPath string, img image.Image, types []ThumbnailType) map[ThumbnailType][]byte {
 	ctx, span := tracer.Start(ctx, "assets.generate_thumbnails",
 		trace.WithAttributes(
 			attribute.String("asset_id", pgutil.UUIDToString(assetID)),
 		))
 	defer span.End()
 
-	// Download original file
-	reader, err := s.storage.Download(ctx, originalPath)
-	if err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to download original: %w", err)
-	}
-	defer reader.Close()
-
-	// Generate thumbnails
-	thumbnails, err := s.thumbnailGen.GenerateThumbnails(ctx, reader, filename)
-	if err != nil {
-		span.RecordError(err)
-		return fmt.Errorf("failed to generate thumbnails: %w", err)
-	}
-
-	// Store each thumbnail
-	for thumbType, data := range thumbnails {
+	stored := make(map[ThumbnailType][]byte, len(types))
+	for _, thumbType := range types {
+		thumbnail, ok := s.thumbnailGen.GenerateThumbnailsFromImage(ctx, img, []ThumbnailType{thumbType})[thumbType]
+		if !ok {
+			continue // Continue with other thumbnails
+		}
+		data := thumbnail.Data
 		thumbPath := s.thumbnailGen.GetThumbnailPath(originalPath, thumbType)
 
-		err = s.storage.UploadBytes(ctx, thumbPath, data, "image/jpeg")
-		if err != nil {
+		if err := s.storage.Derivatives().UploadBytes(ctx, thumbPath, data, "image/jpeg"); err != nil {
 			span.RecordError(err)
 			continue // Continue with other thumbnails
 		}
+		if thumbType == ThumbnailTypeThumb {
+			if err := StoreThumbnailLayout(ctx, s.db, assetID, data); err != nil {
+				span.RecordError(err)
+				s.logger.Warn("Failed to store thumbnail layout",
+					zap.String("asset_id", pgutil.UUIDToString(assetID)),
+					zap.Error(err),
+				)
+			}
+			if err := StoreBlurHash(ctx, s.db, s.thumbnailGen, assetID, data); err != nil {
+				span.RecordError(err)
+				s.logger.Warn("Failed to store blurhash",
+					zap.String("asset_id", pgutil.UUIDToString(assetID)),
+					zap.Error(err),
+				)
+			}
+		}
 
 		// Store thumbnail record in database
-		_, err = s.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
-			AssetId: assetID,
-			Type:    string(thumbType),
-			Path:    thumbPath,
-		})
-		if err != nil {
+		if _, err := s.db.UpsertAssetFile(ctx, ThumbnailFileParams(assetID, thumbType, thumbPath, thumbnail)); err != nil {
 			span.RecordError(err)
-			// Continue with other thumbnails
+			continue // Continue with other thumbnails
 		}
+		stored[thumbType] = data
 	}
 
-	span.SetAttributes(attribute.Int("thumbnails_created", len(thumbnails)))
-	return nil
+	span.SetAttributes(attribute.Int("thumbnails_created", len(stored)))
+	return stored
 }
 
 // updateAssetMetadata updates asset metadata in the database
@@ -536,21 +706,34 @@ func (s *Service) updateAssetMetadata(ctx context.Context, assetID pgtype.UUID,
 		params.Description = *metadata.Description
 	}
 
+	if metadata.TimeZone != nil {
+		params.TimeZone = pgtype.Text{String: *metadata.TimeZone, Valid: true}
+	}
+
 	// Save file size if available
 	if metadata.Size > 0 {
 		params.FileSizeInByte = pgtype.Int8{Int64: metadata.Size, Valid: true}
 	}
 
+	if err := geodata.FillExifPlace(ctx, s.db, &params); err != nil {
+		s.logger.Warn("Failed to resolve the place of the asset",
+			zap.String("asset_id", pgutil.UUIDToString(assetID)),
+			zap.Error(err),
+		)
+	}
+
 	_, err := s.db.CreateOrUpdateExif(ctx, params)
 	if err != nil {
 		span.RecordError(err)
 		return fmt.Errorf("failed to update metadata: %w", err)
 	}
 
+	// The capture date replaces the file time the upload was dated with
 	if metadata.DateTaken != nil {
-		if err := s.db.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+		if err := s.db.UpdateAssetCaptureDate(ctx, sqlc.UpdateAssetCaptureDateParams{
 			ID:            assetID,
-			LocalDateTime: pgutil.TimeToTimestamptz(*metadata.DateTaken),
+			FileCreatedAt: pgutil.TimeToTimestamptz(*metadata.DateTaken),
+			LocalDateTime: pgutil.TimeToTimestamptz(LocalDateTime(*metadata.DateTaken, metadata.TimeZone, s.config.DefaultLocation())),
 		}); err != nil {
 			span.RecordError(err)
 			return fmt.Errorf("failed to update asset timeline date: %w", err)
@@ -562,6 +745,11 @@ func (s *Service) updateAssetMetadata(ctx context.Context, assetID pgtype.UUID,
 
 // markAssetFailed marks an asset as failed with an error message
 func (s *Service) markAssetFailed(ctx context.Context, assetID pgtype.UUID, errorMsg string) {
+	// Processing that timed out is retried and processing interrupted by a
+	// shutdown is not finished, so the asset is kept.
+	if ctx.Err() != nil {
+		return
+	}
 	// In a production system, you'd want to store the error and possibly retry
 	// For now, we'll just log and mark as failed
 	_, _ = s.db.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{
@@ -602,13 +790,13 @@ func (s *Service) GetAsset(ctx context.Context, assetID uuid.UUID, userID uuid.U
 	}
 
 	// Get thumbnails from asset_files table
-	assetFiles, err := s.db.GetAssetFiles(ctx, assetUUID)
+	filesByAsset, err := s.assetFilesByAssetID(ctx, []pgtype.UUID{assetUUID})
 	if err != nil {
 		span.RecordError(err)
 		// Continue without thumbnails
 	}
 
-	thumbnails := s.assetFilesToThumbnails(ctx, assetFiles, withThumbnailSize)
+	thumbnails := s.assetFilesToThumbnails(ctx, filesByAsset[assetUUID], withThumbnailSize)
 
 	return s.convertToAssetInfo(asset, thumbnails), nil
 }
@@ -645,30 +833,31 @@ func (s *Service) DownloadAsset(ctx context.Context, req DownloadRequest) (*Down
 		downloadPath = asset.OriginalPath
 	}
 
-	// Determine expiry time based on file type
-	var expiry time.Duration
+	// Thumbnails live on the derivatives backend. They are small and cached
+	// by clients, so their URLs usually live longer than those of originals.
+	store := s.storage
+	urls := s.storage.PresignedURLs()
+	expiry := urls.Original(asset.Type == AssetTypeVideo)
 	if req.ThumbnailType != nil {
-		// Thumbnails can have longer expiry since they're smaller and cached
-		expiry = 24 * time.Hour
-	} else {
-		// Original files get shorter expiry
-		expiry = time.Hour
+		store = store.Derivatives()
+		expiry = urls.Thumbnail()
 	}
 
 	// Generate download URL with appropriate expiry
-	url, err := s.storage.GeneratePresignedDownloadURL(ctx, downloadPath, expiry)
+	presigned, err := store.PresignDownload(ctx, downloadPath, expiry)
 	if err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to generate download URL: %w", err)
 	}
 
-	// Add cache control headers for thumbnails
+	// Clients may cache the file for as long as the URL is valid
+	maxAge := int(expiry / time.Second)
 	headers := make(map[string]string)
 	if req.ThumbnailType != nil {
-		headers["Cache-Control"] = "public, max-age=86400" // 24 hours
+		headers["Cache-Control"] = fmt.Sprintf("public, max-age=%d", maxAge)
 		headers["Content-Type"] = "image/jpeg"
 	} else {
-		headers["Cache-Control"] = "private, max-age=3600" // 1 hour
+		headers["Cache-Control"] = fmt.Sprintf("private, max-age=%d", maxAge)
 		// Set content type based on asset type
 		switch asset.Type {
 		case AssetTypeImage:
@@ -687,11 +876,10 @@ func (s *Service) DownloadAsset(ctx context.Context, req DownloadRequest) (*Down
 			attribute.Bool("is_thumbnail", req.ThumbnailType != nil),
 		))
 
-	expiresAt := time.Now().Add(expiry)
 	return &DownloadResponse{
-		URL:       url,
+		URL:       presigned.URL,
 		Headers:   headers,
-		ExpiresAt: &expiresAt,
+		ExpiresAt: &presigned.ExpiresAt,
 	}, nil
 }
 
@@ -711,16 +899,19 @@ func (s *Service) SearchAssets(ctx context.Context, req SearchRequest) (*SearchR
 	}
 
 	// Set up pagination
-	limit := req.Limit
-	if limit <= 0 || limit > 100 {
-		limit = 20
-	}
+	limit := int(s.config.PageSize(int32(min(max(req.Limit, 0), math.MaxInt32)), 20))
 
 	offset := req.Offset
 	if offset < 0 {
 		offset = 0
 	}
 
+	sort, err := s.config.Sorting.AssetSort(req.SortBy, req.SortOrder)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
 	var assets []sqlc.Asset
 
 	// Choose search strategy based on request parameters
@@ -743,8 +934,8 @@ func (s *Service) SearchAssets(ctx context.Context, req SearchRequest) (*SearchR
 	case req.StartDate != nil && req.EndDate != nil:
 		// Date range search
 		span.SetAttributes(attribute.String("search_type", "date_range"))
-		startTime := pgutil.TimeToTimestamptz(*req.StartDate)
-		endTime := pgutil.TimeToTimestamptz(*req.EndDate)
+		startTime := pgutil.TimeToTimestamptz(WallClock(*req.StartDate, s.config.DefaultLocation()))
+		endTime := pgutil.TimeToTimestamptz(WallClock(*req.EndDate, s.config.DefaultLocation()))
 
 		dateAssets, err := s.db.GetAssetsByDateRange(ctx, sqlc.GetAssetsByDateRangeParams{
 			OwnerId:         userUUID,
@@ -806,9 +997,11 @@ func (s *Service) SearchAssets(ctx context.Context, req SearchRequest) (*SearchR
 		// Default: get all user assets
 		span.SetAttributes(attribute.String("search_type", "default"))
 		userAssets, err := s.db.GetUserAssets(ctx, sqlc.GetUserAssetsParams{
-			OwnerId: userUUID,
-			Limit:   pgtype.Int4{Int32: int32(limit), Valid: true},
-			Offset:  pgtype.Int4{Int32: int32(offset), Valid: true},
+			OwnerId:    userUUID,
+			Limit:      pgtype.Int4{Int32: int32(limit), Valid: true},
+			Offset:     pgtype.Int4{Int32: int32(offset), Valid: true},
+			SortBy:     sort.Field,
+			Descending: sort.Descending,
 		})
 		if err != nil {
 			span.RecordError(err)
@@ -817,17 +1010,21 @@ func (s *Service) SearchAssets(ctx context.Context, req SearchRequest) (*SearchR
 		assets = userAssets
 	}
 
+	// Fetch thumbnails for the whole page in one round trip
+	ids := make([]pgtype.UUID, len(assets))
+	for i, asset := range assets {
+		ids[i] = asset.ID
+	}
+	filesByAsset, err := s.assetFilesByAssetID(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		// Continue without thumbnails
+	}
+
 	// Convert to response format
 	assetInfos := make([]AssetInfo, len(assets))
 	for i, asset := range assets {
-		// Get thumbnails for each asset
-		assetFiles, err := s.db.GetAssetFiles(ctx, asset.ID)
-		if err != nil {
-			span.RecordError(err)
-			// Continue without thumbnails
-		}
-
-		thumbnails := s.assetFilesToThumbnails(ctx, assetFiles, withoutThumbnailSize)
+		thumbnails := s.assetFilesToThumbnails(ctx, filesByAsset[asset.ID], withoutThumbnailSize)
 
 		assetInfos[i] = *s.convertToAssetInfo(asset, thumbnails)
 	}
@@ -891,10 +1088,7 @@ func (s *Service) DeleteAsset(ctx context.Context, assetID uuid.UUID, userID uui
 	go func() {
 		if err := s.cleanupAssetFiles(cleanupCtx, assetUUID, asset.OriginalPath); err != nil {
 			// Log error but don't fail the deletion
-			s.logger.Error("Failed to cleanup asset files",
-				zap.Error(err),
-				zap.String("assetID", assetUUID.String()),
-				zap.String("path", asset.OriginalPath))
+			s.logCleanupFailure(err, assetUUID)
 		}
 	}()
 
@@ -927,6 +1121,7 @@ func (s *Service) HardDeleteAsset(ctx context.Context, assetID uuid.UUID, userID
 	err = s.cleanupAssetFiles(ctx, assetUUID, asset.OriginalPath)
 	if err != nil {
 		span.RecordError(err)
+		s.logCleanupFailure(err, assetUUID)
 		// Continue with database deletion even if file cleanup fails
 	}
 
@@ -961,7 +1156,26 @@ func (s *Service) HardDeleteAsset(ctx context.Context, assetID uuid.UUID, userID
 	return nil
 }
 
-// cleanupAssetFiles removes the asset files from storage
+// FileCleanupError lists the files of an asset that could not be deleted
+// even after retries. Once the asset is gone they are orphans, which the
+// storage garbage collector removes.
+type FileCleanupError struct {
+	AssetID string
+	Paths   []string
+	Err     error
+}
+
+func (e *FileCleanupError) Error() string {
+	return fmt.Sprintf("failed to delete %d file(s) of asset %s: %v", len(e.Paths), e.AssetID, e.Err)
+}
+
+func (e *FileCleanupError) Unwrap() error {
+	return e.Err
+}
+
+// cleanupAssetFiles removes the asset files from storage. Storage retries
+// transient failures; the files that still could not be deleted are
+// reported in a *FileCleanupError.
 func (s *Service) cleanupAssetFiles(ctx context.Context, assetID pgtype.UUID, originalPath string) error {
 	ctx, span := tracer.Start(ctx, "assets.cleanup_files",
 		trace.WithAttributes(
@@ -969,41 +1183,59 @@ func (s *Service) cleanupAssetFiles(ctx context.Context, assetID pgtype.UUID, or
 		))
 	defer span.End()
 
-	var cleanupErrors []error
+	var (
+		cleanupErrors []error
+		failedPaths   []string
+	)
 
 	// Delete original file
 	err := s.storage.DeleteAsset(ctx, originalPath)
 	if err != nil {
 		span.RecordError(err)
 		cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to delete original file: %w", err))
+		failedPaths = append(failedPaths, originalPath)
 	}
 
 	// Get all associated files from database
-	assetFiles, err := s.db.GetAssetFiles(ctx, assetID)
+	filesByAsset, err := s.assetFilesByAssetID(ctx, []pgtype.UUID{assetID})
 	if err != nil {
 		span.RecordError(err)
-		cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to get asset files: %w", err))
+		cleanupErrors = append(cleanupErrors, err)
 	} else {
 		// Delete each associated file (thumbnails, etc.)
-		for _, file := range assetFiles {
-			err := s.storage.DeleteAsset(ctx, file.Path)
+		for _, file := range filesByAsset[assetID] {
+			err := s.storage.Derivatives().DeleteAsset(ctx, file.Path)
 			if err != nil {
 				span.RecordError(err)
 				cleanupErrors = append(cleanupErrors, fmt.Errorf("failed to delete file %s: %w", file.Path, err))
+				failedPaths = append(failedPaths, file.Path)
 			}
 		}
 	}
 
 	if len(cleanupErrors) > 0 {
 		span.SetAttributes(attribute.Int("cleanup_errors", len(cleanupErrors)))
-		// Return the first error, but log all of them
-		return cleanupErrors[0]
+		return &FileCleanupError{
+			AssetID: pgutil.UUIDToString(assetID),
+			Paths:   failedPaths,
+			Err:     errors.Join(cleanupErrors...),
+		}
 	}
 
 	span.SetAttributes(attribute.String("status", "success"))
 	return nil
 }
 
+// logCleanupFailure logs the files cleanupAssetFiles left behind.
+func (s *Service) logCleanupFailure(err error, assetID pgtype.UUID) {
+	fields := []zap.Field{zap.Error(err), zap.String("assetID", pgutil.UUIDToString(assetID))}
+	var cleanupErr *FileCleanupError
+	if errors.As(err, &cleanupErr) {
+		fields = append(fields, zap.Strings("undeletedPaths", cleanupErr.Paths))
+	}
+	s.logger.Error("Failed to cleanup asset files; the storage garbage collector will remove what is left", fields...)
+}
+
 // RestoreAsset restores a soft-deleted asset
 func (s *Service) RestoreAsset(ctx context.Context, assetID uuid.UUID, userID uuid.UUID) error {
 	ctx, span := tracer.Start(ctx, "assets.restore_asset",
@@ -1044,6 +1276,20 @@ func (s *Service) getAssetTypeFromContentType(contentType string) AssetType {
 	return s.metadataExtractor.getAssetTypeFromContentType(contentType)
 }
 
+// uploadAssetType determines the asset type of an upload from its extension,
+// falling back to the declared content type for extensions the server does
+// not know.
+func (s *Service) uploadAssetType(filename, contentType string) AssetType {
+	switch kind, _, _ := storage.MediaTypeOf(filepath.Ext(filename)); kind {
+	case storage.MediaKindImage:
+		return AssetTypeImage
+	case storage.MediaKindVideo:
+		return AssetTypeVideo
+	default:
+		return s.getAssetTypeFromContentType(contentType)
+	}
+}
+
 func (s *Service) generateStoragePath(userID uuid.UUID, assetID uuid.UUID, filename string, assetType AssetType) string {
 	// Generate a hash-based path for better distribution
 	// Format: assets/{userID}/{year}/{month}/{assetID}/{filename}
@@ -1069,13 +1315,13 @@ func (s *Service) convertToAssetInfo(asset sqlc.Asset, thumbnails []AssetThumbna
 		UpdatedAt:    pgutil.TimestamptzToTime(asset.UpdatedAt),
 		Metadata: AssetMetadata{
 			Filename:    asset.OriginalFileName,
-			ContentType: s.getMimeTypeFromAssetType(asset.Type),
+			ContentType: s.originalMimeType(asset),
 			Size:        0, // Size is populated from EXIF data below
 		},
 	}
 
 	// Add metadata if available
-	if asset.LocalDateTime.Valid {
+	if asset.LocalDateTime.Valid && !asset.IsUndated {
 		dateTaken := pgutil.TimestamptzToTime(asset.LocalDateTime)
 		info.Metadata.DateTaken = &dateTaken
 	}
@@ -1157,6 +1403,25 @@ func (s *Service) convertToAssetInfo(asset sqlc.Asset, thumbnails []AssetThumbna
 	return info
 }
 
+// assetFilesByAssetID loads the asset_files rows of the assets with ids with
+// a single query and groups them by asset ID.
+func (s *Service) assetFilesByAssetID(ctx context.Context, ids []pgtype.UUID) (map[pgtype.UUID][]sqlc.AssetFile, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	files, err := s.db.GetAssetFilesByAssetIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset files: %w", err)
+	}
+
+	grouped := make(map[pgtype.UUID][]sqlc.AssetFile, len(ids))
+	for _, file := range files {
+		grouped[file.AssetId] = append(grouped[file.AssetId], file)
+	}
+	return grouped, nil
+}
+
 func (s *Service) assetFilesToThumbnails(ctx context.Context, files []sqlc.AssetFile, sizeMode thumbnailSizeMode) []AssetThumbnail {
 	thumbnails := make([]AssetThumbnail, 0, len(files))
 	for _, file := range files {
@@ -1165,14 +1430,23 @@ func (s *Service) assetFilesToThumbnails(ctx context.Context, files []sqlc.Asset
 			continue
 		}
 
+		// Thumbnails stored before their dimensions were recorded report
+		// the largest ones of their type
 		width, height := s.thumbnailGen.GetThumbnailDimensions(thumbType)
+		if file.Width.Valid && file.Height.Valid {
+			width, height = file.Width.Int32, file.Height.Int32
+		}
+		size := file.SizeInBytes.Int64
+		if !file.SizeInBytes.Valid {
+			size = s.thumbnailSize(ctx, file.Path, sizeMode)
+		}
 		thumbnails = append(thumbnails, AssetThumbnail{
 			AssetID: uuid.MustParse(pgutil.UUIDToString(file.AssetId)),
 			Type:    file.Type,
 			Path:    file.Path,
 			Width:   width,
 			Height:  height,
-			Size:    s.thumbnailSize(ctx, file.Path, sizeMode),
+			Size:    size,
 		})
 	}
 	return thumbnails
@@ -1191,7 +1465,7 @@ func (s *Service) thumbnailSize(ctx context.Context, path string, sizeMode thumb
 	if sizeMode != withThumbnailSize || s.storage == nil {
 		return 0
 	}
-	metadata, err := s.storage.GetAssetMetadata(ctx, path)
+	metadata, err := s.storage.Derivatives().GetAssetMetadata(ctx, path)
 	if err != nil {
 		return 0
 	}
@@ -1203,6 +1477,16 @@ func stringToUUIDUnsafe(s string) pgtype.UUID {
 	return pgtype.UUID{Bytes: id, Valid: true}
 }
 
+// originalMimeType returns the MIME type recorded when the original of
+// asset was stored, or one derived from its type for assets stored before
+// it was recorded.
+func (s *Service) originalMimeType(asset sqlc.Asset) string {
+	if asset.OriginalMimeType.Valid && asset.OriginalMimeType.String != "" {
+		return asset.OriginalMimeType.String
+	}
+	return s.getMimeTypeFromAssetType(asset.Type)
+}
+
 // getMimeTypeFromAssetType derives MIME type from asset type
 func (s *Service) getMimeTypeFromAssetType(assetType string) string {
 	switch strings.ToLower(assetType) {
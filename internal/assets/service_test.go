@@ -353,6 +353,36 @@ func TestAssetFilesToThumbnailsWithSize(t *testing.T) {
 	assert.Equal(t, int64(len(thumbData)), thumbnails[0].Size)
 }
 
+func TestAssetFilesToThumbnailsRecordedDimensions(t *testing.T) {
+	service := &Service{
+		thumbnailGen: NewThumbnailGenerator(),
+	}
+
+	thumbnails := service.assetFilesToThumbnails(context.Background(), []sqlc.AssetFile{
+		{
+			AssetId:     pgUUID(uuid.New()),
+			Type:        string(ThumbnailTypePreview),
+			Path:        "asset/thumbnails/preview.jpg",
+			Width:       pgtype.Int4{Int32: 1440, Valid: true},
+			Height:      pgtype.Int4{Int32: 1080, Valid: true},
+			SizeInBytes: pgtype.Int8{Int64: 48213, Valid: true},
+		},
+	}, withThumbnailSize)
+
+	assert.Len(t, thumbnails, 1)
+	assert.Equal(t, int32(1440), thumbnails[0].Width)
+	assert.Equal(t, int32(1080), thumbnails[0].Height)
+	assert.Equal(t, int64(48213), thumbnails[0].Size, "the recorded size is used without reading the file")
+}
+
+func TestThumbnailOrderFromConfig(t *testing.T) {
+	assert.Equal(t, DefaultThumbnailOrder(), ThumbnailOrderFromConfig(nil))
+	assert.Equal(t, DefaultThumbnailOrder(), ThumbnailOrderFromConfig(&config.Config{}))
+
+	cfg := &config.Config{Thumbnails: config.ThumbnailsConfig{Order: []string{"preview", "thumb"}}}
+	assert.Equal(t, []ThumbnailType{ThumbnailTypePreview, ThumbnailTypeThumb}, ThumbnailOrderFromConfig(cfg))
+}
+
 func TestAssetMetadata(t *testing.T) {
 	now := time.Now()
 	dateTaken := time.Now().Add(-24 * time.Hour)
@@ -0,0 +1,244 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ErrStripUnsupported is returned by StripMetadata for formats it cannot
+// remove metadata from.
+var ErrStripUnsupported = errors.New("removing metadata is not supported for this format")
+
+// CanStripMetadata reports whether StripMetadata supports files of
+// mimeType.
+func CanStripMetadata(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+// StripMetadata returns a copy of the JPEG, PNG or WebP image in data
+// without its EXIF, XMP and IPTC metadata and comments, which hold the
+// camera, the capture time and the GPS position. What changes how the image
+// looks is kept: color profiles and the orientation of JPEG images. The
+// pixels are copied as they are, without re-encoding.
+func StripMetadata(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, jpegMarkerSOI}):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data)
+	}
+	return nil, ErrStripUnsupported
+}
+
+const (
+	jpegMarkerEOI   = 0xD9
+	jpegMarkerAPP0  = 0xE0 // JFIF
+	jpegMarkerAPP14 = 0xEE // Adobe color transform
+	jpegMarkerAPP15 = 0xEF
+	jpegMarkerCOM   = 0xFE
+)
+
+// stripJPEG drops the APPn segments other than JFIF, ICC profiles and Adobe
+// color transforms, the comments, and whatever follows the end of the
+// image, such as the extra images of a multi-picture file with their own
+// EXIF data.
+func stripJPEG(data []byte) ([]byte, error) {
+	orientation := jpegOrientation(data)
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.Write(data[:2])
+	wroteOrientation := orientation <= 1
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG: no marker at offset %d", pos)
+		}
+		// Markers may be preceded by fill bytes
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos == len(data) {
+			break
+		}
+		marker := data[pos]
+		pos++
+		if marker == jpegMarkerEOI {
+			out.Write([]byte{0xFF, jpegMarkerEOI})
+			return out.Bytes(), nil
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			// Markers without a segment
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+		if pos+2 > len(data) {
+			return nil, errors.New("invalid JPEG: truncated segment")
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, errors.New("invalid JPEG: truncated segment")
+		}
+		segment := data[pos : pos+length]
+		pos += length
+
+		if !keepJPEGSegment(marker, segment[2:]) {
+			continue
+		}
+		if !wroteOrientation && marker != jpegMarkerAPP0 {
+			out.Write(orientationSegment(orientation))
+			wroteOrientation = true
+		}
+		out.Write([]byte{0xFF, marker})
+		out.Write(segment)
+
+		if marker == jpegMarkerSOS {
+			// The scan runs until the next marker that is neither a
+			// stuffed 0xFF nor a restart marker
+			end := pos
+			for end+1 < len(data) {
+				if data[end] == 0xFF && data[end+1] != 0x00 && (data[end+1] < 0xD0 || data[end+1] > 0xD7) {
+					break
+				}
+				end++
+			}
+			if end+1 >= len(data) {
+				return nil, errors.New("invalid JPEG: no end of image")
+			}
+			out.Write(data[pos:end])
+			pos = end
+		}
+	}
+	return nil, errors.New("invalid JPEG: no end of image")
+}
+
+func keepJPEGSegment(marker byte, payload []byte) bool {
+	switch {
+	case marker == jpegMarkerAPP0:
+		return true
+	case marker == jpegMarkerAPP2:
+		return bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	case marker == jpegMarkerAPP14:
+		return bytes.HasPrefix(payload, []byte("Adobe"))
+	case marker >= jpegMarkerAPP1 && marker <= jpegMarkerAPP15, marker == jpegMarkerCOM:
+		return false
+	}
+	return true
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, 1 when it
+// has none.
+func jpegOrientation(data []byte) int {
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 1
+	}
+	tag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+	orientation, err := tag.Int(0)
+	if err != nil || orientation < 1 || orientation > 8 {
+		return 1
+	}
+	return orientation
+}
+
+// orientationSegment returns an APP1 segment with EXIF data holding nothing
+// but orientation.
+func orientationSegment(orientation int) []byte {
+	return []byte{
+		0xFF, jpegMarkerAPP1,
+		0x00, 0x22, // length
+		'E', 'x', 'i', 'f', 0x00, 0x00,
+		'M', 'M', 0x00, 0x2A, // big-endian TIFF header
+		0x00, 0x00, 0x00, 0x08, // offset of the first IFD
+		0x00, 0x01, // one entry
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, // orientation, SHORT, count 1
+		0x00, byte(orientation), 0x00, 0x00, // value
+		0x00, 0x00, 0x00, 0x00, // no next IFD
+	}
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+// pngMetadataChunks are the PNG chunks holding metadata rather than pixels
+// or color information.
+var pngMetadataChunks = map[string]struct{}{
+	"eXIf": {}, "tEXt": {}, "zTXt": {}, "iTXt": {}, "tIME": {},
+}
+
+// stripPNG drops the metadata chunks and whatever follows the end of the
+// image.
+func stripPNG(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.Write(pngSignature)
+	pos := len(pngSignature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errors.New("invalid PNG: truncated chunk")
+		}
+		if _, drop := pngMetadataChunks[chunkType]; !drop {
+			out.Write(data[pos:end])
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+	return nil, errors.New("invalid PNG: no end of image")
+}
+
+// WebP VP8X flags of the metadata chunks
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP drops the EXIF and XMP chunks and clears their flags.
+func stripWebP(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(data))
+	out.Write(data[:12])
+	pos := 12
+	for pos+8 <= len(data) {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return nil, errors.New("invalid WebP: truncated chunk")
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[pos:end])
+			if size > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+	if pos != len(data) {
+		return nil, errors.New("invalid WebP: truncated chunk")
+	}
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
@@ -0,0 +1,130 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exifSegment returns an APP1 segment with EXIF data holding an orientation
+// and an artist.
+func exifSegment(orientation byte, artist string) []byte {
+	value := append([]byte(artist), 0)
+	tiff := []byte{
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08,
+		0x00, 0x02,
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00,
+		0x01, 0x3B, 0x00, 0x02, 0x00, 0x00, 0x00, byte(len(value)), 0x00, 0x00, 0x00, 0x26,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	tiff = append(tiff, value...)
+	return jpegSegment(jpegMarkerAPP1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestStripMetadata_JPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 64, 48)), nil))
+	icc := jpegSegment(jpegMarkerAPP2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	data := insertJPEGSegments(encoded.Bytes(), [][]byte{
+		exifSegment(6, "secret-artist"),
+		jpegSegment(jpegMarkerAPP1, []byte("http://ns.adobe.com/xap/1.0/\x00secret-xmp")),
+		jpegSegment(0xED, []byte("Photoshop 3.0\x00secret-iptc")),
+		jpegSegment(jpegMarkerCOM, []byte("secret-comment")),
+		icc,
+	})
+	data = append(data, []byte("secret-trailer")...)
+	require.Equal(t, 6, jpegOrientation(data))
+
+	stripped, err := StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "secret")
+	assert.Contains(t, string(stripped), string(icc), "color profiles are kept")
+	assert.Equal(t, 6, jpegOrientation(stripped), "the orientation is kept")
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, 64, decoded.Bounds().Dx())
+
+	// An image without orientation gets none
+	stripped, err = StripMetadata(insertJPEGSegments(encoded.Bytes(), [][]byte{exifSegment(1, "secret-artist")}))
+	require.NoError(t, err)
+	assert.Equal(t, encoded.Bytes(), stripped)
+}
+
+func TestStripMetadata_PNG(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 8, 8))))
+	chunk := func(chunkType, content string) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(content)))
+		out = append(out, chunkType+content...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE([]byte(chunkType+content)))
+	}
+	gamma := chunk("gAMA", "\x00\x00\xb1\x8f")
+	// IHDR is 25 bytes long, right after the signature
+	headerEnd := len(pngSignature) + 25
+	var data []byte
+	data = append(data, encoded.Bytes()[:headerEnd]...)
+	data = append(data, gamma...)
+	data = append(data, chunk("tEXt", "Comment\x00secret-text")...)
+	data = append(data, chunk("eXIf", "MM\x00*secret-exif")...)
+	data = append(data, encoded.Bytes()[headerEnd:]...)
+	data = append(data, []byte("secret-trailer")...)
+
+	stripped, err := StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "secret")
+	assert.Contains(t, string(stripped), string(gamma))
+	_, err = png.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+}
+
+func TestStripMetadata_WebP(t *testing.T) {
+	chunk := func(fourCC, content string) []byte {
+		out := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(content)))...)
+		out = append(out, content...)
+		if len(content)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	vp8x := "\x0c\x00\x00\x00\x07\x00\x00\x07\x00\x00" // EXIF and XMP flags
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", "pixels")...)
+	body = append(body, chunk("EXIF", "secret-exif")...)
+	body = append(body, chunk("XMP ", "secret-xmp")...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "secret")
+	want := append([]byte("WEBP"), chunk("VP8X", "\x00"+vp8x[1:])...)
+	want = append(want, chunk("VP8L", "pixels")...)
+	assert.Equal(t, want, stripped[8:])
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+}
+
+func TestStripMetadata_Unsupported(t *testing.T) {
+	_, err := StripMetadata([]byte("\x00\x00\x00\x18ftypheic"))
+	assert.ErrorIs(t, err, ErrStripUnsupported)
+
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil))
+	_, err = StripMetadata(encoded.Bytes()[:encoded.Len()/2])
+	assert.Error(t, err, "a truncated image")
+	assert.NotErrorIs(t, err, ErrStripUnsupported)
+}
@@ -0,0 +1,255 @@
+package assets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// TakeoutMetadataKey is the asset_metadata key the Google Takeout sidecar
+// of an asset is kept under, so metadata extraction can apply it again.
+const TakeoutMetadataKey = "google-takeout"
+
+// ErrNotTakeoutSidecar is returned for sidecars that are not the JSON
+// Google Takeout writes next to each photo or video, e.g. XMP sidecars or
+// the metadata.json of an album.
+var ErrNotTakeoutSidecar = errors.New("not a Google Takeout sidecar")
+
+const (
+	// takeoutSupplementalSuffix is inserted between the media name and
+	// ".json" by newer exports, and truncated with the rest of the name.
+	takeoutSupplementalSuffix = ".supplemental-metadata"
+	// takeoutMaxNameLength is the length Takeout truncates sidecar names
+	// to, including ".json" but not a "(n)" duplicate counter.
+	takeoutMaxNameLength = 51
+	// takeoutEditedSuffix marks the edited copy of a photo, which shares
+	// the sidecar of the original.
+	takeoutEditedSuffix = "-edited"
+)
+
+// takeoutCounter matches the "(n)" Takeout appends to duplicate names.
+var takeoutCounter = regexp.MustCompile(`\(\d+\)$`)
+
+// TakeoutSidecar is the metadata of a Google Takeout sidecar that is applied
+// to an asset.
+type TakeoutSidecar struct {
+	Title          string           `json:"title,omitempty"`
+	Description    string           `json:"description,omitempty"`
+	PhotoTakenTime *takeoutTime     `json:"photoTakenTime,omitempty"`
+	GeoData        *takeoutLocation `json:"geoData,omitempty"`
+	GeoDataExif    *takeoutLocation `json:"geoDataExif,omitempty"`
+	Favorited      bool             `json:"favorited,omitempty"`
+}
+
+type takeoutTime struct {
+	// Timestamp is in seconds since the epoch, usually as a string
+	Timestamp json.Number `json:"timestamp"`
+}
+
+type takeoutLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// ParseTakeoutSidecar parses a Google Takeout sidecar.
+func ParseTakeoutSidecar(data []byte) (*TakeoutSidecar, error) {
+	var sidecar TakeoutSidecar
+	if err := json.Unmarshal(data, &sidecar); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotTakeoutSidecar, err)
+	}
+	// Every photo and video sidecar has a capture time, album metadata has not
+	if sidecar.PhotoTakenTime == nil {
+		return nil, ErrNotTakeoutSidecar
+	}
+	return &sidecar, nil
+}
+
+// TakenAt returns when the photo or video was taken.
+func (t *TakeoutSidecar) TakenAt() (time.Time, bool) {
+	if t.PhotoTakenTime == nil {
+		return time.Time{}, false
+	}
+	seconds, err := t.PhotoTakenTime.Timestamp.Int64()
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0).UTC(), true
+}
+
+// Location returns where the photo or video was taken: the location set in
+// Google Photos, else the one it read from the file. Takeout writes zeros
+// for unknown locations.
+func (t *TakeoutSidecar) Location() (latitude, longitude float64, ok bool) {
+	for _, location := range []*takeoutLocation{t.GeoData, t.GeoDataExif} {
+		if location != nil && (location.Latitude != 0 || location.Longitude != 0) {
+			return location.Latitude, location.Longitude, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Apply sets the fields the sidecar has on metadata extracted from the file.
+// Like an XMP sidecar, it takes precedence over embedded metadata: edits in
+// Google Photos only end up in the sidecar. The capture time zone read from
+// the file is kept, as Takeout only records the instant.
+func (t *TakeoutSidecar) Apply(metadata *AssetMetadata) {
+	if takenAt, ok := t.TakenAt(); ok {
+		metadata.DateTaken = &takenAt
+	}
+	if latitude, longitude, ok := t.Location(); ok {
+		metadata.Latitude = &latitude
+		metadata.Longitude = &longitude
+	}
+	if t.Description != "" {
+		description := t.Description
+		metadata.Description = &description
+	}
+}
+
+// StoreTakeoutSidecar keeps the sidecar of an asset for metadata extraction.
+func StoreTakeoutSidecar(ctx context.Context, db *sqlc.Queries, assetID pgtype.UUID, sidecar *TakeoutSidecar) error {
+	value, err := json.Marshal(sidecar)
+	if err != nil {
+		return fmt.Errorf("failed to encode Takeout sidecar: %w", err)
+	}
+	if _, err := db.UpsertAssetMetadata(ctx, sqlc.UpsertAssetMetadataParams{
+		AssetId: assetID,
+		Key:     TakeoutMetadataKey,
+		Value:   value,
+	}); err != nil {
+		return fmt.Errorf("failed to store Takeout sidecar: %w", err)
+	}
+	return nil
+}
+
+// ApplyStoredTakeoutSidecar applies the sidecar kept for an asset, if any,
+// to metadata extracted from its file.
+func ApplyStoredTakeoutSidecar(ctx context.Context, db *sqlc.Queries, assetID pgtype.UUID, metadata *AssetMetadata) error {
+	row, err := db.GetAssetMetadataByKey(ctx, sqlc.GetAssetMetadataByKeyParams{
+		AssetId: assetID,
+		Key:     TakeoutMetadataKey,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load Takeout sidecar: %w", err)
+	}
+	sidecar, err := ParseTakeoutSidecar(row.Value)
+	if err != nil {
+		return err
+	}
+	sidecar.Apply(metadata)
+	return nil
+}
+
+// takeoutName is a media name as Takeout pairs it with a sidecar: without
+// the "(n)" of duplicates, which moves behind the extension in the sidecar
+// name.
+type takeoutName struct {
+	name    string
+	counter string
+}
+
+// takeoutMediaNames returns the names the sidecar of the media file name may
+// be derived from, the most specific first.
+func takeoutMediaNames(mediaName string) []takeoutName {
+	names := []takeoutName{{name: mediaName}}
+	ext := filepath.Ext(mediaName)
+	stem := strings.TrimSuffix(mediaName, ext)
+	if counter := takeoutCounter.FindString(stem); counter != "" {
+		stem = strings.TrimSuffix(stem, counter)
+		names = append(names, takeoutName{name: stem + ext, counter: counter})
+	}
+	for _, name := range names {
+		nameStem := strings.TrimSuffix(name.name, ext)
+		if edited, ok := strings.CutSuffix(nameStem, takeoutEditedSuffix); ok {
+			names = append(names, takeoutName{name: edited + ext, counter: name.counter})
+		}
+	}
+	return names
+}
+
+// takeoutSidecarName returns the media name a sidecar file name refers to,
+// and whether it may be truncated.
+func takeoutSidecarName(sidecarName string) (takeoutName, bool, bool) {
+	trimmed, ok := strings.CutSuffix(sidecarName, ".json")
+	if !ok {
+		return takeoutName{}, false, false
+	}
+	counter := takeoutCounter.FindString(trimmed)
+	trimmed = strings.TrimSuffix(trimmed, counter)
+	truncated := utf8.RuneCountInString(trimmed)+len(".json") >= takeoutMaxNameLength
+	if dot := strings.LastIndex(trimmed, "."); dot > 0 && strings.HasPrefix(takeoutSupplementalSuffix, trimmed[dot:]) && len(trimmed[dot:]) > 1 {
+		trimmed = trimmed[:dot]
+	}
+	return takeoutName{name: trimmed, counter: counter}, truncated, true
+}
+
+// MatchTakeoutSidecar returns which of the sidecar file names in the
+// directory of mediaName belongs to it. Takeout names sidecars after the
+// media file, but truncates long names, moves the "(n)" of duplicates behind
+// the extension, may insert ".supplemental-metadata" or drop the extension,
+// and shares the sidecar of a photo with its "-edited" copy.
+func MatchTakeoutSidecar(mediaName string, sidecarNames []string) (string, bool) {
+	type sidecar struct {
+		file      string
+		name      takeoutName
+		truncated bool
+	}
+	sidecars := make([]sidecar, 0, len(sidecarNames))
+	for _, file := range sidecarNames {
+		if name, truncated, ok := takeoutSidecarName(file); ok {
+			sidecars = append(sidecars, sidecar{file: file, name: name, truncated: truncated})
+		}
+	}
+
+	matches := func(candidates []takeoutName, match func(media, candidate takeoutName, truncated bool) bool) (string, bool) {
+		for _, media := range candidates {
+			for _, candidate := range sidecars {
+				if candidate.name.counter == media.counter && match(media, candidate.name, candidate.truncated) {
+					return candidate.file, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	names := takeoutMediaNames(mediaName)
+	if file, ok := matches(names, func(media, candidate takeoutName, _ bool) bool {
+		return candidate.name == media.name
+	}); ok {
+		return file, true
+	}
+	if file, ok := matches(names, func(media, candidate takeoutName, _ bool) bool {
+		return candidate.name == strings.TrimSuffix(media.name, filepath.Ext(media.name))
+	}); ok {
+		return file, true
+	}
+	// A duplicate's name also starts with the truncated name of the sidecar
+	// of the original, so its own sidecar is looked for first
+	slices.SortStableFunc(names, func(a, b takeoutName) int {
+		switch {
+		case a.counter != "" && b.counter == "":
+			return -1
+		case a.counter == "" && b.counter != "":
+			return 1
+		}
+		return 0
+	})
+	return matches(names, func(media, candidate takeoutName, truncated bool) bool {
+		return truncated && strings.HasPrefix(media.name, candidate.name)
+	})
+}
@@ -0,0 +1,179 @@
+package assets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const takeoutSidecarJSON = `{
+  "title": "IMG_0001.jpg",
+  "description": "Lake at dawn",
+  "imageViews": "12",
+  "creationTime": {"timestamp": "1700000000", "formatted": "Nov 14, 2023, 10:13:20 PM UTC"},
+  "photoTakenTime": {"timestamp": "1600000000", "formatted": "Sep 13, 2020, 12:26:40 PM UTC"},
+  "geoData": {"latitude": 46.0, "longitude": 8.95, "altitude": 270.0},
+  "geoDataExif": {"latitude": 45.0, "longitude": 9.0, "altitude": 0.0},
+  "favorited": true
+}`
+
+func TestParseTakeoutSidecar(t *testing.T) {
+	sidecar, err := ParseTakeoutSidecar([]byte(takeoutSidecarJSON))
+	require.NoError(t, err)
+
+	takenAt, ok := sidecar.TakenAt()
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), takenAt)
+
+	latitude, longitude, ok := sidecar.Location()
+	require.True(t, ok)
+	assert.Equal(t, 46.0, latitude, "the location set in Google Photos wins")
+	assert.Equal(t, 8.95, longitude)
+	assert.Equal(t, "Lake at dawn", sidecar.Description)
+	assert.True(t, sidecar.Favorited)
+}
+
+func TestParseTakeoutSidecar_NotTakeout(t *testing.T) {
+	for name, data := range map[string]string{
+		"album metadata": `{"title": "Holidays", "date": {"timestamp": "1600000000"}}`,
+		"xmp":            `<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTakeoutSidecar([]byte(data))
+			assert.ErrorIs(t, err, ErrNotTakeoutSidecar)
+		})
+	}
+}
+
+func TestTakeoutSidecarLocation(t *testing.T) {
+	sidecar, err := ParseTakeoutSidecar([]byte(`{
+		"photoTakenTime": {"timestamp": "1600000000"},
+		"geoData": {"latitude": 0.0, "longitude": 0.0},
+		"geoDataExif": {"latitude": 45.5, "longitude": 9.25}
+	}`))
+	require.NoError(t, err)
+	latitude, longitude, ok := sidecar.Location()
+	require.True(t, ok)
+	assert.Equal(t, 45.5, latitude)
+	assert.Equal(t, 9.25, longitude)
+
+	sidecar.GeoDataExif = nil
+	_, _, ok = sidecar.Location()
+	assert.False(t, ok, "zeros are an unknown location")
+}
+
+func TestTakeoutSidecarApply(t *testing.T) {
+	sidecar, err := ParseTakeoutSidecar([]byte(takeoutSidecarJSON))
+	require.NoError(t, err)
+
+	embedded := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	zone := "+02:00"
+	latitude, longitude := 1.0, 2.0
+	metadata := &AssetMetadata{
+		DateTaken: &embedded,
+		TimeZone:  &zone,
+		Latitude:  &latitude,
+		Longitude: &longitude,
+	}
+	sidecar.Apply(metadata)
+
+	require.NotNil(t, metadata.DateTaken)
+	assert.Equal(t, time.Unix(1600000000, 0).UTC(), *metadata.DateTaken)
+	assert.Equal(t, "+02:00", *metadata.TimeZone, "the embedded time zone is kept")
+	assert.Equal(t, 46.0, *metadata.Latitude)
+	assert.Equal(t, 8.95, *metadata.Longitude)
+	require.NotNil(t, metadata.Description)
+	assert.Equal(t, "Lake at dawn", *metadata.Description)
+
+	// Fields the sidecar lacks keep the embedded metadata
+	empty, err := ParseTakeoutSidecar([]byte(`{"photoTakenTime": {"timestamp": "0"}}`))
+	require.NoError(t, err)
+	metadata = &AssetMetadata{DateTaken: &embedded, Latitude: &latitude, Longitude: &longitude}
+	empty.Apply(metadata)
+	assert.Equal(t, embedded, *metadata.DateTaken)
+	assert.Equal(t, 1.0, *metadata.Latitude)
+	assert.Nil(t, metadata.Description)
+}
+
+func TestMatchTakeoutSidecar(t *testing.T) {
+	tests := []struct {
+		name     string
+		media    string
+		sidecars []string
+		want     string
+	}{
+		{
+			name:     "plain",
+			media:    "IMG_0001.jpg",
+			sidecars: []string{"IMG_0002.jpg.json", "IMG_0001.jpg.json", "metadata.json"},
+			want:     "IMG_0001.jpg.json",
+		},
+		{
+			name:     "supplemental metadata",
+			media:    "IMG_0001.jpg",
+			sidecars: []string{"IMG_0001.jpg.supplemental-metadata.json"},
+			want:     "IMG_0001.jpg.supplemental-metadata.json",
+		},
+		{
+			name:     "truncated supplemental metadata",
+			media:    "IMG_0001.jpg",
+			sidecars: []string{"IMG_0001.jpg.suppl.json"},
+			want:     "IMG_0001.jpg.suppl.json",
+		},
+		{
+			name:     "without extension",
+			media:    "IMG_0001.jpg",
+			sidecars: []string{"IMG_0001.json"},
+			want:     "IMG_0001.json",
+		},
+		{
+			name:     "duplicate counter moves behind the extension",
+			media:    "IMG_0001(1).jpg",
+			sidecars: []string{"IMG_0001.jpg.json", "IMG_0001.jpg(1).json"},
+			want:     "IMG_0001.jpg(1).json",
+		},
+		{
+			name:     "counter in the name",
+			media:    "Screenshot(1).png",
+			sidecars: []string{"Screenshot(1).png.json"},
+			want:     "Screenshot(1).png.json",
+		},
+		{
+			name:     "edited copy shares the original's sidecar",
+			media:    "IMG_0001-edited.jpg",
+			sidecars: []string{"IMG_0001.jpg.json"},
+			want:     "IMG_0001.jpg.json",
+		},
+		{
+			name:     "truncated name",
+			media:    "Screenshot_20200913-122640_Some Very Long App Name.jpg",
+			sidecars: []string{"Screenshot_20200913-122640_Some Very Long App N.json"},
+			want:     "Screenshot_20200913-122640_Some Very Long App N.json",
+		},
+		{
+			name:     "truncated name with counter",
+			media:    "Screenshot_20200913-122640_Some Very Long App Name(2).jpg",
+			sidecars: []string{"Screenshot_20200913-122640_Some Very Long App N.json", "Screenshot_20200913-122640_Some Very Long App N(2).json"},
+			want:     "Screenshot_20200913-122640_Some Very Long App N(2).json",
+		},
+		{
+			name:     "short names are not prefixes",
+			media:    "IMG_00011.jpg",
+			sidecars: []string{"IMG_0001.jpg.json"},
+		},
+		{
+			name:     "counters must match",
+			media:    "IMG_0001(1).jpg",
+			sidecars: []string{"IMG_0001.jpg(2).json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MatchTakeoutSidecar(tt.media, tt.sidecars)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// thumbhashMaxSize is the largest width and height a ThumbHash is computed
+// from. Larger images are shrunk first; the hash cannot hold more detail.
+const thumbhashMaxSize = 100
+
+// ThumbnailLayout is what a timeline grid needs of an asset's thumbnails to
+// lay them out before any is loaded.
+type ThumbnailLayout struct {
+	// Thumbhash is a ThumbHash placeholder of the thumbnails.
+	Thumbhash []byte
+	// Ratio is the width of the thumbnails divided by their height.
+	Ratio float64
+}
+
+// NewThumbnailLayout returns the layout of the thumbnail img.
+func NewThumbnailLayout(img image.Image) ThumbnailLayout {
+	bounds := img.Bounds()
+	ratio := 1.0
+	if bounds.Dy() > 0 {
+		ratio = float64(bounds.Dx()) / float64(bounds.Dy())
+	}
+	return ThumbnailLayout{Thumbhash: Thumbhash(img), Ratio: ratio}
+}
+
+// DecodeThumbnailLayout returns the layout of an encoded thumbnail. It is
+// computed from the small thumbnail rather than the original, which keeps
+// it cheap.
+func DecodeThumbnailLayout(thumbnail []byte) (ThumbnailLayout, error) {
+	img, _, err := image.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		return ThumbnailLayout{}, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	return NewThumbnailLayout(img), nil
+}
+
+// StoreThumbnailLayout records the layout of the encoded small thumbnail of
+// an asset, for asset responses and timeline grids.
+func StoreThumbnailLayout(ctx context.Context, db *sqlc.Queries, assetID pgtype.UUID, thumbnail []byte) error {
+	layout, err := DecodeThumbnailLayout(thumbnail)
+	if err != nil {
+		return err
+	}
+	if err := db.SetAssetThumbnailLayout(ctx, sqlc.SetAssetThumbnailLayoutParams{
+		ID:        assetID,
+		Thumbhash: layout.Thumbhash,
+		Ratio:     layout.Ratio,
+	}); err != nil {
+		return fmt.Errorf("failed to store thumbnail layout: %w", err)
+	}
+	return nil
+}
+
+// Thumbhash encodes img as a ThumbHash (https://evanw.github.io/thumbhash/),
+// the placeholder format upstream Immich clients decode.
+func Thumbhash(img image.Image) []byte {
+	if img.Bounds().Dx() > thumbhashMaxSize || img.Bounds().Dy() > thumbhashMaxSize {
+		img = imaging.Fit(img, thumbhashMaxSize, thumbhashMaxSize, imaging.Linear)
+	}
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return nil
+	}
+
+	pixels := make([]color.NRGBA, 0, w*h)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixels = append(pixels, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA))
+		}
+	}
+
+	// Average color, weighted by alpha
+	var avgR, avgG, avgB, avgA float64
+	for _, p := range pixels {
+		alpha := float64(p.A) / 255
+		avgR += alpha / 255 * float64(p.R)
+		avgG += alpha / 255 * float64(p.G)
+		avgB += alpha / 255 * float64(p.B)
+		avgA += alpha
+	}
+	if avgA > 0 {
+		avgR /= avgA
+		avgG /= avgA
+		avgB /= avgA
+	}
+
+	hasAlpha := avgA < float64(w*h)
+	lLimit := 7.0
+	if hasAlpha {
+		// Fewer luminance terms leave room for the alpha channel
+		lLimit = 5
+	}
+	maxSide := float64(max(w, h))
+	lx := max(1, int(jsRound(lLimit*float64(w)/maxSide)))
+	ly := max(1, int(jsRound(lLimit*float64(h)/maxSide)))
+
+	// Convert to LPQA, composited over the average color
+	l := make([]float64, len(pixels))
+	p := make([]float64, len(pixels))
+	q := make([]float64, len(pixels))
+	a := make([]float64, len(pixels))
+	for i, px := range pixels {
+		alpha := float64(px.A) / 255
+		r := avgR*(1-alpha) + alpha/255*float64(px.R)
+		g := avgG*(1-alpha) + alpha/255*float64(px.G)
+		b := avgB*(1-alpha) + alpha/255*float64(px.B)
+		l[i] = (r + g + b) / 3
+		p[i] = (r+g)/2 - b
+		q[i] = r - g
+		a[i] = alpha
+	}
+
+	lDC, lAC, lScale := thumbhashChannel(l, w, h, max(3, lx), max(3, ly))
+	pDC, pAC, pScale := thumbhashChannel(p, w, h, 3, 3)
+	qDC, qAC, qScale := thumbhashChannel(q, w, h, 3, 3)
+
+	isLandscape := w > h
+	header24 := int(jsRound(63*lDC)) |
+		int(jsRound(31.5+31.5*pDC))<<6 |
+		int(jsRound(31.5+31.5*qDC))<<12 |
+		int(jsRound(31*lScale))<<18
+	if hasAlpha {
+		header24 |= 1 << 23
+	}
+	header16 := int(jsRound(63*pScale))<<3 | int(jsRound(63*qScale))<<9
+	if isLandscape {
+		header16 |= ly | 1<<15
+	} else {
+		header16 |= lx
+	}
+	hash := []byte{
+		byte(header24), byte(header24 >> 8), byte(header24 >> 16),
+		byte(header16), byte(header16 >> 8),
+	}
+
+	acs := [][]float64{lAC, pAC, qAC}
+	if hasAlpha {
+		aDC, aAC, aScale := thumbhashChannel(a, w, h, 5, 5)
+		hash = append(hash, byte(int(jsRound(15*aDC))|int(jsRound(15*aScale))<<4))
+		acs = append(acs, aAC)
+	}
+
+	// Two AC terms per byte, low nibble first
+	acStart := len(hash)
+	index := 0
+	for _, ac := range acs {
+		for _, f := range ac {
+			if index%2 == 0 {
+				hash = append(hash, 0)
+			}
+			hash[acStart+index/2] |= byte(int(jsRound(15*f)) << ((index & 1) * 4))
+			index++
+		}
+	}
+	return hash
+}
+
+// thumbhashChannel encodes a channel with the DCT into its DC term and its
+// AC terms, normalized to [0, 1] by scale.
+func thumbhashChannel(channel []float64, w, h, nx, ny int) (dc float64, ac []float64, scale float64) {
+	fx := make([]float64, w)
+	for cy := 0; cy < ny; cy++ {
+		for cx := 0; cx*ny < nx*(ny-cy); cx++ {
+			for x := range fx {
+				fx[x] = math.Cos(math.Pi / float64(w) * float64(cx) * (float64(x) + 0.5))
+			}
+			f := 0.0
+			for y := 0; y < h; y++ {
+				fy := math.Cos(math.Pi / float64(h) * float64(cy) * (float64(y) + 0.5))
+				for x := 0; x < w; x++ {
+					f += channel[x+y*w] * fx[x] * fy
+				}
+			}
+			f /= float64(w * h)
+			if cx > 0 || cy > 0 {
+				ac = append(ac, f)
+				scale = math.Max(scale, math.Abs(f))
+			} else {
+				dc = f
+			}
+		}
+	}
+	if scale > 0 {
+		for i := range ac {
+			ac[i] = 0.5 + 0.5/scale*ac[i]
+		}
+	}
+	return dc, ac, scale
+}
+
+// jsRound rounds halves up, like Math.round in the reference encoder.
+func jsRound(x float64) float64 {
+	return math.Floor(x + 0.5)
+}
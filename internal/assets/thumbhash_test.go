@@ -0,0 +1,51 @@
+package assets
+
+import (
+	"encoding/base64"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// thumbhashTestImage fills an image with pseudo-random pixels, opaque
+// unless withAlpha is set.
+func thumbhashTestImage(width, height int, withAlpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	seed := 1
+	for i := 0; i < width*height*4; i++ {
+		seed = (seed*1103 + 12345) % 65536
+		img.Pix[i] = uint8(seed >> 8)
+		if i%4 == 3 && !withAlpha {
+			img.Pix[i] = 255
+		}
+	}
+	return img
+}
+
+func TestThumbhashMatchesReferenceEncoder(t *testing.T) {
+	// Hashes of the same images by the reference JavaScript encoder
+	tests := []struct {
+		name          string
+		width, height int
+		withAlpha     bool
+		want          string
+	}{
+		{"landscape", 8, 6, false, "nRcGLYaFNcbEOnZvlSVayvmWkAnp"},
+		{"portrait", 6, 8, false, "nRcGJQRKhFR3+WkXWY2reQp2YQTZ"},
+		{"translucent", 8, 6, true, "XSeCHIYYtQOwaWxGyF85haCqVY8jRuNpBg=="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := Thumbhash(thumbhashTestImage(tt.width, tt.height, tt.withAlpha))
+			assert.Equal(t, tt.want, base64.StdEncoding.EncodeToString(hash))
+		})
+	}
+}
+
+func TestNewThumbnailLayoutShrinksLargeImages(t *testing.T) {
+	layout := NewThumbnailLayout(thumbhashTestImage(400, 200, false))
+	assert.InDelta(t, 2.0, layout.Ratio, 1e-9)
+	assert.NotEmpty(t, layout.Thumbhash)
+	assert.LessOrEqual(t, len(layout.Thumbhash), 25, "a ThumbHash is at most 25 bytes")
+}
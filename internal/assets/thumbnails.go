@@ -11,9 +11,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
 	"github.com/disintegration/imaging"
+	"github.com/jackc/pgx/v5/pgtype"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -21,7 +24,17 @@ import (
 // ThumbnailGenerator handles generation of thumbnails for assets
 type ThumbnailGenerator struct {
 	// Configuration for different thumbnail sizes
+	mu    sync.RWMutex
 	sizes map[ThumbnailType]ThumbnailConfig
+	// presets maps the name of each size preset to its thumbnail type
+	presets map[string]ThumbnailType
+}
+
+// Thumbnail is a generated thumbnail with the dimensions it was resized to.
+type Thumbnail struct {
+	Data   []byte
+	Width  int32
+	Height int32
 }
 
 // ThumbnailConfig represents configuration for a thumbnail type
@@ -58,8 +71,85 @@ func NewThumbnailGenerator() *ThumbnailGenerator {
 	}
 }
 
+// Configure sets the size and quality thumbnails of thumbType are generated
+// with from now on. Values out of range are ignored. The format is kept, as
+// it decides where thumbnails are stored and every format is encoded as
+// JPEG for now.
+func (g *ThumbnailGenerator) Configure(thumbType ThumbnailType, size, quality int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	config, ok := g.sizes[thumbType]
+	if !ok {
+		return
+	}
+	if size > 0 {
+		config.MaxWidth = size
+		config.MaxHeight = size
+	}
+	if quality >= 1 && quality <= 100 {
+		config.Quality = quality
+	}
+	g.sizes[thumbType] = config
+}
+
+// ThumbnailPreset is a named thumbnail size clients can ask for, such as a
+// small one for a phone on a cellular connection.
+type ThumbnailPreset struct {
+	Name    string
+	Size    int
+	Quality int
+}
+
+// PresetThumbnailType returns the thumbnail type of presets with the given
+// size and quality. The type is derived from the settings, so presets with
+// the same ones share their thumbnails and changed settings make new ones.
+func PresetThumbnailType(size, quality int) ThumbnailType {
+	return ThumbnailType(fmt.Sprintf("preset-%d-q%d", size, quality))
+}
+
+// SetPresets replaces the size presets thumbnails can be generated for.
+// Presets without a name, or with a size or quality out of range, are left
+// out.
+func (g *ThumbnailGenerator) SetPresets(presets []ThumbnailPreset) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, thumbType := range g.presets {
+		delete(g.sizes, thumbType)
+	}
+	g.presets = make(map[string]ThumbnailType, len(presets))
+	for _, preset := range presets {
+		if preset.Name == "" || preset.Size <= 0 || preset.Quality < 1 || preset.Quality > 100 {
+			continue
+		}
+		thumbType := PresetThumbnailType(preset.Size, preset.Quality)
+		g.presets[preset.Name] = thumbType
+		g.sizes[thumbType] = ThumbnailConfig{
+			MaxWidth:  preset.Size,
+			MaxHeight: preset.Size,
+			Quality:   preset.Quality,
+			Format:    "jpeg",
+		}
+	}
+}
+
+// Preset returns the thumbnail type of the preset called name.
+func (g *ThumbnailGenerator) Preset(name string) (ThumbnailType, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	thumbType, ok := g.presets[name]
+	return thumbType, ok
+}
+
+// config returns the configuration of thumbType.
+func (g *ThumbnailGenerator) config(thumbType ThumbnailType) (ThumbnailConfig, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	config, ok := g.sizes[thumbType]
+	return config, ok
+}
+
 // GenerateThumbnails generates all required thumbnails for an asset
-func (g *ThumbnailGenerator) GenerateThumbnails(ctx context.Context, reader io.Reader, originalFilename string) (map[ThumbnailType][]byte, error) {
+func (g *ThumbnailGenerator) GenerateThumbnails(ctx context.Context, reader io.Reader, originalFilename string) (map[ThumbnailType]Thumbnail, error) {
 	ctx, span := tracer.Start(ctx, "thumbnails.generate_all",
 		trace.WithAttributes(
 			attribute.String("filename", originalFilename),
@@ -79,25 +169,57 @@ func (g *ThumbnailGenerator) GenerateThumbnails(ctx context.Context, reader io.R
 		attribute.Int("original_height", img.Bounds().Dy()),
 	)
 
-	thumbnails := make(map[ThumbnailType][]byte)
+	thumbnails := g.GenerateThumbnailsFromImage(ctx, img, DefaultThumbnailOrder())
 
-	// Generate each thumbnail type
-	for thumbType, config := range g.sizes {
-		thumbData, err := g.generateThumbnail(ctx, img, thumbType, config)
+	span.SetAttributes(attribute.Int("thumbnails_generated", len(thumbnails)))
+	return thumbnails, nil
+}
+
+// GenerateThumbnailsFromImage generates the given thumbnail types from an
+// already decoded image, in order. Types that fail or are unknown are
+// left out of the result.
+func (g *ThumbnailGenerator) GenerateThumbnailsFromImage(ctx context.Context, img image.Image, types []ThumbnailType) map[ThumbnailType]Thumbnail {
+	thumbnails := make(map[ThumbnailType]Thumbnail, len(types))
+	for _, thumbType := range types {
+		config, ok := g.config(thumbType)
+		if !ok {
+			continue
+		}
+		thumbnail, err := g.generateThumbnail(ctx, img, thumbType, config)
 		if err != nil {
-			span.RecordError(err)
 			// Continue with other thumbnails even if one fails
 			continue
 		}
-		thumbnails[thumbType] = thumbData
+		thumbnails[thumbType] = thumbnail
 	}
+	return thumbnails
+}
 
-	span.SetAttributes(attribute.Int("thumbnails_generated", len(thumbnails)))
-	return thumbnails, nil
+// DecodeImage decodes an image for thumbnail generation, refusing images
+// whose header declares a width or height above maxDimension so a
+// decompression bomb is never decoded into memory. A maxDimension of zero
+// or less disables the check.
+func (g *ThumbnailGenerator) DecodeImage(reader io.Reader, maxDimension int) (image.Image, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if maxDimension > 0 {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil &&
+			(cfg.Width > maxDimension || cfg.Height > maxDimension) {
+			return nil, fmt.Errorf("image is %dx%d, larger than the %d pixel decode limit",
+				cfg.Width, cfg.Height, maxDimension)
+		}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return img, nil
 }
 
 // generateThumbnail generates a single thumbnail
-func (g *ThumbnailGenerator) generateThumbnail(ctx context.Context, img image.Image, thumbType ThumbnailType, config ThumbnailConfig) ([]byte, error) {
+func (g *ThumbnailGenerator) generateThumbnail(ctx context.Context, img image.Image, thumbType ThumbnailType, config ThumbnailConfig) (Thumbnail, error) {
 	_, span := tracer.Start(ctx, "thumbnails.generate_single",
 		trace.WithAttributes(
 			attribute.String("thumbnail_type", string(thumbType)),
@@ -129,13 +251,13 @@ func (g *ThumbnailGenerator) generateThumbnail(ctx context.Context, img image.Im
 		err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: config.Quality})
 		if err != nil {
 			span.RecordError(err)
-			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+			return Thumbnail{}, fmt.Errorf("failed to encode JPEG: %w", err)
 		}
 	case "png":
 		err := png.Encode(&buf, resized)
 		if err != nil {
 			span.RecordError(err)
-			return nil, fmt.Errorf("failed to encode PNG: %w", err)
+			return Thumbnail{}, fmt.Errorf("failed to encode PNG: %w", err)
 		}
 	case "webp":
 		// For WebP, we'd need a WebP encoder library
@@ -143,16 +265,16 @@ func (g *ThumbnailGenerator) generateThumbnail(ctx context.Context, img image.Im
 		err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: config.Quality})
 		if err != nil {
 			span.RecordError(err)
-			return nil, fmt.Errorf("failed to encode WebP (fallback JPEG): %w", err)
+			return Thumbnail{}, fmt.Errorf("failed to encode WebP (fallback JPEG): %w", err)
 		}
 	default:
-		return nil, fmt.Errorf("unsupported thumbnail format: %s", config.Format)
+		return Thumbnail{}, fmt.Errorf("unsupported thumbnail format: %s", config.Format)
 	}
 
 	data := buf.Bytes()
 	span.SetAttributes(attribute.Int("thumbnail_size", len(data)))
 
-	return data, nil
+	return Thumbnail{Data: data, Width: int32(newWidth), Height: int32(newHeight)}, nil
 }
 
 // calculateDimensions calculates new dimensions while maintaining aspect ratio
@@ -183,7 +305,7 @@ func (g *ThumbnailGenerator) GetThumbnailPath(originalPath string, thumbType Thu
 	ext := filepath.Ext(filename)
 	nameWithoutExt := strings.TrimSuffix(filename, ext)
 
-	config := g.sizes[thumbType]
+	config, _ := g.config(thumbType)
 	var thumbExt string
 	switch config.Format {
 	case "webp":
@@ -226,9 +348,8 @@ func (g *ThumbnailGenerator) CanGenerateThumbnail(contentType string) bool {
 
 // GenerateVideoThumbnails extracts a frame from a video using ffmpeg and then
 // generates the standard thumbnail set (preview/webp/thumb) from that frame.
-// The frame is extracted to a temp JPEG, then passed to GenerateThumbnails.
-// Returns a map of thumbnail paths by type.
-func (g *ThumbnailGenerator) GenerateVideoThumbnails(ctx context.Context, originalPath, filename string) (map[ThumbnailType][]byte, error) {
+// Returns the thumbnails by type.
+func (g *ThumbnailGenerator) GenerateVideoThumbnails(ctx context.Context, originalPath, filename string) (map[ThumbnailType]Thumbnail, error) {
 	ctx, span := tracer.Start(ctx, "thumbnails.generate_video",
 		trace.WithAttributes(
 			attribute.String("original_path", originalPath),
@@ -236,15 +357,32 @@ func (g *ThumbnailGenerator) GenerateVideoThumbnails(ctx context.Context, origin
 		))
 	defer span.End()
 
+	frame, err := g.ExtractVideoFrame(ctx, originalPath)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	// Generate thumbnails from the frame (same as image thumbnails)
+	thumbnails := g.GenerateThumbnailsFromImage(ctx, frame, DefaultThumbnailOrder())
+
+	span.SetAttributes(attribute.Int("thumbnails_generated", len(thumbnails)))
+	return thumbnails, nil
+}
+
+// ExtractVideoFrame extracts a representative frame from a video file using
+// ffmpeg and decodes it, ready for GenerateThumbnailsFromImage. The frame is
+// taken a tenth into the video, and never before its first second, to skip
+// black lead-in frames. The error wraps ffmpeg.ErrFFmpegNotFound when ffmpeg
+// is not installed.
+func (g *ThumbnailGenerator) ExtractVideoFrame(ctx context.Context, originalPath string) (image.Image, error) {
 	if !ffmpeg.IsAvailable() {
-		span.SetAttributes(attribute.String("error", "ffmpeg_not_found"))
-		return nil, fmt.Errorf("ffmpeg not available, cannot generate video thumbnails")
+		return nil, fmt.Errorf("cannot generate video thumbnails: %w", ffmpeg.ErrFFmpegNotFound)
 	}
 
 	// Create a temp file for the extracted frame
 	tmpFile, err := os.CreateTemp("", "video-frame-*.jpg")
 	if err != nil {
-		span.RecordError(err)
 		return nil, fmt.Errorf("failed to create temp file for video frame: %w", err)
 	}
 	tmpPath := tmpFile.Name()
@@ -254,47 +392,50 @@ func (g *ThumbnailGenerator) GenerateVideoThumbnails(ctx context.Context, origin
 	// Extract a frame from the video
 	opts := ffmpeg.DefaultExtractFrameOptions()
 	if err := ffmpeg.ExtractVideoFrame(ctx, originalPath, tmpPath, opts); err != nil {
-		span.RecordError(err)
 		return nil, fmt.Errorf("failed to extract video frame: %w", err)
 	}
 
 	// Open the extracted frame
 	frameFile, err := os.Open(tmpPath)
 	if err != nil {
-		span.RecordError(err)
 		return nil, fmt.Errorf("failed to open extracted frame: %w", err)
 	}
 	defer frameFile.Close()
 
-	// Generate thumbnails from the frame (same as image thumbnails)
-	thumbnails, err := g.GenerateThumbnails(ctx, frameFile, filename)
+	frame, _, err := image.Decode(frameFile)
 	if err != nil {
-		span.RecordError(err)
-		return nil, fmt.Errorf("failed to generate thumbnails from video frame: %w", err)
+		return nil, fmt.Errorf("failed to decode video frame: %w", err)
 	}
-
-	span.SetAttributes(attribute.Int("thumbnails_generated", len(thumbnails)))
-	return thumbnails, nil
+	return frame, nil
 }
 
 // GetThumbnailInfo returns information about a generated thumbnail
-func (g *ThumbnailGenerator) GetThumbnailInfo(thumbType ThumbnailType, data []byte, path string) ThumbnailInfo {
-	config := g.sizes[thumbType]
-
-	// For a more accurate implementation, you'd decode the thumbnail
-	// to get actual dimensions. For now, we'll use the max dimensions.
+func (g *ThumbnailGenerator) GetThumbnailInfo(thumbType ThumbnailType, thumbnail Thumbnail, path string) ThumbnailInfo {
 	return ThumbnailInfo{
 		Type:   thumbType,
 		Path:   path,
-		Width:  int32(config.MaxWidth),
-		Height: int32(config.MaxHeight),
-		Size:   int64(len(data)),
+		Width:  thumbnail.Width,
+		Height: thumbnail.Height,
+		Size:   int64(len(thumbnail.Data)),
+	}
+}
+
+// ThumbnailFileParams returns the asset_files record of thumbnail, stored
+// at path as the thumbType thumbnail of an asset.
+func ThumbnailFileParams(assetID pgtype.UUID, thumbType ThumbnailType, path string, thumbnail Thumbnail) sqlc.UpsertAssetFileParams {
+	return sqlc.UpsertAssetFileParams{
+		AssetId:     assetID,
+		Type:        string(thumbType),
+		Path:        path,
+		Width:       pgtype.Int4{Int32: thumbnail.Width, Valid: true},
+		Height:      pgtype.Int4{Int32: thumbnail.Height, Valid: true},
+		SizeInBytes: pgtype.Int8{Int64: int64(len(thumbnail.Data)), Valid: true},
 	}
 }
 
 // GetThumbnailDimensions returns the max width and height for a thumbnail type
 func (g *ThumbnailGenerator) GetThumbnailDimensions(thumbType ThumbnailType) (width, height int32) {
-	config, ok := g.sizes[thumbType]
+	config, ok := g.config(thumbType)
 	if !ok {
 		return 0, 0
 	}
@@ -8,9 +8,13 @@ import (
 	"image/png"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
 )
 
 // createTestPNG creates a synthetic PNG image in memory for use in tests.
@@ -51,7 +55,7 @@ func TestGenerateThumbnails_FromJPEG(t *testing.T) {
 	}
 
 	for _, thumbType := range []ThumbnailType{ThumbnailTypePreview, ThumbnailTypeWebp, ThumbnailTypeThumb} {
-		data := thumbnails[thumbType]
+		data := thumbnails[thumbType].Data
 
 		// Data must be non-empty.
 		assert.NotEmpty(t, data, "thumbnail %s data is empty", thumbType)
@@ -68,6 +72,8 @@ func TestGenerateThumbnails_FromJPEG(t *testing.T) {
 		maxW, maxH := maxDims[thumbType][0], maxDims[thumbType][1]
 		gotW := thumbImg.Bounds().Dx()
 		gotH := thumbImg.Bounds().Dy()
+		assert.Equal(t, int32(gotW), thumbnails[thumbType].Width, "thumbnail %s reports its width", thumbType)
+		assert.Equal(t, int32(gotH), thumbnails[thumbType].Height, "thumbnail %s reports its height", thumbType)
 
 		assert.LessOrEqual(t, gotW, maxW, "thumbnail %s width %d exceeds max %d", thumbType, gotW, maxW)
 		assert.LessOrEqual(t, gotH, maxH, "thumbnail %s height %d exceeds max %d", thumbType, gotH, maxH)
@@ -106,7 +112,7 @@ func TestGenerateThumbnails_FromPNG(t *testing.T) {
 	}
 
 	for _, thumbType := range []ThumbnailType{ThumbnailTypePreview, ThumbnailTypeWebp, ThumbnailTypeThumb} {
-		data := thumbnails[thumbType]
+		data := thumbnails[thumbType].Data
 
 		assert.NotEmpty(t, data, "thumbnail %s data is empty", thumbType)
 
@@ -122,6 +128,8 @@ func TestGenerateThumbnails_FromPNG(t *testing.T) {
 		maxW, maxH := maxDims[thumbType][0], maxDims[thumbType][1]
 		gotW := thumbImg.Bounds().Dx()
 		gotH := thumbImg.Bounds().Dy()
+		assert.Equal(t, int32(gotW), thumbnails[thumbType].Width, "thumbnail %s reports its width", thumbType)
+		assert.Equal(t, int32(gotH), thumbnails[thumbType].Height, "thumbnail %s reports its height", thumbType)
 
 		assert.LessOrEqual(t, gotW, maxW,
 			"thumbnail %s width %d exceeds max %d", thumbType, gotW, maxW)
@@ -134,6 +142,46 @@ func TestGenerateThumbnails_FromPNG(t *testing.T) {
 	}
 }
 
+func TestGenerateThumbnails_Dimensions(t *testing.T) {
+	g := NewThumbnailGenerator()
+
+	thumbnails, err := g.GenerateThumbnails(context.Background(), bytes.NewReader(createTestJPEG(2000, 1500)), "test.jpg")
+	require.NoError(t, err)
+
+	want := map[ThumbnailType][2]int32{
+		ThumbnailTypePreview: {1440, 1080},
+		ThumbnailTypeWebp:    {250, 187},
+		ThumbnailTypeThumb:   {160, 120},
+	}
+	for thumbType, dims := range want {
+		thumbnail := thumbnails[thumbType]
+		assert.Equal(t, dims, [2]int32{thumbnail.Width, thumbnail.Height}, "thumbnail %s", thumbType)
+
+		info := g.GetThumbnailInfo(thumbType, thumbnail, "thumb.jpg")
+		assert.Equal(t, dims, [2]int32{info.Width, info.Height})
+		assert.Equal(t, int64(len(thumbnail.Data)), info.Size)
+	}
+
+	// Images smaller than a thumbnail type are not enlarged
+	thumbnails, err = g.GenerateThumbnails(context.Background(), bytes.NewReader(createTestPNG(200, 100)), "small.png")
+	require.NoError(t, err)
+	assert.Equal(t, int32(200), thumbnails[ThumbnailTypePreview].Width)
+	assert.Equal(t, int32(100), thumbnails[ThumbnailTypePreview].Height)
+	assert.Equal(t, int32(160), thumbnails[ThumbnailTypeThumb].Width)
+	assert.Equal(t, int32(80), thumbnails[ThumbnailTypeThumb].Height)
+}
+
+func TestThumbnailFileParams(t *testing.T) {
+	thumbnail := Thumbnail{Data: []byte("jpeg bytes"), Width: 160, Height: 120}
+	params := ThumbnailFileParams(pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, ThumbnailTypeThumb, "thumbs/a_thumb.jpg", thumbnail)
+
+	assert.Equal(t, "thumb", params.Type)
+	assert.Equal(t, "thumbs/a_thumb.jpg", params.Path)
+	assert.Equal(t, pgtype.Int4{Int32: 160, Valid: true}, params.Width)
+	assert.Equal(t, pgtype.Int4{Int32: 120, Valid: true}, params.Height)
+	assert.Equal(t, pgtype.Int8{Int64: 10, Valid: true}, params.SizeInBytes)
+}
+
 func TestGenerateThumbnails_InvalidInput(t *testing.T) {
 	g := NewThumbnailGenerator()
 
@@ -225,7 +273,6 @@ func TestCanGenerateThumbnail(t *testing.T) {
 	}
 
 	unsupported := []string{
-		"video/mp4",
 		"application/pdf",
 		"text/plain",
 		"audio/mpeg",
@@ -235,6 +282,38 @@ func TestCanGenerateThumbnail(t *testing.T) {
 	for _, ct := range unsupported {
 		assert.False(t, g.CanGenerateThumbnail(ct), "expected %s to be unsupported", ct)
 	}
+
+	assert.Equal(t, ffmpeg.IsAvailable(), g.CanGenerateThumbnail("video/mp4"), "videos need ffmpeg")
+}
+
+func TestGenerateVideoThumbnails(t *testing.T) {
+	if !ffmpeg.IsAvailable() {
+		t.Skip("ffmpeg/ffprobe not available in PATH, skipping test")
+	}
+	videoPath := filepath.Join(t.TempDir(), "clip.mp4")
+	require.NoError(t, ffmpeg.GenerateTestVideo(videoPath, 320, 240, 2*time.Second))
+
+	thumbnails, err := NewThumbnailGenerator().GenerateVideoThumbnails(context.Background(), videoPath, "clip.mp4")
+	require.NoError(t, err)
+
+	for _, thumbType := range DefaultThumbnailOrder() {
+		thumbnail, ok := thumbnails[thumbType]
+		require.True(t, ok, "thumbnail %s missing", thumbType)
+		require.GreaterOrEqual(t, len(thumbnail.Data), 2)
+		assert.Equal(t, []byte{0xFF, 0xD8}, thumbnail.Data[:2], "thumbnail %s is a JPEG", thumbType)
+	}
+	assert.Equal(t, int32(160), thumbnails[ThumbnailTypeThumb].Width)
+	assert.Equal(t, int32(120), thumbnails[ThumbnailTypeThumb].Height)
+	assert.Equal(t, int32(320), thumbnails[ThumbnailTypePreview].Width, "small videos are not enlarged")
+}
+
+func TestExtractVideoFrame_FFmpegMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	g := NewThumbnailGenerator()
+
+	_, err := g.ExtractVideoFrame(context.Background(), "clip.mp4")
+	assert.ErrorIs(t, err, ffmpeg.ErrFFmpegNotFound)
+	assert.False(t, g.CanGenerateThumbnail("video/mp4"))
 }
 
 func TestCalculateDimensions(t *testing.T) {
@@ -309,3 +388,92 @@ func TestCalculateDimensions(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateThumbnailsFromImage_OnlyRequestedTypes(t *testing.T) {
+	g := NewThumbnailGenerator()
+
+	img, err := g.DecodeImage(bytes.NewReader(createTestPNG(400, 300)), 0)
+	require.NoError(t, err)
+
+	thumbnails := g.GenerateThumbnailsFromImage(context.Background(), img,
+		[]ThumbnailType{ThumbnailTypeThumb, ThumbnailType("unknown")})
+
+	assert.Len(t, thumbnails, 1)
+	assert.NotEmpty(t, thumbnails[ThumbnailTypeThumb].Data)
+}
+
+func TestDecodeImage_RejectsImagesOverDecodeLimit(t *testing.T) {
+	g := NewThumbnailGenerator()
+
+	_, err := g.DecodeImage(bytes.NewReader(createTestPNG(400, 300)), 200)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "decode limit")
+
+	img, err := g.DecodeImage(bytes.NewReader(createTestPNG(400, 300)), 400)
+	require.NoError(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+}
+
+func TestConfigure(t *testing.T) {
+	g := NewThumbnailGenerator()
+	path := g.GetThumbnailPath("photos/a.jpg", ThumbnailTypeWebp)
+
+	g.Configure(ThumbnailTypeWebp, 100, 60)
+	width, height := g.GetThumbnailDimensions(ThumbnailTypeWebp)
+	assert.Equal(t, int32(100), width)
+	assert.Equal(t, int32(100), height)
+	assert.Equal(t, path, g.GetThumbnailPath("photos/a.jpg", ThumbnailTypeWebp), "thumbnails stay where they are")
+
+	img, err := g.DecodeImage(bytes.NewReader(createTestPNG(400, 300)), 0)
+	require.NoError(t, err)
+	thumb, _, err := image.Decode(bytes.NewReader(
+		g.GenerateThumbnailsFromImage(context.Background(), img, []ThumbnailType{ThumbnailTypeWebp})[ThumbnailTypeWebp].Data))
+	require.NoError(t, err)
+	assert.Equal(t, 100, thumb.Bounds().Dx())
+
+	// Out of range values keep the current ones
+	g.Configure(ThumbnailTypeWebp, 0, 101)
+	width, _ = g.GetThumbnailDimensions(ThumbnailTypeWebp)
+	assert.Equal(t, int32(100), width)
+	config, _ := g.config(ThumbnailTypeWebp)
+	assert.Equal(t, 60, config.Quality)
+}
+
+func TestSetPresets(t *testing.T) {
+	g := NewThumbnailGenerator()
+	g.SetPresets([]ThumbnailPreset{
+		{Name: "grid", Size: 120, Quality: 60},
+		{Name: "detail", Size: 2048, Quality: 85},
+		{Name: "same", Size: 120, Quality: 60},
+		{Name: "", Size: 100, Quality: 50},
+		{Name: "tiny", Size: 0, Quality: 50},
+		{Name: "lossless", Size: 100, Quality: 101},
+	})
+
+	grid, ok := g.Preset("grid")
+	require.True(t, ok)
+	assert.Equal(t, PresetThumbnailType(120, 60), grid)
+	same, _ := g.Preset("same")
+	assert.Equal(t, grid, same, "presets with the same settings share their thumbnails")
+	for _, name := range []string{"tiny", "lossless", "thumb"} {
+		_, ok := g.Preset(name)
+		assert.False(t, ok, name)
+	}
+	assert.Equal(t, "photos/thumbnails/a_preset-120-q60.jpg", g.GetThumbnailPath("photos/a.jpg", grid))
+
+	img, err := g.DecodeImage(bytes.NewReader(createTestPNG(400, 300)), 0)
+	require.NoError(t, err)
+	thumb, _, err := image.Decode(bytes.NewReader(
+		g.GenerateThumbnailsFromImage(context.Background(), img, []ThumbnailType{grid})[grid].Data))
+	require.NoError(t, err)
+	assert.Equal(t, 120, thumb.Bounds().Dx())
+
+	// Replacing the presets drops the ones left out
+	g.SetPresets([]ThumbnailPreset{{Name: "detail", Size: 2048, Quality: 85}})
+	_, ok = g.Preset("grid")
+	assert.False(t, ok)
+	width, _ := g.GetThumbnailDimensions(grid)
+	assert.Zero(t, width)
+	width, _ = g.GetThumbnailDimensions(ThumbnailTypeThumb)
+	assert.Equal(t, int32(160), width, "the built-in types are kept")
+}
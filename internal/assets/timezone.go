@@ -0,0 +1,300 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// EXIF 2.31 offset tags. goexif only loads the tags it knows about from the
+// Exif sub-IFD, so offsetTimeParser re-reads that directory for these.
+const (
+	exifOffsetTime          exif.FieldName = "OffsetTime"
+	exifOffsetTimeOriginal  exif.FieldName = "OffsetTimeOriginal"
+	exifOffsetTimeDigitized exif.FieldName = "OffsetTimeDigitized"
+)
+
+var offsetTimeFields = map[uint16]exif.FieldName{
+	0x9010: exifOffsetTime,
+	0x9011: exifOffsetTimeOriginal,
+	0x9012: exifOffsetTimeDigitized,
+}
+
+const exifDateTimeLayout = "2006:01:02 15:04:05"
+
+func init() {
+	exif.RegisterParsers(offsetTimeParser{})
+}
+
+// offsetTimeParser loads the OffsetTime* tags into the decoded EXIF data.
+type offsetTimeParser struct{}
+
+func (offsetTimeParser) Parse(x *exif.Exif) error {
+	ptr, err := x.Get(exif.ExifIFDPointer)
+	if err != nil {
+		return nil
+	}
+	offset, err := ptr.Int64(0)
+	if err != nil {
+		return nil
+	}
+	r := bytes.NewReader(x.Raw)
+	if _, err := r.Seek(offset, 0); err != nil {
+		return nil
+	}
+	dir, _, err := tiff.DecodeDir(r, x.Tiff.Order)
+	if err != nil {
+		return nil
+	}
+	x.LoadTags(dir, offsetTimeFields, false)
+	return nil
+}
+
+// captureTime resolves the capture instant and timezone of an image from its
+// EXIF data. The wall-clock DateTimeOriginal is interpreted in, in order of
+// preference, OffsetTimeOriginal, the offset implied by the GPS UTC
+// timestamp, and finally fallback when the capture timezone is unknown.
+func captureTime(x *exif.Exif, fallback *time.Location) (*time.Time, *string) {
+	wallClock, ok := exifWallClock(x, exif.DateTimeOriginal)
+	if !ok {
+		return nil, nil
+	}
+
+	if loc, ok := exifOffset(x, exifOffsetTimeOriginal, exifOffsetTime); ok {
+		t := reinterpretInLocation(wallClock, loc)
+		tz := loc.String()
+		return &t, &tz
+	}
+
+	if gpsTime, ok := exifGPSTime(x); ok {
+		if loc, ok := offsetFromGPS(wallClock, gpsTime); ok {
+			t := reinterpretInLocation(wallClock, loc)
+			tz := loc.String()
+			return &t, &tz
+		}
+	}
+
+	t := reinterpretInLocation(wallClock, orUTC(fallback))
+	return &t, nil
+}
+
+// exifWallClock reads an EXIF date/time tag as a UTC wall-clock time.
+func exifWallClock(x *exif.Exif, name exif.FieldName) (time.Time, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return time.Time{}, false
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(exifDateTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// exifOffset returns the location described by the first present offset tag.
+func exifOffset(x *exif.Exif, names ...exif.FieldName) (*time.Location, bool) {
+	for _, name := range names {
+		tag, err := x.Get(name)
+		if err != nil {
+			continue
+		}
+		value, err := tag.StringVal()
+		if err != nil {
+			continue
+		}
+		if loc, ok := ParseTimeZone(value); ok {
+			return loc, true
+		}
+	}
+	return nil, false
+}
+
+// exifGPSTime returns the UTC instant recorded by the GPS receiver.
+func exifGPSTime(x *exif.Exif) (time.Time, bool) {
+	dateTag, err := x.Get(exif.GPSDateStamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	dateStr, err := dateTag.StringVal()
+	if err != nil {
+		return time.Time{}, false
+	}
+	date, err := time.Parse("2006:01:02", strings.TrimSpace(dateStr))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	timeTag, err := x.Get(exif.GPSTimeStamp)
+	if err != nil || timeTag.Count < 3 {
+		return time.Time{}, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num, denom, err := timeTag.Rat2(i)
+		if err != nil || denom == 0 {
+			return time.Time{}, false
+		}
+		parts[i] = float64(num) / float64(denom)
+	}
+
+	seconds := parts[0]*3600 + parts[1]*60 + parts[2]
+	return date.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// offsetFromGPS derives the capture timezone from the difference between the
+// camera wall clock and the GPS UTC time, rounded to the nearest quarter hour.
+func offsetFromGPS(wallClock, gpsTime time.Time) (*time.Location, bool) {
+	diff := wallClock.Sub(gpsTime)
+	quarters := math.Round(diff.Minutes() / 15)
+	offsetMinutes := int(quarters) * 15
+	if offsetMinutes < -12*60 || offsetMinutes > 14*60 {
+		return nil, false
+	}
+	return fixedZone(offsetMinutes * 60), true
+}
+
+// reinterpretInLocation treats the wall clock of t as a time in loc.
+func reinterpretInLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc).UTC()
+}
+
+// ParseTimeZone parses a timezone as stored in exif."timeZone" or found in
+// EXIF offset tags: IANA names ("Europe/Zurich"), "UTC", "UTC+2",
+// "UTC-05:30" and bare offsets ("+09:00").
+func ParseTimeZone(value string) (*time.Location, bool) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if value == "" {
+		return nil, false
+	}
+	if strings.EqualFold(value, "UTC") || strings.EqualFold(value, "Z") {
+		return time.UTC, true
+	}
+
+	offset := value
+	if len(value) > 3 && strings.EqualFold(value[:3], "UTC") {
+		offset = value[3:]
+	}
+	if offset[0] == '+' || offset[0] == '-' {
+		seconds, ok := parseOffset(offset)
+		if !ok {
+			return nil, false
+		}
+		return fixedZone(seconds), true
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+// parseOffset parses "+H", "+HH", "+HHMM" and "+HH:MM" offsets into seconds.
+func parseOffset(offset string) (int, bool) {
+	sign := 1
+	if offset[0] == '-' {
+		sign = -1
+	}
+	rest := strings.ReplaceAll(offset[1:], ":", "")
+
+	var hours, minutes int
+	var err error
+	switch len(rest) {
+	case 1, 2:
+		hours, err = strconv.Atoi(rest)
+	case 3, 4:
+		hours, err = strconv.Atoi(rest[:len(rest)-2])
+		if err == nil {
+			minutes, err = strconv.Atoi(rest[len(rest)-2:])
+		}
+	default:
+		return 0, false
+	}
+	if err != nil || hours > 14 || minutes > 59 {
+		return 0, false
+	}
+	return sign * (hours*3600 + minutes*60), true
+}
+
+// fixedZone returns a fixed-offset location named like Immich's offset
+// timezones ("UTC", "UTC+2", "UTC-5:30").
+func fixedZone(offsetSeconds int) *time.Location {
+	if offsetSeconds == 0 {
+		return time.UTC
+	}
+	sign := "+"
+	abs := offsetSeconds
+	if abs < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	name := fmt.Sprintf("UTC%s%d", sign, abs/3600)
+	if minutes := (abs % 3600) / 60; minutes != 0 {
+		name = fmt.Sprintf("%s:%02d", name, minutes)
+	}
+	return time.FixedZone(name, offsetSeconds)
+}
+
+// LocalDateTime converts a capture instant to Immich's localDateTime: the
+// wall-clock time at the capture location, stored as if it were UTC. When the
+// timezone is unknown the wall clock in fallback is used (UTC when nil).
+func LocalDateTime(taken time.Time, timeZone *string, fallback *time.Location) time.Time {
+	loc := fallback
+	if timeZone != nil {
+		if parsed, ok := ParseTimeZone(*timeZone); ok {
+			loc = parsed
+		}
+	}
+	return WallClock(taken, loc)
+}
+
+// WallClock returns the wall-clock time of t in loc, stored as if it were
+// UTC, which is how localDateTime columns are compared and bucketed.
+func WallClock(t time.Time, loc *time.Location) time.Time {
+	wall := t.In(orUTC(loc))
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC)
+}
+
+// UploadTimes returns the creation and modification times of an uploaded
+// file from the ones its client sent, either standing in for the other, and
+// now for both when it sent neither. dated reports whether the client sent
+// any: an upload without one stays undated until metadata extraction finds
+// a capture date.
+func UploadTimes(createdAt, modifiedAt *time.Time, now time.Time) (created, modified time.Time, dated bool) {
+	switch {
+	case createdAt != nil && modifiedAt != nil:
+		return *createdAt, *modifiedAt, true
+	case createdAt != nil:
+		return *createdAt, *createdAt, true
+	case modifiedAt != nil:
+		return *modifiedAt, *modifiedAt, true
+	}
+	return now, now, false
+}
+
+// LocalDayBounds returns the instants at which the calendar day containing
+// t starts and ends in loc. Around DST changes the day is 23 or 25 hours
+// long rather than 24.
+func LocalDayBounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	wall := t.In(orUTC(loc))
+	start := time.Date(wall.Year(), wall.Month(), wall.Day(), 0, 0, 0, 0, wall.Location())
+	end := time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, wall.Location())
+	return start, end
+}
+
+func orUTC(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
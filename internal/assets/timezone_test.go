@@ -0,0 +1,256 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithExifStrings embeds ASCII tags in the Exif sub-IFD of jpegData.
+func jpegWithExifStrings(jpegData []byte, tags map[uint16]string) []byte {
+	ids := make([]uint16, 0, len(tags))
+	for id := range tags {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	const subIFD = 26
+	dataOffset := subIFD + 2 + 12*len(ids) + 4
+	tiff := make([]byte, dataOffset)
+	copy(tiff, "II")
+	binary.LittleEndian.PutUint16(tiff[2:], 42)
+	binary.LittleEndian.PutUint32(tiff[4:], 8)
+	binary.LittleEndian.PutUint16(tiff[8:], 1)
+	binary.LittleEndian.PutUint16(tiff[10:], 0x8769)
+	binary.LittleEndian.PutUint16(tiff[12:], 4)
+	binary.LittleEndian.PutUint32(tiff[14:], 1)
+	binary.LittleEndian.PutUint32(tiff[18:], subIFD)
+	binary.LittleEndian.PutUint16(tiff[subIFD:], uint16(len(ids)))
+	for i, id := range ids {
+		value := append([]byte(tags[id]), 0)
+		entry := tiff[subIFD+2+12*i:]
+		binary.LittleEndian.PutUint16(entry, id)
+		binary.LittleEndian.PutUint16(entry[2:], 2)
+		binary.LittleEndian.PutUint32(entry[4:], uint32(len(value)))
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(tiff)))
+		tiff = append(tiff, value...)
+	}
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint16(segment, 0xffe1)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	copy(segment[4:], payload)
+
+	result := append([]byte{}, jpegData[:2]...)
+	result = append(result, segment...)
+	return append(result, jpegData[2:]...)
+}
+
+func TestExtractMetadata_OffsetTimeOriginal(t *testing.T) {
+	imgBytes := jpegWithExifStrings(createTestJPEG(64, 48), map[uint16]string{
+		0x9003: "2023:05:01 14:00:00",
+		0x9011: "+09:00",
+	})
+
+	meta, err := NewMetadataExtractor().ExtractMetadata(
+		context.Background(), bytes.NewReader(imgBytes), "tokyo.jpg", "image/jpeg", int64(len(imgBytes)),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, meta.DateTaken)
+	require.NotNil(t, meta.TimeZone)
+	assert.Equal(t, "UTC+9", *meta.TimeZone)
+	assert.True(t, time.Date(2023, time.May, 1, 5, 0, 0, 0, time.UTC).Equal(*meta.DateTaken))
+	assert.Equal(t, time.Date(2023, time.May, 1, 14, 0, 0, 0, time.UTC), LocalDateTime(*meta.DateTaken, meta.TimeZone, nil))
+}
+
+func TestExtractMetadata_WithoutOffsetKeepsWallClock(t *testing.T) {
+	imgBytes := jpegWithExifDate(createTestJPEG(64, 48), "2017:07:22 22:14:34")
+
+	meta, err := NewMetadataExtractor().ExtractMetadata(
+		context.Background(), bytes.NewReader(imgBytes), "dated.jpg", "image/jpeg", int64(len(imgBytes)),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, meta.DateTaken)
+	assert.Nil(t, meta.TimeZone)
+	assert.Equal(t, time.Date(2017, time.July, 22, 22, 14, 34, 0, time.UTC), LocalDateTime(*meta.DateTaken, meta.TimeZone, nil))
+}
+
+func TestExtractMetadata_WithoutOffsetUsesDefaultLocation(t *testing.T) {
+	imgBytes := jpegWithExifDate(createTestJPEG(64, 48), "2017:07:22 22:14:34")
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+
+	meta, err := NewMetadataExtractor().WithDefaultLocation(zurich).ExtractMetadata(
+		context.Background(), bytes.NewReader(imgBytes), "dated.jpg", "image/jpeg", int64(len(imgBytes)),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, meta.DateTaken)
+	assert.Nil(t, meta.TimeZone)
+	assert.True(t, time.Date(2017, time.July, 22, 20, 14, 34, 0, time.UTC).Equal(*meta.DateTaken))
+	assert.Equal(t, time.Date(2017, time.July, 22, 22, 14, 34, 0, time.UTC), LocalDateTime(*meta.DateTaken, meta.TimeZone, zurich))
+}
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+		ok     bool
+	}{
+		{"UTC", 0, true},
+		{"+09:00", 9 * 3600, true},
+		{"-05:30", -(5*3600 + 30*60), true},
+		{"UTC+2", 2 * 3600, true},
+		{"UTC-5:30", -(5*3600 + 30*60), true},
+		{"+0545", 5*3600 + 45*60, true},
+		{"Asia/Tokyo", 9 * 3600, true},
+		{"", 0, false},
+		{"+25:00", 0, false},
+		{"Not/AZone", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			loc, ok := ParseTimeZone(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			if !ok {
+				return
+			}
+			_, offset := time.Date(2024, time.January, 15, 12, 0, 0, 0, loc).Zone()
+			assert.Equal(t, tt.offset, offset)
+		})
+	}
+}
+
+func TestOffsetFromGPS(t *testing.T) {
+	wallClock := time.Date(2023, time.May, 1, 14, 0, 3, 0, time.UTC)
+	gpsTime := time.Date(2023, time.May, 1, 5, 0, 0, 0, time.UTC)
+
+	loc, ok := offsetFromGPS(wallClock, gpsTime)
+	require.True(t, ok)
+	assert.Equal(t, "UTC+9", loc.String())
+
+	_, ok = offsetFromGPS(wallClock, wallClock.Add(-20*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestLocalDateTime(t *testing.T) {
+	taken := time.Date(2024, time.March, 31, 0, 30, 0, 0, time.UTC)
+
+	zurich := "Europe/Zurich"
+	assert.Equal(t, time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC), LocalDateTime(taken, &zurich, nil))
+
+	invalid := "garbage"
+	assert.Equal(t, taken, LocalDateTime(taken, &invalid, nil))
+	assert.Equal(t, taken, LocalDateTime(taken, nil, nil))
+
+	// The recorded timezone wins over the fallback.
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+	assert.Equal(t, time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC), LocalDateTime(taken, &zurich, tokyo))
+	assert.Equal(t, time.Date(2024, time.March, 31, 9, 30, 0, 0, time.UTC), LocalDateTime(taken, &invalid, tokyo))
+}
+
+func TestUploadTimes(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 12, 0, 0, 0, time.UTC)
+	created := time.Date(2012, time.July, 4, 18, 30, 0, 0, time.UTC)
+	modified := time.Date(2013, time.January, 2, 9, 0, 0, 0, time.UTC)
+
+	c, m, dated := UploadTimes(&created, &modified, now)
+	assert.Equal(t, created, c)
+	assert.Equal(t, modified, m)
+	assert.True(t, dated)
+
+	c, m, dated = UploadTimes(&created, nil, now)
+	assert.Equal(t, created, c)
+	assert.Equal(t, created, m)
+	assert.True(t, dated)
+
+	c, m, dated = UploadTimes(nil, &modified, now)
+	assert.Equal(t, modified, c, "the modification time stands in for the creation time")
+	assert.Equal(t, modified, m)
+	assert.True(t, dated)
+
+	c, m, dated = UploadTimes(nil, nil, now)
+	assert.Equal(t, now, c)
+	assert.Equal(t, now, m)
+	assert.False(t, dated)
+}
+
+func TestLocalDateTime_FallbackAcrossDST(t *testing.T) {
+	zurich := mustLoadLocation(t, "Europe/Zurich")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		taken time.Time
+		loc   *time.Location
+		want  time.Time
+	}{
+		{"zurich before spring forward", time.Date(2024, time.March, 31, 0, 30, 0, 0, time.UTC), zurich, time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC)},
+		{"zurich after spring forward", time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC), zurich, time.Date(2024, time.March, 31, 3, 30, 0, 0, time.UTC)},
+		{"zurich first 02:30 of fall back", time.Date(2024, time.October, 27, 0, 30, 0, 0, time.UTC), zurich, time.Date(2024, time.October, 27, 2, 30, 0, 0, time.UTC)},
+		{"zurich second 02:30 of fall back", time.Date(2024, time.October, 27, 1, 30, 0, 0, time.UTC), zurich, time.Date(2024, time.October, 27, 2, 30, 0, 0, time.UTC)},
+		{"new york evening before spring forward", time.Date(2024, time.March, 10, 4, 30, 0, 0, time.UTC), newYork, time.Date(2024, time.March, 9, 23, 30, 0, 0, time.UTC)},
+		{"new york after spring forward", time.Date(2024, time.March, 10, 7, 30, 0, 0, time.UTC), newYork, time.Date(2024, time.March, 10, 3, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LocalDateTime(tt.taken, nil, tt.loc))
+		})
+	}
+}
+
+func TestLocalDateTime_InternationalDateLine(t *testing.T) {
+	// The same instant falls on three different calendar days either side
+	// of the date line.
+	taken := time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, time.January, 2, 0, 30, 0, 0, time.UTC), LocalDateTime(taken, nil, mustLoadLocation(t, "Pacific/Kiritimati")))
+	assert.Equal(t, time.Date(2023, time.December, 31, 23, 30, 0, 0, time.UTC), LocalDateTime(taken, nil, mustLoadLocation(t, "Pacific/Pago_Pago")))
+	assert.Equal(t, time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC), LocalDateTime(taken, nil, nil))
+}
+
+func TestLocalDayBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        time.Time
+		zone      string
+		wantStart time.Time
+		wantHours float64
+	}{
+		{"zurich spring forward", time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC), "Europe/Zurich", time.Date(2024, time.March, 30, 23, 0, 0, 0, time.UTC), 23},
+		{"zurich fall back", time.Date(2024, time.October, 27, 12, 0, 0, 0, time.UTC), "Europe/Zurich", time.Date(2024, time.October, 26, 22, 0, 0, 0, time.UTC), 25},
+		{"zurich regular day", time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC), "Europe/Zurich", time.Date(2024, time.May, 31, 22, 0, 0, 0, time.UTC), 24},
+		{"new york spring forward", time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC), "America/New_York", time.Date(2024, time.March, 10, 5, 0, 0, 0, time.UTC), 23},
+		{"kiritimati is already tomorrow", time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC), "Pacific/Kiritimati", time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC), 24},
+		{"pago pago is still yesterday", time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC), "Pacific/Pago_Pago", time.Date(2023, time.December, 31, 11, 0, 0, 0, time.UTC), 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := LocalDayBounds(tt.at, mustLoadLocation(t, tt.zone))
+			assert.True(t, tt.wantStart.Equal(start), "start %s", start.UTC())
+			assert.Equal(t, tt.wantHours, end.Sub(start).Hours())
+			assert.False(t, tt.at.Before(start))
+			assert.True(t, tt.at.Before(end))
+		})
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestQuickTimeTimeZone(t *testing.T) {
+	tz := quickTimeTimeZone(map[string]string{"com.apple.quicktime.creationdate": "2023-05-01T14:00:00+0900"})
+	require.NotNil(t, tz)
+	assert.Equal(t, "UTC+9", *tz)
+
+	assert.Nil(t, quickTimeTimeZone(map[string]string{"creation_time": "2023-05-01T05:00:00Z"}))
+}
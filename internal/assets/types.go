@@ -39,8 +39,18 @@ const (
 	ThumbnailTypePreview ThumbnailType = "preview" // 1440px
 	ThumbnailTypeWebp    ThumbnailType = "webp"    // 250px
 	ThumbnailTypeThumb   ThumbnailType = "thumb"   // 160px
+
+	// ThumbnailTypeFullsize is a full-resolution, web-compatible copy of an
+	// original browsers cannot display, created on first view.
+	ThumbnailTypeFullsize ThumbnailType = "fullsize"
 )
 
+// DefaultThumbnailOrder returns the order thumbnails are generated in when
+// none is configured: smallest first, so the timeline grid fills quickly.
+func DefaultThumbnailOrder() []ThumbnailType {
+	return []ThumbnailType{ThumbnailTypeThumb, ThumbnailTypeWebp, ThumbnailTypePreview}
+}
+
 // AssetStatus represents the processing status of an asset
 type AssetStatus string
 
@@ -59,6 +69,12 @@ type UploadRequest struct {
 	ContentType string    `json:"contentType" binding:"required"`
 	Size        int64     `json:"size" binding:"required"`
 	Checksum    string    `json:"checksum,omitempty"`
+	// ChecksumAlgorithm of Checksum; inferred from its length when empty.
+	ChecksumAlgorithm ChecksumAlgorithm `json:"checksumAlgorithm,omitempty"`
+	// FileCreatedAt and FileModifiedAt are the file's times on the device.
+	// The upload time stands in for them when the client knows neither.
+	FileCreatedAt  *time.Time `json:"fileCreatedAt,omitempty"`
+	FileModifiedAt *time.Time `json:"fileModifiedAt,omitempty"`
 }
 
 // UploadResponse represents the response for an upload request
@@ -81,6 +97,7 @@ type AssetMetadata struct {
 	CreatedAt  time.Time  `json:"createdAt"`
 	ModifiedAt time.Time  `json:"modifiedAt"`
 	DateTaken  *time.Time `json:"dateTaken,omitempty"`
+	TimeZone   *string    `json:"timeZone,omitempty"` // Capture timezone, e.g. "UTC+2" or "Europe/Zurich"
 
 	// Image/Video specific
 	Width    *int32   `json:"width,omitempty"`
@@ -108,6 +125,16 @@ type AssetMetadata struct {
 	// Additional metadata
 	Description *string  `json:"description,omitempty"`
 	Keywords    []string `json:"keywords,omitempty"`
+
+	// Warnings explains why metadata is partial, e.g. a file over a limit
+	Warnings []string `json:"warnings,omitempty"`
+
+	// exceedsDecodeLimit marks images too large to decode for thumbnails
+	exceedsDecodeLimit bool
+}
+
+func (m *AssetMetadata) warn(warning string) {
+	m.Warnings = append(m.Warnings, warning)
 }
 
 // AssetInfo represents complete asset information
@@ -157,8 +184,10 @@ type SearchRequest struct {
 	Model     *string    `json:"model,omitempty"`
 	Limit     int        `json:"limit,omitempty"`
 	Offset    int        `json:"offset,omitempty"`
-	SortBy    string     `json:"sortBy,omitempty"`    // "created_at", "date_taken", "filename"
-	SortOrder string     `json:"sortOrder,omitempty"` // "asc", "desc"
+	// SortBy and SortOrder override the configured default order of the
+	// listing of all assets
+	SortBy    string `json:"sortBy,omitempty"`    // "date_taken", "created_at", "filename"
+	SortOrder string `json:"sortOrder,omitempty"` // "asc", "desc"
 }
 
 // SearchResponse represents search results
@@ -0,0 +1,385 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// Metadata write-back copies edited capture times and locations from the
+// database into the stored files, so an exported original carries them.
+//
+// Formats exiftool can rewrite get the tags embedded in the original; the
+// first rewrite keeps the uploaded bytes as an original_backup asset file,
+// which is what the asset checksum keeps identifying. Everything else, and
+// every format when exiftool is not installed, gets an XMP sidecar next to
+// the original instead. An existing sidecar is backed up the same way before
+// it is first changed.
+
+const (
+	assetFileTypeOriginalBackup = "original_backup"
+	assetFileTypeSidecarBackup  = "sidecar_backup"
+
+	writeBackBackupSuffix  = ".original"
+	writeBackSidecarSuffix = ".xmp"
+)
+
+// WriteBackTarget says where metadata was written.
+type WriteBackTarget string
+
+const (
+	WriteBackNone     WriteBackTarget = ""
+	WriteBackOriginal WriteBackTarget = "original"
+	WriteBackSidecar  WriteBackTarget = "sidecar"
+)
+
+var errExiftoolNotFound = errors.New("exiftool not found in PATH")
+
+// exiftoolWritableExtensions are the formats whose originals get the tags
+// embedded. Videos and camera RAW files only get a sidecar.
+var exiftoolWritableExtensions = map[string]struct{}{
+	".jpg": {}, ".jpeg": {}, ".heic": {}, ".heif": {}, ".png": {},
+	".tif": {}, ".tiff": {}, ".webp": {}, ".dng": {},
+}
+
+// MetadataWriteBack is the edited metadata of an asset.
+type MetadataWriteBack struct {
+	DateTimeOriginal *time.Time
+	TimeZone         *string
+	Latitude         *float64
+	Longitude        *float64
+}
+
+func metadataWriteBackFromExif(e sqlc.Exif) MetadataWriteBack {
+	var m MetadataWriteBack
+	if e.DateTimeOriginal.Valid {
+		taken := e.DateTimeOriginal.Time
+		m.DateTimeOriginal = &taken
+	}
+	if e.TimeZone.Valid {
+		m.TimeZone = &e.TimeZone.String
+	}
+	if e.Latitude.Valid && e.Longitude.Valid {
+		m.Latitude = &e.Latitude.Float64
+		m.Longitude = &e.Longitude.Float64
+	}
+	return m
+}
+
+func (m MetadataWriteBack) empty() bool {
+	return m.DateTimeOriginal == nil && m.Latitude == nil
+}
+
+// localTaken returns the capture time in the asset's timezone, or UTC when
+// the timezone is unknown.
+func (m MetadataWriteBack) localTaken() time.Time {
+	if m.TimeZone != nil {
+		if loc, ok := ParseTimeZone(*m.TimeZone); ok {
+			return m.DateTimeOriginal.In(loc)
+		}
+	}
+	return m.DateTimeOriginal.UTC()
+}
+
+// exiftoolArgs returns the tag assignments for exiftool.
+func (m MetadataWriteBack) exiftoolArgs() []string {
+	var args []string
+	if m.DateTimeOriginal != nil {
+		taken := m.localTaken()
+		args = append(args,
+			"-DateTimeOriginal="+taken.Format("2006:01:02 15:04:05"),
+			"-OffsetTimeOriginal="+taken.Format("-07:00"),
+		)
+	}
+	if m.Latitude != nil {
+		args = append(args,
+			fmt.Sprintf("-GPSLatitude=%f", math.Abs(*m.Latitude)),
+			"-GPSLatitudeRef="+hemisphere(*m.Latitude, "N", "S"),
+			fmt.Sprintf("-GPSLongitude=%f", math.Abs(*m.Longitude)),
+			"-GPSLongitudeRef="+hemisphere(*m.Longitude, "E", "W"),
+		)
+	}
+	return args
+}
+
+// xmpSidecar renders a minimal XMP sidecar with the edited tags.
+func (m MetadataWriteBack) xmpSidecar() []byte {
+	var attrs []string
+	if m.DateTimeOriginal != nil {
+		taken := m.localTaken().Format("2006-01-02T15:04:05-07:00")
+		attrs = append(attrs,
+			fmt.Sprintf(`exif:DateTimeOriginal="%s"`, taken),
+			fmt.Sprintf(`photoshop:DateCreated="%s"`, taken),
+		)
+	}
+	if m.Latitude != nil {
+		attrs = append(attrs,
+			fmt.Sprintf(`exif:GPSLatitude="%s"`, xmpCoordinate(*m.Latitude, "N", "S")),
+			fmt.Sprintf(`exif:GPSLongitude="%s"`, xmpCoordinate(*m.Longitude, "E", "W")),
+		)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\"\n")
+	b.WriteString("    xmlns:exif=\"http://ns.adobe.com/exif/1.0/\"\n")
+	b.WriteString("    xmlns:photoshop=\"http://ns.adobe.com/photoshop/1.0/\"")
+	for _, attr := range attrs {
+		b.WriteString("\n    " + attr)
+	}
+	b.WriteString("/>\n </rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>\n")
+	return b.Bytes()
+}
+
+func hemisphere(value float64, positive, negative string) string {
+	if value < 0 {
+		return negative
+	}
+	return positive
+}
+
+// xmpCoordinate formats a coordinate the way XMP expects: "DDD,MM.mmmmmmK".
+func xmpCoordinate(value float64, positive, negative string) string {
+	abs := math.Abs(value)
+	degrees := math.Floor(abs)
+	minutes := (abs - degrees) * 60
+	return fmt.Sprintf("%d,%.6f%s", int(degrees), minutes, hemisphere(value, positive, negative))
+}
+
+// TriggerMetadataWriteBack writes an asset's edited metadata back in the
+// background.
+func (s *Service) TriggerMetadataWriteBack(assetID uuid.UUID) {
+	s.background.Go(context.Background(), 0, func(ctx context.Context) {
+		if _, err := s.WriteBackMetadata(ctx, assetID); err != nil {
+			s.logger.Error("Failed to write back asset metadata",
+				zap.Error(err),
+				zap.String("assetID", assetID.String()))
+		}
+	})
+}
+
+// WriteBackMetadata writes the asset's capture time and location from the
+// database into its original, or into an XMP sidecar when the original cannot
+// be rewritten. External library assets are left alone.
+func (s *Service) WriteBackMetadata(ctx context.Context, assetID uuid.UUID) (WriteBackTarget, error) {
+	ctx, span := tracer.Start(ctx, "assets.write_back_metadata",
+		trace.WithAttributes(attribute.String("asset_id", assetID.String())))
+	defer span.End()
+
+	id := pgtype.UUID{Bytes: assetID, Valid: true}
+	asset, err := s.db.GetAssetByID(ctx, id)
+	if err != nil {
+		return WriteBackNone, fmt.Errorf("failed to get asset: %w", err)
+	}
+	if asset.IsExternal || asset.OriginalPath == "" {
+		return WriteBackNone, nil
+	}
+
+	exif, err := s.db.GetExifByAssetId(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return WriteBackNone, nil
+	}
+	if err != nil {
+		return WriteBackNone, fmt.Errorf("failed to get asset metadata: %w", err)
+	}
+	meta := metadataWriteBackFromExif(exif)
+	if meta.empty() {
+		return WriteBackNone, nil
+	}
+
+	exiftool, exiftoolErr := exec.LookPath("exiftool")
+	if exiftoolErr != nil {
+		exiftool = ""
+	}
+
+	if _, ok := exiftoolWritableExtensions[strings.ToLower(path.Ext(asset.OriginalPath))]; ok && exiftool != "" {
+		err := s.writeBackOriginal(ctx, asset, meta, exiftool)
+		if err == nil {
+			span.SetAttributes(attribute.String("target", string(WriteBackOriginal)))
+			return WriteBackOriginal, nil
+		}
+		// The original is untouched when exiftool rejects it; fall back to a
+		// sidecar so the edit still leaves the database.
+		span.RecordError(err)
+		s.logger.Warn("Failed to write metadata into original, writing a sidecar instead",
+			zap.Error(err),
+			zap.String("assetID", assetID.String()))
+	}
+
+	if err := s.writeBackSidecar(ctx, asset, meta, exiftool); err != nil {
+		span.RecordError(err)
+		return WriteBackNone, err
+	}
+	span.SetAttributes(attribute.String("target", string(WriteBackSidecar)))
+	return WriteBackSidecar, nil
+}
+
+// writeBackOriginal embeds the metadata into the original with exiftool,
+// working on a local copy so the stored original is only replaced once
+// exiftool succeeded.
+func (s *Service) writeBackOriginal(ctx context.Context, asset sqlc.Asset, meta MetadataWriteBack, exiftool string) error {
+	local, cleanup, err := s.downloadToTemp(ctx, asset.OriginalPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := s.ensureWriteBackBackup(ctx, asset.ID, assetFileTypeOriginalBackup, asset.OriginalPath, local); err != nil {
+		return err
+	}
+	if err := runExiftool(ctx, exiftool, local, meta); err != nil {
+		return err
+	}
+
+	return s.uploadFromFile(ctx, asset.OriginalPath, local)
+}
+
+// writeBackSidecar updates the asset's XMP sidecar, creating it when there is
+// none. Existing sidecars are updated in place with exiftool when it is
+// installed, and otherwise replaced after being backed up.
+func (s *Service) writeBackSidecar(ctx context.Context, asset sqlc.Asset, meta MetadataWriteBack, exiftool string) error {
+	sidecarPath := asset.OriginalPath + writeBackSidecarSuffix
+	if asset.SidecarPath.Valid && asset.SidecarPath.String != "" {
+		sidecarPath = asset.SidecarPath.String
+	}
+
+	exists, err := s.storage.Exists(ctx, sidecarPath)
+	if err != nil {
+		return fmt.Errorf("failed to check sidecar: %w", err)
+	}
+
+	if exists {
+		local, cleanup, err := s.downloadToTemp(ctx, sidecarPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		if err := s.ensureWriteBackBackup(ctx, asset.ID, assetFileTypeSidecarBackup, sidecarPath, local); err != nil {
+			return err
+		}
+		if exiftool != "" {
+			if err := runExiftool(ctx, exiftool, local, meta); err != nil {
+				return err
+			}
+			return s.uploadFromFile(ctx, sidecarPath, local)
+		}
+	}
+
+	if err := s.storage.Upload(ctx, sidecarPath, bytes.NewReader(meta.xmpSidecar()), "application/rdf+xml"); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+	if asset.SidecarPath.String != sidecarPath {
+		if err := s.db.UpdateAssetSidecarPath(ctx, sqlc.UpdateAssetSidecarPathParams{
+			ID:          asset.ID,
+			SidecarPath: pgtype.Text{String: sidecarPath, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to record sidecar: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureWriteBackBackup stores the local copy of storagePath as its backup
+// unless the asset already has one, so the backup always holds the bytes
+// from before the first write-back.
+func (s *Service) ensureWriteBackBackup(ctx context.Context, assetID pgtype.UUID, fileType, storagePath, local string) error {
+	_, err := s.db.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: assetID, Type: fileType})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to look up backup: %w", err)
+	}
+
+	backupPath := storagePath + writeBackBackupSuffix
+	if err := s.uploadFromFile(ctx, backupPath, local); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", storagePath, err)
+	}
+	if _, err := s.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+		AssetId: assetID,
+		Type:    fileType,
+		Path:    backupPath,
+	}); err != nil {
+		return fmt.Errorf("failed to record backup: %w", err)
+	}
+
+	return nil
+}
+
+// downloadToTemp copies a stored file into a temporary file with the same
+// extension, which exiftool uses to pick the format.
+func (s *Service) downloadToTemp(ctx context.Context, storagePath string) (string, func(), error) {
+	reader, err := s.storage.Download(ctx, storagePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download %s: %w", storagePath, err)
+	}
+	defer reader.Close()
+
+	file, err := os.CreateTemp("", "immich-writeback-*"+path.Ext(storagePath))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+
+	if _, err := io.Copy(file, reader); err != nil {
+		_ = file.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to download %s: %w", storagePath, err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	return file.Name(), cleanup, nil
+}
+
+func (s *Service) uploadFromFile(ctx context.Context, storagePath, local string) error {
+	file, err := os.Open(local)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", local, err)
+	}
+	defer file.Close()
+
+	if err := s.storage.Upload(ctx, storagePath, file, mime.TypeByExtension(path.Ext(storagePath))); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", storagePath, err)
+	}
+	return nil
+}
+
+// runExiftool writes the metadata into a local file in place, preserving the
+// file's modification time and every other tag.
+func runExiftool(ctx context.Context, exiftool, local string, meta MetadataWriteBack) error {
+	if exiftool == "" {
+		return errExiftoolNotFound
+	}
+
+	args := append([]string{"-overwrite_original", "-preserve", "-quiet"}, meta.exiftoolArgs()...)
+	output, err := exec.CommandContext(ctx, exiftool, append(args, local)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("exiftool failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
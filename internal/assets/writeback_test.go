@@ -0,0 +1,52 @@
+package assets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestMetadataWriteBackExiftoolArgs(t *testing.T) {
+	meta := metadataWriteBackFromExif(sqlc.Exif{
+		DateTimeOriginal: pgtype.Timestamptz{Time: time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC), Valid: true},
+		TimeZone:         pgtype.Text{String: "Europe/Zurich", Valid: true},
+		Latitude:         pgtype.Float8{Float64: -33.8568, Valid: true},
+		Longitude:        pgtype.Float8{Float64: 151.2153, Valid: true},
+	})
+
+	assert.Equal(t, []string{
+		"-DateTimeOriginal=2024:06:01 12:30:00",
+		"-OffsetTimeOriginal=+02:00",
+		"-GPSLatitude=33.856800",
+		"-GPSLatitudeRef=S",
+		"-GPSLongitude=151.215300",
+		"-GPSLongitudeRef=E",
+	}, meta.exiftoolArgs())
+}
+
+func TestMetadataWriteBackIgnoresPartialLocation(t *testing.T) {
+	meta := metadataWriteBackFromExif(sqlc.Exif{
+		Latitude: pgtype.Float8{Float64: 47.3769, Valid: true},
+	})
+
+	assert.True(t, meta.empty())
+	assert.Empty(t, meta.exiftoolArgs())
+}
+
+func TestMetadataWriteBackXMPSidecar(t *testing.T) {
+	meta := metadataWriteBackFromExif(sqlc.Exif{
+		DateTimeOriginal: pgtype.Timestamptz{Time: time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC), Valid: true},
+		Latitude:         pgtype.Float8{Float64: 47.5, Valid: true},
+		Longitude:        pgtype.Float8{Float64: -8.25, Valid: true},
+	})
+
+	xmp := string(meta.xmpSidecar())
+	assert.Contains(t, xmp, `exif:DateTimeOriginal="2024-01-15T08:00:00+00:00"`)
+	assert.Contains(t, xmp, `photoshop:DateCreated="2024-01-15T08:00:00+00:00"`)
+	assert.Contains(t, xmp, `exif:GPSLatitude="47,30.000000N"`)
+	assert.Contains(t, xmp, `exif:GPSLongitude="8,15.000000W"`)
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// firstAdminKey is the system metadata key ClaimFirstAdmin inserts.
+const firstAdminKey = "first_admin"
+
+// ClaimFirstAdmin reports whether the account about to be created as userID
+// is the first one of a fresh install and so becomes its administrator. Of
+// concurrent registrations only one claims it. Callers whose account then
+// fails to be created hand the claim back with ReleaseFirstAdmin.
+func ClaimFirstAdmin(ctx context.Context, queries *sqlc.Queries, userID string) (bool, error) {
+	claimed, err := queries.ClaimFirstAdmin(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim first admin: %w", err)
+	}
+	return claimed > 0, nil
+}
+
+// ReleaseFirstAdmin hands back a claim taken by ClaimFirstAdmin.
+func ReleaseFirstAdmin(ctx context.Context, queries *sqlc.Queries) {
+	if err := queries.DeleteSystemMetadata(ctx, firstAdminKey); err != nil {
+		logrus.WithError(err).Error("Failed to release first admin claim")
+	}
+}
+
+// BootstrapAdmin grants admin to the existing account configured as
+// BootstrapAdminEmail. Registering with that address grants nothing: sign-up
+// does not verify emails, so anyone could claim it first.
+func (s *Service) BootstrapAdmin(ctx context.Context) error {
+	email := strings.TrimSpace(s.config.BootstrapAdminEmail)
+	if email == "" {
+		return nil
+	}
+
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logrus.WithField("email", email).Info("Bootstrap admin does not exist yet")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up bootstrap admin: %w", err)
+	}
+	if user.IsAdmin {
+		return nil
+	}
+
+	if _, err := s.queries.UpdateUser(ctx, sqlc.UpdateUserParams{
+		ID:      user.ID,
+		IsAdmin: pgtype.Bool{Bool: true, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to grant admin to bootstrap admin: %w", err)
+	}
+	logrus.WithField("email", email).Info("Granted admin to bootstrap admin")
+	return nil
+}
+
+// CreateAdminResult describes what CreateAdmin did.
+type CreateAdminResult struct {
+	UserID  string
+	Created bool
+	// Password is set when CreateAdmin generated it.
+	Password string
+}
+
+// CreateAdmin makes the account with email an administrator, creating it when
+// it does not exist. A non-empty password replaces the account's password; a
+// new account without one gets a generated password that must be changed on
+// first login. It is the recovery path for an install without a usable admin.
+func (s *Service) CreateAdmin(ctx context.Context, email, name, password string) (*CreateAdminResult, error) {
+	if password != "" {
+		if err := s.validatePassword(password); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if _, err := s.queries.UpdateUser(ctx, sqlc.UpdateUserParams{
+			ID:      user.ID,
+			IsAdmin: pgtype.Bool{Bool: true, Valid: true},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to grant admin: %w", err)
+		}
+		if password != "" {
+			hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash password: %w", err)
+			}
+			if err := s.queries.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+				ID:       user.ID,
+				Password: string(hashed),
+			}); err != nil {
+				return nil, fmt.Errorf("failed to set password: %w", err)
+			}
+		}
+		return &CreateAdminResult{UserID: pgutil.UUIDToString(user.ID)}, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	result := &CreateAdminResult{Created: true}
+	if password == "" {
+		random := make([]byte, 18)
+		if _, err := rand.Read(random); err != nil {
+			return nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		password = base64.RawURLEncoding.EncodeToString(random)
+		result.Password = password
+	}
+	if name == "" {
+		name = "Admin"
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	userID, err := s.generateUserID()
+	if err != nil {
+		return nil, err
+	}
+	userUUID, err := pgutil.StringToUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:       userUUID,
+		Email:    email,
+		Name:     name,
+		Password: string(hashed),
+		IsAdmin:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin: %w", err)
+	}
+	if result.Password != "" {
+		if _, err := s.queries.UpdateUser(ctx, sqlc.UpdateUserParams{
+			ID:                   created.ID,
+			ShouldChangePassword: pgtype.Bool{Bool: true, Valid: true},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to require a password change: %w", err)
+		}
+	}
+	result.UserID = pgutil.UUIDToString(created.ID)
+	return result, nil
+}
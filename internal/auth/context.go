@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -91,7 +92,7 @@ func GetUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	if ok && claims != nil {
 		userID, err := uuid.Parse(claims.UserID)
 		if err != nil {
-			return uuid.UUID{}, status.Error(codes.Internal, "invalid user ID")
+			return uuid.UUID{}, status.Error(codes.Unauthenticated, "invalid user ID")
 		}
 		return userID, nil
 	}
@@ -118,7 +119,26 @@ func GetUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	return uuid.UUID{}, status.Error(codes.Unauthenticated, "authentication required - JWT validation not performed")
 }
 
+// UserIDFromContext returns the caller's user ID in the form queries take it.
+// Like GetUserIDFromContext it fails with Unauthenticated when the context
+// carries no user or an unparsable one, so handlers can return its error as
+// is.
+func UserIDFromContext(ctx context.Context) (pgtype.UUID, error) {
+	userID, err := GetUserIDFromContext(ctx)
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	return pgtype.UUID{Bytes: userID, Valid: true}, nil
+}
+
 // SetUserIDInContext sets the user ID in the context
 func SetUserIDInContext(ctx context.Context, userID uuid.UUID) context.Context {
 	return context.WithValue(ctx, userIDKey, userID)
 }
+
+// AuthenticatedUserID returns the user ID stored by SetUserIDInContext, which
+// the auth interceptor does once it has validated the caller's token.
+func AuthenticatedUserID(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
+	return userID, ok
+}
@@ -26,5 +26,31 @@ func TestGetUserIDFromContext(t *testing.T) {
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 
 	_, err = GetUserIDFromContext(WithClaims(context.Background(), &Claims{UserID: "not-a-uuid"}))
-	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUserIDFromContext(t *testing.T) {
+	userID := uuid.New()
+
+	got, err := UserIDFromContext(WithClaims(context.Background(), &Claims{UserID: userID.String()}))
+	require.NoError(t, err)
+	assert.True(t, got.Valid)
+	assert.Equal(t, [16]byte(userID), got.Bytes)
+
+	_, err = UserIDFromContext(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = UserIDFromContext(WithClaims(context.Background(), &Claims{UserID: "not-a-uuid"}))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthenticatedUserID(t *testing.T) {
+	userID := uuid.New()
+
+	got, ok := AuthenticatedUserID(SetUserIDInContext(context.Background(), userID))
+	assert.True(t, ok)
+	assert.Equal(t, userID, got)
+
+	_, ok = AuthenticatedUserID(WithClaims(context.Background(), &Claims{UserID: userID.String()}))
+	assert.False(t, ok, "claims alone are not a parsed user ID")
 }
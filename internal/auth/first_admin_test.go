@@ -0,0 +1,125 @@
+//go:build integration
+// +build integration
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestRegisterConcurrentFirstUsersYieldOneAdmin verifies that of several
+// registrations racing on a fresh install exactly one becomes admin.
+func TestRegisterConcurrentFirstUsersYieldOneAdmin(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	service := newTestAuthService(t, tdb)
+
+	const registrations = 8
+	admins := make(chan bool, registrations)
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := service.Register(ctx, RegisterRequest{
+				Email:    fmt.Sprintf("racer-%d@test.com", i),
+				Password: "SecurePass123!",
+				Name:     "Racer",
+			})
+			if assert.NoError(t, err) {
+				admins <- response.User.IsAdmin
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(admins)
+
+	count := 0
+	for isAdmin := range admins {
+		if isAdmin {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestBootstrapAdminGrantsConfiguredEmail(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	insertUser(t, tdb, "owner@test.com", "Owner", "TestPassword123!", true)
+	service := newTestAuthService(t, tdb)
+	service.config.BootstrapAdminEmail = "OWNER@test.com"
+
+	require.NoError(t, service.BootstrapAdmin(ctx))
+
+	user, err := tdb.Queries.GetUserByEmail(ctx, "owner@test.com")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+
+	// Registering as the configured account grants nothing, since sign-up
+	// does not prove the address belongs to whoever registers it.
+	service.config.BootstrapAdminEmail = "late@test.com"
+	require.NoError(t, service.BootstrapAdmin(ctx))
+	response, err := service.Register(ctx, RegisterRequest{
+		Email:    "late@test.com",
+		Password: "SecurePass123!",
+		Name:     "Late",
+	})
+	require.NoError(t, err)
+	assert.False(t, response.User.IsAdmin)
+
+	// It is promoted on the next start instead
+	require.NoError(t, service.BootstrapAdmin(ctx))
+	user, err = tdb.Queries.GetUserByEmail(ctx, "late@test.com")
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+}
+
+func TestCreateAdmin(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	service := newTestAuthService(t, tdb)
+
+	insertUser(t, tdb, "existing@test.com", "Existing", "TestPassword123!", true)
+
+	t.Run("grants admin to an existing account", func(t *testing.T) {
+		result, err := service.CreateAdmin(ctx, "existing@test.com", "", "NewPassword123!")
+		require.NoError(t, err)
+		assert.False(t, result.Created)
+		assert.Empty(t, result.Password)
+
+		user, err := tdb.Queries.GetUserByEmail(ctx, "existing@test.com")
+		require.NoError(t, err)
+		assert.True(t, user.IsAdmin)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("NewPassword123!")))
+	})
+
+	t.Run("creates an account with a generated password", func(t *testing.T) {
+		result, err := service.CreateAdmin(ctx, "recovery@test.com", "", "")
+		require.NoError(t, err)
+		assert.True(t, result.Created)
+		require.NotEmpty(t, result.Password)
+
+		user, err := tdb.Queries.GetUserByEmail(ctx, "recovery@test.com")
+		require.NoError(t, err)
+		assert.True(t, user.IsAdmin)
+		assert.True(t, user.ShouldChangePassword)
+		assert.Equal(t, "Admin", user.Name)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(result.Password)))
+	})
+}
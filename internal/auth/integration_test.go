@@ -45,12 +45,20 @@ func TestIntegration_Register(t *testing.T) {
 	assert.NotEmpty(t, response.RefreshToken)
 	assert.Equal(t, "newuser@test.com", response.User.Email)
 	assert.Equal(t, "New User", response.User.Name)
-	assert.False(t, response.User.IsAdmin)
+	assert.True(t, response.User.IsAdmin, "the first account administers a fresh install")
 
 	// Verify user was created in database
 	user, err := tdb.Queries.GetUserByEmail(ctx, "newuser@test.com")
 	require.NoError(t, err)
 	assert.Equal(t, "New User", user.Name)
+
+	second, err := service.Register(ctx, RegisterRequest{
+		Email:    "seconduser@test.com",
+		Password: "SecurePass123!",
+		Name:     "Second User",
+	})
+	require.NoError(t, err)
+	assert.False(t, second.User.IsAdmin)
 }
 
 func TestIntegration_AdminSignUpFirstUser(t *testing.T) {
@@ -355,7 +363,7 @@ func TestIntegration_TokenValidation(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, claims)
 	assert.Equal(t, "tokentest@test.com", claims.Email)
-	assert.False(t, claims.IsAdmin)
+	assert.Equal(t, response.User.IsAdmin, claims.IsAdmin)
 }
 
 func TestIntegration_RefreshToken(t *testing.T) {
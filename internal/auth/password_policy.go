@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Password rule identifiers, stable for clients rendering a checklist.
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleUppercase = "uppercase"
+	PasswordRuleLowercase = "lowercase"
+	PasswordRuleNumber    = "number"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleNotCommon = "not_common"
+)
+
+const passwordSymbols = "!@#$%^&*()_+-=[]{}|;:,.<>?"
+
+//go:embed common_passwords.txt
+var defaultCommonPasswords []byte
+
+var (
+	defaultBlocklistOnce sync.Once
+	defaultBlocklist     map[string]struct{}
+)
+
+// PasswordRequirement is one rule of the active password policy and whether
+// a given password satisfies it.
+type PasswordRequirement struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	Satisfied   bool   `json:"satisfied"`
+}
+
+// PasswordPolicyError lists every requirement of the policy when a password
+// fails at least one of them.
+type PasswordPolicyError struct {
+	Requirements []PasswordRequirement
+}
+
+// Error joins the descriptions of the unmet requirements.
+func (e *PasswordPolicyError) Error() string {
+	var unmet []string
+	for _, req := range e.Requirements {
+		if !req.Satisfied {
+			unmet = append(unmet, req.Description)
+		}
+	}
+	return "password must " + strings.Join(unmet, ", ")
+}
+
+type passwordRule struct {
+	name        string
+	description string
+	check       func(password string) bool
+	// onlyOnSet rules apply to new passwords but not to login attempts, so
+	// existing accounts are not locked out when the rule is enabled.
+	onlyOnSet bool
+}
+
+// passwordRules returns the rules enabled by the auth configuration.
+func (s *Service) passwordRules() []passwordRule {
+	minLength := s.config.PasswordMinLength
+	rules := []passwordRule{{
+		name:        PasswordRuleMinLength,
+		description: fmt.Sprintf("be at least %d characters long", minLength),
+		check:       func(p string) bool { return len(p) >= minLength },
+	}}
+	if s.config.PasswordRequireUppercase {
+		rules = append(rules, passwordRule{
+			name:        PasswordRuleUppercase,
+			description: "contain at least one uppercase letter",
+			check:       func(p string) bool { return strings.ContainsAny(p, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") },
+		})
+	}
+	if s.config.PasswordRequireLowercase {
+		rules = append(rules, passwordRule{
+			name:        PasswordRuleLowercase,
+			description: "contain at least one lowercase letter",
+			check:       func(p string) bool { return strings.ContainsAny(p, "abcdefghijklmnopqrstuvwxyz") },
+		})
+	}
+	if s.config.PasswordRequireNumbers {
+		rules = append(rules, passwordRule{
+			name:        PasswordRuleNumber,
+			description: "contain at least one number",
+			check:       func(p string) bool { return strings.ContainsAny(p, "0123456789") },
+		})
+	}
+	if s.config.PasswordRequireSymbols {
+		rules = append(rules, passwordRule{
+			name:        PasswordRuleSymbol,
+			description: "contain at least one symbol",
+			check:       func(p string) bool { return strings.ContainsAny(p, passwordSymbols) },
+		})
+	}
+	if s.config.PasswordBlockCommon {
+		blocklist := s.commonPasswords()
+		rules = append(rules, passwordRule{
+			name:        PasswordRuleNotCommon,
+			description: "not be a commonly used password",
+			check: func(p string) bool {
+				_, common := blocklist[strings.ToLower(p)]
+				return !common
+			},
+			onlyOnSet: true,
+		})
+	}
+	return rules
+}
+
+// PasswordPolicy returns the active password requirements, unevaluated.
+func (s *Service) PasswordPolicy() []PasswordRequirement {
+	rules := s.passwordRules()
+	requirements := make([]PasswordRequirement, len(rules))
+	for i, rule := range rules {
+		requirements[i] = PasswordRequirement{Rule: rule.name, Description: rule.description}
+	}
+	return requirements
+}
+
+// CheckPassword evaluates password against every active requirement.
+func (s *Service) CheckPassword(password string) []PasswordRequirement {
+	return evaluatePasswordRules(s.passwordRules(), password)
+}
+
+// validatePassword checks a new password against the full policy and reports
+// all unmet requirements at once.
+func (s *Service) validatePassword(password string) error {
+	return passwordPolicyError(evaluatePasswordRules(s.passwordRules(), password))
+}
+
+// validatePasswordFormat checks a login attempt against the complexity rules
+// only.
+func (s *Service) validatePasswordFormat(password string) error {
+	var rules []passwordRule
+	for _, rule := range s.passwordRules() {
+		if !rule.onlyOnSet {
+			rules = append(rules, rule)
+		}
+	}
+	return passwordPolicyError(evaluatePasswordRules(rules, password))
+}
+
+func evaluatePasswordRules(rules []passwordRule, password string) []PasswordRequirement {
+	requirements := make([]PasswordRequirement, len(rules))
+	for i, rule := range rules {
+		requirements[i] = PasswordRequirement{
+			Rule:        rule.name,
+			Description: rule.description,
+			Satisfied:   rule.check(password),
+		}
+	}
+	return requirements
+}
+
+func passwordPolicyError(requirements []PasswordRequirement) error {
+	for _, req := range requirements {
+		if !req.Satisfied {
+			return &PasswordPolicyError{Requirements: requirements}
+		}
+	}
+	return nil
+}
+
+// commonPasswords returns the configured blocklist, or, when no file is
+// configured, the embedded list of about 190 of the most used passwords.
+func (s *Service) commonPasswords() map[string]struct{} {
+	if s.blocklist != nil {
+		return s.blocklist
+	}
+	defaultBlocklistOnce.Do(func() {
+		defaultBlocklist = parsePasswordList(bytes.NewReader(defaultCommonPasswords))
+	})
+	return defaultBlocklist
+}
+
+// loadPasswordBlocklist reads a newline-separated password list, such as a
+// top-10k list, from path.
+func loadPasswordBlocklist(path string) map[string]struct{} {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.WithError(err).WithField("path", path).Warn("Failed to open password blocklist, using built-in list")
+		return nil
+	}
+	defer f.Close()
+	return parsePasswordList(f)
+}
+
+func parsePasswordList(r io.Reader) map[string]struct{} {
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			list[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return list
+}
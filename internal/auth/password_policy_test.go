@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePasswordReportsAllRequirements(t *testing.T) {
+	service := &Service{config: config.AuthConfig{
+		PasswordMinLength:        10,
+		PasswordRequireUppercase: true,
+		PasswordRequireNumbers:   true,
+		PasswordRequireSymbols:   true,
+	}}
+
+	err := service.validatePassword("short1")
+	var policyErr *PasswordPolicyError
+	require.True(t, errors.As(err, &policyErr))
+
+	satisfied := map[string]bool{}
+	for _, req := range policyErr.Requirements {
+		satisfied[req.Rule] = req.Satisfied
+	}
+	assert.Equal(t, map[string]bool{
+		PasswordRuleMinLength: false,
+		PasswordRuleUppercase: false,
+		PasswordRuleNumber:    true,
+		PasswordRuleSymbol:    false,
+	}, satisfied)
+	assert.Contains(t, err.Error(), "at least 10 characters")
+	assert.Contains(t, err.Error(), "uppercase")
+	assert.Contains(t, err.Error(), "symbol")
+	assert.NotContains(t, err.Error(), "number")
+}
+
+func TestPasswordPolicyListsActiveRules(t *testing.T) {
+	service := &Service{config: config.AuthConfig{
+		PasswordMinLength:        8,
+		PasswordRequireLowercase: true,
+		PasswordBlockCommon:      true,
+	}}
+
+	policy := service.PasswordPolicy()
+	rules := make([]string, len(policy))
+	for i, req := range policy {
+		rules[i] = req.Rule
+		assert.False(t, req.Satisfied)
+		assert.NotEmpty(t, req.Description)
+	}
+	assert.Equal(t, []string{PasswordRuleMinLength, PasswordRuleLowercase, PasswordRuleNotCommon}, rules)
+}
+
+func TestCommonPasswordBlocklist(t *testing.T) {
+	service := &Service{config: config.AuthConfig{
+		PasswordMinLength:   8,
+		PasswordBlockCommon: true,
+	}}
+
+	err := service.validatePassword("Password123")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "commonly used")
+	assert.NoError(t, service.validatePassword("correct horse battery staple"))
+
+	// Login attempts only check complexity so existing accounts keep working.
+	assert.NoError(t, service.validatePasswordFormat("Password123"))
+}
+
+func TestCommonPasswordBlocklistFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# custom list\nSpringtime99\n"), 0o600))
+
+	service := NewService(config.AuthConfig{
+		PasswordMinLength:     8,
+		PasswordBlockCommon:   true,
+		PasswordBlocklistFile: path,
+	}, nil)
+
+	assert.Error(t, service.validatePassword("springtime99"))
+	assert.NoError(t, service.validatePassword("password123"), "the file replaces the built-in list")
+}
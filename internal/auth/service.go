@@ -31,6 +31,7 @@ type Service struct {
 	config       config.AuthConfig
 	queries      *sqlc.Queries
 	loginLimiter *loginRateLimiter
+	blocklist    map[string]struct{}
 }
 
 // NewService creates a new authentication service
@@ -39,6 +40,7 @@ func NewService(config config.AuthConfig, queries *sqlc.Queries) *Service {
 		config:       config,
 		queries:      queries,
 		loginLimiter: newLoginRateLimiter(config.LoginRateLimit, config.LoginRateWindow),
+		blocklist:    loadPasswordBlocklist(config.PasswordBlocklistFile),
 	}
 }
 
@@ -105,7 +107,7 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (*AuthResponse, e
 	}
 
 	// Validate password complexity
-	if err := s.validatePassword(req.Password); err != nil {
+	if err := s.validatePasswordFormat(req.Password); err != nil {
 		s.recordFailedLogin(loginKey)
 		return nil, recordedAuthError(span, ErrInvalidCredentials, "Invalid password format", err)
 	}
@@ -177,19 +179,15 @@ func (s *Service) Login(ctx context.Context, req LoginRequest) (*AuthResponse, e
 	}, nil
 }
 
-// Register creates a new user account
+// Register creates a new user account. The first account on a fresh install
+// becomes its administrator.
 func (s *Service) Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
-	return s.register(ctx, req, false)
+	return s.register(ctx, req)
 }
 
 // AdminSignUp creates the initial administrator account during setup.
 func (s *Service) AdminSignUp(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
-	userCount, err := s.countUsers(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return s.register(ctx, req, userCount == 0)
+	return s.register(ctx, req)
 }
 
 // IsInitialized reports whether at least one user account exists, i.e.
@@ -210,7 +208,7 @@ func (s *Service) countUsers(ctx context.Context) (int64, error) {
 	return userCount, nil
 }
 
-func (s *Service) register(ctx context.Context, req RegisterRequest, isAdmin bool) (*AuthResponse, error) {
+func (s *Service) register(ctx context.Context, req RegisterRequest) (*AuthResponse, error) {
 	ctx, span := tracer.Start(ctx, "auth.Register",
 		trace.WithAttributes(attribute.String("auth.email", req.Email)))
 	defer span.End()
@@ -249,6 +247,11 @@ func (s *Service) register(ctx context.Context, req RegisterRequest, isAdmin boo
 		return nil, recordedAuthError(span, ErrUserCreation, "Failed to convert user ID", err)
 	}
 
+	isAdmin, err := ClaimFirstAdmin(ctx, s.queries, userID)
+	if err != nil {
+		return nil, recordedAuthError(span, ErrUserCreation, "Failed to create user account", err)
+	}
+
 	createUserParams := sqlc.CreateUserParams{
 		ID:          userUUID,
 		Email:       req.Email,
@@ -260,6 +263,9 @@ func (s *Service) register(ctx context.Context, req RegisterRequest, isAdmin boo
 
 	user, err := s.queries.CreateUser(ctx, createUserParams)
 	if err != nil {
+		if isAdmin {
+			ReleaseFirstAdmin(ctx, s.queries)
+		}
 		return nil, recordedAuthError(span, ErrUserCreation, "Failed to create user account", err)
 	}
 
@@ -644,6 +650,40 @@ func (s *Service) ChangePinCode(ctx context.Context, userID, currentPinCode, new
 	return nil
 }
 
+// VerifyPassword re-authenticates a signed-in user before a sensitive action
+func (s *Service) VerifyPassword(ctx context.Context, userID, password string) error {
+	ctx, span := tracer.Start(ctx, "auth.VerifyPassword",
+		trace.WithAttributes(attribute.String("auth.user_id", userID)))
+	defer span.End()
+
+	userUUID, err := pgutil.StringToUUID(userID)
+	if err != nil {
+		return recordedAuthError(span, ErrInvalidCredentials, "Invalid user ID", err)
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userUUID)
+	if err != nil {
+		return recordedAuthError(span, ErrUserNotFound, "User not found", err)
+	}
+
+	// Shares the login limit so a stolen token cannot guess the password.
+	loginKey := loginRateLimitKey(user.Email)
+	if !s.allowLoginAttempt(loginKey) {
+		return NewAuthError(ErrRateLimited, "Too many failed login attempts", nil)
+	}
+
+	if user.Password == "" {
+		return NewAuthError(ErrInvalidCredentials, "Password is incorrect", nil)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		s.recordFailedLogin(loginKey)
+		return recordedAuthError(span, ErrInvalidCredentials, "Password is incorrect", err)
+	}
+
+	s.resetLoginAttempts(loginKey)
+	return nil
+}
+
 // ResetPinCode resets the PIN code by verifying the account password
 func (s *Service) ResetPinCode(ctx context.Context, userID, password string) error {
 	ctx, span := tracer.Start(ctx, "auth.ResetPinCode",
@@ -702,7 +742,37 @@ func (s *Service) UnlockSession(ctx context.Context, userID, sessionID, pinCode
 		return recordedAuthError(span, ErrInvalidCredentials, "PIN code is incorrect", err)
 	}
 
-	// Set session PIN elevation (expires in 1 hour)
+	return s.elevateSession(ctx, span, sessionID)
+}
+
+// UnlockSessionWithPassword unlocks a session with the account password, for
+// users who have not set up a PIN code.
+func (s *Service) UnlockSessionWithPassword(ctx context.Context, userID, sessionID, password string) error {
+	ctx, span := tracer.Start(ctx, "auth.UnlockSessionWithPassword",
+		trace.WithAttributes(
+			attribute.String("auth.user_id", userID),
+			attribute.String("auth.session_id", sessionID)))
+	defer span.End()
+
+	userUUID, err := pgutil.StringToUUID(userID)
+	if err != nil {
+		return recordedAuthError(span, ErrInvalidCredentials, "Invalid user ID", err)
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userUUID)
+	if err != nil {
+		return recordedAuthError(span, ErrUserNotFound, "User not found", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return recordedAuthError(span, ErrInvalidCredentials, "Password is incorrect", err)
+	}
+
+	return s.elevateSession(ctx, span, sessionID)
+}
+
+// elevateSession grants the session elevated access for one hour.
+func (s *Service) elevateSession(ctx context.Context, span trace.Span, sessionID string) error {
 	sessionUUID, err := pgutil.StringToUUID(sessionID)
 	if err != nil {
 		return recordedAuthError(span, ErrInvalidCredentials, "Invalid session ID", err)
@@ -756,70 +826,3 @@ func (s *Service) IsSessionElevated(ctx context.Context, sessionID string) (bool
 
 	return isElevated, nil
 }
-
-// validatePassword validates password complexity requirements
-func (s *Service) validatePassword(password string) error {
-	if len(password) < s.config.PasswordMinLength {
-		return fmt.Errorf("password must be at least %d characters long", s.config.PasswordMinLength)
-	}
-
-	if s.config.PasswordRequireUppercase {
-		hasUpper := false
-		for _, r := range password {
-			if r >= 'A' && r <= 'Z' {
-				hasUpper = true
-				break
-			}
-		}
-		if !hasUpper {
-			return fmt.Errorf("password must contain at least one uppercase letter")
-		}
-	}
-
-	if s.config.PasswordRequireLowercase {
-		hasLower := false
-		for _, r := range password {
-			if r >= 'a' && r <= 'z' {
-				hasLower = true
-				break
-			}
-		}
-		if !hasLower {
-			return fmt.Errorf("password must contain at least one lowercase letter")
-		}
-	}
-
-	if s.config.PasswordRequireNumbers {
-		hasNumber := false
-		for _, r := range password {
-			if r >= '0' && r <= '9' {
-				hasNumber = true
-				break
-			}
-		}
-		if !hasNumber {
-			return fmt.Errorf("password must contain at least one number")
-		}
-	}
-
-	if s.config.PasswordRequireSymbols {
-		hasSymbol := false
-		symbols := "!@#$%^&*()_+-=[]{}|;:,.<>?"
-		for _, r := range password {
-			for _, s := range symbols {
-				if r == s {
-					hasSymbol = true
-					break
-				}
-			}
-			if hasSymbol {
-				break
-			}
-		}
-		if !hasSymbol {
-			return fmt.Errorf("password must contain at least one symbol")
-		}
-	}
-
-	return nil
-}
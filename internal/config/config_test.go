@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -235,6 +236,16 @@ func TestDatabaseConfig(t *testing.T) {
 	assert.True(t, cfg.AutoMigrate)
 }
 
+func TestDatabaseReplicaURLFromEnv(t *testing.T) {
+	config := &Config{}
+	setDefaults(config)
+	assert.Empty(t, config.Database.ReplicaURL)
+
+	t.Setenv("DATABASE_REPLICA_URL", "postgres://reader@replica:5432/immich")
+	require.NoError(t, loadFromEnv(config))
+	assert.Equal(t, "postgres://reader@replica:5432/immich", config.Database.ReplicaURL)
+}
+
 func TestAuthConfig(t *testing.T) {
 	cfg := AuthConfig{
 		JWTSecret:                 "super-secret-key",
@@ -307,6 +318,329 @@ func TestJobsConfig(t *testing.T) {
 	assert.Equal(t, 30*time.Minute, cfg.JobTimeout)
 }
 
+func TestMetadataConfigFromEnv(t *testing.T) {
+	t.Setenv("METADATA_CONCURRENCY", "2")
+	t.Setenv("METADATA_MAX_BYTES", "1048576")
+	t.Setenv("METADATA_MAX_DIMENSION", "8000")
+	t.Setenv("METADATA_TIMEOUT", "5s")
+	t.Setenv("METADATA_EXTRACTOR", "ExifTool")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 4, cfg.Metadata.Concurrency)
+	assert.Equal(t, time.Minute, cfg.Metadata.Timeout)
+	assert.Equal(t, "native", cfg.Metadata.Extractor)
+
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 2, cfg.Metadata.Concurrency)
+	assert.Equal(t, int64(1048576), cfg.Metadata.MaxBytes)
+	assert.Equal(t, 8000, cfg.Metadata.MaxDimension)
+	assert.Equal(t, 5*time.Second, cfg.Metadata.Timeout)
+	assert.Equal(t, "exiftool", cfg.Metadata.Extractor)
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+
+	cfg.Metadata.Extractor = "exiv2"
+	assert.ErrorContains(t, validateConfig(cfg), "METADATA_EXTRACTOR")
+}
+
+func TestSortingConfigFromEnv(t *testing.T) {
+	t.Setenv("SORT_FIELD", "Created_At")
+	t.Setenv("SORT_ORDER", "ASC")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, "date_taken", cfg.Sorting.Field)
+	assert.Equal(t, "desc", cfg.Sorting.Order)
+
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, "created_at", cfg.Sorting.Field)
+	assert.Equal(t, "asc", cfg.Sorting.Order)
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+
+	cfg.Sorting.Field = "size"
+	assert.ErrorContains(t, validateConfig(cfg), "SORT_FIELD")
+	cfg.Sorting.Field = "filename"
+	cfg.Sorting.Order = "newest"
+	assert.ErrorContains(t, validateConfig(cfg), "SORT_ORDER")
+}
+
+func TestAssetSort(t *testing.T) {
+	defaults := SortingConfig{Field: "created_at", Order: "asc"}
+
+	sort, err := defaults.AssetSort("", "")
+	require.NoError(t, err)
+	assert.Equal(t, AssetSort{Field: "created_at"}, sort)
+
+	sort, err = defaults.AssetSort("filename", "desc")
+	require.NoError(t, err)
+	assert.Equal(t, AssetSort{Field: "filename", Descending: true}, sort, "requests override the default")
+
+	sort, err = SortingConfig{}.AssetSort("", "")
+	require.NoError(t, err)
+	assert.Equal(t, AssetSort{Field: "date_taken", Descending: true}, sort, "newest first by capture date when unconfigured")
+
+	_, err = defaults.AssetSort("size", "")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	_, err = defaults.AssetSort("", "up")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+
+	assert.False(t, defaults.DescendingByDefault())
+	var unset *Config
+	assert.True(t, unset.ListSorting().DescendingByDefault())
+}
+
+func TestThumbnailsConfigFromEnv(t *testing.T) {
+	t.Setenv("THUMBNAIL_ORDER", "webp, thumb")
+	t.Setenv("THUMBNAIL_FIRST_BEFORE_METADATA", "false")
+	t.Setenv("THUMBNAIL_REGENERATE_ON_CHANGE", "true")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, []string{"thumb", "webp", "preview"}, cfg.Thumbnails.Order)
+	assert.True(t, cfg.Thumbnails.FirstBeforeMetadata)
+	assert.False(t, cfg.Thumbnails.RegenerateOnChange)
+
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, []string{"webp", "thumb"}, cfg.Thumbnails.Order)
+	assert.False(t, cfg.Thumbnails.FirstBeforeMetadata)
+	assert.True(t, cfg.Thumbnails.RegenerateOnChange)
+}
+
+func TestMachineLearningConfigFromEnv(t *testing.T) {
+	t.Setenv("MACHINE_LEARNING_API_KEY", "secret")
+	t.Setenv("MACHINE_LEARNING_MAX_RETRIES", "4")
+	t.Setenv("MACHINE_LEARNING_RETRY_BACKOFF", "250ms")
+	t.Setenv("MACHINE_LEARNING_BREAKER_COOLDOWN", "1m")
+	t.Setenv("MACHINE_LEARNING_OBJECT_SEARCH_MIN_SCORE", "0.7")
+	t.Setenv("MACHINE_LEARNING_MAX_CONCURRENCY", "1")
+	t.Setenv("MACHINE_LEARNING_BATCH_SIZE", "16")
+	t.Setenv("MACHINE_LEARNING_BATCH_MAX_WAIT", "200ms")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, "secret", cfg.MachineLearning.APIKey)
+	assert.Equal(t, 4, cfg.MachineLearning.MaxRetries)
+	assert.Equal(t, 250*time.Millisecond, cfg.MachineLearning.RetryBackoff)
+	assert.Equal(t, time.Minute, cfg.MachineLearning.BreakerCooldown)
+	assert.Equal(t, 0.7, cfg.MachineLearning.ObjectDetection.SearchMinScore)
+	assert.Equal(t, 1, cfg.MachineLearning.MaxConcurrency)
+	assert.Equal(t, 16, cfg.MachineLearning.BatchSize)
+	assert.Equal(t, 200*time.Millisecond, cfg.MachineLearning.BatchMaxWait)
+
+	t.Setenv("MACHINE_LEARNING_RETRY_BACKOFF", "soon")
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 250*time.Millisecond, cfg.MachineLearning.RetryBackoff)
+}
+
+func TestDefaultTimeZone(t *testing.T) {
+	t.Setenv("SERVER_DEFAULT_TIME_ZONE", "Pacific/Kiritimati")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, "UTC", cfg.Server.DefaultTimeZone)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, "Pacific/Kiritimati", cfg.Server.DefaultTimeZone)
+	assert.Equal(t, "Pacific/Kiritimati", cfg.DefaultLocation().String())
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	cfg.Server.DefaultTimeZone = "Mars/Olympus_Mons"
+	assert.Equal(t, time.UTC, cfg.DefaultLocation())
+	assert.ErrorContains(t, validateConfig(cfg), "SERVER_DEFAULT_TIME_ZONE")
+
+	var nilCfg *Config
+	assert.Equal(t, time.UTC, nilCfg.DefaultLocation())
+}
+
+func TestExternalDomainFromEnv(t *testing.T) {
+	t.Setenv("SERVER_EXTERNAL_DOMAIN", "https://photos.example.com/")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Empty(t, cfg.ExternalURL("/share/abc"))
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, "https://photos.example.com/share/abc", cfg.ExternalURL("/share/abc"))
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+	cfg.Server.ExternalDomain = "photos.example.com"
+	assert.ErrorContains(t, validateConfig(cfg), "SERVER_EXTERNAL_DOMAIN")
+}
+
+func TestMaxPageSizeFromEnv(t *testing.T) {
+	t.Setenv("SERVER_MAX_PAGE_SIZE", "250")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 1000, cfg.Server.MaxPageSize)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 250, cfg.Server.MaxPageSize)
+	assert.Equal(t, int32(100), cfg.PageSize(0, 100))
+	assert.Equal(t, int32(250), cfg.PageSize(5000, 100))
+
+	var nilCfg *Config
+	assert.Equal(t, int32(DefaultMaxPageSize), nilCfg.PageSize(5000, 100))
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+	cfg.Server.MaxPageSize = 0
+	assert.ErrorContains(t, validateConfig(cfg), "SERVER_MAX_PAGE_SIZE")
+}
+
+func TestCompressionFromEnv(t *testing.T) {
+	t.Setenv("SERVER_COMPRESSION_ENCODINGS", "gzip")
+	t.Setenv("SERVER_COMPRESSION_MIN_SIZE", "4096")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.True(t, cfg.Server.Compression.Enabled)
+	assert.Equal(t, []string{"zstd", "gzip"}, cfg.Server.Compression.Encodings)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, []string{"gzip"}, cfg.Server.Compression.Encodings)
+	assert.Equal(t, 4096, cfg.Server.Compression.MinSize)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+	cfg.Server.Compression.Encodings = []string{"br"}
+	assert.ErrorContains(t, validateConfig(cfg), "SERVER_COMPRESSION_ENCODINGS")
+	cfg.Server.Compression.Encodings = nil
+	cfg.Server.Compression.MinSize = -1
+	assert.ErrorContains(t, validateConfig(cfg), "SERVER_COMPRESSION_MIN_SIZE")
+}
+
+func TestProcessingTimeoutFromEnv(t *testing.T) {
+	t.Setenv("JOBS_PROCESSING_TIMEOUT", "90s")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 10*time.Minute, cfg.Jobs.ProcessingTimeout)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 90*time.Second, cfg.Jobs.ProcessingTimeout)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+	cfg.Jobs.ProcessingTimeout = 0
+	assert.ErrorContains(t, validateConfig(cfg), "JOBS_PROCESSING_TIMEOUT")
+}
+
+func TestLoggingWriterCreatesLogDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "nested", "immich.log")
+
+	for _, rotation := range []bool{true, false} {
+		cfg := LoggingConfig{Output: "file", FilePath: path, RotationEnabled: rotation, MaxSize: 1}
+		w, err := cfg.Writer()
+		require.NoError(t, err)
+		_, err = w.Write([]byte("line\n"))
+		require.NoError(t, err)
+		if closer, ok := w.(io.Closer); ok {
+			require.NoError(t, closer.Close())
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line\nline\n", string(data))
+}
+
+func TestLoggingOutputFromEnv(t *testing.T) {
+	t.Setenv("LOG_OUTPUT", "stderr")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	require.NoError(t, loadFromEnv(cfg))
+	w, err := cfg.Logging.Writer()
+	require.NoError(t, err)
+	assert.Equal(t, os.Stderr, w)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	require.NoError(t, validateConfig(cfg))
+	cfg.Logging.Output = "syslog"
+	assert.ErrorContains(t, validateConfig(cfg), "LOG_OUTPUT")
+	cfg.Logging.Output = "file"
+	cfg.Logging.FilePath = ""
+	assert.ErrorContains(t, validateConfig(cfg), "LOG_FILE_PATH")
+}
+
+func TestLibraryOfflineRetentionFromEnv(t *testing.T) {
+	t.Setenv("LIBRARY_OFFLINE_RETENTION", "48h")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 30*24*time.Hour, cfg.Libraries.OfflineRetention)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 48*time.Hour, cfg.Libraries.OfflineRetention)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	cfg.Libraries.OfflineRetention = -time.Hour
+	assert.ErrorContains(t, validateConfig(cfg), "LIBRARY_OFFLINE_RETENTION")
+}
+
+func TestLibraryScanConcurrencyFromEnv(t *testing.T) {
+	t.Setenv("LIBRARY_MAX_CONCURRENT_SCANS", "4")
+	t.Setenv("LIBRARY_MAX_CONCURRENT_SCANS_PER_USER", "0")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 2, cfg.Libraries.MaxConcurrentScans)
+	assert.Equal(t, 1, cfg.Libraries.MaxConcurrentScansPerUser)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 4, cfg.Libraries.MaxConcurrentScans)
+	assert.Equal(t, 0, cfg.Libraries.MaxConcurrentScansPerUser)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	cfg.Libraries.MaxConcurrentScans = -1
+	assert.ErrorContains(t, validateConfig(cfg), "LIBRARY_MAX_CONCURRENT_SCANS")
+}
+
+func TestUserLimitsFromEnv(t *testing.T) {
+	t.Setenv("LIMITS_MAX_SHARED_LINKS_PER_USER", "10")
+	t.Setenv("LIMITS_MAX_API_KEYS_PER_USER", "0")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Equal(t, 500, cfg.Limits.MaxSharedLinksPerUser)
+	assert.Equal(t, 50, cfg.Limits.MaxAPIKeysPerUser)
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, 10, cfg.Limits.MaxSharedLinksPerUser)
+	assert.Equal(t, 0, cfg.Limits.MaxAPIKeysPerUser)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	cfg.Limits.MaxAPIKeysPerUser = -1
+	assert.ErrorContains(t, validateConfig(cfg), "LIMITS_MAX_API_KEYS_PER_USER")
+}
+
+func TestStorageDerivativesFromEnv(t *testing.T) {
+	t.Setenv("STORAGE_DERIVATIVES_BACKEND", "local")
+	t.Setenv("STORAGE_DERIVATIVES_LOCAL_ROOT", "/var/cache/immich")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.False(t, cfg.Storage.Derivatives.Separate())
+	require.NoError(t, loadFromEnv(cfg))
+	assert.True(t, cfg.Storage.Derivatives.Separate())
+	assert.Equal(t, "/var/cache/immich", cfg.Storage.Derivatives.Local.RootPath)
+
+	cfg.Auth.JWTSecret = "secret-key-long-enough"
+	cfg.Storage.Derivatives.Local.RootPath = ""
+	assert.Error(t, validateConfig(cfg))
+}
+
+func TestUploadAllowlistFromEnv(t *testing.T) {
+	t.Setenv("UPLOAD_ALLOWED_EXTENSIONS", ".jpg, .mp4,")
+	t.Setenv("UPLOAD_ALLOWED_MIME_TYPES", "image/jpeg,video/mp4")
+
+	cfg := &Config{}
+	setDefaults(cfg)
+	assert.Contains(t, cfg.Storage.Upload.AllowedExtensions, ".cr3")
+
+	require.NoError(t, loadFromEnv(cfg))
+	assert.Equal(t, []string{".jpg", ".mp4"}, cfg.Storage.Upload.AllowedExtensions)
+	assert.Equal(t, []string{"image/jpeg", "video/mp4"}, cfg.Storage.Upload.AllowedMimeTypes)
+	assert.Equal(t, []string{".xmp"}, cfg.Storage.Upload.AllowedSidecarExtensions)
+}
+
 func TestFeatureConfig(t *testing.T) {
 	cfg := FeatureConfig{
 		MachineLearningEnabled:  false,
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
@@ -10,13 +11,53 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 )
 
+// Conn holds the connections to the primary database, which the embedded
+// Queries use, and to an optional read replica.
 type Conn struct {
 	pool *pgxpool.Pool
 	*sqlc.Queries
+
+	// replica is nil when no read replica is configured
+	replica     *pgxpool.Pool
+	readQueries *sqlc.Queries
 }
 
 func (c *Conn) Close() error {
 	c.pool.Close()
+	if c.replica != nil {
+		c.replica.Close()
+	}
+	return nil
+}
+
+// ReadOnly returns the queries of heavy reads that tolerate replication lag,
+// such as statistics, which the read replica serves. Without a replica they
+// run on the primary. Writes, and reads that must see the caller's own
+// writes, use the embedded Queries instead.
+func (c *Conn) ReadOnly() *sqlc.Queries {
+	return c.readQueries
+}
+
+// Ping checks that the database is reachable.
+func (c *Conn) Ping(ctx context.Context) error {
+	return c.pool.Ping(ctx)
+}
+
+// InTx runs fn with queries bound to a transaction, which is committed when
+// fn returns nil and rolled back otherwise.
+func (c *Conn) InTx(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(c.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -26,6 +67,33 @@ func (c *Conn) DB() *sql.DB {
 }
 
 func New(ctx context.Context, databaseURL string) (*Conn, error) {
+	return NewWithReplica(ctx, databaseURL, "")
+}
+
+// NewWithReplica connects to the primary database at databaseURL and, unless
+// replicaURL is empty, to the read replica at replicaURL.
+func NewWithReplica(ctx context.Context, databaseURL, replicaURL string) (*Conn, error) {
+	pool, err := connect(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	conn := &Conn{pool: pool, Queries: sqlc.New(pool)}
+	conn.readQueries = conn.Queries
+	if replicaURL == "" {
+		return conn, nil
+	}
+
+	replica, err := connect(ctx, replicaURL)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("read replica: %w", err)
+	}
+	conn.replica = replica
+	conn.readQueries = sqlc.New(replica)
+	return conn, nil
+}
+
+func connect(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 	pool, err := pgxpool.New(ctx, databaseURL)
 	if err != nil {
 		return nil, err
@@ -36,6 +104,5 @@ func New(ctx context.Context, databaseURL string) (*Conn, error) {
 		pool.Close()
 		return nil, err
 	}
-
-	return &Conn{pool: pool, Queries: sqlc.New(pool)}, nil
+	return pool, nil
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -14,15 +16,168 @@ import (
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
+// downMigrationSuffix marks the file that reverts the migration of the same
+// version, e.g. "009_asset_undated.down.sql".
+const downMigrationSuffix = ".down.sql"
+
 // Migration represents a database migration
 type Migration struct {
 	Version int
 	Name    string
 	SQL     string
+	// DownSQL reverts the migration. Empty when it cannot be rolled back.
+	DownSQL string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Reversible is true when the migration has a down migration.
+	Reversible bool
 }
 
 // RunMigrations runs all pending database migrations
 func RunMigrations(ctx context.Context, db *sql.DB) error {
+	return migrateUp(ctx, db, -1)
+}
+
+// MigrateDown rolls back the last steps applied migrations, newest first.
+// Nothing is rolled back when any of them has no down migration.
+func MigrateDown(ctx context.Context, db *sql.DB, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	applied, migrations, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if steps > len(applied) {
+		return fmt.Errorf("cannot roll back %d migrations: only %d applied", steps, len(applied))
+	}
+	return rollBack(ctx, db, migrations, applied[len(applied)-steps:])
+}
+
+// MigrateTo applies or rolls back migrations until version is the newest one
+// applied. Version 0 rolls back every migration.
+func MigrateTo(ctx context.Context, db *sql.DB, version int) error {
+	if version < 0 {
+		return fmt.Errorf("invalid migration version %d", version)
+	}
+
+	applied, migrations, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		if _, ok := migrations[version]; !ok {
+			return fmt.Errorf("unknown migration version %03d", version)
+		}
+	}
+
+	var newer []int
+	for _, v := range applied {
+		if v > version {
+			newer = append(newer, v)
+		}
+	}
+	if len(newer) > 0 {
+		return rollBack(ctx, db, migrations, newer)
+	}
+	return migrateUp(ctx, db, version)
+}
+
+// RollbackCount returns how many applied migrations MigrateTo(version) would
+// roll back, so callers can ask for confirmation first.
+func RollbackCount(ctx context.Context, db *sql.DB, version int) (int, error) {
+	statuses, err := GetMigrationStatus(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, s := range statuses {
+		if s.Applied && s.Version > version {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetMigrationStatus lists every known migration, and any applied one no
+// longer shipped, with whether it has been applied. It only reads, inside a
+// read-only transaction, and does not create the migrations table, so it is
+// safe to run against a live database.
+func GetMigrationStatus(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT to_regclass('schema_migrations') IS NOT NULL",
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check migrations table: %w", err)
+	}
+
+	statuses := make(map[int]*MigrationStatus, len(migrations))
+	for _, m := range migrations {
+		statuses[m.Version] = &MigrationStatus{Version: m.Version, Name: m.Name, Reversible: m.DownSQL != ""}
+	}
+
+	if exists {
+		rows, err := tx.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				version   int
+				name      string
+				appliedAt sql.NullTime
+			)
+			if err := rows.Scan(&version, &name, &appliedAt); err != nil {
+				return nil, fmt.Errorf("failed to read applied migration: %w", err)
+			}
+			status, ok := statuses[version]
+			if !ok {
+				status = &MigrationStatus{Version: version, Name: name}
+				statuses[version] = status
+			}
+			status.Applied = true
+			if appliedAt.Valid {
+				status.AppliedAt = &appliedAt.Time
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+	}
+
+	result := make([]MigrationStatus, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// migrateUp applies the pending migrations up to and including target, or
+// all of them when target is negative.
+func migrateUp(ctx context.Context, db *sql.DB, target int) error {
 	// Create migrations table if it doesn't exist
 	if err := createMigrationsTable(ctx, db); err != nil {
 		return fmt.Errorf("failed to create migrations table: %w", err)
@@ -50,6 +205,9 @@ func RunMigrations(ctx context.Context, db *sql.DB) error {
 		if m.Version <= currentVersion {
 			continue
 		}
+		if target >= 0 && m.Version > target {
+			break
+		}
 
 		logrus.Infof("Applying migration %03d: %s", m.Version, m.Name)
 
@@ -85,6 +243,86 @@ func RunMigrations(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+// appliedMigrations returns the applied versions in ascending order and the
+// known migrations keyed by version.
+func appliedMigrations(ctx context.Context, db *sql.DB) ([]int, map[int]Migration, error) {
+	if err := createMigrationsTable(ctx, db); err != nil {
+		return nil, nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	loaded, err := loadMigrations()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	migrations := make(map[int]Migration, len(loaded))
+	for _, m := range loaded {
+		migrations[m.Version] = m
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, nil, fmt.Errorf("failed to read applied migration: %w", err)
+		}
+		applied = append(applied, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	return applied, migrations, nil
+}
+
+// errIrreversibleMigration is returned when a rollback reaches a migration
+// without a down migration.
+var errIrreversibleMigration = errors.New("migration has no down migration")
+
+// rollBack reverts the given applied versions, newest first, each in its own
+// transaction. Every version is checked for a down migration before any is
+// reverted.
+func rollBack(ctx context.Context, db *sql.DB, migrations map[int]Migration, versions []int) error {
+	versions = append([]int(nil), versions...)
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	for _, v := range versions {
+		m, ok := migrations[v]
+		if !ok || m.DownSQL == "" {
+			return fmt.Errorf("cannot roll back migration %03d: %w", v, errIrreversibleMigration)
+		}
+	}
+
+	for _, v := range versions {
+		m := migrations[v]
+		logrus.Infof("Rolling back migration %03d: %s", m.Version, m.Name)
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, m.DownSQL); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to roll back migration %03d: %w", m.Version, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.Version); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to unrecord migration %03d: %w", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit rollback of migration %03d: %w", m.Version, err)
+		}
+
+		logrus.Infof("Successfully rolled back migration %03d", m.Version)
+	}
+
+	return nil
+}
+
 func createMigrationsTable(ctx context.Context, db *sql.DB) error {
 	query := `
 	CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -115,14 +353,17 @@ func loadMigrations() ([]Migration, error) {
 		return nil, err
 	}
 
-	var migrations []Migration
+	byVersion := make(map[int]*Migration)
+	downs := make(map[int]string)
 	for _, entry := range entries {
 		if !strings.HasSuffix(entry.Name(), ".sql") {
 			continue
 		}
+		down := strings.HasSuffix(entry.Name(), downMigrationSuffix)
 
 		// Parse version and name from filename (e.g., "001_initial_schema.sql")
-		parts := strings.SplitN(strings.TrimSuffix(entry.Name(), ".sql"), "_", 2)
+		base := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), downMigrationSuffix), ".sql")
+		parts := strings.SplitN(base, "_", 2)
 		if len(parts) != 2 {
 			continue
 		}
@@ -138,12 +379,24 @@ func loadMigrations() ([]Migration, error) {
 			return nil, err
 		}
 
-		migrations = append(migrations, Migration{
+		if down {
+			downs[version] = string(content)
+			continue
+		}
+		byVersion[version] = &Migration{
 			Version: version,
 			Name:    parts[1],
 			SQL:     string(content),
-		})
+		}
 	}
 
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, m := range byVersion {
+		m.DownSQL = downs[version]
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
 	return migrations, nil
 }
@@ -281,3 +281,99 @@ func TestContextCancellation(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, context.Canceled, err)
 }
+
+func TestLoadMigrationsPairsDownMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.NotContains(t, m.Name, ".down", "down files are not migrations of their own")
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version)
+		}
+	}
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Empty(t, migrations[0].DownSQL, "the initial schema cannot be rolled back")
+	for _, m := range migrations[1:] {
+		assert.NotEmpty(t, m.DownSQL, "migration %03d has no down migration", m.Version)
+	}
+}
+
+func TestGetMigrationStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	appliedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version, name, applied_at FROM schema_migrations`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+			AddRow(1, "initial_schema", appliedAt).
+			AddRow(2, "parity_asset_metadata_backups", appliedAt))
+	mock.ExpectRollback()
+
+	statuses, err := GetMigrationStatus(context.Background(), db)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Greater(t, len(statuses), 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].Reversible)
+	require.NotNil(t, statuses[1].AppliedAt)
+	assert.Equal(t, appliedAt, *statuses[1].AppliedAt)
+	assert.True(t, statuses[1].Reversible)
+	assert.False(t, statuses[2].Applied)
+	assert.Nil(t, statuses[2].AppliedAt)
+}
+
+func TestMigrateDown(t *testing.T) {
+	appliedOneTwo := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1).AddRow(2))
+	}
+
+	t.Run("rolls back the newest migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		appliedOneTwo(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(`DROP TABLE IF EXISTS public.database_backups`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, MigrateDown(context.Background(), db, 1))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to roll back the initial schema", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		appliedOneTwo(mock)
+
+		err = MigrateDown(context.Background(), db, 2)
+		require.ErrorIs(t, err, errIrreversibleMigration)
+		assert.NoError(t, mock.ExpectationsWereMet(), "nothing is rolled back")
+	})
+
+	t.Run("rejects more steps than applied", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		appliedOneTwo(mock)
+
+		assert.Error(t, MigrateDown(context.Background(), db, 3))
+	})
+}
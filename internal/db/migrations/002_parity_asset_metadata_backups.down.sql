@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS public.database_backups;
+DROP TABLE IF EXISTS public.asset_ocr;
+DROP TABLE IF EXISTS public.asset_edits;
+DROP TABLE IF EXISTS public.asset_metadata;
+
+ALTER TABLE public.sessions
+    DROP COLUMN IF EXISTS "appVersion",
+    DROP COLUMN IF EXISTS "isPendingSyncReset";
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS public.workflow_executions;
+DROP TABLE IF EXISTS public.workflows;
+
+DROP INDEX IF EXISTS public."IDX_sessions_oauth_sid";
+ALTER TABLE public.sessions
+    DROP COLUMN IF EXISTS "oauthSid";
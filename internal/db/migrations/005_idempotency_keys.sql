@@ -0,0 +1,16 @@
+-- Responses to requests sent with an Idempotency-Key header, replayed when a
+-- client retries the same request. Rows expire after a TTL.
+
+CREATE TABLE IF NOT EXISTS public.idempotency_keys (
+    "userId" uuid NOT NULL,
+    key character varying NOT NULL,
+    fingerprint character varying DEFAULT ''::character varying NOT NULL,
+    "statusCode" integer,
+    response jsonb,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "expiresAt" timestamp with time zone NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY ("userId", key),
+    CONSTRAINT "idempotency_keys_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "IDX_idempotency_keys_expiresAt" ON public.idempotency_keys USING btree ("expiresAt");
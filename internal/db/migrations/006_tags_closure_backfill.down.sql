@@ -0,0 +1,3 @@
+-- The backfilled rows are the same ones tag writes maintain, so they are
+-- kept: removing them would break nested tag lookups.
+SELECT 1;
@@ -0,0 +1,14 @@
+-- Nested tags are resolved through tags_closure. Backfill it for existing
+-- tags: every tag is its own ancestor, plus each tag along its parent chain.
+
+INSERT INTO public.tags_closure (id_ancestor, id_descendant)
+WITH RECURSIVE chain AS (
+    SELECT t.id AS id_ancestor, t.id AS id_descendant, t."parentId" AS parent_id
+    FROM public.tags t
+    UNION
+    SELECT p.id, c.id_descendant, p."parentId"
+    FROM chain c
+    INNER JOIN public.tags p ON p.id = c.parent_id
+)
+SELECT id_ancestor, id_descendant FROM chain
+ON CONFLICT DO NOTHING;
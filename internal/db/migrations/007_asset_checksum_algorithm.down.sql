@@ -0,0 +1,4 @@
+-- Checksums stay in their hex form: the raw digests they were converted from
+-- cannot be told apart from uploads afterwards.
+ALTER TABLE public.assets
+    DROP COLUMN IF EXISTS "checksumAlgorithm";
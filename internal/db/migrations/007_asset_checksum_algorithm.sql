@@ -0,0 +1,26 @@
+-- Record which hash produced each asset checksum so deduplication compares
+-- like with like, and store every checksum as the bytes of its lowercase hex
+-- digest (the format used by uploads).
+
+ALTER TABLE public.assets
+    ADD COLUMN IF NOT EXISTS "checksumAlgorithm" character varying DEFAULT 'sha1'::character varying NOT NULL;
+
+-- Hex SHA-256 digests.
+UPDATE public.assets
+SET "checksumAlgorithm" = 'sha256'
+WHERE octet_length(checksum) = 64
+AND encode(checksum, 'escape') ~ '^[0-9a-fA-F]{64}$';
+
+-- Raw digests: SHA-1 from upstream Immich, SHA-256 from earlier library
+-- scans. Rows whose hex form already exists for the same owner and library
+-- are left alone rather than breaking the unique checksum indexes.
+UPDATE public.assets a
+SET checksum = convert_to(encode(a.checksum, 'hex'), 'UTF8'),
+    "checksumAlgorithm" = CASE WHEN octet_length(a.checksum) = 32 THEN 'sha256' ELSE 'sha1' END
+WHERE octet_length(a.checksum) IN (20, 32)
+AND NOT EXISTS (
+    SELECT 1 FROM public.assets o
+    WHERE o."ownerId" = a."ownerId"
+    AND o."libraryId" IS NOT DISTINCT FROM a."libraryId"
+    AND o.checksum = convert_to(encode(a.checksum, 'hex'), 'UTF8')
+);
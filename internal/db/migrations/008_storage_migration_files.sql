@@ -0,0 +1,15 @@
+-- Files copied by the migrate-storage command, so an interrupted migration
+-- resumes where it left off and source files are only deleted once every
+-- copy has been verified.
+
+CREATE TABLE IF NOT EXISTS public.storage_migration_files (
+    "fromBackend" character varying NOT NULL,
+    "toBackend" character varying NOT NULL,
+    "sourcePath" text NOT NULL,
+    "destinationPath" text NOT NULL,
+    size bigint NOT NULL,
+    checksum bytea NOT NULL,
+    status character varying DEFAULT 'verified'::character varying NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT storage_migration_files_pkey PRIMARY KEY ("fromBackend", "toBackend", "sourcePath")
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public."IDX_assets_undated";
+ALTER TABLE public.assets
+    DROP COLUMN IF EXISTS "isUndated";
@@ -0,0 +1,8 @@
+-- Assets without a known capture date are flagged instead of being dated to
+-- their upload, so the timeline groups them in one bucket after every dated
+-- one. Setting a capture date clears the flag.
+
+ALTER TABLE public.assets
+    ADD COLUMN IF NOT EXISTS "isUndated" boolean DEFAULT false NOT NULL;
+
+CREATE INDEX IF NOT EXISTS "IDX_assets_undated" ON public.assets USING btree ("ownerId") WHERE "isUndated";
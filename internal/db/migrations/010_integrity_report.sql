@@ -0,0 +1,14 @@
+-- Findings of the last integrity scan. The scan job replaces the rows on
+-- every run, so the admin report no longer re-reads every file on request.
+
+CREATE TABLE IF NOT EXISTS public.integrity_report (
+    id uuid NOT NULL,
+    type character varying NOT NULL,
+    path text NOT NULL,
+    "assetId" uuid,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT integrity_report_pkey PRIMARY KEY (id),
+    CONSTRAINT integrity_report_asset_fkey FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS integrity_report_type_path_idx ON public.integrity_report (type, path);
@@ -0,0 +1,14 @@
+-- Objects and scenes detected in an asset by the ML service ("dog",
+-- "beach"). Every detection above the detection threshold is stored with its
+-- confidence so the search threshold can be tuned without re-running it.
+
+CREATE TABLE IF NOT EXISTS public.asset_labels (
+    "assetId" uuid NOT NULL,
+    label text NOT NULL,
+    score real NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_labels_pkey PRIMARY KEY ("assetId", label),
+    CONSTRAINT asset_labels_asset_fkey FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS asset_labels_label_score_idx ON public.asset_labels (label, score);
@@ -0,0 +1,3 @@
+ALTER TABLE public.partners
+    DROP COLUMN IF EXISTS "acceptedAt",
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+-- Sharing a library with a partner needs their consent: a new partnership is
+-- an invite that stays pending until the partner accepts it. Partnerships
+-- that existed before invites were introduced count as accepted.
+
+ALTER TABLE public.partners
+    ADD COLUMN IF NOT EXISTS status character varying DEFAULT 'accepted' NOT NULL,
+    ADD COLUMN IF NOT EXISTS "acceptedAt" timestamp with time zone;
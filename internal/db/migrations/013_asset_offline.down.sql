@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS public."IDX_assets_offline";
+ALTER TABLE public.assets
+    DROP COLUMN IF EXISTS "offlineAt";
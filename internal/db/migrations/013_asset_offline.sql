@@ -0,0 +1,8 @@
+-- Assets of external libraries whose file disappeared are flagged offline by
+-- the library scan. "offlineAt" records since when, so assets that stay
+-- offline past the retention period can be removed.
+
+ALTER TABLE public.assets
+    ADD COLUMN IF NOT EXISTS "offlineAt" timestamp with time zone;
+
+CREATE INDEX IF NOT EXISTS "IDX_assets_offline" ON public.assets USING btree ("libraryId", "offlineAt") WHERE "isOffline";
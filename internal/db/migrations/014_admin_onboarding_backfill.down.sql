@@ -0,0 +1,2 @@
+-- The flag may since have been written by the admin, so it is kept.
+SELECT 1;
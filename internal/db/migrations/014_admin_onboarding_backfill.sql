@@ -0,0 +1,8 @@
+-- Fresh installs report the admin as not onboarded until first-run setup is
+-- completed. Instances that already have users were set up before the flag
+-- was tracked, so mark them onboarded instead of replaying the wizard.
+
+INSERT INTO public.system_metadata (key, value)
+SELECT 'admin_onboarding_completed', 'true'::jsonb
+WHERE EXISTS (SELECT 1 FROM public.users WHERE "deletedAt" IS NULL)
+ON CONFLICT (key) DO NOTHING;
@@ -0,0 +1,15 @@
+-- A user deleted by an admin keeps their data for a grace period, during
+-- which the account can be restored with the token emailed to them. Once
+-- deleteAt has passed the account and its data are removed for good.
+
+CREATE TABLE IF NOT EXISTS public.user_deletion_schedules (
+    "userId" uuid NOT NULL,
+    "deleteAt" timestamp with time zone NOT NULL,
+    "restoreTokenHash" character varying NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT user_deletion_schedules_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "user_deletion_schedules_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "IDX_user_deletion_schedules_restoreTokenHash" ON public.user_deletion_schedules USING btree ("restoreTokenHash");
+CREATE INDEX IF NOT EXISTS "IDX_user_deletion_schedules_deleteAt" ON public.user_deletion_schedules USING btree ("deleteAt");
@@ -0,0 +1 @@
+ALTER TABLE public.asset_faces DROP COLUMN IF EXISTS score;
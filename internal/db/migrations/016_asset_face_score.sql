@@ -0,0 +1,5 @@
+-- The detection confidence of a face, used to pick the face a person's
+-- thumbnail is cropped from. Faces detected before this column existed
+-- score 0.
+
+ALTER TABLE public.asset_faces ADD COLUMN IF NOT EXISTS score real DEFAULT 0 NOT NULL;
@@ -0,0 +1,16 @@
+-- Images larger than the configured upload limit are downscaled before they
+-- are stored. This records the size they were uploaded at, and whether the
+-- uploaded file was kept as the asset's original_backup file. When it was
+-- not, the asset checksum no longer matches any stored file.
+
+CREATE TABLE IF NOT EXISTS public.asset_downscales (
+    "assetId" uuid NOT NULL,
+    "originalWidth" integer NOT NULL,
+    "originalHeight" integer NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    "originalKept" boolean NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_downscales_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_downscales_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public."UQ_assets_owner_device_asset";
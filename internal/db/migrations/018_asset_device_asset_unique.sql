@@ -0,0 +1,25 @@
+-- A device asset is backed up once per owner: a repeated backup of the same
+-- device photo updates its asset instead of creating another one. Library
+-- assets, and assets without a device or device asset ID, are left out.
+--
+-- Uploads through the upload session API used their file name as device
+-- asset ID, which does not identify a photo, so they get their asset ID.
+-- Of the other duplicates the newest asset is kept. The files of the removed
+-- ones show up as untracked files in the next integrity scan.
+
+UPDATE public.assets
+SET "deviceAssetId" = id::text
+WHERE "deviceId" = 'go-backend' AND "libraryId" IS NULL;
+
+DELETE FROM public.assets a
+USING (
+    SELECT id, row_number() OVER (
+        PARTITION BY "ownerId", "deviceId", "deviceAssetId"
+        ORDER BY "createdAt" DESC, id DESC
+    ) AS rank
+    FROM public.assets
+    WHERE "libraryId" IS NULL AND "deviceId" <> '' AND "deviceAssetId" <> ''
+) ranked
+WHERE a.id = ranked.id AND ranked.rank > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "UQ_assets_owner_device_asset" ON public.assets USING btree ("ownerId", "deviceId", "deviceAssetId") WHERE ("libraryId" IS NULL AND "deviceId" <> '' AND "deviceAssetId" <> '');
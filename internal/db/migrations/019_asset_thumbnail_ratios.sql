@@ -0,0 +1,12 @@
+-- The aspect ratio of the thumbnails of an asset, recorded when they are
+-- generated, so timeline grids can be laid out before any thumbnail is
+-- loaded. Thumbnails are not rotated for EXIF orientation, so this can
+-- differ from the ratio of the EXIF dimensions.
+
+CREATE TABLE IF NOT EXISTS public.asset_thumbnail_ratios (
+    "assetId" uuid NOT NULL,
+    ratio double precision NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_thumbnail_ratios_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_thumbnail_ratios_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
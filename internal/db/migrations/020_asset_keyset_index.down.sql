@@ -0,0 +1 @@
+DROP INDEX IF EXISTS public."IDX_assets_owner_local_date_time_id";
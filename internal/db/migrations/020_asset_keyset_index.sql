@@ -0,0 +1,5 @@
+-- Asset listings page newest first, keyed on the capture time and id of the
+-- last asset of the previous page. This index lets such a page start at its
+-- cursor instead of scanning every asset before it.
+
+CREATE INDEX IF NOT EXISTS "IDX_assets_owner_local_date_time_id" ON public.assets USING btree ("ownerId", "localDateTime" DESC, id DESC);
@@ -0,0 +1,19 @@
+-- Server-wide webhook subscriptions. Events of the subscribed types are
+-- POSTed to the URL, signed with the secret. The last* columns record the
+-- outcome of the latest delivery attempt.
+
+CREATE TABLE IF NOT EXISTS public.webhook_subscriptions (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    url text NOT NULL,
+    events text[] NOT NULL,
+    secret text NOT NULL,
+    enabled boolean DEFAULT true NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "lastDeliveryAt" timestamp with time zone,
+    "lastSuccessAt" timestamp with time zone,
+    "lastStatusCode" integer,
+    "lastError" text,
+    "failureCount" integer DEFAULT 0 NOT NULL,
+    CONSTRAINT webhook_subscriptions_pkey PRIMARY KEY (id)
+);
@@ -0,0 +1,14 @@
+-- A user created by an admin can be sent a link to choose their own
+-- password. The link carries a token whose hash is kept here until it is
+-- used or expiresAt has passed.
+
+CREATE TABLE IF NOT EXISTS public.password_setup_tokens (
+    "userId" uuid NOT NULL,
+    "tokenHash" character varying NOT NULL,
+    "expiresAt" timestamp with time zone NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT password_setup_tokens_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "password_setup_tokens_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "IDX_password_setup_tokens_tokenHash" ON public.password_setup_tokens USING btree ("tokenHash");
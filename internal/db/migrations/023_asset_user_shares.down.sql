@@ -0,0 +1 @@
+DROP TABLE IF EXISTS public.asset_user_shares;
@@ -0,0 +1,17 @@
+-- Assets shared directly with another user, one row per asset and
+-- recipient. The recipient can view and download the asset but not modify
+-- or share it; deleting the row revokes access.
+
+CREATE TABLE IF NOT EXISTS public.asset_user_shares (
+    "assetId" uuid NOT NULL,
+    "sharedById" uuid NOT NULL,
+    "sharedWithId" uuid NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_user_shares_pkey PRIMARY KEY ("assetId", "sharedWithId"),
+    CONSTRAINT "asset_user_shares_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE,
+    CONSTRAINT "asset_user_shares_sharedById_fkey" FOREIGN KEY ("sharedById") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT "asset_user_shares_sharedWithId_fkey" FOREIGN KEY ("sharedWithId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "IDX_asset_user_shares_sharedWithId" ON public.asset_user_shares USING btree ("sharedWithId", "createdAt" DESC);
+CREATE INDEX IF NOT EXISTS "IDX_asset_user_shares_sharedById" ON public.asset_user_shares USING btree ("sharedById", "createdAt" DESC);
@@ -0,0 +1,14 @@
+-- Per-user overrides of the shared link and API key limits of the server
+-- configuration. A NULL limit falls back to the configured one and 0 does not
+-- limit the user.
+
+CREATE TABLE IF NOT EXISTS public.user_limits (
+    "userId" uuid NOT NULL,
+    "maxSharedLinks" integer,
+    "maxApiKeys" integer,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT user_limits_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "user_limits_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT user_limits_max_shared_links_check CHECK ("maxSharedLinks" >= 0),
+    CONSTRAINT user_limits_max_api_keys_check CHECK ("maxApiKeys" >= 0)
+);
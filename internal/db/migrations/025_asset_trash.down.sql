@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS asset_trash_status ON public.assets;
+DROP FUNCTION IF EXISTS public.asset_trash_status();
+DROP TABLE IF EXISTS public.asset_trash;
@@ -0,0 +1,34 @@
+-- When each asset was moved to the trash. The updatedAt column of assets
+-- changes on every edit, so it cannot tell how long an asset has been in the
+-- trash. A trigger keeps the table in step with the status of the assets.
+
+CREATE TABLE IF NOT EXISTS public.asset_trash (
+    "assetId" uuid NOT NULL,
+    "trashedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_trash_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_trash_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "IDX_asset_trash_trashedAt" ON public.asset_trash USING btree ("trashedAt" DESC, "assetId" DESC);
+
+CREATE OR REPLACE FUNCTION public.asset_trash_status() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+          BEGIN
+              IF new.status = 'trashed' AND new."deletedAt" IS NULL THEN
+                  INSERT INTO asset_trash ("assetId") VALUES (new.id) ON CONFLICT DO NOTHING;
+              ELSE
+                  DELETE FROM asset_trash WHERE "assetId" = new.id;
+              END IF;
+              RETURN NULL;
+          END;
+          $$;
+
+DROP TRIGGER IF EXISTS asset_trash_status ON public.assets;
+CREATE TRIGGER asset_trash_status AFTER INSERT OR UPDATE OF status, "deletedAt" ON public.assets FOR EACH ROW EXECUTE FUNCTION public.asset_trash_status();
+
+-- Assets trashed before this migration count from their last update
+INSERT INTO public.asset_trash ("assetId", "trashedAt")
+SELECT id, "updatedAt" FROM public.assets
+WHERE status = 'trashed' AND "deletedAt" IS NULL
+ON CONFLICT DO NOTHING;
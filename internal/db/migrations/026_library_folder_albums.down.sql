@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.library_folder_albums;
+DROP TABLE IF EXISTS public.library_folder_album_settings;
@@ -0,0 +1,26 @@
+-- Albums mirroring the folders of external libraries. A library with
+-- settings puts the assets its scans import into the album of their folder,
+-- creating it once: the folder keeps its album across rescans until the
+-- album is deleted.
+
+CREATE TABLE IF NOT EXISTS public.library_folder_album_settings (
+    "libraryId" uuid NOT NULL,
+    depth integer DEFAULT 0 NOT NULL,
+    naming character varying DEFAULT 'folder'::character varying NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT library_folder_album_settings_pkey PRIMARY KEY ("libraryId"),
+    CONSTRAINT "library_folder_album_settings_libraryId_fkey" FOREIGN KEY ("libraryId") REFERENCES public.libraries(id) ON DELETE CASCADE,
+    CONSTRAINT library_folder_album_settings_depth_check CHECK (depth >= 0),
+    CONSTRAINT library_folder_album_settings_naming_check CHECK (naming IN ('folder', 'path'))
+);
+
+CREATE TABLE IF NOT EXISTS public.library_folder_albums (
+    "libraryId" uuid NOT NULL,
+    "folderPath" text NOT NULL,
+    "albumId" uuid NOT NULL,
+    CONSTRAINT library_folder_albums_pkey PRIMARY KEY ("libraryId", "folderPath"),
+    CONSTRAINT "library_folder_albums_libraryId_fkey" FOREIGN KEY ("libraryId") REFERENCES public.libraries(id) ON DELETE CASCADE,
+    CONSTRAINT "library_folder_albums_albumId_fkey" FOREIGN KEY ("albumId") REFERENCES public.albums(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS "IDX_library_folder_albums_albumId" ON public.library_folder_albums USING btree ("albumId");
@@ -0,0 +1,5 @@
+-- The sync manifest pages through all of a user's assets by id, on every
+-- app resume. This index lets a page start at its cursor instead of
+-- sorting every asset of the user.
+
+CREATE INDEX IF NOT EXISTS "IDX_assets_owner_id" ON public.assets USING btree ("ownerId", id);
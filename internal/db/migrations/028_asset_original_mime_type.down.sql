@@ -0,0 +1,2 @@
+ALTER TABLE public.assets
+    DROP COLUMN IF EXISTS "originalMimeType";
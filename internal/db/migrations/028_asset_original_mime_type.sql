@@ -0,0 +1,6 @@
+-- The MIME type of an original as detected when it was uploaded. Downloads
+-- and metadata extraction use it instead of guessing from the file name,
+-- which they still do for assets uploaded before.
+
+ALTER TABLE public.assets
+    ADD COLUMN IF NOT EXISTS "originalMimeType" text;
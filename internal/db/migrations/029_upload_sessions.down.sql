@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.upload_session_chunks;
+DROP TABLE IF EXISTS public.upload_sessions;
@@ -0,0 +1,26 @@
+-- Resumable uploads. A session is opened for an asset whose file arrives in
+-- chunks, with the size and checksum the whole file must have; each chunk
+-- received is recorded, so a client that lost its connection can ask which
+-- byte ranges are still missing. The session ends when the file is stored.
+
+CREATE TABLE IF NOT EXISTS public.upload_sessions (
+    "assetId" uuid NOT NULL,
+    "ownerId" uuid NOT NULL,
+    "totalSize" bigint NOT NULL,
+    checksum character varying NOT NULL,
+    "checksumAlgorithm" character varying NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT upload_sessions_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "upload_sessions_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE,
+    CONSTRAINT "upload_sessions_ownerId_fkey" FOREIGN KEY ("ownerId") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT "upload_sessions_totalSize_check" CHECK ("totalSize" > 0)
+);
+
+CREATE TABLE IF NOT EXISTS public.upload_session_chunks (
+    "assetId" uuid NOT NULL,
+    start bigint NOT NULL,
+    size bigint NOT NULL,
+    "receivedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT upload_session_chunks_pkey PRIMARY KEY ("assetId", start),
+    CONSTRAINT "upload_session_chunks_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.upload_sessions("assetId") ON DELETE CASCADE
+);
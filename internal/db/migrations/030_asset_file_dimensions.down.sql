@@ -0,0 +1,4 @@
+ALTER TABLE public.asset_files
+    DROP COLUMN IF EXISTS "sizeInBytes",
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width;
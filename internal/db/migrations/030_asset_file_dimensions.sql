@@ -0,0 +1,8 @@
+-- The dimensions and size of generated files such as thumbnails, recorded
+-- when they are stored so they can be listed without reading them back.
+-- Files stored before have none.
+
+ALTER TABLE public.asset_files
+    ADD COLUMN IF NOT EXISTS width integer,
+    ADD COLUMN IF NOT EXISTS height integer,
+    ADD COLUMN IF NOT EXISTS "sizeInBytes" bigint;
@@ -0,0 +1,5 @@
+-- A BlurHash placeholder of the thumbnails of an asset, computed from the
+-- small thumbnail next to its ThumbHash. Assets thumbnailed before have none.
+
+ALTER TABLE public.assets
+    ADD COLUMN IF NOT EXISTS blurhash text;
@@ -208,34 +208,49 @@ type ApiKey struct {
 }
 
 type Asset struct {
-	ID               pgtype.UUID
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	IsFavorite       bool
-	Duration         pgtype.Text
-	EncodedVideoPath pgtype.Text
-	Checksum         []byte
-	LivePhotoVideoId pgtype.UUID
-	UpdatedAt        pgtype.Timestamptz
-	CreatedAt        pgtype.Timestamptz
-	OriginalFileName string
-	SidecarPath      pgtype.Text
-	Thumbhash        []byte
-	IsOffline        bool
-	LibraryId        pgtype.UUID
-	IsExternal       bool
-	DeletedAt        pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	StackId          pgtype.UUID
-	DuplicateId      pgtype.UUID
-	Status           AssetsStatusEnum
-	UpdateId         pgtype.UUID
-	Visibility       AssetVisibilityEnum
+	ID                pgtype.UUID
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	IsFavorite        bool
+	Duration          pgtype.Text
+	EncodedVideoPath  pgtype.Text
+	Checksum          []byte
+	LivePhotoVideoId  pgtype.UUID
+	UpdatedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	OriginalFileName  string
+	SidecarPath       pgtype.Text
+	Thumbhash         []byte
+	IsOffline         bool
+	LibraryId         pgtype.UUID
+	IsExternal        bool
+	DeletedAt         pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	StackId           pgtype.UUID
+	DuplicateId       pgtype.UUID
+	Status            AssetsStatusEnum
+	UpdateId          pgtype.UUID
+	Visibility        AssetVisibilityEnum
+	ChecksumAlgorithm string
+	IsUndated         bool
+	OfflineAt         pgtype.Timestamptz
+	OriginalMimeType  pgtype.Text
+	Blurhash          pgtype.Text
+}
+
+type AssetDownscale struct {
+	AssetId        pgtype.UUID
+	OriginalWidth  int32
+	OriginalHeight int32
+	Width          int32
+	Height         int32
+	OriginalKept   bool
+	CreatedAt      pgtype.Timestamptz
 }
 
 type AssetEdit struct {
@@ -260,16 +275,20 @@ type AssetFace struct {
 	ID            pgtype.UUID
 	SourceType    Sourcetype
 	DeletedAt     pgtype.Timestamptz
+	Score         float32
 }
 
 type AssetFile struct {
-	ID        pgtype.UUID
-	AssetId   pgtype.UUID
-	CreatedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
-	Type      string
-	Path      string
-	UpdateId  pgtype.UUID
+	ID          pgtype.UUID
+	AssetId     pgtype.UUID
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+	Type        string
+	Path        string
+	UpdateId    pgtype.UUID
+	Width       pgtype.Int4
+	Height      pgtype.Int4
+	SizeInBytes pgtype.Int8
 }
 
 type AssetJobStatus struct {
@@ -281,6 +300,13 @@ type AssetJobStatus struct {
 	ThumbnailAt          pgtype.Timestamptz
 }
 
+type AssetLabel struct {
+	AssetId   pgtype.UUID
+	Label     string
+	Score     float32
+	CreatedAt pgtype.Timestamptz
+}
+
 type AssetMetadatum struct {
 	AssetId   pgtype.UUID
 	Key       string
@@ -313,6 +339,24 @@ type AssetStack struct {
 	OwnerId        pgtype.UUID
 }
 
+type AssetThumbnailRatio struct {
+	AssetId   pgtype.UUID
+	Ratio     float64
+	UpdatedAt pgtype.Timestamptz
+}
+
+type AssetTrash struct {
+	AssetId   pgtype.UUID
+	TrashedAt pgtype.Timestamptz
+}
+
+type AssetUserShare struct {
+	AssetId      pgtype.UUID
+	SharedById   pgtype.UUID
+	SharedWithId pgtype.UUID
+	CreatedAt    pgtype.Timestamptz
+}
+
 type AssetView struct {
 	AssetID  pgtype.UUID
 	UserID   pgtype.UUID
@@ -397,6 +441,24 @@ type GeodataPlace struct {
 	AlternateNames   pgtype.Text
 }
 
+type IdempotencyKey struct {
+	UserId      pgtype.UUID
+	Key         string
+	Fingerprint string
+	StatusCode  pgtype.Int4
+	Response    []byte
+	CreatedAt   pgtype.Timestamptz
+	ExpiresAt   pgtype.Timestamptz
+}
+
+type IntegrityReport struct {
+	ID        pgtype.UUID
+	Type      string
+	Path      string
+	AssetId   pgtype.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
 type JobFailure struct {
 	ID           pgtype.UUID
 	Queue        string
@@ -432,6 +494,19 @@ type Library struct {
 	UpdateId          pgtype.UUID
 }
 
+type LibraryFolderAlbum struct {
+	LibraryId  pgtype.UUID
+	FolderPath string
+	AlbumId    pgtype.UUID
+}
+
+type LibraryFolderAlbumSetting struct {
+	LibraryId pgtype.UUID
+	Depth     int32
+	Naming    string
+	UpdatedAt pgtype.Timestamptz
+}
+
 type MemoriesAssetsAsset struct {
 	MemoriesId pgtype.UUID
 	AssetsId   pgtype.UUID
@@ -497,6 +572,8 @@ type Partner struct {
 	UpdatedAt    pgtype.Timestamptz
 	InTimeline   bool
 	UpdateId     pgtype.UUID
+	Status       string
+	AcceptedAt   pgtype.Timestamptz
 }
 
 type PartnersAudit struct {
@@ -506,6 +583,13 @@ type PartnersAudit struct {
 	DeletedAt    pgtype.Timestamptz
 }
 
+type PasswordSetupToken struct {
+	UserId    pgtype.UUID
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
 type Person struct {
 	ID            pgtype.UUID
 	CreatedAt     pgtype.Timestamptz
@@ -572,6 +656,17 @@ type SmartSearch struct {
 	Embedding interface{}
 }
 
+type StorageMigrationFile struct {
+	FromBackend     string
+	ToBackend       string
+	SourcePath      string
+	DestinationPath string
+	Size            int64
+	Checksum        []byte
+	Status          string
+	UpdatedAt       pgtype.Timestamptz
+}
+
 type SystemMetadatum struct {
 	Key   string
 	Value []byte
@@ -598,6 +693,22 @@ type TagsClosure struct {
 	IDDescendant pgtype.UUID
 }
 
+type UploadSession struct {
+	AssetId           pgtype.UUID
+	OwnerId           pgtype.UUID
+	TotalSize         int64
+	Checksum          string
+	ChecksumAlgorithm string
+	CreatedAt         pgtype.Timestamptz
+}
+
+type UploadSessionChunk struct {
+	AssetId    pgtype.UUID
+	Start      int64
+	Size       int64
+	ReceivedAt pgtype.Timestamptz
+}
+
 type User struct {
 	ID                   pgtype.UUID
 	Email                string
@@ -621,6 +732,20 @@ type User struct {
 	IsOnboarded          bool
 }
 
+type UserDeletionSchedule struct {
+	UserId           pgtype.UUID
+	DeleteAt         pgtype.Timestamptz
+	RestoreTokenHash string
+	CreatedAt        pgtype.Timestamptz
+}
+
+type UserLimit struct {
+	UserId         pgtype.UUID
+	MaxSharedLinks pgtype.Int4
+	MaxApiKeys     pgtype.Int4
+	UpdatedAt      pgtype.Timestamptz
+}
+
 type UserMetadatum struct {
 	UserId pgtype.UUID
 	Key    string
@@ -639,6 +764,21 @@ type VersionHistory struct {
 	Version   string
 }
 
+type WebhookSubscription struct {
+	ID             pgtype.UUID
+	Url            string
+	Events         []string
+	Secret         string
+	Enabled        bool
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	LastDeliveryAt pgtype.Timestamptz
+	LastSuccessAt  pgtype.Timestamptz
+	LastStatusCode pgtype.Int4
+	LastError      pgtype.Text
+	FailureCount   int32
+}
+
 type Workflow struct {
 	ID              pgtype.UUID
 	OwnerId         pgtype.UUID
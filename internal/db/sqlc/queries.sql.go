@@ -11,6 +11,38 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acceptPartnership = `-- name: AcceptPartnership :one
+UPDATE partners
+SET status = 'accepted',
+    "acceptedAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "sharedById" = $1 AND "sharedWithId" = $2 AND status = 'pending'
+RETURNING "sharedById", "sharedWithId", "createdAt", "updatedAt", "inTimeline", "updateId", status, "acceptedAt"
+`
+
+type AcceptPartnershipParams struct {
+	SharedById   pgtype.UUID
+	SharedWithId pgtype.UUID
+}
+
+// Only the invited user accepts, so the pair is matched in one direction.
+func (q *Queries) AcceptPartnership(ctx context.Context, arg AcceptPartnershipParams) (Partner, error) {
+	row := q.db.QueryRow(ctx, acceptPartnership, arg.SharedById, arg.SharedWithId)
+	var i Partner
+	err := row.Scan(
+		&i.SharedById,
+		&i.SharedWithId,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InTimeline,
+		&i.UpdateId,
+		&i.Status,
+		&i.AcceptedAt,
+	)
+	return i, err
+}
+
 const addAssetToAlbum = `-- name: AddAssetToAlbum :exec
 INSERT INTO albums_assets_assets ("albumsId", "assetsId")
 VALUES ($1, $2)
@@ -43,6 +75,40 @@ func (q *Queries) AddAssetToSharedLink(ctx context.Context, arg AddAssetToShared
 	return err
 }
 
+const addAssetsToAlbum = `-- name: AddAssetsToAlbum :many
+INSERT INTO albums_assets_assets ("albumsId", "assetsId")
+SELECT $1, unnest($2::uuid[])
+ON CONFLICT DO NOTHING
+RETURNING "assetsId"
+`
+
+type AddAssetsToAlbumParams struct {
+	AlbumID  pgtype.UUID
+	AssetIds []pgtype.UUID
+}
+
+// Adds the assets in one statement and returns the ones that were not
+// already in the album.
+func (q *Queries) AddAssetsToAlbum(ctx context.Context, arg AddAssetsToAlbumParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, addAssetsToAlbum, arg.AlbumID, arg.AssetIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var assetsId pgtype.UUID
+		if err := rows.Scan(&assetsId); err != nil {
+			return nil, err
+		}
+		items = append(items, assetsId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const addAssetsToMemory = `-- name: AddAssetsToMemory :exec
 INSERT INTO memories_assets_assets ("memoriesId", "assetsId")
 SELECT $1, unnest($2::uuid[])
@@ -77,6 +143,30 @@ func (q *Queries) AddAssetsToStack(ctx context.Context, arg AddAssetsToStackPara
 	return err
 }
 
+const addTagClosure = `-- name: AddTagClosure :exec
+INSERT INTO tags_closure (id_ancestor, id_descendant)
+SELECT $1::uuid, $1::uuid
+UNION
+SELECT $2::uuid, $1::uuid
+WHERE $2::uuid IS NOT NULL
+UNION
+SELECT tc.id_ancestor, $1::uuid
+FROM tags_closure tc
+WHERE tc.id_descendant = $2::uuid
+ON CONFLICT DO NOTHING
+`
+
+type AddTagClosureParams struct {
+	TagID    pgtype.UUID
+	ParentID pgtype.UUID
+}
+
+// Links a new tag to itself and to every ancestor of its parent.
+func (q *Queries) AddTagClosure(ctx context.Context, arg AddTagClosureParams) error {
+	_, err := q.db.Exec(ctx, addTagClosure, arg.TagID, arg.ParentID)
+	return err
+}
+
 const addTagToAsset = `-- name: AddTagToAsset :exec
 INSERT INTO tag_asset ("tagsId", "assetsId")
 VALUES ($1, $2)
@@ -110,6 +200,77 @@ func (q *Queries) AddUserToAlbum(ctx context.Context, arg AddUserToAlbumParams)
 	return err
 }
 
+const adjustUserQuotaUsage = `-- name: AdjustUserQuotaUsage :exec
+UPDATE users
+SET "quotaUsageInBytes" = GREATEST("quotaUsageInBytes" + $1::bigint, 0),
+    "updatedAt" = now()
+WHERE id = $2
+`
+
+type AdjustUserQuotaUsageParams struct {
+	Delta int64
+	ID    pgtype.UUID
+}
+
+// Usage never goes below zero, in case it was not tracked before.
+func (q *Queries) AdjustUserQuotaUsage(ctx context.Context, arg AdjustUserQuotaUsageParams) error {
+	_, err := q.db.Exec(ctx, adjustUserQuotaUsage, arg.Delta, arg.ID)
+	return err
+}
+
+const bulkAddTagsToAssets = `-- name: BulkAddTagsToAssets :execrows
+INSERT INTO tag_asset ("tagsId", "assetsId")
+SELECT t.id, a.id
+FROM tags t
+CROSS JOIN assets a
+WHERE t.id = ANY($1::uuid[])
+AND t."userId" = $2
+AND a.id = ANY($3::uuid[])
+AND a."ownerId" = $2
+ON CONFLICT DO NOTHING
+`
+
+type BulkAddTagsToAssetsParams struct {
+	TagIds   []pgtype.UUID
+	UserID   pgtype.UUID
+	AssetIds []pgtype.UUID
+}
+
+// Applies every tag to every asset in a single statement, so a batch is
+// written entirely or not at all. Tags and assets not owned by the user are
+// skipped.
+func (q *Queries) BulkAddTagsToAssets(ctx context.Context, arg BulkAddTagsToAssetsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, bulkAddTagsToAssets, arg.TagIds, arg.UserID, arg.AssetIds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const checkAssetDirectlySharedWithUser = `-- name: CheckAssetDirectlySharedWithUser :one
+SELECT EXISTS(
+    SELECT 1 FROM asset_user_shares s
+    JOIN assets a ON a.id = s."assetId"
+    WHERE s."assetId" = $1
+    AND s."sharedWithId" = $2
+    AND a."ownerId" = s."sharedById"
+    AND a.visibility <> 'locked'
+) AS is_shared
+`
+
+type CheckAssetDirectlySharedWithUserParams struct {
+	AssetId      pgtype.UUID
+	SharedWithId pgtype.UUID
+}
+
+// A share lapses when the asset changes owner or is locked.
+func (q *Queries) CheckAssetDirectlySharedWithUser(ctx context.Context, arg CheckAssetDirectlySharedWithUserParams) (bool, error) {
+	row := q.db.QueryRow(ctx, checkAssetDirectlySharedWithUser, arg.AssetId, arg.SharedWithId)
+	var is_shared bool
+	err := row.Scan(&is_shared)
+	return is_shared, err
+}
+
 const checkAssetExistsByPath = `-- name: CheckAssetExistsByPath :one
 SELECT EXISTS(
   SELECT 1 FROM assets
@@ -125,14 +286,81 @@ func (q *Queries) CheckAssetExistsByPath(ctx context.Context, originalpath strin
 	return exists, err
 }
 
+const checkAssetInSharedLink = `-- name: CheckAssetInSharedLink :one
+SELECT EXISTS(
+    SELECT 1 FROM assets a
+    WHERE a.id = $1
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    AND (
+        EXISTS(
+            SELECT 1 FROM shared_link__asset sla
+            WHERE sla."sharedLinksId" = $2 AND sla."assetsId" = a.id
+        ) OR EXISTS(
+            SELECT 1 FROM shared_links l
+            JOIN albums_assets_assets aaa ON aaa."albumsId" = l."albumId"
+            WHERE l.id = $2 AND aaa."assetsId" = a.id
+        )
+    )
+) AS is_shared
+`
+
+type CheckAssetInSharedLinkParams struct {
+	AssetID pgtype.UUID
+	LinkID  pgtype.UUID
+}
+
+// The assets of a link are the ones added to it, or those of its album.
+func (q *Queries) CheckAssetInSharedLink(ctx context.Context, arg CheckAssetInSharedLinkParams) (bool, error) {
+	row := q.db.QueryRow(ctx, checkAssetInSharedLink, arg.AssetID, arg.LinkID)
+	var is_shared bool
+	err := row.Scan(&is_shared)
+	return is_shared, err
+}
+
+const checkAssetSharedWithPartner = `-- name: CheckAssetSharedWithPartner :one
+SELECT EXISTS(
+    SELECT 1 FROM assets a
+    JOIN partners p ON p."sharedById" = a."ownerId"
+    WHERE a.id = $1
+    AND p."sharedWithId" = $2
+    AND p.status = 'accepted'
+    AND a.visibility IN ('timeline', 'archive')
+    AND a."deletedAt" IS NULL
+) AS is_shared
+`
+
+type CheckAssetSharedWithPartnerParams struct {
+	ID           pgtype.UUID
+	SharedWithId pgtype.UUID
+}
+
+// Partners see the timeline and archive of whoever shares with them once
+// they accepted the partnership.
+func (q *Queries) CheckAssetSharedWithPartner(ctx context.Context, arg CheckAssetSharedWithPartnerParams) (bool, error) {
+	row := q.db.QueryRow(ctx, checkAssetSharedWithPartner, arg.ID, arg.SharedWithId)
+	var is_shared bool
+	err := row.Scan(&is_shared)
+	return is_shared, err
+}
+
 const checkAssetSharedWithUser = `-- name: CheckAssetSharedWithUser :one
 SELECT EXISTS(
     SELECT 1 FROM albums_assets_assets aaa
     JOIN albums_shared_users_users asuu ON aaa."albumsId" = asuu."albumsId"
     JOIN albums a ON a.id = aaa."albumsId"
+    JOIN assets asset ON asset.id = aaa."assetsId"
     WHERE aaa."assetsId" = $1
     AND asuu."usersId" = $2
     AND a."deletedAt" IS NULL
+    AND asset.visibility <> 'locked'
+) OR EXISTS(
+    SELECT 1 FROM asset_user_shares s
+    JOIN assets asset ON asset.id = s."assetId"
+    WHERE s."assetId" = $1
+    AND s."sharedWithId" = $2
+    AND asset."ownerId" = s."sharedById"
+    AND asset.visibility <> 'locked'
 ) AS is_shared
 `
 
@@ -141,6 +369,8 @@ type CheckAssetSharedWithUserParams struct {
 	UsersId  pgtype.UUID
 }
 
+// Locked assets are never shared, even when they are in a shared album.
+// Assets shared directly count too.
 func (q *Queries) CheckAssetSharedWithUser(ctx context.Context, arg CheckAssetSharedWithUserParams) (bool, error) {
 	row := q.db.QueryRow(ctx, checkAssetSharedWithUser, arg.AssetsId, arg.UsersId)
 	var is_shared bool
@@ -229,6 +459,15 @@ func (q *Queries) ClearDuplicateGroupByID(ctx context.Context, arg ClearDuplicat
 	return err
 }
 
+const clearIntegrityReport = `-- name: ClearIntegrityReport :exec
+DELETE FROM integrity_report
+`
+
+func (q *Queries) ClearIntegrityReport(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, clearIntegrityReport)
+	return err
+}
+
 const clearSessionPinElevation = `-- name: ClearSessionPinElevation :exec
 UPDATE sessions
 SET "pinExpiresAt" = NULL, "updatedAt" = NOW()
@@ -301,6 +540,69 @@ func (q *Queries) ClearUserProfileImage(ctx context.Context, id pgtype.UUID) (Us
 	return i, err
 }
 
+const claimFirstAdmin = `-- name: ClaimFirstAdmin :execrows
+INSERT INTO system_metadata (key, value)
+SELECT 'first_admin', to_jsonb($1::text)
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE "deletedAt" IS NULL)
+ON CONFLICT (key) DO NOTHING
+`
+
+// Claims the administrator slot of a fresh install for user_id. The key can
+// only be inserted once, so of concurrent first registrations exactly one
+// claims it; installs that already have users never do.
+func (q *Queries) ClaimFirstAdmin(ctx context.Context, userID string) (int64, error) {
+	result, err := q.db.Exec(ctx, claimFirstAdmin, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const completeIdempotencyKey = `-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET "statusCode" = $3, response = $4, "expiresAt" = $5
+WHERE "userId" = $1 AND key = $2
+`
+
+type CompleteIdempotencyKeyParams struct {
+	UserId     pgtype.UUID
+	Key        string
+	StatusCode pgtype.Int4
+	Response   []byte
+	ExpiresAt  pgtype.Timestamptz
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyKey,
+		arg.UserId,
+		arg.Key,
+		arg.StatusCode,
+		arg.Response,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const consumePasswordSetupToken = `-- name: ConsumePasswordSetupToken :one
+DELETE FROM password_setup_tokens
+WHERE "tokenHash" = $1 AND "expiresAt" > now()
+RETURNING "userId", "tokenHash", "expiresAt", "createdAt"
+`
+
+// Deletes a token and returns it, so concurrent requests cannot both use
+// it. Expired tokens cannot be used.
+func (q *Queries) ConsumePasswordSetupToken(ctx context.Context, tokenhash string) (PasswordSetupToken, error) {
+	row := q.db.QueryRow(ctx, consumePasswordSetupToken, tokenhash)
+	var i PasswordSetupToken
+	err := row.Scan(
+		&i.UserId,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const copyAssetAlbums = `-- name: CopyAssetAlbums :exec
 INSERT INTO albums_assets_assets ("albumsId", "assetsId")
 SELECT albums_assets_assets."albumsId", $2
@@ -414,14 +716,51 @@ func (q *Queries) CopyAssetStack(ctx context.Context, arg CopyAssetStackParams)
 	return err
 }
 
+const correctUserQuotaUsage = `-- name: CorrectUserQuotaUsage :execrows
+UPDATE users
+SET "quotaUsageInBytes" = $1::bigint,
+    "updatedAt" = now()
+WHERE id = $2 AND "quotaUsageInBytes" = $3::bigint
+`
+
+type CorrectUserQuotaUsageParams struct {
+	Actual int64
+	ID     pgtype.UUID
+	Stored int64
+}
+
+// Only applies while the stored usage is the one read, so an adjustment
+// made meanwhile is not overwritten.
+func (q *Queries) CorrectUserQuotaUsage(ctx context.Context, arg CorrectUserQuotaUsageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, correctUserQuotaUsage, arg.Actual, arg.ID, arg.Stored)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const countApiKeysByUser = `-- name: CountApiKeysByUser :one
+SELECT COUNT(*) FROM api_keys
+WHERE "userId" = $1
+`
+
+func (q *Queries) CountApiKeysByUser(ctx context.Context, userid pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countApiKeysByUser, userid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countAssets = `-- name: CountAssets :one
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND ($2::text IS NULL OR type = $2)
 AND ($3::boolean IS NULL OR "isFavorite" = $3)
 AND ($4::boolean IS NULL OR visibility = CASE WHEN $4::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND ($5::boolean IS NULL OR status = CASE WHEN $5::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
+AND "isOffline" = COALESCE($6::boolean, false)
 `
 
 type CountAssetsParams struct {
@@ -430,6 +769,7 @@ type CountAssetsParams struct {
 	IsFavorite pgtype.Bool
 	IsArchived pgtype.Bool
 	IsTrashed  pgtype.Bool
+	IsOffline  pgtype.Bool
 }
 
 func (q *Queries) CountAssets(ctx context.Context, arg CountAssetsParams) (int64, error) {
@@ -439,6 +779,7 @@ func (q *Queries) CountAssets(ctx context.Context, arg CountAssetsParams) (int64
 		arg.IsFavorite,
 		arg.IsArchived,
 		arg.IsTrashed,
+		arg.IsOffline,
 	)
 	var count int64
 	err := row.Scan(&count)
@@ -449,6 +790,7 @@ const countAssetsByOriginalPathPrefix = `-- name: CountAssetsByOriginalPathPrefi
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "originalPath" LIKE $2 || '%'
 AND ($3::boolean IS NULL OR visibility = CASE WHEN $3::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND ($4::boolean IS NULL OR "isFavorite" = $4)
@@ -484,6 +826,36 @@ func (q *Queries) CountJobFailures(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const countJobFailuresByQueue = `-- name: CountJobFailuresByQueue :many
+SELECT queue, COUNT(*) AS count FROM job_failures
+GROUP BY queue
+`
+
+type CountJobFailuresByQueueRow struct {
+	Queue string
+	Count int64
+}
+
+func (q *Queries) CountJobFailuresByQueue(ctx context.Context) ([]CountJobFailuresByQueueRow, error) {
+	rows, err := q.db.Query(ctx, countJobFailuresByQueue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountJobFailuresByQueueRow
+	for rows.Next() {
+		var i CountJobFailuresByQueueRow
+		if err := rows.Scan(&i.Queue, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const countLibraryAssets = `-- name: CountLibraryAssets :one
 SELECT COUNT(*) FROM assets
 WHERE "libraryId" = $1 AND "deletedAt" IS NULL
@@ -496,6 +868,20 @@ func (q *Queries) CountLibraryAssets(ctx context.Context, libraryid pgtype.UUID)
 	return count, err
 }
 
+const countLockedAssets = `-- name: CountLockedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND visibility = 'locked'
+`
+
+func (q *Queries) CountLockedAssets(ctx context.Context, ownerid pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countLockedAssets, ownerid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countMemories = `-- name: CountMemories :one
 SELECT COUNT(*) FROM memories
 WHERE "ownerId" = $1 AND "deletedAt" IS NULL
@@ -511,7 +897,7 @@ func (q *Queries) CountMemories(ctx context.Context, ownerid pgtype.UUID) (int64
 const countPersonAssets = `-- name: CountPersonAssets :one
 SELECT COUNT(DISTINCT a.id) FROM assets a
 JOIN asset_faces af ON a.id = af."assetId"
-WHERE af."personId" = $1 AND a."deletedAt" IS NULL
+WHERE af."personId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked'
 `
 
 func (q *Queries) CountPersonAssets(ctx context.Context, personid pgtype.UUID) (int64, error) {
@@ -521,10 +907,54 @@ func (q *Queries) CountPersonAssets(ctx context.Context, personid pgtype.UUID) (
 	return count, err
 }
 
+const countRecentlyAddedAssets = `-- name: CountRecentlyAddedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
+`
+
+func (q *Queries) CountRecentlyAddedAssets(ctx context.Context, ownerid pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countRecentlyAddedAssets, ownerid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countReindexAssets = `-- name: CountReindexAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "deletedAt" IS NULL
+AND status = 'active'
+AND "isOffline" = false
+AND ($1::uuid IS NULL OR "ownerId" = $1::uuid)
+AND ($2::uuid IS NULL OR "libraryId" = $2::uuid)
+AND ($3::timestamptz IS NULL OR "localDateTime" >= $3::timestamptz)
+AND ($4::timestamptz IS NULL OR "localDateTime" < $4::timestamptz)
+`
+
+type CountReindexAssetsParams struct {
+	OwnerID     pgtype.UUID
+	LibraryID   pgtype.UUID
+	TakenAfter  pgtype.Timestamptz
+	TakenBefore pgtype.Timestamptz
+}
+
+// The assets a re-index run with these filters processes.
+func (q *Queries) CountReindexAssets(ctx context.Context, arg CountReindexAssetsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countReindexAssets,
+		arg.OwnerID,
+		arg.LibraryID,
+		arg.TakenAfter,
+		arg.TakenBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countSearchAssets = `-- name: CountSearchAssets :one
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1 
   AND "deletedAt" IS NULL
+  AND visibility <> 'locked'
   AND (
     "originalFileName" ILIKE '%' || $2 || '%' OR
     description ILIKE '%' || $2 || '%'
@@ -548,6 +978,7 @@ SELECT COUNT(*) FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND ($2::text IS NULL OR a.type = $2::text)
 AND ($3::boolean IS NULL OR a."isFavorite" = $3::boolean)
 AND ($4::text IS NULL OR e.city = $4::text)
@@ -560,6 +991,7 @@ AND ($10::uuid IS NULL OR a."libraryId" = $10::uuid)
 AND ($11::text IS NULL OR a."deviceId" = $11::text)
 AND ($12::timestamptz IS NULL OR a."localDateTime" >= $12::timestamptz)
 AND ($13::timestamptz IS NULL OR a."localDateTime" <= $13::timestamptz)
+AND NOT a."isOffline"
 `
 
 type CountSearchAssetsFilteredParams struct {
@@ -604,12 +1036,19 @@ SELECT COUNT(DISTINCT a.id) FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     $2::text IS NULL
     OR a."originalFileName" ILIKE '%' || $2::text || '%'
     OR a."originalPath" ILIKE '%' || $2::text || '%'
     OR e.description ILIKE '%' || $2::text || '%'
     OR e."imageName" ILIKE '%' || $2::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM asset_labels al
+        WHERE al."assetId" = a.id
+        AND al.score >= $20::float8
+        AND al.label ILIKE '%' || $2::text || '%'
+    )
 )
 AND ($3::text IS NULL OR a.type = $3::text)
 AND ($4::boolean IS NULL OR a."isFavorite" = $4::boolean)
@@ -626,30 +1065,38 @@ AND ($14::timestamptz IS NULL OR a."localDateTime" >= $14::timestamptz)
 AND ($15::timestamptz IS NULL OR a."localDateTime" <= $15::timestamptz)
 AND ($16::boolean IS NULL OR (a."encodedVideoPath" IS NOT NULL AND a."encodedVideoPath" <> '') = $16::boolean)
 AND ($17::boolean IS NULL OR (a."livePhotoVideoId" IS NOT NULL) = $17::boolean)
-AND ($18::boolean IS NULL OR a."isOffline" = $18::boolean)
+AND a."isOffline" = COALESCE($18::boolean, false)
 AND ($19::boolean IS NULL OR a."isExternal" = $19::boolean)
+AND ($21::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= $20::float8
+    AND al.label = ANY($21::text[])
+) = cardinality($21::text[]))
 `
 
 type CountSearchAssetsFilteredForPageParams struct {
-	OwnerID     pgtype.UUID
-	Query       pgtype.Text
-	Type        pgtype.Text
-	IsFavorite  pgtype.Bool
-	IsArchived  pgtype.Bool
-	City        pgtype.Text
-	State       pgtype.Text
-	Country     pgtype.Text
-	Make        pgtype.Text
-	Model       pgtype.Text
-	LensModel   pgtype.Text
-	LibraryID   pgtype.UUID
-	DeviceID    pgtype.Text
-	TakenAfter  pgtype.Timestamptz
-	TakenBefore pgtype.Timestamptz
-	IsEncoded   pgtype.Bool
-	IsMotion    pgtype.Bool
-	IsOffline   pgtype.Bool
-	IsExternal  pgtype.Bool
+	OwnerID       pgtype.UUID
+	Query         pgtype.Text
+	Type          pgtype.Text
+	IsFavorite    pgtype.Bool
+	IsArchived    pgtype.Bool
+	City          pgtype.Text
+	State         pgtype.Text
+	Country       pgtype.Text
+	Make          pgtype.Text
+	Model         pgtype.Text
+	LensModel     pgtype.Text
+	LibraryID     pgtype.UUID
+	DeviceID      pgtype.Text
+	TakenAfter    pgtype.Timestamptz
+	TakenBefore   pgtype.Timestamptz
+	IsEncoded     pgtype.Bool
+	IsMotion      pgtype.Bool
+	IsOffline     pgtype.Bool
+	IsExternal    pgtype.Bool
+	LabelMinScore float64
+	Labels        []string
 }
 
 func (q *Queries) CountSearchAssetsFilteredForPage(ctx context.Context, arg CountSearchAssetsFilteredForPageParams) (int64, error) {
@@ -673,13 +1120,58 @@ func (q *Queries) CountSearchAssetsFilteredForPage(ctx context.Context, arg Coun
 		arg.IsMotion,
 		arg.IsOffline,
 		arg.IsExternal,
+		arg.LabelMinScore,
+		arg.Labels,
 	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
-const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+const countSharedLinks = `-- name: CountSharedLinks :one
+SELECT COUNT(*) FROM shared_links sl
+WHERE sl."userId" = $1
+AND ($2::text IS NULL OR sl.type = $2::text)
+AND ($3::uuid IS NULL OR sl."albumId" = $3::uuid)
+AND ($4::boolean IS NULL
+    OR (sl."expiresAt" IS NOT NULL AND sl."expiresAt" <= now()) = $4::boolean)
+`
+
+type CountSharedLinksParams struct {
+	UserID   pgtype.UUID
+	LinkType pgtype.Text
+	AlbumID  pgtype.UUID
+	Expired  pgtype.Bool
+}
+
+func (q *Queries) CountSharedLinks(ctx context.Context, arg CountSharedLinksParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countSharedLinks,
+		arg.UserID,
+		arg.LinkType,
+		arg.AlbumID,
+		arg.Expired,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTrashedAssets = `-- name: CountTrashedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND status = 'trashed'
+AND visibility <> 'locked'
+`
+
+func (q *Queries) CountTrashedAssets(ctx context.Context, ownerid pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countTrashedAssets, ownerid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
 SELECT COUNT(*) FROM notifications
 WHERE "userId" = $1 AND "readAt" IS NULL AND "deletedAt" IS NULL
 `
@@ -691,6 +1183,25 @@ func (q *Queries) CountUnreadNotifications(ctx context.Context, userid pgtype.UU
 	return count, err
 }
 
+const countUserLargestAssets = `-- name: CountUserLargestAssets :one
+SELECT COUNT(*)
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND e."fileSizeInByte" IS NOT NULL
+`
+
+func (q *Queries) CountUserLargestAssets(ctx context.Context, ownerid pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserLargestAssets, ownerid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUserSessions = `-- name: CountUserSessions :one
 SELECT COUNT(*) FROM sessions
 WHERE "userId" = $1
@@ -852,26 +1363,29 @@ const createAsset = `-- name: CreateAsset :one
 INSERT INTO assets (
     "deviceAssetId", "ownerId", "deviceId", type, "originalPath",
     "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
-    checksum, "isFavorite", visibility, status
+    checksum, "isFavorite", visibility, status, "checksumAlgorithm", "isUndated"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14, 'sha1'),
+    COALESCE($15::boolean, false))
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type CreateAssetParams struct {
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	OriginalFileName string
-	Checksum         []byte
-	IsFavorite       bool
-	Visibility       AssetVisibilityEnum
-	Status           AssetsStatusEnum
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	OriginalFileName  string
+	Checksum          []byte
+	IsFavorite        bool
+	Visibility        AssetVisibilityEnum
+	Status            AssetsStatusEnum
+	ChecksumAlgorithm interface{}
+	IsUndated         pgtype.Bool
 }
 
 func (q *Queries) CreateAsset(ctx context.Context, arg CreateAssetParams) (Asset, error) {
@@ -889,6 +1403,8 @@ func (q *Queries) CreateAsset(ctx context.Context, arg CreateAssetParams) (Asset
 		arg.IsFavorite,
 		arg.Visibility,
 		arg.Status,
+		arg.ChecksumAlgorithm,
+		arg.IsUndated,
 	)
 	var i Asset
 	err := row.Scan(
@@ -920,10 +1436,42 @@ func (q *Queries) CreateAsset(ctx context.Context, arg CreateAssetParams) (Asset
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
+const createAssetDownscale = `-- name: CreateAssetDownscale :exec
+INSERT INTO asset_downscales ("assetId", "originalWidth", "originalHeight", width, height, "originalKept")
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateAssetDownscaleParams struct {
+	AssetId        pgtype.UUID
+	OriginalWidth  int32
+	OriginalHeight int32
+	Width          int32
+	Height         int32
+	OriginalKept   bool
+}
+
+// Records that an image was downscaled on upload.
+func (q *Queries) CreateAssetDownscale(ctx context.Context, arg CreateAssetDownscaleParams) error {
+	_, err := q.db.Exec(ctx, createAssetDownscale,
+		arg.AssetId,
+		arg.OriginalWidth,
+		arg.OriginalHeight,
+		arg.Width,
+		arg.Height,
+		arg.OriginalKept,
+	)
+	return err
+}
+
 const createAssetEdit = `-- name: CreateAssetEdit :one
 INSERT INTO asset_edits ("assetId", action, parameters, position)
 VALUES ($1, $2, $3, $4)
@@ -958,9 +1506,9 @@ func (q *Queries) CreateAssetEdit(ctx context.Context, arg CreateAssetEditParams
 }
 
 const createAssetFace = `-- name: CreateAssetFace :one
-INSERT INTO asset_faces ("assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2")
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt"
+INSERT INTO asset_faces ("assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", score)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score
 `
 
 type CreateAssetFaceParams struct {
@@ -972,6 +1520,7 @@ type CreateAssetFaceParams struct {
 	BoundingBoxY1 int32
 	BoundingBoxX2 int32
 	BoundingBoxY2 int32
+	Score         float32
 }
 
 func (q *Queries) CreateAssetFace(ctx context.Context, arg CreateAssetFaceParams) (AssetFace, error) {
@@ -984,6 +1533,7 @@ func (q *Queries) CreateAssetFace(ctx context.Context, arg CreateAssetFaceParams
 		arg.BoundingBoxY1,
 		arg.BoundingBoxX2,
 		arg.BoundingBoxY2,
+		arg.Score,
 	)
 	var i AssetFace
 	err := row.Scan(
@@ -998,25 +1548,36 @@ func (q *Queries) CreateAssetFace(ctx context.Context, arg CreateAssetFaceParams
 		&i.ID,
 		&i.SourceType,
 		&i.DeletedAt,
+		&i.Score,
 	)
 	return i, err
 }
 
 const createAssetFile = `-- name: CreateAssetFile :one
-INSERT INTO asset_files ("assetId", "type", "path")
-VALUES ($1, $2, $3)
-RETURNING id, "assetId", "createdAt", "updatedAt", type, path, "updateId"
+INSERT INTO asset_files ("assetId", "type", "path", width, height, "sizeInBytes")
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes"
 `
 
 type CreateAssetFileParams struct {
-	AssetId pgtype.UUID
-	Type    string
-	Path    string
+	AssetId     pgtype.UUID
+	Type        string
+	Path        string
+	Width       pgtype.Int4
+	Height      pgtype.Int4
+	SizeInBytes pgtype.Int8
 }
 
 // Asset Files queries
 func (q *Queries) CreateAssetFile(ctx context.Context, arg CreateAssetFileParams) (AssetFile, error) {
-	row := q.db.QueryRow(ctx, createAssetFile, arg.AssetId, arg.Type, arg.Path)
+	row := q.db.QueryRow(ctx, createAssetFile,
+		arg.AssetId,
+		arg.Type,
+		arg.Path,
+		arg.Width,
+		arg.Height,
+		arg.SizeInBytes,
+	)
 	var i AssetFile
 	err := row.Scan(
 		&i.ID,
@@ -1026,6 +1587,9 @@ func (q *Queries) CreateAssetFile(ctx context.Context, arg CreateAssetFileParams
 		&i.Type,
 		&i.Path,
 		&i.UpdateId,
+		&i.Width,
+		&i.Height,
+		&i.SizeInBytes,
 	)
 	return i, err
 }
@@ -1170,7 +1734,7 @@ INSERT INTO asset_faces (
     "imageWidth", "imageHeight"
 ) VALUES (
     gen_uuid_v7(), $1, $2, $3, $4, $5, $6, $7, $8
-) RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt"
+) RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score
 `
 
 type CreateFaceParams struct {
@@ -1209,6 +1773,7 @@ func (q *Queries) CreateFace(ctx context.Context, arg CreateFaceParams) (AssetFa
 		&i.ID,
 		&i.SourceType,
 		&i.DeletedAt,
+		&i.Score,
 	)
 	return i, err
 }
@@ -1231,6 +1796,29 @@ func (q *Queries) CreateFaceSearch(ctx context.Context, arg CreateFaceSearchPara
 	return i, err
 }
 
+const createIntegrityReportItem = `-- name: CreateIntegrityReportItem :exec
+INSERT INTO integrity_report (id, type, path, "assetId")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING
+`
+
+type CreateIntegrityReportItemParams struct {
+	ID      pgtype.UUID
+	Type    string
+	Path    string
+	AssetId pgtype.UUID
+}
+
+func (q *Queries) CreateIntegrityReportItem(ctx context.Context, arg CreateIntegrityReportItemParams) error {
+	_, err := q.db.Exec(ctx, createIntegrityReportItem,
+		arg.ID,
+		arg.Type,
+		arg.Path,
+		arg.AssetId,
+	)
+	return err
+}
+
 const createJobFailure = `-- name: CreateJobFailure :one
 INSERT INTO job_failures (queue, job_type, payload, error, max_retries, retried_count, failed_at, last_failed_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -1315,29 +1903,32 @@ const createLibraryAsset = `-- name: CreateLibraryAsset :one
 INSERT INTO assets (
     "deviceAssetId", "ownerId", "libraryId", "deviceId", type, "originalPath",
     "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
-    checksum, "isFavorite", visibility, status, "isExternal"
+    checksum, "isFavorite", visibility, status, "isExternal", "checksumAlgorithm", "isUndated"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, true)
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, true, $15, true)
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type CreateLibraryAssetParams struct {
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	LibraryId        pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	OriginalFileName string
-	Checksum         []byte
-	IsFavorite       bool
-	Visibility       AssetVisibilityEnum
-	Status           AssetsStatusEnum
-}
-
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	LibraryId         pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	OriginalFileName  string
+	Checksum          []byte
+	IsFavorite        bool
+	Visibility        AssetVisibilityEnum
+	Status            AssetsStatusEnum
+	ChecksumAlgorithm string
+}
+
+// Library files start undated: their modification time is when they were
+// copied or scanned, not when they were taken.
 func (q *Queries) CreateLibraryAsset(ctx context.Context, arg CreateLibraryAssetParams) (Asset, error) {
 	row := q.db.QueryRow(ctx, createLibraryAsset,
 		arg.DeviceAssetId,
@@ -1354,6 +1945,7 @@ func (q *Queries) CreateLibraryAsset(ctx context.Context, arg CreateLibraryAsset
 		arg.IsFavorite,
 		arg.Visibility,
 		arg.Status,
+		arg.ChecksumAlgorithm,
 	)
 	var i Asset
 	err := row.Scan(
@@ -1385,24 +1977,72 @@ func (q *Queries) CreateLibraryAsset(ctx context.Context, arg CreateLibraryAsset
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
+const createLibraryFolderAlbum = `-- name: CreateLibraryFolderAlbum :one
+WITH album AS (
+    INSERT INTO albums ("ownerId", "albumName")
+    VALUES ($1, $2)
+    RETURNING id
+)
+INSERT INTO library_folder_albums ("libraryId", "folderPath", "albumId")
+SELECT $3, $4, id FROM album
+ON CONFLICT ("libraryId", "folderPath") DO UPDATE
+SET "albumId" = EXCLUDED."albumId"
+RETURNING "albumId"
+`
+
+type CreateLibraryFolderAlbumParams struct {
+	OwnerId    pgtype.UUID
+	AlbumName  string
+	LibraryId  pgtype.UUID
+	FolderPath string
+}
+
+// Creates the album of a library folder, replacing a deleted one.
+func (q *Queries) CreateLibraryFolderAlbum(ctx context.Context, arg CreateLibraryFolderAlbumParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, createLibraryFolderAlbum,
+		arg.OwnerId,
+		arg.AlbumName,
+		arg.LibraryId,
+		arg.FolderPath,
+	)
+	var albumId pgtype.UUID
+	err := row.Scan(&albumId)
+	return albumId, err
+}
+
 const createMemory = `-- name: CreateMemory :one
-INSERT INTO memories ("ownerId", type, data)
-VALUES ($1, $2, $3)
+INSERT INTO memories ("ownerId", type, data, "memoryAt", "showAt", "hideAt")
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, "createdAt", "updatedAt", "deletedAt", "ownerId", type, data, "isSaved", "memoryAt", "seenAt", "showAt", "hideAt", "updateId"
 `
 
 type CreateMemoryParams struct {
-	OwnerId pgtype.UUID
-	Type    string
-	Data    []byte
+	OwnerId  pgtype.UUID
+	Type     string
+	Data     []byte
+	MemoryAt pgtype.Timestamptz
+	ShowAt   pgtype.Timestamptz
+	HideAt   pgtype.Timestamptz
 }
 
 func (q *Queries) CreateMemory(ctx context.Context, arg CreateMemoryParams) (Memory, error) {
-	row := q.db.QueryRow(ctx, createMemory, arg.OwnerId, arg.Type, arg.Data)
+	row := q.db.QueryRow(ctx, createMemory,
+		arg.OwnerId,
+		arg.Type,
+		arg.Data,
+		arg.MemoryAt,
+		arg.ShowAt,
+		arg.HideAt,
+	)
 	var i Memory
 	err := row.Scan(
 		&i.ID,
@@ -1469,15 +2109,15 @@ INSERT INTO exif (
     "assetId", make, model, "exifImageWidth", "exifImageHeight", 
     "fileSizeInByte", orientation, "dateTimeOriginal", "modifyDate",
     "lensModel", "fNumber", "focalLength", iso, latitude, longitude,
-    city, state, country, description
+    city, state, country, description, "timeZone"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 ON CONFLICT ("assetId") DO UPDATE SET
     make = EXCLUDED.make,
     model = EXCLUDED.model,
     "exifImageWidth" = EXCLUDED."exifImageWidth",
     "exifImageHeight" = EXCLUDED."exifImageHeight",
-    "fileSizeInByte" = EXCLUDED."fileSizeInByte",
+    "fileSizeInByte" = COALESCE(EXCLUDED."fileSizeInByte", exif."fileSizeInByte"),
     orientation = EXCLUDED.orientation,
     "dateTimeOriginal" = EXCLUDED."dateTimeOriginal",
     "modifyDate" = EXCLUDED."modifyDate",
@@ -1491,6 +2131,7 @@ ON CONFLICT ("assetId") DO UPDATE SET
     state = EXCLUDED.state,
     country = EXCLUDED.country,
     description = EXCLUDED.description,
+    "timeZone" = EXCLUDED."timeZone",
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 RETURNING "assetId", make, model, "exifImageWidth", "exifImageHeight", "fileSizeInByte", orientation, "dateTimeOriginal", "modifyDate", "lensModel", "fNumber", "focalLength", iso, latitude, longitude, city, state, country, description, fps, "exposureTime", "livePhotoCID", "timeZone", "projectionType", "profileDescription", colorspace, "bitsPerSample", "autoStackId", rating, "updatedAt", "updateId"
@@ -1516,6 +2157,7 @@ type CreateOrUpdateExifParams struct {
 	State            pgtype.Text
 	Country          pgtype.Text
 	Description      string
+	TimeZone         pgtype.Text
 }
 
 func (q *Queries) CreateOrUpdateExif(ctx context.Context, arg CreateOrUpdateExifParams) (Exif, error) {
@@ -1539,6 +2181,7 @@ func (q *Queries) CreateOrUpdateExif(ctx context.Context, arg CreateOrUpdateExif
 		arg.State,
 		arg.Country,
 		arg.Description,
+		arg.TimeZone,
 	)
 	var i Exif
 	err := row.Scan(
@@ -1578,9 +2221,11 @@ func (q *Queries) CreateOrUpdateExif(ctx context.Context, arg CreateOrUpdateExif
 }
 
 const createPartnership = `-- name: CreatePartnership :one
-INSERT INTO partners ("sharedById", "sharedWithId")
-VALUES ($1, $2)
-RETURNING "sharedById", "sharedWithId", "createdAt", "updatedAt", "inTimeline", "updateId"
+INSERT INTO partners ("sharedById", "sharedWithId", status)
+VALUES ($1, $2, 'pending')
+ON CONFLICT ("sharedById", "sharedWithId") DO UPDATE
+SET "updatedAt" = now()
+RETURNING "sharedById", "sharedWithId", "createdAt", "updatedAt", "inTimeline", "updateId", status, "acceptedAt"
 `
 
 type CreatePartnershipParams struct {
@@ -1588,6 +2233,8 @@ type CreatePartnershipParams struct {
 	SharedWithId pgtype.UUID
 }
 
+// Inviting an existing partner again keeps the partnership's status, so an
+// accepted partnership stays accepted and a pending invite stays pending.
 func (q *Queries) CreatePartnership(ctx context.Context, arg CreatePartnershipParams) (Partner, error) {
 	row := q.db.QueryRow(ctx, createPartnership, arg.SharedById, arg.SharedWithId)
 	var i Partner
@@ -1598,10 +2245,32 @@ func (q *Queries) CreatePartnership(ctx context.Context, arg CreatePartnershipPa
 		&i.UpdatedAt,
 		&i.InTimeline,
 		&i.UpdateId,
+		&i.Status,
+		&i.AcceptedAt,
 	)
 	return i, err
 }
 
+const createPasswordSetupToken = `-- name: CreatePasswordSetupToken :exec
+INSERT INTO password_setup_tokens ("userId", "tokenHash", "expiresAt")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "tokenHash" = EXCLUDED."tokenHash",
+    "expiresAt" = EXCLUDED."expiresAt",
+    "createdAt" = now()
+`
+
+type CreatePasswordSetupTokenParams struct {
+	UserId    pgtype.UUID
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreatePasswordSetupToken(ctx context.Context, arg CreatePasswordSetupTokenParams) error {
+	_, err := q.db.Exec(ctx, createPasswordSetupToken, arg.UserId, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
+
 const createPerson = `-- name: CreatePerson :one
 INSERT INTO person ("ownerId", name, "birthDate", "thumbnailPath", "faceAssetId", "isHidden")
 VALUES ($1, $2, $3, $4, $5, $6)
@@ -1801,19 +2470,25 @@ func (q *Queries) CreateStack(ctx context.Context, arg CreateStackParams) (Asset
 }
 
 const createTag = `-- name: CreateTag :one
-INSERT INTO tags ("userId", value, color)
-VALUES ($1, $2, $3)
+INSERT INTO tags ("userId", value, color, "parentId")
+VALUES ($1, $2, $3, $4)
 RETURNING id, "userId", value, "createdAt", "updatedAt", color, "parentId", "updateId"
 `
 
 type CreateTagParams struct {
-	UserId pgtype.UUID
-	Value  string
-	Color  pgtype.Text
+	UserId   pgtype.UUID
+	Value    string
+	Color    pgtype.Text
+	ParentId pgtype.UUID
 }
 
 func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
-	row := q.db.QueryRow(ctx, createTag, arg.UserId, arg.Value, arg.Color)
+	row := q.db.QueryRow(ctx, createTag,
+		arg.UserId,
+		arg.Value,
+		arg.Color,
+		arg.ParentId,
+	)
 	var i Tag
 	err := row.Scan(
 		&i.ID,
@@ -1828,6 +2503,40 @@ func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, erro
 	return i, err
 }
 
+const createUploadSession = `-- name: CreateUploadSession :one
+INSERT INTO upload_sessions ("assetId", "ownerId", "totalSize", checksum, "checksumAlgorithm")
+VALUES ($1, $2, $3, $4, $5)
+RETURNING "assetId", "ownerId", "totalSize", checksum, "checksumAlgorithm", "createdAt"
+`
+
+type CreateUploadSessionParams struct {
+	AssetId           pgtype.UUID
+	OwnerId           pgtype.UUID
+	TotalSize         int64
+	Checksum          string
+	ChecksumAlgorithm string
+}
+
+func (q *Queries) CreateUploadSession(ctx context.Context, arg CreateUploadSessionParams) (UploadSession, error) {
+	row := q.db.QueryRow(ctx, createUploadSession,
+		arg.AssetId,
+		arg.OwnerId,
+		arg.TotalSize,
+		arg.Checksum,
+		arg.ChecksumAlgorithm,
+	)
+	var i UploadSession
+	err := row.Scan(
+		&i.AssetId,
+		&i.OwnerId,
+		&i.TotalSize,
+		&i.Checksum,
+		&i.ChecksumAlgorithm,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email, name, password, "isAdmin", "isOnboarded")
 VALUES ($1, $2, $3, $4, $5, $6)
@@ -1889,6 +2598,45 @@ func (q *Queries) CreateVersionHistory(ctx context.Context, version string) (Ver
 	return i, err
 }
 
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, events, secret, enabled)
+VALUES ($1, $2, $3, $4)
+RETURNING id, url, events, secret, enabled, "createdAt", "updatedAt", "lastDeliveryAt", "lastSuccessAt", "lastStatusCode", "lastError", "failureCount"
+`
+
+type CreateWebhookSubscriptionParams struct {
+	Url     string
+	Events  []string
+	Secret  string
+	Enabled bool
+}
+
+// Webhook subscription queries
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, createWebhookSubscription,
+		arg.Url,
+		arg.Events,
+		arg.Secret,
+		arg.Enabled,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Events,
+		&i.Secret,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastDeliveryAt,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.FailureCount,
+	)
+	return i, err
+}
+
 const createWorkflow = `-- name: CreateWorkflow :one
 
 INSERT INTO workflows (
@@ -2018,6 +2766,20 @@ func (q *Queries) DeleteAllAssetMetadata(ctx context.Context, assetid pgtype.UUI
 	return err
 }
 
+const deleteAllExpiredIdempotencyKeys = `-- name: DeleteAllExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE "expiresAt" <= now()
+`
+
+// Deletes the expired idempotency keys of every user.
+func (q *Queries) DeleteAllExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAllExpiredIdempotencyKeys)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteApiKey = `-- name: DeleteApiKey :exec
 DELETE FROM api_keys
 WHERE id = $1 AND "userId" = $2
@@ -2033,6 +2795,16 @@ func (q *Queries) DeleteApiKey(ctx context.Context, arg DeleteApiKeyParams) erro
 	return err
 }
 
+const deleteAssetDownscale = `-- name: DeleteAssetDownscale :exec
+DELETE FROM asset_downscales
+WHERE "assetId" = $1
+`
+
+func (q *Queries) DeleteAssetDownscale(ctx context.Context, assetid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteAssetDownscale, assetid)
+	return err
+}
+
 const deleteAssetEdits = `-- name: DeleteAssetEdits :exec
 DELETE FROM asset_edits
 WHERE "assetId" = $1
@@ -2064,6 +2836,23 @@ func (q *Queries) DeleteAssetFacesByAsset(ctx context.Context, assetid pgtype.UU
 	return err
 }
 
+const deleteAssetLabelsExcept = `-- name: DeleteAssetLabelsExcept :exec
+DELETE FROM asset_labels
+WHERE "assetId" = $1
+AND label <> ALL($2::text[])
+`
+
+type DeleteAssetLabelsExceptParams struct {
+	AssetID pgtype.UUID
+	Keep    []string
+}
+
+// Drops labels of an asset that the latest detection no longer reported.
+func (q *Queries) DeleteAssetLabelsExcept(ctx context.Context, arg DeleteAssetLabelsExceptParams) error {
+	_, err := q.db.Exec(ctx, deleteAssetLabelsExcept, arg.AssetID, arg.Keep)
+	return err
+}
+
 const deleteAssetFile = `-- name: DeleteAssetFile :exec
 DELETE FROM asset_files
 WHERE "assetId" = $1 AND "type" = $2
@@ -2143,6 +2932,44 @@ func (q *Queries) DeleteExif(ctx context.Context, assetid pgtype.UUID) error {
 	return err
 }
 
+const deleteExpiredIdempotencyKeys = `-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE "userId" = $1 AND "expiresAt" <= now()
+`
+
+// Idempotency key queries
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, userid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteExpiredIdempotencyKeys, userid)
+	return err
+}
+
+const deleteExpiredOfflineAssets = `-- name: DeleteExpiredOfflineAssets :execrows
+UPDATE assets
+SET status = 'deleted',
+    "deletedAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "libraryId" = $1
+AND "isOffline"
+AND "offlineAt" < $2::timestamptz
+AND "deletedAt" IS NULL
+`
+
+type DeleteExpiredOfflineAssetsParams struct {
+	LibraryId     pgtype.UUID
+	OfflineBefore pgtype.Timestamptz
+}
+
+// Deletes the library's assets that have been offline since before
+// offline_before.
+func (q *Queries) DeleteExpiredOfflineAssets(ctx context.Context, arg DeleteExpiredOfflineAssetsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredOfflineAssets, arg.LibraryId, arg.OfflineBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteExpiredRefreshTokens = `-- name: DeleteExpiredRefreshTokens :exec
 DELETE FROM sessions
 WHERE "expiresAt" IS NOT NULL AND "expiresAt" <= now()
@@ -2163,6 +2990,25 @@ func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
 	return err
 }
 
+const deleteExpiredSharedLinks = `-- name: DeleteExpiredSharedLinks :execrows
+DELETE FROM shared_links
+WHERE "userId" = $1
+AND "expiresAt" <= $2
+`
+
+type DeleteExpiredSharedLinksParams struct {
+	UserID        pgtype.UUID
+	ExpiredBefore pgtype.Timestamptz
+}
+
+func (q *Queries) DeleteExpiredSharedLinks(ctx context.Context, arg DeleteExpiredSharedLinksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredSharedLinks, arg.UserID, arg.ExpiredBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteFace = `-- name: DeleteFace :exec
 UPDATE asset_faces
 SET "deletedAt" = NOW()
@@ -2174,6 +3020,35 @@ func (q *Queries) DeleteFace(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteGeodataPlacesExcept = `-- name: DeleteGeodataPlacesExcept :execrows
+DELETE FROM geodata_places
+WHERE NOT (id = ANY($1::integer[]))
+`
+
+// Deletes the places that are not in the imported dataset.
+func (q *Queries) DeleteGeodataPlacesExcept(ctx context.Context, ids []int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteGeodataPlacesExcept, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteIdempotencyKey = `-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE "userId" = $1 AND key = $2
+`
+
+type DeleteIdempotencyKeyParams struct {
+	UserId pgtype.UUID
+	Key    string
+}
+
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error {
+	_, err := q.db.Exec(ctx, deleteIdempotencyKey, arg.UserId, arg.Key)
+	return err
+}
+
 const deleteJobFailure = `-- name: DeleteJobFailure :exec
 DELETE FROM job_failures
 WHERE id = $1
@@ -2196,6 +3071,18 @@ func (q *Queries) DeleteLibrary(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteLibraryFolderAlbumSettings = `-- name: DeleteLibraryFolderAlbumSettings :exec
+DELETE FROM library_folder_album_settings
+WHERE "libraryId" = $1
+`
+
+// Turns off folder albums for a library. The albums made so far stay, and
+// keep their folders should they be turned on again.
+func (q *Queries) DeleteLibraryFolderAlbumSettings(ctx context.Context, libraryid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteLibraryFolderAlbumSettings, libraryid)
+	return err
+}
+
 const deleteMemory = `-- name: DeleteMemory :exec
 UPDATE memories
 SET "deletedAt" = now(),
@@ -2220,7 +3107,7 @@ func (q *Queries) DeleteNotification(ctx context.Context, id pgtype.UUID) error
 	return err
 }
 
-const deletePartnership = `-- name: DeletePartnership :exec
+const deletePartnership = `-- name: DeletePartnership :execrows
 DELETE FROM partners
 WHERE ("sharedById" = $1 AND "sharedWithId" = $2) OR ("sharedById" = $2 AND "sharedWithId" = $1)
 `
@@ -2230,8 +3117,21 @@ type DeletePartnershipParams struct {
 	SharedWithId pgtype.UUID
 }
 
-func (q *Queries) DeletePartnership(ctx context.Context, arg DeletePartnershipParams) error {
-	_, err := q.db.Exec(ctx, deletePartnership, arg.SharedById, arg.SharedWithId)
+func (q *Queries) DeletePartnership(ctx context.Context, arg DeletePartnershipParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePartnership, arg.SharedById, arg.SharedWithId)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePasswordSetupToken = `-- name: DeletePasswordSetupToken :exec
+DELETE FROM password_setup_tokens
+WHERE "userId" = $1
+`
+
+func (q *Queries) DeletePasswordSetupToken(ctx context.Context, userid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deletePasswordSetupToken, userid)
 	return err
 }
 
@@ -2385,6 +3285,27 @@ func (q *Queries) DeleteStacksByIds(ctx context.Context, dollar_1 []pgtype.UUID)
 	return err
 }
 
+const deleteStacksByPrimaryAssets = `-- name: DeleteStacksByPrimaryAssets :exec
+DELETE FROM asset_stack
+WHERE "primaryAssetId" = ANY($1::uuid[])
+`
+
+// Stacks pin their primary asset, so they go before it.
+func (q *Queries) DeleteStacksByPrimaryAssets(ctx context.Context, assetIds []pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteStacksByPrimaryAssets, assetIds)
+	return err
+}
+
+const deleteSystemMetadata = `-- name: DeleteSystemMetadata :exec
+DELETE FROM system_metadata
+WHERE key = $1
+`
+
+func (q *Queries) DeleteSystemMetadata(ctx context.Context, key string) error {
+	_, err := q.db.Exec(ctx, deleteSystemMetadata, key)
+	return err
+}
+
 const deleteTag = `-- name: DeleteTag :exec
 DELETE FROM tags
 WHERE id = $1
@@ -2395,6 +3316,26 @@ func (q *Queries) DeleteTag(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteUploadSession = `-- name: DeleteUploadSession :exec
+DELETE FROM upload_sessions
+WHERE "assetId" = $1
+`
+
+func (q *Queries) DeleteUploadSession(ctx context.Context, assetid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUploadSession, assetid)
+	return err
+}
+
+const deleteUploadSessionChunks = `-- name: DeleteUploadSessionChunks :exec
+DELETE FROM upload_session_chunks
+WHERE "assetId" = $1
+`
+
+func (q *Queries) DeleteUploadSessionChunks(ctx context.Context, assetid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUploadSessionChunks, assetid)
+	return err
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 UPDATE users
 SET "deletedAt" = now(),
@@ -2407,6 +3348,49 @@ func (q *Queries) DeleteUser(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const deleteUserAlbumAudit = `-- name: DeleteUserAlbumAudit :exec
+DELETE FROM albums_audit
+WHERE "userId" = $1
+`
+
+func (q *Queries) DeleteUserAlbumAudit(ctx context.Context, userid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserAlbumAudit, userid)
+	return err
+}
+
+const deleteUserAssetAudit = `-- name: DeleteUserAssetAudit :exec
+DELETE FROM assets_audit
+WHERE "ownerId" = $1
+`
+
+// The user's own audit rows are only read by the user's clients. Partner,
+// album member and user audit rows stay so other users' clients learn about
+// the removal.
+func (q *Queries) DeleteUserAssetAudit(ctx context.Context, ownerid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserAssetAudit, ownerid)
+	return err
+}
+
+const deleteUserDeletionSchedule = `-- name: DeleteUserDeletionSchedule :exec
+DELETE FROM user_deletion_schedules
+WHERE "userId" = $1
+`
+
+func (q *Queries) DeleteUserDeletionSchedule(ctx context.Context, userid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserDeletionSchedule, userid)
+	return err
+}
+
+const deleteUserLegacyAudit = `-- name: DeleteUserLegacyAudit :exec
+DELETE FROM audit
+WHERE "ownerId" = $1
+`
+
+func (q *Queries) DeleteUserLegacyAudit(ctx context.Context, ownerid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserLegacyAudit, ownerid)
+	return err
+}
+
 const deleteUserLicenseData = `-- name: DeleteUserLicenseData :exec
 DELETE FROM user_metadata
 WHERE "userId" = $1 AND key = 'license'
@@ -2437,6 +3421,30 @@ func (q *Queries) DeleteUserSessions(ctx context.Context, userid pgtype.UUID) er
 	return err
 }
 
+const deleteUserStacks = `-- name: DeleteUserStacks :exec
+DELETE FROM asset_stack
+WHERE "ownerId" = $1
+`
+
+// Stacks pin their primary asset, so they go before the user's assets.
+func (q *Queries) DeleteUserStacks(ctx context.Context, ownerid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserStacks, ownerid)
+	return err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebhookSubscription, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteWorkflow = `-- name: DeleteWorkflow :exec
 DELETE FROM workflows
 WHERE id = $1
@@ -2447,6 +3455,59 @@ func (q *Queries) DeleteWorkflow(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const detachAssetFacesFromPeople = `-- name: DetachAssetFacesFromPeople :exec
+WITH faces AS (
+    UPDATE asset_faces SET "personId" = NULL
+    WHERE "assetId" = $1
+    RETURNING id
+)
+UPDATE person
+SET "faceAssetId" = NULL, "updatedAt" = now()
+WHERE "faceAssetId" IN (SELECT id FROM faces)
+`
+
+// People belong to the owner of an asset, so the faces of a reassigned asset
+// are left for the new owner's people. People featuring one of them get a
+// new thumbnail.
+func (q *Queries) DetachAssetFacesFromPeople(ctx context.Context, assetid pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, detachAssetFacesFromPeople, assetid)
+	return err
+}
+
+const detachLibraryAssets = `-- name: DetachLibraryAssets :execrows
+UPDATE assets
+SET "libraryId" = NULL,
+    "deviceAssetId" = id::text,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id IN (
+    SELECT a.id FROM assets a
+    WHERE a."libraryId" = $1
+    AND NOT EXISTS (
+        SELECT 1 FROM assets u
+        WHERE u."ownerId" = a."ownerId" AND u."libraryId" IS NULL AND u.checksum = a.checksum
+    )
+    ORDER BY a.id
+    LIMIT $2
+)
+`
+
+type DetachLibraryAssetsParams struct {
+	LibraryID pgtype.UUID
+	BatchSize int32
+}
+
+// Turns a batch of a deleted library's assets into uploads of its owner. The
+// files stay where they are and the assets stay external, so nothing deletes
+// them. Assets the owner already uploaded are left in the library.
+func (q *Queries) DetachLibraryAssets(ctx context.Context, arg DetachLibraryAssetsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, detachLibraryAssets, arg.LibraryID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getActivity = `-- name: GetActivity :one
 
 SELECT id, "createdAt", "updatedAt", "albumId", "userId", "assetId", comment, "isLiked", "updateId" FROM activity
@@ -2532,6 +3593,32 @@ func (q *Queries) GetActivityStatistics(ctx context.Context, arg GetActivityStat
 	return i, err
 }
 
+const getAdminUserIDs = `-- name: GetAdminUserIDs :many
+SELECT id FROM users
+WHERE "isAdmin" = true AND "deletedAt" IS NULL
+ORDER BY "createdAt"
+`
+
+func (q *Queries) GetAdminUserIDs(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, getAdminUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbum = `-- name: GetAlbum :one
 SELECT id, "ownerId", "albumName", "createdAt", "albumThumbnailAssetId", "updatedAt", description, "deletedAt", "isActivityEnabled", "order", "updateId" FROM albums
 WHERE id = $1 AND "deletedAt" IS NULL
@@ -2557,10 +3644,77 @@ func (q *Queries) GetAlbum(ctx context.Context, id pgtype.UUID) (Album, error) {
 	return i, err
 }
 
+const getAlbumAssetStatistics = `-- name: GetAlbumAssetStatistics :many
+SELECT s."ownerId", COALESCE(u.name, '') AS owner_name,
+    s.asset_count, s.images, s.videos, s.total_size, s.start_date, s.end_date
+FROM (
+    SELECT a."ownerId",
+        COUNT(*) FILTER (WHERE a.type = 'IMAGE') AS images,
+        COUNT(*) FILTER (WHERE a.type = 'VIDEO') AS videos,
+        COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS total_size,
+        MIN(a."localDateTime") FILTER (WHERE NOT a."isUndated") AS start_date,
+        MAX(a."localDateTime") FILTER (WHERE NOT a."isUndated") AS end_date,
+        COUNT(*) AS asset_count
+    FROM albums_assets_assets aaa
+    JOIN assets a ON a.id = aaa."assetsId"
+    LEFT JOIN exif e ON e."assetId" = a.id
+    WHERE aaa."albumsId" = $1
+    AND a.status = 'active'
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    GROUP BY GROUPING SETS ((), (a."ownerId"))
+) s
+LEFT JOIN users u ON u.id = s."ownerId"
+ORDER BY s."ownerId" IS NOT NULL, s.asset_count DESC, u.name
+`
+
+type GetAlbumAssetStatisticsRow struct {
+	OwnerId    pgtype.UUID
+	OwnerName  string
+	AssetCount int64
+	Images     int64
+	Videos     int64
+	TotalSize  int64
+	StartDate  pgtype.Timestamptz
+	EndDate    pgtype.Timestamptz
+}
+
+// Statistics of an album's assets in one pass: a row per owner of assets in
+// the album and a total row with a NULL "ownerId", which comes first.
+func (q *Queries) GetAlbumAssetStatistics(ctx context.Context, albumsid pgtype.UUID) ([]GetAlbumAssetStatisticsRow, error) {
+	rows, err := q.db.Query(ctx, getAlbumAssetStatistics, albumsid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumAssetStatisticsRow
+	for rows.Next() {
+		var i GetAlbumAssetStatisticsRow
+		if err := rows.Scan(
+			&i.OwnerId,
+			&i.OwnerName,
+			&i.AssetCount,
+			&i.Images,
+			&i.Videos,
+			&i.TotalSize,
+			&i.StartDate,
+			&i.EndDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbumAssets = `-- name: GetAlbumAssets :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 JOIN albums_assets_assets aaa ON a.id = aaa."assetsId"
 WHERE aaa."albumsId" = $1 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY aaa."createdAt" DESC
 `
 
@@ -2602,6 +3756,11 @@ func (q *Queries) GetAlbumAssets(ctx context.Context, albumsid pgtype.UUID) ([]A
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -2613,51 +3772,83 @@ func (q *Queries) GetAlbumAssets(ctx context.Context, albumsid pgtype.UUID) ([]A
 	return items, nil
 }
 
+const getAlbumIdsByAssetIds = `-- name: GetAlbumIdsByAssetIds :many
+SELECT DISTINCT "albumsId" FROM albums_assets_assets
+WHERE "assetsId" = ANY($1::uuid[])
+`
+
+// Albums containing any of the assets, whoever can see them.
+func (q *Queries) GetAlbumIdsByAssetIds(ctx context.Context, assetIds []pgtype.UUID) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, getAlbumIdsByAssetIds, assetIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var albumsId pgtype.UUID
+		if err := rows.Scan(&albumsId); err != nil {
+			return nil, err
+		}
+		items = append(items, albumsId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAlbumMapMarkers = `-- name: GetAlbumMapMarkers :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, e.latitude AS exif_latitude, e.longitude AS exif_longitude, e.city, e.state, e.country FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash, e.latitude AS exif_latitude, e.longitude AS exif_longitude, e.city, e.state, e.country FROM assets a
 JOIN albums_assets_assets aaa ON a.id = aaa."assetsId"
 JOIN exif e ON a.id = e."assetId"
 WHERE aaa."albumsId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.latitude IS NOT NULL
 AND e.longitude IS NOT NULL
 ORDER BY a."localDateTime" DESC
 `
 
 type GetAlbumMapMarkersRow struct {
-	ID               pgtype.UUID
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	IsFavorite       bool
-	Duration         pgtype.Text
-	EncodedVideoPath pgtype.Text
-	Checksum         []byte
-	LivePhotoVideoId pgtype.UUID
-	UpdatedAt        pgtype.Timestamptz
-	CreatedAt        pgtype.Timestamptz
-	OriginalFileName string
-	SidecarPath      pgtype.Text
-	Thumbhash        []byte
-	IsOffline        bool
-	LibraryId        pgtype.UUID
-	IsExternal       bool
-	DeletedAt        pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	StackId          pgtype.UUID
-	DuplicateId      pgtype.UUID
-	Status           AssetsStatusEnum
-	UpdateId         pgtype.UUID
-	Visibility       AssetVisibilityEnum
-	ExifLatitude     pgtype.Float8
-	ExifLongitude    pgtype.Float8
-	City             pgtype.Text
-	State            pgtype.Text
-	Country          pgtype.Text
+	ID                pgtype.UUID
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	IsFavorite        bool
+	Duration          pgtype.Text
+	EncodedVideoPath  pgtype.Text
+	Checksum          []byte
+	LivePhotoVideoId  pgtype.UUID
+	UpdatedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	OriginalFileName  string
+	SidecarPath       pgtype.Text
+	Thumbhash         []byte
+	IsOffline         bool
+	LibraryId         pgtype.UUID
+	IsExternal        bool
+	DeletedAt         pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	StackId           pgtype.UUID
+	DuplicateId       pgtype.UUID
+	Status            AssetsStatusEnum
+	UpdateId          pgtype.UUID
+	Visibility        AssetVisibilityEnum
+	ChecksumAlgorithm string
+	IsUndated         bool
+	OfflineAt         pgtype.Timestamptz
+	OriginalMimeType  pgtype.Text
+	Blurhash          pgtype.Text
+	ExifLatitude      pgtype.Float8
+	ExifLongitude     pgtype.Float8
+	City              pgtype.Text
+	State             pgtype.Text
+	Country           pgtype.Text
 }
 
 func (q *Queries) GetAlbumMapMarkers(ctx context.Context, albumsid pgtype.UUID) ([]GetAlbumMapMarkersRow, error) {
@@ -2698,6 +3889,11 @@ func (q *Queries) GetAlbumMapMarkers(ctx context.Context, albumsid pgtype.UUID)
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 			&i.ExifLatitude,
 			&i.ExifLongitude,
 			&i.City,
@@ -2786,56 +3982,47 @@ func (q *Queries) GetAlbumSharedUsers(ctx context.Context, albumsid pgtype.UUID)
 	return items, nil
 }
 
-const getAlbumStatistics = `-- name: GetAlbumStatistics :one
-SELECT 
-    COUNT(CASE WHEN "ownerId" = $1 THEN 1 END) as owned,
-    COUNT(CASE WHEN "ownerId" != $1 THEN 1 END) as shared,
-    COUNT(CASE WHEN "ownerId" = $1 AND NOT EXISTS(SELECT 1 FROM albums_shared_users_users WHERE "albumsId" = albums.id) THEN 1 END) as not_shared
-FROM albums
-WHERE ("ownerId" = $1 OR EXISTS(SELECT 1 FROM albums_shared_users_users WHERE "albumsId" = albums.id AND "usersId" = $1))
-AND "deletedAt" IS NULL
+const getAlbumSharedUsersByAlbumIds = `-- name: GetAlbumSharedUsersByAlbumIds :many
+SELECT asu."albumsId", u.id, u.email, u.name, u."profileImagePath",
+    u."avatarColor", u."profileChangedAt", u."createdAt", asu.role
+FROM albums_shared_users_users asu
+JOIN users u ON u.id = asu."usersId"
+WHERE asu."albumsId" = ANY($1::uuid[])
+ORDER BY asu."albumsId", u.name
 `
 
-type GetAlbumStatisticsRow struct {
-	Owned     int64
-	Shared    int64
-	NotShared int64
-}
-
-func (q *Queries) GetAlbumStatistics(ctx context.Context, ownerid pgtype.UUID) (GetAlbumStatisticsRow, error) {
-	row := q.db.QueryRow(ctx, getAlbumStatistics, ownerid)
-	var i GetAlbumStatisticsRow
-	err := row.Scan(&i.Owned, &i.Shared, &i.NotShared)
-	return i, err
+type GetAlbumSharedUsersByAlbumIdsRow struct {
+	AlbumsId         pgtype.UUID
+	ID               pgtype.UUID
+	Email            string
+	Name             string
+	ProfileImagePath string
+	AvatarColor      pgtype.Text
+	ProfileChangedAt pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+	Role             string
 }
 
-const getAlbums = `-- name: GetAlbums :many
-SELECT id, "ownerId", "albumName", "createdAt", "albumThumbnailAssetId", "updatedAt", description, "deletedAt", "isActivityEnabled", "order", "updateId" FROM albums
-WHERE "deletedAt" IS NULL
-ORDER BY "createdAt" DESC
-`
-
-func (q *Queries) GetAlbums(ctx context.Context) ([]Album, error) {
-	rows, err := q.db.Query(ctx, getAlbums)
+// The members of several albums at once, for album lists.
+func (q *Queries) GetAlbumSharedUsersByAlbumIds(ctx context.Context, albumIds []pgtype.UUID) ([]GetAlbumSharedUsersByAlbumIdsRow, error) {
+	rows, err := q.db.Query(ctx, getAlbumSharedUsersByAlbumIds, albumIds)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Album
+	var items []GetAlbumSharedUsersByAlbumIdsRow
 	for rows.Next() {
-		var i Album
+		var i GetAlbumSharedUsersByAlbumIdsRow
 		if err := rows.Scan(
+			&i.AlbumsId,
 			&i.ID,
-			&i.OwnerId,
-			&i.AlbumName,
+			&i.Email,
+			&i.Name,
+			&i.ProfileImagePath,
+			&i.AvatarColor,
+			&i.ProfileChangedAt,
 			&i.CreatedAt,
-			&i.AlbumThumbnailAssetId,
-			&i.UpdatedAt,
-			&i.Description,
-			&i.DeletedAt,
-			&i.IsActivityEnabled,
-			&i.Order,
-			&i.UpdateId,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -2847,21 +4034,236 @@ func (q *Queries) GetAlbums(ctx context.Context) ([]Album, error) {
 	return items, nil
 }
 
-const getAlbumsByAssetIdForUser = `-- name: GetAlbumsByAssetIdForUser :many
-SELECT a.id, a."ownerId", a."albumName", a."createdAt", a."albumThumbnailAssetId", a."updatedAt", a.description, a."deletedAt", a."isActivityEnabled", a."order", a."updateId" FROM albums a
-JOIN albums_assets_assets aa ON aa."albumsId" = a.id
-WHERE a."deletedAt" IS NULL
-  AND aa."assetsId" = $1
-  AND (
-    a."ownerId" = $2
-    OR EXISTS (
-      SELECT 1 FROM albums_shared_users_users su
-      WHERE su."albumsId" = a.id AND su."usersId" = $2
-    )
-  )
-ORDER BY a."createdAt" DESC
-`
-
+const getAlbumStatistics = `-- name: GetAlbumStatistics :one
+SELECT 
+    COUNT(CASE WHEN "ownerId" = $1 THEN 1 END) as owned,
+    COUNT(CASE WHEN "ownerId" != $1 THEN 1 END) as shared,
+    COUNT(CASE WHEN "ownerId" = $1 AND NOT EXISTS(SELECT 1 FROM albums_shared_users_users WHERE "albumsId" = albums.id) THEN 1 END) as not_shared
+FROM albums
+WHERE ("ownerId" = $1 OR EXISTS(SELECT 1 FROM albums_shared_users_users WHERE "albumsId" = albums.id AND "usersId" = $1))
+AND "deletedAt" IS NULL
+`
+
+type GetAlbumStatisticsRow struct {
+	Owned     int64
+	Shared    int64
+	NotShared int64
+}
+
+func (q *Queries) GetAlbumStatistics(ctx context.Context, ownerid pgtype.UUID) (GetAlbumStatisticsRow, error) {
+	row := q.db.QueryRow(ctx, getAlbumStatistics, ownerid)
+	var i GetAlbumStatisticsRow
+	err := row.Scan(&i.Owned, &i.Shared, &i.NotShared)
+	return i, err
+}
+
+const getAlbumSummariesForUser = `-- name: GetAlbumSummariesForUser :many
+SELECT a.id, a."ownerId", a."albumName", a."createdAt", a."albumThumbnailAssetId", a."updatedAt", a.description, a."deletedAt", a."isActivityEnabled", a."order", a."updateId",
+    u.email AS owner_email, u.name AS owner_name,
+    u."profileImagePath" AS owner_profile_image_path, u."avatarColor" AS owner_avatar_color,
+    u."profileChangedAt" AS owner_profile_changed_at, u."createdAt" AS owner_created_at,
+    s.asset_count, s.start_date, s.end_date, s.last_modified_asset_at,
+    COALESCE(a."albumThumbnailAssetId", cover.id) AS cover_asset_id,
+    EXISTS (SELECT 1 FROM albums_shared_users_users su WHERE su."albumsId" = a.id) AS shared,
+    EXISTS (SELECT 1 FROM shared_links sl WHERE sl."albumId" = a.id) AS has_shared_link
+FROM albums a
+JOIN users u ON u.id = a."ownerId"
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS asset_count,
+        MIN(x."localDateTime") FILTER (WHERE NOT x."isUndated") AS start_date,
+        MAX(x."localDateTime") FILTER (WHERE NOT x."isUndated") AS end_date,
+        MAX(x."updatedAt") AS last_modified_asset_at
+    FROM albums_assets_assets aaa
+    JOIN assets x ON x.id = aaa."assetsId"
+    WHERE aaa."albumsId" = a.id
+    AND x.status = 'active'
+    AND x."deletedAt" IS NULL
+    AND x.visibility <> 'locked'
+) s
+LEFT JOIN LATERAL (
+    SELECT x.id FROM albums_assets_assets aaa
+    JOIN assets x ON x.id = aaa."assetsId"
+    WHERE a."albumThumbnailAssetId" IS NULL
+    AND aaa."albumsId" = a.id
+    AND x.status = 'active'
+    AND x."deletedAt" IS NULL
+    AND x.visibility <> 'locked'
+    ORDER BY aaa."createdAt" DESC
+    LIMIT 1
+) cover ON true
+WHERE a."deletedAt" IS NULL
+  AND (
+    a."ownerId" = $1
+    OR EXISTS (
+      SELECT 1 FROM albums_shared_users_users su
+      WHERE su."albumsId" = a.id AND su."usersId" = $1
+    )
+  )
+  AND (
+    $2::boolean IS NULL
+    OR (a."ownerId" = $1) = $2::boolean
+  )
+  AND (
+    $3::boolean IS NULL
+    OR (
+      EXISTS (SELECT 1 FROM albums_shared_users_users su2 WHERE su2."albumsId" = a.id)
+      OR EXISTS (SELECT 1 FROM shared_links sl WHERE sl."albumId" = a.id)
+    ) = $3::boolean
+  )
+  AND (
+    $4::uuid IS NULL
+    OR EXISTS (
+      SELECT 1 FROM albums_assets_assets aa
+      WHERE aa."albumsId" = a.id AND aa."assetsId" = $4::uuid
+    )
+  )
+ORDER BY
+    CASE WHEN $5::text = 'albumName' AND NOT $6::boolean THEN lower(a."albumName") END ASC,
+    CASE WHEN $5::text = 'albumName' AND $6::boolean THEN lower(a."albumName") END DESC,
+    CASE WHEN $5::text = 'updatedAt' AND NOT $6::boolean THEN a."updatedAt" END ASC,
+    CASE WHEN $5::text = 'updatedAt' AND $6::boolean THEN a."updatedAt" END DESC,
+    CASE WHEN NOT $6::boolean THEN a."createdAt" END ASC,
+    a."createdAt" DESC,
+    a.id
+`
+
+type GetAlbumSummariesForUserParams struct {
+	UserID     pgtype.UUID
+	IsOwned    pgtype.Bool
+	IsShared   pgtype.Bool
+	AssetID    pgtype.UUID
+	SortBy     string
+	Descending bool
+}
+
+type GetAlbumSummariesForUserRow struct {
+	Album                 Album
+	OwnerEmail            string
+	OwnerName             string
+	OwnerProfileImagePath string
+	OwnerAvatarColor      pgtype.Text
+	OwnerProfileChangedAt pgtype.Timestamptz
+	OwnerCreatedAt        pgtype.Timestamptz
+	AssetCount            int64
+	StartDate             pgtype.Timestamptz
+	EndDate               pgtype.Timestamptz
+	LastModifiedAssetAt   pgtype.Timestamptz
+	CoverAssetID          pgtype.UUID
+	Shared                bool
+	HasSharedLink         bool
+}
+
+// The albums GetAlbumsForUser lists, or those holding asset_id, with what
+// an album list shows of each: its owner, how many assets it has, their
+// date range, when they last changed, its cover and whether it is shared,
+// so the list takes one query rather than a few per album. The cover is
+// the chosen thumbnail, else the asset added last. sort_by is "createdAt",
+// "updatedAt" or "albumName".
+func (q *Queries) GetAlbumSummariesForUser(ctx context.Context, arg GetAlbumSummariesForUserParams) ([]GetAlbumSummariesForUserRow, error) {
+	rows, err := q.db.Query(ctx, getAlbumSummariesForUser,
+		arg.UserID,
+		arg.IsOwned,
+		arg.IsShared,
+		arg.AssetID,
+		arg.SortBy,
+		arg.Descending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAlbumSummariesForUserRow
+	for rows.Next() {
+		var i GetAlbumSummariesForUserRow
+		if err := rows.Scan(
+			&i.Album.ID,
+			&i.Album.OwnerId,
+			&i.Album.AlbumName,
+			&i.Album.CreatedAt,
+			&i.Album.AlbumThumbnailAssetId,
+			&i.Album.UpdatedAt,
+			&i.Album.Description,
+			&i.Album.DeletedAt,
+			&i.Album.IsActivityEnabled,
+			&i.Album.Order,
+			&i.Album.UpdateId,
+			&i.OwnerEmail,
+			&i.OwnerName,
+			&i.OwnerProfileImagePath,
+			&i.OwnerAvatarColor,
+			&i.OwnerProfileChangedAt,
+			&i.OwnerCreatedAt,
+			&i.AssetCount,
+			&i.StartDate,
+			&i.EndDate,
+			&i.LastModifiedAssetAt,
+			&i.CoverAssetID,
+			&i.Shared,
+			&i.HasSharedLink,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAlbums = `-- name: GetAlbums :many
+SELECT id, "ownerId", "albumName", "createdAt", "albumThumbnailAssetId", "updatedAt", description, "deletedAt", "isActivityEnabled", "order", "updateId" FROM albums
+WHERE "deletedAt" IS NULL
+ORDER BY "createdAt" DESC
+`
+
+func (q *Queries) GetAlbums(ctx context.Context) ([]Album, error) {
+	rows, err := q.db.Query(ctx, getAlbums)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Album
+	for rows.Next() {
+		var i Album
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerId,
+			&i.AlbumName,
+			&i.CreatedAt,
+			&i.AlbumThumbnailAssetId,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.DeletedAt,
+			&i.IsActivityEnabled,
+			&i.Order,
+			&i.UpdateId,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAlbumsByAssetIdForUser = `-- name: GetAlbumsByAssetIdForUser :many
+SELECT a.id, a."ownerId", a."albumName", a."createdAt", a."albumThumbnailAssetId", a."updatedAt", a.description, a."deletedAt", a."isActivityEnabled", a."order", a."updateId" FROM albums a
+JOIN albums_assets_assets aa ON aa."albumsId" = a.id
+WHERE a."deletedAt" IS NULL
+  AND aa."assetsId" = $1
+  AND (
+    a."ownerId" = $2
+    OR EXISTS (
+      SELECT 1 FROM albums_shared_users_users su
+      WHERE su."albumsId" = a.id AND su."usersId" = $2
+    )
+  )
+ORDER BY a."createdAt" DESC
+`
+
 type GetAlbumsByAssetIdForUserParams struct {
 	AssetID pgtype.UUID
 	UserID  pgtype.UUID
@@ -3180,7 +4582,7 @@ func (q *Queries) GetApiKeysByUser(ctx context.Context, userid pgtype.UUID) ([]A
 }
 
 const getArchivedAssets = `-- name: GetArchivedAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND visibility = 'archive'
@@ -3232,6 +4634,11 @@ func (q *Queries) GetArchivedAssets(ctx context.Context, arg GetArchivedAssetsPa
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -3244,7 +4651,7 @@ func (q *Queries) GetArchivedAssets(ctx context.Context, arg GetArchivedAssetsPa
 }
 
 const getAsset = `-- name: GetAsset :one
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE id = $1 AND "deletedAt" IS NULL
 `
 
@@ -3281,12 +4688,17 @@ func (q *Queries) GetAsset(ctx context.Context, id pgtype.UUID) (Asset, error) {
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
 const getAssetByID = `-- name: GetAssetByID :one
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE id = $1 AND "deletedAt" IS NULL
 `
 
@@ -3322,12 +4734,17 @@ func (q *Queries) GetAssetByID(ctx context.Context, id pgtype.UUID) (Asset, erro
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
 const getAssetByIDAndUser = `-- name: GetAssetByIDAndUser :one
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE id = $1 AND "ownerId" = $2 AND "deletedAt" IS NULL
 `
 
@@ -3368,6 +4785,11 @@ func (q *Queries) GetAssetByIDAndUser(ctx context.Context, arg GetAssetByIDAndUs
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
@@ -3379,7 +4801,7 @@ const getAssetByPath = `-- name: GetAssetByPath :one
 
 
 
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "originalPath" = $1
 AND "deletedAt" IS NULL
 LIMIT 1
@@ -3434,6 +4856,11 @@ func (q *Queries) GetAssetByPath(ctx context.Context, originalpath string) (Asse
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
@@ -3518,7 +4945,7 @@ func (q *Queries) GetAssetExif(ctx context.Context, assetid pgtype.UUID) (Exif,
 }
 
 const getAssetFaces = `-- name: GetAssetFaces :many
-SELECT af."assetId", af."personId", af."imageWidth", af."imageHeight", af."boundingBoxX1", af."boundingBoxY1", af."boundingBoxX2", af."boundingBoxY2", af.id, af."sourceType", af."deletedAt", p.name as person_name FROM asset_faces af
+SELECT af."assetId", af."personId", af."imageWidth", af."imageHeight", af."boundingBoxX1", af."boundingBoxY1", af."boundingBoxX2", af."boundingBoxY2", af.id, af."sourceType", af."deletedAt", af.score, p.name as person_name FROM asset_faces af
 LEFT JOIN person p ON af."personId" = p.id
 WHERE af."assetId" = $1
 `
@@ -3535,6 +4962,7 @@ type GetAssetFacesRow struct {
 	ID            pgtype.UUID
 	SourceType    Sourcetype
 	DeletedAt     pgtype.Timestamptz
+	Score         float32
 	PersonName    pgtype.Text
 }
 
@@ -3559,6 +4987,7 @@ func (q *Queries) GetAssetFaces(ctx context.Context, assetid pgtype.UUID) ([]Get
 			&i.ID,
 			&i.SourceType,
 			&i.DeletedAt,
+			&i.Score,
 			&i.PersonName,
 		); err != nil {
 			return nil, err
@@ -3572,7 +5001,7 @@ func (q *Queries) GetAssetFaces(ctx context.Context, assetid pgtype.UUID) ([]Get
 }
 
 const getAssetFile = `-- name: GetAssetFile :one
-SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId" FROM asset_files
+SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes" FROM asset_files
 WHERE "assetId" = $1 AND "type" = $2
 LIMIT 1
 `
@@ -3593,12 +5022,15 @@ func (q *Queries) GetAssetFile(ctx context.Context, arg GetAssetFileParams) (Ass
 		&i.Type,
 		&i.Path,
 		&i.UpdateId,
+		&i.Width,
+		&i.Height,
+		&i.SizeInBytes,
 	)
 	return i, err
 }
 
 const getAssetFiles = `-- name: GetAssetFiles :many
-SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId" FROM asset_files
+SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes" FROM asset_files
 WHERE "assetId" = $1
 ORDER BY "createdAt" ASC
 `
@@ -3620,6 +5052,46 @@ func (q *Queries) GetAssetFiles(ctx context.Context, assetid pgtype.UUID) ([]Ass
 			&i.Type,
 			&i.Path,
 			&i.UpdateId,
+			&i.Width,
+			&i.Height,
+			&i.SizeInBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAssetFilesByAssetIDs = `-- name: GetAssetFilesByAssetIDs :many
+SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes" FROM asset_files
+WHERE "assetId" = ANY($1::uuid[])
+ORDER BY "assetId", "createdAt" ASC
+`
+
+func (q *Queries) GetAssetFilesByAssetIDs(ctx context.Context, assetIds []pgtype.UUID) ([]AssetFile, error) {
+	rows, err := q.db.Query(ctx, getAssetFilesByAssetIDs, assetIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AssetFile
+	for rows.Next() {
+		var i AssetFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.AssetId,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Type,
+			&i.Path,
+			&i.UpdateId,
+			&i.Width,
+			&i.Height,
+			&i.SizeInBytes,
 		); err != nil {
 			return nil, err
 		}
@@ -3632,7 +5104,7 @@ func (q *Queries) GetAssetFiles(ctx context.Context, assetid pgtype.UUID) ([]Ass
 }
 
 const getAssetFilesByType = `-- name: GetAssetFilesByType :many
-SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId" FROM asset_files
+SELECT id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes" FROM asset_files
 WHERE "assetId" = $1 AND "type" = $2
 ORDER BY "createdAt" ASC
 `
@@ -3659,6 +5131,72 @@ func (q *Queries) GetAssetFilesByType(ctx context.Context, arg GetAssetFilesByTy
 			&i.Type,
 			&i.Path,
 			&i.UpdateId,
+			&i.Width,
+			&i.Height,
+			&i.SizeInBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAssetIds = `-- name: GetAssetIds :many
+SELECT id, "fileCreatedAt", "localDateTime", checksum FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND visibility <> 'locked'
+AND ($2::boolean IS NULL OR status = CASE WHEN $2::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
+AND "isOffline" = false
+AND ($3::timestamptz IS NULL
+    OR ("localDateTime", id) < ($3::timestamptz, $4::uuid))
+ORDER BY "localDateTime" DESC, id DESC
+LIMIT $5 OFFSET $6
+`
+
+type GetAssetIdsParams struct {
+	OwnerID             pgtype.UUID
+	IsTrashed           pgtype.Bool
+	CursorLocalDateTime pgtype.Timestamptz
+	CursorID            pgtype.UUID
+	Limit               int32
+	Offset              int32
+}
+
+type GetAssetIdsRow struct {
+	ID            pgtype.UUID
+	FileCreatedAt pgtype.Timestamptz
+	LocalDateTime pgtype.Timestamptz
+	Checksum      []byte
+}
+
+// The identity of the assets GetAssets lists, without their metadata, for
+// clients syncing a large timeline.
+func (q *Queries) GetAssetIds(ctx context.Context, arg GetAssetIdsParams) ([]GetAssetIdsRow, error) {
+	rows, err := q.db.Query(ctx, getAssetIds,
+		arg.OwnerID,
+		arg.IsTrashed,
+		arg.CursorLocalDateTime,
+		arg.CursorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAssetIdsRow
+	for rows.Next() {
+		var i GetAssetIdsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileCreatedAt,
+			&i.LocalDateTime,
+			&i.Checksum,
 		); err != nil {
 			return nil, err
 		}
@@ -3764,7 +5302,7 @@ SELECT
     COUNT(CASE WHEN type = 'VIDEO' THEN 1 END) as videos,
     COUNT(*) as total
 FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active'
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
 `
 
 type GetAssetStatisticsRow struct {
@@ -3791,7 +5329,7 @@ SELECT
     COALESCE(SUM(e."fileSizeInByte"), 0)::bigint as total_size
 FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
-WHERE a."ownerId" = $1 AND a."deletedAt" IS NULL
+WHERE a."ownerId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked'
 `
 
 type GetAssetStatsByUserRow struct {
@@ -3819,6 +5357,114 @@ func (q *Queries) GetAssetStatsByUser(ctx context.Context, ownerid pgtype.UUID)
 	return i, err
 }
 
+const getAssetSyncManifest = `-- name: GetAssetSyncManifest :many
+SELECT id, "deviceAssetId", checksum, status = 'trashed' AS is_trashed, "updatedAt"
+FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND status <> 'deleted'
+AND visibility IN ('timeline', 'archive')
+AND ($2::uuid IS NULL OR id > $2::uuid)
+ORDER BY id
+LIMIT $3
+`
+
+type GetAssetSyncManifestParams struct {
+	OwnerID pgtype.UUID
+	AfterID pgtype.UUID
+	Limit   int32
+}
+
+type GetAssetSyncManifestRow struct {
+	ID            pgtype.UUID
+	DeviceAssetId string
+	Checksum      []byte
+	IsTrashed     bool
+	UpdatedAt     pgtype.Timestamptz
+}
+
+// A page of the assets a device may hold, by id after the cursor. Trashed
+// assets are listed so devices can mark them; assets deleted for good,
+// hidden live photo videos and the locked folder are not, so devices drop
+// whatever is missing.
+func (q *Queries) GetAssetSyncManifest(ctx context.Context, arg GetAssetSyncManifestParams) ([]GetAssetSyncManifestRow, error) {
+	rows, err := q.db.Query(ctx, getAssetSyncManifest, arg.OwnerID, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAssetSyncManifestRow
+	for rows.Next() {
+		var i GetAssetSyncManifestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceAssetId,
+			&i.Checksum,
+			&i.IsTrashed,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAssetTagSets = `-- name: GetAssetTagSets :many
+SELECT DISTINCT ta."assetsId" AS asset_id, t.id, t."userId", t.value, t."createdAt", t."updatedAt", t.color, t."parentId", t."updateId"
+FROM tag_asset ta
+INNER JOIN tags_closure tc ON tc.id_descendant = ta."tagsId"
+INNER JOIN tags t ON t.id = tc.id_ancestor
+WHERE ta."assetsId" = ANY($1::uuid[])
+AND t."userId" = $2
+ORDER BY ta."assetsId", t.value
+`
+
+type GetAssetTagSetsParams struct {
+	AssetIds []pgtype.UUID
+	UserID   pgtype.UUID
+}
+
+type GetAssetTagSetsRow struct {
+	AssetID pgtype.UUID
+	Tag     Tag
+}
+
+// Tags of each asset, including the ancestors implied by nested tags. An
+// ancestor stays in the set as long as any of its descendants is applied.
+func (q *Queries) GetAssetTagSets(ctx context.Context, arg GetAssetTagSetsParams) ([]GetAssetTagSetsRow, error) {
+	rows, err := q.db.Query(ctx, getAssetTagSets, arg.AssetIds, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAssetTagSetsRow
+	for rows.Next() {
+		var i GetAssetTagSetsRow
+		if err := rows.Scan(
+			&i.AssetID,
+			&i.Tag.ID,
+			&i.Tag.UserId,
+			&i.Tag.Value,
+			&i.Tag.CreatedAt,
+			&i.Tag.UpdatedAt,
+			&i.Tag.Color,
+			&i.Tag.ParentId,
+			&i.Tag.UpdateId,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAssetTags = `-- name: GetAssetTags :many
 SELECT t.id, t."userId", t.value, t."createdAt", t."updatedAt", t.color, t."parentId", t."updateId" FROM tags t
 JOIN tag_asset ta ON t.id = ta."tagsId"
@@ -3868,27 +5514,36 @@ func (q *Queries) GetAssetViewCount(ctx context.Context, assetID pgtype.UUID) (i
 }
 
 const getAssets = `-- name: GetAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND ($4::text IS NULL OR type = $4)
 AND ($5::boolean IS NULL OR "isFavorite" = $5)
 AND ($6::boolean IS NULL OR visibility = CASE WHEN $6::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND ($7::boolean IS NULL OR status = CASE WHEN $7::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
-ORDER BY "localDateTime" DESC
+AND "isOffline" = COALESCE($8::boolean, false)
+AND ($9::timestamptz IS NULL
+    OR ("localDateTime", id) < ($9::timestamptz, $10::uuid))
+ORDER BY "localDateTime" DESC, id DESC
 LIMIT $2 OFFSET $3
 `
 
 type GetAssetsParams struct {
-	OwnerId    pgtype.UUID
-	Limit      int32
-	Offset     int32
-	Type       pgtype.Text
-	IsFavorite pgtype.Bool
-	IsArchived pgtype.Bool
-	IsTrashed  pgtype.Bool
-}
-
+	OwnerId             pgtype.UUID
+	Limit               int32
+	Offset              int32
+	Type                pgtype.Text
+	IsFavorite          pgtype.Bool
+	IsArchived          pgtype.Bool
+	IsTrashed           pgtype.Bool
+	IsOffline           pgtype.Bool
+	CursorLocalDateTime pgtype.Timestamptz
+	CursorID            pgtype.UUID
+}
+
+// A page of the user's assets, newest first. Pages are either offset or
+// keyed on the capture time and id of the last asset of the previous page.
 func (q *Queries) GetAssets(ctx context.Context, arg GetAssetsParams) ([]Asset, error) {
 	rows, err := q.db.Query(ctx, getAssets,
 		arg.OwnerId,
@@ -3898,6 +5553,9 @@ func (q *Queries) GetAssets(ctx context.Context, arg GetAssetsParams) ([]Asset,
 		arg.IsFavorite,
 		arg.IsArchived,
 		arg.IsTrashed,
+		arg.IsOffline,
+		arg.CursorLocalDateTime,
+		arg.CursorID,
 	)
 	if err != nil {
 		return nil, err
@@ -3935,6 +5593,11 @@ func (q *Queries) GetAssets(ctx context.Context, arg GetAssetsParams) ([]Asset,
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -3947,7 +5610,7 @@ func (q *Queries) GetAssets(ctx context.Context, arg GetAssetsParams) ([]Asset,
 }
 
 const getAssetsByChecksum = `-- name: GetAssetsByChecksum :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE checksum = $1 AND "deletedAt" IS NULL
 `
 
@@ -3989,6 +5652,11 @@ func (q *Queries) GetAssetsByChecksum(ctx context.Context, checksum []byte) ([]A
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4001,7 +5669,7 @@ func (q *Queries) GetAssetsByChecksum(ctx context.Context, checksum []byte) ([]A
 }
 
 const getAssetsByChecksumsAndOwner = `-- name: GetAssetsByChecksumsAndOwner :many
-SELECT id, checksum, status FROM assets
+SELECT id, checksum, "checksumAlgorithm", status FROM assets
 WHERE "ownerId" = $1 AND checksum = ANY($2::bytea[])
 `
 
@@ -4011,9 +5679,10 @@ type GetAssetsByChecksumsAndOwnerParams struct {
 }
 
 type GetAssetsByChecksumsAndOwnerRow struct {
-	ID       pgtype.UUID
-	Checksum []byte
-	Status   AssetsStatusEnum
+	ID                pgtype.UUID
+	Checksum          []byte
+	ChecksumAlgorithm string
+	Status            AssetsStatusEnum
 }
 
 // Bulk-upload duplicate check: includes trashed assets so the client can
@@ -4027,7 +5696,12 @@ func (q *Queries) GetAssetsByChecksumsAndOwner(ctx context.Context, arg GetAsset
 	var items []GetAssetsByChecksumsAndOwnerRow
 	for rows.Next() {
 		var i GetAssetsByChecksumsAndOwnerRow
-		if err := rows.Scan(&i.ID, &i.Checksum, &i.Status); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Checksum,
+			&i.ChecksumAlgorithm,
+			&i.Status,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -4039,9 +5713,10 @@ func (q *Queries) GetAssetsByChecksumsAndOwner(ctx context.Context, arg GetAsset
 }
 
 const getAssetsByDateRange = `-- name: GetAssetsByDateRange :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "localDateTime" BETWEEN $2 AND $3
 ORDER BY "localDateTime" DESC
 LIMIT $4 OFFSET $5
@@ -4099,6 +5774,11 @@ func (q *Queries) GetAssetsByDateRange(ctx context.Context, arg GetAssetsByDateR
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4111,7 +5791,7 @@ func (q *Queries) GetAssetsByDateRange(ctx context.Context, arg GetAssetsByDateR
 }
 
 const getAssetsByDeviceAssetIDs = `-- name: GetAssetsByDeviceAssetIDs :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1
 AND "deviceId" = $2
 AND "deviceAssetId" = ANY($3::text[])
@@ -4163,6 +5843,11 @@ func (q *Queries) GetAssetsByDeviceAssetIDs(ctx context.Context, arg GetAssetsBy
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4205,10 +5890,11 @@ func (q *Queries) GetAssetsByDeviceId(ctx context.Context, arg GetAssetsByDevice
 }
 
 const getAssetsByFileSizeAndUser = `-- name: GetAssetsByFileSizeAndUser :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e."fileSizeInByte" = $2
 ORDER BY a."fileCreatedAt" DESC
 `
@@ -4256,6 +5942,11 @@ func (q *Queries) GetAssetsByFileSizeAndUser(ctx context.Context, arg GetAssetsB
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4268,7 +5959,7 @@ func (q *Queries) GetAssetsByFileSizeAndUser(ctx context.Context, arg GetAssetsB
 }
 
 const getAssetsByIDs = `-- name: GetAssetsByIDs :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE id = ANY($1::uuid[]) AND "deletedAt" IS NULL
 `
 
@@ -4310,6 +6001,11 @@ func (q *Queries) GetAssetsByIDs(ctx context.Context, dollar_1 []pgtype.UUID) ([
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4323,10 +6019,11 @@ func (q *Queries) GetAssetsByIDs(ctx context.Context, dollar_1 []pgtype.UUID) ([
 
 const getAssetsByLocation = `-- name: GetAssetsByLocation :many
 
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, e.latitude AS exif_latitude, e.longitude AS exif_longitude, e.city, e.state, e.country FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash, e.latitude AS exif_latitude, e.longitude AS exif_longitude, e.city, e.state, e.country FROM assets a
 JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.latitude IS NOT NULL
 AND e.longitude IS NOT NULL
 AND e.latitude BETWEEN $2 AND $3
@@ -4354,39 +6051,44 @@ type GetAssetsByLocationParams struct {
 }
 
 type GetAssetsByLocationRow struct {
-	ID               pgtype.UUID
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	IsFavorite       bool
-	Duration         pgtype.Text
-	EncodedVideoPath pgtype.Text
-	Checksum         []byte
-	LivePhotoVideoId pgtype.UUID
-	UpdatedAt        pgtype.Timestamptz
-	CreatedAt        pgtype.Timestamptz
-	OriginalFileName string
-	SidecarPath      pgtype.Text
-	Thumbhash        []byte
-	IsOffline        bool
-	LibraryId        pgtype.UUID
-	IsExternal       bool
-	DeletedAt        pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	StackId          pgtype.UUID
-	DuplicateId      pgtype.UUID
-	Status           AssetsStatusEnum
-	UpdateId         pgtype.UUID
-	Visibility       AssetVisibilityEnum
-	ExifLatitude     pgtype.Float8
-	ExifLongitude    pgtype.Float8
-	City             pgtype.Text
-	State            pgtype.Text
-	Country          pgtype.Text
+	ID                pgtype.UUID
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	IsFavorite        bool
+	Duration          pgtype.Text
+	EncodedVideoPath  pgtype.Text
+	Checksum          []byte
+	LivePhotoVideoId  pgtype.UUID
+	UpdatedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	OriginalFileName  string
+	SidecarPath       pgtype.Text
+	Thumbhash         []byte
+	IsOffline         bool
+	LibraryId         pgtype.UUID
+	IsExternal        bool
+	DeletedAt         pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	StackId           pgtype.UUID
+	DuplicateId       pgtype.UUID
+	Status            AssetsStatusEnum
+	UpdateId          pgtype.UUID
+	Visibility        AssetVisibilityEnum
+	ChecksumAlgorithm string
+	IsUndated         bool
+	OfflineAt         pgtype.Timestamptz
+	OriginalMimeType  pgtype.Text
+	Blurhash          pgtype.Text
+	ExifLatitude      pgtype.Float8
+	ExifLongitude     pgtype.Float8
+	City              pgtype.Text
+	State             pgtype.Text
+	Country           pgtype.Text
 }
 
 // ============================================================================
@@ -4442,6 +6144,11 @@ func (q *Queries) GetAssetsByLocation(ctx context.Context, arg GetAssetsByLocati
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 			&i.ExifLatitude,
 			&i.ExifLongitude,
 			&i.City,
@@ -4459,10 +6166,11 @@ func (q *Queries) GetAssetsByLocation(ctx context.Context, arg GetAssetsByLocati
 }
 
 const getAssetsByMemoryID = `-- name: GetAssetsByMemoryID :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 JOIN memories_assets_assets ma ON a.id = ma."assetsId"
 WHERE ma."memoriesId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY a."fileCreatedAt" DESC
 `
 
@@ -4504,6 +6212,11 @@ func (q *Queries) GetAssetsByMemoryID(ctx context.Context, memoriesid pgtype.UUI
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4517,9 +6230,10 @@ func (q *Queries) GetAssetsByMemoryID(ctx context.Context, memoriesid pgtype.UUI
 
 const getAssetsByOriginalPathPrefix = `-- name: GetAssetsByOriginalPathPrefix :many
 
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "originalPath" LIKE $2 || '%'
 AND ($5::boolean IS NULL OR visibility = CASE WHEN $5::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND ($6::boolean IS NULL OR "isFavorite" = $6)
@@ -4582,6 +6296,11 @@ func (q *Queries) GetAssetsByOriginalPathPrefix(ctx context.Context, arg GetAsse
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4594,7 +6313,7 @@ func (q *Queries) GetAssetsByOriginalPathPrefix(ctx context.Context, arg GetAsse
 }
 
 const getAssetsNeedingFaceDetection = `-- name: GetAssetsNeedingFaceDetection :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN asset_job_status ajs ON a.id = ajs."assetId"
 WHERE a."deletedAt" IS NULL 
 AND a.type = 'IMAGE'
@@ -4641,6 +6360,11 @@ func (q *Queries) GetAssetsNeedingFaceDetection(ctx context.Context, limit int32
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4653,7 +6377,7 @@ func (q *Queries) GetAssetsNeedingFaceDetection(ctx context.Context, limit int32
 }
 
 const getAssetsNeedingMetadata = `-- name: GetAssetsNeedingMetadata :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN asset_job_status ajs ON a.id = ajs."assetId"
 WHERE a."deletedAt" IS NULL 
 AND (ajs."metadataExtractedAt" IS NULL OR ajs."metadataExtractedAt" < a."updatedAt")
@@ -4699,6 +6423,11 @@ func (q *Queries) GetAssetsNeedingMetadata(ctx context.Context, limit int32) ([]
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -4711,7 +6440,7 @@ func (q *Queries) GetAssetsNeedingMetadata(ctx context.Context, limit int32) ([]
 }
 
 const getAssetsNeedingThumbnails = `-- name: GetAssetsNeedingThumbnails :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN asset_job_status ajs ON a.id = ajs."assetId"
 WHERE a."deletedAt" IS NULL 
 AND (ajs."thumbnailAt" IS NULL OR ajs."thumbnailAt" < a."updatedAt")
@@ -4757,6 +6486,84 @@ func (q *Queries) GetAssetsNeedingThumbnails(ctx context.Context, limit int32) (
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAutoStackCandidates = `-- name: GetAutoStackCandidates :many
+SELECT
+    a.id,
+    a.type,
+    a."originalFileName",
+    COALESCE(e."dateTimeOriginal", a."fileCreatedAt")::timestamptz AS "takenAt",
+    COALESCE(e.make, '')::text AS make,
+    COALESCE(e.model, '')::text AS model,
+    COALESCE(e."exposureTime", '')::text AS "exposureTime",
+    COALESCE(e."fileSizeInByte", 0)::bigint AS "fileSizeInByte"
+FROM assets a
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a."stackId" IS NULL
+AND a.visibility <> 'hidden'
+AND a.visibility <> 'locked'
+AND NOT EXISTS (
+    SELECT 1 FROM albums_assets_assets aa
+    WHERE aa."assetsId" = a.id
+    AND aa."albumsId" = ANY($2::uuid[])
+)
+ORDER BY "takenAt", a.id
+`
+
+type GetAutoStackCandidatesParams struct {
+	OwnerID          pgtype.UUID
+	ExcludedAlbumIds []pgtype.UUID
+}
+
+type GetAutoStackCandidatesRow struct {
+	ID               pgtype.UUID
+	Type             string
+	OriginalFileName string
+	TakenAt          pgtype.Timestamptz
+	Make             string
+	Model            string
+	ExposureTime     string
+	FileSizeInByte   int64
+}
+
+// Unstacked, visible assets of an owner in capture order, for burst, bracket
+// and live photo detection. Assets in one of the excluded albums are left out.
+func (q *Queries) GetAutoStackCandidates(ctx context.Context, arg GetAutoStackCandidatesParams) ([]GetAutoStackCandidatesRow, error) {
+	rows, err := q.db.Query(ctx, getAutoStackCandidates, arg.OwnerID, arg.ExcludedAlbumIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAutoStackCandidatesRow
+	for rows.Next() {
+		var i GetAutoStackCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.OriginalFileName,
+			&i.TakenAt,
+			&i.Make,
+			&i.Model,
+			&i.ExposureTime,
+			&i.FileSizeInByte,
 		); err != nil {
 			return nil, err
 		}
@@ -4778,6 +6585,7 @@ WITH scoped_assets AS (
     FROM assets
     WHERE "ownerId" = $4
     AND "deletedAt" IS NULL
+    AND visibility <> 'locked'
 )
 SELECT
     date_trunc('day', activity_at AT TIME ZONE 'UTC')::date AS activity_date,
@@ -4850,6 +6658,7 @@ SELECT id FROM assets
 WHERE "ownerId" = $1
 AND (
     (status IN ('trashed'::assets_status_enum, 'deleted'::assets_status_enum) AND "updatedAt" > $2)
+    OR (visibility = 'locked' AND "updatedAt" > $2)
     OR ("deletedAt" IS NOT NULL AND "deletedAt" > $2)
 )
 ORDER BY "updatedAt" ASC
@@ -4862,6 +6671,8 @@ type GetDeletedAssetIDsForSyncParams struct {
 	Limit        int32
 }
 
+// Assets moved to the locked folder are reported as deleted, so that synced
+// devices drop them.
 func (q *Queries) GetDeletedAssetIDsForSync(ctx context.Context, arg GetDeletedAssetIDsForSyncParams) ([]pgtype.UUID, error) {
 	rows, err := q.db.Query(ctx, getDeletedAssetIDsForSync, arg.OwnerID, arg.UpdatedAfter, arg.Limit)
 	if err != nil {
@@ -4885,7 +6696,7 @@ func (q *Queries) GetDeletedAssetIDsForSync(ctx context.Context, arg GetDeletedA
 const getDistinctCameras = `-- name: GetDistinctCameras :many
 SELECT DISTINCT make, model FROM exif
 WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
+  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
 )
   AND make IS NOT NULL
   AND make != ''
@@ -4928,7 +6739,7 @@ func (q *Queries) GetDistinctCameras(ctx context.Context, arg GetDistinctCameras
 const getDistinctCities = `-- name: GetDistinctCities :many
 SELECT DISTINCT city FROM exif
 WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
+  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
 )
   AND city IS NOT NULL
   AND city != ''
@@ -4962,42 +6773,48 @@ func (q *Queries) GetDistinctCities(ctx context.Context, arg GetDistinctCitiesPa
 }
 
 const getDuplicateAssets = `-- name: GetDuplicateAssets :many
-SELECT a1.id, a1."deviceAssetId", a1."ownerId", a1."deviceId", a1.type, a1."originalPath", a1."fileCreatedAt", a1."fileModifiedAt", a1."isFavorite", a1.duration, a1."encodedVideoPath", a1.checksum, a1."livePhotoVideoId", a1."updatedAt", a1."createdAt", a1."originalFileName", a1."sidecarPath", a1.thumbhash, a1."isOffline", a1."libraryId", a1."isExternal", a1."deletedAt", a1."localDateTime", a1."stackId", a1."duplicateId", a1.status, a1."updateId", a1.visibility, a2.id as duplicate_id FROM assets a1
-JOIN assets a2 ON a1.checksum = a2.checksum AND a2."ownerId" = a1."ownerId" AND a1.id < a2.id
+SELECT a1.id, a1."deviceAssetId", a1."ownerId", a1."deviceId", a1.type, a1."originalPath", a1."fileCreatedAt", a1."fileModifiedAt", a1."isFavorite", a1.duration, a1."encodedVideoPath", a1.checksum, a1."livePhotoVideoId", a1."updatedAt", a1."createdAt", a1."originalFileName", a1."sidecarPath", a1.thumbhash, a1."isOffline", a1."libraryId", a1."isExternal", a1."deletedAt", a1."localDateTime", a1."stackId", a1."duplicateId", a1.status, a1."updateId", a1.visibility, a1."checksumAlgorithm", a1."isUndated", a1."offlineAt", a1."originalMimeType", a1.blurhash, a2.id as duplicate_id FROM assets a1
+JOIN assets a2 ON a1.checksum = a2.checksum AND a1."checksumAlgorithm" = a2."checksumAlgorithm" AND a2."ownerId" = a1."ownerId" AND a1.id < a2.id
 WHERE a1."ownerId" = $1 AND a1."deletedAt" IS NULL AND a2."deletedAt" IS NULL
+AND a1.visibility <> 'locked' AND a2.visibility <> 'locked'
 ORDER BY a1."localDateTime" DESC
 `
 
 type GetDuplicateAssetsRow struct {
-	ID               pgtype.UUID
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	IsFavorite       bool
-	Duration         pgtype.Text
-	EncodedVideoPath pgtype.Text
-	Checksum         []byte
-	LivePhotoVideoId pgtype.UUID
-	UpdatedAt        pgtype.Timestamptz
-	CreatedAt        pgtype.Timestamptz
-	OriginalFileName string
-	SidecarPath      pgtype.Text
-	Thumbhash        []byte
-	IsOffline        bool
-	LibraryId        pgtype.UUID
-	IsExternal       bool
-	DeletedAt        pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	StackId          pgtype.UUID
-	DuplicateId      pgtype.UUID
-	Status           AssetsStatusEnum
-	UpdateId         pgtype.UUID
-	Visibility       AssetVisibilityEnum
-	DuplicateID      pgtype.UUID
+	ID                pgtype.UUID
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	IsFavorite        bool
+	Duration          pgtype.Text
+	EncodedVideoPath  pgtype.Text
+	Checksum          []byte
+	LivePhotoVideoId  pgtype.UUID
+	UpdatedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	OriginalFileName  string
+	SidecarPath       pgtype.Text
+	Thumbhash         []byte
+	IsOffline         bool
+	LibraryId         pgtype.UUID
+	IsExternal        bool
+	DeletedAt         pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	StackId           pgtype.UUID
+	DuplicateId       pgtype.UUID
+	Status            AssetsStatusEnum
+	UpdateId          pgtype.UUID
+	Visibility        AssetVisibilityEnum
+	ChecksumAlgorithm string
+	IsUndated         bool
+	OfflineAt         pgtype.Timestamptz
+	OriginalMimeType  pgtype.Text
+	Blurhash          pgtype.Text
+	DuplicateID       pgtype.UUID
 }
 
 func (q *Queries) GetDuplicateAssets(ctx context.Context, ownerid pgtype.UUID) ([]GetDuplicateAssetsRow, error) {
@@ -5038,6 +6855,11 @@ func (q *Queries) GetDuplicateAssets(ctx context.Context, ownerid pgtype.UUID) (
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 			&i.DuplicateID,
 		); err != nil {
 			return nil, err
@@ -5094,6 +6916,406 @@ func (q *Queries) GetExifByAssetId(ctx context.Context, assetid pgtype.UUID) (Ex
 	return i, err
 }
 
+const getExploreCities = `-- name: GetExploreCities :many
+WITH ranked AS (
+    SELECT e.city AS city,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY e.city) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY e.city ORDER BY a."localDateTime" DESC) AS rn
+    FROM exif e
+    INNER JOIN assets a ON a.id = e."assetId"
+    WHERE a."ownerId" = $2
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND e.city IS NOT NULL
+    AND e.city != ''
+)
+SELECT r.city::text AS city, r.asset_count, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.city
+LIMIT $1
+`
+
+type GetExploreCitiesParams struct {
+	MaxItems int32
+	OwnerID  pgtype.UUID
+}
+
+type GetExploreCitiesRow struct {
+	City       string
+	AssetCount int64
+	Asset      Asset
+}
+
+// One tile per geocoded city with its asset count and most recent asset.
+func (q *Queries) GetExploreCities(ctx context.Context, arg GetExploreCitiesParams) ([]GetExploreCitiesRow, error) {
+	rows, err := q.db.Query(ctx, getExploreCities, arg.MaxItems, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExploreCitiesRow
+	for rows.Next() {
+		var i GetExploreCitiesRow
+		if err := rows.Scan(
+			&i.City,
+			&i.AssetCount,
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExploreLabels = `-- name: GetExploreLabels :many
+WITH ranked AS (
+    SELECT al.label,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY al.label) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY al.label ORDER BY a."localDateTime" DESC) AS rn
+    FROM asset_labels al
+    INNER JOIN assets a ON a.id = al."assetId"
+    WHERE a."ownerId" = $2
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND al.score >= $3::float8
+)
+SELECT r.label::text AS label, r.asset_count, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.label
+LIMIT $1
+`
+
+type GetExploreLabelsParams struct {
+	MaxItems int32
+	OwnerID  pgtype.UUID
+	MinScore float64
+}
+
+type GetExploreLabelsRow struct {
+	Label      string
+	AssetCount int64
+	Asset      Asset
+}
+
+// One tile per detected object label scoring at least min_score, with its
+// asset count and most recent asset.
+func (q *Queries) GetExploreLabels(ctx context.Context, arg GetExploreLabelsParams) ([]GetExploreLabelsRow, error) {
+	rows, err := q.db.Query(ctx, getExploreLabels, arg.MaxItems, arg.OwnerID, arg.MinScore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExploreLabelsRow
+	for rows.Next() {
+		var i GetExploreLabelsRow
+		if err := rows.Scan(
+			&i.Label,
+			&i.AssetCount,
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExplorePeople = `-- name: GetExplorePeople :many
+WITH ranked AS (
+    SELECT p.id AS person_id,
+           p.name AS name,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY p.id) AS asset_count,
+           ROW_NUMBER() OVER (
+               PARTITION BY p.id
+               ORDER BY (f.id = p."faceAssetId") DESC, a."localDateTime" DESC
+           ) AS rn
+    FROM person p
+    INNER JOIN asset_faces f ON f."personId" = p.id AND f."deletedAt" IS NULL
+    INNER JOIN assets a ON a.id = f."assetId"
+    WHERE p."ownerId" = $2
+    AND p."isHidden" = false
+    AND p.name != ''
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+)
+SELECT r.person_id, r.name::text AS name, r.asset_count, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.name
+LIMIT $1
+`
+
+type GetExplorePeopleParams struct {
+	MaxItems int32
+	OwnerID  pgtype.UUID
+}
+
+type GetExplorePeopleRow struct {
+	PersonID   pgtype.UUID
+	Name       string
+	AssetCount int64
+	Asset      Asset
+}
+
+// One tile per named, visible person with their asset count and a
+// representative asset (the face asset when set, otherwise the most recent).
+func (q *Queries) GetExplorePeople(ctx context.Context, arg GetExplorePeopleParams) ([]GetExplorePeopleRow, error) {
+	rows, err := q.db.Query(ctx, getExplorePeople, arg.MaxItems, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExplorePeopleRow
+	for rows.Next() {
+		var i GetExplorePeopleRow
+		if err := rows.Scan(
+			&i.PersonID,
+			&i.Name,
+			&i.AssetCount,
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExploreTags = `-- name: GetExploreTags :many
+WITH tagged AS (
+    SELECT DISTINCT t.id AS tag_id, t.value AS tag, ta."assetsId" AS asset_id
+    FROM tags t
+    INNER JOIN tags_closure tc ON tc.id_ancestor = t.id
+    INNER JOIN tag_asset ta ON ta."tagsId" = tc.id_descendant
+    WHERE t."userId" = $2
+), ranked AS (
+    SELECT tg.tag,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY tg.tag_id) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY tg.tag_id ORDER BY a."localDateTime" DESC) AS rn
+    FROM tagged tg
+    INNER JOIN assets a ON a.id = tg.asset_id
+    WHERE a."ownerId" = $2
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+)
+SELECT r.tag::text AS tag, r.asset_count, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.tag
+LIMIT $1
+`
+
+type GetExploreTagsParams struct {
+	MaxItems int32
+	OwnerID  pgtype.UUID
+}
+
+type GetExploreTagsRow struct {
+	Tag        string
+	AssetCount int64
+	Asset      Asset
+}
+
+// One tile per tag with its asset count and most recent asset. Assets with a
+// nested tag also count towards its ancestors.
+func (q *Queries) GetExploreTags(ctx context.Context, arg GetExploreTagsParams) ([]GetExploreTagsRow, error) {
+	rows, err := q.db.Query(ctx, getExploreTags, arg.MaxItems, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExploreTagsRow
+	for rows.Next() {
+		var i GetExploreTagsRow
+		if err := rows.Scan(
+			&i.Tag,
+			&i.AssetCount,
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFace = `-- name: GetFace :one
+SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score FROM asset_faces
+WHERE id = $1
+AND "deletedAt" IS NULL
+`
+
+func (q *Queries) GetFace(ctx context.Context, id pgtype.UUID) (AssetFace, error) {
+	row := q.db.QueryRow(ctx, getFace, id)
+	var i AssetFace
+	err := row.Scan(
+		&i.AssetId,
+		&i.PersonId,
+		&i.ImageWidth,
+		&i.ImageHeight,
+		&i.BoundingBoxX1,
+		&i.BoundingBoxY1,
+		&i.BoundingBoxX2,
+		&i.BoundingBoxY2,
+		&i.ID,
+		&i.SourceType,
+		&i.DeletedAt,
+		&i.Score,
+	)
+	return i, err
+}
+
 const getFaceSearch = `-- name: GetFaceSearch :many
 SELECT "faceId", embedding FROM face_search
 WHERE "faceId" = $1
@@ -5120,7 +7342,7 @@ func (q *Queries) GetFaceSearch(ctx context.Context, faceid pgtype.UUID) ([]Face
 }
 
 const getFacesByAsset = `-- name: GetFacesByAsset :many
-SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt" FROM asset_faces
+SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score FROM asset_faces
 WHERE "assetId" = $1
 AND "deletedAt" IS NULL
 `
@@ -5146,6 +7368,7 @@ func (q *Queries) GetFacesByAsset(ctx context.Context, assetid pgtype.UUID) ([]A
 			&i.ID,
 			&i.SourceType,
 			&i.DeletedAt,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -5158,7 +7381,7 @@ func (q *Queries) GetFacesByAsset(ctx context.Context, assetid pgtype.UUID) ([]A
 }
 
 const getFacesByPerson = `-- name: GetFacesByPerson :many
-SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt" FROM asset_faces
+SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score FROM asset_faces
 WHERE "personId" = $1
 AND "deletedAt" IS NULL
 `
@@ -5184,6 +7407,7 @@ func (q *Queries) GetFacesByPerson(ctx context.Context, personid pgtype.UUID) ([
 			&i.ID,
 			&i.SourceType,
 			&i.DeletedAt,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -5196,10 +7420,11 @@ func (q *Queries) GetFacesByPerson(ctx context.Context, personid pgtype.UUID) ([
 }
 
 const getFavoriteAssets = `-- name: GetFavoriteAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND "isFavorite" = true
+AND visibility <> 'locked'
 ORDER BY "localDateTime" DESC
 LIMIT $2 OFFSET $3
 `
@@ -5248,6 +7473,11 @@ func (q *Queries) GetFavoriteAssets(ctx context.Context, arg GetFavoriteAssetsPa
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -5259,36 +7489,115 @@ func (q *Queries) GetFavoriteAssets(ctx context.Context, arg GetFavoriteAssetsPa
 	return items, nil
 }
 
-const getIntegrityOriginalAssets = `-- name: GetIntegrityOriginalAssets :many
-SELECT id, "originalPath", checksum
-FROM assets
-WHERE "deletedAt" IS NULL
-AND status != 'deleted'::assets_status_enum
-AND "isExternal" = false
-AND "originalPath" != ''
-ORDER BY "originalPath", id
+const getIdempotencyKey = `-- name: GetIdempotencyKey :one
+SELECT "userId", key, fingerprint, "statusCode", response, "createdAt", "expiresAt" FROM idempotency_keys
+WHERE "userId" = $1 AND key = $2
 `
 
-type GetIntegrityOriginalAssetsRow struct {
-	ID           pgtype.UUID
-	OriginalPath string
-	Checksum     []byte
+type GetIdempotencyKeyParams struct {
+	UserId pgtype.UUID
+	Key    string
 }
 
-func (q *Queries) GetIntegrityOriginalAssets(ctx context.Context) ([]GetIntegrityOriginalAssetsRow, error) {
-	rows, err := q.db.Query(ctx, getIntegrityOriginalAssets)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetIntegrityOriginalAssetsRow
-	for rows.Next() {
-		var i GetIntegrityOriginalAssetsRow
-		if err := rows.Scan(&i.ID, &i.OriginalPath, &i.Checksum); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
+func (q *Queries) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	row := q.db.QueryRow(ctx, getIdempotencyKey, arg.UserId, arg.Key)
+	var i IdempotencyKey
+	err := row.Scan(
+		&i.UserId,
+		&i.Key,
+		&i.Fingerprint,
+		&i.StatusCode,
+		&i.Response,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getIntegrityOriginalAssets = `-- name: GetIntegrityOriginalAssets :many
+SELECT a.id, a."originalPath", a.checksum, a."checksumAlgorithm",
+    COALESCE(b.path, a."originalPath")::text AS "checksumPath",
+    (d."assetId" IS NOT NULL AND b.path IS NULL)::boolean AS "originalReplaced"
+FROM assets a
+LEFT JOIN asset_files b ON b."assetId" = a.id AND b.type = 'original_backup'
+LEFT JOIN asset_downscales d ON d."assetId" = a.id
+WHERE a."deletedAt" IS NULL
+AND a.status != 'deleted'::assets_status_enum
+AND a."isExternal" = false
+AND a."originalPath" != ''
+ORDER BY a."originalPath", a.id
+`
+
+type GetIntegrityOriginalAssetsRow struct {
+	ID                pgtype.UUID
+	OriginalPath      string
+	Checksum          []byte
+	ChecksumAlgorithm string
+	ChecksumPath      string
+	OriginalReplaced  bool
+}
+
+// The checksum identifies the uploaded bytes. When metadata was written back
+// into the original, or it was downscaled on upload, those bytes live in its
+// original_backup file. A downscaled original without one was replaced, so
+// its checksum cannot be verified.
+func (q *Queries) GetIntegrityOriginalAssets(ctx context.Context) ([]GetIntegrityOriginalAssetsRow, error) {
+	rows, err := q.db.Query(ctx, getIntegrityOriginalAssets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIntegrityOriginalAssetsRow
+	for rows.Next() {
+		var i GetIntegrityOriginalAssetsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalPath,
+			&i.Checksum,
+			&i.ChecksumAlgorithm,
+			&i.ChecksumPath,
+			&i.OriginalReplaced,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getIntegrityThumbnailFiles = `-- name: GetIntegrityThumbnailFiles :many
+SELECT af."assetId", af.path
+FROM asset_files af
+JOIN assets a ON a.id = af."assetId"
+WHERE a."deletedAt" IS NULL
+AND a.status != 'deleted'::assets_status_enum
+AND af.type IN ('thumbnail', 'preview', 'fullsize', 'thumb', 'webp')
+AND af.path != ''
+ORDER BY af.path
+`
+
+type GetIntegrityThumbnailFilesRow struct {
+	AssetId pgtype.UUID
+	Path    string
+}
+
+func (q *Queries) GetIntegrityThumbnailFiles(ctx context.Context) ([]GetIntegrityThumbnailFilesRow, error) {
+	rows, err := q.db.Query(ctx, getIntegrityThumbnailFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetIntegrityThumbnailFilesRow
+	for rows.Next() {
+		var i GetIntegrityThumbnailFilesRow
+		if err := rows.Scan(&i.AssetId, &i.Path); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
@@ -5464,8 +7773,86 @@ func (q *Queries) GetLibraryAssetCount(ctx context.Context, libraryid pgtype.UUI
 	return count, err
 }
 
+const getLibraryAssetFiles = `-- name: GetLibraryAssetFiles :many
+SELECT id, "originalPath", checksum, "isOffline" FROM assets
+WHERE "libraryId" = $1 AND "deletedAt" IS NULL
+`
+
+type GetLibraryAssetFilesRow struct {
+	ID           pgtype.UUID
+	OriginalPath string
+	Checksum     []byte
+	IsOffline    bool
+}
+
+// The files backing a library's assets, for reconciling them with a scan.
+func (q *Queries) GetLibraryAssetFiles(ctx context.Context, libraryid pgtype.UUID) ([]GetLibraryAssetFilesRow, error) {
+	rows, err := q.db.Query(ctx, getLibraryAssetFiles, libraryid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetLibraryAssetFilesRow
+	for rows.Next() {
+		var i GetLibraryAssetFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalPath,
+			&i.Checksum,
+			&i.IsOffline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLibraryAssetStatistics = `-- name: GetLibraryAssetStatistics :one
+SELECT
+  COUNT(a.id) FILTER (WHERE a.type = 'IMAGE') AS photos,
+  COUNT(a.id) FILTER (WHERE a.type = 'VIDEO') AS videos,
+  COUNT(a.id) AS total,
+  COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS total_size,
+  u."quotaSizeInBytes" AS owner_quota
+FROM libraries l
+JOIN users u ON u.id = l."ownerId"
+LEFT JOIN assets a ON a."libraryId" = l.id
+  AND a."deletedAt" IS NULL
+  AND a.status = 'active'
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE l.id = $1
+GROUP BY u."quotaSizeInBytes"
+`
+
+type GetLibraryAssetStatisticsRow struct {
+	Photos     int64
+	Videos     int64
+	Total      int64
+	TotalSize  int64
+	OwnerQuota pgtype.Int8
+}
+
+// Active asset counts by type and their total file size for a library, with
+// the owner's quota to compute usage against.
+func (q *Queries) GetLibraryAssetStatistics(ctx context.Context, libraryID pgtype.UUID) (GetLibraryAssetStatisticsRow, error) {
+	row := q.db.QueryRow(ctx, getLibraryAssetStatistics, libraryID)
+	var i GetLibraryAssetStatisticsRow
+	err := row.Scan(
+		&i.Photos,
+		&i.Videos,
+		&i.Total,
+		&i.TotalSize,
+		&i.OwnerQuota,
+	)
+	return i, err
+}
+
 const getLibraryAssets = `-- name: GetLibraryAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "libraryId" = $1 AND "deletedAt" IS NULL
 ORDER BY "localDateTime" DESC
 LIMIT $2 OFFSET $3
@@ -5515,6 +7902,148 @@ func (q *Queries) GetLibraryAssets(ctx context.Context, arg GetLibraryAssetsPara
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLibraryFolderAlbumSettings = `-- name: GetLibraryFolderAlbumSettings :one
+SELECT "libraryId", depth, naming, "updatedAt" FROM library_folder_album_settings
+WHERE "libraryId" = $1
+`
+
+func (q *Queries) GetLibraryFolderAlbumSettings(ctx context.Context, libraryid pgtype.UUID) (LibraryFolderAlbumSetting, error) {
+	row := q.db.QueryRow(ctx, getLibraryFolderAlbumSettings, libraryid)
+	var i LibraryFolderAlbumSetting
+	err := row.Scan(
+		&i.LibraryId,
+		&i.Depth,
+		&i.Naming,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLibraryFolderAlbums = `-- name: GetLibraryFolderAlbums :many
+SELECT f."libraryId", f."folderPath", f."albumId" FROM library_folder_albums f
+JOIN albums a ON a.id = f."albumId"
+WHERE f."libraryId" = $1 AND a."deletedAt" IS NULL
+`
+
+// The albums of a library's folders, leaving out deleted albums so their
+// folders get new ones.
+func (q *Queries) GetLibraryFolderAlbums(ctx context.Context, libraryid pgtype.UUID) ([]LibraryFolderAlbum, error) {
+	rows, err := q.db.Query(ctx, getLibraryFolderAlbums, libraryid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LibraryFolderAlbum
+	for rows.Next() {
+		var i LibraryFolderAlbum
+		if err := rows.Scan(&i.LibraryId, &i.FolderPath, &i.AlbumId); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLibraryIncludingDeleted = `-- name: GetLibraryIncludingDeleted :one
+SELECT id, name, "ownerId", "importPaths", "exclusionPatterns", "createdAt", "updatedAt", "deletedAt", "refreshedAt", "updateId" FROM libraries
+WHERE id = $1
+`
+
+func (q *Queries) GetLibraryIncludingDeleted(ctx context.Context, id pgtype.UUID) (Library, error) {
+	row := q.db.QueryRow(ctx, getLibraryIncludingDeleted, id)
+	var i Library
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.OwnerId,
+		&i.ImportPaths,
+		&i.ExclusionPatterns,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.RefreshedAt,
+		&i.UpdateId,
+	)
+	return i, err
+}
+
+const getLockedAssets = `-- name: GetLockedAssets :many
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND visibility = 'locked'
+ORDER BY "localDateTime" DESC, id DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetLockedAssetsParams struct {
+	OwnerId pgtype.UUID
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) GetLockedAssets(ctx context.Context, arg GetLockedAssetsParams) ([]Asset, error) {
+	rows, err := q.db.Query(ctx, getLockedAssets, arg.OwnerId, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Asset
+	for rows.Next() {
+		var i Asset
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceAssetId,
+			&i.OwnerId,
+			&i.DeviceId,
+			&i.Type,
+			&i.OriginalPath,
+			&i.FileCreatedAt,
+			&i.FileModifiedAt,
+			&i.IsFavorite,
+			&i.Duration,
+			&i.EncodedVideoPath,
+			&i.Checksum,
+			&i.LivePhotoVideoId,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.OriginalFileName,
+			&i.SidecarPath,
+			&i.Thumbhash,
+			&i.IsOffline,
+			&i.LibraryId,
+			&i.IsExternal,
+			&i.DeletedAt,
+			&i.LocalDateTime,
+			&i.StackId,
+			&i.DuplicateId,
+			&i.Status,
+			&i.UpdateId,
+			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -5594,8 +8123,10 @@ func (q *Queries) GetMemory(ctx context.Context, id pgtype.UUID) (Memory, error)
 }
 
 const getMemoryAssets = `-- name: GetMemoryAssets :many
-SELECT "assetsId" FROM memories_assets_assets
-WHERE "memoriesId" = $1
+SELECT ma."assetsId" FROM memories_assets_assets ma
+JOIN assets a ON a.id = ma."assetsId"
+WHERE ma."memoriesId" = $1
+AND a.visibility <> 'locked'
 `
 
 func (q *Queries) GetMemoryAssets(ctx context.Context, memoriesid pgtype.UUID) ([]pgtype.UUID, error) {
@@ -5618,6 +8149,40 @@ func (q *Queries) GetMemoryAssets(ctx context.Context, memoriesid pgtype.UUID) (
 	return items, nil
 }
 
+const getNearestGeodataPlace = `-- name: GetNearestGeodataPlace :one
+SELECT id, name, longitude, latitude, "countryCode", "admin1Code", "admin2Code", "modificationDate", "admin1Name", "admin2Name", "alternateNames" FROM geodata_places
+WHERE earth_box(ll_to_earth_public($1, $2), $3)
+    @> ll_to_earth_public(latitude, longitude)
+ORDER BY earth_distance(ll_to_earth_public($1, $2), ll_to_earth_public(latitude, longitude))
+LIMIT 1
+`
+
+type GetNearestGeodataPlaceParams struct {
+	Latitude    float64
+	Longitude   float64
+	MaxDistance float64
+}
+
+// Returns the place closest to the coordinates within max_distance meters.
+func (q *Queries) GetNearestGeodataPlace(ctx context.Context, arg GetNearestGeodataPlaceParams) (GeodataPlace, error) {
+	row := q.db.QueryRow(ctx, getNearestGeodataPlace, arg.Latitude, arg.Longitude, arg.MaxDistance)
+	var i GeodataPlace
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Longitude,
+		&i.Latitude,
+		&i.CountryCode,
+		&i.Admin1Code,
+		&i.Admin2Code,
+		&i.ModificationDate,
+		&i.Admin1Name,
+		&i.Admin2Name,
+		&i.AlternateNames,
+	)
+	return i, err
+}
+
 const getNotification = `-- name: GetNotification :one
 SELECT id, "createdAt", "updatedAt", "deletedAt", "updateId", "userId", level, type, data, title, description, "readAt" FROM notifications
 WHERE id = $1 AND "deletedAt" IS NULL
@@ -5693,39 +8258,162 @@ func (q *Queries) GetNotifications(ctx context.Context, arg GetNotificationsPara
 	return items, nil
 }
 
-const getPartners = `-- name: GetPartners :many
-
-SELECT u.id, u.email, u.password, u."createdAt", u."profileImagePath", u."isAdmin", u."shouldChangePassword", u."deletedAt", u."oauthId", u."updatedAt", u."storageLabel", u.name, u."quotaSizeInBytes", u."quotaUsageInBytes", u.status, u."profileChangedAt", u."updateId", u."avatarColor", u."pinCode", u."isOnboarded", p."sharedById", p."sharedWithId", p."inTimeline", p."createdAt" as partnership_created_at, p."updatedAt" as partnership_updated_at FROM partners p
-JOIN users u ON (u.id = p."sharedById" OR u.id = p."sharedWithId")
-WHERE (p."sharedById" = $1 OR p."sharedWithId" = $1) AND u.id != $1
+const getOnThisDayAssets = `-- name: GetOnThisDayAssets :many
+SELECT id, "localDateTime" FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND visibility = 'timeline'
+AND status = 'active'
+AND NOT "isUndated"
+AND EXTRACT(MONTH FROM "localDateTime" AT TIME ZONE 'UTC') = $2::int
+AND EXTRACT(DAY FROM "localDateTime" AT TIME ZONE 'UTC') = $3::int
+AND EXTRACT(YEAR FROM "localDateTime" AT TIME ZONE 'UTC') < $4::int
+ORDER BY "localDateTime" ASC
 `
 
-type GetPartnersRow struct {
-	ID                   pgtype.UUID
-	Email                string
-	Password             string
-	CreatedAt            pgtype.Timestamptz
-	ProfileImagePath     string
-	IsAdmin              bool
-	ShouldChangePassword bool
-	DeletedAt            pgtype.Timestamptz
-	OauthId              string
-	UpdatedAt            pgtype.Timestamptz
-	StorageLabel         pgtype.Text
-	Name                 string
-	QuotaSizeInBytes     pgtype.Int8
-	QuotaUsageInBytes    int64
-	Status               string
-	ProfileChangedAt     pgtype.Timestamptz
-	UpdateId             pgtype.UUID
-	AvatarColor          pgtype.Text
-	PinCode              pgtype.Text
-	IsOnboarded          bool
-	SharedById           pgtype.UUID
-	SharedWithId         pgtype.UUID
-	InTimeline           bool
-	PartnershipCreatedAt pgtype.Timestamptz
-	PartnershipUpdatedAt pgtype.Timestamptz
+type GetOnThisDayAssetsParams struct {
+	OwnerID    pgtype.UUID
+	Month      int32
+	Day        int32
+	BeforeYear int32
+}
+
+type GetOnThisDayAssetsRow struct {
+	ID            pgtype.UUID
+	LocalDateTime pgtype.Timestamptz
+}
+
+// Assets captured on the given local month and day in earlier years.
+// "localDateTime" holds the capture wall clock as UTC.
+func (q *Queries) GetOnThisDayAssets(ctx context.Context, arg GetOnThisDayAssetsParams) ([]GetOnThisDayAssetsRow, error) {
+	rows, err := q.db.Query(ctx, getOnThisDayAssets,
+		arg.OwnerID,
+		arg.Month,
+		arg.Day,
+		arg.BeforeYear,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOnThisDayAssetsRow
+	for rows.Next() {
+		var i GetOnThisDayAssetsRow
+		if err := rows.Scan(&i.ID, &i.LocalDateTime); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOwnerAssetsByChecksum = `-- name: GetOwnerAssetsByChecksum :many
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1
+AND checksum = $2
+AND "checksumAlgorithm" = $3
+AND "deletedAt" IS NULL
+`
+
+type GetOwnerAssetsByChecksumParams struct {
+	OwnerID           pgtype.UUID
+	Checksum          []byte
+	ChecksumAlgorithm string
+}
+
+// Upload dedup: a checksum only matches one computed with the same algorithm.
+func (q *Queries) GetOwnerAssetsByChecksum(ctx context.Context, arg GetOwnerAssetsByChecksumParams) ([]Asset, error) {
+	rows, err := q.db.Query(ctx, getOwnerAssetsByChecksum, arg.OwnerID, arg.Checksum, arg.ChecksumAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Asset
+	for rows.Next() {
+		var i Asset
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceAssetId,
+			&i.OwnerId,
+			&i.DeviceId,
+			&i.Type,
+			&i.OriginalPath,
+			&i.FileCreatedAt,
+			&i.FileModifiedAt,
+			&i.IsFavorite,
+			&i.Duration,
+			&i.EncodedVideoPath,
+			&i.Checksum,
+			&i.LivePhotoVideoId,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.OriginalFileName,
+			&i.SidecarPath,
+			&i.Thumbhash,
+			&i.IsOffline,
+			&i.LibraryId,
+			&i.IsExternal,
+			&i.DeletedAt,
+			&i.LocalDateTime,
+			&i.StackId,
+			&i.DuplicateId,
+			&i.Status,
+			&i.UpdateId,
+			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPartners = `-- name: GetPartners :many
+
+SELECT u.id, u.email, u.password, u."createdAt", u."profileImagePath", u."isAdmin", u."shouldChangePassword", u."deletedAt", u."oauthId", u."updatedAt", u."storageLabel", u.name, u."quotaSizeInBytes", u."quotaUsageInBytes", u.status, u."profileChangedAt", u."updateId", u."avatarColor", u."pinCode", u."isOnboarded", p."sharedById", p."sharedWithId", p."inTimeline", p."createdAt" as partnership_created_at, p."updatedAt" as partnership_updated_at, p.status as partnership_status FROM partners p
+JOIN users u ON (u.id = p."sharedById" OR u.id = p."sharedWithId")
+WHERE (p."sharedById" = $1 OR p."sharedWithId" = $1) AND u.id != $1
+AND u."deletedAt" IS NULL
+`
+
+type GetPartnersRow struct {
+	ID                   pgtype.UUID
+	Email                string
+	Password             string
+	CreatedAt            pgtype.Timestamptz
+	ProfileImagePath     string
+	IsAdmin              bool
+	ShouldChangePassword bool
+	DeletedAt            pgtype.Timestamptz
+	OauthId              string
+	UpdatedAt            pgtype.Timestamptz
+	StorageLabel         pgtype.Text
+	Name                 string
+	QuotaSizeInBytes     pgtype.Int8
+	QuotaUsageInBytes    int64
+	Status               string
+	ProfileChangedAt     pgtype.Timestamptz
+	UpdateId             pgtype.UUID
+	AvatarColor          pgtype.Text
+	PinCode              pgtype.Text
+	IsOnboarded          bool
+	SharedById           pgtype.UUID
+	SharedWithId         pgtype.UUID
+	InTimeline           bool
+	PartnershipCreatedAt pgtype.Timestamptz
+	PartnershipUpdatedAt pgtype.Timestamptz
+	PartnershipStatus    string
 }
 
 // ============================================================================
@@ -5766,6 +8454,7 @@ func (q *Queries) GetPartners(ctx context.Context, sharedbyid pgtype.UUID) ([]Ge
 			&i.InTimeline,
 			&i.PartnershipCreatedAt,
 			&i.PartnershipUpdatedAt,
+			&i.PartnershipStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -5846,9 +8535,9 @@ func (q *Queries) GetPerson(ctx context.Context, id pgtype.UUID) (Person, error)
 }
 
 const getPersonAssets = `-- name: GetPersonAssets :many
-SELECT DISTINCT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT DISTINCT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 JOIN asset_faces af ON a.id = af."assetId"
-WHERE af."personId" = $1 AND a."deletedAt" IS NULL
+WHERE af."personId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked'
 ORDER BY a."localDateTime" DESC
 LIMIT $2 OFFSET $3
 `
@@ -5897,6 +8586,11 @@ func (q *Queries) GetPersonAssets(ctx context.Context, arg GetPersonAssetsParams
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -5908,9 +8602,44 @@ func (q *Queries) GetPersonAssets(ctx context.Context, arg GetPersonAssetsParams
 	return items, nil
 }
 
+const getPersonFaceOnAsset = `-- name: GetPersonFaceOnAsset :one
+SELECT "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score FROM asset_faces
+WHERE "personId" = $1 AND "assetId" = $2
+AND "deletedAt" IS NULL
+ORDER BY ("boundingBoxX2" - "boundingBoxX1") * ("boundingBoxY2" - "boundingBoxY1") DESC
+LIMIT 1
+`
+
+type GetPersonFaceOnAssetParams struct {
+	PersonId pgtype.UUID
+	AssetId  pgtype.UUID
+}
+
+// The face of a person on an asset, the largest one should they appear more
+// than once.
+func (q *Queries) GetPersonFaceOnAsset(ctx context.Context, arg GetPersonFaceOnAssetParams) (AssetFace, error) {
+	row := q.db.QueryRow(ctx, getPersonFaceOnAsset, arg.PersonId, arg.AssetId)
+	var i AssetFace
+	err := row.Scan(
+		&i.AssetId,
+		&i.PersonId,
+		&i.ImageWidth,
+		&i.ImageHeight,
+		&i.BoundingBoxX1,
+		&i.BoundingBoxY1,
+		&i.BoundingBoxX2,
+		&i.BoundingBoxY2,
+		&i.ID,
+		&i.SourceType,
+		&i.DeletedAt,
+		&i.Score,
+	)
+	return i, err
+}
+
 const getRandomAssets = `-- name: GetRandomAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active'
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
 ORDER BY RANDOM()
 LIMIT $2
 `
@@ -5958,6 +8687,11 @@ func (q *Queries) GetRandomAssets(ctx context.Context, arg GetRandomAssetsParams
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -5970,7 +8704,7 @@ func (q *Queries) GetRandomAssets(ctx context.Context, arg GetRandomAssetsParams
 }
 
 const getRecentAssets = `-- name: GetRecentAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND status = 'active'
@@ -6022,6 +8756,11 @@ func (q *Queries) GetRecentAssets(ctx context.Context, arg GetRecentAssetsParams
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -6034,19 +8773,22 @@ func (q *Queries) GetRecentAssets(ctx context.Context, arg GetRecentAssetsParams
 }
 
 const getRecentlyAddedAssets = `-- name: GetRecentlyAddedAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active'
-ORDER BY "fileCreatedAt" DESC
-LIMIT $2
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
+ORDER BY "createdAt" DESC, id DESC
+LIMIT $2 OFFSET $3
 `
 
 type GetRecentlyAddedAssetsParams struct {
 	OwnerId pgtype.UUID
 	Limit   int32
+	Offset  int32
 }
 
+// The assets most recently uploaded or imported, newest first. Trashed
+// assets are left out, GetTrashedAssets lists them.
 func (q *Queries) GetRecentlyAddedAssets(ctx context.Context, arg GetRecentlyAddedAssetsParams) ([]Asset, error) {
-	rows, err := q.db.Query(ctx, getRecentlyAddedAssets, arg.OwnerId, arg.Limit)
+	rows, err := q.db.Query(ctx, getRecentlyAddedAssets, arg.OwnerId, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -6083,6 +8825,11 @@ func (q *Queries) GetRecentlyAddedAssets(ctx context.Context, arg GetRecentlyAdd
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -6094,6 +8841,70 @@ func (q *Queries) GetRecentlyAddedAssets(ctx context.Context, arg GetRecentlyAdd
 	return items, nil
 }
 
+const getReferencedStoragePaths = `-- name: GetReferencedStoragePaths :many
+SELECT DISTINCT path
+FROM (
+    SELECT "originalPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+
+    UNION
+
+    SELECT "encodedVideoPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "encodedVideoPath" IS NOT NULL
+
+    UNION
+
+    SELECT "sidecarPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "sidecarPath" IS NOT NULL
+
+    UNION
+
+    SELECT af.path
+    FROM asset_files af
+    JOIN assets a ON a.id = af."assetId"
+    WHERE a.status != 'deleted'::assets_status_enum
+
+    UNION
+
+    SELECT "thumbnailPath" AS path
+    FROM person
+
+    UNION
+
+    SELECT "profileImagePath" AS path
+    FROM users
+) referenced
+WHERE path != ''
+ORDER BY path
+`
+
+// Every storage path a row still points at, including trashed assets, which
+// can be restored. Files of permanently deleted assets are not referenced.
+func (q *Queries) GetReferencedStoragePaths(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, getReferencedStoragePaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		items = append(items, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRefreshToken = `-- name: GetRefreshToken :one
 SELECT id, token, "createdAt", "updatedAt", "userId", "deviceType", "deviceOS", "updateId", "pinExpiresAt", "expiresAt", "parentId", "isPendingSyncReset", "appVersion", "oauthSid" FROM sessions
 WHERE token = $1 AND ("expiresAt" IS NULL OR "expiresAt" > now())
@@ -6121,6 +8932,153 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (Session, e
 	return i, err
 }
 
+const getReindexAssetIDs = `-- name: GetReindexAssetIDs :many
+SELECT id FROM assets
+WHERE "deletedAt" IS NULL
+AND status = 'active'
+AND "isOffline" = false
+AND ($1::uuid IS NULL OR "ownerId" = $1::uuid)
+AND ($2::uuid IS NULL OR "libraryId" = $2::uuid)
+AND ($3::timestamptz IS NULL OR "localDateTime" >= $3::timestamptz)
+AND ($4::timestamptz IS NULL OR "localDateTime" < $4::timestamptz)
+AND ($5::uuid IS NULL OR id > $5::uuid)
+ORDER BY id
+LIMIT $6
+`
+
+type GetReindexAssetIDsParams struct {
+	OwnerID     pgtype.UUID
+	LibraryID   pgtype.UUID
+	TakenAfter  pgtype.Timestamptz
+	TakenBefore pgtype.Timestamptz
+	AfterID     pgtype.UUID
+	Limit       int32
+}
+
+// The next batch of assets of a re-index run, in id order after the last
+// batch.
+func (q *Queries) GetReindexAssetIDs(ctx context.Context, arg GetReindexAssetIDsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, getReindexAssetIDs,
+		arg.OwnerID,
+		arg.LibraryID,
+		arg.TakenAfter,
+		arg.TakenBefore,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSearchSuggestions = `-- name: GetSearchSuggestions :many
+WITH owned_exif AS (
+    SELECT e.city, e.state, e.country, e.make, e.model
+    FROM exif e
+    INNER JOIN assets a ON a.id = e."assetId"
+    WHERE a."ownerId" = $2
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    AND ($3::text IS NULL OR e.country = $3)
+    AND ($4::text IS NULL OR e.state = $4)
+    AND ($5::text IS NULL OR e.make = $5)
+    AND ($6::text IS NULL OR e.model = $6)
+), candidates AS (
+    SELECT 'country' AS category, country AS value FROM owned_exif
+    UNION ALL
+    SELECT 'state', state FROM owned_exif
+    UNION ALL
+    SELECT 'city', city FROM owned_exif
+    UNION ALL
+    SELECT 'camera-make', make FROM owned_exif
+    UNION ALL
+    SELECT 'camera-model', model FROM owned_exif
+    UNION ALL
+    SELECT 'tag', t.value FROM tags t WHERE t."userId" = $2
+    UNION ALL
+    SELECT 'person', p.name FROM person p
+    WHERE p."ownerId" = $2 AND p."isHidden" = false
+), matched AS (
+    SELECT category, value, COUNT(*) AS uses
+    FROM candidates
+    WHERE value IS NOT NULL
+    AND value != ''
+    AND ($7::text IS NULL OR category = $7)
+    AND f_unaccent(lower(value)) LIKE f_unaccent(lower($8::text)) || '%'
+    GROUP BY category, value
+), ranked AS (
+    SELECT category, value,
+           ROW_NUMBER() OVER (PARTITION BY category ORDER BY uses DESC, value) AS rn
+    FROM matched
+)
+SELECT category::text AS category, value::text AS value
+FROM ranked
+WHERE rn <= $1::int
+ORDER BY category, rn
+`
+
+type GetSearchSuggestionsParams struct {
+	MaxPerCategory int32
+	OwnerID        pgtype.UUID
+	Country        pgtype.Text
+	State          pgtype.Text
+	Make           pgtype.Text
+	Model          pgtype.Text
+	Category       pgtype.Text
+	Prefix         string
+}
+
+type GetSearchSuggestionsRow struct {
+	Category string
+	Value    string
+}
+
+// Distinct values from the user's library per suggestion category, filtered by
+// a case- and diacritic-insensitive prefix and capped per category. Exif
+// values can be narrowed by country, state, make and model like Immich's
+// filters.
+func (q *Queries) GetSearchSuggestions(ctx context.Context, arg GetSearchSuggestionsParams) ([]GetSearchSuggestionsRow, error) {
+	rows, err := q.db.Query(ctx, getSearchSuggestions,
+		arg.MaxPerCategory,
+		arg.OwnerID,
+		arg.Country,
+		arg.State,
+		arg.Make,
+		arg.Model,
+		arg.Category,
+		arg.Prefix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSearchSuggestionsRow
+	for rows.Next() {
+		var i GetSearchSuggestionsRow
+		if err := rows.Scan(&i.Category, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getServerAssetStatistics = `-- name: GetServerAssetStatistics :one
 SELECT
     COUNT(CASE WHEN a.type = 'IMAGE' THEN 1 END)::bigint AS photos,
@@ -6296,9 +9254,10 @@ func (q *Queries) GetSharedLink(ctx context.Context, id pgtype.UUID) (SharedLink
 }
 
 const getSharedLinkAssets = `-- name: GetSharedLinkAssets :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 JOIN shared_link__asset sla ON a.id = sla."assetsId"
 WHERE sla."sharedLinksId" = $1 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY a."localDateTime" DESC
 `
 
@@ -6340,6 +9299,11 @@ func (q *Queries) GetSharedLinkAssets(ctx context.Context, sharedlinksid pgtype.
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -6352,10 +9316,12 @@ func (q *Queries) GetSharedLinkAssets(ctx context.Context, sharedlinksid pgtype.
 }
 
 const getSharedLinkByKey = `-- name: GetSharedLinkByKey :one
-SELECT id, description, "userId", key, type, "createdAt", "expiresAt", "allowUpload", "albumId", "allowDownload", "showExif", password FROM shared_links
-WHERE key = $1
+SELECT sl.id, sl.description, sl."userId", sl.key, sl.type, sl."createdAt", sl."expiresAt", sl."allowUpload", sl."albumId", sl."allowDownload", sl."showExif", sl.password FROM shared_links sl
+JOIN users u ON u.id = sl."userId" AND u."deletedAt" IS NULL
+WHERE sl.key = $1
 `
 
+// Links of deleted users stay in place but stop resolving.
 func (q *Queries) GetSharedLinkByKey(ctx context.Context, key []byte) (SharedLink, error) {
 	row := q.db.QueryRow(ctx, getSharedLinkByKey, key)
 	var i SharedLink
@@ -6376,49 +9342,10 @@ func (q *Queries) GetSharedLinkByKey(ctx context.Context, key []byte) (SharedLin
 	return i, err
 }
 
-const getSharedLinks = `-- name: GetSharedLinks :many
-SELECT id, description, "userId", key, type, "createdAt", "expiresAt", "allowUpload", "albumId", "allowDownload", "showExif", password FROM shared_links
-WHERE "userId" = $1
-ORDER BY "createdAt" DESC
-`
-
-func (q *Queries) GetSharedLinks(ctx context.Context, userid pgtype.UUID) ([]SharedLink, error) {
-	rows, err := q.db.Query(ctx, getSharedLinks, userid)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []SharedLink
-	for rows.Next() {
-		var i SharedLink
-		if err := rows.Scan(
-			&i.ID,
-			&i.Description,
-			&i.UserId,
-			&i.Key,
-			&i.Type,
-			&i.CreatedAt,
-			&i.ExpiresAt,
-			&i.AllowUpload,
-			&i.AlbumId,
-			&i.AllowDownload,
-			&i.ShowExif,
-			&i.Password,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const getSmartSearch = `-- name: GetSmartSearch :many
-
-SELECT "assetId", embedding FROM smart_search
-WHERE "assetId" = $1
+const getSmartSearch = `-- name: GetSmartSearch :many
+
+SELECT "assetId", embedding FROM smart_search
+WHERE "assetId" = $1
 `
 
 // ============================================================================
@@ -6457,7 +9384,7 @@ func (q *Queries) GetStack(ctx context.Context, id pgtype.UUID) (AssetStack, err
 }
 
 const getStackAssets = `-- name: GetStackAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "stackId" = $1 AND "deletedAt" IS NULL
 ORDER BY "localDateTime" DESC
 `
@@ -6500,6 +9427,11 @@ func (q *Queries) GetStackAssets(ctx context.Context, stackid pgtype.UUID) ([]As
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -6552,6 +9484,101 @@ func (q *Queries) GetStackWithAssets(ctx context.Context, id pgtype.UUID) (GetSt
 	return i, err
 }
 
+const getStorageMigrationFiles = `-- name: GetStorageMigrationFiles :many
+SELECT "fromBackend", "toBackend", "sourcePath", "destinationPath", size, checksum, status, "updatedAt" FROM storage_migration_files
+WHERE "fromBackend" = $1 AND "toBackend" = $2
+ORDER BY "sourcePath"
+`
+
+type GetStorageMigrationFilesParams struct {
+	FromBackend string
+	ToBackend   string
+}
+
+func (q *Queries) GetStorageMigrationFiles(ctx context.Context, arg GetStorageMigrationFilesParams) ([]StorageMigrationFile, error) {
+	rows, err := q.db.Query(ctx, getStorageMigrationFiles, arg.FromBackend, arg.ToBackend)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StorageMigrationFile
+	for rows.Next() {
+		var i StorageMigrationFile
+		if err := rows.Scan(
+			&i.FromBackend,
+			&i.ToBackend,
+			&i.SourcePath,
+			&i.DestinationPath,
+			&i.Size,
+			&i.Checksum,
+			&i.Status,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStorageMigrationSourcePaths = `-- name: GetStorageMigrationSourcePaths :many
+SELECT DISTINCT path
+FROM (
+    SELECT "originalPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "isExternal" = false
+
+    UNION
+
+    SELECT "encodedVideoPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "encodedVideoPath" IS NOT NULL
+
+    UNION
+
+    SELECT "sidecarPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "isExternal" = false
+    AND "sidecarPath" IS NOT NULL
+
+    UNION
+
+    SELECT af.path
+    FROM asset_files af
+    JOIN assets a ON a.id = af."assetId"
+    WHERE a.status != 'deleted'::assets_status_enum
+) tracked
+WHERE path != ''
+ORDER BY path
+`
+
+// Storage migration queries
+func (q *Queries) GetStorageMigrationSourcePaths(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, getStorageMigrationSourcePaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		items = append(items, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStorageUsageByUser = `-- name: GetStorageUsageByUser :one
 SELECT 
     a."ownerId",
@@ -6628,13 +9655,19 @@ func (q *Queries) GetTag(ctx context.Context, id pgtype.UUID) (Tag, error) {
 }
 
 const getTagAssets = `-- name: GetTagAssets :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
-JOIN tag_asset ta ON a.id = ta."assetsId"
-WHERE ta."tagsId" = $1 AND a."deletedAt" IS NULL
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
+WHERE a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND a.id IN (
+    SELECT ta."assetsId" FROM tag_asset ta
+    INNER JOIN tags_closure tc ON tc.id_descendant = ta."tagsId"
+    WHERE tc.id_ancestor = $1
+)
 `
 
-func (q *Queries) GetTagAssets(ctx context.Context, tagsid pgtype.UUID) ([]Asset, error) {
-	rows, err := q.db.Query(ctx, getTagAssets, tagsid)
+// Assets tagged with the tag or any of its descendants.
+func (q *Queries) GetTagAssets(ctx context.Context, idAncestor pgtype.UUID) ([]Asset, error) {
+	rows, err := q.db.Query(ctx, getTagAssets, idAncestor)
 	if err != nil {
 		return nil, err
 	}
@@ -6671,6 +9704,11 @@ func (q *Queries) GetTagAssets(ctx context.Context, tagsid pgtype.UUID) ([]Asset
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -6836,22 +9874,38 @@ LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
 AND a.visibility = 'timeline'
+AND NOT a."isOffline"
 AND ($6::bool = false AND a.status = 'active' OR $6::bool = true AND a.status = 'trashed')
 AND ($5::bool = false OR a."isFavorite" = true)
-AND ($2 = 'day' AND date_trunc('day', a."localDateTime")::date = $3::date
-     OR $2 = 'month' AND date_trunc('month', a."localDateTime")::date = $3::date
-     OR $2 = 'year' AND date_trunc('year', a."localDateTime")::date = $3::date)
-ORDER BY a."localDateTime" DESC
+AND ($7::bool AND a."isUndated"
+     OR NOT $7::bool AND NOT a."isUndated"
+     AND ($2 = 'day' AND date_trunc('day', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date
+          OR $2 = 'month' AND date_trunc('month', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date
+          OR $2 = 'year' AND date_trunc('year', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date))
+AND ($8::timestamptz IS NULL
+    OR ($10::bool
+        AND (a."localDateTime", a.id) > ($8::timestamptz, $9::uuid))
+    OR (NOT $10::bool
+        AND (a."localDateTime", a.id) < ($8::timestamptz, $9::uuid)))
+ORDER BY
+    CASE WHEN $10::bool THEN a."localDateTime" END ASC,
+    CASE WHEN $10::bool THEN a.id END ASC,
+    a."localDateTime" DESC,
+    a.id DESC
 LIMIT $4 OFFSET 0
 `
 
 type GetTimelineBucketAssetsParams struct {
-	OwnerId pgtype.UUID
-	Column2 interface{}
-	Column3 pgtype.Date
-	Limit   int32
-	Column5 bool
-	Column6 bool
+	OwnerId             pgtype.UUID
+	Column2             interface{}
+	Column3             pgtype.Date
+	Limit               int32
+	Column5             bool
+	Column6             bool
+	Undated             bool
+	CursorLocalDateTime pgtype.Timestamptz
+	CursorID            pgtype.UUID
+	Ascending           bool
 }
 
 type GetTimelineBucketAssetsRow struct {
@@ -6883,6 +9937,8 @@ type GetTimelineBucketAssetsRow struct {
 	Thumbhash        interface{}
 }
 
+// The assets of a bucket, newest first unless ascending, after the capture
+// time and id of the last asset of the previous page.
 func (q *Queries) GetTimelineBucketAssets(ctx context.Context, arg GetTimelineBucketAssetsParams) ([]GetTimelineBucketAssetsRow, error) {
 	rows, err := q.db.Query(ctx, getTimelineBucketAssets,
 		arg.OwnerId,
@@ -6891,6 +9947,10 @@ func (q *Queries) GetTimelineBucketAssets(ctx context.Context, arg GetTimelineBu
 		arg.Limit,
 		arg.Column5,
 		arg.Column6,
+		arg.Undated,
+		arg.CursorLocalDateTime,
+		arg.CursorID,
+		arg.Ascending,
 	)
 	if err != nil {
 		return nil, err
@@ -6940,16 +10000,19 @@ func (q *Queries) GetTimelineBucketAssets(ctx context.Context, arg GetTimelineBu
 const getTimelineBuckets = `-- name: GetTimelineBuckets :many
 
 SELECT
-    date_trunc($2, "localDateTime")::date as time_bucket,
+    CASE WHEN "isUndated" THEN NULL ELSE date_trunc($2, "localDateTime" AT TIME ZONE 'UTC')::date END as time_bucket,
     COUNT(*) as count
 FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
 AND visibility = 'timeline'
+AND NOT "isOffline"
 AND ($4::bool = false AND status = 'active' OR $4::bool = true AND status = 'trashed')
 AND ($3::bool = false OR "isFavorite" = true)
 GROUP BY time_bucket
-ORDER BY time_bucket DESC
+ORDER BY
+    CASE WHEN $5::bool THEN time_bucket END ASC NULLS LAST,
+    time_bucket DESC NULLS LAST
 `
 
 type GetTimelineBucketsParams struct {
@@ -6957,6 +10020,7 @@ type GetTimelineBucketsParams struct {
 	DateTrunc string
 	Column3   bool
 	Column4   bool
+	Ascending bool
 }
 
 type GetTimelineBucketsRow struct {
@@ -6967,12 +10031,17 @@ type GetTimelineBucketsRow struct {
 // ============================================================================
 // TIMELINE & STATISTICS QUERIES
 // ============================================================================
+// Newest first unless ascending. Undated assets form one bucket with a
+// NULL date, after every dated one.
+// "localDateTime" holds the capture wall clock as UTC, so it is truncated in
+// UTC whatever the session timezone is.
 func (q *Queries) GetTimelineBuckets(ctx context.Context, arg GetTimelineBucketsParams) ([]GetTimelineBucketsRow, error) {
 	rows, err := q.db.Query(ctx, getTimelineBuckets,
 		arg.OwnerId,
 		arg.DateTrunc,
 		arg.Column3,
 		arg.Column4,
+		arg.Ascending,
 	)
 	if err != nil {
 		return nil, err
@@ -6992,6 +10061,149 @@ func (q *Queries) GetTimelineBuckets(ctx context.Context, arg GetTimelineBuckets
 	return items, nil
 }
 
+const getTimelineDayAssets = `-- name: GetTimelineDayAssets :many
+SELECT day, id, type, "localDateTime", thumbhash, ratio, "exifImageWidth", "exifImageHeight" FROM (
+    SELECT
+        CASE WHEN a."isUndated" THEN DATE '0001-01-01' ELSE (a."localDateTime" AT TIME ZONE 'UTC')::date END AS day,
+        a.id,
+        a.type,
+        a."localDateTime",
+        encode(a.thumbhash, 'base64') AS thumbhash,
+        r.ratio,
+        e."exifImageWidth",
+        e."exifImageHeight"
+    FROM assets a
+    LEFT JOIN asset_thumbnail_ratios r ON r."assetId" = a.id
+    LEFT JOIN exif e ON e."assetId" = a.id
+    WHERE a."ownerId" = $1
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND NOT a."isOffline"
+    AND (NOT $2::bool AND a.status = 'active' OR $2::bool AND a.status = 'trashed')
+    AND (NOT $3::bool OR a."isFavorite")
+) day_assets
+WHERE day BETWEEN $4::date AND $5::date
+ORDER BY day DESC, "localDateTime" DESC, id DESC
+`
+
+type GetTimelineDayAssetsParams struct {
+	OwnerID    pgtype.UUID
+	IsTrashed  bool
+	IsFavorite bool
+	Oldest     pgtype.Date
+	Newest     pgtype.Date
+}
+
+type GetTimelineDayAssetsRow struct {
+	Day             pgtype.Date
+	ID              pgtype.UUID
+	Type            string
+	LocalDateTime   pgtype.Timestamptz
+	Thumbhash       pgtype.Text
+	Ratio           pgtype.Float8
+	ExifImageWidth  pgtype.Int4
+	ExifImageHeight pgtype.Int4
+}
+
+// The grid layout fields of the timeline assets of the days from oldest to
+// newest, days as returned by GetTimelineDays. ratio is NULL until the
+// thumbnails of the asset are generated.
+func (q *Queries) GetTimelineDayAssets(ctx context.Context, arg GetTimelineDayAssetsParams) ([]GetTimelineDayAssetsRow, error) {
+	rows, err := q.db.Query(ctx, getTimelineDayAssets,
+		arg.OwnerID,
+		arg.IsTrashed,
+		arg.IsFavorite,
+		arg.Oldest,
+		arg.Newest,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTimelineDayAssetsRow
+	for rows.Next() {
+		var i GetTimelineDayAssetsRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.ID,
+			&i.Type,
+			&i.LocalDateTime,
+			&i.Thumbhash,
+			&i.Ratio,
+			&i.ExifImageWidth,
+			&i.ExifImageHeight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelineDays = `-- name: GetTimelineDays :many
+SELECT day, count FROM (
+    SELECT
+        CASE WHEN "isUndated" THEN DATE '0001-01-01' ELSE ("localDateTime" AT TIME ZONE 'UTC')::date END AS day,
+        COUNT(*) AS count
+    FROM assets
+    WHERE "ownerId" = $1
+    AND "deletedAt" IS NULL
+    AND visibility = 'timeline'
+    AND NOT "isOffline"
+    AND (NOT $2::bool AND status = 'active' OR $2::bool AND status = 'trashed')
+    AND (NOT $3::bool OR "isFavorite")
+    GROUP BY 1
+) days
+WHERE $4::date IS NULL OR day < $4::date
+ORDER BY day DESC
+LIMIT $5
+`
+
+type GetTimelineDaysParams struct {
+	OwnerID    pgtype.UUID
+	IsTrashed  bool
+	IsFavorite bool
+	Before     pgtype.Date
+	DayLimit   int32
+}
+
+type GetTimelineDaysRow struct {
+	Day   pgtype.Date
+	Count int64
+}
+
+// The days of the timeline with their asset count, newest first, before the
+// cursor day when one is given. Undated assets form the day 0001-01-01,
+// which sorts after every dated one.
+func (q *Queries) GetTimelineDays(ctx context.Context, arg GetTimelineDaysParams) ([]GetTimelineDaysRow, error) {
+	rows, err := q.db.Query(ctx, getTimelineDays,
+		arg.OwnerID,
+		arg.IsTrashed,
+		arg.IsFavorite,
+		arg.Before,
+		arg.DayLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTimelineDaysRow
+	for rows.Next() {
+		var i GetTimelineDaysRow
+		if err := rows.Scan(&i.Day, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getTopPeople = `-- name: GetTopPeople :many
 SELECT p.id, p."createdAt", p."updatedAt", p."ownerId", p.name, p."thumbnailPath", p."isHidden", p."birthDate", p."faceAssetId", p."isFavorite", p.color, p."updateId", COUNT(f."personId") as face_count
 FROM person p
@@ -7067,6 +10279,7 @@ SELECT
 FROM exif e
 INNER JOIN assets a ON e."assetId" = a.id
 WHERE a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.city IS NOT NULL
 GROUP BY e.city, e.state, e.country
 ORDER BY asset_count DESC
@@ -7107,11 +10320,14 @@ func (q *Queries) GetTopPlaces(ctx context.Context, limit int32) ([]GetTopPlaces
 }
 
 const getTrashedAssets = `-- name: GetTrashedAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
-WHERE "ownerId" = $1 
-AND "deletedAt" IS NULL
-AND status = 'trashed'
-ORDER BY "updatedAt" DESC
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash, t."trashedAt"
+FROM assets a
+INNER JOIN asset_trash t ON t."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."deletedAt" IS NULL
+AND a.status = 'trashed'
+AND a.visibility <> 'locked'
+ORDER BY t."trashedAt" DESC, a.id DESC
 LIMIT $2 OFFSET $3
 `
 
@@ -7121,44 +10337,57 @@ type GetTrashedAssetsParams struct {
 	Offset  int32
 }
 
-func (q *Queries) GetTrashedAssets(ctx context.Context, arg GetTrashedAssetsParams) ([]Asset, error) {
+type GetTrashedAssetsRow struct {
+	Asset     Asset
+	TrashedAt pgtype.Timestamptz
+}
+
+// The assets in the trash with the time they were moved there, most
+// recently trashed first.
+func (q *Queries) GetTrashedAssets(ctx context.Context, arg GetTrashedAssetsParams) ([]GetTrashedAssetsRow, error) {
 	rows, err := q.db.Query(ctx, getTrashedAssets, arg.OwnerId, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Asset
+	var items []GetTrashedAssetsRow
 	for rows.Next() {
-		var i Asset
+		var i GetTrashedAssetsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.DeviceAssetId,
-			&i.OwnerId,
-			&i.DeviceId,
-			&i.Type,
-			&i.OriginalPath,
-			&i.FileCreatedAt,
-			&i.FileModifiedAt,
-			&i.IsFavorite,
-			&i.Duration,
-			&i.EncodedVideoPath,
-			&i.Checksum,
-			&i.LivePhotoVideoId,
-			&i.UpdatedAt,
-			&i.CreatedAt,
-			&i.OriginalFileName,
-			&i.SidecarPath,
-			&i.Thumbhash,
-			&i.IsOffline,
-			&i.LibraryId,
-			&i.IsExternal,
-			&i.DeletedAt,
-			&i.LocalDateTime,
-			&i.StackId,
-			&i.DuplicateId,
-			&i.Status,
-			&i.UpdateId,
-			&i.Visibility,
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+			&i.TrashedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -7172,10 +10401,11 @@ func (q *Queries) GetTrashedAssets(ctx context.Context, arg GetTrashedAssetsPara
 
 const getTrashedAssetsByUser = `-- name: GetTrashedAssetsByUser :many
 
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND status = 'trashed'
+AND visibility <> 'locked'
 ORDER BY "updatedAt" DESC
 `
 
@@ -7220,6 +10450,11 @@ func (q *Queries) GetTrashedAssetsByUser(ctx context.Context, ownerid pgtype.UUI
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -7245,6 +10480,7 @@ SELECT DISTINCT
 FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 ORDER BY path_prefix
 `
 
@@ -7268,62 +10504,184 @@ func (q *Queries) GetUniqueOriginalPathPrefixes(ctx context.Context, ownerid pgt
 	return items, nil
 }
 
-const getUser = `-- name: GetUser :one
-SELECT id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded" FROM users
-WHERE id = $1 AND "deletedAt" IS NULL
+const getUploadSession = `-- name: GetUploadSession :one
+SELECT "assetId", "ownerId", "totalSize", checksum, "checksumAlgorithm", "createdAt" FROM upload_sessions
+WHERE "assetId" = $1 AND "ownerId" = $2
 `
 
-// User queries
-func (q *Queries) GetUser(ctx context.Context, id pgtype.UUID) (User, error) {
-	row := q.db.QueryRow(ctx, getUser, id)
-	var i User
+type GetUploadSessionParams struct {
+	AssetId pgtype.UUID
+	OwnerId pgtype.UUID
+}
+
+func (q *Queries) GetUploadSession(ctx context.Context, arg GetUploadSessionParams) (UploadSession, error) {
+	row := q.db.QueryRow(ctx, getUploadSession, arg.AssetId, arg.OwnerId)
+	var i UploadSession
 	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.Password,
+		&i.AssetId,
+		&i.OwnerId,
+		&i.TotalSize,
+		&i.Checksum,
+		&i.ChecksumAlgorithm,
 		&i.CreatedAt,
-		&i.ProfileImagePath,
-		&i.IsAdmin,
-		&i.ShouldChangePassword,
-		&i.DeletedAt,
-		&i.OauthId,
-		&i.UpdatedAt,
-		&i.StorageLabel,
-		&i.Name,
-		&i.QuotaSizeInBytes,
-		&i.QuotaUsageInBytes,
-		&i.Status,
-		&i.ProfileChangedAt,
-		&i.UpdateId,
-		&i.AvatarColor,
-		&i.PinCode,
-		&i.IsOnboarded,
 	)
 	return i, err
 }
 
-const getUserAssets = `-- name: GetUserAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL
-AND ($2::assets_status_enum IS NULL OR status = $2::assets_status_enum)
-ORDER BY "fileCreatedAt" DESC
-LIMIT $4
-OFFSET $3
+const getUploadSessionChunks = `-- name: GetUploadSessionChunks :many
+SELECT "assetId", start, size, "receivedAt" FROM upload_session_chunks
+WHERE "assetId" = $1
+ORDER BY start
 `
 
-type GetUserAssetsParams struct {
-	OwnerId pgtype.UUID
-	Status  NullAssetsStatusEnum
-	Offset  pgtype.Int4
-	Limit   pgtype.Int4
-}
-
-func (q *Queries) GetUserAssets(ctx context.Context, arg GetUserAssetsParams) ([]Asset, error) {
-	rows, err := q.db.Query(ctx, getUserAssets,
-		arg.OwnerId,
+func (q *Queries) GetUploadSessionChunks(ctx context.Context, assetid pgtype.UUID) ([]UploadSessionChunk, error) {
+	rows, err := q.db.Query(ctx, getUploadSessionChunks, assetid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UploadSessionChunk
+	for rows.Next() {
+		var i UploadSessionChunk
+		if err := rows.Scan(
+			&i.AssetId,
+			&i.Start,
+			&i.Size,
+			&i.ReceivedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUploadedDeviceAsset = `-- name: GetUploadedDeviceAsset :one
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1 AND "deviceId" = $2 AND "deviceAssetId" = $3 AND "libraryId" IS NULL
+`
+
+type GetUploadedDeviceAssetParams struct {
+	OwnerId       pgtype.UUID
+	DeviceId      string
+	DeviceAssetId string
+}
+
+// The uploaded asset of a device asset, trashed ones included.
+func (q *Queries) GetUploadedDeviceAsset(ctx context.Context, arg GetUploadedDeviceAssetParams) (Asset, error) {
+	row := q.db.QueryRow(ctx, getUploadedDeviceAsset, arg.OwnerId, arg.DeviceId, arg.DeviceAssetId)
+	var i Asset
+	err := row.Scan(
+		&i.ID,
+		&i.DeviceAssetId,
+		&i.OwnerId,
+		&i.DeviceId,
+		&i.Type,
+		&i.OriginalPath,
+		&i.FileCreatedAt,
+		&i.FileModifiedAt,
+		&i.IsFavorite,
+		&i.Duration,
+		&i.EncodedVideoPath,
+		&i.Checksum,
+		&i.LivePhotoVideoId,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.OriginalFileName,
+		&i.SidecarPath,
+		&i.Thumbhash,
+		&i.IsOffline,
+		&i.LibraryId,
+		&i.IsExternal,
+		&i.DeletedAt,
+		&i.LocalDateTime,
+		&i.StackId,
+		&i.DuplicateId,
+		&i.Status,
+		&i.UpdateId,
+		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded" FROM users
+WHERE id = $1 AND "deletedAt" IS NULL
+`
+
+// User queries
+func (q *Queries) GetUser(ctx context.Context, id pgtype.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.ProfileImagePath,
+		&i.IsAdmin,
+		&i.ShouldChangePassword,
+		&i.DeletedAt,
+		&i.OauthId,
+		&i.UpdatedAt,
+		&i.StorageLabel,
+		&i.Name,
+		&i.QuotaSizeInBytes,
+		&i.QuotaUsageInBytes,
+		&i.Status,
+		&i.ProfileChangedAt,
+		&i.UpdateId,
+		&i.AvatarColor,
+		&i.PinCode,
+		&i.IsOnboarded,
+	)
+	return i, err
+}
+
+const getUserAssets = `-- name: GetUserAssets :many
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
+AND ($2::assets_status_enum IS NULL OR status = $2::assets_status_enum)
+ORDER BY
+    CASE WHEN $5::text = 'created_at' AND NOT $6::boolean THEN "createdAt" END ASC,
+    CASE WHEN $5::text = 'created_at' AND $6::boolean THEN "createdAt" END DESC,
+    CASE WHEN $5::text = 'filename' AND NOT $6::boolean THEN lower("originalFileName") END ASC,
+    CASE WHEN $5::text = 'filename' AND $6::boolean THEN lower("originalFileName") END DESC,
+    CASE WHEN NOT $6::boolean THEN "fileCreatedAt" END ASC,
+    CASE WHEN $6::boolean THEN "fileCreatedAt" END DESC,
+    CASE WHEN NOT $6::boolean THEN id END ASC,
+    id DESC
+LIMIT $4
+OFFSET $3
+`
+
+type GetUserAssetsParams struct {
+	OwnerId    pgtype.UUID
+	Status     NullAssetsStatusEnum
+	Offset     pgtype.Int4
+	Limit      pgtype.Int4
+	SortBy     string
+	Descending bool
+}
+
+// sort_by is "date_taken", "created_at" or "filename". Assets sorting the
+// same are ordered by id so offset pages neither skip nor repeat them.
+func (q *Queries) GetUserAssets(ctx context.Context, arg GetUserAssetsParams) ([]Asset, error) {
+	rows, err := q.db.Query(ctx, getUserAssets,
+		arg.OwnerId,
 		arg.Status,
 		arg.Offset,
 		arg.Limit,
+		arg.SortBy,
+		arg.Descending,
 	)
 	if err != nil {
 		return nil, err
@@ -7361,6 +10719,11 @@ func (q *Queries) GetUserAssets(ctx context.Context, arg GetUserAssetsParams) ([
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -7471,6 +10834,185 @@ func (q *Queries) GetUserByOAuthId(ctx context.Context, oauthid string) (User, e
 	return i, err
 }
 
+const getUserDeletionSchedule = `-- name: GetUserDeletionSchedule :one
+SELECT "userId", "deleteAt", "restoreTokenHash", "createdAt" FROM user_deletion_schedules
+WHERE "userId" = $1
+`
+
+func (q *Queries) GetUserDeletionSchedule(ctx context.Context, userid pgtype.UUID) (UserDeletionSchedule, error) {
+	row := q.db.QueryRow(ctx, getUserDeletionSchedule, userid)
+	var i UserDeletionSchedule
+	err := row.Scan(
+		&i.UserId,
+		&i.DeleteAt,
+		&i.RestoreTokenHash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserDeletionScheduleByRestoreToken = `-- name: GetUserDeletionScheduleByRestoreToken :one
+SELECT "userId", "deleteAt", "restoreTokenHash", "createdAt" FROM user_deletion_schedules
+WHERE "restoreTokenHash" = $1 AND "deleteAt" > now()
+`
+
+// Only schedules whose grace period has not ended can be restored.
+func (q *Queries) GetUserDeletionScheduleByRestoreToken(ctx context.Context, restoretokenhash string) (UserDeletionSchedule, error) {
+	row := q.db.QueryRow(ctx, getUserDeletionScheduleByRestoreToken, restoretokenhash)
+	var i UserDeletionSchedule
+	err := row.Scan(
+		&i.UserId,
+		&i.DeleteAt,
+		&i.RestoreTokenHash,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserDeletionSummary = `-- name: GetUserDeletionSummary :one
+SELECT
+    (SELECT COUNT(*) FROM assets a WHERE a."ownerId" = $1) AS asset_count,
+    (SELECT COALESCE(SUM(e."fileSizeInByte"), 0) FROM assets a
+        JOIN exif e ON e."assetId" = a.id
+        WHERE a."ownerId" = $1)::bigint AS total_size_bytes,
+    (SELECT COUNT(*) FROM albums al WHERE al."ownerId" = $1) AS album_count,
+    (SELECT COUNT(*) FROM shared_links sl WHERE sl."userId" = $1) AS shared_link_count
+`
+
+type GetUserDeletionSummaryRow struct {
+	AssetCount      int64
+	TotalSizeBytes  int64
+	AlbumCount      int64
+	SharedLinkCount int64
+}
+
+func (q *Queries) GetUserDeletionSummary(ctx context.Context, userID pgtype.UUID) (GetUserDeletionSummaryRow, error) {
+	row := q.db.QueryRow(ctx, getUserDeletionSummary, userID)
+	var i GetUserDeletionSummaryRow
+	err := row.Scan(
+		&i.AssetCount,
+		&i.TotalSizeBytes,
+		&i.AlbumCount,
+		&i.SharedLinkCount,
+	)
+	return i, err
+}
+
+const getUserIncludingDeleted = `-- name: GetUserIncludingDeleted :one
+SELECT id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded" FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserIncludingDeleted(ctx context.Context, id pgtype.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserIncludingDeleted, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.ProfileImagePath,
+		&i.IsAdmin,
+		&i.ShouldChangePassword,
+		&i.DeletedAt,
+		&i.OauthId,
+		&i.UpdatedAt,
+		&i.StorageLabel,
+		&i.Name,
+		&i.QuotaSizeInBytes,
+		&i.QuotaUsageInBytes,
+		&i.Status,
+		&i.ProfileChangedAt,
+		&i.UpdateId,
+		&i.AvatarColor,
+		&i.PinCode,
+		&i.IsOnboarded,
+	)
+	return i, err
+}
+
+const getUserLargestAssets = `-- name: GetUserLargestAssets :many
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash, e."fileSizeInByte"::bigint AS size
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND e."fileSizeInByte" IS NOT NULL
+ORDER BY e."fileSizeInByte" DESC, a.id DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetUserLargestAssetsParams struct {
+	OwnerId pgtype.UUID
+	Limit   int32
+	Offset  int32
+}
+
+type GetUserLargestAssetsRow struct {
+	Asset Asset
+	Size  int64
+}
+
+// A user's assets counting towards quota usage, largest first. Assets of
+// the locked folder are left out, like in every other listing.
+func (q *Queries) GetUserLargestAssets(ctx context.Context, arg GetUserLargestAssetsParams) ([]GetUserLargestAssetsRow, error) {
+	rows, err := q.db.Query(ctx, getUserLargestAssets, arg.OwnerId, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserLargestAssetsRow
+	for rows.Next() {
+		var i GetUserLargestAssetsRow
+		if err := rows.Scan(
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+			&i.Size,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserLicenseData = `-- name: GetUserLicenseData :one
 SELECT value FROM user_metadata
 WHERE "userId" = $1 AND key = 'license'
@@ -7483,6 +11025,23 @@ func (q *Queries) GetUserLicenseData(ctx context.Context, userid pgtype.UUID) ([
 	return value, err
 }
 
+const getUserLimits = `-- name: GetUserLimits :one
+SELECT "userId", "maxSharedLinks", "maxApiKeys", "updatedAt" FROM user_limits
+WHERE "userId" = $1
+`
+
+func (q *Queries) GetUserLimits(ctx context.Context, userid pgtype.UUID) (UserLimit, error) {
+	row := q.db.QueryRow(ctx, getUserLimits, userid)
+	var i UserLimit
+	err := row.Scan(
+		&i.UserId,
+		&i.MaxSharedLinks,
+		&i.MaxApiKeys,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getUserMetadata = `-- name: GetUserMetadata :one
 SELECT "userId", key, value FROM user_metadata
 WHERE "userId" = $1 AND key = $2
@@ -7576,6 +11135,37 @@ func (q *Queries) GetUserPreferencesData(ctx context.Context, userid pgtype.UUID
 	return value, err
 }
 
+const getUserQuotaUsage = `-- name: GetUserQuotaUsage :one
+SELECT
+    u."quotaUsageInBytes" AS stored,
+    COALESCE((
+        SELECT SUM(e."fileSizeInByte")
+        FROM assets a
+        JOIN exif e ON e."assetId" = a.id
+        WHERE a."ownerId" = u.id
+        AND a."libraryId" IS NULL
+        AND a.status = 'active'
+        AND a."deletedAt" IS NULL
+    ), 0)::bigint AS actual
+FROM users u
+WHERE u.id = $1
+`
+
+type GetUserQuotaUsageRow struct {
+	Stored int64
+	Actual int64
+}
+
+// The stored quota usage of a user and the size of the originals it
+// accounts for: the user's uploads outside the trash. Files of external
+// libraries are read in place and use no storage.
+func (q *Queries) GetUserQuotaUsage(ctx context.Context, id pgtype.UUID) (GetUserQuotaUsageRow, error) {
+	row := q.db.QueryRow(ctx, getUserQuotaUsage, id)
+	var i GetUserQuotaUsageRow
+	err := row.Scan(&i.Stored, &i.Actual)
+	return i, err
+}
+
 const getUserRecentViews = `-- name: GetUserRecentViews :many
 SELECT DISTINCT ON (asset_id)
     asset_id,
@@ -7707,43 +11297,36 @@ func (q *Queries) GetUserStacks(ctx context.Context, arg GetUserStacksParams) ([
 	return items, nil
 }
 
-const getUsers = `-- name: GetUsers :many
-SELECT id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded" FROM users
-WHERE "deletedAt" IS NULL
-ORDER BY "createdAt" DESC
+const getUserStorageByType = `-- name: GetUserStorageByType :many
+SELECT a.type, COUNT(*)::bigint AS assets, COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS bytes
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+GROUP BY a.type
+ORDER BY bytes DESC, a.type
 `
 
-func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.Query(ctx, getUsers)
+type GetUserStorageByTypeRow struct {
+	Type   string
+	Assets int64
+	Bytes  int64
+}
+
+// The size of a user's originals by asset type, counting what quota usage
+// counts: uploads outside the trash.
+func (q *Queries) GetUserStorageByType(ctx context.Context, ownerid pgtype.UUID) ([]GetUserStorageByTypeRow, error) {
+	rows, err := q.db.Query(ctx, getUserStorageByType, ownerid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []GetUserStorageByTypeRow
 	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.Email,
-			&i.Password,
-			&i.CreatedAt,
-			&i.ProfileImagePath,
-			&i.IsAdmin,
-			&i.ShouldChangePassword,
-			&i.DeletedAt,
-			&i.OauthId,
-			&i.UpdatedAt,
-			&i.StorageLabel,
-			&i.Name,
-			&i.QuotaSizeInBytes,
-			&i.QuotaUsageInBytes,
-			&i.Status,
-			&i.ProfileChangedAt,
-			&i.UpdateId,
-			&i.AvatarColor,
-			&i.PinCode,
-			&i.IsOnboarded,
-		); err != nil {
+		var i GetUserStorageByTypeRow
+		if err := rows.Scan(&i.Type, &i.Assets, &i.Bytes); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -7754,12 +11337,127 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const getWorkflowByID = `-- name: GetWorkflowByID :one
-SELECT id, "ownerId", name, description, enabled, status, trigger, actions, "executionCount", "lastExecutionAt", "createdAt", "updatedAt" FROM workflows
-WHERE id = $1
+const getUserStorageByYear = `-- name: GetUserStorageByYear :many
+SELECT
+    (CASE WHEN a."isUndated" THEN NULL ELSE EXTRACT(YEAR FROM a."localDateTime" AT TIME ZONE 'UTC') END)::int AS year,
+    COUNT(*)::bigint AS assets,
+    COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS bytes
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+GROUP BY 1
+ORDER BY 1 DESC NULLS LAST
 `
 
-func (q *Queries) GetWorkflowByID(ctx context.Context, id pgtype.UUID) (Workflow, error) {
+type GetUserStorageByYearRow struct {
+	Year   pgtype.Int4
+	Assets int64
+	Bytes  int64
+}
+
+// The size of a user's originals by the year they were taken, newest
+// first. Undated assets come last, with no year.
+func (q *Queries) GetUserStorageByYear(ctx context.Context, ownerid pgtype.UUID) ([]GetUserStorageByYearRow, error) {
+	rows, err := q.db.Query(ctx, getUserStorageByYear, ownerid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserStorageByYearRow
+	for rows.Next() {
+		var i GetUserStorageByYearRow
+		if err := rows.Scan(&i.Year, &i.Assets, &i.Bytes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsers = `-- name: GetUsers :many
+SELECT id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded" FROM users
+WHERE "deletedAt" IS NULL
+ORDER BY "createdAt" DESC
+`
+
+func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Password,
+			&i.CreatedAt,
+			&i.ProfileImagePath,
+			&i.IsAdmin,
+			&i.ShouldChangePassword,
+			&i.DeletedAt,
+			&i.OauthId,
+			&i.UpdatedAt,
+			&i.StorageLabel,
+			&i.Name,
+			&i.QuotaSizeInBytes,
+			&i.QuotaUsageInBytes,
+			&i.Status,
+			&i.ProfileChangedAt,
+			&i.UpdateId,
+			&i.AvatarColor,
+			&i.PinCode,
+			&i.IsOnboarded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, url, events, secret, enabled, "createdAt", "updatedAt", "lastDeliveryAt", "lastSuccessAt", "lastStatusCode", "lastError", "failureCount" FROM webhook_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, id pgtype.UUID) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, getWebhookSubscription, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Events,
+		&i.Secret,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastDeliveryAt,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.FailureCount,
+	)
+	return i, err
+}
+
+const getWorkflowByID = `-- name: GetWorkflowByID :one
+SELECT id, "ownerId", name, description, enabled, status, trigger, actions, "executionCount", "lastExecutionAt", "createdAt", "updatedAt" FROM workflows
+WHERE id = $1
+`
+
+func (q *Queries) GetWorkflowByID(ctx context.Context, id pgtype.UUID) (Workflow, error) {
 	row := q.db.QueryRow(ctx, getWorkflowByID, id)
 	var i Workflow
 	err := row.Scan(
@@ -7832,6 +11530,25 @@ func (q *Queries) IncrementWorkflowExecutionCount(ctx context.Context, id pgtype
 	return i, err
 }
 
+const isAssetInAlbum = `-- name: IsAssetInAlbum :one
+SELECT EXISTS(
+    SELECT 1 FROM albums_assets_assets
+    WHERE "albumsId" = $1 AND "assetsId" = $2
+) AS in_album
+`
+
+type IsAssetInAlbumParams struct {
+	AlbumsId pgtype.UUID
+	AssetsId pgtype.UUID
+}
+
+func (q *Queries) IsAssetInAlbum(ctx context.Context, arg IsAssetInAlbumParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isAssetInAlbum, arg.AlbumsId, arg.AssetsId)
+	var in_album bool
+	err := row.Scan(&in_album)
+	return in_album, err
+}
+
 const isSessionElevated = `-- name: IsSessionElevated :one
 SELECT
     CASE
@@ -7850,32 +11567,595 @@ func (q *Queries) IsSessionElevated(ctx context.Context, id pgtype.UUID) (bool,
 	return is_elevated, err
 }
 
-const listAssetMetadata = `-- name: ListAssetMetadata :many
-
-SELECT "assetId", key, value, "createdAt", "updatedAt" FROM asset_metadata
-WHERE "assetId" = $1
-ORDER BY key ASC
-`
+const libraryHasAssets = `-- name: LibraryHasAssets :one
+SELECT EXISTS(
+    SELECT 1 FROM assets
+    WHERE "libraryId" = $1
+) AS has_assets
+`
+
+func (q *Queries) LibraryHasAssets(ctx context.Context, libraryid pgtype.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, libraryHasAssets, libraryid)
+	var has_assets bool
+	err := row.Scan(&has_assets)
+	return has_assets, err
+}
+
+const listAssetMetadata = `-- name: ListAssetMetadata :many
+
+SELECT "assetId", key, value, "createdAt", "updatedAt" FROM asset_metadata
+WHERE "assetId" = $1
+ORDER BY key ASC
+`
+
+// ============================================================================
+// ASSET METADATA, EDITS, OCR, AND COPY HELPERS
+// ============================================================================
+func (q *Queries) ListAssetMetadata(ctx context.Context, assetid pgtype.UUID) ([]AssetMetadatum, error) {
+	rows, err := q.db.Query(ctx, listAssetMetadata, assetid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AssetMetadatum
+	for rows.Next() {
+		var i AssetMetadatum
+		if err := rows.Scan(
+			&i.AssetId,
+			&i.Key,
+			&i.Value,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssetSharesByUser = `-- name: ListAssetSharesByUser :many
+SELECT s."assetId", s."sharedWithId", s."createdAt", u.name, u.email
+FROM asset_user_shares s
+JOIN users u ON u.id = s."sharedWithId"
+WHERE s."sharedById" = $1
+ORDER BY s."createdAt" DESC
+`
+
+type ListAssetSharesByUserRow struct {
+	AssetId      pgtype.UUID
+	SharedWithId pgtype.UUID
+	CreatedAt    pgtype.Timestamptz
+	Name         string
+	Email        string
+}
+
+func (q *Queries) ListAssetSharesByUser(ctx context.Context, sharedbyid pgtype.UUID) ([]ListAssetSharesByUserRow, error) {
+	rows, err := q.db.Query(ctx, listAssetSharesByUser, sharedbyid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAssetSharesByUserRow
+	for rows.Next() {
+		var i ListAssetSharesByUserRow
+		if err := rows.Scan(
+			&i.AssetId,
+			&i.SharedWithId,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssetSharesWithUser = `-- name: ListAssetSharesWithUser :many
+SELECT s."assetId", s."sharedById", s."createdAt", u.name, u.email
+FROM asset_user_shares s
+JOIN users u ON u.id = s."sharedById"
+WHERE s."sharedWithId" = $1
+ORDER BY s."createdAt" DESC
+`
+
+type ListAssetSharesWithUserRow struct {
+	AssetId    pgtype.UUID
+	SharedById pgtype.UUID
+	CreatedAt  pgtype.Timestamptz
+	Name       string
+	Email      string
+}
+
+func (q *Queries) ListAssetSharesWithUser(ctx context.Context, sharedwithid pgtype.UUID) ([]ListAssetSharesWithUserRow, error) {
+	rows, err := q.db.Query(ctx, listAssetSharesWithUser, sharedwithid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAssetSharesWithUserRow
+	for rows.Next() {
+		var i ListAssetSharesWithUserRow
+		if err := rows.Scan(
+			&i.AssetId,
+			&i.SharedById,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssetsMissingThumbnailLayout = `-- name: ListAssetsMissingThumbnailLayout :many
+SELECT a.id, f.path
+FROM assets a
+JOIN asset_files f ON f."assetId" = a.id AND f.type = 'thumb'
+LEFT JOIN asset_thumbnail_ratios r ON r."assetId" = a.id
+WHERE a."deletedAt" IS NULL
+AND (a.thumbhash IS NULL OR r."assetId" IS NULL)
+AND a.id > $1
+ORDER BY a.id
+LIMIT $2
+`
+
+type ListAssetsMissingThumbnailLayoutParams struct {
+	ID    pgtype.UUID
+	Limit int32
+}
+
+type ListAssetsMissingThumbnailLayoutRow struct {
+	ID   pgtype.UUID
+	Path string
+}
+
+// Assets with a small thumbnail but no recorded thumbnail layout, in id
+// order after the given asset.
+func (q *Queries) ListAssetsMissingThumbnailLayout(ctx context.Context, arg ListAssetsMissingThumbnailLayoutParams) ([]ListAssetsMissingThumbnailLayoutRow, error) {
+	rows, err := q.db.Query(ctx, listAssetsMissingThumbnailLayout, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAssetsMissingThumbnailLayoutRow
+	for rows.Next() {
+		var i ListAssetsMissingThumbnailLayoutRow
+		if err := rows.Scan(&i.ID, &i.Path); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDatabaseBackups = `-- name: ListDatabaseBackups :many
+
+SELECT filename, path, filesize, timezone, "createdAt", "updatedAt" FROM database_backups
+ORDER BY "createdAt" DESC
+`
+
+// ============================================================================
+// DATABASE BACKUP QUERIES
+// ============================================================================
+func (q *Queries) ListDatabaseBackups(ctx context.Context) ([]DatabaseBackup, error) {
+	rows, err := q.db.Query(ctx, listDatabaseBackups)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DatabaseBackup
+	for rows.Next() {
+		var i DatabaseBackup
+		if err := rows.Scan(
+			&i.Filename,
+			&i.Path,
+			&i.Filesize,
+			&i.Timezone,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueUserDeletions = `-- name: ListDueUserDeletions :many
+SELECT "userId" FROM user_deletion_schedules
+WHERE "deleteAt" <= now()
+ORDER BY "deleteAt"
+`
+
+func (q *Queries) ListDueUserDeletions(ctx context.Context) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listDueUserDeletions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var userId pgtype.UUID
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+		items = append(items, userId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGeotaggedExifPlaces = `-- name: ListGeotaggedExifPlaces :many
+SELECT e."assetId", e.city, e.state, e.country,
+    p.name AS place_name, p."admin1Name" AS place_admin1_name, p."countryCode" AS place_country_code
+FROM exif e
+LEFT JOIN LATERAL (
+    SELECT g.name, g."admin1Name", g."countryCode"
+    FROM geodata_places g
+    WHERE earth_box(ll_to_earth_public(e.latitude, e.longitude), $1)
+        @> ll_to_earth_public(g.latitude, g.longitude)
+    ORDER BY earth_distance(ll_to_earth_public(e.latitude, e.longitude), ll_to_earth_public(g.latitude, g.longitude))
+    LIMIT 1
+) p ON true
+WHERE e.latitude IS NOT NULL AND e.longitude IS NOT NULL
+AND e."assetId" > $2
+ORDER BY e."assetId"
+LIMIT $3
+`
+
+type ListGeotaggedExifPlacesParams struct {
+	MaxDistance float64
+	AfterID     pgtype.UUID
+	PageSize    int32
+}
+
+type ListGeotaggedExifPlacesRow struct {
+	AssetId          pgtype.UUID
+	City             pgtype.Text
+	State            pgtype.Text
+	Country          pgtype.Text
+	PlaceName        pgtype.Text
+	PlaceAdmin1Name  pgtype.Text
+	PlaceCountryCode pgtype.Text
+}
+
+// Lists the place names of geotagged assets after an asset ID, with the
+// place closest to their coordinates within max_distance meters.
+func (q *Queries) ListGeotaggedExifPlaces(ctx context.Context, arg ListGeotaggedExifPlacesParams) ([]ListGeotaggedExifPlacesRow, error) {
+	rows, err := q.db.Query(ctx, listGeotaggedExifPlaces, arg.MaxDistance, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListGeotaggedExifPlacesRow
+	for rows.Next() {
+		var i ListGeotaggedExifPlacesRow
+		if err := rows.Scan(
+			&i.AssetId,
+			&i.City,
+			&i.State,
+			&i.Country,
+			&i.PlaceName,
+			&i.PlaceAdmin1Name,
+			&i.PlaceCountryCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIntegrityReport = `-- name: ListIntegrityReport :many
+SELECT id, type, path, "assetId", "createdAt" FROM integrity_report
+ORDER BY type, path, id
+`
+
+func (q *Queries) ListIntegrityReport(ctx context.Context) ([]IntegrityReport, error) {
+	rows, err := q.db.Query(ctx, listIntegrityReport)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IntegrityReport
+	for rows.Next() {
+		var i IntegrityReport
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Path,
+			&i.AssetId,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJobFailures = `-- name: ListJobFailures :many
+SELECT id, queue, job_type, payload, error, max_retries, retried_count, failed_at, last_failed_at FROM job_failures
+ORDER BY failed_at DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListJobFailuresParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListJobFailures(ctx context.Context, arg ListJobFailuresParams) ([]JobFailure, error) {
+	rows, err := q.db.Query(ctx, listJobFailures, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []JobFailure
+	for rows.Next() {
+		var i JobFailure
+		if err := rows.Scan(
+			&i.ID,
+			&i.Queue,
+			&i.JobType,
+			&i.Payload,
+			&i.Error,
+			&i.MaxRetries,
+			&i.RetriedCount,
+			&i.FailedAt,
+			&i.LastFailedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLibraryAssetsForPurge = `-- name: ListLibraryAssetsForPurge :many
+SELECT id, "originalPath", "encodedVideoPath", "sidecarPath", "isExternal" FROM assets
+WHERE "libraryId" = $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListLibraryAssetsForPurgeParams struct {
+	LibraryID pgtype.UUID
+	BatchSize int32
+}
+
+type ListLibraryAssetsForPurgeRow struct {
+	ID               pgtype.UUID
+	OriginalPath     string
+	EncodedVideoPath pgtype.Text
+	SidecarPath      pgtype.Text
+	IsExternal       bool
+}
+
+// A batch of a deleted library's assets, including trashed ones, for the
+// library deletion job.
+func (q *Queries) ListLibraryAssetsForPurge(ctx context.Context, arg ListLibraryAssetsForPurgeParams) ([]ListLibraryAssetsForPurgeRow, error) {
+	rows, err := q.db.Query(ctx, listLibraryAssetsForPurge, arg.LibraryID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLibraryAssetsForPurgeRow
+	for rows.Next() {
+		var i ListLibraryAssetsForPurgeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalPath,
+			&i.EncodedVideoPath,
+			&i.SidecarPath,
+			&i.IsExternal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPeopleNeedingThumbnails = `-- name: ListPeopleNeedingThumbnails :many
+SELECT id, "createdAt", "updatedAt", "ownerId", name, "thumbnailPath", "isHidden", "birthDate", "faceAssetId", "isFavorite", color, "updateId" FROM person p
+WHERE EXISTS (
+    SELECT 1 FROM asset_faces f
+    WHERE f."personId" = p.id AND f."deletedAt" IS NULL
+)
+AND (
+    p."thumbnailPath" = ''
+    OR p."faceAssetId" IS NULL
+    OR strpos(p."thumbnailPath", p."faceAssetId"::text) = 0
+)
+ORDER BY p."createdAt"
+`
+
+// People with faces whose thumbnail is missing or was not cropped from their
+// feature face. Thumbnails are named after the face they show.
+func (q *Queries) ListPeopleNeedingThumbnails(ctx context.Context) ([]Person, error) {
+	rows, err := q.db.Query(ctx, listPeopleNeedingThumbnails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Person
+	for rows.Next() {
+		var i Person
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OwnerId,
+			&i.Name,
+			&i.ThumbnailPath,
+			&i.IsHidden,
+			&i.BirthDate,
+			&i.FaceAssetId,
+			&i.IsFavorite,
+			&i.Color,
+			&i.UpdateId,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSharedLinks = `-- name: ListSharedLinks :many
+SELECT sl.id, sl.description, sl."userId", sl.key, sl.type, sl."createdAt", sl."expiresAt", sl."allowUpload", sl."albumId", sl."allowDownload", sl."showExif", sl.password,
+    (SELECT COUNT(*) FROM shared_link__asset sla
+        JOIN assets a ON a.id = sla."assetsId"
+        WHERE sla."sharedLinksId" = sl.id AND a."deletedAt" IS NULL) AS asset_count
+FROM shared_links sl
+WHERE sl."userId" = $1
+AND ($2::text IS NULL OR sl.type = $2::text)
+AND ($3::uuid IS NULL OR sl."albumId" = $3::uuid)
+AND ($4::boolean IS NULL
+    OR (sl."expiresAt" IS NOT NULL AND sl."expiresAt" <= now()) = $4::boolean)
+AND ($5::timestamptz IS NULL
+    OR ($6::boolean
+        AND (sl."createdAt", sl.id) > ($5::timestamptz, $7::uuid))
+    OR (NOT $6::boolean
+        AND (sl."createdAt", sl.id) < ($5::timestamptz, $7::uuid)))
+ORDER BY
+    CASE WHEN $6::boolean THEN sl."createdAt" END ASC,
+    CASE WHEN $6::boolean THEN sl.id END ASC,
+    sl."createdAt" DESC,
+    sl.id DESC
+LIMIT $8
+`
+
+type ListSharedLinksParams struct {
+	UserID          pgtype.UUID
+	LinkType        pgtype.Text
+	AlbumID         pgtype.UUID
+	Expired         pgtype.Bool
+	CursorCreatedAt pgtype.Timestamptz
+	Ascending       bool
+	CursorID        pgtype.UUID
+	PageSize        int32
+}
+
+type ListSharedLinksRow struct {
+	SharedLink SharedLink
+	AssetCount int64
+}
+
+// A page of the user's links, newest first unless ascending. The cursor is
+// the creation time and id of the last link of the previous page.
+func (q *Queries) ListSharedLinks(ctx context.Context, arg ListSharedLinksParams) ([]ListSharedLinksRow, error) {
+	rows, err := q.db.Query(ctx, listSharedLinks,
+		arg.UserID,
+		arg.LinkType,
+		arg.AlbumID,
+		arg.Expired,
+		arg.CursorCreatedAt,
+		arg.Ascending,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSharedLinksRow
+	for rows.Next() {
+		var i ListSharedLinksRow
+		if err := rows.Scan(
+			&i.SharedLink.ID,
+			&i.SharedLink.Description,
+			&i.SharedLink.UserId,
+			&i.SharedLink.Key,
+			&i.SharedLink.Type,
+			&i.SharedLink.CreatedAt,
+			&i.SharedLink.ExpiresAt,
+			&i.SharedLink.AllowUpload,
+			&i.SharedLink.AlbumId,
+			&i.SharedLink.AllowDownload,
+			&i.SharedLink.ShowExif,
+			&i.SharedLink.Password,
+			&i.AssetCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSmartSearchByOwner = `-- name: ListSmartSearchByOwner :many
+SELECT ss."assetId", ss.embedding, a."duplicateId"
+FROM smart_search ss
+JOIN assets a ON ss."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."deletedAt" IS NULL
+ORDER BY a."createdAt" DESC
+LIMIT $2
+`
+
+type ListSmartSearchByOwnerParams struct {
+	OwnerId pgtype.UUID
+	Limit   int32
+}
+
+type ListSmartSearchByOwnerRow struct {
+	AssetId     pgtype.UUID
+	Embedding   interface{}
+	DuplicateId pgtype.UUID
+}
 
-// ============================================================================
-// ASSET METADATA, EDITS, OCR, AND COPY HELPERS
-// ============================================================================
-func (q *Queries) ListAssetMetadata(ctx context.Context, assetid pgtype.UUID) ([]AssetMetadatum, error) {
-	rows, err := q.db.Query(ctx, listAssetMetadata, assetid)
+func (q *Queries) ListSmartSearchByOwner(ctx context.Context, arg ListSmartSearchByOwnerParams) ([]ListSmartSearchByOwnerRow, error) {
+	rows, err := q.db.Query(ctx, listSmartSearchByOwner, arg.OwnerId, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []AssetMetadatum
+	var items []ListSmartSearchByOwnerRow
 	for rows.Next() {
-		var i AssetMetadatum
-		if err := rows.Scan(
-			&i.AssetId,
-			&i.Key,
-			&i.Value,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
+		var i ListSmartSearchByOwnerRow
+		if err := rows.Scan(&i.AssetId, &i.Embedding, &i.DuplicateId); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -7886,31 +12166,95 @@ func (q *Queries) ListAssetMetadata(ctx context.Context, assetid pgtype.UUID) ([
 	return items, nil
 }
 
-const listDatabaseBackups = `-- name: ListDatabaseBackups :many
-
-SELECT filename, path, filesize, timezone, "createdAt", "updatedAt" FROM database_backups
-ORDER BY "createdAt" DESC
+const listUserAssetsForExport = `-- name: ListUserAssetsForExport :many
+SELECT a.id, a.type, a."originalPath", a."originalFileName", a.checksum,
+    a."fileCreatedAt", a."localDateTime", a."isFavorite", a.visibility, a."deletedAt",
+    e."fileSizeInByte", e."dateTimeOriginal", e."timeZone", e.latitude, e.longitude,
+    e.city, e.state, e.country, e.make, e.model, e.description, e.rating,
+    COALESCE((SELECT array_agg(aa."albumsId" ORDER BY aa."albumsId")
+        FROM albums_assets_assets aa
+        WHERE aa."assetsId" = a.id), '{}')::uuid[] AS album_ids,
+    COALESCE((SELECT array_agg(t.value ORDER BY t.value)
+        FROM tag_asset ta
+        JOIN tags t ON t.id = ta."tagsId"
+        WHERE ta."assetsId" = a.id), '{}')::text[] AS tag_values
+FROM assets a
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a.id > $2::uuid
+ORDER BY a.id
+LIMIT $3
 `
 
-// ============================================================================
-// DATABASE BACKUP QUERIES
-// ============================================================================
-func (q *Queries) ListDatabaseBackups(ctx context.Context) ([]DatabaseBackup, error) {
-	rows, err := q.db.Query(ctx, listDatabaseBackups)
+type ListUserAssetsForExportParams struct {
+	OwnerID   pgtype.UUID
+	AfterID   pgtype.UUID
+	BatchSize int32
+}
+
+type ListUserAssetsForExportRow struct {
+	ID               pgtype.UUID
+	Type             string
+	OriginalPath     string
+	OriginalFileName string
+	Checksum         []byte
+	FileCreatedAt    pgtype.Timestamptz
+	LocalDateTime    pgtype.Timestamptz
+	IsFavorite       bool
+	Visibility       AssetVisibilityEnum
+	DeletedAt        pgtype.Timestamptz
+	FileSizeInByte   pgtype.Int8
+	DateTimeOriginal pgtype.Timestamptz
+	TimeZone         pgtype.Text
+	Latitude         pgtype.Float8
+	Longitude        pgtype.Float8
+	City             pgtype.Text
+	State            pgtype.Text
+	Country          pgtype.Text
+	Make             pgtype.Text
+	Model            pgtype.Text
+	Description      pgtype.Text
+	Rating           pgtype.Int4
+	AlbumIds         []pgtype.UUID
+	TagValues        []string
+}
+
+// One page of a user data export, including trashed assets, ordered by id so
+// an interrupted export resumes after the last exported asset.
+func (q *Queries) ListUserAssetsForExport(ctx context.Context, arg ListUserAssetsForExportParams) ([]ListUserAssetsForExportRow, error) {
+	rows, err := q.db.Query(ctx, listUserAssetsForExport, arg.OwnerID, arg.AfterID, arg.BatchSize)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []DatabaseBackup
+	var items []ListUserAssetsForExportRow
 	for rows.Next() {
-		var i DatabaseBackup
+		var i ListUserAssetsForExportRow
 		if err := rows.Scan(
-			&i.Filename,
-			&i.Path,
-			&i.Filesize,
-			&i.Timezone,
-			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.ID,
+			&i.Type,
+			&i.OriginalPath,
+			&i.OriginalFileName,
+			&i.Checksum,
+			&i.FileCreatedAt,
+			&i.LocalDateTime,
+			&i.IsFavorite,
+			&i.Visibility,
+			&i.DeletedAt,
+			&i.FileSizeInByte,
+			&i.DateTimeOriginal,
+			&i.TimeZone,
+			&i.Latitude,
+			&i.Longitude,
+			&i.City,
+			&i.State,
+			&i.Country,
+			&i.Make,
+			&i.Model,
+			&i.Description,
+			&i.Rating,
+			&i.AlbumIds,
+			&i.TagValues,
 		); err != nil {
 			return nil, err
 		}
@@ -7922,36 +12266,42 @@ func (q *Queries) ListDatabaseBackups(ctx context.Context) ([]DatabaseBackup, er
 	return items, nil
 }
 
-const listJobFailures = `-- name: ListJobFailures :many
-SELECT id, queue, job_type, payload, error, max_retries, retried_count, failed_at, last_failed_at FROM job_failures
-ORDER BY failed_at DESC
-LIMIT $1 OFFSET $2
+const listUserAssetsForPurge = `-- name: ListUserAssetsForPurge :many
+SELECT id, "originalPath", "encodedVideoPath", "sidecarPath", "isExternal" FROM assets
+WHERE "ownerId" = $1
+ORDER BY id
+LIMIT $2
 `
 
-type ListJobFailuresParams struct {
-	Limit  int32
-	Offset int32
+type ListUserAssetsForPurgeParams struct {
+	OwnerID   pgtype.UUID
+	BatchSize int32
 }
 
-func (q *Queries) ListJobFailures(ctx context.Context, arg ListJobFailuresParams) ([]JobFailure, error) {
-	rows, err := q.db.Query(ctx, listJobFailures, arg.Limit, arg.Offset)
+type ListUserAssetsForPurgeRow struct {
+	ID               pgtype.UUID
+	OriginalPath     string
+	EncodedVideoPath pgtype.Text
+	SidecarPath      pgtype.Text
+	IsExternal       bool
+}
+
+// A batch of the user's assets, including trashed ones, for the deletion job.
+func (q *Queries) ListUserAssetsForPurge(ctx context.Context, arg ListUserAssetsForPurgeParams) ([]ListUserAssetsForPurgeRow, error) {
+	rows, err := q.db.Query(ctx, listUserAssetsForPurge, arg.OwnerID, arg.BatchSize)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []JobFailure
+	var items []ListUserAssetsForPurgeRow
 	for rows.Next() {
-		var i JobFailure
+		var i ListUserAssetsForPurgeRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.Queue,
-			&i.JobType,
-			&i.Payload,
-			&i.Error,
-			&i.MaxRetries,
-			&i.RetriedCount,
-			&i.FailedAt,
-			&i.LastFailedAt,
+			&i.OriginalPath,
+			&i.EncodedVideoPath,
+			&i.SidecarPath,
+			&i.IsExternal,
 		); err != nil {
 			return nil, err
 		}
@@ -7963,40 +12313,24 @@ func (q *Queries) ListJobFailures(ctx context.Context, arg ListJobFailuresParams
 	return items, nil
 }
 
-const listSmartSearchByOwner = `-- name: ListSmartSearchByOwner :many
-SELECT ss."assetId", ss.embedding, a."duplicateId"
-FROM smart_search ss
-JOIN assets a ON ss."assetId" = a.id
-WHERE a."ownerId" = $1
-AND a."deletedAt" IS NULL
-ORDER BY a."createdAt" DESC
-LIMIT $2
+const listUserPersonThumbnailPaths = `-- name: ListUserPersonThumbnailPaths :many
+SELECT "thumbnailPath" FROM person
+WHERE "ownerId" = $1 AND "thumbnailPath" != ''
 `
 
-type ListSmartSearchByOwnerParams struct {
-	OwnerId pgtype.UUID
-	Limit   int32
-}
-
-type ListSmartSearchByOwnerRow struct {
-	AssetId     pgtype.UUID
-	Embedding   interface{}
-	DuplicateId pgtype.UUID
-}
-
-func (q *Queries) ListSmartSearchByOwner(ctx context.Context, arg ListSmartSearchByOwnerParams) ([]ListSmartSearchByOwnerRow, error) {
-	rows, err := q.db.Query(ctx, listSmartSearchByOwner, arg.OwnerId, arg.Limit)
+func (q *Queries) ListUserPersonThumbnailPaths(ctx context.Context, ownerid pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listUserPersonThumbnailPaths, ownerid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListSmartSearchByOwnerRow
+	var items []string
 	for rows.Next() {
-		var i ListSmartSearchByOwnerRow
-		if err := rows.Scan(&i.AssetId, &i.Embedding, &i.DuplicateId); err != nil {
+		var thumbnailPath string
+		if err := rows.Scan(&thumbnailPath); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items = append(items, thumbnailPath)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -8084,6 +12418,84 @@ func (q *Queries) ListVersionHistory(ctx context.Context) ([]VersionHistory, err
 	return items, nil
 }
 
+const listWebhookSubscriptions = `-- name: ListWebhookSubscriptions :many
+SELECT id, url, events, secret, enabled, "createdAt", "updatedAt", "lastDeliveryAt", "lastSuccessAt", "lastStatusCode", "lastError", "failureCount" FROM webhook_subscriptions
+ORDER BY "createdAt", id
+`
+
+func (q *Queries) ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookSubscription
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Events,
+			&i.Secret,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastDeliveryAt,
+			&i.LastSuccessAt,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.FailureCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookSubscriptionsForEvent = `-- name: ListWebhookSubscriptionsForEvent :many
+SELECT id, url, events, secret, enabled, "createdAt", "updatedAt", "lastDeliveryAt", "lastSuccessAt", "lastStatusCode", "lastError", "failureCount" FROM webhook_subscriptions
+WHERE enabled = true AND $1::text = ANY(events)
+ORDER BY "createdAt", id
+`
+
+// Lists the enabled subscriptions to an event type.
+func (q *Queries) ListWebhookSubscriptionsForEvent(ctx context.Context, event string) ([]WebhookSubscription, error) {
+	rows, err := q.db.Query(ctx, listWebhookSubscriptionsForEvent, event)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookSubscription
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Events,
+			&i.Secret,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastDeliveryAt,
+			&i.LastSuccessAt,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.FailureCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listWorkflowExecutions = `-- name: ListWorkflowExecutions :many
 SELECT id, "workflowId", status, "startedAt", "completedAt", "errorMessage", "triggerData", "actionResults" FROM workflow_executions
 WHERE "workflowId" = $1
@@ -8210,6 +12622,17 @@ func (q *Queries) ListWorkflowsByOwner(ctx context.Context, ownerid pgtype.UUID)
 	return items, nil
 }
 
+const lockUserLimits = `-- name: LockUserLimits :exec
+SELECT pg_advisory_xact_lock(hashtextextended('user_limits:' || $1::uuid::text, 0))
+`
+
+// Serializes the creation of the items a user's limits count until the end
+// of the transaction.
+func (q *Queries) LockUserLimits(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, lockUserLimits, userID)
+	return err
+}
+
 const markAssetProcessed = `-- name: MarkAssetProcessed :execrows
 UPDATE assets
 SET status = 'active',
@@ -8256,6 +12679,38 @@ func (q *Queries) MarkNotificationAsRead(ctx context.Context, id pgtype.UUID) (N
 	return i, err
 }
 
+const markStorageMigrationSourceDeleted = `-- name: MarkStorageMigrationSourceDeleted :exec
+UPDATE storage_migration_files
+SET status = 'source_deleted', "updatedAt" = now()
+WHERE "fromBackend" = $1 AND "toBackend" = $2 AND "sourcePath" = $3
+`
+
+type MarkStorageMigrationSourceDeletedParams struct {
+	FromBackend string
+	ToBackend   string
+	SourcePath  string
+}
+
+func (q *Queries) MarkStorageMigrationSourceDeleted(ctx context.Context, arg MarkStorageMigrationSourceDeletedParams) error {
+	_, err := q.db.Exec(ctx, markStorageMigrationSourceDeleted, arg.FromBackend, arg.ToBackend, arg.SourcePath)
+	return err
+}
+
+const markUserForRemoval = `-- name: MarkUserForRemoval :exec
+UPDATE users
+SET "deletedAt" = COALESCE("deletedAt", now()),
+    status = 'removing',
+    "updatedAt" = now()
+WHERE id = $1
+`
+
+// Soft-deletes the user (keeping an earlier deletedAt) and flags the account
+// for the background purge of its assets and rows.
+func (q *Queries) MarkUserForRemoval(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, markUserForRemoval, id)
+	return err
+}
+
 const moveAssetToTrash = `-- name: MoveAssetToTrash :exec
 UPDATE assets
 SET status = 'trashed',
@@ -8285,6 +12740,35 @@ func (q *Queries) MoveAssetsToTrash(ctx context.Context, arg MoveAssetsToTrashPa
 	return err
 }
 
+const ownerHasUploadedAsset = `-- name: OwnerHasUploadedAsset :one
+SELECT EXISTS(
+    SELECT 1 FROM assets
+    WHERE "ownerId" = $1 AND "libraryId" IS NULL
+    AND (checksum = $2 OR ("deviceId" = $3 AND "deviceAssetId" = $4 AND "deviceId" <> '' AND "deviceAssetId" <> ''))
+)
+`
+
+type OwnerHasUploadedAssetParams struct {
+	OwnerId       pgtype.UUID
+	Checksum      []byte
+	DeviceId      string
+	DeviceAssetId string
+}
+
+// Uploaded assets are unique per owner and checksum, and per owner and
+// device asset, trashed ones included.
+func (q *Queries) OwnerHasUploadedAsset(ctx context.Context, arg OwnerHasUploadedAssetParams) (bool, error) {
+	row := q.db.QueryRow(ctx, ownerHasUploadedAsset,
+		arg.OwnerId,
+		arg.Checksum,
+		arg.DeviceId,
+		arg.DeviceAssetId,
+	)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const permanentlyDeleteAsset = `-- name: PermanentlyDeleteAsset :exec
 UPDATE assets
 SET "deletedAt" = now(),
@@ -8304,13 +12788,60 @@ SET "deletedAt" = now(),
 WHERE id = ANY($1::uuid[]) AND "ownerId" = $2
 `
 
-type PermanentlyDeleteAssetsParams struct {
-	Column1 []pgtype.UUID
-	OwnerId pgtype.UUID
+type PermanentlyDeleteAssetsParams struct {
+	Column1 []pgtype.UUID
+	OwnerId pgtype.UUID
+}
+
+func (q *Queries) PermanentlyDeleteAssets(ctx context.Context, arg PermanentlyDeleteAssetsParams) error {
+	_, err := q.db.Exec(ctx, permanentlyDeleteAssets, arg.Column1, arg.OwnerId)
+	return err
+}
+
+const purgeAssets = `-- name: PurgeAssets :exec
+DELETE FROM assets
+WHERE id = ANY($1::uuid[])
+AND "ownerId" = $2
+`
+
+type PurgeAssetsParams struct {
+	AssetIds []pgtype.UUID
+	OwnerID  pgtype.UUID
+}
+
+func (q *Queries) PurgeAssets(ctx context.Context, arg PurgeAssetsParams) error {
+	_, err := q.db.Exec(ctx, purgeAssets, arg.AssetIds, arg.OwnerID)
+	return err
+}
+
+const reassignAsset = `-- name: ReassignAsset :exec
+UPDATE assets
+SET "ownerId" = $2,
+    "originalPath" = $3,
+    "sidecarPath" = $4,
+    "encodedVideoPath" = $5,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1
+`
+
+type ReassignAssetParams struct {
+	ID               pgtype.UUID
+	OwnerId          pgtype.UUID
+	OriginalPath     string
+	SidecarPath      pgtype.Text
+	EncodedVideoPath pgtype.Text
 }
 
-func (q *Queries) PermanentlyDeleteAssets(ctx context.Context, arg PermanentlyDeleteAssetsParams) error {
-	_, err := q.db.Exec(ctx, permanentlyDeleteAssets, arg.Column1, arg.OwnerId)
+// Hands an asset to another user, with its paths moved to theirs.
+func (q *Queries) ReassignAsset(ctx context.Context, arg ReassignAssetParams) error {
+	_, err := q.db.Exec(ctx, reassignAsset,
+		arg.ID,
+		arg.OwnerId,
+		arg.OriginalPath,
+		arg.SidecarPath,
+		arg.EncodedVideoPath,
+	)
 	return err
 }
 
@@ -8333,6 +12864,56 @@ func (q *Queries) RecordAssetView(ctx context.Context, arg RecordAssetViewParams
 	return err
 }
 
+const recordWebhookDeliveryFailure = `-- name: RecordWebhookDeliveryFailure :exec
+UPDATE webhook_subscriptions
+SET "lastDeliveryAt" = now(), "lastStatusCode" = $2, "lastError" = $3,
+    "failureCount" = "failureCount" + 1
+WHERE id = $1
+`
+
+type RecordWebhookDeliveryFailureParams struct {
+	ID             pgtype.UUID
+	LastStatusCode pgtype.Int4
+	LastError      pgtype.Text
+}
+
+// Records a failed delivery. The status code is NULL when no response was
+// received.
+func (q *Queries) RecordWebhookDeliveryFailure(ctx context.Context, arg RecordWebhookDeliveryFailureParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookDeliveryFailure, arg.ID, arg.LastStatusCode, arg.LastError)
+	return err
+}
+
+const recordWebhookDeliverySuccess = `-- name: RecordWebhookDeliverySuccess :exec
+UPDATE webhook_subscriptions
+SET "lastDeliveryAt" = now(), "lastSuccessAt" = now(), "lastStatusCode" = $2,
+    "lastError" = NULL, "failureCount" = 0
+WHERE id = $1
+`
+
+type RecordWebhookDeliverySuccessParams struct {
+	ID             pgtype.UUID
+	LastStatusCode pgtype.Int4
+}
+
+func (q *Queries) RecordWebhookDeliverySuccess(ctx context.Context, arg RecordWebhookDeliverySuccessParams) error {
+	_, err := q.db.Exec(ctx, recordWebhookDeliverySuccess, arg.ID, arg.LastStatusCode)
+	return err
+}
+
+const removeAllTagsFromAsset = `-- name: RemoveAllTagsFromAsset :execrows
+DELETE FROM tag_asset
+WHERE "assetsId" = $1
+`
+
+func (q *Queries) RemoveAllTagsFromAsset(ctx context.Context, assetsid pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, removeAllTagsFromAsset, assetsid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const removeAssetFromAlbum = `-- name: RemoveAssetFromAlbum :exec
 DELETE FROM albums_assets_assets
 WHERE "albumsId" = $1 AND "assetsId" = $2
@@ -8348,6 +12929,19 @@ func (q *Queries) RemoveAssetFromAlbum(ctx context.Context, arg RemoveAssetFromA
 	return err
 }
 
+const removeAssetFromAllAlbums = `-- name: RemoveAssetFromAllAlbums :execrows
+DELETE FROM albums_assets_assets
+WHERE "assetsId" = $1
+`
+
+func (q *Queries) RemoveAssetFromAllAlbums(ctx context.Context, assetsid pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, removeAssetFromAllAlbums, assetsid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const removeAssetFromSharedLink = `-- name: RemoveAssetFromSharedLink :exec
 DELETE FROM shared_link__asset
 WHERE "sharedLinksId" = $1 AND "assetsId" = $2
@@ -8424,25 +13018,28 @@ func (q *Queries) RemoveUserFromAlbum(ctx context.Context, arg RemoveUserFromAlb
 const replaceAssetFile = `-- name: ReplaceAssetFile :one
 UPDATE assets
 SET checksum = $1,
-    "fileModifiedAt" = $2,
+    "checksumAlgorithm" = $2,
+    "fileModifiedAt" = $3,
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
-WHERE id = $3
-AND "ownerId" = $4
+WHERE id = $4
+AND "ownerId" = $5
 AND "deletedAt" IS NULL
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type ReplaceAssetFileParams struct {
-	Checksum       []byte
-	FileModifiedAt pgtype.Timestamptz
-	ID             pgtype.UUID
-	OwnerID        pgtype.UUID
+	Checksum          []byte
+	ChecksumAlgorithm string
+	FileModifiedAt    pgtype.Timestamptz
+	ID                pgtype.UUID
+	OwnerID           pgtype.UUID
 }
 
 func (q *Queries) ReplaceAssetFile(ctx context.Context, arg ReplaceAssetFileParams) (Asset, error) {
 	row := q.db.QueryRow(ctx, replaceAssetFile,
 		arg.Checksum,
+		arg.ChecksumAlgorithm,
 		arg.FileModifiedAt,
 		arg.ID,
 		arg.OwnerID,
@@ -8477,10 +13074,41 @@ func (q *Queries) ReplaceAssetFile(ctx context.Context, arg ReplaceAssetFilePara
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
+const reserveIdempotencyKey = `-- name: ReserveIdempotencyKey :execrows
+INSERT INTO idempotency_keys ("userId", key, fingerprint, "expiresAt")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT ("userId", key) DO NOTHING
+`
+
+type ReserveIdempotencyKeyParams struct {
+	UserId      pgtype.UUID
+	Key         string
+	Fingerprint string
+	ExpiresAt   pgtype.Timestamptz
+}
+
+func (q *Queries) ReserveIdempotencyKey(ctx context.Context, arg ReserveIdempotencyKeyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reserveIdempotencyKey,
+		arg.UserId,
+		arg.Key,
+		arg.Fingerprint,
+		arg.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const restoreAssetFromTrash = `-- name: RestoreAssetFromTrash :exec
 UPDATE assets
 SET status = 'active',
@@ -8510,14 +13138,42 @@ func (q *Queries) RestoreAssets(ctx context.Context, arg RestoreAssetsParams) er
 	return err
 }
 
+const restoreOfflineAsset = `-- name: RestoreOfflineAsset :execrows
+UPDATE assets
+SET "isOffline" = false,
+    "offlineAt" = NULL,
+    "originalPath" = $2,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND "isOffline"
+`
+
+type RestoreOfflineAssetParams struct {
+	ID           pgtype.UUID
+	OriginalPath string
+}
+
+// Brings an offline asset back when its file is found again, possibly at a
+// new path.
+func (q *Queries) RestoreOfflineAsset(ctx context.Context, arg RestoreOfflineAssetParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreOfflineAsset, arg.ID, arg.OriginalPath)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const restoreUser = `-- name: RestoreUser :one
 UPDATE users
 SET "deletedAt" = NULL,
+    status = 'active',
     "updatedAt" = now()
 WHERE id = $1
+AND status != 'removing'
 RETURNING id, email, password, "createdAt", "profileImagePath", "isAdmin", "shouldChangePassword", "deletedAt", "oauthId", "updatedAt", "storageLabel", name, "quotaSizeInBytes", "quotaUsageInBytes", status, "profileChangedAt", "updateId", "avatarColor", "pinCode", "isOnboarded"
 `
 
+// Users already queued for removal cannot be restored.
 func (q *Queries) RestoreUser(ctx context.Context, id pgtype.UUID) (User, error) {
 	row := q.db.QueryRow(ctx, restoreUser, id)
 	var i User
@@ -8546,6 +13202,61 @@ func (q *Queries) RestoreUser(ctx context.Context, id pgtype.UUID) (User, error)
 	return i, err
 }
 
+const revokeAssetShares = `-- name: RevokeAssetShares :many
+DELETE FROM asset_user_shares
+WHERE "sharedById" = $1
+  AND "sharedWithId" = $2
+  AND "assetId" = ANY($3::uuid[])
+RETURNING "assetId"
+`
+
+type RevokeAssetSharesParams struct {
+	SharedById   pgtype.UUID
+	SharedWithId pgtype.UUID
+	AssetIds     []pgtype.UUID
+}
+
+// Returns the assets that were shared with the user.
+func (q *Queries) RevokeAssetShares(ctx context.Context, arg RevokeAssetSharesParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, revokeAssetShares, arg.SharedById, arg.SharedWithId, arg.AssetIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var assetId pgtype.UUID
+		if err := rows.Scan(&assetId); err != nil {
+			return nil, err
+		}
+		items = append(items, assetId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :exec
+INSERT INTO user_deletion_schedules ("userId", "deleteAt", "restoreTokenHash")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "deleteAt" = EXCLUDED."deleteAt",
+    "restoreTokenHash" = EXCLUDED."restoreTokenHash",
+    "createdAt" = now()
+`
+
+type ScheduleUserDeletionParams struct {
+	UserId           pgtype.UUID
+	DeleteAt         pgtype.Timestamptz
+	RestoreTokenHash string
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) error {
+	_, err := q.db.Exec(ctx, scheduleUserDeletion, arg.UserId, arg.DeleteAt, arg.RestoreTokenHash)
+	return err
+}
+
 const searchActivity = `-- name: SearchActivity :many
 SELECT a.id, a."createdAt", a."updatedAt", a."albumId", a."userId", a."assetId", a.comment, a."isLiked", a."updateId", u.name as user_name, u.email as user_email FROM activity a
 JOIN users u ON a."userId" = u.id AND u."deletedAt" IS NULL
@@ -8673,9 +13384,10 @@ func (q *Queries) SearchAlbums(ctx context.Context, arg SearchAlbumsParams) ([]A
 }
 
 const searchAssets = `-- name: SearchAssets :many
-SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility FROM assets
+SELECT id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash FROM assets
 WHERE "ownerId" = $1 
   AND "deletedAt" IS NULL
+  AND visibility <> 'locked'
   AND (
     "originalFileName" ILIKE '%' || $2 || '%' OR
     description ILIKE '%' || $2 || '%'
@@ -8735,6 +13447,11 @@ func (q *Queries) SearchAssets(ctx context.Context, arg SearchAssetsParams) ([]A
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -8747,53 +13464,67 @@ func (q *Queries) SearchAssets(ctx context.Context, arg SearchAssetsParams) ([]A
 }
 
 const searchAssetsByEmbedding = `-- name: SearchAssetsByEmbedding :many
-SELECT ss."assetId", ss.embedding, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM smart_search ss
+SELECT ss."assetId", ss.embedding, a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM smart_search ss
 JOIN assets a ON ss."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND ss.embedding <-> $2 < $3
+AND ($6::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= $5::float8
+    AND al.label = ANY($6::text[])
+) = cardinality($6::text[]))
 ORDER BY ss.embedding <-> $2
 LIMIT $4
 `
 
 type SearchAssetsByEmbeddingParams struct {
-	OwnerID     pgtype.UUID
-	Embedding   interface{}
-	MaxDistance interface{}
-	ResultLimit int32
+	OwnerID       pgtype.UUID
+	Embedding     interface{}
+	MaxDistance   interface{}
+	ResultLimit   int32
+	LabelMinScore float64
+	Labels        []string
 }
 
 type SearchAssetsByEmbeddingRow struct {
-	AssetId          pgtype.UUID
-	Embedding        interface{}
-	ID               pgtype.UUID
-	DeviceAssetId    string
-	OwnerId          pgtype.UUID
-	DeviceId         string
-	Type             string
-	OriginalPath     string
-	FileCreatedAt    pgtype.Timestamptz
-	FileModifiedAt   pgtype.Timestamptz
-	IsFavorite       bool
-	Duration         pgtype.Text
-	EncodedVideoPath pgtype.Text
-	Checksum         []byte
-	LivePhotoVideoId pgtype.UUID
-	UpdatedAt        pgtype.Timestamptz
-	CreatedAt        pgtype.Timestamptz
-	OriginalFileName string
-	SidecarPath      pgtype.Text
-	Thumbhash        []byte
-	IsOffline        bool
-	LibraryId        pgtype.UUID
-	IsExternal       bool
-	DeletedAt        pgtype.Timestamptz
-	LocalDateTime    pgtype.Timestamptz
-	StackId          pgtype.UUID
-	DuplicateId      pgtype.UUID
-	Status           AssetsStatusEnum
-	UpdateId         pgtype.UUID
-	Visibility       AssetVisibilityEnum
+	AssetId           pgtype.UUID
+	Embedding         interface{}
+	ID                pgtype.UUID
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	IsFavorite        bool
+	Duration          pgtype.Text
+	EncodedVideoPath  pgtype.Text
+	Checksum          []byte
+	LivePhotoVideoId  pgtype.UUID
+	UpdatedAt         pgtype.Timestamptz
+	CreatedAt         pgtype.Timestamptz
+	OriginalFileName  string
+	SidecarPath       pgtype.Text
+	Thumbhash         []byte
+	IsOffline         bool
+	LibraryId         pgtype.UUID
+	IsExternal        bool
+	DeletedAt         pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	StackId           pgtype.UUID
+	DuplicateId       pgtype.UUID
+	Status            AssetsStatusEnum
+	UpdateId          pgtype.UUID
+	Visibility        AssetVisibilityEnum
+	ChecksumAlgorithm string
+	IsUndated         bool
+	OfflineAt         pgtype.Timestamptz
+	OriginalMimeType  pgtype.Text
+	Blurhash          pgtype.Text
 }
 
 func (q *Queries) SearchAssetsByEmbedding(ctx context.Context, arg SearchAssetsByEmbeddingParams) ([]SearchAssetsByEmbeddingRow, error) {
@@ -8802,6 +13533,8 @@ func (q *Queries) SearchAssetsByEmbedding(ctx context.Context, arg SearchAssetsB
 		arg.Embedding,
 		arg.MaxDistance,
 		arg.ResultLimit,
+		arg.LabelMinScore,
+		arg.Labels,
 	)
 	if err != nil {
 		return nil, err
@@ -8841,6 +13574,11 @@ func (q *Queries) SearchAssetsByEmbedding(ctx context.Context, arg SearchAssetsB
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -8853,10 +13591,11 @@ func (q *Queries) SearchAssetsByEmbedding(ctx context.Context, arg SearchAssetsB
 }
 
 const searchAssetsByText = `-- name: SearchAssetsByText :many
-SELECT DISTINCT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT DISTINCT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = $1 
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     a."originalFileName" ILIKE '%' || $2 || '%'
     OR a."originalPath" ILIKE '%' || $2 || '%'
@@ -8920,6 +13659,11 @@ func (q *Queries) SearchAssetsByText(ctx context.Context, arg SearchAssetsByText
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -8932,16 +13676,23 @@ func (q *Queries) SearchAssetsByText(ctx context.Context, arg SearchAssetsByText
 }
 
 const searchAssetsFiltered = `-- name: SearchAssetsFiltered :many
-SELECT DISTINCT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     $2::text IS NULL
     OR a."originalFileName" ILIKE '%' || $2::text || '%'
     OR a."originalPath" ILIKE '%' || $2::text || '%'
     OR e.description ILIKE '%' || $2::text || '%'
     OR e."imageName" ILIKE '%' || $2::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM asset_labels al
+        WHERE al."assetId" = a.id
+        AND al.score >= $22::float8
+        AND al.label ILIKE '%' || $2::text || '%'
+    )
 )
 AND ($3::text IS NULL OR a.type = $3::text)
 AND ($4::boolean IS NULL OR a."isFavorite" = $4::boolean)
@@ -8958,36 +13709,55 @@ AND ($14::timestamptz IS NULL OR a."localDateTime" >= $14::timestamptz)
 AND ($15::timestamptz IS NULL OR a."localDateTime" <= $15::timestamptz)
 AND ($16::boolean IS NULL OR (a."encodedVideoPath" IS NOT NULL AND a."encodedVideoPath" <> '') = $16::boolean)
 AND ($17::boolean IS NULL OR (a."livePhotoVideoId" IS NOT NULL) = $17::boolean)
-AND ($18::boolean IS NULL OR a."isOffline" = $18::boolean)
+AND a."isOffline" = COALESCE($18::boolean, false)
 AND ($19::boolean IS NULL OR a."isExternal" = $19::boolean)
-ORDER BY a."localDateTime" DESC
+AND ($23::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= $22::float8
+    AND al.label = ANY($23::text[])
+) = cardinality($23::text[]))
+ORDER BY
+    CASE WHEN $24::text = 'created_at' AND NOT $25::boolean THEN a."createdAt" END ASC,
+    CASE WHEN $24::text = 'created_at' AND $25::boolean THEN a."createdAt" END DESC,
+    CASE WHEN $24::text = 'filename' AND NOT $25::boolean THEN lower(a."originalFileName") END ASC,
+    CASE WHEN $24::text = 'filename' AND $25::boolean THEN lower(a."originalFileName") END DESC,
+    CASE WHEN NOT $25::boolean THEN a."localDateTime" END ASC,
+    CASE WHEN $25::boolean THEN a."localDateTime" END DESC,
+    CASE WHEN NOT $25::boolean THEN a.id END ASC,
+    a.id DESC
 LIMIT $21 OFFSET $20
 `
 
 type SearchAssetsFilteredParams struct {
-	OwnerID     pgtype.UUID
-	Query       pgtype.Text
-	Type        pgtype.Text
-	IsFavorite  pgtype.Bool
-	IsArchived  pgtype.Bool
-	City        pgtype.Text
-	State       pgtype.Text
-	Country     pgtype.Text
-	Make        pgtype.Text
-	Model       pgtype.Text
-	LensModel   pgtype.Text
-	LibraryID   pgtype.UUID
-	DeviceID    pgtype.Text
-	TakenAfter  pgtype.Timestamptz
-	TakenBefore pgtype.Timestamptz
-	IsEncoded   pgtype.Bool
-	IsMotion    pgtype.Bool
-	IsOffline   pgtype.Bool
-	IsExternal  pgtype.Bool
-	Offset      int32
-	Limit       int32
+	OwnerID       pgtype.UUID
+	Query         pgtype.Text
+	Type          pgtype.Text
+	IsFavorite    pgtype.Bool
+	IsArchived    pgtype.Bool
+	City          pgtype.Text
+	State         pgtype.Text
+	Country       pgtype.Text
+	Make          pgtype.Text
+	Model         pgtype.Text
+	LensModel     pgtype.Text
+	LibraryID     pgtype.UUID
+	DeviceID      pgtype.Text
+	TakenAfter    pgtype.Timestamptz
+	TakenBefore   pgtype.Timestamptz
+	IsEncoded     pgtype.Bool
+	IsMotion      pgtype.Bool
+	IsOffline     pgtype.Bool
+	IsExternal    pgtype.Bool
+	Offset        int32
+	Limit         int32
+	LabelMinScore float64
+	Labels        []string
+	SortBy        string
+	Descending    bool
 }
 
+// sort_by is "date_taken", "created_at" or "filename", ties broken by id.
 func (q *Queries) SearchAssetsFiltered(ctx context.Context, arg SearchAssetsFilteredParams) ([]Asset, error) {
 	rows, err := q.db.Query(ctx, searchAssetsFiltered,
 		arg.OwnerID,
@@ -9011,6 +13781,10 @@ func (q *Queries) SearchAssetsFiltered(ctx context.Context, arg SearchAssetsFilt
 		arg.IsExternal,
 		arg.Offset,
 		arg.Limit,
+		arg.LabelMinScore,
+		arg.Labels,
+		arg.SortBy,
+		arg.Descending,
 	)
 	if err != nil {
 		return nil, err
@@ -9048,6 +13822,11 @@ func (q *Queries) SearchAssetsFiltered(ctx context.Context, arg SearchAssetsFilt
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -9110,10 +13889,11 @@ func (q *Queries) SearchFacesByEmbedding(ctx context.Context, arg SearchFacesByE
 }
 
 const searchLargeAssets = `-- name: SearchLargeAssets :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY COALESCE(e."fileSizeInByte", 0) DESC, a."createdAt" DESC
 LIMIT $2
 `
@@ -9161,6 +13941,11 @@ func (q *Queries) SearchLargeAssets(ctx context.Context, arg SearchLargeAssetsPa
 			&i.Status,
 			&i.UpdateId,
 			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
 		); err != nil {
 			return nil, err
 		}
@@ -9229,37 +14014,53 @@ func (q *Queries) SearchPeople(ctx context.Context, arg SearchPeopleParams) ([]P
 }
 
 const searchPlaces = `-- name: SearchPlaces :many
-SELECT DISTINCT city, state, country FROM exif
-WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
-)
+SELECT e.city::text AS city,
+       COALESCE(e.state, '')::text AS state,
+       COALESCE(e.country, '')::text AS country,
+       COALESCE(AVG(e.latitude), 0)::float8 AS latitude,
+       COALESCE(AVG(e.longitude), 0)::float8 AS longitude,
+       COUNT(*) AS asset_count
+FROM exif e
+INNER JOIN assets a ON a.id = e."assetId"
+WHERE a."ownerId" = $1
+  AND a."deletedAt" IS NULL
+  AND a.visibility <> 'locked'
+  AND e.city IS NOT NULL
+  AND e.city != ''
   AND (
-    city ILIKE '%' || $2 || '%' OR
-    state ILIKE '%' || $2 || '%' OR
-    country ILIKE '%' || $2 || '%'
+    f_unaccent(lower(e.city)) LIKE f_unaccent(lower($2::text)) || '%' OR
+    f_unaccent(lower(e.state)) LIKE f_unaccent(lower($2::text)) || '%' OR
+    f_unaccent(lower(e.country)) LIKE f_unaccent(lower($2::text)) || '%'
   )
-LIMIT $3 OFFSET $4
+GROUP BY e.city, e.state, e.country
+ORDER BY asset_count DESC, e.city
+LIMIT $4 OFFSET $3
 `
 
 type SearchPlacesParams struct {
-	OwnerId pgtype.UUID
-	Column2 pgtype.Text
-	Limit   int32
-	Offset  int32
+	OwnerID    pgtype.UUID
+	Prefix     string
+	PageOffset int32
+	PageSize   int32
 }
 
 type SearchPlacesRow struct {
-	City    pgtype.Text
-	State   pgtype.Text
-	Country pgtype.Text
+	City       string
+	State      string
+	Country    string
+	Latitude   float64
+	Longitude  float64
+	AssetCount int64
 }
 
+// Geocoded places in the user's library whose city, state or country starts
+// with the prefix, ignoring case and diacritics.
 func (q *Queries) SearchPlaces(ctx context.Context, arg SearchPlacesParams) ([]SearchPlacesRow, error) {
 	rows, err := q.db.Query(ctx, searchPlaces,
-		arg.OwnerId,
-		arg.Column2,
-		arg.Limit,
-		arg.Offset,
+		arg.OwnerID,
+		arg.Prefix,
+		arg.PageOffset,
+		arg.PageSize,
 	)
 	if err != nil {
 		return nil, err
@@ -9268,7 +14069,14 @@ func (q *Queries) SearchPlaces(ctx context.Context, arg SearchPlacesParams) ([]S
 	var items []SearchPlacesRow
 	for rows.Next() {
 		var i SearchPlacesRow
-		if err := rows.Scan(&i.City, &i.State, &i.Country); err != nil {
+		if err := rows.Scan(
+			&i.City,
+			&i.State,
+			&i.Country,
+			&i.Latitude,
+			&i.Longitude,
+			&i.AssetCount,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -9280,10 +14088,11 @@ func (q *Queries) SearchPlaces(ctx context.Context, arg SearchPlacesParams) ([]S
 }
 
 const searchRandomAssets = `-- name: SearchRandomAssets :many
-SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility FROM assets a
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND ($2::boolean = true OR a."deletedAt" IS NULL)
+AND a.visibility <> 'locked'
 AND ($3::text IS NULL OR a.type = $3::text)
 AND ($4::boolean IS NULL OR a."isFavorite" = $4::boolean)
 AND ($5::text IS NULL OR e.city = $5::text)
@@ -9344,34 +14153,132 @@ func (q *Queries) SearchRandomAssets(ctx context.Context, arg SearchRandomAssets
 	for rows.Next() {
 		var i Asset
 		if err := rows.Scan(
-			&i.ID,
-			&i.DeviceAssetId,
-			&i.OwnerId,
-			&i.DeviceId,
-			&i.Type,
-			&i.OriginalPath,
-			&i.FileCreatedAt,
-			&i.FileModifiedAt,
-			&i.IsFavorite,
-			&i.Duration,
-			&i.EncodedVideoPath,
-			&i.Checksum,
-			&i.LivePhotoVideoId,
-			&i.UpdatedAt,
-			&i.CreatedAt,
-			&i.OriginalFileName,
-			&i.SidecarPath,
-			&i.Thumbhash,
-			&i.IsOffline,
-			&i.LibraryId,
-			&i.IsExternal,
-			&i.DeletedAt,
-			&i.LocalDateTime,
-			&i.StackId,
-			&i.DuplicateId,
-			&i.Status,
-			&i.UpdateId,
-			&i.Visibility,
+			&i.ID,
+			&i.DeviceAssetId,
+			&i.OwnerId,
+			&i.DeviceId,
+			&i.Type,
+			&i.OriginalPath,
+			&i.FileCreatedAt,
+			&i.FileModifiedAt,
+			&i.IsFavorite,
+			&i.Duration,
+			&i.EncodedVideoPath,
+			&i.Checksum,
+			&i.LivePhotoVideoId,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.OriginalFileName,
+			&i.SidecarPath,
+			&i.Thumbhash,
+			&i.IsOffline,
+			&i.LibraryId,
+			&i.IsExternal,
+			&i.DeletedAt,
+			&i.LocalDateTime,
+			&i.StackId,
+			&i.DuplicateId,
+			&i.Status,
+			&i.UpdateId,
+			&i.Visibility,
+			&i.ChecksumAlgorithm,
+			&i.IsUndated,
+			&i.OfflineAt,
+			&i.OriginalMimeType,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchSimilarAssets = `-- name: SearchSimilarAssets :many
+SELECT a.id, a."deviceAssetId", a."ownerId", a."deviceId", a.type, a."originalPath", a."fileCreatedAt", a."fileModifiedAt", a."isFavorite", a.duration, a."encodedVideoPath", a.checksum, a."livePhotoVideoId", a."updatedAt", a."createdAt", a."originalFileName", a."sidecarPath", a.thumbhash, a."isOffline", a."libraryId", a."isExternal", a."deletedAt", a."localDateTime", a."stackId", a."duplicateId", a.status, a."updateId", a.visibility, a."checksumAlgorithm", a."isUndated", a."offlineAt", a."originalMimeType", a.blurhash, (ss.embedding <=> src.embedding)::float8 AS distance
+FROM smart_search src
+JOIN smart_search ss ON ss."assetId" != src."assetId"
+JOIN assets a ON ss."assetId" = a.id
+WHERE src."assetId" = $1
+AND a."ownerId" = $2
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND (
+    ($3::asset_visibility_enum IS NULL AND a.visibility IN ('timeline', 'archive'))
+    OR a.visibility = $3::asset_visibility_enum
+)
+ORDER BY ss.embedding <=> src.embedding
+LIMIT $5 OFFSET $4
+`
+
+type SearchSimilarAssetsParams struct {
+	AssetID    pgtype.UUID
+	OwnerID    pgtype.UUID
+	Visibility NullAssetVisibilityEnum
+	PageOffset int32
+	PageSize   int32
+}
+
+type SearchSimilarAssetsRow struct {
+	Asset    Asset
+	Distance float64
+}
+
+// Nearest neighbors of an asset's embedding by cosine distance. Without a
+// visibility filter, hidden assets are excluded; locked assets always are.
+func (q *Queries) SearchSimilarAssets(ctx context.Context, arg SearchSimilarAssetsParams) ([]SearchSimilarAssetsRow, error) {
+	rows, err := q.db.Query(ctx, searchSimilarAssets,
+		arg.AssetID,
+		arg.OwnerID,
+		arg.Visibility,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchSimilarAssetsRow
+	for rows.Next() {
+		var i SearchSimilarAssetsRow
+		if err := rows.Scan(
+			&i.Asset.ID,
+			&i.Asset.DeviceAssetId,
+			&i.Asset.OwnerId,
+			&i.Asset.DeviceId,
+			&i.Asset.Type,
+			&i.Asset.OriginalPath,
+			&i.Asset.FileCreatedAt,
+			&i.Asset.FileModifiedAt,
+			&i.Asset.IsFavorite,
+			&i.Asset.Duration,
+			&i.Asset.EncodedVideoPath,
+			&i.Asset.Checksum,
+			&i.Asset.LivePhotoVideoId,
+			&i.Asset.UpdatedAt,
+			&i.Asset.CreatedAt,
+			&i.Asset.OriginalFileName,
+			&i.Asset.SidecarPath,
+			&i.Asset.Thumbhash,
+			&i.Asset.IsOffline,
+			&i.Asset.LibraryId,
+			&i.Asset.IsExternal,
+			&i.Asset.DeletedAt,
+			&i.Asset.LocalDateTime,
+			&i.Asset.StackId,
+			&i.Asset.DuplicateId,
+			&i.Asset.Status,
+			&i.Asset.UpdateId,
+			&i.Asset.Visibility,
+			&i.Asset.ChecksumAlgorithm,
+			&i.Asset.IsUndated,
+			&i.Asset.OfflineAt,
+			&i.Asset.OriginalMimeType,
+			&i.Asset.Blurhash,
+			&i.Distance,
 		); err != nil {
 			return nil, err
 		}
@@ -9505,6 +14412,25 @@ func (q *Queries) SearchUsersAdmin(ctx context.Context, arg SearchUsersAdminPara
 	return items, nil
 }
 
+const setAssetBlurhash = `-- name: SetAssetBlurhash :exec
+UPDATE assets
+SET blurhash = $2,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1
+`
+
+type SetAssetBlurhashParams struct {
+	ID       pgtype.UUID
+	Blurhash pgtype.Text
+}
+
+// Records the BlurHash of the generated thumbnails of an asset.
+func (q *Queries) SetAssetBlurhash(ctx context.Context, arg SetAssetBlurhashParams) error {
+	_, err := q.db.Exec(ctx, setAssetBlurhash, arg.ID, arg.Blurhash)
+	return err
+}
+
 const setAssetDuplicateId = `-- name: SetAssetDuplicateId :exec
 UPDATE assets
 SET "duplicateId" = $2,
@@ -9524,6 +14450,78 @@ func (q *Queries) SetAssetDuplicateId(ctx context.Context, arg SetAssetDuplicate
 	return err
 }
 
+const setAssetOffline = `-- name: SetAssetOffline :execrows
+UPDATE assets
+SET "isOffline" = true,
+    "offlineAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND NOT "isOffline"
+`
+
+// Flags an asset whose file disappeared; "offlineAt" keeps the time it was
+// first found missing.
+func (q *Queries) SetAssetOffline(ctx context.Context, id pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, setAssetOffline, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setAssetOriginalFile = `-- name: SetAssetOriginalFile :exec
+WITH asset AS (
+    UPDATE assets SET "originalMimeType" = $1
+    WHERE id = $2
+    RETURNING id
+)
+INSERT INTO exif ("assetId", "fileSizeInByte")
+SELECT id, $3 FROM asset
+ON CONFLICT ("assetId") DO UPDATE SET
+    "fileSizeInByte" = EXCLUDED."fileSizeInByte",
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+`
+
+type SetAssetOriginalFileParams struct {
+	MimeType       pgtype.Text
+	AssetID        pgtype.UUID
+	FileSizeInByte pgtype.Int8
+}
+
+// Records the MIME type and size of a stored original. The size is kept in
+// exif, where metadata extraction leaves it when it cannot tell.
+func (q *Queries) SetAssetOriginalFile(ctx context.Context, arg SetAssetOriginalFileParams) error {
+	_, err := q.db.Exec(ctx, setAssetOriginalFile, arg.MimeType, arg.AssetID, arg.FileSizeInByte)
+	return err
+}
+
+const setAssetThumbnailLayout = `-- name: SetAssetThumbnailLayout :exec
+WITH hashed AS (
+    UPDATE assets
+    SET thumbhash = $2,
+        "updatedAt" = now(),
+        "updateId" = immich_uuid_v7()
+    WHERE id = $1
+)
+INSERT INTO asset_thumbnail_ratios ("assetId", ratio)
+VALUES ($1, $3)
+ON CONFLICT ("assetId") DO UPDATE SET ratio = EXCLUDED.ratio, "updatedAt" = now()
+`
+
+type SetAssetThumbnailLayoutParams struct {
+	ID        pgtype.UUID
+	Thumbhash []byte
+	Ratio     float64
+}
+
+// Records the thumbhash and aspect ratio of the generated thumbnails of an
+// asset.
+func (q *Queries) SetAssetThumbnailLayout(ctx context.Context, arg SetAssetThumbnailLayoutParams) error {
+	_, err := q.db.Exec(ctx, setAssetThumbnailLayout, arg.ID, arg.Thumbhash, arg.Ratio)
+	return err
+}
+
 const setSessionPinElevation = `-- name: SetSessionPinElevation :exec
 
 UPDATE sessions
@@ -9675,6 +14673,41 @@ func (q *Queries) SetUserProfileImage(ctx context.Context, arg SetUserProfileIma
 	return i, err
 }
 
+const shareAssetsWithUser = `-- name: ShareAssetsWithUser :many
+INSERT INTO asset_user_shares ("assetId", "sharedById", "sharedWithId")
+SELECT unnest($1::uuid[]), $2, $3
+ON CONFLICT DO NOTHING
+RETURNING "assetId"
+`
+
+type ShareAssetsWithUserParams struct {
+	AssetIds     []pgtype.UUID
+	SharedById   pgtype.UUID
+	SharedWithId pgtype.UUID
+}
+
+// Shares the assets in one statement and returns the ones that were not
+// already shared with the user.
+func (q *Queries) ShareAssetsWithUser(ctx context.Context, arg ShareAssetsWithUserParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, shareAssetsWithUser, arg.AssetIds, arg.SharedById, arg.SharedWithId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var assetId pgtype.UUID
+		if err := rows.Scan(&assetId); err != nil {
+			return nil, err
+		}
+		items = append(items, assetId)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const softDeleteUser = `-- name: SoftDeleteUser :exec
 UPDATE users
 SET "deletedAt" = now(),
@@ -9707,6 +14740,33 @@ func (q *Queries) TrashAssetsByIDsAndOwner(ctx context.Context, arg TrashAssetsB
 	return err
 }
 
+const trashLibraryAssets = `-- name: TrashLibraryAssets :execrows
+UPDATE assets
+SET status = 'trashed',
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id IN (
+    SELECT id FROM assets
+    WHERE "libraryId" = $1 AND status = 'active' AND "deletedAt" IS NULL
+    ORDER BY id
+    LIMIT $2
+)
+`
+
+type TrashLibraryAssetsParams struct {
+	LibraryID pgtype.UUID
+	BatchSize int32
+}
+
+// Moves a batch of a deleted library's active assets to the trash.
+func (q *Queries) TrashLibraryAssets(ctx context.Context, arg TrashLibraryAssetsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, trashLibraryAssets, arg.LibraryID, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateAlbum = `-- name: UpdateAlbum :one
 UPDATE albums
 SET "albumName" = COALESCE($2, "albumName"),
@@ -9786,28 +14846,21 @@ func (q *Queries) UpdateApiKeyName(ctx context.Context, arg UpdateApiKeyNamePara
 const updateAsset = `-- name: UpdateAsset :one
 UPDATE assets
 SET "isFavorite" = COALESCE($2, "isFavorite"),
-    visibility = COALESCE(
-        CASE 
-            WHEN $3::boolean = true THEN 'archive'::asset_visibility_enum
-            WHEN $3::boolean = false THEN 'timeline'::asset_visibility_enum
-            ELSE visibility
-        END, 
-        visibility
-    ),
+    visibility = COALESCE($3::asset_visibility_enum, visibility),
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type UpdateAssetParams struct {
 	ID         pgtype.UUID
 	IsFavorite pgtype.Bool
-	IsArchived pgtype.Bool
+	Visibility NullAssetVisibilityEnum
 }
 
 func (q *Queries) UpdateAsset(ctx context.Context, arg UpdateAssetParams) (Asset, error) {
-	row := q.db.QueryRow(ctx, updateAsset, arg.ID, arg.IsFavorite, arg.IsArchived)
+	row := q.db.QueryRow(ctx, updateAsset, arg.ID, arg.IsFavorite, arg.Visibility)
 	var i Asset
 	err := row.Scan(
 		&i.ID,
@@ -9838,17 +14891,46 @@ func (q *Queries) UpdateAsset(ctx context.Context, arg UpdateAssetParams) (Asset
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
 
+const updateAssetCaptureDate = `-- name: UpdateAssetCaptureDate :exec
+UPDATE assets
+SET "fileCreatedAt" = $2,
+    "localDateTime" = $3,
+    "isUndated" = false,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND "deletedAt" IS NULL
+`
+
+type UpdateAssetCaptureDateParams struct {
+	ID            pgtype.UUID
+	FileCreatedAt pgtype.Timestamptz
+	LocalDateTime pgtype.Timestamptz
+}
+
+// Dates the asset with the capture date metadata extraction found, which
+// replaces the file time it was uploaded with and moves it out of the
+// undated bucket.
+func (q *Queries) UpdateAssetCaptureDate(ctx context.Context, arg UpdateAssetCaptureDateParams) error {
+	_, err := q.db.Exec(ctx, updateAssetCaptureDate, arg.ID, arg.FileCreatedAt, arg.LocalDateTime)
+	return err
+}
+
 const updateAssetEncodedVideoPath = `-- name: UpdateAssetEncodedVideoPath :one
 UPDATE assets
 SET "encodedVideoPath" = $2,
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type UpdateAssetEncodedVideoPathParams struct {
@@ -9888,6 +14970,11 @@ func (q *Queries) UpdateAssetEncodedVideoPath(ctx context.Context, arg UpdateAss
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
@@ -9900,7 +14987,7 @@ SET "personId" = COALESCE($2, "personId"),
     "boundingBoxX2" = COALESCE($5, "boundingBoxX2"),
     "boundingBoxY2" = COALESCE($6, "boundingBoxY2")
 WHERE id = $1
-RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt"
+RETURNING "assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", id, "sourceType", "deletedAt", score
 `
 
 type UpdateAssetFaceParams struct {
@@ -9934,6 +15021,7 @@ func (q *Queries) UpdateAssetFace(ctx context.Context, arg UpdateAssetFaceParams
 		&i.ID,
 		&i.SourceType,
 		&i.DeletedAt,
+		&i.Score,
 	)
 	return i, err
 }
@@ -9967,6 +15055,22 @@ func (q *Queries) UpdateAssetFileInfo(ctx context.Context, arg UpdateAssetFileIn
 	return err
 }
 
+const updateAssetFilePath = `-- name: UpdateAssetFilePath :exec
+UPDATE asset_files
+SET path = $2, "updatedAt" = now(), "updateId" = immich_uuid_v7()
+WHERE id = $1
+`
+
+type UpdateAssetFilePathParams struct {
+	ID   pgtype.UUID
+	Path string
+}
+
+func (q *Queries) UpdateAssetFilePath(ctx context.Context, arg UpdateAssetFilePathParams) error {
+	_, err := q.db.Exec(ctx, updateAssetFilePath, arg.ID, arg.Path)
+	return err
+}
+
 const updateAssetJobStatus = `-- name: UpdateAssetJobStatus :one
 UPDATE asset_job_status
 SET "facesRecognizedAt" = COALESCE($2, "facesRecognizedAt"),
@@ -10011,6 +15115,7 @@ func (q *Queries) UpdateAssetJobStatus(ctx context.Context, arg UpdateAssetJobSt
 const updateAssetLocalDateTime = `-- name: UpdateAssetLocalDateTime :exec
 UPDATE assets
 SET "localDateTime" = $2,
+    "isUndated" = false,
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL
@@ -10021,18 +15126,36 @@ type UpdateAssetLocalDateTimeParams struct {
 	LocalDateTime pgtype.Timestamptz
 }
 
+// Setting the capture date moves the asset out of the undated bucket.
 func (q *Queries) UpdateAssetLocalDateTime(ctx context.Context, arg UpdateAssetLocalDateTimeParams) error {
 	_, err := q.db.Exec(ctx, updateAssetLocalDateTime, arg.ID, arg.LocalDateTime)
 	return err
 }
 
+const updateAssetSidecarPath = `-- name: UpdateAssetSidecarPath :exec
+UPDATE assets
+SET "sidecarPath" = $2,
+    "updatedAt" = now()
+WHERE id = $1
+`
+
+type UpdateAssetSidecarPathParams struct {
+	ID          pgtype.UUID
+	SidecarPath pgtype.Text
+}
+
+func (q *Queries) UpdateAssetSidecarPath(ctx context.Context, arg UpdateAssetSidecarPathParams) error {
+	_, err := q.db.Exec(ctx, updateAssetSidecarPath, arg.ID, arg.SidecarPath)
+	return err
+}
+
 const updateAssetStatus = `-- name: UpdateAssetStatus :one
 UPDATE assets
 SET status = $2,
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL
-RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
 `
 
 type UpdateAssetStatusParams struct {
@@ -10072,6 +15195,11 @@ func (q *Queries) UpdateAssetStatus(ctx context.Context, arg UpdateAssetStatusPa
 		&i.Status,
 		&i.UpdateId,
 		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
 	)
 	return i, err
 }
@@ -10110,29 +15238,141 @@ type UpdateExifParams struct {
 	Fps              pgtype.Float8
 }
 
-func (q *Queries) UpdateExif(ctx context.Context, arg UpdateExifParams) (Exif, error) {
-	row := q.db.QueryRow(ctx, updateExif,
-		arg.AssetId,
-		arg.Make,
-		arg.Model,
-		arg.ExifImageWidth,
-		arg.ExifImageHeight,
-		arg.FileSizeInByte,
-		arg.Orientation,
-		arg.DateTimeOriginal,
-		arg.ModifyDate,
-		arg.LensModel,
-		arg.FNumber,
-		arg.FocalLength,
-		arg.Iso,
-		arg.Latitude,
-		arg.Longitude,
-		arg.City,
-		arg.State,
-		arg.Country,
-		arg.Description,
-		arg.Fps,
-	)
+func (q *Queries) UpdateExif(ctx context.Context, arg UpdateExifParams) (Exif, error) {
+	row := q.db.QueryRow(ctx, updateExif,
+		arg.AssetId,
+		arg.Make,
+		arg.Model,
+		arg.ExifImageWidth,
+		arg.ExifImageHeight,
+		arg.FileSizeInByte,
+		arg.Orientation,
+		arg.DateTimeOriginal,
+		arg.ModifyDate,
+		arg.LensModel,
+		arg.FNumber,
+		arg.FocalLength,
+		arg.Iso,
+		arg.Latitude,
+		arg.Longitude,
+		arg.City,
+		arg.State,
+		arg.Country,
+		arg.Description,
+		arg.Fps,
+	)
+	var i Exif
+	err := row.Scan(
+		&i.AssetId,
+		&i.Make,
+		&i.Model,
+		&i.ExifImageWidth,
+		&i.ExifImageHeight,
+		&i.FileSizeInByte,
+		&i.Orientation,
+		&i.DateTimeOriginal,
+		&i.ModifyDate,
+		&i.LensModel,
+		&i.FNumber,
+		&i.FocalLength,
+		&i.Iso,
+		&i.Latitude,
+		&i.Longitude,
+		&i.City,
+		&i.State,
+		&i.Country,
+		&i.Description,
+		&i.Fps,
+		&i.ExposureTime,
+		&i.LivePhotoCID,
+		&i.TimeZone,
+		&i.ProjectionType,
+		&i.ProfileDescription,
+		&i.Colorspace,
+		&i.BitsPerSample,
+		&i.AutoStackId,
+		&i.Rating,
+		&i.UpdatedAt,
+		&i.UpdateId,
+	)
+	return i, err
+}
+
+const updateExifDateTimeOriginal = `-- name: UpdateExifDateTimeOriginal :one
+INSERT INTO exif ("assetId", "dateTimeOriginal", "timeZone")
+VALUES ($1, $2, $3)
+ON CONFLICT ("assetId") DO UPDATE SET
+    "dateTimeOriginal" = EXCLUDED."dateTimeOriginal",
+    "timeZone" = COALESCE(EXCLUDED."timeZone", exif."timeZone"),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+RETURNING "assetId", make, model, "exifImageWidth", "exifImageHeight", "fileSizeInByte", orientation, "dateTimeOriginal", "modifyDate", "lensModel", "fNumber", "focalLength", iso, latitude, longitude, city, state, country, description, fps, "exposureTime", "livePhotoCID", "timeZone", "projectionType", "profileDescription", colorspace, "bitsPerSample", "autoStackId", rating, "updatedAt", "updateId"
+`
+
+type UpdateExifDateTimeOriginalParams struct {
+	AssetID          pgtype.UUID
+	DateTimeOriginal pgtype.Timestamptz
+	TimeZone         pgtype.Text
+}
+
+func (q *Queries) UpdateExifDateTimeOriginal(ctx context.Context, arg UpdateExifDateTimeOriginalParams) (Exif, error) {
+	row := q.db.QueryRow(ctx, updateExifDateTimeOriginal, arg.AssetID, arg.DateTimeOriginal, arg.TimeZone)
+	var i Exif
+	err := row.Scan(
+		&i.AssetId,
+		&i.Make,
+		&i.Model,
+		&i.ExifImageWidth,
+		&i.ExifImageHeight,
+		&i.FileSizeInByte,
+		&i.Orientation,
+		&i.DateTimeOriginal,
+		&i.ModifyDate,
+		&i.LensModel,
+		&i.FNumber,
+		&i.FocalLength,
+		&i.Iso,
+		&i.Latitude,
+		&i.Longitude,
+		&i.City,
+		&i.State,
+		&i.Country,
+		&i.Description,
+		&i.Fps,
+		&i.ExposureTime,
+		&i.LivePhotoCID,
+		&i.TimeZone,
+		&i.ProjectionType,
+		&i.ProfileDescription,
+		&i.Colorspace,
+		&i.BitsPerSample,
+		&i.AutoStackId,
+		&i.Rating,
+		&i.UpdatedAt,
+		&i.UpdateId,
+	)
+	return i, err
+}
+
+const updateExifLocation = `-- name: UpdateExifLocation :one
+INSERT INTO exif ("assetId", latitude, longitude)
+VALUES ($1, $2, $3)
+ON CONFLICT ("assetId") DO UPDATE SET
+    latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+RETURNING "assetId", make, model, "exifImageWidth", "exifImageHeight", "fileSizeInByte", orientation, "dateTimeOriginal", "modifyDate", "lensModel", "fNumber", "focalLength", iso, latitude, longitude, city, state, country, description, fps, "exposureTime", "livePhotoCID", "timeZone", "projectionType", "profileDescription", colorspace, "bitsPerSample", "autoStackId", rating, "updatedAt", "updateId"
+`
+
+type UpdateExifLocationParams struct {
+	AssetID   pgtype.UUID
+	Latitude  pgtype.Float8
+	Longitude pgtype.Float8
+}
+
+func (q *Queries) UpdateExifLocation(ctx context.Context, arg UpdateExifLocationParams) (Exif, error) {
+	row := q.db.QueryRow(ctx, updateExifLocation, arg.AssetID, arg.Latitude, arg.Longitude)
 	var i Exif
 	err := row.Scan(
 		&i.AssetId,
@@ -10170,6 +15410,31 @@ func (q *Queries) UpdateExif(ctx context.Context, arg UpdateExifParams) (Exif, e
 	return i, err
 }
 
+const updateExifPlace = `-- name: UpdateExifPlace :exec
+UPDATE exif
+SET city = $2, state = $3, country = $4,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "assetId" = $1
+`
+
+type UpdateExifPlaceParams struct {
+	AssetId pgtype.UUID
+	City    pgtype.Text
+	State   pgtype.Text
+	Country pgtype.Text
+}
+
+func (q *Queries) UpdateExifPlace(ctx context.Context, arg UpdateExifPlaceParams) error {
+	_, err := q.db.Exec(ctx, updateExifPlace,
+		arg.AssetId,
+		arg.City,
+		arg.State,
+		arg.Country,
+	)
+	return err
+}
+
 const updateLibrary = `-- name: UpdateLibrary :one
 UPDATE libraries
 SET name = COALESCE($2, name),
@@ -10265,6 +15530,96 @@ func (q *Queries) UpdateMemory(ctx context.Context, arg UpdateMemoryParams) (Mem
 	return i, err
 }
 
+const updateMigratedAssetFilePaths = `-- name: UpdateMigratedAssetFilePaths :execrows
+UPDATE asset_files af
+SET path = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND af.path = m."sourcePath"
+AND m."sourcePath" != m."destinationPath"
+`
+
+type UpdateMigratedAssetFilePathsParams struct {
+	FromBackend string
+	ToBackend   string
+}
+
+func (q *Queries) UpdateMigratedAssetFilePaths(ctx context.Context, arg UpdateMigratedAssetFilePathsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateMigratedAssetFilePaths, arg.FromBackend, arg.ToBackend)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateMigratedEncodedVideoPaths = `-- name: UpdateMigratedEncodedVideoPaths :execrows
+UPDATE assets a
+SET "encodedVideoPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."encodedVideoPath" = m."sourcePath"
+AND m."sourcePath" != m."destinationPath"
+`
+
+type UpdateMigratedEncodedVideoPathsParams struct {
+	FromBackend string
+	ToBackend   string
+}
+
+func (q *Queries) UpdateMigratedEncodedVideoPaths(ctx context.Context, arg UpdateMigratedEncodedVideoPathsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateMigratedEncodedVideoPaths, arg.FromBackend, arg.ToBackend)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateMigratedOriginalPaths = `-- name: UpdateMigratedOriginalPaths :execrows
+UPDATE assets a
+SET "originalPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."originalPath" = m."sourcePath"
+AND a."isExternal" = false
+AND m."sourcePath" != m."destinationPath"
+`
+
+type UpdateMigratedOriginalPathsParams struct {
+	FromBackend string
+	ToBackend   string
+}
+
+func (q *Queries) UpdateMigratedOriginalPaths(ctx context.Context, arg UpdateMigratedOriginalPathsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateMigratedOriginalPaths, arg.FromBackend, arg.ToBackend)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateMigratedSidecarPaths = `-- name: UpdateMigratedSidecarPaths :execrows
+UPDATE assets a
+SET "sidecarPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."sidecarPath" = m."sourcePath"
+AND a."isExternal" = false
+AND m."sourcePath" != m."destinationPath"
+`
+
+type UpdateMigratedSidecarPathsParams struct {
+	FromBackend string
+	ToBackend   string
+}
+
+func (q *Queries) UpdateMigratedSidecarPaths(ctx context.Context, arg UpdateMigratedSidecarPathsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateMigratedSidecarPaths, arg.FromBackend, arg.ToBackend)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateNotification = `-- name: UpdateNotification :one
 UPDATE notifications
 SET "readAt" = $2,
@@ -10303,7 +15658,7 @@ UPDATE partners
 SET "inTimeline" = $3,
     "updatedAt" = now()
 WHERE ("sharedById" = $1 AND "sharedWithId" = $2) OR ("sharedById" = $2 AND "sharedWithId" = $1)
-RETURNING "sharedById", "sharedWithId", "createdAt", "updatedAt", "inTimeline", "updateId"
+RETURNING "sharedById", "sharedWithId", "createdAt", "updatedAt", "inTimeline", "updateId", status, "acceptedAt"
 `
 
 type UpdatePartnershipParams struct {
@@ -10322,6 +15677,8 @@ func (q *Queries) UpdatePartnership(ctx context.Context, arg UpdatePartnershipPa
 		&i.UpdatedAt,
 		&i.InTimeline,
 		&i.UpdateId,
+		&i.Status,
+		&i.AcceptedAt,
 	)
 	return i, err
 }
@@ -10752,6 +16109,47 @@ func (q *Queries) UpdateUserPreferencesData(ctx context.Context, arg UpdateUserP
 	return value, err
 }
 
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = $2, events = $3, secret = $4, enabled = $5, "updatedAt" = now()
+WHERE id = $1
+RETURNING id, url, events, secret, enabled, "createdAt", "updatedAt", "lastDeliveryAt", "lastSuccessAt", "lastStatusCode", "lastError", "failureCount"
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	ID      pgtype.UUID
+	Url     string
+	Events  []string
+	Secret  string
+	Enabled bool
+}
+
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRow(ctx, updateWebhookSubscription,
+		arg.ID,
+		arg.Url,
+		arg.Events,
+		arg.Secret,
+		arg.Enabled,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.Url,
+		&i.Events,
+		&i.Secret,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastDeliveryAt,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.FailureCount,
+	)
+	return i, err
+}
+
 const updateWorkflow = `-- name: UpdateWorkflow :one
 UPDATE workflows
 SET name = COALESCE($2, name),
@@ -10803,6 +16201,71 @@ func (q *Queries) UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams)
 	return i, err
 }
 
+const upsertAssetFile = `-- name: UpsertAssetFile :one
+INSERT INTO asset_files ("assetId", "type", "path", width, height, "sizeInBytes")
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT ("assetId", "type") DO UPDATE
+SET "path" = EXCLUDED."path", width = EXCLUDED.width, height = EXCLUDED.height,
+    "sizeInBytes" = EXCLUDED."sizeInBytes", "updatedAt" = now(), "updateId" = immich_uuid_v7()
+RETURNING id, "assetId", "createdAt", "updatedAt", type, path, "updateId", width, height, "sizeInBytes"
+`
+
+type UpsertAssetFileParams struct {
+	AssetId     pgtype.UUID
+	Type        string
+	Path        string
+	Width       pgtype.Int4
+	Height      pgtype.Int4
+	SizeInBytes pgtype.Int8
+}
+
+// Records a generated file of an asset, replacing the one of its type
+// generated before.
+func (q *Queries) UpsertAssetFile(ctx context.Context, arg UpsertAssetFileParams) (AssetFile, error) {
+	row := q.db.QueryRow(ctx, upsertAssetFile,
+		arg.AssetId,
+		arg.Type,
+		arg.Path,
+		arg.Width,
+		arg.Height,
+		arg.SizeInBytes,
+	)
+	var i AssetFile
+	err := row.Scan(
+		&i.ID,
+		&i.AssetId,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Type,
+		&i.Path,
+		&i.UpdateId,
+		&i.Width,
+		&i.Height,
+		&i.SizeInBytes,
+	)
+	return i, err
+}
+
+const upsertAssetLabels = `-- name: UpsertAssetLabels :exec
+INSERT INTO asset_labels ("assetId", label, score)
+SELECT $1, l.label, l.score
+FROM unnest($2::text[], $3::real[]) AS l(label, score)
+ON CONFLICT ("assetId", label) DO UPDATE SET score = EXCLUDED.score, "createdAt" = now()
+`
+
+type UpsertAssetLabelsParams struct {
+	AssetID pgtype.UUID
+	Labels  []string
+	Scores  []float32
+}
+
+// Stores the detected labels of an asset, refreshing the score of labels
+// detected before.
+func (q *Queries) UpsertAssetLabels(ctx context.Context, arg UpsertAssetLabelsParams) error {
+	_, err := q.db.Exec(ctx, upsertAssetLabels, arg.AssetID, arg.Labels, arg.Scores)
+	return err
+}
+
 const upsertAssetMetadata = `-- name: UpsertAssetMetadata :one
 INSERT INTO asset_metadata ("assetId", key, value)
 VALUES ($1, $2, $3)
@@ -10868,6 +16331,111 @@ func (q *Queries) UpsertDatabaseBackup(ctx context.Context, arg UpsertDatabaseBa
 	return i, err
 }
 
+const upsertDeviceAsset = `-- name: UpsertDeviceAsset :one
+INSERT INTO assets (
+    "deviceAssetId", "ownerId", "deviceId", type, "originalPath",
+    "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
+    checksum, "isFavorite", visibility, status, "checksumAlgorithm", "isUndated"
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14, 'sha1'),
+    COALESCE($15::boolean, false))
+ON CONFLICT ("ownerId", "deviceId", "deviceAssetId")
+    WHERE "libraryId" IS NULL AND "deviceId" <> '' AND "deviceAssetId" <> ''
+DO UPDATE SET
+    type = EXCLUDED.type,
+    "originalPath" = EXCLUDED."originalPath",
+    "fileCreatedAt" = EXCLUDED."fileCreatedAt",
+    "fileModifiedAt" = EXCLUDED."fileModifiedAt",
+    "localDateTime" = EXCLUDED."localDateTime",
+    "originalFileName" = EXCLUDED."originalFileName",
+    checksum = EXCLUDED.checksum,
+    "checksumAlgorithm" = EXCLUDED."checksumAlgorithm",
+    "isUndated" = EXCLUDED."isUndated",
+    "encodedVideoPath" = '',
+    thumbhash = NULL,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+RETURNING id, "deviceAssetId", "ownerId", "deviceId", type, "originalPath", "fileCreatedAt", "fileModifiedAt", "isFavorite", duration, "encodedVideoPath", checksum, "livePhotoVideoId", "updatedAt", "createdAt", "originalFileName", "sidecarPath", thumbhash, "isOffline", "libraryId", "isExternal", "deletedAt", "localDateTime", "stackId", "duplicateId", status, "updateId", visibility, "checksumAlgorithm", "isUndated", "offlineAt", "originalMimeType", blurhash
+`
+
+type UpsertDeviceAssetParams struct {
+	DeviceAssetId     string
+	OwnerId           pgtype.UUID
+	DeviceId          string
+	Type              string
+	OriginalPath      string
+	FileCreatedAt     pgtype.Timestamptz
+	FileModifiedAt    pgtype.Timestamptz
+	LocalDateTime     pgtype.Timestamptz
+	OriginalFileName  string
+	Checksum          []byte
+	IsFavorite        bool
+	Visibility        AssetVisibilityEnum
+	Status            AssetsStatusEnum
+	ChecksumAlgorithm interface{}
+	IsUndated         pgtype.Bool
+}
+
+// A repeated backup of a device asset replaces the file of the asset the
+// first one created. What changed on the server since, like the favorite,
+// visibility or trash state, is kept.
+func (q *Queries) UpsertDeviceAsset(ctx context.Context, arg UpsertDeviceAssetParams) (Asset, error) {
+	row := q.db.QueryRow(ctx, upsertDeviceAsset,
+		arg.DeviceAssetId,
+		arg.OwnerId,
+		arg.DeviceId,
+		arg.Type,
+		arg.OriginalPath,
+		arg.FileCreatedAt,
+		arg.FileModifiedAt,
+		arg.LocalDateTime,
+		arg.OriginalFileName,
+		arg.Checksum,
+		arg.IsFavorite,
+		arg.Visibility,
+		arg.Status,
+		arg.ChecksumAlgorithm,
+		arg.IsUndated,
+	)
+	var i Asset
+	err := row.Scan(
+		&i.ID,
+		&i.DeviceAssetId,
+		&i.OwnerId,
+		&i.DeviceId,
+		&i.Type,
+		&i.OriginalPath,
+		&i.FileCreatedAt,
+		&i.FileModifiedAt,
+		&i.IsFavorite,
+		&i.Duration,
+		&i.EncodedVideoPath,
+		&i.Checksum,
+		&i.LivePhotoVideoId,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.OriginalFileName,
+		&i.SidecarPath,
+		&i.Thumbhash,
+		&i.IsOffline,
+		&i.LibraryId,
+		&i.IsExternal,
+		&i.DeletedAt,
+		&i.LocalDateTime,
+		&i.StackId,
+		&i.DuplicateId,
+		&i.Status,
+		&i.UpdateId,
+		&i.Visibility,
+		&i.ChecksumAlgorithm,
+		&i.IsUndated,
+		&i.OfflineAt,
+		&i.OriginalMimeType,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
 const upsertFaceSearch = `-- name: UpsertFaceSearch :one
 INSERT INTO face_search ("faceId", embedding)
 VALUES ($1, $2)
@@ -10887,6 +16455,95 @@ func (q *Queries) UpsertFaceSearch(ctx context.Context, arg UpsertFaceSearchPara
 	return i, err
 }
 
+const upsertGeodataPlaces = `-- name: UpsertGeodataPlaces :execrows
+INSERT INTO geodata_places (
+    id, name, longitude, latitude, "countryCode", "admin1Code", "admin2Code",
+    "modificationDate", "admin1Name", "admin2Name", "alternateNames"
+)
+SELECT * FROM unnest(
+    $1::integer[], $2::text[], $3::double precision[],
+    $4::double precision[], $5::text[],
+    $6::text[], $7::text[],
+    $8::date[], $9::text[],
+    $10::text[], $11::text[]
+)
+ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    longitude = EXCLUDED.longitude,
+    latitude = EXCLUDED.latitude,
+    "countryCode" = EXCLUDED."countryCode",
+    "admin1Code" = EXCLUDED."admin1Code",
+    "admin2Code" = EXCLUDED."admin2Code",
+    "modificationDate" = EXCLUDED."modificationDate",
+    "admin1Name" = EXCLUDED."admin1Name",
+    "admin2Name" = EXCLUDED."admin2Name",
+    "alternateNames" = EXCLUDED."alternateNames"
+`
+
+type UpsertGeodataPlacesParams struct {
+	Ids               []int32
+	Names             []string
+	Longitudes        []float64
+	Latitudes         []float64
+	CountryCodes      []string
+	Admin1Codes       []pgtype.Text
+	Admin2Codes       []pgtype.Text
+	ModificationDates []pgtype.Date
+	Admin1Names       []pgtype.Text
+	Admin2Names       []pgtype.Text
+	AlternateNames    []pgtype.Text
+}
+
+// Inserts or updates a batch of places, one array element per place.
+func (q *Queries) UpsertGeodataPlaces(ctx context.Context, arg UpsertGeodataPlacesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertGeodataPlaces,
+		arg.Ids,
+		arg.Names,
+		arg.Longitudes,
+		arg.Latitudes,
+		arg.CountryCodes,
+		arg.Admin1Codes,
+		arg.Admin2Codes,
+		arg.ModificationDates,
+		arg.Admin1Names,
+		arg.Admin2Names,
+		arg.AlternateNames,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertLibraryFolderAlbumSettings = `-- name: UpsertLibraryFolderAlbumSettings :one
+INSERT INTO library_folder_album_settings ("libraryId", depth, naming)
+VALUES ($1, $2, $3)
+ON CONFLICT ("libraryId") DO UPDATE
+SET depth = EXCLUDED.depth,
+    naming = EXCLUDED.naming,
+    "updatedAt" = now()
+RETURNING "libraryId", depth, naming, "updatedAt"
+`
+
+type UpsertLibraryFolderAlbumSettingsParams struct {
+	LibraryId pgtype.UUID
+	Depth     int32
+	Naming    string
+}
+
+// Turns on folder albums for a library, or changes how they are made.
+func (q *Queries) UpsertLibraryFolderAlbumSettings(ctx context.Context, arg UpsertLibraryFolderAlbumSettingsParams) (LibraryFolderAlbumSetting, error) {
+	row := q.db.QueryRow(ctx, upsertLibraryFolderAlbumSettings, arg.LibraryId, arg.Depth, arg.Naming)
+	var i LibraryFolderAlbumSetting
+	err := row.Scan(
+		&i.LibraryId,
+		&i.Depth,
+		&i.Naming,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const upsertSmartSearch = `-- name: UpsertSmartSearch :one
 INSERT INTO smart_search ("assetId", embedding)
 VALUES ($1, $2)
@@ -10905,3 +16562,86 @@ func (q *Queries) UpsertSmartSearch(ctx context.Context, arg UpsertSmartSearchPa
 	err := row.Scan(&i.AssetId, &i.Embedding)
 	return i, err
 }
+
+const upsertStorageMigrationFile = `-- name: UpsertStorageMigrationFile :exec
+INSERT INTO storage_migration_files ("fromBackend", "toBackend", "sourcePath", "destinationPath", size, checksum)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT ("fromBackend", "toBackend", "sourcePath") DO UPDATE
+SET "destinationPath" = EXCLUDED."destinationPath",
+    size = EXCLUDED.size,
+    checksum = EXCLUDED.checksum,
+    status = 'verified',
+    "updatedAt" = now()
+`
+
+type UpsertStorageMigrationFileParams struct {
+	FromBackend     string
+	ToBackend       string
+	SourcePath      string
+	DestinationPath string
+	Size            int64
+	Checksum        []byte
+}
+
+func (q *Queries) UpsertStorageMigrationFile(ctx context.Context, arg UpsertStorageMigrationFileParams) error {
+	_, err := q.db.Exec(ctx, upsertStorageMigrationFile,
+		arg.FromBackend,
+		arg.ToBackend,
+		arg.SourcePath,
+		arg.DestinationPath,
+		arg.Size,
+		arg.Checksum,
+	)
+	return err
+}
+
+const upsertUploadSessionChunk = `-- name: UpsertUploadSessionChunk :exec
+INSERT INTO upload_session_chunks ("assetId", start, size)
+VALUES ($1, $2, $3)
+ON CONFLICT ("assetId", start) DO UPDATE SET
+    size = EXCLUDED.size,
+    "receivedAt" = now()
+`
+
+type UpsertUploadSessionChunkParams struct {
+	AssetId pgtype.UUID
+	Start   int64
+	Size    int64
+}
+
+// Records a received chunk. A chunk sent again replaces the one received
+// at the same offset.
+func (q *Queries) UpsertUploadSessionChunk(ctx context.Context, arg UpsertUploadSessionChunkParams) error {
+	_, err := q.db.Exec(ctx, upsertUploadSessionChunk, arg.AssetId, arg.Start, arg.Size)
+	return err
+}
+
+const upsertUserLimits = `-- name: UpsertUserLimits :one
+INSERT INTO user_limits ("userId", "maxSharedLinks", "maxApiKeys")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "maxSharedLinks" = EXCLUDED."maxSharedLinks",
+    "maxApiKeys" = EXCLUDED."maxApiKeys",
+    "updatedAt" = now()
+RETURNING "userId", "maxSharedLinks", "maxApiKeys", "updatedAt"
+`
+
+type UpsertUserLimitsParams struct {
+	UserId         pgtype.UUID
+	MaxSharedLinks pgtype.Int4
+	MaxApiKeys     pgtype.Int4
+}
+
+// Sets the limit overrides of a user; a NULL limit falls back to the
+// configured one.
+func (q *Queries) UpsertUserLimits(ctx context.Context, arg UpsertUserLimitsParams) (UserLimit, error) {
+	row := q.db.QueryRow(ctx, upsertUserLimits, arg.UserId, arg.MaxSharedLinks, arg.MaxApiKeys)
+	var i UserLimit
+	err := row.Scan(
+		&i.UserId,
+		&i.MaxSharedLinks,
+		&i.MaxApiKeys,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
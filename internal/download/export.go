@@ -0,0 +1,332 @@
+package download
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+const (
+	// DefaultExportPartSize is the number of assets in one export archive.
+	// Large accounts are exported as a sequence of parts.
+	DefaultExportPartSize = 1000
+	// MaxExportPartSize caps the assets a single request may stream.
+	MaxExportPartSize = 10000
+
+	exportManifestName    = "manifest.json"
+	exportManifestVersion = 1
+)
+
+// ErrInvalidExportRequest is wrapped by the errors ExportUserData returns for
+// a limit or cursor it cannot use.
+var ErrInvalidExportRequest = errors.New("invalid export request")
+
+// ExportRequest selects one part of a user data export
+type ExportRequest struct {
+	// After resumes the export behind this asset ID; empty starts at the
+	// first asset.
+	After string
+	// Limit is the number of assets in the part, DefaultExportPartSize if 0.
+	Limit int
+}
+
+// ExportManifest describes the contents of one export archive
+type ExportManifest struct {
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exportedAt"`
+	User       ExportUser    `json:"user"`
+	After      string        `json:"after,omitempty"`
+	Next       string        `json:"next,omitempty"`
+	Albums     []ExportAlbum `json:"albums"`
+	Tags       []ExportTag   `json:"tags"`
+	Assets     []ExportAsset `json:"assets"`
+	// Missing lists assets whose original could not be read from storage.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// ExportUser is the account the export belongs to
+type ExportUser struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ExportAlbum is an album owned by the exported user
+type ExportAlbum struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"albumName"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ExportTag is a tag of the exported user
+type ExportTag struct {
+	ID       string `json:"id"`
+	Value    string `json:"value"`
+	Color    string `json:"color,omitempty"`
+	ParentID string `json:"parentId,omitempty"`
+}
+
+// ExportAsset is the metadata of one exported asset
+type ExportAsset struct {
+	ID               string     `json:"id"`
+	Path             string     `json:"path,omitempty"`
+	OriginalFileName string     `json:"originalFileName"`
+	Type             string     `json:"type"`
+	Checksum         string     `json:"checksum"`
+	FileSizeInByte   *int64     `json:"fileSizeInByte,omitempty"`
+	FileCreatedAt    time.Time  `json:"fileCreatedAt"`
+	LocalDateTime    time.Time  `json:"localDateTime"`
+	DateTimeOriginal *time.Time `json:"dateTimeOriginal,omitempty"`
+	TimeZone         string     `json:"timeZone,omitempty"`
+	Latitude         *float64   `json:"latitude,omitempty"`
+	Longitude        *float64   `json:"longitude,omitempty"`
+	City             string     `json:"city,omitempty"`
+	State            string     `json:"state,omitempty"`
+	Country          string     `json:"country,omitempty"`
+	Make             string     `json:"make,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	Description      string     `json:"description,omitempty"`
+	Rating           *int32     `json:"rating,omitempty"`
+	IsFavorite       bool       `json:"isFavorite"`
+	Visibility       string     `json:"visibility"`
+	TrashedAt        *time.Time `json:"trashedAt,omitempty"`
+	AlbumIDs         []string   `json:"albumIds"`
+	Tags             []string   `json:"tags"`
+}
+
+// UserExport is one part of a user data export: the metadata is loaded up
+// front so the caller can announce the next part before streaming.
+type UserExport struct {
+	Manifest ExportManifest
+	// Next is the cursor of the following part, empty on the last part.
+	Next string
+
+	storageService *storage.Service
+	assets         []sqlc.ListUserAssetsForExportRow
+}
+
+// ExportUserData prepares one part of an export of everything the user
+// uploaded: the originals plus a JSON manifest of their metadata, albums and
+// tags. Trashed assets are included.
+func (s *Service) ExportUserData(ctx context.Context, userID uuid.UUID, req ExportRequest) (*UserExport, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultExportPartSize
+	}
+	if limit > MaxExportPartSize {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrInvalidExportRequest, MaxExportPartSize)
+	}
+	after := pgtype.UUID{Valid: true}
+	if req.After != "" {
+		parsed, err := uuid.Parse(req.After)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid export cursor: %v", ErrInvalidExportRequest, err)
+		}
+		after.Bytes = parsed
+	}
+
+	owner := pgtype.UUID{Bytes: userID, Valid: true}
+	user, err := s.db.GetUser(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// One extra row tells whether another part follows.
+	rows, err := s.db.ListUserAssetsForExport(ctx, sqlc.ListUserAssetsForExportParams{
+		OwnerID:   owner,
+		AfterID:   after,
+		BatchSize: int32(limit + 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assets: %w", err)
+	}
+	albums, err := s.db.GetAlbumsByOwner(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list albums: %w", err)
+	}
+	tags, err := s.db.GetTags(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	export := &UserExport{storageService: s.storageService}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		export.Next = pgutil.UUIDToString(rows[len(rows)-1].ID)
+	}
+	export.assets = rows
+	export.Manifest = newExportManifest(user, albums, tags, req.After, export.Next)
+	return export, nil
+}
+
+// WriteArchive streams the part as a ZIP archive with the originals under
+// originals/ and the manifest as the last entry. Originals missing from
+// storage are listed in the manifest; a failure while copying one aborts
+// the stream, since the entry cannot be completed, and the part has to be
+// requested again.
+func (e *UserExport) WriteArchive(ctx context.Context, w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	manifest := e.Manifest
+	manifest.Assets = make([]ExportAsset, 0, len(e.assets))
+	addedFiles := make(map[string]bool)
+
+	for _, row := range e.assets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		asset := exportAssetFromRow(row)
+		reader, err := e.storageService.Download(ctx, exportStoragePath(row))
+		if err != nil {
+			manifest.Missing = append(manifest.Missing, asset.ID)
+			manifest.Assets = append(manifest.Assets, asset)
+			continue
+		}
+
+		asset.Path = uniqueArchivePath(addedFiles, exportArchivePath(row))
+		// Originals are already compressed; storing them keeps large
+		// exports cheap to produce.
+		fileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     asset.Path,
+			Method:   zip.Store,
+			Modified: row.FileCreatedAt.Time,
+		})
+		if err == nil {
+			_, err = io.Copy(fileWriter, reader)
+		}
+		if closeErr := reader.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to export asset %s: %w", asset.ID, err)
+		}
+		manifest.Assets = append(manifest.Assets, asset)
+	}
+
+	manifest.ExportedAt = time.Now().UTC()
+	manifestWriter, err := zipWriter.Create(exportManifestName)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return zipWriter.Close()
+}
+
+func newExportManifest(user sqlc.User, albums []sqlc.Album, tags []sqlc.Tag, after, next string) ExportManifest {
+	manifest := ExportManifest{
+		Version: exportManifestVersion,
+		User: ExportUser{
+			ID:        pgutil.UUIDToString(user.ID),
+			Email:     user.Email,
+			Name:      user.Name,
+			CreatedAt: user.CreatedAt.Time,
+		},
+		After:  after,
+		Next:   next,
+		Albums: make([]ExportAlbum, 0, len(albums)),
+		Tags:   make([]ExportTag, 0, len(tags)),
+	}
+	for _, album := range albums {
+		manifest.Albums = append(manifest.Albums, ExportAlbum{
+			ID:          pgutil.UUIDToString(album.ID),
+			Name:        album.AlbumName,
+			Description: album.Description,
+			CreatedAt:   album.CreatedAt.Time,
+		})
+	}
+	for _, tag := range tags {
+		exportTag := ExportTag{
+			ID:    pgutil.UUIDToString(tag.ID),
+			Value: tag.Value,
+			Color: tag.Color.String,
+		}
+		if tag.ParentId.Valid {
+			exportTag.ParentID = pgutil.UUIDToString(tag.ParentId)
+		}
+		manifest.Tags = append(manifest.Tags, exportTag)
+	}
+	return manifest
+}
+
+func exportAssetFromRow(row sqlc.ListUserAssetsForExportRow) ExportAsset {
+	asset := ExportAsset{
+		ID:               pgutil.UUIDToString(row.ID),
+		OriginalFileName: row.OriginalFileName,
+		Type:             row.Type,
+		Checksum:         base64.StdEncoding.EncodeToString(row.Checksum),
+		FileCreatedAt:    row.FileCreatedAt.Time,
+		LocalDateTime:    row.LocalDateTime.Time,
+		TimeZone:         row.TimeZone.String,
+		City:             row.City.String,
+		State:            row.State.String,
+		Country:          row.Country.String,
+		Make:             row.Make.String,
+		Model:            row.Model.String,
+		Description:      row.Description.String,
+		IsFavorite:       row.IsFavorite,
+		Visibility:       string(row.Visibility),
+		AlbumIDs:         make([]string, len(row.AlbumIds)),
+		Tags:             row.TagValues,
+	}
+	if asset.Tags == nil {
+		asset.Tags = []string{}
+	}
+	for i, id := range row.AlbumIds {
+		asset.AlbumIDs[i] = pgutil.UUIDToString(id)
+	}
+	if row.FileSizeInByte.Valid {
+		asset.FileSizeInByte = &row.FileSizeInByte.Int64
+	}
+	if row.DateTimeOriginal.Valid {
+		asset.DateTimeOriginal = &row.DateTimeOriginal.Time
+	}
+	if row.Latitude.Valid && row.Longitude.Valid {
+		asset.Latitude = &row.Latitude.Float64
+		asset.Longitude = &row.Longitude.Float64
+	}
+	if row.Rating.Valid {
+		asset.Rating = &row.Rating.Int32
+	}
+	if row.DeletedAt.Valid {
+		asset.TrashedAt = &row.DeletedAt.Time
+	}
+	return asset
+}
+
+// exportArchivePath files originals by date like generateArchivePath, below
+// originals/ so they cannot clash with the manifest.
+func exportArchivePath(row sqlc.ListUserAssetsForExportRow) string {
+	name := row.OriginalFileName
+	if name == "" {
+		name = pgutil.UUIDToString(row.ID)
+	}
+	date := row.FileCreatedAt.Time
+	return path.Join("originals", date.Format("2006"), date.Format("01-January"), path.Base(name))
+}
+
+func exportStoragePath(row sqlc.ListUserAssetsForExportRow) string {
+	if row.OriginalPath != "" {
+		return row.OriginalPath
+	}
+	return storage.AssetFallbackPath(uuidFromPG(row.ID), row.OriginalFileName)
+}
@@ -115,19 +115,8 @@ func (s *Service) DownloadArchive(ctx context.Context, userID uuid.UUID, req *Do
 	// Add each asset to the archive
 	for _, asset := range assets {
 		assetID := assetUUID(asset)
-		// Generate archive path
-		archivePath := s.generateArchivePath(&asset)
-
-		// Ensure unique filename in archive
-		basePath := archivePath
-		counter := 1
-		for addedFiles[archivePath] {
-			ext := filepath.Ext(basePath)
-			name := basePath[:len(basePath)-len(ext)]
-			archivePath = fmt.Sprintf("%s_%d%s", name, counter, ext)
-			counter++
-		}
-		addedFiles[archivePath] = true
+		// Generate a unique archive path
+		archivePath := uniqueArchivePath(addedFiles, s.generateArchivePath(&asset))
 
 		// Create file in ZIP
 		fileHeader := &zip.FileHeader{
@@ -196,7 +185,7 @@ func (s *Service) GetThumbnail(ctx context.Context, userID uuid.UUID, assetID uu
 	thumbnailPath := fmt.Sprintf("thumbnails/%s/%s.webp", assetID.String(), size)
 
 	// Get thumbnail from storage
-	reader, err := s.storageService.Download(ctx, thumbnailPath)
+	reader, err := s.storageService.Derivatives().Download(ctx, thumbnailPath)
 	if err != nil {
 		// Fallback to original if thumbnail doesn't exist
 		filePath := assetStoragePath(asset)
@@ -252,6 +241,21 @@ func (s *Service) generateArchivePath(asset *sqlc.Asset) string {
 	return fmt.Sprintf("%s%s", assetUUID(*asset).String(), ext)
 }
 
+// uniqueArchivePath numbers archivePath if it is already in addedFiles and
+// records the result.
+func uniqueArchivePath(addedFiles map[string]bool, archivePath string) string {
+	basePath := archivePath
+	counter := 1
+	for addedFiles[archivePath] {
+		ext := filepath.Ext(basePath)
+		name := basePath[:len(basePath)-len(ext)]
+		archivePath = fmt.Sprintf("%s_%d%s", name, counter, ext)
+		counter++
+	}
+	addedFiles[archivePath] = true
+	return archivePath
+}
+
 func (s *Service) resolveDownloadAssets(ctx context.Context, userID uuid.UUID, req *DownloadRequest) ([]sqlc.Asset, error) {
 	if req.AlbumID != nil {
 		return s.resolveAlbumDownloadAssets(ctx, userID, *req.AlbumID)
@@ -307,7 +311,12 @@ func (s *Service) getAccessibleAsset(ctx context.Context, userID uuid.UUID, asse
 	return asset, nil
 }
 
+// userCanAccessAsset never admits locked assets: downloads carry no unlocked
+// session, so those are only fetched one by one through the asset endpoints.
 func (s *Service) userCanAccessAsset(ctx context.Context, userID uuid.UUID, asset sqlc.Asset) (bool, error) {
+	if asset.Visibility == sqlc.AssetVisibilityEnumLocked {
+		return false, nil
+	}
 	if asset.OwnerId.Valid && uuid.UUID(asset.OwnerId.Bytes) == userID {
 		return true, nil
 	}
@@ -7,6 +7,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"path"
 	"path/filepath"
@@ -180,6 +181,60 @@ func TestIntegration_DownloadInfoAndArchiveRespectSharedAccess(t *testing.T) {
 	assert.NotContains(t, entries, "private.jpg")
 }
 
+func TestIntegration_ExportUserDataResumesInParts(t *testing.T) {
+	env := newDownloadTestEnv(t)
+	ctx := context.Background()
+
+	ownerID := createDownloadUser(t, ctx, env, "export-owner@example.com")
+	otherID := createDownloadUser(t, ctx, env, "export-other@example.com")
+	seeded := []sqlc.Asset{
+		seedDownloadAsset(t, ctx, env, ownerID, "first.jpg", []byte("first")),
+		seedDownloadAsset(t, ctx, env, ownerID, "second.jpg", []byte("second")),
+		seedDownloadAsset(t, ctx, env, ownerID, "gone.jpg", []byte("gone")),
+	}
+	seedDownloadAsset(t, ctx, env, otherID, "foreign.jpg", []byte("foreign"))
+	shareDownloadAssetViaAlbum(t, ctx, env, ownerID, otherID, seeded[0])
+	require.NoError(t, env.storage.Delete(ctx, seeded[2].OriginalPath))
+
+	exported := map[string]ExportAsset{}
+	var contents []string
+	var missing []string
+	req := ExportRequest{Limit: 2}
+	for parts := 0; ; parts++ {
+		require.Less(t, parts, 3, "the export must end")
+
+		export, err := env.service.ExportUserData(ctx, ownerID, req)
+		require.NoError(t, err)
+		var archive bytes.Buffer
+		require.NoError(t, export.WriteArchive(ctx, &archive))
+
+		entries := readZipEntriesByBaseName(t, archive.Bytes())
+		var manifest ExportManifest
+		require.NoError(t, json.Unmarshal([]byte(entries[exportManifestName]), &manifest))
+		assert.Equal(t, export.Next, manifest.Next)
+		assert.Len(t, manifest.Albums, 1)
+		for _, asset := range manifest.Assets {
+			exported[asset.OriginalFileName] = asset
+			if asset.Path != "" {
+				contents = append(contents, entries[path.Base(asset.Path)])
+			}
+		}
+		missing = append(missing, manifest.Missing...)
+
+		if export.Next == "" {
+			break
+		}
+		req.After = export.Next
+	}
+
+	assert.Len(t, exported, 3)
+	assert.NotContains(t, exported, "foreign.jpg")
+	assert.ElementsMatch(t, []string{"first", "second"}, contents)
+	assert.Equal(t, []string{assetUUID(seeded[2]).String()}, missing)
+	assert.Empty(t, exported["gone.jpg"].Path)
+	assert.Len(t, exported["first.jpg"].AlbumIDs, 1)
+}
+
 func readZipEntriesByBaseName(t *testing.T, data []byte) map[string]string {
 	t.Helper()
 
@@ -1,7 +1,9 @@
 package download
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -23,3 +25,44 @@ func TestGenerateArchivePathUsesUUIDFallback(t *testing.T) {
 		Type: "VIDEO",
 	}))
 }
+
+func TestUniqueArchivePathNumbersDuplicates(t *testing.T) {
+	added := map[string]bool{}
+
+	assert.Equal(t, "2024/06-June/IMG_1.jpg", uniqueArchivePath(added, "2024/06-June/IMG_1.jpg"))
+	assert.Equal(t, "2024/06-June/IMG_1_1.jpg", uniqueArchivePath(added, "2024/06-June/IMG_1.jpg"))
+	assert.Equal(t, "2024/06-June/IMG_1_2.jpg", uniqueArchivePath(added, "2024/06-June/IMG_1.jpg"))
+}
+
+func TestExportAssetFromRowKeepsOnlyCompleteLocations(t *testing.T) {
+	assetID := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	albumID := uuid.MustParse("66666666-7777-8888-9999-000000000000")
+	taken := time.Date(2024, 6, 1, 10, 30, 0, 0, time.UTC)
+	row := sqlc.ListUserAssetsForExportRow{
+		ID:               pgtype.UUID{Bytes: assetID, Valid: true},
+		Type:             "IMAGE",
+		OriginalFileName: "IMG_1.jpg",
+		Checksum:         []byte{0x01, 0x02},
+		FileCreatedAt:    pgtype.Timestamptz{Time: taken, Valid: true},
+		Latitude:         pgtype.Float8{Float64: 47.3769, Valid: true},
+		AlbumIds:         []pgtype.UUID{{Bytes: albumID, Valid: true}},
+	}
+
+	asset := exportAssetFromRow(row)
+	assert.Equal(t, assetID.String(), asset.ID)
+	assert.Equal(t, "AQI=", asset.Checksum)
+	assert.Nil(t, asset.Latitude, "a latitude without longitude is not a location")
+	assert.Equal(t, []string{albumID.String()}, asset.AlbumIDs)
+	assert.Equal(t, []string{}, asset.Tags)
+	assert.Equal(t, "originals/2024/06-June/IMG_1.jpg", exportArchivePath(row))
+}
+
+func TestExportUserDataRejectsInvalidRequests(t *testing.T) {
+	service := NewService(nil, nil)
+
+	_, err := service.ExportUserData(context.Background(), uuid.New(), ExportRequest{Limit: MaxExportPartSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidExportRequest)
+
+	_, err = service.ExportUserData(context.Background(), uuid.New(), ExportRequest{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidExportRequest)
+}
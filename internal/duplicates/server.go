@@ -27,13 +27,13 @@ func NewServer(service *Service) *Server {
 // GetAssetDuplicates retrieves duplicate assets for the authenticated user
 func (s *Server) GetAssetDuplicates(ctx context.Context, request *immichv1.GetAssetDuplicatesRequest) (*immichv1.GetAssetDuplicatesResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call service
-	response, err := s.service.GetAssetDuplicates(ctx, claims.UserID)
+	response, err := s.service.GetAssetDuplicates(ctx, userID.String())
 	if err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to get asset duplicates", err)
 	}
@@ -67,12 +67,12 @@ func (s *Server) GetAssetDuplicates(ctx context.Context, request *immichv1.GetAs
 
 // DeleteDuplicates clears the requested duplicate groups for the authenticated user.
 func (s *Server) DeleteDuplicates(ctx context.Context, request *immichv1.DeleteDuplicatesRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.service.DeleteDuplicates(ctx, claims.UserID, request.GetIds()); err != nil {
+	if err := s.service.DeleteDuplicates(ctx, userID.String(), request.GetIds()); err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to delete duplicate groups", err)
 	}
 
@@ -81,16 +81,16 @@ func (s *Server) DeleteDuplicates(ctx context.Context, request *immichv1.DeleteD
 
 // DeleteDuplicate clears a single duplicate group for the authenticated user.
 func (s *Server) DeleteDuplicate(ctx context.Context, request *immichv1.DeleteDuplicateRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	if request.GetId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "duplicate ID is required")
 	}
 
-	if err := s.service.DeleteDuplicate(ctx, claims.UserID, request.GetId()); err != nil {
+	if err := s.service.DeleteDuplicate(ctx, userID.String(), request.GetId()); err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to delete duplicate group", err)
 	}
 
@@ -99,9 +99,9 @@ func (s *Server) DeleteDuplicate(ctx context.Context, request *immichv1.DeleteDu
 
 // ResolveDuplicates trashes selected assets and clears each resolved duplicate group.
 func (s *Server) ResolveDuplicates(ctx context.Context, request *immichv1.ResolveDuplicatesRequest) (*immichv1.ResolveDuplicatesResponse, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	groups := make([]*ResolveDuplicateGroup, len(request.GetGroups()))
@@ -113,7 +113,7 @@ func (s *Server) ResolveDuplicates(ctx context.Context, request *immichv1.Resolv
 		}
 	}
 
-	results, err := s.service.ResolveDuplicates(ctx, claims.UserID, groups)
+	results, err := s.service.ResolveDuplicates(ctx, userID.String(), groups)
 	if err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "failed to resolve duplicate groups", err)
 	}
@@ -105,14 +105,15 @@ func (s *Service) GetAssetDuplicates(ctx context.Context, userID string) (*GetAs
 		duplicateAssetIDs[checksumStr] = make(map[string]struct{})
 		checksumOrder = append(checksumOrder, checksumStr)
 
-		assets, err := s.db.GetAssetsByChecksum(ctx, duplicate.Checksum)
+		assets, err := s.db.GetOwnerAssetsByChecksum(ctx, sqlc.GetOwnerAssetsByChecksumParams{
+			OwnerID:           userUUID,
+			Checksum:          duplicate.Checksum,
+			ChecksumAlgorithm: duplicate.ChecksumAlgorithm,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to get duplicate assets by checksum: %w", err)
 		}
 		for _, asset := range assets {
-			if asset.OwnerId != userUUID || asset.DeletedAt.Valid {
-				continue
-			}
 
 			assetID := uuid.UUID(asset.ID.Bytes).String()
 			if _, ok := duplicateAssetIDs[checksumStr][assetID]; ok {
@@ -26,9 +26,8 @@ func NewServer(service *Service) *Server {
 // GetFaces retrieves faces, optionally filtered by ID
 func (s *Server) GetFaces(ctx context.Context, request *immichv1.GetFacesRequest) (*immichv1.GetFacesResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	if _, err := auth.UserIDFromContext(ctx); err != nil {
+		return nil, err
 	}
 
 	// Convert request
@@ -70,9 +69,8 @@ func (s *Server) GetFaces(ctx context.Context, request *immichv1.GetFacesRequest
 // CreateFace creates a new face detection record
 func (s *Server) CreateFace(ctx context.Context, request *immichv1.CreateFaceRequest) (*immichv1.FaceResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	if _, err := auth.UserIDFromContext(ctx); err != nil {
+		return nil, err
 	}
 
 	// Validate request
@@ -123,9 +121,8 @@ func (s *Server) CreateFace(ctx context.Context, request *immichv1.CreateFaceReq
 // DeleteFace removes a face detection record
 func (s *Server) DeleteFace(ctx context.Context, request *immichv1.DeleteFaceRequest) (*emptypb.Empty, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	if _, err := auth.UserIDFromContext(ctx); err != nil {
+		return nil, err
 	}
 
 	// Validate request
@@ -145,9 +142,8 @@ func (s *Server) DeleteFace(ctx context.Context, request *immichv1.DeleteFaceReq
 // ReassignFacesById reassigns faces to a different person
 func (s *Server) ReassignFacesById(ctx context.Context, request *immichv1.ReassignFacesByIdRequest) (*immichv1.ReassignFacesByIdResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	if _, err := auth.UserIDFromContext(ctx); err != nil {
+		return nil, err
 	}
 
 	// Validate request
@@ -0,0 +1,64 @@
+package geodata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// updatePageSize is how many geotagged assets are resolved per query.
+const updatePageSize = 1000
+
+// UpdateResult reports a run of UpdateAssetPlaces.
+type UpdateResult struct {
+	// Assets with coordinates that were checked
+	Assets int
+	// Updated counts the assets whose place names changed.
+	Updated int
+}
+
+// UpdateAssetPlaces resolves the coordinates of every geotagged asset again
+// and updates the place names that are missing or differ, such as after a
+// newer dataset was imported. Assets no place is close enough to keep their
+// names.
+func UpdateAssetPlaces(ctx context.Context, queries *sqlc.Queries) (*UpdateResult, error) {
+	result := &UpdateResult{}
+	after := pgtype.UUID{Valid: true}
+	for {
+		rows, err := queries.ListGeotaggedExifPlaces(ctx, sqlc.ListGeotaggedExifPlacesParams{
+			MaxDistance: maxPlaceDistance,
+			AfterID:     after,
+			PageSize:    updatePageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list geotagged assets: %w", err)
+		}
+		for _, row := range rows {
+			result.Assets++
+			if !row.PlaceName.Valid {
+				continue
+			}
+			place := newPlace(row.PlaceName.String, row.PlaceAdmin1Name.String, row.PlaceCountryCode.String)
+			city, state, country := optionalText(place.City), optionalText(place.State), optionalText(place.Country)
+			if row.City == city && row.State == state && row.Country == country {
+				continue
+			}
+			if err := queries.UpdateExifPlace(ctx, sqlc.UpdateExifPlaceParams{
+				AssetId: row.AssetId,
+				City:    city,
+				State:   state,
+				Country: country,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to update asset place: %w", err)
+			}
+			result.Updated++
+		}
+		if len(rows) < updatePageSize {
+			return result, nil
+		}
+		after = rows[len(rows)-1].AssetId
+	}
+}
@@ -0,0 +1,86 @@
+// Package geodata resolves the coordinates of assets to place names.
+//
+// Places come from the GeoNames dataset Immich bundles: cities500.txt with
+// admin1CodesASCII.txt and admin2Codes.txt for the names of the regions, and
+// geodata-date.txt for the date of the dataset. The dataset is imported into
+// the geodata_places table, and an asset is given the name, region and
+// country of the closest place within 25 km.
+package geodata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// maxPlaceDistance is how far in meters the closest place may be from the
+// coordinates.
+const maxPlaceDistance = 25000
+
+// Place is what a pair of coordinates resolves to.
+type Place struct {
+	City    string
+	State   string
+	Country string
+}
+
+// Lookup returns the place closest to the coordinates. ok is false when no
+// place is close enough, including when no dataset was imported.
+func Lookup(ctx context.Context, queries *sqlc.Queries, latitude, longitude float64) (place Place, ok bool, err error) {
+	row, err := queries.GetNearestGeodataPlace(ctx, sqlc.GetNearestGeodataPlaceParams{
+		Latitude:    latitude,
+		Longitude:   longitude,
+		MaxDistance: maxPlaceDistance,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Place{}, false, nil
+	}
+	if err != nil {
+		return Place{}, false, fmt.Errorf("failed to look up place: %w", err)
+	}
+	return newPlace(row.Name, row.Admin1Name.String, row.CountryCode), true, nil
+}
+
+// FillExifPlace sets the place names of EXIF data with coordinates but
+// without a city.
+func FillExifPlace(ctx context.Context, queries *sqlc.Queries, params *sqlc.CreateOrUpdateExifParams) error {
+	if !params.Latitude.Valid || !params.Longitude.Valid || params.City.Valid {
+		return nil
+	}
+	place, ok, err := Lookup(ctx, queries, params.Latitude.Float64, params.Longitude.Float64)
+	if err != nil || !ok {
+		return err
+	}
+	params.City = optionalText(place.City)
+	params.State = optionalText(place.State)
+	params.Country = optionalText(place.Country)
+	return nil
+}
+
+func newPlace(name, admin1Name, countryCode string) Place {
+	return Place{City: name, State: admin1Name, Country: CountryName(countryCode)}
+}
+
+// CountryName returns the English name of an ISO 3166 country code, or the
+// code when it is unknown.
+func CountryName(code string) string {
+	region, err := language.ParseRegion(code)
+	if err != nil {
+		return code
+	}
+	if name := display.English.Regions().Name(region); name != "" {
+		return name
+	}
+	return code
+}
+
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
@@ -0,0 +1,116 @@
+//go:build integration
+// +build integration
+
+package geodata
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/systemmetadata"
+)
+
+func writeDataset(t *testing.T, dir string, cities ...string) {
+	t.Helper()
+	files := map[string]string{
+		citiesFile: strings.Join(cities, "\n") + "\n",
+		admin1File: "CH.TI\tTicino\tTicino\t2658370\nCH.ZH\tZurich\tZurich\t2657895\n",
+		dateFile:   "2024-05-01\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+const zurich = "2657896\tZürich\tZurich\tZurich\t47.36667\t8.55\tP\tPPLA\tCH\t\tZH\t112\t261\t\t341730\t\t429\tEurope/Zurich\t2023-03-01"
+
+func TestIntegration_ImportAndUpdateAssetPlaces(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	ownerID := tdb.CreateTestUser(t, "geodata@example.com")
+	withCoordinates := func(deviceAssetID string, latitude, longitude float64, city string) pgtype.UUID {
+		t.Helper()
+		assetID := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, deviceAssetID), Valid: true}
+		_, err := tdb.Queries.CreateOrUpdateExif(ctx, sqlc.CreateOrUpdateExifParams{
+			AssetId:   assetID,
+			Latitude:  pgtype.Float8{Float64: latitude, Valid: true},
+			Longitude: pgtype.Float8{Float64: longitude, Valid: true},
+			City:      optionalText(city),
+		})
+		require.NoError(t, err)
+		return assetID
+	}
+	inLugano := withCoordinates("geodata-lugano", 46.005, 8.95, "")
+	renamed := withCoordinates("geodata-zurich", 47.37, 8.54, "Old name")
+	remote := withCoordinates("geodata-ocean", 0, -140, "Kept")
+
+	writeDataset(t, dir, lugano, zurich)
+	imported, err := Import(ctx, tdb.Queries, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, imported.Places)
+	assert.Equal(t, "2024-05-01", imported.LastUpdate)
+
+	metadata, err := systemmetadata.NewService(tdb.Queries, &config.Config{})
+	require.NoError(t, err)
+	state, err := metadata.GetReverseGeocodingState(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, state.LastUpdate)
+	assert.Equal(t, "2024-05-01", *state.LastUpdate)
+	assert.NotNil(t, state.LastImport)
+
+	place, ok, err := Lookup(ctx, tdb.Queries, 46.005, 8.95)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, Place{City: "Lugano", State: "Ticino", Country: "Switzerland"}, place)
+	_, ok, err = Lookup(ctx, tdb.Queries, 0, -140)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	updated, err := UpdateAssetPlaces(ctx, tdb.Queries)
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated.Assets)
+	assert.Equal(t, 2, updated.Updated)
+
+	exif, err := tdb.Queries.GetExifByAssetId(ctx, inLugano)
+	require.NoError(t, err)
+	assert.Equal(t, "Lugano", exif.City.String)
+	exif, err = tdb.Queries.GetExifByAssetId(ctx, renamed)
+	require.NoError(t, err)
+	assert.Equal(t, "Zürich", exif.City.String)
+	exif, err = tdb.Queries.GetExifByAssetId(ctx, remote)
+	require.NoError(t, err)
+	assert.Equal(t, "Kept", exif.City.String)
+
+	// A second run finds nothing to update
+	updated, err = UpdateAssetPlaces(ctx, tdb.Queries)
+	require.NoError(t, err)
+	assert.Equal(t, 0, updated.Updated)
+
+	// Places dropped from the dataset are removed
+	writeDataset(t, dir, lugano)
+	imported, err = Import(ctx, tdb.Queries, dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), imported.Removed)
+
+	// An empty dataset keeps the places
+	writeDataset(t, dir)
+	_, err = Import(ctx, tdb.Queries, dir)
+	assert.Error(t, err)
+	_, ok, err = Lookup(ctx, tdb.Queries, 46.005, 8.95)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,244 @@
+package geodata
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/systemmetadata"
+)
+
+// Files of the dataset
+const (
+	citiesFile = "cities500.txt"
+	admin1File = "admin1CodesASCII.txt"
+	admin2File = "admin2Codes.txt"
+	dateFile   = "geodata-date.txt"
+)
+
+// importBatchSize is how many places are written per statement.
+const importBatchSize = 5000
+
+// ImportResult reports an import of the dataset.
+type ImportResult struct {
+	// Places in the dataset
+	Places int
+	// Removed counts the places of the previous import that are no longer
+	// in the dataset.
+	Removed int64
+	// LastUpdate is the date of the dataset.
+	LastUpdate string
+}
+
+// Import replaces the places with those of the dataset in dir and records
+// the import in the reverse geocoding state. Places are updated in place, so
+// assets can be resolved while an import runs.
+func Import(ctx context.Context, queries *sqlc.Queries, dir string) (*ImportResult, error) {
+	admin1, err := readAdminNames(filepath.Join(dir, admin1File))
+	if err != nil {
+		return nil, err
+	}
+	admin2, err := readAdminNames(filepath.Join(dir, admin2File))
+	if err != nil {
+		return nil, err
+	}
+
+	citiesPath := filepath.Join(dir, citiesFile)
+	cities, err := os.Open(citiesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", citiesFile, err)
+	}
+	defer cities.Close()
+
+	result := &ImportResult{}
+	var ids []int32
+	batch := sqlc.UpsertGeodataPlacesParams{}
+	flush := func() error {
+		if len(batch.Ids) == 0 {
+			return nil
+		}
+		if _, err := queries.UpsertGeodataPlaces(ctx, batch); err != nil {
+			return fmt.Errorf("failed to store places: %w", err)
+		}
+		batch = sqlc.UpsertGeodataPlacesParams{}
+		return nil
+	}
+	err = parsePlaces(cities, admin1, admin2, func(place sqlc.GeodataPlace) error {
+		ids = append(ids, place.ID)
+		appendPlace(&batch, place)
+		if len(batch.Ids) >= importBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		// Keep the places of the previous import rather than removing them
+		return nil, fmt.Errorf("%s has no places", citiesFile)
+	}
+	result.Places = len(ids)
+
+	if result.Removed, err = queries.DeleteGeodataPlacesExcept(ctx, ids); err != nil {
+		return nil, fmt.Errorf("failed to remove old places: %w", err)
+	}
+
+	if result.LastUpdate, err = datasetDate(dir, cities); err != nil {
+		return nil, err
+	}
+	if err := systemmetadata.SaveReverseGeocodingState(ctx, queries, systemmetadata.ReverseGeocodingState{
+		LastUpdate:         result.LastUpdate,
+		LastImportFileName: citiesFile,
+		LastImport:         time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// datasetDate returns the date of geodata-date.txt, or the modification
+// time of the cities file when there is none.
+func datasetDate(dir string, cities *os.File) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, dateFile))
+	if err == nil {
+		if date := strings.TrimSpace(string(data)); date != "" {
+			return date, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read %s: %w", dateFile, err)
+	}
+	info, err := cities.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", citiesFile, err)
+	}
+	return info.ModTime().UTC().Format(time.RFC3339), nil
+}
+
+// readAdminNames reads an admin code file, which maps codes such as
+// "CH.TI" to region names. A missing file leaves the regions unnamed.
+func readAdminNames(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer f.Close()
+	names, err := parseAdminNames(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return names, nil
+}
+
+func parseAdminNames(r io.Reader) (map[string]string, error) {
+	names := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		names[fields[0]] = fields[1]
+	}
+	return names, scanner.Err()
+}
+
+// parsePlaces calls fn for every place of a GeoNames cities file. Lines
+// that are not a valid place are skipped.
+func parsePlaces(r io.Reader, admin1, admin2 map[string]string, fn func(sqlc.GeodataPlace) error) error {
+	scanner := bufio.NewScanner(r)
+	// Alternate names make some lines long
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		place, ok := parsePlace(scanner.Text(), admin1, admin2)
+		if !ok {
+			continue
+		}
+		if err := fn(place); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", citiesFile, err)
+	}
+	return nil
+}
+
+// parsePlace parses a line of a GeoNames cities file, whose columns are
+// described at https://download.geonames.org/export/dump/readme.txt.
+func parsePlace(line string, admin1, admin2 map[string]string) (sqlc.GeodataPlace, bool) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 19 {
+		return sqlc.GeodataPlace{}, false
+	}
+	id, err := strconv.ParseInt(fields[0], 10, 32)
+	if err != nil {
+		return sqlc.GeodataPlace{}, false
+	}
+	latitude, err := strconv.ParseFloat(fields[4], 64)
+	if err != nil {
+		return sqlc.GeodataPlace{}, false
+	}
+	longitude, err := strconv.ParseFloat(fields[5], 64)
+	if err != nil {
+		return sqlc.GeodataPlace{}, false
+	}
+	countryCode := fields[8]
+	if fields[1] == "" || len(countryCode) != 2 {
+		return sqlc.GeodataPlace{}, false
+	}
+	modified, err := time.Parse(time.DateOnly, fields[18])
+	if err != nil {
+		return sqlc.GeodataPlace{}, false
+	}
+
+	admin1Code, admin2Code := fields[10], fields[11]
+	place := sqlc.GeodataPlace{
+		ID:               int32(id),
+		Name:             fields[1],
+		Longitude:        longitude,
+		Latitude:         latitude,
+		CountryCode:      countryCode,
+		Admin1Code:       optionalText(admin1Code),
+		Admin2Code:       optionalText(admin2Code),
+		ModificationDate: pgtype.Date{Time: modified, Valid: true},
+		AlternateNames:   optionalText(fields[3]),
+	}
+	if admin1Code != "" {
+		place.Admin1Name = optionalText(admin1[countryCode+"."+admin1Code])
+		if admin2Code != "" {
+			place.Admin2Name = optionalText(admin2[countryCode+"."+admin1Code+"."+admin2Code])
+		}
+	}
+	return place, true
+}
+
+func appendPlace(batch *sqlc.UpsertGeodataPlacesParams, place sqlc.GeodataPlace) {
+	batch.Ids = append(batch.Ids, place.ID)
+	batch.Names = append(batch.Names, place.Name)
+	batch.Longitudes = append(batch.Longitudes, place.Longitude)
+	batch.Latitudes = append(batch.Latitudes, place.Latitude)
+	batch.CountryCodes = append(batch.CountryCodes, place.CountryCode)
+	batch.Admin1Codes = append(batch.Admin1Codes, place.Admin1Code)
+	batch.Admin2Codes = append(batch.Admin2Codes, place.Admin2Code)
+	batch.ModificationDates = append(batch.ModificationDates, place.ModificationDate)
+	batch.Admin1Names = append(batch.Admin1Names, place.Admin1Name)
+	batch.Admin2Names = append(batch.Admin2Names, place.Admin2Name)
+	batch.AlternateNames = append(batch.AlternateNames, place.AlternateNames)
+}
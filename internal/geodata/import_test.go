@@ -0,0 +1,75 @@
+package geodata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lugano is the cities500.txt line of Lugano, with shortened alternate names.
+const lugano = "2659836\tLugano\tLugano\tLugan,Lugano\t46.01008\t8.96004\tP\tPPLA3\tCH\t\tTI\t2105\t5192\t\t63185\t\t273\tEurope/Zurich\t2023-03-01"
+
+func TestParseAdminNames(t *testing.T) {
+	names, err := parseAdminNames(strings.NewReader(
+		"CH.TI\tTicino\tTicino\t2658370\n" +
+			"\n" +
+			"CH.ZH\tZurich\tZurich\t2657895\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"CH.TI": "Ticino", "CH.ZH": "Zurich"}, names)
+}
+
+func TestParsePlace(t *testing.T) {
+	admin1 := map[string]string{"CH.TI": "Ticino"}
+	admin2 := map[string]string{"CH.TI.2105": "Lugano District"}
+
+	place, ok := parsePlace(lugano, admin1, admin2)
+	require.True(t, ok)
+	assert.Equal(t, int32(2659836), place.ID)
+	assert.Equal(t, "Lugano", place.Name)
+	assert.InDelta(t, 46.01008, place.Latitude, 1e-9)
+	assert.InDelta(t, 8.96004, place.Longitude, 1e-9)
+	assert.Equal(t, "CH", place.CountryCode)
+	assert.Equal(t, "TI", place.Admin1Code.String)
+	assert.Equal(t, "Ticino", place.Admin1Name.String)
+	assert.Equal(t, "Lugano District", place.Admin2Name.String)
+	assert.Equal(t, "Lugan,Lugano", place.AlternateNames.String)
+	assert.Equal(t, "2023-03-01", place.ModificationDate.Time.Format("2006-01-02"))
+
+	// Regions missing from the admin files are left unnamed
+	place, ok = parsePlace(lugano, nil, nil)
+	require.True(t, ok)
+	assert.False(t, place.Admin1Name.Valid)
+	assert.False(t, place.Admin2Name.Valid)
+}
+
+func TestParsePlaceInvalid(t *testing.T) {
+	fields := strings.Split(lugano, "\t")
+	with := func(i int, value string) string {
+		changed := append([]string(nil), fields...)
+		changed[i] = value
+		return strings.Join(changed, "\t")
+	}
+	for name, line := range map[string]string{
+		"empty":         "",
+		"short":         strings.Join(fields[:10], "\t"),
+		"id":            with(0, "x"),
+		"name":          with(1, ""),
+		"latitude":      with(4, "north"),
+		"longitude":     with(5, ""),
+		"country code":  with(8, "CHE"),
+		"modified date": with(18, "yesterday"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, ok := parsePlace(line, nil, nil)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	assert.Equal(t, "Switzerland", CountryName("CH"))
+	assert.Equal(t, "Japan", CountryName("JP"))
+	assert.Equal(t, "??", CountryName("??"))
+}
@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	otelcodes "go.opentelemetry.io/otel/codes"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -54,3 +55,73 @@ func PublicError(ctx context.Context, code codes.Code, publicMsg string) error {
 	}
 	return status.Error(code, publicMsg)
 }
+
+// WithReason attaches a stable, machine-readable reason (e.g.
+// "invalid_credentials") to a status error as an ErrorInfo detail. The HTTP
+// gateway renders it as the "error" field so clients can branch on it
+// instead of parsing messages. Details already on err are kept.
+func WithReason(err error, reason string) error {
+	st, ok := status.FromError(err)
+	if !ok || reason == "" {
+		return err
+	}
+	withReason, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: reason})
+	if detailErr != nil {
+		return err
+	}
+	return withReason.Err()
+}
+
+// Reason returns the reason attached to st by WithReason, or "".
+func Reason(st *status.Status) string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// FieldViolation describes one invalid field of a request.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// InvalidFields returns a codes.InvalidArgument status error listing the
+// invalid request fields, which the HTTP gateway renders as the per-field
+// message list Immich clients show next to form inputs.
+func InvalidFields(ctx context.Context, publicMsg string, violations ...FieldViolation) error {
+	err := PublicError(ctx, codes.InvalidArgument, publicMsg)
+	if len(violations) == 0 {
+		return err
+	}
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	st, detailErr := status.Convert(err).WithDetails(badRequest)
+	if detailErr != nil {
+		return err
+	}
+	return st.Err()
+}
+
+// FieldViolations returns the field violations attached to st by
+// InvalidFields.
+func FieldViolations(st *status.Status) []FieldViolation {
+	var violations []FieldViolation
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, v := range badRequest.GetFieldViolations() {
+			violations = append(violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+		}
+	}
+	return violations
+}
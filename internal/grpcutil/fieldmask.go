@@ -0,0 +1,75 @@
+package grpcutil
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// FieldSelection is the set of top-level fields of a response message a
+// client asked for. The zero value selects every field.
+type FieldSelection struct {
+	fields map[protoreflect.Name]bool
+}
+
+// ParseFieldMask resolves the paths of mask against the fields of msg. Paths
+// may use the proto name ("file_created_at") or the JSON name
+// ("fileCreatedAt"), and a single path may hold several comma-separated
+// names. Fields listed in always are selected whenever mask is non-empty. An
+// unknown or nested path is an InvalidArgument error.
+func ParseFieldMask(msg proto.Message, mask *fieldmaskpb.FieldMask, always ...protoreflect.Name) (FieldSelection, error) {
+	if len(mask.GetPaths()) == 0 {
+		return FieldSelection{}, nil
+	}
+
+	descriptor := msg.ProtoReflect().Descriptor().Fields()
+	selected := make(map[protoreflect.Name]bool, len(mask.GetPaths())+len(always))
+	for _, name := range always {
+		selected[name] = true
+	}
+	for _, path := range mask.GetPaths() {
+		for _, name := range strings.Split(path, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			field := descriptor.ByName(protoreflect.Name(name))
+			if field == nil {
+				field = descriptor.ByJSONName(name)
+			}
+			if field == nil {
+				return FieldSelection{}, status.Errorf(codes.InvalidArgument, "unknown field %q", name)
+			}
+			selected[field.Name()] = true
+		}
+	}
+	return FieldSelection{fields: selected}, nil
+}
+
+// All reports whether every field is selected.
+func (f FieldSelection) All() bool {
+	return f.fields == nil
+}
+
+// Has reports whether the field with the given proto name is selected.
+func (f FieldSelection) Has(name protoreflect.Name) bool {
+	return f.fields == nil || f.fields[name]
+}
+
+// Apply clears the fields of msg that are not selected.
+func (f FieldSelection) Apply(msg proto.Message) {
+	if f.fields == nil {
+		return
+	}
+	m := msg.ProtoReflect()
+	m.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if !f.fields[field.Name()] {
+			m.Clear(field)
+		}
+		return true
+	})
+}
@@ -0,0 +1,62 @@
+package grpcutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestParseFieldMask(t *testing.T) {
+	asset := func() *immichv1.Asset {
+		return &immichv1.Asset{
+			Id:               "asset-1",
+			OriginalPath:     "/upload/a.jpg",
+			OriginalFileName: "a.jpg",
+			Checksum:         "abc",
+			IsFavorite:       true,
+		}
+	}
+
+	t.Run("unset selects everything", func(t *testing.T) {
+		fields, err := ParseFieldMask(&immichv1.Asset{}, nil, "id")
+		require.NoError(t, err)
+		assert.True(t, fields.All())
+
+		got := asset()
+		fields.Apply(got)
+		assert.True(t, proto.Equal(asset(), got))
+	})
+
+	t.Run("proto and json names", func(t *testing.T) {
+		fields, err := ParseFieldMask(&immichv1.Asset{}, &fieldmaskpb.FieldMask{
+			Paths: []string{"original_file_name", "isFavorite, checksum"},
+		}, "id")
+		require.NoError(t, err)
+		assert.False(t, fields.All())
+		assert.True(t, fields.Has("id"))
+		assert.False(t, fields.Has("original_path"))
+
+		got := asset()
+		fields.Apply(got)
+		assert.True(t, proto.Equal(&immichv1.Asset{
+			Id:               "asset-1",
+			OriginalFileName: "a.jpg",
+			Checksum:         "abc",
+			IsFavorite:       true,
+		}, got), "got %v", got)
+	})
+
+	t.Run("unknown and nested paths are rejected", func(t *testing.T) {
+		for _, path := range []string{"thumbnail", "exif_info.city"} {
+			_, err := ParseFieldMask(&immichv1.Asset{}, &fieldmaskpb.FieldMask{Paths: []string{path}})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), path)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the `validate` tags of request DTOs. Fields are reported
+// by their JSON name, which is what clients send.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return lowerCamel(field.Name)
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks req, a struct with `validate` tags, and returns an
+// InvalidFields error listing every invalid field, or nil. Pointer fields
+// tagged omitnil are only checked when set, which suits update requests;
+// "omitnil,min=1" rejects a string that is set but empty.
+func Validate(ctx context.Context, publicMsg string, req any) error {
+	err := validate.Struct(req)
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+	violations := make([]FieldViolation, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		// Strip the struct name, keeping nested and element paths such as
+		// importPaths[0]
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		violations[i] = FieldViolation{Field: field, Description: describe(field, fieldErr)}
+	}
+	return InvalidFields(ctx, publicMsg, violations...)
+}
+
+// describe phrases a failed check the way Immich's class-validator does, so
+// clients show familiar messages.
+func describe(field string, fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	kind := fieldErr.Kind()
+	isString := kind == reflect.String
+	isList := kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " should not be empty"
+	case "email":
+		return field + " must be an email"
+	case "uuid", "uuid4":
+		return field + " must be a UUID"
+	case "oneof":
+		return field + " must be one of the following values: " + strings.ReplaceAll(param, " ", ", ")
+	case "min", "gte":
+		switch {
+		case isString && param == "1":
+			return field + " should not be empty"
+		case isString:
+			return fmt.Sprintf("%s must be longer than or equal to %s characters", field, param)
+		case isList:
+			return fmt.Sprintf("%s must contain at least %s elements", field, param)
+		}
+		return fmt.Sprintf("%s must not be less than %s", field, param)
+	case "max", "lte":
+		switch {
+		case isString:
+			return fmt.Sprintf("%s must be shorter than or equal to %s characters", field, param)
+		case isList:
+			return fmt.Sprintf("%s must contain no more than %s elements", field, param)
+		}
+		return fmt.Sprintf("%s must not be greater than %s", field, param)
+	}
+	return field + " is invalid"
+}
+
+// lowerCamel turns a Go field name into its JSON-style name, keeping
+// acronyms together: OwnerID becomes ownerID and URL becomes url.
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		// The last capital starts the next word
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
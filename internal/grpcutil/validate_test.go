@@ -0,0 +1,78 @@
+package grpcutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type validatedRequest struct {
+	Email            string   `validate:"required,email"`
+	Name             string   `json:"displayName" validate:"required,max=5"`
+	QuotaSizeInBytes *int64   `validate:"omitnil,min=0"`
+	StorageLabel     *string  `validate:"omitnil,min=1"`
+	ImportPaths      []string `json:"importPaths,omitempty" validate:"dive,required"`
+	OwnerID          *string  `validate:"omitnil,uuid"`
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	quota := int64(-1)
+	empty := ""
+	owner := "not-a-uuid"
+
+	err := Validate(ctx, "invalid user", validatedRequest{
+		Email:            "not-an-email",
+		Name:             "too long",
+		QuotaSizeInBytes: &quota,
+		StorageLabel:     &empty,
+		ImportPaths:      []string{"/photos", ""},
+		OwnerID:          &owner,
+	})
+	require.Error(t, err)
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "invalid user", st.Message())
+	assert.Equal(t, []FieldViolation{
+		{Field: "email", Description: "email must be an email"},
+		{Field: "displayName", Description: "displayName must be shorter than or equal to 5 characters"},
+		{Field: "quotaSizeInBytes", Description: "quotaSizeInBytes must not be less than 0"},
+		{Field: "storageLabel", Description: "storageLabel should not be empty"},
+		{Field: "importPaths[1]", Description: "importPaths[1] should not be empty"},
+		{Field: "ownerID", Description: "ownerID must be a UUID"},
+	}, FieldViolations(st))
+}
+
+func TestValidate_Valid(t *testing.T) {
+	quota := int64(0)
+	assert.NoError(t, Validate(context.Background(), "invalid user", validatedRequest{
+		Email:            "user@example.com",
+		Name:             "Ann",
+		QuotaSizeInBytes: &quota,
+	}))
+
+	// Unset fields of update requests are not checked
+	assert.NoError(t, Validate(context.Background(), "invalid user", &struct {
+		Name *string `validate:"omitnil,min=1"`
+	}{}))
+}
+
+func TestValidate_Required(t *testing.T) {
+	err := Validate(context.Background(), "invalid user", validatedRequest{})
+	assert.Equal(t, []FieldViolation{
+		{Field: "email", Description: "email should not be empty"},
+		{Field: "displayName", Description: "displayName should not be empty"},
+	}, FieldViolations(status.Convert(err)))
+}
+
+func TestLowerCamel(t *testing.T) {
+	assert.Equal(t, "email", lowerCamel("Email"))
+	assert.Equal(t, "quotaSizeInBytes", lowerCamel("QuotaSizeInBytes"))
+	assert.Equal(t, "ownerID", lowerCamel("OwnerID"))
+	assert.Equal(t, "id", lowerCamel("ID"))
+	assert.Equal(t, "urlPath", lowerCamel("URLPath"))
+}
@@ -0,0 +1,409 @@
+// Package integrity verifies that the files tracked in the database are
+// intact in storage.
+//
+// A scan re-reads every original, recomputes its checksum and compares it
+// with the one stored at upload, optionally checks that the generated
+// thumbnails exist, and lists the files in storage that no row points at.
+// Originals are read by a bounded number of workers, and the read rate can be
+// capped, so a scan of a large library does not saturate storage.
+package integrity
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+// Finding types of a report.
+const (
+	TypeChecksumMismatch = "checksum_mismatch"
+	TypeMissingFile      = "missing_file"
+	TypeUntrackedFile    = "untracked_file"
+	TypeMissingThumbnail = "missing_thumbnail"
+)
+
+// Types lists every finding type.
+var Types = []string{TypeChecksumMismatch, TypeMissingFile, TypeUntrackedFile, TypeMissingThumbnail}
+
+const defaultConcurrency = 2
+
+// Queries is the subset of sqlc.Queries used by the scanner.
+type Queries interface {
+	GetIntegrityOriginalAssets(ctx context.Context) ([]sqlc.GetIntegrityOriginalAssetsRow, error)
+	GetIntegrityTrackedPaths(ctx context.Context) ([]string, error)
+	GetIntegrityThumbnailFiles(ctx context.Context) ([]sqlc.GetIntegrityThumbnailFilesRow, error)
+}
+
+// Storage is the subset of storage.Service used by the scanner.
+type Storage interface {
+	Exists(ctx context.Context, path string) (bool, error)
+	Download(ctx context.Context, path string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string, recursive bool) ([]storage.FileInfo, error)
+}
+
+// Options configures a scan.
+type Options struct {
+	// Concurrency bounds the number of originals read at once.
+	Concurrency int
+
+	// MaxBytesPerSecond caps the combined read rate of all workers. Zero
+	// reads as fast as storage allows.
+	MaxBytesPerSecond int64
+
+	// VerifyThumbnails also checks that every recorded thumbnail exists.
+	VerifyThumbnails bool
+
+	// Derivatives is where thumbnails are stored. Nil looks for them next
+	// to the originals.
+	Derivatives Storage
+}
+
+// Item is a single finding of a scan.
+type Item struct {
+	ID   string
+	Type string
+	Path string
+	// AssetID is the asset the finding belongs to, empty for untracked files.
+	AssetID string
+}
+
+// Report is the result of a scan.
+type Report struct {
+	Items      []Item
+	Originals  int
+	Thumbnails int
+	Duration   time.Duration
+}
+
+// Summary counts the findings of every type.
+func (r *Report) Summary() map[string]int64 {
+	counts := make(map[string]int64, len(Types))
+	for _, t := range Types {
+		counts[t] = 0
+	}
+	if r == nil {
+		return counts
+	}
+	for _, item := range r.Items {
+		counts[item.Type]++
+	}
+	return counts
+}
+
+// Scanner verifies the files tracked in the database against storage.
+type Scanner struct {
+	queries Queries
+	storage Storage
+	opts    Options
+	limiter *rateLimiter
+	log     *logrus.Entry
+}
+
+// New creates a scanner.
+func New(queries Queries, storage Storage, opts Options) *Scanner {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+
+	s := &Scanner{
+		queries: queries,
+		storage: storage,
+		opts:    opts,
+		log:     logrus.WithField("component", "integrity"),
+	}
+	if opts.MaxBytesPerSecond > 0 {
+		s.limiter = &rateLimiter{bytesPerSecond: opts.MaxBytesPerSecond}
+	}
+	return s
+}
+
+// Run scans every tracked file. An original that exists but cannot be read
+// is reported as a checksum mismatch; failing to reach storage at all aborts
+// the scan.
+func (s *Scanner) Run(ctx context.Context) (*Report, error) {
+	start := time.Now()
+
+	originals, err := s.queries.GetIntegrityOriginalAssets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list original assets: %w", err)
+	}
+	trackedPaths, err := s.queries.GetIntegrityTrackedPaths(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked paths: %w", err)
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"originals":   len(originals),
+		"concurrency": s.opts.Concurrency,
+	}).Info("starting integrity scan")
+
+	report := &Report{Originals: len(originals)}
+	items, err := forEach(ctx, s.opts.Concurrency, originals, s.checkOriginal)
+	if err != nil {
+		return nil, err
+	}
+	report.Items = append(report.Items, items...)
+
+	if s.opts.VerifyThumbnails {
+		thumbnails, err := s.queries.GetIntegrityThumbnailFiles(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list thumbnails: %w", err)
+		}
+		report.Thumbnails = len(thumbnails)
+		items, err := forEach(ctx, s.opts.Concurrency, thumbnails, s.checkThumbnail)
+		if err != nil {
+			return nil, err
+		}
+		report.Items = append(report.Items, items...)
+	}
+
+	tracked := make(map[string]struct{}, len(trackedPaths)+len(originals))
+	for _, p := range trackedPaths {
+		addTrackedPath(tracked, p)
+	}
+	for _, asset := range originals {
+		addTrackedPath(tracked, asset.OriginalPath)
+	}
+	items, err = s.untrackedFiles(ctx, tracked)
+	if err != nil {
+		return nil, err
+	}
+	report.Items = append(report.Items, items...)
+
+	SortItems(report.Items)
+	report.Duration = time.Since(start)
+
+	summary := report.Summary()
+	s.log.WithFields(logrus.Fields{
+		"originals":         report.Originals,
+		"thumbnails":        report.Thumbnails,
+		"checksum_mismatch": summary[TypeChecksumMismatch],
+		"missing_file":      summary[TypeMissingFile],
+		"missing_thumbnail": summary[TypeMissingThumbnail],
+		"untracked_file":    summary[TypeUntrackedFile],
+		"duration":          report.Duration.String(),
+	}).Info("integrity scan finished")
+
+	return report, nil
+}
+
+func (s *Scanner) checkOriginal(ctx context.Context, asset sqlc.GetIntegrityOriginalAssetsRow) (*Item, error) {
+	if asset.OriginalPath == "" {
+		return nil, nil
+	}
+
+	exists, err := s.storage.Exists(ctx, asset.OriginalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check asset file %q: %w", asset.OriginalPath, err)
+	}
+	item := &Item{ID: asset.ID.String(), Path: asset.OriginalPath, AssetID: asset.ID.String()}
+	if !exists {
+		item.Type = TypeMissingFile
+		return item, nil
+	}
+
+	if asset.OriginalReplaced {
+		// Downscaled on upload without keeping the uploaded file: nothing
+		// stored has the checksum any more.
+		return nil, nil
+	}
+	checksumPath := asset.ChecksumPath
+	if checksumPath == "" {
+		checksumPath = asset.OriginalPath
+	}
+	matches, err := s.checksumMatches(ctx, checksumPath, asset.Checksum, assets.ChecksumAlgorithm(asset.ChecksumAlgorithm))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		s.log.WithError(err).WithField("path", checksumPath).Warn("failed to read asset file")
+	}
+	if err != nil || !matches {
+		item.Type = TypeChecksumMismatch
+		return item, nil
+	}
+	return nil, nil
+}
+
+func (s *Scanner) checkThumbnail(ctx context.Context, file sqlc.GetIntegrityThumbnailFilesRow) (*Item, error) {
+	store := s.storage
+	if s.opts.Derivatives != nil {
+		store = s.opts.Derivatives
+	}
+	exists, err := store.Exists(ctx, file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check thumbnail %q: %w", file.Path, err)
+	}
+	if exists {
+		return nil, nil
+	}
+	return &Item{
+		ID:      derivedID(TypeMissingThumbnail, file.Path),
+		Type:    TypeMissingThumbnail,
+		Path:    file.Path,
+		AssetID: file.AssetId.String(),
+	}, nil
+}
+
+func (s *Scanner) untrackedFiles(ctx context.Context, tracked map[string]struct{}) ([]Item, error) {
+	files, err := s.storage.List(ctx, "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage files: %w", err)
+	}
+
+	var items []Item
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		p := strings.TrimSpace(file.Path)
+		if p == "" || IsIgnoredStoragePath(p) {
+			continue
+		}
+		if _, ok := tracked[p]; ok {
+			continue
+		}
+		items = append(items, Item{
+			ID:   derivedID(TypeUntrackedFile, p),
+			Type: TypeUntrackedFile,
+			Path: p,
+		})
+	}
+	return items, nil
+}
+
+// checksumMatches reads path and compares its checksum with stored, which
+// holds the hex digest, or the raw digest for older rows. A missing stored
+// checksum matches anything.
+func (s *Scanner) checksumMatches(ctx context.Context, path string, stored []byte, algorithm assets.ChecksumAlgorithm) (bool, error) {
+	if len(stored) == 0 {
+		return true, nil
+	}
+
+	reader, err := s.storage.Download(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	if algorithm == "" {
+		algorithm = assets.DefaultChecksumAlgorithm
+	}
+	var r io.Reader = reader
+	if s.limiter != nil {
+		r = &throttledReader{ctx: ctx, r: reader, limiter: s.limiter}
+	}
+	actual, err := assets.ComputeChecksum(r, algorithm)
+	if err != nil {
+		return false, err
+	}
+
+	actualHex := actual.Hex()
+	if strings.ToLower(strings.TrimSpace(string(stored))) == actualHex {
+		return true, nil
+	}
+	return hex.EncodeToString(stored) == actualHex, nil
+}
+
+// forEach runs check on every value with at most concurrency workers and
+// collects the findings. The first error stops the remaining checks.
+func forEach[T any](ctx context.Context, concurrency int, values []T, check func(context.Context, T) (*Item, error)) ([]Item, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		items    []Item
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	work := make(chan T)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range work {
+				item, err := check(ctx, v)
+				mu.Lock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = err
+					}
+					cancel()
+				case item != nil:
+					items = append(items, *item)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, v := range values {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- v
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return items, ctx.Err()
+}
+
+// SortItems orders findings by type, path and ID.
+func SortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Type != items[j].Type {
+			return items[i].Type < items[j].Type
+		}
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		return items[i].ID < items[j].ID
+	})
+}
+
+func addTrackedPath(paths map[string]struct{}, p string) {
+	p = strings.TrimSpace(p)
+	if p != "" {
+		paths[p] = struct{}{}
+	}
+}
+
+// IsIgnoredStoragePath reports whether a file in storage is a marker written
+// by the server rather than a file tracked by a row.
+func IsIgnoredStoragePath(storagePath string) bool {
+	switch path.Base(strings.TrimSpace(storagePath)) {
+	case ".immich", ".immich-go-write-check":
+		return true
+	default:
+		return false
+	}
+}
+
+// derivedID gives findings without an asset of their own a stable ID, so
+// the same file keeps its ID across scans.
+func derivedID(findingType, p string) string {
+	name := "immich-go-backend:integrity:untracked:" + p
+	if findingType != TypeUntrackedFile {
+		name = "immich-go-backend:integrity:" + findingType + ":" + p
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
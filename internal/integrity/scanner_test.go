@@ -0,0 +1,241 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // Immich uses SHA-1 asset checksums; not for crypto.
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+type fakeQueries struct {
+	originals  []sqlc.GetIntegrityOriginalAssetsRow
+	tracked    []string
+	thumbnails []sqlc.GetIntegrityThumbnailFilesRow
+}
+
+func (f *fakeQueries) GetIntegrityOriginalAssets(context.Context) ([]sqlc.GetIntegrityOriginalAssetsRow, error) {
+	return f.originals, nil
+}
+
+func (f *fakeQueries) GetIntegrityTrackedPaths(context.Context) ([]string, error) {
+	return f.tracked, nil
+}
+
+func (f *fakeQueries) GetIntegrityThumbnailFiles(context.Context) ([]sqlc.GetIntegrityThumbnailFilesRow, error) {
+	return f.thumbnails, nil
+}
+
+type fakeStorage struct {
+	files map[string][]byte
+
+	mu        sync.Mutex
+	reading   int
+	maxActive int
+}
+
+func (s *fakeStorage) Exists(_ context.Context, path string) (bool, error) {
+	_, ok := s.files[path]
+	return ok, nil
+}
+
+func (s *fakeStorage) Download(_ context.Context, path string) (io.ReadCloser, error) {
+	data, ok := s.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found")
+	}
+	s.mu.Lock()
+	s.reading++
+	s.maxActive = max(s.maxActive, s.reading)
+	s.mu.Unlock()
+	// Hold the file open for a moment so that concurrent reads overlap.
+	time.Sleep(5 * time.Millisecond)
+	return &trackedReader{Reader: bytes.NewReader(data), storage: s}, nil
+}
+
+func (s *fakeStorage) List(context.Context, string, bool) ([]storage.FileInfo, error) {
+	items := make([]storage.FileInfo, 0, len(s.files))
+	for path := range s.files {
+		items = append(items, storage.FileInfo{Path: path})
+	}
+	return items, nil
+}
+
+type trackedReader struct {
+	*bytes.Reader
+	storage *fakeStorage
+}
+
+func (r *trackedReader) Close() error {
+	r.storage.mu.Lock()
+	r.storage.reading--
+	r.storage.mu.Unlock()
+	return nil
+}
+
+func original(n byte, path string, checksum []byte) sqlc.GetIntegrityOriginalAssetsRow {
+	return sqlc.GetIntegrityOriginalAssetsRow{
+		ID:           pgtype.UUID{Bytes: uuid.UUID{15: n}, Valid: true},
+		OriginalPath: path,
+		Checksum:     checksum,
+	}
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data) //nolint:gosec // Immich uses SHA-1 asset checksums; not for crypto.
+	return hex.EncodeToString(sum[:])
+}
+
+func itemsOfType(report *Report, findingType string) []Item {
+	var items []Item
+	for _, item := range report.Items {
+		if item.Type == findingType {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func TestScannerDetectsMissingChecksumAndUntrackedFiles(t *testing.T) {
+	matchPayload := []byte("matching content")
+	queries := &fakeQueries{
+		originals: []sqlc.GetIntegrityOriginalAssetsRow{
+			original(1, "library/match.jpg", []byte(sha1Hex(matchPayload))),
+			original(2, "library/missing.jpg", []byte("missing-checksum")),
+			original(3, "library/mismatch.jpg", []byte("wrong-checksum")),
+		},
+		tracked: []string{"thumbs/tracked.webp"},
+	}
+	store := &fakeStorage{files: map[string][]byte{
+		".immich":                       []byte("install marker"),
+		"library/match.jpg":             matchPayload,
+		"library/mismatch.jpg":          []byte("actual content"),
+		"thumbs/tracked.webp":           []byte("tracked generated file"),
+		"upload/.immich-go-write-check": []byte("ok"),
+		"orphan/untracked.jpg":          []byte("orphan"),
+	}}
+
+	report, err := New(queries, store, Options{}).Run(context.Background())
+	require.NoError(t, err)
+
+	summary := report.Summary()
+	assert.EqualValues(t, 1, summary[TypeMissingFile])
+	assert.EqualValues(t, 1, summary[TypeChecksumMismatch])
+	assert.EqualValues(t, 1, summary[TypeUntrackedFile])
+	assert.EqualValues(t, 0, summary[TypeMissingThumbnail])
+	assert.Equal(t, 3, report.Originals)
+
+	missing := itemsOfType(report, TypeMissingFile)
+	require.Len(t, missing, 1)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", missing[0].ID)
+	assert.Equal(t, missing[0].ID, missing[0].AssetID)
+	assert.Equal(t, "library/missing.jpg", missing[0].Path)
+
+	mismatch := itemsOfType(report, TypeChecksumMismatch)
+	require.Len(t, mismatch, 1)
+	assert.Equal(t, "library/mismatch.jpg", mismatch[0].Path)
+
+	untracked := itemsOfType(report, TypeUntrackedFile)
+	require.Len(t, untracked, 1)
+	assert.Equal(t, "orphan/untracked.jpg", untracked[0].Path)
+	assert.Empty(t, untracked[0].AssetID)
+	assert.Equal(t, derivedID(TypeUntrackedFile, "orphan/untracked.jpg"), untracked[0].ID, "IDs are stable across scans")
+}
+
+func TestScannerAcceptsRawChecksumBytes(t *testing.T) {
+	payload := []byte("raw checksum content")
+	sum := sha1.Sum(payload) //nolint:gosec // Immich uses SHA-1 asset checksums; not for crypto.
+	queries := &fakeQueries{originals: []sqlc.GetIntegrityOriginalAssetsRow{original(1, "library/raw.jpg", sum[:])}}
+	store := &fakeStorage{files: map[string][]byte{"library/raw.jpg": payload}}
+
+	report, err := New(queries, store, Options{}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Items)
+}
+
+func TestScannerSkipsChecksumOfReplacedOriginals(t *testing.T) {
+	replaced := original(1, "library/downscaled.jpg", []byte("uploaded-checksum"))
+	replaced.OriginalReplaced = true
+	gone := original(2, "library/gone.jpg", []byte("uploaded-checksum"))
+	gone.OriginalReplaced = true
+	queries := &fakeQueries{originals: []sqlc.GetIntegrityOriginalAssetsRow{replaced, gone}}
+	store := &fakeStorage{files: map[string][]byte{"library/downscaled.jpg": []byte("smaller")}}
+
+	report, err := New(queries, store, Options{}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, itemsOfType(report, TypeChecksumMismatch))
+	assert.Len(t, itemsOfType(report, TypeMissingFile), 1, "a replaced original still has to exist")
+}
+
+func TestScannerVerifiesThumbnailsWhenAsked(t *testing.T) {
+	assetID := pgtype.UUID{Bytes: uuid.UUID{15: 1}, Valid: true}
+	queries := &fakeQueries{
+		thumbnails: []sqlc.GetIntegrityThumbnailFilesRow{
+			{AssetId: assetID, Path: "thumbs/present.webp"},
+			{AssetId: assetID, Path: "thumbs/gone.jpeg"},
+		},
+		tracked: []string{"thumbs/present.webp", "thumbs/gone.jpeg"},
+	}
+	store := &fakeStorage{files: map[string][]byte{"thumbs/present.webp": []byte("thumb")}}
+
+	report, err := New(queries, store, Options{}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Items, "thumbnails are only checked when asked to")
+
+	report, err = New(queries, store, Options{VerifyThumbnails: true}).Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, TypeMissingThumbnail, report.Items[0].Type)
+	assert.Equal(t, "thumbs/gone.jpeg", report.Items[0].Path)
+	assert.Equal(t, assetID.String(), report.Items[0].AssetID)
+	assert.Equal(t, 2, report.Thumbnails)
+}
+
+func TestScannerBoundsConcurrentReads(t *testing.T) {
+	queries := &fakeQueries{}
+	store := &fakeStorage{files: map[string][]byte{}}
+	for i := range 12 {
+		path := fmt.Sprintf("library/%02d.jpg", i)
+		payload := []byte(path)
+		store.files[path] = payload
+		queries.originals = append(queries.originals, original(byte(i), path, []byte(sha1Hex(payload))))
+	}
+
+	report, err := New(queries, store, Options{Concurrency: 3}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Items)
+	assert.LessOrEqual(t, store.maxActive, 3)
+	assert.Greater(t, store.maxActive, 1, "originals are read in parallel")
+}
+
+func TestRateLimiterSpreadsReads(t *testing.T) {
+	limiter := &rateLimiter{bytesPerSecond: 1000}
+	reader := &throttledReader{ctx: context.Background(), r: bytes.NewReader(make([]byte, 300)), limiter: limiter}
+
+	start := time.Now()
+	buf := make([]byte, 100)
+	for {
+		if _, err := reader.Read(buf); err == io.EOF {
+			break
+		}
+	}
+	// Three reads of 100 bytes at 1000 B/s: the second waits 100ms and the
+	// third 200ms.
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, limiter.wait(ctx, 100), context.Canceled)
+}
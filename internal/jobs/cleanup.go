@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/denysvitali/immich-go-backend/internal/telemetry"
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// queueNames lists the asynq queues served by the job service.
+var queueNames = []string{"critical", "high", "normal", "low", ReindexQueue}
+
+// cleanupPageSize is the number of tasks inspected per Redis round trip.
+const cleanupPageSize = 100
+
+// withRetention prepends the configured retention so finished tasks stay
+// visible in queue statistics until the cleanup removes them. Options given
+// by the caller take precedence.
+func (s *Service) withRetention(opts []asynq.Option) []asynq.Option {
+	if s.retention <= 0 {
+		return opts
+	}
+	return append([]asynq.Option{asynq.Retention(s.retention)}, opts...)
+}
+
+// registerMetrics exposes the dead-letter table size per queue as a gauge.
+func (s *Service) registerMetrics() error {
+	meter := telemetry.GetMeter()
+
+	deadLetter, err := meter.Int64ObservableGauge(
+		"jobs_dead_letter",
+		metric.WithDescription("Number of failed jobs in the dead-letter table"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		rows, err := s.db.CountJobFailuresByQueue(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count dead-letter jobs: %w", err)
+		}
+		for _, row := range rows {
+			o.ObserveInt64(deadLetter, row.Count, metric.WithAttributes(attribute.String("queue", row.Queue)))
+		}
+		return nil
+	}, deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to register dead-letter gauge: %w", err)
+	}
+	return nil
+}
+
+// runCleanup removes expired tasks and idempotency keys every cleanup
+// interval until stop is closed.
+func (s *Service) runCleanup(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if keys, err := s.CleanupExpiredIdempotencyKeys(context.Background()); err != nil {
+				s.logger.WithError(err).Warn("Idempotency key cleanup failed")
+			} else if keys > 0 {
+				s.logger.WithField("removed", keys).Info("Removed expired idempotency keys")
+			}
+
+			removed, err := s.CleanupExpiredJobs(context.Background())
+			if err != nil {
+				s.logger.WithError(err).Warn("Job cleanup failed")
+				continue
+			}
+			if removed > 0 {
+				s.logger.WithField("removed", removed).Info("Removed expired jobs")
+			}
+		}
+	}
+}
+
+// CleanupExpiredJobs deletes completed and archived tasks that finished more
+// than the retention period ago. Archived tasks are safe to drop because
+// terminal failures are also recorded in the dead-letter table.
+func (s *Service) CleanupExpiredJobs(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.retention)
+	removed := 0
+
+	for _, queue := range queueNames {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		n, err := s.deleteTasksBefore(queue, cutoff, s.inspector.ListCompletedTasks,
+			func(t *asynq.TaskInfo) time.Time { return t.CompletedAt })
+		removed += n
+		if err != nil {
+			return removed, fmt.Errorf("failed to clean completed tasks in %s: %w", queue, err)
+		}
+
+		n, err = s.deleteTasksBefore(queue, cutoff, s.inspector.ListArchivedTasks,
+			func(t *asynq.TaskInfo) time.Time { return t.LastFailedAt })
+		removed += n
+		if err != nil {
+			return removed, fmt.Errorf("failed to clean archived tasks in %s: %w", queue, err)
+		}
+	}
+
+	return removed, nil
+}
+
+// CleanupExpiredIdempotencyKeys deletes the expired idempotency keys of
+// every user. Requests only expire the keys of the user sending them, which
+// leaves the keys of users who stop sending requests behind.
+func (s *Service) CleanupExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	removed, err := s.db.DeleteAllExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return removed, nil
+}
+
+// deleteTasksBefore deletes the tasks returned by list whose finishedAt is
+// before cutoff. Deleting shifts later tasks onto earlier pages, so a page is
+// only advanced past the tasks that were kept.
+func (s *Service) deleteTasksBefore(
+	queue string,
+	cutoff time.Time,
+	list func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error),
+	finishedAt func(*asynq.TaskInfo) time.Time,
+) (int, error) {
+	removed := 0
+	for page := 1; ; {
+		tasks, err := list(queue, asynq.Page(page), asynq.PageSize(cleanupPageSize))
+		if err != nil {
+			return removed, err
+		}
+
+		deleted := 0
+		for _, task := range tasks {
+			if !finishedAt(task).Before(cutoff) {
+				continue
+			}
+			if err := s.inspector.DeleteTask(queue, task.ID); err != nil {
+				return removed, err
+			}
+			deleted++
+		}
+		removed += deleted
+
+		if len(tasks) < cleanupPageSize {
+			return removed, nil
+		}
+		if deleted == 0 {
+			page++
+		}
+	}
+}
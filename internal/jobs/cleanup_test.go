@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRetention(t *testing.T) {
+	s := &Service{retention: 24 * time.Hour}
+
+	opts := s.withRetention([]asynq.Option{asynq.Queue("low")})
+	assert.Len(t, opts, 2)
+	assert.Equal(t, asynq.RetentionOpt, opts[0].Type())
+	assert.Equal(t, 24*time.Hour, opts[0].Value())
+	assert.Equal(t, asynq.QueueOpt, opts[1].Type())
+
+	disabled := &Service{}
+	assert.Len(t, disabled.withRetention([]asynq.Option{asynq.Queue("low")}), 1)
+}
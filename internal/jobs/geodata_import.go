@@ -0,0 +1,124 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/geodata"
+)
+
+// geodataImportKey is the system metadata key of the latest report.
+const geodataImportKey = "geodata-import"
+
+// geodataImportTimeout bounds an import, which also resolves the place of
+// every geotagged asset again.
+const geodataImportTimeout = 2 * time.Hour
+
+// ErrNoGeodataImport is returned when the geodata was never imported.
+var ErrNoGeodataImport = errors.New("geodata was never imported")
+
+// GeodataImport reports a run of the geodata import.
+type GeodataImport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Places in the imported dataset and its date
+	Places     int    `json:"places"`
+	LastUpdate string `json:"last_update"`
+	// Assets counts the geotagged assets that were checked, Updated those
+	// whose place names changed.
+	Assets  int `json:"assets"`
+	Updated int `json:"updated"`
+}
+
+// EnqueueGeodataImport queues an import of the reverse geocoding dataset.
+// While one is queued or running, further imports are not queued.
+func (s *Service) EnqueueGeodataImport(ctx context.Context) error {
+	err := s.EnqueueJob(ctx, JobTypeGeodataImport, struct{}{},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeGeodataImport)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(geodataImportTimeout),
+		asynq.Retention(0),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// HandleGeodataImport imports the reverse geocoding dataset of the geodata
+// directory, then updates the place names of the geotagged assets that are
+// missing or changed with it.
+func (h *Handlers) HandleGeodataImport(ctx context.Context, _ *asynq.Task) error {
+	if h.config == nil || h.config.Geodata.Dir == "" {
+		return fmt.Errorf("no geodata directory is configured: %w", asynq.SkipRetry)
+	}
+
+	report := GeodataImport{StartedAt: time.Now()}
+	imported, err := geodata.Import(ctx, h.db, h.config.Geodata.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to import geodata: %w", err)
+	}
+	report.Places = imported.Places
+	report.LastUpdate = imported.LastUpdate
+	h.logger.WithFields(logrus.Fields{
+		"places":      imported.Places,
+		"removed":     imported.Removed,
+		"last_update": imported.LastUpdate,
+	}).Info("Geodata imported")
+
+	updated, err := geodata.UpdateAssetPlaces(ctx, h.db)
+	if err != nil {
+		return fmt.Errorf("failed to update asset places: %w", err)
+	}
+	report.Assets = updated.Assets
+	report.Updated = updated.Updated
+	report.FinishedAt = time.Now()
+
+	if err := saveGeodataImport(ctx, h.db, &report); err != nil {
+		return err
+	}
+	h.logger.WithFields(logrus.Fields{
+		"assets":  report.Assets,
+		"updated": report.Updated,
+	}).Info("Asset places updated")
+	return nil
+}
+
+// GetGeodataImport returns the report of the latest geodata import.
+func (s *Service) GetGeodataImport(ctx context.Context) (*GeodataImport, error) {
+	row, err := s.db.GetSystemMetadata(ctx, geodataImportKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoGeodataImport
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geodata import: %w", err)
+	}
+	var report GeodataImport
+	if err := json.Unmarshal(row.Value, &report); err != nil {
+		return nil, fmt.Errorf("failed to decode geodata import: %w", err)
+	}
+	return &report, nil
+}
+
+func saveGeodataImport(ctx context.Context, queries *sqlc.Queries, report *GeodataImport) error {
+	value, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode geodata import: %w", err)
+	}
+	if _, err := queries.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   geodataImportKey,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to save geodata import: %w", err)
+	}
+	return nil
+}
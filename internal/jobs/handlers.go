@@ -23,20 +23,24 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
+	"github.com/denysvitali/immich-go-backend/internal/geodata"
+	"github.com/denysvitali/immich-go-backend/internal/integrity"
 	"github.com/denysvitali/immich-go-backend/internal/libraries"
 	"github.com/denysvitali/immich-go-backend/internal/ml"
+	"github.com/denysvitali/immich-go-backend/internal/stacks"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 // Handlers contains all job handlers
 type Handlers struct {
-	db             *sqlc.Queries
-	assetService   *assets.Service
-	libraryService *libraries.Service
-	storageService *storage.Service
-	mlClient       *ml.Client
-	config         *config.Config
-	logger         *logrus.Logger
+	db                *sqlc.Queries
+	assetService      *assets.Service
+	libraryService    *libraries.Service
+	storageService    *storage.Service
+	mlClient          *ml.Client
+	config            *config.Config
+	metadataExtractor *assets.MetadataExtractor
+	logger            *logrus.Logger
 }
 
 // NewHandlers creates new job handlers. mlClient and cfg may be nil (ML jobs skip).
@@ -49,13 +53,14 @@ func NewHandlers(
 	cfg *config.Config,
 ) *Handlers {
 	return &Handlers{
-		db:             db,
-		assetService:   assetService,
-		libraryService: libraryService,
-		storageService: storageService,
-		mlClient:       mlClient,
-		config:         cfg,
-		logger:         logrus.StandardLogger(),
+		db:                db,
+		assetService:      assetService,
+		libraryService:    libraryService,
+		storageService:    storageService,
+		mlClient:          mlClient,
+		config:            cfg,
+		metadataExtractor: assets.NewMetadataExtractorWithLimits(assets.MetadataLimitsFromConfig(cfg)).WithDefaultLocation(cfg.DefaultLocation()).WithExiftool(assets.UseExiftool(cfg)),
+		logger:            logrus.StandardLogger(),
 	}
 }
 
@@ -87,27 +92,42 @@ func (h *Handlers) HandleThumbnailGeneration(ctx context.Context, task *asynq.Ta
 		return fmt.Errorf("failed to get asset: %w", err)
 	}
 
-	// Only generate thumbnails for image assets
-	if !strings.EqualFold(asset.Type, string(assets.AssetTypeImage)) {
-		h.logger.WithFields(logrus.Fields{
-			"asset_id":   asset.ID,
-			"asset_type": asset.Type,
-		}).Info("Skipping thumbnail generation for non-image asset")
+	if h.config != nil && !h.config.Features.ThumbnailGenerationEnabled {
+		h.logger.WithField("asset_id", asset.ID).Info("Thumbnail generation is disabled, skipping")
 		return nil
 	}
 
-	// Download the original asset from storage
-	reader, err := h.storageService.Download(ctx, asset.OriginalPath)
-	if err != nil {
-		return fmt.Errorf("failed to download original asset: %w", err)
-	}
-	defer reader.Close()
+	// Images are thumbnailed from the original, videos from a frame of it
+	generator := h.thumbnailGenerator()
+	var thumbnails map[assets.ThumbnailType]assets.Thumbnail
+	switch {
+	case strings.EqualFold(asset.Type, string(assets.AssetTypeImage)):
+		reader, err := h.storageService.Download(ctx, asset.OriginalPath)
+		if err != nil {
+			return fmt.Errorf("failed to download original asset: %w", err)
+		}
+		defer reader.Close()
 
-	// Generate all thumbnail sizes at once
-	generator := assets.NewThumbnailGenerator()
-	thumbnails, err := generator.GenerateThumbnails(ctx, reader, asset.OriginalFileName)
-	if err != nil {
-		return fmt.Errorf("failed to generate thumbnails: %w", err)
+		thumbnails, err = generator.GenerateThumbnails(ctx, reader, asset.OriginalFileName)
+		if err != nil {
+			return fmt.Errorf("failed to generate thumbnails: %w", err)
+		}
+	case strings.EqualFold(asset.Type, string(assets.AssetTypeVideo)):
+		// Skip gracefully rather than retry a job that cannot succeed
+		if !ffmpeg.IsAvailable() {
+			h.logger.WithField("asset_id", asset.ID).Warn("ffmpeg not available, skipping video thumbnail generation")
+			return nil
+		}
+		thumbnails, err = h.generateVideoThumbnails(ctx, generator, asset)
+		if err != nil {
+			return err
+		}
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"asset_id":   asset.ID,
+			"asset_type": asset.Type,
+		}).Info("Skipping thumbnail generation for asset without a picture")
+		return nil
 	}
 
 	// Content type mapping per thumbnail type
@@ -118,11 +138,11 @@ func (h *Handlers) HandleThumbnailGeneration(ctx context.Context, task *asynq.Ta
 	}
 
 	// Upload each thumbnail and record it in the database
-	for thumbType, data := range thumbnails {
+	for thumbType, thumbnail := range thumbnails {
 		thumbPath := generator.GetThumbnailPath(asset.OriginalPath, thumbType)
 		contentType := thumbContentType[thumbType]
 
-		if err := h.storageService.UploadBytes(ctx, thumbPath, data, contentType); err != nil {
+		if err := h.storageService.Derivatives().UploadBytes(ctx, thumbPath, thumbnail.Data, contentType); err != nil {
 			h.logger.WithError(err).WithFields(logrus.Fields{
 				"asset_id":   asset.ID,
 				"thumb_type": thumbType,
@@ -131,11 +151,7 @@ func (h *Handlers) HandleThumbnailGeneration(ctx context.Context, task *asynq.Ta
 			continue
 		}
 
-		if _, err := h.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
-			AssetId: asset.ID,
-			Type:    string(thumbType),
-			Path:    thumbPath,
-		}); err != nil {
+		if _, err := h.db.UpsertAssetFile(ctx, assets.ThumbnailFileParams(asset.ID, thumbType, thumbPath, thumbnail)); err != nil {
 			h.logger.WithError(err).WithFields(logrus.Fields{
 				"asset_id":   asset.ID,
 				"thumb_type": thumbType,
@@ -148,13 +164,62 @@ func (h *Handlers) HandleThumbnailGeneration(ctx context.Context, task *asynq.Ta
 			"asset_id":   asset.ID,
 			"thumb_type": thumbType,
 			"thumb_path": thumbPath,
-			"size_bytes": len(data),
+			"size_bytes": len(thumbnail.Data),
 		}).Debug("Thumbnail generated and stored")
 	}
 
+	if thumb, ok := thumbnails[assets.ThumbnailTypeThumb]; ok {
+		if err := assets.StoreThumbnailLayout(ctx, h.db, asset.ID, thumb.Data); err != nil {
+			h.logger.WithError(err).WithField("asset_id", asset.ID).Warn("Failed to store thumbnail layout")
+		}
+		if err := assets.StoreBlurHash(ctx, h.db, generator, asset.ID, thumb.Data); err != nil {
+			h.logger.WithError(err).WithField("asset_id", asset.ID).Warn("Failed to store blurhash")
+		}
+	}
+
 	return nil
 }
 
+// generateVideoThumbnails generates the thumbnails of a video asset from a
+// frame of its original. ffmpeg needs a seekable file, so the original is
+// copied to a temp file first.
+func (h *Handlers) generateVideoThumbnails(ctx context.Context, generator *assets.ThumbnailGenerator, asset sqlc.Asset) (map[assets.ThumbnailType]assets.Thumbnail, error) {
+	tmpFile, err := os.CreateTemp("", "video-thumb-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file for video thumbnails: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	reader, err := h.storageService.Download(ctx, asset.OriginalPath)
+	if err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to download original asset: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(tmpFile, reader); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to write video to temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	thumbnails, err := generator.GenerateVideoThumbnails(ctx, tmpPath, asset.OriginalFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate video thumbnails: %w", err)
+	}
+	return thumbnails, nil
+}
+
+// thumbnailGenerator returns the generator of the asset service, which has
+// the configured thumbnail settings, or one with the defaults.
+func (h *Handlers) thumbnailGenerator() *assets.ThumbnailGenerator {
+	if h.assetService != nil {
+		return h.assetService.ThumbnailGenerator()
+	}
+	return assets.NewThumbnailGenerator()
+}
+
 // MetadataExtractionPayload contains data for metadata extraction
 type MetadataExtractionPayload struct {
 	AssetID string `json:"asset_id"`
@@ -210,12 +275,18 @@ func (h *Handlers) HandleMetadataExtraction(ctx context.Context, task *asynq.Tas
 	defer reader.Close()
 
 	// 5. Extract metadata.
-	extractor := assets.NewMetadataExtractor()
-	meta, err := extractor.ExtractMetadata(ctx, reader, asset.OriginalFileName, contentType, fileSize)
+	// The extractor is shared so its concurrency limit spans all workers.
+	meta, err := h.metadataExtractor.ExtractMetadata(ctx, reader, asset.OriginalFileName, contentType, fileSize)
 	if err != nil {
 		// ExtractMetadata itself never returns a hard error — but guard anyway.
 		log.WithError(err).Warn("Metadata extraction returned an error; continuing with partial data")
 	}
+	for _, warning := range meta.Warnings {
+		log.WithField("reason", warning).Warn("Asset metadata is incomplete")
+	}
+	if err := assets.ApplyStoredTakeoutSidecar(ctx, h.db, pgAssetID, meta); err != nil {
+		log.WithError(err).Warn("Failed to apply Takeout sidecar; using embedded metadata")
+	}
 
 	log.WithFields(logrus.Fields{
 		"content_type": contentType,
@@ -276,6 +347,13 @@ func (h *Handlers) HandleMetadataExtraction(ctx context.Context, task *asynq.Tas
 		exifParams.DateTimeOriginal = pgtype.Timestamptz{Time: *meta.DateTaken, Valid: true}
 		exifParams.ModifyDate = pgtype.Timestamptz{Time: *meta.DateTaken, Valid: true}
 	}
+	if meta.TimeZone != nil {
+		exifParams.TimeZone = pgtype.Text{String: *meta.TimeZone, Valid: true}
+	}
+
+	if err := geodata.FillExifPlace(ctx, h.db, &exifParams); err != nil {
+		log.WithError(err).Warn("Failed to resolve the place of the asset")
+	}
 
 	if _, err := h.db.CreateOrUpdateExif(ctx, exifParams); err != nil {
 		return fmt.Errorf("failed to persist EXIF data for asset %s: %w", assetID, err)
@@ -283,12 +361,14 @@ func (h *Handlers) HandleMetadataExtraction(ctx context.Context, task *asynq.Tas
 
 	// Mirror assets.Service.updateAssetMetadata: the timeline buckets group
 	// by assets."localDateTime", so the EXIF capture date must be written
-	// there too. This handler and the inline TriggerProcessing path are
-	// parallel implementations — date handling must stay in sync.
+	// there too, and it replaces the file time the upload was dated with.
+	// This handler and the inline TriggerProcessing path are parallel
+	// implementations — date handling must stay in sync.
 	if meta.DateTaken != nil {
-		if err := h.db.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+		if err := h.db.UpdateAssetCaptureDate(ctx, sqlc.UpdateAssetCaptureDateParams{
 			ID:            pgAssetID,
-			LocalDateTime: pgtype.Timestamptz{Time: *meta.DateTaken, Valid: true},
+			FileCreatedAt: pgtype.Timestamptz{Time: *meta.DateTaken, Valid: true},
+			LocalDateTime: pgtype.Timestamptz{Time: assets.LocalDateTime(*meta.DateTaken, meta.TimeZone, h.config.DefaultLocation()), Valid: true},
 		}); err != nil {
 			return fmt.Errorf("failed to update asset timeline date for asset %s: %w", assetID, err)
 		}
@@ -349,7 +429,7 @@ func (h *Handlers) HandleLibraryScan(ctx context.Context, task *asynq.Task) erro
 	}).Info("Scanning library")
 
 	// Perform library scan
-	_, err = h.libraryService.ScanLibrary(ctx, libraryID, userID, payload.FullScan, payload.ForceRefresh)
+	_, err = h.libraryService.ScanLibrary(ctx, userID, libraryID, payload.FullScan, payload.ForceRefresh)
 	if err != nil {
 		return fmt.Errorf("library scan failed: %w", err)
 	}
@@ -459,7 +539,7 @@ func (h *Handlers) HandleVideoTranscode(ctx context.Context, task *asynq.Task) e
 	}
 	defer outputFile.Close()
 
-	if err := h.storageService.Upload(ctx, encodedPath, outputFile, "video/mp4"); err != nil {
+	if err := h.storageService.Derivatives().Upload(ctx, encodedPath, outputFile, "video/mp4"); err != nil {
 		return fmt.Errorf("failed to upload transcoded video: %w", err)
 	}
 
@@ -587,6 +667,7 @@ func (h *Handlers) HandleFaceDetection(ctx context.Context, task *asynq.Task) er
 			BoundingBoxY1: face.BoundingBox.Y1,
 			BoundingBoxX2: face.BoundingBox.X2,
 			BoundingBoxY2: face.BoundingBox.Y2,
+			Score:         float32(face.Score),
 		})
 		if err != nil {
 			return fmt.Errorf("create asset face: %w", err)
@@ -766,6 +847,93 @@ func (h *Handlers) HandleSmartSearchIndex(ctx context.Context, task *asynq.Task)
 	return nil
 }
 
+// ObjectDetectionPayload contains data for object detection
+type ObjectDetectionPayload struct {
+	AssetID string `json:"asset_id"`
+}
+
+// HandleObjectDetection tags an image with the objects and scenes the ML
+// service detects in it. Running it again for an asset replaces its labels,
+// so a re-detection never leaves stale or duplicate labels behind.
+func (h *Handlers) HandleObjectDetection(ctx context.Context, task *asynq.Task) error {
+	var payload ObjectDetectionPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+
+	assetID, err := uuid.Parse(payload.AssetID)
+	if err != nil {
+		return fmt.Errorf("invalid asset UUID: %w", err)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"asset_id": assetID,
+	}).Info("Detecting objects")
+
+	if h.config == nil || !h.config.ObjectDetectionActive() || h.mlClient == nil || !h.mlClient.Enabled() {
+		h.logger.WithField("asset_id", assetID).Info("Skipping object detection: ML/object detection disabled")
+		return nil
+	}
+
+	assetUUID := pgtype.UUID{Bytes: assetID, Valid: true}
+	asset, err := h.db.GetAsset(ctx, assetUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get asset %s: %w", assetID, err)
+	}
+	if !strings.EqualFold(asset.Type, string(assets.AssetTypeImage)) {
+		h.logger.WithFields(logrus.Fields{
+			"asset_id":   asset.ID,
+			"asset_type": asset.Type,
+		}).Info("Skipping object detection for non-image asset")
+		return nil
+	}
+
+	imageBytes, err := h.loadAssetImageBytes(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	od := h.config.MachineLearning.ObjectDetection
+	objects, err := h.mlClient.DetectObjects(ctx, imageBytes, od.ModelName, od.MinScore)
+	if err != nil {
+		if errors.Is(err, ml.ErrDisabled) {
+			return nil
+		}
+		return fmt.Errorf("object detection ML call: %w", err)
+	}
+
+	objects = ml.NormalizeLabels(objects)
+	// Keep must be an empty array rather than NULL so that an image without
+	// any detected object loses the labels of an earlier detection.
+	labels := make([]string, 0, len(objects))
+	scores := make([]float32, 0, len(objects))
+	for _, object := range objects {
+		labels = append(labels, object.Label)
+		scores = append(scores, float32(object.Score))
+	}
+	if err := h.db.DeleteAssetLabelsExcept(ctx, sqlc.DeleteAssetLabelsExceptParams{
+		AssetID: assetUUID,
+		Keep:    labels,
+	}); err != nil {
+		return fmt.Errorf("clear stale labels: %w", err)
+	}
+	if len(labels) > 0 {
+		if err := h.db.UpsertAssetLabels(ctx, sqlc.UpsertAssetLabelsParams{
+			AssetID: assetUUID,
+			Labels:  labels,
+			Scores:  scores,
+		}); err != nil {
+			return fmt.Errorf("store labels: %w", err)
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"asset_id":    assetID,
+		"label_count": len(labels),
+	}).Info("Object detection complete")
+	return nil
+}
+
 // DuplicateDetectionPayload contains data for duplicate detection
 type DuplicateDetectionPayload struct {
 	UserID string `json:"user_id"`
@@ -840,6 +1008,38 @@ func (h *Handlers) HandleDuplicateDetection(ctx context.Context, task *asynq.Tas
 	return nil
 }
 
+// AutoStackPayload contains data for auto-stacking a user's assets
+type AutoStackPayload struct {
+	UserID  string                  `json:"user_id"`
+	Options stacks.AutoStackOptions `json:"options"`
+}
+
+// HandleAutoStack stacks a user's bursts, exposure brackets and live photo
+// pairs. Stacked assets are not candidates again, so a retried job only
+// stacks what an earlier attempt did not get to.
+func (h *Handlers) HandleAutoStack(ctx context.Context, task *asynq.Task) error {
+	var payload AutoStackPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user UUID: %w", err)
+	}
+
+	groups, err := stacks.NewAutoStacker(h.db).Apply(ctx, pgtype.UUID{Bytes: userID, Valid: true}, payload.Options)
+	if err != nil {
+		return fmt.Errorf("failed to auto-stack assets after %d stacks: %w", len(groups), err)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"stacks":  len(groups),
+	}).Info("Auto-stacking complete")
+	return nil
+}
+
 // detectEmbeddingDuplicates groups assets whose CLIP embeddings are closer than
 // the configured max distance. Returns the number of pairs linked.
 func (h *Handlers) detectEmbeddingDuplicates(ctx context.Context, owner pgtype.UUID) (int, error) {
@@ -971,7 +1171,7 @@ func (h *Handlers) loadAssetImageBytes(ctx context.Context, asset sqlc.Asset) ([
 		Type:    string(assets.ThumbnailTypePreview),
 	})
 	if err == nil && len(previews) > 0 {
-		reader, err := h.storageService.Download(ctx, previews[0].Path)
+		reader, err := h.storageService.Derivatives().Download(ctx, previews[0].Path)
 		if err == nil {
 			defer reader.Close()
 			data, readErr := io.ReadAll(reader)
@@ -1045,6 +1245,169 @@ func parseAssetID(assetID string) (uuid.UUID, error) {
 	return parsed, nil
 }
 
+// UserDeletionPayload contains data for purging a removed user
+type UserDeletionPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// userDeletionBatchSize is the number of assets purged per database round.
+const userDeletionBatchSize = 500
+
+// HandleUserDeletion removes the files and rows of a user marked for removal.
+// Assets are purged in batches and each batch is deleted from the database
+// once its files are gone, so a retried job continues where it stopped.
+// Files that are already missing count as deleted. Originals and sidecars of
+// external library assets belong to the library and are left in place.
+func (h *Handlers) HandleUserDeletion(ctx context.Context, task *asynq.Task) error {
+	var payload UserDeletionPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q: %w", payload.UserID, asynq.SkipRetry)
+	}
+	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
+
+	user, err := h.db.GetUserIncludingDeleted(ctx, userUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// A retry after the user row is gone still clears the audit rows.
+		return h.deleteUserAudit(ctx, userUUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status != "removing" {
+		h.logger.WithField("user_id", payload.UserID).Info("User is no longer marked for removal, skipping deletion")
+		return nil
+	}
+
+	if err := h.db.DeleteUserStacks(ctx, userUUID); err != nil {
+		return fmt.Errorf("failed to delete stacks: %w", err)
+	}
+
+	purged := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, err := h.db.ListUserAssetsForPurge(ctx, sqlc.ListUserAssetsForPurgeParams{
+			OwnerID:   userUUID,
+			BatchSize: userDeletionBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list assets: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]pgtype.UUID, len(batch))
+		paths := make([]string, 0, len(batch)*2)
+		derivedPaths := make([]string, 0, len(batch))
+		for i, asset := range batch {
+			ids[i] = asset.ID
+			if !asset.IsExternal {
+				paths = append(paths, asset.OriginalPath)
+				if asset.SidecarPath.Valid {
+					paths = append(paths, asset.SidecarPath.String)
+				}
+			}
+			if asset.EncodedVideoPath.Valid {
+				derivedPaths = append(derivedPaths, asset.EncodedVideoPath.String)
+			}
+		}
+		files, err := h.db.GetAssetFilesByAssetIDs(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to list asset files: %w", err)
+		}
+		for _, file := range files {
+			derivedPaths = append(derivedPaths, file.Path)
+		}
+
+		for _, path := range paths {
+			if err := h.deleteStoredFile(ctx, h.storageService, path); err != nil {
+				return err
+			}
+		}
+		for _, path := range derivedPaths {
+			if err := h.deleteStoredFile(ctx, h.storageService.Derivatives(), path); err != nil {
+				return err
+			}
+		}
+
+		if err := h.db.PurgeAssets(ctx, sqlc.PurgeAssetsParams{AssetIds: ids, OwnerID: userUUID}); err != nil {
+			return fmt.Errorf("failed to purge assets: %w", err)
+		}
+		purged += len(batch)
+		h.logger.WithFields(logrus.Fields{
+			"user_id": payload.UserID,
+			"purged":  purged,
+		}).Debug("Purged batch of user assets")
+	}
+
+	thumbnails, err := h.db.ListUserPersonThumbnailPaths(ctx, userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to list person thumbnails: %w", err)
+	}
+	for _, path := range thumbnails {
+		if err := h.deleteStoredFile(ctx, h.storageService.Derivatives(), path); err != nil {
+			return err
+		}
+	}
+
+	if err := h.deleteStoredFile(ctx, h.storageService, user.ProfileImagePath); err != nil {
+		return err
+	}
+
+	// Remaining rows (albums, people, faces, activity, shared links,
+	// partners, sessions, ...) cascade.
+	if err := h.db.HardDeleteUser(ctx, userUUID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := h.deleteUserAudit(ctx, userUUID); err != nil {
+		return err
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": payload.UserID,
+		"assets":  purged,
+	}).Info("User deleted")
+	return nil
+}
+
+// deleteUserAudit removes the audit rows written for the user's own clients,
+// including those the purge and the cascading deletes just added.
+func (h *Handlers) deleteUserAudit(ctx context.Context, userID pgtype.UUID) error {
+	if err := h.db.DeleteUserAssetAudit(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete asset audit: %w", err)
+	}
+	if err := h.db.DeleteUserAlbumAudit(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete album audit: %w", err)
+	}
+	if err := h.db.DeleteUserLegacyAudit(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete audit: %w", err)
+	}
+	return nil
+}
+
+// deleteStoredFile removes path from store, treating a missing file as
+// already deleted.
+func (h *Handlers) deleteStoredFile(ctx context.Context, store *storage.Service, path string) error {
+	if path == "" || store == nil {
+		return nil
+	}
+	if err := store.DeleteAsset(ctx, path); err != nil {
+		exists, existsErr := store.AssetExists(ctx, path)
+		if existsErr == nil && !exists {
+			return nil
+		}
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+	return nil
+}
+
 // StorageMigrationPayload contains data for storage migration
 type StorageMigrationPayload struct {
 	AssetID     string `json:"asset_id"`
@@ -1071,24 +1434,145 @@ func (h *Handlers) HandleStorageMigration(ctx context.Context, task *asynq.Task)
 	return nil
 }
 
+// IntegrityScanPayload contains data for an integrity scan
+type IntegrityScanPayload struct {
+	VerifyThumbnails bool `json:"verify_thumbnails"`
+}
+
+// HandleIntegrityScan re-reads every original, compares its checksum with
+// the stored one, replaces the integrity report with the findings and
+// notifies the admins.
+func (h *Handlers) HandleIntegrityScan(ctx context.Context, task *asynq.Task) error {
+	var payload IntegrityScanPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+	if h.storageService == nil {
+		return fmt.Errorf("integrity scan requires storage: %w", asynq.SkipRetry)
+	}
+
+	opts := integrity.Options{
+		VerifyThumbnails: payload.VerifyThumbnails,
+		Derivatives:      h.storageService.Derivatives(),
+	}
+	if h.config != nil {
+		opts.Concurrency = h.config.Integrity.Concurrency
+		opts.MaxBytesPerSecond = h.config.Integrity.MaxBytesPerSecond
+	}
+
+	report, err := integrity.New(h.db, h.storageService, opts).Run(ctx)
+	if err != nil {
+		return fmt.Errorf("integrity scan failed: %w", err)
+	}
+	if err := h.saveIntegrityReport(ctx, report); err != nil {
+		return err
+	}
+	h.notifyIntegrityReport(ctx, report)
+
+	return nil
+}
+
+// saveIntegrityReport replaces the findings of the previous scan.
+func (h *Handlers) saveIntegrityReport(ctx context.Context, report *integrity.Report) error {
+	if err := h.db.ClearIntegrityReport(ctx); err != nil {
+		return fmt.Errorf("failed to clear integrity report: %w", err)
+	}
+
+	for _, item := range report.Items {
+		id, err := uuid.Parse(item.ID)
+		if err != nil {
+			return fmt.Errorf("invalid integrity report item ID %q: %w", item.ID, err)
+		}
+		params := sqlc.CreateIntegrityReportItemParams{
+			ID:   pgtype.UUID{Bytes: id, Valid: true},
+			Type: item.Type,
+			Path: item.Path,
+		}
+		if assetID, err := uuid.Parse(item.AssetID); err == nil {
+			params.AssetId = pgtype.UUID{Bytes: assetID, Valid: true}
+		}
+		if err := h.db.CreateIntegrityReportItem(ctx, params); err != nil {
+			return fmt.Errorf("failed to save integrity report item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// notifyIntegrityReport tells every admin the outcome of a scan. The report
+// is already saved, so failing to notify does not fail the job.
+func (h *Handlers) notifyIntegrityReport(ctx context.Context, report *integrity.Report) {
+	admins, err := h.db.GetAdminUserIDs(ctx)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to list admins for the integrity scan notification")
+		return
+	}
+
+	summary := report.Summary()
+	problems := summary[integrity.TypeChecksumMismatch] + summary[integrity.TypeMissingFile] + summary[integrity.TypeMissingThumbnail]
+	level, title := "success", "Integrity scan found no problems"
+	if problems > 0 {
+		level, title = "warning", fmt.Sprintf("Integrity scan found %d corrupted or missing files", problems)
+	}
+	description := fmt.Sprintf("Checked %d originals: %d corrupted, %d missing, %d missing thumbnails, %d untracked files.",
+		report.Originals,
+		summary[integrity.TypeChecksumMismatch],
+		summary[integrity.TypeMissingFile],
+		summary[integrity.TypeMissingThumbnail],
+		summary[integrity.TypeUntrackedFile])
+	data, err := json.Marshal(summary)
+	if err != nil {
+		data = []byte("{}")
+	}
+
+	for _, adminID := range admins {
+		if _, err := h.db.CreateNotification(ctx, sqlc.CreateNotificationParams{
+			UserId:      adminID,
+			Level:       level,
+			Type:        "system_message",
+			Data:        data,
+			Title:       title,
+			Description: pgtype.Text{String: description, Valid: true},
+		}); err != nil {
+			h.logger.WithError(err).WithField("user_id", adminID.String()).Warn("Failed to notify admin of the integrity scan")
+		}
+	}
+}
+
 // RegisterAllHandlers registers all job handlers with the service
 func (h *Handlers) RegisterAllHandlers(service *Service) {
 	// Asset processing
 	service.RegisterHandler(JobTypeThumbnailGeneration, h.HandleThumbnailGeneration)
 	service.RegisterHandler(JobTypeMetadataExtraction, h.HandleMetadataExtraction)
 	service.RegisterHandler(JobTypeVideoTranscode, h.HandleVideoTranscode)
+	service.RegisterHandler(JobTypeThumbnailLayouts, h.HandleThumbnailLayouts)
 
 	// Machine learning
 	service.RegisterHandler(JobTypeFaceDetection, h.HandleFaceDetection)
 	service.RegisterHandler(JobTypeFaceRecognition, h.HandleFaceRecognition)
+	service.RegisterHandler(JobTypePersonThumbnails, h.HandlePersonThumbnails)
 	service.RegisterHandler(JobTypeSmartSearch, h.HandleSmartSearchIndex)
+	service.RegisterHandler(JobTypeObjectDetection, h.HandleObjectDetection)
 
 	// Library management
 	service.RegisterHandler(JobTypeLibraryScan, h.HandleLibraryScan)
+	service.RegisterHandler(JobTypeLibraryDeletion, h.HandleLibraryDeletion)
 	service.RegisterHandler(JobTypeDuplicateDetect, h.HandleDuplicateDetection)
+	service.RegisterHandler(JobTypeAutoStack, h.HandleAutoStack)
 
 	// Storage
 	service.RegisterHandler(JobTypeStorageMigration, h.HandleStorageMigration)
 
+	service.RegisterHandler(JobTypeIntegrityScan, h.HandleIntegrityScan)
+	service.RegisterHandler(JobTypeReindex, service.HandleReindex)
+
+	// Users
+	service.RegisterHandler(JobTypeUserDeletion, h.HandleUserDeletion)
+	service.RegisterHandler(JobTypeUserDeletionCheck, service.HandleUserDeletionCheck)
+	service.RegisterHandler(JobTypeQuotaUsageSync, service.HandleQuotaUsageSync)
+	service.RegisterHandler(JobTypeGeodataImport, h.HandleGeodataImport)
+
+	service.RegisterHandler(JobTypeWebhookDelivery, service.HandleWebhookDelivery)
+
 	h.logger.Info("All job handlers registered")
 }
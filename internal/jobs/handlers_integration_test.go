@@ -10,14 +10,19 @@ import (
 	"image"
 	"image/color"
 	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
+	"github.com/denysvitali/immich-go-backend/internal/ml"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
@@ -190,6 +195,12 @@ func TestIntegration_HandleThumbnailGeneration(t *testing.T) {
 		assert.True(t, recordedTypes[string(thumbType)],
 			"expected asset_file record for thumbnail type %s", thumbType)
 	}
+
+	// The small thumbnail also gives the asset its BlurHash placeholder.
+	updated, err := tdb.Queries.GetAssetByID(ctx, asset.ID)
+	require.NoError(t, err)
+	assert.True(t, updated.Blurhash.Valid, "expected a blurhash to be stored")
+	assert.Len(t, updated.Blurhash.String, 28)
 }
 
 // TestIntegration_HandleMetadataExtraction_PlainJPEG tests the full
@@ -357,7 +368,8 @@ func TestIntegration_HandleMetadataExtraction_AssetNotInDB(t *testing.T) {
 }
 
 // TestIntegration_HandleThumbnailGeneration_NonImageAsset verifies that the
-// handler skips thumbnail generation for non-image assets and returns no error.
+// handler skips thumbnail generation for assets that are neither images nor
+// videos and returns no error.
 func TestIntegration_HandleThumbnailGeneration_NonImageAsset(t *testing.T) {
 	testdb.SkipIfNoDocker(t)
 
@@ -384,7 +396,7 @@ func TestIntegration_HandleThumbnailGeneration_NonImageAsset(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	// Create a VIDEO asset (no file needs to be in storage since handler exits early).
+	// Create an OTHER asset (no file needs to be in storage since handler exits early).
 	assetID := uuid.New()
 	assetUUID := pgtype.UUID{}
 	require.NoError(t, assetUUID.Scan(assetID.String()))
@@ -396,12 +408,12 @@ func TestIntegration_HandleThumbnailGeneration_NonImageAsset(t *testing.T) {
 		DeviceAssetId:    "device-" + assetID.String(),
 		OwnerId:          userUUID,
 		DeviceId:         "test-device",
-		Type:             string(assets.AssetTypeVideo),
-		OriginalPath:     "uploads/" + userID.String() + "/video.mp4",
+		Type:             string(assets.AssetTypeOther),
+		OriginalPath:     "uploads/" + userID.String() + "/document.pdf",
 		FileCreatedAt:    nowPg,
 		FileModifiedAt:   nowPg,
 		LocalDateTime:    nowPg,
-		OriginalFileName: "video.mp4",
+		OriginalFileName: "document.pdf",
 		Checksum:         []byte("fakechecksum"),
 		IsFavorite:       false,
 		Visibility:       sqlc.AssetVisibilityEnumTimeline,
@@ -419,5 +431,273 @@ func TestIntegration_HandleThumbnailGeneration_NonImageAsset(t *testing.T) {
 	// No thumbnail files should have been created.
 	assetFiles, err := tdb.Queries.GetAssetFiles(ctx, asset.ID)
 	require.NoError(t, err)
-	assert.Empty(t, assetFiles, "no thumbnail records should exist for a video asset")
+	assert.Empty(t, assetFiles, "no thumbnail records should exist for an asset without a picture")
+}
+
+// TestIntegration_HandleThumbnailGeneration_Video verifies that the handler
+// generates thumbnails for a video from a frame extracted with ffmpeg, unless
+// thumbnail generation is disabled.
+func TestIntegration_HandleThumbnailGeneration_Video(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	if !ffmpeg.IsAvailable() {
+		t.Skip("ffmpeg/ffprobe not available in PATH, skipping test")
+	}
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	storageService := newLocalStorageService(t, tmpDir)
+
+	userUUID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:       userUUID,
+		Email:    "videothumb@example.com",
+		Name:     "Video Thumb User",
+		Password: "hashed-password-placeholder",
+	})
+	require.NoError(t, err)
+
+	videoPath := filepath.Join(t.TempDir(), "clip.mp4")
+	require.NoError(t, ffmpeg.GenerateTestVideo(videoPath, 320, 240, 2*time.Second))
+	videoData, err := os.ReadFile(videoPath)
+	require.NoError(t, err)
+	originalStoragePath := filepath.Join("uploads", userUUID.String(), "clip.mp4")
+	require.NoError(t, storageService.UploadBytes(ctx, originalStoragePath, videoData, "video/mp4"))
+
+	nowPg := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+		DeviceAssetId:    "device-clip",
+		OwnerId:          userUUID,
+		DeviceId:         "test-device",
+		Type:             string(assets.AssetTypeVideo),
+		OriginalPath:     originalStoragePath,
+		FileCreatedAt:    nowPg,
+		FileModifiedAt:   nowPg,
+		LocalDateTime:    nowPg,
+		OriginalFileName: "clip.mp4",
+		Checksum:         []byte("fakechecksum"),
+		Visibility:       sqlc.AssetVisibilityEnumTimeline,
+		Status:           sqlc.AssetsStatusEnumActive,
+	})
+	require.NoError(t, err)
+	task := newTestTask(t, JobTypeThumbnailGeneration, ThumbnailGenerationPayload{AssetID: asset.ID.String()})
+
+	disabled := &config.Config{}
+	require.NoError(t, NewHandlers(tdb.Queries, nil, nil, storageService, nil, disabled).HandleThumbnailGeneration(ctx, task))
+	assetFiles, err := tdb.Queries.GetAssetFiles(ctx, asset.ID)
+	require.NoError(t, err)
+	assert.Empty(t, assetFiles, "thumbnail generation is disabled")
+
+	require.NoError(t, NewHandlers(tdb.Queries, nil, nil, storageService, nil, nil).HandleThumbnailGeneration(ctx, task))
+	assetFiles, err = tdb.Queries.GetAssetFiles(ctx, asset.ID)
+	require.NoError(t, err)
+	assert.Len(t, assetFiles, 3)
+
+	thumbPath := assets.NewThumbnailGenerator().GetThumbnailPath(originalStoragePath, assets.ThumbnailTypeThumb)
+	reader, err := storageService.Derivatives().Download(ctx, thumbPath)
+	require.NoError(t, err)
+	defer reader.Close()
+	thumb, format, err := image.Decode(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 160, thumb.Bounds().Dx())
+}
+
+// TestIntegration_HandleUserDeletion verifies that a user marked for removal
+// is purged together with their files and audit rows, that a missing file
+// does not stop the job, and that external library originals are kept.
+func TestIntegration_HandleUserDeletion(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	storageService := newLocalStorageService(t, t.TempDir())
+
+	userID := uuid.New()
+	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:       userUUID,
+		Email:    "deleteme@example.com",
+		Name:     "Delete Me",
+		Password: "hashed-password-placeholder",
+	})
+	require.NoError(t, err)
+
+	nowPg := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	var paths []string
+	for i, name := range []string{"kept.jpg", "missing.jpg", "external.jpg"} {
+		path := filepath.Join("uploads", userID.String(), name)
+		if i != 1 {
+			require.NoError(t, storageService.UploadBytes(ctx, path, []byte("data"), "image/jpeg"))
+		}
+		paths = append(paths, path)
+		asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          userUUID,
+			DeviceId:         "test-device",
+			Type:             string(assets.AssetTypeImage),
+			OriginalPath:     path,
+			FileCreatedAt:    nowPg,
+			FileModifiedAt:   nowPg,
+			LocalDateTime:    nowPg,
+			OriginalFileName: name,
+			Checksum:         []byte(name),
+			Visibility:       sqlc.AssetVisibilityEnumTimeline,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		if name == "external.jpg" {
+			_, err = tdb.Pool.Exec(ctx, `UPDATE assets SET "isExternal" = true WHERE id = $1`, asset.ID)
+			require.NoError(t, err)
+		}
+	}
+
+	personThumbnail := filepath.Join("thumbs", userID.String(), "person.jpeg")
+	require.NoError(t, storageService.UploadBytes(ctx, personThumbnail, []byte("face"), "image/jpeg"))
+	_, err = tdb.Queries.CreatePerson(ctx, sqlc.CreatePersonParams{
+		OwnerId:       userUUID,
+		Name:          "Someone",
+		ThumbnailPath: personThumbnail,
+	})
+	require.NoError(t, err)
+
+	summary, err := tdb.Queries.GetUserDeletionSummary(ctx, userUUID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.AssetCount)
+
+	handlers := NewHandlers(tdb.Queries, nil, nil, storageService, nil, nil)
+	task := newTestTask(t, JobTypeUserDeletion, UserDeletionPayload{UserID: userID.String()})
+
+	// Not marked for removal yet: the job leaves the user alone.
+	require.NoError(t, handlers.HandleUserDeletion(ctx, task))
+	_, err = tdb.Queries.GetUserByID(ctx, userUUID)
+	require.NoError(t, err)
+
+	require.NoError(t, tdb.Queries.MarkUserForRemoval(ctx, userUUID))
+	require.NoError(t, handlers.HandleUserDeletion(ctx, task))
+
+	_, err = tdb.Queries.GetUserIncludingDeleted(ctx, userUUID)
+	assert.Error(t, err, "user row should be gone")
+	exists, err := storageService.AssetExists(ctx, paths[0])
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = storageService.AssetExists(ctx, personThumbnail)
+	require.NoError(t, err)
+	assert.False(t, exists, "person thumbnails are deleted")
+	exists, err = storageService.AssetExists(ctx, paths[2])
+	require.NoError(t, err)
+	assert.True(t, exists, "external library originals are left in place")
+
+	var auditRows int
+	require.NoError(t, tdb.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM assets_audit WHERE "ownerId" = $1`, userUUID).Scan(&auditRows))
+	assert.Zero(t, auditRows, "the user's asset audit rows are removed")
+
+	// Running again after completion is a no-op.
+	require.NoError(t, handlers.HandleUserDeletion(ctx, task))
+}
+
+// TestIntegration_HandleObjectDetection_ReplacesLabels runs object detection
+// twice against a fake ML service and checks that the second run replaces
+// the labels of the first instead of adding to them.
+func TestIntegration_HandleObjectDetection_ReplacesLabels(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	responses := []string{
+		`{"image-classification":[{"label":"Dog","score":0.9},{"label":"beach","score":0.6}]}`,
+		`{"image-classification":[{"label":"dog","score":0.95}]}`,
+	}
+	calls := 0
+	mlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(responses[calls]))
+		calls++
+	}))
+	t.Cleanup(mlServer.Close)
+
+	tmpDir := t.TempDir()
+	storageService := newLocalStorageService(t, tmpDir)
+
+	userUUID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:       userUUID,
+		Email:    "objects@example.com",
+		Name:     "Objects Test User",
+		Password: "hashed-password-placeholder",
+	})
+	require.NoError(t, err)
+
+	originalPath := filepath.Join("uploads", "objects", "photo.jpg")
+	require.NoError(t, storageService.UploadBytes(ctx, originalPath, createIntegrationTestJPEG(64, 64), "image/jpeg"))
+
+	nowPg := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+		DeviceAssetId:    "device-objects",
+		OwnerId:          userUUID,
+		DeviceId:         "test-device",
+		Type:             string(assets.AssetTypeImage),
+		OriginalPath:     originalPath,
+		FileCreatedAt:    nowPg,
+		FileModifiedAt:   nowPg,
+		LocalDateTime:    nowPg,
+		OriginalFileName: "photo.jpg",
+		Checksum:         []byte("objects-checksum"),
+		Visibility:       sqlc.AssetVisibilityEnumTimeline,
+		Status:           sqlc.AssetsStatusEnumActive,
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.Features.MachineLearningEnabled = true
+	cfg.Features.ObjectDetectionEnabled = true
+	cfg.MachineLearning.Enabled = true
+	cfg.MachineLearning.URL = mlServer.URL
+	cfg.MachineLearning.ObjectDetection = config.ObjectDetectionMLConfig{Enabled: true, MinScore: 0.3, SearchMinScore: 0.5}
+	mlClient := ml.NewClient(ml.Config{Enabled: true, URL: mlServer.URL, ObjectsEnabled: true})
+
+	handlers := NewHandlers(tdb.Queries, nil, nil, storageService, mlClient, cfg)
+	task := newTestTask(t, JobTypeObjectDetection, ObjectDetectionPayload{AssetID: uuid.UUID(asset.ID.Bytes).String()})
+
+	labels := func() map[string]float32 {
+		rows, err := tdb.Pool.Query(ctx, `SELECT label, score FROM asset_labels WHERE "assetId" = $1`, asset.ID)
+		require.NoError(t, err)
+		defer rows.Close()
+		got := map[string]float32{}
+		for rows.Next() {
+			var label string
+			var score float32
+			require.NoError(t, rows.Scan(&label, &score))
+			got[label] = score
+		}
+		require.NoError(t, rows.Err())
+		return got
+	}
+
+	require.NoError(t, handlers.HandleObjectDetection(ctx, task))
+	assert.Equal(t, map[string]float32{"dog": 0.9, "beach": 0.6}, labels())
+
+	require.NoError(t, handlers.HandleObjectDetection(ctx, task))
+	assert.Equal(t, map[string]float32{"dog": 0.95}, labels())
+
+	found, err := tdb.Queries.SearchAssetsFiltered(ctx, sqlc.SearchAssetsFilteredParams{
+		OwnerID:       userUUID,
+		Labels:        []string{"dog"},
+		LabelMinScore: 0.5,
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, asset.ID, found[0].ID)
+
+	found, err = tdb.Queries.SearchAssetsFiltered(ctx, sqlc.SearchAssetsFilteredParams{
+		OwnerID:       userUUID,
+		Labels:        []string{"dog", "beach"},
+		LabelMinScore: 0.5,
+		Limit:         10,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, found)
 }
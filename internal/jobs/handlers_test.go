@@ -76,6 +76,9 @@ func TestMLJobsSkipWhenDisabled(t *testing.T) {
 	smartTask := newTask(t, JobTypeSmartSearch, SmartSearchIndexPayload{AssetID: uuid.NewString()})
 	require.NoError(t, h.HandleSmartSearchIndex(t.Context(), smartTask))
 
+	objectTask := newTask(t, JobTypeObjectDetection, ObjectDetectionPayload{AssetID: uuid.NewString()})
+	require.NoError(t, h.HandleObjectDetection(t.Context(), objectTask))
+
 	recogTask := newTask(t, JobTypeFaceRecognition, FaceRecognitionPayload{FaceID: uuid.NewString()})
 	require.NoError(t, h.HandleFaceRecognition(t.Context(), recogTask))
 }
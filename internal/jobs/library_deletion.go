@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/libraries"
+)
+
+// libraryDeletionBatchSize is the number of assets handled per database
+// round.
+const libraryDeletionBatchSize = 500
+
+// LibraryDeletionPayload contains data for handling the assets of a deleted
+// library
+type LibraryDeletionPayload struct {
+	LibraryID   string                     `json:"library_id"`
+	Disposition libraries.AssetDisposition `json:"disposition"`
+}
+
+// EnqueueLibraryDeletion queues the disposition of a deleted library's
+// assets. The task ID is derived from the library, so while one is queued,
+// deleting the library again is a no-op.
+func (s *Service) EnqueueLibraryDeletion(ctx context.Context, libraryID uuid.UUID, disposition libraries.AssetDisposition) error {
+	err := s.EnqueueJob(ctx, JobTypeLibraryDeletion, LibraryDeletionPayload{
+		LibraryID:   libraryID.String(),
+		Disposition: disposition,
+	},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(fmt.Sprintf("%s:%s", JobTypeLibraryDeletion, libraryID)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(libraryDeletionTimeout),
+		asynq.Retention(0),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// HandleLibraryDeletion keeps, trashes or deletes the assets of a deleted
+// library, as chosen when it was deleted. Assets are handled in batches that
+// drop out of the next listing, so a retried job continues where it stopped.
+// Originals and sidecars of external assets are never deleted.
+func (h *Handlers) HandleLibraryDeletion(ctx context.Context, task *asynq.Task) error {
+	var payload LibraryDeletionPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+	libraryID, err := uuid.Parse(payload.LibraryID)
+	if err != nil {
+		return fmt.Errorf("invalid library ID %q: %w", payload.LibraryID, asynq.SkipRetry)
+	}
+	disposition, err := libraries.ParseAssetDisposition(string(payload.Disposition))
+	if err != nil {
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+	libraryUUID := pgtype.UUID{Bytes: libraryID, Valid: true}
+
+	library, err := h.db.GetLibraryIncludingDeleted(ctx, libraryUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get library: %w", err)
+	}
+	if !library.DeletedAt.Valid {
+		h.logger.WithField("library_id", payload.LibraryID).Info("Library is not deleted, skipping asset disposition")
+		return nil
+	}
+
+	var handled int64
+	switch disposition {
+	case libraries.AssetDispositionKeep:
+		handled, err = h.batchLibraryAssets(ctx, func() (int64, error) {
+			return h.db.DetachLibraryAssets(ctx, sqlc.DetachLibraryAssetsParams{
+				LibraryID: libraryUUID,
+				BatchSize: libraryDeletionBatchSize,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to detach assets: %w", err)
+		}
+		// What is left duplicates an upload of the owner.
+		purged, err := h.purgeLibraryAssets(ctx, library)
+		if err != nil {
+			return err
+		}
+		handled += purged
+	case libraries.AssetDispositionTrash:
+		handled, err = h.batchLibraryAssets(ctx, func() (int64, error) {
+			return h.db.TrashLibraryAssets(ctx, sqlc.TrashLibraryAssetsParams{
+				LibraryID: libraryUUID,
+				BatchSize: libraryDeletionBatchSize,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to trash assets: %w", err)
+		}
+	case libraries.AssetDispositionDelete:
+		handled, err = h.purgeLibraryAssets(ctx, library)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"library_id":  payload.LibraryID,
+		"disposition": disposition,
+		"assets":      handled,
+	}).Info("Library assets handled")
+	return nil
+}
+
+// batchLibraryAssets runs update until it affects no more assets and returns
+// how many it affected.
+func (h *Handlers) batchLibraryAssets(ctx context.Context, update func() (int64, error)) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		affected, err := update()
+		if err != nil {
+			return total, err
+		}
+		if affected == 0 {
+			return total, nil
+		}
+		total += affected
+	}
+}
+
+// purgeLibraryAssets removes the rows and derived files of the library's
+// assets. Originals and sidecars are only deleted for assets that are not
+// external.
+func (h *Handlers) purgeLibraryAssets(ctx context.Context, library sqlc.Library) (int64, error) {
+	var purged int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		batch, err := h.db.ListLibraryAssetsForPurge(ctx, sqlc.ListLibraryAssetsForPurgeParams{
+			LibraryID: library.ID,
+			BatchSize: libraryDeletionBatchSize,
+		})
+		if err != nil {
+			return purged, fmt.Errorf("failed to list assets: %w", err)
+		}
+		if len(batch) == 0 {
+			return purged, nil
+		}
+
+		ids := make([]pgtype.UUID, len(batch))
+		paths := make([]string, 0, len(batch))
+		derivedPaths := make([]string, 0, len(batch))
+		for i, asset := range batch {
+			ids[i] = asset.ID
+			if !asset.IsExternal {
+				paths = append(paths, asset.OriginalPath)
+				if asset.SidecarPath.Valid {
+					paths = append(paths, asset.SidecarPath.String)
+				}
+			}
+			if asset.EncodedVideoPath.Valid {
+				derivedPaths = append(derivedPaths, asset.EncodedVideoPath.String)
+			}
+		}
+		files, err := h.db.GetAssetFilesByAssetIDs(ctx, ids)
+		if err != nil {
+			return purged, fmt.Errorf("failed to list asset files: %w", err)
+		}
+		for _, file := range files {
+			derivedPaths = append(derivedPaths, file.Path)
+		}
+
+		for _, path := range paths {
+			if err := h.deleteStoredFile(ctx, h.storageService, path); err != nil {
+				return purged, err
+			}
+		}
+		for _, path := range derivedPaths {
+			if err := h.deleteStoredFile(ctx, h.storageService.Derivatives(), path); err != nil {
+				return purged, err
+			}
+		}
+
+		if err := h.db.DeleteStacksByPrimaryAssets(ctx, ids); err != nil {
+			return purged, fmt.Errorf("failed to delete stacks: %w", err)
+		}
+		if err := h.db.PurgeAssets(ctx, sqlc.PurgeAssetsParams{AssetIds: ids, OwnerID: library.OwnerId}); err != nil {
+			return purged, fmt.Errorf("failed to purge assets: %w", err)
+		}
+		purged += int64(len(batch))
+	}
+}
@@ -0,0 +1,154 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/libraries"
+)
+
+// createDeletionTestLibrary creates a library of the owner with an external
+// asset per name, backed by a file on disk, and returns the library and the
+// asset IDs.
+func createDeletionTestLibrary(t *testing.T, tdb *testdb.TestDB, ownerID uuid.UUID, names ...string) (pgtype.UUID, []pgtype.UUID) {
+	t.Helper()
+	ctx := context.Background()
+
+	library, err := tdb.Queries.CreateLibrary(ctx, sqlc.CreateLibraryParams{
+		OwnerId:     pgtype.UUID{Bytes: ownerID, Valid: true},
+		Name:        "Deleted",
+		ImportPaths: []string{t.TempDir()},
+	})
+	require.NoError(t, err)
+
+	var ids []pgtype.UUID
+	for _, name := range names {
+		path := filepath.Join(library.ImportPaths[0], name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0o644))
+		asset, err := tdb.Queries.CreateLibraryAsset(ctx, sqlc.CreateLibraryAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          library.OwnerId,
+			LibraryId:        library.ID,
+			DeviceId:         "library-scanner",
+			Type:             "IMAGE",
+			OriginalPath:     path,
+			OriginalFileName: name,
+			Checksum:         []byte("checksum-" + name),
+			Visibility:       sqlc.AssetVisibilityEnumTimeline,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		ids = append(ids, asset.ID)
+	}
+	return library.ID, ids
+}
+
+func runLibraryDeletion(t *testing.T, handlers *Handlers, libraryID pgtype.UUID, disposition libraries.AssetDisposition) {
+	t.Helper()
+	task := newTestTask(t, JobTypeLibraryDeletion, LibraryDeletionPayload{
+		LibraryID:   uuid.UUID(libraryID.Bytes).String(),
+		Disposition: disposition,
+	})
+	require.NoError(t, handlers.HandleLibraryDeletion(context.Background(), task))
+}
+
+func TestIntegration_HandleLibraryDeletion_Keep(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	handlers := NewHandlers(tdb.Queries, nil, nil, newLocalStorageService(t, t.TempDir()), nil, nil)
+
+	userID := tdb.CreateTestUser(t, "library-keep@example.com")
+	libraryID, assetIDs := createDeletionTestLibrary(t, tdb, userID, "kept.jpg", "uploaded.jpg")
+	upload := tdb.CreateTestAssetWithChecksum(t, userID, "upload", []byte("checksum-uploaded.jpg"))
+
+	// A library that is not deleted is left alone.
+	runLibraryDeletion(t, handlers, libraryID, libraries.AssetDispositionKeep)
+	asset, err := tdb.Queries.GetAsset(ctx, assetIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, libraryID, asset.LibraryId)
+
+	require.NoError(t, tdb.Queries.DeleteLibrary(ctx, libraryID))
+	runLibraryDeletion(t, handlers, libraryID, libraries.AssetDispositionKeep)
+
+	asset, err = tdb.Queries.GetAsset(ctx, assetIDs[0])
+	require.NoError(t, err)
+	assert.False(t, asset.LibraryId.Valid, "the asset becomes an upload")
+	assert.True(t, asset.IsExternal, "the file stays where it is")
+	_, err = os.Stat(asset.OriginalPath)
+	assert.NoError(t, err)
+
+	// The duplicate of an upload is dropped, its file is not.
+	_, err = tdb.Queries.GetAsset(ctx, assetIDs[1])
+	assert.Error(t, err)
+	_, err = tdb.Queries.GetAsset(ctx, pgtype.UUID{Bytes: upload, Valid: true})
+	assert.NoError(t, err)
+}
+
+func TestIntegration_HandleLibraryDeletion_Trash(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	handlers := NewHandlers(tdb.Queries, nil, nil, newLocalStorageService(t, t.TempDir()), nil, nil)
+
+	userID := tdb.CreateTestUser(t, "library-trash@example.com")
+	libraryID, assetIDs := createDeletionTestLibrary(t, tdb, userID, "a.jpg", "b.jpg")
+	require.NoError(t, tdb.Queries.DeleteLibrary(ctx, libraryID))
+	runLibraryDeletion(t, handlers, libraryID, libraries.AssetDispositionTrash)
+
+	for _, assetID := range assetIDs {
+		asset, err := tdb.Queries.GetAsset(ctx, assetID)
+		require.NoError(t, err)
+		assert.Equal(t, sqlc.AssetsStatusEnumTrashed, asset.Status)
+	}
+}
+
+func TestIntegration_HandleLibraryDeletion_Delete(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	storageService := newLocalStorageService(t, t.TempDir())
+	handlers := NewHandlers(tdb.Queries, nil, nil, storageService, nil, nil)
+
+	userID := tdb.CreateTestUser(t, "library-delete@example.com")
+	libraryID, assetIDs := createDeletionTestLibrary(t, tdb, userID, "a.jpg")
+	thumbnail := "thumbs/" + assetIDs[0].String() + ".jpg"
+	require.NoError(t, storageService.Derivatives().UploadBytes(ctx, thumbnail, []byte("thumb"), "image/jpeg"))
+	_, err := tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+		AssetId: assetIDs[0],
+		Type:    "thumb",
+		Path:    thumbnail,
+	})
+	require.NoError(t, err)
+	asset, err := tdb.Queries.GetAsset(ctx, assetIDs[0])
+	require.NoError(t, err)
+
+	require.NoError(t, tdb.Queries.DeleteLibrary(ctx, libraryID))
+	runLibraryDeletion(t, handlers, libraryID, libraries.AssetDispositionDelete)
+
+	_, err = tdb.Queries.GetAsset(ctx, assetIDs[0])
+	assert.Error(t, err, "asset row should be gone")
+	exists, err := storageService.Derivatives().AssetExists(ctx, thumbnail)
+	require.NoError(t, err)
+	assert.False(t, exists, "derived files are deleted")
+	_, err = os.Stat(asset.OriginalPath)
+	assert.NoError(t, err, "external originals are left in place")
+
+	// Running again after completion is a no-op.
+	runLibraryDeletion(t, handlers, libraryID, libraries.AssetDispositionDelete)
+}
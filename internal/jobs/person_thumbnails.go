@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/people"
+)
+
+// personThumbnailsSchedule is how often people without an up to date
+// thumbnail get one.
+const personThumbnailsSchedule = "@every 15m"
+
+// SchedulePersonThumbnails runs person thumbnail generation periodically once
+// the service is started.
+func (s *Service) SchedulePersonThumbnails() error {
+	return s.SchedulePeriodicJob(personThumbnailsSchedule, JobTypePersonThumbnails, struct{}{},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypePersonThumbnails)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+		asynq.Retention(0),
+	)
+}
+
+// HandlePersonThumbnails crops a thumbnail for every person that has none or
+// whose feature face changed since theirs was cropped. People without a
+// feature face get their best face as one. Running it again does nothing
+// until a person's faces change.
+func (h *Handlers) HandlePersonThumbnails(ctx context.Context, _ *asynq.Task) error {
+	if h.storageService == nil {
+		return fmt.Errorf("person thumbnails need a storage service: %w", asynq.SkipRetry)
+	}
+
+	persons, err := h.db.ListPeopleNeedingThumbnails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list people needing thumbnails: %w", err)
+	}
+
+	generated, failed := 0, 0
+	for _, person := range persons {
+		if err := h.generatePersonThumbnail(ctx, person); err != nil {
+			// One unreadable asset should not hold back everyone else;
+			// the person is picked up again on the next run.
+			failed++
+			h.logger.WithError(err).WithField("person_id", person.ID.String()).
+				Warn("Failed to generate person thumbnail")
+			continue
+		}
+		generated++
+	}
+
+	if generated > 0 || failed > 0 {
+		h.logger.WithFields(logrus.Fields{
+			"generated": generated,
+			"failed":    failed,
+		}).Info("Generated person thumbnails")
+	}
+	return nil
+}
+
+func (h *Handlers) generatePersonThumbnail(ctx context.Context, person sqlc.Person) error {
+	face, err := h.personFeatureFace(ctx, person)
+	if err != nil {
+		return err
+	}
+
+	thumbnailPath, err := people.GenerateThumbnail(ctx, h.db, h.storageService, person, face)
+	if err != nil {
+		return err
+	}
+	if _, err := h.db.UpdatePerson(ctx, sqlc.UpdatePersonParams{
+		ID:            person.ID,
+		FaceAssetID:   face.ID,
+		ThumbnailPath: pgtype.Text{String: thumbnailPath, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to update person: %w", err)
+	}
+
+	if person.ThumbnailPath != "" && person.ThumbnailPath != thumbnailPath {
+		if err := h.deleteStoredFile(ctx, h.storageService.Derivatives(), person.ThumbnailPath); err != nil {
+			h.logger.WithError(err).WithField("path", person.ThumbnailPath).Warn("Failed to delete old person thumbnail")
+		}
+	}
+	return nil
+}
+
+// personFeatureFace returns the person's feature face, or their best face
+// when they have none or it no longer shows them.
+func (h *Handlers) personFeatureFace(ctx context.Context, person sqlc.Person) (sqlc.AssetFace, error) {
+	if person.FaceAssetId.Valid {
+		face, err := h.db.GetFace(ctx, person.FaceAssetId)
+		if err == nil && face.PersonId == person.ID {
+			return face, nil
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return sqlc.AssetFace{}, fmt.Errorf("failed to get feature face: %w", err)
+		}
+	}
+
+	faces, err := h.db.GetFacesByPerson(ctx, person.ID)
+	if err != nil {
+		return sqlc.AssetFace{}, fmt.Errorf("failed to list faces: %w", err)
+	}
+	face, ok := people.BestFace(faces)
+	if !ok {
+		return sqlc.AssetFace{}, errors.New("person has no faces")
+	}
+	return face, nil
+}
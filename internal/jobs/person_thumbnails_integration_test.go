@@ -0,0 +1,87 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+// TestIntegration_HandlePersonThumbnails generates a thumbnail from the best
+// face, checks a second run changes nothing, then re-crops after the feature
+// face is changed.
+func TestIntegration_HandlePersonThumbnails(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	storageService := newLocalStorageService(t, t.TempDir())
+
+	userID := tdb.CreateTestUser(t, "person-thumbs@example.com")
+	asset, err := tdb.Queries.GetAsset(ctx, pgtype.UUID{Bytes: tdb.CreateTestAsset(t, userID, "group"), Valid: true})
+	require.NoError(t, err)
+	originalPath := filepath.Join("uploads", userID.String(), "group.jpg")
+	_, err = tdb.Pool.Exec(ctx, `UPDATE assets SET "originalPath" = $2 WHERE id = $1`, asset.ID, originalPath)
+	require.NoError(t, err)
+	require.NoError(t, storageService.UploadBytes(ctx, originalPath, createIntegrationTestJPEG(400, 300), "image/jpeg"))
+
+	person, err := tdb.Queries.CreatePerson(ctx, sqlc.CreatePersonParams{
+		OwnerId: pgtype.UUID{Bytes: userID, Valid: true},
+		Name:    "Ada",
+	})
+	require.NoError(t, err)
+	createFace := func(x int32, score float32) sqlc.AssetFace {
+		face, err := tdb.Queries.CreateAssetFace(ctx, sqlc.CreateAssetFaceParams{
+			AssetId:       asset.ID,
+			PersonId:      person.ID,
+			ImageWidth:    400,
+			ImageHeight:   300,
+			BoundingBoxX1: x,
+			BoundingBoxY1: 100,
+			BoundingBoxX2: x + 80,
+			BoundingBoxY2: 180,
+			Score:         score,
+		})
+		require.NoError(t, err)
+		return face
+	}
+	weak := createFace(20, 0.7)
+	strong := createFace(200, 0.95)
+
+	handlers := NewHandlers(tdb.Queries, nil, nil, storageService, nil, nil)
+	task := newTestTask(t, JobTypePersonThumbnails, struct{}{})
+	require.NoError(t, handlers.HandlePersonThumbnails(ctx, task))
+
+	first, err := tdb.Queries.GetPerson(ctx, person.ID)
+	require.NoError(t, err)
+	assert.Equal(t, strong.ID, first.FaceAssetId, "the most confident face is the default")
+	require.NotEmpty(t, first.ThumbnailPath)
+	exists, err := storageService.Derivatives().AssetExists(ctx, first.ThumbnailPath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	pending, err := tdb.Queries.ListPeopleNeedingThumbnails(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "a second run has nothing to do")
+
+	_, err = tdb.Queries.UpdatePerson(ctx, sqlc.UpdatePersonParams{ID: person.ID, FaceAssetID: weak.ID})
+	require.NoError(t, err)
+	require.NoError(t, handlers.HandlePersonThumbnails(ctx, task))
+
+	second, err := tdb.Queries.GetPerson(ctx, person.ID)
+	require.NoError(t, err)
+	assert.Equal(t, weak.ID, second.FaceAssetId)
+	assert.NotEqual(t, first.ThumbnailPath, second.ThumbnailPath)
+	exists, err = storageService.Derivatives().AssetExists(ctx, first.ThumbnailPath)
+	require.NoError(t, err)
+	assert.False(t, exists, "the old thumbnail is deleted")
+}
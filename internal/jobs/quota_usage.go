@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+const (
+	// quotaUsageSyncSchedule is how often the stored quota usage of every
+	// user is checked against their assets.
+	quotaUsageSyncSchedule = "@daily"
+	// quotaUsageSyncKey is the system metadata key of the latest report.
+	quotaUsageSyncKey = "quota-usage-sync"
+)
+
+// ErrNoQuotaUsageSync is returned when quota usage was never synced.
+var ErrNoQuotaUsageSync = errors.New("quota usage was never synced")
+
+// QuotaUsageSync reports a run of the quota usage sync.
+type QuotaUsageSync struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Users      int       `json:"users"`
+	// Corrections has the users whose stored usage was wrong.
+	Corrections []QuotaUsageCorrection `json:"corrections"`
+	// Skipped counts users whose usage changed while they were checked;
+	// the next run checks them again.
+	Skipped int `json:"skipped"`
+}
+
+// QuotaUsageCorrection is a user whose stored quota usage was corrected.
+type QuotaUsageCorrection struct {
+	UserID uuid.UUID `json:"user_id"`
+	Stored int64     `json:"stored"`
+	Actual int64     `json:"actual"`
+}
+
+// ScheduleQuotaUsageSync runs the quota usage sync periodically once the
+// service is started.
+func (s *Service) ScheduleQuotaUsageSync() error {
+	return s.SchedulePeriodicJob(quotaUsageSyncSchedule, JobTypeQuotaUsageSync, struct{}{}, s.quotaUsageSyncOptions()...)
+}
+
+// EnqueueQuotaUsageSync queues a quota usage sync. While one is queued or
+// running, further runs are not queued.
+func (s *Service) EnqueueQuotaUsageSync(ctx context.Context) error {
+	err := s.EnqueueJob(ctx, JobTypeQuotaUsageSync, struct{}{}, s.quotaUsageSyncOptions()...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) quotaUsageSyncOptions() []asynq.Option {
+	return []asynq.Option{
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeQuotaUsageSync)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+		asynq.Retention(0),
+	}
+}
+
+// HandleQuotaUsageSync sets the stored quota usage of every user to the size
+// of their assets, correcting what failed cleanups and crashes left behind,
+// and saves which users it corrected.
+func (s *Service) HandleQuotaUsageSync(ctx context.Context, _ *asynq.Task) error {
+	if s.db == nil {
+		return fmt.Errorf("quota usage sync needs a database: %w", asynq.SkipRetry)
+	}
+
+	report := QuotaUsageSync{StartedAt: time.Now(), Corrections: []QuotaUsageCorrection{}}
+	users, err := s.db.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+	for _, user := range users {
+		usage, err := s.db.GetUserQuotaUsage(ctx, user.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to compute quota usage: %w", err)
+		}
+		report.Users++
+		if usage.Stored == usage.Actual {
+			continue
+		}
+
+		corrected, err := s.db.CorrectUserQuotaUsage(ctx, sqlc.CorrectUserQuotaUsageParams{
+			Actual: usage.Actual,
+			ID:     user.ID,
+			Stored: usage.Stored,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to correct quota usage: %w", err)
+		}
+		if corrected == 0 {
+			report.Skipped++
+			continue
+		}
+		report.Corrections = append(report.Corrections, QuotaUsageCorrection{
+			UserID: uuid.UUID(user.ID.Bytes),
+			Stored: usage.Stored,
+			Actual: usage.Actual,
+		})
+		s.logger.WithFields(logrus.Fields{
+			"user_id": user.ID.String(),
+			"stored":  usage.Stored,
+			"actual":  usage.Actual,
+		}).Warn("Corrected quota usage")
+	}
+	report.FinishedAt = time.Now()
+
+	if err := s.saveQuotaUsageSync(ctx, &report); err != nil {
+		return err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"users":     report.Users,
+		"corrected": len(report.Corrections),
+		"skipped":   report.Skipped,
+	}).Info("Quota usage synced")
+	return nil
+}
+
+// GetQuotaUsageSync returns the report of the latest quota usage sync.
+func (s *Service) GetQuotaUsageSync(ctx context.Context) (*QuotaUsageSync, error) {
+	row, err := s.db.GetSystemMetadata(ctx, quotaUsageSyncKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoQuotaUsageSync
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quota usage sync: %w", err)
+	}
+	var report QuotaUsageSync
+	if err := json.Unmarshal(row.Value, &report); err != nil {
+		return nil, fmt.Errorf("failed to decode quota usage sync: %w", err)
+	}
+	return &report, nil
+}
+
+func (s *Service) saveQuotaUsageSync(ctx context.Context, report *QuotaUsageSync) error {
+	value, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota usage sync: %w", err)
+	}
+	if _, err := s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   quotaUsageSyncKey,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to save quota usage sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+func TestIntegration_HandleQuotaUsageSync(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	svc := newTestService(t, tdb)
+
+	driftedID := tdb.CreateTestUser(t, "quota-drifted@example.com")
+	exactID := tdb.CreateTestUser(t, "quota-exact@example.com")
+	drifted := pgtype.UUID{Bytes: driftedID, Valid: true}
+	exact := pgtype.UUID{Bytes: exactID, Valid: true}
+
+	withSize := func(ownerID pgtype.UUID, deviceAssetID string, size int64) pgtype.UUID {
+		t.Helper()
+		assetID := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID.Bytes, deviceAssetID), Valid: true}
+		_, err := tdb.Queries.CreateOrUpdateExif(ctx, sqlc.CreateOrUpdateExifParams{
+			AssetId:        assetID,
+			FileSizeInByte: pgtype.Int8{Int64: size, Valid: true},
+		})
+		require.NoError(t, err)
+		return assetID
+	}
+	withSize(drifted, "quota-kept", 1000)
+	trashed := withSize(drifted, "quota-trashed", 500)
+	require.NoError(t, tdb.Queries.MoveAssetsToTrash(ctx, sqlc.MoveAssetsToTrashParams{
+		Column1: []pgtype.UUID{trashed},
+		OwnerId: drifted,
+	}))
+	withSize(exact, "quota-exact", 200)
+
+	// The drifted user's usage still counts the trashed asset and more
+	require.NoError(t, tdb.Queries.AdjustUserQuotaUsage(ctx, sqlc.AdjustUserQuotaUsageParams{Delta: 2500, ID: drifted}))
+	require.NoError(t, tdb.Queries.AdjustUserQuotaUsage(ctx, sqlc.AdjustUserQuotaUsageParams{Delta: 200, ID: exact}))
+
+	_, err := svc.GetQuotaUsageSync(ctx)
+	assert.ErrorIs(t, err, ErrNoQuotaUsageSync)
+
+	require.NoError(t, svc.HandleQuotaUsageSync(ctx, nil))
+
+	user, err := tdb.Queries.GetUser(ctx, drifted)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), user.QuotaUsageInBytes)
+	user, err = tdb.Queries.GetUser(ctx, exact)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), user.QuotaUsageInBytes)
+
+	report, err := svc.GetQuotaUsageSync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Users)
+	assert.Zero(t, report.Skipped)
+	require.Len(t, report.Corrections, 1)
+	assert.Equal(t, driftedID, report.Corrections[0].UserID)
+	assert.Equal(t, int64(2500), report.Corrections[0].Stored)
+	assert.Equal(t, int64(1000), report.Corrections[0].Actual)
+
+	// A correction based on a usage read before an adjustment is not applied
+	corrected, err := tdb.Queries.CorrectUserQuotaUsage(ctx, sqlc.CorrectUserQuotaUsageParams{
+		Actual: 0,
+		ID:     drifted,
+		Stored: 2500,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, corrected)
+}
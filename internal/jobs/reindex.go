@@ -0,0 +1,400 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// JobTypeReindex queues the per-asset jobs of a re-index run.
+const JobTypeReindex JobType = "reindex"
+
+// ReindexQueue holds the per-asset jobs of re-index runs, apart from the
+// priority queues, so a run can be paused without stopping new uploads from
+// being processed.
+const ReindexQueue = "reindex"
+
+const (
+	// reindexRunKey is the system metadata key of the latest re-index run.
+	reindexRunKey = "reindex-run"
+
+	// reindexBatchSize is the number of assets queued per database round
+	// trip. Progress is saved after every batch.
+	reindexBatchSize = 500
+
+	// reindexTimeout bounds a single run of the queuing job. The job resumes
+	// after the last saved batch when it is retried.
+	reindexTimeout = 6 * time.Hour
+)
+
+// ReindexStep is a processing step a re-index run repeats for each asset.
+type ReindexStep string
+
+const (
+	ReindexStepMetadata   ReindexStep = "metadata"
+	ReindexStepThumbnails ReindexStep = "thumbnails"
+	// ReindexStepGeocode resolves the location again; this happens as part
+	// of the metadata extraction.
+	ReindexStepGeocode    ReindexStep = "geocode"
+	ReindexStepFaces      ReindexStep = "faces"
+	ReindexStepEmbeddings ReindexStep = "embeddings"
+)
+
+// reindexStepJobs maps each step to the job that performs it.
+var reindexStepJobs = map[ReindexStep]JobType{
+	ReindexStepMetadata:   JobTypeMetadataExtraction,
+	ReindexStepThumbnails: JobTypeThumbnailGeneration,
+	ReindexStepGeocode:    JobTypeMetadataExtraction,
+	ReindexStepFaces:      JobTypeFaceDetection,
+	ReindexStepEmbeddings: JobTypeSmartSearch,
+}
+
+var (
+	// ErrInvalidReindexStep is returned for an unknown re-index step.
+	ErrInvalidReindexStep = errors.New("invalid reindex step")
+	// ErrReindexInProgress is returned when a re-index run is started while
+	// the previous one still has jobs to queue or process.
+	ErrReindexInProgress = errors.New("a reindex is already in progress")
+	// ErrNoReindexRun is returned when no re-index run was ever started.
+	ErrNoReindexRun = errors.New("no reindex run found")
+)
+
+// reindexJobTypes returns the jobs that perform steps, in step order and
+// without duplicates.
+func reindexJobTypes(steps []ReindexStep) ([]JobType, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: no steps given", ErrInvalidReindexStep)
+	}
+	var jobTypes []JobType
+	for _, step := range steps {
+		jobType, ok := reindexStepJobs[ReindexStep(strings.ToLower(string(step)))]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidReindexStep, step)
+		}
+		if !slices.Contains(jobTypes, jobType) {
+			jobTypes = append(jobTypes, jobType)
+		}
+	}
+	return jobTypes, nil
+}
+
+// reindexAssetPayload returns the payload of the job of the given type for
+// one asset.
+func reindexAssetPayload(jobType JobType, assetID string) any {
+	switch jobType {
+	case JobTypeThumbnailGeneration:
+		return ThumbnailGenerationPayload{AssetID: assetID}
+	case JobTypeFaceDetection:
+		return FaceDetectionPayload{AssetID: assetID}
+	case JobTypeSmartSearch:
+		return SmartSearchIndexPayload{AssetID: assetID}
+	default:
+		return MetadataExtractionPayload{AssetID: assetID}
+	}
+}
+
+// ReindexFilter narrows a re-index run. Zero fields do not filter.
+type ReindexFilter struct {
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	LibraryID   *uuid.UUID `json:"library_id,omitempty"`
+	TakenAfter  *time.Time `json:"taken_after,omitempty"`
+	TakenBefore *time.Time `json:"taken_before,omitempty"`
+}
+
+func (f ReindexFilter) countParams() sqlc.CountReindexAssetsParams {
+	params := sqlc.CountReindexAssetsParams{}
+	if f.UserID != nil {
+		params.OwnerID = pgtype.UUID{Bytes: *f.UserID, Valid: true}
+	}
+	if f.LibraryID != nil {
+		params.LibraryID = pgtype.UUID{Bytes: *f.LibraryID, Valid: true}
+	}
+	if f.TakenAfter != nil {
+		params.TakenAfter = pgtype.Timestamptz{Time: *f.TakenAfter, Valid: true}
+	}
+	if f.TakenBefore != nil {
+		params.TakenBefore = pgtype.Timestamptz{Time: *f.TakenBefore, Valid: true}
+	}
+	return params
+}
+
+// ReindexRun is the state of a re-index run, kept in the system metadata so
+// progress survives restarts.
+type ReindexRun struct {
+	ID              string        `json:"id"`
+	Steps           []ReindexStep `json:"steps"`
+	Filter          ReindexFilter `json:"filter"`
+	EstimatedAssets int64         `json:"estimated_assets"`
+	EstimatedJobs   int64         `json:"estimated_jobs"`
+	EnqueuedJobs    int64         `json:"enqueued_jobs"`
+	// Cursor is the ID of the last asset whose jobs are queued.
+	Cursor     *uuid.UUID `json:"cursor,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	EnqueuedAt *time.Time `json:"enqueued_at,omitempty"`
+}
+
+// ReindexPayload contains data for the job that queues a re-index run
+type ReindexPayload struct {
+	RunID string `json:"run_id"`
+}
+
+// ReindexStatus is a re-index run with the state of its queued jobs.
+type ReindexStatus struct {
+	ReindexRun
+	Pending   int64
+	Active    int64
+	Completed int64
+	Failed    int64
+	Paused    bool
+}
+
+// EnqueueReindex starts a re-index run of the given steps over the assets
+// matching filter. The per-asset jobs are queued in the background by a
+// single job, so the call returns as soon as the work is estimated.
+func (s *Service) EnqueueReindex(ctx context.Context, steps []ReindexStep, filter ReindexFilter) (*ReindexRun, error) {
+	jobTypes, err := reindexJobTypes(steps)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.GetReindexStatus(ctx)
+	if err != nil && !errors.Is(err, ErrNoReindexRun) {
+		return nil, err
+	}
+	if status != nil && (status.EnqueuedAt == nil && s.reindexQueuing() || status.Pending+status.Active > 0) {
+		return nil, ErrReindexInProgress
+	}
+	if s.inspector != nil {
+		// A failed queuing job keeps its task ID reserved, and failures of
+		// the previous run would be counted against this one.
+		err := s.inspector.DeleteTask(s.getQueueByPriority(PriorityLow), string(JobTypeReindex))
+		if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) && !errors.Is(err, asynq.ErrQueueNotFound) {
+			s.logger.WithError(err).Warn("Failed to delete the failed job of the previous reindex")
+		}
+		if _, err := s.inspector.DeleteAllArchivedTasks(ReindexQueue); err != nil && !errors.Is(err, asynq.ErrQueueNotFound) {
+			s.logger.WithError(err).Warn("Failed to clear failed jobs of the previous reindex")
+		}
+	}
+
+	assets, err := s.db.CountReindexAssets(ctx, filter.countParams())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count assets to reindex: %w", err)
+	}
+
+	normalized := make([]ReindexStep, len(steps))
+	for i, step := range steps {
+		normalized[i] = ReindexStep(strings.ToLower(string(step)))
+	}
+	run := &ReindexRun{
+		ID:              uuid.NewString(),
+		Steps:           normalized,
+		Filter:          filter,
+		EstimatedAssets: assets,
+		EstimatedJobs:   assets * int64(len(jobTypes)),
+		StartedAt:       time.Now().UTC(),
+	}
+	if err := s.saveReindexRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	err = s.EnqueueJob(ctx, JobTypeReindex, ReindexPayload{RunID: run.ID},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeReindex)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(reindexTimeout),
+		asynq.Retention(0),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil, ErrReindexInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":           run.ID,
+		"steps":            run.Steps,
+		"estimated_assets": run.EstimatedAssets,
+		"estimated_jobs":   run.EstimatedJobs,
+	}).Info("Reindex started")
+
+	return run, nil
+}
+
+// reindexQueuing reports whether the job queuing a re-index run is still
+// queued, running or waiting for a retry. Without an inspector it is
+// assumed to be.
+func (s *Service) reindexQueuing() bool {
+	if s.inspector == nil {
+		return true
+	}
+	info, err := s.inspector.GetTaskInfo(s.getQueueByPriority(PriorityLow), string(JobTypeReindex))
+	return err == nil && info.State != asynq.TaskStateArchived
+}
+
+// HandleReindex queues the per-asset jobs of a re-index run in batches,
+// continuing after the last batch saved by a previous attempt.
+func (s *Service) HandleReindex(ctx context.Context, task *asynq.Task) error {
+	var payload ReindexPayload
+	if err := unmarshalTypedPayload(task, &payload); err != nil {
+		return err
+	}
+
+	run, err := s.loadReindexRun(ctx)
+	if err != nil {
+		return err
+	}
+	if run.ID != payload.RunID {
+		s.logger.WithField("run_id", payload.RunID).Warn("Skipping superseded reindex run")
+		return nil
+	}
+	jobTypes, err := reindexJobTypes(run.Steps)
+	if err != nil {
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	params := run.Filter.countParams()
+	for run.EnqueuedAt == nil {
+		batch := sqlc.GetReindexAssetIDsParams{
+			OwnerID:     params.OwnerID,
+			LibraryID:   params.LibraryID,
+			TakenAfter:  params.TakenAfter,
+			TakenBefore: params.TakenBefore,
+			Limit:       reindexBatchSize,
+		}
+		if run.Cursor != nil {
+			batch.AfterID = pgtype.UUID{Bytes: *run.Cursor, Valid: true}
+		}
+		ids, err := s.db.GetReindexAssetIDs(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to list assets to reindex: %w", err)
+		}
+
+		for _, id := range ids {
+			assetID := uuid.UUID(id.Bytes).String()
+			for _, jobType := range jobTypes {
+				if err := s.enqueueReindexJob(ctx, jobType, assetID); err != nil {
+					return err
+				}
+				run.EnqueuedJobs++
+			}
+		}
+
+		if len(ids) > 0 {
+			last := uuid.UUID(ids[len(ids)-1].Bytes)
+			run.Cursor = &last
+		}
+		if len(ids) < reindexBatchSize {
+			now := time.Now().UTC()
+			run.EnqueuedAt = &now
+		}
+		if err := s.saveReindexRun(ctx, run); err != nil {
+			return err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":        run.ID,
+		"enqueued_jobs": run.EnqueuedJobs,
+	}).Info("Reindex jobs queued")
+
+	return nil
+}
+
+// enqueueReindexJob queues one per-asset job. Unlike EnqueueJob it does not
+// log every job, as a run may queue millions.
+func (s *Service) enqueueReindexJob(ctx context.Context, jobType JobType, assetID string) error {
+	data, err := json.Marshal(reindexAssetPayload(jobType, assetID))
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	_, err = s.client.EnqueueContext(ctx, asynq.NewTask(string(jobType), data), s.withRetention([]asynq.Option{
+		asynq.Queue(ReindexQueue),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+	})...)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s job: %w", jobType, err)
+	}
+	return nil
+}
+
+// GetReindexStatus returns the latest re-index run and the state of its
+// jobs, or ErrNoReindexRun.
+func (s *Service) GetReindexStatus(ctx context.Context) (*ReindexStatus, error) {
+	run, err := s.loadReindexRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ReindexStatus{ReindexRun: *run}
+	if s.inspector == nil {
+		return status, nil
+	}
+	info, err := s.inspector.GetQueueInfo(ReindexQueue)
+	if errors.Is(err, asynq.ErrQueueNotFound) {
+		status.Completed = run.EnqueuedJobs
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect reindex queue: %w", err)
+	}
+	status.Pending = int64(info.Pending + info.Scheduled + info.Retry)
+	status.Active = int64(info.Active)
+	status.Failed = int64(info.Archived)
+	status.Completed = max(run.EnqueuedJobs-status.Pending-status.Active-status.Failed, 0)
+	status.Paused = info.Paused
+	return status, nil
+}
+
+// PauseReindex stops processing of re-index jobs. Jobs already running
+// finish, and queuing continues.
+func (s *Service) PauseReindex(ctx context.Context) error {
+	return s.PauseQueue(ctx, ReindexQueue)
+}
+
+// ResumeReindex resumes processing of re-index jobs.
+func (s *Service) ResumeReindex(ctx context.Context) error {
+	return s.ResumeQueue(ctx, ReindexQueue)
+}
+
+func (s *Service) loadReindexRun(ctx context.Context) (*ReindexRun, error) {
+	row, err := s.db.GetSystemMetadata(ctx, reindexRunKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoReindexRun
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reindex run: %w", err)
+	}
+	var run ReindexRun
+	if err := json.Unmarshal(row.Value, &run); err != nil {
+		return nil, fmt.Errorf("failed to decode reindex run: %w", err)
+	}
+	return &run, nil
+}
+
+func (s *Service) saveReindexRun(ctx context.Context, run *ReindexRun) error {
+	value, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode reindex run: %w", err)
+	}
+	if _, err := s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   reindexRunKey,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to save reindex run: %w", err)
+	}
+	return nil
+}
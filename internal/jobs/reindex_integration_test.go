@@ -0,0 +1,67 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+func TestIntegration_Reindex(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	svc := newTestService(t, tdb)
+	queue := svc.client.(*fakeEnqueuer)
+
+	userID := tdb.CreateTestUser(t, "reindex@example.com")
+	otherID := tdb.CreateTestUser(t, "reindex-other@example.com")
+	assetIDs := map[string]bool{}
+	for range 3 {
+		assetIDs[tdb.CreateTestAsset(t, userID, uuid.NewString()).String()] = true
+	}
+	tdb.CreateTestAsset(t, otherID, uuid.NewString())
+
+	run, err := svc.EnqueueReindex(ctx, []ReindexStep{ReindexStepThumbnails, ReindexStepFaces}, ReindexFilter{UserID: &userID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), run.EstimatedAssets)
+	assert.Equal(t, int64(6), run.EstimatedJobs)
+	require.Len(t, queue.tasks, 1)
+	assert.Equal(t, string(JobTypeReindex), queue.tasks[0].Type())
+
+	_, err = svc.EnqueueReindex(ctx, []ReindexStep{ReindexStepMetadata}, ReindexFilter{})
+	assert.ErrorIs(t, err, ErrReindexInProgress, "the first run is still queuing")
+
+	require.NoError(t, svc.HandleReindex(ctx, queue.tasks[0]))
+
+	perAsset := queue.tasks[1:]
+	require.Len(t, perAsset, 6)
+	for i, task := range perAsset {
+		var payload struct {
+			AssetID string `json:"asset_id"`
+		}
+		require.NoError(t, json.Unmarshal(task.Payload(), &payload))
+		assert.True(t, assetIDs[payload.AssetID], "only assets of the user are re-indexed")
+		assert.Contains(t, queue.opts[i+1], asynq.Queue(ReindexQueue))
+	}
+
+	status, err := svc.GetReindexStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, run.ID, status.ID)
+	assert.Equal(t, int64(6), status.EnqueuedJobs)
+	assert.NotNil(t, status.EnqueuedAt)
+
+	// A retried job of a finished run queues nothing more.
+	require.NoError(t, svc.HandleReindex(ctx, queue.tasks[0]))
+	assert.Len(t, queue.tasks, 7)
+}
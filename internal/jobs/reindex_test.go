@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReindexJobTypes(t *testing.T) {
+	jobTypes, err := reindexJobTypes([]ReindexStep{"Thumbnails", ReindexStepMetadata, ReindexStepGeocode, ReindexStepEmbeddings})
+	require.NoError(t, err)
+	assert.Equal(t, []JobType{JobTypeThumbnailGeneration, JobTypeMetadataExtraction, JobTypeSmartSearch}, jobTypes,
+		"geocode runs with the metadata extraction")
+
+	_, err = reindexJobTypes([]ReindexStep{ReindexStepFaces, "ocr"})
+	assert.ErrorIs(t, err, ErrInvalidReindexStep)
+
+	_, err = reindexJobTypes(nil)
+	assert.ErrorIs(t, err, ErrInvalidReindexStep)
+}
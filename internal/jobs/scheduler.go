@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+)
+
+// SchedulePeriodicJob queues jobType with payload on every match of the cron
+// expression cronspec, evaluated in the server's local time zone, once the
+// service is started. Every server sharing the Redis instance runs its own
+// scheduler, so periodic jobs should carry an asynq.TaskID to be queued only
+// once.
+func (s *Service) SchedulePeriodicJob(cronspec string, jobType JobType, payload any, opts ...asynq.Option) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	if s.scheduler == nil {
+		s.scheduler = asynq.NewScheduler(s.redisOpt, &asynq.SchedulerOpts{
+			Location:        time.Local,
+			PostEnqueueFunc: s.logScheduledJob,
+		})
+	}
+
+	entryID, err := s.scheduler.Register(cronspec, asynq.NewTask(string(jobType), data), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to schedule %s job with %q: %w", jobType, cronspec, err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"entry_id": entryID,
+		"job_type": jobType,
+		"schedule": cronspec,
+	}).Info("Periodic job scheduled")
+
+	return nil
+}
+
+// logScheduledJob reports jobs queued by the scheduler. A task ID conflict
+// means the previous run is still queued or another server queued it first.
+func (s *Service) logScheduledJob(info *asynq.TaskInfo, err error) {
+	switch {
+	case errors.Is(err, asynq.ErrTaskIDConflict):
+		return
+	case err != nil:
+		s.logger.WithError(err).Warn("Failed to enqueue scheduled job")
+	default:
+		s.logger.WithFields(logrus.Fields{
+			"job_id":   info.ID,
+			"job_type": info.Type,
+			"queue":    info.Queue,
+		}).Info("Scheduled job enqueued")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/stacks"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -23,28 +24,50 @@ const (
 	JobTypeMetadataExtraction  JobType = "metadata_extraction"
 	JobTypeVideoTranscode      JobType = "video_transcode"
 	JobTypeAssetOptimization   JobType = "asset_optimization"
+	JobTypeThumbnailLayouts    JobType = "thumbnail_layouts"
 
 	// Machine learning jobs
-	JobTypeFaceDetection   JobType = "face_detection"
-	JobTypeFaceRecognition JobType = "face_recognition"
-	JobTypeSmartSearch     JobType = "smart_search_indexing"
-	JobTypeObjectDetection JobType = "object_detection"
+	JobTypeFaceDetection    JobType = "face_detection"
+	JobTypeFaceRecognition  JobType = "face_recognition"
+	JobTypePersonThumbnails JobType = "person_thumbnails"
+	JobTypeSmartSearch      JobType = "smart_search_indexing"
+	JobTypeObjectDetection  JobType = "object_detection"
 
 	// Library jobs
 	JobTypeLibraryScan     JobType = "library_scan"
 	JobTypeLibraryWatch    JobType = "library_watch"
+	JobTypeLibraryDeletion JobType = "library_deletion"
 	JobTypeDuplicateDetect JobType = "duplicate_detection"
 	JobTypeSidecarProcess  JobType = "sidecar_processing"
+	JobTypeAutoStack       JobType = "auto_stack"
 
 	// System jobs
-	JobTypeStorageMigration JobType = "storage_migration"
-	JobTypeCleanup          JobType = "cleanup"
-	JobTypeBackup           JobType = "backup"
+	JobTypeStorageMigration  JobType = "storage_migration"
+	JobTypeCleanup           JobType = "cleanup"
+	JobTypeBackup            JobType = "backup"
+	JobTypeUserDeletion      JobType = "user_deletion"
+	JobTypeUserDeletionCheck JobType = "user_deletion_check"
+	JobTypeIntegrityScan     JobType = "integrity_scan"
+	JobTypeQuotaUsageSync    JobType = "quota_usage_sync"
+	JobTypeGeodataImport     JobType = "geodata_import"
+	JobTypeWebhookDelivery   JobType = "webhook_delivery"
 )
 
 const (
 	defaultMaxRetry = 10
 	defaultTimeout  = 30 * time.Minute
+
+	// userDeletionTimeout bounds a single run of the user deletion job. The
+	// job resumes where it stopped when it is retried after a timeout.
+	userDeletionTimeout = 6 * time.Hour
+	// libraryDeletionTimeout does the same for the library deletion job.
+	libraryDeletionTimeout = 6 * time.Hour
+
+	// integrityScanTimeout bounds a scan, which re-reads every original.
+	integrityScanTimeout = 48 * time.Hour
+	// integrityScanMaxRetry keeps a failing scan from re-reading the library
+	// over and over; the next scheduled scan starts afresh anyway.
+	integrityScanMaxRetry = 2
 )
 
 // JobPriority represents the priority level of a job
@@ -73,6 +96,14 @@ type Service struct {
 	handlers   map[string]func(context.Context, *asynq.Task) error
 	db         *sqlc.Queries
 	maxRetries int
+
+	cleanupEnabled  bool
+	cleanupInterval time.Duration
+	retention       time.Duration
+	stopCleanup     chan struct{}
+
+	redisOpt  asynq.RedisClientOpt
+	scheduler *asynq.Scheduler
 }
 
 // Config holds job queue configuration
@@ -84,6 +115,12 @@ type Config struct {
 	QueueName     string
 	MaxRetries    int
 	DB            *sqlc.Queries
+
+	// CleanupEnabled periodically removes completed and archived tasks older
+	// than RetentionPeriod from Redis.
+	CleanupEnabled  bool
+	CleanupInterval time.Duration
+	RetentionPeriod time.Duration
 }
 
 // NewService creates a new job queue service
@@ -112,6 +149,16 @@ func NewService(cfg *Config) (*Service, error) {
 		handlers:   make(map[string]func(context.Context, *asynq.Task) error),
 		db:         cfg.DB,
 		maxRetries: maxRetries,
+
+		cleanupEnabled:  cfg.CleanupEnabled,
+		cleanupInterval: cfg.CleanupInterval,
+		retention:       cfg.RetentionPeriod,
+
+		redisOpt: redisOpt,
+	}
+
+	if err := s.registerMetrics(); err != nil {
+		return nil, err
 	}
 
 	serverCfg := asynq.Config{
@@ -121,6 +168,9 @@ func NewService(cfg *Config) (*Service, error) {
 			"high":     3,
 			"normal":   2,
 			"low":      1,
+			// Re-index runs share the lowest weight so they only use
+			// capacity the regular work leaves.
+			ReindexQueue: 1,
 		},
 		ErrorHandler: asynq.ErrorHandlerFunc(s.handleTaskError),
 	}
@@ -229,12 +279,11 @@ func (s *Service) RetryDeadLetterJob(ctx context.Context, id string) error {
 	}
 
 	task := asynq.NewTask(row.JobType, row.Payload)
-	_, err = s.client.EnqueueContext(
-		ctx, task,
+	_, err = s.client.EnqueueContext(ctx, task, s.withRetention([]asynq.Option{
 		asynq.Queue(row.Queue),
 		asynq.MaxRetry(s.maxRetries),
 		asynq.Timeout(defaultTimeout),
-	)
+	})...)
 	if err != nil {
 		return fmt.Errorf("failed to re-enqueue dead-letter job: %w", err)
 	}
@@ -285,7 +334,7 @@ func (s *Service) EnqueueJob(ctx context.Context, jobType JobType, payload any,
 
 	task := asynq.NewTask(string(jobType), data)
 
-	info, err := s.client.EnqueueContext(ctx, task, opts...)
+	info, err := s.client.EnqueueContext(ctx, task, s.withRetention(opts)...)
 	if err != nil {
 		return fmt.Errorf("failed to enqueue job: %w", err)
 	}
@@ -311,6 +360,66 @@ func (s *Service) EnqueueJobWithPriority(ctx context.Context, jobType JobType, p
 	return s.EnqueueJob(ctx, jobType, payload, opts...)
 }
 
+// EnqueueUserDeletion queues the purge of a user marked for removal. The task
+// ID is derived from the user, so queueing the same user twice is a no-op.
+func (s *Service) EnqueueUserDeletion(ctx context.Context, userID uuid.UUID) error {
+	err := s.EnqueueJob(ctx, JobTypeUserDeletion, UserDeletionPayload{UserID: userID.String()},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(fmt.Sprintf("%s:%s", JobTypeUserDeletion, userID)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(userDeletionTimeout),
+		// A retained task would keep its ID reserved and block a later
+		// deletion of the same user.
+		asynq.Retention(0),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// EnqueueAutoStack queues auto-stacking of a user's assets. While a run for
+// the user is queued or running, further runs are not queued.
+func (s *Service) EnqueueAutoStack(ctx context.Context, userID string, opts stacks.AutoStackOptions) error {
+	err := s.EnqueueJob(ctx, JobTypeAutoStack, AutoStackPayload{UserID: userID, Options: opts},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(fmt.Sprintf("%s:%s", JobTypeAutoStack, userID)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+		asynq.Retention(0),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// EnqueueIntegrityScan queues an integrity scan. While a scan is queued or
+// running, further scans are not queued.
+func (s *Service) EnqueueIntegrityScan(ctx context.Context, payload IntegrityScanPayload) error {
+	err := s.EnqueueJob(ctx, JobTypeIntegrityScan, payload, s.integrityScanOptions()...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// ScheduleIntegrityScan queues an integrity scan on every match of the cron
+// expression cronspec once the service is started.
+func (s *Service) ScheduleIntegrityScan(cronspec string, payload IntegrityScanPayload) error {
+	return s.SchedulePeriodicJob(cronspec, JobTypeIntegrityScan, payload, s.integrityScanOptions()...)
+}
+
+func (s *Service) integrityScanOptions() []asynq.Option {
+	return []asynq.Option{
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeIntegrityScan)),
+		asynq.MaxRetry(integrityScanMaxRetry),
+		asynq.Timeout(integrityScanTimeout),
+		asynq.Retention(0),
+	}
+}
+
 // ScheduleJob schedules a job to run at a specific time.
 func (s *Service) ScheduleJob(ctx context.Context, jobType JobType, payload any, processAt time.Time) error {
 	opts := []asynq.Option{
@@ -367,12 +476,11 @@ type JobStatus struct {
 
 // GetQueueStats returns statistics for all queues
 func (s *Service) GetQueueStats(ctx context.Context) (*QueueStats, error) {
-	queues := []string{"critical", "high", "normal", "low"}
 	stats := &QueueStats{
 		Queues: make(map[string]*QueueInfo),
 	}
 
-	for _, queue := range queues {
+	for _, queue := range queueNames {
 		info, err := s.inspector.GetQueueInfo(queue)
 		if err != nil {
 			s.logger.WithError(err).Warnf("Failed to get stats for queue: %s", queue)
@@ -392,6 +500,16 @@ func (s *Service) GetQueueStats(ctx context.Context) (*QueueStats, error) {
 		}
 	}
 
+	deadLetter, err := s.db.CountJobFailuresByQueue(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to count dead-letter jobs")
+	}
+	for _, row := range deadLetter {
+		if info, ok := stats.Queues[row.Queue]; ok {
+			info.DeadLetter = int(row.Count)
+		}
+	}
+
 	return stats, nil
 }
 
@@ -411,6 +529,8 @@ type QueueInfo struct {
 	Archived  int    `json:"archived"`
 	Completed int    `json:"completed"`
 	Paused    bool   `json:"paused"`
+	// DeadLetter counts failures recorded in the dead-letter table
+	DeadLetter int `json:"dead_letter"`
 }
 
 // PauseQueue pauses processing of a specific queue
@@ -456,12 +576,32 @@ func (s *Service) Start() error {
 	for jobType, handler := range s.handlers {
 		mux.HandleFunc(jobType, handler)
 	}
-	return s.server.Start(mux)
+	if err := s.server.Start(mux); err != nil {
+		return err
+	}
+	if s.cleanupEnabled && s.cleanupInterval > 0 && s.retention > 0 {
+		s.stopCleanup = make(chan struct{})
+		go s.runCleanup(s.stopCleanup)
+	}
+	if s.scheduler != nil {
+		if err := s.scheduler.Start(); err != nil {
+			s.logger.WithError(err).Warn("Failed to start job scheduler, periodic jobs disabled")
+			s.scheduler = nil
+		}
+	}
+	return nil
 }
 
 // Stop gracefully stops the job queue server
 func (s *Service) Stop() {
 	s.logger.Info("Stopping job queue server")
+	if s.stopCleanup != nil {
+		close(s.stopCleanup)
+		s.stopCleanup = nil
+	}
+	if s.scheduler != nil {
+		s.scheduler.Shutdown()
+	}
 	s.server.Stop()
 	s.server.Shutdown()
 	s.client.Close()
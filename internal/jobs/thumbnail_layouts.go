@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+const (
+	// thumbnailLayoutsSchedule is how often assets whose thumbnails were
+	// generated without a thumbhash get one.
+	thumbnailLayoutsSchedule = "@every 15m"
+	// thumbnailLayoutsBatchSize is how many assets are listed at once.
+	thumbnailLayoutsBatchSize = 500
+)
+
+// ScheduleThumbnailLayouts runs the thumbnail layout backfill periodically
+// once the service is started.
+func (s *Service) ScheduleThumbnailLayouts() error {
+	return s.SchedulePeriodicJob(thumbnailLayoutsSchedule, JobTypeThumbnailLayouts, struct{}{},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeThumbnailLayouts)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+		asynq.Retention(0),
+	)
+}
+
+// HandleThumbnailLayouts records the thumbhash and aspect ratio of the assets
+// whose thumbnails were generated before they were recorded, from their
+// stored small thumbnail. Assets whose thumbnail cannot be read are tried
+// again on the next run.
+func (h *Handlers) HandleThumbnailLayouts(ctx context.Context, _ *asynq.Task) error {
+	if h.storageService == nil {
+		return fmt.Errorf("thumbnail layouts need a storage service: %w", asynq.SkipRetry)
+	}
+
+	after := pgtype.UUID{Valid: true}
+	stored, failed := 0, 0
+	for {
+		rows, err := h.db.ListAssetsMissingThumbnailLayout(ctx, sqlc.ListAssetsMissingThumbnailLayoutParams{
+			ID:    after,
+			Limit: thumbnailLayoutsBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list assets missing a thumbnail layout: %w", err)
+		}
+		for _, row := range rows {
+			if err := h.storeThumbnailLayout(ctx, row); err != nil {
+				failed++
+				h.logger.WithError(err).WithField("asset_id", row.ID.String()).
+					Warn("Failed to store thumbnail layout")
+				continue
+			}
+			stored++
+		}
+		if len(rows) < thumbnailLayoutsBatchSize {
+			break
+		}
+		after = rows[len(rows)-1].ID
+	}
+
+	if stored > 0 || failed > 0 {
+		h.logger.WithFields(logrus.Fields{
+			"stored": stored,
+			"failed": failed,
+		}).Info("Stored thumbnail layouts")
+	}
+	return nil
+}
+
+func (h *Handlers) storeThumbnailLayout(ctx context.Context, row sqlc.ListAssetsMissingThumbnailLayoutRow) error {
+	reader, err := h.storageService.Derivatives().Download(ctx, row.Path)
+	if err != nil {
+		return fmt.Errorf("failed to download thumbnail: %w", err)
+	}
+	defer reader.Close()
+	thumb, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read thumbnail: %w", err)
+	}
+	return assets.StoreThumbnailLayout(ctx, h.db, row.ID, thumb)
+}
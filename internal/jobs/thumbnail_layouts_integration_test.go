@@ -0,0 +1,61 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+// TestIntegration_HandleThumbnailLayouts backfills the thumbhash and ratio
+// of an asset from its stored small thumbnail, and skips an asset whose
+// thumbnail is missing.
+func TestIntegration_HandleThumbnailLayouts(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	storageService := newLocalStorageService(t, t.TempDir())
+
+	userID := tdb.CreateTestUser(t, "thumbnail-layouts@example.com")
+	withThumb := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, userID, "with-thumb"), Valid: true}
+	missingThumb := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, userID, "missing-thumb"), Valid: true}
+	for _, assetID := range []pgtype.UUID{withThumb, missingThumb} {
+		_, err := tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+			AssetId: assetID,
+			Type:    "thumb",
+			Path:    "thumbs/" + assetID.String() + ".jpg",
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, storageService.Derivatives().UploadBytes(ctx,
+		"thumbs/"+withThumb.String()+".jpg", createIntegrationTestJPEG(160, 80), "image/jpeg"))
+
+	handlers := NewHandlers(tdb.Queries, nil, nil, storageService, nil, nil)
+	task := newTestTask(t, JobTypeThumbnailLayouts, struct{}{})
+	require.NoError(t, handlers.HandleThumbnailLayouts(ctx, task))
+
+	asset, err := tdb.Queries.GetAsset(ctx, withThumb)
+	require.NoError(t, err)
+	assert.NotEmpty(t, asset.Thumbhash)
+	var ratio float64
+	require.NoError(t, tdb.Pool.QueryRow(ctx,
+		`SELECT ratio FROM asset_thumbnail_ratios WHERE "assetId" = $1`, withThumb).Scan(&ratio))
+	assert.InDelta(t, 2.0, ratio, 1e-9)
+
+	pending, err := tdb.Queries.ListAssetsMissingThumbnailLayout(ctx, sqlc.ListAssetsMissingThumbnailLayoutParams{
+		ID:    pgtype.UUID{Valid: true},
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, missingThumb, pending[0].ID, "an unreadable thumbnail is tried again on the next run")
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// userDeletionCheckSchedule is how often accounts whose deletion grace period
+// has ended are handed to the user deletion job.
+const userDeletionCheckSchedule = "@hourly"
+
+// ScheduleUserDeletionCheck runs the user deletion check periodically once
+// the service is started.
+func (s *Service) ScheduleUserDeletionCheck() error {
+	return s.SchedulePeriodicJob(userDeletionCheckSchedule, JobTypeUserDeletionCheck, struct{}{},
+		asynq.Queue(s.getQueueByPriority(PriorityLow)),
+		asynq.TaskID(string(JobTypeUserDeletionCheck)),
+		asynq.MaxRetry(s.maxRetries),
+		asynq.Timeout(defaultTimeout),
+		asynq.Retention(0),
+	)
+}
+
+// HandleUserDeletionCheck marks users whose deletion grace period has ended
+// for removal and queues their deletion.
+func (s *Service) HandleUserDeletionCheck(ctx context.Context, _ *asynq.Task) error {
+	if s.db == nil {
+		return fmt.Errorf("user deletion check needs a database: %w", asynq.SkipRetry)
+	}
+
+	due, err := s.db.ListDueUserDeletions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list due user deletions: %w", err)
+	}
+	for _, userID := range due {
+		if err := s.db.MarkUserForRemoval(ctx, userID); err != nil {
+			return fmt.Errorf("failed to mark user for removal: %w", err)
+		}
+		if err := s.EnqueueUserDeletion(ctx, uuid.UUID(userID.Bytes)); err != nil {
+			return fmt.Errorf("failed to queue user deletion: %w", err)
+		}
+		// Dropped last, so a failure above is picked up by the next check.
+		if err := s.db.DeleteUserDeletionSchedule(ctx, userID); err != nil {
+			return fmt.Errorf("failed to clear user deletion schedule: %w", err)
+		}
+	}
+
+	if len(due) > 0 {
+		s.logger.WithField("users", len(due)).Info("Queued deletion of users past their grace period")
+	}
+	return nil
+}
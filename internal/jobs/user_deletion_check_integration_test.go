@@ -0,0 +1,60 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+func TestIntegration_HandleUserDeletionCheck(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	svc := newTestService(t, tdb)
+	queue := svc.client.(*fakeEnqueuer)
+
+	dueID := tdb.CreateTestUser(t, "deletion-due@example.com")
+	pendingID := tdb.CreateTestUser(t, "deletion-pending@example.com")
+	due := pgtype.UUID{Bytes: dueID, Valid: true}
+	pending := pgtype.UUID{Bytes: pendingID, Valid: true}
+	for userID, deleteAt := range map[pgtype.UUID]time.Time{
+		due:     time.Now().Add(-time.Minute),
+		pending: time.Now().Add(time.Hour),
+	} {
+		require.NoError(t, tdb.Queries.SoftDeleteUser(ctx, userID))
+		require.NoError(t, tdb.Queries.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+			UserId:           userID,
+			DeleteAt:         pgtype.Timestamptz{Time: deleteAt, Valid: true},
+			RestoreTokenHash: userID.String(),
+		}))
+	}
+
+	require.NoError(t, svc.HandleUserDeletionCheck(ctx, nil))
+
+	require.Len(t, queue.tasks, 1)
+	assert.Equal(t, string(JobTypeUserDeletion), queue.tasks[0].Type())
+	assert.Contains(t, string(queue.tasks[0].Payload()), dueID.String())
+
+	user, err := tdb.Queries.GetUserIncludingDeleted(ctx, due)
+	require.NoError(t, err)
+	assert.Equal(t, "removing", user.Status)
+	_, err = tdb.Queries.GetUserDeletionSchedule(ctx, due)
+	assert.Error(t, err, "the schedule of a queued deletion is dropped")
+
+	user, err = tdb.Queries.GetUserIncludingDeleted(ctx, pending)
+	require.NoError(t, err)
+	assert.NotEqual(t, "removing", user.Status)
+	_, err = tdb.Queries.GetUserDeletionSchedule(ctx, pending)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
+)
+
+// webhookDeliveryTimeout bounds a delivery attempt, which itself times out
+// after a few seconds.
+const webhookDeliveryTimeout = time.Minute
+
+// WebhookDeliveryPayload is the payload of a webhook delivery: an event to
+// send to one subscription.
+type WebhookDeliveryPayload struct {
+	SubscriptionID string         `json:"subscription_id"`
+	Event          webhooks.Event `json:"event"`
+}
+
+// PublishWebhookEvent queues a delivery of an event to every enabled
+// subscription to its type. Failed deliveries are retried with backoff.
+func (s *Service) PublishWebhookEvent(ctx context.Context, eventType webhooks.EventType, data map[string]any) error {
+	subscriptions, err := webhooks.NewService(s.db).ListForEvent(ctx, eventType)
+	if err != nil {
+		return err
+	}
+
+	event := webhooks.NewEvent(eventType, data)
+	var errs []error
+	for _, subscription := range subscriptions {
+		subscriptionID := uuid.UUID(subscription.ID.Bytes).String()
+		err := s.EnqueueJob(ctx, JobTypeWebhookDelivery, WebhookDeliveryPayload{SubscriptionID: subscriptionID, Event: event},
+			asynq.Queue(s.getQueueByPriority(PriorityNormal)),
+			asynq.TaskID(fmt.Sprintf("%s:%s:%s", JobTypeWebhookDelivery, subscriptionID, event.ID)),
+			asynq.MaxRetry(s.maxRetries),
+			asynq.Timeout(webhookDeliveryTimeout),
+		)
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			errs = append(errs, fmt.Errorf("failed to queue webhook delivery to %s: %w", subscriptionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandleWebhookDelivery sends an event to a subscription and records the
+// outcome on the subscription. Deliveries to subscriptions that were since
+// deleted or disabled are dropped.
+func (s *Service) HandleWebhookDelivery(ctx context.Context, task *asynq.Task) error {
+	var payload WebhookDeliveryPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("invalid webhook delivery payload: %w: %w", err, asynq.SkipRetry)
+	}
+	subscriptionID, err := uuid.Parse(payload.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("invalid webhook subscription ID %q: %w", payload.SubscriptionID, asynq.SkipRetry)
+	}
+
+	service := webhooks.NewService(s.db)
+	subscription, err := service.Get(ctx, subscriptionID)
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !subscription.Enabled {
+		return nil
+	}
+
+	if err := service.Deliver(ctx, subscription, payload.Event); err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"subscription_id": payload.SubscriptionID,
+			"event_type":      payload.Event.Type,
+		}).Warn("Webhook delivery failed")
+		return err
+	}
+	return nil
+}
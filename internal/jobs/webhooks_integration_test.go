@@ -0,0 +1,92 @@
+//go:build integration
+// +build integration
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
+)
+
+func TestIntegration_WebhookDelivery(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	svc := newTestService(t, tdb)
+	queue := svc.client.(*fakeEnqueuer)
+
+	var failing atomic.Bool
+	var deliveries atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer endpoint.Close()
+
+	subscriptions := webhooks.NewService(tdb.Queries)
+	subscribed, err := subscriptions.Create(ctx, webhooks.CreateParams{
+		URL:     endpoint.URL,
+		Events:  []webhooks.EventType{webhooks.EventAssetUploaded},
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, subscribed.Secret, "a secret is generated")
+	_, err = subscriptions.Create(ctx, webhooks.CreateParams{
+		URL:     endpoint.URL,
+		Events:  []webhooks.EventType{webhooks.EventAssetUploaded},
+		Enabled: false,
+	})
+	require.NoError(t, err)
+	_, err = subscriptions.Create(ctx, webhooks.CreateParams{
+		URL:     endpoint.URL,
+		Events:  []webhooks.EventType{webhooks.EventUserCreated},
+		Enabled: true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.PublishWebhookEvent(ctx, webhooks.EventAssetUploaded, map[string]any{"assetId": "asset"}))
+	require.Len(t, queue.tasks, 1, "only the enabled subscription to the event gets a delivery")
+	task := queue.tasks[0]
+	assert.Equal(t, string(JobTypeWebhookDelivery), task.Type())
+	var payload WebhookDeliveryPayload
+	require.NoError(t, json.Unmarshal(task.Payload(), &payload))
+	assert.Equal(t, uuid.UUID(subscribed.ID.Bytes).String(), payload.SubscriptionID)
+	assert.Equal(t, webhooks.EventAssetUploaded, payload.Event.Type)
+
+	failing.Store(true)
+	require.Error(t, svc.HandleWebhookDelivery(ctx, task), "failed deliveries are retried")
+	subscription, err := subscriptions.Get(ctx, subscribed.ID.Bytes)
+	require.NoError(t, err)
+	assert.True(t, subscription.LastDeliveryAt.Valid)
+	assert.False(t, subscription.LastSuccessAt.Valid)
+	assert.Equal(t, int32(http.StatusServiceUnavailable), subscription.LastStatusCode.Int32)
+	assert.Contains(t, subscription.LastError.String, "unavailable")
+	assert.Equal(t, int32(1), subscription.FailureCount)
+
+	failing.Store(false)
+	require.NoError(t, svc.HandleWebhookDelivery(ctx, task))
+	subscription, err = subscriptions.Get(ctx, subscribed.ID.Bytes)
+	require.NoError(t, err)
+	assert.True(t, subscription.LastSuccessAt.Valid)
+	assert.Equal(t, int32(http.StatusOK), subscription.LastStatusCode.Int32)
+	assert.False(t, subscription.LastError.Valid)
+	assert.Zero(t, subscription.FailureCount)
+
+	require.NoError(t, subscriptions.Delete(ctx, subscribed.ID.Bytes))
+	require.NoError(t, svc.HandleWebhookDelivery(ctx, task), "deliveries to deleted subscriptions are dropped")
+	assert.Equal(t, int32(2), deliveries.Load())
+}
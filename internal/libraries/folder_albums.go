@@ -0,0 +1,163 @@
+package libraries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// FolderAlbumNaming is how the albums of library folders are named.
+type FolderAlbumNaming string
+
+const (
+	// FolderAlbumNamingFolder names an album after its folder, e.g.
+	// "Italy Trip".
+	FolderAlbumNamingFolder FolderAlbumNaming = "folder"
+	// FolderAlbumNamingPath names an album after its folder's path below the
+	// import path, e.g. "2023/Italy Trip".
+	FolderAlbumNamingPath FolderAlbumNaming = "path"
+)
+
+// FolderAlbums are the settings of the albums mirroring a library's folders.
+// Each asset a scan imports goes into the album of its folder, which is
+// created the first time and reused by later scans.
+type FolderAlbums struct {
+	Enabled bool `json:"enabled"`
+	// Depth is how many folder levels below an import path make albums; the
+	// files further down go into the album of their folder at that level. 0
+	// does not limit the levels.
+	Depth  int32             `json:"depth" validate:"min=0,max=32"`
+	Naming FolderAlbumNaming `json:"naming" validate:"omitempty,oneof=folder path"`
+}
+
+// getFolderAlbums returns the folder album settings of a library, disabled
+// when it has none.
+func (s *Service) getFolderAlbums(ctx context.Context, libraryID uuid.UUID) (FolderAlbums, error) {
+	settings, err := s.db.GetLibraryFolderAlbumSettings(ctx, pgutil.UUIDToPgtype(libraryID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return FolderAlbums{}, nil
+	}
+	if err != nil {
+		return FolderAlbums{}, fmt.Errorf("failed to get folder album settings: %w", err)
+	}
+	return FolderAlbums{
+		Enabled: true,
+		Depth:   settings.Depth,
+		Naming:  FolderAlbumNaming(settings.Naming),
+	}, nil
+}
+
+// setFolderAlbums saves the folder album settings of a library and returns
+// them as saved.
+func (s *Service) setFolderAlbums(ctx context.Context, libraryID uuid.UUID, folderAlbums FolderAlbums) (FolderAlbums, error) {
+	if !folderAlbums.Enabled {
+		if err := s.db.DeleteLibraryFolderAlbumSettings(ctx, pgutil.UUIDToPgtype(libraryID)); err != nil {
+			return FolderAlbums{}, fmt.Errorf("failed to turn off folder albums: %w", err)
+		}
+		return FolderAlbums{}, nil
+	}
+	if folderAlbums.Naming == "" {
+		folderAlbums.Naming = FolderAlbumNamingFolder
+	}
+	if _, err := s.db.UpsertLibraryFolderAlbumSettings(ctx, sqlc.UpsertLibraryFolderAlbumSettingsParams{
+		LibraryId: pgutil.UUIDToPgtype(libraryID),
+		Depth:     folderAlbums.Depth,
+		Naming:    string(folderAlbums.Naming),
+	}); err != nil {
+		return FolderAlbums{}, fmt.Errorf("failed to save folder album settings: %w", err)
+	}
+	return folderAlbums, nil
+}
+
+// folderAlbumPath returns the folder of filePath below root whose album the
+// file goes into, as a slash-separated path cut to depth levels, or "" for a
+// file right under root.
+func folderAlbumPath(root, filePath string, depth int32) string {
+	rel, err := filepath.Rel(root, filepath.Dir(filePath))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if depth > 0 && len(parts) > int(depth) {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// folderAlbumName returns the name of the album of folder, a path returned
+// by folderAlbumPath.
+func folderAlbumName(folder string, naming FolderAlbumNaming) string {
+	if naming == FolderAlbumNamingPath {
+		return folder
+	}
+	return path.Base(folder)
+}
+
+// folderAlbumIndex holds the albums of a library's folders during a scan.
+type folderAlbumIndex struct {
+	settings FolderAlbums
+	albums   map[string]pgtype.UUID
+}
+
+// loadFolderAlbums reads the albums of the library's folders, when it has
+// folder albums.
+func (ls *LibraryScanner) loadFolderAlbums(ctx context.Context) error {
+	ls.folderAlbums = nil
+	if !ls.library.FolderAlbums.Enabled {
+		return nil
+	}
+	rows, err := ls.db.GetLibraryFolderAlbums(ctx, pgutil.UUIDToPgtype(ls.library.ID))
+	if err != nil {
+		return fmt.Errorf("failed to list folder albums: %w", err)
+	}
+	index := &folderAlbumIndex{
+		settings: ls.library.FolderAlbums,
+		albums:   make(map[string]pgtype.UUID, len(rows)),
+	}
+	for _, row := range rows {
+		index.albums[row.FolderPath] = row.AlbumId
+	}
+	ls.folderAlbums = index
+	return nil
+}
+
+// addToFolderAlbum adds an asset imported from filePath, found under the
+// import path root, to the album of its folder, creating the album when the
+// folder has none yet.
+func (ls *LibraryScanner) addToFolderAlbum(ctx context.Context, root, filePath string, assetID pgtype.UUID) error {
+	if ls.folderAlbums == nil {
+		return nil
+	}
+	folder := folderAlbumPath(root, filePath, ls.folderAlbums.settings.Depth)
+	if folder == "" {
+		return nil
+	}
+	albumID, ok := ls.folderAlbums.albums[folder]
+	if !ok {
+		var err error
+		albumID, err = ls.db.CreateLibraryFolderAlbum(ctx, sqlc.CreateLibraryFolderAlbumParams{
+			OwnerId:    pgutil.UUIDToPgtype(ls.library.OwnerID),
+			AlbumName:  folderAlbumName(folder, ls.folderAlbums.settings.Naming),
+			LibraryId:  pgutil.UUIDToPgtype(ls.library.ID),
+			FolderPath: folder,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create album of folder %s: %w", folder, err)
+		}
+		ls.folderAlbums.albums[folder] = albumID
+	}
+	if err := ls.db.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: albumID, AssetsId: assetID}); err != nil {
+		return fmt.Errorf("failed to add asset to album of folder %s: %w", folder, err)
+	}
+	return nil
+}
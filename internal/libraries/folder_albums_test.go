@@ -0,0 +1,38 @@
+package libraries
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderAlbumPath(t *testing.T) {
+	root := filepath.FromSlash("/photos")
+	file := func(path string) string { return filepath.Join(root, filepath.FromSlash(path)) }
+
+	tests := []struct {
+		name  string
+		file  string
+		depth int32
+		want  string
+	}{
+		{"right under the import path", file("IMG_0001.jpg"), 0, ""},
+		{"one level", file("Italy Trip/IMG_0001.jpg"), 0, "Italy Trip"},
+		{"unlimited depth", file("2023/Italy Trip/IMG_0001.jpg"), 0, "2023/Italy Trip"},
+		{"cut to the depth", file("2023/Italy Trip/Day 1/IMG_0001.jpg"), 2, "2023/Italy Trip"},
+		{"shallower than the depth", file("2023/IMG_0001.jpg"), 2, "2023"},
+		{"outside the import path", filepath.FromSlash("/other/2023/IMG_0001.jpg"), 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, folderAlbumPath(root, tt.file, tt.depth))
+		})
+	}
+}
+
+func TestFolderAlbumName(t *testing.T) {
+	assert.Equal(t, "Italy Trip", folderAlbumName("2023/Italy Trip", FolderAlbumNamingFolder))
+	assert.Equal(t, "Italy Trip", folderAlbumName("2023/Italy Trip", ""))
+	assert.Equal(t, "2023/Italy Trip", folderAlbumName("2023/Italy Trip", FolderAlbumNamingPath))
+}
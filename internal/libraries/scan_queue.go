@@ -0,0 +1,132 @@
+package libraries
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// scanQueue runs library scans with at most maxRunning at once, and at most
+// maxPerOwner of the libraries of one owner. Scans over a limit wait, and
+// start in the order they were added once a running one finishes. A limit
+// of 0 does not limit.
+type scanQueue struct {
+	mu          sync.Mutex
+	maxRunning  int
+	maxPerOwner int
+	scans       map[uuid.UUID]*queuedScan
+	waiting     []*queuedScan
+	running     int
+	perOwner    map[uuid.UUID]int
+}
+
+// queuedScan is a scan of one library, waiting or running.
+type queuedScan struct {
+	libraryID uuid.UUID
+	ownerID   uuid.UUID
+	scanner   *LibraryScanner
+	run       func()
+	running   bool
+}
+
+func newScanQueue(maxRunning, maxPerOwner int) *scanQueue {
+	return &scanQueue{
+		maxRunning:  maxRunning,
+		maxPerOwner: maxPerOwner,
+		scans:       make(map[uuid.UUID]*queuedScan),
+		perOwner:    make(map[uuid.UUID]int),
+	}
+}
+
+// add queues run as the scan of libraryID by scanner, and starts it when
+// the limits allow. It reports false when the library is already queued or
+// being scanned.
+func (q *scanQueue) add(libraryID, ownerID uuid.UUID, scanner *LibraryScanner, run func()) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, exists := q.scans[libraryID]; exists {
+		return false
+	}
+	scan := &queuedScan{libraryID: libraryID, ownerID: ownerID, scanner: scanner, run: run}
+	q.scans[libraryID] = scan
+	q.waiting = append(q.waiting, scan)
+	q.startLocked()
+	return true
+}
+
+// remove stops the scan of libraryID if it runs, or drops it if it waits.
+func (q *scanQueue) remove(libraryID uuid.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	scan, exists := q.scans[libraryID]
+	if !exists {
+		return
+	}
+	if !scan.running {
+		delete(q.scans, libraryID)
+		q.dropWaitingLocked(scan)
+		return
+	}
+	// The scan stays counted until its run returns, so a stopped scan
+	// still holds its slot while it winds down.
+	if scan.scanner != nil {
+		scan.scanner.Stop()
+		scan.scanner = nil
+	}
+}
+
+// counts returns the number of running and waiting scans.
+func (q *scanQueue) counts() (running, waiting int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running, len(q.waiting)
+}
+
+// startLocked starts the waiting scans the limits allow, oldest first. A
+// scan held back by its owner's limit does not hold back those of others.
+func (q *scanQueue) startLocked() {
+	remaining := q.waiting[:0]
+	for _, scan := range q.waiting {
+		if !q.canStartLocked(scan.ownerID) {
+			remaining = append(remaining, scan)
+			continue
+		}
+		scan.running = true
+		q.running++
+		q.perOwner[scan.ownerID]++
+		go q.execute(scan)
+	}
+	clear(q.waiting[len(remaining):])
+	q.waiting = remaining
+}
+
+func (q *scanQueue) canStartLocked(ownerID uuid.UUID) bool {
+	if q.maxRunning > 0 && q.running >= q.maxRunning {
+		return false
+	}
+	return q.maxPerOwner <= 0 || q.perOwner[ownerID] < q.maxPerOwner
+}
+
+// execute runs scan, then frees its slot for the next waiting scan.
+func (q *scanQueue) execute(scan *queuedScan) {
+	defer func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		delete(q.scans, scan.libraryID)
+		q.running--
+		if q.perOwner[scan.ownerID]--; q.perOwner[scan.ownerID] <= 0 {
+			delete(q.perOwner, scan.ownerID)
+		}
+		q.startLocked()
+	}()
+	scan.run()
+}
+
+func (q *scanQueue) dropWaitingLocked(scan *queuedScan) {
+	for i, waiting := range q.waiting {
+		if waiting == scan {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package libraries
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanQueueLimitsConcurrentScans(t *testing.T) {
+	queue := newScanQueue(2, 1)
+	ownerA, ownerB := uuid.New(), uuid.New()
+	libraries := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	owners := []uuid.UUID{ownerA, ownerA, ownerB, ownerB}
+
+	started := make(chan uuid.UUID, len(libraries))
+	release := make(map[uuid.UUID]chan struct{})
+	for _, libraryID := range libraries {
+		release[libraryID] = make(chan struct{})
+	}
+	for i, libraryID := range libraries {
+		require.True(t, queue.add(libraryID, owners[i], nil, func() {
+			started <- libraryID
+			<-release[libraryID]
+		}))
+	}
+	assert.False(t, queue.add(libraries[0], ownerA, nil, func() {}), "a library is scanned once at a time")
+
+	expectStarted := func(want ...uuid.UUID) {
+		t.Helper()
+		var got []uuid.UUID
+		for range want {
+			select {
+			case id := <-started:
+				got = append(got, id)
+			case <-time.After(time.Second):
+				t.Fatal("scan did not start")
+			}
+		}
+		assert.ElementsMatch(t, want, got)
+		select {
+		case id := <-started:
+			t.Fatalf("unexpected scan of %s started", id)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	// One scan per owner, the first of each
+	expectStarted(libraries[0], libraries[2])
+	running, waiting := queue.counts()
+	assert.Equal(t, 2, running)
+	assert.Equal(t, 2, waiting)
+
+	// A waiting scan is dropped when its library goes away
+	queue.remove(libraries[3])
+	_, waiting = queue.counts()
+	assert.Equal(t, 1, waiting)
+
+	// The next scan of the owner starts once theirs finishes
+	close(release[libraries[0]])
+	expectStarted(libraries[1])
+
+	close(release[libraries[1]])
+	close(release[libraries[2]])
+	require.Eventually(t, func() bool {
+		running, waiting := queue.counts()
+		return running == 0 && waiting == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestScanQueueConcurrentStartsAndStops starts and stops scans of one library
+// from many goroutines, for the race detector, and checks no two of its
+// scans ever run at once.
+func TestScanQueueConcurrentStartsAndStops(t *testing.T) {
+	queue := newScanQueue(0, 0)
+	libraryID, ownerID := uuid.New(), uuid.New()
+
+	var inFlight, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			scanner := NewLibraryScanner(&Library{ID: libraryID}, nil, nil, time.UTC, 0)
+			queue.add(libraryID, ownerID, scanner, func() {
+				if inFlight.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				select {
+				case <-scanner.stopCh:
+				case <-time.After(time.Millisecond):
+				}
+				inFlight.Add(-1)
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			queue.remove(libraryID)
+			queue.counts()
+		}()
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		running, waiting := queue.counts()
+		return running == 0 && waiting == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(t, overlaps.Load())
+}
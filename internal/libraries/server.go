@@ -2,6 +2,7 @@ package libraries
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -11,6 +12,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 )
 
@@ -41,6 +43,10 @@ func (s *Server) CreateLibrary(ctx context.Context, req *immichv1.CreateLibraryR
 		ExclusionPatterns: req.ExclusionPatterns,
 		IsWatched:         false,
 		IsVisible:         true,
+		FolderAlbums:      folderAlbumsFromProto(req.FolderAlbums),
+	}
+	if err := grpcutil.Validate(ctx, "invalid library", createReq); err != nil {
+		return nil, err
 	}
 	library, err := s.service.CreateLibrary(ctx, userID, createReq)
 	if err != nil {
@@ -108,6 +114,10 @@ func (s *Server) UpdateLibrary(ctx context.Context, req *immichv1.UpdateLibraryR
 		Name:              req.Name,
 		ImportPaths:       req.ImportPaths,
 		ExclusionPatterns: req.ExclusionPatterns,
+		FolderAlbums:      folderAlbumsFromProto(req.FolderAlbums),
+	}
+	if err := grpcutil.Validate(ctx, "invalid library", updateReq); err != nil {
+		return nil, err
 	}
 	library, err := s.service.UpdateLibrary(ctx, userID, libraryID, updateReq)
 	if err != nil {
@@ -129,7 +139,18 @@ func (s *Server) DeleteLibrary(ctx context.Context, req *immichv1.DeleteLibraryR
 		return nil, status.Error(codes.InvalidArgument, "invalid library ID")
 	}
 
-	if err := s.service.DeleteLibrary(ctx, userID, libraryID); err != nil {
+	disposition, err := ParseAssetDisposition(req.GetAssetDisposition())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.service.DeleteLibrary(ctx, userID, libraryID, disposition); err != nil {
+		switch {
+		case errors.Is(err, ErrLibraryNotFound):
+			return nil, status.Error(codes.NotFound, "library not found")
+		case errors.Is(err, ErrDeletionQueueUnavailable):
+			return nil, status.Error(codes.Unavailable, "job service is not available")
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -164,10 +185,11 @@ func (s *Server) GetLibraryStatistics(ctx context.Context, req *immichv1.GetLibr
 	}
 
 	return &immichv1.LibraryStatisticsResponse{
-		Photos: int32(photos), // Safe after bounds check
-		Videos: int32(videos), // Safe after bounds check
-		Total:  stats.AssetCount,
-		Usage:  stats.TotalSize,
+		Photos:     int32(photos), // Safe after bounds check
+		Videos:     int32(videos), // Safe after bounds check
+		Total:      stats.AssetCount,
+		Usage:      stats.TotalSize,
+		QuotaUsage: stats.Usage,
 	}, nil
 }
 
@@ -190,7 +212,8 @@ func (s *Server) ScanLibrary(ctx context.Context, req *immichv1.ScanLibraryReque
 	return &emptypb.Empty{}, nil
 }
 
-// ValidateLibrary validates a library
+// ValidateLibrary reports for each import path of a library, or each path
+// given, whether it can be scanned.
 func (s *Server) ValidateLibrary(ctx context.Context, req *immichv1.ValidateLibraryRequest) (*immichv1.ValidateLibraryResponse, error) {
 	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
@@ -214,9 +237,26 @@ func (s *Server) ValidateLibrary(ctx context.Context, req *immichv1.ValidateLibr
 		return nil, status.Error(codes.PermissionDenied, "access denied")
 	}
 
-	// Return the import paths from the library
+	// Paths being edited are validated before they are saved
+	importPaths := req.GetImportPaths()
+	if len(importPaths) == 0 {
+		importPaths = library.ImportPaths
+	}
+	validation, err := s.service.ValidateLibrary(ctx, ValidateLibraryRequest{ImportPaths: importPaths})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	results := make([]*immichv1.ValidateLibraryImportPathResponseDto, len(validation.Results))
+	for i, result := range validation.Results {
+		results[i] = &immichv1.ValidateLibraryImportPathResponseDto{
+			ImportPath: result.Path,
+			IsValid:    result.IsValid,
+			Message:    &result.Message,
+		}
+	}
 	return &immichv1.ValidateLibraryResponse{
-		ImportPaths: library.ImportPaths,
+		ImportPaths: results,
 	}, nil
 }
 
@@ -228,6 +268,15 @@ func (s *Server) libraryToProto(lib *Library) *immichv1.LibraryResponse {
 		assetCount = 2147483647
 	}
 
+	var folderAlbums *immichv1.LibraryFolderAlbums
+	if lib.FolderAlbums.Enabled {
+		folderAlbums = &immichv1.LibraryFolderAlbums{
+			Enabled: true,
+			Depth:   lib.FolderAlbums.Depth,
+			Naming:  string(lib.FolderAlbums.Naming),
+		}
+	}
+
 	return &immichv1.LibraryResponse{
 		Id:                lib.ID.String(),
 		OwnerId:           lib.OwnerID.String(),
@@ -237,5 +286,19 @@ func (s *Server) libraryToProto(lib *Library) *immichv1.LibraryResponse {
 		CreatedAt:         timestamppb.New(lib.CreatedAt),
 		UpdatedAt:         timestamppb.New(lib.UpdatedAt),
 		AssetCount:        int32(assetCount), // Safe after bounds check
+		FolderAlbums:      folderAlbums,
+	}
+}
+
+// folderAlbumsFromProto converts the folder album settings of a request,
+// nil when it leaves them out.
+func folderAlbumsFromProto(folderAlbums *immichv1.LibraryFolderAlbums) *FolderAlbums {
+	if folderAlbums == nil {
+		return nil
+	}
+	return &FolderAlbums{
+		Enabled: folderAlbums.Enabled,
+		Depth:   folderAlbums.Depth,
+		Naming:  FolderAlbumNaming(folderAlbums.Naming),
 	}
 }
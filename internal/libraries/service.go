@@ -2,20 +2,21 @@ package libraries
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
-	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/assets"
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sirupsen/logrus"
 )
@@ -42,6 +43,53 @@ type Library struct {
 	UpdatedAt         time.Time
 	RefreshedAt       *time.Time
 	AssetCount        int64
+	FolderAlbums      FolderAlbums
+}
+
+// AssetDisposition is what happens to the assets of a deleted library. The
+// files of external libraries are never deleted, whichever is chosen.
+type AssetDisposition string
+
+const (
+	// AssetDispositionKeep turns the assets into uploads of the owner.
+	AssetDispositionKeep AssetDisposition = "keep"
+	// AssetDispositionTrash moves the assets to the owner's trash.
+	AssetDispositionTrash AssetDisposition = "trash"
+	// AssetDispositionDelete removes the assets and their derived files.
+	AssetDispositionDelete AssetDisposition = "delete"
+)
+
+// DefaultAssetDisposition is used when a deletion does not choose one. The
+// trash can still be restored from.
+const DefaultAssetDisposition = AssetDispositionTrash
+
+var (
+	// ErrLibraryNotFound is returned for a library that does not exist.
+	ErrLibraryNotFound = errors.New("library not found")
+	// ErrInvalidAssetDisposition is returned for an unknown asset disposition.
+	ErrInvalidAssetDisposition = errors.New("invalid asset disposition")
+	// ErrDeletionQueueUnavailable is returned when a library with assets is
+	// deleted without a job queue to handle them.
+	ErrDeletionQueueUnavailable = errors.New("library deletion needs the job service")
+)
+
+// ParseAssetDisposition validates an asset disposition, defaulting an empty
+// one to DefaultAssetDisposition.
+func ParseAssetDisposition(value string) (AssetDisposition, error) {
+	switch disposition := AssetDisposition(value); disposition {
+	case "":
+		return DefaultAssetDisposition, nil
+	case AssetDispositionKeep, AssetDispositionTrash, AssetDispositionDelete:
+		return disposition, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidAssetDisposition, value)
+	}
+}
+
+// DeletionQueue runs the asset disposition of deleted libraries as a
+// background job.
+type DeletionQueue interface {
+	EnqueueLibraryDeletion(ctx context.Context, libraryID uuid.UUID, disposition AssetDisposition) error
 }
 
 // Service manages libraries
@@ -49,16 +97,22 @@ type Service struct {
 	db             *sqlc.Queries
 	config         *config.Config
 	storageService *storage.Service
-	scanners       map[uuid.UUID]*LibraryScanner
+	scans          *scanQueue
+	deletionQueue  DeletionQueue
 }
 
 // NewService creates a new library service
 func NewService(db *sqlc.Queries, config *config.Config, storageService *storage.Service) *Service {
+	var maxScans, maxScansPerUser int
+	if config != nil {
+		maxScans = config.Libraries.MaxConcurrentScans
+		maxScansPerUser = config.Libraries.MaxConcurrentScansPerUser
+	}
 	return &Service{
 		db:             db,
 		config:         config,
 		storageService: storageService,
-		scanners:       make(map[uuid.UUID]*LibraryScanner),
+		scans:          newScanQueue(maxScans, maxScansPerUser),
 	}
 }
 
@@ -84,6 +138,14 @@ func (s *Service) CreateLibrary(ctx context.Context, userID uuid.UUID, req Creat
 		return nil, fmt.Errorf("failed to create library: %w", err)
 	}
 
+	var folderAlbums FolderAlbums
+	if req.FolderAlbums != nil {
+		folderAlbums, err = s.setFolderAlbums(ctx, pgutil.PgtypeToUUID(library.ID), *req.FolderAlbums)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Library{
 		ID:                pgutil.PgtypeToUUID(library.ID),
 		OwnerID:           pgutil.PgtypeToUUID(library.OwnerId),
@@ -102,7 +164,8 @@ func (s *Service) CreateLibrary(ctx context.Context, userID uuid.UUID, req Creat
 			}
 			return &t
 		}(),
-		AssetCount: 0,
+		AssetCount:   0,
+		FolderAlbums: folderAlbums,
 	}, nil
 }
 
@@ -122,6 +185,11 @@ func (s *Service) GetLibrary(ctx context.Context, userID, libraryID uuid.UUID) (
 		count = 0
 	}
 
+	folderAlbums, err := s.getFolderAlbums(ctx, libraryID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Library{
 		ID:                pgutil.PgtypeToUUID(library.ID),
 		OwnerID:           pgutil.PgtypeToUUID(library.OwnerId),
@@ -140,7 +208,8 @@ func (s *Service) GetLibrary(ctx context.Context, userID, libraryID uuid.UUID) (
 			}
 			return &t
 		}(),
-		AssetCount: count,
+		AssetCount:   count,
+		FolderAlbums: folderAlbums,
 	}, nil
 }
 
@@ -159,6 +228,10 @@ func (s *Service) GetLibraries(ctx context.Context, userID uuid.UUID) ([]*Librar
 			logrus.WithError(err).Warn("Failed to get library asset count")
 			count = 0
 		}
+		folderAlbums, err := s.getFolderAlbums(ctx, pgutil.PgtypeToUUID(dbLib.ID))
+		if err != nil {
+			return nil, err
+		}
 
 		libraries[i] = &Library{
 			ID:                pgutil.PgtypeToUUID(dbLib.ID),
@@ -178,7 +251,8 @@ func (s *Service) GetLibraries(ctx context.Context, userID uuid.UUID) ([]*Librar
 				}
 				return &t
 			}(),
-			AssetCount: count,
+			AssetCount:   count,
+			FolderAlbums: folderAlbums,
 		}
 	}
 
@@ -218,6 +292,16 @@ func (s *Service) UpdateLibrary(ctx context.Context, userID, libraryID uuid.UUID
 		return nil, fmt.Errorf("failed to update library: %w", err)
 	}
 
+	var folderAlbums FolderAlbums
+	if req.FolderAlbums != nil {
+		folderAlbums, err = s.setFolderAlbums(ctx, libraryID, *req.FolderAlbums)
+	} else {
+		folderAlbums, err = s.getFolderAlbums(ctx, libraryID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	// Get asset count
 	var count int64
 	if countResult, err := s.db.CountLibraryAssets(ctx, pgutil.UUIDToPgtype(libraryID)); err == nil {
@@ -245,49 +329,82 @@ func (s *Service) UpdateLibrary(ctx context.Context, userID, libraryID uuid.UUID
 			}
 			return &t
 		}(),
-		AssetCount: count,
+		AssetCount:   count,
+		FolderAlbums: folderAlbums,
 	}, nil
 }
 
-// DeleteLibrary deletes a library
-func (s *Service) DeleteLibrary(ctx context.Context, userID, libraryID uuid.UUID) error {
-	// Stop any active scanning
-	if scanner, exists := s.scanners[libraryID]; exists {
-		scanner.Stop()
-		delete(s.scanners, libraryID)
+// SetDeletionQueue makes DeleteLibrary hand the assets of deleted libraries
+// to a background job. Without one, only libraries without assets can be
+// deleted.
+func (s *Service) SetDeletionQueue(queue DeletionQueue) {
+	s.deletionQueue = queue
+}
+
+// DeleteLibrary deletes a library right away and queues the disposition of
+// its assets. Deleting a library again queues the disposition again, so a
+// failure to queue it can be retried.
+func (s *Service) DeleteLibrary(ctx context.Context, userID, libraryID uuid.UUID, disposition AssetDisposition) error {
+	disposition, err := ParseAssetDisposition(string(disposition))
+	if err != nil {
+		return err
+	}
+
+	libraryUUID := pgutil.UUIDToPgtype(libraryID)
+	library, err := s.db.GetLibraryIncludingDeleted(ctx, libraryUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLibraryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get library: %w", err)
+	}
+
+	// Stop any active or waiting scan
+	s.scans.remove(libraryID)
+
+	hasAssets, err := s.db.LibraryHasAssets(ctx, libraryUUID)
+	if err != nil {
+		return fmt.Errorf("failed to check library assets: %w", err)
+	}
+	if hasAssets && s.deletionQueue == nil {
+		return ErrDeletionQueueUnavailable
 	}
 
-	// Delete library and associated assets
-	if err := s.db.DeleteLibrary(ctx, pgutil.UUIDToPgtype(libraryID)); err != nil {
-		return fmt.Errorf("failed to delete library: %w", err)
+	if !library.DeletedAt.Valid {
+		if err := s.db.DeleteLibrary(ctx, libraryUUID); err != nil {
+			return fmt.Errorf("failed to delete library: %w", err)
+		}
+	}
+
+	if hasAssets {
+		if err := s.deletionQueue.EnqueueLibraryDeletion(ctx, libraryID, disposition); err != nil {
+			return fmt.Errorf("failed to queue library deletion: %w", err)
+		}
 	}
 
 	return nil
 }
 
-// ScanLibrary starts scanning a library for assets
+// ScanLibrary queues a scan of a library for assets. It starts once fewer
+// scans than the configured limits run, in total and of the owner's
+// libraries.
 func (s *Service) ScanLibrary(ctx context.Context, userID, libraryID uuid.UUID, forceRefresh, refreshAllFiles bool) (uuid.UUID, error) {
-	// Check if already scanning
-	if _, exists := s.scanners[libraryID]; exists {
-		return uuid.Nil, fmt.Errorf("library is already being scanned")
-	}
-
 	// Get library
 	library, err := s.GetLibrary(ctx, userID, libraryID)
 	if err != nil {
 		return uuid.Nil, err
 	}
 
-	// Create and start scanner
-	scanner := NewLibraryScanner(library, s.db, s.storageService)
-	s.scanners[libraryID] = scanner
-
-	// Start scanning in background
-	go func() {
-		defer func() {
-			delete(s.scanners, libraryID)
-		}()
+	// Create scanner
+	var offlineRetention time.Duration
+	if s.config != nil {
+		offlineRetention = s.config.Libraries.OfflineRetention
+	}
+	scanner := NewLibraryScanner(library, s.db, s.storageService, s.config.DefaultLocation(), offlineRetention)
 
+	// The scan outlives the request that queued it
+	ctx = context.WithoutCancel(ctx)
+	queued := s.scans.add(libraryID, library.OwnerID, scanner, func() {
 		if err := scanner.Scan(ctx, forceRefresh); err != nil {
 			logrus.WithError(err).Error("Library scan failed")
 		}
@@ -296,29 +413,41 @@ func (s *Service) ScanLibrary(ctx context.Context, userID, libraryID uuid.UUID,
 		if err := s.db.UpdateLibraryRefreshedAt(ctx, pgutil.UUIDToPgtype(libraryID)); err != nil {
 			logrus.WithError(err).Error("Failed to update library refresh timestamp")
 		}
-	}()
+	})
+	if !queued {
+		return uuid.Nil, fmt.Errorf("library is already being scanned")
+	}
 
 	// Return a job ID (simplified for now)
 	jobID := uuid.New()
 	return jobID, nil
 }
 
-// GetLibraryStatistics retrieves statistics for a library
+// ScanCounts returns the number of library scans running and waiting for
+// one to finish.
+func (s *Service) ScanCounts() (running, waiting int) {
+	return s.scans.counts()
+}
+
+// GetLibraryStatistics retrieves statistics for a library's active assets.
+// Usage is the fraction of the owner's quota the library takes up, or zero
+// when the owner has no quota.
 func (s *Service) GetLibraryStatistics(ctx context.Context, userID, libraryID uuid.UUID) (*LibraryStatistics, error) {
-	// Get total asset count from database
-	totalCount, err := s.db.CountLibraryAssets(ctx, pgutil.UUIDToPgtype(libraryID))
+	row, err := s.db.GetLibraryAssetStatistics(ctx, pgutil.UUIDToPgtype(libraryID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get library statistics: %w", err)
 	}
 
-	// Return actual count from database
-	// A more complete implementation would count by asset type
-	return &LibraryStatistics{
-		Photos:    totalCount, // Would need separate count by type
-		Videos:    0,          // Would need separate count by type
-		TotalSize: 0,          // Would need SUM(file_size) query
-		Usage:     0,          // Would need calculation against quota
-	}, nil
+	stats := &LibraryStatistics{
+		AssetCount: row.Total,
+		Photos:     row.Photos,
+		Videos:     row.Videos,
+		TotalSize:  row.TotalSize,
+	}
+	if row.OwnerQuota.Valid && row.OwnerQuota.Int64 > 0 {
+		stats.Usage = float32(float64(row.TotalSize) / float64(row.OwnerQuota.Int64))
+	}
+	return stats, nil
 }
 
 // ValidateImportPath validates an import path
@@ -336,58 +465,26 @@ func (s *Service) ValidateImportPath(path string) (bool, string) {
 	return true, ""
 }
 
-// ValidateLibrary validates library import paths
-func (s *Service) ValidateLibrary(ctx context.Context, req ValidateLibraryRequest) (*ValidateLibraryResponse, error) {
-	results := make(map[string]PathValidation)
-
-	for _, path := range req.ImportPaths {
-		validation := PathValidation{
-			Path:       path,
-			IsValid:    false,
-			IsReadable: false,
-		}
-
-		// Check if path exists
-		info, err := filepath.Glob(path)
-		if err != nil || len(info) == 0 {
-			validation.Message = "Path does not exist"
-		} else {
-			validation.IsValid = true
-			// Check read permissions by attempting to read the directory
-			_, readErr := filepath.Glob(filepath.Join(path, "*"))
-			if readErr != nil {
-				validation.IsReadable = false
-				validation.Message = "Path exists but is not readable"
-			} else {
-				validation.IsReadable = true
-				validation.Message = "Path is valid and readable"
-			}
-		}
-
-		results[path] = validation
-	}
-
-	return &ValidateLibraryResponse{
-		Results: results,
-	}, nil
-}
-
 // Request/Response types
 
 type CreateLibraryRequest struct {
-	Name              string      `json:"name"`
+	Name              string      `json:"name" validate:"required,max=255"`
 	Type              LibraryType `json:"type"`
-	ImportPaths       []string    `json:"importPaths"`
-	ExclusionPatterns []string    `json:"exclusionPatterns"`
+	ImportPaths       []string    `json:"importPaths" validate:"dive,required"`
+	ExclusionPatterns []string    `json:"exclusionPatterns" validate:"dive,required"`
 	IsWatched         bool        `json:"isWatched"`
 	IsVisible         bool        `json:"isVisible"`
+	// FolderAlbums turns on albums mirroring the folders, when set.
+	FolderAlbums *FolderAlbums `json:"folderAlbums,omitempty" validate:"omitnil"`
 }
 
 type UpdateLibraryRequest struct {
-	Name              *string  `json:"name,omitempty"`
-	ImportPaths       []string `json:"importPaths,omitempty"`
-	ExclusionPatterns []string `json:"exclusionPatterns,omitempty"`
+	Name              *string  `json:"name,omitempty" validate:"omitnil,min=1,max=255"`
+	ImportPaths       []string `json:"importPaths,omitempty" validate:"dive,required"`
+	ExclusionPatterns []string `json:"exclusionPatterns,omitempty" validate:"dive,required"`
 	IsWatched         *bool    `json:"isWatched,omitempty"`
+	// FolderAlbums replaces the folder album settings, when set.
+	FolderAlbums *FolderAlbums `json:"folderAlbums,omitempty" validate:"omitnil"`
 }
 
 type LibraryStatistics struct {
@@ -403,7 +500,8 @@ type ValidateLibraryRequest struct {
 }
 
 type ValidateLibraryResponse struct {
-	Results map[string]PathValidation `json:"results"`
+	// Results has one validation per import path, in request order.
+	Results []PathValidation `json:"results"`
 }
 
 type PathValidation struct {
@@ -415,40 +513,174 @@ type PathValidation struct {
 
 // LibraryScanner handles scanning library directories for assets
 type LibraryScanner struct {
-	library        *Library
-	db             *sqlc.Queries
-	storageService *storage.Service
-	stopCh         chan struct{}
+	library          *Library
+	db               *sqlc.Queries
+	storageService   *storage.Service
+	location         *time.Location
+	offlineRetention time.Duration
+	sidecars         sidecarDir
+	folderAlbums     *folderAlbumIndex
+	stopCh           chan struct{}
 }
 
-// NewLibraryScanner creates a new library scanner
-func NewLibraryScanner(library *Library, db *sqlc.Queries, storageService *storage.Service) *LibraryScanner {
+// NewLibraryScanner creates a new library scanner. location is the timezone
+// file modification times are shown in until metadata extraction replaces
+// them. Assets whose file stays missing for longer than offlineRetention are
+// deleted by the scan; 0 keeps them offline.
+func NewLibraryScanner(library *Library, db *sqlc.Queries, storageService *storage.Service, location *time.Location, offlineRetention time.Duration) *LibraryScanner {
 	return &LibraryScanner{
-		library:        library,
-		db:             db,
-		storageService: storageService,
-		stopCh:         make(chan struct{}),
+		library:          library,
+		db:               db,
+		storageService:   storageService,
+		location:         location,
+		offlineRetention: offlineRetention,
+		stopCh:           make(chan struct{}),
+	}
+}
+
+// libraryFiles holds the files of a library's assets during a scan: which of
+// them the walk found again, and the offline assets a reappearing file can
+// bring back.
+type libraryFiles struct {
+	assets  []sqlc.GetLibraryAssetFilesRow
+	byPath  map[string]sqlc.GetLibraryAssetFilesRow
+	offline map[string][]sqlc.GetLibraryAssetFilesRow
+	seen    map[string]bool
+}
+
+func newLibraryFiles(assets []sqlc.GetLibraryAssetFilesRow) *libraryFiles {
+	files := &libraryFiles{
+		assets:  assets,
+		byPath:  make(map[string]sqlc.GetLibraryAssetFilesRow, len(assets)),
+		offline: make(map[string][]sqlc.GetLibraryAssetFilesRow),
+		seen:    make(map[string]bool),
+	}
+	for _, asset := range assets {
+		// An online asset wins over an offline one left at the same path
+		if existing, ok := files.byPath[asset.OriginalPath]; !ok || existing.IsOffline {
+			files.byPath[asset.OriginalPath] = asset
+		}
+		if asset.IsOffline {
+			key := string(asset.Checksum)
+			files.offline[key] = append(files.offline[key], asset)
+		}
+	}
+	return files
+}
+
+// takeOffline returns an offline asset with checksum, preferring one last
+// seen at path, and forgets it so no other file restores it too.
+func (f *libraryFiles) takeOffline(checksum []byte, path string) (sqlc.GetLibraryAssetFilesRow, bool) {
+	key := string(checksum)
+	candidates := f.offline[key]
+	if len(candidates) == 0 {
+		return sqlc.GetLibraryAssetFilesRow{}, false
+	}
+	pick := 0
+	for i, candidate := range candidates {
+		if candidate.OriginalPath == path {
+			pick = i
+			break
+		}
+	}
+	asset := candidates[pick]
+	f.offline[key] = append(candidates[:pick:pick], candidates[pick+1:]...)
+	return asset, true
+}
+
+// missing returns the online assets the walk did not find again.
+func (f *libraryFiles) missing() []sqlc.GetLibraryAssetFilesRow {
+	var missing []sqlc.GetLibraryAssetFilesRow
+	for _, asset := range f.assets {
+		if !asset.IsOffline && !f.seen[asset.OriginalPath] {
+			missing = append(missing, asset)
+		}
 	}
+	return missing
 }
 
-// Scan scans the library for assets
+// Scan scans the library for assets. Assets whose file is gone are marked
+// offline, and come back online when a file with the same checksum turns up
+// again, at their old path or a new one.
 func (ls *LibraryScanner) Scan(ctx context.Context, forceRefresh bool) error {
 	logrus.Infof("Starting scan of library %s", ls.library.Name)
 
+	known, err := ls.db.GetLibraryAssetFiles(ctx, pgutil.UUIDToPgtype(ls.library.ID))
+	if err != nil {
+		return fmt.Errorf("failed to list library assets: %w", err)
+	}
+	files := newLibraryFiles(known)
+
+	// Assets are still imported when the folder albums cannot be read
+	if err := ls.loadFolderAlbums(ctx); err != nil {
+		logrus.WithError(err).Errorf("Failed to load folder albums of library %s", ls.library.Name)
+	}
+
 	for _, importPath := range ls.library.ImportPaths {
-		if err := ls.scanPath(ctx, importPath, forceRefresh); err != nil {
+		if err := ls.scanPath(ctx, importPath, files, forceRefresh); err != nil {
 			logrus.WithError(err).Errorf("Failed to scan path %s", importPath)
 			continue
 		}
 	}
 
+	// A stopped scan has not seen every file, so nothing is marked offline
+	select {
+	case <-ls.stopCh:
+		return fmt.Errorf("scan stopped")
+	default:
+	}
+
+	ls.markMissingOffline(ctx, files)
+	ls.deleteExpiredOffline(ctx)
+
 	logrus.Infof("Completed scan of library %s", ls.library.Name)
 	return nil
 }
 
-// scanPath scans a specific path for assets
-func (ls *LibraryScanner) scanPath(ctx context.Context, path string, forceRefresh bool) error {
-	return filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+// markMissingOffline marks the assets whose file no longer exists as
+// offline. Files the walk skipped, e.g. under an import path that could not
+// be read, are checked one by one, so they only go offline once they are
+// really gone.
+func (ls *LibraryScanner) markMissingOffline(ctx context.Context, files *libraryFiles) {
+	marked := 0
+	for _, asset := range files.missing() {
+		if _, err := os.Stat(asset.OriginalPath); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		rows, err := ls.db.SetAssetOffline(ctx, asset.ID)
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to mark asset offline: %s", asset.OriginalPath)
+			continue
+		}
+		marked += int(rows)
+	}
+	if marked > 0 {
+		logrus.Infof("Marked %d assets of library %s offline", marked, ls.library.Name)
+	}
+}
+
+// deleteExpiredOffline deletes the assets that have been offline for longer
+// than the retention period.
+func (ls *LibraryScanner) deleteExpiredOffline(ctx context.Context) {
+	if ls.offlineRetention <= 0 {
+		return
+	}
+	deleted, err := ls.db.DeleteExpiredOfflineAssets(ctx, sqlc.DeleteExpiredOfflineAssetsParams{
+		LibraryId:     pgutil.UUIDToPgtype(ls.library.ID),
+		OfflineBefore: pgtype.Timestamptz{Time: time.Now().Add(-ls.offlineRetention), Valid: true},
+	})
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to delete offline assets of library %s", ls.library.Name)
+		return
+	}
+	if deleted > 0 {
+		logrus.Infof("Deleted %d assets of library %s offline for over %s", deleted, ls.library.Name, ls.offlineRetention)
+	}
+}
+
+// scanPath scans an import path for assets
+func (ls *LibraryScanner) scanPath(ctx context.Context, root string, files *libraryFiles, forceRefresh bool) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		// Check if stopped
 		select {
 		case <-ls.stopCh:
@@ -485,19 +717,45 @@ func (ls *LibraryScanner) scanPath(ctx context.Context, path string, forceRefres
 			return nil
 		}
 
+		files.seen[path] = true
+
 		// Check if asset already exists (by path)
-		result, err := ls.db.CheckAssetExistsByPath(ctx, path)
+		asset, known := files.byPath[path]
+		if known && !asset.IsOffline && !forceRefresh {
+			return nil
+		}
+		if !known {
+			result, err := ls.db.CheckAssetExistsByPath(ctx, path)
+			if err != nil {
+				logrus.WithError(err).Errorf("Failed to check if asset exists: %s", path)
+				return nil // Continue with other files even if this check fails
+			}
+			if result && !forceRefresh {
+				return nil
+			}
+		}
+
+		checksum, err := ls.calculateChecksum(path)
 		if err != nil {
-			logrus.WithError(err).Errorf("Failed to check if asset exists: %s", path)
-			return nil // Continue with other files even if this check fails
+			logrus.WithError(err).Errorf("Failed to calculate checksum: %s", path)
+			return nil
 		}
 
-		if result && !forceRefresh {
+		// A file that went missing and came back keeps its asset
+		if offline, ok := files.takeOffline(checksum.Stored(), path); ok {
+			if _, err := ls.db.RestoreOfflineAsset(ctx, sqlc.RestoreOfflineAssetParams{
+				ID:           offline.ID,
+				OriginalPath: path,
+			}); err != nil {
+				logrus.WithError(err).Errorf("Failed to restore offline asset: %s", path)
+			} else {
+				logrus.Debugf("Restored offline asset: %s", path)
+			}
 			return nil
 		}
 
 		// Import the asset
-		if err := ls.importAsset(ctx, path); err != nil {
+		if err := ls.importAsset(ctx, root, path, checksum); err != nil {
 			logrus.WithError(err).Errorf("Failed to import asset: %s", path)
 			// Continue with other files even if this import fails
 		}
@@ -506,20 +764,15 @@ func (ls *LibraryScanner) scanPath(ctx context.Context, path string, forceRefres
 	})
 }
 
-// importAsset creates an asset record in the database for the given file
-func (ls *LibraryScanner) importAsset(ctx context.Context, filePath string) error {
+// importAsset creates an asset record in the database for the given file,
+// found under the import path root
+func (ls *LibraryScanner) importAsset(ctx context.Context, root, filePath string, checksum assets.Checksum) error {
 	// Get file info
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	// Calculate file checksum
-	checksum, err := ls.calculateChecksum(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to calculate checksum: %w", err)
-	}
-
 	// Determine asset type based on file extension
 	assetType := ls.getAssetType(filePath)
 
@@ -527,45 +780,60 @@ func (ls *LibraryScanner) importAsset(ctx context.Context, filePath string) erro
 	modTime := fileInfo.ModTime()
 	modTimePg := pgtype.Timestamptz{Time: modTime, Valid: !modTime.IsZero()}
 
+	// A Google Takeout sidecar knows when the photo was taken, the file
+	// only when it was exported
+	createdAt := modTime
+	sidecar := ls.takeoutSidecar(filePath)
+	if sidecar != nil {
+		if takenAt, ok := sidecar.TakenAt(); ok {
+			createdAt = takenAt
+		}
+	}
+
 	// Create asset record in database
-	_, err = ls.db.CreateLibraryAsset(ctx, sqlc.CreateLibraryAssetParams{
-		DeviceAssetId:    filepath.Base(filePath),
-		OwnerId:          pgutil.UUIDToPgtype(ls.library.OwnerID),
-		LibraryId:        pgutil.UUIDToPgtype(ls.library.ID),
-		DeviceId:         "library-scanner",
-		Type:             assetType,
-		OriginalPath:     filePath,
-		FileCreatedAt:    modTimePg,
-		FileModifiedAt:   modTimePg,
-		LocalDateTime:    modTimePg,
-		OriginalFileName: filepath.Base(filePath),
-		Checksum:         checksum,
-		IsFavorite:       false,
-		Visibility:       sqlc.AssetVisibilityEnumTimeline,
-		Status:           sqlc.AssetsStatusEnumActive,
+	asset, err := ls.db.CreateLibraryAsset(ctx, sqlc.CreateLibraryAssetParams{
+		DeviceAssetId:     filepath.Base(filePath),
+		OwnerId:           pgutil.UUIDToPgtype(ls.library.OwnerID),
+		LibraryId:         pgutil.UUIDToPgtype(ls.library.ID),
+		DeviceId:          "library-scanner",
+		Type:              assetType,
+		OriginalPath:      filePath,
+		FileCreatedAt:     pgtype.Timestamptz{Time: createdAt, Valid: !createdAt.IsZero()},
+		FileModifiedAt:    modTimePg,
+		LocalDateTime:     pgtype.Timestamptz{Time: assets.WallClock(createdAt, ls.location), Valid: !createdAt.IsZero()},
+		OriginalFileName:  filepath.Base(filePath),
+		Checksum:          checksum.Stored(),
+		IsFavorite:        sidecar != nil && sidecar.Favorited,
+		Visibility:        sqlc.AssetVisibilityEnumTimeline,
+		Status:            sqlc.AssetsStatusEnumActive,
+		ChecksumAlgorithm: string(checksum.Algorithm),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create asset record: %w", err)
 	}
+	if sidecar != nil {
+		if err := ls.recordTakeoutSidecar(ctx, asset.ID, fileInfo.Size(), sidecar); err != nil {
+			logrus.WithError(err).Warnf("Failed to import sidecar of %s", filePath)
+		}
+	}
+	if err := ls.addToFolderAlbum(ctx, root, filePath, asset.ID); err != nil {
+		logrus.WithError(err).Warnf("Failed to add %s to its folder album", filePath)
+	}
 
 	logrus.Debugf("Imported asset: %s", filePath)
 	return nil
 }
 
-// calculateChecksum calculates the SHA-256 checksum of a file
-func (ls *LibraryScanner) calculateChecksum(filePath string) ([]byte, error) {
+// calculateChecksum calculates the checksum of a file with the algorithm
+// clients use, so imported assets deduplicate against uploads
+func (ls *LibraryScanner) calculateChecksum(filePath string) (assets.Checksum, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return assets.Checksum{}, err
 	}
 	defer file.Close()
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return nil, err
-	}
-
-	return hash.Sum(nil), nil
+	return assets.ComputeChecksum(file, assets.DefaultChecksumAlgorithm)
 }
 
 // getAssetType determines the asset type based on file extension
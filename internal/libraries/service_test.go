@@ -5,9 +5,15 @@ package libraries
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/util"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -272,7 +278,7 @@ func TestIntegration_DeleteLibrary(t *testing.T) {
 	assert.Len(t, libs, 1)
 
 	// Delete the library
-	err = service.DeleteLibrary(ctx, userID, created.ID)
+	err = service.DeleteLibrary(ctx, userID, created.ID, AssetDispositionTrash)
 	require.NoError(t, err)
 
 	// Verify library is deleted
@@ -281,6 +287,67 @@ func TestIntegration_DeleteLibrary(t *testing.T) {
 	assert.Empty(t, libs)
 }
 
+// recordingDeletionQueue records the library deletions queued with it.
+type recordingDeletionQueue struct {
+	queued map[uuid.UUID]AssetDisposition
+}
+
+func (q *recordingDeletionQueue) EnqueueLibraryDeletion(_ context.Context, libraryID uuid.UUID, disposition AssetDisposition) error {
+	q.queued[libraryID] = disposition
+	return nil
+}
+
+func TestIntegration_DeleteLibrary_QueuesAssetDisposition(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	userID := createTestUser(t, tdb, "delete-assets@test.com")
+	created, err := service.CreateLibrary(ctx, userID, CreateLibraryRequest{
+		Name:        "With Assets",
+		ImportPaths: []string{"/photos"},
+	})
+	require.NoError(t, err)
+	_, err = tdb.Queries.CreateLibraryAsset(ctx, sqlc.CreateLibraryAssetParams{
+		DeviceAssetId:    "photo.jpg",
+		OwnerId:          pgutil.UUIDToPgtype(userID),
+		LibraryId:        pgutil.UUIDToPgtype(created.ID),
+		DeviceId:         "library-scanner",
+		Type:             "IMAGE",
+		OriginalPath:     "/photos/photo.jpg",
+		OriginalFileName: "photo.jpg",
+		Checksum:         []byte("photo"),
+		Visibility:       sqlc.AssetVisibilityEnumTimeline,
+		Status:           sqlc.AssetsStatusEnumActive,
+	})
+	require.NoError(t, err)
+
+	// Without a queue the assets could not be handled, so nothing is deleted
+	err = service.DeleteLibrary(ctx, userID, created.ID, AssetDispositionKeep)
+	assert.ErrorIs(t, err, ErrDeletionQueueUnavailable)
+	_, err = service.GetLibrary(ctx, userID, created.ID)
+	require.NoError(t, err)
+
+	err = service.DeleteLibrary(ctx, userID, created.ID, "archive")
+	assert.ErrorIs(t, err, ErrInvalidAssetDisposition)
+
+	queue := &recordingDeletionQueue{queued: map[uuid.UUID]AssetDisposition{}}
+	service.SetDeletionQueue(queue)
+	require.NoError(t, service.DeleteLibrary(ctx, userID, created.ID, ""))
+	assert.Equal(t, AssetDispositionTrash, queue.queued[created.ID], "assets are trashed by default")
+	_, err = service.GetLibrary(ctx, userID, created.ID)
+	assert.Error(t, err)
+
+	// Deleting again queues the disposition again
+	require.NoError(t, service.DeleteLibrary(ctx, userID, created.ID, AssetDispositionDelete))
+	assert.Equal(t, AssetDispositionDelete, queue.queued[created.ID])
+
+	err = service.DeleteLibrary(ctx, userID, uuid.New(), AssetDispositionTrash)
+	assert.ErrorIs(t, err, ErrLibraryNotFound)
+}
+
 func TestIntegration_GetLibraryStatistics(t *testing.T) {
 	testdb.SkipIfNoDocker(t)
 
@@ -303,9 +370,32 @@ func TestIntegration_GetLibraryStatistics(t *testing.T) {
 	// Get statistics (should be empty initially)
 	stats, err := service.GetLibraryStatistics(ctx, userID, created.ID)
 	require.NoError(t, err)
-	assert.NotNil(t, stats)
-	// Initially no assets
-	assert.GreaterOrEqual(t, stats.Photos, int64(0))
+	assert.Equal(t, &LibraryStatistics{}, stats)
+
+	// Two photos, a video and a trashed photo that must not be counted
+	addAsset := func(name, assetType, status string, size int64) {
+		assetID := tdb.CreateTestAsset(t, userID, name)
+		_, err := tdb.Pool.Exec(ctx, `UPDATE assets SET "libraryId" = $2, type = $3, status = $4 WHERE id = $1`,
+			assetID, created.ID, assetType, status)
+		require.NoError(t, err)
+		_, err = tdb.Pool.Exec(ctx, `INSERT INTO exif ("assetId", "fileSizeInByte") VALUES ($1, $2)`, assetID, size)
+		require.NoError(t, err)
+	}
+	addAsset("photo-1", "IMAGE", "active", 100)
+	addAsset("photo-2", "IMAGE", "active", 200)
+	addAsset("video-1", "VIDEO", "active", 700)
+	addAsset("trashed-1", "IMAGE", "trashed", 5000)
+
+	_, err = tdb.Pool.Exec(ctx, `UPDATE users SET "quotaSizeInBytes" = 4000 WHERE id = $1`, userID)
+	require.NoError(t, err)
+
+	stats, err = service.GetLibraryStatistics(ctx, userID, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.AssetCount)
+	assert.Equal(t, int64(2), stats.Photos)
+	assert.Equal(t, int64(1), stats.Videos)
+	assert.Equal(t, int64(1000), stats.TotalSize)
+	assert.InDelta(t, 0.25, stats.Usage, 0.0001)
 }
 
 func TestIntegration_ValidateImportPath(t *testing.T) {
@@ -367,7 +457,7 @@ func TestIntegration_LibraryLifecycle(t *testing.T) {
 	assert.Equal(t, "Updated Lifecycle", lib.Name)
 
 	// Delete library
-	err = service.DeleteLibrary(ctx, userID, created.ID)
+	err = service.DeleteLibrary(ctx, userID, created.ID, AssetDispositionTrash)
 	require.NoError(t, err)
 
 	// Should have no libraries
@@ -407,7 +497,7 @@ func TestIntegration_MultipleLibraries(t *testing.T) {
 	assert.Len(t, libs, 3)
 
 	// Delete middle library
-	err = service.DeleteLibrary(ctx, userID, libraryIDs[1])
+	err = service.DeleteLibrary(ctx, userID, libraryIDs[1], AssetDispositionTrash)
 	require.NoError(t, err)
 
 	// Should have 2 libraries
@@ -424,3 +514,181 @@ func TestIntegration_MultipleLibraries(t *testing.T) {
 	assert.True(t, names["Mixed"])
 	assert.False(t, names["Videos"])
 }
+
+func TestIntegration_ScanMarksMissingFilesOffline(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	userID := createTestUser(t, tdb, "offline@test.com")
+
+	dir := t.TempDir()
+	original := filepath.Join(dir, "beach.jpg")
+	require.NoError(t, os.WriteFile(original, []byte("beach photo"), 0o600))
+
+	library, err := service.CreateLibrary(ctx, userID, CreateLibraryRequest{
+		Name:        "External",
+		ImportPaths: []string{dir},
+	})
+	require.NoError(t, err)
+
+	scan := func(retention time.Duration) sqlc.GetLibraryAssetFilesRow {
+		t.Helper()
+		scanner := NewLibraryScanner(library, tdb.Queries, nil, time.UTC, retention)
+		require.NoError(t, scanner.Scan(ctx, false))
+		files, err := tdb.Queries.GetLibraryAssetFiles(ctx, pgutil.UUIDToPgtype(library.ID))
+		require.NoError(t, err)
+		if len(files) == 0 {
+			return sqlc.GetLibraryAssetFilesRow{}
+		}
+		require.Len(t, files, 1)
+		return files[0]
+	}
+	listed := func(isOffline *bool) int64 {
+		t.Helper()
+		count, err := tdb.Queries.CountAssets(ctx, sqlc.CountAssetsParams{
+			OwnerId:   pgutil.UUIDToPgtype(userID),
+			IsOffline: util.OptionalBool(isOffline),
+		})
+		require.NoError(t, err)
+		return count
+	}
+	offline := true
+
+	imported := scan(0)
+	assert.False(t, imported.IsOffline)
+	assert.Equal(t, int64(1), listed(nil))
+
+	// The file disappears: the asset goes offline and out of listings
+	require.NoError(t, os.Remove(original))
+	gone := scan(0)
+	assert.Equal(t, imported.ID, gone.ID)
+	assert.True(t, gone.IsOffline)
+	assert.Equal(t, int64(0), listed(nil))
+	assert.Equal(t, int64(1), listed(&offline))
+
+	// The same file shows up under another name: the asset is restored
+	moved := filepath.Join(dir, "moved.jpg")
+	require.NoError(t, os.WriteFile(moved, []byte("beach photo"), 0o600))
+	restored := scan(0)
+	assert.Equal(t, imported.ID, restored.ID)
+	assert.False(t, restored.IsOffline)
+	assert.Equal(t, moved, restored.OriginalPath)
+	assert.Equal(t, int64(1), listed(nil))
+
+	// Offline past the retention period, the asset is deleted
+	require.NoError(t, os.Remove(moved))
+	assert.True(t, scan(0).IsOffline)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, scan(time.Millisecond).ID.Valid)
+}
+
+func TestIntegration_ScanImportsTakeoutSidecars(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	userID := createTestUser(t, tdb, "takeout@test.com")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "IMG_0001.jpg"), []byte("lake photo"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "IMG_0001.jpg.supplemental-metadata.json"), []byte(`{
+		"title": "IMG_0001.jpg",
+		"description": "Lake at dawn",
+		"photoTakenTime": {"timestamp": "1600000000"},
+		"geoData": {"latitude": 46.0, "longitude": 8.95},
+		"favorited": true
+	}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(`{"title": "Holidays"}`), 0o600))
+
+	library, err := service.CreateLibrary(ctx, userID, CreateLibraryRequest{
+		Name:        "Takeout",
+		ImportPaths: []string{dir},
+	})
+	require.NoError(t, err)
+
+	scanner := NewLibraryScanner(library, tdb.Queries, nil, time.UTC, 0)
+	require.NoError(t, scanner.Scan(ctx, false))
+
+	files, err := tdb.Queries.GetLibraryAssetFiles(ctx, pgutil.UUIDToPgtype(library.ID))
+	require.NoError(t, err)
+	require.Len(t, files, 1, "sidecars are not imported as assets")
+
+	asset, err := tdb.Queries.GetAssetByID(ctx, files[0].ID)
+	require.NoError(t, err)
+	assert.True(t, asset.FileCreatedAt.Time.Equal(time.Unix(1600000000, 0)))
+	assert.True(t, asset.IsFavorite)
+
+	exif, err := tdb.Queries.GetExifByAssetId(ctx, asset.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Lake at dawn", exif.Description)
+	assert.Equal(t, 46.0, exif.Latitude.Float64)
+	assert.Equal(t, 8.95, exif.Longitude.Float64)
+	assert.True(t, exif.DateTimeOriginal.Time.Equal(time.Unix(1600000000, 0)))
+}
+
+func TestIntegration_ScanCreatesFolderAlbums(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	userID := createTestUser(t, tdb, "folders@test.com")
+
+	dir := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o700))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o600))
+	}
+	writeFile("loose.jpg", "loose photo")
+	writeFile("2023/Italy Trip/IMG_0001.jpg", "rome")
+	writeFile("2023/Italy Trip/Day 2/IMG_0002.jpg", "florence")
+	writeFile("2023/Ski/IMG_0003.jpg", "snow")
+
+	library, err := service.CreateLibrary(ctx, userID, CreateLibraryRequest{
+		Name:         "Folders",
+		ImportPaths:  []string{dir},
+		FolderAlbums: &FolderAlbums{Enabled: true, Depth: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, FolderAlbumNamingFolder, library.FolderAlbums.Naming)
+
+	albumAssets := func() map[string]int {
+		t.Helper()
+		albums, err := tdb.Queries.GetAlbumsByOwner(ctx, pgutil.UUIDToPgtype(userID))
+		require.NoError(t, err)
+		counts := make(map[string]int, len(albums))
+		for _, album := range albums {
+			assets, err := tdb.Queries.GetAlbumAssets(ctx, album.ID)
+			require.NoError(t, err)
+			counts[album.AlbumName] += len(assets)
+		}
+		return counts
+	}
+
+	require.NoError(t, NewLibraryScanner(library, tdb.Queries, nil, time.UTC, 0).Scan(ctx, false))
+	assert.Equal(t, map[string]int{"Italy Trip": 2, "Ski": 1}, albumAssets(),
+		"files below the depth join their folder's album, loose files none")
+
+	// A rescan adds new files to the existing albums
+	writeFile("2023/Ski/IMG_0004.jpg", "more snow")
+	library, err = service.GetLibrary(ctx, userID, library.ID)
+	require.NoError(t, err)
+	require.NoError(t, NewLibraryScanner(library, tdb.Queries, nil, time.UTC, 0).Scan(ctx, false))
+	assert.Equal(t, map[string]int{"Italy Trip": 2, "Ski": 2}, albumAssets())
+
+	// Turned off, new files join no album
+	library, err = service.UpdateLibrary(ctx, userID, library.ID, &UpdateLibraryRequest{FolderAlbums: &FolderAlbums{}})
+	require.NoError(t, err)
+	assert.False(t, library.FolderAlbums.Enabled)
+	writeFile("2024/IMG_0005.jpg", "new year")
+	require.NoError(t, NewLibraryScanner(library, tdb.Queries, nil, time.UTC, 0).Scan(ctx, false))
+	assert.Equal(t, map[string]int{"Italy Trip": 2, "Ski": 2}, albumAssets())
+}
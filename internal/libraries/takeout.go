@@ -0,0 +1,91 @@
+package libraries
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// sidecarDir holds the JSON file names of the directory the scan is in, so
+// pairing its media files with their Takeout sidecars lists it only once.
+type sidecarDir struct {
+	path  string
+	names []string
+}
+
+// jsonNames returns the names of the JSON files in dir.
+func (d *sidecarDir) jsonNames(dir string) []string {
+	if d.path == dir {
+		return d.names
+	}
+	d.path, d.names = dir, nil
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to list sidecars in %s", dir)
+		return nil
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			d.names = append(d.names, entry.Name())
+		}
+	}
+	return d.names
+}
+
+// takeoutSidecar returns the Google Takeout sidecar next to filePath, if it
+// has one.
+func (ls *LibraryScanner) takeoutSidecar(filePath string) *assets.TakeoutSidecar {
+	dir := filepath.Dir(filePath)
+	name, ok := assets.MatchTakeoutSidecar(filepath.Base(filePath), ls.sidecars.jsonNames(dir))
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to read sidecar of %s", filePath)
+		return nil
+	}
+	sidecar, err := assets.ParseTakeoutSidecar(data)
+	if err != nil {
+		logrus.Debugf("Ignoring sidecar %s of %s: %v", name, filePath, err)
+		return nil
+	}
+	return sidecar
+}
+
+// recordTakeoutSidecar keeps the sidecar of an imported asset and records
+// its metadata, as library assets have no other source of it.
+func (ls *LibraryScanner) recordTakeoutSidecar(ctx context.Context, assetID pgtype.UUID, fileSize int64, sidecar *assets.TakeoutSidecar) error {
+	if err := assets.StoreTakeoutSidecar(ctx, ls.db, assetID, sidecar); err != nil {
+		return err
+	}
+
+	var metadata assets.AssetMetadata
+	sidecar.Apply(&metadata)
+	exif := sqlc.CreateOrUpdateExifParams{
+		AssetId:        assetID,
+		FileSizeInByte: pgtype.Int8{Int64: fileSize, Valid: true},
+	}
+	if metadata.DateTaken != nil {
+		exif.DateTimeOriginal = pgtype.Timestamptz{Time: *metadata.DateTaken, Valid: true}
+	}
+	if metadata.Latitude != nil && metadata.Longitude != nil {
+		exif.Latitude = pgtype.Float8{Float64: *metadata.Latitude, Valid: true}
+		exif.Longitude = pgtype.Float8{Float64: *metadata.Longitude, Valid: true}
+	}
+	if metadata.Description != nil {
+		exif.Description = *metadata.Description
+	}
+	if _, err := ls.db.CreateOrUpdateExif(ctx, exif); err != nil {
+		return fmt.Errorf("failed to record sidecar metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package libraries
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ValidateLibrary checks that each import path is a directory the server can
+// read.
+func (s *Service) ValidateLibrary(ctx context.Context, req ValidateLibraryRequest) (*ValidateLibraryResponse, error) {
+	results := make([]PathValidation, 0, len(req.ImportPaths))
+	for _, path := range req.ImportPaths {
+		results = append(results, validateImportPath(path))
+	}
+	return &ValidateLibraryResponse{Results: results}, nil
+}
+
+// validateImportPath reports whether path can be scanned. Only a directory
+// whose entries can be listed is valid.
+func validateImportPath(path string) PathValidation {
+	validation := PathValidation{Path: path}
+
+	if path == "" {
+		validation.Message = "Path cannot be empty"
+		return validation
+	}
+	if !filepath.IsAbs(path) {
+		validation.Message = "Path must be absolute"
+		return validation
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		validation.Message = "Path does not exist"
+		return validation
+	case errors.Is(err, fs.ErrPermission):
+		validation.Message = "Path cannot be accessed"
+		return validation
+	case err != nil:
+		validation.Message = "Path cannot be accessed: " + err.Error()
+		return validation
+	}
+	if !info.IsDir() {
+		validation.Message = "Path is not a directory"
+		return validation
+	}
+
+	if err := readDir(path); err != nil {
+		validation.Message = "Path exists but is not readable"
+		return validation
+	}
+	validation.IsValid = true
+	validation.IsReadable = true
+	validation.Message = "Path is valid and readable"
+	return validation
+}
+
+// readDir lists the first entry of the directory at path, which fails when
+// it cannot be read even though it can be stat'ed.
+func readDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if _, err := dir.ReadDir(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package libraries
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateImportPath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "photo.jpg")
+	require.NoError(t, os.WriteFile(file, []byte("photo"), 0o600))
+
+	tests := []struct {
+		name    string
+		path    string
+		message string
+	}{
+		{name: "empty", path: "", message: "Path cannot be empty"},
+		{name: "relative", path: "photos", message: "Path must be absolute"},
+		{name: "missing", path: filepath.Join(dir, "missing"), message: "Path does not exist"},
+		{name: "file", path: file, message: "Path is not a directory"},
+		{name: "glob pattern", path: filepath.Join(dir, "*"), message: "Path does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := validateImportPath(tt.path)
+			assert.Equal(t, tt.path, validation.Path)
+			assert.False(t, validation.IsValid)
+			assert.False(t, validation.IsReadable)
+			assert.Equal(t, tt.message, validation.Message)
+		})
+	}
+
+	validation := validateImportPath(dir)
+	assert.True(t, validation.IsValid)
+	assert.True(t, validation.IsReadable)
+}
+
+func TestValidateImportPath_PermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+
+	dir := t.TempDir()
+	unreadable := filepath.Join(dir, "unreadable")
+	require.NoError(t, os.Mkdir(unreadable, 0o300))
+	inaccessible := filepath.Join(dir, "inaccessible")
+	require.NoError(t, os.MkdirAll(filepath.Join(inaccessible, "photos"), 0o700))
+	require.NoError(t, os.Chmod(inaccessible, 0o600))
+	t.Cleanup(func() {
+		_ = os.Chmod(unreadable, 0o700)
+		_ = os.Chmod(inaccessible, 0o700)
+	})
+
+	validation := validateImportPath(unreadable)
+	assert.False(t, validation.IsValid)
+	assert.False(t, validation.IsReadable)
+	assert.Equal(t, "Path exists but is not readable", validation.Message)
+
+	validation = validateImportPath(filepath.Join(inaccessible, "photos"))
+	assert.False(t, validation.IsValid)
+	assert.Equal(t, "Path cannot be accessed", validation.Message)
+}
+
+func TestValidateLibrary_KeepsPathOrder(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "missing"), dir, filepath.Join(dir, "missing")}
+
+	response, err := (&Service{}).ValidateLibrary(context.Background(), ValidateLibraryRequest{ImportPaths: paths})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 3)
+	for i, result := range response.Results {
+		assert.Equal(t, paths[i], result.Path)
+	}
+	assert.False(t, response.Results[0].IsValid)
+	assert.True(t, response.Results[1].IsValid)
+}
@@ -29,15 +29,9 @@ func NewServer(queries *sqlc.Queries) *Server {
 // GetMapMarkers gets map markers for assets with location data
 func (s *Server) GetMapMarkers(ctx context.Context, request *immichv1.GetMapMarkersRequest) (*immichv1.GetMapMarkersResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
 
@@ -131,15 +125,9 @@ func (s *Server) GetMapMarkers(ctx context.Context, request *immichv1.GetMapMark
 // ReverseGeocode converts coordinates to location information
 func (s *Server) ReverseGeocode(ctx context.Context, request *immichv1.ReverseGeocodeRequest) (*immichv1.ReverseGeocodeResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
 
@@ -2,6 +2,7 @@ package memories
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -88,19 +89,20 @@ func (s *Server) assetsForMemoryIDs(ctx context.Context, memoryIDs []string) (ma
 // intentionally a lighter conversion than the full asset viewer.
 func (s *Server) convertAssetToProto(asset sqlc.Asset) *immichv1.Asset {
 	proto := &immichv1.Asset{
-		Id:               uuid.UUID(asset.ID.Bytes).String(),
-		DeviceAssetId:    asset.DeviceAssetId,
-		OwnerId:          uuid.UUID(asset.OwnerId.Bytes).String(),
-		DeviceId:         asset.DeviceId,
-		Type:             assetdomain.AssetTypeFromString(asset.Type),
-		OriginalPath:     asset.OriginalPath,
-		OriginalFileName: asset.OriginalFileName,
-		CreatedAt:        timestamppb.New(asset.CreatedAt.Time),
-		UpdatedAt:        timestamppb.New(asset.UpdatedAt.Time),
-		IsFavorite:       asset.IsFavorite,
-		IsArchived:       asset.Visibility == sqlc.AssetVisibilityEnumArchive,
-		IsTrashed:        asset.Status == sqlc.AssetsStatusEnumTrashed,
-		Checksum:         fmt.Sprintf("%x", asset.Checksum),
+		Id:                uuid.UUID(asset.ID.Bytes).String(),
+		DeviceAssetId:     asset.DeviceAssetId,
+		OwnerId:           uuid.UUID(asset.OwnerId.Bytes).String(),
+		DeviceId:          asset.DeviceId,
+		Type:              assetdomain.AssetTypeFromString(asset.Type),
+		OriginalPath:      asset.OriginalPath,
+		OriginalFileName:  asset.OriginalFileName,
+		CreatedAt:         timestamppb.New(asset.CreatedAt.Time),
+		UpdatedAt:         timestamppb.New(asset.UpdatedAt.Time),
+		IsFavorite:        asset.IsFavorite,
+		IsArchived:        asset.Visibility == sqlc.AssetVisibilityEnumArchive,
+		IsTrashed:         asset.Status == sqlc.AssetsStatusEnumTrashed,
+		Checksum:          fmt.Sprintf("%x", asset.Checksum),
+		ChecksumAlgorithm: asset.ChecksumAlgorithm,
 	}
 
 	if asset.Duration.Valid {
@@ -117,21 +119,59 @@ func (s *Server) convertAssetToProto(asset sqlc.Asset) *immichv1.Asset {
 		proto.StackParentId = &stackParentID
 	}
 
+	if len(asset.Thumbhash) > 0 {
+		thumbhash := base64.StdEncoding.EncodeToString(asset.Thumbhash)
+		proto.Thumbhash = &thumbhash
+	}
+
 	return proto
 }
 
+// memoryYear returns the year an on this day memory looks back to. Older
+// memories without a stored year fall back to the year of memoryAt.
+func memoryYear(m *Memory) int32 {
+	if m.Year != 0 {
+		return int32(m.Year)
+	}
+	return int32(m.Date.Year())
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
 // SearchMemories returns memories based on search criteria
 func (s *Server) SearchMemories(ctx context.Context, req *immichv1.SearchMemoriesRequest) (*immichv1.SearchMemoriesResponse, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The web client asks for the memories of "now"; generate that local
+	// day's on this day memories first so they are ready to show.
+	if req.ForDate != nil {
+		if err := s.service.GenerateMemories(ctx, userID.String(), req.ForDate.AsTime()); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate memories: %v", err)
+		}
 	}
 
 	// Get memories from service
-	memories, err := s.service.GetMemories(ctx, claims.UserID)
+	memories, err := s.service.GetMemories(ctx, userID.String())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get memories: %v", err)
 	}
+	if req.ForDate != nil {
+		visible := memories[:0]
+		for _, m := range memories {
+			if m.VisibleAt(req.ForDate.AsTime()) {
+				visible = append(visible, m)
+			}
+		}
+		memories = visible
+	}
 
 	// Pre-load assets for all memories in a single batch so the
 	// Memory.assets proto field is populated. Without this the web
@@ -157,6 +197,9 @@ func (s *Server) SearchMemories(ctx context.Context, req *immichv1.SearchMemorie
 			UpdatedAt: timestamppb.New(mem.UpdatedAt),
 			IsSaved:   false,
 			Assets:    assetsByMem[mem.ID],
+			Data:      &immichv1.OnThisDayData{Year: memoryYear(mem)},
+			ShowAt:    optionalTimestamp(mem.ShowAt),
+			HideAt:    optionalTimestamp(mem.HideAt),
 		}
 
 		// Apply filters if provided
@@ -177,13 +220,13 @@ func (s *Server) SearchMemories(ctx context.Context, req *immichv1.SearchMemorie
 
 // CreateMemory creates a new memory
 func (s *Server) CreateMemory(ctx context.Context, req *immichv1.CreateMemoryRequest) (*immichv1.Memory, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	memory := &Memory{
-		UserID:   claims.UserID,
+		UserID:   userID.String(),
 		Title:    "New Memory",
 		Date:     req.MemoryAt.AsTime(),
 		Type:     "on_this_day",
@@ -210,19 +253,19 @@ func (s *Server) CreateMemory(ctx context.Context, req *immichv1.CreateMemoryReq
 		IsSaved:   req.IsSaved != nil && *req.IsSaved,
 		Assets:    assets[created.ID],
 		Data: &immichv1.OnThisDayData{
-			Year: int32(created.Date.Year()),
+			Year: memoryYear(created),
 		},
 	}, nil
 }
 
 // GetMemory gets a memory by ID
 func (s *Server) GetMemory(ctx context.Context, req *immichv1.GetMemoryRequest) (*immichv1.Memory, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	memory, err := s.service.GetMemory(ctx, claims.UserID, req.Id)
+	memory, err := s.service.GetMemory(ctx, userID.String(), req.Id)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "memory not found: %v", err)
 	}
@@ -242,16 +285,16 @@ func (s *Server) GetMemory(ctx context.Context, req *immichv1.GetMemoryRequest)
 		IsSaved:   false,
 		Assets:    assets[memory.ID],
 		Data: &immichv1.OnThisDayData{
-			Year: int32(memory.Date.Year()),
+			Year: memoryYear(memory),
 		},
 	}, nil
 }
 
 // UpdateMemory updates a memory
 func (s *Server) UpdateMemory(ctx context.Context, req *immichv1.UpdateMemoryRequest) (*immichv1.Memory, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	updates := make(map[string]interface{})
@@ -265,7 +308,7 @@ func (s *Server) UpdateMemory(ctx context.Context, req *immichv1.UpdateMemoryReq
 		updates["seen_at"] = req.SeenAt.AsTime()
 	}
 
-	memory, err := s.service.UpdateMemory(ctx, claims.UserID, req.Id, updates)
+	memory, err := s.service.UpdateMemory(ctx, userID.String(), req.Id, updates)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update memory: %v", err)
 	}
@@ -289,12 +332,12 @@ func (s *Server) UpdateMemory(ctx context.Context, req *immichv1.UpdateMemoryReq
 
 // DeleteMemory deletes a memory
 func (s *Server) DeleteMemory(ctx context.Context, req *immichv1.DeleteMemoryRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.service.DeleteMemory(ctx, claims.UserID, req.Id)
+	err = s.service.DeleteMemory(ctx, userID.String(), req.Id)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to delete memory: %v", err)
 	}
@@ -304,13 +347,13 @@ func (s *Server) DeleteMemory(ctx context.Context, req *immichv1.DeleteMemoryReq
 
 // AddMemoryAssets adds assets to a memory
 func (s *Server) AddMemoryAssets(ctx context.Context, req *immichv1.AddMemoryAssetsRequest) (*immichv1.BulkIdResponseList, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	assetIDs := req.BulkIds.Ids
-	err := s.service.AddAssetsToMemory(ctx, claims.UserID, req.Id, assetIDs)
+	err = s.service.AddAssetsToMemory(ctx, userID.String(), req.Id, assetIDs)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to add assets: %v", err)
 	}
@@ -331,13 +374,13 @@ func (s *Server) AddMemoryAssets(ctx context.Context, req *immichv1.AddMemoryAss
 
 // RemoveMemoryAssets removes assets from a memory
 func (s *Server) RemoveMemoryAssets(ctx context.Context, req *immichv1.RemoveMemoryAssetsRequest) (*immichv1.BulkIdResponseList, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	assetIDs := req.BulkIds.Ids
-	err := s.service.RemoveAssetsFromMemory(ctx, claims.UserID, req.Id, assetIDs)
+	err = s.service.RemoveAssetsFromMemory(ctx, userID.String(), req.Id, assetIDs)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to remove assets: %v", err)
 	}
@@ -358,12 +401,12 @@ func (s *Server) RemoveMemoryAssets(ctx context.Context, req *immichv1.RemoveMem
 
 // MemoriesStatistics returns aggregate statistics about the current user's memories.
 func (s *Server) MemoriesStatistics(ctx context.Context, _ *emptypb.Empty) (*immichv1.MemoryStatisticsResponse, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	total, err := s.service.CountMemories(ctx, claims.UserID)
+	total, err := s.service.CountMemories(ctx, userID.String())
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to count memories: %v", err)
 	}
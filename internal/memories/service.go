@@ -4,20 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
+	assetdomain "github.com/denysvitali/immich-go-backend/internal/assets"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// memoryTypeOnThisDay is the type of memories generated from assets taken
+// on the same local day in earlier years.
+const memoryTypeOnThisDay = "on_this_day"
+
 type Service struct {
-	queries *sqlc.Queries
+	queries  *sqlc.Queries
+	location *time.Location
 }
 
-func NewService(queries *sqlc.Queries) *Service {
+// NewService creates the memories service. location is the timezone "on this
+// day" memories are computed in; nil means UTC.
+func NewService(queries *sqlc.Queries, location *time.Location) *Service {
+	if location == nil {
+		location = time.UTC
+	}
 	return &Service{
-		queries: queries,
+		queries:  queries,
+		location: location,
 	}
 }
 
@@ -31,6 +44,11 @@ type Memory struct {
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
 	Type        string    `json:"type"` // "on_this_day", "year_ago", "custom"
+	// Year the memory looks back to, for "on_this_day" memories
+	Year int `json:"year,omitempty"`
+	// Window in which the memory is shown; nil when it is always shown
+	ShowAt *time.Time `json:"showAt,omitempty"`
+	HideAt *time.Time `json:"hideAt,omitempty"`
 }
 
 func (s *Service) GetMemories(ctx context.Context, userID string) ([]*Memory, error) {
@@ -83,6 +101,7 @@ func (s *Service) hydrateMemory(ctx context.Context, dbMem sqlc.Memory, userID s
 
 	// Extract description
 	description, _ := data["description"].(string)
+	year, _ := data["year"].(float64)
 
 	// Look up the asset IDs linked to this memory so callers can fetch
 	// the full asset rows. Failures here are non-fatal: a memory without
@@ -105,12 +124,34 @@ func (s *Service) hydrateMemory(ctx context.Context, dbMem sqlc.Memory, userID s
 		Description: description,
 		Date:        dbMem.MemoryAt.Time,
 		Type:        memoryType,
+		Year:        int(year),
 		AssetIDs:    assetIDs,
 		CreatedAt:   dbMem.CreatedAt.Time,
 		UpdatedAt:   dbMem.UpdatedAt.Time,
+		ShowAt:      optionalTime(dbMem.ShowAt),
+		HideAt:      optionalTime(dbMem.HideAt),
 	}, nil
 }
 
+func optionalTime(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
+
+// VisibleAt reports whether the memory should be shown at the given instant.
+func (m *Memory) VisibleAt(at time.Time) bool {
+	if m.ShowAt != nil && at.Before(*m.ShowAt) {
+		return false
+	}
+	if m.HideAt != nil && !at.Before(*m.HideAt) {
+		return false
+	}
+	return true
+}
+
 func (s *Service) GetMemory(ctx context.Context, userID string, memoryID string) (*Memory, error) {
 	// Parse memory ID
 	memUUID, err := uuid.Parse(memoryID)
@@ -143,6 +184,7 @@ func (s *Service) GetMemory(ctx context.Context, userID string, memoryID string)
 	}
 
 	description, _ := data["description"].(string)
+	year, _ := data["year"].(float64)
 
 	return &Memory{
 		ID:          memoryID,
@@ -151,9 +193,12 @@ func (s *Service) GetMemory(ctx context.Context, userID string, memoryID string)
 		Description: description,
 		Date:        dbMemory.MemoryAt.Time,
 		Type:        dbMemory.Type,
+		Year:        int(year),
 		AssetIDs:    []string{}, // Would need to query memory_assets table
 		CreatedAt:   dbMemory.CreatedAt.Time,
 		UpdatedAt:   dbMemory.UpdatedAt.Time,
+		ShowAt:      optionalTime(dbMemory.ShowAt),
+		HideAt:      optionalTime(dbMemory.HideAt),
 	}, nil
 }
 
@@ -171,23 +216,35 @@ func (s *Service) CreateMemory(ctx context.Context, memory *Memory) (*Memory, er
 		"title":       memory.Title,
 		"description": memory.Description,
 	}
+	if memory.Year != 0 {
+		data["year"] = memory.Year
+	}
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
+	memoryAt := memory.Date
+	if memoryAt.IsZero() {
+		memoryAt = time.Now()
+	}
+
 	// Create memory in database
 	dbMemory, err := s.queries.CreateMemory(ctx, sqlc.CreateMemoryParams{
-		OwnerId: pgUserUUID,
-		Type:    memory.Type,
-		Data:    jsonData,
+		OwnerId:  pgUserUUID,
+		Type:     memory.Type,
+		Data:     jsonData,
+		MemoryAt: pgtype.Timestamptz{Time: memoryAt, Valid: true},
+		ShowAt:   timestamptz(memory.ShowAt),
+		HideAt:   timestamptz(memory.HideAt),
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	memory.ID = uuid.UUID(dbMemory.ID.Bytes).String()
+	memory.Date = dbMemory.MemoryAt.Time
 	memory.CreatedAt = dbMemory.CreatedAt.Time
 	memory.UpdatedAt = dbMemory.UpdatedAt.Time
 
@@ -410,12 +467,103 @@ func (s *Service) GetMemoryAssets(ctx context.Context, userID string, memoryID s
 	return assetIDs, nil
 }
 
-func (s *Service) GenerateMemories(ctx context.Context, userID string) error {
-	// Generate memories based on date patterns
-	// This would typically run as a background job analyzing user's assets
-	// and creating memories for special dates, trips, etc.
-	// Implementation requires job queue system (not yet available)
-	return fmt.Errorf("memory generation requires job queue system implementation")
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+// GenerateMemories creates the "on this day" memories of the local day that
+// contains at: one per earlier year with assets taken on the same month and
+// day. The day is computed in the service location, so each memory is shown
+// from local midnight to the next one even when DST makes that 23 or 25
+// hours. Memories that already exist for the day are left alone.
+func (s *Service) GenerateMemories(ctx context.Context, userID string, at time.Time) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	ownerID := pgtype.UUID{Bytes: uid, Valid: true}
+
+	showAt, hideAt := assetdomain.LocalDayBounds(at, s.location)
+	rows, err := s.queries.GetOnThisDayAssets(ctx, sqlc.GetOnThisDayAssetsParams{
+		OwnerID:    ownerID,
+		Month:      int32(showAt.Month()),
+		Day:        int32(showAt.Day()),
+		BeforeYear: int32(showAt.Year()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load on this day assets: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	existing, err := s.queries.GetMemories(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to load memories: %w", err)
+	}
+	generated := make(map[int]bool)
+	for _, mem := range existing {
+		if mem.Type != memoryTypeOnThisDay || !mem.ShowAt.Valid || !mem.ShowAt.Time.Equal(showAt) {
+			continue
+		}
+		var data struct {
+			Year int `json:"year"`
+		}
+		if err := json.Unmarshal(mem.Data, &data); err == nil {
+			generated[data.Year] = true
+		}
+	}
+
+	byYear := assetsByLocalYear(rows)
+	years := make([]int, 0, len(byYear))
+	for year := range byYear {
+		if !generated[year] {
+			years = append(years, year)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+
+	for _, year := range years {
+		memoryAt := time.Date(year, showAt.Month(), showAt.Day(), 0, 0, 0, 0, s.location)
+		memory, err := s.CreateMemory(ctx, &Memory{
+			UserID: userID,
+			Title:  fmt.Sprintf("%d", year),
+			Date:   memoryAt,
+			Type:   memoryTypeOnThisDay,
+			Year:   year,
+			ShowAt: &showAt,
+			HideAt: &hideAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create memory for %d: %w", year, err)
+		}
+		memUUID, err := uuid.Parse(memory.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.queries.AddAssetsToMemory(ctx, sqlc.AddAssetsToMemoryParams{
+			MemoriesId: pgtype.UUID{Bytes: memUUID, Valid: true},
+			Column2:    byYear[year],
+		}); err != nil {
+			return fmt.Errorf("failed to add assets to memory for %d: %w", year, err)
+		}
+	}
+
+	return nil
+}
+
+// assetsByLocalYear groups on this day assets by the year of their
+// localDateTime, which already holds the capture wall clock.
+func assetsByLocalYear(rows []sqlc.GetOnThisDayAssetsRow) map[int][]pgtype.UUID {
+	byYear := make(map[int][]pgtype.UUID)
+	for _, row := range rows {
+		year := row.LocalDateTime.Time.UTC().Year()
+		byYear[year] = append(byYear[year], row.ID)
+	}
+	return byYear
 }
 
 // CountMemories returns the total number of (non-deleted) memories owned by
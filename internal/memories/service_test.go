@@ -6,9 +6,12 @@ package memories
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,7 +30,7 @@ func TestIntegration_CreateMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user
 	userID := createTestUser(t, tdb, "memory@test.com")
@@ -55,7 +58,7 @@ func TestIntegration_CreateMemory_InvalidUserID(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Try to create memory with invalid user ID
 	memory := &Memory{
@@ -74,7 +77,7 @@ func TestIntegration_GetMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user and memory
 	userID := createTestUser(t, tdb, "getmemory@test.com")
@@ -103,7 +106,7 @@ func TestIntegration_GetMemory_NotOwned(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "owner@test.com")
@@ -130,7 +133,7 @@ func TestIntegration_GetMemories(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user
 	userID := createTestUser(t, tdb, "memories@test.com")
@@ -158,7 +161,7 @@ func TestIntegration_GetMemories_UserIsolation(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "user1@test.com")
@@ -206,7 +209,7 @@ func TestIntegration_GetMemories_PopulatesAssetIDs(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	userID := createTestUser(t, tdb, "assetids@test.com")
 	asset1ID := createTestAsset(t, tdb, userID, "a1")
@@ -268,7 +271,7 @@ func TestIntegration_UpdateMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user and memory
 	userID := createTestUser(t, tdb, "update@test.com")
@@ -300,7 +303,7 @@ func TestIntegration_UpdateMemory_NotOwned(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "updateowner@test.com")
@@ -331,7 +334,7 @@ func TestIntegration_DeleteMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user and memory
 	userID := createTestUser(t, tdb, "delete@test.com")
@@ -360,7 +363,7 @@ func TestIntegration_DeleteMemory_NotOwned(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "deleteowner@test.com")
@@ -387,7 +390,7 @@ func TestIntegration_AddAssetsToMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user, memory, and assets
 	userID := createTestUser(t, tdb, "addassets@test.com")
@@ -422,7 +425,7 @@ func TestIntegration_AddAssetsToMemory_NotOwned(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "assetsowner@test.com")
@@ -451,7 +454,7 @@ func TestIntegration_RemoveAssetsFromMemory(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user, memory, and assets
 	userID := createTestUser(t, tdb, "removeassets@test.com")
@@ -495,7 +498,7 @@ func TestIntegration_GetMemoryAssets_NotOwned(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create two users
 	user1ID := createTestUser(t, tdb, "getassetsowner@test.com")
@@ -523,7 +526,7 @@ func TestIntegration_MemoryTypes(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	// Create user
 	userID := createTestUser(t, tdb, "types@test.com")
@@ -555,7 +558,7 @@ func TestIntegration_InvalidMemoryID(t *testing.T) {
 	tdb := testdb.SetupTestDB(t)
 	ctx := context.Background()
 
-	service := NewService(tdb.Queries)
+	service := NewService(tdb.Queries, time.UTC)
 
 	userID := createTestUser(t, tdb, "invalid@test.com")
 
@@ -575,3 +578,97 @@ func TestIntegration_InvalidMemoryID(t *testing.T) {
 	_, err = service.GetMemoryAssets(ctx, userID.String(), "not-a-valid-uuid")
 	assert.Error(t, err)
 }
+
+func setLocalDateTime(t *testing.T, tdb *testdb.TestDB, assetID uuid.UUID, local time.Time) {
+	t.Helper()
+	require.NoError(t, tdb.Queries.UpdateAssetLocalDateTime(context.Background(), sqlc.UpdateAssetLocalDateTimeParams{
+		ID:            pgtype.UUID{Bytes: assetID, Valid: true},
+		LocalDateTime: pgtype.Timestamptz{Time: local, Valid: true},
+	}))
+}
+
+func TestIntegration_GenerateMemories_InternationalDateLine(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati")
+	require.NoError(t, err)
+	service := NewService(tdb.Queries, kiritimati)
+
+	userID := createTestUser(t, tdb, "dateline@test.com")
+	jan2of2022 := createTestAsset(t, tdb, userID, "2022")
+	jan2of2023 := createTestAsset(t, tdb, userID, "2023")
+	jan1of2023 := createTestAsset(t, tdb, userID, "2023-jan-1")
+	thisYear := createTestAsset(t, tdb, userID, "2024")
+	setLocalDateTime(t, tdb, jan2of2022, time.Date(2022, time.January, 2, 8, 0, 0, 0, time.UTC))
+	setLocalDateTime(t, tdb, jan2of2023, time.Date(2023, time.January, 2, 23, 30, 0, 0, time.UTC))
+	setLocalDateTime(t, tdb, jan1of2023, time.Date(2023, time.January, 1, 23, 0, 0, 0, time.UTC))
+	setLocalDateTime(t, tdb, thisYear, time.Date(2024, time.January, 2, 0, 10, 0, 0, time.UTC))
+
+	// 2024-01-01 10:30 UTC is already 2024-01-02 00:30 in Kiritimati.
+	at := time.Date(2024, time.January, 1, 10, 30, 0, 0, time.UTC)
+	require.NoError(t, service.GenerateMemories(ctx, userID.String(), at))
+	require.NoError(t, service.GenerateMemories(ctx, userID.String(), at), "generation is idempotent")
+
+	memories, err := service.GetMemories(ctx, userID.String())
+	require.NoError(t, err)
+	require.Len(t, memories, 2)
+
+	byYear := map[int]*Memory{}
+	for _, m := range memories {
+		byYear[m.Year] = m
+		assert.Equal(t, "on_this_day", m.Type)
+		require.NotNil(t, m.ShowAt)
+		require.NotNil(t, m.HideAt)
+		assert.True(t, time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC).Equal(*m.ShowAt))
+		assert.True(t, time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC).Equal(*m.HideAt))
+		assert.True(t, m.VisibleAt(at))
+	}
+	require.Contains(t, byYear, 2022)
+	require.Contains(t, byYear, 2023)
+	assert.Equal(t, []string{jan2of2022.String()}, byYear[2022].AssetIDs)
+	assert.Equal(t, []string{jan2of2023.String()}, byYear[2023].AssetIDs)
+
+	// In UTC the same instant is still January 1st.
+	utcUser := createTestUser(t, tdb, "dateline-utc@test.com")
+	utcAsset := createTestAsset(t, tdb, utcUser, "utc")
+	setLocalDateTime(t, tdb, utcAsset, time.Date(2023, time.January, 1, 23, 0, 0, 0, time.UTC))
+	utcService := NewService(tdb.Queries, time.UTC)
+	require.NoError(t, utcService.GenerateMemories(ctx, utcUser.String(), at))
+	utcMemories, err := utcService.GetMemories(ctx, utcUser.String())
+	require.NoError(t, err)
+	require.Len(t, utcMemories, 1)
+	assert.Equal(t, 2023, utcMemories[0].Year)
+}
+
+func TestIntegration_GenerateMemories_DSTDay(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	require.NoError(t, err)
+	service := NewService(tdb.Queries, zurich)
+
+	userID := createTestUser(t, tdb, "dst@test.com")
+	assetID := createTestAsset(t, tdb, userID, "spring")
+	setLocalDateTime(t, tdb, assetID, time.Date(2023, time.March, 31, 23, 45, 0, 0, time.UTC))
+
+	// 2024-03-31 is only 23 hours long in Zurich.
+	require.NoError(t, service.GenerateMemories(ctx, userID.String(), time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC)))
+
+	memories, err := service.GetMemories(ctx, userID.String())
+	require.NoError(t, err)
+	require.Len(t, memories, 1)
+	mem := memories[0]
+	require.NotNil(t, mem.ShowAt)
+	require.NotNil(t, mem.HideAt)
+	assert.True(t, time.Date(2024, time.March, 30, 23, 0, 0, 0, time.UTC).Equal(*mem.ShowAt))
+	assert.Equal(t, 23*time.Hour, mem.HideAt.Sub(*mem.ShowAt))
+	assert.True(t, mem.VisibleAt(time.Date(2024, time.March, 31, 21, 59, 0, 0, time.UTC)))
+	assert.False(t, mem.VisibleAt(time.Date(2024, time.March, 31, 22, 0, 0, 0, time.UTC)))
+	assert.False(t, mem.VisibleAt(time.Date(2024, time.March, 30, 22, 59, 0, 0, time.UTC)))
+}
@@ -0,0 +1,174 @@
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batcher gathers image predictions with the same entries into batch
+// requests. A batch is sent once it holds size images or its first image
+// has waited maxWait, so it fills with the predictions of jobs running at
+// the same time and a lone prediction is only held up briefly.
+type batcher struct {
+	client  *Client
+	size    int
+	maxWait time.Duration
+
+	mu sync.Mutex
+	// pending holds the batch being gathered for each entries JSON.
+	pending map[string]*batch
+}
+
+type batch struct {
+	entries map[string]any
+	items   []batchItem
+	timer   *time.Timer
+}
+
+type batchItem struct {
+	image  []byte
+	result chan predictResult
+}
+
+type predictResult struct {
+	raw json.RawMessage
+	err error
+}
+
+func newBatcher(client *Client, size int, maxWait time.Duration) *batcher {
+	return &batcher{
+		client:  client,
+		size:    size,
+		maxWait: maxWait,
+		pending: make(map[string]*batch),
+	}
+}
+
+// predict adds image to the batch of entries and waits for its result.
+func (b *batcher) predict(ctx context.Context, entries map[string]any, image []byte) (json.RawMessage, error) {
+	keyJSON, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("marshal ML entries: %w", err)
+	}
+	key := string(keyJSON)
+	item := batchItem{image: image, result: make(chan predictResult, 1)}
+
+	b.mu.Lock()
+	pending := b.pending[key]
+	if pending == nil {
+		pending = &batch{entries: entries}
+		pending.timer = time.AfterFunc(b.maxWait, func() { b.flush(key, pending) })
+		b.pending[key] = pending
+	}
+	pending.items = append(pending.items, item)
+	if len(pending.items) >= b.size {
+		go b.flush(key, pending)
+	}
+	b.mu.Unlock()
+
+	select {
+	case result := <-item.result:
+		return result.raw, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush sends pending unless another flush already took it.
+func (b *batcher) flush(key string, pending *batch) {
+	b.mu.Lock()
+	if b.pending[key] != pending {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	pending.timer.Stop()
+	b.mu.Unlock()
+
+	b.send(pending)
+}
+
+// send predicts on the images of a batch and hands each its result. When the
+// batch request fails, or fails for some of its images, those images are
+// sent again one by one, so one bad image does not fail the others.
+func (b *batcher) send(pending *batch) {
+	// The batch serves several callers, so no single caller's context
+	// cancels it; the client timeout still bounds each request
+	ctx := context.Background()
+
+	if len(pending.items) == 1 {
+		raw, err := b.client.predict(ctx, pending.entries, pending.items[0].image, "")
+		pending.items[0].result <- predictResult{raw: raw, err: err}
+		return
+	}
+
+	images := make([][]byte, len(pending.items))
+	for i, item := range pending.items {
+		images[i] = item.image
+	}
+	results, err := b.client.predictBatch(ctx, pending.entries, images)
+	for i, item := range pending.items {
+		if err == nil && results[i].err == nil {
+			item.result <- results[i]
+			continue
+		}
+		go func() {
+			raw, err := b.client.predict(ctx, pending.entries, item.image, "")
+			item.result <- predictResult{raw: raw, err: err}
+		}()
+	}
+}
+
+// predictBatch posts images with the same entries as one request to
+// /predict/batch, which answers with a JSON array holding the response of
+// each image in order, or {"error": "..."} for an image it failed on. The
+// batch is tried once; its images are retried on their own instead.
+func (c *Client) predictBatch(ctx context.Context, entries map[string]any, images [][]byte) ([]predictResult, error) {
+	body, contentType, err := predictForm(entries, images, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	if !c.breaker.allow() {
+		return nil, fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+	}
+
+	raw, err := c.post(ctx, "/predict/batch", body, contentType)
+	var retryable *retryableError
+	switch {
+	case errors.As(err, &retryable):
+		c.breaker.failure()
+		return nil, retryable.err
+	case err != nil:
+		c.breaker.success()
+		return nil, err
+	}
+	c.breaker.success()
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("%w: batch: %v", ErrBadResponse, err)
+	}
+	if len(items) != len(images) {
+		return nil, fmt.Errorf("%w: batch of %d images answered with %d results", ErrBadResponse, len(images), len(items))
+	}
+	results := make([]predictResult, len(items))
+	for i, item := range items {
+		var failed struct {
+			Error *string `json:"error"`
+		}
+		if err := json.Unmarshal(item, &failed); err == nil && failed.Error != nil {
+			results[i].err = fmt.Errorf("%w: %s", ErrUnavailable, *failed.Error)
+			continue
+		}
+		results[i].raw = item
+	}
+	return results, nil
+}
@@ -0,0 +1,156 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchServer answers CLIP predictions with an embedding holding the number
+// each image encodes, failing the images listed in failing when in a batch.
+type batchServer struct {
+	failing   map[string]bool
+	batchCode int
+	batches   atomic.Int32
+	singles   atomic.Int32
+}
+
+func (s *batchServer) images(t *testing.T, w http.ResponseWriter, r *http.Request) []string {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	require.NoError(t, r.ParseMultipartForm(1<<20)) //nolint:gosec // G120: test handler, body capped via MaxBytesReader
+	var images []string
+	for _, header := range r.MultipartForm.File["image"] {
+		file, err := header.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		file.Close()
+		images = append(images, string(data))
+	}
+	return images
+}
+
+func (s *batchServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		images := s.images(t, w, r)
+		switch r.URL.Path {
+		case "/predict/batch":
+			s.batches.Add(1)
+			if s.batchCode != 0 {
+				w.WriteHeader(s.batchCode)
+				return
+			}
+			results := make([]string, len(images))
+			for i, image := range images {
+				if s.failing[image] {
+					results[i] = `{"error":"cannot decode image"}`
+					continue
+				}
+				results[i] = fmt.Sprintf(`{"clip":[%s]}`, image)
+			}
+			_, _ = w.Write([]byte("[" + strings.Join(results, ",") + "]"))
+		case "/predict":
+			s.singles.Add(1)
+			require.Len(t, images, 1)
+			_, _ = fmt.Fprintf(w, `{"clip":[%s]}`, images[0])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// encodeAll encodes the images "1" to "n" at the same time and returns the
+// first value of each embedding.
+func encodeAll(t *testing.T, c *Client, n int) []float32 {
+	t.Helper()
+	got := make([]float32, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			emb, err := c.EncodeImage(context.Background(), []byte(strconv.Itoa(i+1)), "")
+			if assert.NoError(t, err) && assert.Len(t, emb, 1) {
+				got[i] = emb[0]
+			}
+		}()
+	}
+	wg.Wait()
+	return got
+}
+
+func TestBatchGathersConcurrentPredictions(t *testing.T) {
+	server := &batchServer{}
+	srv := httptest.NewServer(server.handler(t))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, BatchSize: 3, BatchMaxWait: time.Minute})
+	assert.Equal(t, []float32{1, 2, 3}, encodeAll(t, c, 3))
+	assert.Equal(t, int32(1), server.batches.Load())
+	assert.Equal(t, int32(0), server.singles.Load())
+}
+
+func TestBatchSentAfterMaxWait(t *testing.T) {
+	server := &batchServer{}
+	srv := httptest.NewServer(server.handler(t))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, BatchSize: 8, BatchMaxWait: 20 * time.Millisecond})
+	assert.Equal(t, []float32{1, 2}, encodeAll(t, c, 2))
+	// The predictions may land in one batch of two or, if the first batch
+	// was sent before the second arrived, two lone predictions
+	assert.Equal(t, int32(2), 2*server.batches.Load()+server.singles.Load())
+}
+
+func TestBatchRetriesFailedImagesAlone(t *testing.T) {
+	server := &batchServer{failing: map[string]bool{"2": true}}
+	srv := httptest.NewServer(server.handler(t))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, BatchSize: 3, BatchMaxWait: time.Minute})
+	assert.Equal(t, []float32{1, 2, 3}, encodeAll(t, c, 3))
+	assert.Equal(t, int32(1), server.batches.Load())
+	assert.Equal(t, int32(1), server.singles.Load(), "only the failed image is sent again")
+}
+
+func TestBatchFallsBackWhenBatchesAreNotServed(t *testing.T) {
+	server := &batchServer{batchCode: http.StatusNotFound}
+	srv := httptest.NewServer(server.handler(t))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, BatchSize: 3, BatchMaxWait: time.Minute})
+	assert.Equal(t, []float32{1, 2, 3}, encodeAll(t, c, 3))
+	assert.Equal(t, int32(3), server.singles.Load())
+}
+
+func TestMaxConcurrencyBoundsPredictions(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"clip":[1]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, MaxConcurrency: 2})
+	encodeAll(t, c, 6)
+	assert.Equal(t, int32(2), peak.Load())
+}
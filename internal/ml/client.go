@@ -7,6 +7,9 @@
 //
 // Features are gated by configuration; when ML is disabled or unreachable the
 // caller is expected to degrade gracefully (metadata search, skip jobs, etc.).
+// Failed predictions are retried with exponential backoff, and a circuit
+// breaker fails calls fast while the service is persistently down. Image
+// predictions can be gathered into batch requests, see Config.BatchSize.
 package ml
 
 import (
@@ -18,6 +21,7 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,6 +33,15 @@ const (
 	DefaultMinScore    = 0.7
 	DefaultMaxDistance = 0.6
 	DefaultTimeout     = 60 * time.Second
+
+	DefaultObjectModel      = "microsoft/resnet-50"
+	DefaultObjectMinScore   = 0.3
+	DefaultMaxRetries       = 2
+	DefaultRetryBackoff     = 500 * time.Millisecond
+	DefaultMaxConnections   = 16
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+	DefaultBatchMaxWait     = 50 * time.Millisecond
 )
 
 // Sentinel errors for graceful degradation.
@@ -47,12 +60,52 @@ type Config struct {
 	URL string
 	// Timeout bounds each HTTP request. Zero uses DefaultTimeout.
 	Timeout time.Duration
+	// APIKey, when set, is sent as a bearer token, for ML services behind
+	// an authenticating proxy.
+	APIKey string
+	// CLIPEnabled, FacesEnabled and ObjectsEnabled gate the calls of each
+	// feature; a gated call returns ErrDisabled without contacting the service.
+	CLIPEnabled    bool
+	FacesEnabled   bool
+	ObjectsEnabled bool
+	// MaxRetries is the number of retries of a prediction that failed with a
+	// network error or a 5xx status. Zero uses DefaultMaxRetries; negative
+	// disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// further one. Zero uses DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// MaxConnections bounds the pooled keep-alive connections to the
+	// service. Zero uses DefaultMaxConnections.
+	MaxConnections int
+	// BreakerThreshold is the number of consecutive failed predictions that
+	// opens the circuit breaker. Zero uses DefaultBreakerThreshold.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker rejects calls before
+	// letting a trial call through. Zero uses DefaultBreakerCooldown.
+	BreakerCooldown time.Duration
+	// MaxConcurrency bounds the predictions in flight at once, a batch
+	// counting as one, so a single-GPU service is not overwhelmed. Zero uses
+	// MaxConnections.
+	MaxConcurrency int
+	// BatchSize is the most images sent in one request to /predict/batch.
+	// Image predictions with the same model and options made while others
+	// wait are gathered into a batch. 0 or 1 sends each image on its own to
+	// /predict, which is all the Immich ML container serves.
+	BatchSize int
+	// BatchMaxWait is how long the first image of a batch waits for others
+	// before the batch is sent anyway. Zero uses DefaultBatchMaxWait.
+	BatchMaxWait time.Duration
 	// CLIP model name (visual + textual).
 	CLIPModel string
 	// Face model name (detection + recognition pipeline).
 	FaceModel string
 	// FaceMinScore filters low-confidence detections.
 	FaceMinScore float64
+	// ObjectModel is the image classification model used for object tagging.
+	ObjectModel string
+	// ObjectMinScore filters low-confidence object labels.
+	ObjectMinScore float64
 	// FaceMaxDistance is the embedding distance threshold for person matching.
 	FaceMaxDistance float64
 	// CLIPMaxDistance is the embedding distance threshold for smart search.
@@ -66,6 +119,10 @@ type Client struct {
 	cfg        Config
 	httpClient *http.Client
 	baseURL    string
+	breaker    *breaker
+	// slots holds a token per prediction in flight.
+	slots   chan struct{}
+	batcher *batcher
 }
 
 // NewClient builds a Client. A nil/disabled config yields a client that always
@@ -92,15 +149,61 @@ func NewClient(cfg Config) *Client {
 	if cfg.DuplicateMaxDistance <= 0 {
 		cfg.DuplicateMaxDistance = 0.01
 	}
+	if cfg.ObjectModel == "" {
+		cfg.ObjectModel = DefaultObjectModel
+	}
+	if cfg.ObjectMinScore <= 0 {
+		cfg.ObjectMinScore = DefaultObjectMinScore
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	if cfg.MaxConnections <= 0 {
+		cfg.MaxConnections = DefaultMaxConnections
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = DefaultBreakerCooldown
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = cfg.MaxConnections
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.BatchMaxWait <= 0 {
+		cfg.BatchMaxWait = DefaultBatchMaxWait
+	}
+
+	// Reuse keep-alive connections across predictions; the default
+	// transport keeps only two idle connections per host.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxConnections
+	transport.MaxIdleConnsPerHost = cfg.MaxConnections
+	transport.MaxConnsPerHost = cfg.MaxConnections
 
 	base := strings.TrimRight(strings.TrimSpace(cfg.URL), "/")
-	return &Client{
+	c := &Client{
 		cfg:     cfg,
 		baseURL: base,
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Timeout:   cfg.Timeout,
+			Transport: transport,
 		},
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		slots:   make(chan struct{}, cfg.MaxConcurrency),
+	}
+	if cfg.BatchSize > 1 {
+		c.batcher = newBatcher(c, cfg.BatchSize, cfg.BatchMaxWait)
 	}
+	return c
 }
 
 // Enabled reports whether ML calls should be attempted.
@@ -125,6 +228,7 @@ func (c *Client) Ping(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c.authorize(req)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrUnavailable, err)
@@ -136,9 +240,39 @@ func (c *Client) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Health states reported by Health.
+const (
+	HealthDisabled    = "disabled"
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// Health pings the service and reports whether it can be used, for the
+// readiness endpoint. An open circuit breaker reports unavailable even
+// when the ping succeeds, since predictions are still being rejected.
+func (c *Client) Health(ctx context.Context) (string, error) {
+	if !c.Enabled() {
+		return HealthDisabled, nil
+	}
+	if err := c.Ping(ctx); err != nil {
+		return HealthUnavailable, err
+	}
+	if c.breaker.open() {
+		return HealthUnavailable, fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+	}
+	return HealthOK, nil
+}
+
+// authorize adds the configured API key to req.
+func (c *Client) authorize(req *http.Request) {
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+}
+
 // EncodeImage produces a CLIP visual embedding for the given image bytes.
 func (c *Client) EncodeImage(ctx context.Context, image []byte, modelName string) ([]float32, error) {
-	if !c.Enabled() {
+	if !c.Enabled() || !c.cfg.CLIPEnabled {
 		return nil, ErrDisabled
 	}
 	if len(image) == 0 {
@@ -154,7 +288,7 @@ func (c *Client) EncodeImage(ctx context.Context, image []byte, modelName string
 			},
 		},
 	}
-	raw, err := c.predict(ctx, entries, image, "")
+	raw, err := c.predictImage(ctx, entries, image)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +297,7 @@ func (c *Client) EncodeImage(ctx context.Context, image []byte, modelName string
 
 // EncodeText produces a CLIP textual embedding for the given query string.
 func (c *Client) EncodeText(ctx context.Context, text, modelName string) ([]float32, error) {
-	if !c.Enabled() {
+	if !c.Enabled() || !c.cfg.CLIPEnabled {
 		return nil, ErrDisabled
 	}
 	text = strings.TrimSpace(text)
@@ -214,7 +348,7 @@ type FaceDetectionResult struct {
 
 // DetectFaces runs face detection + recognition on the given image.
 func (c *Client) DetectFaces(ctx context.Context, image []byte, modelName string, minScore float64) (*FaceDetectionResult, error) {
-	if !c.Enabled() {
+	if !c.Enabled() || !c.cfg.FacesEnabled {
 		return nil, ErrDisabled
 	}
 	if len(image) == 0 {
@@ -239,67 +373,198 @@ func (c *Client) DetectFaces(ctx context.Context, image []byte, modelName string
 			},
 		},
 	}
-	raw, err := c.predict(ctx, entries, image, "")
+	raw, err := c.predictImage(ctx, entries, image)
 	if err != nil {
 		return nil, err
 	}
 	return parseFaceDetection(raw)
 }
 
-// predict posts a multipart /predict request and returns the raw JSON body.
+// DetectedObject is one label from the image classification model.
+type DetectedObject struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+// DetectObjects tags the objects and scenes in an image (e.g. "dog",
+// "beach"), keeping labels scoring at least minScore.
+func (c *Client) DetectObjects(ctx context.Context, image []byte, modelName string, minScore float64) ([]DetectedObject, error) {
+	if !c.Enabled() || !c.cfg.ObjectsEnabled {
+		return nil, ErrDisabled
+	}
+	if len(image) == 0 {
+		return nil, ErrEmptyInput
+	}
+	if modelName == "" {
+		modelName = c.cfg.ObjectModel
+	}
+	if minScore <= 0 {
+		minScore = c.cfg.ObjectMinScore
+	}
+	entries := map[string]any{
+		"image-classification": map[string]any{
+			"modelName": modelName,
+			"options": map[string]any{
+				"minScore": minScore,
+			},
+		},
+	}
+	raw, err := c.predictImage(ctx, entries, image)
+	if err != nil {
+		return nil, err
+	}
+	return parseObjectDetection(raw, minScore)
+}
+
+// predictImage predicts on an image, in a batch with other images when
+// batching is on.
+func (c *Client) predictImage(ctx context.Context, entries map[string]any, image []byte) (json.RawMessage, error) {
+	if c.batcher != nil {
+		return c.batcher.predict(ctx, entries, image)
+	}
+	return c.predict(ctx, entries, image, "")
+}
+
+// acquire waits for a free prediction slot; release frees it.
+func (c *Client) acquire(ctx context.Context) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	<-c.slots
+}
+
+// predict posts a /predict request through the circuit breaker, retrying
+// network errors and 5xx responses with exponential backoff. The prediction
+// keeps its slot through its retries.
 func (c *Client) predict(ctx context.Context, entries map[string]any, image []byte, text string) (json.RawMessage, error) {
+	if err := c.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer c.release()
+	if !c.breaker.allow() {
+		return nil, fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+	}
+
+	backoff := c.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		raw, err := c.predictOnce(ctx, entries, image, text)
+		if err == nil {
+			c.breaker.success()
+			return raw, nil
+		}
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			// The service answered; the request itself was bad.
+			c.breaker.success()
+			return nil, err
+		}
+		if attempt >= c.cfg.MaxRetries || ctx.Err() != nil {
+			c.breaker.failure()
+			return nil, retryable.err
+		}
+		select {
+		case <-ctx.Done():
+			c.breaker.failure()
+			return nil, retryable.err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryableError marks a prediction failure worth retrying: the service
+// could not be reached or failed server-side.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// predictOnce posts a multipart /predict request and returns the raw JSON body.
+func (c *Client) predictOnce(ctx context.Context, entries map[string]any, image []byte, text string) (json.RawMessage, error) {
+	var images [][]byte
+	if len(image) > 0 {
+		images = [][]byte{image}
+	}
+	body, contentType, err := predictForm(entries, images, text)
+	if err != nil {
+		return nil, err
+	}
+	return c.post(ctx, "/predict", body, contentType)
+}
+
+// predictForm builds the multipart body of a prediction on images, or on
+// text when there are none.
+func predictForm(entries map[string]any, images [][]byte, text string) (*bytes.Buffer, string, error) {
 	entriesJSON, err := json.Marshal(entries)
 	if err != nil {
-		return nil, fmt.Errorf("marshal ML entries: %w", err)
+		return nil, "", fmt.Errorf("marshal ML entries: %w", err)
 	}
 
 	var body bytes.Buffer
 	w := multipart.NewWriter(&body)
 	if err := w.WriteField("entries", string(entriesJSON)); err != nil {
-		return nil, fmt.Errorf("write entries field: %w", err)
+		return nil, "", fmt.Errorf("write entries field: %w", err)
 	}
 	switch {
-	case len(image) > 0:
-		part, err := w.CreateFormFile("image", "asset.jpg")
-		if err != nil {
-			return nil, fmt.Errorf("create image part: %w", err)
-		}
-		if _, err := part.Write(image); err != nil {
-			return nil, fmt.Errorf("write image part: %w", err)
+	case len(images) > 0:
+		for _, image := range images {
+			part, err := w.CreateFormFile("image", "asset.jpg")
+			if err != nil {
+				return nil, "", fmt.Errorf("create image part: %w", err)
+			}
+			if _, err := part.Write(image); err != nil {
+				return nil, "", fmt.Errorf("write image part: %w", err)
+			}
 		}
 	case text != "":
 		if err := w.WriteField("text", text); err != nil {
-			return nil, fmt.Errorf("write text field: %w", err)
+			return nil, "", fmt.Errorf("write text field: %w", err)
 		}
 	default:
-		return nil, ErrEmptyInput
+		return nil, "", ErrEmptyInput
 	}
 	if err := w.Close(); err != nil {
-		return nil, fmt.Errorf("close multipart writer: %w", err)
+		return nil, "", fmt.Errorf("close multipart writer: %w", err)
 	}
+	return &body, w.FormDataContentType(), nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/predict", &body)
+// post sends a prediction body to path and returns the raw JSON response.
+// Failures worth retrying are wrapped in a retryableError.
+func (c *Client) post(ctx context.Context, path string, body io.Reader, contentType string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		return nil, &retryableError{fmt.Errorf("%w: %v", ErrUnavailable, err)}
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
 	if err != nil {
-		return nil, fmt.Errorf("read ML response: %w", err)
+		return nil, &retryableError{fmt.Errorf("%w: read response: %v", ErrUnavailable, err)}
 	}
 	if resp.StatusCode != http.StatusOK {
 		msg := strings.TrimSpace(string(respBody))
 		if len(msg) > 200 {
 			msg = msg[:200] + "..."
 		}
-		return nil, fmt.Errorf("%w: status %d: %s", ErrUnavailable, resp.StatusCode, msg)
+		err := fmt.Errorf("%w: status %d: %s", ErrUnavailable, resp.StatusCode, msg)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, &retryableError{err}
+		}
+		return nil, err
 	}
 	return json.RawMessage(respBody), nil
 }
@@ -420,6 +685,68 @@ func parseFaceDetection(raw json.RawMessage) (*FaceDetectionResult, error) {
 	return result, nil
 }
 
+// parseObjectDetection extracts labels from an image-classification predict
+// response. The service returns either scored objects
+// ({"image-classification": [{"label": "dog", "score": 0.9}]}) or, for
+// older models, bare label strings that already passed the threshold.
+func parseObjectDetection(raw json.RawMessage, minScore float64) ([]DetectedObject, error) {
+	var envelope struct {
+		Objects []json.RawMessage `json:"image-classification"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadResponse, err)
+	}
+
+	objects := []DetectedObject{}
+	for _, item := range envelope.Objects {
+		var label string
+		if err := json.Unmarshal(item, &label); err == nil {
+			objects = append(objects, DetectedObject{Label: label, Score: 1})
+			continue
+		}
+		var object struct {
+			Label string          `json:"label"`
+			Score json.RawMessage `json:"score"`
+		}
+		if err := json.Unmarshal(item, &object); err != nil {
+			return nil, fmt.Errorf("%w: object: %v", ErrBadResponse, err)
+		}
+		score, err := strconv.ParseFloat(strings.Trim(string(object.Score), `"`), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: object score: %v", ErrBadResponse, err)
+		}
+		if object.Label == "" || score < minScore {
+			continue
+		}
+		objects = append(objects, DetectedObject{Label: object.Label, Score: score})
+	}
+	return objects, nil
+}
+
+// NormalizeLabels prepares detected objects for storage and search: labels
+// are lower-cased and trimmed, comma-separated synonyms ("tabby, tabby cat")
+// become labels of their own, and duplicates keep their highest score. The
+// order of first appearance is preserved.
+func NormalizeLabels(objects []DetectedObject) []DetectedObject {
+	index := make(map[string]int, len(objects))
+	normalized := make([]DetectedObject, 0, len(objects))
+	for _, object := range objects {
+		for _, name := range strings.Split(object.Label, ",") {
+			label := strings.ToLower(strings.TrimSpace(name))
+			if label == "" {
+				continue
+			}
+			if i, ok := index[label]; ok {
+				normalized[i].Score = max(normalized[i].Score, object.Score)
+				continue
+			}
+			index[label] = len(normalized)
+			normalized = append(normalized, DetectedObject{Label: label, Score: object.Score})
+		}
+	}
+	return normalized
+}
+
 // FormatVector converts a float embedding to the pgvector text input form
 // "[0.1,0.2,...]" which PostgreSQL casts to vector.
 func FormatVector(emb []float32) string {
@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -138,7 +140,7 @@ func TestEncodeTextHTTP(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPModel: "test-model"})
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, CLIPModel: "test-model"})
 	emb, err := c.EncodeText(context.Background(), "a dog running", "")
 	require.NoError(t, err)
 	require.Len(t, emb, 3)
@@ -164,7 +166,7 @@ func TestEncodeImageHTTP(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := NewClient(Config{Enabled: true, URL: srv.URL})
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true})
 	emb, err := c.EncodeImage(context.Background(), []byte("jpeg-bytes"), "ViT-B-32__openai")
 	require.NoError(t, err)
 	require.Len(t, emb, 4)
@@ -190,7 +192,7 @@ func TestDetectFacesHTTP(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := NewClient(Config{Enabled: true, URL: srv.URL, FaceModel: "buffalo_l", FaceMinScore: 0.7})
+	c := NewClient(Config{Enabled: true, URL: srv.URL, FacesEnabled: true, FaceModel: "buffalo_l", FaceMinScore: 0.7})
 	result, err := c.DetectFaces(context.Background(), []byte("img"), "", 0)
 	require.NoError(t, err)
 	require.Len(t, result.Faces, 1)
@@ -206,7 +208,7 @@ func TestPredictUnavailableStatus(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := NewClient(Config{Enabled: true, URL: srv.URL})
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, RetryBackoff: time.Millisecond})
 	_, err := c.EncodeText(context.Background(), "hello", "")
 	require.Error(t, err)
 	assert.ErrorIs(t, err, ErrUnavailable)
@@ -223,3 +225,148 @@ func TestPing(t *testing.T) {
 	c := NewClient(Config{Enabled: true, URL: srv.URL})
 	require.NoError(t, c.Ping(context.Background()))
 }
+
+func TestClientFeatureGates(t *testing.T) {
+	c := NewClient(Config{Enabled: true, URL: "http://localhost:3003", FacesEnabled: true})
+	assert.True(t, c.Enabled())
+
+	_, err := c.EncodeText(context.Background(), "cat", "")
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	_, err = c.DetectObjects(context.Background(), []byte("img"), "", 0)
+	assert.ErrorIs(t, err, ErrDisabled)
+}
+
+func TestDetectObjectsHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		require.NoError(t, r.ParseMultipartForm(1<<20)) //nolint:gosec // G120: test handler, body capped via MaxBytesReader
+		assert.Contains(t, r.FormValue("entries"), `"image-classification"`)
+		_, _ = w.Write([]byte(`{"image-classification":[
+			{"label":"dog","score":0.92},
+			{"label":"beach","score":"0.41"},
+			{"label":"car","score":0.05}
+		]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, ObjectsEnabled: true})
+	objects, err := c.DetectObjects(context.Background(), []byte("img"), "", 0.3)
+	require.NoError(t, err)
+	assert.Equal(t, []DetectedObject{{Label: "dog", Score: 0.92}, {Label: "beach", Score: 0.41}}, objects)
+}
+
+func TestParseObjectDetectionLabelsOnly(t *testing.T) {
+	objects, err := parseObjectDetection(json.RawMessage(`{"image-classification":["cat","sofa"]}`), 0.3)
+	require.NoError(t, err)
+	assert.Equal(t, []DetectedObject{{Label: "cat", Score: 1}, {Label: "sofa", Score: 1}}, objects)
+}
+
+func TestPredictRetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"clip":[1,2]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, RetryBackoff: time.Millisecond})
+	emb, err := c.EncodeText(context.Background(), "hello", "")
+	require.NoError(t, err)
+	assert.Len(t, emb, 2)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestPredictDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, CLIPEnabled: true, RetryBackoff: time.Millisecond})
+	_, err := c.EncodeText(context.Background(), "hello", "")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestPredictCircuitBreakerFailsFast(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{
+		Enabled: true, URL: srv.URL, CLIPEnabled: true,
+		MaxRetries: -1, BreakerThreshold: 2, BreakerCooldown: time.Hour,
+	})
+	for range 2 {
+		_, err := c.EncodeText(context.Background(), "hello", "")
+		assert.ErrorIs(t, err, ErrUnavailable)
+	}
+	_, err := c.EncodeText(context.Background(), "hello", "")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Contains(t, err.Error(), "circuit breaker open")
+	assert.Equal(t, int32(2), calls.Load())
+
+	health, err := c.Health(context.Background())
+	assert.Equal(t, HealthUnavailable, health)
+	assert.Error(t, err)
+}
+
+func TestBreakerTrialCallClosesBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	assert.False(t, b.allow())
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow(), "only one trial call while half-open")
+
+	b.success()
+	assert.True(t, b.allow())
+	assert.False(t, b.open())
+}
+
+func TestClientSendsAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("pong"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(Config{Enabled: true, URL: srv.URL, APIKey: "secret"})
+	health, err := c.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, HealthOK, health)
+}
+
+func TestHealthDisabled(t *testing.T) {
+	var c *Client
+	health, err := c.Health(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, HealthDisabled, health)
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	got := NormalizeLabels([]DetectedObject{
+		{Label: "Tabby, tabby cat", Score: 0.6},
+		{Label: " tabby ", Score: 0.8},
+		{Label: "sofa", Score: 0.4},
+		{Label: " , ", Score: 0.9},
+	})
+	assert.Equal(t, []DetectedObject{
+		{Label: "tabby", Score: 0.8},
+		{Label: "tabby cat", Score: 0.6},
+		{Label: "sofa", Score: 0.4},
+	}, got)
+}
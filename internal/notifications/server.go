@@ -98,13 +98,13 @@ func notificationStatusError(ctx context.Context, publicMsg string, err error) e
 
 // GetNotifications returns notifications based on filters
 func (s *Server) GetNotifications(ctx context.Context, req *immichv1.GetNotificationsRequest) (*immichv1.GetNotificationsResponse, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	unreadOnly := req.Unread != nil && *req.Unread
-	notifications, err := s.service.GetNotifications(ctx, claims.UserID, unreadOnly)
+	notifications, err := s.service.GetNotifications(ctx, userID.String(), unreadOnly)
 	if err != nil {
 		return nil, notificationStatusError(ctx, "failed to get notifications", err)
 	}
@@ -135,13 +135,13 @@ func (s *Server) GetNotifications(ctx context.Context, req *immichv1.GetNotifica
 
 // GetNotification gets a single notification by ID
 func (s *Server) GetNotification(ctx context.Context, req *immichv1.GetNotificationRequest) (*immichv1.NotificationDto, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get the specific notification from service
-	notification, err := s.service.GetNotification(ctx, claims.UserID, req.Id)
+	notification, err := s.service.GetNotification(ctx, userID.String(), req.Id)
 	if err != nil {
 		return nil, notificationStatusError(ctx, "failed to get notification", err)
 	}
@@ -155,21 +155,21 @@ func (s *Server) GetNotification(ctx context.Context, req *immichv1.GetNotificat
 
 // UpdateNotification updates a notification (mainly to mark as read)
 func (s *Server) UpdateNotification(ctx context.Context, req *immichv1.UpdateNotificationRequest) (*immichv1.NotificationDto, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Mark as read if read_at is provided
 	if req.ReadAt != nil {
-		err := s.service.MarkAsRead(ctx, claims.UserID, req.Id)
+		err := s.service.MarkAsRead(ctx, userID.String(), req.Id)
 		if err != nil {
 			return nil, notificationStatusError(ctx, "failed to mark as read", err)
 		}
 	}
 
 	// Retrieve the updated notification from service
-	notification, err := s.service.GetNotification(ctx, claims.UserID, req.Id)
+	notification, err := s.service.GetNotification(ctx, userID.String(), req.Id)
 	if err != nil {
 		return nil, notificationStatusError(ctx, "failed to get updated notification", err)
 	}
@@ -190,12 +190,12 @@ func (s *Server) UpdateNotification(ctx context.Context, req *immichv1.UpdateNot
 
 // DeleteNotification deletes a notification
 func (s *Server) DeleteNotification(ctx context.Context, req *immichv1.DeleteNotificationRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.service.DeleteNotification(ctx, claims.UserID, req.Id)
+	err = s.service.DeleteNotification(ctx, userID.String(), req.Id)
 	if err != nil {
 		return nil, notificationStatusError(ctx, "failed to delete notification", err)
 	}
@@ -205,23 +205,23 @@ func (s *Server) DeleteNotification(ctx context.Context, req *immichv1.DeleteNot
 
 // UpdateNotifications updates multiple notifications
 func (s *Server) UpdateNotifications(ctx context.Context, req *immichv1.UpdateNotificationsRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Mark all as read if read_at is provided
 	if req.ReadAt != nil {
 		if len(req.Ids) == 0 {
 			// Mark all as read
-			err := s.service.MarkAllAsRead(ctx, claims.UserID)
+			err := s.service.MarkAllAsRead(ctx, userID.String())
 			if err != nil {
 				return nil, notificationStatusError(ctx, "failed to mark all as read", err)
 			}
 		} else {
 			// Mark specific ones as read
 			for _, id := range req.Ids {
-				err := s.service.MarkAsRead(ctx, claims.UserID, id)
+				err := s.service.MarkAsRead(ctx, userID.String(), id)
 				if err != nil {
 					return nil, notificationStatusError(ctx, "failed to mark notification as read", err)
 				}
@@ -234,13 +234,13 @@ func (s *Server) UpdateNotifications(ctx context.Context, req *immichv1.UpdateNo
 
 // DeleteNotifications deletes multiple notifications
 func (s *Server) DeleteNotifications(ctx context.Context, req *immichv1.DeleteNotificationsRequest) (*emptypb.Empty, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, id := range req.Ids {
-		err := s.service.DeleteNotification(ctx, claims.UserID, id)
+		err := s.service.DeleteNotification(ctx, userID.String(), id)
 		if err != nil {
 			return nil, notificationStatusError(ctx, "failed to delete notification", err)
 		}
@@ -12,12 +12,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// webLoginPath is the web client page that completes an OAuth login.
+const webLoginPath = "/auth/login"
+
 // OAuthProvider represents an OAuth provider configuration
 type OAuthProvider struct {
 	Name         string
@@ -63,7 +67,7 @@ func (s *Service) initProviders() {
 			TokenURL:     "https://oauth2.googleapis.com/token",
 			UserInfoURL:  "https://www.googleapis.com/oauth2/v2/userinfo",
 			Scopes:       []string{"openid", "email", "profile"},
-			RedirectURL:  s.config.Auth.OAuth.Google.RedirectURL,
+			RedirectURL:  s.redirectURL(s.config.Auth.OAuth.Google.RedirectURL),
 		}
 	}
 
@@ -77,7 +81,7 @@ func (s *Service) initProviders() {
 			TokenURL:     "https://github.com/login/oauth/access_token",
 			UserInfoURL:  "https://api.github.com/user",
 			Scopes:       []string{"user:email"},
-			RedirectURL:  s.config.Auth.OAuth.GitHub.RedirectURL,
+			RedirectURL:  s.redirectURL(s.config.Auth.OAuth.GitHub.RedirectURL),
 		}
 	}
 
@@ -91,11 +95,20 @@ func (s *Service) initProviders() {
 			TokenURL:     "https://login.microsoftonline.com/common/oauth2/v2.0/token",
 			UserInfoURL:  "https://graph.microsoft.com/v1.0/me",
 			Scopes:       []string{"openid", "email", "profile"},
-			RedirectURL:  s.config.Auth.OAuth.Microsoft.RedirectURL,
+			RedirectURL:  s.redirectURL(s.config.Auth.OAuth.Microsoft.RedirectURL),
 		}
 	}
 }
 
+// redirectURL returns configured, or the web login page under the external
+// domain when no redirect URL is configured for the provider.
+func (s *Service) redirectURL(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return s.config.ExternalURL(webLoginPath)
+}
+
 // GenerateState generates a random state parameter for OAuth
 func (s *Service) GenerateState() (string, error) {
 	b := make([]byte, 32)
@@ -266,8 +279,9 @@ func (s *Service) UnlinkOAuthAccount(ctx context.Context, userID uuid.UUID) erro
 	return nil
 }
 
-// FindOrCreateUserByOAuth finds or creates a user based on OAuth info
-func (s *Service) FindOrCreateUserByOAuth(ctx context.Context, userInfo *OAuthUserInfo) (*sqlc.User, error) {
+// FindOrCreateUserByOAuth finds or creates a user based on OAuth info. It
+// reports whether the user was created.
+func (s *Service) FindOrCreateUserByOAuth(ctx context.Context, userInfo *OAuthUserInfo) (*sqlc.User, bool, error) {
 	oauthID := ""
 	if userInfo.Provider != "" && userInfo.ID != "" {
 		oauthID = fmt.Sprintf("%s:%s", userInfo.Provider, userInfo.ID)
@@ -278,7 +292,7 @@ func (s *Service) FindOrCreateUserByOAuth(ctx context.Context, userInfo *OAuthUs
 	// Prefer lookup by OAuth subject (stable across email changes).
 	if oauthID != "" {
 		if user, err := s.db.GetUserByOAuthId(ctx, oauthID); err == nil {
-			return &user, nil
+			return &user, false, nil
 		}
 	}
 
@@ -291,29 +305,39 @@ func (s *Service) FindOrCreateUserByOAuth(ctx context.Context, userInfo *OAuthUs
 				OauthId: oauthID,
 			})
 			if linkErr == nil {
-				return &updated, nil
+				return &updated, false, nil
 			}
 		}
-		return &user, nil
+		return &user, false, nil
 	}
 
 	// If user doesn't exist, create a new one
 	// Generate a random password since OAuth users don't need one
 	randomPass := make([]byte, 32)
 	if _, err := rand.Read(randomPass); err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	// The first account on a fresh install becomes its administrator.
+	newID := uuid.New()
+	isAdmin, err := auth.ClaimFirstAdmin(ctx, s.db, newID.String())
+	if err != nil {
+		return nil, false, err
 	}
 
 	newUser, err := s.db.CreateUser(ctx, sqlc.CreateUserParams{
-		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		ID:          pgtype.UUID{Bytes: newID, Valid: true},
 		Email:       userInfo.Email,
 		Name:        userInfo.Name,
 		Password:    base64.URLEncoding.EncodeToString(randomPass), // Random password for OAuth users
-		IsAdmin:     false,
+		IsAdmin:     isAdmin,
 		IsOnboarded: false,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		if isAdmin {
+			auth.ReleaseFirstAdmin(ctx, s.db)
+		}
+		return nil, false, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	if oauthID != "" {
@@ -321,11 +345,11 @@ func (s *Service) FindOrCreateUserByOAuth(ctx context.Context, userInfo *OAuthUs
 			ID:      newUser.ID,
 			OauthId: oauthID,
 		}); linkErr == nil {
-			return &updated, nil
+			return &updated, true, nil
 		}
 	}
 
-	return &newUser, nil
+	return &newUser, true, nil
 }
 
 // OAuthUserInfo represents user information from an OAuth provider
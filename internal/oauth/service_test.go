@@ -0,0 +1,29 @@
+package oauth
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+func TestAuthorizationURLDefaultsRedirectToExternalDomain(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.ExternalDomain = "https://photos.example.com/"
+	cfg.Auth.OAuth.GitHub = config.OAuthProviderConfig{Enabled: true, ClientID: "client"}
+	cfg.Auth.OAuth.Google = config.OAuthProviderConfig{Enabled: true, ClientID: "client", RedirectURL: "https://login.example.com/cb"}
+	s := NewService(nil, cfg)
+
+	redirectURI := func(provider string) string {
+		raw, err := s.GetAuthorizationURL(provider, "state")
+		require.NoError(t, err)
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		return u.Query().Get("redirect_uri")
+	}
+	assert.Equal(t, "https://photos.example.com/auth/login", redirectURI("github"))
+	assert.Equal(t, "https://login.example.com/cb", redirectURI("google"))
+}
@@ -2,29 +2,55 @@ package partners
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Partnership statuses. A partnership starts as an invite and only shares
+// the inviting user's library once the partner accepts it.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+)
+
+// Actions of the partner events and notifications sent to both users.
+const (
+	ActionInvite = "invite"
+	ActionAccept = "accept"
+	ActionRevoke = "revoke"
+)
+
+// EventBroadcaster pushes partner changes to a user's open sessions.
+type EventBroadcaster interface {
+	BroadcastPartnerEvent(userID string, partnerID string, action string)
+}
+
 // Server implements the PartnersService
 type Server struct {
 	immichv1.UnimplementedPartnersServiceServer
 	queries *sqlc.Queries
+	events  EventBroadcaster
 }
 
-// NewServer creates a new partners server
-func NewServer(queries *sqlc.Queries) *Server {
+// NewServer creates a new partners server. events may be nil.
+func NewServer(queries *sqlc.Queries, events EventBroadcaster) *Server {
 	return &Server{
 		queries: queries,
+		events:  events,
 	}
 }
 
@@ -55,6 +81,7 @@ func buildPartnerResponse(
 	email string,
 	name string,
 	inTimeline bool,
+	status string,
 	createdAt pgtype.Timestamptz,
 	updatedAt pgtype.Timestamptz,
 ) *immichv1.PartnerResponse {
@@ -67,6 +94,7 @@ func buildPartnerResponse(
 			Name:  name,
 		},
 		InTimeline: inTimeline,
+		Status:     status,
 		CreatedAt:  timestamppb.New(createdAt.Time),
 		UpdatedAt:  timestamppb.New(updatedAt.Time),
 	}
@@ -78,13 +106,112 @@ func partnerResponseFromRow(row sqlc.GetPartnersRow) *immichv1.PartnerResponse {
 		row.Email,
 		row.Name,
 		row.InTimeline,
+		row.PartnershipStatus,
 		row.PartnershipCreatedAt,
 		row.PartnershipUpdatedAt,
 	)
 }
 
-func partnerResponseFromUser(user sqlc.User, inTimeline bool, createdAt, updatedAt pgtype.Timestamptz) *immichv1.PartnerResponse {
-	return buildPartnerResponse(user.ID, user.Email, user.Name, inTimeline, createdAt, updatedAt)
+func partnerResponseFromUser(user sqlc.User, partnership sqlc.Partner) *immichv1.PartnerResponse {
+	return buildPartnerResponse(
+		user.ID,
+		user.Email,
+		user.Name,
+		partnership.InTimeline,
+		partnership.Status,
+		partnership.CreatedAt,
+		partnership.UpdatedAt,
+	)
+}
+
+// filterPartnerRows keeps the partnerships matching direction. Libraries
+// shared with the user only count once the user accepted the invite, so a
+// pending invite never exposes the inviting user's assets; the inviting user
+// still sees it among the partners they share with.
+func filterPartnerRows(rows []sqlc.GetPartnersRow, userID pgtype.UUID, direction immichv1.PartnerDirection) []sqlc.GetPartnersRow {
+	filtered := make([]sqlc.GetPartnersRow, 0, len(rows))
+	for _, row := range rows {
+		switch direction {
+		case immichv1.PartnerDirection_PARTNER_DIRECTION_SHARED_BY:
+			if row.SharedById.Bytes != userID.Bytes {
+				continue
+			}
+		case immichv1.PartnerDirection_PARTNER_DIRECTION_SHARED_WITH:
+			if row.SharedWithId.Bytes != userID.Bytes || row.PartnershipStatus != StatusAccepted {
+				continue
+			}
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
+
+// notify tells userID that partnerID changed their partnership, both as a
+// stored notification and as a realtime event, and sends the event to
+// partnerID's own sessions too. Failures are only logged: the partnership
+// change itself already succeeded.
+func (s *Server) notify(ctx context.Context, userID, partnerID pgtype.UUID, action, title string) {
+	recipient := uuid.UUID(userID.Bytes).String()
+	actor := uuid.UUID(partnerID.Bytes).String()
+
+	data, err := json.Marshal(map[string]string{"partnerId": actor, "action": action})
+	if err != nil {
+		data = []byte("{}")
+	}
+	if _, err := s.queries.CreateNotification(ctx, sqlc.CreateNotificationParams{
+		UserId: userID,
+		Level:  "info",
+		Type:   "partner_" + action,
+		Data:   data,
+		Title:  title,
+	}); err != nil {
+		logrus.WithError(err).WithField("user_id", recipient).Warn("Failed to create partner notification")
+	}
+
+	if s.events != nil {
+		s.events.BroadcastPartnerEvent(recipient, actor, action)
+		s.events.BroadcastPartnerEvent(actor, recipient, action)
+	}
+}
+
+func displayName(user sqlc.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.Email
+}
+
+// Invite shares sharedBy's library with sharedWith once sharedWith accepts.
+// Inviting again while the invite is pending re-sends it; an accepted
+// partnership is returned unchanged. After a revoke the partnership is gone,
+// so inviting the same user again needs a new acceptance.
+func (s *Server) Invite(ctx context.Context, sharedBy, sharedWith pgtype.UUID) (sqlc.User, sqlc.Partner, error) {
+	if sharedBy.Bytes == sharedWith.Bytes {
+		return sqlc.User{}, sqlc.Partner{}, status.Error(codes.InvalidArgument, "cannot partner with yourself")
+	}
+
+	partnerUser, err := s.queries.GetUserByID(ctx, sharedWith)
+	if err != nil {
+		return sqlc.User{}, sqlc.Partner{}, status.Error(codes.NotFound, "partner user not found")
+	}
+
+	partnership, err := s.queries.CreatePartnership(ctx, sqlc.CreatePartnershipParams{
+		SharedById:   sharedBy,
+		SharedWithId: sharedWith,
+	})
+	if err != nil {
+		return sqlc.User{}, sqlc.Partner{}, status.Errorf(codes.Internal, "failed to create partnership: %v", err)
+	}
+
+	if partnership.Status == StatusPending {
+		title := "You have a new partner invite"
+		if inviter, err := s.queries.GetUserByID(ctx, sharedBy); err == nil {
+			title = fmt.Sprintf("%s wants to share their library with you", displayName(inviter))
+		}
+		s.notify(ctx, sharedWith, sharedBy, ActionInvite, title)
+	}
+
+	return partnerUser, partnership, nil
 }
 
 // GetPartners gets all partners for the user
@@ -101,6 +228,7 @@ func (s *Server) GetPartners(ctx context.Context, request *immichv1.GetPartnersR
 	}
 
 	// Convert to proto response
+	partnerRows = filterPartnerRows(partnerRows, userUUID, request.GetDirection())
 	partners := make([]*immichv1.PartnerResponse, 0, len(partnerRows))
 	for _, row := range partnerRows {
 		partners = append(partners, partnerResponseFromRow(row))
@@ -124,8 +252,9 @@ func (s *Server) RemovePartner(ctx context.Context, request *immichv1.RemovePart
 		return nil, err
 	}
 
-	// Delete the partnership from database
-	err = s.queries.DeletePartnership(ctx, sqlc.DeletePartnershipParams{
+	// Delete the partnership from database. Either side can revoke it,
+	// whether or not the invite was accepted.
+	removed, err := s.queries.DeletePartnership(ctx, sqlc.DeletePartnershipParams{
 		SharedById:   userUUID,
 		SharedWithId: partnerUUID,
 	})
@@ -133,38 +262,75 @@ func (s *Server) RemovePartner(ctx context.Context, request *immichv1.RemovePart
 		return nil, status.Errorf(codes.Internal, "failed to remove partnership: %v", err)
 	}
 
+	if removed > 0 {
+		title := "A partner stopped sharing with you"
+		if revoker, err := s.queries.GetUserByID(ctx, userUUID); err == nil {
+			title = fmt.Sprintf("%s ended your partnership", displayName(revoker))
+		}
+		s.notify(ctx, partnerUUID, userUUID, ActionRevoke, title)
+	}
+
 	return &emptypb.Empty{}, nil
 }
 
-// CreatePartner creates a new partnership
-func (s *Server) CreatePartner(ctx context.Context, request *immichv1.CreatePartnerRequest) (*immichv1.PartnerResponse, error) {
+// AcceptPartner accepts the pending invite of the user given by id, which
+// starts sharing their library with the caller.
+func (s *Server) AcceptPartner(ctx context.Context, request *immichv1.AcceptPartnerRequest) (*immichv1.PartnerResponse, error) {
 	userUUID, err := currentUserUUIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse partner ID from request
-	partnerUUID, err := parseUUIDParam(request.GetSharedWithId(), "invalid partner ID")
+	inviterUUID, err := parseUUIDParam(request.GetId(), "invalid partner ID")
 	if err != nil {
 		return nil, err
 	}
 
-	// First check if partner user exists
-	partnerUser, err := s.queries.GetUserByID(ctx, partnerUUID)
+	partnership, err := s.queries.AcceptPartnership(ctx, sqlc.AcceptPartnershipParams{
+		SharedById:   inviterUUID,
+		SharedWithId: userUUID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Error(codes.NotFound, "no pending partner invite")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to accept partner invite: %v", err)
+	}
+
+	inviter, err := s.queries.GetUserByID(ctx, inviterUUID)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, "partner user not found")
 	}
 
-	// Create the partnership in database
-	partnership, err := s.queries.CreatePartnership(ctx, sqlc.CreatePartnershipParams{
-		SharedById:   userUUID,
-		SharedWithId: partnerUUID,
-	})
+	title := "Your partner invite was accepted"
+	if accepter, err := s.queries.GetUserByID(ctx, userUUID); err == nil {
+		title = fmt.Sprintf("%s accepted your partner invite", displayName(accepter))
+	}
+	s.notify(ctx, inviterUUID, userUUID, ActionAccept, title)
+
+	return partnerResponseFromUser(inviter, partnership), nil
+}
+
+// CreatePartner creates a new partnership
+func (s *Server) CreatePartner(ctx context.Context, request *immichv1.CreatePartnerRequest) (*immichv1.PartnerResponse, error) {
+	userUUID, err := currentUserUUIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse partner ID from request
+	partnerUUID, err := parseUUIDParam(request.GetSharedWithId(), "invalid partner ID")
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create partnership: %v", err)
+		return nil, err
 	}
 
-	return partnerResponseFromUser(partnerUser, partnership.InTimeline, partnership.CreatedAt, partnership.UpdatedAt), nil
+	// The partnership starts as an invite the partner has to accept
+	partnerUser, partnership, err := s.Invite(ctx, userUUID, partnerUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	return partnerResponseFromUser(partnerUser, partnership), nil
 }
 
 // UpdatePartner updates partnership settings
@@ -194,23 +360,26 @@ func (s *Server) UpdatePartner(ctx context.Context, request *immichv1.UpdatePart
 	}
 
 	// Verify the partner exists in the partnership
-	found := false
-	for _, row := range partnerRows {
+	var found *sqlc.GetPartnersRow
+	for i, row := range partnerRows {
 		if row.ID.Bytes == partnerUUID.Bytes {
-			found = true
+			found = &partnerRows[i]
 			break
 		}
 	}
 
-	if !found {
+	if found == nil {
 		return nil, status.Error(codes.NotFound, "partnership not found")
 	}
 
 	// Return the updated partnership info
 	// Note: InTimeline update requires SQLC regeneration
-	return partnerResponseFromUser(
-		partnerUser,
+	return buildPartnerResponse(
+		partnerUser.ID,
+		partnerUser.Email,
+		partnerUser.Name,
 		request.GetInTimeline(),
+		found.PartnershipStatus,
 		partnerUser.CreatedAt,
 		pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	), nil
@@ -14,6 +14,7 @@ import (
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 )
 
 func TestCurrentUserUUIDFromContext(t *testing.T) {
@@ -27,7 +28,7 @@ func TestCurrentUserUUIDFromContext(t *testing.T) {
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 
 	_, err = currentUserUUIDFromContext(auth.WithClaims(context.Background(), &auth.Claims{UserID: "not-a-uuid"}))
-	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
 
 func TestParseUUIDParam(t *testing.T) {
@@ -50,6 +51,7 @@ func TestPartnerResponseFromRowUsesPartnershipFields(t *testing.T) {
 		Email:                "ada@example.com",
 		Name:                 "Ada",
 		InTimeline:           true,
+		PartnershipStatus:    StatusAccepted,
 		PartnershipCreatedAt: pgtype.Timestamptz{Time: createdAt, Valid: true},
 		PartnershipUpdatedAt: pgtype.Timestamptz{Time: updatedAt, Valid: true},
 	}
@@ -61,6 +63,7 @@ func TestPartnerResponseFromRowUsesPartnershipFields(t *testing.T) {
 	assert.Equal(t, "ada@example.com", resp.GetUser().GetEmail())
 	assert.Equal(t, "Ada", resp.GetUser().GetName())
 	assert.True(t, resp.GetInTimeline())
+	assert.Equal(t, StatusAccepted, resp.GetStatus())
 	assert.Equal(t, createdAt, resp.GetCreatedAt().AsTime())
 	assert.Equal(t, updatedAt, resp.GetUpdatedAt().AsTime())
 }
@@ -75,16 +78,59 @@ func TestPartnerResponseFromUser(t *testing.T) {
 		Name:  "Grace",
 	}
 
-	resp := partnerResponseFromUser(
-		user,
-		false,
-		pgtype.Timestamptz{Time: createdAt, Valid: true},
-		pgtype.Timestamptz{Time: updatedAt, Valid: true},
-	)
+	resp := partnerResponseFromUser(user, sqlc.Partner{
+		Status:    StatusPending,
+		CreatedAt: pgtype.Timestamptz{Time: createdAt, Valid: true},
+		UpdatedAt: pgtype.Timestamptz{Time: updatedAt, Valid: true},
+	})
 	require.NotNil(t, resp)
 	assert.Equal(t, partnerID.String(), resp.GetId())
 	assert.Equal(t, "grace@example.com", resp.GetUser().GetEmail())
 	assert.False(t, resp.GetInTimeline())
+	assert.Equal(t, StatusPending, resp.GetStatus())
 	assert.Equal(t, createdAt, resp.GetCreatedAt().AsTime())
 	assert.Equal(t, updatedAt, resp.GetUpdatedAt().AsTime())
 }
+
+func TestFilterPartnerRowsHidesPendingInvitesFromInvitee(t *testing.T) {
+	me := pgUUID(uuid.New())
+	other := pgUUID(uuid.New())
+	third := pgUUID(uuid.New())
+	rows := []sqlc.GetPartnersRow{
+		{ID: other, SharedById: me, SharedWithId: other, PartnershipStatus: StatusPending},
+		{ID: other, SharedById: other, SharedWithId: me, PartnershipStatus: StatusPending},
+		{ID: third, SharedById: third, SharedWithId: me, PartnershipStatus: StatusAccepted},
+	}
+
+	sharedBy := filterPartnerRows(rows, me, immichv1.PartnerDirection_PARTNER_DIRECTION_SHARED_BY)
+	require.Len(t, sharedBy, 1)
+	assert.Equal(t, other, sharedBy[0].SharedWithId)
+
+	sharedWith := filterPartnerRows(rows, me, immichv1.PartnerDirection_PARTNER_DIRECTION_SHARED_WITH)
+	require.Len(t, sharedWith, 1)
+	assert.Equal(t, third, sharedWith[0].SharedById)
+
+	assert.Len(t, filterPartnerRows(rows, me, immichv1.PartnerDirection_PARTNER_DIRECTION_UNSPECIFIED), 3)
+}
+
+type recordedEvent struct {
+	userID, partnerID, action string
+}
+
+type recordingBroadcaster struct {
+	events []recordedEvent
+}
+
+func (b *recordingBroadcaster) BroadcastPartnerEvent(userID, partnerID, action string) {
+	b.events = append(b.events, recordedEvent{userID, partnerID, action})
+}
+
+func TestInviteRejectsSelf(t *testing.T) {
+	events := &recordingBroadcaster{}
+	server := NewServer(nil, events)
+	me := pgUUID(uuid.New())
+
+	_, _, err := server.Invite(context.Background(), me, me)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Empty(t, events.events)
+}
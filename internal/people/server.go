@@ -9,10 +9,12 @@ import (
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/denysvitali/immich-go-backend/internal/storage"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -137,12 +139,20 @@ func (s *Server) GetAllPeople(ctx context.Context, request *immichv1.GetAllPeopl
 	}, nil
 }
 
+// personFields are the validated fields of create and update requests.
+type personFields struct {
+	Name *string `json:"name" validate:"omitnil,max=255"`
+}
+
 // CreatePerson creates a new person
 func (s *Server) CreatePerson(ctx context.Context, request *immichv1.CreatePersonRequest) (*immichv1.PersonResponse, error) {
 	userID, err := currentUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if err := grpcutil.Validate(ctx, "invalid person", personFields{Name: request.Name}); err != nil {
+		return nil, err
+	}
 
 	// Prepare birth date
 	var birthDatePG pgtype.Date
@@ -304,7 +314,10 @@ func (s *Server) UpdatePerson(ctx context.Context, request *immichv1.UpdatePerso
 		return nil, err
 	}
 
-	_, personUUID, err := s.getOwnedPerson(ctx, userID, request.GetId(), "invalid person ID", "person not found")
+	if err := grpcutil.Validate(ctx, "invalid person", personFields{Name: request.Name}); err != nil {
+		return nil, err
+	}
+	person, personUUID, err := s.getOwnedPerson(ctx, userID, request.GetId(), "invalid person ID", "person not found")
 	if err != nil {
 		return nil, err
 	}
@@ -337,12 +350,19 @@ func (s *Server) UpdatePerson(ctx context.Context, request *immichv1.UpdatePerso
 		updateParams.IsHidden = pgtype.Bool{Bool: *request.IsHidden, Valid: true}
 	}
 
-	// Set feature face asset if provided
+	// Set feature face if provided: the person's face on the given asset
+	// becomes the thumbnail
 	if request.FeatureFaceAssetId != nil {
-		faceAssetID, err := uuid.Parse(*request.FeatureFaceAssetId)
-		if err == nil {
-			updateParams.FaceAssetID = pgUUID(faceAssetID)
+		face, err := s.featureFace(ctx, userID, personUUID, *request.FeatureFaceAssetId)
+		if err != nil {
+			return nil, err
+		}
+		thumbnailPath, err := GenerateThumbnail(ctx, s.queries, s.storage, person, face)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to generate person thumbnail: %v", err)
 		}
+		updateParams.FaceAssetID = face.ID
+		updateParams.ThumbnailPath = pgtype.Text{String: thumbnailPath, Valid: true}
 	}
 
 	// Update person in database
@@ -351,6 +371,12 @@ func (s *Server) UpdatePerson(ctx context.Context, request *immichv1.UpdatePerso
 		return nil, status.Errorf(codes.Internal, "failed to update person: %v", err)
 	}
 
+	if person.ThumbnailPath != "" && person.ThumbnailPath != updatedPerson.ThumbnailPath {
+		if err := s.storage.Derivatives().Delete(ctx, person.ThumbnailPath); err != nil {
+			logrus.WithError(err).WithField("path", person.ThumbnailPath).Warn("Failed to delete old person thumbnail")
+		}
+	}
+
 	return s.personResponse(ctx, updatedPerson), nil
 }
 
@@ -452,7 +478,7 @@ func (s *Server) GetPersonThumbnail(ctx context.Context, request *immichv1.GetPe
 		return nil, status.Error(codes.FailedPrecondition, "storage backend not configured")
 	}
 
-	reader, err := s.storage.Download(ctx, person.ThumbnailPath)
+	reader, err := s.storage.Derivatives().Download(ctx, person.ThumbnailPath)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "thumbnail not found in storage: %v", err)
 	}
@@ -0,0 +1,71 @@
+//go:build integration
+// +build integration
+
+package people
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestUpdatePersonRejectsForeignFeatureFaces checks the feature face can only
+// be set from an asset of the user that shows the person.
+func TestUpdatePersonRejectsForeignFeatureFaces(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "people-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "people-other@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	other := pgtype.UUID{Bytes: otherID, Valid: true}
+	ownAsset := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "own"), Valid: true}
+	foreignAsset := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, otherID, "foreign"), Valid: true}
+
+	person, err := tdb.Queries.CreatePerson(ctx, sqlc.CreatePersonParams{OwnerId: owner, Name: "Ada"})
+	require.NoError(t, err)
+	stranger, err := tdb.Queries.CreatePerson(ctx, sqlc.CreatePersonParams{OwnerId: owner, Name: "Bob"})
+	require.NoError(t, err)
+	otherPerson, err := tdb.Queries.CreatePerson(ctx, sqlc.CreatePersonParams{OwnerId: other, Name: "Ada"})
+	require.NoError(t, err)
+
+	face := sqlc.CreateAssetFaceParams{
+		ImageWidth: 100, ImageHeight: 100,
+		BoundingBoxX1: 10, BoundingBoxY1: 10, BoundingBoxX2: 50, BoundingBoxY2: 50,
+	}
+	face.AssetId, face.PersonId = ownAsset, stranger.ID
+	_, err = tdb.Queries.CreateAssetFace(ctx, face)
+	require.NoError(t, err)
+	face.AssetId, face.PersonId = foreignAsset, otherPerson.ID
+	_, err = tdb.Queries.CreateAssetFace(ctx, face)
+	require.NoError(t, err)
+
+	srv := NewServer(tdb.Queries, nil)
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "people-owner@example.com"})
+	update := func(assetID pgtype.UUID) error {
+		id := assetID.String()
+		_, err := srv.UpdatePerson(userCtx, &immichv1.UpdatePersonRequest{
+			Id:                 person.ID.String(),
+			FeatureFaceAssetId: &id,
+		})
+		return err
+	}
+
+	assert.Equal(t, codes.NotFound, status.Code(update(foreignAsset)), "asset of another user")
+	assert.Equal(t, codes.InvalidArgument, status.Code(update(ownAsset)), "face of another person")
+
+	unchanged, err := tdb.Queries.GetPerson(ctx, person.ID)
+	require.NoError(t, err)
+	assert.False(t, unchanged.FaceAssetId.Valid)
+}
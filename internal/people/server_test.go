@@ -27,7 +27,7 @@ func TestCurrentUserIDFromContext(t *testing.T) {
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 
 	_, err = currentUserIDFromContext(auth.WithClaims(context.Background(), &auth.Claims{UserID: "not-a-uuid"}))
-	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
 
 func TestBuildPersonResponse(t *testing.T) {
@@ -0,0 +1,184 @@
+package people
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"path"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+const (
+	// personThumbnailSize is the edge of the square person thumbnails.
+	personThumbnailSize = 250
+	// personThumbnailMargin widens the face crop on each side, as a share
+	// of the face's larger dimension, so the thumbnail shows some context.
+	personThumbnailMargin  = 0.3
+	personThumbnailQuality = 85
+)
+
+// featureFace returns the face of the person on the user's asset assetID.
+func (s *Server) featureFace(ctx context.Context, userID uuid.UUID, personID pgtype.UUID, assetID string) (sqlc.AssetFace, error) {
+	id, err := uuid.Parse(assetID)
+	if err != nil {
+		return sqlc.AssetFace{}, status.Error(codes.InvalidArgument, "invalid feature face asset ID")
+	}
+	asset, err := s.queries.GetAsset(ctx, pgUUID(id))
+	if err != nil || !isPGUUID(asset.OwnerId, userID) {
+		return sqlc.AssetFace{}, status.Error(codes.NotFound, "asset not found")
+	}
+
+	face, err := s.queries.GetPersonFaceOnAsset(ctx, sqlc.GetPersonFaceOnAssetParams{
+		PersonId: personID,
+		AssetId:  asset.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.AssetFace{}, status.Error(codes.InvalidArgument, "the person has no face on this asset")
+	}
+	if err != nil {
+		return sqlc.AssetFace{}, status.Errorf(codes.Internal, "failed to get face: %v", err)
+	}
+	return face, nil
+}
+
+// GenerateThumbnail crops the face out of its asset, stores it as the
+// person's thumbnail and returns the thumbnail path.
+func GenerateThumbnail(ctx context.Context, queries *sqlc.Queries, store *storage.Service, person sqlc.Person, face sqlc.AssetFace) (string, error) {
+	if store == nil {
+		return "", errors.New("storage backend not configured")
+	}
+
+	data, err := faceImage(ctx, queries, store, face.AssetId)
+	if err != nil {
+		return "", err
+	}
+	img, err := imaging.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	thumbnail, err := cropFace(img, face)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: personThumbnailQuality}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	thumbnailPath := ThumbnailPath(person, face)
+	if err := store.Derivatives().UploadBytes(ctx, thumbnailPath, buf.Bytes(), "image/jpeg"); err != nil {
+		return "", fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	return thumbnailPath, nil
+}
+
+// faceImage reads the image faces of the asset were detected on: its preview
+// when there is one, as for face detection, else the original.
+func faceImage(ctx context.Context, queries *sqlc.Queries, store *storage.Service, assetID pgtype.UUID) ([]byte, error) {
+	previews, err := queries.GetAssetFilesByType(ctx, sqlc.GetAssetFilesByTypeParams{
+		AssetId: assetID,
+		Type:    "preview",
+	})
+	if err == nil && len(previews) > 0 {
+		if data, err := readAll(store.Derivatives().Download(ctx, previews[0].Path)); err == nil && len(data) > 0 {
+			return data, nil
+		}
+	}
+
+	asset, err := queries.GetAsset(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset: %w", err)
+	}
+	data, err := readAll(store.Download(ctx, asset.OriginalPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+	return data, nil
+}
+
+func readAll(reader io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// BestFace picks the face a person's thumbnail is cropped from by default:
+// the most confident detection, preferring frontal faces, whose bounding
+// boxes are close to square, over profiles. It returns false when there are
+// no faces.
+func BestFace(faces []sqlc.AssetFace) (sqlc.AssetFace, bool) {
+	var best sqlc.AssetFace
+	bestQuality := -1.0
+	for _, face := range faces {
+		if quality := faceQuality(face); quality > bestQuality {
+			best, bestQuality = face, quality
+		}
+	}
+	return best, bestQuality >= 0
+}
+
+// faceQuality rates a face by its detection score times how square its
+// bounding box is. Faces detected before scores were stored rate by the
+// box alone.
+func faceQuality(face sqlc.AssetFace) float64 {
+	width := float64(face.BoundingBoxX2 - face.BoundingBoxX1)
+	height := float64(face.BoundingBoxY2 - face.BoundingBoxY1)
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	frontal := min(width, height) / max(width, height)
+	if face.Score <= 0 {
+		return frontal
+	}
+	return float64(face.Score) * frontal
+}
+
+// cropFace crops a square around the face, with some margin, out of img and
+// scales it to the thumbnail size. The bounding box is relative to the image
+// the face was detected on, which may differ in size from img.
+func cropFace(img image.Image, face sqlc.AssetFace) (image.Image, error) {
+	if face.ImageWidth <= 0 || face.ImageHeight <= 0 {
+		return nil, errors.New("face has no image dimensions")
+	}
+	bounds := img.Bounds()
+	scaleX := float64(bounds.Dx()) / float64(face.ImageWidth)
+	scaleY := float64(bounds.Dy()) / float64(face.ImageHeight)
+	x1, x2 := float64(face.BoundingBoxX1)*scaleX, float64(face.BoundingBoxX2)*scaleX
+	y1, y2 := float64(face.BoundingBoxY1)*scaleY, float64(face.BoundingBoxY2)*scaleY
+
+	half := max(x2-x1, y2-y1) * (0.5 + personThumbnailMargin)
+	centerX, centerY := (x1+x2)/2, (y1+y2)/2
+	crop := image.Rect(
+		int(centerX-half), int(centerY-half),
+		int(centerX+half), int(centerY+half),
+	).Add(bounds.Min).Intersect(bounds)
+	if crop.Empty() {
+		return nil, errors.New("face is outside the image")
+	}
+
+	return imaging.Fill(imaging.Crop(img, crop), personThumbnailSize, personThumbnailSize,
+		imaging.Center, imaging.Lanczos), nil
+}
+
+// ThumbnailPath names the thumbnail after the face it shows, so a new
+// feature face gets a new path and clients do not keep showing a cached one.
+func ThumbnailPath(person sqlc.Person, face sqlc.AssetFace) string {
+	personID := uuid.UUID(person.ID.Bytes).String()
+	return path.Join("thumbs", uuid.UUID(person.OwnerId.Bytes).String(),
+		personID[0:2], personID[2:4], personID, uuid.UUID(face.ID.Bytes).String()+".jpeg")
+}
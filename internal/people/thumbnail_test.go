@@ -0,0 +1,78 @@
+package people
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestCropFace(t *testing.T) {
+	// A 400x200 image, red on the right half, with the face detected on a
+	// 200x100 preview.
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 200; x < 400; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	face := sqlc.AssetFace{
+		ImageWidth: 200, ImageHeight: 100,
+		BoundingBoxX1: 140, BoundingBoxY1: 40,
+		BoundingBoxX2: 160, BoundingBoxY2: 60,
+	}
+
+	thumbnail, err := cropFace(img, face)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, personThumbnailSize, personThumbnailSize), thumbnail.Bounds())
+	r, _, _, _ := thumbnail.At(personThumbnailSize/2, personThumbnailSize/2).RGBA()
+	assert.Equal(t, uint32(0xffff), r, "the crop should be taken from the scaled face box")
+}
+
+func TestCropFaceRejectsInvalidFaces(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+
+	_, err := cropFace(img, sqlc.AssetFace{BoundingBoxX2: 10, BoundingBoxY2: 10})
+	assert.Error(t, err)
+
+	_, err = cropFace(img, sqlc.AssetFace{
+		ImageWidth: 100, ImageHeight: 100,
+		BoundingBoxX1: 300, BoundingBoxY1: 300,
+		BoundingBoxX2: 310, BoundingBoxY2: 310,
+	})
+	assert.Error(t, err)
+}
+
+func TestPersonThumbnailPath(t *testing.T) {
+	owner := uuid.MustParse("aaaaaaaa-0000-0000-0000-000000000000")
+	personID := uuid.MustParse("12345678-0000-0000-0000-000000000000")
+	person := sqlc.Person{ID: pgUUID(personID), OwnerId: pgUUID(owner)}
+	face := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+	other := uuid.MustParse("eeeeeeee-0000-0000-0000-000000000000")
+
+	path := ThumbnailPath(person, sqlc.AssetFace{ID: pgUUID(face)})
+	assert.Equal(t, "thumbs/"+owner.String()+"/12/34/"+personID.String()+"/"+face.String()+".jpeg", path)
+	assert.NotEqual(t, path, ThumbnailPath(person, sqlc.AssetFace{ID: pgUUID(other)}))
+}
+
+func TestBestFace(t *testing.T) {
+	box := func(width, height int32, score float32) sqlc.AssetFace {
+		return sqlc.AssetFace{BoundingBoxX2: width, BoundingBoxY2: height, Score: score}
+	}
+
+	_, ok := BestFace(nil)
+	assert.False(t, ok)
+
+	face, ok := BestFace([]sqlc.AssetFace{box(100, 100, 0.7), box(100, 100, 0.9)})
+	require.True(t, ok)
+	assert.Equal(t, float32(0.9), face.Score, "the most confident face wins")
+
+	face, ok = BestFace([]sqlc.AssetFace{box(40, 100, 0.95), box(90, 100, 0.85)})
+	require.True(t, ok)
+	assert.Equal(t, int32(90), face.BoundingBoxX2, "a frontal face beats a slightly more confident profile")
+}
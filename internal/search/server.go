@@ -2,7 +2,10 @@ package search
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -13,6 +16,7 @@ import (
 
 	assetdomain "github.com/denysvitali/immich-go-backend/internal/assets"
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
@@ -38,9 +42,17 @@ func (s *Server) SearchMetadata(ctx context.Context, req *immichv1.SearchMetadat
 		return nil, status.Error(codes.Unauthenticated, "unauthorized")
 	}
 
+	fields, err := grpcutil.ParseFieldMask(&immichv1.AssetResponseDto{}, req.GetFields(), "id")
+	if err != nil {
+		return nil, err
+	}
+
 	searchReq := metadataSearchRequestFromFilter("", req.GetFilter())
 
 	result, err := s.service.SearchMetadata(ctx, userID, searchReq)
+	if errors.Is(err, config.ErrInvalidSort) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "search failed", err)
 	}
@@ -48,6 +60,15 @@ func (s *Server) SearchMetadata(ctx context.Context, req *immichv1.SearchMetadat
 	// Convert real search results to proto
 	assets := make([]*immichv1.AssetResponseDto, 0)
 	for _, item := range result.Items {
+		if !fields.All() {
+			// A projection is built from the search row alone, without
+			// reloading each asset.
+			dto := assetToSearchResponseDto(item.Asset)
+			fields.Apply(dto)
+			assets = append(assets, dto)
+			continue
+		}
+
 		// Get full asset details
 		id, err := uuid.Parse(item.ID)
 		if err != nil {
@@ -166,10 +187,10 @@ func (s *Server) SearchPlaces(ctx context.Context, req *immichv1.SearchPlacesReq
 	for i, place := range result.Places {
 		places[i] = &immichv1.PlaceResponseDto{
 			Name:      place.City,
-			Latitude:  0.0,
-			Longitude: 0.0,
+			Latitude:  place.Latitude,
+			Longitude: place.Longitude,
 			Admin1:    place.State,
-			Admin2:    place.City,
+			Admin2:    place.Country,
 		}
 	}
 
@@ -214,9 +235,12 @@ func (s *Server) GetSearchSuggestions(ctx context.Context, req *immichv1.GetSear
 	}
 
 	suggestReq := SuggestionsRequest{
-		IncludePeople:  stringValue(req.Country) != "",
-		IncludePlaces:  stringValue(req.State) != "",
-		IncludeCameras: stringValue(req.Make) != "",
+		Query:   stringValue(req.Query),
+		Type:    stringValue(req.Type),
+		Country: stringValue(req.Country),
+		State:   stringValue(req.State),
+		Make:    stringValue(req.Make),
+		Model:   stringValue(req.Model),
 	}
 
 	result, err := s.service.GetSearchSuggestions(ctx, userID, suggestReq)
@@ -224,14 +248,20 @@ func (s *Server) GetSearchSuggestions(ctx context.Context, req *immichv1.GetSear
 		return nil, grpcutil.SanitizedInternal(ctx, "search suggestions failed", err)
 	}
 
-	// Combine all suggestions into a single list
-	var suggestions []string
-	suggestions = append(suggestions, result.People...)
-	suggestions = append(suggestions, result.Places...)
-	suggestions = append(suggestions, result.Cameras...)
+	// Immich clients read the flat list; the groups keep the categories apart.
+	suggestions := []string{}
+	groups := make([]*immichv1.SearchSuggestionGroupDto, len(result.Groups))
+	for i, group := range result.Groups {
+		suggestions = append(suggestions, group.Values...)
+		groups[i] = &immichv1.SearchSuggestionGroupDto{
+			Type:   group.Type,
+			Values: group.Values,
+		}
+	}
 
 	return &immichv1.GetSearchSuggestionsResponse{
 		Suggestions: suggestions,
+		Groups:      groups,
 	}, nil
 }
 
@@ -247,25 +277,20 @@ func (s *Server) SearchExplore(ctx context.Context, req *emptypb.Empty) (*immich
 		return nil, grpcutil.SanitizedInternal(ctx, "explore search failed", err)
 	}
 
-	// Convert results to proto
-	items := make([]*immichv1.SearchExploreItemResponseDto, 0)
-	// Convert actual search results from database
+	items := make([]*immichv1.SearchExploreItemResponseDto, 0, len(result.Categories))
 	for _, category := range result.Categories {
-		// Create value items from category data
-		valueItems := make([]*immichv1.SearchExploreItemValueResponseDto, 0)
-
-		// Add a value item for this category
-		// In a real implementation, this would be populated with actual data
-		if len(category.AssetIDs) > 0 {
-			valueItems = append(valueItems, &immichv1.SearchExploreItemValueResponseDto{
-				Value: category.Name,
-				Data:  nil, // Would be populated with actual asset data from DB
-			})
+		values := make([]*immichv1.SearchExploreItemValueResponseDto, len(category.Items))
+		for i, item := range category.Items {
+			values[i] = &immichv1.SearchExploreItemValueResponseDto{
+				Value: item.Value,
+				Data:  assetToSearchResponseDto(item.Asset),
+				Count: item.AssetCount,
+			}
 		}
 
 		items = append(items, &immichv1.SearchExploreItemResponseDto{
-			FieldName: category.Name,
-			Items:     valueItems,
+			FieldName: category.FieldName,
+			Items:     values,
 		})
 	}
 
@@ -364,9 +389,9 @@ func (s *Server) SearchRandom(ctx context.Context, req *immichv1.SearchRandomReq
 		LensModel:   optionalText(req.GetLensModel()),
 		LibraryID:   optionalUUID(req.GetLibraryId()),
 		DeviceID:    optionalText(req.GetDeviceId()),
-		Limit:       optionalLimit(req.GetSize(), 100),
-		TakenAfter:  timestampToPg(req.GetTakenAfter()),
-		TakenBefore: timestampToPg(req.GetTakenBefore()),
+		Limit:       pgtype.Int4{Int32: s.service.config.PageSize(req.GetSize(), 100), Valid: true},
+		TakenAfter:  s.localTimestamp(req.GetTakenAfter()),
+		TakenBefore: s.localTimestamp(req.GetTakenBefore()),
 	}
 	if req.WithDeleted != nil {
 		params.WithDeleted = pgtype.Bool{Bool: req.GetWithDeleted(), Valid: true}
@@ -387,10 +412,7 @@ func (s *Server) SearchLargeAssets(ctx context.Context, req *immichv1.SearchLarg
 		return nil, status.Error(codes.Unauthenticated, "unauthorized")
 	}
 
-	size := req.GetSize()
-	if size <= 0 {
-		size = 100
-	}
+	size := s.service.config.PageSize(req.GetSize(), 100)
 
 	assets, err := s.service.db.SearchLargeAssets(ctx, sqlc.SearchLargeAssetsParams{
 		OwnerId: pgtype.UUID{Bytes: userID, Valid: true},
@@ -403,6 +425,58 @@ func (s *Server) SearchLargeAssets(ctx context.Context, req *immichv1.SearchLarg
 	return &immichv1.SearchLargeAssetsResponse{Assets: assetsToSearchResponseDtos(assets)}, nil
 }
 
+// SearchSimilar returns assets visually similar to the requested asset.
+func (s *Server) SearchSimilar(ctx context.Context, req *immichv1.SearchSimilarRequest) (*immichv1.SearchSimilarResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+
+	assetID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid asset ID")
+	}
+	visibility := req.GetVisibility()
+	switch sqlc.AssetVisibilityEnum(visibility) {
+	case "", sqlc.AssetVisibilityEnumArchive, sqlc.AssetVisibilityEnumTimeline,
+		sqlc.AssetVisibilityEnumHidden:
+	case sqlc.AssetVisibilityEnumLocked:
+		return nil, status.Error(codes.PermissionDenied, "locked assets are not searchable")
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid visibility")
+	}
+
+	result, err := s.service.SearchSimilar(ctx, userID, SimilarSearchRequest{
+		AssetID:    assetID,
+		Visibility: visibility,
+		Page:       int(req.GetPage()),
+		Size:       int(req.GetSize()),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAssetNotFound):
+			return nil, status.Error(codes.NotFound, "asset not found")
+		case errors.Is(err, ErrEmbeddingMissing):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, grpcutil.SanitizedInternal(ctx, "similar asset search failed", err)
+	}
+
+	items := make([]*immichv1.SimilarAssetResponseDto, len(result.Items))
+	for i, item := range result.Items {
+		items[i] = &immichv1.SimilarAssetResponseDto{
+			Asset:    assetToSearchResponseDto(item.Asset),
+			Distance: item.Distance,
+		}
+	}
+	resp := &immichv1.SearchSimilarResponse{Items: items}
+	if result.NextPage != nil {
+		next := int32(*result.NextPage)
+		resp.NextPage = &next
+	}
+	return resp, nil
+}
+
 // SearchAssetStatistics returns an asset count for the supplied metadata filters.
 func (s *Server) SearchAssetStatistics(ctx context.Context, req *immichv1.SearchAssetStatisticsRequest) (*immichv1.SearchAssetStatisticsResponse, error) {
 	userID, err := auth.GetUserIDFromContext(ctx)
@@ -422,8 +496,8 @@ func (s *Server) SearchAssetStatistics(ctx context.Context, req *immichv1.Search
 		LensModel:   optionalText(req.GetLensModel()),
 		LibraryID:   optionalUUID(req.GetLibraryId()),
 		DeviceID:    optionalText(req.GetDeviceId()),
-		TakenAfter:  timestampToPg(req.GetTakenAfter()),
-		TakenBefore: timestampToPg(req.GetTakenBefore()),
+		TakenAfter:  s.localTimestamp(req.GetTakenAfter()),
+		TakenBefore: s.localTimestamp(req.GetTakenBefore()),
 	})
 	if err != nil {
 		return nil, grpcutil.SanitizedInternal(ctx, "asset statistics search failed", err)
@@ -463,6 +537,9 @@ func metadataSearchRequestFromFilter(query string, filter *immichv1.SearchFilter
 	req.Model = stringValue(filter.Model)
 	req.LensModel = stringValue(filter.LensModel)
 	req.LibraryID = stringValue(filter.LibraryId)
+	if objects := stringValue(filter.Objects); objects != "" {
+		req.Labels = strings.Split(objects, ",")
+	}
 	req.IsFavorite = filter.IsFavorite
 	req.IsArchived = filter.IsArchived
 	req.IsEncoded = filter.IsEncoded
@@ -484,6 +561,13 @@ func metadataSearchRequestFromFilter(query string, filter *immichv1.SearchFilter
 	if filter.Page != nil && filter.GetPage() > 0 {
 		req.Page = int(filter.GetPage())
 	}
+	req.SortBy = filter.GetSortBy()
+	if req.SortBy == "" && filter.GetRecentlyAdded() {
+		req.SortBy = "created_at"
+	}
+	if filter.Order != nil {
+		req.Order = strings.ToLower(filter.GetOrder().String())
+	}
 	return req
 }
 
@@ -518,20 +602,27 @@ func assetToSearchResponseDto(asset sqlc.Asset) *immichv1.AssetResponseDto {
 		},
 	}
 	if asset.Duration.Valid {
-		dto.Duration = asset.Duration.String
+		dto.Duration = &asset.Duration.String
 	}
 	if asset.LibraryId.Valid {
-		dto.LibraryId = uuid.UUID(asset.LibraryId.Bytes).String()
+		libraryID := uuid.UUID(asset.LibraryId.Bytes).String()
+		dto.LibraryId = &libraryID
 	}
 	if asset.StackId.Valid {
-		dto.StackId = uuid.UUID(asset.StackId.Bytes).String()
+		stackID := uuid.UUID(asset.StackId.Bytes).String()
+		dto.StackId = &stackID
+	}
+	if asset.LivePhotoVideoId.Valid {
+		livePhotoID := uuid.UUID(asset.LivePhotoVideoId.Bytes).String()
+		dto.LivePhotoVideoId = &livePhotoID
 	}
 	if asset.DuplicateId.Valid {
 		duplicateID := uuid.UUID(asset.DuplicateId.Bytes).String()
 		dto.DuplicateId = &duplicateID
 	}
 	if len(asset.Thumbhash) > 0 {
-		dto.Thumbhash = string(asset.Thumbhash)
+		thumbhash := base64.StdEncoding.EncodeToString(asset.Thumbhash)
+		dto.Thumbhash = &thumbhash
 	}
 	return dto
 }
@@ -558,16 +649,11 @@ func assetTypeFilter(value immichv1.AssetType) string {
 	}
 }
 
-func optionalLimit(size int32, fallback int32) pgtype.Int4 {
-	if size <= 0 {
-		size = fallback
-	}
-	return pgtype.Int4{Int32: size, Valid: true}
-}
-
-func timestampToPg(ts *timestamppb.Timestamp) pgtype.Timestamptz {
+// localTimestamp interprets a user-supplied date in the server's default
+// timezone so it can be compared with "localDateTime".
+func (s *Server) localTimestamp(ts *timestamppb.Timestamp) pgtype.Timestamptz {
 	if ts == nil {
 		return pgtype.Timestamptz{}
 	}
-	return pgtype.Timestamptz{Time: ts.AsTime(), Valid: true}
+	return s.service.localTime(ts.AsTime())
 }
@@ -6,15 +6,21 @@ package search
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/ml"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 func TestSearchPerson_ShortQueries(t *testing.T) {
@@ -114,3 +120,325 @@ func TestSearchPerson_ShortQueries(t *testing.T) {
 		assert.True(t, resp.People[0].IsHidden)
 	})
 }
+
+func TestSearchExplore_PlacesAndCache(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	server := NewServer(service)
+
+	ownerID := uuid.New()
+	ownerUUID := pgtype.UUID{Bytes: ownerID, Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:          ownerUUID,
+		Email:       "explore@example.com",
+		Name:        "Explorer",
+		Password:    "secret",
+		IsOnboarded: true,
+	})
+	require.NoError(t, err)
+	ownerCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+
+	createAssetInCity := func(name, city string, taken time.Time) sqlc.Asset {
+		asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          ownerUUID,
+			DeviceId:         "test",
+			Type:             "IMAGE",
+			OriginalPath:     "/photos/" + name,
+			FileCreatedAt:    pgtype.Timestamptz{Time: taken, Valid: true},
+			FileModifiedAt:   pgtype.Timestamptz{Time: taken, Valid: true},
+			LocalDateTime:    pgtype.Timestamptz{Time: taken, Valid: true},
+			OriginalFileName: name,
+			Checksum:         []byte(name),
+			Visibility:       sqlc.AssetVisibilityEnumTimeline,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		_, err = tdb.Queries.CreateOrUpdateExif(ctx, sqlc.CreateOrUpdateExifParams{
+			AssetId: asset.ID,
+			City:    pgtype.Text{String: city, Valid: true},
+		})
+		require.NoError(t, err)
+		return asset
+	}
+
+	base := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	createAssetInCity("zurich-1.jpg", "Zürich", base)
+	latestZurich := createAssetInCity("zurich-2.jpg", "Zürich", base.Add(time.Hour))
+	createAssetInCity("bern-1.jpg", "Bern", base)
+
+	resp, err := server.SearchExplore(ownerCtx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 1, "tags and people are omitted when empty")
+	assert.Equal(t, ExploreFieldCity, resp.Items[0].FieldName)
+	require.Len(t, resp.Items[0].Items, 2)
+	assert.Equal(t, "Zürich", resp.Items[0].Items[0].Value)
+	assert.Equal(t, int64(2), resp.Items[0].Items[0].Count)
+	assert.Equal(t, uuid.UUID(latestZurich.ID.Bytes).String(), resp.Items[0].Items[0].Data.Id)
+
+	// A new city is not visible until the cached aggregation expires.
+	createAssetInCity("geneva-1.jpg", "Geneva", base)
+	resp, err = server.SearchExplore(ownerCtx, &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Len(t, resp.Items[0].Items, 2)
+}
+
+func TestSearchByDetectedObjects(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	server := NewServer(service)
+
+	ownerID := uuid.New()
+	ownerUUID := pgtype.UUID{Bytes: ownerID, Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:          ownerUUID,
+		Email:       "objects@example.com",
+		Name:        "Objects",
+		Password:    "secret",
+		IsOnboarded: true,
+	})
+	require.NoError(t, err)
+	ownerCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+
+	taken := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	createLabeledAsset := func(name string, labels []string, scores []float32) sqlc.Asset {
+		asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          ownerUUID,
+			DeviceId:         "test",
+			Type:             "IMAGE",
+			OriginalPath:     "/photos/" + name,
+			FileCreatedAt:    pgtype.Timestamptz{Time: taken, Valid: true},
+			FileModifiedAt:   pgtype.Timestamptz{Time: taken, Valid: true},
+			LocalDateTime:    pgtype.Timestamptz{Time: taken, Valid: true},
+			OriginalFileName: name,
+			Checksum:         []byte(name),
+			Visibility:       sqlc.AssetVisibilityEnumTimeline,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		require.NoError(t, tdb.Queries.UpsertAssetLabels(ctx, sqlc.UpsertAssetLabelsParams{
+			AssetID: asset.ID,
+			Labels:  labels,
+			Scores:  scores,
+		}))
+		return asset
+	}
+
+	dogOnBeach := createLabeledAsset("IMG_0001.jpg", []string{"dog", "beach"}, []float32{0.9, 0.8})
+	createLabeledAsset("IMG_0002.jpg", []string{"dog"}, []float32{0.35}) // below the search threshold
+	createLabeledAsset("IMG_0003.jpg", []string{"car"}, []float32{0.7})
+
+	resp, err := server.SearchMetadata(ownerCtx, &immichv1.SearchMetadataRequest{
+		Filter: &immichv1.SearchFilter{Objects: stringPtr("Dog")},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Assets, 1)
+	assert.Equal(t, uuid.UUID(dogOnBeach.ID.Bytes).String(), resp.Assets[0].Id)
+	assert.Equal(t, int32(1), resp.Total)
+
+	resp, err = server.SearchMetadata(ownerCtx, &immichv1.SearchMetadataRequest{
+		Filter: &immichv1.SearchFilter{Objects: stringPtr("Dog")},
+		Fields: &fieldmaskpb.FieldMask{Paths: []string{"fileCreatedAt", "checksum"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Assets, 1)
+	assert.Equal(t, uuid.UUID(dogOnBeach.ID.Bytes).String(), resp.Assets[0].Id)
+	assert.NotEmpty(t, resp.Assets[0].Checksum)
+	assert.NotNil(t, resp.Assets[0].FileCreatedAt)
+	assert.Empty(t, resp.Assets[0].OriginalPath)
+	assert.Nil(t, resp.Assets[0].Owner)
+
+	resp, err = server.SearchMetadata(ownerCtx, &immichv1.SearchMetadataRequest{
+		Filter: &immichv1.SearchFilter{Objects: stringPtr("dog,car")},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Assets, "every requested label must be present")
+
+	smart, err := server.SearchSmart(ownerCtx, &immichv1.SearchSmartRequest{Query: "beach"})
+	require.NoError(t, err)
+	require.Len(t, smart.Assets, 1, "the query also matches detected labels")
+	assert.Equal(t, uuid.UUID(dogOnBeach.ID.Bytes).String(), smart.Assets[0].Id)
+
+	explore, err := server.SearchExplore(ownerCtx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, explore.Items, 1)
+	assert.Equal(t, ExploreFieldObjects, explore.Items[0].FieldName)
+	values := make(map[string]int64)
+	for _, item := range explore.Items[0].Items {
+		values[item.Value] = item.Count
+	}
+	assert.Equal(t, map[string]int64{"dog": 1, "beach": 1, "car": 1}, values)
+}
+
+func TestGetSearchSuggestions_AccentInsensitivePrefix(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	server := NewServer(service)
+
+	ownerID := uuid.New()
+	ownerUUID := pgtype.UUID{Bytes: ownerID, Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:          ownerUUID,
+		Email:       "suggest@example.com",
+		Name:        "Suggester",
+		Password:    "secret",
+		IsOnboarded: true,
+	})
+	require.NoError(t, err)
+	ownerCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+
+	createAsset := func(name string, exif sqlc.CreateOrUpdateExifParams) {
+		taken := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+		asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          ownerUUID,
+			DeviceId:         "test",
+			Type:             "IMAGE",
+			OriginalPath:     "/photos/" + name,
+			FileCreatedAt:    pgtype.Timestamptz{Time: taken, Valid: true},
+			FileModifiedAt:   pgtype.Timestamptz{Time: taken, Valid: true},
+			LocalDateTime:    pgtype.Timestamptz{Time: taken, Valid: true},
+			OriginalFileName: name,
+			Checksum:         []byte(name),
+			Visibility:       sqlc.AssetVisibilityEnumTimeline,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		exif.AssetId = asset.ID
+		_, err = tdb.Queries.CreateOrUpdateExif(ctx, exif)
+		require.NoError(t, err)
+	}
+
+	createAsset("zurich.jpg", sqlc.CreateOrUpdateExifParams{
+		City:      pgtype.Text{String: "Zürich", Valid: true},
+		State:     pgtype.Text{String: "Zürich", Valid: true},
+		Country:   pgtype.Text{String: "Switzerland", Valid: true},
+		Latitude:  pgtype.Float8{Float64: 47.37, Valid: true},
+		Longitude: pgtype.Float8{Float64: 8.54, Valid: true},
+		Make:      pgtype.Text{String: "Nikon", Valid: true},
+		Model:     pgtype.Text{String: "Zf", Valid: true},
+	})
+	createAsset("bern.jpg", sqlc.CreateOrUpdateExifParams{
+		City:    pgtype.Text{String: "Bern", Valid: true},
+		Country: pgtype.Text{String: "Switzerland", Valid: true},
+	})
+
+	resp, err := server.GetSearchSuggestions(ownerCtx, &immichv1.GetSearchSuggestionsRequest{
+		Query: stringPtr("zur"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Zürich", "Zürich"}, resp.Suggestions)
+	require.Len(t, resp.Groups, 2)
+	assert.Equal(t, SuggestionTypeState, resp.Groups[0].Type)
+	assert.Equal(t, SuggestionTypeCity, resp.Groups[1].Type)
+
+	resp, err = server.GetSearchSuggestions(ownerCtx, &immichv1.GetSearchSuggestionsRequest{
+		Type:    stringPtr(SuggestionTypeCity),
+		Country: stringPtr("Switzerland"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bern", "Zürich"}, resp.Suggestions)
+
+	places, err := server.SearchPlaces(ownerCtx, &immichv1.SearchPlacesRequest{Name: "ZURICH"})
+	require.NoError(t, err)
+	require.Len(t, places.Places, 1)
+	assert.Equal(t, "Zürich", places.Places[0].Name)
+	assert.InDelta(t, 47.37, places.Places[0].Latitude, 0.001)
+}
+
+func TestSearchSimilar_NearestNeighbors(t *testing.T) {
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries, nil, nil)
+	server := NewServer(service)
+
+	ownerID := uuid.New()
+	ownerUUID := pgtype.UUID{Bytes: ownerID, Valid: true}
+	_, err := tdb.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:          ownerUUID,
+		Email:       "similar@example.com",
+		Name:        "Similar",
+		Password:    "secret",
+		IsOnboarded: true,
+	})
+	require.NoError(t, err)
+	ownerCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+
+	// Embeddings point along a direction in the first two dimensions so the
+	// cosine distance to the source grows with the angle.
+	createAsset := func(name string, visibility sqlc.AssetVisibilityEnum, x, y float32) sqlc.Asset {
+		taken := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+		asset, err := tdb.Queries.CreateAsset(ctx, sqlc.CreateAssetParams{
+			DeviceAssetId:    name,
+			OwnerId:          ownerUUID,
+			DeviceId:         "test",
+			Type:             "IMAGE",
+			OriginalPath:     "/photos/" + name,
+			FileCreatedAt:    pgtype.Timestamptz{Time: taken, Valid: true},
+			FileModifiedAt:   pgtype.Timestamptz{Time: taken, Valid: true},
+			LocalDateTime:    pgtype.Timestamptz{Time: taken, Valid: true},
+			OriginalFileName: name,
+			Checksum:         []byte(name),
+			Visibility:       visibility,
+			Status:           sqlc.AssetsStatusEnumActive,
+		})
+		require.NoError(t, err)
+		if x != 0 || y != 0 {
+			embedding := make([]float32, 512)
+			embedding[0], embedding[1] = x, y
+			_, err = tdb.Queries.UpsertSmartSearch(ctx, sqlc.UpsertSmartSearchParams{
+				AssetId:   asset.ID,
+				Embedding: ml.FormatVector(embedding),
+			})
+			require.NoError(t, err)
+		}
+		return asset
+	}
+
+	source := createAsset("burst-1.jpg", sqlc.AssetVisibilityEnumTimeline, 1, 0)
+	closest := createAsset("burst-2.jpg", sqlc.AssetVisibilityEnumTimeline, 1, 0.1)
+	further := createAsset("burst-3.jpg", sqlc.AssetVisibilityEnumArchive, 1, 1)
+	createAsset("hidden.jpg", sqlc.AssetVisibilityEnumHidden, 1, 0.05)
+	noEmbedding := createAsset("pending.jpg", sqlc.AssetVisibilityEnumTimeline, 0, 0)
+
+	resp, err := server.SearchSimilar(ownerCtx, &immichv1.SearchSimilarRequest{
+		Id:   uuid.UUID(source.ID.Bytes).String(),
+		Size: int32Ptr(1),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, uuid.UUID(closest.ID.Bytes).String(), resp.Items[0].Asset.Id)
+	require.NotNil(t, resp.NextPage)
+
+	resp, err = server.SearchSimilar(ownerCtx, &immichv1.SearchSimilarRequest{
+		Id:   uuid.UUID(source.ID.Bytes).String(),
+		Page: resp.NextPage,
+		Size: int32Ptr(1),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, uuid.UUID(further.ID.Bytes).String(), resp.Items[0].Asset.Id)
+	assert.Greater(t, resp.Items[0].Distance, 0.0)
+	assert.Nil(t, resp.NextPage, "hidden assets are excluded by default")
+
+	resp, err = server.SearchSimilar(ownerCtx, &immichv1.SearchSimilarRequest{
+		Id:         uuid.UUID(source.ID.Bytes).String(),
+		Visibility: stringPtr(string(sqlc.AssetVisibilityEnumArchive)),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 1)
+	assert.Equal(t, uuid.UUID(further.ID.Bytes).String(), resp.Items[0].Asset.Id)
+
+	_, err = server.SearchSimilar(ownerCtx, &immichv1.SearchSimilarRequest{
+		Id: uuid.UUID(noEmbedding.ID.Bytes).String(),
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
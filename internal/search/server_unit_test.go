@@ -4,7 +4,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -99,6 +103,48 @@ func TestMetadataSearchRequestFromFilterMapsSupportedFields(t *testing.T) {
 	assertBoolPtr(t, "isExternal", req.IsExternal, false)
 }
 
+func TestMetadataSearchRequestFromFilterObjects(t *testing.T) {
+	req := metadataSearchRequestFromFilter("", &immichv1.SearchFilter{
+		Objects: stringPtr("Dog, beach,,dog"),
+	})
+
+	got := normalizeLabelFilter(req.Labels)
+	if len(got) != 2 || got[0] != "dog" || got[1] != "beach" {
+		t.Fatalf("unexpected label filter: %q", got)
+	}
+	if normalizeLabelFilter([]string{" ", ""}) != nil {
+		t.Fatal("expected blank labels to mean no filter")
+	}
+}
+
+func TestMetadataSearchRequestFromFilterSort(t *testing.T) {
+	req := metadataSearchRequestFromFilter("", &immichv1.SearchFilter{})
+	if req.SortBy != "" || req.Order != "" {
+		t.Fatalf("expected the configured sort, got %q %q", req.SortBy, req.Order)
+	}
+
+	recentlyAdded := true
+	order := immichv1.AssetOrder_ASC
+	req = metadataSearchRequestFromFilter("", &immichv1.SearchFilter{RecentlyAdded: &recentlyAdded, Order: &order})
+	if req.SortBy != "created_at" || req.Order != "asc" {
+		t.Fatalf("expected created_at asc, got %q %q", req.SortBy, req.Order)
+	}
+
+	req = metadataSearchRequestFromFilter("", &immichv1.SearchFilter{RecentlyAdded: &recentlyAdded, SortBy: stringPtr("filename")})
+	if req.SortBy != "filename" {
+		t.Fatalf("expected sort_by to win over recently_added, got %q", req.SortBy)
+	}
+}
+
+func TestEscapeLikePattern(t *testing.T) {
+	if got := escapeLikePattern(`100%_off\`); got != `100\%\_off\\` {
+		t.Fatalf("expected wildcards to be escaped, got %q", got)
+	}
+	if got := escapeLikePattern("Zürich"); got != "Zürich" {
+		t.Fatalf("expected plain text to be unchanged, got %q", got)
+	}
+}
+
 func assertBoolPtr(t *testing.T, name string, got *bool, want bool) {
 	t.Helper()
 	if got == nil {
@@ -120,3 +166,81 @@ func boolPtr(value bool) *bool {
 func int32Ptr(value int32) *int32 {
 	return &value
 }
+
+func TestLocalTimeUsesDefaultTimeZone(t *testing.T) {
+	// Midnight of 2024-01-02 in Kiritimati (UTC+14) is still 2024-01-01 in
+	// UTC; it must be compared with localDateTime as 2024-01-02 00:00.
+	instant := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	svc := &Service{config: &config.Config{Server: config.ServerConfig{DefaultTimeZone: "Pacific/Kiritimati"}}}
+	got := svc.localTime(instant)
+	want := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+	if !got.Valid || !got.Time.Equal(want) {
+		t.Fatalf("expected %s, got %+v", want, got)
+	}
+
+	// Across a DST change the offset of the date itself is used.
+	svc.config.Server.DefaultTimeZone = "Europe/Zurich"
+	got = svc.localTime(time.Date(2024, time.October, 26, 22, 0, 0, 0, time.UTC))
+	want = time.Date(2024, time.October, 27, 0, 0, 0, 0, time.UTC)
+	if !got.Time.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, got.Time)
+	}
+
+	if svc.localTime(time.Time{}).Valid {
+		t.Fatal("expected an unset date to stay unset")
+	}
+
+	// Without a config the dates are taken as UTC.
+	svc = &Service{}
+	if got := svc.localTime(instant); !got.Time.Equal(instant) {
+		t.Fatalf("expected %s, got %s", instant, got.Time)
+	}
+}
+
+func TestAssetToSearchResponseDtoLeavesAbsentFieldsUnset(t *testing.T) {
+	asset := sqlc.Asset{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, Type: "IMAGE"}
+
+	dto := assetToSearchResponseDto(asset)
+	if dto.Duration != nil || dto.LibraryId != nil || dto.StackId != nil || dto.LivePhotoVideoId != nil || dto.Thumbhash != nil {
+		t.Fatalf("expected absent fields to be unset, got %v", dto)
+	}
+
+	livePhotoID := uuid.New()
+	asset.LivePhotoVideoId = pgtype.UUID{Bytes: livePhotoID, Valid: true}
+	dto = assetToSearchResponseDto(asset)
+	if dto.GetLivePhotoVideoId() != livePhotoID.String() {
+		t.Fatalf("expected live photo video %s, got %q", livePhotoID, dto.GetLivePhotoVideoId())
+	}
+}
+
+func TestReadsFallBackToPrimaryQueries(t *testing.T) {
+	primary := sqlc.New(nil)
+	service := NewService(primary, nil, &config.Config{})
+	if service.reads() != primary {
+		t.Fatal("reads() should use the primary queries without read queries")
+	}
+
+	replica := sqlc.New(nil)
+	if service.WithReadQueries(replica) != service {
+		t.Fatal("WithReadQueries() should return the service")
+	}
+	if service.reads() != replica {
+		t.Fatal("reads() should use the read queries")
+	}
+}
+
+func TestInvalidateExploreDropsOnlyThatUser(t *testing.T) {
+	service := NewService(nil, nil, &config.Config{})
+	userID, otherID := uuid.New(), uuid.New()
+	service.storeExplore(userID, &ExploreResult{})
+	service.storeExplore(otherID, &ExploreResult{})
+
+	service.InvalidateExplore(userID)
+	if _, ok := service.cachedExplore(userID); ok {
+		t.Fatal("the Explore page of the user should be computed again")
+	}
+	if _, ok := service.cachedExplore(otherID); !ok {
+		t.Fatal("the Explore page of other users should stay cached")
+	}
+}
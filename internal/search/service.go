@@ -4,22 +4,37 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"time"
 
+	assetdomain "github.com/denysvitali/immich-go-backend/internal/assets"
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/ml"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrAssetNotFound    = errors.New("asset not found")
+	ErrEmbeddingMissing = errors.New("asset has no embedding; run smart search for it first")
+)
+
 // Service handles search operations
 type Service struct {
 	db       *sqlc.Queries
 	mlClient *ml.Client
 	config   *config.Config
+	// readDB runs the Explore aggregations; nil uses db
+	readDB *sqlc.Queries
+
+	exploreMu    sync.Mutex
+	exploreCache map[uuid.UUID]exploreCacheEntry
 }
 
 // NewService creates a new search service. mlClient may be nil (smart search
@@ -32,38 +47,59 @@ func NewService(db *sqlc.Queries, mlClient *ml.Client, cfg *config.Config) *Serv
 	}
 }
 
+// WithReadQueries runs the Explore aggregations with queries, such as those
+// of a read replica, and returns s.
+func (s *Service) WithReadQueries(queries *sqlc.Queries) *Service {
+	s.readDB = queries
+	return s
+}
+
+// reads returns the queries of reads that tolerate replication lag.
+func (s *Service) reads() *sqlc.Queries {
+	if s.readDB != nil {
+		return s.readDB
+	}
+	return s.db
+}
+
 // SearchMetadata searches assets by metadata
 func (s *Service) SearchMetadata(ctx context.Context, userID uuid.UUID, req MetadataSearchRequest) (*SearchResult, error) {
-	if req.Size <= 0 {
-		req.Size = 30
-	}
+	req.Size = s.pageSize(req.Size)
 	if req.Page < 0 {
 		req.Page = 0
 	}
+	sort, err := s.config.ListSorting().AssetSort(req.SortBy, req.Order)
+	if err != nil {
+		return nil, err
+	}
 
 	// Execute search
 	params := sqlc.SearchAssetsFilteredParams{
-		OwnerID:     pgutil.UUIDToPgtype(userID),
-		Query:       optionalText(req.Query),
-		Type:        optionalText(req.Type),
-		IsFavorite:  optionalBool(req.IsFavorite),
-		IsArchived:  optionalBool(req.IsArchived),
-		City:        optionalText(req.City),
-		State:       optionalText(req.State),
-		Country:     optionalText(req.Country),
-		Make:        optionalText(req.Make),
-		Model:       optionalText(req.Model),
-		LensModel:   optionalText(req.LensModel),
-		LibraryID:   optionalUUID(req.LibraryID),
-		DeviceID:    optionalText(req.DeviceID),
-		TakenAfter:  optionalTime(req.TakenAfter),
-		TakenBefore: optionalTime(req.TakenBefore),
-		IsEncoded:   optionalBool(req.IsEncoded),
-		IsMotion:    optionalBool(req.IsMotion),
-		IsOffline:   optionalBool(req.IsOffline),
-		IsExternal:  optionalBool(req.IsExternal),
-		Limit:       int32(req.Size),
-		Offset:      int32(req.Page * req.Size),
+		OwnerID:       pgutil.UUIDToPgtype(userID),
+		Query:         optionalText(req.Query),
+		Type:          optionalText(req.Type),
+		IsFavorite:    optionalBool(req.IsFavorite),
+		IsArchived:    optionalBool(req.IsArchived),
+		City:          optionalText(req.City),
+		State:         optionalText(req.State),
+		Country:       optionalText(req.Country),
+		Make:          optionalText(req.Make),
+		Model:         optionalText(req.Model),
+		LensModel:     optionalText(req.LensModel),
+		LibraryID:     optionalUUID(req.LibraryID),
+		DeviceID:      optionalText(req.DeviceID),
+		TakenAfter:    s.localTime(req.TakenAfter),
+		TakenBefore:   s.localTime(req.TakenBefore),
+		IsEncoded:     optionalBool(req.IsEncoded),
+		IsMotion:      optionalBool(req.IsMotion),
+		IsOffline:     optionalBool(req.IsOffline),
+		IsExternal:    optionalBool(req.IsExternal),
+		Limit:         int32(req.Size),
+		Offset:        int32(req.Page * req.Size),
+		LabelMinScore: s.labelMinScore(),
+		Labels:        normalizeLabelFilter(req.Labels),
+		SortBy:        sort.Field,
+		Descending:    sort.Descending,
 	}
 	assets, err := s.db.SearchAssetsFiltered(ctx, params)
 	if err != nil {
@@ -72,25 +108,27 @@ func (s *Service) SearchMetadata(ctx context.Context, userID uuid.UUID, req Meta
 
 	// Get total count
 	count, err := s.db.CountSearchAssetsFilteredForPage(ctx, sqlc.CountSearchAssetsFilteredForPageParams{
-		OwnerID:     params.OwnerID,
-		Query:       params.Query,
-		Type:        params.Type,
-		IsFavorite:  params.IsFavorite,
-		IsArchived:  params.IsArchived,
-		City:        params.City,
-		State:       params.State,
-		Country:     params.Country,
-		Make:        params.Make,
-		Model:       params.Model,
-		LensModel:   params.LensModel,
-		LibraryID:   params.LibraryID,
-		DeviceID:    params.DeviceID,
-		TakenAfter:  params.TakenAfter,
-		TakenBefore: params.TakenBefore,
-		IsEncoded:   params.IsEncoded,
-		IsMotion:    params.IsMotion,
-		IsOffline:   params.IsOffline,
-		IsExternal:  params.IsExternal,
+		OwnerID:       params.OwnerID,
+		Query:         params.Query,
+		Type:          params.Type,
+		IsFavorite:    params.IsFavorite,
+		IsArchived:    params.IsArchived,
+		City:          params.City,
+		State:         params.State,
+		Country:       params.Country,
+		Make:          params.Make,
+		Model:         params.Model,
+		LensModel:     params.LensModel,
+		LibraryID:     params.LibraryID,
+		DeviceID:      params.DeviceID,
+		TakenAfter:    params.TakenAfter,
+		TakenBefore:   params.TakenBefore,
+		IsEncoded:     params.IsEncoded,
+		IsMotion:      params.IsMotion,
+		IsOffline:     params.IsOffline,
+		IsExternal:    params.IsExternal,
+		LabelMinScore: params.LabelMinScore,
+		Labels:        params.Labels,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to count search results: %w", err)
@@ -108,8 +146,11 @@ func (s *Service) SearchMetadata(ctx context.Context, userID uuid.UUID, req Meta
 			UpdatedAt:    pgutil.TimestamptzToTime(asset.UpdatedAt),
 			IsFavorite:   asset.IsFavorite,
 			IsArchived:   false, // Not in current schema
-			Duration:     &asset.Duration.String,
 			// Add more fields as needed
+			Asset: asset,
+		}
+		if asset.Duration.Valid {
+			items[i].Duration = &asset.Duration.String
 		}
 	}
 
@@ -126,9 +167,7 @@ func (s *Service) SearchMetadata(ctx context.Context, userID uuid.UUID, req Meta
 // including single-character queries. Hidden people are excluded unless
 // req.WithHidden is true.
 func (s *Service) SearchPeople(ctx context.Context, userID uuid.UUID, req PeopleSearchRequest) (*PeopleSearchResult, error) {
-	if req.Size <= 0 {
-		req.Size = 30
-	}
+	req.Size = s.pageSize(req.Size)
 	if req.Page < 0 {
 		req.Page = 0
 	}
@@ -165,37 +204,27 @@ func (s *Service) SearchPeople(ctx context.Context, userID uuid.UUID, req People
 	}, nil
 }
 
-// SearchPlaces searches for locations
+// SearchPlaces searches for locations in the user's library by name prefix
 func (s *Service) SearchPlaces(ctx context.Context, userID uuid.UUID, req PlacesSearchRequest) (*PlacesSearchResult, error) {
-	// Search for places
 	places, err := s.db.SearchPlaces(ctx, sqlc.SearchPlacesParams{
-		OwnerId: pgutil.UUIDToPgtype(userID),
-		Column2: pgtype.Text{String: req.Query, Valid: true},
-		Limit:   int32(req.Size),
-		Offset:  int32(req.Page * req.Size),
+		OwnerID:    pgutil.UUIDToPgtype(userID),
+		Prefix:     escapeLikePattern(req.Query),
+		PageSize:   int32(req.Size),
+		PageOffset: int32(req.Page * req.Size),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search places: %w", err)
 	}
 
-	// Convert to search results
 	items := make([]*PlaceResult, len(places))
 	for i, place := range places {
-		var city, state, country string
-		if place.City.Valid {
-			city = place.City.String
-		}
-		if place.State.Valid {
-			state = place.State.String
-		}
-		if place.Country.Valid {
-			country = place.Country.String
-		}
 		items[i] = &PlaceResult{
-			City:       city,
-			State:      state,
-			Country:    country,
-			AssetCount: 0, // Not returned by our query
+			City:       place.City,
+			State:      place.State,
+			Country:    place.Country,
+			Latitude:   place.Latitude,
+			Longitude:  place.Longitude,
+			AssetCount: int(place.AssetCount),
 		}
 	}
 
@@ -231,62 +260,108 @@ func (s *Service) SearchCities(ctx context.Context, userID uuid.UUID, req Cities
 	return results, nil
 }
 
-// GetSearchSuggestions returns search suggestions based on user's data
+// GetSearchSuggestions returns autocomplete suggestions from the user's
+// library, grouped by category in SuggestionTypes order.
 func (s *Service) GetSearchSuggestions(ctx context.Context, userID uuid.UUID, req SuggestionsRequest) (*SuggestionsResult, error) {
-	suggestions := &SuggestionsResult{
-		People:    []string{},
-		Places:    []string{},
-		Tags:      []string{},
-		Albums:    []string{},
-		Cameras:   []string{},
-		FileTypes: []string{},
-	}
-
-	// Get people suggestions
-	if req.IncludePeople {
-		people, err := s.db.GetTopPeople(ctx, sqlc.GetTopPeopleParams{
-			OwnerId: pgutil.UUIDToPgtype(userID),
-			Limit:   10,
-		})
-		if err == nil {
-			for _, p := range people {
-				suggestions.People = append(suggestions.People, p.Name)
-			}
-		}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSuggestionLimit
+	}
+
+	rows, err := s.db.GetSearchSuggestions(ctx, sqlc.GetSearchSuggestionsParams{
+		OwnerID:        pgutil.UUIDToPgtype(userID),
+		Prefix:         escapeLikePattern(req.Query),
+		Category:       optionalText(req.Type),
+		Country:        optionalText(req.Country),
+		State:          optionalText(req.State),
+		Make:           optionalText(req.Make),
+		Model:          optionalText(req.Model),
+		MaxPerCategory: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search suggestions: %w", err)
 	}
 
-	// Get place suggestions
-	if req.IncludePlaces {
-		places, err := s.db.GetTopPlaces(ctx, int32(10))
-		if err == nil {
-			for _, p := range places {
-				if p.City.Valid && p.City.String != "" {
-					suggestions.Places = append(suggestions.Places, p.City.String)
-				}
-			}
+	byType := make(map[string][]string)
+	for _, row := range rows {
+		byType[row.Category] = append(byType[row.Category], row.Value)
+	}
+
+	result := &SuggestionsResult{Groups: []SuggestionGroup{}}
+	for _, suggestionType := range SuggestionTypes {
+		if values := byType[suggestionType]; len(values) > 0 {
+			result.Groups = append(result.Groups, SuggestionGroup{Type: suggestionType, Values: values})
 		}
 	}
+	return result, nil
+}
+
+// escapeLikePattern escapes LIKE wildcards so user input matches literally.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// SearchSimilar returns the user's assets nearest to the source asset's CLIP
+// embedding by cosine distance, excluding the source itself.
+func (s *Service) SearchSimilar(ctx context.Context, userID uuid.UUID, req SimilarSearchRequest) (*SimilarSearchResult, error) {
+	req.Size = s.pageSize(req.Size)
+	if req.Page < 0 {
+		req.Page = 0
+	}
 
-	// Get camera make/model suggestions
-	if req.IncludeCameras {
-		cameras, err := s.db.GetDistinctCameras(ctx, sqlc.GetDistinctCamerasParams{
-			OwnerId: pgutil.UUIDToPgtype(userID),
-			Limit:   10,
-		})
-		if err == nil {
-			for _, c := range cameras {
-				if c.Make.Valid && c.Model.Valid && c.Make.String != "" && c.Model.String != "" {
-					suggestions.Cameras = append(suggestions.Cameras, fmt.Sprintf("%s %s", c.Make.String, c.Model.String))
-				}
-			}
+	assetID := pgutil.UUIDToPgtype(req.AssetID)
+	asset, err := s.db.GetAssetByID(ctx, assetID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAssetNotFound
 		}
+		return nil, fmt.Errorf("failed to get asset: %w", err)
+	}
+	if pgutil.PgtypeToUUID(asset.OwnerId) != userID {
+		return nil, ErrAssetNotFound
+	}
+
+	embeddings, err := s.db.GetSmartSearch(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset embedding: %w", err)
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmbeddingMissing
+	}
+
+	visibility := sqlc.NullAssetVisibilityEnum{}
+	if req.Visibility != "" {
+		visibility = sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: sqlc.AssetVisibilityEnum(req.Visibility), Valid: true}
 	}
 
-	return suggestions, nil
+	// Fetch one extra row to tell whether another page follows.
+	rows, err := s.db.SearchSimilarAssets(ctx, sqlc.SearchSimilarAssetsParams{
+		AssetID:    assetID,
+		OwnerID:    pgutil.UUIDToPgtype(userID),
+		Visibility: visibility,
+		PageSize:   int32(req.Size + 1),
+		PageOffset: int32(req.Page * req.Size),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search similar assets: %w", err)
+	}
+
+	result := &SimilarSearchResult{Items: make([]SimilarAsset, 0, len(rows))}
+	if len(rows) > req.Size {
+		rows = rows[:req.Size]
+		next := req.Page + 1
+		result.NextPage = &next
+	}
+	for _, row := range rows {
+		result.Items = append(result.Items, SimilarAsset{Asset: row.Asset, Distance: row.Distance})
+	}
+	return result, nil
 }
 
 // SearchSmart performs CLIP embedding search when ML is enabled and reachable.
-// On any ML failure it degrades to metadata search so the API stays useful.
+// On any ML failure it degrades to metadata search so the API stays useful;
+// that search also matches the query against detected object labels. The
+// Labels filter applies to both.
 func (s *Service) SearchSmart(ctx context.Context, userID uuid.UUID, req SmartSearchRequest) (*SearchResult, error) {
 	if req.Query == "" || s.mlClient == nil || s.config == nil || !s.config.CLIPActive() {
 		return s.SearchMetadata(ctx, userID, req)
@@ -305,9 +380,7 @@ func (s *Service) SearchSmart(ctx context.Context, userID uuid.UUID, req SmartSe
 }
 
 func (s *Service) searchByCLIP(ctx context.Context, userID uuid.UUID, req SmartSearchRequest) (*SearchResult, error) {
-	if req.Size <= 0 {
-		req.Size = 30
-	}
+	req.Size = s.pageSize(req.Size)
 	if req.Page < 0 {
 		req.Page = 0
 	}
@@ -331,10 +404,12 @@ func (s *Service) searchByCLIP(ctx context.Context, userID uuid.UUID, req SmartS
 	}
 
 	rows, err := s.db.SearchAssetsByEmbedding(ctx, sqlc.SearchAssetsByEmbeddingParams{
-		OwnerID:     pgutil.UUIDToPgtype(userID),
-		Embedding:   ml.FormatVector(embedding),
-		MaxDistance: maxDistance,
-		ResultLimit: limit,
+		OwnerID:       pgutil.UUIDToPgtype(userID),
+		Embedding:     ml.FormatVector(embedding),
+		MaxDistance:   maxDistance,
+		ResultLimit:   limit,
+		LabelMinScore: s.labelMinScore(),
+		Labels:        normalizeLabelFilter(req.Labels),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("embedding search: %w", err)
@@ -377,100 +452,164 @@ func (s *Service) searchByCLIP(ctx context.Context, userID uuid.UUID, req SmartS
 	}, nil
 }
 
-// SearchExplore returns explore/discovery results
+// SearchExplore returns the Explore screen tiles: places, tags, detected
+// objects and people, each with an asset count and a representative asset.
+// Categories without any data (no geocoding, tags, object labels or
+// recognised faces yet) are omitted. Results are cached per user for
+// exploreCacheTTL because the aggregation is expensive and runs every time
+// the app opens, and the aggregations run on the read queries.
 func (s *Service) SearchExplore(ctx context.Context, userID uuid.UUID) (*ExploreResult, error) {
+	if cached, ok := s.cachedExplore(userID); ok {
+		return cached, nil
+	}
+
 	result := &ExploreResult{
 		Categories: []ExploreCategory{},
 	}
 
 	ownerUUID := pgutil.UUIDToPgtype(userID)
+	reads := s.reads()
 
-	// Recent uploads
-	recentAssets, err := s.db.GetRecentAssets(ctx, sqlc.GetRecentAssetsParams{
-		OwnerId: ownerUUID,
-		Limit:   12,
-	})
-	if err == nil && len(recentAssets) > 0 {
-		result.Categories = append(result.Categories, ExploreCategory{
-			Name:      "Recently Added",
-			AssetIDs:  assetsToIDs(recentAssets),
-			Thumbnail: pgutil.PgtypeToUUID(recentAssets[0].ID).String(),
-		})
-	}
-
-	// Favorites
-	favoriteAssets, err := s.db.GetFavoriteAssets(ctx, sqlc.GetFavoriteAssetsParams{
-		OwnerId: ownerUUID,
-		Limit:   12,
-		Offset:  0,
+	cities, err := reads.GetExploreCities(ctx, sqlc.GetExploreCitiesParams{
+		OwnerID:  ownerUUID,
+		MaxItems: exploreMaxItems,
 	})
-	if err == nil && len(favoriteAssets) > 0 {
-		result.Categories = append(result.Categories, ExploreCategory{
-			Name:      "Favorites",
-			AssetIDs:  assetsToIDs(favoriteAssets),
-			Thumbnail: pgutil.PgtypeToUUID(favoriteAssets[0].ID).String(),
-		})
-	}
-
-	// Archived
-	archivedAssets, err := s.db.GetArchivedAssets(ctx, sqlc.GetArchivedAssetsParams{
-		OwnerId: ownerUUID,
-		Limit:   12,
-		Offset:  0,
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explore places: %w", err)
+	}
+	if len(cities) > 0 {
+		category := ExploreCategory{FieldName: ExploreFieldCity}
+		for _, city := range cities {
+			category.Items = append(category.Items, ExploreItem{
+				Value:      city.City,
+				AssetCount: city.AssetCount,
+				Asset:      city.Asset,
+			})
+		}
+		result.Categories = append(result.Categories, category)
+	}
+
+	tags, err := reads.GetExploreTags(ctx, sqlc.GetExploreTagsParams{
+		OwnerID:  ownerUUID,
+		MaxItems: exploreMaxItems,
 	})
-	if err == nil && len(archivedAssets) > 0 {
-		result.Categories = append(result.Categories, ExploreCategory{
-			Name:      "Archive",
-			AssetIDs:  assetsToIDs(archivedAssets),
-			Thumbnail: pgutil.PgtypeToUUID(archivedAssets[0].ID).String(),
-		})
-	}
-
-	// Top people - show people with most assets
-	topPeople, err := s.db.GetTopPeople(ctx, sqlc.GetTopPeopleParams{
-		OwnerId: ownerUUID,
-		Limit:   6,
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explore tags: %w", err)
+	}
+	if len(tags) > 0 {
+		category := ExploreCategory{FieldName: ExploreFieldTags}
+		for _, tag := range tags {
+			category.Items = append(category.Items, ExploreItem{
+				Value:      tag.Tag,
+				AssetCount: tag.AssetCount,
+				Asset:      tag.Asset,
+			})
+		}
+		result.Categories = append(result.Categories, category)
+	}
+
+	labels, err := reads.GetExploreLabels(ctx, sqlc.GetExploreLabelsParams{
+		OwnerID:  ownerUUID,
+		MaxItems: exploreMaxItems,
+		MinScore: s.labelMinScore(),
 	})
-	if err == nil && len(topPeople) > 0 {
-		for _, person := range topPeople {
-			if person.Name != "" {
-				result.Categories = append(result.Categories, ExploreCategory{
-					Name:      person.Name,
-					AssetIDs:  []string{}, // Person category doesn't have asset IDs directly
-					Thumbnail: person.ThumbnailPath,
-				})
-			}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explore objects: %w", err)
+	}
+	if len(labels) > 0 {
+		category := ExploreCategory{FieldName: ExploreFieldObjects}
+		for _, label := range labels {
+			category.Items = append(category.Items, ExploreItem{
+				Value:      label.Label,
+				AssetCount: label.AssetCount,
+				Asset:      label.Asset,
+			})
 		}
+		result.Categories = append(result.Categories, category)
 	}
 
-	// Top places
-	topPlaces, err := s.db.GetTopPlaces(ctx, 6)
-	if err == nil && len(topPlaces) > 0 {
-		for _, place := range topPlaces {
-			if place.City.Valid && place.City.String != "" {
-				placeName := place.City.String
-				if place.Country.Valid && place.Country.String != "" {
-					placeName = fmt.Sprintf("%s, %s", place.City.String, place.Country.String)
-				}
-				result.Categories = append(result.Categories, ExploreCategory{
-					Name:      placeName,
-					AssetIDs:  []string{},
-					Thumbnail: "",
-				})
-			}
+	people, err := reads.GetExplorePeople(ctx, sqlc.GetExplorePeopleParams{
+		OwnerID:  ownerUUID,
+		MaxItems: exploreMaxItems,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explore people: %w", err)
+	}
+	if len(people) > 0 {
+		category := ExploreCategory{FieldName: ExploreFieldPeople}
+		for _, person := range people {
+			category.Items = append(category.Items, ExploreItem{
+				Value:      person.Name,
+				AssetCount: person.AssetCount,
+				Asset:      person.Asset,
+			})
 		}
+		result.Categories = append(result.Categories, category)
 	}
 
+	s.storeExplore(userID, result)
 	return result, nil
 }
 
-// assetsToIDs converts a slice of assets to a slice of ID strings
-func assetsToIDs(assets []sqlc.Asset) []string {
-	ids := make([]string, len(assets))
-	for i, asset := range assets {
-		ids[i] = pgutil.PgtypeToUUID(asset.ID).String()
+// labelMinScore is the lowest object label confidence search and Explore use.
+func (s *Service) labelMinScore() float64 {
+	if s.config == nil {
+		return defaultLabelMinScore
 	}
-	return ids
+	return s.config.MachineLearning.ObjectDetection.SearchMinScore
+}
+
+// normalizeLabelFilter lower-cases and de-duplicates the requested labels the
+// way detected labels are stored. It returns nil, meaning no filter, when no
+// label is left.
+func normalizeLabelFilter(labels []string) []string {
+	var normalized []string
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	return normalized
+}
+
+func (s *Service) cachedExplore(userID uuid.UUID) (*ExploreResult, bool) {
+	s.exploreMu.Lock()
+	defer s.exploreMu.Unlock()
+
+	entry, ok := s.exploreCache[userID]
+	if !ok || time.Since(entry.fetchedAt) >= exploreCacheTTL {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// InvalidateExplore drops the cached Explore page of userID, after assets
+// were archived or moved out of the timeline.
+func (s *Service) InvalidateExplore(userID uuid.UUID) {
+	s.exploreMu.Lock()
+	defer s.exploreMu.Unlock()
+	delete(s.exploreCache, userID)
+}
+
+func (s *Service) storeExplore(userID uuid.UUID, result *ExploreResult) {
+	s.exploreMu.Lock()
+	defer s.exploreMu.Unlock()
+
+	if s.exploreCache == nil {
+		s.exploreCache = make(map[uuid.UUID]exploreCacheEntry)
+	}
+	// Drop expired entries so the cache does not grow with every user that
+	// ever opened the Explore screen.
+	for id, entry := range s.exploreCache {
+		if time.Since(entry.fetchedAt) >= exploreCacheTTL {
+			delete(s.exploreCache, id)
+		}
+	}
+	s.exploreCache[userID] = exploreCacheEntry{result: result, fetchedAt: time.Now()}
 }
 
 // Request/Response types
@@ -498,6 +637,12 @@ type MetadataSearchRequest struct {
 	TakenBefore time.Time `json:"takenBefore,omitempty"`
 	PersonIDs   []string  `json:"personIds,omitempty"`
 	AlbumIDs    []string  `json:"albumIds,omitempty"`
+	// Labels only matches assets in which every label was detected.
+	Labels []string `json:"labels,omitempty"`
+	// SortBy and Order override the configured sort: "date_taken",
+	// "created_at" or "filename", and "asc" or "desc".
+	SortBy string `json:"sortBy,omitempty"`
+	Order  string `json:"order,omitempty"`
 }
 
 type SearchResult struct {
@@ -518,6 +663,9 @@ type SearchResultItem struct {
 	IsArchived   bool      `json:"isArchived"`
 	Duration     *string   `json:"duration,omitempty"`
 	// Add more fields as needed
+
+	// Asset is the row the item was built from.
+	Asset sqlc.Asset `json:"-"`
 }
 
 type PeopleSearchRequest struct {
@@ -553,10 +701,12 @@ type PlacesSearchResult struct {
 }
 
 type PlaceResult struct {
-	City       string `json:"city"`
-	State      string `json:"state"`
-	Country    string `json:"country"`
-	AssetCount int    `json:"assetCount"`
+	City       string  `json:"city"`
+	State      string  `json:"state"`
+	Country    string  `json:"country"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	AssetCount int     `json:"assetCount"`
 }
 
 type CitiesSearchRequest struct {
@@ -570,35 +720,115 @@ type CityResult struct {
 	Country string `json:"country"`
 }
 
+// Suggestion types, matching the type values Immich clients send.
+const (
+	SuggestionTypeCountry     = "country"
+	SuggestionTypeState       = "state"
+	SuggestionTypeCity        = "city"
+	SuggestionTypeCameraMake  = "camera-make"
+	SuggestionTypeCameraModel = "camera-model"
+	SuggestionTypeTag         = "tag"
+	SuggestionTypePerson      = "person"
+)
+
+// SuggestionTypes lists every suggestion category in response order.
+var SuggestionTypes = []string{
+	SuggestionTypeCountry,
+	SuggestionTypeState,
+	SuggestionTypeCity,
+	SuggestionTypeCameraMake,
+	SuggestionTypeCameraModel,
+	SuggestionTypeTag,
+	SuggestionTypePerson,
+}
+
+const defaultSuggestionLimit = 10
+
+// defaultSearchPageSize is the page size of searches that do not set one.
+const defaultSearchPageSize = 30
+
+// pageSize returns the requested page size, or defaultSearchPageSize when
+// none was requested, capped at the configured maximum.
+func (s *Service) pageSize(size int) int {
+	return int(s.config.PageSize(int32(min(max(size, 0), math.MaxInt32)), defaultSearchPageSize))
+}
+
 type SuggestionsRequest struct {
-	IncludePeople  bool `json:"includePeople"`
-	IncludePlaces  bool `json:"includePlaces"`
-	IncludeTags    bool `json:"includeTags"`
-	IncludeAlbums  bool `json:"includeAlbums"`
-	IncludeCameras bool `json:"includeCameras"`
+	Query   string `json:"query"`
+	Type    string `json:"type"`
+	Country string `json:"country"`
+	State   string `json:"state"`
+	Make    string `json:"make"`
+	Model   string `json:"model"`
+	Limit   int    `json:"limit"`
 }
 
 type SuggestionsResult struct {
-	People    []string `json:"people"`
-	Places    []string `json:"places"`
-	Tags      []string `json:"tags"`
-	Albums    []string `json:"albums"`
-	Cameras   []string `json:"cameras"`
-	FileTypes []string `json:"fileTypes"`
+	Groups []SuggestionGroup `json:"groups"`
+}
+
+type SuggestionGroup struct {
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// SimilarSearchRequest selects a page of assets similar to AssetID.
+type SimilarSearchRequest struct {
+	AssetID    uuid.UUID `json:"assetId"`
+	Visibility string    `json:"visibility"`
+	Page       int       `json:"page"`
+	Size       int       `json:"size"`
+}
+
+type SimilarSearchResult struct {
+	Items    []SimilarAsset `json:"items"`
+	NextPage *int           `json:"nextPage,omitempty"`
+}
+
+type SimilarAsset struct {
+	Asset    sqlc.Asset `json:"asset"`
+	Distance float64    `json:"distance"`
 }
 
 // SmartSearchRequest reuses metadata filters; when CLIP is active the Query
 // field is encoded as a text embedding.
 type SmartSearchRequest = MetadataSearchRequest
 
+// Explore field names, matching the fieldName values Immich clients expect.
+const (
+	ExploreFieldCity    = "exifInfo.city"
+	ExploreFieldTags    = "tags"
+	ExploreFieldObjects = "smartInfo.objects"
+	ExploreFieldPeople  = "people"
+)
+
+const (
+	exploreMaxItems = 12
+	exploreCacheTTL = time.Minute
+
+	// defaultLabelMinScore matches the object_detection.search_min_score
+	// default, for services built without a config.
+	defaultLabelMinScore = 0.5
+)
+
 type ExploreResult struct {
 	Categories []ExploreCategory `json:"categories"`
 }
 
 type ExploreCategory struct {
-	Name      string   `json:"name"`
-	AssetIDs  []string `json:"assetIds"`
-	Thumbnail string   `json:"thumbnail"`
+	FieldName string        `json:"fieldName"`
+	Items     []ExploreItem `json:"items"`
+}
+
+type ExploreItem struct {
+	Value      string     `json:"value"`
+	AssetCount int64      `json:"assetCount"`
+	Asset      sqlc.Asset `json:"asset"`
+}
+
+type exploreCacheEntry struct {
+	result    *ExploreResult
+	fetchedAt time.Time
 }
 
 func optionalText(value string) pgtype.Text {
@@ -622,6 +852,15 @@ func optionalTime(value time.Time) pgtype.Timestamptz {
 	return pgtype.Timestamptz{Time: value, Valid: true}
 }
 
+// localTime converts a user-supplied instant into the wall clock of the
+// default timezone, which is how "localDateTime" stores capture dates.
+func (s *Service) localTime(value time.Time) pgtype.Timestamptz {
+	if value.IsZero() {
+		return pgtype.Timestamptz{}
+	}
+	return optionalTime(assetdomain.WallClock(value, s.config.DefaultLocation()))
+}
+
 func optionalUUID(value string) pgtype.UUID {
 	if value == "" {
 		return pgtype.UUID{}
@@ -15,43 +15,54 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/util"
 )
 
+// GetAllAlbums lists the user's own albums, or with shared set the albums
+// they take part in that are shared or not, each with its asset count, date
+// range, members and cover.
 func (s *Server) GetAllAlbums(ctx context.Context, request *immichv1.GetAllAlbumsRequest) (*immichv1.GetAllAlbumsResponse, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+	sortBy, ok := albumSortFields[request.GetSortBy()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid sort field: %v", request.GetSortBy())
+	}
+	params := sqlc.GetAlbumSummariesForUserParams{
+		UserID:     userID,
+		IsShared:   pgOptionalBool(request.Shared),
+		SortBy:     sortBy,
+		Descending: albumListDescending(sortBy, request.Order, s.config.ListSorting().DescendingByDefault()),
+	}
+	if request.AssetId != nil {
+		params.AssetID, err = pgutil.StringToUUID(request.GetAssetId())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid asset ID: %v", err)
+		}
+	} else if request.Shared == nil {
+		params.IsOwned = pgtype.Bool{Bool: true, Valid: true}
 	}
 
-	// Get albums owned by the user
-	albums, err := s.db.GetAlbumsByOwner(ctx, userID)
+	albums, members, err := s.albumSummaries(ctx, params)
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to get albums", err)
 	}
 
 	immichAlbums := make([]*immichv1.Album, len(albums))
 	for i, album := range albums {
-		immichAlbums[i] = s.convertAlbumToProto(album)
+		immichAlbums[i] = albumSummaryToProto(album, members[album.Album.ID])
 	}
 	return &immichv1.GetAllAlbumsResponse{Albums: immichAlbums}, nil
 }
 
 func (s *Server) CreateAlbum(ctx context.Context, request *immichv1.CreateAlbumRequest) (*immichv1.Album, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	album, err := s.db.CreateAlbum(ctx, sqlc.CreateAlbumParams{
 		OwnerId:     userID,
 		AlbumName:   request.AlbumName,
@@ -91,41 +102,18 @@ func (s *Server) GetAlbumInfo(ctx context.Context, request *immichv1.GetAlbumInf
 }
 
 func (s *Server) GetAlbumMapMarkers(ctx context.Context, request *immichv1.GetAlbumMapMarkersRequest) (*immichv1.GetAlbumMapMarkersResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.StringToUUID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	albumID, err := pgutil.StringToUUID(request.Id)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
 	}
 
-	album, err := s.db.GetAlbum(ctx, albumID)
-	if err != nil {
-		return nil, status.Error(codes.NotFound, "album not found")
-	}
-	if album.OwnerId != userID {
-		sharedUsers, err := s.db.GetAlbumSharedUsers(ctx, albumID)
-		if err != nil {
-			return nil, SanitizedInternal(ctx, "failed to check album access", err)
-		}
-
-		allowed := false
-		for _, sharedUser := range sharedUsers {
-			if sharedUser.ID == userID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return nil, status.Error(codes.PermissionDenied, "access denied")
-		}
+	if err := s.checkAlbumMember(ctx, albumID, userID); err != nil {
+		return nil, err
 	}
 
 	rows, err := s.db.GetAlbumMapMarkers(ctx, albumID)
@@ -141,6 +129,29 @@ func (s *Server) GetAlbumMapMarkers(ctx context.Context, request *immichv1.GetAl
 	return &immichv1.GetAlbumMapMarkersResponse{Markers: markers}, nil
 }
 
+// checkAlbumMember returns a status error unless the album exists and
+// userID owns it or it is shared with them.
+func (s *Server) checkAlbumMember(ctx context.Context, albumID, userID pgtype.UUID) error {
+	album, err := s.db.GetAlbum(ctx, albumID)
+	if err != nil {
+		return status.Error(codes.NotFound, "album not found")
+	}
+	if album.OwnerId == userID {
+		return nil
+	}
+
+	sharedUsers, err := s.db.GetAlbumSharedUsers(ctx, albumID)
+	if err != nil {
+		return SanitizedInternal(ctx, "failed to check album access", err)
+	}
+	for _, sharedUser := range sharedUsers {
+		if sharedUser.ID == userID {
+			return nil
+		}
+	}
+	return status.Error(codes.PermissionDenied, "access denied")
+}
+
 func (s *Server) UpdateAlbumInfo(ctx context.Context, request *immichv1.UpdateAlbumInfoRequest) (*immichv1.Album, error) {
 	albumID := pgtype.UUID{}
 	if err := albumID.Scan(request.Id); err != nil {
@@ -153,6 +164,13 @@ func (s *Server) UpdateAlbumInfo(ctx context.Context, request *immichv1.UpdateAl
 
 	var thumbnailAssetID pgtype.UUID
 	if request.AlbumThumbnailAssetId != nil {
+		userID, err := s.userIDFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := s.checkAlbumCover(ctx, userID, albumID, *request.AlbumThumbnailAssetId); err != nil {
+			return nil, err
+		}
 		if err := thumbnailAssetID.Scan(*request.AlbumThumbnailAssetId); err != nil {
 			return nil, status.Errorf(codes.InvalidArgument, "invalid thumbnail asset ID: %v", err)
 		}
@@ -172,6 +190,74 @@ func (s *Server) UpdateAlbumInfo(ctx context.Context, request *immichv1.UpdateAl
 	return s.convertAlbumToProto(album), nil
 }
 
+// SetAlbumCover makes one of the user's assets in the album its cover and
+// returns the updated album.
+func (s *Server) SetAlbumCover(ctx context.Context, request *immichv1.SetAlbumCoverRequest) (*immichv1.Album, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	albumID := pgtype.UUID{}
+	if err := albumID.Scan(request.Id); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
+	}
+	assetID, sharedUsers, err := s.checkAlbumCover(ctx, userID, albumID, request.AssetId)
+	if err != nil {
+		return nil, err
+	}
+
+	album, err := s.db.UpdateAlbum(ctx, sqlc.UpdateAlbumParams{
+		ID:                    albumID,
+		AlbumThumbnailAssetID: assetID,
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to update album", err)
+	}
+
+	if s.syncService != nil {
+		s.syncService.BroadcastAlbumEvent(album.OwnerId.String(), request.Id, "update")
+		for _, user := range sharedUsers {
+			s.syncService.BroadcastAlbumEvent(user.ID.String(), request.Id, "update")
+		}
+	}
+
+	return s.convertAlbumToProto(album), nil
+}
+
+// checkAlbumCover checks that the user may make assetID the cover of an
+// album: they own or edit the album, and the asset is theirs and in the
+// album. It returns the asset ID and the users the album is shared with.
+func (s *Server) checkAlbumCover(ctx context.Context, userID, albumID pgtype.UUID, assetID string) (pgtype.UUID, []sqlc.GetAlbumSharedUsersRow, error) {
+	album, err := s.db.GetAlbum(ctx, albumID)
+	if err != nil {
+		return pgtype.UUID{}, nil, status.Error(codes.NotFound, "album not found")
+	}
+	sharedUsers, err := s.db.GetAlbumSharedUsers(ctx, albumID)
+	if err != nil {
+		return pgtype.UUID{}, nil, SanitizedInternal(ctx, "failed to check album access", err)
+	}
+	if !canEditAlbum(album, sharedUsers, userID) {
+		return pgtype.UUID{}, nil, status.Error(codes.PermissionDenied, "access denied")
+	}
+
+	asset, err := s.getAssetForUser(ctx, userID, assetID)
+	if err != nil {
+		return pgtype.UUID{}, nil, err
+	}
+	inAlbum, err := s.db.IsAssetInAlbum(ctx, sqlc.IsAssetInAlbumParams{
+		AlbumsId: albumID,
+		AssetsId: asset.ID,
+	})
+	if err != nil {
+		return pgtype.UUID{}, nil, SanitizedInternal(ctx, "failed to check album assets", err)
+	}
+	if !inAlbum {
+		return pgtype.UUID{}, nil, status.Error(codes.InvalidArgument, "asset is not in the album")
+	}
+	return asset.ID, sharedUsers, nil
+}
+
 func (s *Server) DeleteAlbum(ctx context.Context, request *immichv1.DeleteAlbumRequest) (*emptypb.Empty, error) {
 	albumID := pgtype.UUID{}
 	if err := albumID.Scan(request.Id); err != nil {
@@ -185,41 +271,146 @@ func (s *Server) DeleteAlbum(ctx context.Context, request *immichv1.DeleteAlbumR
 	return &emptypb.Empty{}, nil
 }
 
+// Reasons reported per asset when adding assets to an album, as upstream.
+const (
+	albumAssetDuplicate    = "duplicate"
+	albumAssetNoPermission = "no_permission"
+	albumAssetNotFound     = "not_found"
+)
+
+// AddAssetsToAlbum adds the user's assets to an album they own or edit and
+// reports for every asset whether it was added or why it was skipped.
 func (s *Server) AddAssetsToAlbum(ctx context.Context, request *immichv1.AddAssetsToAlbumRequest) (*immichv1.AddAssetsToAlbumResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	albumID := pgtype.UUID{}
 	if err := albumID.Scan(request.Id); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
 	}
 
-	results := make([]*immichv1.BulkIdResponse, len(request.AssetIds.Ids))
-	for i, assetID := range request.AssetIds.Ids {
+	album, err := s.db.GetAlbum(ctx, albumID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "album not found")
+	}
+	sharedUsers, err := s.db.GetAlbumSharedUsers(ctx, albumID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to check album access", err)
+	}
+	if !canEditAlbum(album, sharedUsers, userID) {
+		return nil, status.Error(codes.PermissionDenied, "access denied")
+	}
+
+	ids := request.GetAssetIds().GetIds()
+	assetUUIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
 		assetUUID := pgtype.UUID{}
-		if err := assetUUID.Scan(assetID); err != nil {
-			results[i] = &immichv1.BulkIdResponse{
-				Id:      assetID,
-				Success: false,
-				Error:   util.Ptr("invalid asset ID"),
-			}
-			continue
+		if err := assetUUID.Scan(id); err == nil {
+			assetUUIDs = append(assetUUIDs, assetUUID)
 		}
+	}
+	found, err := s.db.GetAssetsByIDs(ctx, assetUUIDs)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get assets", err)
+	}
+	owners := make(map[pgtype.UUID]pgtype.UUID, len(found))
+	for _, asset := range found {
+		owners[asset.ID] = asset.OwnerId
+	}
 
-		err := s.db.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{
-			AlbumsId: albumID,
-			AssetsId: assetUUID,
+	results, candidates := albumAssetCandidates(ids, owners, userID)
+	var added []pgtype.UUID
+	if len(candidates) > 0 {
+		added, err = s.db.AddAssetsToAlbum(ctx, sqlc.AddAssetsToAlbumParams{
+			AlbumID:  albumID,
+			AssetIds: candidates,
 		})
-		results[i] = &immichv1.BulkIdResponse{
-			Id:      assetID,
-			Success: err == nil,
-		}
 		if err != nil {
-			errMsg := err.Error()
-			results[i].Error = &errMsg
+			return nil, SanitizedInternal(ctx, "failed to add assets to album", err)
+		}
+	}
+	markAlbumAssetsAdded(results, added)
+	if len(added) > 0 {
+		s.albumStatistics.invalidate(albumID)
+	}
+
+	if len(added) > 0 && s.syncService != nil {
+		s.syncService.BroadcastAlbumEvent(album.OwnerId.String(), request.Id, "update")
+		for _, user := range sharedUsers {
+			s.syncService.BroadcastAlbumEvent(user.ID.String(), request.Id, "update")
 		}
 	}
 
 	return &immichv1.AddAssetsToAlbumResponse{Results: results}, nil
 }
 
+// canEditAlbum reports whether userID owns album or is one of its editors.
+func canEditAlbum(album sqlc.Album, sharedUsers []sqlc.GetAlbumSharedUsersRow, userID pgtype.UUID) bool {
+	if album.OwnerId == userID {
+		return true
+	}
+	for _, user := range sharedUsers {
+		if user.ID == userID {
+			return user.Role == "editor"
+		}
+	}
+	return false
+}
+
+// albumAssetCandidates builds one result per requested ID, failing the
+// ones that do not exist, belong to someone else or repeat an earlier ID,
+// and returns the assets left to insert.
+func albumAssetCandidates(ids []string, owners map[pgtype.UUID]pgtype.UUID, userID pgtype.UUID) ([]*immichv1.BulkIdResponse, []pgtype.UUID) {
+	results := make([]*immichv1.BulkIdResponse, len(ids))
+	candidates := make([]pgtype.UUID, 0, len(ids))
+	seen := make(map[pgtype.UUID]bool, len(ids))
+	for i, id := range ids {
+		results[i] = &immichv1.BulkIdResponse{Id: id}
+
+		assetUUID := pgtype.UUID{}
+		if err := assetUUID.Scan(id); err != nil {
+			results[i].Error = util.Ptr(albumAssetNotFound)
+			continue
+		}
+		owner, ok := owners[assetUUID]
+		switch {
+		case !ok:
+			results[i].Error = util.Ptr(albumAssetNotFound)
+		case owner != userID:
+			results[i].Error = util.Ptr(albumAssetNoPermission)
+		case seen[assetUUID]:
+			results[i].Error = util.Ptr(albumAssetDuplicate)
+		default:
+			seen[assetUUID] = true
+			candidates = append(candidates, assetUUID)
+		}
+	}
+	return results, candidates
+}
+
+// markAlbumAssetsAdded settles the results still pending after the insert:
+// the assets it returned were added, the others were already in the album.
+func markAlbumAssetsAdded(results []*immichv1.BulkIdResponse, added []pgtype.UUID) {
+	inserted := make(map[pgtype.UUID]bool, len(added))
+	for _, id := range added {
+		inserted[id] = true
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		assetUUID := pgtype.UUID{}
+		_ = assetUUID.Scan(result.Id)
+		if inserted[assetUUID] {
+			result.Success = true
+		} else {
+			result.Error = util.Ptr(albumAssetDuplicate)
+		}
+	}
+}
+
 func (s *Server) RemoveAssetFromAlbum(ctx context.Context, request *immichv1.RemoveAssetFromAlbumRequest) (*immichv1.RemoveAssetFromAlbumResponse, error) {
 	albumID := pgtype.UUID{}
 	if err := albumID.Scan(request.Id); err != nil {
@@ -252,6 +443,8 @@ func (s *Server) RemoveAssetFromAlbum(ctx context.Context, request *immichv1.Rem
 		}
 	}
 
+	s.albumStatistics.invalidate(albumID)
+
 	return &immichv1.RemoveAssetFromAlbumResponse{Results: results}, nil
 }
 
@@ -332,16 +525,11 @@ func (s *Server) UpdateAlbumUser(ctx context.Context, request *immichv1.UpdateAl
 
 func (s *Server) GetAlbumStatistics(ctx context.Context, request *immichv1.GetAlbumStatisticsRequest) (*immichv1.AlbumStatisticsResponse, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	stats, err := s.db.GetAlbumStatistics(ctx, userID)
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to get album statistics", err)
@@ -399,14 +587,10 @@ func albumMapMarkerToProto(row sqlc.GetAlbumMapMarkersRow) *immichv1.MapMarker {
 // AddAssetsToAlbums adds a set of assets to multiple albums owned by the
 // current user in one call (upstream PUT /albums/assets).
 func (s *Server) AddAssetsToAlbums(ctx context.Context, request *immichv1.AddAssetsToAlbumsRequest) (*immichv1.AlbumsAddAssetsResponseDto, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	userID, err := pgutil.StringToUUID(claims.UserID)
-	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user ID: %v", err)
-	}
 
 	if len(request.GetAlbumIds()) == 0 || len(request.GetAssetIds()) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "albumIds and assetIds must not be empty")
@@ -457,5 +641,7 @@ func (s *Server) AddAssetsToAlbums(ctx context.Context, request *immichv1.AddAss
 		}
 	}
 
+	s.albumStatistics.invalidate(albumUUIDs...)
+
 	return &immichv1.AlbumsAddAssetsResponseDto{Success: true}, nil
 }
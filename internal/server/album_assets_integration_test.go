@@ -0,0 +1,148 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestAddAssetsToAlbumReportsSkippedAssets adds a new asset, one already in
+// the album and one of another user, and checks only the first is inserted.
+func TestAddAssetsToAlbumReportsSkippedAssets(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "album-add-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "album-add-other@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	fresh := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "fresh"), Valid: true}
+	present := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "present"), Valid: true}
+	foreign := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, otherID, "foreign"), Valid: true}
+
+	album, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Trip"})
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: present}))
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn}
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "album-add-owner@example.com"})
+
+	resp, err := srv.AddAssetsToAlbum(userCtx, &immichv1.AddAssetsToAlbumRequest{
+		Id:       album.ID.String(),
+		AssetIds: &immichv1.BulkIds{Ids: []string{fresh.String(), present.String(), foreign.String()}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", albumAssetDuplicate, albumAssetNoPermission}, albumAssetErrors(resp.Results))
+
+	assets, err := tdb.Queries.GetAlbumAssets(ctx, album.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []pgtype.UUID{fresh, present}, lockedTestAssetIDs(assets))
+}
+
+// TestSetAlbumCoverRejectsForeignAssets checks the cover can only be set to
+// an asset of the user that is in the album.
+func TestSetAlbumCoverRejectsForeignAssets(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "album-cover-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "album-cover-other@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	cover := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "cover"), Valid: true}
+	outside := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "outside"), Valid: true}
+	foreign := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, otherID, "foreign"), Valid: true}
+
+	album, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Trip"})
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: cover}))
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn}
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "album-cover-owner@example.com"})
+
+	_, err = srv.SetAlbumCover(userCtx, &immichv1.SetAlbumCoverRequest{Id: album.ID.String(), AssetId: foreign.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = srv.SetAlbumCover(userCtx, &immichv1.SetAlbumCoverRequest{Id: album.ID.String(), AssetId: outside.String()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := srv.SetAlbumCover(userCtx, &immichv1.SetAlbumCoverRequest{Id: album.ID.String(), AssetId: cover.String()})
+	require.NoError(t, err)
+	assert.Equal(t, cover.String(), resp.GetAlbumThumbnailAssetId())
+
+	otherCtx := auth.WithClaims(ctx, &auth.Claims{UserID: otherID.String(), Email: "album-cover-other@example.com"})
+	_, err = srv.SetAlbumCover(otherCtx, &immichv1.SetAlbumCoverRequest{Id: album.ID.String(), AssetId: cover.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// TestGetAlbumAssetStatistics checks the statistics of a shared album leave
+// out trashed assets, break the assets down by owner and are only served to
+// the album's members.
+func TestGetAlbumAssetStatistics(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "album-stats-owner@example.com")
+	memberID := tdb.CreateTestUser(t, "album-stats-member@example.com")
+	outsiderID := tdb.CreateTestUser(t, "album-stats-outsider@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	member := pgtype.UUID{Bytes: memberID, Valid: true}
+
+	album, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Trip"})
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.AddUserToAlbum(ctx, sqlc.AddUserToAlbumParams{
+		AlbumsId: album.ID, UsersId: member, Role: "editor",
+	}))
+
+	ownerAssets := []pgtype.UUID{
+		{Bytes: tdb.CreateTestAsset(t, ownerID, "first"), Valid: true},
+		{Bytes: tdb.CreateTestAsset(t, ownerID, "second"), Valid: true},
+	}
+	memberAsset := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, memberID, "member"), Valid: true}
+	trashed := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "trashed"), Valid: true}
+	for _, assetID := range append(ownerAssets, memberAsset, trashed) {
+		require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: assetID}))
+	}
+	require.NoError(t, tdb.Queries.MoveAssetToTrash(ctx, trashed))
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn}
+	request := &immichv1.GetAlbumAssetStatisticsRequest{Id: album.ID.String()}
+
+	memberCtx := auth.WithClaims(ctx, &auth.Claims{UserID: memberID.String(), Email: "album-stats-member@example.com"})
+	resp, err := srv.GetAlbumAssetStatistics(memberCtx, request)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), resp.AssetCount)
+	assert.Equal(t, int32(3), resp.Images)
+	require.Len(t, resp.Contributors, 2)
+	assert.Equal(t, owner.String(), resp.Contributors[0].UserId, "the owner contributed most")
+	assert.Equal(t, int32(2), resp.Contributors[0].AssetCount)
+	assert.Equal(t, member.String(), resp.Contributors[1].UserId)
+	assert.Equal(t, int32(1), resp.Contributors[1].AssetCount)
+
+	outsiderCtx := auth.WithClaims(ctx, &auth.Claims{UserID: outsiderID.String(), Email: "album-stats-outsider@example.com"})
+	_, err = srv.GetAlbumAssetStatistics(outsiderCtx, request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// albumSortFields are the fields album lists can be sorted by, as
+// GetAlbumSummariesForUser and the HTTP sortBy parameter name them.
+var albumSortFields = map[immichv1.AlbumSortBy]string{
+	immichv1.AlbumSortBy_ALBUM_SORT_BY_CREATED_AT: "createdAt",
+	immichv1.AlbumSortBy_ALBUM_SORT_BY_UPDATED_AT: "updatedAt",
+	immichv1.AlbumSortBy_ALBUM_SORT_BY_NAME:       "albumName",
+}
+
+func isAlbumSortField(name string) bool {
+	for _, field := range albumSortFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// albumListDescending is the direction of an album list sorted by sortBy
+// when the client did not choose one: names from A to Z, dates in the
+// configured default order.
+func albumListDescending(sortBy string, order *immichv1.AssetOrder, defaultDescending bool) bool {
+	if order != nil {
+		return *order == immichv1.AssetOrder_DESC
+	}
+	if sortBy == "albumName" {
+		return false
+	}
+	return defaultDescending
+}
+
+// albumSummaries lists albums with their members, keyed by album, in two
+// queries however many albums there are.
+func (s *Server) albumSummaries(ctx context.Context, params sqlc.GetAlbumSummariesForUserParams) ([]sqlc.GetAlbumSummariesForUserRow, map[pgtype.UUID][]sqlc.GetAlbumSharedUsersByAlbumIdsRow, error) {
+	albums, err := s.db.GetAlbumSummariesForUser(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sharedIDs []pgtype.UUID
+	for _, album := range albums {
+		if album.Shared {
+			sharedIDs = append(sharedIDs, album.Album.ID)
+		}
+	}
+	members := make(map[pgtype.UUID][]sqlc.GetAlbumSharedUsersByAlbumIdsRow)
+	if len(sharedIDs) == 0 {
+		return albums, members, nil
+	}
+	rows, err := s.db.GetAlbumSharedUsersByAlbumIds(ctx, sharedIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		members[row.AlbumsId] = append(members[row.AlbumsId], row)
+	}
+	return albums, members, nil
+}
+
+func albumSummaryToProto(row sqlc.GetAlbumSummariesForUserRow, members []sqlc.GetAlbumSharedUsersByAlbumIdsRow) *immichv1.Album {
+	album := row.Album
+	protoAlbum := &immichv1.Album{
+		Id:                album.ID.String(),
+		AlbumName:         album.AlbumName,
+		Description:       album.Description,
+		OwnerId:           album.OwnerId.String(),
+		Owner:             albumUserToProto(album.OwnerId, row.OwnerEmail, row.OwnerName, row.OwnerProfileImagePath, row.OwnerAvatarColor, row.OwnerProfileChangedAt),
+		IsActivityEnabled: album.IsActivityEnabled,
+		AssetCount:        int32(row.AssetCount),
+		CreatedAt:         timestamppb.New(album.CreatedAt.Time),
+		UpdatedAt:         timestamppb.New(album.UpdatedAt.Time),
+		HasSharedLink:     row.HasSharedLink,
+		Shared:            row.Shared,
+	}
+	if row.CoverAssetID.Valid {
+		coverID := row.CoverAssetID.String()
+		protoAlbum.AlbumThumbnailAssetId = &coverID
+	}
+	if row.StartDate.Valid {
+		protoAlbum.StartDate = timestamppb.New(row.StartDate.Time)
+	}
+	if row.EndDate.Valid {
+		protoAlbum.EndDate = timestamppb.New(row.EndDate.Time)
+	}
+	if row.LastModifiedAssetAt.Valid {
+		protoAlbum.LastModifiedAssetTimestamp = timestamppb.New(row.LastModifiedAssetAt.Time)
+	}
+	for _, member := range members {
+		role := immichv1.AlbumUserRole_ALBUM_USER_ROLE_EDITOR
+		if member.Role == "viewer" {
+			role = immichv1.AlbumUserRole_ALBUM_USER_ROLE_VIEWER
+		}
+		protoAlbum.SharedUsers = append(protoAlbum.SharedUsers, &immichv1.AlbumUser{
+			UserId: member.ID.String(),
+			User:   albumUserToProto(member.ID, member.Email, member.Name, member.ProfileImagePath, member.AvatarColor, member.ProfileChangedAt),
+			Role:   role,
+		})
+	}
+	return protoAlbum
+}
+
+func albumUserToProto(id pgtype.UUID, email, name, profileImagePath string, avatarColor pgtype.Text, profileChangedAt pgtype.Timestamptz) *immichv1.User {
+	user := &immichv1.User{
+		Id:               id.String(),
+		Email:            email,
+		Name:             name,
+		ProfileImagePath: profileImagePath,
+	}
+	if avatarColor.Valid {
+		user.AvatarColor = userAvatarColorToProto(&avatarColor.String)
+	} else {
+		user.AvatarColor = userAvatarColorToProto(nil)
+	}
+	if profileChangedAt.Valid {
+		user.ProfileChangedAt = timestamppb.New(profileChangedAt.Time)
+	}
+	return user
+}
@@ -0,0 +1,82 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestGetAllAlbumsSummaries checks the album list carries what each album
+// card shows, and follows the requested order.
+func TestGetAllAlbumsSummaries(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "album-list-owner@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	member := pgtype.UUID{Bytes: tdb.CreateTestUser(t, "album-list-member@example.com"), Valid: true}
+
+	trip, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Trip"})
+	require.NoError(t, err)
+	var lastAdded pgtype.UUID
+	for _, name := range []string{"first", "second", "third"} {
+		lastAdded = pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, name), Valid: true}
+		require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: trip.ID, AssetsId: lastAdded}))
+	}
+	require.NoError(t, tdb.Queries.AddUserToAlbum(ctx, sqlc.AddUserToAlbumParams{AlbumsId: trip.ID, UsersId: member, Role: "viewer"}))
+	_, err = tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "archive"})
+	require.NoError(t, err)
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn}
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "album-list-owner@example.com"})
+
+	resp, err := srv.GetAllAlbums(userCtx, &immichv1.GetAllAlbumsRequest{SortBy: immichv1.AlbumSortBy_ALBUM_SORT_BY_NAME})
+	require.NoError(t, err)
+	require.Len(t, resp.Albums, 2)
+	assert.Equal(t, "archive", resp.Albums[0].AlbumName, "names sort from A to Z, whatever the case")
+	empty, album := resp.Albums[0], resp.Albums[1]
+	assert.Zero(t, empty.AssetCount)
+	assert.Nil(t, empty.AlbumThumbnailAssetId)
+	assert.False(t, empty.Shared)
+
+	assert.Equal(t, int32(3), album.AssetCount)
+	require.NotNil(t, album.AlbumThumbnailAssetId)
+	assert.Equal(t, lastAdded.String(), *album.AlbumThumbnailAssetId, "without a chosen thumbnail the last asset added is the cover")
+	assert.NotNil(t, album.LastModifiedAssetTimestamp)
+	assert.True(t, album.Shared)
+	assert.Equal(t, "album-list-owner@example.com", album.Owner.GetEmail())
+	require.Len(t, album.SharedUsers, 1)
+	assert.Equal(t, member.String(), album.SharedUsers[0].UserId)
+	assert.Equal(t, immichv1.AlbumUserRole_ALBUM_USER_ROLE_VIEWER, album.SharedUsers[0].Role)
+
+	resp, err = srv.GetAllAlbums(userCtx, &immichv1.GetAllAlbumsRequest{
+		SortBy: immichv1.AlbumSortBy_ALBUM_SORT_BY_NAME,
+		Order:  immichv1.AssetOrder_DESC.Enum(),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Albums, 2)
+	assert.Equal(t, "Trip", resp.Albums[0].AlbumName)
+
+	memberCtx := auth.WithClaims(ctx, &auth.Claims{UserID: member.String(), Email: "album-list-member@example.com"})
+	shared := true
+	resp, err = srv.GetAllAlbums(memberCtx, &immichv1.GetAllAlbumsRequest{Shared: &shared})
+	require.NoError(t, err)
+	require.Len(t, resp.Albums, 1, "albums shared with the user are listed")
+	assert.Equal(t, trip.ID.String(), resp.Albums[0].Id)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestAlbumListDescending(t *testing.T) {
+	assert.True(t, albumListDescending("createdAt", nil, true))
+	assert.True(t, albumListDescending("updatedAt", nil, true))
+	assert.False(t, albumListDescending("updatedAt", nil, false), "the configured order applies to dates")
+	assert.False(t, albumListDescending("albumName", nil, true))
+	assert.True(t, albumListDescending("albumName", immichv1.AssetOrder_DESC.Enum(), true))
+	assert.False(t, albumListDescending("createdAt", immichv1.AssetOrder_ASC.Enum(), true))
+}
+
+func TestFrontendAlbumSummaryResponse(t *testing.T) {
+	created := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	row := sqlc.GetAlbumSummariesForUserRow{
+		Album: sqlc.Album{
+			ID:        pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+			OwnerId:   pgtype.UUID{Bytes: [16]byte{2}, Valid: true},
+			AlbumName: "Trip",
+			CreatedAt: pgtype.Timestamptz{Time: created, Valid: true},
+			UpdatedAt: pgtype.Timestamptz{Time: created, Valid: true},
+			Order:     "desc",
+		},
+		OwnerEmail:          "owner@example.com",
+		OwnerName:           "Owner",
+		OwnerCreatedAt:      pgtype.Timestamptz{Time: created, Valid: true},
+		AssetCount:          12,
+		StartDate:           pgtype.Timestamptz{Time: created.AddDate(0, 0, -7), Valid: true},
+		EndDate:             pgtype.Timestamptz{Time: created.AddDate(0, 0, -1), Valid: true},
+		LastModifiedAssetAt: pgtype.Timestamptz{Time: created, Valid: true},
+		CoverAssetID:        pgtype.UUID{Bytes: [16]byte{3}, Valid: true},
+		Shared:              true,
+	}
+	members := []sqlc.GetAlbumSharedUsersByAlbumIdsRow{{
+		AlbumsId: row.Album.ID,
+		ID:       pgtype.UUID{Bytes: [16]byte{4}, Valid: true},
+		Email:    "member@example.com",
+	}}
+
+	item := frontendAlbumSummaryResponse(row, members)
+
+	assert.Equal(t, int64(12), item["assetCount"])
+	assert.Equal(t, row.CoverAssetID.String(), item["albumThumbnailAssetId"])
+	assert.Equal(t, true, item["shared"])
+	assert.Equal(t, false, item["hasSharedLink"])
+	assert.Equal(t, "2024-02-23T10:00:00Z", item["startDate"])
+	assert.Equal(t, "2024-02-29T10:00:00Z", item["endDate"])
+	assert.Equal(t, "2024-03-01T10:00:00Z", item["lastModifiedAssetTimestamp"])
+	albumUsers := item["albumUsers"].([]map[string]any)
+	if assert.Len(t, albumUsers, 2) {
+		assert.Equal(t, "owner", albumUsers[0]["role"], "the owner comes first")
+		assert.Equal(t, "editor", albumUsers[1]["role"], "members without a role are editors")
+		assert.Equal(t, "member@example.com", albumUsers[1]["user"].(map[string]any)["email"])
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// albumStatisticsTTL is how long album statistics are served before they
+// are computed again. Album pages are revisited often, while most changes
+// to their assets happen elsewhere and do not invalidate them.
+const albumStatisticsTTL = 30 * time.Second
+
+// albumStatisticsCache holds recently computed album statistics by album.
+type albumStatisticsCache struct {
+	sync.Mutex
+	entries map[pgtype.UUID]albumStatisticsEntry
+}
+
+type albumStatisticsEntry struct {
+	response   *immichv1.AlbumAssetStatisticsResponse
+	computedAt time.Time
+}
+
+// get returns a copy of the statistics of albumID computed less than
+// albumStatisticsTTL ago.
+func (c *albumStatisticsCache) get(albumID pgtype.UUID) (*immichv1.AlbumAssetStatisticsResponse, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[albumID]
+	if !ok || time.Since(entry.computedAt) >= albumStatisticsTTL {
+		return nil, false
+	}
+	return proto.Clone(entry.response).(*immichv1.AlbumAssetStatisticsResponse), true
+}
+
+// put stores the statistics of albumID and drops the expired ones.
+func (c *albumStatisticsCache) put(albumID pgtype.UUID, response *immichv1.AlbumAssetStatisticsResponse) {
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[pgtype.UUID]albumStatisticsEntry)
+	}
+	for id, entry := range c.entries {
+		if time.Since(entry.computedAt) >= albumStatisticsTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[albumID] = albumStatisticsEntry{
+		response:   proto.Clone(response).(*immichv1.AlbumAssetStatisticsResponse),
+		computedAt: time.Now(),
+	}
+}
+
+// invalidate makes the next request compute the statistics of the albums
+// again, after assets were added to or removed from them.
+func (c *albumStatisticsCache) invalidate(albumIDs ...pgtype.UUID) {
+	c.Lock()
+	defer c.Unlock()
+	for _, albumID := range albumIDs {
+		delete(c.entries, albumID)
+	}
+}
+
+// GetAlbumAssetStatistics returns the asset counts, size, date range and
+// contributors of an album to its owner and members.
+func (s *Server) GetAlbumAssetStatistics(ctx context.Context, request *immichv1.GetAlbumAssetStatisticsRequest) (*immichv1.AlbumAssetStatisticsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	albumID, err := pgutil.StringToUUID(request.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
+	}
+
+	if err := s.checkAlbumMember(ctx, albumID, userID); err != nil {
+		return nil, err
+	}
+
+	if response, ok := s.albumStatistics.get(albumID); ok {
+		return response, nil
+	}
+
+	rows, err := s.db.GetAlbumAssetStatistics(ctx, albumID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get album statistics", err)
+	}
+	response := albumAssetStatisticsToProto(rows)
+	s.albumStatistics.put(albumID, response)
+
+	return response, nil
+}
+
+// albumAssetStatisticsToProto converts the total row, which comes first, and
+// the rows per owner of GetAlbumAssetStatistics.
+func albumAssetStatisticsToProto(rows []sqlc.GetAlbumAssetStatisticsRow) *immichv1.AlbumAssetStatisticsResponse {
+	response := &immichv1.AlbumAssetStatisticsResponse{
+		Contributors: []*immichv1.AlbumContributor{},
+	}
+	for _, row := range rows {
+		if !row.OwnerId.Valid {
+			response.AssetCount = int32(row.AssetCount)
+			response.Images = int32(row.Images)
+			response.Videos = int32(row.Videos)
+			response.TotalSize = row.TotalSize
+			if row.StartDate.Valid {
+				response.StartDate = timestamppb.New(row.StartDate.Time)
+			}
+			if row.EndDate.Valid {
+				response.EndDate = timestamppb.New(row.EndDate.Time)
+			}
+			continue
+		}
+		response.Contributors = append(response.Contributors, &immichv1.AlbumContributor{
+			UserId:     row.OwnerId.String(),
+			Name:       row.OwnerName,
+			AssetCount: int32(row.AssetCount),
+			Images:     int32(row.Images),
+			Videos:     int32(row.Videos),
+		})
+	}
+	return response
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestAlbumAssetStatisticsToProto(t *testing.T) {
+	ownerID := uuid.MustParse("00000000-0000-4000-8000-000000000001")
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)
+
+	response := albumAssetStatisticsToProto([]sqlc.GetAlbumAssetStatisticsRow{
+		{
+			AssetCount: 3, Images: 2, Videos: 1, TotalSize: 4096,
+			StartDate: pgtype.Timestamptz{Time: start, Valid: true},
+			EndDate:   pgtype.Timestamptz{Time: end, Valid: true},
+		},
+		{
+			OwnerId:    pgtype.UUID{Bytes: ownerID, Valid: true},
+			OwnerName:  "Owner",
+			AssetCount: 3, Images: 2, Videos: 1, TotalSize: 4096,
+		},
+	})
+
+	assert.Equal(t, int32(3), response.AssetCount)
+	assert.Equal(t, int32(2), response.Images)
+	assert.Equal(t, int32(1), response.Videos)
+	assert.Equal(t, int64(4096), response.TotalSize)
+	assert.Equal(t, start, response.StartDate.AsTime())
+	assert.Equal(t, end, response.EndDate.AsTime())
+	require.Len(t, response.Contributors, 1)
+	assert.Equal(t, ownerID.String(), response.Contributors[0].UserId)
+	assert.Equal(t, "Owner", response.Contributors[0].Name)
+	assert.Equal(t, int32(3), response.Contributors[0].AssetCount)
+}
+
+func TestAlbumAssetStatisticsToProtoEmptyAlbum(t *testing.T) {
+	response := albumAssetStatisticsToProto([]sqlc.GetAlbumAssetStatisticsRow{{}})
+
+	assert.Zero(t, response.AssetCount)
+	assert.Nil(t, response.StartDate)
+	assert.Nil(t, response.EndDate)
+	assert.NotNil(t, response.Contributors)
+	assert.Empty(t, response.Contributors)
+}
+
+func TestAlbumStatisticsCache(t *testing.T) {
+	var cache albumStatisticsCache
+	albumID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+
+	_, ok := cache.get(albumID)
+	assert.False(t, ok)
+
+	cache.put(albumID, &immichv1.AlbumAssetStatisticsResponse{AssetCount: 2})
+	cached, ok := cache.get(albumID)
+	require.True(t, ok)
+	assert.Equal(t, int32(2), cached.AssetCount)
+
+	// Callers get a copy they may change
+	cached.AssetCount = 5
+	cached, _ = cache.get(albumID)
+	assert.Equal(t, int32(2), cached.AssetCount)
+
+	cache.invalidate(albumID)
+	_, ok = cache.get(albumID)
+	assert.False(t, ok)
+
+	// Expired statistics are computed again
+	cache.put(albumID, &immichv1.AlbumAssetStatisticsResponse{})
+	cache.entries[albumID] = albumStatisticsEntry{
+		response:   cache.entries[albumID].response,
+		computedAt: time.Now().Add(-albumStatisticsTTL),
+	}
+	_, ok = cache.get(albumID)
+	assert.False(t, ok)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestAlbumAssetCandidates(t *testing.T) {
+	user := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	other := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	mine := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	present := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	foreign := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	missing := uuid.NewString()
+	owners := map[pgtype.UUID]pgtype.UUID{mine: user, present: user, foreign: other}
+
+	ids := []string{mine.String(), present.String(), foreign.String(), missing, "not-a-uuid", mine.String()}
+	results, candidates := albumAssetCandidates(ids, owners, user)
+	assert.Equal(t, []pgtype.UUID{mine, present}, candidates)
+
+	markAlbumAssetsAdded(results, []pgtype.UUID{mine})
+	assert.Equal(t, []string{"", albumAssetDuplicate, albumAssetNoPermission, albumAssetNotFound, albumAssetNotFound, albumAssetDuplicate}, albumAssetErrors(results))
+	assert.True(t, results[0].Success)
+	for _, result := range results[1:] {
+		assert.False(t, result.Success, result.Id)
+	}
+}
+
+func TestCanEditAlbum(t *testing.T) {
+	owner := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	editor := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	viewer := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	album := sqlc.Album{OwnerId: owner}
+	shared := []sqlc.GetAlbumSharedUsersRow{{ID: editor, Role: "editor"}, {ID: viewer, Role: "viewer"}}
+
+	assert.True(t, canEditAlbum(album, shared, owner))
+	assert.True(t, canEditAlbum(album, shared, editor))
+	assert.False(t, canEditAlbum(album, shared, viewer))
+	assert.False(t, canEditAlbum(album, shared, pgtype.UUID{Bytes: uuid.New(), Valid: true}))
+}
+
+func albumAssetErrors(results []*immichv1.BulkIdResponse) []string {
+	errs := make([]string, len(results))
+	for i, result := range results {
+		errs[i] = result.GetError()
+	}
+	return errs
+}
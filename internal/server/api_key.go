@@ -39,9 +39,15 @@ func (s *Server) GetApiKeys(ctx context.Context, _ *emptypb.Empty) (*immichv1.Ge
 		return nil, SanitizedInternal(ctx, "failed to get API keys", err)
 	}
 
+	limits, err := s.userLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert to response format
 	response := &immichv1.GetApiKeysResponse{
 		ApiKeys: make([]*immichv1.ApiKeyResponseDto, len(keys)),
+		Limit:   limits.APIKeys.Limit,
 	}
 
 	for i, key := range keys {
@@ -69,13 +75,19 @@ func (s *Server) CreateApiKey(ctx context.Context, req *immichv1.CreateApiKeyReq
 		return nil, status.Error(codes.Unauthenticated, "authentication required")
 	}
 
-	// Create service
-	apiKeyService := apikeys.NewService(s.db.Queries)
-
 	// Create the API key
-	apiKey, rawKey, err := apiKeyService.CreateAPIKey(ctx, userID, req.Name)
+	var apiKey *sqlc.ApiKey
+	var rawKey string
+	err = s.createWithinLimit(ctx, userID, apiKeyLimitError, func(q *sqlc.Queries) error {
+		var err error
+		apiKey, rawKey, err = apikeys.NewService(q).CreateAPIKey(ctx, userID, req.Name)
+		if err != nil {
+			return SanitizedInternal(ctx, "failed to create API key", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "failed to create API key", err)
+		return nil, err
 	}
 
 	// Return response with the raw key (only shown once)
@@ -118,13 +130,9 @@ func (s *Server) DeleteApiKey(ctx context.Context, req *immichv1.DeleteApiKeyReq
 // UpdateApiKey updates an API key's name
 func (s *Server) UpdateApiKey(ctx context.Context, req *immichv1.UpdateApiKeyRequest) (*immichv1.ApiKeyResponseDto, error) {
 	// Get user ID from context
-	claims, err := s.claimsFromContext(ctx)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	keyID, err := uuid.Parse(req.Id)
@@ -194,13 +202,9 @@ func (s *Server) GetMyApiKey(ctx context.Context, _ *emptypb.Empty) (*immichv1.A
 // GetApiKey retrieves a specific API key by ID
 func (s *Server) GetApiKey(ctx context.Context, req *immichv1.GetApiKeyRequest) (*immichv1.ApiKeyResponseDto, error) {
 	// Get user ID from context
-	claims, err := s.claimsFromContext(ctx)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Get all API keys for the user and find the requested one
@@ -3,44 +3,61 @@ package server
 import (
 	"bytes"
 	"context"
-	"crypto/sha1" //nolint:gosec // Immich uses SHA-1 asset checksums; not for crypto.
 	"encoding/base64"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/denysvitali/immich-go-backend/internal/assets"
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	"github.com/denysvitali/immich-go-backend/internal/jobs"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/sharedlinks"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
 	"github.com/denysvitali/immich-go-backend/internal/util"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
 )
 
 func (s *Server) GetAssets(ctx context.Context, request *immichv1.GetAssetsRequest) (*immichv1.GetAssetsResponse, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	fields, err := grpcutil.ParseFieldMask(&immichv1.Asset{}, request.GetFields(), "id")
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return nil, err
 	}
 
-	// Calculate offset for pagination
-	offset := util.Offset(request.GetPage(), request.GetSize())
+	size := s.config.PageSize(request.GetSize(), defaultAssetsPageSize)
+	cursorAt, cursorID, err := decodeAssetCursor(request.GetCursor())
+	if err != nil {
+		return nil, err
+	}
+	// A cursor replaces the page number.
+	offset := util.Offset(request.GetPage(), size)
+	if request.Cursor != nil {
+		offset = 0
+	}
 
 	// Build query parameters
 	var assetType pgtype.Text
@@ -56,19 +73,30 @@ func (s *Server) GetAssets(ctx context.Context, request *immichv1.GetAssetsReque
 	isFavorite := util.OptionalBool(request.IsFavorite)
 	isArchived := util.OptionalBool(request.IsArchived)
 	isTrashed := util.OptionalBool(request.IsTrashed)
+	isOffline := util.OptionalBool(request.IsOffline)
 
 	assets, err := s.db.GetAssets(ctx, sqlc.GetAssetsParams{
-		OwnerId:    userID,
-		Limit:      request.Size,
-		Offset:     offset,
-		Type:       assetType,
-		IsFavorite: isFavorite,
-		IsArchived: isArchived,
-		IsTrashed:  isTrashed,
+		OwnerId: userID,
+		// One extra row tells whether another page follows.
+		Limit:               size + 1,
+		Offset:              offset,
+		Type:                assetType,
+		IsFavorite:          isFavorite,
+		IsArchived:          isArchived,
+		IsTrashed:           isTrashed,
+		IsOffline:           isOffline,
+		CursorLocalDateTime: cursorAt,
+		CursorID:            cursorID,
 	})
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to get assets", err)
 	}
+	var nextCursor *string
+	if len(assets) > int(size) {
+		assets = assets[:size]
+		last := assets[len(assets)-1]
+		nextCursor = util.Ptr(util.EncodeCursor(last.LocalDateTime.Time, last.ID.Bytes))
+	}
 
 	// Get total count for pagination
 	totalCount, err := s.db.CountAssets(ctx, sqlc.CountAssetsParams{
@@ -77,6 +105,7 @@ func (s *Server) GetAssets(ctx context.Context, request *immichv1.GetAssetsReque
 		IsFavorite: isFavorite,
 		IsArchived: isArchived,
 		IsTrashed:  isTrashed,
+		IsOffline:  isOffline,
 	})
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to count assets", err)
@@ -86,43 +115,105 @@ func (s *Server) GetAssets(ctx context.Context, request *immichv1.GetAssetsReque
 	protoAssets := make([]*immichv1.Asset, len(assets))
 	for i, asset := range assets {
 		protoAssets[i] = s.convertAssetToProto(asset)
+		fields.Apply(protoAssets[i])
 	}
 
 	return &immichv1.GetAssetsResponse{
 		Assets: protoAssets,
 		PageInfo: &immichv1.PageInfo{
 			Page:  request.Page,
-			Size:  request.Size,
+			Size:  size,
 			Total: totalCount,
 		},
+		NextCursor: nextCursor,
 	}, nil
 }
 
+// decodeAssetCursor returns the capture time and id of the last asset of the
+// page cursor ends, or null values when cursor is empty.
+func decodeAssetCursor(cursor string) (pgtype.Timestamptz, pgtype.UUID, error) {
+	if cursor == "" {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, nil
+	}
+	at, id, err := util.DecodeCursor(cursor)
+	if err != nil {
+		return pgtype.Timestamptz{}, pgtype.UUID{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return pgtype.Timestamptz{Time: at, Valid: true}, pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
 func (s *Server) GetAsset(ctx context.Context, request *immichv1.GetAssetRequest) (*immichv1.Asset, error) {
-	asset, err := s.getAuthenticatedAsset(ctx, request.AssetId)
+	asset, link, err := s.getViewableOrSharedAsset(ctx, request.AssetId, request.GetKey())
 	if err != nil {
 		return nil, err
 	}
 
-	return s.convertAssetToProto(asset), nil
+	protoAsset := s.convertAssetToProto(asset)
+	if link != nil && !link.ShowExif {
+		return redactSharedLinkAsset(protoAsset), nil
+	}
+	exif, err := s.db.GetExifByAssetId(ctx, asset.ID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Metadata not extracted yet, or found in no file
+		protoAsset.ExifInfo = &immichv1.ExifInfo{}
+	case err != nil:
+		return nil, SanitizedInternal(ctx, "failed to get asset metadata", err)
+	default:
+		protoAsset.ExifInfo = exifToProto(exif)
+	}
+	return protoAsset, nil
 }
 
+// UploadAsset creates an asset. When the request carries an Idempotency-Key,
+// a retry with the same key returns the asset created by the first request.
 func (s *Server) UploadAsset(ctx context.Context, request *immichv1.UploadAssetRequest) (*immichv1.Asset, error) {
-	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	key := idempotencyKeyFromContext(ctx)
+	if key == "" {
+		return s.uploadAsset(ctx, request)
+	}
+
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	resp, _, err := s.runIdempotent(ctx, userID, key, request.GetChecksum(), func() (idempotentResponse, error) {
+		asset, err := s.uploadAsset(ctx, request)
+		if err != nil {
+			return idempotentResponse{}, err
+		}
+		body, err := protojson.Marshal(asset)
+		if err != nil {
+			return idempotentResponse{}, SanitizedInternal(ctx, "failed to encode asset", err)
+		}
+		return idempotentResponse{StatusCode: http.StatusCreated, Body: body}, nil
+	})
+	if err != nil {
+		return nil, idempotencyGrpcError(ctx, err)
+	}
+
+	var asset immichv1.Asset
+	if err := protojson.Unmarshal(resp.Body, &asset); err != nil {
+		return nil, SanitizedInternal(ctx, "failed to decode stored asset", err)
+	}
+	return &asset, nil
+}
+
+func (s *Server) uploadAsset(ctx context.Context, request *immichv1.UploadAssetRequest) (*immichv1.Asset, error) {
+	// Get user ID from context/auth
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return nil, err
 	}
 
 	assetData := request.AssetData
 	if assetData == nil {
 		return nil, status.Errorf(codes.InvalidArgument, "asset data is required")
 	}
+	if err := s.assetService.GetStorageService().ValidateUpload(assetData.OriginalFileName, ""); err != nil {
+		return nil, uploadValidationError(ctx, err)
+	}
 
 	// Convert asset type
 	assetType := "IMAGE"
@@ -130,29 +221,59 @@ func (s *Server) UploadAsset(ctx context.Context, request *immichv1.UploadAssetR
 		assetType = "VIDEO"
 	}
 
-	// Checksum is required - either from request or computed from file
-	var checksum []byte
-	if request.Checksum != nil && *request.Checksum != "" {
-		// Assume checksum is a hex string and decode it
-		if len(*request.Checksum) >= 2 {
-			checksum = []byte(*request.Checksum)
-		} else {
-			return nil, status.Error(codes.InvalidArgument, "invalid checksum format")
-		}
-	} else {
-		// Checksum is required for asset creation
+	// Checksum is required for asset creation
+	if request.GetChecksum() == "" {
 		return nil, status.Error(codes.InvalidArgument, "checksum is required for asset creation")
 	}
+	checksum, err := requestChecksum(request.GetChecksum(), request.GetChecksumAlgorithm())
+	if err != nil {
+		return nil, err
+	}
 
-	// Set default timestamps if not provided
-	fileCreatedAt := timestamppb.Now()
-	if assetData.FileCreatedAt != nil {
-		fileCreatedAt = assetData.FileCreatedAt
+	// A repeated backup of a device asset updates the asset the first one
+	// created. When its file did not change there is nothing to store.
+	deviceAsset := assetData.DeviceId != "" && assetData.DeviceAssetId != ""
+	var previous *sqlc.Asset
+	if deviceAsset {
+		existing, err := s.db.GetUploadedDeviceAsset(ctx, sqlc.GetUploadedDeviceAssetParams{
+			OwnerId:       userID,
+			DeviceId:      assetData.DeviceId,
+			DeviceAssetId: assetData.DeviceAssetId,
+		})
+		switch {
+		case err == nil:
+			if bytes.Equal(existing.Checksum, checksum.Stored()) {
+				return s.convertAssetToProto(existing), nil
+			}
+			previous = &existing
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, SanitizedInternal(ctx, "failed to look up device asset", err)
+		}
 	}
 
-	fileModifiedAt := timestamppb.Now()
-	if assetData.FileModifiedAt != nil {
-		fileModifiedAt = assetData.FileModifiedAt
+	// The file's times on the device date the asset, the upload time only
+	// stands in when the client sent neither. Such an asset is undated
+	// until metadata extraction finds a capture date.
+	createdAt, modifiedAt, dated := assets.UploadTimes(optionalTime(assetData.FileCreatedAt), optionalTime(assetData.FileModifiedAt), time.Now())
+	fileCreatedAt := timestamppb.New(createdAt)
+	fileModifiedAt := timestamppb.New(modifiedAt)
+	undated := !dated
+
+	// A Takeout sidecar dates the asset before metadata extraction applies
+	// the rest of it. Other sidecars are not read.
+	isFavorite := assetData.IsFavorite != nil && *assetData.IsFavorite
+	var takeout *assets.TakeoutSidecar
+	if len(request.SidecarData) > 0 {
+		takeout, err = assets.ParseTakeoutSidecar(request.SidecarData)
+		if err != nil {
+			logrus.WithError(err).WithField("file_name", assetData.OriginalFileName).Debug("UploadAsset: ignoring sidecar")
+		} else {
+			if takenAt, ok := takeout.TakenAt(); ok {
+				fileCreatedAt = timestamppb.New(takenAt)
+				undated = false
+			}
+			isFavorite = isFavorite || takeout.Favorited
+		}
 	}
 
 	// Determine storage path and optionally store the file.
@@ -161,14 +282,24 @@ func (s *Server) UploadAsset(ctx context.Context, request *immichv1.UploadAssetR
 	// Otherwise fall back to the client-supplied OriginalPath.
 	originalPath := assetData.OriginalPath
 	fileContent := request.FileContent
+	var downscaled *assets.DownscaledImage
+	var downscaledBackupPath string
+	stored := fileContent
 	if len(fileContent) > 0 {
+		if assetType == "IMAGE" {
+			downscaled = s.downscaleUpload(assetData.OriginalFileName, fileContent)
+		}
+		if downscaled != nil {
+			stored = downscaled.Data
+		}
+
 		storageService := s.assetService.GetStorageService()
 		uploadResult, uploadErr := storageService.UploadAsset(
 			ctx,
-			claims.UserID,
+			pgutil.UUIDToString(userID),
 			assetData.OriginalFileName,
-			bytes.NewReader(fileContent),
-			int64(len(fileContent)),
+			bytes.NewReader(stored),
+			int64(len(stored)),
 		)
 		if uploadErr != nil {
 			// Do not create a database record for an asset whose media was not
@@ -179,26 +310,63 @@ func (s *Server) UploadAsset(ctx context.Context, request *immichv1.UploadAssetR
 		} else {
 			originalPath = uploadResult.Path
 		}
+
+		// The uploaded file is kept before the asset exists, so failing to
+		// keep it fails the upload rather than losing it.
+		if downscaled != nil && s.config.Storage.Upload.KeepDownscaledOriginal {
+			downscaledBackupPath, err = s.assetService.KeepDownscaledOriginal(ctx, originalPath, fileContent)
+			if err != nil {
+				return nil, SanitizedInternal(ctx, "failed to store uploaded asset", err)
+			}
+		}
 	}
 
-	asset, err := s.db.CreateAsset(ctx, sqlc.CreateAssetParams{
-		DeviceAssetId:    assetData.DeviceAssetId,
-		OwnerId:          userID,
-		DeviceId:         assetData.DeviceId,
-		Type:             assetType,
-		OriginalPath:     originalPath,
-		FileCreatedAt:    pgtype.Timestamptz{Time: fileCreatedAt.AsTime(), Valid: true},
-		FileModifiedAt:   pgtype.Timestamptz{Time: fileModifiedAt.AsTime(), Valid: true},
-		LocalDateTime:    pgtype.Timestamptz{Time: fileCreatedAt.AsTime(), Valid: true},
-		OriginalFileName: assetData.OriginalFileName,
-		Checksum:         checksum,
-		IsFavorite:       assetData.IsFavorite != nil && *assetData.IsFavorite,
-		Visibility:       sqlc.AssetVisibilityEnumTimeline,
-		Status:           sqlc.AssetsStatusEnumActive,
-	})
+	params := sqlc.CreateAssetParams{
+		DeviceAssetId:     assetData.DeviceAssetId,
+		OwnerId:           userID,
+		DeviceId:          assetData.DeviceId,
+		Type:              assetType,
+		OriginalPath:      originalPath,
+		FileCreatedAt:     pgtype.Timestamptz{Time: fileCreatedAt.AsTime(), Valid: true},
+		FileModifiedAt:    pgtype.Timestamptz{Time: fileModifiedAt.AsTime(), Valid: true},
+		LocalDateTime:     pgtype.Timestamptz{Time: assets.WallClock(fileCreatedAt.AsTime(), s.config.DefaultLocation()), Valid: true},
+		OriginalFileName:  assetData.OriginalFileName,
+		Checksum:          checksum.Stored(),
+		IsFavorite:        isFavorite,
+		Visibility:        sqlc.AssetVisibilityEnumTimeline,
+		Status:            sqlc.AssetsStatusEnumActive,
+		ChecksumAlgorithm: pgtype.Text{String: string(checksum.Algorithm), Valid: true},
+		IsUndated:         pgtype.Bool{Bool: undated, Valid: true},
+	}
+	var asset sqlc.Asset
+	if deviceAsset {
+		asset, err = s.db.UpsertDeviceAsset(ctx, sqlc.UpsertDeviceAssetParams(params))
+	} else {
+		asset, err = s.db.CreateAsset(ctx, params)
+	}
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to create asset", err)
 	}
+	if len(stored) > 0 {
+		if err := s.assetService.RecordOriginalFile(ctx, &asset, stored); err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).Error("UploadAsset: failed to record original file")
+		}
+	}
+	if previous != nil {
+		if err := s.assetService.DiscardReplacedOriginal(ctx, *previous, asset.OriginalPath); err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("UploadAsset: failed to discard replaced original")
+		}
+	}
+	if downscaled != nil {
+		if err := s.assetService.RecordDownscale(ctx, asset.ID, downscaled, downscaledBackupPath); err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).Error("UploadAsset: failed to record downscaled image")
+		}
+	}
+	if takeout != nil {
+		if err := assets.StoreTakeoutSidecar(ctx, s.db.Queries, asset.ID, takeout); err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).Error("UploadAsset: failed to store Takeout sidecar")
+		}
+	}
 
 	// Enqueue background jobs for thumbnail generation and metadata extraction.
 	// When Redis / the job service is unavailable, fall back to an in-process goroutine
@@ -233,6 +401,8 @@ func (s *Server) UploadAsset(ctx context.Context, request *immichv1.UploadAssetR
 		s.assetService.TriggerProcessing(assetUUID)
 	}
 
+	s.publishWebhookEvent(ctx, webhooks.EventAssetUploaded, webhooks.AssetData(asset))
+
 	return s.convertAssetToProto(asset), nil
 }
 
@@ -242,28 +412,137 @@ func (s *Server) UpdateAsset(ctx context.Context, request *immichv1.UpdateAssetR
 		return nil, err
 	}
 
-	var isFavorite, isArchived pgtype.Bool
+	var isFavorite pgtype.Bool
 	if request.IsFavorite != nil {
 		isFavorite = pgtype.Bool{Bool: *request.IsFavorite, Valid: true}
 	}
-	if request.IsArchived != nil {
-		isArchived = pgtype.Bool{Bool: *request.IsArchived, Valid: true}
+	visibility, err := requestedVisibility(request.Visibility, request.IsArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.DateTimeOriginal != nil {
+		if err := s.updateAssetDateTimeOriginal(ctx, existingAsset.ID, request.DateTimeOriginal); err != nil {
+			return nil, err
+		}
+	}
+	if request.Latitude != nil && request.Longitude != nil {
+		if err := s.updateAssetLocation(ctx, existingAsset.ID, *request.Latitude, *request.Longitude); err != nil {
+			return nil, err
+		}
 	}
 
 	asset, err := s.db.UpdateAsset(ctx, sqlc.UpdateAssetParams{
 		ID:         existingAsset.ID,
 		IsFavorite: isFavorite,
-		IsArchived: isArchived,
+		Visibility: visibility,
 	})
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to update asset", err)
 	}
 
+	if metadataEdited(request.DateTimeOriginal, request.Latitude, request.Longitude) {
+		s.triggerMetadataWriteBack(ctx, asset.ID)
+	}
+	s.assetsUpdated(ctx, asset.OwnerId, []pgtype.UUID{asset.ID}, visibility.Valid)
+
 	return s.convertAssetToProto(asset), nil
 }
 
+// updateAssetLocation stores an edited GPS position.
+func (s *Server) updateAssetLocation(ctx context.Context, assetID pgtype.UUID, latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return status.Error(codes.InvalidArgument, "latitude or longitude out of range")
+	}
+	if _, err := s.db.UpdateExifLocation(ctx, sqlc.UpdateExifLocationParams{
+		AssetID:   assetID,
+		Latitude:  pgtype.Float8{Float64: latitude, Valid: true},
+		Longitude: pgtype.Float8{Float64: longitude, Valid: true},
+	}); err != nil {
+		return SanitizedInternal(ctx, "failed to update asset location", err)
+	}
+	return nil
+}
+
+// requestedVisibility resolves the visibility an update asks for. An explicit
+// visibility wins over is_archived; hidden is reserved for the motion part of
+// live photos and cannot be set by clients.
+func requestedVisibility(visibility *string, isArchived *bool) (sqlc.NullAssetVisibilityEnum, error) {
+	if visibility != nil {
+		switch v := sqlc.AssetVisibilityEnum(*visibility); v {
+		case sqlc.AssetVisibilityEnumTimeline, sqlc.AssetVisibilityEnumArchive, sqlc.AssetVisibilityEnumLocked:
+			return sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: v, Valid: true}, nil
+		default:
+			return sqlc.NullAssetVisibilityEnum{}, status.Errorf(codes.InvalidArgument, "invalid visibility: %q", *visibility)
+		}
+	}
+	if isArchived != nil {
+		v := sqlc.AssetVisibilityEnumTimeline
+		if *isArchived {
+			v = sqlc.AssetVisibilityEnumArchive
+		}
+		return sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: v, Valid: true}, nil
+	}
+	return sqlc.NullAssetVisibilityEnum{}, nil
+}
+
+func metadataEdited(taken *timestamppb.Timestamp, latitude, longitude *float64) bool {
+	return taken != nil || (latitude != nil && longitude != nil)
+}
+
+// triggerMetadataWriteBack writes an edited date or location back into the
+// asset's files when metadata write-back is enabled in the system config.
+func (s *Server) triggerMetadataWriteBack(ctx context.Context, assetID pgtype.UUID) {
+	if s.systemConfigService == nil || s.assetService == nil || !assetID.Valid {
+		return
+	}
+	cfg, err := s.systemConfigService.GetConfigDto(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read system config for metadata write-back")
+		return
+	}
+	if !cfg.Metadata.WriteBack.Enabled {
+		return
+	}
+	s.assetService.TriggerMetadataWriteBack(uuid.UUID(assetID.Bytes))
+}
+
+// optionalTime returns the time of ts, or nil when it is unset.
+func optionalTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// updateAssetDateTimeOriginal stores an edited capture time and re-derives
+// localDateTime from the timezone already recorded for the asset, so the
+// timeline keeps showing the wall-clock time at the capture location.
+func (s *Server) updateAssetDateTimeOriginal(ctx context.Context, assetID pgtype.UUID, taken *timestamppb.Timestamp) error {
+	exif, err := s.db.UpdateExifDateTimeOriginal(ctx, sqlc.UpdateExifDateTimeOriginalParams{
+		AssetID:          assetID,
+		DateTimeOriginal: pgtype.Timestamptz{Time: taken.AsTime(), Valid: true},
+	})
+	if err != nil {
+		return SanitizedInternal(ctx, "failed to update asset date", err)
+	}
+
+	var timeZone *string
+	if exif.TimeZone.Valid {
+		timeZone = &exif.TimeZone.String
+	}
+	if err := s.db.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+		ID:            assetID,
+		LocalDateTime: pgtype.Timestamptz{Time: assets.LocalDateTime(taken.AsTime(), timeZone, s.config.DefaultLocation()), Valid: true},
+	}); err != nil {
+		return SanitizedInternal(ctx, "failed to update asset date", err)
+	}
+	return nil
+}
+
 func (s *Server) UpdateAssets(ctx context.Context, request *immichv1.UpdateAssetsRequest) (*emptypb.Empty, error) {
-	userID, err := s.userUUIDFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -277,30 +556,57 @@ func (s *Server) UpdateAssets(ctx context.Context, request *immichv1.UpdateAsset
 		assetIDs = append(assetIDs, asset.ID)
 	}
 
-	var isFavorite, isArchived pgtype.Bool
+	var isFavorite pgtype.Bool
 	if request.IsFavorite != nil {
 		isFavorite = pgtype.Bool{Bool: *request.IsFavorite, Valid: true}
 	}
-	if request.IsArchived != nil {
-		isArchived = pgtype.Bool{Bool: *request.IsArchived, Valid: true}
+	visibility, err := requestedVisibility(request.Visibility, request.IsArchived)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, assetID := range assetIDs {
-		_, err := s.db.UpdateAsset(ctx, sqlc.UpdateAssetParams{
-			ID:         assetID,
-			IsFavorite: isFavorite,
-			IsArchived: isArchived,
-		})
-		if err != nil {
-			return nil, SanitizedInternal(ctx, "failed to update assets", err)
+	for i, assetID := range assetIDs {
+		if err := s.updateAssetForBulk(ctx, assetID, request, isFavorite, visibility); err != nil {
+			// Those updated so far are still announced
+			s.assetsUpdated(ctx, userID, assetIDs[:i], visibility.Valid)
+			return nil, err
 		}
 	}
+	s.assetsUpdated(ctx, userID, assetIDs, visibility.Valid)
 
 	return &emptypb.Empty{}, nil
 }
 
+// updateAssetForBulk applies the changes of a bulk update to one asset.
+func (s *Server) updateAssetForBulk(ctx context.Context, assetID pgtype.UUID, request *immichv1.UpdateAssetsRequest, isFavorite pgtype.Bool, visibility sqlc.NullAssetVisibilityEnum) error {
+	if request.DateTimeOriginal != nil {
+		if err := s.updateAssetDateTimeOriginal(ctx, assetID, request.DateTimeOriginal); err != nil {
+			return err
+		}
+	}
+	if request.Latitude != nil && request.Longitude != nil {
+		if err := s.updateAssetLocation(ctx, assetID, *request.Latitude, *request.Longitude); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.UpdateAsset(ctx, sqlc.UpdateAssetParams{
+		ID:         assetID,
+		IsFavorite: isFavorite,
+		Visibility: visibility,
+	})
+	if err != nil {
+		return SanitizedInternal(ctx, "failed to update assets", err)
+	}
+
+	if metadataEdited(request.DateTimeOriginal, request.Latitude, request.Longitude) {
+		s.triggerMetadataWriteBack(ctx, assetID)
+	}
+	return nil
+}
+
 func (s *Server) DeleteAssets(ctx context.Context, request *immichv1.DeleteAssetsRequest) (*emptypb.Empty, error) {
-	userID, err := s.userUUIDFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -321,6 +627,7 @@ func (s *Server) DeleteAssets(ctx context.Context, request *immichv1.DeleteAsset
 			if err := s.db.PermanentlyDeleteAsset(ctx, asset.ID); err != nil {
 				return nil, SanitizedInternal(ctx, "failed to delete assets", err)
 			}
+			s.publishWebhookEvent(ctx, webhooks.EventAssetDeleted, webhooks.AssetData(asset))
 			continue
 		}
 
@@ -330,6 +637,7 @@ func (s *Server) DeleteAssets(ctx context.Context, request *immichv1.DeleteAsset
 		}); err != nil {
 			return nil, SanitizedInternal(ctx, "failed to delete assets", err)
 		}
+		s.publishWebhookEvent(ctx, webhooks.EventAssetTrashed, webhooks.AssetData(asset))
 	}
 
 	return &emptypb.Empty{}, nil
@@ -337,16 +645,11 @@ func (s *Server) DeleteAssets(ctx context.Context, request *immichv1.DeleteAsset
 
 func (s *Server) CheckExistingAssets(ctx context.Context, request *immichv1.CheckExistingAssetsRequest) (*immichv1.CheckExistingAssetsResponse, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	existingAssets, err := s.db.CheckExistingAssets(ctx, sqlc.CheckExistingAssetsParams{
 		OwnerId:  userID,
 		DeviceId: request.DeviceId,
@@ -378,33 +681,28 @@ func (s *Server) CheckExistingAssets(ctx context.Context, request *immichv1.Chec
 // per file before uploading; known checksums come back as action "reject"
 // with reason "duplicate" so the client can skip the upload.
 func (s *Server) CheckBulkUpload(ctx context.Context, request *immichv1.CheckBulkUploadRequest) (*immichv1.CheckBulkUploadResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	items := request.GetAssets()
-	normalized := make([]string, len(items))
+	normalized := make([]assets.Checksum, len(items))
 	checksums := make([][]byte, 0, len(items))
 	seen := make(map[string]struct{}, len(items))
 	for i, item := range items {
-		hexSum := normalizeUploadChecksum(item.GetChecksum())
-		normalized[i] = hexSum
-		if hexSum == "" {
+		// Unusable checksums are accepted for upload rather than failing
+		// the whole check.
+		checksum, ok := bulkUploadChecksum(item)
+		normalized[i] = checksum
+		if !ok {
 			continue
 		}
-		if _, ok := seen[hexSum]; ok {
+		if _, ok := seen[checksum.Hex()]; ok {
 			continue
 		}
-		seen[hexSum] = struct{}{}
-		// Checksums are stored as the bytes of the hex string (same
-		// convention as UploadAsset).
-		checksums = append(checksums, []byte(hexSum))
+		seen[checksum.Hex()] = struct{}{}
+		checksums = append(checksums, checksum.Stored())
 	}
 
 	type duplicate struct {
@@ -421,7 +719,7 @@ func (s *Server) CheckBulkUpload(ctx context.Context, request *immichv1.CheckBul
 			return nil, SanitizedInternal(ctx, "failed to check bulk upload assets", err)
 		}
 		for _, row := range rows {
-			duplicates[string(row.Checksum)] = duplicate{
+			duplicates[row.ChecksumAlgorithm+":"+string(row.Checksum)] = duplicate{
 				assetID:   row.ID.String(),
 				isTrashed: row.Status == sqlc.AssetsStatusEnumTrashed,
 			}
@@ -434,7 +732,7 @@ func (s *Server) CheckBulkUpload(ctx context.Context, request *immichv1.CheckBul
 			Id:     item.GetId(),
 			Action: "accept",
 		}
-		if dup, ok := duplicates[normalized[i]]; ok {
+		if dup, ok := duplicates[string(normalized[i].Algorithm)+":"+normalized[i].Hex()]; ok {
 			reason := "duplicate"
 			result.Action = "reject"
 			result.Reason = &reason
@@ -447,32 +745,115 @@ func (s *Server) CheckBulkUpload(ctx context.Context, request *immichv1.CheckBul
 	return &immichv1.CheckBulkUploadResponse{Results: results}, nil
 }
 
-// normalizeUploadChecksum accepts the SHA-1 forms Immich clients send —
-// 40-char hex (web) or base64 (mobile) — and returns lowercase hex, or ""
-// when the value is unusable.
-func normalizeUploadChecksum(v string) string {
-	v = strings.TrimSpace(v)
-	if len(v) == 2*sha1.Size {
-		if _, err := hex.DecodeString(v); err == nil {
-			return strings.ToLower(v)
+// maxChecksumLookup caps the checksums of one GetAssetsByChecksums request.
+const maxChecksumLookup = 1000
+
+// GetAssetsByChecksums tells a client which of its files the user already
+// has, by content rather than device asset id, so the answer survives
+// reinstalls that change device ids. Trashed assets count as existing.
+func (s *Server) GetAssetsByChecksums(ctx context.Context, request *immichv1.GetAssetsByChecksumsRequest) (*immichv1.GetAssetsByChecksumsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := request.GetChecksums()
+	if len(requested) > maxChecksumLookup {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d checksums can be looked up at once", maxChecksumLookup)
+	}
+
+	normalized := make([]assets.Checksum, len(requested))
+	checksums := make([][]byte, 0, len(requested))
+	seen := make(map[string]struct{}, len(requested))
+	for i, value := range requested {
+		checksum, err := requestChecksum(value, request.GetChecksumAlgorithm())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "checksums[%d]: %s", i, status.Convert(err).Message())
 		}
+		normalized[i] = checksum
+		if _, ok := seen[checksum.Hex()]; ok {
+			continue
+		}
+		seen[checksum.Hex()] = struct{}{}
+		checksums = append(checksums, checksum.Stored())
 	}
-	if raw, err := base64.StdEncoding.DecodeString(v); err == nil && len(raw) == sha1.Size {
-		return hex.EncodeToString(raw)
+
+	response := &immichv1.GetAssetsByChecksumsResponse{Assets: []*immichv1.AssetChecksumMatch{}}
+	if len(checksums) == 0 {
+		return response, nil
 	}
-	return ""
+
+	rows, err := s.db.GetAssetsByChecksumsAndOwner(ctx, sqlc.GetAssetsByChecksumsAndOwnerParams{
+		OwnerID:   userID,
+		Checksums: checksums,
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to look up asset checksums", err)
+	}
+	existing := make(map[string]sqlc.GetAssetsByChecksumsAndOwnerRow, len(rows))
+	for _, row := range rows {
+		existing[row.ChecksumAlgorithm+":"+string(row.Checksum)] = row
+	}
+
+	for i, checksum := range normalized {
+		row, ok := existing[string(checksum.Algorithm)+":"+checksum.Hex()]
+		if !ok {
+			continue
+		}
+		response.Assets = append(response.Assets, &immichv1.AssetChecksumMatch{
+			Checksum:  requested[i],
+			AssetId:   row.ID.String(),
+			IsTrashed: row.Status == sqlc.AssetsStatusEnumTrashed,
+		})
+	}
+
+	return response, nil
 }
 
-func (s *Server) GetAssetStatistics(ctx context.Context, request *immichv1.GetAssetStatisticsRequest) (*immichv1.AssetStatisticsResponse, error) {
-	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+// bulkUploadChecksum parses the checksum of a bulk upload check item, in
+// any form Immich clients send: hex (web) or base64 (mobile) SHA-1, or a
+// declared SHA-256.
+func bulkUploadChecksum(item *immichv1.AssetBulkUploadCheckItem) (assets.Checksum, bool) {
+	algorithm, err := assets.ParseChecksumAlgorithm(item.GetChecksumAlgorithm())
 	if err != nil {
-		return nil, err
+		return assets.Checksum{}, false
+	}
+	checksum, err := assets.ParseChecksum(item.GetChecksum(), algorithm)
+	if err != nil {
+		return assets.Checksum{}, false
+	}
+	return checksum, true
+}
+
+// requestChecksum parses a client-supplied checksum and its optional
+// algorithm, rejecting digests of the wrong length.
+// uploadValidationError reports files the upload configuration does not
+// accept as a bad request, with the reason the storage layer gave.
+func uploadValidationError(ctx context.Context, err error) error {
+	var storageErr *storage.StorageError
+	if errors.Is(err, storage.ErrUnsupportedMediaType) && errors.As(err, &storageErr) {
+		return status.Error(codes.InvalidArgument, storageErr.Err.Error())
 	}
+	return SanitizedInternal(ctx, "failed to validate upload", err)
+}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
+func requestChecksum(value, algorithm string) (assets.Checksum, error) {
+	alg, err := assets.ParseChecksumAlgorithm(algorithm)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return assets.Checksum{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	checksum, err := assets.ParseChecksum(value, alg)
+	if err != nil {
+		return assets.Checksum{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return checksum, nil
+}
+
+func (s *Server) GetAssetStatistics(ctx context.Context, request *immichv1.GetAssetStatisticsRequest) (*immichv1.AssetStatisticsResponse, error) {
+	// Get user ID from context/auth
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	stats, err := s.db.GetAssetStatistics(ctx, userID)
@@ -487,16 +868,82 @@ func (s *Server) GetAssetStatistics(ctx context.Context, request *immichv1.GetAs
 	}, nil
 }
 
-func (s *Server) GetAllUserAssetsByDeviceId(ctx context.Context, request *immichv1.GetAllUserAssetsByDeviceIdRequest) (*immichv1.GetAllUserAssetsByDeviceIdResponse, error) {
-	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+const (
+	defaultAssetsPageSize   = 100
+	defaultAssetIdsPageSize = 1000
+)
+
+// GetAssetIds lists the assets GetAssets would, but only their ids, dates and
+// checksums, so syncing a large timeline does not transfer its metadata.
+func (s *Server) GetAssetIds(ctx context.Context, request *immichv1.GetAssetIdsRequest) (*immichv1.GetAssetIdsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	size := s.config.PageSize(request.GetSize(), defaultAssetIdsPageSize)
+	cursorAt, cursorID, err := decodeAssetCursor(request.GetCursor())
 	if err != nil {
 		return nil, err
 	}
+	offset := util.Offset(request.GetPage(), size)
+	if request.Cursor != nil {
+		offset = 0
+	}
+	isTrashed := util.OptionalBool(request.IsTrashed)
+
+	rows, err := s.db.GetAssetIds(ctx, sqlc.GetAssetIdsParams{
+		OwnerID:             userID,
+		IsTrashed:           isTrashed,
+		CursorLocalDateTime: cursorAt,
+		CursorID:            cursorID,
+		Limit:               size + 1,
+		Offset:              offset,
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get asset ids", err)
+	}
+	var nextCursor *string
+	if len(rows) > int(size) {
+		rows = rows[:size]
+		last := rows[len(rows)-1]
+		nextCursor = util.Ptr(util.EncodeCursor(last.LocalDateTime.Time, last.ID.Bytes))
+	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	totalCount, err := s.db.CountAssets(ctx, sqlc.CountAssetsParams{
+		OwnerId:   userID,
+		IsTrashed: isTrashed,
+	})
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return nil, SanitizedInternal(ctx, "failed to count assets", err)
+	}
+
+	entries := make([]*immichv1.AssetIdEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = &immichv1.AssetIdEntry{
+			Id:            row.ID.String(),
+			FileCreatedAt: timestamppb.New(row.FileCreatedAt.Time),
+			LocalDateTime: timestamppb.New(row.LocalDateTime.Time),
+			Checksum:      fmt.Sprintf("%x", row.Checksum),
+		}
+	}
+
+	return &immichv1.GetAssetIdsResponse{
+		Assets: entries,
+		PageInfo: &immichv1.PageInfo{
+			Page:  request.Page,
+			Size:  size,
+			Total: totalCount,
+		},
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (s *Server) GetAllUserAssetsByDeviceId(ctx context.Context, request *immichv1.GetAllUserAssetsByDeviceIdRequest) (*immichv1.GetAllUserAssetsByDeviceIdResponse, error) {
+	// Get user ID from context/auth
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	assetIDs, err := s.db.GetAssetsByDeviceId(ctx, sqlc.GetAssetsByDeviceIdParams{
@@ -520,16 +967,11 @@ func (s *Server) GetAllUserAssetsByDeviceId(ctx context.Context, request *immich
 
 func (s *Server) GetRandom(ctx context.Context, request *immichv1.GetRandomRequest) (*immichv1.GetRandomResponse, error) {
 	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	count := int32(10) // Default count
 	if request.Count != nil {
 		count = *request.Count
@@ -553,33 +995,139 @@ func (s *Server) GetRandom(ctx context.Context, request *immichv1.GetRandomReque
 	}, nil
 }
 
+// GetRecentlyAddedAssets lists the caller's assets by upload time, newest
+// first. Limit is the page size; trashed assets are listed by
+// GetTrashedAssets instead.
 func (s *Server) GetRecentlyAddedAssets(ctx context.Context, request *immichv1.GetRecentlyAddedAssetsRequest) (*immichv1.GetRecentlyAddedAssetsResponse, error) {
-	// Get user ID from context/auth
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	size := s.config.PageSize(int32(min(request.GetLimit(), math.MaxInt32)), 12)
+
+	assets, err := s.db.GetRecentlyAddedAssets(ctx, sqlc.GetRecentlyAddedAssetsParams{
+		OwnerId: userID,
+		Limit:   size,
+		Offset:  util.Offset(request.GetPage(), size),
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get recently added assets", err)
+	}
+	total, err := s.db.CountRecentlyAddedAssets(ctx, userID)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return nil, SanitizedInternal(ctx, "failed to count recently added assets", err)
 	}
 
-	// Default to 12 when limit is 0, cap at 100 to prevent abuse
-	limit := request.GetLimit()
-	if limit == 0 {
-		limit = 12
+	protoAssets := make([]*immichv1.Asset, len(assets))
+	for i, asset := range assets {
+		protoAssets[i] = s.convertAssetToProto(asset)
 	}
-	if limit > 100 {
-		limit = 100
+
+	return &immichv1.GetRecentlyAddedAssetsResponse{
+		Assets: protoAssets,
+		PageInfo: &immichv1.PageInfo{
+			Page:  request.GetPage(),
+			Size:  size,
+			Total: total,
+		},
+	}, nil
+}
+
+// GetTrashedAssets lists the caller's trashed assets, most recently trashed
+// first, with when the trash retention setting has them deleted for good.
+func (s *Server) GetTrashedAssets(ctx context.Context, request *immichv1.GetTrashedAssetsRequest) (*immichv1.GetTrashedAssetsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	assets, err := s.db.GetRecentlyAddedAssets(ctx, sqlc.GetRecentlyAddedAssetsParams{
+	size := s.config.PageSize(request.GetSize(), defaultAssetsPageSize)
+
+	rows, err := s.db.GetTrashedAssets(ctx, sqlc.GetTrashedAssetsParams{
 		OwnerId: userID,
-		Limit:   int32(limit),
+		Limit:   size,
+		Offset:  util.Offset(request.GetPage(), size),
 	})
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "failed to get recently added assets", err)
+		return nil, SanitizedInternal(ctx, "failed to get trashed assets", err)
+	}
+	total, err := s.db.CountTrashedAssets(ctx, userID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to count trashed assets", err)
+	}
+
+	trash := systemconfig.DefaultDto().Trash
+	if s.systemConfigService != nil {
+		cfg, err := s.systemConfigService.GetConfigDto(ctx)
+		if err != nil {
+			return nil, SanitizedInternal(ctx, "failed to read trash settings", err)
+		}
+		trash = cfg.Trash
+	}
+
+	now := time.Now()
+	items := make([]*immichv1.TrashedAsset, len(rows))
+	for i, row := range rows {
+		item := &immichv1.TrashedAsset{
+			Asset:     s.convertAssetToProto(row.Asset),
+			TrashedAt: timestamppb.New(row.TrashedAt.Time),
+		}
+		if trash.Enabled {
+			purgeAt, days := trashPurge(row.TrashedAt.Time, trash.Days, now)
+			item.PurgeAt = timestamppb.New(purgeAt)
+			item.DaysUntilPurge = &days
+		}
+		items[i] = item
+	}
+
+	return &immichv1.GetTrashedAssetsResponse{
+		Items: items,
+		PageInfo: &immichv1.PageInfo{
+			Page:  request.GetPage(),
+			Size:  size,
+			Total: total,
+		},
+	}, nil
+}
+
+// trashPurge returns when an asset trashed at trashedAt is due to be deleted
+// for good, and the days left until then counting a started day as a whole
+// one. An asset past its time has 0 days left.
+func trashPurge(trashedAt time.Time, retentionDays int, now time.Time) (time.Time, int32) {
+	purgeAt := trashedAt.AddDate(0, 0, retentionDays)
+	left := purgeAt.Sub(now)
+	if left <= 0 {
+		return purgeAt, 0
+	}
+	const day = 24 * time.Hour
+	return purgeAt, int32((left + day - 1) / day)
+}
+
+// GetLockedAssets lists the assets in the caller's locked folder. They are
+// left out of every other listing, so this is the only way to browse them.
+func (s *Server) GetLockedAssets(ctx context.Context, request *immichv1.GetLockedAssetsRequest) (*immichv1.GetAssetsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireUnlockedSession(ctx); err != nil {
+		return nil, err
+	}
+
+	size := s.config.PageSize(request.GetSize(), defaultAssetsPageSize)
+
+	assets, err := s.db.GetLockedAssets(ctx, sqlc.GetLockedAssetsParams{
+		OwnerId: userID,
+		Limit:   size,
+		Offset:  util.Offset(request.GetPage(), size),
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get locked assets", err)
+	}
+	total, err := s.db.CountLockedAssets(ctx, userID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to count locked assets", err)
 	}
 
 	protoAssets := make([]*immichv1.Asset, len(assets))
@@ -587,13 +1135,18 @@ func (s *Server) GetRecentlyAddedAssets(ctx context.Context, request *immichv1.G
 		protoAssets[i] = s.convertAssetToProto(asset)
 	}
 
-	return &immichv1.GetRecentlyAddedAssetsResponse{
+	return &immichv1.GetAssetsResponse{
 		Assets: protoAssets,
+		PageInfo: &immichv1.PageInfo{
+			Page:  request.GetPage(),
+			Size:  size,
+			Total: total,
+		},
 	}, nil
 }
 
 func (s *Server) RunAssetJobs(ctx context.Context, request *immichv1.RunAssetJobsRequest) (*emptypb.Empty, error) {
-	userID, err := s.userUUIDFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -619,6 +1172,10 @@ func (s *Server) RunAssetJobs(ctx context.Context, request *immichv1.RunAssetJob
 		if err := s.enqueueAssetJobsForAssets(ctx, userID, request.GetAssetIds(), jobs.JobTypeVideoTranscode, jobs.PriorityHigh); err != nil {
 			return nil, err
 		}
+	case immichv1.AssetJobName_ASSET_JOB_NAME_OBJECT_DETECTION:
+		if err := s.enqueueAssetJobsForAssets(ctx, userID, request.GetAssetIds(), jobs.JobTypeObjectDetection, jobs.PriorityNormal); err != nil {
+			return nil, err
+		}
 	default:
 		return nil, status.Errorf(codes.InvalidArgument, "unknown asset job name: %v", request.GetName())
 	}
@@ -651,6 +1208,8 @@ func (s *Server) enqueueAssetJobsForAssets(
 			payload = jobs.MetadataExtractionPayload{AssetID: asset.ID.String()}
 		case jobs.JobTypeVideoTranscode:
 			payload = jobs.VideoTranscodePayload{AssetID: asset.ID.String(), Quality: "medium", Format: "mp4"}
+		case jobs.JobTypeObjectDetection:
+			payload = jobs.ObjectDetectionPayload{AssetID: asset.ID.String()}
 		default:
 			return status.Errorf(codes.InvalidArgument, "unsupported asset job type: %s", jobType)
 		}
@@ -664,10 +1223,45 @@ func (s *Server) enqueueAssetJobsForAssets(
 }
 
 func (s *Server) DownloadAsset(ctx context.Context, request *immichv1.DownloadAssetRequest) (*immichv1.DownloadAssetResponse, error) {
-	asset, err := s.getAuthenticatedAsset(ctx, request.AssetId)
+	asset, link, err := s.getViewableOrSharedAsset(ctx, request.AssetId, request.GetKey())
 	if err != nil {
 		return nil, err
 	}
+	stripMetadata := request.StripMetadata
+	// Link holders may not be signed in; they are never the owner.
+	var userID pgtype.UUID
+	if link != nil {
+		if !link.AllowDownload {
+			return nil, status.Error(codes.PermissionDenied, "shared link does not allow downloads")
+		}
+		if !link.ShowExif {
+			strip := true
+			stripMetadata = &strip
+		}
+	} else if userID, err = s.userIDFromContext(ctx); err != nil {
+		return nil, err
+	}
+	if asset.IsOffline {
+		return nil, assetOfflineError(ctx)
+	}
+
+	if wantsStrippedOriginal(asset, userID, stripMetadata) {
+		data, err := s.strippedOriginal(ctx, asset)
+		if err == nil {
+			return &immichv1.DownloadAssetResponse{
+				Data:             data,
+				ContentType:      assetContentType(asset),
+				Filename:         asset.OriginalFileName,
+				MetadataStripped: true,
+			}, nil
+		}
+		if !errors.Is(err, assets.ErrStripUnsupported) {
+			return nil, err
+		}
+		if asset.OwnerId != userID {
+			return nil, errStripRequired
+		}
+	}
 
 	// Get storage service
 	storageService := s.assetService.GetStorageService()
@@ -675,7 +1269,7 @@ func (s *Server) DownloadAsset(ctx context.Context, request *immichv1.DownloadAs
 	// Download the asset
 	assetData, err := storageService.Download(ctx, asset.OriginalPath)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "failed to retrieve asset", err)
+		return nil, s.originalReadError(ctx, storageService, asset, asset.OriginalPath, err)
 	}
 	defer assetData.Close()
 
@@ -687,7 +1281,7 @@ func (s *Server) DownloadAsset(ctx context.Context, request *immichv1.DownloadAs
 
 	return &immichv1.DownloadAssetResponse{
 		Data:        data,
-		ContentType: assetDownloadContentType(asset.OriginalFileName),
+		ContentType: assetContentType(asset),
 		Filename:    asset.OriginalFileName,
 	}, nil
 }
@@ -703,11 +1297,12 @@ func (s *Server) ReplaceAsset(ctx context.Context, request *immichv1.ReplaceAsse
 		return nil, status.Error(codes.InvalidArgument, "file content is required for asset replacement")
 	}
 
-	if request.Checksum == nil || *request.Checksum == "" {
+	if request.GetChecksum() == "" {
 		return nil, status.Error(codes.InvalidArgument, "checksum is required for asset replacement")
 	}
-	if len(*request.Checksum) < 2 {
-		return nil, status.Error(codes.InvalidArgument, "invalid checksum format")
+	checksum, err := requestChecksum(request.GetChecksum(), request.GetChecksumAlgorithm())
+	if err != nil {
+		return nil, err
 	}
 
 	fileModifiedAt := timestamppb.Now()
@@ -721,14 +1316,18 @@ func (s *Server) ReplaceAsset(ctx context.Context, request *immichv1.ReplaceAsse
 	}
 
 	updatedAsset, err := s.db.ReplaceAssetFile(ctx, sqlc.ReplaceAssetFileParams{
-		Checksum:       []byte(*request.Checksum),
-		FileModifiedAt: pgtype.Timestamptz{Time: fileModifiedAt.AsTime(), Valid: true},
-		ID:             existingAsset.ID,
-		OwnerID:        existingAsset.OwnerId,
+		Checksum:          checksum.Stored(),
+		ChecksumAlgorithm: string(checksum.Algorithm),
+		FileModifiedAt:    pgtype.Timestamptz{Time: fileModifiedAt.AsTime(), Valid: true},
+		ID:                existingAsset.ID,
+		OwnerID:           existingAsset.OwnerId,
 	})
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to update asset after replacement", err)
 	}
+	if err := s.assetService.RecordOriginalFile(ctx, &updatedAsset, fileContent); err != nil {
+		logrus.WithError(err).WithField("asset_id", updatedAsset.ID.String()).Error("ReplaceAsset: failed to record original file")
+	}
 
 	assetUUID := uuid.UUID(updatedAsset.ID.Bytes)
 	assetIDStr := assetUUID.String()
@@ -757,8 +1356,8 @@ func (s *Server) ReplaceAsset(ctx context.Context, request *immichv1.ReplaceAsse
 	return s.convertAssetToProto(updatedAsset), nil
 }
 
-// enqueueMLJobsForAsset queues face detection + CLIP indexing when those
-// features are active. Handlers themselves no-op if ML is later disabled.
+// enqueueMLJobsForAsset queues face detection, CLIP indexing and object
+// detection when those features are active. Handlers themselves no-op if ML is later disabled.
 func (s *Server) enqueueMLJobsForAsset(ctx context.Context, assetID, assetType string) {
 	if s.jobService == nil || s.config == nil {
 		return
@@ -778,17 +1377,24 @@ func (s *Server) enqueueMLJobsForAsset(ctx context.Context, assetID, assetType s
 			logrus.WithError(err).Warn("failed to enqueue smart search indexing job")
 		}
 	}
+	if s.config.ObjectDetectionActive() {
+		payload := jobs.ObjectDetectionPayload{AssetID: assetID}
+		if err := s.jobService.EnqueueJobWithPriority(ctx, jobs.JobTypeObjectDetection, payload, jobs.PriorityLow); err != nil {
+			logrus.WithError(err).Warn("failed to enqueue object detection job")
+		}
+	}
 }
 
 func (s *Server) GetAssetThumbnail(ctx context.Context, request *immichv1.GetAssetThumbnailRequest) (*immichv1.GetAssetThumbnailResponse, error) {
-	asset, err := s.getAuthenticatedAsset(ctx, request.AssetId)
+	asset, err := s.getViewableAsset(ctx, request.AssetId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Determine thumbnail type. The upstream clients select via
-	// ?size=thumbnail|preview|fullsize (AssetMediaSize); format is a
-	// secondary hint used by older callers.
+	// ?size=thumbnail|preview|fullsize (AssetMediaSize), other clients may
+	// name a preset of the system config instead; format is a secondary
+	// hint used by older callers.
 	thumbnailType := assets.ThumbnailTypeWebp
 	if request.Format != nil {
 		switch *request.Format {
@@ -806,58 +1412,134 @@ func (s *Server) GetAssetThumbnail(ctx context.Context, request *immichv1.GetAss
 			thumbnailType = assets.ThumbnailTypeThumb
 		case "preview", "fullsize":
 			thumbnailType = assets.ThumbnailTypePreview
+		default:
+			// An unknown preset gets the default thumbnail
+			if presetType, ok := s.assetService.ThumbnailGenerator().Preset(*request.Size); ok {
+				thumbnailType = presetType
+			}
+		}
+		if *request.Size == "fullsize" && assets.NeedsWebConversion(storage.MimeTypeByExtension(fileExtension(asset.OriginalFileName))) {
+			return s.assetFullsize(ctx, asset)
 		}
 	}
 
-	// Generate thumbnail path
-	generator := assets.NewThumbnailGenerator()
-	thumbnailPath := generator.GetThumbnailPath(asset.OriginalPath, thumbnailType)
+	return s.assetThumbnail(ctx, asset, thumbnailType)
+}
 
-	// Try to retrieve existing thumbnail from storage
+// assetFullsize serves a full-resolution JPEG of an original browsers cannot
+// display, converting it on first request and keeping the result as an asset
+// file. Without a HEIC decoder, or when conversion fails, the preview is
+// served instead. The original itself is never modified.
+func (s *Server) assetFullsize(ctx context.Context, asset sqlc.Asset) (*immichv1.GetAssetThumbnailResponse, error) {
 	storageService := s.assetService.GetStorageService()
-	thumbnailData, err := storageService.Download(ctx, thumbnailPath)
-	if err != nil {
-		// If thumbnail doesn't exist, try to generate it
-		originalData, err := storageService.Download(ctx, asset.OriginalPath)
-		if err != nil {
-			return nil, SanitizedInternal(ctx, "failed to retrieve original asset", err)
-		}
+	derivatives := storageService.Derivatives()
 
-		// Generate thumbnails
-		thumbnails, err := generator.GenerateThumbnails(ctx, originalData, asset.OriginalFileName)
-		if err != nil {
-			return nil, SanitizedInternal(ctx, "failed to generate thumbnail", err)
+	files, err := s.db.GetAssetFilesByType(ctx, sqlc.GetAssetFilesByTypeParams{
+		AssetId: asset.ID,
+		Type:    string(assets.ThumbnailTypeFullsize),
+	})
+	if err == nil && len(files) > 0 {
+		if data, err := downloadAll(ctx, derivatives, files[0].Path); err == nil {
+			return &immichv1.GetAssetThumbnailResponse{Data: data, ContentType: "image/jpeg"}, nil
 		}
+	}
 
-		// Get the requested thumbnail type
-		thumbData, ok := thumbnails[thumbnailType]
-		if !ok {
-			return nil, status.Errorf(codes.Internal, "thumbnail type not generated")
-		}
+	if !assets.HEICDecoderAvailable() {
+		return s.assetThumbnail(ctx, asset, assets.ThumbnailTypePreview)
+	}
 
-		// Store the generated thumbnail for future use
-		if err := storageService.Upload(ctx, thumbnailPath, bytes.NewReader(thumbData), s.getThumbnailContentType(thumbnailType)); err != nil {
-			// Log error but don't fail the request
-			logrus.WithError(err).Warn("Failed to store generated thumbnail")
-		}
+	original, err := downloadAll(ctx, storageService, asset.OriginalPath)
+	if err != nil {
+		logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("Failed to read original for full-size conversion")
+		return s.assetThumbnail(ctx, asset, assets.ThumbnailTypePreview)
+	}
+	data, err := assets.ConvertHEICToJPEG(ctx, original, assets.FullsizeQuality)
+	if err != nil {
+		logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("Failed to convert original to JPEG")
+		return s.assetThumbnail(ctx, asset, assets.ThumbnailTypePreview)
+	}
 
-		// Return the generated thumbnail data
-		return &immichv1.GetAssetThumbnailResponse{
-			Data:        thumbData,
-			ContentType: s.getThumbnailContentType(thumbnailType),
-		}, nil
+	// Caching is best effort: the converted bytes are served either way.
+	fullsizePath := assets.NewThumbnailGenerator().GetThumbnailPath(asset.OriginalPath, assets.ThumbnailTypeFullsize)
+	if err := derivatives.UploadBytes(ctx, fullsizePath, data, "image/jpeg"); err != nil {
+		logrus.WithError(err).Warn("Failed to store full-size conversion")
+	} else if _, err := s.db.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{
+		AssetId: asset.ID,
+		Type:    string(assets.ThumbnailTypeFullsize),
+		Path:    fullsizePath,
+	}); err != nil {
+		logrus.WithError(err).Warn("Failed to record full-size conversion")
 	}
 
-	// Read thumbnail data
-	thumbData, err := io.ReadAll(thumbnailData)
+	return &immichv1.GetAssetThumbnailResponse{Data: data, ContentType: "image/jpeg"}, nil
+}
+
+// downloadAll reads the whole file at path from storageService.
+func downloadAll(ctx context.Context, storageService *storage.Service, path string) ([]byte, error) {
+	reader, err := storageService.Download(ctx, path)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "failed to read thumbnail data", err)
+		return nil, err
 	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
 
-	return &immichv1.GetAssetThumbnailResponse{
-		Data:        thumbData,
-		ContentType: s.getThumbnailContentType(thumbnailType),
-	}, nil
+// assetThumbnail loads the stored thumbnail of asset, generating and storing
+// it when it is missing or was generated before the thumbnail settings last
+// changed. Callers are responsible for access checks.
+func (s *Server) assetThumbnail(ctx context.Context, asset sqlc.Asset, thumbnailType assets.ThumbnailType) (*immichv1.GetAssetThumbnailResponse, error) {
+	storageService := s.assetService.GetStorageService()
+	thumbnailStorage := storageService.Derivatives()
+	contentType := s.getThumbnailContentType(thumbnailType)
+
+	files, err := s.db.GetAssetFilesByType(ctx, sqlc.GetAssetFilesByTypeParams{
+		AssetId: asset.ID,
+		Type:    string(thumbnailType),
+	})
+	if err == nil && len(files) > 0 && !s.thumbnailStaleness.stale(files[0]) {
+		if thumbData, err := downloadAll(ctx, thumbnailStorage, files[0].Path); err == nil {
+			return &immichv1.GetAssetThumbnailResponse{Data: thumbData, ContentType: contentType}, nil
+		}
+	}
+
+	// The thumbnail is missing or stale, so generate it again. Without the
+	// original, any thumbnail stored before is better than none.
+	if asset.IsOffline {
+		return s.staleThumbnailOr(ctx, asset, thumbnailType, assetOfflineError(ctx))
+	}
+	thumbData, err := s.assetService.RegenerateThumbnail(ctx, asset, thumbnailType)
+	if errors.Is(err, assets.ErrOriginalUnreadable) {
+		return s.staleThumbnailOr(ctx, asset, thumbnailType,
+			s.originalReadError(ctx, storageService, asset, asset.OriginalPath, err))
+	}
+	if err != nil {
+		return s.staleThumbnailOr(ctx, asset, thumbnailType,
+			SanitizedInternal(ctx, "failed to generate thumbnail", err))
+	}
+
+	return &immichv1.GetAssetThumbnailResponse{Data: thumbData, ContentType: contentType}, nil
+}
+
+// staleThumbnailOr serves the thumbnail stored for asset at the path of
+// thumbnailType, or any other stored thumbnail, or fails with err when there
+// is none.
+func (s *Server) staleThumbnailOr(ctx context.Context, asset sqlc.Asset, thumbnailType assets.ThumbnailType, err error) (*immichv1.GetAssetThumbnailResponse, error) {
+	thumbnailPath := s.assetService.ThumbnailGenerator().GetThumbnailPath(asset.OriginalPath, thumbnailType)
+	if data, readErr := downloadAll(ctx, s.assetService.GetStorageService().Derivatives(), thumbnailPath); readErr == nil && len(data) > 0 {
+		return &immichv1.GetAssetThumbnailResponse{Data: data, ContentType: s.getThumbnailContentType(thumbnailType)}, nil
+	}
+	return s.cachedThumbnailOr(ctx, asset, thumbnailType, err)
+}
+
+// cachedThumbnailOr serves a stored thumbnail of asset, or fails with err
+// when there is none.
+func (s *Server) cachedThumbnailOr(ctx context.Context, asset sqlc.Asset, thumbnailType assets.ThumbnailType, err error) (*immichv1.GetAssetThumbnailResponse, error) {
+	data, ok := s.cachedThumbnail(ctx, asset, thumbnailType)
+	if !ok {
+		return nil, err
+	}
+	// Every stored thumbnail type is a JPEG.
+	return &immichv1.GetAssetThumbnailResponse{Data: data, ContentType: "image/jpeg"}, nil
 }
 
 // getThumbnailContentType returns the MIME type for a thumbnail type
@@ -877,7 +1559,7 @@ var thumbnailContentTypes = map[assets.ThumbnailType]string{
 }
 
 func (s *Server) PlayAssetVideo(ctx context.Context, request *immichv1.PlayAssetVideoRequest) (*immichv1.PlayAssetVideoResponse, error) {
-	asset, err := s.getAuthenticatedAsset(ctx, request.AssetId)
+	asset, err := s.getViewableAsset(ctx, request.AssetId)
 	if err != nil {
 		return nil, err
 	}
@@ -891,14 +1573,22 @@ func (s *Server) PlayAssetVideo(ctx context.Context, request *immichv1.PlayAsset
 	storageService := s.assetService.GetStorageService()
 
 	// Prefer encoded H.264 copy if available, otherwise fall back to original
-	videoPath := asset.OriginalPath
+	var videoStream io.ReadCloser
 	if asset.EncodedVideoPath.Valid && asset.EncodedVideoPath.String != "" {
-		videoPath = asset.EncodedVideoPath.String
+		videoStream, err = storageService.Derivatives().Download(ctx, asset.EncodedVideoPath.String)
+		if err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).
+				Warn("Failed to read encoded video, serving the original")
+		}
 	}
-
-	videoStream, err := storageService.Download(ctx, videoPath)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "failed to retrieve video", err)
+	if videoStream == nil {
+		if asset.IsOffline {
+			return nil, assetOfflineError(ctx)
+		}
+		videoStream, err = storageService.Download(ctx, asset.OriginalPath)
+		if err != nil {
+			return nil, s.originalReadError(ctx, storageService, asset, asset.OriginalPath, err)
+		}
 	}
 	defer videoStream.Close()
 
@@ -933,6 +1623,16 @@ var videoContentTypes = map[string]string{
 	".m4v":  "video/x-m4v",
 }
 
+// assetContentType is the content type the original of asset is served
+// with: the MIME type recorded when it was stored, or one guessed from its
+// file name for assets stored before that.
+func assetContentType(asset sqlc.Asset) string {
+	if asset.OriginalMimeType.Valid && asset.OriginalMimeType.String != "" {
+		return asset.OriginalMimeType.String
+	}
+	return assetDownloadContentType(asset.OriginalFileName)
+}
+
 func assetDownloadContentType(filename string) string {
 	if contentType, ok := assetDownloadContentTypes[fileExtension(filename)]; ok {
 		return contentType
@@ -940,8 +1640,14 @@ func assetDownloadContentType(filename string) string {
 	return "application/octet-stream"
 }
 
+// assetOfflineError reports that the original of an external library asset
+// is gone from disk. The HTTP gateway renders it as 410 Gone.
+func assetOfflineError(ctx context.Context) error {
+	return grpcutil.WithReason(PublicError(ctx, codes.FailedPrecondition, "asset is offline"), reasonAssetOffline)
+}
+
 func (s *Server) getAuthenticatedAsset(ctx context.Context, assetID string) (sqlc.Asset, error) {
-	userID, err := s.userUUIDFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return sqlc.Asset{}, err
 	}
@@ -949,6 +1655,87 @@ func (s *Server) getAuthenticatedAsset(ctx context.Context, assetID string) (sql
 	return s.getAssetForUser(ctx, userID, assetID)
 }
 
+// getViewableAsset returns an asset the current user owns, that was shared
+// with them directly, or that a partner shares with them. Only endpoints
+// that read the asset use it, so a recipient cannot modify what was shared
+// with them. Assets the user cannot see are not found, so their existence
+// does not leak.
+func (s *Server) getViewableAsset(ctx context.Context, assetID string) (sqlc.Asset, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return sqlc.Asset{}, err
+	}
+
+	asset, err := s.getAssetForUser(ctx, userID, assetID)
+	if status.Code(err) != codes.NotFound {
+		return asset, err
+	}
+	assetUUID := pgtype.UUID{Bytes: uuid.MustParse(assetID), Valid: true}
+	shared, sharedErr := s.db.CheckAssetDirectlySharedWithUser(ctx, sqlc.CheckAssetDirectlySharedWithUserParams{
+		AssetId:      assetUUID,
+		SharedWithId: userID,
+	})
+	if sharedErr == nil && !shared {
+		shared, sharedErr = s.db.CheckAssetSharedWithPartner(ctx, sqlc.CheckAssetSharedWithPartnerParams{
+			ID:           assetUUID,
+			SharedWithId: userID,
+		})
+	}
+	if sharedErr != nil {
+		return sqlc.Asset{}, SanitizedInternal(ctx, "failed to check asset access", sharedErr)
+	}
+	if !shared {
+		return sqlc.Asset{}, err
+	}
+	asset, sharedErr = s.db.GetAsset(ctx, assetUUID)
+	if sharedErr != nil {
+		return sqlc.Asset{}, err
+	}
+	return asset, nil
+}
+
+// getViewableOrSharedAsset returns an asset the caller can view or, failing
+// that, one the shared link with key shares along with the link. Callers
+// that are not signed in can only get assets through the link.
+func (s *Server) getViewableOrSharedAsset(ctx context.Context, assetID, key string) (sqlc.Asset, *sharedlinks.SharedLink, error) {
+	asset, err := s.getViewableAsset(ctx, assetID)
+	if code := status.Code(err); key != "" && (code == codes.NotFound || code == codes.Unauthenticated) {
+		return s.getSharedLinkAsset(ctx, key, assetID)
+	}
+	return asset, nil, err
+}
+
+// getSharedLinkAsset returns an asset the shared link with key shares. Links
+// that expired or are protected by a password give no access, and neither
+// do links that do not share the asset: all are reported as not found.
+func (s *Server) getSharedLinkAsset(ctx context.Context, key, assetID string) (sqlc.Asset, *sharedlinks.SharedLink, error) {
+	notFound := status.Error(codes.NotFound, "asset not found")
+	parsedAssetID, err := uuid.Parse(assetID)
+	if err != nil {
+		return sqlc.Asset{}, nil, status.Errorf(codes.InvalidArgument, "invalid asset ID: %v", err)
+	}
+	link, err := s.sharedLinksService.GetSharedLinkByKey(ctx, key, "")
+	if err != nil {
+		return sqlc.Asset{}, nil, notFound
+	}
+	assetUUID := pgtype.UUID{Bytes: parsedAssetID, Valid: true}
+	shared, err := s.db.CheckAssetInSharedLink(ctx, sqlc.CheckAssetInSharedLinkParams{
+		AssetID: assetUUID,
+		LinkID:  pgtype.UUID{Bytes: link.ID, Valid: true},
+	})
+	if err != nil {
+		return sqlc.Asset{}, nil, SanitizedInternal(ctx, "failed to check asset access", err)
+	}
+	if !shared {
+		return sqlc.Asset{}, nil, notFound
+	}
+	asset, err := s.db.GetAsset(ctx, assetUUID)
+	if err != nil {
+		return sqlc.Asset{}, nil, notFound
+	}
+	return asset, link, nil
+}
+
 func (s *Server) getAssetForUser(ctx context.Context, userID pgtype.UUID, assetID string) (sqlc.Asset, error) {
 	parsedAssetID, err := uuid.Parse(assetID)
 	if err != nil {
@@ -962,6 +1749,11 @@ func (s *Server) getAssetForUser(ctx context.Context, userID pgtype.UUID, assetI
 	if err != nil {
 		return sqlc.Asset{}, status.Errorf(codes.NotFound, "asset not found: %v", err)
 	}
+	if asset.Visibility == sqlc.AssetVisibilityEnumLocked {
+		if err := s.requireUnlockedSession(ctx); err != nil {
+			return sqlc.Asset{}, err
+		}
+	}
 
 	return asset, nil
 }
@@ -984,21 +1776,77 @@ func fileExtension(filename string) string {
 }
 
 // Helper function to convert database asset to proto
+// exifToProto converts the EXIF row of an asset, leaving out the values it
+// does not have.
+func exifToProto(exif sqlc.Exif) *immichv1.ExifInfo {
+	info := &immichv1.ExifInfo{
+		FileSizeInByte: exif.FileSizeInByte.Int64,
+		Description:    &exif.Description,
+	}
+	optionalText := func(value pgtype.Text) *string {
+		if !value.Valid {
+			return nil
+		}
+		return &value.String
+	}
+	optionalFloat := func(value pgtype.Float8) *float64 {
+		if !value.Valid {
+			return nil
+		}
+		return &value.Float64
+	}
+	optionalInt := func(value pgtype.Int4) *int32 {
+		if !value.Valid {
+			return nil
+		}
+		return &value.Int32
+	}
+	optionalTime := func(value pgtype.Timestamptz) *timestamppb.Timestamp {
+		if !value.Valid {
+			return nil
+		}
+		return timestamppb.New(value.Time)
+	}
+
+	info.Make = optionalText(exif.Make)
+	info.Model = optionalText(exif.Model)
+	info.ExifImageWidth = optionalInt(exif.ExifImageWidth)
+	info.ExifImageHeight = optionalInt(exif.ExifImageHeight)
+	info.Orientation = optionalText(exif.Orientation)
+	info.DateTimeOriginal = optionalTime(exif.DateTimeOriginal)
+	info.ModifyDate = optionalTime(exif.ModifyDate)
+	info.TimeZone = optionalText(exif.TimeZone)
+	info.LensModel = optionalText(exif.LensModel)
+	info.FNumber = optionalFloat(exif.FNumber)
+	info.FocalLength = optionalFloat(exif.FocalLength)
+	info.Iso = optionalInt(exif.Iso)
+	info.ExposureTime = optionalText(exif.ExposureTime)
+	info.Latitude = optionalFloat(exif.Latitude)
+	info.Longitude = optionalFloat(exif.Longitude)
+	info.City = optionalText(exif.City)
+	info.State = optionalText(exif.State)
+	info.Country = optionalText(exif.Country)
+	return info
+}
+
 func (s *Server) convertAssetToProto(asset sqlc.Asset) *immichv1.Asset {
 	protoAsset := &immichv1.Asset{
-		Id:               asset.ID.String(),
-		DeviceAssetId:    asset.DeviceAssetId,
-		OwnerId:          asset.OwnerId.String(),
-		DeviceId:         asset.DeviceId,
-		Type:             assets.AssetTypeFromString(asset.Type),
-		OriginalPath:     asset.OriginalPath,
-		OriginalFileName: asset.OriginalFileName,
-		CreatedAt:        timestamppb.New(asset.CreatedAt.Time),
-		UpdatedAt:        timestamppb.New(asset.UpdatedAt.Time),
-		IsFavorite:       asset.IsFavorite,
-		IsArchived:       asset.Visibility == sqlc.AssetVisibilityEnumArchive,
-		IsTrashed:        asset.Status == sqlc.AssetsStatusEnumTrashed,
-		Checksum:         fmt.Sprintf("%x", asset.Checksum),
+		Id:                asset.ID.String(),
+		DeviceAssetId:     asset.DeviceAssetId,
+		OwnerId:           asset.OwnerId.String(),
+		DeviceId:          asset.DeviceId,
+		Type:              assets.AssetTypeFromString(asset.Type),
+		OriginalPath:      asset.OriginalPath,
+		OriginalFileName:  asset.OriginalFileName,
+		CreatedAt:         timestamppb.New(asset.CreatedAt.Time),
+		UpdatedAt:         timestamppb.New(asset.UpdatedAt.Time),
+		IsFavorite:        asset.IsFavorite,
+		IsArchived:        asset.Visibility == sqlc.AssetVisibilityEnumArchive,
+		IsTrashed:         asset.Status == sqlc.AssetsStatusEnumTrashed,
+		Checksum:          fmt.Sprintf("%x", asset.Checksum),
+		ChecksumAlgorithm: asset.ChecksumAlgorithm,
+		Visibility:        string(asset.Visibility),
+		IsOffline:         asset.IsOffline,
 	}
 
 	if asset.Duration.Valid {
@@ -1015,5 +1863,13 @@ func (s *Server) convertAssetToProto(asset sqlc.Asset) *immichv1.Asset {
 		protoAsset.StackParentId = &stackParentID
 	}
 
+	if len(asset.Thumbhash) > 0 {
+		thumbhash := base64.StdEncoding.EncodeToString(asset.Thumbhash)
+		protoAsset.Thumbhash = &thumbhash
+	}
+	if asset.Blurhash.Valid {
+		protoAsset.Blurhash = &asset.Blurhash.String
+	}
+
 	return protoAsset
 }
@@ -0,0 +1,190 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/sharedlinks"
+)
+
+// TestServer_AssetAccess checks who can view and download an asset: its
+// owner and the partners the owner shares with, but not other users, who
+// cannot tell it exists.
+func TestServer_AssetAccess(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	partner := createAssetViewerTestUser(t, ctx, env.tdb)
+	other := createAssetViewerTestUser(t, ctx, env.tdb)
+	asset := seedAsset(t, ctx, env, owner, "partner.jpg", "image/jpeg", testJPEG(t))
+	video := seedAsset(t, ctx, env, owner, "partner.mp4", "video/mp4", []byte("partner-video"))
+	assetID := uuid.UUID(asset.ID.Bytes).String()
+
+	getAsset := func(userID uuid.UUID) error {
+		_, err := env.srv.GetAsset(assetViewerContext(userID), &immichv1.GetAssetRequest{AssetId: assetID})
+		return err
+	}
+	downloadAsset := func(userID uuid.UUID) error {
+		_, err := env.srv.DownloadAsset(assetViewerContext(userID), &immichv1.DownloadAssetRequest{AssetId: assetID})
+		return err
+	}
+
+	require.NoError(t, getAsset(owner))
+	require.NoError(t, downloadAsset(owner))
+	assertAssetViewerNotFound(t, func() error { return getAsset(other) })
+	assertAssetViewerNotFound(t, func() error { return downloadAsset(other) })
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(other), &immichv1.GetAssetRequest{AssetId: uuid.New().String()})
+		return err
+	})
+
+	// A partner invite gives access once it is accepted
+	_, err := env.tdb.Queries.CreatePartnership(ctx, sqlc.CreatePartnershipParams{
+		SharedById:   mustUUID(t, owner),
+		SharedWithId: mustUUID(t, partner),
+	})
+	require.NoError(t, err)
+	assertAssetViewerNotFound(t, func() error { return getAsset(partner) })
+	_, err = env.tdb.Queries.AcceptPartnership(ctx, sqlc.AcceptPartnershipParams{
+		SharedById:   mustUUID(t, owner),
+		SharedWithId: mustUUID(t, partner),
+	})
+	require.NoError(t, err)
+	require.NoError(t, getAsset(partner))
+	download, err := env.srv.DownloadAsset(assetViewerContext(partner), &immichv1.DownloadAssetRequest{AssetId: assetID})
+	require.NoError(t, err)
+	assert.True(t, download.GetMetadataStripped(), "partners get originals without metadata")
+	assertAssetViewerNotFound(t, func() error { return getAsset(other) })
+
+	// Originals whose metadata cannot be removed are refused unless the
+	// partner asks for them as they are
+	videoID := uuid.UUID(video.ID.Bytes).String()
+	_, err = env.srv.DownloadAsset(assetViewerContext(partner), &immichv1.DownloadAssetRequest{AssetId: videoID})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	download, err = env.srv.DownloadAsset(assetViewerContext(partner), &immichv1.DownloadAssetRequest{AssetId: videoID, StripMetadata: proto.Bool(false)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("partner-video"), download.GetData())
+
+	// Partnerships go one way
+	partnerAsset := seedAsset(t, ctx, env, partner, "partner-own.jpg", "image/jpeg", []byte("own-bytes"))
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(owner), &immichv1.GetAssetRequest{AssetId: uuid.UUID(partnerAsset.ID.Bytes).String()})
+		return err
+	})
+
+	// Partners cannot modify what they see, nor see locked assets
+	favorite := true
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.UpdateAsset(assetViewerContext(partner), &immichv1.UpdateAssetRequest{AssetId: assetID, IsFavorite: &favorite})
+		return err
+	})
+	_, err = env.tdb.Queries.UpdateAsset(ctx, sqlc.UpdateAssetParams{
+		ID:         asset.ID,
+		Visibility: sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: sqlc.AssetVisibilityEnumLocked, Valid: true},
+	})
+	require.NoError(t, err)
+	assertAssetViewerNotFound(t, func() error { return getAsset(partner) })
+}
+
+// TestServer_AssetAccess_SharedLink checks a signed-in user holding the key
+// of a shared link can view the assets it shares, and download them only
+// when the link allows it.
+func TestServer_AssetAccess_SharedLink(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	env.srv.sharedLinksService = sharedlinks.NewService(env.tdb.Queries)
+	ctx := context.Background()
+
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	viewer := createAssetViewerTestUser(t, ctx, env.tdb)
+	shared := seedAsset(t, ctx, env, owner, "linked.jpg", "image/jpeg", testJPEG(t))
+	video := seedAsset(t, ctx, env, owner, "linked.mp4", "video/mp4", []byte("linked-video"))
+	unshared := seedAsset(t, ctx, env, owner, "private.jpg", "image/jpeg", []byte("private-bytes"))
+	sharedID := uuid.UUID(shared.ID.Bytes).String()
+	unsharedID := uuid.UUID(unshared.ID.Bytes).String()
+
+	viewOnly, err := env.srv.sharedLinksService.CreateSharedLink(ctx, owner, &sharedlinks.CreateSharedLinkRequest{
+		Type:     sharedlinks.SharedLinkTypeIndividual,
+		AssetIDs: []string{sharedID},
+	})
+	require.NoError(t, err)
+
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(viewer), &immichv1.GetAssetRequest{AssetId: sharedID})
+		return err
+	})
+	got, err := env.srv.GetAsset(assetViewerContext(viewer), &immichv1.GetAssetRequest{AssetId: sharedID, Key: proto.String(viewOnly.Key)})
+	require.NoError(t, err)
+	assert.Equal(t, sharedID, got.GetId())
+	assert.Empty(t, got.GetOriginalFileName(), "the link does not show metadata")
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(viewer), &immichv1.GetAssetRequest{AssetId: unsharedID, Key: proto.String(viewOnly.Key)})
+		return err
+	})
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(viewer), &immichv1.GetAssetRequest{AssetId: sharedID, Key: proto.String("not-a-key")})
+		return err
+	})
+
+	_, err = env.srv.DownloadAsset(assetViewerContext(viewer), &immichv1.DownloadAssetRequest{AssetId: sharedID, Key: proto.String(viewOnly.Key)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	downloadable, err := env.srv.sharedLinksService.CreateSharedLink(ctx, owner, &sharedlinks.CreateSharedLinkRequest{
+		Type:          sharedlinks.SharedLinkTypeIndividual,
+		AssetIDs:      []string{sharedID},
+		AllowDownload: true,
+		ShowExif:      true,
+	})
+	require.NoError(t, err)
+	download, err := env.srv.DownloadAsset(assetViewerContext(viewer), &immichv1.DownloadAssetRequest{AssetId: sharedID, Key: proto.String(downloadable.Key)})
+	require.NoError(t, err)
+	assert.True(t, download.GetMetadataStripped())
+
+	// A link hiding metadata never serves it, even when asked to
+	hidden, err := env.srv.sharedLinksService.CreateSharedLink(ctx, owner, &sharedlinks.CreateSharedLinkRequest{
+		Type:          sharedlinks.SharedLinkTypeIndividual,
+		AssetIDs:      []string{uuid.UUID(video.ID.Bytes).String()},
+		AllowDownload: true,
+	})
+	require.NoError(t, err)
+	_, err = env.srv.DownloadAsset(assetViewerContext(viewer), &immichv1.DownloadAssetRequest{
+		AssetId:       uuid.UUID(video.ID.Bytes).String(),
+		Key:           proto.String(hidden.Key),
+		StripMetadata: proto.Bool(false),
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	protected, err := env.srv.sharedLinksService.CreateSharedLink(ctx, owner, &sharedlinks.CreateSharedLinkRequest{
+		Type:     sharedlinks.SharedLinkTypeIndividual,
+		AssetIDs: []string{sharedID},
+		Password: "secret",
+	})
+	require.NoError(t, err)
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(viewer), &immichv1.GetAssetRequest{AssetId: sharedID, Key: proto.String(protected.Key)})
+		return err
+	})
+}
+
+// testJPEG returns a small encoded JPEG, whose metadata can be removed.
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil))
+	return encoded.Bytes()
+}
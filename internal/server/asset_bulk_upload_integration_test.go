@@ -13,6 +13,8 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/db"
@@ -105,3 +107,49 @@ func TestCheckBulkUploadRequiresAuthentication(t *testing.T) {
 	require.Error(t, err)
 	assert.Nil(t, resp)
 }
+
+func TestGetAssetsByChecksumsReturnsExistingSubset(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	ownerID := tdb.CreateTestUser(t, "checksums-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "checksums-other@example.com")
+
+	hexActive := strings.Repeat("a1", 20)
+	hexForeign := strings.Repeat("c3", 20)
+	hexNew := strings.Repeat("d4", 20)
+
+	activeAsset := tdb.CreateTestAssetWithChecksum(t, ownerID, "checksums-active", []byte(hexActive))
+	tdb.CreateTestAssetWithChecksum(t, otherID, "checksums-foreign", []byte(hexForeign))
+
+	rawActive, err := hex.DecodeString(hexActive)
+	require.NoError(t, err)
+	base64Active := base64.StdEncoding.EncodeToString(rawActive)
+
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+	resp, err := srv.GetAssetsByChecksums(userCtx, &immichv1.GetAssetsByChecksumsRequest{
+		Checksums: []string{hexActive, hexForeign, hexNew, base64Active},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Assets, 2)
+	assert.Equal(t, hexActive, resp.Assets[0].Checksum)
+	assert.Equal(t, activeAsset.String(), resp.Assets[0].AssetId)
+	assert.Equal(t, base64Active, resp.Assets[1].Checksum)
+	assert.Equal(t, activeAsset.String(), resp.Assets[1].AssetId)
+
+	_, err = srv.GetAssetsByChecksums(userCtx, &immichv1.GetAssetsByChecksumsRequest{
+		Checksums: []string{hexActive, "not-a-checksum"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = srv.GetAssetsByChecksums(userCtx, &immichv1.GetAssetsByChecksumsRequest{
+		Checksums: make([]string, maxChecksumLookup+1),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,6 +37,13 @@ func TestAssetDownloadContentType(t *testing.T) {
 	}
 }
 
+func TestAssetContentType(t *testing.T) {
+	asset := sqlc.Asset{OriginalFileName: "IMG_0001.HEIC"}
+	assert.Equal(t, "application/octet-stream", assetContentType(asset), "guessed from the name when nothing was recorded")
+	asset.OriginalMimeType = pgtype.Text{String: "image/heic", Valid: true}
+	assert.Equal(t, "image/heic", assetContentType(asset))
+}
+
 func TestGetThumbnailContentType(t *testing.T) {
 	tests := []struct {
 		name          string
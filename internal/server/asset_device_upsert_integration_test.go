@@ -0,0 +1,73 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestRepeatedDeviceBackupUpdatesAsset backs up the same device asset three
+// times, twice with the same file, and checks a single asset ends up with
+// the last file.
+func TestRepeatedDeviceBackupUpdatesAsset(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	env := newAssetViewerTestEnv(t)
+	env.srv.config = &config.Config{}
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+
+	backup := func(content string) *immichv1.Asset {
+		checksum := assets.SumChecksum([]byte(content), assets.DefaultChecksumAlgorithm).Hex()
+		uploaded, err := env.srv.uploadAsset(assetViewerContext(userID), &immichv1.UploadAssetRequest{
+			AssetData: &immichv1.CreateAssetRequest{
+				DeviceAssetId:    "IMG_0001",
+				DeviceId:         "phone",
+				Type:             immichv1.AssetType_ASSET_TYPE_IMAGE,
+				OriginalFileName: "IMG_0001.jpg",
+			},
+			Checksum:    &checksum,
+			FileContent: []byte(content),
+		})
+		require.NoError(t, err)
+		return uploaded
+	}
+
+	first := backup("first")
+	assert.Equal(t, first.Id, backup("first").Id, "the same file is not stored again")
+
+	var assetID pgtype.UUID
+	require.NoError(t, assetID.Scan(first.Id))
+	before, err := env.tdb.Queries.GetAsset(ctx, assetID)
+	require.NoError(t, err)
+
+	second := backup("second")
+	assert.Equal(t, first.Id, second.Id)
+
+	after, err := env.tdb.Queries.GetAsset(ctx, assetID)
+	require.NoError(t, err)
+	assert.Equal(t, assets.SumChecksum([]byte("second"), assets.DefaultChecksumAlgorithm).Stored(), after.Checksum)
+	store := env.srv.assetService.GetStorageService()
+	assert.Equal(t, []byte("second"), readStoredFile(t, store, after.OriginalPath))
+	if after.OriginalPath != before.OriginalPath {
+		exists, err := store.Exists(ctx, before.OriginalPath)
+		require.NoError(t, err)
+		assert.False(t, exists, "the replaced original is deleted")
+	}
+
+	var count int
+	require.NoError(t, env.tdb.Pool.QueryRow(ctx,
+		`SELECT count(*) FROM assets WHERE "ownerId" = $1 AND "deviceId" = 'phone' AND "deviceAssetId" = 'IMG_0001'`, userID,
+	).Scan(&count))
+	assert.Equal(t, 1, count)
+}
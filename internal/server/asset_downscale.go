@@ -0,0 +1,36 @@
+package server
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+)
+
+// downscaleUpload scales an uploaded image down to the configured size
+// limit. It returns nil when the image is stored as uploaded: there is no
+// limit, the image fits it, or it cannot be downscaled. Downscaling loses
+// detail, so every time it happens, or fails to, is logged.
+func (s *Server) downscaleUpload(fileName string, content []byte) *assets.DownscaledImage {
+	upload := s.config.Storage.Upload
+	limit := assets.ImageSizeLimit{
+		MaxMegapixels: upload.MaxImageMegapixels,
+		MaxDimension:  upload.MaxImageDimension,
+	}
+	downscaled, err := assets.DownscaleImage(content, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("file", fileName).
+			Warn("Upload is over the image size limit but could not be downscaled, storing it as uploaded")
+		return nil
+	}
+	if downscaled != nil {
+		logrus.WithFields(logrus.Fields{
+			"file":            fileName,
+			"original_width":  downscaled.OriginalWidth,
+			"original_height": downscaled.OriginalHeight,
+			"width":           downscaled.Width,
+			"height":          downscaled.Height,
+			"original_kept":   upload.KeepDownscaledOriginal,
+		}).Info("Downscaled upload over the image size limit")
+	}
+	return downscaled
+}
@@ -0,0 +1,101 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+// TestUploadDownscalesOversizedImages uploads an image over the size limit
+// with and without keeping the uploaded file, and checks what is stored and
+// recorded.
+func TestUploadDownscalesOversizedImages(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	for _, keep := range []bool{true, false} {
+		env := newAssetViewerTestEnv(t)
+		ctx := context.Background()
+		env.srv.config = &config.Config{Storage: storage.StorageConfig{Upload: storage.UploadConfig{
+			MaxImageDimension:      100,
+			KeepDownscaledOriginal: keep,
+		}}}
+
+		userID := createAssetViewerTestUser(t, ctx, env.tdb)
+		img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+		for x := 0; x < 400; x++ {
+			img.Set(x, 100, color.RGBA{R: 255, A: 255})
+		}
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+		content := buf.Bytes()
+		checksum := assets.SumChecksum(content, assets.DefaultChecksumAlgorithm).Hex()
+
+		uploaded, err := env.srv.uploadAsset(assetViewerContext(userID), &immichv1.UploadAssetRequest{
+			AssetData: &immichv1.CreateAssetRequest{
+				DeviceAssetId:    "panorama",
+				DeviceId:         "test-device",
+				Type:             immichv1.AssetType_ASSET_TYPE_IMAGE,
+				OriginalFileName: "panorama.jpg",
+			},
+			Checksum:    &checksum,
+			FileContent: content,
+		})
+		require.NoError(t, err)
+
+		var assetID pgtype.UUID
+		require.NoError(t, assetID.Scan(uploaded.Id))
+		asset, err := env.tdb.Queries.GetAsset(ctx, assetID)
+		require.NoError(t, err)
+		store := env.srv.assetService.GetStorageService()
+		stored := readStoredFile(t, store, asset.OriginalPath)
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(stored))
+		require.NoError(t, err)
+		assert.Equal(t, 100, cfg.Width)
+		assert.Equal(t, 50, cfg.Height)
+
+		var originalWidth, width int32
+		var originalKept bool
+		require.NoError(t, env.tdb.Pool.QueryRow(ctx,
+			`SELECT "originalWidth", width, "originalKept" FROM asset_downscales WHERE "assetId" = $1`, assetID,
+		).Scan(&originalWidth, &width, &originalKept))
+		assert.EqualValues(t, 400, originalWidth)
+		assert.EqualValues(t, 100, width)
+		assert.Equal(t, keep, originalKept)
+
+		backup, err := env.tdb.Queries.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: assetID, Type: "original_backup"})
+		if keep {
+			require.NoError(t, err)
+			assert.Equal(t, content, readStoredFile(t, store, backup.Path), "the uploaded file is kept")
+		} else {
+			assert.Error(t, err, "nothing is kept")
+		}
+	}
+}
+
+func readStoredFile(t *testing.T, store *storage.Service, path string) []byte {
+	t.Helper()
+	reader, err := store.Download(context.Background(), path)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+)
+
+// assetUpdateAction is the sync event action of assets whose favorite flag,
+// visibility, date or location changed.
+const assetUpdateAction = "update"
+
+// assetsUpdated tells the owner's open sessions about updated assets with a
+// single event, however many there are, so favoriting a whole selection
+// does not flood them. When the visibility changed, the cached aggregates
+// that leave archived or locked assets out are dropped: the owner's Explore
+// page and the statistics of the albums holding the assets.
+func (s *Server) assetsUpdated(ctx context.Context, ownerID pgtype.UUID, assetIDs []pgtype.UUID, visibilityChanged bool) {
+	if len(assetIDs) == 0 {
+		return
+	}
+	if s.syncService != nil {
+		ids := make([]string, len(assetIDs))
+		for i, assetID := range assetIDs {
+			ids[i] = assetID.String()
+		}
+		s.syncService.BroadcastAssetsEvent(ownerID.String(), ids, assetUpdateAction)
+	}
+
+	if !visibilityChanged {
+		return
+	}
+	if s.searchService != nil {
+		s.searchService.InvalidateExplore(uuid.UUID(ownerID.Bytes))
+	}
+	albumIDs, err := s.db.GetAlbumIdsByAssetIds(ctx, assetIDs)
+	if err != nil {
+		// The statistics expire on their own shortly
+		logrus.WithError(err).Warn("Failed to find the albums of updated assets")
+		return
+	}
+	s.albumStatistics.invalidate(albumIDs...)
+}
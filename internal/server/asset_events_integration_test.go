@@ -0,0 +1,68 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/sync"
+)
+
+// TestUpdateAssetsSendsOneEvent favorites and archives several assets at
+// once and checks the owner's sessions get a single event for all of them,
+// and the statistics of their album are computed again.
+func TestUpdateAssetsSendsOneEvent(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "asset-events-owner@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	albumAsset := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "in-album"), Valid: true}
+	assetIDs := []string{
+		albumAsset.String(),
+		pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "second"), Valid: true}.String(),
+		pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "third"), Valid: true}.String(),
+	}
+	album, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Trip"})
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: albumAsset}))
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn, syncService: sync.NewService(nil, nil)}
+	srv.albumStatistics.put(album.ID, &immichv1.AlbumAssetStatisticsResponse{AssetCount: 1})
+	events := srv.syncService.SubscribeToEvents(ownerID.String())
+	defer srv.syncService.UnsubscribeFromEvents(ownerID.String(), events)
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "asset-events-owner@example.com"})
+
+	isFavorite := true
+	_, err = srv.UpdateAssets(userCtx, &immichv1.UpdateAssetsRequest{AssetIds: assetIDs, IsFavorite: &isFavorite})
+	require.NoError(t, err)
+	event := <-events
+	assert.Equal(t, "update", event.Action)
+	assert.ElementsMatch(t, assetIDs, event.ResourceIDs)
+	assert.Empty(t, events, "one event for the whole selection")
+	_, cached := srv.albumStatistics.get(album.ID)
+	assert.True(t, cached, "favorites do not change album statistics")
+
+	isArchived := true
+	_, err = srv.UpdateAssets(userCtx, &immichv1.UpdateAssetsRequest{AssetIds: assetIDs, IsArchived: &isArchived})
+	require.NoError(t, err)
+	event = <-events
+	assert.ElementsMatch(t, assetIDs, event.ResourceIDs)
+	_, cached = srv.albumStatistics.get(album.ID)
+	assert.False(t, cached, "archiving invalidates the statistics of the album")
+}
@@ -0,0 +1,57 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestGetAsset_ExifInfo checks that an asset comes with its EXIF metadata,
+// and with empty metadata when none was extracted.
+func TestGetAsset_ExifInfo(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	withExif := seedAsset(t, ctx, env, userID, "exif.jpg", "image/jpeg", []byte("photo"))
+	_, err := env.tdb.Queries.CreateExif(ctx, sqlc.CreateExifParams{
+		AssetId:         withExif.ID,
+		Make:            pgtype.Text{String: "Canon", Valid: true},
+		Model:           pgtype.Text{String: "EOS R6", Valid: true},
+		ExifImageWidth:  pgtype.Int4{Int32: 6000, Valid: true},
+		ExifImageHeight: pgtype.Int4{Int32: 4000, Valid: true},
+		FileSizeInByte:  pgtype.Int8{Int64: 5, Valid: true},
+		Latitude:        pgtype.Float8{Float64: 41.9, Valid: true},
+		Longitude:       pgtype.Float8{Float64: 12.5, Valid: true},
+		City:            pgtype.Text{String: "Rome", Valid: true},
+		Description:     "Colosseum",
+	})
+	require.NoError(t, err)
+	withoutExif := seedAsset(t, ctx, env, userID, "plain.jpg", "image/jpeg", []byte("photo"))
+
+	asset, err := env.srv.GetAsset(userCtx, &immichv1.GetAssetRequest{AssetId: withExif.ID.String()})
+	require.NoError(t, err)
+	require.NotNil(t, asset.ExifInfo)
+	assert.Equal(t, "Canon", asset.ExifInfo.GetMake())
+	assert.Equal(t, int32(6000), asset.ExifInfo.GetExifImageWidth())
+	assert.Equal(t, int64(5), asset.ExifInfo.GetFileSizeInByte())
+	assert.Equal(t, "Rome", asset.ExifInfo.GetCity())
+	assert.Equal(t, "Colosseum", asset.ExifInfo.GetDescription())
+	assert.Nil(t, asset.ExifInfo.LensModel)
+
+	asset, err = env.srv.GetAsset(userCtx, &immichv1.GetAssetRequest{AssetId: withoutExif.ID.String()})
+	require.NoError(t, err, "an asset without metadata is no error")
+	require.NotNil(t, asset.ExifInfo)
+	assert.Nil(t, asset.ExifInfo.Make)
+	assert.Nil(t, asset.ExifInfo.ExifImageWidth)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestExifToProto(t *testing.T) {
+	taken := time.Date(2023, 6, 1, 10, 30, 0, 0, time.UTC)
+	info := exifToProto(sqlc.Exif{
+		Make:             pgtype.Text{String: "Canon", Valid: true},
+		Model:            pgtype.Text{String: "EOS R6", Valid: true},
+		LensModel:        pgtype.Text{String: "RF 24-105mm", Valid: true},
+		ExifImageWidth:   pgtype.Int4{Int32: 6000, Valid: true},
+		ExifImageHeight:  pgtype.Int4{Int32: 4000, Valid: true},
+		FileSizeInByte:   pgtype.Int8{Int64: 1 << 20, Valid: true},
+		DateTimeOriginal: pgtype.Timestamptz{Time: taken, Valid: true},
+		FNumber:          pgtype.Float8{Float64: 4, Valid: true},
+		Iso:              pgtype.Int4{Int32: 200, Valid: true},
+		ExposureTime:     pgtype.Text{String: "1/250", Valid: true},
+		Latitude:         pgtype.Float8{Float64: 41.9, Valid: true},
+		Longitude:        pgtype.Float8{Float64: 12.5, Valid: true},
+		City:             pgtype.Text{String: "Rome", Valid: true},
+		Country:          pgtype.Text{String: "Italy", Valid: true},
+		Description:      "Colosseum",
+	})
+
+	assert.Equal(t, "Canon", info.GetMake())
+	assert.Equal(t, "EOS R6", info.GetModel())
+	assert.Equal(t, "RF 24-105mm", info.GetLensModel())
+	assert.Equal(t, int32(6000), info.GetExifImageWidth())
+	assert.Equal(t, int32(4000), info.GetExifImageHeight())
+	assert.Equal(t, int64(1<<20), info.GetFileSizeInByte())
+	assert.True(t, info.GetDateTimeOriginal().AsTime().Equal(taken))
+	assert.Equal(t, 4.0, info.GetFNumber())
+	assert.Equal(t, int32(200), info.GetIso())
+	assert.Equal(t, "1/250", info.GetExposureTime())
+	assert.Equal(t, 41.9, info.GetLatitude())
+	assert.Equal(t, "Rome", info.GetCity())
+	assert.Equal(t, "Italy", info.GetCountry())
+	assert.Equal(t, "Colosseum", info.GetDescription())
+
+	// Values the file did not have stay unset
+	assert.Nil(t, info.FocalLength)
+	assert.Nil(t, info.State)
+	assert.Nil(t, info.ModifyDate)
+}
@@ -0,0 +1,102 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestLockedAssetsAreHidden moves one of two assets into the locked folder
+// and checks it disappears from the listings and from a shared album, while
+// remaining reachable through the locked folder listing.
+func TestLockedAssetsAreHidden(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	ownerID := tdb.CreateTestUser(t, "locked-owner@example.com")
+	viewerID := tdb.CreateTestUser(t, "locked-viewer@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	viewer := pgtype.UUID{Bytes: viewerID, Valid: true}
+	visible := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "visible"), Valid: true}
+	locked := pgtype.UUID{Bytes: tdb.CreateTestAsset(t, ownerID, "locked"), Valid: true}
+
+	before := time.Now().Add(-time.Minute)
+	_, err := tdb.Queries.UpdateAsset(ctx, sqlc.UpdateAssetParams{
+		ID:         locked,
+		Visibility: sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: sqlc.AssetVisibilityEnumLocked, Valid: true},
+	})
+	require.NoError(t, err)
+
+	album, err := tdb.Queries.CreateAlbum(ctx, sqlc.CreateAlbumParams{OwnerId: owner, AlbumName: "Shared"})
+	require.NoError(t, err)
+	for _, id := range []pgtype.UUID{visible, locked} {
+		require.NoError(t, tdb.Queries.AddAssetToAlbum(ctx, sqlc.AddAssetToAlbumParams{AlbumsId: album.ID, AssetsId: id}))
+	}
+	require.NoError(t, tdb.Queries.AddUserToAlbum(ctx, sqlc.AddUserToAlbumParams{AlbumsId: album.ID, UsersId: viewer, Role: "viewer"}))
+
+	userAssets, err := tdb.Queries.GetUserAssets(ctx, sqlc.GetUserAssetsParams{OwnerId: owner})
+	require.NoError(t, err)
+	assert.Equal(t, []pgtype.UUID{visible}, lockedTestAssetIDs(userAssets))
+
+	count, err := tdb.Queries.CountAssets(ctx, sqlc.CountAssetsParams{OwnerId: owner})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	albumAssets, err := tdb.Queries.GetAlbumAssets(ctx, album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []pgtype.UUID{visible}, lockedTestAssetIDs(albumAssets))
+
+	shared, err := tdb.Queries.CheckAssetSharedWithUser(ctx, sqlc.CheckAssetSharedWithUserParams{AssetsId: visible, UsersId: viewer})
+	require.NoError(t, err)
+	assert.True(t, shared)
+	shared, err = tdb.Queries.CheckAssetSharedWithUser(ctx, sqlc.CheckAssetSharedWithUserParams{AssetsId: locked, UsersId: viewer})
+	require.NoError(t, err)
+	assert.False(t, shared, "locked assets are not shared through albums")
+
+	lockedAssets, err := tdb.Queries.GetLockedAssets(ctx, sqlc.GetLockedAssetsParams{OwnerId: owner, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []pgtype.UUID{locked}, lockedTestAssetIDs(lockedAssets))
+
+	deleted, err := tdb.Queries.GetDeletedAssetIDsForSync(ctx, sqlc.GetDeletedAssetIDsForSyncParams{
+		OwnerID:      owner,
+		UpdatedAfter: pgtype.Timestamptz{Time: before, Valid: true},
+		Limit:        10,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, deleted, locked, "synced devices drop assets moved to the locked folder")
+
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	srv := &Server{db: conn}
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String(), Email: "locked-owner@example.com"})
+
+	_, err = srv.GetAsset(userCtx, &immichv1.GetAssetRequest{AssetId: locked.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "locked assets need an unlocked session")
+	_, err = srv.GetAsset(userCtx, &immichv1.GetAssetRequest{AssetId: visible.String()})
+	require.NoError(t, err)
+}
+
+func lockedTestAssetIDs(assets []sqlc.Asset) []pgtype.UUID {
+	ids := make([]pgtype.UUID, 0, len(assets))
+	for _, asset := range assets {
+		ids = append(ids, asset.ID)
+	}
+	return ids
+}
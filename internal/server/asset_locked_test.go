@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestRequestedVisibility(t *testing.T) {
+	locked := "locked"
+	archived := true
+	unarchived := false
+
+	got, err := requestedVisibility(&locked, &unarchived)
+	require.NoError(t, err)
+	assert.Equal(t, sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: sqlc.AssetVisibilityEnumLocked, Valid: true}, got, "visibility takes precedence over isArchived")
+
+	got, err = requestedVisibility(nil, &archived)
+	require.NoError(t, err)
+	assert.Equal(t, sqlc.AssetVisibilityEnumArchive, got.AssetVisibilityEnum)
+
+	got, err = requestedVisibility(nil, &unarchived)
+	require.NoError(t, err)
+	assert.Equal(t, sqlc.AssetVisibilityEnumTimeline, got.AssetVisibilityEnum)
+
+	got, err = requestedVisibility(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, got.Valid, "nothing requested leaves the visibility unchanged")
+
+	hidden := "hidden"
+	_, err = requestedVisibility(&hidden, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "hidden is reserved for the server")
+}
+
+func TestRequireUnlockedSessionWithoutSession(t *testing.T) {
+	err := (&Server{}).requireUnlockedSession(context.Background())
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -19,14 +19,10 @@ import (
 // ownedAssetUUID parses the asset ID and verifies the asset belongs to the
 // authenticated user, returning both UUIDs.
 func (s *Server) ownedAssetUUID(ctx context.Context, assetID string) (pgtype.UUID, pgtype.UUID, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return pgtype.UUID{}, pgtype.UUID{}, err
 	}
-	userUUID, err := pgutil.StringToUUID(claims.UserID)
-	if err != nil {
-		return pgtype.UUID{}, pgtype.UUID{}, status.Errorf(codes.InvalidArgument, "invalid user ID: %v", err)
-	}
 	assetUUID, err := pgutil.StringToUUID(assetID)
 	if err != nil {
 		return pgtype.UUID{}, pgtype.UUID{}, status.Errorf(codes.InvalidArgument, "invalid asset ID: %v", err)
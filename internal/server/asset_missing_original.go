@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/integrity"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+// cachedThumbnailTypes are the stored thumbnails that can stand in for one
+// another when the original is unreadable, largest first.
+var cachedThumbnailTypes = []assets.ThumbnailType{
+	assets.ThumbnailTypePreview,
+	assets.ThumbnailTypeWebp,
+	assets.ThumbnailTypeThumb,
+}
+
+// placeholderSize is the edge of the placeholder image; clients scale it.
+const placeholderSize = 64
+
+// mediaPlaceholder is a plain grey JPEG served in place of a thumbnail that
+// cannot be read or generated.
+var mediaPlaceholder = sync.OnceValue(func() []byte {
+	img := image.NewGray(image.Rect(0, 0, placeholderSize, placeholderSize))
+	for i := range img.Pix {
+		img.Pix[i] = 0xd4
+	}
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+})
+
+// placeholderReasons are the errors of media requests answered with the
+// placeholder image rather than a JSON body, when enabled.
+var placeholderReasons = []string{reasonAssetOffline, reasonOriginalMissing, reasonStorageUnavailable}
+
+// cachedThumbnail returns a stored thumbnail of asset, preferring
+// thumbnailType, for when the original cannot be read to generate it.
+func (s *Server) cachedThumbnail(ctx context.Context, asset sqlc.Asset, thumbnailType assets.ThumbnailType) ([]byte, bool) {
+	files, err := s.db.GetAssetFiles(ctx, asset.ID)
+	if err != nil {
+		return nil, false
+	}
+	types := append([]assets.ThumbnailType{thumbnailType}, cachedThumbnailTypes...)
+	derivatives := s.assetService.GetStorageService().Derivatives()
+	for _, fileType := range types {
+		for _, file := range files {
+			if file.Type != string(fileType) {
+				continue
+			}
+			if data, err := downloadAll(ctx, derivatives, file.Path); err == nil && len(data) > 0 {
+				return data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// originalReadError describes a failure to read the original of asset at
+// path without leaking the storage error: NotFound when the file is gone,
+// Unavailable when storage fails. A missing original is recorded for the
+// integrity report, and an external library asset is marked offline.
+func (s *Server) originalReadError(ctx context.Context, store *storage.Service, asset sqlc.Asset, path string, err error) error {
+	missing := false
+	if !storage.IsRetryable(err) {
+		exists, existsErr := store.Exists(ctx, path)
+		missing = existsErr == nil && !exists
+	}
+	log := logrus.WithFields(logrus.Fields{
+		"asset_id": asset.ID.String(),
+		"path":     path,
+	})
+
+	if !missing {
+		log.WithError(err).Error("Failed to read original of asset")
+		return grpcutil.WithReason(PublicError(ctx, codes.Unavailable, "storage is unavailable"), reasonStorageUnavailable)
+	}
+
+	log.Warn("Original of asset is missing from storage")
+	if path == asset.OriginalPath {
+		s.recordMissingOriginal(ctx, asset)
+	}
+	return grpcutil.WithReason(PublicError(ctx, codes.NotFound, "original file is missing"), reasonOriginalMissing)
+}
+
+// recordMissingOriginal adds a missing original to the integrity report
+// until the next scan replaces it, and marks an external library asset
+// offline until a library scan finds its file again.
+func (s *Server) recordMissingOriginal(ctx context.Context, asset sqlc.Asset) {
+	if err := s.db.CreateIntegrityReportItem(ctx, sqlc.CreateIntegrityReportItemParams{
+		ID:      asset.ID,
+		Type:    integrity.TypeMissingFile,
+		Path:    asset.OriginalPath,
+		AssetId: asset.ID,
+	}); err != nil {
+		logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("Failed to report missing original")
+	}
+	if asset.LibraryId.Valid && !asset.IsOffline {
+		if _, err := s.db.SetAssetOffline(ctx, asset.ID); err != nil {
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("Failed to mark asset offline")
+		}
+	}
+}
+
+// writeMediaPlaceholder answers a thumbnail request that failed with one of
+// the placeholderReasons with the placeholder image and the status of err,
+// and reports whether it did.
+func (s *Server) writeMediaPlaceholder(w http.ResponseWriter, err error) bool {
+	if s.config == nil || !s.config.Storage.MissingOriginalPlaceholder {
+		return false
+	}
+	st, ok := status.FromError(err)
+	if !ok || !slices.Contains(placeholderReasons, grpcutil.Reason(st)) {
+		return false
+	}
+
+	statusCode, _ := immichErrorResponse(err)
+	w.Header().Set("Content-Type", "image/jpeg")
+	// The placeholder must not replace the thumbnail in client caches once
+	// the original is back.
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(mediaPlaceholder())
+	return true
+}
@@ -0,0 +1,63 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/integrity"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestMissingOriginalFallsBackToStoredThumbnails removes the original of an
+// asset and checks a stored preview is served for its thumbnail, then that
+// thumbnail and download fail with NotFound once the preview is gone too.
+func TestMissingOriginalFallsBackToStoredThumbnails(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+	store := env.srv.assetService.GetStorageService()
+
+	asset := seedAsset(t, ctx, env, userID, "lost.jpg", "image/jpeg", []byte("original"))
+	previewPath := "users/" + userID.String() + "/cache/lost_preview.jpeg"
+	require.NoError(t, store.UploadBytes(ctx, previewPath, []byte("stored preview"), "image/jpeg"))
+	_, err := env.tdb.Queries.CreateAssetFile(ctx, sqlc.CreateAssetFileParams{AssetId: asset.ID, Type: "preview", Path: previewPath})
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(ctx, asset.OriginalPath))
+
+	thumbnail, err := env.srv.GetAssetThumbnail(userCtx, &immichv1.GetAssetThumbnailRequest{AssetId: asset.ID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("stored preview"), thumbnail.GetData())
+	assert.Equal(t, "image/jpeg", thumbnail.GetContentType())
+
+	require.NoError(t, store.Delete(ctx, previewPath))
+	_, err = env.srv.GetAssetThumbnail(userCtx, &immichv1.GetAssetThumbnailRequest{AssetId: asset.ID.String()})
+	assertOriginalMissing(t, err)
+	_, err = env.srv.DownloadAsset(userCtx, &immichv1.DownloadAssetRequest{AssetId: asset.ID.String()})
+	assertOriginalMissing(t, err)
+
+	report, err := env.tdb.Queries.ListIntegrityReport(ctx)
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, integrity.TypeMissingFile, report[0].Type)
+	assert.Equal(t, asset.OriginalPath, report[0].Path)
+}
+
+func assertOriginalMissing(t *testing.T, err error) {
+	t.Helper()
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, reasonOriginalMissing, grpcutil.Reason(st))
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+func TestWriteMediaPlaceholder(t *testing.T) {
+	srv := &Server{config: &config.Config{Storage: storage.StorageConfig{MissingOriginalPlaceholder: true}}}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"missing original", grpcutil.WithReason(status.Error(codes.NotFound, "original file is missing"), reasonOriginalMissing), http.StatusNotFound},
+		{"storage unavailable", grpcutil.WithReason(status.Error(codes.Unavailable, "storage is unavailable"), reasonStorageUnavailable), http.StatusServiceUnavailable},
+		{"offline", grpcutil.WithReason(status.Error(codes.FailedPrecondition, "asset is offline"), reasonAssetOffline), http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			require.True(t, srv.writeMediaPlaceholder(rec, tt.err))
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+			_, err := jpeg.Decode(bytes.NewReader(rec.Body.Bytes()))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("other errors", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		assert.False(t, srv.writeMediaPlaceholder(rec, status.Error(codes.NotFound, "asset not found")))
+		assert.Zero(t, rec.Body.Len())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := &Server{config: &config.Config{}}
+		rec := httptest.NewRecorder()
+		assert.False(t, disabled.writeMediaPlaceholder(rec, tests[0].err))
+	})
+}
@@ -0,0 +1,138 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/util"
+)
+
+func TestGetAssetsFieldMask(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	userID := createRecentlyAddedTestUser(t, ctx, tdb, "field-mask")
+	seeded := seedFiveAssets(t, ctx, tdb, userID)
+
+	resp, err := srv.GetAssets(ctxWithClaims(t, userID), &immichv1.GetAssetsRequest{
+		Page:   1,
+		Size:   10,
+		Fields: &fieldmaskpb.FieldMask{Paths: []string{"createdAt,checksum"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Assets, len(seeded))
+	for _, asset := range resp.Assets {
+		assert.NotEmpty(t, asset.Id, "the id is always returned")
+		assert.NotNil(t, asset.CreatedAt)
+		assert.NotEmpty(t, asset.Checksum)
+		assert.Empty(t, asset.OriginalPath)
+		assert.Empty(t, asset.OriginalFileName)
+		assert.Empty(t, asset.OwnerId)
+	}
+
+	_, err = srv.GetAssets(ctxWithClaims(t, userID), &immichv1.GetAssetsRequest{
+		Page:   1,
+		Size:   10,
+		Fields: &fieldmaskpb.FieldMask{Paths: []string{"no_such_field"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGetAssetIds(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	userID := createRecentlyAddedTestUser(t, ctx, tdb, "asset-ids")
+	otherID := createRecentlyAddedTestUser(t, ctx, tdb, "asset-ids-other")
+	seeded := seedFiveAssets(t, ctx, tdb, userID)
+	seedFiveAssets(t, ctx, tdb, otherID)
+
+	resp, err := srv.GetAssetIds(ctxWithClaims(t, userID), &immichv1.GetAssetIdsRequest{Page: 1, Size: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(seeded)), resp.PageInfo.Total)
+	require.Len(t, resp.Assets, 2)
+
+	newest := seeded[len(seeded)-1]
+	assert.Equal(t, uuid.UUID(newest.ID.Bytes).String(), resp.Assets[0].Id)
+	assert.Equal(t, fmt.Sprintf("%x", newest.Checksum), resp.Assets[0].Checksum)
+	assert.True(t, newest.LocalDateTime.Time.Equal(resp.Assets[0].LocalDateTime.AsTime()))
+	assert.True(t, newest.FileCreatedAt.Time.Equal(resp.Assets[0].FileCreatedAt.AsTime()))
+
+	resp, err = srv.GetAssetIds(ctxWithClaims(t, userID), &immichv1.GetAssetIdsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(defaultAssetIdsPageSize), resp.PageInfo.Size)
+	assert.Len(t, resp.Assets, len(seeded), "assets of other users are not listed")
+}
+
+func TestGetAssetsCursorPagination(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	userID := createRecentlyAddedTestUser(t, ctx, tdb, "asset-cursor")
+	seeded := seedFiveAssets(t, ctx, tdb, userID)
+
+	var ids, assetIDs []string
+	request := &immichv1.GetAssetsRequest{Size: 2}
+	idsRequest := &immichv1.GetAssetIdsRequest{Size: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, len(seeded), "paging must end")
+		resp, err := srv.GetAssets(ctxWithClaims(t, userID), request)
+		require.NoError(t, err)
+		for _, asset := range resp.Assets {
+			ids = append(ids, asset.Id)
+		}
+		idsResp, err := srv.GetAssetIds(ctxWithClaims(t, userID), idsRequest)
+		require.NoError(t, err)
+		for _, asset := range idsResp.Assets {
+			assetIDs = append(assetIDs, asset.Id)
+		}
+		assert.Equal(t, resp.NextCursor, idsResp.NextCursor)
+		if resp.NextCursor == nil {
+			break
+		}
+		request.Cursor = resp.NextCursor
+		idsRequest.Cursor = idsResp.NextCursor
+	}
+
+	want := make([]string, len(seeded))
+	for i, asset := range seeded {
+		want[len(seeded)-1-i] = uuid.UUID(asset.ID.Bytes).String()
+	}
+	assert.Equal(t, want, ids, "newest first, each asset once")
+	assert.Equal(t, want, assetIDs)
+
+	_, err = srv.GetAssets(ctxWithClaims(t, userID), &immichv1.GetAssetsRequest{Cursor: util.Ptr("not-a-cursor")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/util"
+)
+
+// Assets can be shared one by one with another user, without an album or a
+// link. The recipient can view and download them through the asset
+// endpoints but not modify or share them; revoking a share removes access on
+// the next request.
+
+// ShareAssets shares assets of the current user with another user and
+// reports for every asset whether it was shared or why it was skipped.
+func (s *Server) ShareAssets(ctx context.Context, request *immichv1.ShareAssetsRequest) (*immichv1.ShareAssetsResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipientID, err := s.assetShareRecipient(ctx, userID, request.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	assetUUIDs := parseAssetUUIDs(request.GetAssetIds())
+	found, err := s.db.GetAssetsByIDs(ctx, assetUUIDs)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get assets", err)
+	}
+	// Locked assets are never shared, so they count as owned by nobody
+	owners := make(map[pgtype.UUID]pgtype.UUID, len(found))
+	for _, asset := range found {
+		if asset.Visibility == sqlc.AssetVisibilityEnumLocked {
+			owners[asset.ID] = pgtype.UUID{}
+			continue
+		}
+		owners[asset.ID] = asset.OwnerId
+	}
+
+	// The results follow those of adding assets to an album
+	results, candidates := albumAssetCandidates(request.GetAssetIds(), owners, userID)
+	var shared []pgtype.UUID
+	if len(candidates) > 0 {
+		shared, err = s.db.ShareAssetsWithUser(ctx, sqlc.ShareAssetsWithUserParams{
+			AssetIds:     candidates,
+			SharedById:   userID,
+			SharedWithId: recipientID,
+		})
+		if err != nil {
+			return nil, SanitizedInternal(ctx, "failed to share assets", err)
+		}
+	}
+	markAlbumAssetsAdded(results, shared)
+
+	if len(shared) > 0 {
+		s.notifyAssetsShared(ctx, userID, recipientID, shared)
+	}
+
+	return &immichv1.ShareAssetsResponse{Results: results}, nil
+}
+
+// RevokeAssetShares stops sharing assets of the current user with another
+// user. Assets that were not shared with them are reported as not found.
+func (s *Server) RevokeAssetShares(ctx context.Context, request *immichv1.RevokeAssetSharesRequest) (*immichv1.RevokeAssetSharesResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := uuid.Parse(request.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+
+	revoked, err := s.db.RevokeAssetShares(ctx, sqlc.RevokeAssetSharesParams{
+		SharedById:   userID,
+		SharedWithId: pgtype.UUID{Bytes: recipient, Valid: true},
+		AssetIds:     parseAssetUUIDs(request.GetAssetIds()),
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to revoke asset shares", err)
+	}
+
+	removed := make(map[pgtype.UUID]bool, len(revoked))
+	for _, id := range revoked {
+		removed[id] = true
+	}
+	results := make([]*immichv1.BulkIdResponse, len(request.GetAssetIds()))
+	for i, id := range request.GetAssetIds() {
+		results[i] = &immichv1.BulkIdResponse{Id: id}
+		assetUUID := pgtype.UUID{}
+		if err := assetUUID.Scan(id); err == nil && removed[assetUUID] {
+			results[i].Success = true
+			// Revoking the same asset twice in one request reports it once
+			delete(removed, assetUUID)
+			continue
+		}
+		results[i].Error = util.Ptr(albumAssetNotFound)
+	}
+
+	return &immichv1.RevokeAssetSharesResponse{Results: results}, nil
+}
+
+// GetAssetShares lists the assets shared with the current user, newest
+// first, or with the shared-by direction the assets they shared. Shares of
+// assets that were trashed, locked or moved to another owner are left out.
+func (s *Server) GetAssetShares(ctx context.Context, request *immichv1.GetAssetSharesRequest) (*immichv1.GetAssetSharesResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := &immichv1.User{Id: userID.String()}
+
+	type share struct {
+		assetID    pgtype.UUID
+		sharer     pgtype.UUID
+		sharedBy   *immichv1.User
+		sharedWith *immichv1.User
+		createdAt  pgtype.Timestamptz
+	}
+	var shares []share
+	if request.GetDirection() == immichv1.AssetShareDirection_ASSET_SHARE_DIRECTION_SHARED_BY {
+		rows, err := s.db.ListAssetSharesByUser(ctx, userID)
+		if err != nil {
+			return nil, SanitizedInternal(ctx, "failed to list asset shares", err)
+		}
+		for _, row := range rows {
+			shares = append(shares, share{
+				assetID:    row.AssetId,
+				sharer:     userID,
+				sharedBy:   current,
+				sharedWith: &immichv1.User{Id: row.SharedWithId.String(), Name: row.Name, Email: row.Email},
+				createdAt:  row.CreatedAt,
+			})
+		}
+	} else {
+		rows, err := s.db.ListAssetSharesWithUser(ctx, userID)
+		if err != nil {
+			return nil, SanitizedInternal(ctx, "failed to list asset shares", err)
+		}
+		for _, row := range rows {
+			shares = append(shares, share{
+				assetID:    row.AssetId,
+				sharer:     row.SharedById,
+				sharedBy:   &immichv1.User{Id: row.SharedById.String(), Name: row.Name, Email: row.Email},
+				sharedWith: current,
+				createdAt:  row.CreatedAt,
+			})
+		}
+	}
+
+	assetUUIDs := make([]pgtype.UUID, len(shares))
+	for i, share := range shares {
+		assetUUIDs[i] = share.assetID
+	}
+	found, err := s.db.GetAssetsByIDs(ctx, assetUUIDs)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get assets", err)
+	}
+	assets := make(map[pgtype.UUID]sqlc.Asset, len(found))
+	for _, asset := range found {
+		assets[asset.ID] = asset
+	}
+
+	response := &immichv1.GetAssetSharesResponse{Shares: make([]*immichv1.AssetShareResponse, 0, len(shares))}
+	for _, share := range shares {
+		asset, ok := assets[share.assetID]
+		if !ok || asset.OwnerId != share.sharer || asset.Visibility == sqlc.AssetVisibilityEnumLocked {
+			continue
+		}
+		response.Shares = append(response.Shares, &immichv1.AssetShareResponse{
+			Asset:      s.convertAssetToProto(asset),
+			SharedBy:   share.sharedBy,
+			SharedWith: share.sharedWith,
+			CreatedAt:  timestamppb.New(share.createdAt.Time),
+		})
+	}
+	return response, nil
+}
+
+// assetShareRecipient checks that id names another user assets can be
+// shared with.
+func (s *Server) assetShareRecipient(ctx context.Context, userID pgtype.UUID, id string) (pgtype.UUID, error) {
+	recipient, err := uuid.Parse(id)
+	if err != nil {
+		return pgtype.UUID{}, status.Error(codes.InvalidArgument, "invalid user ID")
+	}
+	recipientID := pgtype.UUID{Bytes: recipient, Valid: true}
+	if recipientID == userID {
+		return pgtype.UUID{}, status.Error(codes.InvalidArgument, "cannot share assets with yourself")
+	}
+	if _, err := s.db.GetUserByID(ctx, recipientID); err != nil {
+		return pgtype.UUID{}, status.Error(codes.NotFound, "user not found")
+	}
+	return recipientID, nil
+}
+
+// notifyAssetsShared tells the recipient about newly shared assets. A
+// failure is only logged: the assets are shared already.
+func (s *Server) notifyAssetsShared(ctx context.Context, sharedBy, sharedWith pgtype.UUID, assetIDs []pgtype.UUID) {
+	ids := make([]string, len(assetIDs))
+	for i, id := range assetIDs {
+		ids[i] = id.String()
+	}
+	data, err := json.Marshal(map[string]any{"sharedById": sharedBy.String(), "assetIds": ids})
+	if err != nil {
+		data = []byte("{}")
+	}
+
+	sharer := "Someone"
+	if user, err := s.db.GetUserByID(ctx, sharedBy); err == nil {
+		sharer = user.Name
+		if sharer == "" {
+			sharer = user.Email
+		}
+	}
+	title := fmt.Sprintf("%s shared a photo with you", sharer)
+	if len(ids) > 1 {
+		title = fmt.Sprintf("%s shared %d photos with you", sharer, len(ids))
+	}
+
+	if _, err := s.db.CreateNotification(ctx, sqlc.CreateNotificationParams{
+		UserId: sharedWith,
+		Level:  "info",
+		Type:   "asset_share",
+		Data:   data,
+		Title:  title,
+	}); err != nil {
+		logrus.WithError(err).WithField("user_id", sharedWith.String()).Warn("Failed to create asset share notification")
+	}
+}
+
+// parseAssetUUIDs returns the valid asset IDs of ids.
+func parseAssetUUIDs(ids []string) []pgtype.UUID {
+	assetUUIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		assetUUID := pgtype.UUID{}
+		if err := assetUUID.Scan(id); err == nil {
+			assetUUIDs = append(assetUUIDs, assetUUID)
+		}
+	}
+	return assetUUIDs
+}
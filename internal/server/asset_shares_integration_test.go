@@ -0,0 +1,148 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestServer_AssetShares(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	recipient := createAssetViewerTestUser(t, ctx, env.tdb)
+	other := createAssetViewerTestUser(t, ctx, env.tdb)
+	asset := seedAsset(t, ctx, env, owner, "shared.jpg", "image/jpeg", []byte("shared-bytes"))
+	assetID := uuid.UUID(asset.ID.Bytes).String()
+	unknownID := uuid.New().String()
+
+	shared, err := env.srv.ShareAssets(assetViewerContext(owner), &immichv1.ShareAssetsRequest{
+		UserId:   recipient.String(),
+		AssetIds: []string{assetID, unknownID, assetID},
+	})
+	require.NoError(t, err)
+	require.Len(t, shared.GetResults(), 3)
+	assert.True(t, shared.GetResults()[0].GetSuccess())
+	assert.Equal(t, albumAssetNotFound, shared.GetResults()[1].GetError())
+	assert.Equal(t, albumAssetDuplicate, shared.GetResults()[2].GetError())
+
+	notifications, err := env.tdb.Queries.GetNotifications(ctx, sqlc.GetNotificationsParams{
+		UserId: mustUUID(t, recipient),
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "asset_share", notifications[0].Type)
+
+	// The recipient can view and download the asset
+	got, err := env.srv.GetAsset(assetViewerContext(recipient), &immichv1.GetAssetRequest{AssetId: assetID})
+	require.NoError(t, err)
+	assert.Equal(t, assetID, got.GetId())
+	download, err := env.srv.DownloadAsset(assetViewerContext(recipient), &immichv1.DownloadAssetRequest{AssetId: assetID})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("shared-bytes"), download.GetData())
+
+	// but neither modify nor share it
+	favorite := true
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.UpdateAsset(assetViewerContext(recipient), &immichv1.UpdateAssetRequest{
+			AssetId:    assetID,
+			IsFavorite: &favorite,
+		})
+		return err
+	})
+	reshared, err := env.srv.ShareAssets(assetViewerContext(recipient), &immichv1.ShareAssetsRequest{
+		UserId:   other.String(),
+		AssetIds: []string{assetID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, albumAssetNoPermission, reshared.GetResults()[0].GetError())
+
+	// Others still cannot see it
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(other), &immichv1.GetAssetRequest{AssetId: assetID})
+		return err
+	})
+
+	sharedWithMe, err := env.srv.GetAssetShares(assetViewerContext(recipient), &immichv1.GetAssetSharesRequest{})
+	require.NoError(t, err)
+	require.Len(t, sharedWithMe.GetShares(), 1)
+	assert.Equal(t, assetID, sharedWithMe.GetShares()[0].GetAsset().GetId())
+	assert.Equal(t, owner.String(), sharedWithMe.GetShares()[0].GetSharedBy().GetId())
+	sharedByMe, err := env.srv.GetAssetShares(assetViewerContext(owner), &immichv1.GetAssetSharesRequest{
+		Direction: immichv1.AssetShareDirection_ASSET_SHARE_DIRECTION_SHARED_BY,
+	})
+	require.NoError(t, err)
+	require.Len(t, sharedByMe.GetShares(), 1)
+	assert.Equal(t, recipient.String(), sharedByMe.GetShares()[0].GetSharedWith().GetId())
+
+	// Only the owner can revoke the share
+	notRevoked, err := env.srv.RevokeAssetShares(assetViewerContext(other), &immichv1.RevokeAssetSharesRequest{
+		UserId:   recipient.String(),
+		AssetIds: []string{assetID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, albumAssetNotFound, notRevoked.GetResults()[0].GetError())
+
+	revoked, err := env.srv.RevokeAssetShares(assetViewerContext(owner), &immichv1.RevokeAssetSharesRequest{
+		UserId:   recipient.String(),
+		AssetIds: []string{assetID},
+	})
+	require.NoError(t, err)
+	assert.True(t, revoked.GetResults()[0].GetSuccess())
+
+	// Revoking cuts off access right away
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAsset(assetViewerContext(recipient), &immichv1.GetAssetRequest{AssetId: assetID})
+		return err
+	})
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.DownloadAsset(assetViewerContext(recipient), &immichv1.DownloadAssetRequest{AssetId: assetID})
+		return err
+	})
+	assertAssetViewerNotFound(t, func() error {
+		_, err := env.srv.GetAssetThumbnail(assetViewerContext(recipient), &immichv1.GetAssetThumbnailRequest{AssetId: assetID})
+		return err
+	})
+	sharedWithMe, err = env.srv.GetAssetShares(assetViewerContext(recipient), &immichv1.GetAssetSharesRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, sharedWithMe.GetShares())
+}
+
+func TestServer_ShareAssetsRejectsLockedAssetsAndSelf(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	recipient := createAssetViewerTestUser(t, ctx, env.tdb)
+	asset := seedAsset(t, ctx, env, owner, "locked.jpg", "image/jpeg", []byte("locked-bytes"))
+	_, err := env.tdb.Queries.UpdateAsset(ctx, sqlc.UpdateAssetParams{
+		ID:         asset.ID,
+		Visibility: sqlc.NullAssetVisibilityEnum{AssetVisibilityEnum: sqlc.AssetVisibilityEnumLocked, Valid: true},
+	})
+	require.NoError(t, err)
+	assetID := uuid.UUID(asset.ID.Bytes).String()
+
+	shared, err := env.srv.ShareAssets(assetViewerContext(owner), &immichv1.ShareAssetsRequest{
+		UserId:   recipient.String(),
+		AssetIds: []string{assetID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, albumAssetNoPermission, shared.GetResults()[0].GetError())
+
+	_, err = env.srv.ShareAssets(assetViewerContext(owner), &immichv1.ShareAssetsRequest{
+		UserId:   owner.String(),
+		AssetIds: []string{assetID},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+const (
+	// strippedOriginalTTL is how long a copy of an original without its
+	// metadata is kept for the downloads that follow, such as the Range
+	// requests of a resumed download.
+	strippedOriginalTTL = 5 * time.Minute
+	// strippedOriginalCacheBytes bounds the memory the copies take.
+	strippedOriginalCacheBytes = 128 << 20
+)
+
+// stripUnsupportedWarning is the Warning header of an original served with
+// its metadata because its format does not support removing it. Only owners
+// are served such originals.
+const stripUnsupportedWarning = `299 - "metadata could not be removed from this format, the original is served"`
+
+// errStripRequired is returned instead of the original when a user other
+// than the owner is to get it without metadata, but it cannot be removed.
+var errStripRequired = status.Error(codes.FailedPrecondition, "metadata cannot be removed from this file format")
+
+// strippedOriginalCache holds recently made copies of originals without
+// their metadata. Entries are keyed by the asset's last update, so a
+// replaced original is not served from an old copy.
+type strippedOriginalCache struct {
+	sync.Mutex
+	entries map[strippedOriginalKey]strippedOriginalEntry
+	size    int
+}
+
+type strippedOriginalKey struct {
+	assetID   pgtype.UUID
+	updatedAt int64
+}
+
+type strippedOriginalEntry struct {
+	data     []byte
+	storedAt time.Time
+}
+
+func strippedOriginalKeyOf(asset sqlc.Asset) strippedOriginalKey {
+	return strippedOriginalKey{assetID: asset.ID, updatedAt: asset.UpdatedAt.Time.UnixNano()}
+}
+
+// get returns the copy stored for key less than strippedOriginalTTL ago.
+func (c *strippedOriginalCache) get(key strippedOriginalKey) ([]byte, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.storedAt) >= strippedOriginalTTL {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// put stores the copy of key, dropping the expired ones and then the oldest
+// ones until it fits. Copies larger than a quarter of the cache are not
+// stored.
+func (c *strippedOriginalCache) put(key strippedOriginalKey, data []byte) {
+	if len(data) > strippedOriginalCacheBytes/4 {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[strippedOriginalKey]strippedOriginalEntry)
+	}
+	for k, entry := range c.entries {
+		if time.Since(entry.storedAt) >= strippedOriginalTTL {
+			c.remove(k)
+		}
+	}
+	for c.size+len(data) > strippedOriginalCacheBytes && len(c.entries) > 0 {
+		var oldest strippedOriginalKey
+		var oldestAt time.Time
+		for k, entry := range c.entries {
+			if oldestAt.IsZero() || entry.storedAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.storedAt
+			}
+		}
+		c.remove(oldest)
+	}
+	c.remove(key)
+	c.entries[key] = strippedOriginalEntry{data: data, storedAt: time.Now()}
+	c.size += len(data)
+}
+
+func (c *strippedOriginalCache) remove(key strippedOriginalKey) {
+	if entry, ok := c.entries[key]; ok {
+		c.size -= len(entry.data)
+		delete(c.entries, key)
+	}
+}
+
+// wantsStrippedOriginal reports whether a download of asset by userID
+// removes its metadata. Owners get the original unless they ask otherwise,
+// the users it is shared with get a copy without the metadata.
+func wantsStrippedOriginal(asset sqlc.Asset, userID pgtype.UUID, requested *bool) bool {
+	if requested != nil {
+		return *requested
+	}
+	return asset.OwnerId != userID
+}
+
+// strippedOriginal returns a copy of the original of asset without its
+// metadata, making it when none was made recently. The stored original is
+// left untouched. It fails with assets.ErrStripUnsupported for formats the
+// metadata cannot be removed from.
+func (s *Server) strippedOriginal(ctx context.Context, asset sqlc.Asset) ([]byte, error) {
+	if !assets.CanStripMetadata(storage.MimeTypeByExtension(fileExtension(asset.OriginalFileName))) {
+		return nil, assets.ErrStripUnsupported
+	}
+	key := strippedOriginalKeyOf(asset)
+	if data, ok := s.strippedOriginals.get(key); ok {
+		return data, nil
+	}
+	store := s.assetService.GetStorageService()
+	original, err := downloadAll(ctx, store, asset.OriginalPath)
+	if err != nil {
+		return nil, s.originalReadError(ctx, store, asset, asset.OriginalPath, err)
+	}
+	data, err := assets.StripMetadata(original)
+	if errors.Is(err, assets.ErrStripUnsupported) {
+		return nil, err
+	}
+	if err != nil {
+		// A file that does not parse is served like one of an unsupported
+		// format
+		logrus.WithError(err).WithField("asset_id", asset.ID.String()).Warn("Failed to remove metadata from original")
+		return nil, assets.ErrStripUnsupported
+	}
+	s.strippedOriginals.put(key, data)
+	return data, nil
+}
@@ -0,0 +1,59 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestDownloadAsset_StripMetadata downloads an original with and without its
+// metadata and checks the stored original keeps it.
+func TestDownloadAsset_StripMetadata(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil))
+	comment := []byte{0xFF, 0xFE, 0x00, 0x0D, 's', 'e', 'c', 'r', 'e', 't', '-', 'g', 'p', 's', 0x00}
+	original := append(append(append([]byte{}, encoded.Bytes()[:2]...), comment...), encoded.Bytes()[2:]...)
+	photo := seedAsset(t, ctx, env, userID, "strip.jpg", "image/jpeg", original)
+	video := seedAsset(t, ctx, env, userID, "strip.mp4", "video/mp4", []byte("secret-video"))
+
+	download := func(assetID string, strip *bool) *immichv1.DownloadAssetResponse {
+		t.Helper()
+		response, err := env.srv.DownloadAsset(userCtx, &immichv1.DownloadAssetRequest{AssetId: assetID, StripMetadata: strip})
+		require.NoError(t, err)
+		return response
+	}
+	yes := true
+
+	response := download(photo.ID.String(), nil)
+	assert.Equal(t, original, response.GetData(), "owners get the original by default")
+	assert.False(t, response.GetMetadataStripped())
+
+	response = download(photo.ID.String(), &yes)
+	assert.True(t, response.GetMetadataStripped())
+	assert.NotContains(t, string(response.GetData()), "secret")
+	_, err := jpeg.Decode(bytes.NewReader(response.GetData()))
+	require.NoError(t, err)
+
+	// A second download is served from the cache, the original is kept
+	assert.Equal(t, response.GetData(), download(photo.ID.String(), &yes).GetData())
+	assert.Equal(t, original, download(photo.ID.String(), nil).GetData())
+
+	response = download(video.ID.String(), &yes)
+	assert.False(t, response.GetMetadataStripped(), "videos are served as they are")
+	assert.Equal(t, []byte("secret-video"), response.GetData())
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+func TestWantsStrippedOriginal(t *testing.T) {
+	owner := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	other := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+	asset := sqlc.Asset{OwnerId: owner}
+	yes, no := true, false
+
+	assert.False(t, wantsStrippedOriginal(asset, owner, nil), "owners get the original")
+	assert.True(t, wantsStrippedOriginal(asset, other, nil), "others get a copy without metadata")
+	assert.True(t, wantsStrippedOriginal(asset, owner, &yes))
+	assert.False(t, wantsStrippedOriginal(asset, other, &no))
+}
+
+func TestStrippedOriginalCache(t *testing.T) {
+	var cache strippedOriginalCache
+	key := func(n byte, updatedAt int64) strippedOriginalKey {
+		return strippedOriginalKey{assetID: pgtype.UUID{Bytes: [16]byte{n}, Valid: true}, updatedAt: updatedAt}
+	}
+	quarter := bytes.Repeat([]byte{1}, strippedOriginalCacheBytes/4)
+
+	cache.put(key(1, 1), []byte("copy"))
+	data, ok := cache.get(key(1, 1))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("copy"), data)
+	_, ok = cache.get(key(1, 2))
+	assert.False(t, ok, "an updated asset is not served an old copy")
+
+	cache.put(key(2, 1), append(quarter, 1))
+	_, ok = cache.get(key(2, 1))
+	assert.False(t, ok, "large copies are not kept")
+
+	// The oldest copies make room for new ones
+	for n := byte(2); n <= 5; n++ {
+		cache.put(key(n, 1), quarter)
+	}
+	_, ok = cache.get(key(1, 1))
+	assert.False(t, ok)
+	_, ok = cache.get(key(5, 1))
+	assert.True(t, ok)
+	assert.LessOrEqual(t, cache.size, strippedOriginalCacheBytes)
+
+	// Expired copies are not served
+	cache.entries[key(5, 1)] = strippedOriginalEntry{data: quarter, storedAt: time.Now().Add(-strippedOriginalTTL)}
+	_, ok = cache.get(key(5, 1))
+	assert.False(t, ok)
+}
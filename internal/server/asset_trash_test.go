@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashPurge(t *testing.T) {
+	trashedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	purgeAt := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		now  time.Time
+		days int32
+	}{
+		"just trashed":       {trashedAt, 30},
+		"part of a day left": {purgeAt.Add(-time.Hour), 1},
+		"a day and a bit":    {purgeAt.Add(-25 * time.Hour), 2},
+		"due":                {purgeAt, 0},
+		"overdue":            {purgeAt.Add(72 * time.Hour), 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gotPurgeAt, days := trashPurge(trashedAt, 30, tc.now)
+			assert.Equal(t, purgeAt, gotPurgeAt)
+			assert.Equal(t, tc.days, days)
+		})
+	}
+}
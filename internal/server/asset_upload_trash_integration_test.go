@@ -0,0 +1,94 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestUploadRestoresTrashedDuplicate(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	cfg := &config.Config{}
+	cfg.Storage.Upload.RestoreTrashedDuplicates = true
+	srv := &Server{db: conn, config: cfg}
+
+	ownerID := tdb.CreateTestUser(t, "upload-trash@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	content := []byte("the same photo, uploaded twice")
+	checksum := assets.SumChecksum(content, assets.DefaultChecksumAlgorithm)
+	assetID := tdb.CreateTestAssetWithChecksum(t, ownerID, "upload-trash", checksum.Stored())
+
+	userCtx := auth.WithClaims(ctx, &auth.Claims{UserID: ownerID.String()})
+	_, err = srv.DeleteAssets(userCtx, &immichv1.DeleteAssetsRequest{Ids: []string{assetID.String()}})
+	require.NoError(t, err)
+
+	resp, err := srv.createUploadedAsset(userCtx, ownerID.String(), nil, nil, content, checksum)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(resp.Body, &body))
+	assert.Equal(t, assetID.String(), body["id"])
+	assert.Equal(t, "duplicate", body["status"])
+
+	matches, err := tdb.Queries.GetOwnerAssetsByChecksum(ctx, sqlc.GetOwnerAssetsByChecksumParams{
+		OwnerID:           owner,
+		Checksum:          checksum.Stored(),
+		ChecksumAlgorithm: string(checksum.Algorithm),
+	})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, sqlc.AssetsStatusEnumActive, matches[0].Status)
+}
+
+func TestUploadReplacesTrashedDuplicateWhenRestoreIsOff(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn, config: &config.Config{}}
+
+	ownerID := tdb.CreateTestUser(t, "upload-fresh@example.com")
+	owner := pgtype.UUID{Bytes: ownerID, Valid: true}
+	checksum := assets.SumChecksum([]byte("a photo to start over with"), assets.DefaultChecksumAlgorithm)
+	assetID := tdb.CreateTestAssetWithChecksum(t, ownerID, "upload-fresh", checksum.Stored())
+	require.NoError(t, tdb.Queries.TrashAssetsByIDsAndOwner(ctx, sqlc.TrashAssetsByIDsAndOwnerParams{
+		OwnerId: owner,
+		Column2: []pgtype.UUID{{Bytes: assetID, Valid: true}},
+	}))
+	trashed, err := tdb.Queries.GetAsset(ctx, pgtype.UUID{Bytes: assetID, Valid: true})
+	require.NoError(t, err)
+
+	duplicate, err := srv.resolveDuplicateUpload(ctx, trashed)
+	require.NoError(t, err)
+	assert.False(t, duplicate, "the upload goes ahead as a fresh asset")
+
+	_, err = tdb.Queries.GetAsset(ctx, trashed.ID)
+	assert.Error(t, err, "the trashed asset makes way for the fresh one")
+}
@@ -6,8 +6,12 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/sha1" //nolint:gosec // Immich asset checksum convention.
+	"encoding/base64"
+	"encoding/hex"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -293,7 +297,7 @@ func TestServer_ReplaceAsset_UserIsolation(t *testing.T) {
 	assetAID := uuid.UUID(assetA.ID.Bytes).String()
 
 	assertAssetViewerNotFound(t, func() error {
-		checksum := "replacement-checksum"
+		checksum := strings.Repeat("ab", 20)
 		_, err := env.srv.ReplaceAsset(assetViewerContext(userB), &immichv1.ReplaceAssetRequest{
 			AssetId:     assetAID,
 			Checksum:    &checksum,
@@ -310,8 +314,17 @@ func TestServer_ReplaceAsset_UserIsolation(t *testing.T) {
 	require.True(t, ok, "expected a gRPC status error")
 	assert.Equal(t, codes.InvalidArgument, st.Code())
 
+	invalidChecksum := "replacement-checksum"
+	_, err = env.srv.ReplaceAsset(assetViewerContext(userA), &immichv1.ReplaceAssetRequest{
+		AssetId:     assetAID,
+		Checksum:    &invalidChecksum,
+		FileContent: []byte("replacement-bytes"),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "checksums must be a SHA-1 or SHA-256 digest")
+
 	replacement := []byte("replacement-bytes")
-	checksum := "replacement-checksum"
+	sum := sha1.Sum(replacement)
+	checksum := base64.StdEncoding.EncodeToString(sum[:])
 	resp, err := env.srv.ReplaceAsset(assetViewerContext(userA), &immichv1.ReplaceAssetRequest{
 		AssetId:     assetAID,
 		Checksum:    &checksum,
@@ -322,7 +335,8 @@ func TestServer_ReplaceAsset_UserIsolation(t *testing.T) {
 	assert.Equal(t, assetAID, resp.GetId())
 	assert.Equal(t, userA.String(), resp.GetOwnerId())
 	assert.Equal(t, assetA.OriginalFileName, resp.GetOriginalFileName())
-	assert.Equal(t, "7265706c6163656d656e742d636865636b73756d", resp.GetChecksum())
+	assert.Equal(t, hex.EncodeToString([]byte(hex.EncodeToString(sum[:]))), resp.GetChecksum())
+	assert.Equal(t, "sha1", resp.GetChecksumAlgorithm())
 
 	downloaded, err := env.srv.assetService.GetStorageService().Download(ctx, assetA.OriginalPath)
 	require.NoError(t, err)
@@ -333,7 +347,7 @@ func TestServer_ReplaceAsset_UserIsolation(t *testing.T) {
 
 	reloaded, err := env.tdb.Queries.GetAssetByID(ctx, assetA.ID)
 	require.NoError(t, err)
-	assert.Equal(t, []byte(checksum), reloaded.Checksum)
+	assert.Equal(t, []byte(hex.EncodeToString(sum[:])), reloaded.Checksum, "checksums are stored as lowercase hex")
 }
 
 func TestServer_DeleteAssets_UserIsolation(t *testing.T) {
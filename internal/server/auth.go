@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"google.golang.org/grpc"
@@ -11,10 +12,23 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
 )
 
 func (s *Server) Login(ctx context.Context, req *immichv1.LoginRequest) (*immichv1.LoginResponse, error) {
+	var violations []grpcutil.FieldViolation
+	if req.Email == "" {
+		violations = append(violations, grpcutil.FieldViolation{Field: "email", Description: "email should not be empty"})
+	}
+	if req.Password == "" {
+		violations = append(violations, grpcutil.FieldViolation{Field: "password", Description: "password should not be empty"})
+	}
+	if len(violations) > 0 {
+		return nil, grpcutil.InvalidFields(ctx, "invalid login request", violations...)
+	}
+
 	// Use the actual auth service
 	loginRequest := &auth.LoginRequest{
 		Email:    req.Email,
@@ -97,6 +111,8 @@ func (s *Server) AdminSignUp(ctx context.Context, req *immichv1.AdminSignUpReque
 		}
 		return nil, SanitizedInternal(ctx, "admin registration failed", err)
 	}
+	s.publishWebhookEvent(ctx, webhooks.EventUserCreated,
+		webhooks.UserData(response.User.ID, response.User.Email, response.User.Name, response.User.IsAdmin))
 
 	// The Register method already returns the token in AuthResponse
 	return &immichv1.LoginResponse{
@@ -245,7 +261,8 @@ func (s *Server) ResetPinCode(ctx context.Context, req *immichv1.PinCodeResetReq
 	return &emptypb.Empty{}, nil
 }
 
-// UnlockSession unlocks the session with a PIN code for elevated access
+// UnlockSession unlocks the session with a PIN code, or the account password,
+// for elevated access
 func (s *Server) UnlockSession(ctx context.Context, req *immichv1.SessionUnlockRequest) (*emptypb.Empty, error) {
 	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
@@ -257,7 +274,11 @@ func (s *Server) UnlockSession(ctx context.Context, req *immichv1.SessionUnlockR
 		return nil, status.Errorf(codes.InvalidArgument, "no session found")
 	}
 
-	err = s.authService.UnlockSession(ctx, userID.String(), sessionID, req.PinCode)
+	if req.PinCode == "" && req.Password != nil {
+		err = s.authService.UnlockSessionWithPassword(ctx, userID.String(), sessionID, req.GetPassword())
+	} else {
+		err = s.authService.UnlockSession(ctx, userID.String(), sessionID, req.PinCode)
+	}
 	if err != nil {
 		if grpcErr, ok := publicAuthError(ctx, err, codes.InvalidArgument); ok {
 			return nil, grpcErr
@@ -287,7 +308,42 @@ func publicAuthError(ctx context.Context, err error, invalidCredentialsCode code
 		message = authErr.Err.Error()
 	}
 
-	return PublicError(ctx, code, message), true
+	publicErr := PublicError(ctx, code, message)
+	var policyErr *auth.PasswordPolicyError
+	if authErr.Type == auth.ErrInvalidPassword && errors.As(err, &policyErr) {
+		var violations []grpcutil.FieldViolation
+		for _, req := range policyErr.Requirements {
+			if !req.Satisfied {
+				violations = append(violations, grpcutil.FieldViolation{Field: "password", Description: "password must " + req.Description})
+			}
+		}
+		st, detailErr := status.Convert(grpcutil.InvalidFields(ctx, message, violations...)).
+			WithDetails(passwordPolicyToProto(policyErr.Requirements))
+		if detailErr == nil {
+			publicErr = st.Err()
+		}
+	}
+	return grpcutil.WithReason(publicErr, string(authErr.Type)), true
+}
+
+// GetPasswordPolicy returns the active password requirements so sign-up and
+// change-password forms can show them upfront.
+func (s *Server) GetPasswordPolicy(ctx context.Context, _ *emptypb.Empty) (*immichv1.PasswordPolicyResponseDto, error) {
+	return passwordPolicyToProto(s.authService.PasswordPolicy()), nil
+}
+
+func passwordPolicyToProto(requirements []auth.PasswordRequirement) *immichv1.PasswordPolicyResponseDto {
+	dto := &immichv1.PasswordPolicyResponseDto{
+		Requirements: make([]*immichv1.PasswordRequirementDto, len(requirements)),
+	}
+	for i, req := range requirements {
+		dto.Requirements[i] = &immichv1.PasswordRequirementDto{
+			Rule:        req.Rule,
+			Description: req.Description,
+			Satisfied:   req.Satisfied,
+		}
+	}
+	return dto
 }
 
 // LockSession locks the session to revoke elevated access
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -66,3 +67,18 @@ func testAccessTokenCookie(value string) *http.Cookie {
 		SameSite: http.SameSiteLaxMode,
 	}
 }
+
+func TestHTTPResponseModifierEmitsEveryCookie(t *testing.T) {
+	md := runtime.ServerMetadata{HeaderMD: metadata.MD{
+		"set-cookie": {"immich_access_token=a; Path=/", "immich_is_authenticated=true; Path=/"},
+	}}
+	ctx := runtime.NewServerMetadataContext(context.Background(), md)
+	rec := httptest.NewRecorder()
+	rec.Header().Add("Grpc-Metadata-set-cookie", "immich_access_token=a; Path=/")
+
+	require.NoError(t, httpResponseModifier(ctx, rec, nil))
+
+	assert.Equal(t, []string{"immich_access_token=a; Path=/", "immich_is_authenticated=true; Path=/"},
+		rec.Result().Header.Values("Set-Cookie"))
+	assert.Empty(t, rec.Result().Header.Values("Grpc-Metadata-Set-Cookie"))
+}
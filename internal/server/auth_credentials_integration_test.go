@@ -0,0 +1,112 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/denysvitali/immich-go-backend/internal/apikeys"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/sharedlinks"
+)
+
+// TestServer_AuthCredentials calls handlers through the auth interceptor
+// with each kind of credential: a Bearer token, an API key, and the key of a
+// shared link held by a caller who is not signed in.
+func TestServer_AuthCredentials(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	env.srv.authService = auth.NewService(config.AuthConfig{
+		JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+	}, env.tdb.Queries)
+	env.srv.apiKeyService = apikeys.NewService(env.tdb.Queries)
+	env.srv.sharedLinksService = sharedlinks.NewService(env.tdb.Queries)
+	ctx := context.Background()
+
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	asset := seedAsset(t, ctx, env, owner, "credentials.jpg", "image/jpeg", testJPEG(t))
+	assetID := uuid.UUID(asset.ID.Bytes).String()
+
+	call := func(md metadata.MD, method string, req any, handler grpc.UnaryHandler) (any, error) {
+		return env.srv.unaryAuthInterceptor(metadata.NewIncomingContext(ctx, md), req,
+			&grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+	getAsset := func(md metadata.MD, req *immichv1.GetAssetRequest) (*immichv1.Asset, error) {
+		resp, err := call(md, immichv1.AssetService_GetAsset_FullMethodName, req, func(ctx context.Context, req any) (any, error) {
+			return env.srv.GetAsset(ctx, req.(*immichv1.GetAssetRequest))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.(*immichv1.Asset), nil
+	}
+
+	t.Run("bearer token", func(t *testing.T) {
+		token, err := env.srv.authService.GenerateToken(owner.String(), "owner@example.com", time.Hour)
+		require.NoError(t, err)
+		got, err := getAsset(metadata.Pairs("authorization", "Bearer "+token), &immichv1.GetAssetRequest{AssetId: assetID})
+		require.NoError(t, err)
+		assert.Equal(t, assetID, got.GetId())
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		apiKey, rawKey, err := env.srv.apiKeyService.CreateAPIKey(ctx, owner, "cli")
+		require.NoError(t, err)
+		md := metadata.Pairs("x-api-key", rawKey)
+
+		got, err := getAsset(md, &immichv1.GetAssetRequest{AssetId: assetID})
+		require.NoError(t, err)
+		assert.Equal(t, assetID, got.GetId())
+
+		resp, err := call(md, immichv1.ApiKeyService_GetMyApiKey_FullMethodName, &emptypb.Empty{},
+			func(ctx context.Context, _ any) (any, error) {
+				return env.srv.GetMyApiKey(ctx, &emptypb.Empty{})
+			})
+		require.NoError(t, err)
+		assert.Equal(t, uuid.UUID(apiKey.ID.Bytes).String(), resp.(*immichv1.ApiKeyResponseDto).GetId())
+
+		_, err = getAsset(metadata.Pairs("x-api-key", "not-a-key"), &immichv1.GetAssetRequest{AssetId: assetID})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("shared link key", func(t *testing.T) {
+		link, err := env.srv.sharedLinksService.CreateSharedLink(ctx, owner, &sharedlinks.CreateSharedLinkRequest{
+			Type:          sharedlinks.SharedLinkTypeIndividual,
+			AssetIDs:      []string{assetID},
+			AllowDownload: true,
+			ShowExif:      true,
+		})
+		require.NoError(t, err)
+
+		got, err := getAsset(metadata.MD{}, &immichv1.GetAssetRequest{AssetId: assetID, Key: proto.String(link.Key)})
+		require.NoError(t, err)
+		assert.Equal(t, assetID, got.GetId())
+
+		req := &immichv1.DownloadAssetRequest{AssetId: assetID, Key: proto.String(link.Key)}
+		resp, err := call(metadata.MD{}, immichv1.AssetService_DownloadAsset_FullMethodName, req,
+			func(ctx context.Context, req any) (any, error) {
+				return env.srv.DownloadAsset(ctx, req.(*immichv1.DownloadAssetRequest))
+			})
+		require.NoError(t, err)
+		assert.NotEmpty(t, resp.(*immichv1.DownloadAssetResponse).GetData())
+
+		_, err = getAsset(metadata.MD{}, &immichv1.GetAssetRequest{AssetId: assetID, Key: proto.String("not-a-key")})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = getAsset(metadata.MD{}, &immichv1.GetAssetRequest{AssetId: assetID})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
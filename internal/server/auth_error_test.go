@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
@@ -66,3 +68,30 @@ func TestPublicAuthErrorRejectsNonAuthError(t *testing.T) {
 	assert.False(t, ok)
 	assert.NoError(t, got)
 }
+
+func TestPublicAuthErrorAttachesPasswordRequirements(t *testing.T) {
+	policyErr := &auth.PasswordPolicyError{Requirements: []auth.PasswordRequirement{
+		{Rule: auth.PasswordRuleMinLength, Description: "be at least 8 characters long", Satisfied: false},
+		{Rule: auth.PasswordRuleNumber, Description: "contain at least one number", Satisfied: true},
+	}}
+	err := auth.NewAuthError(auth.ErrInvalidPassword, "Password does not meet requirements", policyErr)
+
+	got, ok := publicAuthError(context.Background(), err, codes.OK)
+
+	require.True(t, ok)
+	st, ok := status.FromError(got)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "password must be at least 8 characters long", st.Message())
+	require.Len(t, st.Details(), 3)
+	assert.Equal(t, "invalid_password", grpcutil.Reason(st))
+	assert.Equal(t, []grpcutil.FieldViolation{
+		{Field: "password", Description: "password must be at least 8 characters long"},
+	}, grpcutil.FieldViolations(st))
+	detail, ok := st.Details()[1].(*immichv1.PasswordPolicyResponseDto)
+	require.True(t, ok)
+	require.Len(t, detail.Requirements, 2)
+	assert.Equal(t, auth.PasswordRuleMinLength, detail.Requirements[0].Rule)
+	assert.False(t, detail.Requirements[0].Satisfied)
+	assert.True(t, detail.Requirements[1].Satisfied)
+}
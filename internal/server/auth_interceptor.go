@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// publicMethods are the RPCs that can be called without signing in. Every
+// other method is rejected with Unauthenticated before its handler runs, both
+// over gRPC and through the HTTP gateway. Shared link endpoints authenticate
+// with the link key themselves.
+//
+// Callers authenticate with a Bearer token, the session cookie or an API key
+// in the x-api-key header.
+var publicMethods = map[string]bool{
+	immichv1.AuthService_Login_FullMethodName:                       true,
+	immichv1.AuthService_AdminSignUp_FullMethodName:                 true,
+	immichv1.AuthService_GetPasswordPolicy_FullMethodName:           true,
+	immichv1.OAuthService_AuthorizeOAuth_FullMethodName:             true,
+	immichv1.OAuthService_CallbackOAuth_FullMethodName:              true,
+	immichv1.OAuthService_GenerateOAuthConfig_FullMethodName:        true,
+	immichv1.OAuthService_LogoutOAuth_FullMethodName:                true,
+	immichv1.ServerService_PingServer_FullMethodName:                true,
+	immichv1.ServerService_GetServerVersion_FullMethodName:          true,
+	immichv1.ServerService_GetServerFeatures_FullMethodName:         true,
+	immichv1.ServerService_GetServerConfig_FullMethodName:           true,
+	immichv1.ServerService_GetTheme_FullMethodName:                  true,
+	immichv1.ServerService_GetSupportedMediaTypes_FullMethodName:    true,
+	immichv1.SharedLinksService_GetMySharedLink_FullMethodName:      true,
+	immichv1.SharedLinksService_SharedLinkLogin_FullMethodName:      true,
+	immichv1.MaintenanceService_GetMaintenanceStatus_FullMethodName: true,
+	immichv1.MaintenanceService_MaintenanceLogin_FullMethodName:     true,
+
+	// Standard gRPC services for probes and tooling. Reflection is only
+	// served when server.grpc_reflection is enabled.
+	healthpb.Health_Check_FullMethodName:                                   true,
+	healthpb.Health_Watch_FullMethodName:                                   true,
+	reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName:      true,
+	reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: true,
+}
+
+// sharedLinkKeyMethods are the RPCs that also serve callers who are not
+// signed in but send the key of a shared link, which the handler checks.
+var sharedLinkKeyMethods = map[string]bool{
+	immichv1.AssetService_GetAsset_FullMethodName:      true,
+	immichv1.AssetService_DownloadAsset_FullMethodName: true,
+}
+
+// apiKeyHeader is the header, and gRPC metadata key, carrying an API key.
+const apiKeyHeader = "x-api-key"
+
+var errAuthenticationRequired = status.Error(codes.Unauthenticated, "authentication required")
+
+// authenticate validates the Bearer token once for the request and returns a
+// context carrying the caller's claims, parsed user ID and, when the user can
+// be loaded, the user info read by auth.RequireUser.
+func (s *Server) authenticate(ctx context.Context, token string) (context.Context, error) {
+	if s.authService == nil {
+		return ctx, errAuthenticationRequired
+	}
+	claims, err := s.authService.ValidateToken(token)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return ctx, errInvalidUserID
+	}
+
+	ctx = auth.WithClaims(ctx, claims)
+	ctx = auth.SetUserIDInContext(ctx, userID)
+	if userInfo, err := s.authService.LoadUserInfo(ctx, claims); err == nil {
+		ctx = auth.WithUser(ctx, *userInfo)
+	}
+	return ctx, nil
+}
+
+// authenticateAPIKey is authenticate for an API key. The caller gets the
+// claims a token of the key's owner would carry.
+func (s *Server) authenticateAPIKey(ctx context.Context, rawKey string) (context.Context, error) {
+	if s.authService == nil || s.apiKeyService == nil {
+		return ctx, errAuthenticationRequired
+	}
+	apiKey, err := s.apiKeyService.ValidateAPIKey(ctx, strings.TrimPrefix(rawKey, "immich_"))
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	userID := uuid.UUID(apiKey.UserId.Bytes)
+	claims := &auth.Claims{UserID: userID.String()}
+	userInfo, err := s.authService.LoadUserInfo(ctx, claims)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	claims.Email = userInfo.Email
+	claims.IsAdmin = userInfo.IsAdmin
+
+	ctx = auth.WithClaims(ctx, claims)
+	ctx = auth.SetUserIDInContext(ctx, userID)
+	return auth.WithUser(ctx, *userInfo), nil
+}
+
+// authenticateMetadata authenticates a gRPC call from its authorization or
+// x-api-key metadata. Public methods, and shared link key methods called with
+// a key, go through unauthenticated when the caller sent no usable
+// credentials.
+func (s *Server) authenticateMetadata(ctx context.Context, fullMethod string, req any) (context.Context, error) {
+	token, err := auth.BearerTokenFromGRPCMetadata(ctx)
+	if err == nil {
+		var authCtx context.Context
+		authCtx, err = s.authenticate(ctx, token)
+		if err == nil {
+			return authCtx, nil
+		}
+	} else if values := metadata.ValueFromIncomingContext(ctx, apiKeyHeader); len(values) > 0 && values[0] != "" {
+		var authCtx context.Context
+		authCtx, err = s.authenticateAPIKey(ctx, values[0])
+		if err == nil {
+			return authCtx, nil
+		}
+	}
+	if publicMethods[fullMethod] {
+		return ctx, nil
+	}
+	if keyed, ok := req.(interface{ GetKey() string }); ok && sharedLinkKeyMethods[fullMethod] && keyed.GetKey() != "" {
+		return ctx, nil
+	}
+	return ctx, err
+}
+
+func (s *Server) unaryAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticateMetadata(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuthInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticateMetadata(stream.Context(), info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream carries the authenticated context to stream handlers.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// requireGatewayAuth rejects gateway requests for non-public RPCs that
+// authContextMiddleware could not authenticate. The gateway calls handlers
+// directly, bypassing the gRPC interceptors, so the route is mapped back to
+// its RPC to apply the same allowlist; a route that cannot be mapped is
+// rejected rather than let through. Shared link keys are sent as the key
+// query parameter. mux is read per request because the middleware is
+// installed while the mux is being built.
+func requireGatewayAuth(mux func() *runtime.ServeMux) runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			if _, ok := auth.AuthenticatedUserID(r.Context()); ok {
+				next(w, r, pathParams)
+				return
+			}
+			method, ok := gatewayRPCForRequest(r.Method, r.URL.Path)
+			if ok && (publicMethods[method] || (sharedLinkKeyMethods[method] && r.URL.Query().Get("key") != "")) {
+				next(w, r, pathParams)
+				return
+			}
+			_, outbound := runtime.MarshalerForRequest(mux(), r)
+			runtime.HTTPError(r.Context(), mux(), outbound, w, r, errAuthenticationRequired)
+		}
+	}
+}
+
+// gatewayRoute is the HTTP binding of one RPC, split into path segments.
+// Variable segments such as {asset_id} are stored as "*".
+type gatewayRoute struct {
+	verb     string
+	segments []string
+	method   string
+}
+
+var gatewayRoutes = sync.OnceValue(func() []gatewayRoute {
+	var routes []gatewayRoute
+	protoregistry.GlobalFiles.RangeFilesByPackage("immich.v1", func(file protoreflect.FileDescriptor) bool {
+		services := file.Services()
+		for i := range services.Len() {
+			service := services.Get(i)
+			methods := service.Methods()
+			for j := range methods.Len() {
+				method := methods.Get(j)
+				rule, ok := proto.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule)
+				if !ok || rule == nil {
+					continue
+				}
+				fullMethod := "/" + string(service.FullName()) + "/" + string(method.Name())
+				for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+					if route, ok := newGatewayRoute(binding, fullMethod); ok {
+						routes = append(routes, route)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return routes
+})
+
+func newGatewayRoute(rule *annotations.HttpRule, method string) (gatewayRoute, bool) {
+	var verb, path string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		verb, path = http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		verb, path = http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		verb, path = http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		verb, path = http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		verb, path = http.MethodPatch, pattern.Patch
+	default:
+		return gatewayRoute{}, false
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			segments[i] = "*"
+		}
+	}
+	return gatewayRoute{verb: verb, segments: segments, method: method}, true
+}
+
+// gatewayRPCForRequest returns the RPC a gateway request is routed to. When
+// several bindings match, the one with the most literal segments wins, so
+// /api/assets/locked resolves to GetLockedAssets rather than GetAsset.
+func gatewayRPCForRequest(verb, path string) (string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	best, bestLiterals := "", -1
+	for _, route := range gatewayRoutes() {
+		if route.verb != verb || len(route.segments) != len(segments) {
+			continue
+		}
+		literals := 0
+		matched := true
+		for i, segment := range route.segments {
+			if segment == "*" {
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+			literals++
+		}
+		if matched && literals > bestLiterals {
+			best, bestLiterals = route.method, literals
+		}
+	}
+	return best, bestLiterals >= 0
+}
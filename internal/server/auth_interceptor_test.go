@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/denysvitali/immich-go-backend/internal/apikeys"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestUnaryAuthInterceptorRejectsAnonymousCalls(t *testing.T) {
+	srv := &Server{authService: auth.NewService(config.AuthConfig{
+		JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+	}, nil)}
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := srv.unaryAuthInterceptor(context.Background(), nil,
+		&grpc.UnaryServerInfo{FullMethod: immichv1.AssetService_GetAssets_FullMethodName}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer invalid-token"))
+	_, err = srv.unaryAuthInterceptor(ctx, nil,
+		&grpc.UnaryServerInfo{FullMethod: immichv1.AssetService_GetAssets_FullMethodName}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+
+	_, err = srv.unaryAuthInterceptor(ctx, nil,
+		&grpc.UnaryServerInfo{FullMethod: immichv1.ServerService_PingServer_FullMethodName}, handler)
+	require.NoError(t, err)
+	assert.True(t, called, "public methods accept anonymous callers")
+}
+
+func TestUnaryAuthInterceptorSetsUserID(t *testing.T) {
+	authService := auth.NewService(config.AuthConfig{
+		JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+	}, newNoUserQueries())
+	srv := &Server{authService: authService}
+	userID := uuid.New()
+	token, err := authService.GenerateToken(userID.String(), "user@example.com", time.Hour)
+	require.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	_, err = srv.unaryAuthInterceptor(ctx, nil,
+		&grpc.UnaryServerInfo{FullMethod: immichv1.AssetService_GetAssets_FullMethodName},
+		func(ctx context.Context, req any) (any, error) {
+			got, err := srv.getUserIDFromContext(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, userID, got)
+			return nil, nil
+		})
+	require.NoError(t, err)
+}
+
+func TestUnaryAuthInterceptorRejectsInvalidAPIKey(t *testing.T) {
+	srv := &Server{
+		authService: auth.NewService(config.AuthConfig{
+			JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+		}, newNoUserQueries()),
+		apiKeyService: apikeys.NewService(newNoUserQueries()),
+	}
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "immich_not-a-key"))
+	_, err := srv.unaryAuthInterceptor(ctx, nil,
+		&grpc.UnaryServerInfo{FullMethod: immichv1.ApiKeyService_GetMyApiKey_FullMethodName}, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.False(t, called)
+}
+
+func TestUnaryAuthInterceptorAcceptsSharedLinkKeys(t *testing.T) {
+	srv := &Server{authService: auth.NewService(config.AuthConfig{
+		JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+	}, nil)}
+	call := func(method string, req any) (bool, error) {
+		called := false
+		_, err := srv.unaryAuthInterceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			})
+		return called, err
+	}
+
+	called, err := call(immichv1.AssetService_GetAsset_FullMethodName, &immichv1.GetAssetRequest{Key: proto.String("link-key")})
+	require.NoError(t, err)
+	assert.True(t, called, "the handler checks the key")
+	called, err = call(immichv1.AssetService_DownloadAsset_FullMethodName, &immichv1.DownloadAssetRequest{Key: proto.String("link-key")})
+	require.NoError(t, err)
+	assert.True(t, called)
+
+	_, err = call(immichv1.AssetService_GetAsset_FullMethodName, &immichv1.GetAssetRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = call(immichv1.AssetService_UpdateAsset_FullMethodName, &immichv1.UpdateAssetRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "only methods that check keys accept them")
+}
+
+func TestRequireGatewayAuthAcceptsSharedLinkKeys(t *testing.T) {
+	mux := runtime.NewServeMux()
+	serve := func(target string) (bool, int) {
+		called := false
+		rec := httptest.NewRecorder()
+		requireGatewayAuth(func() *runtime.ServeMux { return mux })(
+			func(http.ResponseWriter, *http.Request, map[string]string) { called = true },
+		)(rec, httptest.NewRequest(http.MethodGet, target, nil), nil)
+		return called, rec.Code
+	}
+
+	called, _ := serve("/api/assets/" + uuid.NewString() + "?key=link-key")
+	assert.True(t, called)
+	called, code := serve("/api/assets/" + uuid.NewString())
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	called, _ = serve("/api/assets?key=link-key")
+	assert.False(t, called, "only methods that check keys accept them")
+}
+
+func TestRequireGatewayAuthRejectsUnmappedRoutes(t *testing.T) {
+	mux := runtime.NewServeMux()
+	called := false
+	rec := httptest.NewRecorder()
+	requireGatewayAuth(func() *runtime.ServeMux { return mux })(
+		func(http.ResponseWriter, *http.Request, map[string]string) { called = true },
+	)(rec, httptest.NewRequest(http.MethodGet, "/api/not/a/route/at/all?key=link-key", nil), nil)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestGatewayRPCForRequest(t *testing.T) {
+	tests := []struct {
+		verb, path string
+		want       string
+	}{
+		{http.MethodGet, "/api/server/ping", immichv1.ServerService_PingServer_FullMethodName},
+		{http.MethodGet, "/api/assets/locked", immichv1.AssetService_GetLockedAssets_FullMethodName},
+		{http.MethodGet, "/api/assets/" + uuid.NewString(), immichv1.AssetService_GetAsset_FullMethodName},
+		{http.MethodPost, "/api/auth/login", immichv1.AuthService_Login_FullMethodName},
+	}
+	for _, tt := range tests {
+		got, ok := gatewayRPCForRequest(tt.verb, tt.path)
+		require.True(t, ok, tt.path)
+		assert.Equal(t, tt.want, got, tt.path)
+	}
+
+	_, ok := gatewayRPCForRequest(http.MethodGet, "/api/not/a/route/at/all")
+	assert.False(t, ok)
+}
+
+func TestHTTPHandlerRequiresAuthentication(t *testing.T) {
+	srv := &Server{
+		config: &config.Config{},
+		authService: auth.NewService(config.AuthConfig{
+			JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+		}, nil),
+	}
+	handler := srv.HTTPHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/assets", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/assets", nil)
+	req.Header.Set("Authorization", "Bearer invalid-token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// noUserDB is a database in which no user exists, for tests that only need
+// token validation to succeed.
+type noUserDB struct{}
+
+func (noUserDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, pgx.ErrNoRows
+}
+
+func (noUserDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (noUserDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return noUserRow{}
+}
+
+type noUserRow struct{}
+
+func (noUserRow) Scan(...any) error {
+	return pgx.ErrNoRows
+}
+
+func newNoUserQueries() *sqlc.Queries {
+	return sqlc.New(noUserDB{})
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+// zstdWindowSize is the largest window browsers decode zstd content with.
+const zstdWindowSize = 8 << 20
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+	zstdWriters = sync.Pool{New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(zstdWindowSize))
+		return w
+	}}
+)
+
+// compressionHandler compresses responses with the first of the configured
+// encodings the client accepts. Only textual content types are compressed,
+// so thumbnails, originals and archives are sent as they are, and so are
+// responses smaller than the minimum size, Range requests and WebSocket
+// upgrades.
+func compressionHandler(cfg config.CompressionConfig, next http.Handler) http.Handler {
+	if !cfg.Enabled || len(cfg.Encodings) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings),
+			minSize:        cfg.MinSize,
+			head:           r.Method == http.MethodHead,
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the encoding of encodings the Accept-Encoding
+// header gives the highest preference, the first one on a tie, or "" when
+// the client accepts none of them.
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressibleType reports whether responses of contentType are worth
+// compressing: text, JSON, JavaScript, XML and SVG.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "application/wasm":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds back the start of a response until it knows whether
+// to compress it: once minSize bytes were written, the response is flushed
+// or the handler returns.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	head     bool
+
+	status  int
+	buf     []byte
+	started bool
+	encoder io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.started || cw.status != 0 {
+		return
+	}
+	if status >= 100 && status < 200 {
+		// Informational responses go out right away
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.started {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) == 0 || len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.start(false); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// start sends the headers, compressing the response when it is compressible
+// and either streamed or at least minSize bytes long, and then writes what
+// was held back.
+func (cw *compressWriter) start(streamed bool) error {
+	cw.started = true
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	compressible := cw.status != http.StatusNoContent && cw.status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" && compressibleType(header.Get("Content-Type"))
+	if compressible {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if compressible && cw.encoding != "" && !cw.head && (streamed || len(cw.buf) > 0 && len(cw.buf) >= cw.minSize) {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// The compressed body is a different representation
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.encoder = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what was written so far, so streamed responses such as the
+// sync stream are compressed as they go.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.start(true); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) close() {
+	if !cw.started && (cw.status != 0 || len(cw.buf) > 0) {
+		_ = cw.start(false)
+	}
+	if cw.encoder == nil {
+		return
+	}
+	_ = cw.encoder.Close()
+	releaseEncoder(cw.encoder)
+	cw.encoder = nil
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "zstd":
+		encoder := zstdWriters.Get().(*zstd.Encoder)
+		encoder.Reset(w)
+		return encoder
+	default:
+		encoder := gzipWriters.Get().(*gzip.Writer)
+		encoder.Reset(w)
+		return encoder
+	}
+}
+
+func releaseEncoder(encoder io.WriteCloser) {
+	switch encoder := encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		zstdWriters.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		gzipWriters.Put(encoder)
+	}
+}
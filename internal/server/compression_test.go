@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	encodings := []string{"zstd", "gzip"}
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"gzip;q=1.0, zstd;q=0.5", "gzip"},
+		{"GZIP", "gzip"},
+		{"zstd;q=0, gzip", "gzip"},
+		{"*", "zstd"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"br", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateEncoding(tt.acceptEncoding, encodings), tt.acceptEncoding)
+	}
+	assert.Equal(t, "gzip", negotiateEncoding("zstd, gzip", []string{"gzip"}))
+}
+
+func TestCompressibleType(t *testing.T) {
+	for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "text/html", "application/problem+json", "image/svg+xml", "application/javascript"} {
+		assert.True(t, compressibleType(contentType), contentType)
+	}
+	for _, contentType := range []string{"", "image/jpeg", "image/webp", "video/mp4", "application/zip", "application/octet-stream"} {
+		assert.False(t, compressibleType(contentType), contentType)
+	}
+}
+
+func TestCompressionHandler(t *testing.T) {
+	body := strings.Repeat(`{"id":"asset","type":"IMAGE"},`, 200)
+	cfg := config.CompressionConfig{Enabled: true, Encodings: []string{"zstd", "gzip"}, MinSize: 1024}
+	handler := compressionHandler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, body[:len(body)/2])
+			_, _ = io.WriteString(w, body[len(body)/2:])
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"ok":true}`)
+		case "/thumbnail":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = io.WriteString(w, body)
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, body)
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/json", "gzip")
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Equal(t, `W/"v1"`, rec.Header().Get("ETag"))
+	assert.Less(t, rec.Body.Len(), len(body))
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	rec = get("/json", "gzip, zstd")
+	assert.Equal(t, "zstd", rec.Header().Get("Content-Encoding"))
+	decoder, err := zstd.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err = io.ReadAll(decoder)
+	decoder.Close()
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	rec = get("/json", "")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"), "caches keep the variants apart")
+	assert.Equal(t, body, rec.Body.String())
+
+	rec = get("/small", "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"), "small responses are sent as they are")
+	assert.Equal(t, `{"ok":true}`, rec.Body.String())
+
+	rec = get("/thumbnail", "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"), "images are not compressed again")
+	assert.Empty(t, rec.Header().Get("Vary"))
+	assert.Equal(t, body, rec.Body.String())
+
+	rec = get("/created", "gzip")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), "the sniffed content type is text")
+
+	rec = get("/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Zero(t, rec.Body.Len())
+
+	req := httptest.NewRequest(http.MethodGet, "/json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"), "ranges refer to the uncompressed body")
+
+	disabled := compressionHandler(config.CompressionConfig{Enabled: false, Encodings: []string{"gzip"}}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	disabled.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestCompressionHandler_Flush(t *testing.T) {
+	cfg := config.CompressionConfig{Enabled: true, Encodings: []string{"gzip"}, MinSize: 1024}
+	flushed := make(chan []byte, 1)
+	handler := compressionHandler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson+json")
+		_, _ = io.WriteString(w, `{"type":"AssetV1"}`+"\n")
+		w.(http.Flusher).Flush()
+		flushed <- bytes.Clone(w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder).Body.Bytes())
+		_, _ = io.WriteString(w, `{"type":"SyncAckV1"}`+"\n")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), "streamed responses are compressed whatever their size")
+	reader, err := gzip.NewReader(bytes.NewReader(<-flushed))
+	require.NoError(t, err)
+	line := make([]byte, len(`{"type":"AssetV1"}`+"\n"))
+	_, err = io.ReadFull(reader, line)
+	require.NoError(t, err, "what was flushed can be decoded before the response ends")
+	assert.Equal(t, `{"type":"AssetV1"}`+"\n", string(line))
+
+	reader, err = gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"AssetV1"}`+"\n"+`{"type":"SyncAckV1"}`+"\n", string(decoded))
+}
+
+func TestGRPCAcceptsGzip(t *testing.T) {
+	_, conn := serveGRPCHealth(t, config.ServerConfig{GRPCHealthEnabled: true})
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.UseCompressor(grpcgzip.Name))
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
@@ -49,7 +49,9 @@ func (s *Server) buildDownloadRequest(ctx context.Context, assetIDs []string, al
 		}
 
 		ownedAssets, err := s.db.GetUserAssets(ctx, sqlc.GetUserAssetsParams{
-			OwnerId: ownerUUID,
+			OwnerId:    ownerUUID,
+			SortBy:     "date_taken",
+			Descending: true,
 		})
 		if err != nil {
 			return nil, SanitizedInternal(ctx, "failed to list user assets", err)
@@ -70,16 +72,11 @@ func (s *Server) buildDownloadRequest(ctx context.Context, assetIDs []string, al
 // GetDownloadInfo returns size information and the resolved set of asset IDs
 // for a potential download (POST /api/download/info).
 func (s *Server) GetDownloadInfo(ctx context.Context, request *immichv1.DownloadInfoRequest) (*immichv1.DownloadInfoResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	req, err := s.buildDownloadRequest(ctx, request.GetAssetIds(), request.AlbumId, request.UserId)
 	if err != nil {
 		return nil, err
@@ -104,16 +101,11 @@ func (s *Server) GetDownloadInfo(ctx context.Context, request *immichv1.Download
 // bytes of each asset are fetched separately via the asset download
 // endpoints.
 func (s *Server) DownloadArchive(ctx context.Context, request *immichv1.DownloadArchiveRequest) (*immichv1.DownloadResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := pgutil.ParseUserID(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	req, err := s.buildDownloadRequest(ctx, request.GetAssetIds(), request.AlbumId, request.UserId)
 	if err != nil {
 		return nil, err
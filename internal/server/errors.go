@@ -1,6 +1,13 @@
 package server
 
 import (
+	"errors"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 )
 
@@ -26,3 +33,74 @@ var SanitizedInternal = grpcutil.SanitizedInternal
 // span as errored (when recording) but never leaks err into the public
 // message and does not log.
 var PublicError = grpcutil.PublicError
+
+// reasonAssetOffline marks requests for the original of an asset whose
+// external library file disappeared.
+const reasonAssetOffline = "asset_offline"
+
+// reasonOriginalMissing and reasonStorageUnavailable mark media requests
+// that failed because the original of an asset could not be read: it is
+// gone from storage, or storage failed.
+const (
+	reasonOriginalMissing    = "original_missing"
+	reasonStorageUnavailable = "storage_unavailable"
+)
+
+// reasonHTTPStatus overrides the HTTP status of errors carrying one of these
+// reasons, for responses no gRPC code maps to.
+var reasonHTTPStatus = map[string]int{
+	reasonAssetOffline: http.StatusGone,
+}
+
+// immichError is the error body Immich clients expect from every endpoint,
+// e.g. {"message": "Asset not found", "statusCode": 404, "error": "Not Found"}.
+// Error is the reason attached with grpcutil.WithReason when there is one,
+// so clients can tell a failed login from a missing asset without parsing
+// messages, and the HTTP status text otherwise. For 400s carrying field
+// violations, Message is the list of per-field messages instead, matching
+// the upstream validation responses.
+type immichError struct {
+	Message    any    `json:"message"`
+	StatusCode int    `json:"statusCode"`
+	Error      string `json:"error"`
+}
+
+// immichErrorResponse converts err into the HTTP status code and Immich
+// error body. Errors that are not gRPC status errors become a generic 500,
+// so their details never reach the client.
+func immichErrorResponse(err error) (int, immichError) {
+	statusCode := 0
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		statusCode = httpErr.HTTPStatus
+		err = httpErr.Err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		st = status.New(codes.Internal, "internal server error")
+	}
+	if statusCode == 0 {
+		statusCode = reasonHTTPStatus[grpcutil.Reason(st)]
+	}
+	if statusCode == 0 {
+		statusCode = runtime.HTTPStatusFromCode(st.Code())
+	}
+
+	body := immichError{
+		Message:    st.Message(),
+		StatusCode: statusCode,
+		Error:      grpcutil.Reason(st),
+	}
+	if body.Error == "" {
+		body.Error = http.StatusText(statusCode)
+	}
+	if violations := grpcutil.FieldViolations(st); len(violations) > 0 {
+		messages := make([]string, len(violations))
+		for i, v := range violations {
+			messages[i] = v.Description
+		}
+		body.Message = messages
+	}
+	return statusCode, body
+}
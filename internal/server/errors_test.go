@@ -3,12 +3,17 @@ package server
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
 	"testing"
 
 	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
+	"github.com/denysvitali/immich-go-backend/internal/users"
 )
 
 func TestSanitizedInternal_DoesNotLeakErrorMessage(t *testing.T) {
@@ -86,3 +91,86 @@ func TestPublicError_RoundTrip(t *testing.T) {
 		t.Fatalf("message mismatch: got %q, want %q", st.Message(), "user not found")
 	}
 }
+
+func TestImmichErrorResponse_UsesStatusTextWithoutReason(t *testing.T) {
+	code, body := immichErrorResponse(status.Error(codes.NotFound, "Asset not found"))
+
+	if code != http.StatusNotFound {
+		t.Fatalf("status code: got %d, want %d", code, http.StatusNotFound)
+	}
+	want := immichError{Message: "Asset not found", StatusCode: http.StatusNotFound, Error: "Not Found"}
+	if body != want {
+		t.Fatalf("body: got %+v, want %+v", body, want)
+	}
+}
+
+func TestImmichErrorResponse_UsesAuthErrorTypeAsError(t *testing.T) {
+	grpcErr, ok := publicAuthError(context.Background(), auth.NewInvalidCredentialsError("Incorrect email or password"), codes.OK)
+	if !ok {
+		t.Fatal("expected a public auth error")
+	}
+
+	code, body := immichErrorResponse(grpcErr)
+
+	if code != http.StatusUnauthorized {
+		t.Fatalf("status code: got %d, want %d", code, http.StatusUnauthorized)
+	}
+	if body.Error != "invalid_credentials" {
+		t.Fatalf("error: got %q, want %q", body.Error, "invalid_credentials")
+	}
+	if body.Message != "Incorrect email or password" {
+		t.Fatalf("message: got %v", body.Message)
+	}
+}
+
+func TestImmichErrorResponse_UsesUserErrorTypeAsError(t *testing.T) {
+	grpcErr, ok := publicUserError(context.Background(), users.NewUserNotFoundError("User not found"))
+	if !ok {
+		t.Fatal("expected a public user error")
+	}
+
+	code, body := immichErrorResponse(grpcErr)
+
+	if code != http.StatusNotFound || body.Error != "user_not_found" {
+		t.Fatalf("got %d %q, want 404 user_not_found", code, body.Error)
+	}
+}
+
+func TestImmichErrorResponse_ListsFieldViolations(t *testing.T) {
+	err := grpcutil.InvalidFields(context.Background(), "invalid login request",
+		grpcutil.FieldViolation{Field: "email", Description: "email should not be empty"},
+		grpcutil.FieldViolation{Field: "password", Description: "password should not be empty"},
+	)
+
+	code, body := immichErrorResponse(err)
+
+	if code != http.StatusBadRequest || body.Error != "Bad Request" {
+		t.Fatalf("got %d %q, want 400 Bad Request", code, body.Error)
+	}
+	messages, ok := body.Message.([]string)
+	if !ok || len(messages) != 2 || messages[0] != "email should not be empty" {
+		t.Fatalf("message: got %#v", body.Message)
+	}
+}
+
+func TestImmichErrorResponse_OfflineAssetIsGone(t *testing.T) {
+	code, body := immichErrorResponse(assetOfflineError(context.Background()))
+
+	if code != http.StatusGone || body.Error != "asset_offline" {
+		t.Fatalf("got %d %q, want 410 asset_offline", code, body.Error)
+	}
+}
+
+func TestImmichErrorResponse_HidesNonStatusErrors(t *testing.T) {
+	code, body := immichErrorResponse(errors.New("pq: connection refused"))
+
+	if code != http.StatusInternalServerError || body.Message != "internal server error" {
+		t.Fatalf("got %d %v, want a generic 500", code, body.Message)
+	}
+}
+
+func TestUserOperationalErrorIsNotPublic(t *testing.T) {
+	if _, ok := publicUserError(context.Background(), users.NewDatabaseError("failed", errors.New("db down"))); ok {
+		t.Fatal("database errors must be sanitized by the caller")
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+// registerGRPCHealth adds the standard gRPC health service and, when enabled,
+// server reflection to srv. It must run after every other service is
+// registered: each of them, and the server as a whole (the empty service
+// name), is reported as SERVING. The returned health server is nil when the
+// health service is disabled.
+func registerGRPCHealth(srv *grpc.Server, cfg config.ServerConfig) *health.Server {
+	var healthServer *health.Server
+	if cfg.GRPCHealthEnabled {
+		healthServer = health.NewServer()
+		for name := range srv.GetServiceInfo() {
+			healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+		}
+		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		healthpb.RegisterHealthServer(srv, healthServer)
+	}
+	if cfg.GRPCReflection {
+		reflection.Register(srv)
+	}
+	return healthServer
+}
+
+// markNotServing reports every service as NOT_SERVING, so load balancers
+// stop sending new calls while in-flight ones finish.
+func (s *Server) markNotServing() {
+	if s.grpcHealth != nil {
+		s.grpcHealth.Shutdown()
+	}
+}
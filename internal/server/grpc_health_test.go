@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func serveGRPCHealth(t *testing.T, cfg config.ServerConfig) (*Server, *grpc.ClientConn) {
+	t.Helper()
+
+	s := &Server{}
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuthInterceptor),
+		grpc.ChainStreamInterceptor(s.streamAuthInterceptor),
+	)
+	immichv1.RegisterServerServiceServer(s.grpcServer, s)
+	s.grpcHealth = registerGRPCHealth(s.grpcServer, cfg)
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = s.grpcServer.Serve(listener) }()
+	t.Cleanup(s.grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return s, conn
+}
+
+func TestGRPCHealthReportsServicesUntilShutdown(t *testing.T) {
+	s, conn := serveGRPCHealth(t, config.ServerConfig{GRPCHealthEnabled: true})
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+
+	for _, service := range []string{"", immichv1.ServerService_ServiceDesc.ServiceName} {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err, "health checks need no credentials")
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus(), service)
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "immich.v1.Unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.NoError(t, s.Shutdown(ctx))
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+}
+
+func TestGRPCReflectionIsOptIn(t *testing.T) {
+	listServices := func(conn *grpc.ClientConn) error {
+		stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+		}); err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	_, conn := serveGRPCHealth(t, config.ServerConfig{})
+	assert.Equal(t, codes.Unimplemented, status.Code(listServices(conn)))
+	_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, conn = serveGRPCHealth(t, config.ServerConfig{GRPCReflection: true})
+	assert.NoError(t, listServices(conn))
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/admin"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/users"
+)
+
+// The password link of the welcome email opens a form to choose a password;
+// like the restore link, only the form submission uses up the token.
+
+var passwordSetupTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Heading}}</title>
+</head>
+<body>
+<h1>{{.Heading}}</h1>
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+{{- if .Requirements}}
+<ul>
+{{- range .Requirements}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Token}}
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<p><label>New password <input type="password" name="password" autocomplete="new-password"{{if .MinLength}} minlength="{{.MinLength}}"{{end}} required></label></p>
+<p><label>Confirm password <input type="password" name="confirm" autocomplete="new-password"{{if .MinLength}} minlength="{{.MinLength}}"{{end}} required></label></p>
+<button type="submit">Set password</button>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+type passwordSetupPage struct {
+	Heading      string
+	Message      string
+	Requirements []string
+	Action       string
+	Token        string
+	MinLength    int
+}
+
+// passwordSetupForm is the page asking for a password with token. It lists
+// the requirements of the password policy, or only those a refused password
+// did not meet.
+func (s *Server) passwordSetupForm(token string, policyErr *auth.PasswordPolicyError) passwordSetupPage {
+	page := passwordSetupPage{
+		Heading: "Choose your password",
+		Action:  admin.PasswordSetupPath,
+		Token:   token,
+	}
+	if s.config != nil {
+		page.MinLength = s.config.Auth.PasswordMinLength
+	}
+	switch {
+	case policyErr != nil:
+		page.Message = "The password must:"
+		for _, req := range policyErr.Requirements {
+			if !req.Satisfied {
+				page.Requirements = append(page.Requirements, req.Description)
+			}
+		}
+	case s.authService != nil:
+		page.Message = "Your password must:"
+		for _, req := range s.authService.PasswordPolicy() {
+			page.Requirements = append(page.Requirements, req.Description)
+		}
+	}
+	return page
+}
+
+// passwordSetupHandler serves the password setup page ahead of the web UI,
+// passing every other request to next.
+func (s *Server) passwordSetupHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != admin.PasswordSetupPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				writePasswordSetupPage(w, http.StatusBadRequest, passwordSetupPage{
+					Heading: "Invalid link",
+					Message: "This password link is incomplete.",
+				})
+				return
+			}
+			writePasswordSetupPage(w, http.StatusOK, s.passwordSetupForm(token, nil))
+		case http.MethodPost:
+			s.handlePasswordSetup(w, r)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (s *Server) handlePasswordSetup(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if token == "" || s.userService == nil {
+		writePasswordSetupPage(w, http.StatusBadRequest, passwordSetupPage{
+			Heading: "Invalid link",
+			Message: "This password link is incomplete.",
+		})
+		return
+	}
+	if password := r.PostFormValue("password"); password != r.PostFormValue("confirm") {
+		page := s.passwordSetupForm(token, nil)
+		page.Message, page.Requirements = "The passwords do not match.", nil
+		writePasswordSetupPage(w, http.StatusBadRequest, page)
+		return
+	}
+
+	user, err := s.userService.SetPasswordWithToken(r.Context(), token, r.PostFormValue("password"))
+	var userErr *users.UserError
+	var policyErr *auth.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		writePasswordSetupPage(w, http.StatusBadRequest, s.passwordSetupForm(token, policyErr))
+		return
+	case errors.As(err, &userErr) && userErr.Type == users.ErrInvalidPassword:
+		page := s.passwordSetupForm(token, nil)
+		page.Message, page.Requirements = "The password does not meet the requirements.", nil
+		writePasswordSetupPage(w, http.StatusBadRequest, page)
+		return
+	case errors.As(err, &userErr) && userErr.Type == users.ErrUserNotFound:
+		writePasswordSetupPage(w, http.StatusNotFound, passwordSetupPage{
+			Heading: "Password not set",
+			Message: userErr.Message + ".",
+		})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to set password from a password link")
+		writePasswordSetupPage(w, http.StatusInternalServerError, passwordSetupPage{
+			Heading: "Something went wrong",
+			Message: "Your password could not be set. Please try again later.",
+		})
+		return
+	}
+
+	logrus.WithField("user_id", user.ID).Info("Password set from the welcome email")
+	writePasswordSetupPage(w, http.StatusOK, passwordSetupPage{
+		Heading: "Password set",
+		Message: "Your password has been set. You can sign in now.",
+	})
+}
+
+func writePasswordSetupPage(w http.ResponseWriter, status int, page passwordSetupPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := passwordSetupTemplate.Execute(w, page); err != nil {
+		logrus.WithError(err).Error("Failed to render password setup page")
+	}
+}
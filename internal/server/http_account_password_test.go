@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+func TestPasswordSetupHandlerShowsForm(t *testing.T) {
+	s := &Server{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := s.passwordSetupHandler(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account/set-password?token=a%22b", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<form method="post" action="/account/set-password">`)
+	assert.Contains(t, rec.Body.String(), `value="a&#34;b"`)
+	assert.Contains(t, rec.Body.String(), `name="confirm"`)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account/set-password", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "<form")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account", nil))
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestPasswordSetupFormListsPolicy(t *testing.T) {
+	cfg := &config.Config{Auth: config.AuthConfig{
+		PasswordMinLength:      12,
+		PasswordRequireSymbols: true,
+	}}
+	s := &Server{config: cfg, authService: auth.NewService(cfg.Auth, nil)}
+
+	rec := httptest.NewRecorder()
+	s.passwordSetupHandler(http.NotFoundHandler()).ServeHTTP(rec,
+		httptest.NewRequest(http.MethodGet, "/account/set-password?token=abc", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `minlength="12"`)
+	assert.Contains(t, body, "<li>be at least 12 characters long</li>")
+	assert.Contains(t, body, "<li>contain at least one symbol</li>")
+	assert.NotContains(t, body, "8 characters")
+
+	page := s.passwordSetupForm("abc", &auth.PasswordPolicyError{
+		Requirements: s.authService.CheckPassword("long enough password"),
+	})
+	assert.Equal(t, []string{"contain at least one symbol"}, page.Requirements)
+}
+
+func TestPasswordSetupHandlerRequiresToken(t *testing.T) {
+	s := &Server{}
+	handler := s.passwordSetupHandler(http.NotFoundHandler())
+
+	form := url.Values{"password": {"a-new-password"}, "confirm": {"a-new-password"}}
+	req := httptest.NewRequest(http.MethodPost, "/account/set-password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/account/set-password", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
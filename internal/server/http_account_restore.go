@@ -0,0 +1,126 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/admin"
+	"github.com/denysvitali/immich-go-backend/internal/users"
+)
+
+// The restore link of an account scheduled for deletion opens a page asking
+// to confirm; only the form submission restores the account, so link
+// scanners and prefetching mail clients cannot restore it by opening the
+// link.
+
+var accountRestoreTemplate = template.Must(template.New("restore").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Heading}}</title>
+</head>
+<body>
+<h1>{{.Heading}}</h1>
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+{{- if .Token}}
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">Restore account</button>
+</form>
+{{- end}}
+</body>
+</html>
+`))
+
+type accountRestorePage struct {
+	Heading string
+	Message string
+	Action  string
+	Token   string
+}
+
+// accountRestoreHandler serves the account restore page ahead of the web
+// UI, passing every other request to next.
+func (s *Server) accountRestoreHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != admin.AccountRestorePath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				writeAccountRestorePage(w, http.StatusBadRequest, accountRestorePage{
+					Heading: "Invalid link",
+					Message: "This restore link is incomplete.",
+				})
+				return
+			}
+			writeAccountRestorePage(w, http.StatusOK, accountRestorePage{
+				Heading: "Restore your account",
+				Message: "Your account is scheduled for deletion. Restore it to keep your photos, videos and albums.",
+				Action:  admin.AccountRestorePath,
+				Token:   token,
+			})
+		case http.MethodPost:
+			s.handleAccountRestore(w, r)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (s *Server) handleAccountRestore(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if token == "" || s.userService == nil {
+		writeAccountRestorePage(w, http.StatusBadRequest, accountRestorePage{
+			Heading: "Invalid link",
+			Message: "This restore link is incomplete.",
+		})
+		return
+	}
+
+	user, err := s.userService.RestoreScheduledDeletion(r.Context(), token)
+	var userErr *users.UserError
+	switch {
+	case errors.As(err, &userErr) && userErr.Type == users.ErrUserNotFound:
+		writeAccountRestorePage(w, http.StatusNotFound, accountRestorePage{
+			Heading: "Account not restored",
+			Message: userErr.Message + ".",
+		})
+		return
+	case err != nil:
+		logrus.WithError(err).Error("Failed to restore account scheduled for deletion")
+		writeAccountRestorePage(w, http.StatusInternalServerError, accountRestorePage{
+			Heading: "Something went wrong",
+			Message: "Your account could not be restored. Please try again later.",
+		})
+		return
+	}
+
+	logrus.WithField("user_id", user.ID).Info("Account restored from its deletion email")
+	writeAccountRestorePage(w, http.StatusOK, accountRestorePage{
+		Heading: "Account restored",
+		Message: "Your account has been restored. You can sign in again.",
+	})
+}
+
+func writeAccountRestorePage(w http.ResponseWriter, status int, page accountRestorePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := accountRestoreTemplate.Execute(w, page); err != nil {
+		logrus.WithError(err).Error("Failed to render account restore page")
+	}
+}
@@ -13,6 +13,7 @@ type adminIntegritySummaryResponse struct {
 	ChecksumMismatch int64 `json:"checksumMismatch"`
 	MissingFile      int64 `json:"missingFile"`
 	UntrackedFile    int64 `json:"untrackedFile"`
+	MissingThumbnail int64 `json:"missingThumbnail"`
 }
 
 func adminIntegrityCSVTypeFromPath(path string) (string, bool) {
@@ -55,6 +56,7 @@ func (s *Server) handleAdminIntegritySummary(w http.ResponseWriter, r *http.Requ
 		ChecksumMismatch: resp.GetChecksumMismatch(),
 		MissingFile:      resp.GetMissingFile(),
 		UntrackedFile:    resp.GetUntrackedFile(),
+		MissingThumbnail: resp.GetMissingThumbnail(),
 	})
 }
 
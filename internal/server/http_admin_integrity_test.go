@@ -55,6 +55,7 @@ func TestAdminIntegritySummaryResponseEncodesNumericCounts(t *testing.T) {
 		ChecksumMismatch: 1,
 		MissingFile:      2,
 		UntrackedFile:    3,
+		MissingThumbnail: 4,
 	})
 
 	resp := w.Result()
@@ -62,5 +63,5 @@ func TestAdminIntegritySummaryResponseEncodesNumericCounts(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
-	assert.Equal(t, "{\"checksumMismatch\":1,\"missingFile\":2,\"untrackedFile\":3}\n", w.Body.String())
+	assert.Equal(t, "{\"checksumMismatch\":1,\"missingFile\":2,\"untrackedFile\":3,\"missingThumbnail\":4}\n", w.Body.String())
 }
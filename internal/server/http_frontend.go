@@ -16,8 +16,6 @@ import (
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sirupsen/logrus"
-	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -33,6 +31,8 @@ func (s *Server) handleFrontendShape(w http.ResponseWriter, r *http.Request) (ha
 		switch r.URL.Path {
 		case "/api/partners":
 			normalizePartnerDirectionQuery(r)
+		case "/api/asset-shares":
+			normalizeDirectionQuery(r, "ASSET_SHARE_DIRECTION_")
 		case "/system-metadata/version-check-state", "/api/system-metadata/version-check-state":
 			s.handleVersionCheckState(w, r)
 			return true
@@ -126,6 +126,13 @@ func (s *Server) handleFrontendShape(w http.ResponseWriter, r *http.Request) (ha
 			return true
 		}
 
+	case http.MethodHead:
+		// Download managers ask for the size and Range support first
+		if assetID, kind := assetMediaRouteFromPath(r.URL.Path); kind != assetMediaNone {
+			s.handleAssetMedia(w, r, assetID, kind)
+			return true
+		}
+
 	case http.MethodPut:
 		if r.URL.Path == "/api/system-config" {
 			s.handleSystemConfigPut(w, r)
@@ -161,6 +168,9 @@ func (s *Server) handleFrontendShape(w http.ResponseWriter, r *http.Request) (ha
 		case "/api/download/archive":
 			s.handleDownloadArchive(w, r)
 			return true
+		case "/api/users/me/export":
+			s.handleUserExport(w, r)
+			return true
 		case "/api/oauth/backchannel-logout":
 			s.handleOAuthBackchannelLogout(w, r)
 			return true
@@ -195,12 +205,18 @@ func partnerIDFromPath(path string) (string, bool) {
 // upstream web client. grpc-gateway otherwise only accepts protobuf enum
 // identifiers for this field.
 func normalizePartnerDirectionQuery(r *http.Request) {
+	normalizeDirectionQuery(r, "PARTNER_DIRECTION_")
+}
+
+// normalizeDirectionQuery rewrites a kebab-case direction query parameter
+// into the protobuf enum identifier starting with enumPrefix.
+func normalizeDirectionQuery(r *http.Request, enumPrefix string) {
 	query := r.URL.Query()
 	switch query.Get("direction") {
 	case "shared-by":
-		query.Set("direction", "PARTNER_DIRECTION_SHARED_BY")
+		query.Set("direction", enumPrefix+"SHARED_BY")
 	case "shared-with":
-		query.Set("direction", "PARTNER_DIRECTION_SHARED_WITH")
+		query.Set("direction", enumPrefix+"SHARED_WITH")
 	default:
 		return
 	}
@@ -230,11 +246,7 @@ func writeJSON(w http.ResponseWriter, status int, data any) {
 }
 
 func frontendProtoMarshaler() runtime.Marshaler {
-	return &runtime.JSONPb{
-		MarshalOptions: protojson.MarshalOptions{
-			EmitDefaultValues: true,
-		},
-	}
+	return gatewayMarshaler()
 }
 
 func writeProtoJSONArray[T proto.Message](w http.ResponseWriter, marshaler runtime.Marshaler, messages []T) {
@@ -251,13 +263,7 @@ func writeProtoJSONArray[T proto.Message](w http.ResponseWriter, marshaler runti
 }
 
 func writeGRPCErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
-	st, ok := status.FromError(err)
-	statusCode := http.StatusInternalServerError
-	message := "internal server error"
-	if ok {
-		statusCode = runtime.HTTPStatusFromCode(st.Code())
-		message = st.Message()
-	}
+	statusCode, body := immichErrorResponse(err)
 
 	entry := logrus.WithError(err).WithFields(logrus.Fields{
 		"method": r.Method,
@@ -271,11 +277,7 @@ func writeGRPCErrorJSON(w http.ResponseWriter, r *http.Request, err error) {
 		entry.Warn("Frontend compatibility request failed")
 	}
 
-	writeJSON(w, statusCode, map[string]any{
-		"error":      message,
-		"message":    message,
-		"statusCode": statusCode,
-	})
+	writeJSON(w, statusCode, body)
 }
 
 func (s *Server) frontendGatewayContext(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
@@ -409,11 +411,18 @@ func (s *Server) handleTimelineBuckets(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	descending, err := s.config.ListSorting().Descending(r.URL.Query().Get("order"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid order"})
+		return
+	}
+
 	opts := timeline.ListOptions{
 		UserID:     claims.UserID,
 		Bucket:     "day",
 		IsFavorite: parseBoolQuery(r, "isFavorite"),
 		IsTrashed:  parseBoolQuery(r, "isTrashed"),
+		Ascending:  !descending,
 	}
 
 	buckets, err := s.timelineService.GetTimeBuckets(r.Context(), opts)
@@ -449,6 +458,11 @@ func (s *Server) handleTimelineBucket(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid timeBucket"})
 		return
 	}
+	descending, err := s.config.ListSorting().Descending(r.URL.Query().Get("order"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid order"})
+		return
+	}
 
 	opts := timeline.ListOptions{
 		UserID:     claims.UserID,
@@ -457,6 +471,7 @@ func (s *Server) handleTimelineBucket(w http.ResponseWriter, r *http.Request) {
 		IsFavorite: parseBoolQuery(r, "isFavorite"),
 		IsTrashed:  parseBoolQuery(r, "isTrashed"),
 		Limit:      500,
+		Ascending:  !descending,
 	}
 
 	assets, err := s.timelineService.GetBucketAssets(r.Context(), opts)
@@ -584,41 +599,55 @@ func (s *Server) handleAlbums(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var albums []sqlc.Album
+	sortBy := r.URL.Query().Get("sortBy")
+	if sortBy == "" {
+		sortBy = "createdAt"
+	}
+	if !isAlbumSortField(sortBy) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid sortBy"})
+		return
+	}
+	var order *immichv1.AssetOrder
+	switch r.URL.Query().Get("order") {
+	case "":
+	case "asc":
+		order = immichv1.AssetOrder_ASC.Enum()
+	case "desc":
+		order = immichv1.AssetOrder_DESC.Enum()
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid order"})
+		return
+	}
+	params := sqlc.GetAlbumSummariesForUserParams{
+		UserID:     userUUID,
+		SortBy:     sortBy,
+		Descending: albumListDescending(sortBy, order, s.config.ListSorting().DescendingByDefault()),
+	}
 	if assetID := r.URL.Query().Get("assetId"); assetID != "" {
-		assetUUID, err := pgutil.StringToUUID(assetID)
+		params.AssetID, err = pgutil.StringToUUID(assetID)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid asset id"})
 			return
 		}
-		albums, err = s.db.GetAlbumsByAssetIdForUser(r.Context(), sqlc.GetAlbumsByAssetIdForUserParams{
-			AssetID: assetUUID,
-			UserID:  userUUID,
-		})
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
-			return
-		}
 	} else {
 		isShared := optionalBoolQuery(r, "isShared")
 		if isShared == nil {
 			// v2 web sends ?shared= instead of ?isShared=.
 			isShared = optionalBoolQuery(r, "shared")
 		}
-		albums, err = s.db.GetAlbumsForUser(r.Context(), sqlc.GetAlbumsForUserParams{
-			UserID:   userUUID,
-			IsOwned:  pgOptionalBool(optionalBoolQuery(r, "isOwned")),
-			IsShared: pgOptionalBool(isShared),
-		})
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
-			return
-		}
+		params.IsOwned = pgOptionalBool(optionalBoolQuery(r, "isOwned"))
+		params.IsShared = pgOptionalBool(isShared)
+	}
+
+	albums, members, err := s.albumSummaries(r.Context(), params)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+		return
 	}
 
 	resp := make([]map[string]any, len(albums))
 	for i, album := range albums {
-		resp[i] = s.frontendAlbumResponse(r.Context(), album)
+		resp[i] = frontendAlbumSummaryResponse(album, members[album.Album.ID])
 	}
 
 	writeJSON(w, http.StatusOK, resp)
@@ -632,26 +661,7 @@ func pgOptionalBool(v *bool) pgtype.Bool {
 }
 
 func (s *Server) frontendAlbumResponse(ctx context.Context, album sqlc.Album) map[string]any {
-	item := map[string]any{
-		"id":                    album.ID.String(),
-		"albumName":             album.AlbumName,
-		"description":           album.Description,
-		"ownerId":               album.OwnerId.String(),
-		"owner":                 nil,
-		"assetCount":            0,
-		"assets":                []any{},
-		"albumUsers":            []any{},
-		"sharedUsers":           []any{},
-		"shared":                false,
-		"hasSharedLink":         false,
-		"isActivityEnabled":     album.IsActivityEnabled,
-		"createdAt":             album.CreatedAt.Time.Format(time.RFC3339Nano),
-		"updatedAt":             album.UpdatedAt.Time.Format(time.RFC3339Nano),
-		"albumThumbnailAssetId": nil,
-		"order":                 album.Order,
-		"startDate":             nil,
-		"endDate":               nil,
-	}
+	item := frontendAlbumBase(album)
 	if album.AlbumThumbnailAssetId.Valid {
 		item["albumThumbnailAssetId"] = album.AlbumThumbnailAssetId.String()
 	}
@@ -668,15 +678,8 @@ func (s *Server) frontendAlbumResponse(ctx context.Context, album sqlc.Album) ma
 	}
 	if shared, err := s.db.GetAlbumSharedUsers(ctx, album.ID); err == nil {
 		for _, su := range shared {
-			role := su.Role
-			if role == "" {
-				role = "editor"
-			}
-			albumUsers = append(albumUsers, map[string]any{
-				"role": role,
-				"user": frontendAlbumUser(su.ID, su.Email, su.Name, su.ProfileImagePath,
-					su.AvatarColor, su.ProfileChangedAt, su.CreatedAt),
-			})
+			albumUsers = append(albumUsers, frontendAlbumMember(su.Role, frontendAlbumUser(su.ID, su.Email, su.Name,
+				su.ProfileImagePath, su.AvatarColor, su.ProfileChangedAt, su.CreatedAt)))
 		}
 		if len(shared) > 0 {
 			item["shared"] = true
@@ -691,6 +694,75 @@ func (s *Server) frontendAlbumResponse(ctx context.Context, album sqlc.Album) ma
 	return item
 }
 
+// frontendAlbumSummaryResponse is frontendAlbumResponse for album lists,
+// built from what GetAlbumSummariesForUser and the batched members query
+// return instead of querying each album.
+func frontendAlbumSummaryResponse(row sqlc.GetAlbumSummariesForUserRow, members []sqlc.GetAlbumSharedUsersByAlbumIdsRow) map[string]any {
+	album := row.Album
+	item := frontendAlbumBase(album)
+	if row.CoverAssetID.Valid {
+		item["albumThumbnailAssetId"] = row.CoverAssetID.String()
+	}
+
+	owner := frontendAlbumUser(album.OwnerId, row.OwnerEmail, row.OwnerName, row.OwnerProfileImagePath,
+		row.OwnerAvatarColor, row.OwnerProfileChangedAt, row.OwnerCreatedAt)
+	item["owner"] = owner
+	albumUsers := []map[string]any{{"role": "owner", "user": owner}}
+	for _, member := range members {
+		albumUsers = append(albumUsers, frontendAlbumMember(member.Role, frontendAlbumUser(member.ID, member.Email,
+			member.Name, member.ProfileImagePath, member.AvatarColor, member.ProfileChangedAt, member.CreatedAt)))
+	}
+	item["albumUsers"] = albumUsers
+
+	item["assetCount"] = row.AssetCount
+	item["shared"] = row.Shared
+	item["hasSharedLink"] = row.HasSharedLink
+	if row.StartDate.Valid {
+		item["startDate"] = row.StartDate.Time.Format(time.RFC3339Nano)
+	}
+	if row.EndDate.Valid {
+		item["endDate"] = row.EndDate.Time.Format(time.RFC3339Nano)
+	}
+	if row.LastModifiedAssetAt.Valid {
+		item["lastModifiedAssetTimestamp"] = row.LastModifiedAssetAt.Time.Format(time.RFC3339Nano)
+	}
+	return item
+}
+
+// frontendAlbumBase is the part of an album response read from the album
+// row alone, with defaults for the rest.
+func frontendAlbumBase(album sqlc.Album) map[string]any {
+	return map[string]any{
+		"id":                    album.ID.String(),
+		"albumName":             album.AlbumName,
+		"description":           album.Description,
+		"ownerId":               album.OwnerId.String(),
+		"owner":                 nil,
+		"assetCount":            0,
+		"assets":                []any{},
+		"albumUsers":            []any{},
+		"sharedUsers":           []any{},
+		"shared":                false,
+		"hasSharedLink":         false,
+		"isActivityEnabled":     album.IsActivityEnabled,
+		"createdAt":             album.CreatedAt.Time.Format(time.RFC3339Nano),
+		"updatedAt":             album.UpdatedAt.Time.Format(time.RFC3339Nano),
+		"albumThumbnailAssetId": nil,
+		"order":                 album.Order,
+		"startDate":             nil,
+		"endDate":               nil,
+	}
+}
+
+// frontendAlbumMember is an albumUsers entry of a shared member. Members
+// added before roles existed are editors.
+func frontendAlbumMember(role string, user map[string]any) map[string]any {
+	if role == "" {
+		role = "editor"
+	}
+	return map[string]any{"role": role, "user": user}
+}
+
 // frontendAlbumUser builds the UserResponseDto shape embedded in album
 // responses. avatarColor and profileChangedAt are required by the upstream
 // DTO, so they fall back to upstream defaults when unset.
@@ -99,3 +99,11 @@ func TestNormalizePartnerDirectionQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeAssetShareDirectionQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/asset-shares?direction=shared-by", nil)
+
+	normalizeDirectionQuery(req, "ASSET_SHARE_DIRECTION_")
+
+	assert.Equal(t, "ASSET_SHARE_DIRECTION_SHARED_BY", req.URL.Query().Get("direction"))
+}
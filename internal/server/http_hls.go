@@ -26,6 +26,7 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/ffmpeg"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 const (
@@ -322,16 +323,14 @@ func (s *Server) hlsSegmentFilePath(ctx context.Context, assetID, sessionID stri
 	return path, nil
 }
 
-func downloadAssetVideoToTemp(ctx context.Context, storage interface {
-	Download(context.Context, string) (io.ReadCloser, error)
-}, asset sqlc.Asset,
-) (string, error) {
+func downloadAssetVideoToTemp(ctx context.Context, storageService *storage.Service, asset sqlc.Asset) (string, error) {
 	videoPath := asset.OriginalPath
 	if asset.EncodedVideoPath.Valid && asset.EncodedVideoPath.String != "" {
 		videoPath = asset.EncodedVideoPath.String
+		storageService = storageService.Derivatives()
 	}
 
-	reader, err := storage.Download(ctx, videoPath)
+	reader, err := storageService.Download(ctx, videoPath)
 	if err != nil {
 		return "", SanitizedInternal(ctx, "failed to retrieve video", err)
 	}
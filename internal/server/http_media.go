@@ -2,12 +2,23 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 // Asset media endpoints (thumbnail, original, video playback) must return raw
@@ -56,28 +67,125 @@ func (s *Server) handleAssetMedia(w http.ResponseWriter, r *http.Request, assetI
 		}
 		response, err := s.GetAssetThumbnail(ctx, request)
 		if err != nil {
-			writeGrpcError(w, err)
+			if !s.writeMediaPlaceholder(w, err) {
+				writeGrpcError(w, err)
+			}
 			return
 		}
+		// The ETag follows the content, so clients revalidating a thumbnail
+		// that was regenerated get the new one.
+		w.Header().Set("ETag", mediaETag(response.GetData()))
 		writeMediaBytes(w, r, response.GetContentType(), response.GetData())
 
 	case assetMediaOriginal:
-		response, err := s.DownloadAsset(ctx, &immichv1.DownloadAssetRequest{AssetId: assetID})
+		asset, err := s.getViewableAsset(ctx, assetID)
 		if err != nil {
 			writeGrpcError(w, err)
 			return
 		}
-		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", response.GetFilename()))
-		writeMediaBytes(w, r, response.GetContentType(), response.GetData())
+		if asset.IsOffline {
+			writeGrpcError(w, assetOfflineError(ctx))
+			return
+		}
+		store := s.assetService.GetStorageService()
+		file, err := store.GetAssetMetadata(ctx, asset.OriginalPath)
+		if err != nil {
+			writeGrpcError(w, s.originalReadError(ctx, store, asset, asset.OriginalPath, err))
+			return
+		}
+		contentType := assetContentType(asset)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", asset.OriginalFileName))
+		userID, err := s.userIDFromContext(ctx)
+		if err != nil {
+			writeGrpcError(w, err)
+			return
+		}
+		if wantsStrippedOriginal(asset, userID, optionalBoolQuery(r, "stripMetadata")) {
+			data, err := s.strippedOriginal(ctx, asset)
+			if err == nil {
+				w.Header().Set("ETag", mediaETag(data))
+				writeMediaBytes(w, r, contentType, data)
+				return
+			}
+			if !errors.Is(err, assets.ErrStripUnsupported) {
+				writeGrpcError(w, err)
+				return
+			}
+			if asset.OwnerId != userID {
+				writeGrpcError(w, errStripRequired)
+				return
+			}
+			w.Header().Set("Warning", stripUnsupportedWarning)
+		}
+		serveStorageFile(ctx, w, r, store, asset.OriginalPath, file, contentType)
 
 	case assetMediaVideoPlayback:
-		response, err := s.PlayAssetVideo(ctx, &immichv1.PlayAssetVideoRequest{AssetId: assetID})
+		asset, err := s.getViewableAsset(ctx, assetID)
 		if err != nil {
 			writeGrpcError(w, err)
 			return
 		}
-		writeMediaBytes(w, r, response.GetContentType(), response.GetData())
+		if asset.Type != "VIDEO" {
+			writeGrpcError(w, status.Error(codes.InvalidArgument, "asset is not a video"))
+			return
+		}
+		contentType := s.getVideoContentType(asset.OriginalFileName)
+		store := s.assetService.GetStorageService()
+
+		// Prefer the encoded copy, like PlayAssetVideo
+		if asset.EncodedVideoPath.Valid && asset.EncodedVideoPath.String != "" {
+			file, err := store.Derivatives().GetAssetMetadata(ctx, asset.EncodedVideoPath.String)
+			if err == nil {
+				serveStorageFile(ctx, w, r, store.Derivatives(), asset.EncodedVideoPath.String, file, contentType)
+				return
+			}
+			logrus.WithError(err).WithField("asset_id", asset.ID.String()).
+				Warn("Failed to read encoded video, serving the original")
+		}
+		if asset.IsOffline {
+			writeGrpcError(w, assetOfflineError(ctx))
+			return
+		}
+		file, err := store.GetAssetMetadata(ctx, asset.OriginalPath)
+		if err != nil {
+			writeGrpcError(w, s.originalReadError(ctx, store, asset, asset.OriginalPath, err))
+			return
+		}
+		serveStorageFile(ctx, w, r, store, asset.OriginalPath, file, contentType)
+	}
+}
+
+// serveStorageFile serves a stored file with Range support, downloading
+// only the requested bytes from storage, so interrupted downloads resume
+// where they stopped and video players can seek. The ETag follows the
+// stored file: a download resumed with If-Range gets the whole file again
+// when it changed in between.
+func serveStorageFile(ctx context.Context, w http.ResponseWriter, r *http.Request, store *storage.Service, path string, file *storage.FileMetadata, contentType string) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
 	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("ETag", storageETag(file))
+
+	content := store.NewRangeReader(ctx, path, file.Size)
+	defer content.Close()
+	http.ServeContent(w, r, "", file.ModTime, content)
+}
+
+// storageETag returns a strong ETag of a stored file: the backend's when it
+// has one, otherwise one of its size and modification time.
+func storageETag(file *storage.FileMetadata) string {
+	if file.ETag != "" {
+		return `"` + file.ETag + `"`
+	}
+	return `"` + strconv.FormatInt(file.Size, 36) + "-" + strconv.FormatInt(file.ModTime.UnixNano(), 36) + `"`
+}
+
+// mediaETag returns a strong ETag of data.
+func mediaETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
 }
 
 // writeMediaBytes serves an in-memory media payload with Range support so
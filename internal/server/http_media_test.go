@@ -1,9 +1,15 @@
 package server
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 func TestAssetMediaRouteFromPath(t *testing.T) {
@@ -33,3 +39,67 @@ func TestAssetMediaRouteFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestMediaETag_FollowsContent(t *testing.T) {
+	etag := mediaETag([]byte("thumbnail"))
+	assert.Equal(t, etag, mediaETag([]byte("thumbnail")))
+	assert.NotEqual(t, etag, mediaETag([]byte("regenerated thumbnail")))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/assets/abc-123/thumbnail", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec.Header().Set("ETag", etag)
+	writeMediaBytes(rec, req, "image/jpeg", []byte("thumbnail"))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestServeStorageFile_Ranges(t *testing.T) {
+	store, err := storage.NewService(storage.StorageConfig{
+		Backend: "local",
+		Local:   storage.LocalConfig{RootPath: t.TempDir()},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, store.UploadBytes(ctx, "original.jpg", []byte("0123456789"), "image/jpeg"))
+
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		file, err := store.GetAssetMetadata(ctx, "original.jpg")
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/assets/abc-123/original", nil)
+		req.Header = header
+		serveStorageFile(ctx, rec, req, store, "original.jpg", file, "image/jpeg")
+		return rec
+	}
+
+	rec := serve(http.Header{})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "0123456789", rec.Body.String())
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Resuming a download
+	rec = serve(http.Header{"Range": {"bytes=4-"}, "If-Range": {etag}})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "bytes 4-9/10", rec.Header().Get("Content-Range"))
+	assert.Equal(t, "456789", rec.Body.String())
+
+	rec = serve(http.Header{"Range": {"bytes=2-3"}})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "23", rec.Body.String())
+
+	rec = serve(http.Header{"Range": {"bytes=20-"}})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+
+	rec = serve(http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	// A file that changed since the download started is sent whole
+	require.NoError(t, store.UploadBytes(ctx, "original.jpg", []byte("changed file"), "image/jpeg"))
+	rec = serve(http.Header{"Range": {"bytes=4-"}, "If-Range": {etag}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "changed file", rec.Body.String())
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
@@ -23,6 +23,7 @@ type partnerResponseDTO struct {
 	Name             string `json:"name"`
 	ProfileChangedAt string `json:"profileChangedAt"`
 	ProfileImagePath string `json:"profileImagePath"`
+	Status           string `json:"status"`
 }
 
 func (s *Server) handlePartnerCreate(w http.ResponseWriter, r *http.Request, pathPartnerID string) {
@@ -52,32 +53,28 @@ func (s *Server) handlePartnerCreate(w http.ResponseWriter, r *http.Request, pat
 		return
 	}
 
-	partnerUser, err := s.queries.GetUserByID(r.Context(), pgtype.UUID{Bytes: partnerID, Valid: true})
+	partnerUser, partnership, err := s.partnersService.Invite(
+		r.Context(),
+		pgtype.UUID{Bytes: userID, Valid: true},
+		pgtype.UUID{Bytes: partnerID, Valid: true},
+	)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]any{"error": "partner user not found"})
+		writeGRPCErrorJSON(w, r, err)
 		return
 	}
 
-	partnership, err := s.queries.CreatePartnership(r.Context(), sqlc.CreatePartnershipParams{
-		SharedById:   pgtype.UUID{Bytes: userID, Valid: true},
-		SharedWithId: pgtype.UUID{Bytes: partnerID, Valid: true},
-	})
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to create partnership"})
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, partnerDTO(partnerUser, partnership.InTimeline))
+	writeJSON(w, http.StatusCreated, partnerDTO(partnerUser, partnership))
 }
 
-func partnerDTO(user sqlc.User, inTimeline bool) partnerResponseDTO {
+func partnerDTO(user sqlc.User, partnership sqlc.Partner) partnerResponseDTO {
 	return partnerResponseDTO{
 		AvatarColor:      user.AvatarColor.String,
 		Email:            user.Email,
 		ID:               uuid.UUID(user.ID.Bytes).String(),
-		InTimeline:       inTimeline,
+		InTimeline:       partnership.InTimeline,
 		Name:             user.Name,
 		ProfileChangedAt: user.ProfileChangedAt.Time.Format(time.RFC3339Nano),
 		ProfileImagePath: user.ProfileImagePath,
+		Status:           partnership.Status,
 	}
 }
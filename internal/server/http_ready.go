@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// readyCheckTimeout bounds each dependency check of GET /ready.
+const readyCheckTimeout = 3 * time.Second
+
+// handleReady serves GET /ready, the readiness probe. It reports the state
+// of the database and the machine-learning service. Only an unreachable
+// database fails the probe: ML features degrade gracefully, so an
+// unavailable ML service is reported without taking the server out of
+// rotation.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Path != "/ready" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	statusCode := http.StatusOK
+	database := "ok"
+	if s.db == nil {
+		database = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	} else if err := s.db.Ping(ctx); err != nil {
+		logrus.WithError(err).Warn("Readiness check: database unavailable")
+		database = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	machineLearning, err := s.mlClient.Health(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Readiness check: machine learning unavailable")
+	}
+
+	writeJSON(w, statusCode, map[string]string{
+		"database":        database,
+		"machineLearning": machineLearning,
+	})
+	return true
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// Shared link landing pages (/share/{key}) are what chat apps and social
+// networks fetch to unfurl a link. Crawlers do not run the web app, so the
+// Open Graph and Twitter card tags must be in the HTML returned by the
+// server. When a web build is configured the tags are injected into its
+// index.html so browsers still get the app; otherwise a minimal page is
+// served.
+
+const (
+	sharePathPrefix = "/share/"
+
+	// sharePreviewImage is the path segment serving the link's preview image.
+	sharePreviewImage = "og-image.jpg"
+
+	shareDefaultTitle = "Immich"
+)
+
+// sharePreview holds the metadata rendered into a shared link landing page.
+type sharePreview struct {
+	Title       string
+	Description string
+	ImageURL    string
+	PageURL     string
+}
+
+var shareMetaTemplate = template.Must(template.New("meta").Parse(`<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta name="twitter:title" content="{{.Title}}">
+{{- if .PageURL}}
+<meta property="og:url" content="{{.PageURL}}">
+{{- end}}
+{{- if .Description}}
+<meta name="description" content="{{.Description}}">
+<meta property="og:description" content="{{.Description}}">
+<meta name="twitter:description" content="{{.Description}}">
+{{- end}}
+{{- if .ImageURL}}
+<meta property="og:image" content="{{.ImageURL}}">
+<meta name="twitter:image" content="{{.ImageURL}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+`))
+
+var sharePageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Preview.Title}}</title>
+{{.Meta}}</head>
+<body>
+<h1>{{.Heading}}</h1>
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// shareLinkFromPath extracts the shared link key from /share/{key} and
+// reports whether the preview image was requested.
+func shareLinkFromPath(path string) (key string, image bool, ok bool) {
+	rest, found := strings.CutPrefix(path, sharePathPrefix)
+	if !found {
+		return "", false, false
+	}
+	key, suffix, _ := strings.Cut(strings.TrimSuffix(rest, "/"), "/")
+	switch {
+	case key == "":
+		return "", false, false
+	case suffix == "":
+		return key, false, true
+	case suffix == sharePreviewImage:
+		return key, true, true
+	}
+	return "", false, false
+}
+
+// shareLandingHandler serves shared link landing pages and preview images
+// ahead of the web UI, passing every other request to next.
+func (s *Server) shareLandingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, image, ok := shareLinkFromPath(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if image {
+			s.handleSharePreviewImage(w, r, key)
+			return
+		}
+		s.handleShareLanding(w, r, key)
+	})
+}
+
+// publicSharedLink loads a shared link for an anonymous visitor. It writes the
+// error page and returns false when the link does not resolve or has expired.
+func (s *Server) publicSharedLink(w http.ResponseWriter, r *http.Request, key string) (sqlc.SharedLink, bool) {
+	link, err := s.db.GetSharedLinkByKey(r.Context(), []byte(key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeSharePage(w, http.StatusNotFound, "Link not found", "This shared link does not exist.")
+		return link, false
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to load shared link for landing page")
+		writeSharePage(w, http.StatusInternalServerError, "Something went wrong", "")
+		return link, false
+	}
+	if link.ExpiresAt.Valid && link.ExpiresAt.Time.Before(time.Now()) {
+		writeSharePage(w, http.StatusGone, "Link expired", "This shared link has expired.")
+		return link, false
+	}
+	return link, true
+}
+
+// handleShareLanding renders the landing page of a shared link with Open Graph
+// and Twitter card tags. Password protected links get generic tags only, so
+// their title, description and photos are not disclosed.
+func (s *Server) handleShareLanding(w http.ResponseWriter, r *http.Request, key string) {
+	link, ok := s.publicSharedLink(w, r, key)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	baseURL := s.shareBaseURL(ctx, r)
+	preview := sharePreview{
+		Title:   shareDefaultTitle,
+		PageURL: baseURL + sharePathPrefix + url.PathEscape(key),
+	}
+	if !link.Password.Valid || link.Password.String == "" {
+		s.fillSharePreview(ctx, link, &preview)
+		if _, err := s.sharePreviewAsset(ctx, link); err == nil {
+			preview.ImageURL = preview.PageURL + "/" + sharePreviewImage
+		}
+	}
+
+	var meta bytes.Buffer
+	if err := shareMetaTemplate.Execute(&meta, preview); err != nil {
+		logrus.WithError(err).Error("Failed to render shared link meta tags")
+		writeSharePage(w, http.StatusInternalServerError, "Something went wrong", "")
+		return
+	}
+
+	if page, ok := s.webUIIndexWithMeta(meta.String()); ok {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(page)
+		return
+	}
+
+	renderSharePage(w, http.StatusOK, preview, template.HTML(meta.String()), preview.Title, preview.Description) //nolint:gosec // meta is rendered by html/template.
+}
+
+// handleSharePreviewImage serves the representative thumbnail of a shared link.
+func (s *Server) handleSharePreviewImage(w http.ResponseWriter, r *http.Request, key string) {
+	link, ok := s.publicSharedLink(w, r, key)
+	if !ok {
+		return
+	}
+	if link.Password.Valid && link.Password.String != "" {
+		writeSharePage(w, http.StatusNotFound, "Link not found", "")
+		return
+	}
+
+	ctx := r.Context()
+	asset, err := s.sharePreviewAsset(ctx, link)
+	if err != nil {
+		writeSharePage(w, http.StatusNotFound, "No preview available", "")
+		return
+	}
+
+	thumbnail, err := s.assetThumbnail(ctx, asset, assets.ThumbnailTypePreview)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load shared link preview image")
+		writeSharePage(w, http.StatusNotFound, "No preview available", "")
+		return
+	}
+	writeMediaBytes(w, r, thumbnail.GetContentType(), thumbnail.GetData())
+}
+
+// fillSharePreview sets the title and description of an unprotected link.
+func (s *Server) fillSharePreview(ctx context.Context, link sqlc.SharedLink, preview *sharePreview) {
+	count := 0
+	if link.Type == sharedLinkTypeAlbum && link.AlbumId.Valid {
+		if album, err := s.db.GetAlbum(ctx, link.AlbumId); err == nil {
+			preview.Title = album.AlbumName
+			preview.Description = album.Description
+		}
+		if albumAssets, err := s.db.GetAlbumAssets(ctx, link.AlbumId); err == nil {
+			count = len(albumAssets)
+		}
+	} else if linkAssets, err := s.db.GetSharedLinkAssets(ctx, link.ID); err == nil {
+		preview.Title = "Public Share"
+		count = len(linkAssets)
+	}
+
+	if link.Description.Valid && link.Description.String != "" {
+		preview.Description = link.Description.String
+	}
+	if preview.Description == "" {
+		preview.Description = fmt.Sprintf("%d shared photos & videos", count)
+	}
+}
+
+// sharePreviewAsset picks the asset shown as a link's preview image: the album
+// cover for album links, otherwise the most recent shared asset.
+func (s *Server) sharePreviewAsset(ctx context.Context, link sqlc.SharedLink) (sqlc.Asset, error) {
+	if link.Type == sharedLinkTypeAlbum && link.AlbumId.Valid {
+		album, err := s.db.GetAlbum(ctx, link.AlbumId)
+		if err != nil {
+			return sqlc.Asset{}, err
+		}
+		if album.AlbumThumbnailAssetId.Valid {
+			asset, err := s.db.GetAsset(ctx, album.AlbumThumbnailAssetId)
+			if err == nil && asset.Visibility != sqlc.AssetVisibilityEnumLocked {
+				return asset, nil
+			}
+		}
+		albumAssets, err := s.db.GetAlbumAssets(ctx, link.AlbumId)
+		if err != nil {
+			return sqlc.Asset{}, err
+		}
+		if len(albumAssets) == 0 {
+			return sqlc.Asset{}, pgx.ErrNoRows
+		}
+		return albumAssets[0], nil
+	}
+
+	linkAssets, err := s.db.GetSharedLinkAssets(ctx, link.ID)
+	if err != nil {
+		return sqlc.Asset{}, err
+	}
+	if len(linkAssets) == 0 {
+		return sqlc.Asset{}, pgx.ErrNoRows
+	}
+	return linkAssets[0], nil
+}
+
+// shareBaseURL returns the public origin used for absolute Open Graph URLs:
+// the configured external domain, or the origin of the request. The
+// X-Forwarded-* headers are ignored since any client can set them, so
+// servers behind a proxy need the external domain configured.
+func (s *Server) shareBaseURL(ctx context.Context, r *http.Request) string {
+	if domain := s.externalDomain(ctx); domain != "" {
+		return domain
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// externalDomain returns the public origin of the server without a trailing
+// slash: the external domain from the admin settings, else the one from the
+// config file, else an empty string.
+func (s *Server) externalDomain(ctx context.Context) string {
+	if s.systemConfigService != nil {
+		if dto, err := s.systemConfigService.GetConfigDto(ctx); err == nil && dto.Server.ExternalDomain != "" {
+			return strings.TrimRight(dto.Server.ExternalDomain, "/")
+		}
+	}
+	return s.config.ExternalURL("")
+}
+
+// webUIIndexWithMeta returns the web UI's index.html with meta inserted at the
+// end of its head, or false when no web build is configured.
+func (s *Server) webUIIndexWithMeta(meta string) ([]byte, bool) {
+	if s.config == nil || strings.TrimSpace(s.config.WebUIDir) == "" {
+		return nil, false
+	}
+	index, err := os.ReadFile(filepath.Join(s.config.WebUIDir, "index.html"))
+	if err != nil {
+		return nil, false
+	}
+	head := bytes.Index(index, []byte("</head>"))
+	if head < 0 {
+		return nil, false
+	}
+	page := make([]byte, 0, len(index)+len(meta))
+	page = append(page, index[:head]...)
+	page = append(page, meta...)
+	return append(page, index[head:]...), true
+}
+
+// writeSharePage renders a landing page without preview metadata, used for
+// missing, expired and failing links.
+func writeSharePage(w http.ResponseWriter, statusCode int, heading, message string) {
+	renderSharePage(w, statusCode, sharePreview{Title: shareDefaultTitle}, "", heading, message)
+}
+
+func renderSharePage(w http.ResponseWriter, statusCode int, preview sharePreview, meta template.HTML, heading, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(statusCode)
+	err := sharePageTemplate.Execute(w, struct {
+		Preview sharePreview
+		Meta    template.HTML
+		Heading string
+		Message string
+	}{preview, meta, heading, message})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to write shared link page")
+	}
+}
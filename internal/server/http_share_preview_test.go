@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+func TestShareLinkFromPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		key   string
+		image bool
+		ok    bool
+	}{
+		{"landing", "/share/abc_-123", "abc_-123", false, true},
+		{"trailing slash", "/share/abc/", "abc", false, true},
+		{"preview image", "/share/abc/og-image.jpg", "abc", true, true},
+		{"subpage", "/share/abc/photos/1", "", false, false},
+		{"missing key", "/share/", "", false, false},
+		{"api route", "/api/shared-links/me", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, image, ok := shareLinkFromPath(tt.path)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.key, key)
+			assert.Equal(t, tt.image, image)
+		})
+	}
+}
+
+func TestShareMetaTemplateEscapesValues(t *testing.T) {
+	var meta bytes.Buffer
+	require.NoError(t, shareMetaTemplate.Execute(&meta, sharePreview{
+		Title:       `Trip "2024" <script>`,
+		Description: "3 shared photos & videos",
+		ImageURL:    "https://photos.example.com/share/abc/og-image.jpg",
+		PageURL:     "https://photos.example.com/share/abc",
+	}))
+
+	out := meta.String()
+	assert.Contains(t, out, `<meta property="og:title" content="Trip &#34;2024&#34; &lt;script&gt;">`)
+	assert.Contains(t, out, `<meta property="og:image" content="https://photos.example.com/share/abc/og-image.jpg">`)
+	assert.Contains(t, out, `<meta name="twitter:card" content="summary_large_image">`)
+	assert.NotContains(t, out, "<script>")
+}
+
+func TestShareMetaTemplateWithoutImage(t *testing.T) {
+	var meta bytes.Buffer
+	require.NoError(t, shareMetaTemplate.Execute(&meta, sharePreview{Title: shareDefaultTitle}))
+
+	out := meta.String()
+	assert.NotContains(t, out, "og:image")
+	assert.NotContains(t, out, "og:description")
+	assert.Contains(t, out, `<meta name="twitter:card" content="summary">`)
+}
+
+func TestWebUIIndexWithMeta(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"),
+		[]byte("<html><head><title>Immich</title></head><body></body></html>"), 0o600))
+
+	s := &Server{config: &config.Config{WebUIDir: dir}}
+	page, ok := s.webUIIndexWithMeta(`<meta property="og:title" content="x">`)
+	require.True(t, ok)
+	assert.Equal(t, `<html><head><title>Immich</title><meta property="og:title" content="x"></head><body></body></html>`, string(page))
+
+	_, ok = (&Server{config: &config.Config{}}).webUIIndexWithMeta("")
+	assert.False(t, ok)
+}
+
+func TestShareBaseURLPrefersExternalDomain(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/share/key", nil)
+	r.Host = "10.0.0.5:3001"
+
+	r.Header.Set("X-Forwarded-Host", "attacker.example.com")
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	s := &Server{config: &config.Config{}}
+	assert.Equal(t, "http://10.0.0.5:3001", s.shareBaseURL(context.Background(), r), "forwarded headers are not trusted")
+
+	s.config.Server.ExternalDomain = "https://photos.example.com/"
+	assert.Equal(t, "https://photos.example.com", s.shareBaseURL(context.Background(), r))
+}
@@ -4,6 +4,9 @@ import (
 	"io"
 	"net/http"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
 )
@@ -16,10 +19,7 @@ func (s *Server) requireAdminHTTP(w http.ResponseWriter, r *http.Request) (*auth
 		return nil, false
 	}
 	if !claims.IsAdmin {
-		writeJSON(w, http.StatusForbidden, map[string]any{
-			"message":    "Forbidden",
-			"statusCode": http.StatusForbidden,
-		})
+		writeGrpcError(w, status.Error(codes.PermissionDenied, "Forbidden"))
 		return nil, false
 	}
 	return claims, true
@@ -53,7 +53,7 @@ func (s *Server) handleSystemConfigPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, err := s.systemConfigService.UpdateConfigDto(r.Context(), body)
+	cfg, err := s.updateSystemConfig(r.Context(), body)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 		return
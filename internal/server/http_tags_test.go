@@ -27,7 +27,7 @@ func TestHandleWsRoutesBulkTagAssetsBeforeGatewayTagIDMatch(t *testing.T) {
 	require.NotNil(t, tagsServer.bulkReq)
 	assert.Equal(t, []string{"asset-1"}, tagsServer.bulkReq.GetAssetIds())
 	assert.Equal(t, []string{"tag-1"}, tagsServer.bulkReq.GetTagIds())
-	assert.JSONEq(t, `{"count":1}`, rec.Body.String())
+	assert.JSONEq(t, `{"count":1,"assets":[]}`, rec.Body.String())
 }
 
 type fakeTagsServiceServer struct {
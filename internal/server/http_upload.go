@@ -1,31 +1,35 @@
 package server
 
 import (
-	"crypto/sha1" //nolint:gosec // Immich uses SHA-1 asset checksums; not for crypto.
-	"encoding/hex"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
 )
 
 // writeGrpcError converts a gRPC status error into the upstream-style JSON
 // error envelope with the mapped HTTP status code.
 func writeGrpcError(w http.ResponseWriter, err error) {
-	st := status.Convert(err)
-	code := runtime.HTTPStatusFromCode(st.Code())
-	writeJSON(w, code, map[string]any{
-		"message":    st.Message(),
-		"statusCode": code,
-	})
+	code, body := immichErrorResponse(err)
+	writeJSON(w, code, body)
 }
 
 // maxUploadMemory is the in-memory buffer for multipart parsing; larger
@@ -45,17 +49,14 @@ func (s *Server) handleAssetUpload(w http.ResponseWriter, r *http.Request) {
 
 	claims, err := s.claimsFromContext(ctx)
 	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{
-			"message":    "Unauthorized",
-			"statusCode": http.StatusUnauthorized,
-		})
+		writeGrpcError(w, status.Error(codes.Unauthenticated, "Unauthorized"))
 		return
 	}
 
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 	//nolint:gosec // G120: the body is bounded by MaxBytesReader above.
 	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid multipart form: " + err.Error()})
+		writeGrpcError(w, status.Error(codes.InvalidArgument, "invalid multipart form: "+err.Error()))
 		return
 	}
 	defer func() {
@@ -75,20 +76,93 @@ func (s *Server) handleAssetUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sum := sha1.Sum(content) //nolint:gosec // Immich asset checksum convention.
-	checksum := hex.EncodeToString(sum[:])
-
-	// Duplicate detection: same convention as UploadAsset — checksums are
-	// stored as the bytes of the hex string.
-	if existing, err := s.db.GetAssetsByChecksum(ctx, []byte(checksum)); err == nil {
-		for _, a := range existing {
-			if a.OwnerId.Valid && a.OwnerId.String() == claims.UserID {
-				writeJSON(w, http.StatusOK, map[string]any{
-					"id":     a.ID.String(),
-					"status": "duplicate",
-				})
-				return
-			}
+	checksum := assets.SumChecksum(content, assets.DefaultChecksumAlgorithm)
+
+	create := func() (idempotentResponse, error) {
+		return s.createUploadedAsset(ctx, claims.UserID, r, header, content, checksum)
+	}
+
+	var resp idempotentResponse
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		userID, parseErr := pgutil.ParseUserID(claims.UserID)
+		if parseErr != nil {
+			writeGrpcError(w, SanitizedInternal(ctx, "invalid user ID", parseErr))
+			return
+		}
+		var replayed bool
+		resp, replayed, err = s.runIdempotent(ctx, userID, key, checksum.Hex(), create)
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+		}
+		err = idempotencyGrpcError(ctx, err)
+	} else {
+		resp, err = create()
+	}
+	if err != nil {
+		writeGrpcError(w, err)
+		return
+	}
+
+	writeJSON(w, resp.StatusCode, json.RawMessage(resp.Body))
+}
+
+// maxSidecarSize caps the sidecarData part, which is read into memory.
+// Sidecars are a few kilobytes.
+const maxSidecarSize = 1 << 20
+
+// readSidecarPart returns the optional sidecarData file part of an upload.
+func readSidecarPart(r *http.Request) ([]byte, error) {
+	file, header, err := r.FormFile("sidecarData")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to read sidecarData")
+	}
+	defer file.Close()
+	if header.Size > maxSidecarSize {
+		return nil, status.Error(codes.InvalidArgument, "sidecarData is too large")
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to read sidecarData")
+	}
+	return data, nil
+}
+
+// createUploadedAsset creates the asset for a multipart upload, or reports the
+// existing asset when the user already uploaded the same file. It returns the
+// response to send, so a retry with the same Idempotency-Key can replay it.
+func (s *Server) createUploadedAsset(
+	ctx context.Context,
+	userID string,
+	r *http.Request,
+	header *multipart.FileHeader,
+	content []byte,
+	checksum assets.Checksum,
+) (idempotentResponse, error) {
+	ownerID, err := pgutil.ParseUserID(userID)
+	if err != nil {
+		return idempotentResponse{}, SanitizedInternal(ctx, "invalid user ID", err)
+	}
+	existing, err := s.db.GetOwnerAssetsByChecksum(ctx, sqlc.GetOwnerAssetsByChecksumParams{
+		OwnerID:           ownerID,
+		Checksum:          checksum.Stored(),
+		ChecksumAlgorithm: string(checksum.Algorithm),
+	})
+	if err != nil {
+		return idempotentResponse{}, SanitizedInternal(ctx, "failed to check for duplicate assets", err)
+	}
+	if len(existing) > 0 {
+		duplicate, err := s.resolveDuplicateUpload(ctx, existing[0])
+		if err != nil {
+			return idempotentResponse{}, err
+		}
+		if duplicate {
+			return jsonResponse(http.StatusOK, map[string]any{
+				"id":     existing[0].ID.String(),
+				"status": "duplicate",
+			})
 		}
 	}
 
@@ -130,22 +204,62 @@ func (s *Server) handleAssetUpload(w http.ResponseWriter, r *http.Request) {
 		assetData.IsFavorite = &fav
 	}
 
-	asset, err := s.UploadAsset(ctx, &immichv1.UploadAssetRequest{
-		AssetData:   assetData,
-		Checksum:    &checksum,
-		FileContent: content,
+	sidecar, err := readSidecarPart(r)
+	if err != nil {
+		return idempotentResponse{}, err
+	}
+
+	checksumHex := checksum.Hex()
+	algorithm := string(checksum.Algorithm)
+	asset, err := s.uploadAsset(ctx, &immichv1.UploadAssetRequest{
+		AssetData:         assetData,
+		Checksum:          &checksumHex,
+		ChecksumAlgorithm: &algorithm,
+		FileContent:       content,
+		SidecarData:       sidecar,
 	})
 	if err != nil {
-		writeGrpcError(w, err)
-		return
+		return idempotentResponse{}, err
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{
+	return jsonResponse(http.StatusCreated, map[string]any{
 		"id":     asset.Id,
 		"status": "created",
 	})
 }
 
+// resolveDuplicateUpload decides what the upload of a file the user already
+// has resolves to, and reports whether it is the existing asset. A trashed
+// asset is restored from the trash, unless restoring is turned off: then it
+// is removed for good, so the upload creates a fresh asset in its place.
+func (s *Server) resolveDuplicateUpload(ctx context.Context, existing sqlc.Asset) (bool, error) {
+	if existing.Status != sqlc.AssetsStatusEnumTrashed {
+		return true, nil
+	}
+	if s.config.Storage.Upload.RestoreTrashedDuplicates {
+		if err := s.db.RestoreAssetFromTrash(ctx, existing.ID); err != nil {
+			return false, SanitizedInternal(ctx, "failed to restore trashed asset", err)
+		}
+		return true, nil
+	}
+	if err := s.db.PurgeAssets(ctx, sqlc.PurgeAssetsParams{
+		AssetIds: []pgtype.UUID{existing.ID},
+		OwnerID:  existing.OwnerId,
+	}); err != nil {
+		return false, SanitizedInternal(ctx, "failed to replace trashed asset", err)
+	}
+	return false, nil
+}
+
+// jsonResponse encodes data as the body of an idempotent response.
+func jsonResponse(statusCode int, data any) (idempotentResponse, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return idempotentResponse{}, fmt.Errorf("failed to encode response: %w", err)
+	}
+	return idempotentResponse{StatusCode: statusCode, Body: body}, nil
+}
+
 // parseUploadTime accepts the timestamp formats the Immich clients send
 // (RFC3339 or unix epoch milliseconds).
 func parseUploadTime(v string) (*timestamppb.Timestamp, bool) {
@@ -168,9 +282,6 @@ func isVideoUpload(contentType, filename string) bool {
 	if strings.HasPrefix(contentType, "video/") {
 		return true
 	}
-	switch strings.ToLower(filepath.Ext(filename)) {
-	case ".mp4", ".mov", ".avi", ".mkv", ".webm", ".m4v", ".3gp", ".mts", ".m2ts", ".wmv", ".flv":
-		return true
-	}
-	return false
+	kind, _, _ := storage.MediaTypeOf(filepath.Ext(filename))
+	return kind == storage.MediaKindVideo
 }
@@ -2,11 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/download"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/sirupsen/logrus"
 )
 
 // writeUserAdminJSON preserves nullable fields that protojson omits for
@@ -51,3 +55,68 @@ func (s *Server) handleUserOnboardingDelete(w http.ResponseWriter, r *http.Reque
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// userExportRequestBody re-authenticates the export and selects its part.
+type userExportRequestBody struct {
+	Password string `json:"password"`
+	After    string `json:"after"`
+	Limit    int    `json:"limit"`
+}
+
+// userExportNextHeader carries the cursor of the next export part; it is
+// absent on the last part.
+const userExportNextHeader = "X-Immich-Export-Next"
+
+// handleUserExport implements `POST /api/users/me/export`, streaming one part
+// of the user's data export. The account password is required again since
+// the archive holds every original the user uploaded. Large accounts are
+// exported part by part by passing the previous part's cursor as "after".
+func (s *Server) handleUserExport(w http.ResponseWriter, r *http.Request) {
+	claims, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid user id"})
+		return
+	}
+
+	var body userExportRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid request body"})
+		return
+	}
+
+	if err := s.authService.VerifyPassword(r.Context(), claims.UserID, body.Password); err != nil {
+		if authErr, ok := auth.AsAuthError(err); ok && authErr.Type == auth.ErrRateLimited {
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": authErr.Message})
+			return
+		}
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "password is incorrect"})
+		return
+	}
+
+	export, err := s.downloadService.ExportUserData(r.Context(), userID, download.ExportRequest{
+		After: body.After,
+		Limit: body.Limit,
+	})
+	if errors.Is(err, download.ErrInvalidExportRequest) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeGrpcError(w, SanitizedInternal(r.Context(), "failed to export user data", err))
+		return
+	}
+
+	if export.Next != "" {
+		w.Header().Set(userExportNextHeader, export.Next)
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="immich-export.zip"`)
+	if err := export.WriteArchive(r.Context(), w); err != nil {
+		logrus.WithError(err).WithField("user_id", claims.UserID).Warn("user export streaming failed")
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+const (
+	// idempotencyKeyHeader lets clients retry a create request without
+	// creating a second resource.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotencyKeyMetadata is the gRPC metadata key the gateway forwards
+	// the header as.
+	idempotencyKeyMetadata = "idempotency-key"
+
+	// idempotencyKeyTTL is how long a stored response is replayed.
+	idempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyLease is how long a key is held for a request that has not
+	// completed. A key left behind by a crash, or whose response failed to
+	// be stored, can be used again once its lease ends.
+	idempotencyLease = 5 * time.Minute
+
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	// errIdempotencyInProgress is returned while the first request with a
+	// key is still being processed.
+	errIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+
+	// errIdempotencyKeyReused is returned when a key is sent again with a
+	// different request.
+	errIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+	errIdempotencyKeyTooLong = fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+)
+
+// idempotentResponse is the response stored for an idempotency key.
+type idempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// idempotencyKeyFromContext returns the Idempotency-Key sent with a gRPC or
+// gateway request, if any.
+func idempotencyKeyFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(idempotencyKeyMetadata); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// runIdempotent runs fn once per (user, key). A retry with the same key and
+// fingerprint gets the stored response of the first run, reported as
+// replayed; fn is not called again. The key is released when fn fails so the
+// client can retry, and is only held for idempotencyLease until fn's
+// response is stored.
+func (s *Server) runIdempotent(
+	ctx context.Context,
+	userID pgtype.UUID,
+	key, fingerprint string,
+	fn func() (idempotentResponse, error),
+) (idempotentResponse, bool, error) {
+	if len(key) > maxIdempotencyKeyLength {
+		return idempotentResponse{}, false, errIdempotencyKeyTooLong
+	}
+
+	if err := s.db.DeleteExpiredIdempotencyKeys(ctx, userID); err != nil {
+		return idempotentResponse{}, false, fmt.Errorf("failed to expire idempotency keys: %w", err)
+	}
+
+	reserved, err := s.db.ReserveIdempotencyKey(ctx, sqlc.ReserveIdempotencyKeyParams{
+		UserId:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(idempotencyLease), Valid: true},
+	})
+	if err != nil {
+		return idempotentResponse{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	if reserved == 0 {
+		stored, err := s.db.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{UserId: userID, Key: key})
+		if err != nil {
+			return idempotentResponse{}, false, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		if stored.Fingerprint != fingerprint {
+			return idempotentResponse{}, false, errIdempotencyKeyReused
+		}
+		if !stored.StatusCode.Valid {
+			return idempotentResponse{}, false, errIdempotencyInProgress
+		}
+		return idempotentResponse{StatusCode: int(stored.StatusCode.Int32), Body: stored.Response}, true, nil
+	}
+
+	resp, err := fn()
+	if err != nil {
+		// Use a fresh context so a cancelled request still frees the key.
+		if delErr := s.db.DeleteIdempotencyKey(context.WithoutCancel(ctx), sqlc.DeleteIdempotencyKeyParams{UserId: userID, Key: key}); delErr != nil {
+			return idempotentResponse{}, false, errors.Join(err, fmt.Errorf("failed to release idempotency key: %w", delErr))
+		}
+		return idempotentResponse{}, false, err
+	}
+
+	if err := s.db.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		UserId:     userID,
+		Key:        key,
+		StatusCode: pgtype.Int4{Int32: int32(resp.StatusCode), Valid: true},
+		Response:   resp.Body,
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(idempotencyKeyTTL), Valid: true},
+	}); err != nil {
+		// The resource exists now; report success and let a retry see the
+		// key as in progress until its lease ends.
+		logrus.WithError(err).Warn("Failed to store idempotent response")
+	}
+
+	return resp, false, nil
+}
+
+// idempotencyGrpcError maps idempotency conflicts to gRPC status errors and
+// returns other errors unchanged.
+func idempotencyGrpcError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, errIdempotencyInProgress):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, errIdempotencyKeyReused), errors.Is(err, errIdempotencyKeyTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return SanitizedInternal(ctx, "failed to process idempotency key", err)
+}
@@ -0,0 +1,117 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+)
+
+func TestRunIdempotentReplaysFirstResponse(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	userID := pgtype.UUID{Bytes: tdb.CreateTestUser(t, "idempotency@example.com"), Valid: true}
+
+	calls := 0
+	create := func() (idempotentResponse, error) {
+		calls++
+		return idempotentResponse{StatusCode: http.StatusCreated, Body: []byte(`{"id":"first"}`)}, nil
+	}
+
+	resp, replayed, err := srv.runIdempotent(ctx, userID, "key-1", "checksum", create)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, replayed, err = srv.runIdempotent(ctx, userID, "key-1", "checksum", create)
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.JSONEq(t, `{"id":"first"}`, string(resp.Body))
+	assert.Equal(t, 1, calls)
+
+	_, _, err = srv.runIdempotent(ctx, userID, "key-1", "other-checksum", create)
+	assert.ErrorIs(t, err, errIdempotencyKeyReused)
+
+	// A failed request releases its key so the retry runs again.
+	failure := errors.New("storage unavailable")
+	_, _, err = srv.runIdempotent(ctx, userID, "key-2", "checksum", func() (idempotentResponse, error) {
+		return idempotentResponse{}, failure
+	})
+	assert.ErrorIs(t, err, failure)
+	_, replayed, err = srv.runIdempotent(ctx, userID, "key-2", "checksum", create)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRunIdempotentTakesOverStaleReservations(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	srv := &Server{db: conn}
+	userID := pgtype.UUID{Bytes: tdb.CreateTestUser(t, "idempotency-lease@example.com"), Valid: true}
+	otherID := pgtype.UUID{Bytes: tdb.CreateTestUser(t, "idempotency-other@example.com"), Valid: true}
+	reserve := func(userID pgtype.UUID, key string, expiresAt time.Time) {
+		reserved, err := conn.ReserveIdempotencyKey(ctx, sqlc.ReserveIdempotencyKeyParams{
+			UserId:      userID,
+			Key:         key,
+			Fingerprint: "checksum",
+			ExpiresAt:   pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 1, reserved)
+	}
+	create := func() (idempotentResponse, error) {
+		return idempotentResponse{StatusCode: http.StatusCreated, Body: []byte(`{}`)}, nil
+	}
+
+	// A request still holding its lease blocks retries
+	reserve(userID, "running", time.Now().Add(idempotencyLease))
+	_, _, err = srv.runIdempotent(ctx, userID, "running", "checksum", create)
+	assert.ErrorIs(t, err, errIdempotencyInProgress)
+
+	// One whose lease ended, such as after a crash, is taken over
+	reserve(userID, "crashed", time.Now().Add(-time.Second))
+	_, replayed, err := srv.runIdempotent(ctx, userID, "crashed", "checksum", create)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	// Stored responses are kept for the whole TTL
+	stored, err := conn.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{UserId: userID, Key: "crashed"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(idempotencyKeyTTL), stored.ExpiresAt.Time, time.Minute)
+
+	// The global sweep removes expired keys of every user
+	reserve(otherID, "abandoned", time.Now().Add(-time.Second))
+	removed, err := conn.DeleteAllExpiredIdempotencyKeys(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, removed)
+	_, err = conn.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{UserId: otherID, Key: "abandoned"})
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -93,7 +93,8 @@ func emptyJobStatus() *immichv1.JobStatusDto {
 
 // jobStatusFromQueueInfo converts asynq queue statistics into the Immich job
 // status DTO shape. asynq semantics map as follows: pending -> waiting,
-// scheduled + retry -> delayed, archived (retries exhausted) -> failed.
+// scheduled + retry -> delayed, archived (retries exhausted) -> failed. The
+// dead-letter count comes from the job_failures table.
 func jobStatusFromQueueInfo(info *jobs.QueueInfo) *immichv1.JobStatusDto {
 	if info == nil {
 		return emptyJobStatus()
@@ -102,12 +103,13 @@ func jobStatusFromQueueInfo(info *jobs.QueueInfo) *immichv1.JobStatusDto {
 		IsActive: info.Active > 0,
 		IsPaused: info.Paused,
 		QueueStatus: &immichv1.QueueStatusDto{
-			Active:    clampInt32(info.Active),
-			Completed: clampInt32(info.Completed),
-			Delayed:   clampInt32(info.Scheduled + info.Retry),
-			Failed:    clampInt32(info.Archived),
-			Paused:    0,
-			Waiting:   clampInt32(info.Pending),
+			Active:     clampInt32(info.Active),
+			Completed:  clampInt32(info.Completed),
+			Delayed:    clampInt32(info.Scheduled + info.Retry),
+			Failed:     clampInt32(info.Archived),
+			Paused:     0,
+			Waiting:    clampInt32(info.Pending),
+			DeadLetter: clampInt32(info.DeadLetter),
 		},
 	}
 }
@@ -143,6 +145,7 @@ func (s *Server) GetAllJobStatuses(ctx context.Context, _ *emptypb.Empty) (*immi
 			statuses[name] = jobStatusFromQueueInfo(stats.Queues[queue])
 		}
 	}
+	s.addLibraryScans(statuses[immichv1.JobName_JOB_NAME_LIBRARY])
 
 	return &immichv1.AllJobStatusResponseDto{
 		BackgroundTask:           statuses[immichv1.JobName_JOB_NAME_BACKGROUND_TASK],
@@ -163,6 +166,19 @@ func (s *Server) GetAllJobStatuses(ctx context.Context, _ *emptypb.Empty) (*immi
 	}, nil
 }
 
+// addLibraryScans counts the library scans of this server in the library
+// job status: a library job only queues its scan, which then waits for the
+// scan concurrency limits and runs outside the job queue.
+func (s *Server) addLibraryScans(status *immichv1.JobStatusDto) {
+	if s.libraryService == nil {
+		return
+	}
+	running, waiting := s.libraryService.ScanCounts()
+	status.IsActive = status.IsActive || running > 0
+	status.QueueStatus.Active += clampInt32(running)
+	status.QueueStatus.Waiting += clampInt32(waiting)
+}
+
 // SendJobCommand applies a command (start/pause/resume/empty/clear-failed) to
 // the queue backing the given job name and returns the queue's status.
 func (s *Server) SendJobCommand(ctx context.Context, request *immichv1.SendJobCommandRequest) (*immichv1.JobStatusDto, error) {
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func marshalGatewayJSON(t *testing.T, msg any) map[string]any {
+	t.Helper()
+	data, err := gatewayMarshaler().Marshal(msg)
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+	return fields
+}
+
+func TestGatewayMarshalerOmitsUnsetOptionalAssetFields(t *testing.T) {
+	s := &Server{}
+	asset := sqlc.Asset{
+		ID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		OwnerId: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Type:    "IMAGE",
+	}
+
+	fields := marshalGatewayJSON(t, s.convertAssetToProto(asset))
+	for _, name := range []string{"livePhotoVideoId", "stackParentId", "duration", "exifInfo", "thumbhash"} {
+		assert.NotContains(t, fields, name)
+	}
+	assert.Equal(t, false, fields["isFavorite"], "required fields keep their defaults")
+	assert.Equal(t, "", fields["originalPath"])
+	assert.Equal(t, []any{}, fields["tags"])
+
+	livePhotoID := uuid.New()
+	asset.LivePhotoVideoId = pgtype.UUID{Bytes: livePhotoID, Valid: true}
+	asset.Duration = pgtype.Text{String: "", Valid: true}
+	asset.Thumbhash = []byte{1, 2, 3}
+
+	fields = marshalGatewayJSON(t, s.convertAssetToProto(asset))
+	assert.Equal(t, livePhotoID.String(), fields["livePhotoVideoId"])
+	assert.Equal(t, "AQID", fields["thumbhash"], "stored bytes are sent as base64")
+	assert.Equal(t, "", fields["duration"], "a present but empty value is kept")
+}
+
+func TestGatewayMarshalerOmitsUnsetOptionalUserFields(t *testing.T) {
+	fields := marshalGatewayJSON(t, &immichv1.UserAdminResponseDto{Id: uuid.NewString()})
+	assert.NotContains(t, fields, "quotaSizeInBytes")
+	assert.NotContains(t, fields, "storageLabel")
+	assert.NotContains(t, fields, "deletedAt")
+	assert.Equal(t, "", fields["oauthId"])
+
+	quota := int64(0)
+	fields = marshalGatewayJSON(t, &immichv1.UserAdminResponseDto{QuotaSizeInBytes: &quota})
+	assert.Equal(t, "0", fields["quotaSizeInBytes"])
+}
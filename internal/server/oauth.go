@@ -8,6 +8,7 @@ import (
 
 	"github.com/denysvitali/immich-go-backend/internal/oauth"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -72,10 +73,14 @@ func (s *Server) CallbackOAuth(ctx context.Context, req *immichv1.CallbackOAuthR
 	}
 
 	// Find or create user
-	user, err := oauthService.FindOrCreateUserByOAuth(ctx, userInfo)
+	user, created, err := oauthService.FindOrCreateUserByOAuth(ctx, userInfo)
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to find or create user", err)
 	}
+	if created {
+		s.publishWebhookEvent(ctx, webhooks.EventUserCreated,
+			webhooks.UserData(user.ID.String(), user.Email, user.Name, user.IsAdmin))
+	}
 
 	// Prefer a durable session row (with optional OIDC sid) so backchannel
 	// logout can invalidate it. Fall back to a bare JWT when sessions are
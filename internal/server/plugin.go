@@ -35,7 +35,7 @@ func (s *Server) ListPlugins(ctx context.Context, _ *emptypb.Empty) (*immichv1.L
 
 // SearchPluginMethods returns available workflow methods exposed by plugins.
 func (s *Server) SearchPluginMethods(ctx context.Context, req *immichv1.SearchPluginMethodsRequest) (*immichv1.SearchPluginMethodsResponse, error) {
-	if _, err := s.getUserFromContext(ctx); err != nil {
+	if _, err := s.claimsFromContext(ctx); err != nil {
 		return nil, status.Error(codes.Unauthenticated, "authentication required")
 	}
 
@@ -68,7 +68,7 @@ func (s *Server) SearchPluginMethods(ctx context.Context, req *immichv1.SearchPl
 
 // SearchPluginTemplates returns workflow templates exposed by plugins.
 func (s *Server) SearchPluginTemplates(ctx context.Context, _ *emptypb.Empty) (*immichv1.SearchPluginTemplatesResponse, error) {
-	if _, err := s.getUserFromContext(ctx); err != nil {
+	if _, err := s.claimsFromContext(ctx); err != nil {
 		return nil, status.Error(codes.Unauthenticated, "authentication required")
 	}
 
@@ -106,16 +106,15 @@ func ctxWithClaims(t *testing.T, userID uuid.UUID) context.Context {
 	return auth.WithClaims(context.Background(), claims)
 }
 
-// seedFiveAssets creates 5 assets for ownerID with strictly increasing
-// fileCreatedAt timestamps so the test can assert reverse-chronological
-// ordering. The base time is in the past to avoid clock-skew issues with
-// the now() column default on assets.createdAt.
+// seedFiveAssets uploads 5 assets for ownerID one after the other. Their
+// fileCreatedAt timestamps go back in time, so a listing sorted by capture
+// time would come out reversed from one sorted by upload time.
 func seedFiveAssets(t *testing.T, ctx context.Context, tdb *testdb.TestDB, ownerID uuid.UUID) []sqlc.Asset {
 	t.Helper()
 	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
 	assets := make([]sqlc.Asset, 0, 5)
 	for i := 0; i < 5; i++ {
-		ts := base.Add(time.Duration(i) * time.Hour)
+		ts := base.Add(-time.Duration(i) * time.Hour)
 		filename := uuid.NewString() + ".jpg"
 		assets = append(assets, seedRecentlyAddedAsset(t, ctx, tdb, ownerID, filename, ts))
 	}
@@ -124,7 +123,7 @@ func seedFiveAssets(t *testing.T, ctx context.Context, tdb *testdb.TestDB, owner
 
 // TestServer_GetRecentlyAddedAssets_OK seeds 5 assets for one user, calls
 // Server.GetRecentlyAddedAssets, and asserts that the response contains
-// all 5 assets in reverse fileCreatedAt order (newest first).
+// all 5 assets in reverse upload order (newest first).
 func TestServer_GetRecentlyAddedAssets_OK(t *testing.T) {
 	testdb.SkipIfNoDocker(t)
 	env := newRecentlyAddedTestEnv(t)
@@ -140,7 +139,7 @@ func TestServer_GetRecentlyAddedAssets_OK(t *testing.T) {
 	require.NotNil(t, resp)
 	require.Len(t, resp.GetAssets(), 5, "all 5 seeded assets should be returned")
 
-	// Expected order: reverse of seeded order (newest fileCreatedAt first).
+	// Expected order: reverse of seeded order (latest upload first).
 	for i, asset := range resp.GetAssets() {
 		expectedID := uuid.UUID(seeded[len(seeded)-1-i].ID.Bytes).String()
 		assert.Equal(t, expectedID, asset.GetId(), "asset at index %d should be the %d-th newest", i, i)
@@ -239,3 +238,85 @@ func TestServer_GetRecentlyAddedAssets_UserIsolation(t *testing.T) {
 		assert.Equal(t, expectedID, a.GetId(), "userB's asset at index %d should be the %d-th newest", i, i)
 	}
 }
+
+// TestServer_GetRecentlyAddedAssets_PagesAndSkipsTrash trashes one of 5
+// assets and pages through the rest, 2 at a time.
+func TestServer_GetRecentlyAddedAssets_PagesAndSkipsTrash(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	env := newRecentlyAddedTestEnv(t)
+	ctx := context.Background()
+
+	userID := createRecentlyAddedTestUser(t, ctx, env.tdb, "pages")
+	seeded := seedFiveAssets(t, ctx, env.tdb, userID)
+	_, err := env.tdb.Queries.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{ID: seeded[3].ID, Status: sqlc.AssetsStatusEnumTrashed})
+	require.NoError(t, err)
+
+	var got []string
+	for page := int32(1); page <= 3; page++ {
+		resp, err := env.srv.GetRecentlyAddedAssets(ctxWithClaims(t, userID), &immichv1.GetRecentlyAddedAssetsRequest{
+			Limit: 2,
+			Page:  page,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), resp.GetPageInfo().GetTotal())
+		for _, asset := range resp.GetAssets() {
+			got = append(got, asset.GetId())
+		}
+	}
+
+	want := []string{
+		uuid.UUID(seeded[4].ID.Bytes).String(),
+		uuid.UUID(seeded[2].ID.Bytes).String(),
+		uuid.UUID(seeded[1].ID.Bytes).String(),
+		uuid.UUID(seeded[0].ID.Bytes).String(),
+	}
+	assert.Equal(t, want, got)
+}
+
+// TestServer_GetTrashedAssets lists trashed assets by the time they were
+// trashed, with the days left under the default 30 day retention.
+func TestServer_GetTrashedAssets(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	env := newRecentlyAddedTestEnv(t)
+	ctx := context.Background()
+
+	userID := createRecentlyAddedTestUser(t, ctx, env.tdb, "trash")
+	seeded := seedFiveAssets(t, ctx, env.tdb, userID)
+	trash := func(asset sqlc.Asset, daysAgo int) {
+		t.Helper()
+		_, err := env.tdb.Queries.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{ID: asset.ID, Status: sqlc.AssetsStatusEnumTrashed})
+		require.NoError(t, err)
+		_, err = env.tdb.Pool.Exec(ctx, `UPDATE asset_trash SET "trashedAt" = now() - make_interval(days => $2) WHERE "assetId" = $1`, asset.ID, daysAgo)
+		require.NoError(t, err)
+	}
+	trash(seeded[0], 1)
+	trash(seeded[1], 10)
+	trash(seeded[2], 40)
+
+	resp, err := env.srv.GetTrashedAssets(ctxWithClaims(t, userID), &immichv1.GetTrashedAssetsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.GetPageInfo().GetTotal())
+	require.Len(t, resp.GetItems(), 3)
+
+	for i, want := range []struct {
+		asset sqlc.Asset
+		days  int32
+	}{{seeded[0], 29}, {seeded[1], 20}, {seeded[2], 0}} {
+		item := resp.GetItems()[i]
+		assert.Equal(t, uuid.UUID(want.asset.ID.Bytes).String(), item.GetAsset().GetId())
+		assert.True(t, item.GetAsset().GetIsTrashed())
+		require.NotNil(t, item.DaysUntilPurge)
+		assert.Equal(t, want.days, item.GetDaysUntilPurge())
+		assert.Equal(t, item.GetTrashedAt().AsTime().AddDate(0, 0, 30), item.GetPurgeAt().AsTime())
+	}
+
+	// Restored assets leave the trash and come back to recently added
+	_, err = env.tdb.Queries.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{ID: seeded[1].ID, Status: sqlc.AssetsStatusEnumActive})
+	require.NoError(t, err)
+	resp, err = env.srv.GetTrashedAssets(ctxWithClaims(t, userID), &immichv1.GetTrashedAssetsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.GetItems(), 2)
+	recent, err := env.srv.GetRecentlyAddedAssets(ctxWithClaims(t, userID), &immichv1.GetRecentlyAddedAssetsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, recent.GetAssets(), 3)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+// pageContentSecurityPolicy is the Content-Security-Policy of the HTML pages
+// rendered by the server itself, such as shared link landing pages and the
+// account restore page. They load no scripts, styles or images.
+const pageContentSecurityPolicy = "default-src 'none'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+// securityHeadersHandler adds the configured security headers to every
+// response. Handlers may still override them, as the server-rendered pages do
+// with a stricter Content-Security-Policy.
+func securityHeadersHandler(cfg config.SecurityHeadersConfig, next http.Handler) http.Handler {
+	if !cfg.Enabled {
+		return next
+	}
+	hsts := ""
+	if seconds := int64(cfg.HSTSMaxAge.Seconds()); seconds > 0 {
+		hsts = "max-age=" + strconv.FormatInt(seconds, 10)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if cfg.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+		}
+		if cfg.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		// Browsers ignore HSTS received over plain HTTP; not sending it keeps
+		// plaintext deployments from advertising a policy they cannot honor.
+		if hsts != "" && isHTTPSRequest(r) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isHTTPSRequest reports whether the client reached the server over HTTPS,
+// directly or through a proxy terminating TLS.
+func isHTTPSRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+)
+
+func TestSecurityHeadersHandler(t *testing.T) {
+	cfg := config.SecurityHeadersConfig{
+		Enabled:               true,
+		ContentSecurityPolicy: config.DefaultContentSecurityPolicy,
+		ReferrerPolicy:        "same-origin",
+		HSTSMaxAge:            48 * time.Hour,
+	}
+	handler := securityHeadersHandler(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy)
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/server/ping", nil))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, config.DefaultContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "same-origin", rec.Header().Get("Referrer-Policy"))
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"), "no HSTS over plain HTTP")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/server/ping", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "max-age=172800", rec.Header().Get("Strict-Transport-Security"))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/server/ping", nil)
+	req.Header.Set("X-Forwarded-Proto", "HTTPS, http")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "max-age=172800", rec.Header().Get("Strict-Transport-Security"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/page", nil))
+	assert.Equal(t, pageContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersHandlerHonorsOverrides(t *testing.T) {
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+	securityHeadersHandler(config.SecurityHeadersConfig{Enabled: true}, next).ServeHTTP(rec, req)
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, rec.Header().Get("Referrer-Policy"))
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+
+	rec = httptest.NewRecorder()
+	securityHeadersHandler(config.SecurityHeadersConfig{Enabled: false, ReferrerPolicy: "no-referrer"}, next).ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, rec.Header().Get("Referrer-Policy"))
+}
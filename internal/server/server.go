@@ -16,6 +16,9 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	// Lets gRPC clients send and receive gzip-compressed messages
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -76,6 +79,7 @@ type Server struct {
 	config      *config.Config
 	db          *db.Conn
 	grpcServer  *grpc.Server
+	grpcHealth  *health.Server
 	authService *auth.Service
 	wsHub       *websocket.Hub
 
@@ -125,11 +129,17 @@ type Server struct {
 	pluginService         *plugin.Service
 	workflowService       *workflow.Service
 	queries               *sqlc.Queries
+	mlClient              *ml.Client
 	grpcClientConn        *grpc.ClientConn
+	features              featureCache
+	albumStatistics       albumStatisticsCache
+	strippedOriginals     strippedOriginalCache
+	thumbnailStaleness    thumbnailStaleness
 
 	immichv1.UnimplementedAlbumServiceServer
 	immichv1.UnimplementedApiKeyServiceServer
 	immichv1.UnimplementedAssetServiceServer
+	immichv1.UnimplementedAssetSharesServiceServer
 	immichv1.UnimplementedAuthServiceServer
 	immichv1.UnimplementedDownloadServiceServer
 	immichv1.UnimplementedJobServiceServer
@@ -196,10 +206,24 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 		Enabled:              cfg.MLActive(),
 		URL:                  cfg.MachineLearning.URL,
 		Timeout:              cfg.MachineLearning.Timeout,
+		APIKey:               cfg.MachineLearning.APIKey,
+		CLIPEnabled:          cfg.CLIPActive(),
+		FacesEnabled:         cfg.FaceRecognitionActive(),
+		ObjectsEnabled:       cfg.ObjectDetectionActive(),
+		MaxRetries:           cfg.MachineLearning.MaxRetries,
+		RetryBackoff:         cfg.MachineLearning.RetryBackoff,
+		MaxConnections:       cfg.MachineLearning.MaxConnections,
+		BreakerThreshold:     cfg.MachineLearning.BreakerThreshold,
+		BreakerCooldown:      cfg.MachineLearning.BreakerCooldown,
+		MaxConcurrency:       cfg.MachineLearning.MaxConcurrency,
+		BatchSize:            cfg.MachineLearning.BatchSize,
+		BatchMaxWait:         cfg.MachineLearning.BatchMaxWait,
 		CLIPModel:            cfg.MachineLearning.Clip.ModelName,
 		FaceModel:            cfg.MachineLearning.FacialRecognition.ModelName,
 		FaceMinScore:         cfg.MachineLearning.FacialRecognition.MinScore,
 		FaceMaxDistance:      cfg.MachineLearning.FacialRecognition.MaxDistance,
+		ObjectModel:          cfg.MachineLearning.ObjectDetection.ModelName,
+		ObjectMinScore:       cfg.MachineLearning.ObjectDetection.MinScore,
 		CLIPMaxDistance:      cfg.MachineLearning.Clip.MaxDistance,
 		DuplicateMaxDistance: cfg.MachineLearning.DuplicateDetection.MaxDistance,
 	})
@@ -212,15 +236,16 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 	albumService := albums.NewService(db.Queries)
 	apiKeyService := apikeys.NewService(db.Queries)
 	libraryService := libraries.NewService(db.Queries, cfg, storageService)
-	searchService := search.NewService(db.Queries, mlClient, cfg)
+	searchService := search.NewService(db.Queries, mlClient, cfg).WithReadQueries(db.ReadOnly())
 	downloadService := download.NewService(db.Queries, storageService)
 	sharedLinksService := sharedlinks.NewService(db.Queries)
 	systemConfigService := systemconfig.NewService(db.Queries)
 	trashService := trash.NewServer(db.Queries)
 	tagsService := tags.NewServer(db.Queries)
-	mapService := mapservice.NewServer(db.Queries)
+	// The map only reads, and markers may lag behind uploads
+	mapService := mapservice.NewServer(db.ReadOnly())
 	peopleService := people.NewServer(db.Queries, storageService)
-	partnersService := partners.NewServer(db.Queries)
+	partnersService := partners.NewServer(db.Queries, syncService)
 	activityService := activity.NewServer(db.Queries)
 
 	duplicatesService, err := duplicates.NewService(db.Queries, cfg)
@@ -261,7 +286,7 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 	syncServer := sync.NewServer(syncService)
 
 	// Initialize Memories service
-	memoriesService := memories.NewService(db.Queries)
+	memoriesService := memories.NewService(db.Queries, cfg.DefaultLocation())
 	memoriesServer := memories.NewServer(memoriesService)
 
 	// Initialize Notifications service
@@ -270,7 +295,7 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 
 	// Initialize Timeline service
 	timelineService := timeline.NewService(db.Queries)
-	timelineServer := timeline.NewServer(timelineService)
+	timelineServer := timeline.NewServer(timelineService, cfg)
 
 	// Initialize Maintenance service
 	maintenanceService, err := maintenance.NewService(db.Queries, cfg)
@@ -313,6 +338,10 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 			QueueName:     "immich",
 			MaxRetries:    cfg.Jobs.RetryMaxRetries,
 			DB:            db.Queries,
+
+			CleanupEnabled:  cfg.Jobs.CleanupEnabled,
+			CleanupInterval: cfg.Jobs.CleanupInterval,
+			RetentionPeriod: cfg.Jobs.RetentionPeriod,
 		}
 		var err error
 		jobService, err = jobs.NewService(jobCfg)
@@ -322,6 +351,24 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 			// Register real job handlers so enqueued jobs are processed
 			jobHandlers := jobs.NewHandlers(db.Queries, assetService, libraryService, storageService, mlClient, cfg)
 			jobHandlers.RegisterAllHandlers(jobService)
+			if schedule := cfg.Integrity.Schedule; schedule != "" {
+				payload := jobs.IntegrityScanPayload{VerifyThumbnails: cfg.Integrity.VerifyThumbnails}
+				if err := jobService.ScheduleIntegrityScan(schedule, payload); err != nil {
+					logrus.WithError(err).Warn("Invalid integrity scan schedule, scheduled scans disabled")
+				}
+			}
+			if err := jobService.ScheduleUserDeletionCheck(); err != nil {
+				logrus.WithError(err).Warn("Failed to schedule the user deletion check, deleted users will not be purged")
+			}
+			if err := jobService.SchedulePersonThumbnails(); err != nil {
+				logrus.WithError(err).Warn("Failed to schedule person thumbnail generation, new people will have no thumbnail")
+			}
+			if err := jobService.ScheduleThumbnailLayouts(); err != nil {
+				logrus.WithError(err).Warn("Failed to schedule the thumbnail layout backfill, older assets will have no thumbhash")
+			}
+			if err := jobService.ScheduleQuotaUsageSync(); err != nil {
+				logrus.WithError(err).Warn("Failed to schedule the quota usage sync, quota usage will not be corrected")
+			}
 			// Start the asynq worker server; without this, enqueued jobs
 			// (thumbnails, metadata extraction, transcodes) sit in Redis
 			// forever.
@@ -333,12 +380,19 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 	} else {
 		logrus.Warn("Job service not configured, background processing disabled")
 	}
+	if jobService != nil {
+		stacksServer.SetAutoStackQueue(jobService)
+		libraryService.SetDeletionQueue(jobService)
+	}
 
 	// Initialize admin service (depends on job service for dead-letter ops)
 	adminService, err := admin.NewService(db.Queries, cfg, storageService)
 	if err != nil {
 		return nil, err
 	}
+	adminService.SetTxRunner(db)
+	userService.SetTxRunner(db)
+	userService.SetPasswordChecker(authService)
 	adminServer := admin.NewServer(adminService, jobService)
 
 	s := &Server{
@@ -391,8 +445,12 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 		pluginService:         pluginService,
 		workflowService:       workflowService,
 		queries:               db.Queries,
+		mlClient:              mlClient,
 	}
-	s.grpcServer = grpc.NewServer()
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuthInterceptor),
+		grpc.ChainStreamInterceptor(s.streamAuthInterceptor),
+	)
 
 	// Register gRPC services
 	immichv1.RegisterAuthServiceServer(s.grpcServer, s)
@@ -408,6 +466,7 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 	immichv1.RegisterSearchServiceServer(s.grpcServer, s.searchServer)
 	immichv1.RegisterServerServiceServer(s.grpcServer, s)
 	immichv1.RegisterSharedLinksServiceServer(s.grpcServer, s)
+	immichv1.RegisterAssetSharesServiceServer(s.grpcServer, s)
 	immichv1.RegisterSystemConfigServiceServer(s.grpcServer, s)
 	immichv1.RegisterTimelineServiceServer(s.grpcServer, s.timelineServer)
 	immichv1.RegisterUsersServiceServer(s.grpcServer, s)
@@ -429,8 +488,13 @@ func NewServer(cfg *config.Config, db *db.Conn) (*Server, error) {
 	immichv1.RegisterQueueServiceServer(s.grpcServer, s)
 	immichv1.RegisterPluginServiceServer(s.grpcServer, s)
 	immichv1.RegisterWorkflowServiceServer(s.grpcServer, s)
+	s.grpcHealth = registerGRPCHealth(s.grpcServer, cfg.Server)
 
 	s.recordVersionHistory(context.Background())
+	s.loadThumbnailSettings(context.Background())
+	if err := authService.BootstrapAdmin(context.Background()); err != nil {
+		logrus.WithError(err).Warn("failed to grant admin to the bootstrap admin")
+	}
 
 	return s, nil
 }
@@ -466,38 +530,51 @@ func (s *Server) ServeGRPC(listener net.Listener) error {
 // Immich-specific x-api-key header so API-key-authenticated endpoints (e.g.
 // GET /api-keys/me) can inspect the key used for the current request.
 func incomingHeaderMatcher(key string) (string, bool) {
-	if strings.EqualFold(key, "x-api-key") {
-		return "x-api-key", true
+	if strings.EqualFold(key, apiKeyHeader) {
+		return apiKeyHeader, true
+	}
+	if strings.EqualFold(key, idempotencyKeyHeader) {
+		return idempotencyKeyMetadata, true
 	}
 	return runtime.DefaultHeaderMatcher(key)
 }
 
+// responseHeaders are the gRPC response headers copied onto gateway HTTP
+// responses. Headers marked repeatable are emitted once per value, which
+// Set-Cookie needs for responses that set several cookies; for the others
+// only the first value is kept.
+var responseHeaders = map[string]bool{
+	"set-cookie": true,
+}
+
 func httpResponseModifier(ctx context.Context, w http.ResponseWriter, p proto.Message) error {
 	md, ok := runtime.ServerMetadataFromContext(ctx)
 	if !ok {
 		return nil
 	}
 
-	allowedHeaders := map[string]any{
-		"set-cookie": struct{}{},
-	}
-
 	// Set some headers
 	for key, values := range md.HeaderMD {
 		cleanKey := strings.TrimPrefix(strings.ToLower(key), "grpc-metadata-")
-		if _, ok := allowedHeaders[cleanKey]; !ok {
+		if cleanKey == "x-http-code" {
+			continue
+		}
+		repeatable, ok := responseHeaders[cleanKey]
+		if !ok {
 			logrus.Warnf("Ignoring header %s in HTTP response", key)
 			continue
 		}
 
-		if len(values) > 1 {
+		w.Header().Del("Grpc-Metadata-" + key)
+		w.Header().Del(cleanKey)
+		if !repeatable && len(values) > 1 {
 			logrus.Warnf("Multiple values for header %s, using first value only", key)
+			values = values[:1]
 		}
 		for _, value := range values {
-			w.Header().Add(key, value)
+			w.Header().Add(cleanKey, value)
 		}
 		delete(md.HeaderMD, key)
-		w.Header().Del("Grpc-Metadata-" + key)
 	}
 
 	// set http status code
@@ -515,15 +592,25 @@ func httpResponseModifier(ctx context.Context, w http.ResponseWriter, p proto.Me
 	return nil
 }
 
+// gatewayMarshaler renders responses as JSON. Scalars, lists and maps are
+// written even when empty, so required fields are always present. Fields
+// declared optional in the protos and unset messages are left out instead,
+// which lets clients tell an absent value, such as the live photo video of
+// a still image, from an empty one.
+func gatewayMarshaler() *runtime.JSONPb {
+	return &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			EmitDefaultValues: true,
+		},
+	}
+}
+
 // HTTPHandler creates and returns the HTTP handler with grpc-gateway
 func (s *Server) HTTPHandler() http.Handler {
-	mux := runtime.NewServeMux(
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
-			MarshalOptions: protojson.MarshalOptions{
-				EmitDefaultValues: true,
-			},
-		}),
-		runtime.WithMiddlewares(s.authContextMiddleware),
+	var mux *runtime.ServeMux
+	mux = runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, gatewayMarshaler()),
+		runtime.WithMiddlewares(s.authContextMiddleware, requireGatewayAuth(func() *runtime.ServeMux { return mux })),
 		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
 		runtime.WithErrorHandler(loggingHTTPErrorHandler),
 		runtime.WithForwardResponseOption(httpResponseModifier),
@@ -576,6 +663,9 @@ func (s *Server) HTTPHandler() http.Handler {
 	if err := immichv1.RegisterSharedLinksServiceHandlerServer(ctx, mux, s); err != nil {
 		logrus.WithError(err).Error("Failed to register SharedLinksService handler")
 	}
+	if err := immichv1.RegisterAssetSharesServiceHandlerServer(ctx, mux, s); err != nil {
+		logrus.WithError(err).Error("Failed to register AssetSharesService handler")
+	}
 	if err := immichv1.RegisterSystemConfigServiceHandlerServer(ctx, mux, s); err != nil {
 		logrus.WithError(err).Error("Failed to register SystemConfigService handler")
 	}
@@ -643,19 +733,17 @@ func (s *Server) HTTPHandler() http.Handler {
 	if err := immichv1.RegisterWorkflowServiceHandlerServer(ctx, mux, s); err != nil {
 		logrus.WithError(err).Error("Failed to register WorkflowService handler")
 	}
-	// Outermost wrapper serves a static frontend (e.g. the Immich web build)
+	// The webui wrapper serves a static frontend (e.g. the Immich web build)
 	// from s.config.WebUIDir. Unmatched requests fall through to the API mux
 	// so REST/gRPC routes keep working. Empty WebUIDir is a transparent
-	// passthrough — the API is reachable directly.
-	return webui.Handler(s.config.WebUIDir, httpLoggingHandler(s.handleWs(mux)))
+	// passthrough — the API is reachable directly. Shared link landing pages
+	// sit in front of it so their preview tags reach link crawlers.
+	handler := s.shareLandingHandler(s.accountRestoreHandler(s.passwordSetupHandler(webui.Handler(s.config.WebUIDir, httpLoggingHandler(s.handleWs(mux))))))
+	return compressionHandler(s.config.Server.Compression, securityHeadersHandler(s.config.Server.SecurityHeaders, handler))
 }
 
 func (s *Server) handleWs(mux *runtime.ServeMux) http.Handler {
-	marshaler := &runtime.JSONPb{
-		MarshalOptions: protojson.MarshalOptions{
-			EmitDefaultValues: true,
-		},
-	}
+	marshaler := gatewayMarshaler()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/api/socket.io/" {
@@ -682,6 +770,9 @@ func (s *Server) handleWs(mux *runtime.ServeMux) http.Handler {
 			})(w, r, nil)
 			return
 		}
+		if s.handleReady(w, r) {
+			return
+		}
 		if s.handleActivityRoutes(w, r) {
 			return
 		}
@@ -713,11 +804,12 @@ func writeProtoJSON(w http.ResponseWriter, marshaler runtime.Marshaler, msg prot
 	_, _ = w.Write(data)
 }
 
+// loggingHTTPErrorHandler logs failed gateway requests and renders the error
+// in the Immich error shape (see immichError).
 func loggingHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
-	code := status.Code(err)
-	statusCode := runtime.HTTPStatusFromCode(code)
+	statusCode, body := immichErrorResponse(err)
 	entry := logrus.WithError(err).WithFields(logrus.Fields{
-		"grpcCode": code.String(),
+		"grpcCode": status.Code(err).String(),
 		"method":   r.Method,
 		"path":     r.URL.Path,
 		"query":    r.URL.RawQuery,
@@ -728,7 +820,7 @@ func loggingHTTPErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshal
 	} else {
 		entry.Warn("Gateway request failed")
 	}
-	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+	writeJSON(w, statusCode, body)
 }
 
 func httpLoggingHandler(next http.Handler) http.Handler {
@@ -842,26 +934,25 @@ func (s *Server) authContextMiddleware(handlerFunc runtime.HandlerFunc) runtime.
 		if authHeader != "" && r.Header.Get("Authorization") == "" {
 			r = requestWithAuthorization(r, authHeader)
 		}
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			handlerFunc(w, r, pathParams)
-			return
-		}
 
-		claims, err := s.authService.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		// auth.RequireUser/RequireAdmin (used by admin.* and systemmetadata.*
+		// services) read UserContextKey, which authenticate sets alongside the
+		// claims and the user ID read by the server handlers.
+		var ctx context.Context
+		var err error
+		switch {
+		case strings.HasPrefix(authHeader, "Bearer "):
+			ctx, err = s.authenticate(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+		case r.Header.Get(apiKeyHeader) != "":
+			ctx, err = s.authenticateAPIKey(r.Context(), r.Header.Get(apiKeyHeader))
+		default:
+			err = errAuthenticationRequired
+		}
 		if err != nil {
 			handlerFunc(w, r, pathParams)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), auth.ClaimsContextKey, claims)
-
-		// auth.RequireUser/RequireAdmin (used by admin.* and systemmetadata.*
-		// services) read UserContextKey, not ClaimsContextKey. Without this
-		// lookup those endpoints reject every caller, admins included.
-		if userInfo, err := s.authService.LoadUserInfo(ctx, claims); err == nil {
-			ctx = auth.WithUser(ctx, *userInfo)
-		}
-
 		handlerFunc(w, r.WithContext(ctx), pathParams)
 	}
 }
@@ -893,10 +984,15 @@ func (s *Server) SetGRPCConn(conn *grpc.ClientConn) {
 
 func (s *Server) Stop() {
 	logrus.Info("Stopping gRPC server...")
+	s.markNotServing()
 	if s.jobService != nil {
 		s.jobService.Stop()
 	}
 	s.grpcServer.GracefulStop()
+	// Requests are drained, so no further processing is started.
+	if s.assetService != nil {
+		s.assetService.Close()
+	}
 	if s.grpcClientConn != nil {
 		if err := s.grpcClientConn.Close(); err != nil {
 			logrus.WithError(err).Error("Failed to close gRPC client connection")
@@ -904,7 +1000,10 @@ func (s *Server) Stop() {
 	}
 }
 
+// Shutdown starts draining the server: the gRPC health service reports
+// NOT_SERVING from now on. Stop then closes the listeners.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.markNotServing()
 	return nil
 }
 
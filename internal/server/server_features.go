@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/denysvitali/immich-go-backend/internal/ml"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+const (
+	// serverFeaturesTTL is how long computed feature flags are served before
+	// the machine-learning service is checked again.
+	serverFeaturesTTL = 30 * time.Second
+	// serverFeaturesMLTimeout bounds the machine-learning check of a refresh.
+	serverFeaturesMLTimeout = 2 * time.Second
+)
+
+// featureCache holds the last computed feature flags.
+type featureCache struct {
+	sync.Mutex
+	response   *immichv1.ServerFeaturesResponse
+	computedAt time.Time
+}
+
+// serverFeatures returns the feature flags, computing them when the cached
+// ones are older than serverFeaturesTTL or were invalidated.
+func (s *Server) serverFeatures(ctx context.Context) *immichv1.ServerFeaturesResponse {
+	s.features.Lock()
+	defer s.features.Unlock()
+	if s.features.response == nil || time.Since(s.features.computedAt) >= serverFeaturesTTL {
+		s.features.response = s.computeServerFeatures(ctx)
+		s.features.computedAt = time.Now()
+	}
+	return proto.Clone(s.features.response).(*immichv1.ServerFeaturesResponse)
+}
+
+// invalidateServerFeatures makes the next request compute the feature flags
+// again, after the configuration they depend on changed.
+func (s *Server) invalidateServerFeatures() {
+	s.features.Lock()
+	s.features.response = nil
+	s.features.Unlock()
+}
+
+// computeServerFeatures reports what the server can do right now. Clients
+// hide what is reported as unsupported, so a feature is only reported when
+// it is configured and what it depends on is reachable.
+func (s *Server) computeServerFeatures(ctx context.Context) *immichv1.ServerFeaturesResponse {
+	// The result is cached for other requests, so it must not depend on
+	// this one being cancelled.
+	mlCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverFeaturesMLTimeout)
+	defer cancel()
+	health, err := s.mlClient.Health(mlCtx)
+	if err != nil {
+		logrus.WithError(err).Warn("Server features: machine learning unavailable")
+	}
+	mlAvailable := health == ml.HealthOK
+
+	oauthEnabled := false
+	if oidc, err := s.oauthOIDCConfig(ctx); err != nil {
+		logrus.WithError(err).Warn("Server features: failed to load OAuth config")
+	} else {
+		oauthEnabled = oidc.Enabled
+	}
+
+	return &immichv1.ServerFeaturesResponse{
+		SmartSearch:        mlAvailable && s.config.CLIPActive(),
+		FacialRecognition:  mlAvailable && s.config.FaceRecognitionActive(),
+		DuplicateDetection: s.config.DuplicateDetectionActive(),
+		Map:                true,
+		ReverseGeocoding:   true,
+		ImportFaces:        false,
+		// Sidecar files are stored with their asset, but never read for
+		// metadata.
+		Sidecar:             false,
+		Search:              true,
+		Trash:               true,
+		Oauth:               oauthEnabled,
+		OauthAutoLaunch:     false,
+		PasswordLogin:       true,
+		ConfigFile:          false,
+		Email:               false,
+		Ocr:                 false,
+		RealtimeTranscoding: s.config.Features.VideoTranscodingEnabled,
+	}
+}
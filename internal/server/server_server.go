@@ -51,6 +51,10 @@ func (s *Server) GetServerConfig(ctx context.Context, empty *emptypb.Empty) (*im
 	if err != nil {
 		return nil, SanitizedInternal(ctx, "failed to check initialization state", err)
 	}
+	onboarding, err := s.systemMetadataService.GetAdminOnboarding(ctx)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to check onboarding state", err)
+	}
 
 	return &immichv1.ServerConfigResponse{
 		LoginPageMessage: "Welcome to Immich",
@@ -58,11 +62,10 @@ func (s *Server) GetServerConfig(ctx context.Context, empty *emptypb.Empty) (*im
 		UserDeleteDelay:  7,
 		OauthButtonText:  "Login with OAuth",
 		IsInitialized:    isInitialized,
-		// IsOnboarded here tracks the post-signup admin setup wizard, a
-		// separate concept from IsInitialized — see
-		// SystemMetadataService.GetAdminOnboarding for the real flag.
-		IsOnboarded:      true,
-		ExternalDomain:   "",
+		// IsOnboarded tracks the post-signup admin setup wizard, a separate
+		// concept from IsInitialized.
+		IsOnboarded:      onboarding.IsOnboarded,
+		ExternalDomain:   s.externalDomain(ctx),
 		PublicUsers:      true,
 		MapDarkStyleUrl:  "https://tiles.immich.cloud/v1/style/dark.json",
 		MapLightStyleUrl: "https://tiles.immich.cloud/v1/style/light.json",
@@ -74,24 +77,7 @@ func (s *Server) GetServerConfig(ctx context.Context, empty *emptypb.Empty) (*im
 }
 
 func (s *Server) GetServerFeatures(ctx context.Context, empty *emptypb.Empty) (*immichv1.ServerFeaturesResponse, error) {
-	return &immichv1.ServerFeaturesResponse{
-		SmartSearch:         true,
-		FacialRecognition:   true,
-		DuplicateDetection:  true,
-		Map:                 true,
-		ReverseGeocoding:    true,
-		ImportFaces:         false,
-		Sidecar:             true,
-		Search:              true,
-		Trash:               true,
-		Oauth:               false,
-		OauthAutoLaunch:     false,
-		PasswordLogin:       true,
-		ConfigFile:          false,
-		Email:               false,
-		Ocr:                 false,
-		RealtimeTranscoding: s.config.Features.VideoTranscodingEnabled,
-	}, nil
+	return s.serverFeatures(ctx), nil
 }
 
 func (s *Server) GetServerLicense(ctx context.Context, empty *emptypb.Empty) (*immichv1.LicenseResponse, error) {
@@ -119,25 +105,15 @@ func (s *Server) DeleteServerLicense(ctx context.Context, empty *emptypb.Empty)
 
 // GetSupportedMediaTypes returns file extensions (not MIME types): the web
 // uploader filters picked files with file.name.endsWith(<entry>), so anything
-// other than upstream's extension lists silently rejects every upload.
-// Lists mirror upstream server/src/utils/mime-types.ts.
+// other than extension lists silently rejects every upload. The lists are the
+// configured upload allowlist, which defaults to upstream's
+// server/src/utils/mime-types.ts.
 func (s *Server) GetSupportedMediaTypes(ctx context.Context, empty *emptypb.Empty) (*immichv1.ServerMediaTypesResponse, error) {
+	types := s.config.Storage.Upload.SupportedMediaTypes()
 	return &immichv1.ServerMediaTypesResponse{
-		Image: []string{
-			".avif", ".bmp", ".gif", ".jpeg", ".jpg", ".png", ".webp",
-			".3fr", ".ari", ".arw", ".cap", ".cin", ".cr2", ".cr3", ".crw",
-			".dcr", ".dng", ".erf", ".fff", ".iiq", ".k25", ".kdc", ".mrw",
-			".nef", ".nrw", ".orf", ".ori", ".pef", ".psd", ".raf", ".raw",
-			".rw2", ".rwl", ".sr2", ".srf", ".srw", ".x3f",
-			".heic", ".heif", ".hif", ".insp", ".jp2", ".jpe", ".jxl",
-			".svg", ".tif", ".tiff",
-		},
-		Video: []string{
-			".3gp", ".3gpp", ".avi", ".flv", ".insv", ".m2t", ".m2ts",
-			".m4v", ".mkv", ".mov", ".mp4", ".mpe", ".mpeg", ".mpg",
-			".mts", ".mxf", ".ts", ".vob", ".webm", ".wmv",
-		},
-		Sidecar: []string{".xmp"},
+		Image:   types.Image,
+		Video:   types.Video,
+		Sidecar: types.Sidecar,
 	}, nil
 }
 
@@ -147,13 +123,16 @@ func (s *Server) PingServer(ctx context.Context, empty *emptypb.Empty) (*immichv
 	}, nil
 }
 
+// GetServerStatistics aggregates every asset, so it runs on the read
+// replica when there is one.
 func (s *Server) GetServerStatistics(ctx context.Context, empty *emptypb.Empty) (*immichv1.ServerStatsResponse, error) {
-	stats, err := s.queries.GetServerAssetStatistics(ctx)
+	reads := s.db.ReadOnly()
+	stats, err := reads.GetServerAssetStatistics(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get server statistics: %v", err)
 	}
 
-	userRows, err := s.queries.GetServerUsageByUser(ctx)
+	userRows, err := reads.GetServerUsageByUser(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get per-user usage: %v", err)
 	}
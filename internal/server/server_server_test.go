@@ -2,10 +2,20 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/ml"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -28,3 +38,67 @@ func TestGetServerVersionFallsBackToZeroForDevelopmentBuild(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, &immichv1.ServerVersionResponse{}, got)
 }
+
+func TestGetSupportedMediaTypesUsesUploadAllowlist(t *testing.T) {
+	srv := &Server{config: &config.Config{Storage: storage.StorageConfig{Upload: storage.UploadConfig{
+		AllowedExtensions:        []string{".jpg", ".mov"},
+		AllowedSidecarExtensions: []string{".xmp"},
+	}}}}
+
+	got, err := srv.GetSupportedMediaTypes(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Equal(t, []string{".jpg"}, got.GetImage())
+	require.Equal(t, []string{".mov"}, got.GetVideo())
+	require.Equal(t, []string{".xmp"}, got.GetSidecar())
+}
+
+func TestUploadAssetRejectsUnsupportedMediaType(t *testing.T) {
+	storageConfig := storage.GetDefaultStorageConfig()
+	storageConfig.Local.RootPath = t.TempDir()
+	storageService, err := storage.NewService(storageConfig)
+	require.NoError(t, err)
+	assetService, err := assets.NewService(nil, storageService, &config.Config{}, nil)
+	require.NoError(t, err)
+	srv := &Server{assetService: assetService}
+	ctx := auth.SetUserIDInContext(context.Background(), uuid.New())
+
+	for _, filename := range []string{"notes.txt", "IMG_0001.xmp"} {
+		_, err := srv.UploadAsset(ctx, &immichv1.UploadAssetRequest{
+			AssetData:   &immichv1.CreateAssetRequest{OriginalFileName: filename},
+			FileContent: []byte("content"),
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), filename)
+	}
+}
+
+func TestGetServerFeaturesFollowsMachineLearningAvailability(t *testing.T) {
+	mlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	cfg := &config.Config{}
+	cfg.Features.MachineLearningEnabled = true
+	cfg.Features.CLIPSearchEnabled = true
+	cfg.MachineLearning.Enabled = true
+	cfg.MachineLearning.URL = mlServer.URL
+	cfg.MachineLearning.Clip.Enabled = true
+	cfg.Auth.OAuth.Enabled = true
+	srv := &Server{config: cfg, mlClient: ml.NewClient(ml.Config{Enabled: true, URL: mlServer.URL})}
+
+	got, err := srv.GetServerFeatures(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.True(t, got.GetSmartSearch())
+	require.False(t, got.GetFacialRecognition(), "face recognition is not enabled")
+	require.True(t, got.GetOauth())
+	require.True(t, got.GetTrash())
+	require.False(t, got.GetSidecar())
+
+	mlServer.Close()
+	got, err = srv.GetServerFeatures(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.True(t, got.GetSmartSearch(), "cached until the TTL passes")
+
+	srv.invalidateServerFeatures()
+	got, err = srv.GetServerFeatures(context.Background(), &emptypb.Empty{})
+	require.NoError(t, err)
+	require.False(t, got.GetSmartSearch(), "an unreachable ML service disables smart search")
+}
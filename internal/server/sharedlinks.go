@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/denysvitali/immich-go-backend/internal/sharedlinks"
 )
@@ -24,29 +27,94 @@ const (
 	sharedLinkTypeIndividual = "INDIVIDUAL"
 )
 
-// GetAllSharedLinks returns all shared links owned by the authenticated user.
-func (s *Server) GetAllSharedLinks(ctx context.Context, _ *immichv1.GetAllSharedLinksRequest) (*immichv1.GetAllSharedLinksResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+// GetAllSharedLinks returns a page of the shared links owned by the
+// authenticated user, optionally filtered by album, type and status.
+func (s *Server) GetAllSharedLinks(ctx context.Context, request *immichv1.GetAllSharedLinksRequest) (*immichv1.GetAllSharedLinksResponse, error) {
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
+	opts, err := sharedLinkListOptions(request)
 	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
+		return nil, err
 	}
 
-	links, err := s.sharedLinksService.GetSharedLinks(ctx, userID)
+	page, err := s.sharedLinksService.GetSharedLinks(ctx, userID, opts)
 	if err != nil {
+		if errors.Is(err, sharedlinks.ErrInvalidListOptions) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, SanitizedInternal(ctx, "failed to get shared links", err)
 	}
 
-	protoLinks := make([]*immichv1.SharedLinkResponse, len(links))
-	for i, link := range links {
+	protoLinks := make([]*immichv1.SharedLinkResponse, len(page.Links))
+	for i, link := range page.Links {
 		protoLinks[i] = convertSharedLinkToProto(link)
 	}
 
-	return &immichv1.GetAllSharedLinksResponse{SharedLinks: protoLinks}, nil
+	limits, err := s.userLimits(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &immichv1.GetAllSharedLinksResponse{
+		SharedLinks: protoLinks,
+		Total:       page.Total,
+		ActiveCount: limits.SharedLinks.Count,
+		ActiveLimit: limits.SharedLinks.Limit,
+	}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
+}
+
+func sharedLinkListOptions(request *immichv1.GetAllSharedLinksRequest) (sharedlinks.ListSharedLinksOptions, error) {
+	opts := sharedlinks.ListSharedLinksOptions{
+		Cursor: request.GetCursor(),
+		Limit:  int(request.GetLimit()),
+	}
+	if request.Limit != nil && request.GetLimit() <= 0 {
+		return opts, status.Error(codes.InvalidArgument, "limit must be positive")
+	}
+
+	if request.AlbumId != nil {
+		albumID, err := uuid.Parse(request.GetAlbumId())
+		if err != nil {
+			return opts, status.Error(codes.InvalidArgument, "invalid album ID")
+		}
+		opts.AlbumID = &albumID
+	}
+
+	switch request.GetType() {
+	case immichv1.SharedLinkType_SHARED_LINK_TYPE_ALBUM:
+		opts.Type = sharedLinkTypeAlbum
+	case immichv1.SharedLinkType_SHARED_LINK_TYPE_INDIVIDUAL:
+		opts.Type = sharedLinkTypeIndividual
+	}
+
+	switch request.GetStatus() {
+	case "":
+	case "active":
+		expired := false
+		opts.Expired = &expired
+	case "expired":
+		expired := true
+		opts.Expired = &expired
+	default:
+		return opts, status.Errorf(codes.InvalidArgument, "status must be active or expired, got %q", request.GetStatus())
+	}
+
+	switch strings.ToLower(request.GetOrder()) {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return opts, status.Errorf(codes.InvalidArgument, "order must be asc or desc, got %q", request.GetOrder())
+	}
+
+	return opts, nil
 }
 
 // GetMySharedLink resolves a shared link from its key (passed as the token
@@ -88,16 +156,11 @@ func (s *Server) GetMySharedLink(ctx context.Context, request *immichv1.GetMySha
 
 // GetSharedLinkById returns a shared link owned by the authenticated user.
 func (s *Server) GetSharedLinkById(ctx context.Context, request *immichv1.GetSharedLinkByIdRequest) (*immichv1.SharedLinkResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	linkID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid shared link ID: %v", err)
@@ -113,16 +176,11 @@ func (s *Server) GetSharedLinkById(ctx context.Context, request *immichv1.GetSha
 
 // CreateSharedLink creates a new shared link for an album or a set of assets.
 func (s *Server) CreateSharedLink(ctx context.Context, request *immichv1.CreateSharedLinkRequest) (*immichv1.SharedLinkResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	var linkType string
 	switch request.GetType() {
 	case immichv1.SharedLinkType_SHARED_LINK_TYPE_ALBUM:
@@ -139,12 +197,6 @@ func (s *Server) CreateSharedLink(ctx context.Context, request *immichv1.CreateS
 		return nil, status.Error(codes.InvalidArgument, "shared link type must be ALBUM or INDIVIDUAL")
 	}
 
-	if request.AlbumId != nil {
-		if _, err := uuid.Parse(request.GetAlbumId()); err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
-		}
-	}
-
 	var expiresAt *time.Time
 	if request.ExpiresAt != nil {
 		t := request.GetExpiresAt().AsTime()
@@ -166,7 +218,7 @@ func (s *Server) CreateSharedLink(ctx context.Context, request *immichv1.CreateS
 		showMetadata = request.GetShowMetadata()
 	}
 
-	link, err := s.sharedLinksService.CreateSharedLink(ctx, userID, &sharedlinks.CreateSharedLinkRequest{
+	createReq := &sharedlinks.CreateSharedLinkRequest{
 		Type:          linkType,
 		AssetIDs:      request.GetAssetIds(),
 		AlbumID:       request.AlbumId,
@@ -176,9 +228,22 @@ func (s *Server) CreateSharedLink(ctx context.Context, request *immichv1.CreateS
 		AllowDownload: allowDownload,
 		AllowUpload:   allowUpload,
 		ShowExif:      showMetadata,
+	}
+	if err := grpcutil.Validate(ctx, "invalid shared link", createReq); err != nil {
+		return nil, err
+	}
+
+	var link *sharedlinks.SharedLink
+	err = s.createWithinLimit(ctx, userID, sharedLinkLimitError, func(q *sqlc.Queries) error {
+		var err error
+		link, err = sharedlinks.NewService(q).CreateSharedLink(ctx, userID, createReq)
+		if err != nil {
+			return sharedLinkError(ctx, err)
+		}
+		return nil
 	})
 	if err != nil {
-		return nil, sharedLinkError(ctx, err)
+		return nil, err
 	}
 
 	response := convertSharedLinkToProto(link)
@@ -188,16 +253,11 @@ func (s *Server) CreateSharedLink(ctx context.Context, request *immichv1.CreateS
 
 // UpdateSharedLink updates an existing shared link owned by the authenticated user.
 func (s *Server) UpdateSharedLink(ctx context.Context, request *immichv1.UpdateSharedLinkRequest) (*immichv1.SharedLinkResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	linkID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid shared link ID: %v", err)
@@ -215,6 +275,9 @@ func (s *Server) UpdateSharedLink(ctx context.Context, request *immichv1.UpdateS
 		updateReq.ExpiresAt = &t
 		updateReq.ChangeExpiryTime = true
 	}
+	if err := grpcutil.Validate(ctx, "invalid shared link", updateReq); err != nil {
+		return nil, err
+	}
 
 	link, err := s.sharedLinksService.UpdateSharedLink(ctx, userID, linkID, updateReq)
 	if err != nil {
@@ -226,16 +289,11 @@ func (s *Server) UpdateSharedLink(ctx context.Context, request *immichv1.UpdateS
 
 // RemoveSharedLink deletes a shared link owned by the authenticated user.
 func (s *Server) RemoveSharedLink(ctx context.Context, request *immichv1.RemoveSharedLinkRequest) (*emptypb.Empty, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	linkID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid shared link ID: %v", err)
@@ -250,16 +308,11 @@ func (s *Server) RemoveSharedLink(ctx context.Context, request *immichv1.RemoveS
 
 // AddSharedLinkAssets adds assets to a shared link owned by the authenticated user.
 func (s *Server) AddSharedLinkAssets(ctx context.Context, request *immichv1.AddSharedLinkAssetsRequest) (*immichv1.SharedLinkResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	linkID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid shared link ID: %v", err)
@@ -279,16 +332,11 @@ func (s *Server) AddSharedLinkAssets(ctx context.Context, request *immichv1.AddS
 
 // RemoveSharedLinkAssets removes assets from a shared link owned by the authenticated user.
 func (s *Server) RemoveSharedLinkAssets(ctx context.Context, request *immichv1.RemoveSharedLinkAssetsRequest) (*immichv1.SharedLinkResponse, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
 	linkID, err := uuid.Parse(request.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid shared link ID: %v", err)
@@ -3,7 +3,11 @@ package server
 import (
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
@@ -44,3 +48,43 @@ func TestRedactSharedLinkAsset_DoesNotAlterOtherFields(t *testing.T) {
 	assert.Empty(t, redacted.OriginalFileName)
 	assert.Empty(t, redacted.OriginalPath)
 }
+
+func TestSharedLinkListOptions(t *testing.T) {
+	albumID := uuid.NewString()
+	statusFilter := "expired"
+	order := "ASC"
+	limit := int32(50)
+	linkType := immichv1.SharedLinkType_SHARED_LINK_TYPE_ALBUM
+
+	opts, err := sharedLinkListOptions(&immichv1.GetAllSharedLinksRequest{
+		AlbumId: &albumID,
+		Type:    &linkType,
+		Status:  &statusFilter,
+		Order:   &order,
+		Limit:   &limit,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.AlbumID)
+	assert.Equal(t, albumID, opts.AlbumID.String())
+	assert.Equal(t, sharedLinkTypeAlbum, opts.Type)
+	require.NotNil(t, opts.Expired)
+	assert.True(t, *opts.Expired)
+	assert.True(t, opts.Ascending)
+	assert.Equal(t, 50, opts.Limit)
+
+	opts, err = sharedLinkListOptions(&immichv1.GetAllSharedLinksRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, opts.Expired)
+	assert.False(t, opts.Ascending)
+	assert.Zero(t, opts.Limit)
+
+	for _, request := range []*immichv1.GetAllSharedLinksRequest{
+		{Status: proto.String("pending")},
+		{Order: proto.String("newest")},
+		{AlbumId: proto.String("not-a-uuid")},
+		{Limit: proto.Int32(0)},
+	} {
+		_, err := sharedLinkListOptions(request)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
@@ -177,7 +177,7 @@ func TestSyncServiceHandlerFallsBackToServer(t *testing.T) {
 		t.Skip("Docker tests disabled")
 	}
 
-	srv, _, cleanup := newSyncGRPCIntegrationTestEnv(t)
+	srv, database, cleanup := newSyncGRPCIntegrationTestEnv(t)
 	defer cleanup()
 
 	_ = startTestGRPCServer(t, srv)
@@ -188,10 +188,13 @@ func TestSyncServiceHandlerFallsBackToServer(t *testing.T) {
 	server := httptest.NewServer(srv.HTTPHandler())
 	defer server.Close()
 
+	_, token := createSyncTestUser(t, context.Background(), database)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/sync/stream", http.NoBody)
 	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := http.DefaultClient.Do(req)
 	require.NoError(t, err)
@@ -6,13 +6,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/denysvitali/immich-go-backend/internal/sync"
@@ -23,10 +26,14 @@ func TestSetGRPCConnChangesSyncRegistration(t *testing.T) {
 	// without a database. Only SyncService is registered with a real
 	// implementation; the rest are left as nil because their handlers are
 	// only invoked when their routes are hit.
+	authService := auth.NewService(config.AuthConfig{
+		JWTSecret: "test-secret-key-for-testing-only-needs-32-chars",
+	}, newNoUserQueries())
 	srv := &Server{
-		config:     &config.Config{},
-		grpcServer: grpc.NewServer(),
-		syncServer: sync.NewServer(sync.NewService(nil, nil)),
+		config:      &config.Config{},
+		authService: authService,
+		grpcServer:  grpc.NewServer(),
+		syncServer:  sync.NewServer(sync.NewService(nil, nil)),
 	}
 	immichv1.RegisterSyncServiceServer(srv.grpcServer, srv.syncServer)
 
@@ -40,7 +47,19 @@ func TestSetGRPCConnChangesSyncRegistration(t *testing.T) {
 	serverWithoutConn := httptest.NewServer(srv.HTTPHandler())
 	defer serverWithoutConn.Close()
 
-	resp, err := http.Post(serverWithoutConn.URL+"/api/sync/stream", "application/json", nil)
+	token, err := authService.GenerateToken(uuid.NewString(), "sync@example.com", time.Hour)
+	require.NoError(t, err)
+	postSyncStream := func(url string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, url+"/api/sync/stream", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		return http.DefaultClient.Do(req)
+	}
+
+	resp, err := postSyncStream(serverWithoutConn.URL)
 	require.NoError(t, err)
 	_ = resp.Body.Close()
 	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
@@ -58,7 +77,7 @@ func TestSetGRPCConnChangesSyncRegistration(t *testing.T) {
 	serverWithConn := httptest.NewServer(srv.HTTPHandler())
 	defer serverWithConn.Close()
 
-	resp, err = http.Post(serverWithConn.URL+"/api/sync/stream", "application/json", nil)
+	resp, err = postSyncStream(serverWithConn.URL)
 	require.NoError(t, err)
 	_ = resp.Body.Close()
 	assert.NotEqual(t, http.StatusNotImplemented, resp.StatusCode, "sync stream should no longer be in-process unimplemented")
@@ -51,7 +51,7 @@ func (s *Server) UpdateSystemConfig(ctx context.Context, req *immichv1.SystemCon
 		return nil, SanitizedInternal(ctx, "failed to encode system config", err)
 	}
 
-	updated, err := s.systemConfigService.UpdateConfigDto(ctx, raw)
+	updated, err := s.updateSystemConfig(ctx, raw)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to update system config: %v", err)
 	}
@@ -0,0 +1,69 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestUploadAppliesTakeoutSidecar uploads an asset with its Google Takeout
+// sidecar and checks the sidecar dates it and is kept for metadata
+// extraction.
+func TestUploadAppliesTakeoutSidecar(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	checksum := "0123456789abcdef0123456789abcdef01234567"
+	uploaded, err := env.srv.uploadAsset(userCtx, &immichv1.UploadAssetRequest{
+		AssetData: &immichv1.CreateAssetRequest{
+			DeviceAssetId:    "takeout-1",
+			DeviceId:         "takeout",
+			Type:             immichv1.AssetType_ASSET_TYPE_IMAGE,
+			OriginalFileName: "IMG_0001.jpg",
+			OriginalPath:     "users/" + userID.String() + "/IMG_0001.jpg",
+		},
+		Checksum: &checksum,
+		SidecarData: []byte(`{
+			"title": "IMG_0001.jpg",
+			"description": "Lake at dawn",
+			"photoTakenTime": {"timestamp": "1600000000"},
+			"geoData": {"latitude": 46.0, "longitude": 8.95},
+			"favorited": true
+		}`),
+	})
+	require.NoError(t, err)
+
+	assetID, err := pgutil.StringToUUID(uploaded.GetId())
+	require.NoError(t, err)
+	asset, err := env.tdb.Queries.GetAssetByID(ctx, assetID)
+	require.NoError(t, err)
+	assert.True(t, asset.FileCreatedAt.Time.Equal(time.Unix(1600000000, 0)))
+	assert.True(t, asset.IsFavorite)
+	assert.False(t, asset.IsUndated)
+
+	_, err = env.tdb.Queries.GetAssetMetadataByKey(ctx, sqlc.GetAssetMetadataByKeyParams{
+		AssetId: assetID,
+		Key:     assets.TakeoutMetadataKey,
+	})
+	require.NoError(t, err)
+
+	var metadata assets.AssetMetadata
+	require.NoError(t, assets.ApplyStoredTakeoutSidecar(ctx, env.tdb.Queries, assetID, &metadata))
+	require.NotNil(t, metadata.Description)
+	assert.Equal(t, "Lake at dawn", *metadata.Description)
+	require.NotNil(t, metadata.Latitude)
+	assert.Equal(t, 46.0, *metadata.Latitude)
+}
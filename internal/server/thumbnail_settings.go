@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/jobs"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+)
+
+// thumbnailStaleness holds when the thumbnail settings last changed.
+// Thumbnails recorded before were generated with other settings.
+type thumbnailStaleness struct {
+	sync.RWMutex
+	staleBefore time.Time
+}
+
+// set records that thumbnails recorded before t are stale.
+func (t *thumbnailStaleness) set(staleBefore time.Time) {
+	t.Lock()
+	t.staleBefore = staleBefore
+	t.Unlock()
+}
+
+// stale reports whether file was recorded before the thumbnail settings last
+// changed.
+func (t *thumbnailStaleness) stale(file sqlc.AssetFile) bool {
+	t.RLock()
+	defer t.RUnlock()
+	return file.UpdatedAt.Time.Before(t.staleBefore)
+}
+
+// applyThumbnailSettings configures the thumbnail generator with the image
+// settings of the system config. The thumbnail setting covers both small
+// thumbnail types.
+func applyThumbnailSettings(generator *assets.ThumbnailGenerator, image systemconfig.ImageDto) {
+	generator.Configure(assets.ThumbnailTypeThumb, image.Thumbnail.Size, image.Thumbnail.Quality)
+	generator.Configure(assets.ThumbnailTypeWebp, image.Thumbnail.Size, image.Thumbnail.Quality)
+	generator.Configure(assets.ThumbnailTypePreview, image.Preview.Size, image.Preview.Quality)
+
+	presets := make([]assets.ThumbnailPreset, len(image.Presets))
+	for i, preset := range image.Presets {
+		presets[i] = assets.ThumbnailPreset{Name: preset.Name, Size: preset.Size, Quality: preset.Quality}
+	}
+	generator.SetPresets(presets)
+}
+
+// loadThumbnailSettings applies the stored thumbnail settings at startup.
+func (s *Server) loadThumbnailSettings(ctx context.Context) {
+	cfg, err := s.systemConfigService.GetConfigDto(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load system config, generating thumbnails with the default settings")
+	} else {
+		applyThumbnailSettings(s.assetService.ThumbnailGenerator(), cfg.Image)
+	}
+
+	staleBefore, err := s.systemConfigService.ThumbnailsStaleBefore(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load when the thumbnail settings changed")
+		return
+	}
+	s.thumbnailStaleness.set(staleBefore)
+}
+
+// updateSystemConfig stores raw as the system config and applies the parts
+// of it the server holds in memory.
+func (s *Server) updateSystemConfig(ctx context.Context, raw []byte) (systemconfig.Dto, error) {
+	before, loadErr := s.systemConfigService.GetConfigDto(ctx)
+	updated, err := s.systemConfigService.UpdateConfigDto(ctx, raw)
+	if err != nil {
+		return systemconfig.Dto{}, err
+	}
+	s.invalidateServerFeatures()
+
+	applyThumbnailSettings(s.assetService.ThumbnailGenerator(), updated.Image)
+	if loadErr != nil {
+		logrus.WithError(loadErr).Warn("Failed to load the previous system config, existing thumbnails are kept")
+	} else if systemconfig.ThumbnailSettingsChanged(before, updated) {
+		s.thumbnailSettingsChanged(ctx)
+	}
+	return updated, nil
+}
+
+// thumbnailSettingsChanged marks the existing thumbnails stale, so they are
+// regenerated when next requested, and starts regenerating all of them when
+// configured to.
+func (s *Server) thumbnailSettingsChanged(ctx context.Context) {
+	staleBefore, err := s.systemConfigService.MarkThumbnailsStale(ctx)
+	if err != nil {
+		// Stale thumbnails are still regenerated until the next restart.
+		logrus.WithError(err).Warn("Failed to record the thumbnail settings change")
+		staleBefore = time.Now()
+	}
+	s.thumbnailStaleness.set(staleBefore)
+
+	if !s.config.Thumbnails.RegenerateOnChange || s.jobService == nil {
+		logrus.Info("Thumbnail settings changed: existing thumbnails are regenerated when requested, " +
+			"start a thumbnails reindex with POST /api/admin/reindex to regenerate all of them")
+		return
+	}
+	run, err := s.jobService.EnqueueReindex(ctx, []jobs.ReindexStep{jobs.ReindexStepThumbnails}, jobs.ReindexFilter{})
+	if errors.Is(err, jobs.ErrReindexInProgress) {
+		logrus.Warn("Thumbnail settings changed during a reindex: existing thumbnails are regenerated when requested, " +
+			"start a thumbnails reindex once it finished to regenerate all of them")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to start regenerating thumbnails after the thumbnail settings changed")
+		return
+	}
+	logrus.WithField("run_id", run.ID).Info("Thumbnail settings changed, regenerating all thumbnails")
+}
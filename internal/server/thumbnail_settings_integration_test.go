@@ -0,0 +1,123 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+)
+
+// TestThumbnailSettingsChangeRegeneratesThumbnails changes the thumbnail
+// size and checks the stored thumbnail is regenerated with it on request.
+func TestThumbnailSettingsChangeRegeneratesThumbnails(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	env.srv.config = &config.Config{}
+	env.srv.systemConfigService = systemconfig.NewService(env.tdb.Queries)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for x := 0; x < 800; x++ {
+		img.Set(x, x%600, color.RGBA{R: 200, A: 255})
+	}
+	var original bytes.Buffer
+	require.NoError(t, jpeg.Encode(&original, img, nil))
+	asset := seedAsset(t, ctx, env, userID, "settings.jpg", "image/jpeg", original.Bytes())
+
+	size := "thumbnail"
+	request := &immichv1.GetAssetThumbnailRequest{AssetId: asset.ID.String(), Size: &size}
+	thumbnailWidth := func() (int, []byte) {
+		t.Helper()
+		response, err := env.srv.GetAssetThumbnail(userCtx, request)
+		require.NoError(t, err)
+		decoded, _, err := image.Decode(bytes.NewReader(response.GetData()))
+		require.NoError(t, err)
+		return decoded.Bounds().Dx(), response.GetData()
+	}
+
+	width, before := thumbnailWidth()
+	assert.Equal(t, 160, width)
+	_, err := env.tdb.Queries.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: asset.ID, Type: "thumb"})
+	require.NoError(t, err, "the generated thumbnail is recorded")
+
+	// Settings the thumbnails do not depend on keep them.
+	_, err = env.srv.updateSystemConfig(ctx, []byte(`{"image":{"colorspace":"srgb"}}`))
+	require.NoError(t, err)
+	staleBefore, err := env.srv.systemConfigService.ThumbnailsStaleBefore(ctx)
+	require.NoError(t, err)
+	assert.True(t, staleBefore.IsZero())
+
+	_, err = env.srv.updateSystemConfig(ctx, []byte(`{"image":{"thumbnail":{"size":100}}}`))
+	require.NoError(t, err)
+	staleBefore, err = env.srv.systemConfigService.ThumbnailsStaleBefore(ctx)
+	require.NoError(t, err)
+	assert.False(t, staleBefore.IsZero())
+
+	width, after := thumbnailWidth()
+	assert.Equal(t, 100, width)
+	assert.NotEqual(t, mediaETag(before), mediaETag(after), "clients refetch the regenerated thumbnail")
+
+	file, err := env.tdb.Queries.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: asset.ID, Type: "thumb"})
+	require.NoError(t, err)
+	assert.False(t, env.srv.thumbnailStaleness.stale(file))
+}
+
+// TestThumbnailPresets requests thumbnails by preset name and checks each
+// preset gets its own stored thumbnail.
+func TestThumbnailPresets(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	env.srv.config = &config.Config{}
+	env.srv.systemConfigService = systemconfig.NewService(env.tdb.Queries)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	var original bytes.Buffer
+	require.NoError(t, jpeg.Encode(&original, image.NewRGBA(image.Rect(0, 0, 800, 600)), nil))
+	asset := seedAsset(t, ctx, env, userID, "presets.jpg", "image/jpeg", original.Bytes())
+
+	_, err := env.srv.updateSystemConfig(ctx, []byte(`{"image":{"presets":[
+		{"name":"grid","format":"jpeg","size":64,"quality":50},
+		{"name":"detail","format":"webp","size":400,"quality":85}]}}`))
+	require.NoError(t, err)
+	staleBefore, err := env.srv.systemConfigService.ThumbnailsStaleBefore(ctx)
+	require.NoError(t, err)
+	assert.True(t, staleBefore.IsZero(), "presets do not make the other thumbnails stale")
+
+	thumbnail := func(size string) (int, []byte) {
+		t.Helper()
+		response, err := env.srv.GetAssetThumbnail(userCtx, &immichv1.GetAssetThumbnailRequest{AssetId: asset.ID.String(), Size: &size})
+		require.NoError(t, err)
+		decoded, _, err := image.Decode(bytes.NewReader(response.GetData()))
+		require.NoError(t, err)
+		return decoded.Bounds().Dx(), response.GetData()
+	}
+
+	gridWidth, grid := thumbnail("grid")
+	assert.Equal(t, 64, gridWidth)
+	detailWidth, detail := thumbnail("detail")
+	assert.Equal(t, 400, detailWidth)
+	assert.NotEqual(t, mediaETag(grid), mediaETag(detail))
+	for _, fileType := range []string{"preset-64-q50", "preset-400-q85"} {
+		_, err := env.tdb.Queries.GetAssetFile(ctx, sqlc.GetAssetFileParams{AssetId: asset.ID, Type: fileType})
+		require.NoError(t, err, "the %s thumbnail is recorded", fileType)
+	}
+
+	// A preset that does not exist gets the default thumbnail
+	width, _ := thumbnail("huge")
+	assert.Equal(t, 250, width)
+}
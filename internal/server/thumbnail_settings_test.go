@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/denysvitali/immich-go-backend/internal/assets"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/systemconfig"
+)
+
+func TestThumbnailSettingsChanged(t *testing.T) {
+	before := systemconfig.DefaultDto()
+
+	after := systemconfig.DefaultDto()
+	after.Image.Colorspace = "srgb"
+	assert.False(t, systemconfig.ThumbnailSettingsChanged(before, after), "other image settings")
+
+	after = systemconfig.DefaultDto()
+	after.Image.Thumbnail.Format = "jpeg"
+	assert.False(t, systemconfig.ThumbnailSettingsChanged(before, after), "every format is encoded as JPEG")
+
+	after = systemconfig.DefaultDto()
+	after.Image.Thumbnail.Size = 300
+	assert.True(t, systemconfig.ThumbnailSettingsChanged(before, after))
+
+	after = systemconfig.DefaultDto()
+	after.Image.Preview.Quality = 90
+	assert.True(t, systemconfig.ThumbnailSettingsChanged(before, after))
+
+	after = systemconfig.DefaultDto()
+	after.Image.Presets = []systemconfig.ImagePresetDto{{Name: "grid", ImageOptionsDto: systemconfig.ImageOptionsDto{Size: 100, Quality: 60}}}
+	assert.False(t, systemconfig.ThumbnailSettingsChanged(before, after), "presets are stored apart")
+}
+
+func TestApplyThumbnailSettings(t *testing.T) {
+	generator := assets.NewThumbnailGenerator()
+	image := systemconfig.DefaultDto().Image
+	image.Thumbnail.Size = 300
+	image.Preview.Size = 2048
+	image.Presets = []systemconfig.ImagePresetDto{{Name: "grid", ImageOptionsDto: systemconfig.ImageOptionsDto{Format: "jpeg", Size: 100, Quality: 60}}}
+
+	applyThumbnailSettings(generator, image)
+	grid, ok := generator.Preset("grid")
+	assert.True(t, ok)
+	assert.Equal(t, assets.PresetThumbnailType(100, 60), grid)
+	for thumbType, want := range map[assets.ThumbnailType]int32{
+		assets.ThumbnailTypeThumb:   300,
+		assets.ThumbnailTypeWebp:    300,
+		assets.ThumbnailTypePreview: 2048,
+	} {
+		width, _ := generator.GetThumbnailDimensions(thumbType)
+		assert.Equal(t, want, width, thumbType)
+	}
+}
+
+func TestThumbnailStaleness(t *testing.T) {
+	changedAt := time.Now()
+	file := func(updatedAt time.Time) sqlc.AssetFile {
+		return sqlc.AssetFile{UpdatedAt: pgtype.Timestamptz{Time: updatedAt, Valid: true}}
+	}
+
+	var staleness thumbnailStaleness
+	assert.False(t, staleness.stale(file(changedAt.Add(-time.Hour))), "settings never changed")
+
+	staleness.set(changedAt)
+	assert.True(t, staleness.stale(file(changedAt.Add(-time.Second))))
+	assert.False(t, staleness.stale(file(changedAt.Add(time.Second))))
+}
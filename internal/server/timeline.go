@@ -30,6 +30,7 @@ func (s *Server) GetTimeBucket(ctx context.Context, request *immichv1.GetTimeBuc
 		IsTrashed:  request.GetIsTrashed(),
 		IsArchived: request.GetIsTrashed(),
 		Limit:      500,
+		Ascending:  s.timelineAscending(request.Order),
 	}
 
 	assets, err := s.timelineService.GetBucketAssets(ctx, opts)
@@ -61,6 +62,7 @@ func (s *Server) GetTimeBuckets(ctx context.Context, request *immichv1.GetTimeBu
 		IsFavorite: request.GetIsFavorite(),
 		IsTrashed:  request.GetIsTrashed(),
 		IsArchived: request.GetIsTrashed(),
+		Ascending:  s.timelineAscending(request.Order),
 	}
 
 	buckets, err := s.timelineService.GetTimeBuckets(ctx, opts)
@@ -81,6 +83,15 @@ func (s *Server) GetTimeBuckets(ctx context.Context, request *immichv1.GetTimeBu
 	}, nil
 }
 
+// timelineAscending tells whether a timeline request asked for the oldest
+// assets first, taking the configured order when it did not choose.
+func (s *Server) timelineAscending(order *immichv1.AssetOrder) bool {
+	if order != nil {
+		return *order == immichv1.AssetOrder_ASC
+	}
+	return !s.config.ListSorting().DescendingByDefault()
+}
+
 func (s *Server) convertBucketAssetToProto(asset timeline.BucketAsset) *immichv1.Asset {
 	protoAsset := &immichv1.Asset{
 		Id:               asset.ID.String(),
@@ -112,6 +123,9 @@ func (s *Server) convertBucketAssetToProto(asset timeline.BucketAsset) *immichv1
 	if asset.EncodedVideoPath != nil {
 		protoAsset.EncodedVideoPath = asset.EncodedVideoPath
 	}
+	if asset.Thumbhash != nil {
+		protoAsset.Thumbhash = asset.Thumbhash
+	}
 
 	return protoAsset
 }
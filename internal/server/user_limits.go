@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/admin"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// Users can have a limited number of shared links and API keys, see the
+// limits section of the configuration. An item is counted and created in one
+// transaction holding a per-user lock, so requests racing each other cannot
+// exceed the limit.
+
+func (s *Server) userLimits(ctx context.Context, userID uuid.UUID) (*admin.UserLimitsDto, error) {
+	limits, err := s.adminService.GetUserLimits(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get user limits", err)
+	}
+	return limits, nil
+}
+
+// createWithinLimit runs create in a transaction once the user's limits,
+// counted under the lock, pass check. create must use the queries it is
+// given for the insert to happen under the lock.
+func (s *Server) createWithinLimit(
+	ctx context.Context,
+	userID uuid.UUID,
+	check func(*admin.UserLimitsDto) error,
+	create func(*sqlc.Queries) error,
+) error {
+	owner := pgtype.UUID{Bytes: userID, Valid: true}
+	err := s.db.InTx(ctx, func(q *sqlc.Queries) error {
+		if err := q.LockUserLimits(ctx, owner); err != nil {
+			return SanitizedInternal(ctx, "failed to lock user limits", err)
+		}
+		limits, err := s.adminService.GetUserLimitsTx(ctx, q, owner)
+		if err != nil {
+			return SanitizedInternal(ctx, "failed to get user limits", err)
+		}
+		if err := check(limits); err != nil {
+			return err
+		}
+		return create(q)
+	})
+	if _, ok := status.FromError(err); !ok {
+		return SanitizedInternal(ctx, "failed to commit", err)
+	}
+	return err
+}
+
+// sharedLinkLimitError fails when the user cannot create another shared
+// link.
+func sharedLinkLimitError(limits *admin.UserLimitsDto) error {
+	if limits.SharedLinks.Reached() {
+		return status.Errorf(codes.FailedPrecondition,
+			"shared link limit reached: %d of %d shared links in use, delete one before creating another",
+			limits.SharedLinks.Count, limits.SharedLinks.Limit)
+	}
+	return nil
+}
+
+// apiKeyLimitError fails when the user cannot create another API key.
+func apiKeyLimitError(limits *admin.UserLimitsDto) error {
+	if limits.APIKeys.Reached() {
+		return status.Errorf(codes.FailedPrecondition,
+			"API key limit reached: %d of %d API keys in use, delete one before creating another",
+			limits.APIKeys.Count, limits.APIKeys.Limit)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/denysvitali/immich-go-backend/internal/admin"
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestServer_UserLimitsHoldUnderConcurrency creates shared links and API keys
+// concurrently and checks no more than the limit get created.
+func TestServer_UserLimitsHoldUnderConcurrency(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	cfg := &config.Config{}
+	cfg.Limits.MaxSharedLinksPerUser = 2
+	cfg.Limits.MaxAPIKeysPerUser = 3
+	adminService, err := admin.NewService(env.tdb.Queries, cfg, nil)
+	require.NoError(t, err)
+	env.srv.adminService = adminService
+
+	ctx := context.Background()
+	owner := createAssetViewerTestUser(t, ctx, env.tdb)
+	asset := seedAsset(t, ctx, env, owner, "limits.jpg", "image/jpeg", testJPEG(t))
+	ctx = auth.SetUserIDInContext(ctx, owner)
+
+	race := func(create func(i int) error) (created, refused int) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := create(i)
+				mu.Lock()
+				defer mu.Unlock()
+				switch status.Code(err) {
+				case codes.OK:
+					created++
+				case codes.FailedPrecondition:
+					refused++
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		return created, refused
+	}
+
+	created, refused := race(func(i int) error {
+		_, err := env.srv.CreateApiKey(ctx, &immichv1.CreateApiKeyRequest{Name: fmt.Sprintf("key %d", i)})
+		return err
+	})
+	assert.Equal(t, 3, created)
+	assert.Equal(t, 7, refused)
+
+	created, refused = race(func(int) error {
+		_, err := env.srv.CreateSharedLink(ctx, &immichv1.CreateSharedLinkRequest{
+			Type:     immichv1.SharedLinkType_SHARED_LINK_TYPE_INDIVIDUAL,
+			AssetIds: []string{uuid.UUID(asset.ID.Bytes).String()},
+		})
+		return err
+	})
+	assert.Equal(t, 2, created)
+	assert.Equal(t, 8, refused)
+
+	limits, err := adminService.GetUserLimits(ctx, pgtype.UUID{Bytes: owner, Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), limits.APIKeys.Count)
+	assert.Equal(t, int64(2), limits.SharedLinks.Count)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/util"
+)
+
+const defaultLargestAssetsPageSize = 50
+
+// GetUserStorageBreakdown splits the caller's quota usage by asset type and
+// by year, and pages their largest assets so they can pick what to delete.
+// It counts what quota usage counts: uploads outside the trash. The largest
+// assets leave out the locked folder.
+func (s *Server) GetUserStorageBreakdown(ctx context.Context, request *immichv1.GetUserStorageBreakdownRequest) (*immichv1.UserStorageBreakdownResponse, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byType, err := s.db.GetUserStorageByType(ctx, userID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get storage usage", err)
+	}
+	byYear, err := s.db.GetUserStorageByYear(ctx, userID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get storage usage", err)
+	}
+
+	size := s.config.PageSize(request.GetSize(), defaultLargestAssetsPageSize)
+	largest, err := s.db.GetUserLargestAssets(ctx, sqlc.GetUserLargestAssetsParams{
+		OwnerId: userID,
+		Limit:   size,
+		Offset:  util.Offset(request.GetPage(), size),
+	})
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to get largest assets", err)
+	}
+	total, err := s.db.CountUserLargestAssets(ctx, userID)
+	if err != nil {
+		return nil, SanitizedInternal(ctx, "failed to count largest assets", err)
+	}
+
+	response := storageBreakdownToProto(byType, byYear)
+	response.Largest = make([]*immichv1.LargestAsset, len(largest))
+	for i, row := range largest {
+		response.Largest[i] = &immichv1.LargestAsset{
+			Asset:       s.convertAssetToProto(row.Asset),
+			SizeInBytes: row.Size,
+		}
+	}
+	response.PageInfo = &immichv1.PageInfo{
+		Page:  request.GetPage(),
+		Size:  size,
+		Total: total,
+	}
+	return response, nil
+}
+
+// storageBreakdownToProto returns the usage split by type and by year, with
+// the totals taken from the split by type.
+func storageBreakdownToProto(byType []sqlc.GetUserStorageByTypeRow, byYear []sqlc.GetUserStorageByYearRow) *immichv1.UserStorageBreakdownResponse {
+	response := &immichv1.UserStorageBreakdownResponse{
+		ByType: make([]*immichv1.StorageUsageByType, len(byType)),
+		ByYear: make([]*immichv1.StorageUsageByYear, len(byYear)),
+	}
+	for i, row := range byType {
+		response.ByType[i] = &immichv1.StorageUsageByType{
+			Type:   convertAssetTypeString(row.Type),
+			Assets: row.Assets,
+			Bytes:  row.Bytes,
+		}
+		response.Assets += row.Assets
+		response.UsageInBytes += row.Bytes
+	}
+	for i, row := range byYear {
+		item := &immichv1.StorageUsageByYear{Assets: row.Assets, Bytes: row.Bytes}
+		if row.Year.Valid {
+			item.Year = &row.Year.Int32
+		}
+		response.ByYear[i] = item
+	}
+	return response
+}
@@ -0,0 +1,72 @@
+//go:build integration
+// +build integration
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+// TestGetUserStorageBreakdown checks the split of a user's usage and the
+// pages of their largest assets, which leave out the trash like quota
+// usage does.
+func TestGetUserStorageBreakdown(t *testing.T) {
+	env := newAssetViewerTestEnv(t)
+	ctx := context.Background()
+	userID := createAssetViewerTestUser(t, ctx, env.tdb)
+	userCtx := assetViewerContext(userID)
+
+	seed := func(name, mimeType string, size int64, takenAt string) sqlc.Asset {
+		asset := seedAsset(t, ctx, env, userID, name, mimeType, []byte("payload"))
+		_, err := env.tdb.Queries.CreateExif(ctx, sqlc.CreateExifParams{
+			AssetId:        asset.ID,
+			FileSizeInByte: pgtype.Int8{Int64: size, Valid: true},
+		})
+		require.NoError(t, err)
+		_, err = env.tdb.Pool.Exec(ctx, `UPDATE assets SET "localDateTime" = $2::timestamptz WHERE id = $1`, asset.ID, takenAt)
+		require.NoError(t, err)
+		return asset
+	}
+	small := seed("small.jpg", "image/jpeg", 100, "2019-06-01T10:00:00Z")
+	big := seed("big.mp4", "video/mp4", 5000, "2024-03-01T10:00:00Z")
+	medium := seed("medium.jpg", "image/jpeg", 700, "2024-08-01T10:00:00Z")
+	trashed := seed("trashed.mp4", "video/mp4", 90000, "2024-01-01T10:00:00Z")
+	_, err := env.tdb.Queries.UpdateAssetStatus(ctx, sqlc.UpdateAssetStatusParams{ID: trashed.ID, Status: sqlc.AssetsStatusEnumTrashed})
+	require.NoError(t, err)
+
+	response, err := env.srv.GetUserStorageBreakdown(userCtx, &immichv1.GetUserStorageBreakdownRequest{Size: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5800), response.UsageInBytes)
+	assert.Equal(t, int64(3), response.Assets)
+
+	require.Len(t, response.ByType, 2)
+	assert.Equal(t, immichv1.AssetType_ASSET_TYPE_VIDEO, response.ByType[0].Type)
+	assert.Equal(t, int64(5000), response.ByType[0].Bytes)
+	assert.Equal(t, immichv1.AssetType_ASSET_TYPE_IMAGE, response.ByType[1].Type)
+	assert.Equal(t, int64(2), response.ByType[1].Assets)
+	assert.Equal(t, int64(800), response.ByType[1].Bytes)
+
+	require.Len(t, response.ByYear, 2)
+	assert.Equal(t, int32(2024), response.ByYear[0].GetYear())
+	assert.Equal(t, int64(5700), response.ByYear[0].Bytes)
+	assert.Equal(t, int32(2019), response.ByYear[1].GetYear())
+
+	require.Len(t, response.Largest, 2)
+	assert.Equal(t, big.ID.String(), response.Largest[0].Asset.Id)
+	assert.Equal(t, int64(5000), response.Largest[0].SizeInBytes)
+	assert.Equal(t, medium.ID.String(), response.Largest[1].Asset.Id)
+	assert.Equal(t, int64(3), response.PageInfo.Total)
+
+	response, err = env.srv.GetUserStorageBreakdown(userCtx, &immichv1.GetUserStorageBreakdownRequest{Page: 2, Size: 2})
+	require.NoError(t, err)
+	require.Len(t, response.Largest, 1)
+	assert.Equal(t, small.ID.String(), response.Largest[0].Asset.Id)
+}
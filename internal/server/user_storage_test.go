@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+)
+
+func TestStorageBreakdownToProto(t *testing.T) {
+	response := storageBreakdownToProto(
+		[]sqlc.GetUserStorageByTypeRow{
+			{Type: "VIDEO", Assets: 2, Bytes: 900},
+			{Type: "IMAGE", Assets: 5, Bytes: 100},
+		},
+		[]sqlc.GetUserStorageByYearRow{
+			{Year: pgtype.Int4{Int32: 2024, Valid: true}, Assets: 4, Bytes: 700},
+			{Year: pgtype.Int4{Int32: 2019, Valid: true}, Assets: 2, Bytes: 250},
+			{Assets: 1, Bytes: 50},
+		},
+	)
+
+	assert.Equal(t, int64(7), response.Assets)
+	assert.Equal(t, int64(1000), response.UsageInBytes)
+	require.Len(t, response.ByType, 2)
+	assert.Equal(t, immichv1.AssetType_ASSET_TYPE_VIDEO, response.ByType[0].Type)
+	assert.Equal(t, int64(900), response.ByType[0].Bytes)
+	require.Len(t, response.ByYear, 3)
+	assert.Equal(t, int32(2024), response.ByYear[0].GetYear())
+	assert.Equal(t, int32(2019), response.ByYear[1].GetYear())
+	assert.Nil(t, response.ByYear[2].Year, "undated assets have no year")
+	assert.Equal(t, int64(50), response.ByYear[2].Bytes)
+
+	empty := storageBreakdownToProto(nil, nil)
+	assert.Zero(t, empty.UsageInBytes)
+	assert.NotNil(t, empty.ByType)
+	assert.NotNil(t, empty.ByYear)
+}
@@ -19,9 +19,9 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
-	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/calendarheatmap"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/denysvitali/immich-go-backend/internal/users"
 )
@@ -33,15 +33,15 @@ type userLicenseMetadata struct {
 }
 
 func (s *Server) GetMyUser(ctx context.Context, empty *emptypb.Empty) (*immichv1.UserAdminResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	user, err := s.userService.GetUser(ctx, userID)
 	if err != nil {
-		if users.IsNotFoundError(err) {
-			return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
+		if grpcErr, ok := publicUserError(ctx, err); ok {
+			return nil, grpcErr
 		}
 		return nil, SanitizedInternal(ctx, "failed to get user", err)
 	}
@@ -50,7 +50,7 @@ func (s *Server) GetMyUser(ctx context.Context, empty *emptypb.Empty) (*immichv1
 }
 
 func (s *Server) UpdateMyUser(ctx context.Context, request *immichv1.UserUpdateMeRequest) (*immichv1.UserAdminResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -68,14 +68,14 @@ func (s *Server) UpdateMyUser(ctx context.Context, request *immichv1.UserUpdateM
 		avatarColor := request.AvatarColor.String()
 		updateReq.AvatarColor = &avatarColor
 	}
+	if err := grpcutil.Validate(ctx, "invalid user", updateReq); err != nil {
+		return nil, err
+	}
 
 	user, err := s.userService.UpdateUser(ctx, userID, *updateReq)
 	if err != nil {
-		if users.IsNotFoundError(err) {
-			return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
-		}
-		if users.IsValidationError(err) {
-			return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+		if grpcErr, ok := publicUserError(ctx, err); ok {
+			return nil, grpcErr
 		}
 		return nil, SanitizedInternal(ctx, "failed to update user", err)
 	}
@@ -84,7 +84,7 @@ func (s *Server) UpdateMyUser(ctx context.Context, request *immichv1.UserUpdateM
 }
 
 func (s *Server) GetUserLicense(ctx context.Context, empty *emptypb.Empty) (*immichv1.UserLicenseResponse, error) {
-	userUUID, err := s.userUUIDFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -106,7 +106,7 @@ func (s *Server) GetUserLicense(ctx context.Context, empty *emptypb.Empty) (*imm
 }
 
 func (s *Server) SetUserLicense(ctx context.Context, request *immichv1.UserLicenseKeyRequest) (*immichv1.UserLicenseResponse, error) {
-	userUUID, err := s.userUUIDFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +138,7 @@ func (s *Server) SetUserLicense(ctx context.Context, request *immichv1.UserLicen
 }
 
 func (s *Server) DeleteUserLicense(ctx context.Context, empty *emptypb.Empty) (*emptypb.Empty, error) {
-	userUUID, err := s.userUUIDFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -150,32 +150,6 @@ func (s *Server) DeleteUserLicense(ctx context.Context, empty *emptypb.Empty) (*
 	return &emptypb.Empty{}, nil
 }
 
-func (s *Server) claimsFromContext(ctx context.Context) (*auth.Claims, error) {
-	return auth.ClaimsFromContext(ctx, s.authService.ValidateToken)
-}
-
-func (s *Server) userIDFromContext(ctx context.Context) (uuid.UUID, error) {
-	claims, err := s.claimsFromContext(ctx)
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return uuid.Nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-
-	return userID, nil
-}
-
-func (s *Server) userUUIDFromContext(ctx context.Context) (pgtype.UUID, error) {
-	userID, err := s.userIDFromContext(ctx)
-	if err != nil {
-		return pgtype.UUID{}, err
-	}
-	return pgtype.UUID{Bytes: userID, Valid: true}, nil
-}
-
 func parseUserLicense(data []byte) (userLicenseMetadata, error) {
 	var license userLicenseMetadata
 	if err := json.Unmarshal(data, &license); err != nil {
@@ -196,7 +170,7 @@ func userLicenseToProto(license userLicenseMetadata) *immichv1.UserLicenseRespon
 }
 
 func (s *Server) GetMyPreferences(ctx context.Context, empty *emptypb.Empty) (*immichv1.UserPreferencesResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -210,7 +184,7 @@ func (s *Server) GetMyPreferences(ctx context.Context, empty *emptypb.Empty) (*i
 }
 
 func (s *Server) UpdateMyPreferences(ctx context.Context, request *immichv1.UserPreferencesUpdateRequest) (*immichv1.UserPreferencesResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -346,7 +320,7 @@ func stringValue(value *string) string {
 }
 
 func (s *Server) GetMyCalendarHeatmap(ctx context.Context, request *immichv1.GetMyCalendarHeatmapRequest) (*immichv1.CalendarHeatmapResponseDto, error) {
-	userUUID, err := s.userUUIDFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -364,15 +338,10 @@ func (s *Server) GetMyCalendarHeatmap(ctx context.Context, request *immichv1.Get
 
 func (s *Server) CreateProfileImage(ctx context.Context, request *immichv1.CreateProfileImageRequest) (*immichv1.CreateProfileImageResponse, error) {
 	// Get user ID from context
-	claims, err := s.claimsFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
 	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
 
 	// Validate image data
@@ -421,17 +390,11 @@ func (s *Server) CreateProfileImage(ctx context.Context, request *immichv1.Creat
 }
 
 func (s *Server) DeleteProfileImage(ctx context.Context, empty *emptypb.Empty) (*emptypb.Empty, error) {
-	claims, err := s.claimsFromContext(ctx)
+	userUUID, err := s.userIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	userID, err := uuid.Parse(claims.UserID)
-	if err != nil {
-		return nil, SanitizedInternal(ctx, "invalid user ID", err)
-	}
-	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
-
 	user, err := s.db.GetUserByID(ctx, userUUID)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
@@ -461,8 +424,8 @@ func (s *Server) GetUser(ctx context.Context, request *immichv1.GetUserRequest)
 
 	user, err := s.userService.GetUser(ctx, userID)
 	if err != nil {
-		if users.IsNotFoundError(err) {
-			return nil, status.Errorf(codes.NotFound, "user not found: %v", err)
+		if grpcErr, ok := publicUserError(ctx, err); ok {
+			return nil, grpcErr
 		}
 		return nil, SanitizedInternal(ctx, "failed to get user", err)
 	}
@@ -634,7 +597,7 @@ func (s *Server) ListUsers(ctx context.Context, _ *emptypb.Empty) (*immichv1.Lis
 
 // GetOnboarding returns the user's onboarding status
 func (s *Server) GetOnboarding(ctx context.Context, _ *emptypb.Empty) (*immichv1.OnboardingResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -651,7 +614,7 @@ func (s *Server) GetOnboarding(ctx context.Context, _ *emptypb.Empty) (*immichv1
 
 // UpdateOnboarding updates the user's onboarding status
 func (s *Server) UpdateOnboarding(ctx context.Context, req *immichv1.OnboardingUpdateRequest) (*immichv1.OnboardingResponse, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -668,7 +631,7 @@ func (s *Server) UpdateOnboarding(ctx context.Context, req *immichv1.OnboardingU
 
 // DeleteOnboarding clears the user's onboarding status.
 func (s *Server) DeleteOnboarding(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
-	userID, err := s.userIDFromContext(ctx)
+	userID, err := s.getUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -679,3 +642,18 @@ func (s *Server) DeleteOnboarding(ctx context.Context, _ *emptypb.Empty) (*empty
 
 	return &emptypb.Empty{}, nil
 }
+
+// publicUserError converts a *users.UserError the client can act on into a
+// status error carrying the error type as its reason. Operational failures
+// (e.g. database errors) return false so callers sanitize them instead.
+func publicUserError(ctx context.Context, err error) (error, bool) {
+	userErr, ok := users.AsUserError(err)
+	if !ok {
+		return nil, false
+	}
+	code := users.MapUserErrorToGRPC(err)
+	if code == codes.Internal {
+		return nil, false
+	}
+	return grpcutil.WithReason(PublicError(ctx, code, userErr.Message), string(userErr.Type)), true
+}
@@ -2,30 +2,55 @@ package server
 
 import (
 	"context"
+	"errors"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// getUserIDFromContext extracts the user ID from the gRPC context by first
-// checking for claims set by middleware, then falling back to validating the
-// Bearer token from metadata.
-func (s *Server) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
-	claims, err := auth.ClaimsFromContext(ctx, s.authService.ValidateToken)
+// claimsFromContext returns the caller's JWT claims. Requests that went
+// through the auth interceptor or the gateway middleware carry them already;
+// handlers called from elsewhere fall back to the Bearer token in metadata.
+func (s *Server) claimsFromContext(ctx context.Context) (*auth.Claims, error) {
+	return auth.ClaimsFromContext(ctx, s.authService.ValidateToken)
+}
+
+// userIDFromContext returns the caller's user ID. It is parsed once by the
+// auth interceptor; a malformed ID in the claims is an authentication
+// failure, not an internal error.
+func (s *Server) userIDFromContext(ctx context.Context) (pgtype.UUID, error) {
+	if userID, ok := auth.AuthenticatedUserID(ctx); ok {
+		return pgtype.UUID{Bytes: userID, Valid: true}, nil
+	}
+
+	claims, err := s.claimsFromContext(ctx)
 	if err != nil {
-		return uuid.UUID{}, err
+		return pgtype.UUID{}, err
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return uuid.UUID{}, status.Error(codes.Internal, "invalid user ID format")
+		return pgtype.UUID{}, errInvalidUserID
 	}
+	return pgtype.UUID{Bytes: userID, Valid: true}, nil
+}
 
-	return userID, nil
+// getUserIDFromContext is userIDFromContext for callers that work with
+// uuid.UUID.
+func (s *Server) getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	userID, err := s.userIDFromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.UUID(userID.Bytes), nil
 }
 
+var errInvalidUserID = status.Error(codes.Unauthenticated, "invalid user ID")
+
 // getSessionIDFromContext extracts the session ID from the gRPC context.
 // It first checks the x-session-id header, then falls back to looking up the
 // session by the Bearer token.
@@ -50,14 +75,34 @@ func (s *Server) getSessionIDFromContext(ctx context.Context) (string, error) {
 	return "", nil
 }
 
-// getUserFromContext extracts the user claims from the gRPC context.
-// It is a thin wrapper around auth.ClaimsFromContext.
-func (s *Server) getUserFromContext(ctx context.Context) (*auth.Claims, error) {
-	return auth.ClaimsFromContext(ctx, s.authService.ValidateToken)
+// requireUnlockedSession fails unless the caller's session was unlocked with
+// the PIN code or password, which is what the locked folder asks for.
+func (s *Server) requireUnlockedSession(ctx context.Context) error {
+	if s.authService == nil || s.sessionsService == nil {
+		return errSessionLocked
+	}
+	sessionID, err := s.getSessionIDFromContext(ctx)
+	if err != nil || sessionID == "" {
+		return errSessionLocked
+	}
+
+	elevated, err := s.authService.IsSessionElevated(ctx, sessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errSessionLocked
+	}
+	if err != nil {
+		return SanitizedInternal(ctx, "failed to check session", err)
+	}
+	if !elevated {
+		return errSessionLocked
+	}
+	return nil
 }
 
+var errSessionLocked = status.Error(codes.PermissionDenied, "session must be unlocked to access the locked folder")
+
 func (s *Server) requireAdmin(ctx context.Context) (*auth.Claims, error) {
-	claims, err := s.getUserFromContext(ctx)
+	claims, err := s.claimsFromContext(ctx)
 	if err != nil {
 		return nil, status.Error(codes.Unauthenticated, "authentication required")
 	}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
+)
+
+// publishWebhookEvent queues an event for the webhook subscriptions. Without
+// a job service, which delivers them, events are dropped.
+func (s *Server) publishWebhookEvent(ctx context.Context, eventType webhooks.EventType, data map[string]any) {
+	if s.jobService == nil {
+		return
+	}
+	if err := s.jobService.PublishWebhookEvent(ctx, eventType, data); err != nil {
+		logrus.WithError(err).WithField("event_type", eventType).Warn("Failed to publish webhook event")
+	}
+}
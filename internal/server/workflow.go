@@ -46,7 +46,7 @@ func (s *Server) ListWorkflows(ctx context.Context, _ *emptypb.Empty) (*immichv1
 }
 
 func (s *Server) GetWorkflowTriggers(ctx context.Context, _ *emptypb.Empty) (*immichv1.GetWorkflowTriggersResponse, error) {
-	if _, err := s.getUserFromContext(ctx); err != nil {
+	if _, err := s.claimsFromContext(ctx); err != nil {
 		return nil, status.Error(codes.Unauthenticated, "authentication required")
 	}
 
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +14,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/util"
 )
 
 // Service handles shared link operations
@@ -25,6 +28,17 @@ const (
 	SharedLinkTypeIndividual = "INDIVIDUAL"
 )
 
+const (
+	// MaxSharedLinkPageSize caps the links returned per page.
+	MaxSharedLinkPageSize = 1000
+	// ExpiredLinkRetention is how long expired links stay listed before
+	// they are deleted.
+	ExpiredLinkRetention = 30 * 24 * time.Hour
+)
+
+// ErrInvalidListOptions is returned for a malformed shared link listing.
+var ErrInvalidListOptions = errors.New("invalid shared link list options")
+
 // NewService creates a new shared links service
 func NewService(db *sqlc.Queries) *Service {
 	return &Service{db: db}
@@ -49,12 +63,36 @@ type SharedLink struct {
 	UpdatedAt     time.Time   `json:"updatedAt"`
 }
 
+// ListSharedLinksOptions filters and pages GetSharedLinks
+type ListSharedLinksOptions struct {
+	// Type is SharedLinkTypeAlbum or SharedLinkTypeIndividual; empty lists both.
+	Type    string
+	AlbumID *uuid.UUID
+	// Expired lists only expired links when true and only active ones when
+	// false.
+	Expired   *bool
+	Ascending bool
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+	// Limit is the page size; 0 returns every matching link.
+	Limit int
+}
+
+// SharedLinkPage is one page of shared links
+type SharedLinkPage struct {
+	Links []*SharedLink
+	// Total counts every link matching the filters, across all pages.
+	Total int64
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
 // CreateSharedLinkRequest represents a request to create a shared link
 type CreateSharedLinkRequest struct {
 	Type          string     `json:"type"`
-	AssetIDs      []string   `json:"assetIds,omitempty"`
-	AlbumID       *string    `json:"albumId,omitempty"`
-	Description   string     `json:"description,omitempty"`
+	AssetIDs      []string   `json:"assetIds,omitempty" validate:"dive,uuid"`
+	AlbumID       *string    `json:"albumId,omitempty" validate:"omitnil,uuid"`
+	Description   string     `json:"description,omitempty" validate:"max=1000"`
 	Password      string     `json:"password,omitempty"`
 	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
 	AllowDownload bool       `json:"allowDownload"`
@@ -64,7 +102,7 @@ type CreateSharedLinkRequest struct {
 
 // UpdateSharedLinkRequest represents a request to update a shared link
 type UpdateSharedLinkRequest struct {
-	Description      *string    `json:"description,omitempty"`
+	Description      *string    `json:"description,omitempty" validate:"omitnil,max=1000"`
 	Password         *string    `json:"password,omitempty"`
 	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
 	AllowDownload    *bool      `json:"allowDownload,omitempty"`
@@ -206,24 +244,81 @@ func (s *Service) GetSharedLinkByKey(ctx context.Context, key string, password s
 	return convertSharedLink(&link, len(assets)), nil
 }
 
-// GetSharedLinks retrieves all shared links for a user
-func (s *Service) GetSharedLinks(ctx context.Context, userID uuid.UUID) ([]*SharedLink, error) {
-	links, err := s.db.GetSharedLinks(ctx, pgtype.UUID{Bytes: userID, Valid: true})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get shared links: %w", err)
+// GetSharedLinks lists a page of the user's shared links matching opts,
+// newest first unless opts.Ascending is set. Links that expired more than
+// ExpiredLinkRetention ago are deleted first.
+func (s *Service) GetSharedLinks(ctx context.Context, userID uuid.UUID, opts ListSharedLinksOptions) (*SharedLinkPage, error) {
+	if opts.Limit < 0 || opts.Limit > MaxSharedLinkPageSize {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListOptions, MaxSharedLinkPageSize)
+	}
+	if opts.Type != "" && opts.Type != SharedLinkTypeAlbum && opts.Type != SharedLinkTypeIndividual {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidListOptions, opts.Type)
 	}
 
-	result := make([]*SharedLink, 0, len(links))
-	for _, link := range links {
-		// Get asset count for each link
-		assets, err := s.db.GetSharedLinkAssets(ctx, link.ID)
+	owner := pgtype.UUID{Bytes: userID, Valid: true}
+	if _, err := s.db.DeleteExpiredSharedLinks(ctx, sqlc.DeleteExpiredSharedLinksParams{
+		UserID:        owner,
+		ExpiredBefore: pgtype.Timestamptz{Time: time.Now().Add(-ExpiredLinkRetention), Valid: true},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to delete expired shared links: %w", err)
+	}
+
+	params := sqlc.ListSharedLinksParams{
+		UserID:    owner,
+		Ascending: opts.Ascending,
+		// Without a limit every link is returned, as upstream clients expect.
+		PageSize: math.MaxInt32,
+	}
+	if opts.Limit > 0 {
+		// One extra row tells whether another page follows.
+		params.PageSize = int32(opts.Limit + 1)
+	}
+	if opts.Type != "" {
+		params.LinkType = pgtype.Text{String: opts.Type, Valid: true}
+	}
+	if opts.AlbumID != nil {
+		params.AlbumID = pgtype.UUID{Bytes: *opts.AlbumID, Valid: true}
+	}
+	if opts.Expired != nil {
+		params.Expired = pgtype.Bool{Bool: *opts.Expired, Valid: true}
+	}
+	if opts.Cursor != "" {
+		createdAt, id, err := util.DecodeCursor(opts.Cursor)
 		if err != nil {
-			assets = []sqlc.Asset{}
+			return nil, fmt.Errorf("%w: %w", ErrInvalidListOptions, err)
 		}
-		result = append(result, convertSharedLink(&link, len(assets)))
+		params.CursorCreatedAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: id, Valid: true}
 	}
 
-	return result, nil
+	rows, err := s.db.ListSharedLinks(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared links: %w", err)
+	}
+	total, err := s.db.CountSharedLinks(ctx, sqlc.CountSharedLinksParams{
+		UserID:   params.UserID,
+		LinkType: params.LinkType,
+		AlbumID:  params.AlbumID,
+		Expired:  params.Expired,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count shared links: %w", err)
+	}
+
+	page := &SharedLinkPage{Total: total}
+	if opts.Limit > 0 && len(rows) > opts.Limit {
+		rows = rows[:opts.Limit]
+		last := rows[len(rows)-1].SharedLink
+		// Keyed on the creation time and id of the last link, so links
+		// added or deleted meanwhile do not shift the next page.
+		page.NextCursor = util.EncodeCursor(last.CreatedAt.Time, last.ID.Bytes)
+	}
+	page.Links = make([]*SharedLink, 0, len(rows))
+	for _, row := range rows {
+		page.Links = append(page.Links, convertSharedLink(&row.SharedLink, int(row.AssetCount)))
+	}
+
+	return page, nil
 }
 
 // UpdateSharedLink updates an existing shared link
@@ -380,15 +475,19 @@ func (s *Service) AddAssetsToSharedLink(ctx context.Context, userID uuid.UUID, l
 }
 
 // ensureOwnedAsset verifies that assetID exists, is not deleted, and is owned
-// by userID. Returns a non-nil error when the asset is missing or foreign.
+// by userID. Returns a non-nil error when the asset is missing or foreign, or
+// is in the locked folder, which is never shared.
 func (s *Service) ensureOwnedAsset(ctx context.Context, userID, assetID uuid.UUID) error {
-	_, err := s.db.GetAssetByIDAndUser(ctx, sqlc.GetAssetByIDAndUserParams{
+	asset, err := s.db.GetAssetByIDAndUser(ctx, sqlc.GetAssetByIDAndUserParams{
 		ID:      pgtype.UUID{Bytes: assetID, Valid: true},
 		OwnerId: pgtype.UUID{Bytes: userID, Valid: true},
 	})
 	if err != nil {
 		return fmt.Errorf("asset not owned by user: %w", err)
 	}
+	if asset.Visibility == sqlc.AssetVisibilityEnumLocked {
+		return fmt.Errorf("asset is in the locked folder")
+	}
 	return nil
 }
 
@@ -279,9 +279,68 @@ func TestIntegration_GetSharedLinks(t *testing.T) {
 	require.NoError(t, err)
 
 	// Get all shared links
-	links, err := service.GetSharedLinks(ctx, userID)
+	page, err := service.GetSharedLinks(ctx, userID, ListSharedLinksOptions{})
 	require.NoError(t, err)
-	assert.Len(t, links, 2)
+	assert.Len(t, page.Links, 2)
+	assert.Equal(t, int64(2), page.Total)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, "Link 2", page.Links[0].Description, "newest first by default")
+	assert.Equal(t, 1, page.Links[0].AssetCount)
+}
+
+func TestIntegration_GetSharedLinksPagesAndFilters(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries)
+
+	userID := createTestUser(t, tdb, "pagelinks@test.com")
+	assetID := createTestAsset(t, tdb, userID, "pageasset")
+
+	expired := time.Now().Add(-time.Hour)
+	longExpired := time.Now().Add(-ExpiredLinkRetention - time.Hour)
+	for i, expiresAt := range []*time.Time{nil, nil, nil, &expired, &longExpired} {
+		_, err := service.CreateSharedLink(ctx, userID, &CreateSharedLinkRequest{
+			Type:        SharedLinkTypeIndividual,
+			AssetIDs:    []string{assetID.String()},
+			Description: string(rune('A' + i)),
+			ExpiresAt:   expiresAt,
+		})
+		require.NoError(t, err)
+	}
+
+	var descriptions []string
+	opts := ListSharedLinksOptions{Limit: 2, Ascending: true}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, err := service.GetSharedLinks(ctx, userID, opts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), page.Total, "links past the retention period are deleted")
+		for _, link := range page.Links {
+			descriptions = append(descriptions, link.Description)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D"}, descriptions)
+
+	isExpired := true
+	page, err := service.GetSharedLinks(ctx, userID, ListSharedLinksOptions{Expired: &isExpired})
+	require.NoError(t, err)
+	require.Len(t, page.Links, 1)
+	assert.Equal(t, "D", page.Links[0].Description)
+
+	page, err = service.GetSharedLinks(ctx, userID, ListSharedLinksOptions{Type: SharedLinkTypeAlbum})
+	require.NoError(t, err)
+	assert.Empty(t, page.Links)
+	assert.Zero(t, page.Total)
+
+	_, err = service.GetSharedLinks(ctx, userID, ListSharedLinksOptions{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
 }
 
 func TestIntegration_UpdateSharedLink(t *testing.T) {
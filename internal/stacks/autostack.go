@@ -0,0 +1,385 @@
+package stacks
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// DefaultAutoStackWindow is the largest gap between two shots of one burst.
+const DefaultAutoStackWindow = 2 * time.Second
+
+// AutoStackKind says what an auto-detected stack is.
+type AutoStackKind string
+
+const (
+	AutoStackBurst     AutoStackKind = "burst"
+	AutoStackBracket   AutoStackKind = "bracket"
+	AutoStackLivePhoto AutoStackKind = "live_photo"
+)
+
+// AutoStackPrimary picks the primary asset of a burst.
+type AutoStackPrimary string
+
+const (
+	// AutoStackPrimaryFirst makes the first shot the primary asset.
+	AutoStackPrimaryFirst AutoStackPrimary = "first"
+	// AutoStackPrimarySharpest makes the largest file the primary asset.
+	// Shots of one burst share camera and settings, so the frame with the
+	// most detail is the one that compresses worst.
+	AutoStackPrimarySharpest AutoStackPrimary = "sharpest"
+)
+
+// AutoStackOptions controls auto-stack detection.
+type AutoStackOptions struct {
+	// Window is the largest gap between consecutive shots of a burst.
+	Window time.Duration `json:"window"`
+	// Primary picks the primary asset of bursts.
+	Primary AutoStackPrimary `json:"primary,omitempty"`
+	// Brackets stacks exposure brackets, such as HDR sequences.
+	Brackets bool `json:"brackets,omitempty"`
+	// LivePhotos stacks a photo with its motion video when the two were
+	// uploaded separately and are not linked yet.
+	LivePhotos bool `json:"live_photos,omitempty"`
+	// ExcludedAlbumIDs are albums whose assets are never auto-stacked.
+	ExcludedAlbumIDs []string `json:"excluded_album_ids,omitempty"`
+}
+
+// AutoStackGroup is a stack auto-stacking creates, or would create.
+type AutoStackGroup struct {
+	Kind           AutoStackKind
+	PrimaryAssetID string
+	AssetIDs       []string
+	// StackID is set once the stack has been created.
+	StackID string
+
+	taken time.Time
+}
+
+type autoStackCandidate struct {
+	ID           pgtype.UUID
+	Type         string
+	FileName     string
+	TakenAt      time.Time
+	Camera       string
+	ExposureTime string
+	Size         int64
+}
+
+type autoStackQueries interface {
+	GetAutoStackCandidates(ctx context.Context, arg sqlc.GetAutoStackCandidatesParams) ([]sqlc.GetAutoStackCandidatesRow, error)
+	CreateStack(ctx context.Context, arg sqlc.CreateStackParams) (sqlc.AssetStack, error)
+	AddAssetsToStack(ctx context.Context, arg sqlc.AddAssetsToStackParams) error
+}
+
+// AutoStacker groups an owner's burst shots, exposure brackets and live photo
+// pairs into stacks. Stacked assets are never candidates again, so running it
+// twice creates no extra stacks.
+type AutoStacker struct {
+	db autoStackQueries
+}
+
+// NewAutoStacker creates an auto-stacker.
+func NewAutoStacker(queries autoStackQueries) *AutoStacker {
+	return &AutoStacker{db: queries}
+}
+
+// Preview returns the stacks Apply would create, without creating them.
+func (a *AutoStacker) Preview(ctx context.Context, ownerID pgtype.UUID, opts AutoStackOptions) ([]AutoStackGroup, error) {
+	ctx, span := tracer.Start(ctx, "stacks.auto_stack_preview")
+	defer span.End()
+
+	groups, err := a.detect(ctx, ownerID, opts)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("group_count", len(groups)))
+	return groups, nil
+}
+
+// Apply creates a stack for every detected group.
+func (a *AutoStacker) Apply(ctx context.Context, ownerID pgtype.UUID, opts AutoStackOptions) ([]AutoStackGroup, error) {
+	ctx, span := tracer.Start(ctx, "stacks.auto_stack_apply")
+	defer span.End()
+
+	groups, err := a.detect(ctx, ownerID, opts)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	for i := range groups {
+		stackID, err := a.createStack(ctx, ownerID, groups[i])
+		if err != nil {
+			span.RecordError(err)
+			return groups[:i], err
+		}
+		groups[i].StackID = stackID
+	}
+
+	span.SetAttributes(attribute.Int("stack_count", len(groups)))
+	return groups, nil
+}
+
+func (a *AutoStacker) createStack(ctx context.Context, ownerID pgtype.UUID, group AutoStackGroup) (string, error) {
+	primaryID, err := pgutil.StringToUUID(group.PrimaryAssetID)
+	if err != nil {
+		return "", fmt.Errorf("invalid primary asset ID: %w", err)
+	}
+	assetIDs, err := stringsToPgtypeUUIDs(group.AssetIDs)
+	if err != nil {
+		return "", fmt.Errorf("invalid asset IDs: %w", err)
+	}
+
+	stack, err := a.db.CreateStack(ctx, sqlc.CreateStackParams{
+		PrimaryAssetId: primaryID,
+		OwnerId:        ownerID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create stack: %w", err)
+	}
+	if err := a.db.AddAssetsToStack(ctx, sqlc.AddAssetsToStackParams{
+		StackId: stack.ID,
+		Column2: assetIDs,
+	}); err != nil {
+		return "", fmt.Errorf("failed to add assets to stack: %w", err)
+	}
+
+	return pgutil.UUIDToString(stack.ID), nil
+}
+
+func (a *AutoStacker) detect(ctx context.Context, ownerID pgtype.UUID, opts AutoStackOptions) ([]AutoStackGroup, error) {
+	excluded, err := stringsToPgtypeUUIDs(opts.ExcludedAlbumIDs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid excluded album IDs: %w", err)
+	}
+
+	rows, err := a.db.GetAutoStackCandidates(ctx, sqlc.GetAutoStackCandidatesParams{
+		OwnerID:          ownerID,
+		ExcludedAlbumIds: excluded,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auto-stack candidates: %w", err)
+	}
+
+	candidates := make([]autoStackCandidate, 0, len(rows))
+	for _, row := range rows {
+		if !row.TakenAt.Valid {
+			continue
+		}
+		candidates = append(candidates, autoStackCandidate{
+			ID:           row.ID,
+			Type:         row.Type,
+			FileName:     row.OriginalFileName,
+			TakenAt:      row.TakenAt.Time,
+			Camera:       strings.TrimSpace(row.Make + " " + row.Model),
+			ExposureTime: row.ExposureTime,
+			Size:         row.FileSizeInByte,
+		})
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("candidate_count", len(candidates)))
+	return detectAutoStacks(candidates, opts), nil
+}
+
+// detectAutoStacks groups candidates, which must be sorted by capture time.
+//
+// A burst is a run of photos from one camera with at most opts.Window between
+// consecutive shots. A run of three or more shots that all have a different
+// exposure time is an exposure bracket instead. A live photo pair is a photo
+// and a video with the same file name taken within opts.Window of each other.
+func detectAutoStacks(candidates []autoStackCandidate, opts AutoStackOptions) []AutoStackGroup {
+	window := opts.Window
+	if window <= 0 {
+		window = DefaultAutoStackWindow
+	}
+
+	var groups []AutoStackGroup
+	grouped := make(map[pgtype.UUID]struct{})
+
+	var photos []autoStackCandidate
+	for _, c := range candidates {
+		if c.Type == "IMAGE" {
+			photos = append(photos, c)
+		}
+	}
+
+	// Runs are per camera, so interleaved shots of two cameras at one
+	// event still form two bursts.
+	byCamera := make(map[string][]autoStackCandidate)
+	var cameras []string
+	for _, p := range photos {
+		if p.Camera == "" {
+			continue
+		}
+		if _, ok := byCamera[p.Camera]; !ok {
+			cameras = append(cameras, p.Camera)
+		}
+		byCamera[p.Camera] = append(byCamera[p.Camera], p)
+	}
+
+	for _, camera := range cameras {
+		shots := byCamera[camera]
+		start := 0
+		for i := 1; i <= len(shots); i++ {
+			if i < len(shots) && shots[i].TakenAt.Sub(shots[i-1].TakenAt) <= window {
+				continue
+			}
+			run := shots[start:i]
+			start = i
+			if len(run) < 2 {
+				continue
+			}
+
+			kind := AutoStackBurst
+			if isExposureBracket(run) {
+				if !opts.Brackets {
+					continue
+				}
+				kind = AutoStackBracket
+			}
+
+			groups = append(groups, newAutoStackGroup(kind, autoStackPrimary(kind, run, opts.Primary), run))
+			for _, shot := range run {
+				grouped[shot.ID] = struct{}{}
+			}
+		}
+	}
+
+	if opts.LivePhotos {
+		groups = append(groups, detectLivePhotoPairs(candidates, grouped, window)...)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].taken.Before(groups[j].taken)
+	})
+	return groups
+}
+
+func detectLivePhotoPairs(candidates []autoStackCandidate, grouped map[pgtype.UUID]struct{}, window time.Duration) []AutoStackGroup {
+	photosByName := make(map[string][]autoStackCandidate)
+	for _, c := range candidates {
+		if _, ok := grouped[c.ID]; ok || c.Type != "IMAGE" {
+			continue
+		}
+		name := baseFileName(c.FileName)
+		photosByName[name] = append(photosByName[name], c)
+	}
+
+	var groups []AutoStackGroup
+	for _, video := range candidates {
+		if video.Type != "VIDEO" {
+			continue
+		}
+		for _, photo := range photosByName[baseFileName(video.FileName)] {
+			if _, ok := grouped[photo.ID]; ok {
+				continue
+			}
+			gap := video.TakenAt.Sub(photo.TakenAt)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > window {
+				continue
+			}
+
+			groups = append(groups, newAutoStackGroup(AutoStackLivePhoto, photo, []autoStackCandidate{photo, video}))
+			grouped[photo.ID] = struct{}{}
+			grouped[video.ID] = struct{}{}
+			break
+		}
+	}
+	return groups
+}
+
+func newAutoStackGroup(kind AutoStackKind, primary autoStackCandidate, members []autoStackCandidate) AutoStackGroup {
+	group := AutoStackGroup{
+		Kind:           kind,
+		PrimaryAssetID: pgutil.UUIDToString(primary.ID),
+		AssetIDs:       []string{pgutil.UUIDToString(primary.ID)},
+	}
+	for _, m := range members {
+		if m.ID != primary.ID {
+			group.AssetIDs = append(group.AssetIDs, pgutil.UUIDToString(m.ID))
+		}
+	}
+	group.taken = members[0].TakenAt
+	return group
+}
+
+// autoStackPrimary picks the primary asset of a run. Brackets use the middle
+// exposure, which is the one the camera metered.
+func autoStackPrimary(kind AutoStackKind, run []autoStackCandidate, primary AutoStackPrimary) autoStackCandidate {
+	if kind == AutoStackBracket {
+		byExposure := append([]autoStackCandidate(nil), run...)
+		sort.SliceStable(byExposure, func(i, j int) bool {
+			return exposureSeconds(byExposure[i].ExposureTime) < exposureSeconds(byExposure[j].ExposureTime)
+		})
+		return byExposure[len(byExposure)/2]
+	}
+
+	best := run[0]
+	if primary == AutoStackPrimarySharpest {
+		for _, shot := range run[1:] {
+			if shot.Size > best.Size {
+				best = shot
+			}
+		}
+	}
+	return best
+}
+
+// isExposureBracket reports whether every shot of run has a known and
+// different exposure time. Bursts keep their exposure, brackets step it.
+func isExposureBracket(run []autoStackCandidate) bool {
+	if len(run) < 3 {
+		return false
+	}
+	seen := make(map[float64]struct{}, len(run))
+	for _, shot := range run {
+		seconds := exposureSeconds(shot.ExposureTime)
+		if seconds <= 0 {
+			return false
+		}
+		if _, ok := seen[seconds]; ok {
+			return false
+		}
+		seen[seconds] = struct{}{}
+	}
+	return true
+}
+
+// exposureSeconds parses an EXIF exposure time such as "1/250" or "0.5".
+// It returns 0 when the value cannot be parsed.
+func exposureSeconds(value string) float64 {
+	value = strings.TrimSpace(value)
+	if num, den, ok := strings.Cut(value, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0
+		}
+		return n / d
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return seconds
+}
+
+func baseFileName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+}
@@ -0,0 +1,171 @@
+package stacks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+var autoStackBase = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func autoStackShot(n int, offset time.Duration, camera, exposure string, size int64) autoStackCandidate {
+	return autoStackCandidate{
+		ID:           pgtype.UUID{Bytes: uuid.UUID{byte(n)}, Valid: true},
+		Type:         "IMAGE",
+		FileName:     "IMG_" + string(rune('A'+n)) + ".jpg",
+		TakenAt:      autoStackBase.Add(offset),
+		Camera:       camera,
+		ExposureTime: exposure,
+		Size:         size,
+	}
+}
+
+func autoStackIDs(candidates ...autoStackCandidate) []string {
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = pgutil.UUIDToString(c.ID)
+	}
+	return ids
+}
+
+func TestDetectAutoStacksGroupsBurstsPerCamera(t *testing.T) {
+	a1 := autoStackShot(1, 0, "Canon R5", "1/1000", 100)
+	b1 := autoStackShot(2, 500*time.Millisecond, "Sony A7", "1/500", 100)
+	a2 := autoStackShot(3, time.Second, "Canon R5", "1/1000", 300)
+	a3 := autoStackShot(4, 2500*time.Millisecond, "Canon R5", "1/1000", 200)
+	lone := autoStackShot(5, 10*time.Second, "Canon R5", "1/1000", 100)
+	noCamera1 := autoStackShot(6, 20*time.Second, "", "", 100)
+	noCamera2 := autoStackShot(7, 20*time.Second, "", "", 100)
+
+	groups := detectAutoStacks([]autoStackCandidate{a1, b1, a2, a3, lone, noCamera1, noCamera2}, AutoStackOptions{})
+	require.Len(t, groups, 1, "the Sony shot, the late shot and shots without a camera are not bursts")
+	assert.Equal(t, AutoStackBurst, groups[0].Kind)
+	assert.Equal(t, autoStackIDs(a1, a2, a3), groups[0].AssetIDs)
+	assert.Equal(t, pgutil.UUIDToString(a1.ID), groups[0].PrimaryAssetID)
+
+	groups = detectAutoStacks([]autoStackCandidate{a1, a2, a3}, AutoStackOptions{Primary: AutoStackPrimarySharpest})
+	require.Len(t, groups, 1)
+	assert.Equal(t, pgutil.UUIDToString(a2.ID), groups[0].PrimaryAssetID)
+	assert.Equal(t, autoStackIDs(a2, a1, a3), groups[0].AssetIDs, "the primary asset comes first")
+
+	groups = detectAutoStacks([]autoStackCandidate{a1, a2, a3}, AutoStackOptions{Window: 800 * time.Millisecond})
+	assert.Empty(t, groups, "shots further apart than the window are not a burst")
+}
+
+func TestDetectAutoStacksRecognizesBrackets(t *testing.T) {
+	under := autoStackShot(1, 0, "Nikon Z6", "1/1000", 100)
+	normal := autoStackShot(2, 300*time.Millisecond, "Nikon Z6", "1/250", 100)
+	over := autoStackShot(3, 600*time.Millisecond, "Nikon Z6", "1/60", 100)
+	candidates := []autoStackCandidate{under, normal, over}
+
+	assert.Empty(t, detectAutoStacks(candidates, AutoStackOptions{}), "brackets are only stacked when asked for")
+
+	groups := detectAutoStacks(candidates, AutoStackOptions{Brackets: true})
+	require.Len(t, groups, 1)
+	assert.Equal(t, AutoStackBracket, groups[0].Kind)
+	assert.Equal(t, pgutil.UUIDToString(normal.ID), groups[0].PrimaryAssetID, "the middle exposure is the primary asset")
+	assert.ElementsMatch(t, autoStackIDs(under, normal, over), groups[0].AssetIDs)
+}
+
+func TestDetectAutoStacksPairsLivePhotos(t *testing.T) {
+	photo := autoStackShot(1, 0, "", "", 100)
+	photo.FileName = "IMG_0042.HEIC"
+	video := autoStackShot(2, 200*time.Millisecond, "", "", 100)
+	video.Type = "VIDEO"
+	video.FileName = "img_0042.mov"
+	other := autoStackShot(3, 300*time.Millisecond, "", "", 100)
+	other.Type = "VIDEO"
+	other.FileName = "IMG_0043.MOV"
+	candidates := []autoStackCandidate{photo, video, other}
+
+	assert.Empty(t, detectAutoStacks(candidates, AutoStackOptions{}))
+
+	groups := detectAutoStacks(candidates, AutoStackOptions{LivePhotos: true})
+	require.Len(t, groups, 1)
+	assert.Equal(t, AutoStackLivePhoto, groups[0].Kind)
+	assert.Equal(t, autoStackIDs(photo, video), groups[0].AssetIDs)
+}
+
+func TestExposureSeconds(t *testing.T) {
+	assert.InDelta(t, 0.004, exposureSeconds("1/250"), 1e-9)
+	assert.InDelta(t, 0.5, exposureSeconds("0.5"), 1e-9)
+	assert.Zero(t, exposureSeconds(""))
+	assert.Zero(t, exposureSeconds("1/0"))
+}
+
+type fakeAutoStackQueries struct {
+	rows    []sqlc.GetAutoStackCandidatesRow
+	params  sqlc.GetAutoStackCandidatesParams
+	stacked map[pgtype.UUID]pgtype.UUID
+	stacks  []sqlc.CreateStackParams
+}
+
+func (f *fakeAutoStackQueries) GetAutoStackCandidates(_ context.Context, arg sqlc.GetAutoStackCandidatesParams) ([]sqlc.GetAutoStackCandidatesRow, error) {
+	f.params = arg
+	var rows []sqlc.GetAutoStackCandidatesRow
+	for _, row := range f.rows {
+		if _, ok := f.stacked[row.ID]; !ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeAutoStackQueries) CreateStack(_ context.Context, arg sqlc.CreateStackParams) (sqlc.AssetStack, error) {
+	f.stacks = append(f.stacks, arg)
+	return sqlc.AssetStack{ID: pgtype.UUID{Bytes: uuid.New(), Valid: true}, PrimaryAssetId: arg.PrimaryAssetId, OwnerId: arg.OwnerId}, nil
+}
+
+func (f *fakeAutoStackQueries) AddAssetsToStack(_ context.Context, arg sqlc.AddAssetsToStackParams) error {
+	for _, id := range arg.Column2 {
+		f.stacked[id] = arg.StackId
+	}
+	return nil
+}
+
+func TestAutoStackerApplyIsIdempotent(t *testing.T) {
+	owner := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	albumID := uuid.NewString()
+	queries := &fakeAutoStackQueries{stacked: map[pgtype.UUID]pgtype.UUID{}}
+	for i, offset := range []time.Duration{0, time.Second} {
+		shot := autoStackShot(i+1, offset, "", "", 100)
+		queries.rows = append(queries.rows, sqlc.GetAutoStackCandidatesRow{
+			ID:               shot.ID,
+			Type:             "IMAGE",
+			OriginalFileName: shot.FileName,
+			TakenAt:          pgtype.Timestamptz{Time: shot.TakenAt, Valid: true},
+			Make:             "Apple",
+			Model:            "iPhone 15",
+		})
+	}
+	stacker := NewAutoStacker(queries)
+	opts := AutoStackOptions{ExcludedAlbumIDs: []string{albumID}}
+
+	preview, err := stacker.Preview(context.Background(), owner, opts)
+	require.NoError(t, err)
+	require.Len(t, preview, 1)
+	assert.Empty(t, queries.stacks, "a preview creates no stacks")
+	require.Len(t, queries.params.ExcludedAlbumIds, 1)
+	assert.Equal(t, albumID, pgutil.UUIDToString(queries.params.ExcludedAlbumIds[0]))
+
+	applied, err := stacker.Apply(context.Background(), owner, opts)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.NotEmpty(t, applied[0].StackID)
+	assert.Equal(t, preview[0].AssetIDs, applied[0].AssetIDs)
+	require.Len(t, queries.stacks, 1)
+	assert.Equal(t, owner, queries.stacks[0].OwnerId)
+
+	applied, err = stacker.Apply(context.Background(), owner, opts)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "stacked assets are not stacked again")
+	assert.Len(t, queries.stacks, 1)
+}
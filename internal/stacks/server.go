@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
@@ -22,12 +23,23 @@ type stackService interface {
 	DeleteStacks(ctx context.Context, userID string, stackIDs []string) error
 	SearchStacks(ctx context.Context, req SearchStacksRequest) (*SearchStacksResponse, error)
 	RemoveAssetFromStack(ctx context.Context, userID, stackID, assetID string) error
+	AutoStack(ctx context.Context, userID string, opts AutoStackOptions, dryRun bool) ([]AutoStackGroup, error)
 }
 
+// AutoStackQueue runs auto-stacking as a background job.
+type AutoStackQueue interface {
+	EnqueueAutoStack(ctx context.Context, userID string, opts AutoStackOptions) error
+}
+
+// maxAutoStackWindow bounds the burst window, so a typo cannot stack a whole
+// afternoon.
+const maxAutoStackWindow = time.Minute
+
 // Server implements the StacksService
 type Server struct {
 	immichv1.UnimplementedStacksServiceServer
-	service stackService
+	service        stackService
+	autoStackQueue AutoStackQueue
 }
 
 // NewServer creates a new stacks server
@@ -37,6 +49,12 @@ func NewServer(service stackService) *Server {
 	}
 }
 
+// SetAutoStackQueue makes AutoStack hand applied runs to a background job
+// instead of stacking within the request.
+func (s *Server) SetAutoStackQueue(queue AutoStackQueue) {
+	s.autoStackQueue = queue
+}
+
 func currentUserIDFromContext(ctx context.Context) (string, error) {
 	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
@@ -223,3 +241,79 @@ func (s *Server) RemoveAssetFromStack(ctx context.Context, request *immichv1.Rem
 
 	return &emptypb.Empty{}, nil
 }
+
+// AutoStack previews, or with dry_run false applies, the stacks detected in
+// the current user's bursts, exposure brackets and live photo pairs.
+func (s *Server) AutoStack(ctx context.Context, request *immichv1.AutoStackRequest) (*immichv1.AutoStackResponse, error) {
+	userID, err := currentUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := autoStackOptions(request)
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun := request.DryRun == nil || request.GetDryRun()
+	queued := !dryRun && s.autoStackQueue != nil
+
+	// A queued run is previewed here, so the caller sees what the job will
+	// stack.
+	groups, err := s.service.AutoStack(ctx, userID, opts, dryRun || queued)
+	if err != nil {
+		return nil, stackStatusError(err, "failed to auto-stack assets")
+	}
+	if queued {
+		if err := s.autoStackQueue.EnqueueAutoStack(ctx, userID, opts); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to queue auto-stacking: %v", err)
+		}
+	}
+
+	response := &immichv1.AutoStackResponse{
+		DryRun: dryRun,
+		Queued: queued,
+		Stacks: make([]*immichv1.AutoStackGroupDto, len(groups)),
+	}
+	for i, group := range groups {
+		dto := &immichv1.AutoStackGroupDto{
+			Kind:           string(group.Kind),
+			PrimaryAssetId: group.PrimaryAssetID,
+			AssetIds:       group.AssetIDs,
+		}
+		if group.StackID != "" {
+			dto.StackId = &group.StackID
+		}
+		response.Stacks[i] = dto
+	}
+
+	return response, nil
+}
+
+func autoStackOptions(request *immichv1.AutoStackRequest) (AutoStackOptions, error) {
+	opts := AutoStackOptions{
+		Window:           DefaultAutoStackWindow,
+		Primary:          AutoStackPrimaryFirst,
+		Brackets:         request.GetBrackets(),
+		LivePhotos:       request.GetLivePhotos(),
+		ExcludedAlbumIDs: request.GetExcludedAlbumIds(),
+	}
+
+	if request.WindowMs != nil {
+		opts.Window = time.Duration(request.GetWindowMs()) * time.Millisecond
+		if opts.Window <= 0 || opts.Window > maxAutoStackWindow {
+			return opts, status.Errorf(codes.InvalidArgument, "window_ms must be between 1 and %d", maxAutoStackWindow.Milliseconds())
+		}
+	}
+
+	if request.Primary != nil {
+		switch primary := AutoStackPrimary(request.GetPrimary()); primary {
+		case AutoStackPrimaryFirst, AutoStackPrimarySharpest:
+			opts.Primary = primary
+		default:
+			return opts, status.Errorf(codes.InvalidArgument, "primary must be %q or %q", AutoStackPrimaryFirst, AutoStackPrimarySharpest)
+		}
+	}
+
+	return opts, nil
+}
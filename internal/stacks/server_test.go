@@ -28,7 +28,7 @@ func TestCurrentUserIDFromContext(t *testing.T) {
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 
 	_, err = currentUserIDFromContext(auth.WithClaims(context.Background(), &auth.Claims{UserID: "not-a-uuid"}))
-	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
 
 func TestStackResponse(t *testing.T) {
@@ -216,6 +216,11 @@ type fakeStackService struct {
 	removeStackID string
 	removeAssetID string
 	removeErr     error
+
+	autoStackUserID string
+	autoStackOpts   AutoStackOptions
+	autoStackDryRun bool
+	autoStackGroups []AutoStackGroup
 }
 
 func (f *fakeStackService) CreateStack(ctx context.Context, userID string, req CreateStackRequest) (*StackResponse, error) {
@@ -292,3 +297,83 @@ func firstString(values []string) string {
 func stringPtr(value string) *string {
 	return &value
 }
+
+func (f *fakeStackService) AutoStack(ctx context.Context, userID string, opts AutoStackOptions, dryRun bool) ([]AutoStackGroup, error) {
+	f.autoStackUserID = userID
+	f.autoStackOpts = opts
+	f.autoStackDryRun = dryRun
+	return f.autoStackGroups, nil
+}
+
+type fakeAutoStackQueue struct {
+	userID string
+	opts   AutoStackOptions
+}
+
+func (f *fakeAutoStackQueue) EnqueueAutoStack(ctx context.Context, userID string, opts AutoStackOptions) error {
+	f.userID = userID
+	f.opts = opts
+	return nil
+}
+
+func TestAutoStackDefaultsToDryRun(t *testing.T) {
+	userID := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	ctx := auth.WithClaims(context.Background(), &auth.Claims{UserID: userID.String()})
+	fake := &fakeStackService{autoStackGroups: []AutoStackGroup{{
+		Kind:           AutoStackBurst,
+		PrimaryAssetID: "asset-1",
+		AssetIDs:       []string{"asset-1", "asset-2"},
+	}}}
+	server := NewServer(fake)
+
+	resp, err := server.AutoStack(ctx, &immichv1.AutoStackRequest{})
+	require.NoError(t, err)
+	assert.True(t, resp.GetDryRun())
+	assert.False(t, resp.GetQueued())
+	assert.True(t, fake.autoStackDryRun)
+	assert.Equal(t, userID.String(), fake.autoStackUserID)
+	assert.Equal(t, DefaultAutoStackWindow, fake.autoStackOpts.Window)
+	assert.Equal(t, AutoStackPrimaryFirst, fake.autoStackOpts.Primary)
+	require.Len(t, resp.GetStacks(), 1)
+	assert.Equal(t, "burst", resp.GetStacks()[0].GetKind())
+	assert.Equal(t, []string{"asset-1", "asset-2"}, resp.GetStacks()[0].GetAssetIds())
+	assert.Nil(t, resp.GetStacks()[0].StackId)
+}
+
+func TestAutoStackApplyIsQueuedWhenJobsRun(t *testing.T) {
+	ctx := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.NewString()})
+	fake := &fakeStackService{}
+	queue := &fakeAutoStackQueue{}
+	server := NewServer(fake)
+	server.SetAutoStackQueue(queue)
+
+	dryRun := false
+	windowMs := int32(500)
+	resp, err := server.AutoStack(ctx, &immichv1.AutoStackRequest{
+		DryRun:           &dryRun,
+		WindowMs:         &windowMs,
+		Brackets:         true,
+		ExcludedAlbumIds: []string{"album-1"},
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.GetDryRun())
+	assert.True(t, resp.GetQueued())
+	assert.True(t, fake.autoStackDryRun, "a queued run is only previewed in the request")
+	assert.Equal(t, fake.autoStackUserID, queue.userID)
+	assert.Equal(t, 500*time.Millisecond, queue.opts.Window)
+	assert.True(t, queue.opts.Brackets)
+	assert.Equal(t, []string{"album-1"}, queue.opts.ExcludedAlbumIDs)
+}
+
+func TestAutoStackRejectsInvalidOptions(t *testing.T) {
+	ctx := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.NewString()})
+	server := NewServer(&fakeStackService{})
+
+	windowMs := int32(10 * 60 * 1000)
+	_, err := server.AutoStack(ctx, &immichv1.AutoStackRequest{WindowMs: &windowMs})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	primary := "blurriest"
+	_, err = server.AutoStack(ctx, &immichv1.AutoStackRequest{Primary: &primary})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
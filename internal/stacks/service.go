@@ -705,3 +705,29 @@ func (s *Service) RemoveAssetFromStack(ctx context.Context, userID, stackID, ass
 
 	return nil
 }
+
+// AutoStack detects the user's bursts, brackets and live photo pairs. Unless
+// dryRun is false it only returns the stacks it would create.
+func (s *Service) AutoStack(ctx context.Context, userID string, opts AutoStackOptions, dryRun bool) ([]AutoStackGroup, error) {
+	userUUID, err := userUUIDFromString(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		s.operationDuration.Record(ctx, time.Since(start).Seconds(),
+			metric.WithAttributes(attribute.String("operation", "auto_stack")))
+		s.operationCounter.Add(ctx, 1,
+			metric.WithAttributes(attribute.String("operation", "auto_stack")))
+	}()
+
+	stacker := NewAutoStacker(s.db)
+	if dryRun {
+		return stacker.Preview(ctx, userUUID, opts)
+	}
+
+	groups, err := stacker.Apply(ctx, userUUID, opts)
+	s.stackCounter.Add(ctx, int64(len(groups)))
+	return groups, err
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type storageBackendDefinition struct {
@@ -67,8 +69,69 @@ func ValidateStorageConfig(config StorageConfig) error {
 	if !ok {
 		return wrapError("validate storage config", "", backend, fmt.Errorf("unsupported storage backend: %s", backend))
 	}
+	if err := definition.validate(config); err != nil {
+		return err
+	}
+
+	derivatives := config
+	if config.Derivatives.Separate() {
+		derivatives = config.Derivatives.storageConfig(config)
+		derivativesBackend := strings.ToLower(derivatives.Backend)
+		definition, ok := lookupStorageBackendDefinition(derivativesBackend)
+		if !ok {
+			return wrapError("validate derivatives config", "", derivativesBackend, fmt.Errorf("unsupported storage backend: %s", derivativesBackend))
+		}
+		if err := definition.validate(derivatives); err != nil {
+			return err
+		}
+	}
 
-	return definition.validate(config)
+	if err := validateRetryConfig(config.Retry); err != nil {
+		return err
+	}
+
+	return validatePresignedURLConfig(config.PresignedURLs, config, derivatives)
+}
+
+// validateRetryConfig validates the retry policy of storage operations
+func validateRetryConfig(config RetryConfig) error {
+	if config.MaxAttempts < 0 {
+		return wrapError("validate retry config", "", "", fmt.Errorf("max_attempts must not be negative"))
+	}
+	if config.InitialBackoff < 0 || config.MaxBackoff < 0 {
+		return wrapError("validate retry config", "", "", fmt.Errorf("backoffs must not be negative"))
+	}
+	if config.MaxBackoff > 0 && config.InitialBackoff > config.MaxBackoff {
+		return wrapError("validate retry config", "", "", fmt.Errorf("initial_backoff must not exceed max_backoff"))
+	}
+	return nil
+}
+
+// validatePresignedURLConfig checks the pre-signed URL lifetimes against the
+// limit of the backend signing them: originals are signed by the originals
+// backend, thumbnails by the derivatives backend.
+func validatePresignedURLConfig(urls PresignedURLConfig, originals, derivatives StorageConfig) error {
+	checks := []struct {
+		name    string
+		expiry  time.Duration
+		backend StorageConfig
+	}{
+		{"original_expiry", urls.OriginalExpiry, originals},
+		{"video_expiry", urls.VideoExpiry, originals},
+		{"thumbnail_expiry", urls.ThumbnailExpiry, derivatives},
+	}
+	for _, check := range checks {
+		if check.expiry < 0 {
+			return wrapError("validate presigned url config", "", "", fmt.Errorf("%s must not be negative", check.name))
+		}
+		if strings.ToLower(check.backend.Backend) != "s3" {
+			continue
+		}
+		if limit := MaxS3PresignExpiry - check.backend.S3.ClockSkew; check.expiry > limit {
+			return wrapError("validate presigned url config", "", "s3", fmt.Errorf("%s must be at most %s with a clock_skew of %s", check.name, limit, check.backend.S3.ClockSkew))
+		}
+	}
+	return nil
 }
 
 // validateLocalConfig validates local storage configuration
@@ -98,6 +161,17 @@ func validateS3Config(config S3Config) error {
 		return wrapError("validate s3 config", "", "s3", fmt.Errorf("region is required"))
 	}
 
+	if config.ClockSkew < 0 || config.ClockSkew > maxS3ClockSkew {
+		return wrapError("validate s3 config", "", "s3", fmt.Errorf("clock_skew must be between 0 and %s", maxS3ClockSkew))
+	}
+
+	if config.ProxyURL != "" {
+		u, err := url.Parse(config.ProxyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return wrapError("validate s3 config", "", "s3", fmt.Errorf("proxy_url must be an http(s) URL"))
+		}
+	}
+
 	return nil
 }
 
@@ -120,19 +194,29 @@ func GetDefaultStorageConfig() StorageConfig {
 			DirMode:  "0755",
 		},
 		Upload: UploadConfig{
-			MaxFileSize: 104857600, // 100MB
-			AllowedExtensions: []string{
-				".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff", ".webp", ".avif",
-				".mp4", ".mov", ".avi", ".mkv", ".webm", ".m4v",
-				".heic", ".heif", ".dng", ".raw", ".cr2", ".nef", ".arw",
-			},
-			AllowedMimeTypes: []string{
-				"image/jpeg", "image/png", "image/gif", "image/bmp", "image/tiff", "image/webp", "image/avif",
-				"video/mp4", "video/quicktime", "video/x-msvideo", "video/x-matroska", "video/webm",
-				"image/heic", "image/heif", "image/x-adobe-dng", "image/x-canon-cr2", "image/x-nikon-nef",
-			},
-			VirusScanEnabled: false,
-			TempDir:          "/tmp/immich-uploads",
+			MaxFileSize:              104857600, // 100MB
+			AllowedExtensions:        defaultUploadExtensions(),
+			AllowedMimeTypes:         defaultUploadMimeTypes(),
+			AllowedSidecarExtensions: []string{".xmp"},
+			RestoreTrashedDuplicates: true,
+			KeepDownscaledOriginal:   true,
+			VirusScanEnabled:         false,
+			TempDir:                  "/tmp/immich-uploads",
+		},
+		S3: S3Config{ClockSkew: DefaultS3ClockSkew},
+		Derivatives: DerivativesConfig{
+			S3: S3Config{ClockSkew: DefaultS3ClockSkew},
+		},
+		PresignedURLs: PresignedURLConfig{
+			OriginalExpiry:  DefaultOriginalURLExpiry,
+			VideoExpiry:     DefaultOriginalURLExpiry,
+			ThumbnailExpiry: DefaultThumbnailURLExpiry,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:    DefaultRetryMaxAttempts,
+			InitialBackoff: DefaultRetryInitialBackoff,
+			MaxBackoff:     DefaultRetryMaxBackoff,
 		},
+		MissingOriginalPlaceholder: true,
 	}
 }
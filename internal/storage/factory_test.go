@@ -2,6 +2,7 @@ package storage
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -102,3 +103,81 @@ func TestLookupStorageBackendDefinitionRejectsUnsupportedBackend(t *testing.T) {
 
 	assert.False(t, ok)
 }
+
+func TestValidateStorageConfigChecksDerivativesBackend(t *testing.T) {
+	config := StorageConfig{
+		Backend:     "local",
+		Local:       LocalConfig{RootPath: t.TempDir()},
+		Derivatives: DerivativesConfig{Backend: "s3", S3: S3Config{Region: "us-east-1"}},
+	}
+	assert.Error(t, ValidateStorageConfig(config))
+
+	config.Derivatives = DerivativesConfig{Backend: "memory"}
+	assert.Error(t, ValidateStorageConfig(config))
+
+	config.Derivatives = DerivativesConfig{Backend: "local", Local: LocalConfig{RootPath: t.TempDir()}}
+	assert.NoError(t, ValidateStorageConfig(config))
+}
+
+func TestValidateStorageConfigChecksPresignedURLExpiry(t *testing.T) {
+	config := StorageConfig{
+		Backend: "s3",
+		S3: S3Config{
+			Bucket:          "photos",
+			Region:          "us-east-1",
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+			ClockSkew:       time.Minute,
+		},
+		PresignedURLs: PresignedURLConfig{ThumbnailExpiry: 24 * time.Hour},
+	}
+	assert.NoError(t, ValidateStorageConfig(config))
+
+	// S3 caps pre-signed URLs at a week, including the clock skew allowance.
+	config.PresignedURLs.ThumbnailExpiry = MaxS3PresignExpiry
+	assert.ErrorContains(t, ValidateStorageConfig(config), "thumbnail_expiry")
+
+	config.PresignedURLs.ThumbnailExpiry = 0
+	config.PresignedURLs.OriginalExpiry = -time.Hour
+	assert.ErrorContains(t, ValidateStorageConfig(config), "original_expiry")
+
+	config.PresignedURLs.OriginalExpiry = 0
+	config.S3.ClockSkew = time.Hour
+	assert.ErrorContains(t, ValidateStorageConfig(config), "clock_skew")
+
+	// Backends without pre-signed URLs have no upper limit.
+	local := StorageConfig{
+		Backend:       "local",
+		Local:         LocalConfig{RootPath: t.TempDir()},
+		PresignedURLs: PresignedURLConfig{ThumbnailExpiry: 30 * 24 * time.Hour},
+	}
+	assert.NoError(t, ValidateStorageConfig(local))
+}
+
+func TestPresignedURLConfigDefaults(t *testing.T) {
+	var urls PresignedURLConfig
+	assert.Equal(t, DefaultOriginalURLExpiry, urls.Original(false))
+	assert.Equal(t, DefaultOriginalURLExpiry, urls.Original(true))
+	assert.Equal(t, DefaultThumbnailURLExpiry, urls.Thumbnail())
+
+	urls = PresignedURLConfig{OriginalExpiry: time.Minute, VideoExpiry: 3 * time.Hour, ThumbnailExpiry: 48 * time.Hour}
+	assert.Equal(t, time.Minute, urls.Original(false))
+	assert.Equal(t, 3*time.Hour, urls.Original(true))
+	assert.Equal(t, 48*time.Hour, urls.Thumbnail())
+}
+
+func TestValidateStorageConfigChecksRetry(t *testing.T) {
+	config := StorageConfig{
+		Backend: "local",
+		Local:   LocalConfig{RootPath: t.TempDir()},
+		Retry:   RetryConfig{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: time.Minute},
+	}
+	assert.NoError(t, ValidateStorageConfig(config))
+
+	config.Retry.MaxAttempts = -1
+	assert.ErrorContains(t, ValidateStorageConfig(config), "max_attempts")
+
+	config.Retry.MaxAttempts = 0
+	config.Retry.InitialBackoff = 2 * time.Minute
+	assert.ErrorContains(t, ValidateStorageConfig(config), "initial_backoff")
+}
@@ -18,6 +18,10 @@ type StorageBackend interface {
 	// Download downloads a file from the storage backend
 	Download(ctx context.Context, path string) (io.ReadCloser, error)
 
+	// DownloadRange downloads length bytes of a file starting at offset, or
+	// the rest of the file when length is negative
+	DownloadRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+
 	// Delete deletes a file from the storage backend
 	Delete(ctx context.Context, path string) error
 
@@ -101,6 +105,97 @@ type StorageConfig struct {
 
 	// Upload configuration
 	Upload UploadConfig `yaml:"upload"`
+
+	// Derivatives configures where thumbnails, previews and transcodes are
+	// stored. Left unset, they live alongside the originals.
+	Derivatives DerivativesConfig `yaml:"derivatives,omitempty"`
+
+	// Lifetimes of pre-signed download URLs
+	PresignedURLs PresignedURLConfig `yaml:"presigned_urls"`
+
+	// Retries of idempotent operations after transient failures
+	Retry RetryConfig `yaml:"retry"`
+
+	// Answer thumbnail requests that fail because the original is missing
+	// or storage is unavailable with a placeholder image, keeping the 404
+	// or 503 status, instead of an error body
+	MissingOriginalPlaceholder bool `yaml:"missing_original_placeholder" env:"STORAGE_MISSING_ORIGINAL_PLACEHOLDER" default:"true"`
+}
+
+// Default lifetimes of pre-signed download URLs. Thumbnails are small and
+// cached by clients, so their URLs live longer.
+const (
+	DefaultOriginalURLExpiry  = time.Hour
+	DefaultThumbnailURLExpiry = 24 * time.Hour
+)
+
+// PresignedURLConfig sets how long pre-signed download URLs stay valid, per
+// kind of file. Zero keeps the default.
+type PresignedURLConfig struct {
+	// Originals of photos and other non-video assets
+	OriginalExpiry time.Duration `yaml:"original_expiry" env:"STORAGE_PRESIGNED_ORIGINAL_EXPIRY" default:"1h"`
+
+	// Originals of videos, which slow clients may stream for longer
+	VideoExpiry time.Duration `yaml:"video_expiry" env:"STORAGE_PRESIGNED_VIDEO_EXPIRY" default:"1h"`
+
+	// Thumbnails and previews
+	ThumbnailExpiry time.Duration `yaml:"thumbnail_expiry" env:"STORAGE_PRESIGNED_THUMBNAIL_EXPIRY" default:"24h"`
+}
+
+// Original returns the lifetime of download URLs for an asset's original.
+func (c PresignedURLConfig) Original(video bool) time.Duration {
+	expiry := c.OriginalExpiry
+	if video {
+		expiry = c.VideoExpiry
+	}
+	if expiry <= 0 {
+		return DefaultOriginalURLExpiry
+	}
+	return expiry
+}
+
+// Thumbnail returns the lifetime of download URLs for thumbnails.
+func (c PresignedURLConfig) Thumbnail() time.Duration {
+	if c.ThumbnailExpiry <= 0 {
+		return DefaultThumbnailURLExpiry
+	}
+	return c.ThumbnailExpiry
+}
+
+// DerivativesConfig represents the storage backend for files generated from
+// originals. Everything in it can be regenerated, so it may sit on cheaper or
+// faster storage than the originals.
+type DerivativesConfig struct {
+	// Backend type; empty stores derivatives on the originals backend
+	Backend string `yaml:"backend" env:"STORAGE_DERIVATIVES_BACKEND"`
+
+	// Local storage configuration
+	Local LocalConfig `yaml:"local,omitempty"`
+
+	// S3 configuration
+	S3 S3Config `yaml:"s3,omitempty"`
+
+	// Rclone configuration
+	Rclone RcloneConfig `yaml:"rclone,omitempty"`
+}
+
+// Separate reports whether derivatives have a backend of their own.
+func (c DerivativesConfig) Separate() bool {
+	return c.Backend != ""
+}
+
+// storageConfig returns the configuration of the derivatives backend. Upload
+// limits, which only matter for validation, and the retry policy are
+// inherited from base.
+func (c DerivativesConfig) storageConfig(base StorageConfig) StorageConfig {
+	return StorageConfig{
+		Backend: c.Backend,
+		Local:   c.Local,
+		S3:      c.S3,
+		Rclone:  c.Rclone,
+		Upload:  base.Upload,
+		Retry:   base.Retry,
+	}
 }
 
 // LocalConfig represents local filesystem storage configuration
@@ -149,6 +244,15 @@ type S3Config struct {
 
 	// Pre-signed URL expiry duration
 	PresignedURLExpiry time.Duration `yaml:"presigned_url_expiry" env:"S3_PRESIGNED_URL_EXPIRY" default:"15m"`
+
+	// Base URL pre-signed URLs are rewritten to, for buckets only reachable
+	// through a reverse proxy (e.g. "https://photos.example.com/s3"). The
+	// proxy strips the path and forwards to the endpoint with its Host.
+	ProxyURL string `yaml:"proxy_url" env:"S3_PROXY_URL"`
+
+	// Pre-signed URLs are signed this long in the past, so that a service
+	// whose clock runs slightly behind still accepts them
+	ClockSkew time.Duration `yaml:"clock_skew" env:"S3_CLOCK_SKEW" default:"1m"`
 }
 
 // RcloneConfig represents rclone storage configuration
@@ -180,6 +284,23 @@ type UploadConfig struct {
 	// Allowed MIME types
 	AllowedMimeTypes []string `yaml:"allowed_mime_types" env:"UPLOAD_ALLOWED_MIME_TYPES"`
 
+	// Sidecar extensions, accepted only alongside their asset and never as
+	// a standalone upload
+	AllowedSidecarExtensions []string `yaml:"allowed_sidecar_extensions" env:"UPLOAD_ALLOWED_SIDECAR_EXTENSIONS"`
+
+	// Restore a trashed asset when the same file is uploaded again. When
+	// off, the trashed asset is removed for good and a fresh one created
+	RestoreTrashedDuplicates bool `yaml:"restore_trashed_duplicates" env:"UPLOAD_RESTORE_TRASHED_DUPLICATES" default:"true"`
+
+	// Downscale uploaded images larger than this many megapixels, or with a
+	// longer edge than MaxImageDimension pixels. Zero does not limit
+	MaxImageMegapixels float64 `yaml:"max_image_megapixels" env:"UPLOAD_MAX_IMAGE_MEGAPIXELS" default:"0"`
+	MaxImageDimension  int     `yaml:"max_image_dimension" env:"UPLOAD_MAX_IMAGE_DIMENSION" default:"0"`
+
+	// Keep the uploaded file of a downscaled image next to the downscaled
+	// one. When off, the downscaled image replaces it
+	KeepDownscaledOriginal bool `yaml:"keep_downscaled_original" env:"UPLOAD_KEEP_DOWNSCALED_ORIGINAL" default:"true"`
+
 	// Enable virus scanning
 	VirusScanEnabled bool `yaml:"virus_scan_enabled" env:"UPLOAD_VIRUS_SCAN_ENABLED" default:"false"`
 
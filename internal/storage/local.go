@@ -158,6 +158,29 @@ func (l *LocalBackend) Download(ctx context.Context, path string) (io.ReadCloser
 	return file, nil
 }
 
+// DownloadRange opens a file from the local filesystem at offset
+func (l *LocalBackend) DownloadRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	file, err := l.Download(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	f := file.(*os.File)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, wrapError("download", path, "local", fmt.Errorf("failed to seek file: %w", err))
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// limitedReadCloser closes the file a limited reader reads from
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // Delete deletes a file from the local filesystem
 func (l *LocalBackend) Delete(ctx context.Context, path string) error {
 	_, span := tracer.Start(ctx, "local.Delete",
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"errors"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is wrapped by upload validation errors for files
+// whose extension or MIME type the upload configuration does not accept.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// MediaKind classifies an upload by what the server can do with it.
+type MediaKind string
+
+const (
+	MediaKindImage   MediaKind = "image"
+	MediaKindVideo   MediaKind = "video"
+	MediaKindSidecar MediaKind = "sidecar"
+)
+
+// Media types the server can thumbnail or play, keyed by extension. They
+// mirror upstream server/src/utils/mime-types.ts.
+var (
+	imageMediaTypes = map[string]string{
+		".3fr": "image/3fr", ".ari": "image/ari", ".arw": "image/arw",
+		".cap": "image/cap", ".cin": "image/cin", ".cr2": "image/cr2",
+		".cr3": "image/cr3", ".crw": "image/crw", ".dcr": "image/dcr",
+		".dng": "image/dng", ".erf": "image/erf", ".fff": "image/fff",
+		".iiq": "image/iiq", ".k25": "image/k25", ".kdc": "image/kdc",
+		".mrw": "image/mrw", ".nef": "image/nef", ".nrw": "image/nrw",
+		".orf": "image/orf", ".ori": "image/ori", ".pef": "image/pef",
+		".psd": "image/psd", ".raf": "image/raf", ".raw": "image/raw",
+		".rw2": "image/rw2", ".rwl": "image/rwl", ".sr2": "image/sr2",
+		".srf": "image/srf", ".srw": "image/srw", ".x3f": "image/x3f",
+		".avif": "image/avif", ".bmp": "image/bmp", ".gif": "image/gif",
+		".jpeg": "image/jpeg", ".jpg": "image/jpeg", ".png": "image/png",
+		".webp": "image/webp", ".heic": "image/heic", ".heif": "image/heif",
+		".hif": "image/hif", ".insp": "image/jpeg", ".jp2": "image/jp2",
+		".jpe": "image/jpeg", ".jxl": "image/jxl", ".svg": "image/svg",
+		".tif": "image/tiff", ".tiff": "image/tiff",
+	}
+	videoMediaTypes = map[string]string{
+		".3gp": "video/3gpp", ".3gpp": "video/3gpp", ".avi": "video/avi",
+		".flv": "video/x-flv", ".insv": "video/mp4", ".m2t": "video/mp2t",
+		".m2ts": "video/mp2t", ".m4v": "video/x-m4v", ".mkv": "video/x-matroska",
+		".mov": "video/quicktime", ".mp4": "video/mp4", ".mpe": "video/mpeg",
+		".mpeg": "video/mpeg", ".mpg": "video/mpeg", ".mts": "video/mp2t",
+		".mxf": "application/mxf", ".ts": "video/mp2t", ".vob": "video/mpeg",
+		".webm": "video/webm", ".wmv": "video/x-ms-wmv",
+	}
+	sidecarMediaTypes = map[string]string{
+		".xmp": "application/xml",
+	}
+)
+
+// mimeTypeAliases are other names clients and the system MIME database use
+// for the default media types.
+var mimeTypeAliases = []string{
+	"image/x-adobe-dng", "image/x-canon-cr2", "image/x-canon-cr3", "image/x-nikon-nef",
+	"image/x-sony-arw", "image/x-fuji-raf", "image/x-panasonic-rw2", "image/x-olympus-orf",
+	"image/svg+xml", "image/vnd.adobe.photoshop",
+	"video/x-msvideo", "video/x-ms-asf",
+}
+
+// MediaTypeOf returns the kind and MIME type of a file extension the server
+// knows how to handle.
+func MediaTypeOf(ext string) (MediaKind, string, bool) {
+	ext = strings.ToLower(ext)
+	if mimeType, ok := imageMediaTypes[ext]; ok {
+		return MediaKindImage, mimeType, true
+	}
+	if mimeType, ok := videoMediaTypes[ext]; ok {
+		return MediaKindVideo, mimeType, true
+	}
+	if mimeType, ok := sidecarMediaTypes[ext]; ok {
+		return MediaKindSidecar, mimeType, true
+	}
+	return "", "", false
+}
+
+// MimeTypeByExtension returns the MIME type of ext, preferring the media
+// types above over the system MIME database.
+func MimeTypeByExtension(ext string) string {
+	if _, mimeType, ok := MediaTypeOf(ext); ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// SupportedMediaTypes lists the extensions an upload configuration accepts,
+// split the way the web uploader filters picked files.
+type SupportedMediaTypes struct {
+	Image   []string
+	Video   []string
+	Sidecar []string
+}
+
+// SupportedMediaTypes returns the accepted extensions of the configuration;
+// an empty allowlist lists every known media type. Extensions the server does
+// not know are classified by their MIME type.
+func (c UploadConfig) SupportedMediaTypes() SupportedMediaTypes {
+	extensions := c.AllowedExtensions
+	if len(extensions) == 0 {
+		extensions = defaultUploadExtensions()
+	}
+
+	var types SupportedMediaTypes
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		kind, _, ok := MediaTypeOf(ext)
+		if !ok {
+			switch mimeType := mime.TypeByExtension(ext); {
+			case strings.HasPrefix(mimeType, "image/"):
+				kind = MediaKindImage
+			case strings.HasPrefix(mimeType, "video/"):
+				kind = MediaKindVideo
+			}
+		}
+		switch kind {
+		case MediaKindImage:
+			types.Image = append(types.Image, ext)
+		case MediaKindVideo:
+			types.Video = append(types.Video, ext)
+		}
+	}
+	types.Sidecar = append(types.Sidecar, c.AllowedSidecarExtensions...)
+	sort.Strings(types.Image)
+	sort.Strings(types.Video)
+	return types
+}
+
+// ValidateUpload checks filename and contentType against the upload
+// configuration. Sidecar files are rejected: they are only accepted
+// alongside the asset they describe, never as an asset of their own.
+func (s *Service) ValidateUpload(filename, contentType string) error {
+	_, err := s.validateUpload(filename, contentType)
+	return err
+}
+
+func (s *Service) isSidecarExtension(ext string) bool {
+	for _, sidecar := range s.config.Upload.AllowedSidecarExtensions {
+		if strings.EqualFold(ext, sidecar) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultUploadExtensions are the image and video extensions accepted by
+// default.
+func defaultUploadExtensions() []string {
+	return append(sortedKeys(imageMediaTypes), sortedKeys(videoMediaTypes)...)
+}
+
+// defaultUploadMimeTypes are the MIME types of the default extensions, plus
+// their common aliases.
+func defaultUploadMimeTypes() []string {
+	seen := make(map[string]struct{})
+	for _, types := range []map[string]string{imageMediaTypes, videoMediaTypes} {
+		for _, mimeType := range types {
+			seen[mimeType] = struct{}{}
+		}
+	}
+	for _, alias := range mimeTypeAliases {
+		seen[alias] = struct{}{}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
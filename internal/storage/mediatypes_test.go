@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultUploadConfigAcceptsSupportedMediaTypes(t *testing.T) {
+	svc := &Service{config: GetDefaultStorageConfig()}
+
+	for _, filename := range []string{"IMG_0001.CR3", "clip.mxf", "photo.jpg", "scan.tif"} {
+		assert.NoError(t, svc.ValidateUpload(filename, ""), filename)
+	}
+	assert.NoError(t, svc.ValidateUpload("IMG_0001.cr3", "application/octet-stream"), "octet-stream is resolved from the extension")
+
+	err := svc.ValidateUpload("notes.txt", "text/plain")
+	require.ErrorIs(t, err, ErrUnsupportedMediaType)
+	assertStorageError(t, err, "upload asset", "notes.txt", "local", "file extension .txt is not allowed")
+
+	err = svc.ValidateUpload("IMG_0001.xmp", "")
+	require.ErrorIs(t, err, ErrUnsupportedMediaType)
+	assertStorageError(t, err, "upload asset", "IMG_0001.xmp", "local", "sidecar file .xmp can only be uploaded with its asset")
+}
+
+func TestUploadConfigSupportedMediaTypes(t *testing.T) {
+	defaults := GetDefaultStorageConfig().Upload.SupportedMediaTypes()
+	assert.Contains(t, defaults.Image, ".heic")
+	assert.Contains(t, defaults.Video, ".mxf")
+	assert.NotContains(t, defaults.Image, ".xmp")
+	assert.Equal(t, []string{".xmp"}, defaults.Sidecar)
+
+	custom := UploadConfig{AllowedExtensions: []string{".MP4", ".jpg", ".txt"}}.SupportedMediaTypes()
+	assert.Equal(t, []string{".jpg"}, custom.Image)
+	assert.Equal(t, []string{".mp4"}, custom.Video)
+	assert.Empty(t, custom.Sidecar)
+}
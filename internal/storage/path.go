@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -48,6 +49,24 @@ func buildS3PublicURL(bucket, region, endpoint, key string, forcePathStyle, useS
 	return "https://" + bucket + ".s3." + region + ".amazonaws.com/" + key
 }
 
+// proxyPresignedURL moves a pre-signed URL under proxyURL, keeping its path
+// and signed query so the proxy can forward it unchanged. An empty proxyURL
+// returns presigned as is.
+func proxyPresignedURL(presigned, proxyURL string) (string, error) {
+	if proxyURL == "" {
+		return presigned, nil
+	}
+	u, err := url.Parse(presigned)
+	if err != nil {
+		return "", err
+	}
+	rewritten := strings.TrimRight(proxyURL, "/") + u.EscapedPath()
+	if u.RawQuery != "" {
+		rewritten += "?" + u.RawQuery
+	}
+	return rewritten, nil
+}
+
 // wrapError creates a StorageError with the given operation, path, backend,
 // and underlying error. It is the canonical way to wrap errors in the storage
 // package so that every call site uses the same pattern.
@@ -92,3 +92,15 @@ func TestWrapError(t *testing.T) {
 	assert.Equal(t, "local", storageErr.Backend)
 	assert.ErrorIs(t, storageErr.Err, cause)
 }
+
+func TestProxyPresignedURL(t *testing.T) {
+	presigned := "https://s3.internal:9000/photos/library/a%20b.jpg?X-Amz-Signature=abc&X-Amz-Expires=900"
+
+	got, err := proxyPresignedURL(presigned, "https://photos.example.com/s3/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://photos.example.com/s3/photos/library/a%20b.jpg?X-Amz-Signature=abc&X-Amz-Expires=900", got)
+
+	got, err = proxyPresignedURL(presigned, "")
+	require.NoError(t, err)
+	assert.Equal(t, presigned, got)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// RangeReader reads a stored file as an io.ReadSeeker, so it can be served
+// with http.ServeContent. Seeking is free: the file is only downloaded from
+// the offset of the first read after a seek, which lets Range requests read
+// just the bytes they ask for.
+type RangeReader struct {
+	ctx     context.Context
+	service *Service
+	path    string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+// NewRangeReader returns a reader of the file at path, whose size is known.
+// Close it to release the download in progress.
+func (s *Service) NewRangeReader(ctx context.Context, path string, size int64) *RangeReader {
+	return &RangeReader{ctx: ctx, service: s, path: path, size: size}
+}
+
+// Read reads from the current offset, starting a download from there when
+// none is in progress.
+func (r *RangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.service.DownloadRange(r.ctx, r.path, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	if remaining := r.size - r.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) && r.offset < r.size {
+		// The file is shorter than it was when its size was read
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek moves the offset of the next read. A download in progress is dropped
+// unless the offset stays the same.
+func (r *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	case io.SeekStart:
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if offset != r.offset {
+		r.closeBody()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+// Close stops the download in progress.
+func (r *RangeReader) Close() error {
+	r.closeBody()
+	return nil
+}
+
+func (r *RangeReader) closeBody() {
+	if r.body != nil {
+		// A download closed before its end may fail to close, which is
+		// expected
+		_ = r.body.Close()
+		r.body = nil
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocalTestService(t *testing.T) *Service {
+	t.Helper()
+	service, err := NewService(StorageConfig{
+		Backend: "local",
+		Local:   LocalConfig{RootPath: t.TempDir()},
+	})
+	require.NoError(t, err)
+	return service
+}
+
+func TestLocalBackend_DownloadRange(t *testing.T) {
+	service := newLocalTestService(t)
+	ctx := context.Background()
+	require.NoError(t, service.UploadBytes(ctx, "a.txt", []byte("0123456789"), "text/plain"))
+
+	read := func(offset, length int64) string {
+		t.Helper()
+		reader, err := service.DownloadRange(ctx, "a.txt", offset, length)
+		require.NoError(t, err)
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "234", read(2, 3))
+	assert.Equal(t, "789", read(7, -1))
+	assert.Equal(t, "89", read(8, 10))
+
+	_, err := service.DownloadRange(ctx, "missing.txt", 0, -1)
+	assert.Error(t, err)
+}
+
+func TestRangeReader(t *testing.T) {
+	service := newLocalTestService(t)
+	ctx := context.Background()
+	require.NoError(t, service.UploadBytes(ctx, "a.txt", []byte("0123456789"), "text/plain"))
+
+	reader := service.NewRangeReader(ctx, "a.txt", 10)
+	defer reader.Close()
+
+	size, err := reader.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+
+	_, err = reader.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "6789", string(data))
+
+	_, err = reader.Seek(-8, io.SeekCurrent)
+	require.NoError(t, err)
+	part := make([]byte, 3)
+	_, err = io.ReadFull(reader, part)
+	require.NoError(t, err)
+	assert.Equal(t, "234", string(part))
+
+	_, err = reader.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
+
+func TestRangeReader_FileShrunk(t *testing.T) {
+	service := newLocalTestService(t)
+	ctx := context.Background()
+	require.NoError(t, service.UploadBytes(ctx, "a.txt", []byte("0123"), "text/plain"))
+
+	reader := service.NewRangeReader(ctx, "a.txt", 10)
+	defer reader.Close()
+	_, err := io.ReadAll(reader)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
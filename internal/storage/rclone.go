@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -172,10 +173,25 @@ func (r *RcloneBackend) Download(ctx context.Context, path string) (io.ReadClose
 		trace.WithAttributes(attribute.String("storage.path", path)))
 	defer span.End()
 
+	return r.cat(ctx, path)
+}
+
+// DownloadRange streams part of a file from the rclone remote
+func (r *RcloneBackend) DownloadRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "rclone.DownloadRange",
+		trace.WithAttributes(attribute.String("storage.path", path)))
+	defer span.End()
+
+	return r.cat(ctx, path, "--offset", strconv.FormatInt(offset, 10), "--count", strconv.FormatInt(length, 10))
+}
+
+// cat streams a file with rclone cat and the given flags
+func (r *RcloneBackend) cat(ctx context.Context, path string, flags ...string) (io.ReadCloser, error) {
+	span := trace.SpanFromContext(ctx)
 	remotePath := r.getRemotePath(path)
 
 	// Use rclone cat to stream the file
-	cmd := r.buildCommand(ctx, "cat", remotePath)
+	cmd := r.buildCommand(ctx, append(append([]string{"cat"}, flags...), remotePath)...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default retry policy of storage operations.
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 200 * time.Millisecond
+	DefaultRetryMaxBackoff     = 5 * time.Second
+)
+
+// rcloneTemporaryErrorExitCode is the exit status rclone uses for errors it
+// considers temporary, such as a rate limit or a remote 5xx.
+const rcloneTemporaryErrorExitCode = 5
+
+// RetryConfig sets how idempotent storage operations are retried after a
+// transient failure. Each retry waits a random duration up to a backoff that
+// doubles from InitialBackoff to at most MaxBackoff. Zero values keep the
+// defaults; MaxAttempts of 1 disables retries.
+type RetryConfig struct {
+	// Attempts per operation, including the first
+	MaxAttempts int `yaml:"max_attempts" env:"STORAGE_RETRY_MAX_ATTEMPTS" default:"3"`
+
+	// Backoff before the first retry
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"STORAGE_RETRY_INITIAL_BACKOFF" default:"200ms"`
+
+	// Upper bound of the backoff between retries
+	MaxBackoff time.Duration `yaml:"max_backoff" env:"STORAGE_RETRY_MAX_BACKOFF" default:"5s"`
+}
+
+func (c RetryConfig) attempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// backoff returns the longest wait before the given retry, counted from 1.
+func (c RetryConfig) backoff(retry int) time.Duration {
+	initial, limit := c.InitialBackoff, c.MaxBackoff
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if limit <= 0 {
+		limit = DefaultRetryMaxBackoff
+	}
+	backoff := initial
+	for i := 1; i < retry && backoff < limit; i++ {
+		backoff *= 2
+	}
+	return min(backoff, limit)
+}
+
+// IsRetryable reports whether err is a transient failure that may succeed
+// when the operation is repeated: a 5xx, 429 or 408 response, a timeout, a
+// dropped connection or a temporary rclone error. Missing files, denied
+// access and other client errors are not retryable, nor is anything not
+// known to be transient.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var response interface{ HTTPStatusCode() int }
+	if errors.As(err, &response) {
+		code := response.HTTPStatusCode()
+		return code >= http.StatusInternalServerError ||
+			code == http.StatusTooManyRequests ||
+			code == http.StatusRequestTimeout
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode() == rcloneTemporaryErrorExitCode
+	}
+
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// withRetry runs the idempotent operation fn until it succeeds, fails with
+// an error that is not retryable, runs out of attempts or ctx is done.
+func withRetry(ctx context.Context, config RetryConfig, op, path string, fn func() error) error {
+	attempts := config.attempts()
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= attempts || !IsRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := rand.N(config.backoff(attempt) + 1) //nolint:gosec // jitter does not need a secure source
+		trace.SpanFromContext(ctx).AddEvent("storage.retry", trace.WithAttributes(
+			attribute.String("storage.op", op),
+			attribute.Int("storage.attempt", attempt),
+			attribute.String("storage.backoff", wait.String()),
+		))
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"op":      op,
+			"path":    path,
+			"attempt": attempt,
+			"backoff": wait,
+		}).Warn("Retrying storage operation after a transient error")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
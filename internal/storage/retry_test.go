@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type httpStatusError int
+
+func (e httpStatusError) Error() string       { return fmt.Sprintf("http status %d", int(e)) }
+func (e httpStatusError) HTTPStatusCode() int { return int(e) }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"500", httpStatusError(http.StatusInternalServerError), true},
+		{"503 wrapped", wrapError("delete", "a.jpg", "s3", fmt.Errorf("failed to delete from S3: %w", httpStatusError(http.StatusServiceUnavailable))), true},
+		{"429", httpStatusError(http.StatusTooManyRequests), true},
+		{"404", httpStatusError(http.StatusNotFound), false},
+		{"403", httpStatusError(http.StatusForbidden), false},
+		{"timeout", timeoutError{}, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"not found", ErrFileNotFound, false},
+		{"unknown", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestRetryConfigBackoff(t *testing.T) {
+	config := RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, config.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, config.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, config.backoff(4))
+	assert.Equal(t, time.Second, config.backoff(5))
+	assert.Equal(t, time.Second, config.backoff(30))
+
+	assert.Equal(t, DefaultRetryInitialBackoff, RetryConfig{}.backoff(1))
+	assert.Equal(t, DefaultRetryMaxAttempts, RetryConfig{}.attempts())
+}
+
+func TestServiceRetriesTransientFailures(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{
+		httpStatusError(http.StatusBadGateway),
+		timeoutError{},
+	}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: fastRetry(3)}}
+
+	require.NoError(t, service.Delete(context.Background(), "a.jpg"))
+	assert.Equal(t, 3, backend.calls)
+}
+
+func TestServiceGivesUpAfterMaxAttempts(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{
+		httpStatusError(http.StatusInternalServerError),
+		httpStatusError(http.StatusInternalServerError),
+		httpStatusError(http.StatusInternalServerError),
+	}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: fastRetry(2)}}
+
+	err := service.DeleteAsset(context.Background(), "a.jpg")
+	assert.Equal(t, httpStatusError(http.StatusInternalServerError), err)
+	assert.Equal(t, 2, backend.calls)
+}
+
+func TestServiceDoesNotRetryPermanentFailures(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{httpStatusError(http.StatusForbidden)}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: fastRetry(3)}}
+
+	err := service.Delete(context.Background(), "a.jpg")
+	assert.Error(t, err)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestServiceStopsRetryingWhenContextIsDone(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{
+		httpStatusError(http.StatusServiceUnavailable),
+		httpStatusError(http.StatusServiceUnavailable),
+	}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Hour,
+		MaxBackoff:     time.Hour,
+	}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := service.Delete(ctx, "a.jpg")
+	assert.Error(t, err)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestServiceUploadRewindsSeekableReaderOnRetry(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{io.ErrUnexpectedEOF}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: fastRetry(3)}}
+
+	reader := bytes.NewReader([]byte("xxpayload"))
+	_, err := reader.Seek(2, io.SeekStart)
+	require.NoError(t, err)
+
+	require.NoError(t, service.Upload(context.Background(), "a.jpg", reader, "image/jpeg"))
+	assert.Equal(t, 2, backend.calls)
+	assert.Equal(t, []byte("payload"), backend.uploadData)
+	assert.Equal(t, int64(7), backend.uploadSize)
+}
+
+func TestServiceUploadDoesNotRetryStreamingReader(t *testing.T) {
+	backend := &flakyStorageBackend{errs: []error{io.ErrUnexpectedEOF}}
+	service := &Service{backend: backend, config: StorageConfig{Retry: fastRetry(3)}}
+
+	err := service.Upload(context.Background(), "a.jpg", io.MultiReader(bytes.NewReader([]byte("payload"))), "image/jpeg")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func fastRetry(attempts int) RetryConfig {
+	return RetryConfig{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+}
+
+// flakyStorageBackend fails Upload and Delete with errs, in order, before
+// succeeding.
+type flakyStorageBackend struct {
+	recordingStorageBackend
+	errs  []error
+	calls int
+}
+
+func (b *flakyStorageBackend) fail() error {
+	b.calls++
+	if len(b.errs) == 0 {
+		return nil
+	}
+	err := b.errs[0]
+	b.errs = b.errs[1:]
+	return err
+}
+
+func (b *flakyStorageBackend) Upload(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	if err := b.fail(); err != nil {
+		// Consume part of the body like an interrupted transfer would.
+		_, _ = reader.Read(make([]byte, 3))
+		return err
+	}
+	return b.recordingStorageBackend.Upload(ctx, path, reader, size, contentType)
+}
+
+func (b *flakyStorageBackend) Delete(context.Context, string) error {
+	return b.fail()
+}
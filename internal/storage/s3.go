@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -184,6 +186,33 @@ func (s *S3Backend) Download(ctx context.Context, path string) (io.ReadCloser, e
 	return result.Body, nil
 }
 
+// DownloadRange downloads part of a file from S3 with a Range request
+func (s *S3Backend) DownloadRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "s3.DownloadRange",
+		trace.WithAttributes(attribute.String("storage.path", path)))
+	defer span.End()
+
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		if length == 0 {
+			return io.NopCloser(bytes.NewReader(nil)), nil
+		}
+		byteRange += fmt.Sprint(offset + length - 1)
+	}
+
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.getObjectKey(path)),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		span.RecordError(err)
+		return nil, wrapError("download", path, "s3", fmt.Errorf("failed to download from S3: %w", err))
+	}
+
+	return result.Body, nil
+}
+
 // Delete deletes a file from S3
 func (s *S3Backend) Delete(ctx context.Context, path string) error {
 	ctx, span := tracer.Start(ctx, "s3.Delete",
@@ -250,6 +279,50 @@ func (s *S3Backend) GetSize(ctx context.Context, path string) (int64, error) {
 	return aws.ToInt64(result.ContentLength), nil
 }
 
+const (
+	// MaxS3PresignExpiry is the longest lifetime S3 accepts for a
+	// pre-signed URL.
+	MaxS3PresignExpiry = 7 * 24 * time.Hour
+
+	// DefaultS3ClockSkew is how far in the past pre-signed URLs are signed
+	// by default.
+	DefaultS3ClockSkew = time.Minute
+
+	// maxS3ClockSkew matches the time difference S3 tolerates for signed
+	// requests.
+	maxS3ClockSkew = 15 * time.Minute
+)
+
+// skewedPresigner signs pre-signed URLs skew in the past, so a service whose
+// clock runs behind ours does not reject them as not yet valid.
+type skewedPresigner struct {
+	s3.HTTPPresignerV4
+	skew time.Duration
+}
+
+func (p skewedPresigner) PresignHTTP(ctx context.Context, credentials aws.Credentials, r *http.Request, payloadHash, service, region string, signingTime time.Time, optFns ...func(*v4.SignerOptions)) (string, http.Header, error) {
+	return p.HTTPPresignerV4.PresignHTTP(ctx, credentials, r, payloadHash, service, region, signingTime.Add(-p.skew), optFns...)
+}
+
+// presignClient returns a pre-signing client whose URLs stay valid for
+// expiry from now despite the clock skew allowance.
+func (s *S3Backend) presignClient(expiry time.Duration) *s3.PresignClient {
+	skew := s.config.ClockSkew
+	return s3.NewPresignClient(s.client, func(opts *s3.PresignOptions) {
+		opts.Expires = expiry + skew
+		if skew > 0 {
+			opts.Presigner = skewedPresigner{
+				// Same as the SDK's default presigner, which S3 object
+				// keys need unescaped.
+				HTTPPresignerV4: v4.NewSigner(func(so *v4.SignerOptions) {
+					so.DisableURIPathEscaping = true
+				}),
+				skew: skew,
+			}
+		}
+	})
+}
+
 // GetPresignedUploadURL generates a pre-signed URL for uploading to S3
 func (s *S3Backend) GetPresignedUploadURL(ctx context.Context, path string, contentType string, expiry time.Duration) (*PresignedURL, error) {
 	ctx, span := tracer.Start(ctx, "s3.GetPresignedUploadURL",
@@ -262,7 +335,7 @@ func (s *S3Backend) GetPresignedUploadURL(ctx context.Context, path string, cont
 
 	key := s.getObjectKey(path)
 
-	presigner := s3.NewPresignClient(s.client)
+	presigner := s.presignClient(expiry)
 
 	putObjectInput := &s3.PutObjectInput{
 		Bucket: aws.String(s.config.Bucket),
@@ -273,14 +346,18 @@ func (s *S3Backend) GetPresignedUploadURL(ctx context.Context, path string, cont
 		putObjectInput.ContentType = aws.String(contentType)
 	}
 
-	request, err := presigner.PresignPutObject(ctx, putObjectInput, func(opts *s3.PresignOptions) {
-		opts.Expires = expiry
-	})
+	request, err := presigner.PresignPutObject(ctx, putObjectInput)
 	if err != nil {
 		span.RecordError(err)
 		return nil, wrapError("get presigned upload URL", path, "s3", fmt.Errorf("failed to generate presigned upload URL: %w", err))
 	}
 
+	uploadURL, err := proxyPresignedURL(request.URL, s.config.ProxyURL)
+	if err != nil {
+		span.RecordError(err)
+		return nil, wrapError("get presigned upload URL", path, "s3", fmt.Errorf("failed to rewrite presigned upload URL: %w", err))
+	}
+
 	headers := make(map[string]string)
 	for k, v := range request.SignedHeader {
 		if len(v) > 0 {
@@ -289,7 +366,7 @@ func (s *S3Backend) GetPresignedUploadURL(ctx context.Context, path string, cont
 	}
 
 	return &PresignedURL{
-		URL:       request.URL,
+		URL:       uploadURL,
 		Method:    request.Method,
 		Headers:   headers,
 		ExpiresAt: time.Now().Add(expiry),
@@ -307,21 +384,25 @@ func (s *S3Backend) GetPresignedDownloadURL(ctx context.Context, path string, ex
 
 	key := s.getObjectKey(path)
 
-	presigner := s3.NewPresignClient(s.client)
+	presigner := s.presignClient(expiry)
 
 	request, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(key),
-	}, func(opts *s3.PresignOptions) {
-		opts.Expires = expiry
 	})
 	if err != nil {
 		span.RecordError(err)
 		return nil, wrapError("get presigned download URL", path, "s3", fmt.Errorf("failed to generate presigned download URL: %w", err))
 	}
 
+	downloadURL, err := proxyPresignedURL(request.URL, s.config.ProxyURL)
+	if err != nil {
+		span.RecordError(err)
+		return nil, wrapError("get presigned download URL", path, "s3", fmt.Errorf("failed to rewrite presigned download URL: %w", err))
+	}
+
 	return &PresignedURL{
-		URL:       request.URL,
+		URL:       downloadURL,
 		Method:    request.Method,
 		ExpiresAt: time.Now().Add(expiry),
 	}, nil
@@ -2,9 +2,18 @@ package storage
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +30,84 @@ func TestNewS3UploadInputOmitsUnknownContentLength(t *testing.T) {
 
 	assert.Nil(t, input.ContentLength)
 }
+
+func TestS3PresignedDownloadURLAllowsClockSkew(t *testing.T) {
+	backend := &S3Backend{
+		config: S3Config{Bucket: "photos", ClockSkew: time.Minute},
+		client: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String("http://minio:9000"),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		}),
+	}
+
+	before := time.Now()
+	presigned, err := backend.GetPresignedDownloadURL(context.Background(), "library/a b.jpg", time.Hour)
+	require.NoError(t, err)
+
+	u, err := url.Parse(presigned.URL)
+	require.NoError(t, err)
+	query := u.Query()
+
+	// Signed a minute early, and valid a minute longer to make up for it.
+	assert.Equal(t, "3660", query.Get("X-Amz-Expires"))
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, before.Add(-time.Minute), signedAt, 5*time.Second)
+	assert.WithinDuration(t, before.Add(time.Hour), presigned.ExpiresAt, 5*time.Second)
+}
+
+func TestS3DownloadRange(t *testing.T) {
+	source := []byte("0123456789")
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/photos/library/a.mp4", r.URL.Path)
+		byteRange := r.Header.Get("Range")
+		ranges = append(ranges, byteRange)
+		var start, end int
+		if _, err := fmt.Sscanf(byteRange, "bytes=%d-%d", &start, &end); err != nil {
+			end = len(source) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(source)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(source[start : end+1])
+	}))
+	defer server.Close()
+
+	backend := &S3Backend{
+		config: S3Config{Bucket: "photos"},
+		client: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+			Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		}),
+	}
+
+	for _, tt := range []struct {
+		offset, length int64
+		header         string
+	}{
+		{offset: 2, length: 3, header: "bytes=2-4"},
+		{offset: 7, length: -1, header: "bytes=7-"},
+	} {
+		reader, err := backend.DownloadRange(context.Background(), "library/a.mp4", tt.offset, tt.length)
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, reader.Close())
+		require.NoError(t, err)
+		want := source[tt.offset:]
+		if tt.length >= 0 {
+			want = want[:tt.length]
+		}
+		assert.Equal(t, want, data)
+		assert.Equal(t, tt.header, ranges[len(ranges)-1])
+	}
+
+	reader, err := backend.DownloadRange(context.Background(), "library/a.mp4", 4, 0)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	assert.Empty(t, data)
+	assert.Len(t, ranges, 2, "empty ranges are not requested")
+}
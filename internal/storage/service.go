@@ -5,7 +5,6 @@ import (
 	"crypto/md5" //nolint:gosec // MD5 is used for path generation, not security
 	"fmt"
 	"io"
-	"mime"
 	"path/filepath"
 	"strings"
 	"time"
@@ -18,6 +17,10 @@ import (
 type Service struct {
 	backend StorageBackend
 	config  StorageConfig
+
+	// derivatives holds thumbnails, previews and transcodes when they are
+	// configured to live apart from the originals
+	derivatives *Service
 }
 
 // NewService creates a new storage service
@@ -31,10 +34,40 @@ func NewService(config StorageConfig) (*Service, error) {
 		return nil, err
 	}
 
-	return &Service{
+	service := &Service{
 		backend: backend,
 		config:  config,
-	}, nil
+	}
+
+	if config.Derivatives.Separate() {
+		derivativesConfig := config.Derivatives.storageConfig(config)
+		derivativesBackend, err := NewStorageBackend(derivativesConfig)
+		if err != nil {
+			_ = backend.Close()
+			return nil, err
+		}
+		service.derivatives = &Service{
+			backend: derivativesBackend,
+			config:  derivativesConfig,
+		}
+	}
+
+	return service, nil
+}
+
+// Derivatives returns the service that stores files generated from
+// originals: thumbnails, previews and transcoded videos. It is the service
+// itself unless a separate derivatives backend is configured.
+func (s *Service) Derivatives() *Service {
+	if s == nil || s.derivatives == nil {
+		return s
+	}
+	return s.derivatives
+}
+
+// PresignedURLs returns the configured lifetimes of pre-signed download URLs.
+func (s *Service) PresignedURLs() PresignedURLConfig {
+	return s.config.PresignedURLs
 }
 
 // validateUpload checks that filename and contentType are allowed by the
@@ -42,17 +75,19 @@ func NewService(config StorageConfig) (*Service, error) {
 // if any rule is violated.
 func (s *Service) validateUpload(filename, contentType string) (ext string, err error) {
 	ext = strings.ToLower(filepath.Ext(filename))
+	if s.isSidecarExtension(ext) {
+		return "", wrapError("upload asset", filename, s.config.Backend, fmt.Errorf("%w: sidecar file %s can only be uploaded with its asset", ErrUnsupportedMediaType, ext))
+	}
 	if !s.isAllowedExtension(ext) {
-		return "", wrapError("upload asset", filename, s.config.Backend, fmt.Errorf("file extension %s is not allowed", ext))
+		return "", wrapError("upload asset", filename, s.config.Backend, fmt.Errorf("%w: file extension %s is not allowed", ErrUnsupportedMediaType, ext))
 	}
-	if contentType == "" {
-		contentType = mime.TypeByExtension(ext)
-		if contentType == "" {
-			contentType = "application/octet-stream"
-		}
+	// Browsers send application/octet-stream for files they do not
+	// recognise, such as most RAW formats; the extension decides then.
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = MimeTypeByExtension(ext)
 	}
 	if !s.isAllowedMimeType(contentType) {
-		return "", wrapError("upload asset", filename, s.config.Backend, fmt.Errorf("MIME type %s is not allowed", contentType))
+		return "", wrapError("upload asset", filename, s.config.Backend, fmt.Errorf("%w: MIME type %s is not allowed", ErrUnsupportedMediaType, contentType))
 	}
 	return ext, nil
 }
@@ -79,16 +114,13 @@ func (s *Service) UploadAsset(ctx context.Context, userID string, filename strin
 	}
 
 	// Detect content type
-	contentType := mime.TypeByExtension(ext)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
+	contentType := MimeTypeByExtension(ext)
 
 	// Generate asset path
 	assetPath := s.generateAssetPath(userID, filename)
 
 	// Upload the file
-	if err := s.backend.Upload(ctx, assetPath, reader, size, contentType); err != nil {
+	if err := s.upload(ctx, "upload asset", assetPath, reader, size, contentType); err != nil {
 		span.RecordError(err)
 		return nil, err
 	}
@@ -186,14 +218,22 @@ func (s *Service) DownloadAsset(ctx context.Context, assetPath string) (io.ReadC
 	defer span.End()
 
 	// Get metadata first
-	metadata, err := s.backend.GetMetadata(ctx, assetPath)
+	var metadata *FileMetadata
+	err := s.retry(ctx, "get metadata", assetPath, func() (err error) {
+		metadata, err = s.backend.GetMetadata(ctx, assetPath)
+		return err
+	})
 	if err != nil {
 		span.RecordError(err)
 		return nil, nil, err
 	}
 
 	// Download the file
-	reader, err := s.backend.Download(ctx, assetPath)
+	var reader io.ReadCloser
+	err = s.retry(ctx, "download", assetPath, func() (err error) {
+		reader, err = s.backend.Download(ctx, assetPath)
+		return err
+	})
 	if err != nil {
 		span.RecordError(err)
 		return nil, nil, err
@@ -208,7 +248,9 @@ func (s *Service) DeleteAsset(ctx context.Context, assetPath string) error {
 		trace.WithAttributes(attribute.String("storage.asset_path", assetPath)))
 	defer span.End()
 
-	return s.backend.Delete(ctx, assetPath)
+	return s.retry(ctx, "delete", assetPath, func() error {
+		return s.backend.Delete(ctx, assetPath)
+	})
 }
 
 // AssetExists checks if an asset exists
@@ -217,7 +259,12 @@ func (s *Service) AssetExists(ctx context.Context, assetPath string) (bool, erro
 		trace.WithAttributes(attribute.String("storage.asset_path", assetPath)))
 	defer span.End()
 
-	return s.backend.Exists(ctx, assetPath)
+	var exists bool
+	err := s.retry(ctx, "exists", assetPath, func() (err error) {
+		exists, err = s.backend.Exists(ctx, assetPath)
+		return err
+	})
+	return exists, err
 }
 
 // GetAssetMetadata returns metadata about an asset
@@ -226,7 +273,12 @@ func (s *Service) GetAssetMetadata(ctx context.Context, assetPath string) (*File
 		trace.WithAttributes(attribute.String("storage.asset_path", assetPath)))
 	defer span.End()
 
-	return s.backend.GetMetadata(ctx, assetPath)
+	var metadata *FileMetadata
+	err := s.retry(ctx, "get metadata", assetPath, func() (err error) {
+		metadata, err = s.backend.GetMetadata(ctx, assetPath)
+		return err
+	})
+	return metadata, err
 }
 
 // ListAssets lists assets with optional prefix filtering
@@ -245,7 +297,12 @@ func (s *Service) ListAssets(ctx context.Context, userID string, prefix string,
 		userPrefix = fmt.Sprintf("%s/%s", userPrefix, prefix)
 	}
 
-	return s.backend.List(ctx, userPrefix, recursive)
+	var files []FileInfo
+	err := s.retry(ctx, "list", userPrefix, func() (err error) {
+		files, err = s.backend.List(ctx, userPrefix, recursive)
+		return err
+	})
+	return files, err
 }
 
 // generateAssetPath generates a unique path for an asset
@@ -296,7 +353,13 @@ func (s *Service) isAllowedMimeType(mimeType string) bool {
 
 // Close closes the storage service
 func (s *Service) Close() error {
-	return s.backend.Close()
+	err := s.backend.Close()
+	if s.derivatives != nil {
+		if derr := s.derivatives.Close(); err == nil {
+			err = derr
+		}
+	}
+	return err
 }
 
 // AssetUploadResult represents the result of an asset upload
@@ -353,7 +416,36 @@ func (s *Service) Upload(ctx context.Context, path string, reader io.Reader, con
 		return wrapError("upload", path, s.config.Backend, err)
 	}
 
-	return s.backend.Upload(ctx, path, reader, size, contentType)
+	return s.upload(ctx, "upload", path, reader, size, contentType)
+}
+
+// upload uploads reader to path. A seekable reader is rewound and the upload
+// retried after a transient failure; any other reader is uploaded once.
+func (s *Service) upload(ctx context.Context, op, path string, reader io.Reader, size int64, contentType string) error {
+	seeker, ok := reader.(io.Seeker)
+	if !ok {
+		return s.backend.Upload(ctx, path, reader, size, contentType)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return s.backend.Upload(ctx, path, reader, size, contentType)
+	}
+
+	attempt := 0
+	return s.retry(ctx, op, path, func() error {
+		attempt++
+		if attempt > 1 {
+			if err := restoreReaderPosition(seeker, start); err != nil {
+				return wrapError(op, path, s.config.Backend, fmt.Errorf("failed to rewind upload: %w", err))
+			}
+		}
+		return s.backend.Upload(ctx, path, reader, size, contentType)
+	})
+}
+
+// retry runs the idempotent operation fn with the configured retry policy.
+func (s *Service) retry(ctx context.Context, op, path string, fn func() error) error {
+	return withRetry(ctx, s.config.Retry, op, path, fn)
 }
 
 func readableSize(reader io.Reader) (int64, error) {
@@ -399,7 +491,27 @@ func (s *Service) Download(ctx context.Context, path string) (io.ReadCloser, err
 		trace.WithAttributes(attribute.String("storage.path", path)))
 	defer span.End()
 
-	return s.backend.Download(ctx, path)
+	var reader io.ReadCloser
+	err := s.retry(ctx, "download", path, func() (err error) {
+		reader, err = s.backend.Download(ctx, path)
+		return err
+	})
+	return reader, err
+}
+
+// DownloadRange retrieves length bytes from the specified path starting at
+// offset, or the rest of the file when length is negative.
+func (s *Service) DownloadRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "storage.DownloadRange",
+		trace.WithAttributes(attribute.String("storage.path", path)))
+	defer span.End()
+
+	var reader io.ReadCloser
+	err := s.retry(ctx, "download", path, func() (err error) {
+		reader, err = s.backend.DownloadRange(ctx, path, offset, length)
+		return err
+	})
+	return reader, err
 }
 
 // Delete deletes data from the specified path.
@@ -408,7 +520,26 @@ func (s *Service) Delete(ctx context.Context, path string) error {
 		trace.WithAttributes(attribute.String("storage.path", path)))
 	defer span.End()
 
-	return s.backend.Delete(ctx, path)
+	return s.retry(ctx, "delete", path, func() error {
+		return s.backend.Delete(ctx, path)
+	})
+}
+
+// Move moves data from srcPath to dstPath. It is not retried: a move that
+// failed after the data arrived cannot be repeated.
+func (s *Service) Move(ctx context.Context, srcPath, dstPath string) error {
+	ctx, span := tracer.Start(ctx, "storage.Move",
+		trace.WithAttributes(
+			attribute.String("storage.src_path", srcPath),
+			attribute.String("storage.dst_path", dstPath),
+		))
+	defer span.End()
+
+	if err := s.backend.Move(ctx, srcPath, dstPath); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
 }
 
 // Exists reports whether data exists at the specified path.
@@ -417,7 +548,12 @@ func (s *Service) Exists(ctx context.Context, path string) (bool, error) {
 		trace.WithAttributes(attribute.String("storage.path", path)))
 	defer span.End()
 
-	return s.backend.Exists(ctx, path)
+	var exists bool
+	err := s.retry(ctx, "exists", path, func() (err error) {
+		exists, err = s.backend.Exists(ctx, path)
+		return err
+	})
+	return exists, err
 }
 
 // List lists storage entries beneath the specified prefix.
@@ -429,7 +565,12 @@ func (s *Service) List(ctx context.Context, prefix string, recursive bool) ([]Fi
 		))
 	defer span.End()
 
-	return s.backend.List(ctx, prefix, recursive)
+	var files []FileInfo
+	err := s.retry(ctx, "list", prefix, func() (err error) {
+		files, err = s.backend.List(ctx, prefix, recursive)
+		return err
+	})
+	return files, err
 }
 
 // UploadBytes uploads byte data to the specified path
@@ -442,11 +583,24 @@ func (s *Service) UploadBytes(ctx context.Context, path string, data []byte, con
 		))
 	defer span.End()
 
-	return s.backend.UploadBytes(ctx, path, data, contentType)
+	return s.retry(ctx, "upload bytes", path, func() error {
+		return s.backend.UploadBytes(ctx, path, data, contentType)
+	})
 }
 
 // GeneratePresignedDownloadURL generates a presigned URL for downloading
 func (s *Service) GeneratePresignedDownloadURL(ctx context.Context, path string, expiry time.Duration) (string, error) {
+	presignedURL, err := s.PresignDownload(ctx, path, expiry)
+	if err != nil {
+		return "", err
+	}
+
+	return presignedURL.URL, nil
+}
+
+// PresignDownload generates a presigned URL for downloading, along with when
+// it expires.
+func (s *Service) PresignDownload(ctx context.Context, path string, expiry time.Duration) (*PresignedURL, error) {
 	ctx, span := tracer.Start(ctx, "storage.GeneratePresignedDownloadURL",
 		trace.WithAttributes(
 			attribute.String("storage.path", path),
@@ -454,10 +608,5 @@ func (s *Service) GeneratePresignedDownloadURL(ctx context.Context, path string,
 		))
 	defer span.End()
 
-	presignedURL, err := s.backend.GetPresignedDownloadURL(ctx, path, expiry)
-	if err != nil {
-		return "", err
-	}
-
-	return presignedURL.URL, nil
+	return s.backend.GetPresignedDownloadURL(ctx, path, expiry)
 }
@@ -52,6 +52,45 @@ func TestServiceUploadUsesUnknownSizeForStreamingReader(t *testing.T) {
 	assert.Equal(t, content, backend.uploadData)
 }
 
+func TestServiceDerivativesDefaultsToOriginals(t *testing.T) {
+	service, err := NewService(StorageConfig{
+		Backend: "local",
+		Local:   LocalConfig{RootPath: t.TempDir()},
+	})
+	require.NoError(t, err)
+	defer service.Close()
+
+	assert.Same(t, service, service.Derivatives())
+	assert.Nil(t, (*Service)(nil).Derivatives())
+}
+
+func TestServiceDerivativesUseSeparateBackend(t *testing.T) {
+	originalsRoot := t.TempDir()
+	derivativesRoot := t.TempDir()
+	service, err := NewService(StorageConfig{
+		Backend: "local",
+		Local:   LocalConfig{RootPath: originalsRoot},
+		Derivatives: DerivativesConfig{
+			Backend: "local",
+			Local:   LocalConfig{RootPath: derivativesRoot},
+		},
+	})
+	require.NoError(t, err)
+	defer service.Close()
+
+	ctx := context.Background()
+	const path = "library/user/thumbnails/photo_thumbnail.jpg"
+	require.NoError(t, service.Derivatives().UploadBytes(ctx, path, []byte("thumb"), "image/jpeg"))
+
+	exists, err := service.Derivatives().Exists(ctx, path)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = service.Exists(ctx, path)
+	require.NoError(t, err)
+	assert.False(t, exists, "derivatives must not land on the originals backend")
+}
+
 type recordingStorageBackend struct {
 	uploadPath        string
 	uploadSize        int64
@@ -92,6 +131,10 @@ func (b *recordingStorageBackend) Download(context.Context, string) (io.ReadClos
 	return nil, nil
 }
 
+func (b *recordingStorageBackend) DownloadRange(context.Context, string, int64, int64) (io.ReadCloser, error) {
+	return nil, nil
+}
+
 func (b *recordingStorageBackend) Delete(context.Context, string) error {
 	return nil
 }
@@ -0,0 +1,457 @@
+// Package storagemigration copies the files tracked in the database from one
+// storage backend to another.
+//
+// A migration runs in three steps. Run copies every original, thumbnail,
+// encoded video and sidecar to the destination, verifies each copy against a
+// SHA-256 of the source stream and records it in storage_migration_files, so
+// an interrupted run resumes with the files that are left. Once every file is
+// verified, Run rewrites the stored paths whose prefix changes. DeleteSource
+// then removes the source files; the migrate-storage command only calls it
+// when asked to with --delete-source and after Run verified every file.
+package storagemigration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+const (
+	// StatusVerified marks a file whose copy matches the source.
+	StatusVerified = "verified"
+
+	// StatusSourceDeleted marks a verified file whose source was deleted.
+	StatusSourceDeleted = "source_deleted"
+
+	defaultConcurrency      = 4
+	defaultProgressInterval = 10 * time.Second
+)
+
+// errSourceMissing is returned for tracked files that are absent from the
+// source backend. There is nothing to copy, so they do not fail the run.
+var errSourceMissing = errors.New("source file does not exist")
+
+// Queries is the subset of sqlc.Queries used by the migrator.
+type Queries interface {
+	GetStorageMigrationSourcePaths(ctx context.Context) ([]string, error)
+	GetStorageMigrationFiles(ctx context.Context, arg sqlc.GetStorageMigrationFilesParams) ([]sqlc.StorageMigrationFile, error)
+	UpsertStorageMigrationFile(ctx context.Context, arg sqlc.UpsertStorageMigrationFileParams) error
+	MarkStorageMigrationSourceDeleted(ctx context.Context, arg sqlc.MarkStorageMigrationSourceDeletedParams) error
+	UpdateMigratedOriginalPaths(ctx context.Context, arg sqlc.UpdateMigratedOriginalPathsParams) (int64, error)
+	UpdateMigratedEncodedVideoPaths(ctx context.Context, arg sqlc.UpdateMigratedEncodedVideoPathsParams) (int64, error)
+	UpdateMigratedSidecarPaths(ctx context.Context, arg sqlc.UpdateMigratedSidecarPathsParams) (int64, error)
+	UpdateMigratedAssetFilePaths(ctx context.Context, arg sqlc.UpdateMigratedAssetFilePathsParams) (int64, error)
+}
+
+// Backend is the subset of storage.StorageBackend used by the migrator.
+type Backend interface {
+	Upload(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error
+	Download(ctx context.Context, path string) (io.ReadCloser, error)
+	Delete(ctx context.Context, path string) error
+	Exists(ctx context.Context, path string) (bool, error)
+	GetSize(ctx context.Context, path string) (int64, error)
+}
+
+// Options configures a migration.
+type Options struct {
+	// From and To name the source and destination backends. Progress is
+	// recorded per pair, so another pair starts from scratch.
+	From string
+	To   string
+
+	// FromPrefix is replaced by ToPrefix in the destination path of every
+	// file that starts with it. Paths are kept as they are when both are
+	// equal.
+	FromPrefix string
+	ToPrefix   string
+
+	// Concurrency bounds the number of files copied at once.
+	Concurrency int
+
+	// ProgressInterval is how often progress is logged.
+	ProgressInterval time.Duration
+}
+
+// Progress counts the files of a migration run.
+type Progress struct {
+	Total   int64
+	Copied  int64
+	Skipped int64
+	Missing int64
+	Failed  int64
+	Bytes   int64
+}
+
+// Migrator copies tracked files between two storage backends.
+type Migrator struct {
+	queries     Queries
+	source      Backend
+	destination Backend
+	opts        Options
+	log         *logrus.Entry
+}
+
+// New creates a migrator from source to destination.
+func New(queries Queries, source, destination Backend, opts Options) *Migrator {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = defaultProgressInterval
+	}
+
+	return &Migrator{
+		queries:     queries,
+		source:      source,
+		destination: destination,
+		opts:        opts,
+		log:         logrus.WithFields(logrus.Fields{"from": opts.From, "to": opts.To}),
+	}
+}
+
+// DestinationPath returns the path of a file on the destination backend.
+func (m *Migrator) DestinationPath(sourcePath string) string {
+	if m.opts.FromPrefix == m.opts.ToPrefix || !strings.HasPrefix(sourcePath, m.opts.FromPrefix) {
+		return sourcePath
+	}
+
+	return m.opts.ToPrefix + strings.TrimPrefix(sourcePath, m.opts.FromPrefix)
+}
+
+// Run copies and verifies every tracked file not migrated yet. When all files
+// are verified it rewrites the stored paths and returns nil; otherwise it
+// returns an error and the stored paths are left untouched, so the server
+// keeps serving from the source and a re-run retries the failed files.
+func (m *Migrator) Run(ctx context.Context) (Progress, error) {
+	paths, err := m.queries.GetStorageMigrationSourcePaths(ctx)
+	if err != nil {
+		return Progress{}, fmt.Errorf("failed to list tracked files: %w", err)
+	}
+
+	migrated, err := m.migratedFiles(ctx)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	// Files migrated before are recognised by their source path, or by their
+	// destination path once the stored paths have been rewritten.
+	done := make(map[string]struct{}, 2*len(migrated))
+	for _, file := range migrated {
+		done[file.SourcePath] = struct{}{}
+		done[file.DestinationPath] = struct{}{}
+	}
+
+	progress := &Progress{Total: int64(len(paths))}
+	var pending []string
+	for _, p := range paths {
+		if _, ok := done[p]; ok {
+			progress.Skipped++
+			continue
+		}
+		pending = append(pending, p)
+	}
+
+	m.log.WithFields(logrus.Fields{
+		"total":       progress.Total,
+		"pending":     len(pending),
+		"concurrency": m.opts.Concurrency,
+	}).Info("starting storage migration")
+
+	stopProgress := m.reportProgress(progress)
+	m.copyAll(ctx, pending, progress)
+	stopProgress()
+
+	result := snapshot(progress)
+	m.logProgress(result).Info("storage migration copy finished")
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d of %d files failed to migrate; re-run to retry them", result.Failed, result.Total)
+	}
+
+	if err := m.rewritePaths(ctx); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DeleteSource deletes the source of every verified file after checking its
+// copy is still in place. Call it only after Run returned nil.
+func (m *Migrator) DeleteSource(ctx context.Context) (int, error) {
+	files, err := m.migratedFiles(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, file := range files {
+		if file.Status != StatusVerified {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		size, err := m.destination.GetSize(ctx, file.DestinationPath)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to check copy of %s: %w", file.SourcePath, err)
+		}
+		if size != file.Size {
+			return deleted, fmt.Errorf("copy of %s is %d bytes, expected %d; keeping the source", file.SourcePath, size, file.Size)
+		}
+
+		if err := m.source.Delete(ctx, file.SourcePath); err != nil {
+			return deleted, fmt.Errorf("failed to delete source %s: %w", file.SourcePath, err)
+		}
+		if err := m.queries.MarkStorageMigrationSourceDeleted(ctx, sqlc.MarkStorageMigrationSourceDeletedParams{
+			FromBackend: m.opts.From,
+			ToBackend:   m.opts.To,
+			SourcePath:  file.SourcePath,
+		}); err != nil {
+			return deleted, fmt.Errorf("failed to record deletion of %s: %w", file.SourcePath, err)
+		}
+		deleted++
+	}
+
+	m.log.WithField("deleted", deleted).Info("deleted migrated source files")
+
+	return deleted, nil
+}
+
+func (m *Migrator) migratedFiles(ctx context.Context) ([]sqlc.StorageMigrationFile, error) {
+	files, err := m.queries.GetStorageMigrationFiles(ctx, sqlc.GetStorageMigrationFilesParams{
+		FromBackend: m.opts.From,
+		ToBackend:   m.opts.To,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration progress: %w", err)
+	}
+
+	return files, nil
+}
+
+// copyAll copies paths with at most Concurrency workers.
+func (m *Migrator) copyAll(ctx context.Context, paths []string, progress *Progress) {
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for range m.opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range work {
+				m.migrateFile(ctx, p, progress)
+			}
+		}()
+	}
+
+	for _, p := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- p
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (m *Migrator) migrateFile(ctx context.Context, sourcePath string, progress *Progress) {
+	size, err := m.copyFile(ctx, sourcePath)
+	switch {
+	case errors.Is(err, errSourceMissing):
+		atomic.AddInt64(&progress.Missing, 1)
+		m.log.WithField("path", sourcePath).Warn("tracked file is missing from the source, skipping")
+	case err != nil:
+		atomic.AddInt64(&progress.Failed, 1)
+		m.log.WithError(err).WithField("path", sourcePath).Error("failed to migrate file")
+	default:
+		atomic.AddInt64(&progress.Copied, 1)
+		atomic.AddInt64(&progress.Bytes, size)
+	}
+}
+
+// copyFile streams a file to the destination, verifies the copy and records
+// it. It returns the number of bytes copied.
+func (m *Migrator) copyFile(ctx context.Context, sourcePath string) (int64, error) {
+	exists, err := m.source.Exists(ctx, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check source: %w", err)
+	}
+	if !exists {
+		return 0, errSourceMissing
+	}
+
+	size, err := m.source.GetSize(ctx, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get source size: %w", err)
+	}
+
+	reader, err := m.source.Download(ctx, sourcePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer reader.Close()
+
+	// Hash the source while it streams to the destination instead of
+	// reading it twice.
+	destinationPath := m.DestinationPath(sourcePath)
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(reader, hash)}
+	if err := m.destination.Upload(ctx, destinationPath, counter, size, contentType(sourcePath)); err != nil {
+		return 0, fmt.Errorf("failed to upload to %s: %w", destinationPath, err)
+	}
+	sourceSum := hash.Sum(nil)
+
+	copySum, err := m.checksum(ctx, destinationPath)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(sourceSum, copySum) {
+		return 0, fmt.Errorf("checksum mismatch after copy to %s: source %x, copy %x", destinationPath, sourceSum, copySum)
+	}
+
+	if err := m.queries.UpsertStorageMigrationFile(ctx, sqlc.UpsertStorageMigrationFileParams{
+		FromBackend:     m.opts.From,
+		ToBackend:       m.opts.To,
+		SourcePath:      sourcePath,
+		DestinationPath: destinationPath,
+		Size:            counter.n,
+		Checksum:        sourceSum,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to record migrated file: %w", err)
+	}
+
+	return counter.n, nil
+}
+
+// checksum reads a file back from the destination and hashes it.
+func (m *Migrator) checksum(ctx context.Context, destinationPath string) ([]byte, error) {
+	reader, err := m.destination.Download(ctx, destinationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back %s: %w", destinationPath, err)
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, fmt.Errorf("failed to verify %s: %w", destinationPath, err)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// rewritePaths points the stored paths of every migrated file at its
+// destination path. Files whose path does not change are left alone.
+func (m *Migrator) rewritePaths(ctx context.Context) error {
+	if m.opts.FromPrefix == m.opts.ToPrefix {
+		return nil
+	}
+
+	originals, err := m.queries.UpdateMigratedOriginalPaths(ctx, sqlc.UpdateMigratedOriginalPathsParams{FromBackend: m.opts.From, ToBackend: m.opts.To})
+	if err != nil {
+		return fmt.Errorf("failed to update original paths: %w", err)
+	}
+	videos, err := m.queries.UpdateMigratedEncodedVideoPaths(ctx, sqlc.UpdateMigratedEncodedVideoPathsParams{FromBackend: m.opts.From, ToBackend: m.opts.To})
+	if err != nil {
+		return fmt.Errorf("failed to update encoded video paths: %w", err)
+	}
+	sidecars, err := m.queries.UpdateMigratedSidecarPaths(ctx, sqlc.UpdateMigratedSidecarPathsParams{FromBackend: m.opts.From, ToBackend: m.opts.To})
+	if err != nil {
+		return fmt.Errorf("failed to update sidecar paths: %w", err)
+	}
+	files, err := m.queries.UpdateMigratedAssetFilePaths(ctx, sqlc.UpdateMigratedAssetFilePathsParams{FromBackend: m.opts.From, ToBackend: m.opts.To})
+	if err != nil {
+		return fmt.Errorf("failed to update asset file paths: %w", err)
+	}
+
+	m.log.WithFields(logrus.Fields{
+		"originals":      originals,
+		"encoded_videos": videos,
+		"sidecars":       sidecars,
+		"asset_files":    files,
+	}).Info("updated stored paths")
+
+	return nil
+}
+
+// reportProgress logs progress every ProgressInterval until the returned
+// function is called.
+func (m *Migrator) reportProgress(progress *Progress) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.logProgress(snapshot(progress)).Info("storage migration progress")
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (m *Migrator) logProgress(p Progress) *logrus.Entry {
+	return m.log.WithFields(logrus.Fields{
+		"processed": p.Copied + p.Skipped + p.Missing + p.Failed,
+		"total":     p.Total,
+		"copied":    p.Copied,
+		"skipped":   p.Skipped,
+		"missing":   p.Missing,
+		"failed":    p.Failed,
+		"bytes":     p.Bytes,
+	})
+}
+
+func snapshot(p *Progress) Progress {
+	return Progress{
+		Total:   p.Total,
+		Copied:  atomic.LoadInt64(&p.Copied),
+		Skipped: atomic.LoadInt64(&p.Skipped),
+		Missing: atomic.LoadInt64(&p.Missing),
+		Failed:  atomic.LoadInt64(&p.Failed),
+		Bytes:   atomic.LoadInt64(&p.Bytes),
+	}
+}
+
+func contentType(p string) string {
+	if value := mime.TypeByExtension(path.Ext(p)); value != "" {
+		return value
+	}
+	return "application/octet-stream"
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,194 @@
+package storagemigration
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/storage"
+)
+
+type fakeQueries struct {
+	mu       sync.Mutex
+	paths    []string
+	files    map[string]sqlc.StorageMigrationFile
+	rewrites int
+}
+
+func newFakeQueries(paths ...string) *fakeQueries {
+	return &fakeQueries{paths: paths, files: map[string]sqlc.StorageMigrationFile{}}
+}
+
+func (f *fakeQueries) GetStorageMigrationSourcePaths(context.Context) ([]string, error) {
+	return f.paths, nil
+}
+
+func (f *fakeQueries) GetStorageMigrationFiles(context.Context, sqlc.GetStorageMigrationFilesParams) ([]sqlc.StorageMigrationFile, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	files := make([]sqlc.StorageMigrationFile, 0, len(f.files))
+	for _, file := range f.files {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].SourcePath < files[j].SourcePath })
+	return files, nil
+}
+
+func (f *fakeQueries) UpsertStorageMigrationFile(_ context.Context, arg sqlc.UpsertStorageMigrationFileParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[arg.SourcePath] = sqlc.StorageMigrationFile{
+		FromBackend:     arg.FromBackend,
+		ToBackend:       arg.ToBackend,
+		SourcePath:      arg.SourcePath,
+		DestinationPath: arg.DestinationPath,
+		Size:            arg.Size,
+		Checksum:        arg.Checksum,
+		Status:          StatusVerified,
+	}
+	return nil
+}
+
+func (f *fakeQueries) MarkStorageMigrationSourceDeleted(_ context.Context, arg sqlc.MarkStorageMigrationSourceDeletedParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file := f.files[arg.SourcePath]
+	file.Status = StatusSourceDeleted
+	f.files[arg.SourcePath] = file
+	return nil
+}
+
+// rewrite applies the stored path update of all four queries to f.paths.
+func (f *fakeQueries) rewrite() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rewrites++
+	var n int64
+	for i, p := range f.paths {
+		if file, ok := f.files[p]; ok && file.DestinationPath != p {
+			f.paths[i] = file.DestinationPath
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeQueries) UpdateMigratedOriginalPaths(context.Context, sqlc.UpdateMigratedOriginalPathsParams) (int64, error) {
+	return f.rewrite(), nil
+}
+
+func (f *fakeQueries) UpdateMigratedEncodedVideoPaths(context.Context, sqlc.UpdateMigratedEncodedVideoPathsParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeQueries) UpdateMigratedSidecarPaths(context.Context, sqlc.UpdateMigratedSidecarPathsParams) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeQueries) UpdateMigratedAssetFilePaths(context.Context, sqlc.UpdateMigratedAssetFilePathsParams) (int64, error) {
+	return 0, nil
+}
+
+func newLocalBackend(t *testing.T) *storage.LocalBackend {
+	t.Helper()
+	backend, err := storage.NewLocalBackend(storage.LocalConfig{RootPath: t.TempDir()})
+	require.NoError(t, err)
+	return backend
+}
+
+func readFile(t *testing.T, backend Backend, p string) string {
+	t.Helper()
+	reader, err := backend.Download(context.Background(), p)
+	require.NoError(t, err)
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// corruptingBackend flips the first byte of every upload.
+type corruptingBackend struct {
+	*storage.LocalBackend
+}
+
+func (c corruptingBackend) Upload(ctx context.Context, p string, reader io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	data[0] ^= 0xff
+	return c.UploadBytes(ctx, p, data, contentType)
+}
+
+func TestRunCopiesResumesAndDeletesSource(t *testing.T) {
+	ctx := context.Background()
+	source := newLocalBackend(t)
+	destination := newLocalBackend(t)
+	files := map[string]string{
+		"upload/library/a/photo.jpg":  "original",
+		"upload/thumbs/a/preview.jpg": "thumbnail",
+		"other/sidecar.xmp":           "sidecar",
+	}
+	for p, content := range files {
+		require.NoError(t, source.UploadBytes(ctx, p, []byte(content), "application/octet-stream"))
+	}
+	queries := newFakeQueries("other/sidecar.xmp", "upload/gone.jpg", "upload/library/a/photo.jpg", "upload/thumbs/a/preview.jpg")
+	migrator := New(queries, source, destination, Options{From: "local", To: "s3", FromPrefix: "upload/", ToPrefix: "immich/", Concurrency: 2})
+
+	progress, err := migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Progress{Total: 4, Copied: 3, Missing: 1, Bytes: int64(len("original") + len("thumbnail") + len("sidecar"))}, progress)
+	assert.Equal(t, "original", readFile(t, destination, "immich/library/a/photo.jpg"))
+	assert.Equal(t, "thumbnail", readFile(t, destination, "immich/thumbs/a/preview.jpg"))
+	assert.Equal(t, "sidecar", readFile(t, destination, "other/sidecar.xmp"), "paths outside the prefix are kept")
+	assert.Equal(t, []string{"other/sidecar.xmp", "upload/gone.jpg", "immich/library/a/photo.jpg", "immich/thumbs/a/preview.jpg"}, queries.paths)
+
+	progress, err = migrator.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), progress.Skipped, "verified files are not copied again")
+	assert.Zero(t, progress.Copied)
+
+	deleted, err := migrator.DeleteSource(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	for p := range files {
+		exists, err := source.Exists(ctx, p)
+		require.NoError(t, err)
+		assert.False(t, exists, p)
+	}
+	deleted, err = migrator.DeleteSource(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestRunKeepsPathsWhenACopyDoesNotVerify(t *testing.T) {
+	ctx := context.Background()
+	source := newLocalBackend(t)
+	require.NoError(t, source.UploadBytes(ctx, "upload/photo.jpg", []byte("original"), "image/jpeg"))
+	queries := newFakeQueries("upload/photo.jpg")
+	migrator := New(queries, source, corruptingBackend{newLocalBackend(t)}, Options{From: "local", To: "s3", FromPrefix: "upload/", ToPrefix: "immich/"})
+
+	progress, err := migrator.Run(ctx)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "re-run"), err.Error())
+	assert.Equal(t, int64(1), progress.Failed)
+	assert.Empty(t, queries.files, "unverified copies are not recorded")
+	assert.Zero(t, queries.rewrites)
+	assert.Equal(t, []string{"upload/photo.jpg"}, queries.paths)
+}
+
+func TestDestinationPath(t *testing.T) {
+	migrator := New(nil, nil, nil, Options{FromPrefix: "/data/upload/", ToPrefix: "upload/"})
+	assert.Equal(t, "upload/a/b.jpg", migrator.DestinationPath("/data/upload/a/b.jpg"))
+	assert.Equal(t, "/elsewhere/b.jpg", migrator.DestinationPath("/elsewhere/b.jpg"))
+
+	migrator = New(nil, nil, nil, Options{})
+	assert.Equal(t, "upload/a/b.jpg", migrator.DestinationPath("upload/a/b.jpg"))
+}
@@ -2,12 +2,16 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 )
 
@@ -23,6 +27,7 @@ type syncService interface {
 	DeleteAcknowledgment(ctx context.Context, userID string, assetIDs []string) error
 	GetDeltaSync(ctx context.Context, userID string, updatedAfter time.Time) (*DeltaSyncResult, error)
 	GetFullSync(ctx context.Context, userID string, limit int, updatedUntil *time.Time) ([]string, bool, *time.Time, error)
+	GetAssetSyncManifest(ctx context.Context, userID string, limit int, cursor string) (*ManifestPage, error)
 	SubscribeToEvents(userID string) chan *SyncEvent
 	UnsubscribeFromEvents(userID string, eventChan chan *SyncEvent)
 }
@@ -151,6 +156,43 @@ func (s *Server) GetFullSyncForUser(ctx context.Context, req *immichv1.GetFullSy
 	return response, nil
 }
 
+// GetAssetSyncManifest returns a page of the compact manifest of the
+// user's assets that devices reconcile with when delta sync is not enough
+func (s *Server) GetAssetSyncManifest(ctx context.Context, req *immichv1.GetAssetSyncManifestRequest) (*immichv1.GetAssetSyncManifestResponse, error) {
+	userID, err := currentUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.service.GetAssetSyncManifest(ctx, userID, int(req.GetLimit()), req.GetCursor())
+	if errors.Is(err, ErrInvalidManifestCursor) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get sync manifest", err)
+	}
+
+	assets := make([]*immichv1.AssetSyncManifestEntry, len(page.Assets))
+	for i, asset := range page.Assets {
+		assets[i] = &immichv1.AssetSyncManifestEntry{
+			Id:            asset.ID,
+			DeviceAssetId: asset.DeviceAssetID,
+			Checksum:      asset.Checksum,
+			IsTrashed:     asset.IsTrashed,
+			UpdatedAt:     timestamppb.New(asset.UpdatedAt),
+		}
+	}
+
+	response := &immichv1.GetAssetSyncManifestResponse{
+		Assets:   assets,
+		SyncedAt: timestamppb.New(page.SyncedAt),
+	}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
+}
+
 // GetSyncStream returns a stream of sync events (for real-time updates)
 func (s *Server) GetSyncStream(req *immichv1.GetSyncStreamRequest, stream immichv1.SyncService_GetSyncStreamServer) error {
 	ctx := stream.Context()
@@ -219,12 +261,17 @@ func (s *Server) GetSyncStream(req *immichv1.GetSyncStreamRequest, stream immich
 
 			switch event.Type {
 			case "asset":
+				assetIDs := event.ResourceIDs
+				if len(assetIDs) == 0 {
+					assetIDs = []string{event.ResourceID}
+				}
 				syncResponse = &immichv1.SyncStreamResponse{
 					Event: &immichv1.SyncStreamResponse_AssetEvent{
 						AssetEvent: &immichv1.AssetSyncEvent{
 							Type:      event.Action,
 							AssetId:   event.ResourceID,
 							Timestamp: timestamppb.New(event.Timestamp),
+							AssetIds:  assetIDs,
 						},
 					},
 				}
@@ -42,6 +42,12 @@ type fakeSyncService struct {
 	fullAssetIDs     []string
 	fullHasMore      bool
 	fullLastUpdated  *time.Time
+
+	manifestUserID string
+	manifestLimit  int
+	manifestCursor string
+	manifestPage   *ManifestPage
+	manifestErr    error
 }
 
 func (f *fakeSyncService) GetAcknowledgedAssets(ctx context.Context, userID string) ([]string, error) {
@@ -77,6 +83,16 @@ func (f *fakeSyncService) GetFullSync(ctx context.Context, userID string, limit
 	return f.fullAssetIDs, f.fullHasMore, f.fullLastUpdated, nil
 }
 
+func (f *fakeSyncService) GetAssetSyncManifest(ctx context.Context, userID string, limit int, cursor string) (*ManifestPage, error) {
+	f.manifestUserID = userID
+	f.manifestLimit = limit
+	f.manifestCursor = cursor
+	if f.manifestErr != nil {
+		return nil, f.manifestErr
+	}
+	return f.manifestPage, nil
+}
+
 func (f *fakeSyncService) SubscribeToEvents(userID string) chan *SyncEvent {
 	return make(chan *SyncEvent)
 }
@@ -166,6 +182,48 @@ func TestGetFullSyncForUserUsesAuthenticatedUserWithoutMutatingRequest(t *testin
 	assert.True(t, resp.LastUpdated.AsTime().Equal(lastUpdated))
 }
 
+func TestGetAssetSyncManifest(t *testing.T) {
+	updatedAt := time.Date(2026, 7, 5, 14, 0, 0, 0, time.UTC)
+	service := &fakeSyncService{
+		manifestPage: &ManifestPage{
+			Assets: []ManifestEntry{
+				{ID: "asset-1", DeviceAssetID: "IMG_0001", Checksum: "ab12", UpdatedAt: updatedAt},
+				{ID: "asset-2", DeviceAssetID: "IMG_0002", Checksum: "cd34", IsTrashed: true, UpdatedAt: updatedAt},
+			},
+			NextCursor: "asset-2",
+			SyncedAt:   updatedAt.Add(time.Minute),
+		},
+	}
+	server := newServer(service)
+	limit := int32(2)
+
+	resp, err := server.GetAssetSyncManifest(syncTestContext(syncTestUserID), &immichv1.GetAssetSyncManifestRequest{
+		Limit:  &limit,
+		Cursor: stringPtr("asset-0"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, syncTestUserID, service.manifestUserID)
+	assert.Equal(t, 2, service.manifestLimit)
+	assert.Equal(t, "asset-0", service.manifestCursor)
+	require.Len(t, resp.Assets, 2)
+	assert.Equal(t, "IMG_0002", resp.Assets[1].DeviceAssetId)
+	assert.Equal(t, "cd34", resp.Assets[1].Checksum)
+	assert.True(t, resp.Assets[1].IsTrashed)
+	assert.True(t, resp.Assets[1].UpdatedAt.AsTime().Equal(updatedAt))
+	assert.Equal(t, "asset-2", resp.GetNextCursor())
+	assert.True(t, resp.SyncedAt.AsTime().Equal(updatedAt.Add(time.Minute)))
+
+	service.manifestPage = &ManifestPage{}
+	resp, err = server.GetAssetSyncManifest(syncTestContext(syncTestUserID), &immichv1.GetAssetSyncManifestRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, resp.NextCursor, "the last page has no cursor")
+
+	service.manifestErr = ErrInvalidManifestCursor
+	_, err = server.GetAssetSyncManifest(syncTestContext(syncTestUserID), &immichv1.GetAssetSyncManifestRequest{Cursor: stringPtr("nope")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func syncTestContext(userID string) context.Context {
 	return auth.WithClaims(context.Background(), &auth.Claims{UserID: userID})
 }
@@ -2,6 +2,8 @@ package sync
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -59,8 +61,11 @@ type SyncEvent struct {
 	Action     string // "upsert", "delete"
 	UserID     string // User who owns the resource
 	ResourceID string // ID of the asset/album/partner
-	Timestamp  time.Time
-	Data       interface{} // Optional additional data
+	// ResourceIDs holds every resource of an event about several of them,
+	// such as a bulk update, and is empty otherwise
+	ResourceIDs []string
+	Timestamp   time.Time
+	Data        interface{} // Optional additional data
 }
 
 // DeltaSyncResult contains changes since last sync
@@ -97,8 +102,10 @@ func (s *Service) GetDeltaSync(ctx context.Context, userID string, updatedAfter
 			AssetsStatusEnum: sqlc.AssetsStatusEnumActive,
 			Valid:            true,
 		},
-		Offset: pgtype.Int4{Int32: 0, Valid: true},
-		Limit:  pgtype.Int4{Int32: 100, Valid: true},
+		Offset:     pgtype.Int4{Int32: 0, Valid: true},
+		Limit:      pgtype.Int4{Int32: 100, Valid: true},
+		SortBy:     "date_taken",
+		Descending: true,
 	})
 	if err != nil {
 		// If query fails, fall back to full sync
@@ -159,10 +166,12 @@ func (s *Service) GetFullSync(ctx context.Context, userID string, limit int, upd
 
 	// Get assets for user
 	params := sqlc.GetUserAssetsParams{
-		OwnerId: userUUID,
-		Status:  sqlc.NullAssetsStatusEnum{},
-		Offset:  pgtype.Int4{Int32: 0, Valid: true},
-		Limit:   pgtype.Int4{Int32: int32(limit + 1), Valid: true}, // Get one extra to check if there are more
+		OwnerId:    userUUID,
+		Status:     sqlc.NullAssetsStatusEnum{},
+		Offset:     pgtype.Int4{Int32: 0, Valid: true},
+		Limit:      pgtype.Int4{Int32: int32(limit + 1), Valid: true}, // Get one extra to check if there are more
+		SortBy:     "date_taken",
+		Descending: true,
 	}
 
 	assets, err := s.queries.GetUserAssets(ctx, params)
@@ -194,6 +203,89 @@ func (s *Service) GetFullSync(ctx context.Context, userID string, limit int, upd
 	return assetIDs, hasMore, lastUpdated, nil
 }
 
+const (
+	// DefaultManifestPageSize is the number of assets of a manifest page
+	// when the client does not choose.
+	DefaultManifestPageSize = 5000
+	// MaxManifestPageSize is the most assets a manifest page holds.
+	MaxManifestPageSize = 10000
+)
+
+// ErrInvalidManifestCursor is returned for a manifest cursor that is not
+// the next cursor of an earlier page.
+var ErrInvalidManifestCursor = errors.New("invalid manifest cursor")
+
+// ManifestEntry is an asset as a device needs it to reconcile its copy of
+// the library.
+type ManifestEntry struct {
+	ID            string
+	DeviceAssetID string
+	Checksum      string // hex digest
+	IsTrashed     bool
+	UpdatedAt     time.Time
+}
+
+// ManifestPage is a page of the asset sync manifest.
+type ManifestPage struct {
+	Assets     []ManifestEntry
+	NextCursor string // empty on the last page
+	SyncedAt   time.Time
+}
+
+// GetAssetSyncManifest returns the page of the user's assets after cursor,
+// by id. It reads only the assets table, so a device can afford to walk it
+// on every resume; the last page makes delta sync available again.
+func (s *Service) GetAssetSyncManifest(ctx context.Context, userID string, limit int, cursor string) (*ManifestPage, error) {
+	userUUID := pgtype.UUID{}
+	if err := userUUID.Scan(userID); err != nil {
+		return nil, err
+	}
+	afterID := pgtype.UUID{}
+	if cursor != "" {
+		if err := afterID.Scan(cursor); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidManifestCursor, err)
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultManifestPageSize
+	}
+	limit = min(limit, MaxManifestPageSize)
+
+	syncedAt := time.Now()
+	rows, err := s.queries.GetAssetSyncManifest(ctx, sqlc.GetAssetSyncManifestParams{
+		OwnerID: userUUID,
+		AfterID: afterID,
+		Limit:   int32(limit + 1), // one more tells whether another page follows
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ManifestPage{SyncedAt: syncedAt}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.NextCursor = rows[limit-1].ID.String()
+	}
+	page.Assets = make([]ManifestEntry, len(rows))
+	for i, row := range rows {
+		page.Assets[i] = ManifestEntry{
+			ID:            row.ID.String(),
+			DeviceAssetID: row.DeviceAssetId,
+			Checksum:      string(row.Checksum),
+			IsTrashed:     row.IsTrashed,
+			UpdatedAt:     row.UpdatedAt.Time,
+		}
+	}
+
+	if page.NextCursor == "" {
+		s.lastSyncMutex.Lock()
+		s.lastSync[userID] = time.Now()
+		s.lastSyncMutex.Unlock()
+	}
+
+	return page, nil
+}
+
 // AcknowledgeSync marks assets as acknowledged by the client
 func (s *Service) AcknowledgeSync(ctx context.Context, userID string, assetIDs []string) error {
 	s.syncAckMutex.Lock()
@@ -340,6 +432,23 @@ func (s *Service) BroadcastAssetEvent(ownerID string, assetID string, action str
 	s.broadcastEvent(ownerID, event)
 }
 
+// BroadcastAssetsEvent broadcasts a change of several assets as a single
+// event, so a bulk update does not send one event per asset.
+func (s *Service) BroadcastAssetsEvent(ownerID string, assetIDs []string, action string) {
+	if len(assetIDs) == 0 {
+		return
+	}
+	event := &SyncEvent{
+		Type:        "asset",
+		Action:      action,
+		UserID:      ownerID,
+		ResourceID:  assetIDs[0],
+		ResourceIDs: assetIDs,
+		Timestamp:   time.Now(),
+	}
+	s.broadcastEvent(ownerID, event)
+}
+
 // BroadcastAlbumEvent broadcasts an album change event to all subscribers
 func (s *Service) BroadcastAlbumEvent(ownerID string, albumID string, action string) {
 	event := &SyncEvent{
@@ -58,6 +58,72 @@ func TestIntegrationGetDeltaSyncReportsDeletedAssets(t *testing.T) {
 	}, result.DeletedAssets)
 }
 
+func TestIntegrationGetAssetSyncManifest(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	ctx := context.Background()
+	tdb := testdb.SetupTestDB(t)
+
+	ownerID := tdb.CreateTestUser(t, "manifest-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "manifest-other@example.com")
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	active := tdb.CreateTestAsset(t, ownerID, "active")
+	trashed := tdb.CreateTestAsset(t, ownerID, "trashed")
+	archived := tdb.CreateTestAsset(t, ownerID, "archived")
+	deleted := tdb.CreateTestAsset(t, ownerID, "deleted")
+	locked := tdb.CreateTestAsset(t, ownerID, "locked")
+	tdb.CreateTestAsset(t, otherID, "other")
+
+	setSyncAssetState(t, ctx, tdb, trashed, sqlc.AssetsStatusEnumTrashed, now, nil)
+	setSyncAssetState(t, ctx, tdb, deleted, sqlc.AssetsStatusEnumDeleted, now, &now)
+	for assetID, visibility := range map[uuid.UUID]string{archived: "archive", locked: "locked"} {
+		_, err := tdb.Pool.Exec(ctx, `UPDATE assets SET visibility = $2::asset_visibility_enum WHERE id = $1`,
+			pgtype.UUID{Bytes: assetID, Valid: true}, visibility)
+		require.NoError(t, err)
+	}
+
+	service := NewService(tdb.Queries, nil)
+	entries := map[string]ManifestEntry{}
+	cursor := ""
+	pages := 0
+	for {
+		page, err := service.GetAssetSyncManifest(ctx, ownerID.String(), 2, cursor)
+		require.NoError(t, err)
+		pages++
+		for _, entry := range page.Assets {
+			assert.Greater(t, entry.ID, cursor, "pages are ordered by id")
+			entries[entry.ID] = entry
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Equal(t, 2, pages)
+	assert.ElementsMatch(t, []string{active.String(), trashed.String(), archived.String()}, keys(entries))
+	assert.Equal(t, "active", entries[active.String()].DeviceAssetID)
+	assert.NotEmpty(t, entries[active.String()].Checksum)
+	assert.False(t, entries[active.String()].IsTrashed)
+	assert.True(t, entries[trashed.String()].IsTrashed)
+
+	result, err := service.GetDeltaSync(ctx, ownerID.String(), now)
+	require.NoError(t, err)
+	assert.False(t, result.NeedsFullSync, "delta sync follows a complete manifest")
+
+	_, err = service.GetAssetSyncManifest(ctx, ownerID.String(), 0, "not-an-id")
+	assert.ErrorIs(t, err, ErrInvalidManifestCursor)
+}
+
+func keys(entries map[string]ManifestEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func setSyncAssetState(
 	t *testing.T,
 	ctx context.Context,
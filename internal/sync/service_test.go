@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -72,3 +73,24 @@ func TestServiceDoesNotBroadcastEventsAcrossUsers(t *testing.T) {
 	default:
 	}
 }
+
+func TestServiceBroadcastsBulkAssetEventsOnce(t *testing.T) {
+	service := NewService(nil, nil)
+	userID := "11111111-2222-3333-4444-555555555555"
+	ch := service.SubscribeToEvents(userID)
+	defer service.UnsubscribeFromEvents(userID, ch)
+
+	assetIDs := make([]string, 200)
+	for i := range assetIDs {
+		assetIDs[i] = fmt.Sprintf("asset-%d", i)
+	}
+	service.BroadcastAssetsEvent(userID, assetIDs, "update")
+	service.BroadcastAssetsEvent(userID, nil, "update")
+
+	event := <-ch
+	assert.Equal(t, "asset", event.Type)
+	assert.Equal(t, "update", event.Action)
+	assert.Equal(t, "asset-0", event.ResourceID)
+	assert.Equal(t, assetIDs, event.ResourceIDs)
+	assert.Empty(t, ch, "one event for the whole update, none without assets")
+}
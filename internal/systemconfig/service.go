@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 
@@ -241,6 +242,56 @@ func (s *Service) UpdateConfigDto(ctx context.Context, raw []byte) (Dto, error)
 	return cfg, nil
 }
 
+// thumbnailsStaleMetadataKey is the system_metadata key holding when the
+// thumbnail settings last changed.
+const thumbnailsStaleMetadataKey = "thumbnails-stale-before"
+
+// ThumbnailSettingsChanged reports whether the settings thumbnails are
+// generated with differ between before and after. The format is not
+// compared: every thumbnail is encoded as JPEG for now. Neither are the
+// presets, whose thumbnails are stored apart for each size and quality.
+func ThumbnailSettingsChanged(before, after Dto) bool {
+	changed := func(a, b ImageOptionsDto) bool {
+		return a.Size != b.Size || a.Quality != b.Quality
+	}
+	return changed(before.Image.Thumbnail, after.Image.Thumbnail) ||
+		changed(before.Image.Preview, after.Image.Preview)
+}
+
+// MarkThumbnailsStale records that thumbnails generated until now were made
+// with other settings and returns the time recorded.
+func (s *Service) MarkThumbnailsStale(ctx context.Context) (time.Time, error) {
+	now := time.Now().UTC()
+	value, err := json.Marshal(now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("marshal thumbnail change time: %w", err)
+	}
+	if _, err := s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   thumbnailsStaleMetadataKey,
+		Value: value,
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("store thumbnail change time: %w", err)
+	}
+	return now, nil
+}
+
+// ThumbnailsStaleBefore returns when the thumbnail settings last changed, or
+// the zero time when they never did.
+func (s *Service) ThumbnailsStaleBefore(ctx context.Context) (time.Time, error) {
+	meta, err := s.db.GetSystemMetadata(ctx, thumbnailsStaleMetadataKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("load thumbnail change time: %w", err)
+	}
+	var staleBefore time.Time
+	if err := json.Unmarshal(meta.Value, &staleBefore); err != nil {
+		return time.Time{}, fmt.Errorf("parse thumbnail change time: %w", err)
+	}
+	return staleBefore, nil
+}
+
 // GetSystemConfig retrieves the current system configuration
 func (s *Service) GetSystemConfig(ctx context.Context) (*SystemConfig, error) {
 	// Load config from database
@@ -70,6 +70,9 @@ type ImageDto struct {
 	Colorspace      string           `json:"colorspace"`
 	ExtractEmbedded bool             `json:"extractEmbedded"`
 	Fullsize        FullsizeImageDto `json:"fullsize"`
+	// Presets is not part of upstream: named thumbnail sizes clients can
+	// request with ?size=<name>. The web UI keeps the key when saving.
+	Presets []ImagePresetDto `json:"presets"`
 }
 
 type ImageOptionsDto struct {
@@ -78,6 +81,11 @@ type ImageOptionsDto struct {
 	Quality int    `json:"quality"`
 }
 
+type ImagePresetDto struct {
+	Name string `json:"name"`
+	ImageOptionsDto
+}
+
 type FullsizeImageDto struct {
 	Enabled bool   `json:"enabled"`
 	Format  string `json:"format"`
@@ -173,12 +181,19 @@ type MapDto struct {
 
 type MetadataDto struct {
 	Faces MetadataFacesDto `json:"faces"`
+	// WriteBack is not part of upstream: it writes edited dates and
+	// locations back into the stored files.
+	WriteBack MetadataWriteBackDto `json:"writeBack"`
 }
 
 type MetadataFacesDto struct {
 	Import bool `json:"import"`
 }
 
+type MetadataWriteBackDto struct {
+	Enabled bool `json:"enabled"`
+}
+
 type NewVersionCheckDto struct {
 	Enabled bool `json:"enabled"`
 }
@@ -316,6 +331,7 @@ func DefaultDto() Dto {
 			Colorspace:      "p3",
 			ExtractEmbedded: false,
 			Fullsize:        FullsizeImageDto{Enabled: false, Format: "jpeg", Quality: 80},
+			Presets:         []ImagePresetDto{},
 		},
 		Job: JobDto{
 			BackgroundTask:      JobSettingsDto{Concurrency: 5},
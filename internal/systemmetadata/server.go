@@ -80,6 +80,7 @@ func (s *Server) GetReverseGeocodingState(ctx context.Context, request *immichv1
 	return &immichv1.GetReverseGeocodingStateResponse{
 		LastUpdate:         response.LastUpdate,
 		LastImportFileName: response.LastImportFileName,
+		LastImport:         response.LastImport,
 	}, nil
 }
 
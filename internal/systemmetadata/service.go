@@ -2,12 +2,15 @@ package systemmetadata
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/telemetry"
+	"github.com/jackc/pgx/v5"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
@@ -15,6 +18,53 @@ import (
 
 var tracer = telemetry.GetTracer("systemmetadata")
 
+// AdminOnboardingKey holds whether an admin has completed first-run setup.
+const AdminOnboardingKey = "admin_onboarding_completed"
+
+// ReverseGeocodingStateKey holds the ReverseGeocodingState of the latest
+// geodata import.
+const ReverseGeocodingStateKey = "reverse-geocoding-state"
+
+// ReverseGeocodingState describes the imported reverse geocoding dataset.
+type ReverseGeocodingState struct {
+	// LastUpdate is the date of the dataset
+	LastUpdate         string    `json:"lastUpdate"`
+	LastImportFileName string    `json:"lastImportFileName"`
+	LastImport         time.Time `json:"lastImport"`
+}
+
+// SaveReverseGeocodingState records an import of the reverse geocoding
+// dataset.
+func SaveReverseGeocodingState(ctx context.Context, queries *sqlc.Queries, state ReverseGeocodingState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode reverse geocoding state: %w", err)
+	}
+	if _, err := queries.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
+		Key:   ReverseGeocodingStateKey,
+		Value: value,
+	}); err != nil {
+		return fmt.Errorf("failed to save reverse geocoding state: %w", err)
+	}
+	return nil
+}
+
+// ErrNotOnboarded is returned for actions that wait for first-run setup.
+var ErrNotOnboarded = errors.New("server setup has not been completed")
+
+// AdminOnboarded reports whether an admin has completed first-run setup. A
+// missing key means a fresh install that has not been set up yet.
+func AdminOnboarded(ctx context.Context, queries *sqlc.Queries) (bool, error) {
+	metadata, err := queries.GetSystemMetadata(ctx, AdminOnboardingKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return string(metadata.Value) == "true", nil
+}
+
 // Service handles system metadata operations
 type Service struct {
 	db     *sqlc.Queries
@@ -66,19 +116,9 @@ func (s *Service) GetAdminOnboarding(ctx context.Context) (*GetAdminOnboardingRe
 			metric.WithAttributes(attribute.String("operation", "get_admin_onboarding")))
 	}()
 
-	// Get admin onboarding status from system metadata
-	metadata, err := s.db.GetSystemMetadata(ctx, "admin_onboarding_completed")
+	isOnboarded, err := AdminOnboarded(ctx, s.db)
 	if err != nil {
-		// If key doesn't exist, admin hasn't been onboarded yet
-		return &GetAdminOnboardingResponse{
-			IsOnboarded: false,
-		}, nil
-	}
-
-	// Parse the value as boolean
-	isOnboarded := false
-	if len(metadata.Value) > 0 {
-		isOnboarded = string(metadata.Value) == "true"
+		return nil, err
 	}
 
 	return &GetAdminOnboardingResponse{
@@ -107,7 +147,7 @@ func (s *Service) UpdateAdminOnboarding(ctx context.Context, req UpdateAdminOnbo
 	}
 
 	_, err := s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
-		Key:   "admin_onboarding_completed",
+		Key:   AdminOnboardingKey,
 		Value: []byte(value),
 	})
 	if err != nil {
@@ -132,25 +172,24 @@ func (s *Service) GetReverseGeocodingState(ctx context.Context) (*GetReverseGeoc
 			metric.WithAttributes(attribute.String("operation", "get_reverse_geocoding_state")))
 	}()
 
-	// Get reverse geocoding state from system metadata
-	updateMetadata, _ := s.db.GetSystemMetadata(ctx, "reverse_geocoding_last_update")
-	fileMetadata, _ := s.db.GetSystemMetadata(ctx, "reverse_geocoding_last_file")
-
-	lastUpdate := int32(0)
-	if len(updateMetadata.Value) > 0 {
-		// Parse as int32
-		_, _ = fmt.Sscanf(string(updateMetadata.Value), "%d", &lastUpdate)
+	// Nothing is reported until a dataset was imported
+	metadata, err := s.db.GetSystemMetadata(ctx, ReverseGeocodingStateKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &GetReverseGeocodingStateResponse{}, nil
 	}
-
-	lastFile := int32(0)
-	if len(fileMetadata.Value) > 0 {
-		// Parse as int32
-		_, _ = fmt.Sscanf(string(fileMetadata.Value), "%d", &lastFile)
+	if err != nil {
+		return nil, err
+	}
+	var state ReverseGeocodingState
+	if err := json.Unmarshal(metadata.Value, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode reverse geocoding state: %w", err)
 	}
 
+	lastImport := state.LastImport.UTC().Format(time.RFC3339)
 	return &GetReverseGeocodingStateResponse{
-		LastUpdate:         lastUpdate,
-		LastImportFileName: lastFile,
+		LastUpdate:         &state.LastUpdate,
+		LastImportFileName: &state.LastImportFileName,
+		LastImport:         &lastImport,
 	}, nil
 }
 
@@ -184,43 +223,6 @@ func metadataStringPtr(value []byte) *string {
 	return &s
 }
 
-// SetReverseGeocodingState updates reverse geocoding state
-func (s *Service) SetReverseGeocodingState(ctx context.Context, lastUpdate int32, lastImportFileName int32) error {
-	ctx, span := tracer.Start(ctx, "systemmetadata.set_reverse_geocoding_state",
-		trace.WithAttributes(
-			attribute.Int("last_update", int(lastUpdate)),
-			attribute.Int("last_import_file_name", int(lastImportFileName)),
-		))
-	defer span.End()
-
-	start := time.Now()
-	defer func() {
-		s.operationDuration.Record(ctx, time.Since(start).Seconds(),
-			metric.WithAttributes(attribute.String("operation", "set_reverse_geocoding_state")))
-		s.operationCounter.Add(ctx, 1,
-			metric.WithAttributes(attribute.String("operation", "set_reverse_geocoding_state")))
-	}()
-
-	// Update reverse geocoding state in system metadata
-	_, err := s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
-		Key:   "reverse_geocoding_last_update",
-		Value: []byte(fmt.Sprintf("%d", lastUpdate)),
-	})
-	if err != nil {
-		return err
-	}
-
-	_, err = s.db.SetSystemMetadata(ctx, sqlc.SetSystemMetadataParams{
-		Key:   "reverse_geocoding_last_file",
-		Value: []byte(fmt.Sprintf("%d", lastImportFileName)),
-	})
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // Request/Response types
 
 type GetAdminOnboardingResponse struct {
@@ -236,8 +238,9 @@ type UpdateAdminOnboardingResponse struct {
 }
 
 type GetReverseGeocodingStateResponse struct {
-	LastUpdate         int32
-	LastImportFileName int32
+	LastUpdate         *string
+	LastImportFileName *string
+	LastImport         *string
 }
 
 type VersionCheckStateResponse struct {
@@ -0,0 +1,44 @@
+//go:build integration
+// +build integration
+
+package systemmetadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAdminOnboardingPersisted verifies a fresh install reports the admin as
+// not onboarded until setup is completed, and that the flag is persisted.
+func TestAdminOnboardingPersisted(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, err := NewService(tdb.Queries, &config.Config{})
+	require.NoError(t, err)
+
+	onboarding, err := service.GetAdminOnboarding(ctx)
+	require.NoError(t, err)
+	assert.False(t, onboarding.IsOnboarded, "a fresh install must not report onboarding as done")
+
+	_, err = service.UpdateAdminOnboarding(ctx, UpdateAdminOnboardingRequest{IsOnboarded: true})
+	require.NoError(t, err)
+
+	onboarded, err := AdminOnboarded(ctx, tdb.Queries)
+	require.NoError(t, err)
+	assert.True(t, onboarded)
+
+	_, err = service.UpdateAdminOnboarding(ctx, UpdateAdminOnboardingRequest{IsOnboarded: false})
+	require.NoError(t, err)
+
+	onboarding, err = service.GetAdminOnboarding(ctx)
+	require.NoError(t, err)
+	assert.False(t, onboarding.IsOnboarded)
+}
@@ -0,0 +1,201 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// tagPathSeparator separates the levels of a nested tag such as
+// Travel/Italy/Rome. Each level is stored as its own tag whose value is the
+// full path, linked to its parent and recorded in tags_closure.
+const tagPathSeparator = "/"
+
+// AssetTags is the resulting tag set of an asset, including the ancestors of
+// its nested tags.
+type AssetTags struct {
+	AssetID uuid.UUID
+	Tags    []sqlc.Tag
+}
+
+// normalizeTagValue trims every level of a nested tag and drops empty ones,
+// so "Travel / Italy//Rome/" becomes "Travel/Italy/Rome".
+func normalizeTagValue(value string) string {
+	var levels []string
+	for _, level := range strings.Split(value, tagPathSeparator) {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+
+	return strings.Join(levels, tagPathSeparator)
+}
+
+// createTag creates the tag with the given value, creating missing ancestors
+// first.
+func (s *Server) createTag(ctx context.Context, userID pgtype.UUID, value string, color pgtype.Text) (sqlc.Tag, error) {
+	parentID, err := s.upsertTagParent(ctx, userID, value)
+	if err != nil {
+		return sqlc.Tag{}, err
+	}
+
+	tag, err := s.queries.CreateTag(ctx, sqlc.CreateTagParams{
+		UserId:   userID,
+		Value:    value,
+		Color:    color,
+		ParentId: parentID,
+	})
+	if err != nil {
+		return sqlc.Tag{}, fmt.Errorf("failed to create tag %q: %w", value, err)
+	}
+
+	if err := s.queries.AddTagClosure(ctx, sqlc.AddTagClosureParams{TagID: tag.ID, ParentID: parentID}); err != nil {
+		return sqlc.Tag{}, fmt.Errorf("failed to link tag %q to its ancestors: %w", value, err)
+	}
+
+	return tag, nil
+}
+
+// upsertTag returns the user's tag with the given value, creating it and its
+// missing ancestors.
+func (s *Server) upsertTag(ctx context.Context, userID pgtype.UUID, value string) (sqlc.Tag, error) {
+	tag, err := s.queries.GetTagByValue(ctx, sqlc.GetTagByValueParams{UserId: userID, Value: value})
+	if err == nil {
+		return tag, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Tag{}, fmt.Errorf("failed to get tag %q: %w", value, err)
+	}
+
+	return s.createTag(ctx, userID, value, pgtype.Text{})
+}
+
+// upsertTagParent ensures the parent of a nested tag exists and returns its
+// ID, or an invalid UUID for a top-level tag.
+func (s *Server) upsertTagParent(ctx context.Context, userID pgtype.UUID, value string) (pgtype.UUID, error) {
+	i := strings.LastIndex(value, tagPathSeparator)
+	if i <= 0 {
+		return pgtype.UUID{}, nil
+	}
+
+	parent, err := s.upsertTag(ctx, userID, value[:i])
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+
+	return parent.ID, nil
+}
+
+// UpsertTagPath returns the user's tag for a possibly nested value such as
+// Travel/Italy/Rome, creating it and any missing ancestors.
+func (s *Server) UpsertTagPath(ctx context.Context, userID uuid.UUID, value string) (sqlc.Tag, error) {
+	value = normalizeTagValue(value)
+	if value == "" {
+		return sqlc.Tag{}, errors.New("tag value is empty")
+	}
+
+	return s.upsertTag(ctx, pgUUID(userID), value)
+}
+
+// ApplyTags adds every tag to every asset in one statement and returns the
+// number of new tag assignments along with the resulting tag set of each
+// asset. Tags and assets not owned by the user are skipped. Both the bulk
+// tag API and the add_tag workflow action go through here.
+func (s *Server) ApplyTags(ctx context.Context, userID uuid.UUID, assetIDs, tagIDs []uuid.UUID) (int64, []AssetTags, error) {
+	count := int64(0)
+	if len(assetIDs) > 0 && len(tagIDs) > 0 {
+		var err error
+		count, err = s.queries.BulkAddTagsToAssets(ctx, sqlc.BulkAddTagsToAssetsParams{
+			TagIds:   pgUUIDs(tagIDs),
+			UserID:   pgUUID(userID),
+			AssetIds: pgUUIDs(assetIDs),
+		})
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to add tags to assets: %w", err)
+		}
+	}
+
+	assets, err := s.assetTagSets(ctx, userID, assetIDs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, assets, nil
+}
+
+// assetTagSets returns the user's tags of each asset, in the order the assets
+// were given. Assets without tags get an empty set.
+func (s *Server) assetTagSets(ctx context.Context, userID uuid.UUID, assetIDs []uuid.UUID) ([]AssetTags, error) {
+	if len(assetIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.queries.GetAssetTagSets(ctx, sqlc.GetAssetTagSetsParams{
+		AssetIds: pgUUIDs(assetIDs),
+		UserID:   pgUUID(userID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset tags: %w", err)
+	}
+
+	byAsset := make(map[uuid.UUID][]sqlc.Tag, len(assetIDs))
+	for _, row := range rows {
+		assetID := uuid.UUID(row.AssetID.Bytes)
+		byAsset[assetID] = append(byAsset[assetID], row.Tag)
+	}
+
+	assets := make([]AssetTags, len(assetIDs))
+	for i, assetID := range assetIDs {
+		assets[i] = AssetTags{AssetID: assetID, Tags: byAsset[assetID]}
+	}
+
+	return assets, nil
+}
+
+// parseUUIDList parses the valid, distinct UUIDs of ids in order, skipping
+// the rest like the other bulk endpoints do.
+func parseUUIDList(ids []string) []uuid.UUID {
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		value, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		parsed = append(parsed, value)
+	}
+
+	return parsed
+}
+
+func pgUUIDs(ids []uuid.UUID) []pgtype.UUID {
+	values := make([]pgtype.UUID, len(ids))
+	for i, id := range ids {
+		values[i] = pgUUID(id)
+	}
+
+	return values
+}
+
+func assetTagsResponses(assets []AssetTags) []*immichv1.AssetTagsDto {
+	responses := make([]*immichv1.AssetTagsDto, len(assets))
+	for i, asset := range assets {
+		responses[i] = &immichv1.AssetTagsDto{
+			AssetId: asset.AssetID.String(),
+			Tags:    tagResponses(asset.Tags),
+		}
+	}
+
+	return responses
+}
@@ -22,6 +22,10 @@ type tagQueries interface {
 	UpdateTag(ctx context.Context, arg sqlc.UpdateTagParams) (sqlc.Tag, error)
 	AddTagToAsset(ctx context.Context, arg sqlc.AddTagToAssetParams) error
 	RemoveTagFromAsset(ctx context.Context, arg sqlc.RemoveTagFromAssetParams) error
+	GetTagByValue(ctx context.Context, arg sqlc.GetTagByValueParams) (sqlc.Tag, error)
+	AddTagClosure(ctx context.Context, arg sqlc.AddTagClosureParams) error
+	BulkAddTagsToAssets(ctx context.Context, arg sqlc.BulkAddTagsToAssetsParams) (int64, error)
+	GetAssetTagSets(ctx context.Context, arg sqlc.GetAssetTagSetsParams) ([]sqlc.GetAssetTagSetsRow, error)
 }
 
 // Server implements the TagsService with real database operations
@@ -75,6 +79,10 @@ func tagResponse(tag sqlc.Tag) *immichv1.TagResponse {
 		color := tag.Color.String
 		response.Color = &color
 	}
+	if tag.ParentId.Valid {
+		parentID := uuid.UUID(tag.ParentId.Bytes).String()
+		response.ParentId = &parentID
+	}
 
 	return response
 }
@@ -122,26 +130,27 @@ func (s *Server) GetAllTags(ctx context.Context, request *immichv1.GetAllTagsReq
 	}, nil
 }
 
-// CreateTag creates a new tag in the database
+// CreateTag creates a new tag in the database. Nested names such as
+// Travel/Italy/Rome also create the missing ancestor tags.
 func (s *Server) CreateTag(ctx context.Context, request *immichv1.CreateTagRequest) (*immichv1.TagResponse, error) {
 	userUUID, err := currentUserUUIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare tag creation params
-	params := sqlc.CreateTagParams{
-		UserId: userUUID,
-		Value:  request.GetName(),
+	value := normalizeTagValue(request.GetName())
+	if value == "" {
+		return nil, status.Error(codes.InvalidArgument, "tag name is required")
 	}
 
 	// Set color if provided
+	var color pgtype.Text
 	if request.Color != nil && *request.Color != "" {
-		params.Color = pgtype.Text{String: *request.Color, Valid: true}
+		color = pgtype.Text{String: *request.Color, Valid: true}
 	}
 
 	// Create tag in database
-	tag, err := s.queries.CreateTag(ctx, params)
+	tag, err := s.createTag(ctx, userUUID, value, color)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create tag: %v", err)
 	}
@@ -166,17 +175,17 @@ func (s *Server) UpsertTags(ctx context.Context, request *immichv1.UpsertTagsReq
 
 	// Process each tag to upsert
 	for _, tagUpsert := range request.GetTags() {
-		tagName := tagUpsert.GetName()
+		tagName := normalizeTagValue(tagUpsert.GetName())
+		if tagName == "" {
+			continue
+		}
 		if existingTag, ok := existingByName[tagName]; ok {
 			tags = append(tags, tagResponse(existingTag))
 			continue
 		}
 
 		// Tag doesn't exist, create it
-		newTag, err := s.queries.CreateTag(ctx, sqlc.CreateTagParams{
-			UserId: userUUID,
-			Value:  tagName,
-		})
+		newTag, err := s.createTag(ctx, userUUID, tagName, pgtype.Text{})
 		if err == nil {
 			existingByName[tagName] = newTag
 			tags = append(tags, tagResponse(newTag))
@@ -188,50 +197,24 @@ func (s *Server) UpsertTags(ctx context.Context, request *immichv1.UpsertTagsReq
 	}, nil
 }
 
-// BulkTagAssets performs bulk tagging operations
+// BulkTagAssets adds every tag to every asset in a single transaction and
+// returns the resulting tag set of each asset
 func (s *Server) BulkTagAssets(ctx context.Context, request *immichv1.BulkTagAssetsRequest) (*immichv1.BulkTagAssetsResponse, error) {
 	userID, err := currentUserIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	count := int32(0)
-
-	// Process bulk operations
-	for _, tagID := range request.GetTagIds() {
-		// Parse tag ID
-		tagPgUUID, err := parseUUIDParam(tagID, "invalid tag ID")
-		if err != nil {
-			continue
-		}
-
-		// Verify tag ownership
-		tag, err := s.queries.GetTag(ctx, tagPgUUID)
-		if err != nil || !tagBelongsToUser(tag, userID) {
-			continue
-		}
-
-		// Add tag to each asset
-		for _, assetID := range request.GetAssetIds() {
-			// Parse asset ID
-			assetUUID, err := uuid.Parse(assetID)
-			if err != nil {
-				continue
-			}
-			assetPgUUID := pgUUID(assetUUID)
-
-			// Add tag to asset (ignore errors for bulk operation)
-			err = s.queries.AddTagToAsset(ctx, sqlc.AddTagToAssetParams{
-				TagsId:   tagPgUUID,
-				AssetsId: assetPgUUID,
-			})
-			if err == nil {
-				count++
-			}
-		}
+	// Invalid IDs are skipped, as are tags and assets owned by someone else
+	count, assets, err := s.ApplyTags(ctx, userID, parseUUIDList(request.GetAssetIds()), parseUUIDList(request.GetTagIds()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to tag assets: %v", err)
 	}
 
-	return &immichv1.BulkTagAssetsResponse{Count: count}, nil
+	return &immichv1.BulkTagAssetsResponse{
+		Count:  int32(count),
+		Assets: assetTagsResponses(assets),
+	}, nil
 }
 
 // DeleteTag deletes a tag from the database
@@ -363,8 +346,15 @@ func (s *Server) UntagAssets(ctx context.Context, request *immichv1.UntagAssetsR
 		}
 	}
 
+	// Ancestors stay in the set while another of their descendants is applied
+	assets, err := s.assetTagSets(ctx, userID, parseUUIDList(request.GetAssetIds()))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get asset tags: %v", err)
+	}
+
 	return &immichv1.UntagAssetsResponse{
-		Count: count,
+		Count:  count,
+		Assets: assetTagsResponses(assets),
 	}, nil
 }
 
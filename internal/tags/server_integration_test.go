@@ -0,0 +1,64 @@
+//go:build integration
+
+package tags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tagNames(tags []*immichv1.TagResponse) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.GetName()
+	}
+	return names
+}
+
+func TestIntegrationNestedTagsPropagateToAncestors(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+	tdb := testdb.SetupTestDB(t)
+	userID := tdb.CreateTestUser(t, "tags-owner@example.com")
+	otherID := tdb.CreateTestUser(t, "tags-other@example.com")
+	assetID := tdb.CreateTestAsset(t, userID, "tags-asset")
+	otherAssetID := tdb.CreateTestAsset(t, otherID, "tags-other-asset")
+	ctx := auth.WithClaims(context.Background(), &auth.Claims{UserID: userID.String()})
+
+	server := NewServer(tdb.Queries)
+	rome, err := server.CreateTag(ctx, &immichv1.CreateTagRequest{Name: "Travel/Italy/Rome"})
+	require.NoError(t, err)
+	milan, err := server.CreateTag(ctx, &immichv1.CreateTagRequest{Name: "Travel/Italy/Milan"})
+	require.NoError(t, err)
+	assert.Equal(t, rome.GetParentId(), milan.GetParentId(), "siblings share the existing parent")
+
+	resp, err := server.BulkTagAssets(ctx, &immichv1.BulkTagAssetsRequest{
+		AssetIds: []string{assetID.String(), otherAssetID.String()},
+		TagIds:   []string{rome.GetId(), milan.GetId()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.GetCount(), "assets of other users are skipped")
+	require.Len(t, resp.GetAssets(), 2)
+	assert.Equal(t, []string{"Travel", "Travel/Italy", "Travel/Italy/Milan", "Travel/Italy/Rome"}, tagNames(resp.GetAssets()[0].GetTags()))
+	assert.Empty(t, resp.GetAssets()[1].GetTags())
+
+	italyAssets, err := tdb.Queries.GetTagAssets(context.Background(), pgUUID(uuid.MustParse(rome.GetParentId())))
+	require.NoError(t, err)
+	require.Len(t, italyAssets, 1, "ancestor tags find assets of their descendants")
+
+	untagged, err := server.UntagAssets(ctx, &immichv1.UntagAssetsRequest{Id: rome.GetId(), AssetIds: []string{assetID.String()}})
+	require.NoError(t, err)
+	require.Len(t, untagged.GetAssets(), 1)
+	assert.Equal(t, []string{"Travel", "Travel/Italy", "Travel/Italy/Milan"}, tagNames(untagged.GetAssets()[0].GetTags()))
+
+	untagged, err = server.UntagAssets(ctx, &immichv1.UntagAssetsRequest{Id: milan.GetId(), AssetIds: []string{assetID.String()}})
+	require.NoError(t, err)
+	require.Len(t, untagged.GetAssets(), 1)
+	assert.Empty(t, untagged.GetAssets()[0].GetTags())
+}
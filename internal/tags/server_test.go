@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,7 +30,7 @@ func TestCurrentUserIDFromContext(t *testing.T) {
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 
 	_, err = currentUserIDFromContext(auth.WithClaims(context.Background(), &auth.Claims{UserID: "not-a-uuid"}))
-	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
 
 func TestTagResponse(t *testing.T) {
@@ -100,30 +101,65 @@ func TestUpsertTagsReusesExistingTagsAndCachesCreatedTags(t *testing.T) {
 	assert.Equal(t, "new", fake.createTagCalls[0].Value)
 }
 
-func TestBulkTagAssetsCountsSuccessfulWritesForOwnedTags(t *testing.T) {
+func TestBulkTagAssetsAppliesValidIDsInOneWrite(t *testing.T) {
 	userID := uuid.MustParse("11111111-2222-3333-4444-555555555555")
-	otherUserID := uuid.MustParse("22222222-3333-4444-5555-666666666666")
-	ownedTagID := uuid.MustParse("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee")
-	otherTagID := uuid.MustParse("bbbbbbbb-cccc-dddd-eeee-ffffffffffff")
+	tagID := uuid.MustParse("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee")
 	assetID1 := uuid.MustParse("cccccccc-dddd-eeee-ffff-000000000000")
 	assetID2 := uuid.MustParse("dddddddd-eeee-ffff-0000-111111111111")
-	fake := newFakeTagQueries(
-		tagFixture(ownedTagID, userID, "owned"),
-		tagFixture(otherTagID, otherUserID, "other"),
-	)
+	tag := tagFixture(tagID, userID, "owned")
+	fake := newFakeTagQueries(tag)
+	fake.bulkAddRows = 2
+	fake.assetTagSets = []sqlc.GetAssetTagSetsRow{{AssetID: pgUUID(assetID2), Tag: tag}}
 	server := &Server{queries: fake}
 
 	resp, err := server.BulkTagAssets(auth.WithClaims(context.Background(), &auth.Claims{UserID: userID.String()}), &immichv1.BulkTagAssetsRequest{
-		TagIds:   []string{"not-a-uuid", ownedTagID.String(), otherTagID.String()},
+		TagIds:   []string{"not-a-uuid", tagID.String(), tagID.String()},
 		AssetIds: []string{assetID1.String(), "not-a-uuid", assetID2.String()},
 	})
 	require.NoError(t, err)
 	assert.Equal(t, int32(2), resp.GetCount())
-	require.Len(t, fake.addTagToAssetCalls, 2)
-	assert.Equal(t, pgUUID(ownedTagID), fake.addTagToAssetCalls[0].TagsId)
-	assert.Equal(t, pgUUID(assetID1), fake.addTagToAssetCalls[0].AssetsId)
-	assert.Equal(t, pgUUID(ownedTagID), fake.addTagToAssetCalls[1].TagsId)
-	assert.Equal(t, pgUUID(assetID2), fake.addTagToAssetCalls[1].AssetsId)
+	require.Len(t, fake.bulkAddCalls, 1)
+	assert.Equal(t, []pgtype.UUID{pgUUID(tagID)}, fake.bulkAddCalls[0].TagIds)
+	assert.Equal(t, []pgtype.UUID{pgUUID(assetID1), pgUUID(assetID2)}, fake.bulkAddCalls[0].AssetIds)
+	assert.Equal(t, pgUUID(userID), fake.bulkAddCalls[0].UserID)
+
+	require.Len(t, resp.GetAssets(), 2)
+	assert.Equal(t, assetID1.String(), resp.GetAssets()[0].GetAssetId())
+	assert.Empty(t, resp.GetAssets()[0].GetTags())
+	assert.Equal(t, assetID2.String(), resp.GetAssets()[1].GetAssetId())
+	require.Len(t, resp.GetAssets()[1].GetTags(), 1)
+	assert.Equal(t, tagID.String(), resp.GetAssets()[1].GetTags()[0].GetId())
+}
+
+func TestCreateTagCreatesMissingAncestors(t *testing.T) {
+	userID := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	travelID := uuid.MustParse("aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee")
+	italyID := uuid.MustParse("bbbbbbbb-cccc-dddd-eeee-ffffffffffff")
+	romeID := uuid.MustParse("cccccccc-dddd-eeee-ffff-000000000000")
+	fake := newFakeTagQueries(tagFixture(travelID, userID, "Travel"))
+	fake.nextCreateIDs = []uuid.UUID{italyID, romeID}
+	server := &Server{queries: fake}
+
+	resp, err := server.CreateTag(auth.WithClaims(context.Background(), &auth.Claims{UserID: userID.String()}), &immichv1.CreateTagRequest{
+		Name: " Travel / Italy//Rome/",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, romeID.String(), resp.GetId())
+	assert.Equal(t, "Travel/Italy/Rome", resp.GetName())
+	assert.Equal(t, italyID.String(), resp.GetParentId())
+
+	require.Len(t, fake.createTagCalls, 2)
+	assert.Equal(t, "Travel/Italy", fake.createTagCalls[0].Value)
+	assert.Equal(t, pgUUID(travelID), fake.createTagCalls[0].ParentId)
+	assert.Equal(t, "Travel/Italy/Rome", fake.createTagCalls[1].Value)
+	assert.Equal(t, pgUUID(italyID), fake.createTagCalls[1].ParentId)
+	assert.Equal(t, []sqlc.AddTagClosureParams{
+		{TagID: pgUUID(italyID), ParentID: pgUUID(travelID)},
+		{TagID: pgUUID(romeID), ParentID: pgUUID(italyID)},
+	}, fake.addClosureCalls)
+
+	_, err = server.CreateTag(auth.WithClaims(context.Background(), &auth.Claims{UserID: userID.String()}), &immichv1.CreateTagRequest{Name: " / "})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
 }
 
 type fakeTagQueries struct {
@@ -135,6 +171,10 @@ type fakeTagQueries struct {
 	deleteTagCalls        []pgtype.UUID
 	addTagToAssetCalls    []sqlc.AddTagToAssetParams
 	removeTagToAssetCalls []sqlc.RemoveTagFromAssetParams
+	addClosureCalls       []sqlc.AddTagClosureParams
+	bulkAddCalls          []sqlc.BulkAddTagsToAssetsParams
+	bulkAddRows           int64
+	assetTagSets          []sqlc.GetAssetTagSetsRow
 	nextCreateIDs         []uuid.UUID
 	now                   time.Time
 }
@@ -178,6 +218,7 @@ func (f *fakeTagQueries) CreateTag(ctx context.Context, arg sqlc.CreateTagParams
 		CreatedAt: pgtype.Timestamptz{Time: f.now, Valid: true},
 		UpdatedAt: pgtype.Timestamptz{Time: f.now, Valid: true},
 		Color:     arg.Color,
+		ParentId:  arg.ParentId,
 	}
 	f.tags = append(f.tags, tag)
 	f.tagsByID[tagID] = tag
@@ -223,6 +264,30 @@ func (f *fakeTagQueries) RemoveTagFromAsset(ctx context.Context, arg sqlc.Remove
 	return nil
 }
 
+func (f *fakeTagQueries) GetTagByValue(ctx context.Context, arg sqlc.GetTagByValueParams) (sqlc.Tag, error) {
+	for _, tag := range f.tags {
+		if tag.UserId.Bytes == arg.UserId.Bytes && tag.Value == arg.Value {
+			return tag, nil
+		}
+	}
+
+	return sqlc.Tag{}, pgx.ErrNoRows
+}
+
+func (f *fakeTagQueries) AddTagClosure(ctx context.Context, arg sqlc.AddTagClosureParams) error {
+	f.addClosureCalls = append(f.addClosureCalls, arg)
+	return nil
+}
+
+func (f *fakeTagQueries) BulkAddTagsToAssets(ctx context.Context, arg sqlc.BulkAddTagsToAssetsParams) (int64, error) {
+	f.bulkAddCalls = append(f.bulkAddCalls, arg)
+	return f.bulkAddRows, nil
+}
+
+func (f *fakeTagQueries) GetAssetTagSets(ctx context.Context, arg sqlc.GetAssetTagSetsParams) ([]sqlc.GetAssetTagSetsRow, error) {
+	return f.assetTagSets, nil
+}
+
 func tagFixture(tagID uuid.UUID, userID uuid.UUID, name string) sqlc.Tag {
 	now := time.Date(2026, 7, 5, 10, 0, 0, 0, time.UTC)
 	return sqlc.Tag{
@@ -2,51 +2,71 @@ package timeline
 
 import (
 	"context"
+	"errors"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/grpcutil"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
+	"github.com/denysvitali/immich-go-backend/internal/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Server implements the TimelineService
 type Server struct {
 	immichv1.UnimplementedTimelineServiceServer
 	service *Service
+	config  *config.Config
 }
 
+// defaultBucketPageSize is how many assets of a time bucket are returned when
+// the request does not set a page size.
+const defaultBucketPageSize = 500
+
 // NewServer creates a new timeline server
-func NewServer(service *Service) *Server {
+func NewServer(service *Service, cfg *config.Config) *Server {
 	return &Server{
 		service: service,
+		config:  cfg,
 	}
 }
 
 // GetTimeBucket returns assets for a specific time bucket
 func (s *Server) GetTimeBucket(ctx context.Context, req *immichv1.GetTimeBucketRequest) (*immichv1.TimeBucketAssetResponseDto, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	opts := ListOptions{
-		UserID:     claims.UserID,
+		UserID:     userID.String(),
 		Bucket:     "day",
 		Date:       req.GetTimeBucket(),
 		IsFavorite: req.GetIsFavorite(),
 		IsTrashed:  req.GetIsTrashed(),
 		IsArchived: req.GetIsTrashed(),
-		Limit:      500,
-	}
-
-	if req.PageSize != nil {
-		opts.Limit = *req.PageSize
+		Cursor:     req.GetCursor(),
+		Ascending:  s.ascending(req.Order),
 	}
+	size := s.config.PageSize(req.GetPageSize(), defaultBucketPageSize)
+	// One extra asset tells whether the bucket continues.
+	opts.Limit = size + 1
 
 	assets, err := s.service.GetBucketAssets(ctx, opts)
 	if err != nil {
+		if errors.Is(err, ErrInvalidListOptions) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to get timeline assets: %v", err)
 	}
+	var nextCursor *string
+	if len(assets) > int(size) {
+		assets = assets[:size]
+		last := assets[len(assets)-1]
+		nextCursor = util.Ptr(util.EncodeCursor(last.LocalDateTime, last.ID))
+	}
 
 	protoAssets := make([]*immichv1.Asset, len(assets))
 	for i, asset := range assets {
@@ -59,22 +79,24 @@ func (s *Server) GetTimeBucket(ctx context.Context, req *immichv1.GetTimeBucketR
 		Assets:     protoAssets,
 		TimeBucket: req.GetTimeBucket(),
 		Count:      int32(len(protoAssets)),
+		NextCursor: nextCursor,
 	}, nil
 }
 
 // GetTimeBuckets returns time buckets with asset counts
 func (s *Server) GetTimeBuckets(ctx context.Context, req *immichv1.GetTimeBucketsRequest) (*immichv1.GetTimeBucketsResponse, error) {
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	opts := ListOptions{
-		UserID:     claims.UserID,
+		UserID:     userID.String(),
 		Bucket:     "day",
 		IsFavorite: req.GetIsFavorite(),
 		IsTrashed:  req.GetIsTrashed(),
 		IsArchived: req.GetIsTrashed(),
+		Ascending:  s.ascending(req.Order),
 	}
 
 	buckets, err := s.service.GetTimeBuckets(ctx, opts)
@@ -94,3 +116,59 @@ func (s *Server) GetTimeBuckets(ctx context.Context, req *immichv1.GetTimeBucket
 		Buckets: protoBuckets,
 	}, nil
 }
+
+// GetTimelineDays returns a page of the days of the timeline with their
+// assets.
+func (s *Server) GetTimelineDays(ctx context.Context, req *immichv1.GetTimelineDaysRequest) (*immichv1.GetTimelineDaysResponse, error) {
+	userID, err := auth.UserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Limit != nil && req.GetLimit() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be positive")
+	}
+
+	page, err := s.service.GetDays(ctx, ListOptions{
+		UserID:     userID.String(),
+		IsFavorite: req.GetIsFavorite(),
+		IsTrashed:  req.GetIsTrashed(),
+		Limit:      req.GetLimit(),
+		Cursor:     req.GetCursor(),
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidListOptions) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, grpcutil.SanitizedInternal(ctx, "failed to get timeline days", err)
+	}
+
+	days := make([]*immichv1.TimelineDay, len(page.Days))
+	for i, day := range page.Days {
+		assets := make([]*immichv1.TimelineDayAsset, len(day.Assets))
+		for j, asset := range day.Assets {
+			assets[j] = &immichv1.TimelineDayAsset{
+				Id:            asset.ID.String(),
+				Type:          asset.Type,
+				Thumbhash:     asset.Thumbhash,
+				Ratio:         asset.Ratio,
+				LocalDateTime: timestamppb.New(asset.LocalDateTime),
+			}
+		}
+		days[i] = &immichv1.TimelineDay{Date: day.Date, Assets: assets}
+	}
+
+	response := &immichv1.GetTimelineDaysResponse{Days: days}
+	if page.NextCursor != "" {
+		response.NextCursor = &page.NextCursor
+	}
+	return response, nil
+}
+
+// ascending tells whether a request asked for the oldest assets first,
+// taking the configured order when it did not choose.
+func (s *Server) ascending(order *immichv1.AssetOrder) bool {
+	if order != nil {
+		return *order == immichv1.AssetOrder_ASC
+	}
+	return !s.config.ListSorting().DescendingByDefault()
+}
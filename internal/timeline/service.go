@@ -2,11 +2,13 @@ package timeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/util"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -21,10 +23,26 @@ func NewService(queries *sqlc.Queries) *Service {
 	}
 }
 
+// ErrInvalidListOptions is returned for a malformed cursor or page size.
+var ErrInvalidListOptions = errors.New("invalid timeline list options")
+
+const (
+	// DefaultDayPageSize is the number of days GetDays returns when no
+	// limit is given.
+	DefaultDayPageSize = 30
+	// MaxDayPageSize is the most days GetDays returns at once.
+	MaxDayPageSize = 100
+)
+
+// UndatedBucket is the date of the bucket holding assets without a known
+// capture date. It is the zero time, so it sorts after every dated bucket.
+const UndatedBucket = "0001-01-01"
+
 // Bucket groups assets by a calendar period.
 type Bucket struct {
-	Date  string
-	Count int64
+	Date    string
+	Count   int64
+	Undated bool
 }
 
 // BucketAsset is the data required by the upstream web UI for a timeline
@@ -58,15 +76,41 @@ type BucketAsset struct {
 	Thumbhash        *string
 }
 
+// Day is a day of the timeline with the grid layout of its assets.
+type Day struct {
+	Date    string
+	Undated bool
+	Assets  []DayAsset
+}
+
+// DayAsset is what a timeline grid needs to lay out an asset before its
+// thumbnail is loaded.
+type DayAsset struct {
+	ID            uuid.UUID
+	Type          string
+	Thumbhash     *string
+	Ratio         float64
+	LocalDateTime time.Time
+}
+
+// DayPage is a page of timeline days, newest first.
+type DayPage struct {
+	Days []Day
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
 // ListOptions selects which assets are included in a timeline view.
 type ListOptions struct {
 	UserID     string
 	Bucket     string // "day", "month", "year"
-	Date       string // YYYY-MM-DD, YYYY-MM-01 or YYYY-01-01 depending on bucket
+	Date       string // YYYY-MM-DD, YYYY-MM-01 or YYYY-01-01 depending on bucket, or UndatedBucket
 	IsFavorite bool
 	IsTrashed  bool
 	IsArchived bool
-	Limit      int32
+	Limit      int32  // assets, or days for GetDays
+	Cursor     string // NextCursor of the previous GetDays page, or util.EncodeCursor of the last asset of the previous GetBucketAssets page
+	Ascending  bool   // oldest first; GetDays is always newest first
 }
 
 func (s *Service) GetTimeBuckets(ctx context.Context, opts ListOptions) ([]Bucket, error) {
@@ -80,6 +124,7 @@ func (s *Service) GetTimeBuckets(ctx context.Context, opts ListOptions) ([]Bucke
 		DateTrunc: opts.Bucket,
 		Column3:   opts.IsFavorite,
 		Column4:   opts.IsTrashed,
+		Ascending: opts.Ascending,
 	})
 	if err != nil {
 		return nil, err
@@ -87,6 +132,10 @@ func (s *Service) GetTimeBuckets(ctx context.Context, opts ListOptions) ([]Bucke
 
 	buckets := make([]Bucket, len(rows))
 	for i, row := range rows {
+		if !row.TimeBucket.Valid {
+			buckets[i] = Bucket{Date: UndatedBucket, Count: row.Count, Undated: true}
+			continue
+		}
 		buckets[i] = Bucket{
 			Date:  row.TimeBucket.Time.Format("2006-01-02"),
 			Count: row.Count,
@@ -111,14 +160,26 @@ func (s *Service) GetBucketAssets(ctx context.Context, opts ListOptions) ([]Buck
 		limit = 500
 	}
 
-	rows, err := s.queries.GetTimelineBucketAssets(ctx, sqlc.GetTimelineBucketAssetsParams{
-		OwnerId: userUUID,
-		Column2: opts.Bucket,
-		Column3: pgtype.Date{Time: parsedDate, Valid: true},
-		Limit:   limit,
-		Column5: opts.IsFavorite,
-		Column6: opts.IsTrashed,
-	})
+	params := sqlc.GetTimelineBucketAssetsParams{
+		OwnerId:   userUUID,
+		Column2:   opts.Bucket,
+		Column3:   pgtype.Date{Time: parsedDate, Valid: true},
+		Limit:     limit,
+		Column5:   opts.IsFavorite,
+		Column6:   opts.IsTrashed,
+		Undated:   opts.Date == UndatedBucket,
+		Ascending: opts.Ascending,
+	}
+	if opts.Cursor != "" {
+		at, id, err := util.DecodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidListOptions, err)
+		}
+		params.CursorLocalDateTime = pgtype.Timestamptz{Time: at, Valid: true}
+		params.CursorID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	rows, err := s.queries.GetTimelineBucketAssets(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -193,6 +254,109 @@ func (s *Service) GetBucketAssets(ctx context.Context, opts ListOptions) ([]Buck
 	return assets, nil
 }
 
+// GetDays returns a page of the days of the timeline with their assets, so a
+// grid can be laid out with one request rather than one per bucket. Assets
+// without a known capture date come last, as the day UndatedBucket.
+func (s *Service) GetDays(ctx context.Context, opts ListOptions) (DayPage, error) {
+	userUUID, err := pgutil.StringToUUID(opts.UserID)
+	if err != nil {
+		return DayPage{}, err
+	}
+
+	limit := opts.Limit
+	if limit < 0 || limit > MaxDayPageSize {
+		return DayPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidListOptions, MaxDayPageSize)
+	}
+	if limit == 0 {
+		limit = DefaultDayPageSize
+	}
+
+	var before pgtype.Date
+	if opts.Cursor != "" {
+		cursor, err := time.Parse("2006-01-02", opts.Cursor)
+		if err != nil {
+			return DayPage{}, fmt.Errorf("%w: invalid cursor %q", ErrInvalidListOptions, opts.Cursor)
+		}
+		before = pgtype.Date{Time: cursor, Valid: true}
+	}
+
+	// One day more than asked tells whether there is a next page
+	dayRows, err := s.queries.GetTimelineDays(ctx, sqlc.GetTimelineDaysParams{
+		OwnerID:    userUUID,
+		IsTrashed:  opts.IsTrashed,
+		IsFavorite: opts.IsFavorite,
+		Before:     before,
+		DayLimit:   limit + 1,
+	})
+	if err != nil {
+		return DayPage{}, err
+	}
+
+	var page DayPage
+	if len(dayRows) > int(limit) {
+		dayRows = dayRows[:limit]
+		page.NextCursor = dayRows[len(dayRows)-1].Day.Time.Format("2006-01-02")
+	}
+	if len(dayRows) == 0 {
+		return page, nil
+	}
+
+	assetRows, err := s.queries.GetTimelineDayAssets(ctx, sqlc.GetTimelineDayAssetsParams{
+		OwnerID:    userUUID,
+		IsTrashed:  opts.IsTrashed,
+		IsFavorite: opts.IsFavorite,
+		Oldest:     dayRows[len(dayRows)-1].Day,
+		Newest:     dayRows[0].Day,
+	})
+	if err != nil {
+		return DayPage{}, err
+	}
+
+	page.Days = make([]Day, len(dayRows))
+	index := make(map[string]int, len(dayRows))
+	for i, row := range dayRows {
+		date := row.Day.Time.Format("2006-01-02")
+		page.Days[i] = Day{
+			Date:    date,
+			Undated: date == UndatedBucket,
+			Assets:  make([]DayAsset, 0, row.Count),
+		}
+		index[date] = i
+	}
+	for _, row := range assetRows {
+		// Assets added since the days were counted may fall on a day
+		// between two of the page that it does not hold.
+		i, ok := index[row.Day.Time.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		asset := DayAsset{
+			ID:            uuid.UUID(row.ID.Bytes),
+			Type:          row.Type,
+			Ratio:         dayAssetRatio(row),
+			LocalDateTime: row.LocalDateTime.Time,
+		}
+		if row.Thumbhash.Valid {
+			th := row.Thumbhash.String
+			asset.Thumbhash = &th
+		}
+		page.Days[i].Assets = append(page.Days[i].Assets, asset)
+	}
+	return page, nil
+}
+
+// dayAssetRatio is the aspect ratio of the thumbnails of an asset, or of its
+// EXIF dimensions until thumbnails are generated, or 1 when neither is known.
+func dayAssetRatio(row sqlc.GetTimelineDayAssetsRow) float64 {
+	if row.Ratio.Valid && row.Ratio.Float64 > 0 {
+		return row.Ratio.Float64
+	}
+	if row.ExifImageWidth.Valid && row.ExifImageHeight.Valid && row.ExifImageHeight.Int32 > 0 {
+		return float64(row.ExifImageWidth.Int32) / float64(row.ExifImageHeight.Int32)
+	}
+	return 1
+}
+
 func (s *Service) GetTimelineStats(ctx context.Context, userID string) (map[string]interface{}, error) {
 	userUUID, err := pgutil.ParseUserID(userID)
 	if err != nil {
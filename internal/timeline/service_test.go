@@ -8,8 +8,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
+	"github.com/denysvitali/immich-go-backend/internal/util"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -195,3 +198,197 @@ func TestIntegration_EmptyTimeline(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, int64(0), stats["total"])
 }
+
+func TestIntegration_UndatedBucket(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries)
+
+	userID := createTestUser(t, tdb, "undated@test.com")
+	createTestAsset(t, tdb, userID, "dated")
+	undatedID := createTestAsset(t, tdb, userID, "undated")
+	_, err := tdb.Pool.Exec(ctx, `UPDATE assets SET "isUndated" = true WHERE id = $1`, undatedID)
+	require.NoError(t, err)
+
+	opts := ListOptions{
+		UserID: userID.String(),
+		Bucket: "day",
+	}
+
+	buckets, err := service.GetTimeBuckets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.False(t, buckets[0].Undated)
+	assert.Equal(t, Bucket{Date: UndatedBucket, Count: 1, Undated: true}, buckets[1])
+
+	opts.Date = UndatedBucket
+	opts.Limit = 10
+	assets, err := service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, assets, 1)
+	assert.Equal(t, undatedID, assets[0].ID)
+
+	takenAt := time.Date(2020, 7, 14, 10, 0, 0, 0, time.UTC)
+	err = tdb.Queries.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+		ID:            pgtype.UUID{Bytes: undatedID, Valid: true},
+		LocalDateTime: pgtype.Timestamptz{Time: takenAt, Valid: true},
+	})
+	require.NoError(t, err)
+
+	buckets, err = service.GetTimeBuckets(ctx, ListOptions{UserID: userID.String(), Bucket: "day"})
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "2020-07-14", buckets[1].Date, "a dated asset leaves the undated bucket")
+	assert.False(t, buckets[1].Undated)
+
+	assets, err = service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	assert.Empty(t, assets)
+}
+
+func TestIntegration_GetDays(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries)
+
+	userID := createTestUser(t, tdb, "days@test.com")
+	takenAt := func(assetID uuid.UUID, at time.Time) {
+		require.NoError(t, tdb.Queries.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+			ID:            pgtype.UUID{Bytes: assetID, Valid: true},
+			LocalDateTime: pgtype.Timestamptz{Time: at, Valid: true},
+		}))
+	}
+	morning := createTestAsset(t, tdb, userID, "morning")
+	takenAt(morning, time.Date(2021, 3, 2, 9, 0, 0, 0, time.UTC))
+	evening := createTestAsset(t, tdb, userID, "evening")
+	takenAt(evening, time.Date(2021, 3, 2, 20, 0, 0, 0, time.UTC))
+	older := createTestAsset(t, tdb, userID, "older")
+	takenAt(older, time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC))
+	undated := createTestAsset(t, tdb, userID, "undated")
+	_, err := tdb.Pool.Exec(ctx, `UPDATE assets SET "isUndated" = true WHERE id = $1`, undated)
+	require.NoError(t, err)
+
+	require.NoError(t, tdb.Queries.SetAssetThumbnailLayout(ctx, sqlc.SetAssetThumbnailLayoutParams{
+		ID:        pgtype.UUID{Bytes: evening, Valid: true},
+		Thumbhash: []byte{1, 2, 3},
+		Ratio:     1.5,
+	}))
+	_, err = tdb.Pool.Exec(ctx,
+		`INSERT INTO exif ("assetId", "exifImageWidth", "exifImageHeight") VALUES ($1, 300, 400)`, morning)
+	require.NoError(t, err)
+
+	page, err := service.GetDays(ctx, ListOptions{UserID: userID.String(), Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Days, 2)
+	assert.Equal(t, "2020-12-31", page.NextCursor)
+
+	day := page.Days[0]
+	assert.Equal(t, "2021-03-02", day.Date)
+	require.Len(t, day.Assets, 2)
+	assert.Equal(t, evening, day.Assets[0].ID, "newest first")
+	assert.Equal(t, 1.5, day.Assets[0].Ratio)
+	require.NotNil(t, day.Assets[0].Thumbhash)
+	assert.Equal(t, "AQID", *day.Assets[0].Thumbhash)
+	assert.Equal(t, morning, day.Assets[1].ID)
+	assert.InDelta(t, 0.75, day.Assets[1].Ratio, 1e-9, "EXIF dimensions until thumbnails exist")
+	assert.Nil(t, day.Assets[1].Thumbhash)
+	assert.Equal(t, "2020-12-31", page.Days[1].Date)
+
+	page, err = service.GetDays(ctx, ListOptions{UserID: userID.String(), Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Days, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, Day{
+		Date:    UndatedBucket,
+		Undated: true,
+		Assets:  []DayAsset{{ID: undated, Type: "IMAGE", Ratio: 1, LocalDateTime: page.Days[0].Assets[0].LocalDateTime}},
+	}, page.Days[0])
+
+	_, err = service.GetDays(ctx, ListOptions{UserID: userID.String(), Cursor: "yesterday"})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+	_, err = service.GetDays(ctx, ListOptions{UserID: userID.String(), Limit: MaxDayPageSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+}
+
+func TestIntegration_GetBucketAssetsCursor(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries)
+
+	userID := createTestUser(t, tdb, "bucket-cursor@test.com")
+	takenAt := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	for _, deviceAssetID := range []string{"a", "b", "c"} {
+		assetID := createTestAsset(t, tdb, userID, deviceAssetID)
+		// The same capture time for all, so pages are split by id.
+		require.NoError(t, tdb.Queries.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+			ID:            pgtype.UUID{Bytes: assetID, Valid: true},
+			LocalDateTime: pgtype.Timestamptz{Time: takenAt, Valid: true},
+		}))
+	}
+
+	opts := ListOptions{UserID: userID.String(), Bucket: "day", Date: "2022-08-01", Limit: 2}
+	first, err := service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	last := first[len(first)-1]
+	opts.Cursor = util.EncodeCursor(last.LocalDateTime, last.ID)
+	rest, err := service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.NotContains(t, []uuid.UUID{first[0].ID, first[1].ID}, rest[0].ID)
+
+	opts.Cursor = "not-a-cursor"
+	_, err = service.GetBucketAssets(ctx, opts)
+	assert.ErrorIs(t, err, ErrInvalidListOptions)
+}
+
+func TestIntegration_TimelineAscending(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service := NewService(tdb.Queries)
+
+	userID := createTestUser(t, tdb, "ascending@test.com")
+	var assetIDs []uuid.UUID
+	for i, takenAt := range []time.Time{
+		time.Date(2022, 8, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2022, 8, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(2022, 8, 3, 12, 0, 0, 0, time.UTC),
+	} {
+		assetID := createTestAsset(t, tdb, userID, string(rune('a'+i)))
+		require.NoError(t, tdb.Queries.UpdateAssetLocalDateTime(ctx, sqlc.UpdateAssetLocalDateTimeParams{
+			ID:            pgtype.UUID{Bytes: assetID, Valid: true},
+			LocalDateTime: pgtype.Timestamptz{Time: takenAt, Valid: true},
+		}))
+		assetIDs = append(assetIDs, assetID)
+	}
+
+	buckets, err := service.GetTimeBuckets(ctx, ListOptions{UserID: userID.String(), Bucket: "day", Ascending: true})
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "2022-08-01", buckets[0].Date, "oldest day first")
+
+	opts := ListOptions{UserID: userID.String(), Bucket: "day", Date: "2022-08-01", Limit: 1, Ascending: true}
+	first, err := service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	assert.Equal(t, assetIDs[0], first[0].ID, "oldest asset first")
+
+	opts.Cursor = util.EncodeCursor(first[0].LocalDateTime, first[0].ID)
+	rest, err := service.GetBucketAssets(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, assetIDs[1], rest[0].ID, "the cursor continues forward")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
-	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
 	"github.com/google/uuid"
@@ -30,15 +29,9 @@ func NewServer(queries *sqlc.Queries) *Server {
 // EmptyTrash permanently deletes all trashed assets for the user
 func (s *Server) EmptyTrash(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	userID, err := auth.UserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Get all trashed assets for the user
@@ -62,15 +55,9 @@ func (s *Server) EmptyTrash(ctx context.Context, _ *emptypb.Empty) (*emptypb.Emp
 // RestoreTrash restores all trashed assets for the user
 func (s *Server) RestoreTrash(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	userID, err := auth.UserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Get all trashed assets for the user
@@ -94,15 +81,9 @@ func (s *Server) RestoreTrash(ctx context.Context, _ *emptypb.Empty) (*emptypb.E
 // RestoreAssets restores specific assets from trash
 func (s *Server) RestoreAssets(ctx context.Context, request *immichv1.RestoreAssetsRequest) (*emptypb.Empty, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID
-	userID, err := pgutil.ParseUserID(claims.UserID)
+	userID, err := auth.UserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Collect valid asset UUIDs
@@ -0,0 +1,77 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// NewRestoreToken returns a random token that restores an account scheduled
+// for deletion, and the hash stored in its place.
+func NewRestoreToken() (token, hash string, err error) {
+	return newToken("restore")
+}
+
+// HashRestoreToken hashes a restore token for storage and lookup.
+func HashRestoreToken(token string) string {
+	return hashToken(token)
+}
+
+// newToken returns a random token sent to a user by email, and the hash
+// stored in its place.
+func newToken(kind string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate %s token: %w", kind, err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// RestoreScheduledDeletion restores the account a restore token was issued
+// for, as long as its grace period has not ended, and cancels the scheduled
+// deletion.
+func (s *Service) RestoreScheduledDeletion(ctx context.Context, token string) (*UserInfo, error) {
+	ctx, span := tracer.Start(ctx, "users.restore_scheduled_deletion")
+	defer span.End()
+
+	s.operationCounter.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("operation", "restore_scheduled_deletion")))
+
+	schedule, err := s.db.GetUserDeletionScheduleByRestoreToken(ctx, HashRestoreToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewUserNotFoundError("Restore link is invalid or has expired")
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, NewDatabaseError("Failed to look up scheduled deletion", err)
+	}
+
+	user, err := s.db.RestoreUser(ctx, schedule.UserId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewUserNotFoundError("Account is already being deleted")
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, NewDatabaseError("Failed to restore user", err)
+	}
+	if err := s.db.DeleteUserDeletionSchedule(ctx, schedule.UserId); err != nil {
+		span.RecordError(err)
+		return nil, NewDatabaseError("Failed to cancel scheduled deletion", err)
+	}
+
+	return s.dbUserToUserInfo(user), nil
+}
@@ -5,9 +5,13 @@ package users
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/denysvitali/immich-go-backend/internal/config"
+	"github.com/denysvitali/immich-go-backend/internal/db"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
 	"github.com/denysvitali/immich-go-backend/internal/db/testdb"
 	"github.com/google/uuid"
@@ -361,3 +365,109 @@ func TestIntegration_UniqueEmailConstraint(t *testing.T) {
 	})
 	assert.Error(t, err) // Should fail due to unique constraint
 }
+
+func TestIntegration_RestoreScheduledDeletion(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, err := NewService(tdb.Queries, &config.Config{})
+	require.NoError(t, err)
+
+	userID := tdb.CreateTestUser(t, "restore@example.com")
+	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
+	require.NoError(t, tdb.Queries.SoftDeleteUser(ctx, userUUID))
+
+	token, hash, err := NewRestoreToken()
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+		UserId:           userUUID,
+		DeleteAt:         pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		RestoreTokenHash: hash,
+	}))
+
+	_, err = service.RestoreScheduledDeletion(ctx, "not-the-token")
+	var userErr *UserError
+	require.ErrorAs(t, err, &userErr)
+	assert.Equal(t, ErrUserNotFound, userErr.Type)
+
+	restored, err := service.RestoreScheduledDeletion(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, restored.ID)
+
+	user, err := tdb.Queries.GetUserByID(ctx, userUUID)
+	require.NoError(t, err)
+	assert.False(t, user.DeletedAt.Valid)
+
+	// The token only works once
+	_, err = service.RestoreScheduledDeletion(ctx, token)
+	require.ErrorAs(t, err, &userErr)
+	assert.Equal(t, ErrUserNotFound, userErr.Type)
+}
+
+func TestIntegration_RestoreScheduledDeletionAfterGracePeriod(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+
+	service, err := NewService(tdb.Queries, &config.Config{})
+	require.NoError(t, err)
+
+	userID := tdb.CreateTestUser(t, "expired-restore@example.com")
+	userUUID := pgtype.UUID{Bytes: userID, Valid: true}
+	require.NoError(t, tdb.Queries.SoftDeleteUser(ctx, userUUID))
+
+	token, hash, err := NewRestoreToken()
+	require.NoError(t, err)
+	require.NoError(t, tdb.Queries.ScheduleUserDeletion(ctx, sqlc.ScheduleUserDeletionParams{
+		UserId:           userUUID,
+		DeleteAt:         pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+		RestoreTokenHash: hash,
+	}))
+
+	_, err = service.RestoreScheduledDeletion(ctx, token)
+	var userErr *UserError
+	require.ErrorAs(t, err, &userErr)
+	assert.Equal(t, ErrUserNotFound, userErr.Type)
+}
+
+func TestIntegration_SetPasswordWithTokenConcurrently(t *testing.T) {
+	testdb.SkipIfNoDocker(t)
+
+	tdb := testdb.SetupTestDB(t)
+	ctx := context.Background()
+	conn, err := db.New(ctx, tdb.ConnStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	service, err := NewService(conn.Queries, &config.Config{})
+	require.NoError(t, err)
+	service.SetTxRunner(conn)
+
+	userID := tdb.CreateTestUser(t, "password-setup@test.com")
+	token, hash, err := NewPasswordSetupToken()
+	require.NoError(t, err)
+	require.NoError(t, conn.CreatePasswordSetupToken(ctx, sqlc.CreatePasswordSetupTokenParams{
+		UserId:    pgtype.UUID{Bytes: userID, Valid: true},
+		TokenHash: hash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}))
+
+	// Of concurrent requests with the same link only one sets the password
+	const attempts = 5
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.SetPasswordWithToken(ctx, token, "a-new-password"); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 1, succeeded.Load())
+}
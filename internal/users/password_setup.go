@@ -0,0 +1,91 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// TxRunner runs database work in a transaction.
+type TxRunner interface {
+	InTx(ctx context.Context, fn func(*sqlc.Queries) error) error
+}
+
+// SetTxRunner enables the operations that need a transaction, such as
+// SetPasswordWithToken.
+func (s *Service) SetTxRunner(tx TxRunner) {
+	s.tx = tx
+}
+
+// NewPasswordSetupToken returns a random token that lets a user choose their
+// password, and the hash stored in its place.
+func NewPasswordSetupToken() (token, hash string, err error) {
+	return newToken("password setup")
+}
+
+// HashPasswordSetupToken hashes a password setup token for storage and
+// lookup.
+func HashPasswordSetupToken(token string) string {
+	return hashToken(token)
+}
+
+// SetPasswordWithToken sets the password of the user a password setup token
+// was issued for, as long as it has not expired. The token is consumed in
+// the same transaction as the password is set, so it can only be used once
+// even by concurrent requests.
+func (s *Service) SetPasswordWithToken(ctx context.Context, token, password string) (*UserInfo, error) {
+	ctx, span := tracer.Start(ctx, "users.set_password_with_token")
+	defer span.End()
+
+	s.operationCounter.Add(ctx, 1,
+		metric.WithAttributes(attribute.String("operation", "set_password_with_token")))
+
+	if s.tx == nil {
+		return nil, NewDatabaseError("Password links are not available", errors.New("no transaction runner"))
+	}
+
+	// A password that is refused leaves the link usable.
+	hashedPassword, err := s.hashNewPassword(password)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	var userID pgtype.UUID
+	err = s.tx.InTx(ctx, func(q *sqlc.Queries) error {
+		setup, err := q.ConsumePasswordSetupToken(ctx, HashPasswordSetupToken(token))
+		if err != nil {
+			return err
+		}
+		userID = setup.UserId
+		return q.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+			ID:       setup.UserId,
+			Password: hashedPassword,
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewUserNotFoundError("Password link is invalid or has expired")
+	}
+	if err != nil {
+		span.RecordError(err)
+		return nil, NewDatabaseError("Failed to set password", err)
+	}
+
+	// Sign out sessions that might have been started with an old password
+	if err := s.db.DeleteUserRefreshTokens(ctx, userID); err != nil {
+		span.RecordError(err)
+	}
+
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		return nil, NewDatabaseError("Failed to get user", err)
+	}
+	return s.dbUserToUserInfo(user), nil
+}
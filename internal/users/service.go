@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/denysvitali/immich-go-backend/internal/auth"
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
@@ -26,6 +28,8 @@ var tracer = telemetry.GetTracer("users")
 type Service struct {
 	db     *sqlc.Queries
 	config *config.Config
+	tx     TxRunner
+	policy PasswordChecker
 
 	// Metrics
 	userCounter       metric.Int64UpDownCounter
@@ -160,26 +164,20 @@ func (s *Service) ListUsers(ctx context.Context, req ListUsersRequest) (*ListUse
 	}()
 
 	// Set defaults for pagination
-	limit := req.Limit
-	if limit <= 0 || limit > 100 {
-		limit = 50 // Default limit
-	}
+	limit := s.config.PageSize(int32(min(max(req.Limit, 0), math.MaxInt32)), 50)
 	offset := req.Offset
 	if offset < 0 {
 		offset = 0
 	}
 
-	// Ensure values fit in int32 to prevent overflow
-	if limit > 2147483647 {
-		limit = 100
-	}
+	// Ensure the offset fits in int32 to prevent overflow
 	if offset > 2147483647 {
 		offset = 0
 	}
 
 	// Get users from database
 	dbUsers, err := s.db.ListUsers(ctx, sqlc.ListUsersParams{
-		Limit:  int32(limit),  // Safe after bounds check above
+		Limit:  limit,
 		Offset: int32(offset), // Safe after bounds check above
 	})
 	if err != nil {
@@ -215,7 +213,7 @@ func (s *Service) ListUsers(ctx context.Context, req ListUsersRequest) (*ListUse
 	return &ListUsersResponse{
 		Users:  users,
 		Total:  int(total),
-		Limit:  limit,
+		Limit:  int(limit),
 		Offset: offset,
 	}, nil
 }
@@ -281,31 +279,16 @@ func (s *Service) UpdateUserPassword(ctx context.Context, userID uuid.UUID, req
 
 	userUUID := pgutil.UUIDToPgtype(userID)
 
-	// Validate password
-	if err := s.validatePassword(req.NewPassword); err != nil {
-		span.RecordError(err)
-		return &UserError{
-			Type:    ErrInvalidPassword,
-			Message: "Password does not meet requirements",
-			Err:     err,
-		}
-	}
-
-	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := s.hashNewPassword(req.NewPassword)
 	if err != nil {
 		span.RecordError(err)
-		return &UserError{
-			Type:    ErrPasswordHashing,
-			Message: "Failed to hash password",
-			Err:     err,
-		}
+		return err
 	}
 
 	// Update password
 	updateParams := sqlc.UpdateUserPasswordParams{
 		ID:       userUUID,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
 	if err := s.db.UpdateUserPassword(ctx, updateParams); err != nil {
@@ -326,6 +309,28 @@ func (s *Service) UpdateUserPassword(ctx context.Context, userID uuid.UUID, req
 	return nil
 }
 
+// hashNewPassword checks a password meets the requirements and hashes it for
+// storage.
+func (s *Service) hashNewPassword(password string) (string, error) {
+	if err := s.validatePassword(password); err != nil {
+		return "", &UserError{
+			Type:    ErrInvalidPassword,
+			Message: "Password does not meet requirements",
+			Err:     err,
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", &UserError{
+			Type:    ErrPasswordHashing,
+			Message: "Failed to hash password",
+			Err:     err,
+		}
+	}
+	return string(hashedPassword), nil
+}
+
 // UpdateUserAdmin updates a user's admin status
 func (s *Service) UpdateUserAdmin(ctx context.Context, userID uuid.UUID, isAdmin bool) (*UserInfo, error) {
 	ctx, span := tracer.Start(ctx, "users.update_user_admin",
@@ -642,12 +647,33 @@ func (s *Service) getDefaultUserPreferences(userID uuid.UUID) *UserPreferences {
 	}
 }
 
-// validatePassword validates password complexity requirements
+// PasswordChecker evaluates a password against the configured password
+// policy. *auth.Service implements it.
+type PasswordChecker interface {
+	CheckPassword(password string) []auth.PasswordRequirement
+}
+
+// SetPasswordChecker makes new passwords follow the password policy, as
+// sign-up and change password do, instead of only a minimum length.
+func (s *Service) SetPasswordChecker(policy PasswordChecker) {
+	s.policy = policy
+}
+
+// validatePassword checks a new password against the password policy and
+// reports every unmet requirement in an *auth.PasswordPolicyError.
 func (s *Service) validatePassword(password string) error {
-	if len(password) < 8 {
-		return fmt.Errorf("password must be at least 8 characters long")
+	if s.policy == nil {
+		if len(password) < 8 {
+			return fmt.Errorf("password must be at least 8 characters long")
+		}
+		return nil
+	}
+	requirements := s.policy.CheckPassword(password)
+	for _, req := range requirements {
+		if !req.Satisfied {
+			return &auth.PasswordPolicyError{Requirements: requirements}
+		}
 	}
-	// Add additional password complexity requirements here
 	return nil
 }
 
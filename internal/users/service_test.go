@@ -1,11 +1,16 @@
 package users
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/denysvitali/immich-go-backend/internal/auth"
+	"github.com/denysvitali/immich-go-backend/internal/config"
 )
 
 // MockQueries implements a minimal mock for testing
@@ -77,6 +82,30 @@ func TestService_ValidatePassword(t *testing.T) {
 	}
 }
 
+func TestService_ValidatePasswordUsesPolicy(t *testing.T) {
+	service, _ := NewService(nil, nil)
+	service.SetPasswordChecker(auth.NewService(config.AuthConfig{
+		PasswordMinLength:      10,
+		PasswordRequireNumbers: true,
+		PasswordBlockCommon:    true,
+	}, nil))
+
+	assert.NoError(t, service.validatePassword("correct horse 42"))
+
+	var policyErr *auth.PasswordPolicyError
+	require.True(t, errors.As(service.validatePassword("password"), &policyErr))
+	var unmet []string
+	for _, req := range policyErr.Requirements {
+		if !req.Satisfied {
+			unmet = append(unmet, req.Rule)
+		}
+	}
+	assert.Equal(t, []string{auth.PasswordRuleMinLength, auth.PasswordRuleNumber, auth.PasswordRuleNotCommon}, unmet)
+
+	_, err := service.hashNewPassword("password1")
+	assert.True(t, errors.As(err, &policyErr), "the policy error is kept behind the UserError")
+}
+
 func TestService_GetDefaultUserPreferences(t *testing.T) {
 	service, _ := NewService(nil, nil)
 	userID := uuid.New()
@@ -1,9 +1,11 @@
 package users
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 )
 
 // UserInfo represents user information
@@ -65,12 +67,12 @@ type ListUsersResponse struct {
 
 // UpdateUserRequest represents a request to update user information
 type UpdateUserRequest struct {
-	Name             *string `json:"name,omitempty"`
-	Email            *string `json:"email,omitempty"`
+	Name             *string `json:"name,omitempty" validate:"omitnil,min=1,max=255"`
+	Email            *string `json:"email,omitempty" validate:"omitnil,email"`
 	AvatarColor      *string `json:"avatarColor,omitempty"`
 	ProfileImagePath *string `json:"profileImagePath,omitempty"`
-	QuotaSizeInBytes *int64  `json:"quotaSizeInBytes,omitempty"`
-	StorageLabel     *string `json:"storageLabel,omitempty"`
+	QuotaSizeInBytes *int64  `json:"quotaSizeInBytes,omitempty" validate:"omitnil,min=0"`
+	StorageLabel     *string `json:"storageLabel,omitempty" validate:"omitnil,max=255"`
 }
 
 // UpdatePasswordRequest represents a request to update a user's password
@@ -179,6 +181,37 @@ func NewUnauthorizedError(message string) *UserError {
 	}
 }
 
+// AsUserError returns the UserError inside err, including wrapped errors.
+func AsUserError(err error) (*UserError, bool) {
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		return userErr, true
+	}
+	return nil, false
+}
+
+// MapUserErrorToGRPC maps a *UserError to a gRPC codes.Code.
+// Non-UserError values and operational failures fall back to codes.Internal.
+func MapUserErrorToGRPC(err error) codes.Code {
+	userErr, ok := AsUserError(err)
+	if !ok {
+		return codes.Internal
+	}
+
+	switch userErr.Type {
+	case ErrUserNotFound, ErrUserDeleted:
+		return codes.NotFound
+	case ErrUserExists:
+		return codes.AlreadyExists
+	case ErrInvalidUserID, ErrInvalidInput, ErrInvalidPassword:
+		return codes.InvalidArgument
+	case ErrUnauthorized:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
+
 // IsNotFoundError checks if an error is a user not found error
 func IsNotFoundError(err error) bool {
 	if userErr, ok := err.(*UserError); ok {
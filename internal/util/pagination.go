@@ -1,5 +1,18 @@
 package util
 
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned by DecodeCursor for a cursor it did not encode.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // Offset computes the SQL offset for paginated queries.
 // Page is 1-based; if page <= 0, the result is 0.
 func Offset(page, size int32) int32 {
@@ -8,3 +21,44 @@ func Offset(page, size int32) int32 {
 	}
 	return (page - 1) * size
 }
+
+// PageSize returns the requested page size, or defaultSize when none was
+// requested, capped at maxSize.
+func PageSize(requested, defaultSize, maxSize int32) int32 {
+	size := requested
+	if size <= 0 {
+		size = defaultSize
+	}
+	if maxSize > 0 && size > maxSize {
+		size = maxSize
+	}
+	return size
+}
+
+// EncodeCursor returns an opaque cursor for a keyset page that ends with the
+// row with this timestamp and id.
+func EncodeCursor(at time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(at.UnixMicro(), 10) + "_" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor returns the timestamp and id EncodeCursor encoded in cursor.
+func DecodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	micros, rawID, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	unixMicro, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return time.UnixMicro(unixMicro), id, nil
+}
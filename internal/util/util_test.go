@@ -2,8 +2,11 @@ package util
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPtr(t *testing.T) {
@@ -117,3 +120,37 @@ func TestOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested int32
+		expected  int32
+	}{
+		{"unset", 0, 100},
+		{"negative", -5, 100},
+		{"within max", 250, 250},
+		{"over max", 5000, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PageSize(tt.requested, 100, 1000))
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	id := uuid.New()
+
+	decodedAt, decodedID, err := DecodeCursor(EncodeCursor(at, id))
+	require.NoError(t, err)
+	assert.True(t, at.Equal(decodedAt))
+	assert.Equal(t, id, decodedID)
+
+	for _, cursor := range []string{"not base64!", "bm9zZXBhcmF0b3I", "eF9ub3RhdXVpZA"} {
+		_, _, err := DecodeCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
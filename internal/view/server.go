@@ -5,7 +5,6 @@ import (
 
 	"github.com/denysvitali/immich-go-backend/internal/auth"
 	immichv1 "github.com/denysvitali/immich-go-backend/internal/proto/gen/immich/v1"
-	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -26,15 +25,9 @@ func NewServer(service *Service) *Server {
 // GetAssetsByOriginalPath retrieves assets by their original file path
 func (s *Server) GetAssetsByOriginalPath(ctx context.Context, request *immichv1.GetAssetsByOriginalPathRequest) (*immichv1.GetAssetsByOriginalPathResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID from claims
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Convert request
@@ -94,15 +87,9 @@ func (s *Server) GetAssetsByOriginalPath(ctx context.Context, request *immichv1.
 // GetUniqueOriginalPaths retrieves all unique original file paths
 func (s *Server) GetUniqueOriginalPaths(ctx context.Context, request *immichv1.GetUniqueOriginalPathsRequest) (*immichv1.GetUniqueOriginalPathsResponse, error) {
 	// Get user from context
-	claims, ok := auth.GetClaimsFromStdContext(ctx)
-	if !ok || claims == nil {
-		return nil, status.Error(codes.Unauthenticated, "unauthorized")
-	}
-
-	// Parse user ID from claims
-	userID, err := uuid.Parse(claims.UserID)
+	userID, err := auth.GetUserIDFromContext(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "invalid user ID: %v", err)
+		return nil, err
 	}
 
 	// Call service
@@ -0,0 +1,227 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned for a subscription that does not
+	// exist.
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// ErrInvalidSubscription is returned for a subscription without a valid
+	// URL or event types.
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+)
+
+// Service manages the server-wide webhook subscriptions.
+type Service struct {
+	db *sqlc.Queries
+}
+
+// NewService creates a new webhook subscription service
+func NewService(db *sqlc.Queries) *Service {
+	return &Service{db: db}
+}
+
+// CreateParams describes a new subscription. A secret is generated when
+// Secret is empty.
+type CreateParams struct {
+	URL     string
+	Events  []EventType
+	Secret  string
+	Enabled bool
+}
+
+// UpdateParams changes the fields of a subscription that are set.
+type UpdateParams struct {
+	URL     *string
+	Events  []EventType
+	Secret  *string
+	Enabled *bool
+}
+
+// List returns all subscriptions, oldest first.
+func (s *Service) List(ctx context.Context) ([]sqlc.WebhookSubscription, error) {
+	subscriptions, err := s.db.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// ListForEvent returns the enabled subscriptions to eventType.
+func (s *Service) ListForEvent(ctx context.Context, eventType EventType) ([]sqlc.WebhookSubscription, error) {
+	subscriptions, err := s.db.ListWebhookSubscriptionsForEvent(ctx, string(eventType))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// Get returns a subscription.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*sqlc.WebhookSubscription, error) {
+	subscription, err := s.db.GetWebhookSubscription(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// Create adds a subscription.
+func (s *Service) Create(ctx context.Context, params CreateParams) (*sqlc.WebhookSubscription, error) {
+	if err := validateURL(params.URL); err != nil {
+		return nil, err
+	}
+	events, err := eventStrings(params.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret := params.Secret
+	if secret == "" {
+		if secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription, err := s.db.CreateWebhookSubscription(ctx, sqlc.CreateWebhookSubscriptionParams{
+		Url:     params.URL,
+		Events:  events,
+		Secret:  secret,
+		Enabled: params.Enabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// Update changes a subscription.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateParams) (*sqlc.WebhookSubscription, error) {
+	current, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := sqlc.UpdateWebhookSubscriptionParams{
+		ID:      current.ID,
+		Url:     current.Url,
+		Events:  current.Events,
+		Secret:  current.Secret,
+		Enabled: current.Enabled,
+	}
+	if params.URL != nil {
+		if err := validateURL(*params.URL); err != nil {
+			return nil, err
+		}
+		update.Url = *params.URL
+	}
+	if params.Events != nil {
+		if update.Events, err = eventStrings(params.Events); err != nil {
+			return nil, err
+		}
+	}
+	if params.Secret != nil {
+		if *params.Secret == "" {
+			return nil, fmt.Errorf("%w: secret cannot be empty", ErrInvalidSubscription)
+		}
+		update.Secret = *params.Secret
+	}
+	if params.Enabled != nil {
+		update.Enabled = *params.Enabled
+	}
+
+	subscription, err := s.db.UpdateWebhookSubscription(ctx, update)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// Delete removes a subscription.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	deleted, err := s.db.DeleteWebhookSubscription(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if deleted == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Deliver sends event to the subscription and records the outcome as its
+// delivery status. It returns the delivery error, if any.
+func (s *Service) Deliver(ctx context.Context, subscription *sqlc.WebhookSubscription, event Event) error {
+	statusCode, deliveryErr := Deliver(ctx, subscription.Url, subscription.Secret, event)
+
+	status := pgtype.Int4{Int32: int32(statusCode), Valid: statusCode != 0}
+	var err error
+	if deliveryErr == nil {
+		err = s.db.RecordWebhookDeliverySuccess(ctx, sqlc.RecordWebhookDeliverySuccessParams{
+			ID:             subscription.ID,
+			LastStatusCode: status,
+		})
+	} else {
+		err = s.db.RecordWebhookDeliveryFailure(ctx, sqlc.RecordWebhookDeliveryFailureParams{
+			ID:             subscription.ID,
+			LastStatusCode: status,
+			LastError:      pgtype.Text{String: deliveryErr.Error(), Valid: true},
+		})
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return errors.Join(deliveryErr, err)
+}
+
+func validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+func eventStrings(events []EventType) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required", ErrInvalidSubscription)
+	}
+	result := make([]string, 0, len(events))
+	seen := make(map[EventType]bool, len(events))
+	for _, event := range events {
+		if !event.Valid() {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, string(event))
+		}
+	}
+	return result, nil
+}
+
+// generateSecret returns a random signing secret.
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
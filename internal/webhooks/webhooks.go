@@ -0,0 +1,162 @@
+// Package webhooks delivers server events to HTTP endpoints. Deliveries are
+// JSON POSTs signed with HMAC-SHA256, so receivers can check that an event
+// was sent by this server.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+)
+
+// EventType names an event webhooks can subscribe to.
+type EventType string
+
+const (
+	EventUserCreated   EventType = "user.created"
+	EventAssetUploaded EventType = "asset.uploaded"
+	EventAssetTrashed  EventType = "asset.trashed"
+	EventAssetDeleted  EventType = "asset.deleted"
+)
+
+// EventTypes lists the event types subscriptions can subscribe to.
+var EventTypes = []EventType{EventUserCreated, EventAssetUploaded, EventAssetTrashed, EventAssetDeleted}
+
+// Valid reports whether t is one of EventTypes.
+func (t EventType) Valid() bool {
+	for _, eventType := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// EventHeader carries the event type of a delivery.
+	EventHeader = "X-Immich-Event"
+	// DeliveryHeader carries the event ID. Retries of a delivery keep it,
+	// so receivers can drop duplicates.
+	DeliveryHeader = "X-Immich-Delivery"
+	// TimestampHeader carries the Unix time the delivery was signed at.
+	TimestampHeader = "X-Immich-Timestamp"
+	// SignatureHeader carries the Sign signature of a delivery.
+	SignatureHeader = "X-Immich-Signature"
+
+	deliveryTimeout = 10 * time.Second
+	// maxErrorBody bounds how much of an error response is kept.
+	maxErrorBody = 512
+)
+
+var httpClient = &http.Client{Timeout: deliveryTimeout}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID        string         `json:"id"`
+	Type      EventType      `json:"type"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Data      map[string]any `json:"data"`
+}
+
+// NewEvent returns an event of eventType that happened now.
+func NewEvent(eventType EventType, data map[string]any) Event {
+	return Event{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// AssetData is the data of the asset events.
+func AssetData(asset sqlc.Asset) map[string]any {
+	return map[string]any{
+		"assetId":          uuid.UUID(asset.ID.Bytes).String(),
+		"ownerId":          uuid.UUID(asset.OwnerId.Bytes).String(),
+		"type":             asset.Type,
+		"originalFileName": asset.OriginalFileName,
+	}
+}
+
+// UserData is the data of the user events.
+func UserData(id, email, name string, isAdmin bool) map[string]any {
+	return map[string]any{
+		"userId":  id,
+		"email":   email,
+		"name":    name,
+		"isAdmin": isAdmin,
+	}
+}
+
+// Sign returns the signature of a delivery body sent at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256, keyed by secret, of the
+// timestamp, a dot and the body. Signing the timestamp lets receivers
+// reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StatusError is returned by Deliver when the endpoint did not answer with a
+// 2xx status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook endpoint returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook endpoint returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Deliver POSTs event to url, signed with secret when it is set. It returns
+// the status code of the response, or 0 when there was none.
+func Deliver(ctx context.Context, url, secret string, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(errBody))}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,103 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", Sign("secret", 1700000000, []byte("{}")))
+	assert.NotEqual(t, Sign("secret", 1700000000, []byte("{}")), Sign("secret", 1700000001, []byte("{}")))
+}
+
+func TestDeliver(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	event := NewEvent(EventAssetUploaded, map[string]any{"assetId": "asset"})
+	statusCode, err := Deliver(context.Background(), server.URL, "secret", event)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, statusCode)
+
+	require.NotNil(t, received)
+	assert.Equal(t, http.MethodPost, received.Method)
+	assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+	assert.Equal(t, string(EventAssetUploaded), received.Header.Get(EventHeader))
+	assert.Equal(t, event.ID, received.Header.Get(DeliveryHeader))
+	timestamp, err := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, Sign("secret", timestamp, body), received.Header.Get(SignatureHeader))
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, EventAssetUploaded, decoded.Type)
+	assert.Equal(t, "asset", decoded.Data["assetId"])
+}
+
+func TestDeliver_Unsigned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(SignatureHeader))
+	}))
+	defer server.Close()
+
+	statusCode, err := Deliver(context.Background(), server.URL, "", NewEvent(EventUserCreated, nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+}
+
+func TestDeliver_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	statusCode, err := Deliver(context.Background(), server.URL, "secret", NewEvent(EventAssetDeleted, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, "try again later", statusErr.Body)
+}
+
+func TestDeliver_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	statusCode, err := Deliver(context.Background(), server.URL, "secret", NewEvent(EventAssetTrashed, nil))
+	assert.Error(t, err)
+	assert.Zero(t, statusCode)
+}
+
+func TestEventStrings(t *testing.T) {
+	events, err := eventStrings([]EventType{EventAssetUploaded, EventUserCreated, EventAssetUploaded})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"asset.uploaded", "user.created"}, events)
+
+	_, err = eventStrings(nil)
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	_, err = eventStrings([]EventType{"asset.renamed"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, validateURL("https://example.com/hooks/immich"))
+	assert.NoError(t, validateURL("http://home-assistant.local:8123/api/webhook/immich"))
+	for _, rawURL := range []string{"", "example.com/hook", "ftp://example.com/hook", "https://"} {
+		assert.ErrorIs(t, validateURL(rawURL), ErrInvalidSubscription, rawURL)
+	}
+}
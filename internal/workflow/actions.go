@@ -0,0 +1,121 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/denysvitali/immich-go-backend/internal/webhooks"
+)
+
+// runAction executes a built-in workflow action. Actions without a built-in
+// implementation run as successful no-ops until the plugin host handles them.
+func (s *Service) runAction(ctx context.Context, workflow *WorkflowInfo, action Action, triggerData map[string]interface{}) error {
+	switch action.Type {
+	case ActionTypeAddTag:
+		return s.addTagAction(ctx, workflow, action, triggerData)
+	case ActionTypeWebhook:
+		return webhookAction(ctx, workflow, action, triggerData)
+	default:
+		return nil
+	}
+}
+
+// addTagAction tags the assets of the trigger data ("assetId" or "assetIds")
+// with the tags of the action params: existing tags by "tagId"/"tagIds", and
+// tags by value ("tag"/"tags"), created on demand. Nested values such as
+// Travel/Italy/Rome also create their ancestors. It shares the bulk tag API's
+// code path, so the assets are tagged in one statement.
+func (s *Service) addTagAction(ctx context.Context, workflow *WorkflowInfo, action Action, triggerData map[string]interface{}) error {
+	ownerID, err := uuid.Parse(workflow.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("invalid workflow owner: %w", err)
+	}
+
+	assetIDs, err := uuidValues(stringValues(triggerData, "assetId", "assetIds"))
+	if err != nil {
+		return fmt.Errorf("invalid asset ID: %w", err)
+	}
+	if len(assetIDs) == 0 {
+		return errors.New("add_tag needs assetId or assetIds in the trigger data")
+	}
+
+	tagIDs, err := uuidValues(stringValues(action.Params, "tagId", "tagIds"))
+	if err != nil {
+		return fmt.Errorf("invalid tag ID: %w", err)
+	}
+	for _, value := range stringValues(action.Params, "tag", "tags") {
+		tag, err := s.tags.UpsertTagPath(ctx, ownerID, value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve tag %q: %w", value, err)
+		}
+		tagIDs = append(tagIDs, tag.ID.Bytes)
+	}
+	if len(tagIDs) == 0 {
+		return errors.New("add_tag needs tag, tags, tagId or tagIds params")
+	}
+
+	if _, _, err := s.tags.ApplyTags(ctx, ownerID, assetIDs, tagIDs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// workflowWebhookEvent is the event type of workflow webhook deliveries.
+const workflowWebhookEvent webhooks.EventType = "workflow.triggered"
+
+// webhookAction POSTs the workflow and its trigger data to the "url" param,
+// signed with the "secret" param when it is set. It shares the delivery code
+// of the server-wide webhook subscriptions.
+func webhookAction(ctx context.Context, workflow *WorkflowInfo, action Action, triggerData map[string]interface{}) error {
+	url, _ := action.Params["url"].(string)
+	if url == "" {
+		return errors.New("webhook needs a url param")
+	}
+	secret, _ := action.Params["secret"].(string)
+
+	event := webhooks.NewEvent(workflowWebhookEvent, map[string]any{
+		"workflowId":   workflow.ID,
+		"workflowName": workflow.Name,
+		"triggerData":  triggerData,
+	})
+	_, err := webhooks.Deliver(ctx, url, secret, event)
+	return err
+}
+
+// stringValues collects the string values stored under keys, which may hold
+// a single string or a decoded JSON array.
+func stringValues(values map[string]interface{}, keys ...string) []string {
+	var result []string
+	for _, key := range keys {
+		switch v := values[key].(type) {
+		case string:
+			if v != "" {
+				result = append(result, v)
+			}
+		case []string:
+			result = append(result, v...)
+		case []interface{}:
+			for _, item := range v {
+				if str, ok := item.(string); ok && str != "" {
+					result = append(result, str)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func uuidValues(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
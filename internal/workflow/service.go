@@ -9,6 +9,7 @@ import (
 	"github.com/denysvitali/immich-go-backend/internal/config"
 	"github.com/denysvitali/immich-go-backend/internal/db/pgutil"
 	"github.com/denysvitali/immich-go-backend/internal/db/sqlc"
+	"github.com/denysvitali/immich-go-backend/internal/tags"
 	"github.com/denysvitali/immich-go-backend/internal/telemetry"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -124,6 +125,7 @@ type ExecutionInfo struct {
 type Service struct {
 	db     *sqlc.Queries
 	config *config.Config
+	tags   *tags.Server
 
 	workflowCounter   metric.Int64UpDownCounter
 	executionCounter  metric.Int64Counter
@@ -161,6 +163,7 @@ func NewService(queries *sqlc.Queries, cfg *config.Config) (*Service, error) {
 	return &Service{
 		db:                queries,
 		config:            cfg,
+		tags:              tags.NewServer(queries),
 		workflowCounter:   workflowCounter,
 		executionCounter:  executionCounter,
 		operationDuration: operationDuration,
@@ -358,15 +361,26 @@ func (s *Service) TriggerWorkflow(ctx context.Context, workflowID string, trigge
 	}
 
 	now := time.Now()
+	execStatus := ExecutionStatusCompleted
+	var execError pgtype.Text
 	actionResults := make([]ActionResult, len(workflow.Actions))
 	for i, action := range workflow.Actions {
-		// Built-in actions run as successful no-ops for now; plugin host wires later.
+		actionStart := time.Now()
+		err := s.runAction(ctx, workflow, action, triggerData)
 		actionResults[i] = ActionResult{
 			Type:       action.Type,
-			Success:    true,
-			DurationMs: 1,
+			Success:    err == nil,
+			DurationMs: time.Since(actionStart).Milliseconds(),
+		}
+		if err != nil {
+			actionResults[i].ErrorMessage = err.Error()
+			if execStatus == ExecutionStatusCompleted {
+				execStatus = ExecutionStatusFailed
+				execError = pgtype.Text{String: fmt.Sprintf("action %s failed: %v", action.Type, err), Valid: true}
+			}
 		}
 	}
+	completedAt := time.Now()
 
 	execID := pgtype.UUID{Bytes: uuid.New(), Valid: true}
 	wfID, _ := parseWorkflowUUID(workflowID)
@@ -377,10 +391,10 @@ func (s *Service) TriggerWorkflow(ctx context.Context, workflowID string, trigge
 	row, err := s.db.CreateWorkflowExecution(ctx, sqlc.CreateWorkflowExecutionParams{
 		ID:            execID,
 		WorkflowId:    wfID,
-		Status:        string(ExecutionStatusCompleted),
+		Status:        string(execStatus),
 		StartedAt:     pgtype.Timestamptz{Time: now, Valid: true},
-		CompletedAt:   pgtype.Timestamptz{Time: now, Valid: true},
-		ErrorMessage:  pgtype.Text{},
+		CompletedAt:   pgtype.Timestamptz{Time: completedAt, Valid: true},
+		ErrorMessage:  execError,
 		TriggerData:   triggerJSON,
 		ActionResults: resultsJSON,
 	})
@@ -395,7 +409,7 @@ func (s *Service) TriggerWorkflow(ctx context.Context, workflowID string, trigge
 	s.executionCounter.Add(ctx, 1,
 		metric.WithAttributes(
 			attribute.String("workflow_id", workflowID),
-			attribute.String("status", string(ExecutionStatusCompleted)),
+			attribute.String("status", string(execStatus)),
 		))
 
 	return executionFromDB(row)
@@ -39,6 +39,90 @@ WHERE a."deletedAt" IS NULL
   )
 ORDER BY a."createdAt" DESC;
 
+-- name: GetAlbumSummariesForUser :many
+-- The albums GetAlbumsForUser lists, or those holding asset_id, with what
+-- an album list shows of each: its owner, how many assets it has, their
+-- date range, when they last changed, its cover and whether it is shared,
+-- so the list takes one query rather than a few per album. The cover is
+-- the chosen thumbnail, else the asset added last. sort_by is "createdAt",
+-- "updatedAt" or "albumName".
+SELECT sqlc.embed(a),
+    u.email AS owner_email, u.name AS owner_name,
+    u."profileImagePath" AS owner_profile_image_path, u."avatarColor" AS owner_avatar_color,
+    u."profileChangedAt" AS owner_profile_changed_at, u."createdAt" AS owner_created_at,
+    s.asset_count, s.start_date, s.end_date, s.last_modified_asset_at,
+    COALESCE(a."albumThumbnailAssetId", cover.id) AS cover_asset_id,
+    EXISTS (SELECT 1 FROM albums_shared_users_users su WHERE su."albumsId" = a.id) AS shared,
+    EXISTS (SELECT 1 FROM shared_links sl WHERE sl."albumId" = a.id) AS has_shared_link
+FROM albums a
+JOIN users u ON u.id = a."ownerId"
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS asset_count,
+        MIN(x."localDateTime") FILTER (WHERE NOT x."isUndated") AS start_date,
+        MAX(x."localDateTime") FILTER (WHERE NOT x."isUndated") AS end_date,
+        MAX(x."updatedAt") AS last_modified_asset_at
+    FROM albums_assets_assets aaa
+    JOIN assets x ON x.id = aaa."assetsId"
+    WHERE aaa."albumsId" = a.id
+    AND x.status = 'active'
+    AND x."deletedAt" IS NULL
+    AND x.visibility <> 'locked'
+) s
+LEFT JOIN LATERAL (
+    SELECT x.id FROM albums_assets_assets aaa
+    JOIN assets x ON x.id = aaa."assetsId"
+    WHERE a."albumThumbnailAssetId" IS NULL
+    AND aaa."albumsId" = a.id
+    AND x.status = 'active'
+    AND x."deletedAt" IS NULL
+    AND x.visibility <> 'locked'
+    ORDER BY aaa."createdAt" DESC
+    LIMIT 1
+) cover ON true
+WHERE a."deletedAt" IS NULL
+  AND (
+    a."ownerId" = sqlc.arg(user_id)
+    OR EXISTS (
+      SELECT 1 FROM albums_shared_users_users su
+      WHERE su."albumsId" = a.id AND su."usersId" = sqlc.arg(user_id)
+    )
+  )
+  AND (
+    sqlc.narg(is_owned)::boolean IS NULL
+    OR (a."ownerId" = sqlc.arg(user_id)) = sqlc.narg(is_owned)::boolean
+  )
+  AND (
+    sqlc.narg(is_shared)::boolean IS NULL
+    OR (
+      EXISTS (SELECT 1 FROM albums_shared_users_users su2 WHERE su2."albumsId" = a.id)
+      OR EXISTS (SELECT 1 FROM shared_links sl WHERE sl."albumId" = a.id)
+    ) = sqlc.narg(is_shared)::boolean
+  )
+  AND (
+    sqlc.narg(asset_id)::uuid IS NULL
+    OR EXISTS (
+      SELECT 1 FROM albums_assets_assets aa
+      WHERE aa."albumsId" = a.id AND aa."assetsId" = sqlc.narg(asset_id)::uuid
+    )
+  )
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'albumName' AND NOT sqlc.arg(descending)::boolean THEN lower(a."albumName") END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'albumName' AND sqlc.arg(descending)::boolean THEN lower(a."albumName") END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updatedAt' AND NOT sqlc.arg(descending)::boolean THEN a."updatedAt" END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updatedAt' AND sqlc.arg(descending)::boolean THEN a."updatedAt" END DESC,
+    CASE WHEN NOT sqlc.arg(descending)::boolean THEN a."createdAt" END ASC,
+    a."createdAt" DESC,
+    a.id;
+
+-- name: GetAlbumSharedUsersByAlbumIds :many
+-- The members of several albums at once, for album lists.
+SELECT asu."albumsId", u.id, u.email, u.name, u."profileImagePath",
+    u."avatarColor", u."profileChangedAt", u."createdAt", asu.role
+FROM albums_shared_users_users asu
+JOIN users u ON u.id = asu."usersId"
+WHERE asu."albumsId" = ANY(sqlc.arg(album_ids)::uuid[])
+ORDER BY asu."albumsId", u.name;
+
 -- name: GetAlbumsByAssetIdForUser :many
 -- Albums containing the asset that the user owns or participates in.
 SELECT a.* FROM albums a
@@ -54,6 +138,11 @@ WHERE a."deletedAt" IS NULL
   )
 ORDER BY a."createdAt" DESC;
 
+-- name: GetAlbumIdsByAssetIds :many
+-- Albums containing any of the assets, whoever can see them.
+SELECT DISTINCT "albumsId" FROM albums_assets_assets
+WHERE "assetsId" = ANY(sqlc.arg(asset_ids)::uuid[]);
+
 -- name: CreateAlbum :one
 INSERT INTO albums ("ownerId", "albumName", description)
 VALUES ($1, $2, $3)
@@ -81,6 +170,7 @@ WHERE id = $1;
 SELECT a.* FROM assets a
 JOIN albums_assets_assets aaa ON a.id = aaa."assetsId"
 WHERE aaa."albumsId" = $1 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY aaa."createdAt" DESC;
 
 -- name: GetAlbumMapMarkers :many
@@ -89,19 +179,59 @@ JOIN albums_assets_assets aaa ON a.id = aaa."assetsId"
 JOIN exif e ON a.id = e."assetId"
 WHERE aaa."albumsId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.latitude IS NOT NULL
 AND e.longitude IS NOT NULL
 ORDER BY a."localDateTime" DESC;
 
+-- name: GetAlbumAssetStatistics :many
+-- Statistics of an album's assets in one pass: a row per owner of assets in
+-- the album and a total row with a NULL "ownerId", which comes first.
+SELECT s."ownerId", COALESCE(u.name, '') AS owner_name,
+    s.asset_count, s.images, s.videos, s.total_size, s.start_date, s.end_date
+FROM (
+    SELECT a."ownerId",
+        COUNT(*) FILTER (WHERE a.type = 'IMAGE') AS images,
+        COUNT(*) FILTER (WHERE a.type = 'VIDEO') AS videos,
+        COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS total_size,
+        MIN(a."localDateTime") FILTER (WHERE NOT a."isUndated") AS start_date,
+        MAX(a."localDateTime") FILTER (WHERE NOT a."isUndated") AS end_date,
+        COUNT(*) AS asset_count
+    FROM albums_assets_assets aaa
+    JOIN assets a ON a.id = aaa."assetsId"
+    LEFT JOIN exif e ON e."assetId" = a.id
+    WHERE aaa."albumsId" = $1
+    AND a.status = 'active'
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    GROUP BY GROUPING SETS ((), (a."ownerId"))
+) s
+LEFT JOIN users u ON u.id = s."ownerId"
+ORDER BY s."ownerId" IS NOT NULL, s.asset_count DESC, u.name;
+
 -- name: AddAssetToAlbum :exec
 INSERT INTO albums_assets_assets ("albumsId", "assetsId")
 VALUES ($1, $2)
 ON CONFLICT DO NOTHING;
 
+-- name: AddAssetsToAlbum :many
+-- Adds the assets in one statement and returns the ones that were not
+-- already in the album.
+INSERT INTO albums_assets_assets ("albumsId", "assetsId")
+SELECT sqlc.arg(album_id), unnest(sqlc.arg(asset_ids)::uuid[])
+ON CONFLICT DO NOTHING
+RETURNING "assetsId";
+
 -- name: RemoveAssetFromAlbum :exec
 DELETE FROM albums_assets_assets
 WHERE "albumsId" = $1 AND "assetsId" = $2;
 
+-- name: IsAssetInAlbum :one
+SELECT EXISTS(
+    SELECT 1 FROM albums_assets_assets
+    WHERE "albumsId" = $1 AND "assetsId" = $2
+) AS in_album;
+
 -- name: SearchAlbums :many
 SELECT * FROM albums
 WHERE "ownerId" = $1
@@ -116,13 +246,24 @@ JOIN albums_shared_users_users asu ON u.id = asu."usersId"
 WHERE asu."albumsId" = $1;
 
 -- name: CheckAssetSharedWithUser :one
+-- Locked assets are never shared, even when they are in a shared album.
+-- Assets shared directly count too.
 SELECT EXISTS(
     SELECT 1 FROM albums_assets_assets aaa
     JOIN albums_shared_users_users asuu ON aaa."albumsId" = asuu."albumsId"
     JOIN albums a ON a.id = aaa."albumsId"
+    JOIN assets asset ON asset.id = aaa."assetsId"
     WHERE aaa."assetsId" = $1
     AND asuu."usersId" = $2
     AND a."deletedAt" IS NULL
+    AND asset.visibility <> 'locked'
+) OR EXISTS(
+    SELECT 1 FROM asset_user_shares s
+    JOIN assets asset ON asset.id = s."assetId"
+    WHERE s."assetId" = $1
+    AND s."sharedWithId" = $2
+    AND asset."ownerId" = s."sharedById"
+    AND asset.visibility <> 'locked'
 ) AS is_shared;
 
 -- name: AddUserToAlbum :exec
@@ -149,54 +290,132 @@ SELECT * FROM assets
 WHERE id = $1 AND "deletedAt" IS NULL;
 
 -- name: GetAssets :many
+-- A page of the user's assets, newest first. Pages are either offset or
+-- keyed on the capture time and id of the last asset of the previous page.
 SELECT * FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND (sqlc.narg('type')::text IS NULL OR type = sqlc.narg('type'))
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR "isFavorite" = sqlc.narg('is_favorite'))
 AND (sqlc.narg('is_archived')::boolean IS NULL OR visibility = CASE WHEN sqlc.narg('is_archived')::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND (sqlc.narg('is_trashed')::boolean IS NULL OR status = CASE WHEN sqlc.narg('is_trashed')::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
-ORDER BY "localDateTime" DESC
+AND "isOffline" = COALESCE(sqlc.narg('is_offline')::boolean, false)
+AND (sqlc.narg('cursor_local_date_time')::timestamptz IS NULL
+    OR ("localDateTime", id) < (sqlc.narg('cursor_local_date_time')::timestamptz, sqlc.narg('cursor_id')::uuid))
+ORDER BY "localDateTime" DESC, id DESC
 LIMIT $2 OFFSET $3;
 
+-- name: GetAssetIds :many
+-- The identity of the assets GetAssets lists, without their metadata, for
+-- clients syncing a large timeline.
+SELECT id, "fileCreatedAt", "localDateTime", checksum FROM assets
+WHERE "ownerId" = sqlc.arg(owner_id)
+AND "deletedAt" IS NULL
+AND visibility <> 'locked'
+AND (sqlc.narg('is_trashed')::boolean IS NULL OR status = CASE WHEN sqlc.narg('is_trashed')::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
+AND "isOffline" = false
+AND (sqlc.narg('cursor_local_date_time')::timestamptz IS NULL
+    OR ("localDateTime", id) < (sqlc.narg('cursor_local_date_time')::timestamptz, sqlc.narg('cursor_id')::uuid))
+ORDER BY "localDateTime" DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountReindexAssets :one
+-- The assets a re-index run with these filters processes.
+SELECT COUNT(*) FROM assets
+WHERE "deletedAt" IS NULL
+AND status = 'active'
+AND "isOffline" = false
+AND (sqlc.narg('owner_id')::uuid IS NULL OR "ownerId" = sqlc.narg('owner_id')::uuid)
+AND (sqlc.narg('library_id')::uuid IS NULL OR "libraryId" = sqlc.narg('library_id')::uuid)
+AND (sqlc.narg('taken_after')::timestamptz IS NULL OR "localDateTime" >= sqlc.narg('taken_after')::timestamptz)
+AND (sqlc.narg('taken_before')::timestamptz IS NULL OR "localDateTime" < sqlc.narg('taken_before')::timestamptz);
+
+-- name: GetReindexAssetIDs :many
+-- The next batch of assets of a re-index run, in id order after the last
+-- batch.
+SELECT id FROM assets
+WHERE "deletedAt" IS NULL
+AND status = 'active'
+AND "isOffline" = false
+AND (sqlc.narg('owner_id')::uuid IS NULL OR "ownerId" = sqlc.narg('owner_id')::uuid)
+AND (sqlc.narg('library_id')::uuid IS NULL OR "libraryId" = sqlc.narg('library_id')::uuid)
+AND (sqlc.narg('taken_after')::timestamptz IS NULL OR "localDateTime" >= sqlc.narg('taken_after')::timestamptz)
+AND (sqlc.narg('taken_before')::timestamptz IS NULL OR "localDateTime" < sqlc.narg('taken_before')::timestamptz)
+AND (sqlc.narg('after_id')::uuid IS NULL OR id > sqlc.narg('after_id')::uuid)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
 -- name: CountAssets :one
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND (sqlc.narg('type')::text IS NULL OR type = sqlc.narg('type'))
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR "isFavorite" = sqlc.narg('is_favorite'))
 AND (sqlc.narg('is_archived')::boolean IS NULL OR visibility = CASE WHEN sqlc.narg('is_archived')::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
-AND (sqlc.narg('is_trashed')::boolean IS NULL OR status = CASE WHEN sqlc.narg('is_trashed')::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END);
+AND (sqlc.narg('is_trashed')::boolean IS NULL OR status = CASE WHEN sqlc.narg('is_trashed')::boolean THEN 'trashed'::assets_status_enum ELSE 'active'::assets_status_enum END)
+AND "isOffline" = COALESCE(sqlc.narg('is_offline')::boolean, false);
 
 -- name: CreateAsset :one
 INSERT INTO assets (
     "deviceAssetId", "ownerId", "deviceId", type, "originalPath",
     "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
-    checksum, "isFavorite", visibility, status
+    checksum, "isFavorite", visibility, status, "checksumAlgorithm", "isUndated"
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE(sqlc.narg('checksum_algorithm'), 'sha1'),
+    COALESCE(sqlc.narg('is_undated')::boolean, false))
+RETURNING *;
+
+-- name: UpsertDeviceAsset :one
+-- A repeated backup of a device asset replaces the file of the asset the
+-- first one created. What changed on the server since, like the favorite,
+-- visibility or trash state, is kept.
+INSERT INTO assets (
+    "deviceAssetId", "ownerId", "deviceId", type, "originalPath",
+    "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
+    checksum, "isFavorite", visibility, status, "checksumAlgorithm", "isUndated"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE(sqlc.narg('checksum_algorithm'), 'sha1'),
+    COALESCE(sqlc.narg('is_undated')::boolean, false))
+ON CONFLICT ("ownerId", "deviceId", "deviceAssetId")
+    WHERE "libraryId" IS NULL AND "deviceId" <> '' AND "deviceAssetId" <> ''
+DO UPDATE SET
+    type = EXCLUDED.type,
+    "originalPath" = EXCLUDED."originalPath",
+    "fileCreatedAt" = EXCLUDED."fileCreatedAt",
+    "fileModifiedAt" = EXCLUDED."fileModifiedAt",
+    "localDateTime" = EXCLUDED."localDateTime",
+    "originalFileName" = EXCLUDED."originalFileName",
+    checksum = EXCLUDED.checksum,
+    "checksumAlgorithm" = EXCLUDED."checksumAlgorithm",
+    "isUndated" = EXCLUDED."isUndated",
+    "encodedVideoPath" = '',
+    thumbhash = NULL,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
 RETURNING *;
 
+-- name: GetUploadedDeviceAsset :one
+-- The uploaded asset of a device asset, trashed ones included.
+SELECT * FROM assets
+WHERE "ownerId" = $1 AND "deviceId" = $2 AND "deviceAssetId" = $3 AND "libraryId" IS NULL;
+
 -- name: CreateLibraryAsset :one
+-- Library files start undated: their modification time is when they were
+-- copied or scanned, not when they were taken.
 INSERT INTO assets (
     "deviceAssetId", "ownerId", "libraryId", "deviceId", type, "originalPath",
     "fileCreatedAt", "fileModifiedAt", "localDateTime", "originalFileName",
-    checksum, "isFavorite", visibility, status, "isExternal"
+    checksum, "isFavorite", visibility, status, "isExternal", "checksumAlgorithm", "isUndated"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, true)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, true, $15, true)
 RETURNING *;
 
 -- name: UpdateAsset :one
 UPDATE assets
 SET "isFavorite" = COALESCE(sqlc.narg('is_favorite'), "isFavorite"),
-    visibility = COALESCE(
-        CASE 
-            WHEN sqlc.narg('is_archived')::boolean = true THEN 'archive'::asset_visibility_enum
-            WHEN sqlc.narg('is_archived')::boolean = false THEN 'timeline'::asset_visibility_enum
-            ELSE visibility
-        END, 
-        visibility
-    ),
+    visibility = COALESCE(sqlc.narg('visibility')::asset_visibility_enum, visibility),
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL
@@ -232,19 +451,25 @@ SELECT
     COUNT(CASE WHEN type = 'VIDEO' THEN 1 END) as videos,
     COUNT(*) as total
 FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active';
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked';
 
 -- name: GetRandomAssets :many
 SELECT * FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active'
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
 ORDER BY RANDOM()
 LIMIT $2;
 
 -- name: GetRecentlyAddedAssets :many
+-- The assets most recently uploaded or imported, newest first. Trashed
+-- assets are left out, GetTrashedAssets lists them.
 SELECT * FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active'
-ORDER BY "fileCreatedAt" DESC
-LIMIT $2;
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked'
+ORDER BY "createdAt" DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountRecentlyAddedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND status = 'active' AND visibility <> 'locked';
 
 -- name: UpdateAssetStatus :one
 UPDATE assets
@@ -257,6 +482,7 @@ RETURNING *;
 -- name: ReplaceAssetFile :one
 UPDATE assets
 SET checksum = sqlc.arg(checksum),
+    "checksumAlgorithm" = sqlc.arg(checksum_algorithm),
     "fileModifiedAt" = sqlc.arg(file_modified_at),
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
@@ -283,23 +509,51 @@ SELECT * FROM assets
 WHERE id = $1 AND "ownerId" = $2 AND "deletedAt" IS NULL;
 
 -- name: GetUserAssets :many
+-- sort_by is "date_taken", "created_at" or "filename". Assets sorting the
+-- same are ordered by id so offset pages neither skip nor repeat them.
 SELECT * FROM assets
-WHERE "ownerId" = $1 AND "deletedAt" IS NULL
+WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
 AND (sqlc.narg('status')::assets_status_enum IS NULL OR status = sqlc.narg('status')::assets_status_enum)
-ORDER BY "fileCreatedAt" DESC
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(descending)::boolean THEN "createdAt" END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(descending)::boolean THEN "createdAt" END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'filename' AND NOT sqlc.arg(descending)::boolean THEN lower("originalFileName") END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'filename' AND sqlc.arg(descending)::boolean THEN lower("originalFileName") END DESC,
+    CASE WHEN NOT sqlc.arg(descending)::boolean THEN "fileCreatedAt" END ASC,
+    CASE WHEN sqlc.arg(descending)::boolean THEN "fileCreatedAt" END DESC,
+    CASE WHEN NOT sqlc.arg(descending)::boolean THEN id END ASC,
+    id DESC
 LIMIT sqlc.narg('limit')
 OFFSET sqlc.narg('offset');
 
 -- name: GetDeletedAssetIDsForSync :many
+-- Assets moved to the locked folder are reported as deleted, so that synced
+-- devices drop them.
 SELECT id FROM assets
 WHERE "ownerId" = sqlc.arg(owner_id)
 AND (
     (status IN ('trashed'::assets_status_enum, 'deleted'::assets_status_enum) AND "updatedAt" > sqlc.arg(updated_after))
+    OR (visibility = 'locked' AND "updatedAt" > sqlc.arg(updated_after))
     OR ("deletedAt" IS NOT NULL AND "deletedAt" > sqlc.arg(updated_after))
 )
 ORDER BY "updatedAt" ASC
 LIMIT sqlc.arg('limit');
 
+-- name: GetAssetSyncManifest :many
+-- A page of the assets a device may hold, by id after the cursor. Trashed
+-- assets are listed so devices can mark them; assets deleted for good,
+-- hidden live photo videos and the locked folder are not, so devices drop
+-- whatever is missing.
+SELECT id, "deviceAssetId", checksum, status = 'trashed' AS is_trashed, "updatedAt"
+FROM assets
+WHERE "ownerId" = sqlc.arg(owner_id)
+AND "deletedAt" IS NULL
+AND status <> 'deleted'
+AND visibility IN ('timeline', 'archive')
+AND (sqlc.narg(after_id)::uuid IS NULL OR id > sqlc.narg(after_id)::uuid)
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
 -- EXIF queries
 -- name: CreateExif :one
 INSERT INTO exif (
@@ -334,6 +588,10 @@ WHERE "assetId" = $1;
 SELECT * FROM users
 WHERE id = $1 AND "deletedAt" IS NULL;
 
+-- name: GetUserIncludingDeleted :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1 AND "deletedAt" IS NULL;
@@ -452,12 +710,90 @@ DELETE FROM users
 WHERE id = $1;
 
 -- name: RestoreUser :one
+-- Users already queued for removal cannot be restored.
 UPDATE users
 SET "deletedAt" = NULL,
+    status = 'active',
     "updatedAt" = now()
 WHERE id = $1
+AND status != 'removing'
 RETURNING *;
 
+-- name: MarkUserForRemoval :exec
+-- Soft-deletes the user (keeping an earlier deletedAt) and flags the account
+-- for the background purge of its assets and rows.
+UPDATE users
+SET "deletedAt" = COALESCE("deletedAt", now()),
+    status = 'removing',
+    "updatedAt" = now()
+WHERE id = $1;
+
+-- name: GetUserDeletionSummary :one
+SELECT
+    (SELECT COUNT(*) FROM assets a WHERE a."ownerId" = sqlc.arg(user_id)) AS asset_count,
+    (SELECT COALESCE(SUM(e."fileSizeInByte"), 0) FROM assets a
+        JOIN exif e ON e."assetId" = a.id
+        WHERE a."ownerId" = sqlc.arg(user_id))::bigint AS total_size_bytes,
+    (SELECT COUNT(*) FROM albums al WHERE al."ownerId" = sqlc.arg(user_id)) AS album_count,
+    (SELECT COUNT(*) FROM shared_links sl WHERE sl."userId" = sqlc.arg(user_id)) AS shared_link_count;
+
+-- name: ListUserAssetsForPurge :many
+-- A batch of the user's assets, including trashed ones, for the deletion job.
+SELECT id, "originalPath", "encodedVideoPath", "sidecarPath", "isExternal" FROM assets
+WHERE "ownerId" = sqlc.arg(owner_id)
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+-- name: ListUserPersonThumbnailPaths :many
+SELECT "thumbnailPath" FROM person
+WHERE "ownerId" = $1 AND "thumbnailPath" != '';
+
+-- name: DeleteUserAssetAudit :exec
+-- The user's own audit rows are only read by the user's clients. Partner,
+-- album member and user audit rows stay so other users' clients learn about
+-- the removal.
+DELETE FROM assets_audit
+WHERE "ownerId" = $1;
+
+-- name: DeleteUserAlbumAudit :exec
+DELETE FROM albums_audit
+WHERE "userId" = $1;
+
+-- name: DeleteUserLegacyAudit :exec
+DELETE FROM audit
+WHERE "ownerId" = $1;
+
+-- name: ListUserAssetsForExport :many
+-- One page of a user data export, including trashed assets, ordered by id so
+-- an interrupted export resumes after the last exported asset.
+SELECT a.id, a.type, a."originalPath", a."originalFileName", a.checksum,
+    a."fileCreatedAt", a."localDateTime", a."isFavorite", a.visibility, a."deletedAt",
+    e."fileSizeInByte", e."dateTimeOriginal", e."timeZone", e.latitude, e.longitude,
+    e.city, e.state, e.country, e.make, e.model, e.description, e.rating,
+    COALESCE((SELECT array_agg(aa."albumsId" ORDER BY aa."albumsId")
+        FROM albums_assets_assets aa
+        WHERE aa."assetsId" = a.id), '{}')::uuid[] AS album_ids,
+    COALESCE((SELECT array_agg(t.value ORDER BY t.value)
+        FROM tag_asset ta
+        JOIN tags t ON t.id = ta."tagsId"
+        WHERE ta."assetsId" = a.id), '{}')::text[] AS tag_values
+FROM assets a
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = sqlc.arg(owner_id)
+AND a.id > sqlc.arg(after_id)::uuid
+ORDER BY a.id
+LIMIT sqlc.arg(batch_size);
+
+-- name: DeleteUserStacks :exec
+-- Stacks pin their primary asset, so they go before the user's assets.
+DELETE FROM asset_stack
+WHERE "ownerId" = $1;
+
+-- name: PurgeAssets :exec
+DELETE FROM assets
+WHERE id = ANY(sqlc.arg(asset_ids)::uuid[])
+AND "ownerId" = sqlc.arg(owner_id);
+
 -- name: ClearAllOAuthIds :exec
 UPDATE users
 SET "oauthId" = '',
@@ -480,6 +816,11 @@ SELECT * FROM users
 WHERE "deletedAt" IS NULL
 ORDER BY "createdAt" DESC;
 
+-- name: GetAdminUserIDs :many
+SELECT id FROM users
+WHERE "isAdmin" = true AND "deletedAt" IS NULL
+ORDER BY "createdAt";
+
 -- name: GetAllUsersWithDeleted :many
 SELECT * FROM users
 ORDER BY "createdAt" DESC;
@@ -547,15 +888,15 @@ INSERT INTO exif (
     "assetId", make, model, "exifImageWidth", "exifImageHeight", 
     "fileSizeInByte", orientation, "dateTimeOriginal", "modifyDate",
     "lensModel", "fNumber", "focalLength", iso, latitude, longitude,
-    city, state, country, description
+    city, state, country, description, "timeZone"
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 ON CONFLICT ("assetId") DO UPDATE SET
     make = EXCLUDED.make,
     model = EXCLUDED.model,
     "exifImageWidth" = EXCLUDED."exifImageWidth",
     "exifImageHeight" = EXCLUDED."exifImageHeight",
-    "fileSizeInByte" = EXCLUDED."fileSizeInByte",
+    "fileSizeInByte" = COALESCE(EXCLUDED."fileSizeInByte", exif."fileSizeInByte"),
     orientation = EXCLUDED.orientation,
     "dateTimeOriginal" = EXCLUDED."dateTimeOriginal",
     "modifyDate" = EXCLUDED."modifyDate",
@@ -569,13 +910,69 @@ ON CONFLICT ("assetId") DO UPDATE SET
     state = EXCLUDED.state,
     country = EXCLUDED.country,
     description = EXCLUDED.description,
+    "timeZone" = EXCLUDED."timeZone",
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 RETURNING *;
 
+-- name: SetAssetOriginalFile :exec
+-- Records the MIME type and size of a stored original. The size is kept in
+-- exif, where metadata extraction leaves it when it cannot tell.
+WITH asset AS (
+    UPDATE assets SET "originalMimeType" = sqlc.arg(mime_type)
+    WHERE id = sqlc.arg(asset_id)
+    RETURNING id
+)
+INSERT INTO exif ("assetId", "fileSizeInByte")
+SELECT id, sqlc.arg(file_size_in_byte) FROM asset
+ON CONFLICT ("assetId") DO UPDATE SET
+    "fileSizeInByte" = EXCLUDED."fileSizeInByte",
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7();
+
+-- name: UpdateExifDateTimeOriginal :one
+INSERT INTO exif ("assetId", "dateTimeOriginal", "timeZone")
+VALUES (sqlc.arg(asset_id), sqlc.arg(date_time_original), sqlc.narg(time_zone))
+ON CONFLICT ("assetId") DO UPDATE SET
+    "dateTimeOriginal" = EXCLUDED."dateTimeOriginal",
+    "timeZone" = COALESCE(EXCLUDED."timeZone", exif."timeZone"),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+RETURNING *;
+
+-- name: UpdateExifLocation :one
+INSERT INTO exif ("assetId", latitude, longitude)
+VALUES (sqlc.arg(asset_id), sqlc.arg(latitude), sqlc.arg(longitude))
+ON CONFLICT ("assetId") DO UPDATE SET
+    latitude = EXCLUDED.latitude,
+    longitude = EXCLUDED.longitude,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+RETURNING *;
+
+-- name: UpdateAssetSidecarPath :exec
+UPDATE assets
+SET "sidecarPath" = $2,
+    "updatedAt" = now()
+WHERE id = $1;
+
+-- name: UpdateAssetCaptureDate :exec
+-- Dates the asset with the capture date metadata extraction found, which
+-- replaces the file time it was uploaded with and moves it out of the
+-- undated bucket.
+UPDATE assets
+SET "fileCreatedAt" = $2,
+    "localDateTime" = $3,
+    "isUndated" = false,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND "deletedAt" IS NULL;
+
 -- name: UpdateAssetLocalDateTime :exec
+-- Setting the capture date moves the asset out of the undated bucket.
 UPDATE assets
 SET "localDateTime" = $2,
+    "isUndated" = false,
     "updatedAt" = now(),
     "updateId" = immich_uuid_v7()
 WHERE id = $1 AND "deletedAt" IS NULL;
@@ -599,6 +996,10 @@ RETURNING *;
 DELETE FROM api_keys
 WHERE id = $1 AND "userId" = $2;
 
+-- name: CountApiKeysByUser :one
+SELECT COUNT(*) FROM api_keys
+WHERE "userId" = $1;
+
 -- name: GetApiKeyByIDAndUser :one
 SELECT * FROM api_keys
 WHERE id = $1 AND "userId" = $2;
@@ -622,10 +1023,24 @@ SELECT * FROM memories
 WHERE id = $1 AND "deletedAt" IS NULL;
 
 -- name: CreateMemory :one
-INSERT INTO memories ("ownerId", type, data)
-VALUES ($1, $2, $3)
+INSERT INTO memories ("ownerId", type, data, "memoryAt", "showAt", "hideAt")
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
+-- name: GetOnThisDayAssets :many
+-- Assets captured on the given local month and day in earlier years.
+-- "localDateTime" holds the capture wall clock as UTC.
+SELECT id, "localDateTime" FROM assets
+WHERE "ownerId" = sqlc.arg(owner_id)
+AND "deletedAt" IS NULL
+AND visibility = 'timeline'
+AND status = 'active'
+AND NOT "isUndated"
+AND EXTRACT(MONTH FROM "localDateTime" AT TIME ZONE 'UTC') = sqlc.arg(month)::int
+AND EXTRACT(DAY FROM "localDateTime" AT TIME ZONE 'UTC') = sqlc.arg(day)::int
+AND EXTRACT(YEAR FROM "localDateTime" AT TIME ZONE 'UTC') < sqlc.arg(before_year)::int
+ORDER BY "localDateTime" ASC;
+
 -- name: UpdateMemory :one
 UPDATE memories
 SET type = COALESCE(sqlc.narg('type'), type),
@@ -651,14 +1066,17 @@ DELETE FROM memories_assets_assets
 WHERE "memoriesId" = $1 AND "assetsId" = ANY($2::uuid[]);
 
 -- name: GetMemoryAssets :many
-SELECT "assetsId" FROM memories_assets_assets
-WHERE "memoriesId" = $1;
+SELECT ma."assetsId" FROM memories_assets_assets ma
+JOIN assets a ON a.id = ma."assetsId"
+WHERE ma."memoriesId" = $1
+AND a.visibility <> 'locked';
 
 -- name: GetAssetsByMemoryID :many
 SELECT a.* FROM assets a
 JOIN memories_assets_assets ma ON a.id = ma."assetsId"
 WHERE ma."memoriesId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY a."fileCreatedAt" DESC;
 
 -- name: CountMemories :one
@@ -701,14 +1119,14 @@ WHERE id = $1;
 -- name: GetPersonAssets :many
 SELECT DISTINCT a.* FROM assets a
 JOIN asset_faces af ON a.id = af."assetId"
-WHERE af."personId" = $1 AND a."deletedAt" IS NULL
+WHERE af."personId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked'
 ORDER BY a."localDateTime" DESC
 LIMIT $2 OFFSET $3;
 
 -- name: CountPersonAssets :one
 SELECT COUNT(DISTINCT a.id) FROM assets a
 JOIN asset_faces af ON a.id = af."assetId"
-WHERE af."personId" = $1 AND a."deletedAt" IS NULL;
+WHERE af."personId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked';
 
 -- name: GetAssetFaces :many
 SELECT af.*, p.name as person_name FROM asset_faces af
@@ -716,8 +1134,8 @@ LEFT JOIN person p ON af."personId" = p.id
 WHERE af."assetId" = $1;
 
 -- name: CreateAssetFace :one
-INSERT INTO asset_faces ("assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2")
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO asset_faces ("assetId", "personId", "imageWidth", "imageHeight", "boundingBoxX1", "boundingBoxY1", "boundingBoxX2", "boundingBoxY2", score)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: UpdateAssetFace :one
@@ -798,12 +1216,86 @@ SET "deletedAt" = now(),
     "updatedAt" = now()
 WHERE id = $1;
 
+-- name: GetLibraryIncludingDeleted :one
+SELECT * FROM libraries
+WHERE id = $1;
+
+-- name: LibraryHasAssets :one
+SELECT EXISTS(
+    SELECT 1 FROM assets
+    WHERE "libraryId" = $1
+) AS has_assets;
+
+-- name: DetachLibraryAssets :execrows
+-- Turns a batch of a deleted library's assets into uploads of its owner. The
+-- files stay where they are and the assets stay external, so nothing deletes
+-- them. Assets the owner already uploaded are left in the library.
+UPDATE assets
+SET "libraryId" = NULL,
+    "deviceAssetId" = id::text,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id IN (
+    SELECT a.id FROM assets a
+    WHERE a."libraryId" = sqlc.arg(library_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM assets u
+        WHERE u."ownerId" = a."ownerId" AND u."libraryId" IS NULL AND u.checksum = a.checksum
+    )
+    ORDER BY a.id
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: TrashLibraryAssets :execrows
+-- Moves a batch of a deleted library's active assets to the trash.
+UPDATE assets
+SET status = 'trashed',
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id IN (
+    SELECT id FROM assets
+    WHERE "libraryId" = sqlc.arg(library_id) AND status = 'active' AND "deletedAt" IS NULL
+    ORDER BY id
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: ListLibraryAssetsForPurge :many
+-- A batch of a deleted library's assets, including trashed ones, for the
+-- library deletion job.
+SELECT id, "originalPath", "encodedVideoPath", "sidecarPath", "isExternal" FROM assets
+WHERE "libraryId" = sqlc.arg(library_id)
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+-- name: DeleteStacksByPrimaryAssets :exec
+-- Stacks pin their primary asset, so they go before it.
+DELETE FROM asset_stack
+WHERE "primaryAssetId" = ANY(sqlc.arg(asset_ids)::uuid[]);
+
 -- name: UpdateLibraryRefreshedAt :exec
 UPDATE libraries
 SET "refreshedAt" = now(),
     "updatedAt" = now()
 WHERE id = $1 AND "deletedAt" IS NULL;
 
+-- name: GetLibraryAssetStatistics :one
+-- Active asset counts by type and their total file size for a library, with
+-- the owner's quota to compute usage against.
+SELECT
+  COUNT(a.id) FILTER (WHERE a.type = 'IMAGE') AS photos,
+  COUNT(a.id) FILTER (WHERE a.type = 'VIDEO') AS videos,
+  COUNT(a.id) AS total,
+  COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS total_size,
+  u."quotaSizeInBytes" AS owner_quota
+FROM libraries l
+JOIN users u ON u.id = l."ownerId"
+LEFT JOIN assets a ON a."libraryId" = l.id
+  AND a."deletedAt" IS NULL
+  AND a.status = 'active'
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE l.id = sqlc.arg(library_id)
+GROUP BY u."quotaSizeInBytes";
+
 -- name: GetLibraryAssets :many
 SELECT * FROM assets
 WHERE "libraryId" = $1 AND "deletedAt" IS NULL
@@ -814,6 +1306,85 @@ LIMIT $2 OFFSET $3;
 SELECT COUNT(*) FROM assets
 WHERE "libraryId" = $1 AND "deletedAt" IS NULL;
 
+-- name: GetLibraryAssetFiles :many
+-- The files backing a library's assets, for reconciling them with a scan.
+SELECT id, "originalPath", checksum, "isOffline" FROM assets
+WHERE "libraryId" = $1 AND "deletedAt" IS NULL;
+
+-- name: GetLibraryFolderAlbumSettings :one
+SELECT * FROM library_folder_album_settings
+WHERE "libraryId" = $1;
+
+-- name: UpsertLibraryFolderAlbumSettings :one
+-- Turns on folder albums for a library, or changes how they are made.
+INSERT INTO library_folder_album_settings ("libraryId", depth, naming)
+VALUES ($1, $2, $3)
+ON CONFLICT ("libraryId") DO UPDATE
+SET depth = EXCLUDED.depth,
+    naming = EXCLUDED.naming,
+    "updatedAt" = now()
+RETURNING *;
+
+-- name: DeleteLibraryFolderAlbumSettings :exec
+-- Turns off folder albums for a library. The albums made so far stay, and
+-- keep their folders should they be turned on again.
+DELETE FROM library_folder_album_settings
+WHERE "libraryId" = $1;
+
+-- name: GetLibraryFolderAlbums :many
+-- The albums of a library's folders, leaving out deleted albums so their
+-- folders get new ones.
+SELECT f."libraryId", f."folderPath", f."albumId" FROM library_folder_albums f
+JOIN albums a ON a.id = f."albumId"
+WHERE f."libraryId" = $1 AND a."deletedAt" IS NULL;
+
+-- name: CreateLibraryFolderAlbum :one
+-- Creates the album of a library folder, replacing a deleted one.
+WITH album AS (
+    INSERT INTO albums ("ownerId", "albumName")
+    VALUES (sqlc.arg(owner_id), sqlc.arg(album_name))
+    RETURNING id
+)
+INSERT INTO library_folder_albums ("libraryId", "folderPath", "albumId")
+SELECT sqlc.arg(library_id), sqlc.arg(folder_path), id FROM album
+ON CONFLICT ("libraryId", "folderPath") DO UPDATE
+SET "albumId" = EXCLUDED."albumId"
+RETURNING "albumId";
+
+-- name: SetAssetOffline :execrows
+-- Flags an asset whose file disappeared; "offlineAt" keeps the time it was
+-- first found missing.
+UPDATE assets
+SET "isOffline" = true,
+    "offlineAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND NOT "isOffline";
+
+-- name: RestoreOfflineAsset :execrows
+-- Brings an offline asset back when its file is found again, possibly at a
+-- new path.
+UPDATE assets
+SET "isOffline" = false,
+    "offlineAt" = NULL,
+    "originalPath" = $2,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1 AND "isOffline";
+
+-- name: DeleteExpiredOfflineAssets :execrows
+-- Deletes the library's assets that have been offline since before
+-- offline_before.
+UPDATE assets
+SET status = 'deleted',
+    "deletedAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "libraryId" = $1
+AND "isOffline"
+AND "offlineAt" < sqlc.arg(offline_before)::timestamptz
+AND "deletedAt" IS NULL;
+
 -- ============================================================================
 -- JOBS & PROCESSING QUERIES
 -- ============================================================================
@@ -887,15 +1458,54 @@ VALUES ($1, $2)
 ON CONFLICT ("assetId") DO UPDATE SET embedding = EXCLUDED.embedding
 RETURNING *;
 
+-- name: UpsertAssetLabels :exec
+-- Stores the detected labels of an asset, refreshing the score of labels
+-- detected before.
+INSERT INTO asset_labels ("assetId", label, score)
+SELECT sqlc.arg(asset_id), l.label, l.score
+FROM unnest(sqlc.arg(labels)::text[], sqlc.arg(scores)::real[]) AS l(label, score)
+ON CONFLICT ("assetId", label) DO UPDATE SET score = EXCLUDED.score, "createdAt" = now();
+
+-- name: DeleteAssetLabelsExcept :exec
+-- Drops labels of an asset that the latest detection no longer reported.
+DELETE FROM asset_labels
+WHERE "assetId" = sqlc.arg(asset_id)
+AND label <> ALL(sqlc.arg(keep)::text[]);
+
 -- name: SearchAssetsByEmbedding :many
 SELECT ss.*, a.* FROM smart_search ss
 JOIN assets a ON ss."assetId" = a.id
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND ss.embedding <-> sqlc.arg(embedding) < sqlc.arg(max_distance)
+AND (sqlc.narg('labels')::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= sqlc.arg('label_min_score')::float8
+    AND al.label = ANY(sqlc.narg('labels')::text[])
+) = cardinality(sqlc.narg('labels')::text[]))
 ORDER BY ss.embedding <-> sqlc.arg(embedding)
 LIMIT sqlc.arg(result_limit);
 
+-- name: SearchSimilarAssets :many
+-- Nearest neighbors of an asset's embedding by cosine distance. Without a
+-- visibility filter, hidden assets are excluded; locked assets always are.
+SELECT sqlc.embed(a), (ss.embedding <=> src.embedding)::float8 AS distance
+FROM smart_search src
+JOIN smart_search ss ON ss."assetId" != src."assetId"
+JOIN assets a ON ss."assetId" = a.id
+WHERE src."assetId" = sqlc.arg(asset_id)
+AND a."ownerId" = sqlc.arg(owner_id)
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND (
+    (sqlc.narg(visibility)::asset_visibility_enum IS NULL AND a.visibility IN ('timeline', 'archive'))
+    OR a.visibility = sqlc.narg(visibility)::asset_visibility_enum
+)
+ORDER BY ss.embedding <=> src.embedding
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
 -- name: ListSmartSearchByOwner :many
 SELECT ss."assetId", ss.embedding, a."duplicateId"
 FROM smart_search ss
@@ -918,6 +1528,7 @@ SELECT DISTINCT a.* FROM assets a
 LEFT JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = $1 
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     a."originalFileName" ILIKE '%' || $2 || '%'
     OR a."originalPath" ILIKE '%' || $2 || '%'
@@ -944,10 +1555,23 @@ WHERE "userId" = $1
 ORDER BY value ASC;
 
 -- name: CreateTag :one
-INSERT INTO tags ("userId", value, color)
-VALUES ($1, $2, $3)
+INSERT INTO tags ("userId", value, color, "parentId")
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
+-- name: AddTagClosure :exec
+-- Links a new tag to itself and to every ancestor of its parent.
+INSERT INTO tags_closure (id_ancestor, id_descendant)
+SELECT sqlc.arg(tag_id)::uuid, sqlc.arg(tag_id)::uuid
+UNION
+SELECT sqlc.narg(parent_id)::uuid, sqlc.arg(tag_id)::uuid
+WHERE sqlc.narg(parent_id)::uuid IS NOT NULL
+UNION
+SELECT tc.id_ancestor, sqlc.arg(tag_id)::uuid
+FROM tags_closure tc
+WHERE tc.id_descendant = sqlc.narg(parent_id)::uuid
+ON CONFLICT DO NOTHING;
+
 -- name: UpdateTag :one
 UPDATE tags
 SET value = COALESCE(sqlc.narg('value'), value),
@@ -974,6 +1598,31 @@ ON CONFLICT DO NOTHING;
 DELETE FROM tag_asset
 WHERE "tagsId" = $1 AND "assetsId" = $2;
 
+-- name: BulkAddTagsToAssets :execrows
+-- Applies every tag to every asset in a single statement, so a batch is
+-- written entirely or not at all. Tags and assets not owned by the user are
+-- skipped.
+INSERT INTO tag_asset ("tagsId", "assetsId")
+SELECT t.id, a.id
+FROM tags t
+CROSS JOIN assets a
+WHERE t.id = ANY(sqlc.arg(tag_ids)::uuid[])
+AND t."userId" = sqlc.arg(user_id)
+AND a.id = ANY(sqlc.arg(asset_ids)::uuid[])
+AND a."ownerId" = sqlc.arg(user_id)
+ON CONFLICT DO NOTHING;
+
+-- name: GetAssetTagSets :many
+-- Tags of each asset, including the ancestors implied by nested tags. An
+-- ancestor stays in the set as long as any of its descendants is applied.
+SELECT DISTINCT ta."assetsId" AS asset_id, sqlc.embed(t)
+FROM tag_asset ta
+INNER JOIN tags_closure tc ON tc.id_descendant = ta."tagsId"
+INNER JOIN tags t ON t.id = tc.id_ancestor
+WHERE ta."assetsId" = ANY(sqlc.arg(asset_ids)::uuid[])
+AND t."userId" = sqlc.arg(user_id)
+ORDER BY ta."assetsId", t.value;
+
 -- ============================================================================
 -- SHARED LINKS QUERIES
 -- ============================================================================
@@ -983,13 +1632,48 @@ SELECT * FROM shared_links
 WHERE id = $1;
 
 -- name: GetSharedLinkByKey :one
-SELECT * FROM shared_links
-WHERE key = $1;
-
--- name: GetSharedLinks :many
-SELECT * FROM shared_links
-WHERE "userId" = $1
-ORDER BY "createdAt" DESC;
+-- Links of deleted users stay in place but stop resolving.
+SELECT sl.* FROM shared_links sl
+JOIN users u ON u.id = sl."userId" AND u."deletedAt" IS NULL
+WHERE sl.key = $1;
+
+-- name: ListSharedLinks :many
+-- A page of the user's links, newest first unless ascending. The cursor is
+-- the creation time and id of the last link of the previous page.
+SELECT sqlc.embed(sl),
+    (SELECT COUNT(*) FROM shared_link__asset sla
+        JOIN assets a ON a.id = sla."assetsId"
+        WHERE sla."sharedLinksId" = sl.id AND a."deletedAt" IS NULL) AS asset_count
+FROM shared_links sl
+WHERE sl."userId" = sqlc.arg(user_id)
+AND (sqlc.narg(link_type)::text IS NULL OR sl.type = sqlc.narg(link_type)::text)
+AND (sqlc.narg(album_id)::uuid IS NULL OR sl."albumId" = sqlc.narg(album_id)::uuid)
+AND (sqlc.narg(expired)::boolean IS NULL
+    OR (sl."expiresAt" IS NOT NULL AND sl."expiresAt" <= now()) = sqlc.narg(expired)::boolean)
+AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+    OR (sqlc.arg(ascending)::boolean
+        AND (sl."createdAt", sl.id) > (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::uuid))
+    OR (NOT sqlc.arg(ascending)::boolean
+        AND (sl."createdAt", sl.id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::uuid)))
+ORDER BY
+    CASE WHEN sqlc.arg(ascending)::boolean THEN sl."createdAt" END ASC,
+    CASE WHEN sqlc.arg(ascending)::boolean THEN sl.id END ASC,
+    sl."createdAt" DESC,
+    sl.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: CountSharedLinks :one
+SELECT COUNT(*) FROM shared_links sl
+WHERE sl."userId" = sqlc.arg(user_id)
+AND (sqlc.narg(link_type)::text IS NULL OR sl.type = sqlc.narg(link_type)::text)
+AND (sqlc.narg(album_id)::uuid IS NULL OR sl."albumId" = sqlc.narg(album_id)::uuid)
+AND (sqlc.narg(expired)::boolean IS NULL
+    OR (sl."expiresAt" IS NOT NULL AND sl."expiresAt" <= now()) = sqlc.narg(expired)::boolean);
+
+-- name: DeleteExpiredSharedLinks :execrows
+DELETE FROM shared_links
+WHERE "userId" = sqlc.arg(user_id)
+AND "expiresAt" <= sqlc.arg(expired_before);
 
 -- name: CreateSharedLink :one
 INSERT INTO shared_links ("userId", key, type, "albumId", "expiresAt", "allowUpload", "allowDownload", description, password, "showExif")
@@ -1015,6 +1699,7 @@ WHERE id = $1;
 SELECT a.* FROM assets a
 JOIN shared_link__asset sla ON a.id = sla."assetsId"
 WHERE sla."sharedLinksId" = $1 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY a."localDateTime" DESC;
 
 -- name: AddAssetToSharedLink :exec
@@ -1077,16 +1762,31 @@ WHERE id = $1;
 -- ============================================================================
 
 -- name: GetPartners :many
-SELECT u.*, p."sharedById", p."sharedWithId", p."inTimeline", p."createdAt" as partnership_created_at, p."updatedAt" as partnership_updated_at FROM partners p
+SELECT u.*, p."sharedById", p."sharedWithId", p."inTimeline", p."createdAt" as partnership_created_at, p."updatedAt" as partnership_updated_at, p.status as partnership_status FROM partners p
 JOIN users u ON (u.id = p."sharedById" OR u.id = p."sharedWithId")
-WHERE (p."sharedById" = $1 OR p."sharedWithId" = $1) AND u.id != $1;
+WHERE (p."sharedById" = $1 OR p."sharedWithId" = $1) AND u.id != $1
+AND u."deletedAt" IS NULL;
 
 -- name: CreatePartnership :one
-INSERT INTO partners ("sharedById", "sharedWithId")
-VALUES ($1, $2)
+-- Inviting an existing partner again keeps the partnership's status, so an
+-- accepted partnership stays accepted and a pending invite stays pending.
+INSERT INTO partners ("sharedById", "sharedWithId", status)
+VALUES ($1, $2, 'pending')
+ON CONFLICT ("sharedById", "sharedWithId") DO UPDATE
+SET "updatedAt" = now()
 RETURNING *;
 
--- name: DeletePartnership :exec
+-- name: AcceptPartnership :one
+-- Only the invited user accepts, so the pair is matched in one direction.
+UPDATE partners
+SET status = 'accepted',
+    "acceptedAt" = now(),
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "sharedById" = $1 AND "sharedWithId" = $2 AND status = 'pending'
+RETURNING *;
+
+-- name: DeletePartnership :execrows
 DELETE FROM partners
 WHERE ("sharedById" = $1 AND "sharedWithId" = $2) OR ("sharedById" = $2 AND "sharedWithId" = $1);
 
@@ -1111,6 +1811,19 @@ VALUES ($1, $2)
 ON CONFLICT (key) DO UPDATE SET value = $2
 RETURNING *;
 
+-- name: DeleteSystemMetadata :exec
+DELETE FROM system_metadata
+WHERE key = $1;
+
+-- name: ClaimFirstAdmin :execrows
+-- Claims the administrator slot of a fresh install for user_id. The key can
+-- only be inserted once, so of concurrent first registrations exactly one
+-- claims it; installs that already have users never do.
+INSERT INTO system_metadata (key, value)
+SELECT 'first_admin', to_jsonb(sqlc.arg(user_id)::text)
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE "deletedAt" IS NULL)
+ON CONFLICT (key) DO NOTHING;
+
 -- name: GetUserMetadata :one
 SELECT * FROM user_metadata
 WHERE "userId" = $1 AND key = $2;
@@ -1173,17 +1886,24 @@ WHERE "userId" = $1 AND "readAt" IS NULL AND "deletedAt" IS NULL;
 -- ============================================================================
 
 -- name: GetTimelineBuckets :many
+-- Newest first unless ascending. Undated assets form one bucket with a
+-- NULL date, after every dated one.
+-- "localDateTime" holds the capture wall clock as UTC, so it is truncated in
+-- UTC whatever the session timezone is.
 SELECT
-    date_trunc($2, "localDateTime")::date as time_bucket,
+    CASE WHEN "isUndated" THEN NULL ELSE date_trunc($2, "localDateTime" AT TIME ZONE 'UTC')::date END as time_bucket,
     COUNT(*) as count
 FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
 AND visibility = 'timeline'
+AND NOT "isOffline"
 AND ($4::bool = false AND status = 'active' OR $4::bool = true AND status = 'trashed')
 AND ($3::bool = false OR "isFavorite" = true)
 GROUP BY time_bucket
-ORDER BY time_bucket DESC;
+ORDER BY
+    CASE WHEN sqlc.arg(ascending)::bool THEN time_bucket END ASC NULLS LAST,
+    time_bucket DESC NULLS LAST;
 
 -- name: GetCalendarHeatmap :many
 WITH scoped_assets AS (
@@ -1195,6 +1915,7 @@ WITH scoped_assets AS (
     FROM assets
     WHERE "ownerId" = sqlc.arg(owner_id)
     AND "deletedAt" IS NULL
+    AND visibility <> 'locked'
 )
 SELECT
     date_trunc('day', activity_at AT TIME ZONE 'UTC')::date AS activity_date,
@@ -1206,6 +1927,8 @@ GROUP BY activity_date
 ORDER BY activity_date ASC;
 
 -- name: GetTimelineBucketAssets :many
+-- The assets of a bucket, newest first unless ascending, after the capture
+-- time and id of the last asset of the previous page.
 SELECT
     a.id,
     a."deviceAssetId",
@@ -1238,14 +1961,74 @@ LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
 AND a.visibility = 'timeline'
+AND NOT a."isOffline"
 AND ($6::bool = false AND a.status = 'active' OR $6::bool = true AND a.status = 'trashed')
 AND ($5::bool = false OR a."isFavorite" = true)
-AND ($2 = 'day' AND date_trunc('day', a."localDateTime")::date = $3::date
-     OR $2 = 'month' AND date_trunc('month', a."localDateTime")::date = $3::date
-     OR $2 = 'year' AND date_trunc('year', a."localDateTime")::date = $3::date)
-ORDER BY a."localDateTime" DESC
+AND (sqlc.arg(undated)::bool AND a."isUndated"
+     OR NOT sqlc.arg(undated)::bool AND NOT a."isUndated"
+     AND ($2 = 'day' AND date_trunc('day', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date
+          OR $2 = 'month' AND date_trunc('month', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date
+          OR $2 = 'year' AND date_trunc('year', a."localDateTime" AT TIME ZONE 'UTC')::date = $3::date))
+AND (sqlc.narg(cursor_local_date_time)::timestamptz IS NULL
+    OR (sqlc.arg(ascending)::bool
+        AND (a."localDateTime", a.id) > (sqlc.narg(cursor_local_date_time)::timestamptz, sqlc.narg(cursor_id)::uuid))
+    OR (NOT sqlc.arg(ascending)::bool
+        AND (a."localDateTime", a.id) < (sqlc.narg(cursor_local_date_time)::timestamptz, sqlc.narg(cursor_id)::uuid)))
+ORDER BY
+    CASE WHEN sqlc.arg(ascending)::bool THEN a."localDateTime" END ASC,
+    CASE WHEN sqlc.arg(ascending)::bool THEN a.id END ASC,
+    a."localDateTime" DESC,
+    a.id DESC
 LIMIT $4 OFFSET 0;
 
+-- name: GetTimelineDays :many
+-- The days of the timeline with their asset count, newest first, before the
+-- cursor day when one is given. Undated assets form the day 0001-01-01,
+-- which sorts after every dated one.
+SELECT day, count FROM (
+    SELECT
+        CASE WHEN "isUndated" THEN DATE '0001-01-01' ELSE ("localDateTime" AT TIME ZONE 'UTC')::date END AS day,
+        COUNT(*) AS count
+    FROM assets
+    WHERE "ownerId" = sqlc.arg(owner_id)
+    AND "deletedAt" IS NULL
+    AND visibility = 'timeline'
+    AND NOT "isOffline"
+    AND (NOT sqlc.arg(is_trashed)::bool AND status = 'active' OR sqlc.arg(is_trashed)::bool AND status = 'trashed')
+    AND (NOT sqlc.arg(is_favorite)::bool OR "isFavorite")
+    GROUP BY 1
+) days
+WHERE sqlc.narg(before)::date IS NULL OR day < sqlc.narg(before)::date
+ORDER BY day DESC
+LIMIT sqlc.arg(day_limit);
+
+-- name: GetTimelineDayAssets :many
+-- The grid layout fields of the timeline assets of the days from oldest to
+-- newest, days as returned by GetTimelineDays. ratio is NULL until the
+-- thumbnails of the asset are generated.
+SELECT day, id, type, "localDateTime", thumbhash, ratio, "exifImageWidth", "exifImageHeight" FROM (
+    SELECT
+        CASE WHEN a."isUndated" THEN DATE '0001-01-01' ELSE (a."localDateTime" AT TIME ZONE 'UTC')::date END AS day,
+        a.id,
+        a.type,
+        a."localDateTime",
+        encode(a.thumbhash, 'base64') AS thumbhash,
+        r.ratio,
+        e."exifImageWidth",
+        e."exifImageHeight"
+    FROM assets a
+    LEFT JOIN asset_thumbnail_ratios r ON r."assetId" = a.id
+    LEFT JOIN exif e ON e."assetId" = a.id
+    WHERE a."ownerId" = sqlc.arg(owner_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND NOT a."isOffline"
+    AND (NOT sqlc.arg(is_trashed)::bool AND a.status = 'active' OR sqlc.arg(is_trashed)::bool AND a.status = 'trashed')
+    AND (NOT sqlc.arg(is_favorite)::bool OR a."isFavorite")
+) day_assets
+WHERE day BETWEEN sqlc.arg(oldest)::date AND sqlc.arg(newest)::date
+ORDER BY day DESC, "localDateTime" DESC, id DESC;
+
 -- name: GetAssetStatsByUser :one
 SELECT 
     COUNT(*) as total,
@@ -1257,7 +2040,7 @@ SELECT
     COALESCE(SUM(e."fileSizeInByte"), 0)::bigint as total_size
 FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
-WHERE a."ownerId" = $1 AND a."deletedAt" IS NULL;
+WHERE a."ownerId" = $1 AND a."deletedAt" IS NULL AND a.visibility <> 'locked';
 
 -- name: GetStorageUsageByUser :one
 SELECT 
@@ -1280,6 +2063,7 @@ SELECT a.*, e.latitude AS exif_latitude, e.longitude AS exif_longitude, e.city,
 JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.latitude IS NOT NULL
 AND e.longitude IS NOT NULL
 AND e.latitude BETWEEN sqlc.arg(min_lat) AND sqlc.arg(max_lat)
@@ -1295,24 +2079,34 @@ LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 SELECT * FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "localDateTime" BETWEEN $2 AND $3
 ORDER BY "localDateTime" DESC
 LIMIT $4 OFFSET $5;
 
 -- name: GetDuplicateAssets :many
 SELECT a1.*, a2.id as duplicate_id FROM assets a1
-JOIN assets a2 ON a1.checksum = a2.checksum AND a2."ownerId" = a1."ownerId" AND a1.id < a2.id
+JOIN assets a2 ON a1.checksum = a2.checksum AND a1."checksumAlgorithm" = a2."checksumAlgorithm" AND a2."ownerId" = a1."ownerId" AND a1.id < a2.id
 WHERE a1."ownerId" = $1 AND a1."deletedAt" IS NULL AND a2."deletedAt" IS NULL
+AND a1.visibility <> 'locked' AND a2.visibility <> 'locked'
 ORDER BY a1."localDateTime" DESC;
 
 -- name: GetAssetsByChecksum :many
 SELECT * FROM assets
 WHERE checksum = $1 AND "deletedAt" IS NULL;
 
+-- name: GetOwnerAssetsByChecksum :many
+-- Upload dedup: a checksum only matches one computed with the same algorithm.
+SELECT * FROM assets
+WHERE "ownerId" = sqlc.arg(owner_id)
+AND checksum = sqlc.arg(checksum)
+AND "checksumAlgorithm" = sqlc.arg(checksum_algorithm)
+AND "deletedAt" IS NULL;
+
 -- name: GetAssetsByChecksumsAndOwner :many
 -- Bulk-upload duplicate check: includes trashed assets so the client can
 -- surface "duplicate (in trash)" like upstream.
-SELECT id, checksum, status FROM assets
+SELECT id, checksum, "checksumAlgorithm", status FROM assets
 WHERE "ownerId" = sqlc.arg(owner_id) AND checksum = ANY(sqlc.arg(checksums)::bytea[]);
 
 -- name: TrashAssetsByIDsAndOwner :exec
@@ -1345,6 +2139,7 @@ SELECT a.* FROM assets a
 JOIN exif e ON a.id = e."assetId"
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e."fileSizeInByte" = $2
 ORDER BY a."fileCreatedAt" DESC;
 
@@ -1461,6 +2256,7 @@ SELECT a.* FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND (sqlc.narg('with_deleted')::boolean = true OR a."deletedAt" IS NULL)
+AND a.visibility <> 'locked'
 AND (sqlc.narg('type')::text IS NULL OR a.type = sqlc.narg('type')::text)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR a."isFavorite" = sqlc.narg('is_favorite')::boolean)
 AND (sqlc.narg('city')::text IS NULL OR e.city = sqlc.narg('city')::text)
@@ -1477,16 +2273,24 @@ ORDER BY random()
 LIMIT COALESCE(sqlc.narg('limit')::integer, 100);
 
 -- name: SearchAssetsFiltered :many
-SELECT DISTINCT a.* FROM assets a
+-- sort_by is "date_taken", "created_at" or "filename", ties broken by id.
+SELECT a.* FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     sqlc.narg('query')::text IS NULL
     OR a."originalFileName" ILIKE '%' || sqlc.narg('query')::text || '%'
     OR a."originalPath" ILIKE '%' || sqlc.narg('query')::text || '%'
     OR e.description ILIKE '%' || sqlc.narg('query')::text || '%'
     OR e."imageName" ILIKE '%' || sqlc.narg('query')::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM asset_labels al
+        WHERE al."assetId" = a.id
+        AND al.score >= sqlc.arg('label_min_score')::float8
+        AND al.label ILIKE '%' || sqlc.narg('query')::text || '%'
+    )
 )
 AND (sqlc.narg('type')::text IS NULL OR a.type = sqlc.narg('type')::text)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR a."isFavorite" = sqlc.narg('is_favorite')::boolean)
@@ -1503,9 +2307,23 @@ AND (sqlc.narg('taken_after')::timestamptz IS NULL OR a."localDateTime" >= sqlc.
 AND (sqlc.narg('taken_before')::timestamptz IS NULL OR a."localDateTime" <= sqlc.narg('taken_before')::timestamptz)
 AND (sqlc.narg('is_encoded')::boolean IS NULL OR (a."encodedVideoPath" IS NOT NULL AND a."encodedVideoPath" <> '') = sqlc.narg('is_encoded')::boolean)
 AND (sqlc.narg('is_motion')::boolean IS NULL OR (a."livePhotoVideoId" IS NOT NULL) = sqlc.narg('is_motion')::boolean)
-AND (sqlc.narg('is_offline')::boolean IS NULL OR a."isOffline" = sqlc.narg('is_offline')::boolean)
+AND a."isOffline" = COALESCE(sqlc.narg('is_offline')::boolean, false)
 AND (sqlc.narg('is_external')::boolean IS NULL OR a."isExternal" = sqlc.narg('is_external')::boolean)
-ORDER BY a."localDateTime" DESC
+AND (sqlc.narg('labels')::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= sqlc.arg('label_min_score')::float8
+    AND al.label = ANY(sqlc.narg('labels')::text[])
+) = cardinality(sqlc.narg('labels')::text[]))
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(descending)::boolean THEN a."createdAt" END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(descending)::boolean THEN a."createdAt" END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'filename' AND NOT sqlc.arg(descending)::boolean THEN lower(a."originalFileName") END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'filename' AND sqlc.arg(descending)::boolean THEN lower(a."originalFileName") END DESC,
+    CASE WHEN NOT sqlc.arg(descending)::boolean THEN a."localDateTime" END ASC,
+    CASE WHEN sqlc.arg(descending)::boolean THEN a."localDateTime" END DESC,
+    CASE WHEN NOT sqlc.arg(descending)::boolean THEN a.id END ASC,
+    a.id DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: CountSearchAssetsFilteredForPage :one
@@ -1513,12 +2331,19 @@ SELECT COUNT(DISTINCT a.id) FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (
     sqlc.narg('query')::text IS NULL
     OR a."originalFileName" ILIKE '%' || sqlc.narg('query')::text || '%'
     OR a."originalPath" ILIKE '%' || sqlc.narg('query')::text || '%'
     OR e.description ILIKE '%' || sqlc.narg('query')::text || '%'
     OR e."imageName" ILIKE '%' || sqlc.narg('query')::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM asset_labels al
+        WHERE al."assetId" = a.id
+        AND al.score >= sqlc.arg('label_min_score')::float8
+        AND al.label ILIKE '%' || sqlc.narg('query')::text || '%'
+    )
 )
 AND (sqlc.narg('type')::text IS NULL OR a.type = sqlc.narg('type')::text)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR a."isFavorite" = sqlc.narg('is_favorite')::boolean)
@@ -1535,14 +2360,21 @@ AND (sqlc.narg('taken_after')::timestamptz IS NULL OR a."localDateTime" >= sqlc.
 AND (sqlc.narg('taken_before')::timestamptz IS NULL OR a."localDateTime" <= sqlc.narg('taken_before')::timestamptz)
 AND (sqlc.narg('is_encoded')::boolean IS NULL OR (a."encodedVideoPath" IS NOT NULL AND a."encodedVideoPath" <> '') = sqlc.narg('is_encoded')::boolean)
 AND (sqlc.narg('is_motion')::boolean IS NULL OR (a."livePhotoVideoId" IS NOT NULL) = sqlc.narg('is_motion')::boolean)
-AND (sqlc.narg('is_offline')::boolean IS NULL OR a."isOffline" = sqlc.narg('is_offline')::boolean)
-AND (sqlc.narg('is_external')::boolean IS NULL OR a."isExternal" = sqlc.narg('is_external')::boolean);
+AND a."isOffline" = COALESCE(sqlc.narg('is_offline')::boolean, false)
+AND (sqlc.narg('is_external')::boolean IS NULL OR a."isExternal" = sqlc.narg('is_external')::boolean)
+AND (sqlc.narg('labels')::text[] IS NULL OR (
+    SELECT COUNT(*) FROM asset_labels al
+    WHERE al."assetId" = a.id
+    AND al.score >= sqlc.arg('label_min_score')::float8
+    AND al.label = ANY(sqlc.narg('labels')::text[])
+) = cardinality(sqlc.narg('labels')::text[]));
 
 -- name: SearchLargeAssets :many
 SELECT a.* FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = $1
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 ORDER BY COALESCE(e."fileSizeInByte", 0) DESC, a."createdAt" DESC
 LIMIT $2;
 
@@ -1551,6 +2383,7 @@ SELECT COUNT(*) FROM assets a
 LEFT JOIN exif e ON e."assetId" = a.id
 WHERE a."ownerId" = sqlc.arg(owner_id)
 AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND (sqlc.narg('type')::text IS NULL OR a.type = sqlc.narg('type')::text)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR a."isFavorite" = sqlc.narg('is_favorite')::boolean)
 AND (sqlc.narg('city')::text IS NULL OR e.city = sqlc.narg('city')::text)
@@ -1562,7 +2395,8 @@ AND (sqlc.narg('lens_model')::text IS NULL OR e."lensModel" = sqlc.narg('lens_mo
 AND (sqlc.narg('library_id')::uuid IS NULL OR a."libraryId" = sqlc.narg('library_id')::uuid)
 AND (sqlc.narg('device_id')::text IS NULL OR a."deviceId" = sqlc.narg('device_id')::text)
 AND (sqlc.narg('taken_after')::timestamptz IS NULL OR a."localDateTime" >= sqlc.narg('taken_after')::timestamptz)
-AND (sqlc.narg('taken_before')::timestamptz IS NULL OR a."localDateTime" <= sqlc.narg('taken_before')::timestamptz);
+AND (sqlc.narg('taken_before')::timestamptz IS NULL OR a."localDateTime" <= sqlc.narg('taken_before')::timestamptz)
+AND NOT a."isOffline";
 
 -- ============================================================================
 -- DATABASE BACKUP QUERIES
@@ -1599,6 +2433,7 @@ SELECT * FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND status = 'trashed'
+AND visibility <> 'locked'
 ORDER BY "updatedAt" DESC;
 
 -- name: RestoreAssetFromTrash :exec
@@ -1643,15 +2478,22 @@ SELECT * FROM tags
 WHERE "userId" = $1 AND value = $2;
 
 -- name: GetTagAssets :many
+-- Assets tagged with the tag or any of its descendants.
 SELECT a.* FROM assets a
-JOIN tag_asset ta ON a.id = ta."assetsId"
-WHERE ta."tagsId" = $1 AND a."deletedAt" IS NULL;
+WHERE a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND a.id IN (
+    SELECT ta."assetsId" FROM tag_asset ta
+    INNER JOIN tags_closure tc ON tc.id_descendant = ta."tagsId"
+    WHERE tc.id_ancestor = $1
+);
 
 -- name: GetFavoriteAssets :many
 SELECT * FROM assets
 WHERE "ownerId" = $1 
 AND "deletedAt" IS NULL
 AND "isFavorite" = true
+AND visibility <> 'locked'
 ORDER BY "localDateTime" DESC
 LIMIT $2 OFFSET $3;
 
@@ -1663,14 +2505,40 @@ AND visibility = 'archive'
 ORDER BY "localDateTime" DESC
 LIMIT $2 OFFSET $3;
 
--- name: GetTrashedAssets :many
+-- name: GetLockedAssets :many
 SELECT * FROM assets
-WHERE "ownerId" = $1 
+WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
-AND status = 'trashed'
-ORDER BY "updatedAt" DESC
+AND visibility = 'locked'
+ORDER BY "localDateTime" DESC, id DESC
 LIMIT $2 OFFSET $3;
 
+-- name: CountLockedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND visibility = 'locked';
+
+-- name: GetTrashedAssets :many
+-- The assets in the trash with the time they were moved there, most
+-- recently trashed first.
+SELECT sqlc.embed(a), t."trashedAt"
+FROM assets a
+INNER JOIN asset_trash t ON t."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."deletedAt" IS NULL
+AND a.status = 'trashed'
+AND a.visibility <> 'locked'
+ORDER BY t."trashedAt" DESC, a.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountTrashedAssets :one
+SELECT COUNT(*) FROM assets
+WHERE "ownerId" = $1
+AND "deletedAt" IS NULL
+AND status = 'trashed'
+AND visibility <> 'locked';
+
 -- name: RestoreAssets :exec
 UPDATE assets
 SET status = 'active',
@@ -1685,23 +2553,225 @@ WHERE id = ANY($1::uuid[]) AND "ownerId" = $2;
 
 -- Asset Files queries
 -- name: CreateAssetFile :one
-INSERT INTO asset_files ("assetId", "type", "path")
-VALUES ($1, $2, $3)
+INSERT INTO asset_files ("assetId", "type", "path", width, height, "sizeInBytes")
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
+-- name: UpsertAssetFile :one
+-- Records a generated file of an asset, replacing the one of its type
+-- generated before.
+INSERT INTO asset_files ("assetId", "type", "path", width, height, "sizeInBytes")
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT ("assetId", "type") DO UPDATE
+SET "path" = EXCLUDED."path", width = EXCLUDED.width, height = EXCLUDED.height,
+    "sizeInBytes" = EXCLUDED."sizeInBytes", "updatedAt" = now(), "updateId" = immich_uuid_v7()
+RETURNING *;
+
+-- name: CreateAssetDownscale :exec
+-- Records that an image was downscaled on upload.
+INSERT INTO asset_downscales ("assetId", "originalWidth", "originalHeight", width, height, "originalKept")
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: DeleteAssetDownscale :exec
+DELETE FROM asset_downscales
+WHERE "assetId" = $1;
+
+-- name: SetAssetBlurhash :exec
+-- Records the BlurHash of the generated thumbnails of an asset.
+UPDATE assets
+SET blurhash = $2,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1;
+
+-- name: SetAssetThumbnailLayout :exec
+-- Records the thumbhash and aspect ratio of the generated thumbnails of an
+-- asset.
+WITH hashed AS (
+    UPDATE assets
+    SET thumbhash = $2,
+        "updatedAt" = now(),
+        "updateId" = immich_uuid_v7()
+    WHERE id = $1
+)
+INSERT INTO asset_thumbnail_ratios ("assetId", ratio)
+VALUES ($1, $3)
+ON CONFLICT ("assetId") DO UPDATE SET ratio = EXCLUDED.ratio, "updatedAt" = now();
+
+-- name: ListAssetsMissingThumbnailLayout :many
+-- Assets with a small thumbnail but no recorded thumbnail layout, in id
+-- order after the given asset.
+SELECT a.id, f.path
+FROM assets a
+JOIN asset_files f ON f."assetId" = a.id AND f.type = 'thumb'
+LEFT JOIN asset_thumbnail_ratios r ON r."assetId" = a.id
+WHERE a."deletedAt" IS NULL
+AND (a.thumbhash IS NULL OR r."assetId" IS NULL)
+AND a.id > $1
+ORDER BY a.id
+LIMIT $2;
+
+-- Asset reassignment queries
+-- name: ReassignAsset :exec
+-- Hands an asset to another user, with its paths moved to theirs.
+UPDATE assets
+SET "ownerId" = $2,
+    "originalPath" = $3,
+    "sidecarPath" = $4,
+    "encodedVideoPath" = $5,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE id = $1;
+
+-- name: UpdateAssetFilePath :exec
+UPDATE asset_files
+SET path = $2, "updatedAt" = now(), "updateId" = immich_uuid_v7()
+WHERE id = $1;
+
+-- name: OwnerHasUploadedAsset :one
+-- Uploaded assets are unique per owner and checksum, and per owner and
+-- device asset, trashed ones included.
+SELECT EXISTS(
+    SELECT 1 FROM assets
+    WHERE "ownerId" = $1 AND "libraryId" IS NULL
+    AND (checksum = $2 OR ("deviceId" = $3 AND "deviceAssetId" = $4 AND "deviceId" <> '' AND "deviceAssetId" <> ''))
+);
+
+-- name: DetachAssetFacesFromPeople :exec
+-- People belong to the owner of an asset, so the faces of a reassigned asset
+-- are left for the new owner's people. People featuring one of them get a
+-- new thumbnail.
+WITH faces AS (
+    UPDATE asset_faces SET "personId" = NULL
+    WHERE "assetId" = $1
+    RETURNING id
+)
+UPDATE person
+SET "faceAssetId" = NULL, "updatedAt" = now()
+WHERE "faceAssetId" IN (SELECT id FROM faces);
+
+-- name: RemoveAssetFromAllAlbums :execrows
+DELETE FROM albums_assets_assets
+WHERE "assetsId" = $1;
+
+-- name: RemoveAllTagsFromAsset :execrows
+DELETE FROM tag_asset
+WHERE "assetsId" = $1;
+
+-- name: AdjustUserQuotaUsage :exec
+-- Usage never goes below zero, in case it was not tracked before.
+UPDATE users
+SET "quotaUsageInBytes" = GREATEST("quotaUsageInBytes" + sqlc.arg(delta)::bigint, 0),
+    "updatedAt" = now()
+WHERE id = sqlc.arg(id);
+
+-- name: GetUserQuotaUsage :one
+-- The stored quota usage of a user and the size of the originals it
+-- accounts for: the user's uploads outside the trash. Files of external
+-- libraries are read in place and use no storage.
+SELECT
+    u."quotaUsageInBytes" AS stored,
+    COALESCE((
+        SELECT SUM(e."fileSizeInByte")
+        FROM assets a
+        JOIN exif e ON e."assetId" = a.id
+        WHERE a."ownerId" = u.id
+        AND a."libraryId" IS NULL
+        AND a.status = 'active'
+        AND a."deletedAt" IS NULL
+    ), 0)::bigint AS actual
+FROM users u
+WHERE u.id = $1;
+
+-- name: CorrectUserQuotaUsage :execrows
+-- Only applies while the stored usage is the one read, so an adjustment
+-- made meanwhile is not overwritten.
+UPDATE users
+SET "quotaUsageInBytes" = sqlc.arg(actual)::bigint,
+    "updatedAt" = now()
+WHERE id = sqlc.arg(id) AND "quotaUsageInBytes" = sqlc.arg(stored)::bigint;
+
+-- name: GetUserStorageByType :many
+-- The size of a user's originals by asset type, counting what quota usage
+-- counts: uploads outside the trash.
+SELECT a.type, COUNT(*)::bigint AS assets, COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS bytes
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+GROUP BY a.type
+ORDER BY bytes DESC, a.type;
+
+-- name: GetUserStorageByYear :many
+-- The size of a user's originals by the year they were taken, newest
+-- first. Undated assets come last, with no year.
+SELECT
+    (CASE WHEN a."isUndated" THEN NULL ELSE EXTRACT(YEAR FROM a."localDateTime" AT TIME ZONE 'UTC') END)::int AS year,
+    COUNT(*)::bigint AS assets,
+    COALESCE(SUM(e."fileSizeInByte"), 0)::bigint AS bytes
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+GROUP BY 1
+ORDER BY 1 DESC NULLS LAST;
+
+-- name: GetUserLargestAssets :many
+-- A user's assets counting towards quota usage, largest first. Assets of
+-- the locked folder are left out, like in every other listing.
+SELECT sqlc.embed(a), e."fileSizeInByte"::bigint AS size
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND e."fileSizeInByte" IS NOT NULL
+ORDER BY e."fileSizeInByte" DESC, a.id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUserLargestAssets :one
+SELECT COUNT(*)
+FROM assets a
+JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = $1
+AND a."libraryId" IS NULL
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
+AND e."fileSizeInByte" IS NOT NULL;
+
 -- name: GetAssetFiles :many
 SELECT * FROM asset_files
 WHERE "assetId" = $1
 ORDER BY "createdAt" ASC;
 
+-- name: GetAssetFilesByAssetIDs :many
+SELECT * FROM asset_files
+WHERE "assetId" = ANY(sqlc.arg(asset_ids)::uuid[])
+ORDER BY "assetId", "createdAt" ASC;
+
 -- name: GetIntegrityOriginalAssets :many
-SELECT id, "originalPath", checksum
-FROM assets
-WHERE "deletedAt" IS NULL
-AND status != 'deleted'::assets_status_enum
-AND "isExternal" = false
-AND "originalPath" != ''
-ORDER BY "originalPath", id;
+-- The checksum identifies the uploaded bytes. When metadata was written back
+-- into the original, or it was downscaled on upload, those bytes live in its
+-- original_backup file. A downscaled original without one was replaced, so
+-- its checksum cannot be verified.
+SELECT a.id, a."originalPath", a.checksum, a."checksumAlgorithm",
+    COALESCE(b.path, a."originalPath")::text AS "checksumPath",
+    (d."assetId" IS NOT NULL AND b.path IS NULL)::boolean AS "originalReplaced"
+FROM assets a
+LEFT JOIN asset_files b ON b."assetId" = a.id AND b.type = 'original_backup'
+LEFT JOIN asset_downscales d ON d."assetId" = a.id
+WHERE a."deletedAt" IS NULL
+AND a.status != 'deleted'::assets_status_enum
+AND a."isExternal" = false
+AND a."originalPath" != ''
+ORDER BY a."originalPath", a.id;
 
 -- name: GetIntegrityTrackedPaths :many
 SELECT DISTINCT path
@@ -1741,6 +2811,71 @@ FROM (
 WHERE path != ''
 ORDER BY path;
 
+-- name: GetIntegrityThumbnailFiles :many
+SELECT af."assetId", af.path
+FROM asset_files af
+JOIN assets a ON a.id = af."assetId"
+WHERE a."deletedAt" IS NULL
+AND a.status != 'deleted'::assets_status_enum
+AND af.type IN ('thumbnail', 'preview', 'fullsize', 'thumb', 'webp')
+AND af.path != ''
+ORDER BY af.path;
+
+-- name: ClearIntegrityReport :exec
+DELETE FROM integrity_report;
+
+-- name: CreateIntegrityReportItem :exec
+INSERT INTO integrity_report (id, type, path, "assetId")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: ListIntegrityReport :many
+SELECT * FROM integrity_report
+ORDER BY type, path, id;
+
+-- name: GetReferencedStoragePaths :many
+-- Every storage path a row still points at, including trashed assets, which
+-- can be restored. Files of permanently deleted assets are not referenced.
+SELECT DISTINCT path
+FROM (
+    SELECT "originalPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+
+    UNION
+
+    SELECT "encodedVideoPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "encodedVideoPath" IS NOT NULL
+
+    UNION
+
+    SELECT "sidecarPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "sidecarPath" IS NOT NULL
+
+    UNION
+
+    SELECT af.path
+    FROM asset_files af
+    JOIN assets a ON a.id = af."assetId"
+    WHERE a.status != 'deleted'::assets_status_enum
+
+    UNION
+
+    SELECT "thumbnailPath" AS path
+    FROM person
+
+    UNION
+
+    SELECT "profileImagePath" AS path
+    FROM users
+) referenced
+WHERE path != ''
+ORDER BY path;
+
 -- name: GetAssetFilesByType :many
 SELECT * FROM asset_files
 WHERE "assetId" = $1 AND "type" = $2
@@ -1764,6 +2899,7 @@ WHERE "assetId" = $1;
 SELECT * FROM assets
 WHERE "ownerId" = $1 
   AND "deletedAt" IS NULL
+  AND visibility <> 'locked'
   AND (
     "originalFileName" ILIKE '%' || $2 || '%' OR
     description ILIKE '%' || $2 || '%'
@@ -1775,6 +2911,7 @@ LIMIT $3 OFFSET $4;
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1 
   AND "deletedAt" IS NULL
+  AND visibility <> 'locked'
   AND (
     "originalFileName" ILIKE '%' || $2 || '%' OR
     description ILIKE '%' || $2 || '%'
@@ -1789,21 +2926,34 @@ ORDER BY name
 LIMIT $4 OFFSET $5;
 
 -- name: SearchPlaces :many
-SELECT DISTINCT city, state, country FROM exif
-WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
-)
+-- Geocoded places in the user's library whose city, state or country starts
+-- with the prefix, ignoring case and diacritics.
+SELECT e.city::text AS city,
+       COALESCE(e.state, '')::text AS state,
+       COALESCE(e.country, '')::text AS country,
+       COALESCE(AVG(e.latitude), 0)::float8 AS latitude,
+       COALESCE(AVG(e.longitude), 0)::float8 AS longitude,
+       COUNT(*) AS asset_count
+FROM exif e
+INNER JOIN assets a ON a.id = e."assetId"
+WHERE a."ownerId" = sqlc.arg(owner_id)
+  AND a."deletedAt" IS NULL
+  AND a.visibility <> 'locked'
+  AND e.city IS NOT NULL
+  AND e.city != ''
   AND (
-    city ILIKE '%' || $2 || '%' OR
-    state ILIKE '%' || $2 || '%' OR
-    country ILIKE '%' || $2 || '%'
+    f_unaccent(lower(e.city)) LIKE f_unaccent(lower(sqlc.arg(prefix)::text)) || '%' OR
+    f_unaccent(lower(e.state)) LIKE f_unaccent(lower(sqlc.arg(prefix)::text)) || '%' OR
+    f_unaccent(lower(e.country)) LIKE f_unaccent(lower(sqlc.arg(prefix)::text)) || '%'
   )
-LIMIT $3 OFFSET $4;
+GROUP BY e.city, e.state, e.country
+ORDER BY asset_count DESC, e.city
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
 
 -- name: GetDistinctCities :many
 SELECT DISTINCT city FROM exif
 WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
+  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
 )
   AND city IS NOT NULL
   AND city != ''
@@ -1813,7 +2963,7 @@ LIMIT $2;
 -- name: GetDistinctCameras :many
 SELECT DISTINCT make, model FROM exif
 WHERE "assetId" IN (
-  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL
+  SELECT id FROM assets WHERE "ownerId" = $1 AND "deletedAt" IS NULL AND visibility <> 'locked'
 )
   AND make IS NOT NULL
   AND make != ''
@@ -1906,11 +3056,160 @@ SELECT
 FROM exif e
 INNER JOIN assets a ON e."assetId" = a.id
 WHERE a."deletedAt" IS NULL
+AND a.visibility <> 'locked'
 AND e.city IS NOT NULL
 GROUP BY e.city, e.state, e.country
 ORDER BY asset_count DESC
 LIMIT $1;
 
+-- name: GetExploreCities :many
+-- One tile per geocoded city with its asset count and most recent asset.
+WITH ranked AS (
+    SELECT e.city AS city,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY e.city) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY e.city ORDER BY a."localDateTime" DESC) AS rn
+    FROM exif e
+    INNER JOIN assets a ON a.id = e."assetId"
+    WHERE a."ownerId" = sqlc.arg(owner_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND e.city IS NOT NULL
+    AND e.city != ''
+)
+SELECT r.city::text AS city, r.asset_count, sqlc.embed(a)
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.city
+LIMIT sqlc.arg(max_items);
+
+-- name: GetExploreTags :many
+-- One tile per tag with its asset count and most recent asset. Assets with a
+-- nested tag also count towards its ancestors.
+WITH tagged AS (
+    SELECT DISTINCT t.id AS tag_id, t.value AS tag, ta."assetsId" AS asset_id
+    FROM tags t
+    INNER JOIN tags_closure tc ON tc.id_ancestor = t.id
+    INNER JOIN tag_asset ta ON ta."tagsId" = tc.id_descendant
+    WHERE t."userId" = sqlc.arg(owner_id)
+), ranked AS (
+    SELECT tg.tag,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY tg.tag_id) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY tg.tag_id ORDER BY a."localDateTime" DESC) AS rn
+    FROM tagged tg
+    INNER JOIN assets a ON a.id = tg.asset_id
+    WHERE a."ownerId" = sqlc.arg(owner_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+)
+SELECT r.tag::text AS tag, r.asset_count, sqlc.embed(a)
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.tag
+LIMIT sqlc.arg(max_items);
+
+-- name: GetExploreLabels :many
+-- One tile per detected object label scoring at least min_score, with its
+-- asset count and most recent asset.
+WITH ranked AS (
+    SELECT al.label,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY al.label) AS asset_count,
+           ROW_NUMBER() OVER (PARTITION BY al.label ORDER BY a."localDateTime" DESC) AS rn
+    FROM asset_labels al
+    INNER JOIN assets a ON a.id = al."assetId"
+    WHERE a."ownerId" = sqlc.arg(owner_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+    AND al.score >= sqlc.arg(min_score)::float8
+)
+SELECT r.label::text AS label, r.asset_count, sqlc.embed(a)
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.label
+LIMIT sqlc.arg(max_items);
+
+-- name: GetExplorePeople :many
+-- One tile per named, visible person with their asset count and a
+-- representative asset (the face asset when set, otherwise the most recent).
+WITH ranked AS (
+    SELECT p.id AS person_id,
+           p.name AS name,
+           a.id AS asset_id,
+           COUNT(*) OVER (PARTITION BY p.id) AS asset_count,
+           ROW_NUMBER() OVER (
+               PARTITION BY p.id
+               ORDER BY (f.id = p."faceAssetId") DESC, a."localDateTime" DESC
+           ) AS rn
+    FROM person p
+    INNER JOIN asset_faces f ON f."personId" = p.id AND f."deletedAt" IS NULL
+    INNER JOIN assets a ON a.id = f."assetId"
+    WHERE p."ownerId" = sqlc.arg(owner_id)
+    AND p."isHidden" = false
+    AND p.name != ''
+    AND a."deletedAt" IS NULL
+    AND a.visibility = 'timeline'
+)
+SELECT r.person_id, r.name::text AS name, r.asset_count, sqlc.embed(a)
+FROM ranked r
+INNER JOIN assets a ON a.id = r.asset_id
+WHERE r.rn = 1
+ORDER BY r.asset_count DESC, r.name
+LIMIT sqlc.arg(max_items);
+
+-- name: GetSearchSuggestions :many
+-- Distinct values from the user's library per suggestion category, filtered by
+-- a case- and diacritic-insensitive prefix and capped per category. Exif
+-- values can be narrowed by country, state, make and model like Immich's
+-- filters.
+WITH owned_exif AS (
+    SELECT e.city, e.state, e.country, e.make, e.model
+    FROM exif e
+    INNER JOIN assets a ON a.id = e."assetId"
+    WHERE a."ownerId" = sqlc.arg(owner_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    AND (sqlc.narg(country)::text IS NULL OR e.country = sqlc.narg(country))
+    AND (sqlc.narg(state)::text IS NULL OR e.state = sqlc.narg(state))
+    AND (sqlc.narg(make)::text IS NULL OR e.make = sqlc.narg(make))
+    AND (sqlc.narg(model)::text IS NULL OR e.model = sqlc.narg(model))
+), candidates AS (
+    SELECT 'country' AS category, country AS value FROM owned_exif
+    UNION ALL
+    SELECT 'state', state FROM owned_exif
+    UNION ALL
+    SELECT 'city', city FROM owned_exif
+    UNION ALL
+    SELECT 'camera-make', make FROM owned_exif
+    UNION ALL
+    SELECT 'camera-model', model FROM owned_exif
+    UNION ALL
+    SELECT 'tag', t.value FROM tags t WHERE t."userId" = sqlc.arg(owner_id)
+    UNION ALL
+    SELECT 'person', p.name FROM person p
+    WHERE p."ownerId" = sqlc.arg(owner_id) AND p."isHidden" = false
+), matched AS (
+    SELECT category, value, COUNT(*) AS uses
+    FROM candidates
+    WHERE value IS NOT NULL
+    AND value != ''
+    AND (sqlc.narg(category)::text IS NULL OR category = sqlc.narg(category))
+    AND f_unaccent(lower(value)) LIKE f_unaccent(lower(sqlc.arg(prefix)::text)) || '%'
+    GROUP BY category, value
+), ranked AS (
+    SELECT category, value,
+           ROW_NUMBER() OVER (PARTITION BY category ORDER BY uses DESC, value) AS rn
+    FROM matched
+)
+SELECT category::text AS category, value::text AS value
+FROM ranked
+WHERE rn <= sqlc.arg(max_per_category)::int
+ORDER BY category, rn;
+
 -- ================== FACE RECOGNITION QUERIES ==================
 
 -- name: CreateFace :one
@@ -1933,6 +3232,35 @@ SELECT * FROM asset_faces
 WHERE "personId" = $1
 AND "deletedAt" IS NULL;
 
+-- name: GetFace :one
+SELECT * FROM asset_faces
+WHERE id = $1
+AND "deletedAt" IS NULL;
+
+-- name: ListPeopleNeedingThumbnails :many
+-- People with faces whose thumbnail is missing or was not cropped from their
+-- feature face. Thumbnails are named after the face they show.
+SELECT * FROM person p
+WHERE EXISTS (
+    SELECT 1 FROM asset_faces f
+    WHERE f."personId" = p.id AND f."deletedAt" IS NULL
+)
+AND (
+    p."thumbnailPath" = ''
+    OR p."faceAssetId" IS NULL
+    OR strpos(p."thumbnailPath", p."faceAssetId"::text) = 0
+)
+ORDER BY p."createdAt";
+
+-- name: GetPersonFaceOnAsset :one
+-- The face of a person on an asset, the largest one should they appear more
+-- than once.
+SELECT * FROM asset_faces
+WHERE "personId" = $1 AND "assetId" = $2
+AND "deletedAt" IS NULL
+ORDER BY ("boundingBoxX2" - "boundingBoxX1") * ("boundingBoxY2" - "boundingBoxY1") DESC
+LIMIT 1;
+
 -- name: DeleteFace :exec
 UPDATE asset_faces
 SET "deletedAt" = NOW()
@@ -2140,6 +3468,7 @@ WHERE id = $1;
 SELECT * FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "originalPath" LIKE $2 || '%'
 AND (sqlc.narg('is_archived')::boolean IS NULL OR visibility = CASE WHEN sqlc.narg('is_archived')::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR "isFavorite" = sqlc.narg('is_favorite'))
@@ -2150,6 +3479,7 @@ LIMIT $3 OFFSET $4;
 SELECT COUNT(*) FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 AND "originalPath" LIKE $2 || '%'
 AND (sqlc.narg('is_archived')::boolean IS NULL OR visibility = CASE WHEN sqlc.narg('is_archived')::boolean THEN 'archive'::asset_visibility_enum ELSE 'timeline'::asset_visibility_enum END)
 AND (sqlc.narg('is_favorite')::boolean IS NULL OR "isFavorite" = sqlc.narg('is_favorite'));
@@ -2168,6 +3498,7 @@ SELECT DISTINCT
 FROM assets
 WHERE "ownerId" = $1
 AND "deletedAt" IS NULL
+AND visibility <> 'locked'
 ORDER BY path_prefix;
 
 -- ============================================================================
@@ -2251,6 +3582,33 @@ SET "stackId" = NULL,
     "updateId" = immich_uuid_v7()
 WHERE "stackId" = $1 AND "deletedAt" IS NULL;
 
+-- name: GetAutoStackCandidates :many
+-- Unstacked, visible assets of an owner in capture order, for burst, bracket
+-- and live photo detection. Assets in one of the excluded albums are left out.
+SELECT
+    a.id,
+    a.type,
+    a."originalFileName",
+    COALESCE(e."dateTimeOriginal", a."fileCreatedAt")::timestamptz AS "takenAt",
+    COALESCE(e.make, '')::text AS make,
+    COALESCE(e.model, '')::text AS model,
+    COALESCE(e."exposureTime", '')::text AS "exposureTime",
+    COALESCE(e."fileSizeInByte", 0)::bigint AS "fileSizeInByte"
+FROM assets a
+LEFT JOIN exif e ON e."assetId" = a.id
+WHERE a."ownerId" = sqlc.arg(owner_id)
+AND a.status = 'active'
+AND a."deletedAt" IS NULL
+AND a."stackId" IS NULL
+AND a.visibility <> 'hidden'
+AND a.visibility <> 'locked'
+AND NOT EXISTS (
+    SELECT 1 FROM albums_assets_assets aa
+    WHERE aa."assetsId" = a.id
+    AND aa."albumsId" = ANY(sqlc.arg(excluded_album_ids)::uuid[])
+)
+ORDER BY "takenAt", a.id;
+
 -- name: SearchStacks :many
 SELECT
     s.*,
@@ -2329,3 +3687,406 @@ WHERE id = $1;
 
 -- name: CountJobFailures :one
 SELECT COUNT(*) FROM job_failures;
+
+-- name: CountJobFailuresByQueue :many
+SELECT queue, COUNT(*) AS count FROM job_failures
+GROUP BY queue;
+
+-- Idempotency key queries
+-- name: DeleteExpiredIdempotencyKeys :exec
+DELETE FROM idempotency_keys
+WHERE "userId" = $1 AND "expiresAt" <= now();
+
+-- Deletes the expired idempotency keys of every user.
+-- name: DeleteAllExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE "expiresAt" <= now();
+
+-- name: ReserveIdempotencyKey :execrows
+INSERT INTO idempotency_keys ("userId", key, fingerprint, "expiresAt")
+VALUES ($1, $2, $3, $4)
+ON CONFLICT ("userId", key) DO NOTHING;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE "userId" = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys
+SET "statusCode" = $3, response = $4, "expiresAt" = $5
+WHERE "userId" = $1 AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE "userId" = $1 AND key = $2;
+
+-- User deletion schedule queries
+-- name: ScheduleUserDeletion :exec
+INSERT INTO user_deletion_schedules ("userId", "deleteAt", "restoreTokenHash")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "deleteAt" = EXCLUDED."deleteAt",
+    "restoreTokenHash" = EXCLUDED."restoreTokenHash",
+    "createdAt" = now();
+
+-- name: GetUserDeletionSchedule :one
+SELECT * FROM user_deletion_schedules
+WHERE "userId" = $1;
+
+-- name: GetUserDeletionScheduleByRestoreToken :one
+-- Only schedules whose grace period has not ended can be restored.
+SELECT * FROM user_deletion_schedules
+WHERE "restoreTokenHash" = $1 AND "deleteAt" > now();
+
+-- name: DeleteUserDeletionSchedule :exec
+DELETE FROM user_deletion_schedules
+WHERE "userId" = $1;
+
+-- name: ListDueUserDeletions :many
+SELECT "userId" FROM user_deletion_schedules
+WHERE "deleteAt" <= now()
+ORDER BY "deleteAt";
+
+-- Password setup token queries
+-- name: CreatePasswordSetupToken :exec
+INSERT INTO password_setup_tokens ("userId", "tokenHash", "expiresAt")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "tokenHash" = EXCLUDED."tokenHash",
+    "expiresAt" = EXCLUDED."expiresAt",
+    "createdAt" = now();
+
+-- name: ConsumePasswordSetupToken :one
+-- Deletes a token and returns it, so concurrent requests cannot both use
+-- it. Expired tokens cannot be used.
+DELETE FROM password_setup_tokens
+WHERE "tokenHash" = $1 AND "expiresAt" > now()
+RETURNING *;
+
+-- name: DeletePasswordSetupToken :exec
+DELETE FROM password_setup_tokens
+WHERE "userId" = $1;
+
+-- Asset user share queries
+-- name: ShareAssetsWithUser :many
+-- Shares the assets in one statement and returns the ones that were not
+-- already shared with the user.
+INSERT INTO asset_user_shares ("assetId", "sharedById", "sharedWithId")
+SELECT unnest(sqlc.arg(asset_ids)::uuid[]), sqlc.arg(shared_by_id), sqlc.arg(shared_with_id)
+ON CONFLICT DO NOTHING
+RETURNING "assetId";
+
+-- name: RevokeAssetShares :many
+-- Returns the assets that were shared with the user.
+DELETE FROM asset_user_shares
+WHERE "sharedById" = sqlc.arg(shared_by_id)
+  AND "sharedWithId" = sqlc.arg(shared_with_id)
+  AND "assetId" = ANY(sqlc.arg(asset_ids)::uuid[])
+RETURNING "assetId";
+
+-- name: CheckAssetDirectlySharedWithUser :one
+-- A share lapses when the asset changes owner or is locked.
+SELECT EXISTS(
+    SELECT 1 FROM asset_user_shares s
+    JOIN assets a ON a.id = s."assetId"
+    WHERE s."assetId" = $1
+    AND s."sharedWithId" = $2
+    AND a."ownerId" = s."sharedById"
+    AND a.visibility <> 'locked'
+) AS is_shared;
+
+-- name: CheckAssetSharedWithPartner :one
+-- Partners see the timeline and archive of whoever shares with them once
+-- they accepted the partnership.
+SELECT EXISTS(
+    SELECT 1 FROM assets a
+    JOIN partners p ON p."sharedById" = a."ownerId"
+    WHERE a.id = $1
+    AND p."sharedWithId" = $2
+    AND p.status = 'accepted'
+    AND a.visibility IN ('timeline', 'archive')
+    AND a."deletedAt" IS NULL
+) AS is_shared;
+
+-- name: CheckAssetInSharedLink :one
+-- The assets of a link are the ones added to it, or those of its album.
+SELECT EXISTS(
+    SELECT 1 FROM assets a
+    WHERE a.id = sqlc.arg(asset_id)
+    AND a."deletedAt" IS NULL
+    AND a.visibility <> 'locked'
+    AND (
+        EXISTS(
+            SELECT 1 FROM shared_link__asset sla
+            WHERE sla."sharedLinksId" = sqlc.arg(link_id) AND sla."assetsId" = a.id
+        ) OR EXISTS(
+            SELECT 1 FROM shared_links l
+            JOIN albums_assets_assets aaa ON aaa."albumsId" = l."albumId"
+            WHERE l.id = sqlc.arg(link_id) AND aaa."assetsId" = a.id
+        )
+    )
+) AS is_shared;
+
+-- name: ListAssetSharesWithUser :many
+SELECT s."assetId", s."sharedById", s."createdAt", u.name, u.email
+FROM asset_user_shares s
+JOIN users u ON u.id = s."sharedById"
+WHERE s."sharedWithId" = $1
+ORDER BY s."createdAt" DESC;
+
+-- name: ListAssetSharesByUser :many
+SELECT s."assetId", s."sharedWithId", s."createdAt", u.name, u.email
+FROM asset_user_shares s
+JOIN users u ON u.id = s."sharedWithId"
+WHERE s."sharedById" = $1
+ORDER BY s."createdAt" DESC;
+
+-- Storage migration queries
+-- name: GetStorageMigrationSourcePaths :many
+SELECT DISTINCT path
+FROM (
+    SELECT "originalPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "isExternal" = false
+
+    UNION
+
+    SELECT "encodedVideoPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "encodedVideoPath" IS NOT NULL
+
+    UNION
+
+    SELECT "sidecarPath" AS path
+    FROM assets
+    WHERE status != 'deleted'::assets_status_enum
+    AND "isExternal" = false
+    AND "sidecarPath" IS NOT NULL
+
+    UNION
+
+    SELECT af.path
+    FROM asset_files af
+    JOIN assets a ON a.id = af."assetId"
+    WHERE a.status != 'deleted'::assets_status_enum
+) tracked
+WHERE path != ''
+ORDER BY path;
+
+-- name: GetStorageMigrationFiles :many
+SELECT * FROM storage_migration_files
+WHERE "fromBackend" = $1 AND "toBackend" = $2
+ORDER BY "sourcePath";
+
+-- name: UpsertStorageMigrationFile :exec
+INSERT INTO storage_migration_files ("fromBackend", "toBackend", "sourcePath", "destinationPath", size, checksum)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT ("fromBackend", "toBackend", "sourcePath") DO UPDATE
+SET "destinationPath" = EXCLUDED."destinationPath",
+    size = EXCLUDED.size,
+    checksum = EXCLUDED.checksum,
+    status = 'verified',
+    "updatedAt" = now();
+
+-- name: MarkStorageMigrationSourceDeleted :exec
+UPDATE storage_migration_files
+SET status = 'source_deleted', "updatedAt" = now()
+WHERE "fromBackend" = $1 AND "toBackend" = $2 AND "sourcePath" = $3;
+
+-- name: UpdateMigratedOriginalPaths :execrows
+UPDATE assets a
+SET "originalPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."originalPath" = m."sourcePath"
+AND a."isExternal" = false
+AND m."sourcePath" != m."destinationPath";
+
+-- name: UpdateMigratedEncodedVideoPaths :execrows
+UPDATE assets a
+SET "encodedVideoPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."encodedVideoPath" = m."sourcePath"
+AND m."sourcePath" != m."destinationPath";
+
+-- name: UpdateMigratedSidecarPaths :execrows
+UPDATE assets a
+SET "sidecarPath" = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND a."sidecarPath" = m."sourcePath"
+AND a."isExternal" = false
+AND m."sourcePath" != m."destinationPath";
+
+-- name: UpdateMigratedAssetFilePaths :execrows
+UPDATE asset_files af
+SET path = m."destinationPath", "updatedAt" = now()
+FROM storage_migration_files m
+WHERE m."fromBackend" = $1 AND m."toBackend" = $2
+AND af.path = m."sourcePath"
+AND m."sourcePath" != m."destinationPath";
+
+-- Webhook subscription queries
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (url, events, secret, enabled)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+ORDER BY "createdAt", id;
+
+-- name: ListWebhookSubscriptionsForEvent :many
+-- Lists the enabled subscriptions to an event type.
+SELECT * FROM webhook_subscriptions
+WHERE enabled = true AND sqlc.arg(event)::text = ANY(events)
+ORDER BY "createdAt", id;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = $2, events = $3, secret = $4, enabled = $5, "updatedAt" = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: RecordWebhookDeliverySuccess :exec
+UPDATE webhook_subscriptions
+SET "lastDeliveryAt" = now(), "lastSuccessAt" = now(), "lastStatusCode" = $2,
+    "lastError" = NULL, "failureCount" = 0
+WHERE id = $1;
+
+-- name: RecordWebhookDeliveryFailure :exec
+-- Records a failed delivery. The status code is NULL when no response was
+-- received.
+UPDATE webhook_subscriptions
+SET "lastDeliveryAt" = now(), "lastStatusCode" = $2, "lastError" = $3,
+    "failureCount" = "failureCount" + 1
+WHERE id = $1;
+
+-- User limit queries
+-- name: GetUserLimits :one
+SELECT * FROM user_limits
+WHERE "userId" = $1;
+
+-- name: LockUserLimits :exec
+-- Serializes the creation of the items a user's limits count until the end
+-- of the transaction.
+SELECT pg_advisory_xact_lock(hashtextextended('user_limits:' || sqlc.arg(user_id)::uuid::text, 0));
+
+-- name: UpsertUserLimits :one
+-- Sets the limit overrides of a user; a NULL limit falls back to the
+-- configured one.
+INSERT INTO user_limits ("userId", "maxSharedLinks", "maxApiKeys")
+VALUES ($1, $2, $3)
+ON CONFLICT ("userId") DO UPDATE
+SET "maxSharedLinks" = EXCLUDED."maxSharedLinks",
+    "maxApiKeys" = EXCLUDED."maxApiKeys",
+    "updatedAt" = now()
+RETURNING *;
+
+-- Geodata queries
+-- name: UpsertGeodataPlaces :execrows
+-- Inserts or updates a batch of places, one array element per place.
+INSERT INTO geodata_places (
+    id, name, longitude, latitude, "countryCode", "admin1Code", "admin2Code",
+    "modificationDate", "admin1Name", "admin2Name", "alternateNames"
+)
+SELECT * FROM unnest(
+    sqlc.arg(ids)::integer[], sqlc.arg(names)::text[], sqlc.arg(longitudes)::double precision[],
+    sqlc.arg(latitudes)::double precision[], sqlc.arg(country_codes)::text[],
+    sqlc.arg(admin1_codes)::text[], sqlc.arg(admin2_codes)::text[],
+    sqlc.arg(modification_dates)::date[], sqlc.arg(admin1_names)::text[],
+    sqlc.arg(admin2_names)::text[], sqlc.arg(alternate_names)::text[]
+)
+ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    longitude = EXCLUDED.longitude,
+    latitude = EXCLUDED.latitude,
+    "countryCode" = EXCLUDED."countryCode",
+    "admin1Code" = EXCLUDED."admin1Code",
+    "admin2Code" = EXCLUDED."admin2Code",
+    "modificationDate" = EXCLUDED."modificationDate",
+    "admin1Name" = EXCLUDED."admin1Name",
+    "admin2Name" = EXCLUDED."admin2Name",
+    "alternateNames" = EXCLUDED."alternateNames";
+
+-- name: DeleteGeodataPlacesExcept :execrows
+-- Deletes the places that are not in the imported dataset.
+DELETE FROM geodata_places
+WHERE NOT (id = ANY(sqlc.arg(ids)::integer[]));
+
+-- name: GetNearestGeodataPlace :one
+-- Returns the place closest to the coordinates within max_distance meters.
+SELECT * FROM geodata_places
+WHERE earth_box(ll_to_earth_public(sqlc.arg(latitude), sqlc.arg(longitude)), sqlc.arg(max_distance))
+    @> ll_to_earth_public(latitude, longitude)
+ORDER BY earth_distance(ll_to_earth_public(sqlc.arg(latitude), sqlc.arg(longitude)), ll_to_earth_public(latitude, longitude))
+LIMIT 1;
+
+-- name: ListGeotaggedExifPlaces :many
+-- Lists the place names of geotagged assets after an asset ID, with the
+-- place closest to their coordinates within max_distance meters.
+SELECT e."assetId", e.city, e.state, e.country,
+    p.name AS place_name, p."admin1Name" AS place_admin1_name, p."countryCode" AS place_country_code
+FROM exif e
+LEFT JOIN LATERAL (
+    SELECT g.name, g."admin1Name", g."countryCode"
+    FROM geodata_places g
+    WHERE earth_box(ll_to_earth_public(e.latitude, e.longitude), sqlc.arg(max_distance))
+        @> ll_to_earth_public(g.latitude, g.longitude)
+    ORDER BY earth_distance(ll_to_earth_public(e.latitude, e.longitude), ll_to_earth_public(g.latitude, g.longitude))
+    LIMIT 1
+) p ON true
+WHERE e.latitude IS NOT NULL AND e.longitude IS NOT NULL
+AND e."assetId" > sqlc.arg(after_id)
+ORDER BY e."assetId"
+LIMIT sqlc.arg(page_size);
+
+-- name: UpdateExifPlace :exec
+UPDATE exif
+SET city = $2, state = $3, country = $4,
+    "updatedAt" = now(),
+    "updateId" = immich_uuid_v7()
+WHERE "assetId" = $1;
+
+-- Upload session queries
+-- name: CreateUploadSession :one
+INSERT INTO upload_sessions ("assetId", "ownerId", "totalSize", checksum, "checksumAlgorithm")
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetUploadSession :one
+SELECT * FROM upload_sessions
+WHERE "assetId" = $1 AND "ownerId" = $2;
+
+-- name: GetUploadSessionChunks :many
+SELECT * FROM upload_session_chunks
+WHERE "assetId" = $1
+ORDER BY start;
+
+-- name: UpsertUploadSessionChunk :exec
+-- Records a received chunk. A chunk sent again replaces the one received
+-- at the same offset.
+INSERT INTO upload_session_chunks ("assetId", start, size)
+VALUES ($1, $2, $3)
+ON CONFLICT ("assetId", start) DO UPDATE SET
+    size = EXCLUDED.size,
+    "receivedAt" = now();
+
+-- name: DeleteUploadSessionChunks :exec
+DELETE FROM upload_session_chunks
+WHERE "assetId" = $1;
+
+-- name: DeleteUploadSession :exec
+DELETE FROM upload_sessions
+WHERE "assetId" = $1;
@@ -356,7 +356,8 @@ CREATE TABLE public.asset_faces (
     "boundingBoxY2" integer DEFAULT 0 NOT NULL,
     id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
     "sourceType" public.sourcetype DEFAULT 'machine-learning'::public.sourcetype NOT NULL,
-    "deletedAt" timestamp with time zone
+    "deletedAt" timestamp with time zone,
+    score real DEFAULT 0 NOT NULL
 );
 
 
@@ -1910,6 +1911,13 @@ CREATE INDEX "IDX_users_updated_at_asc_id_asc" ON public.users USING btree ("upd
 CREATE UNIQUE INDEX "UQ_assets_owner_checksum" ON public.assets USING btree ("ownerId", checksum) WHERE ("libraryId" IS NULL);
 
 
+--
+-- Name: UQ_assets_owner_device_asset; Type: INDEX; Schema: public; Owner: immich
+--
+
+CREATE UNIQUE INDEX "UQ_assets_owner_device_asset" ON public.assets USING btree ("ownerId", "deviceId", "deviceAssetId") WHERE ("libraryId" IS NULL AND "deviceId" <> '' AND "deviceAssetId" <> '');
+
+
 --
 -- Name: UQ_assets_owner_library_checksum; Type: INDEX; Schema: public; Owner: immich
 --
@@ -2695,3 +2703,328 @@ CREATE TABLE public.job_failures (
 
 CREATE INDEX idx_job_failures_failed_at ON public.job_failures USING btree (failed_at DESC);
 CREATE INDEX idx_job_failures_job_type ON public.job_failures USING btree (job_type);
+
+--
+-- Name: idempotency_keys; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.idempotency_keys (
+    "userId" uuid NOT NULL,
+    key character varying NOT NULL,
+    fingerprint character varying DEFAULT ''::character varying NOT NULL,
+    "statusCode" integer,
+    response jsonb,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "expiresAt" timestamp with time zone NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY ("userId", key),
+    CONSTRAINT "idempotency_keys_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "IDX_idempotency_keys_expiresAt" ON public.idempotency_keys USING btree ("expiresAt");
+
+--
+-- Name: assets checksumAlgorithm; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.assets
+    ADD COLUMN "checksumAlgorithm" character varying DEFAULT 'sha1'::character varying NOT NULL;
+
+--
+-- Name: storage_migration_files; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.storage_migration_files (
+    "fromBackend" character varying NOT NULL,
+    "toBackend" character varying NOT NULL,
+    "sourcePath" text NOT NULL,
+    "destinationPath" text NOT NULL,
+    size bigint NOT NULL,
+    checksum bytea NOT NULL,
+    status character varying DEFAULT 'verified'::character varying NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT storage_migration_files_pkey PRIMARY KEY ("fromBackend", "toBackend", "sourcePath")
+);
+
+--
+-- Name: assets isUndated; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.assets
+    ADD COLUMN "isUndated" boolean DEFAULT false NOT NULL;
+
+CREATE INDEX "IDX_assets_undated" ON public.assets USING btree ("ownerId") WHERE "isUndated";
+
+--
+-- Name: integrity_report; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.integrity_report (
+    id uuid NOT NULL,
+    type character varying NOT NULL,
+    path text NOT NULL,
+    "assetId" uuid,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT integrity_report_pkey PRIMARY KEY (id),
+    CONSTRAINT integrity_report_asset_fkey FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX integrity_report_type_path_idx ON public.integrity_report USING btree (type, path);
+
+--
+-- Name: asset_labels; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.asset_labels (
+    "assetId" uuid NOT NULL,
+    label text NOT NULL,
+    score real NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_labels_pkey PRIMARY KEY ("assetId", label),
+    CONSTRAINT asset_labels_asset_fkey FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX asset_labels_label_score_idx ON public.asset_labels USING btree (label, score);
+
+--
+-- Name: partners status; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.partners
+    ADD COLUMN status character varying DEFAULT 'accepted' NOT NULL,
+    ADD COLUMN "acceptedAt" timestamp with time zone;
+
+--
+-- Name: assets offlineAt; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.assets
+    ADD COLUMN "offlineAt" timestamp with time zone;
+
+CREATE INDEX "IDX_assets_offline" ON public.assets USING btree ("libraryId", "offlineAt") WHERE "isOffline";
+
+--
+-- Name: user_deletion_schedules; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.user_deletion_schedules (
+    "userId" uuid NOT NULL,
+    "deleteAt" timestamp with time zone NOT NULL,
+    "restoreTokenHash" character varying NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT user_deletion_schedules_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "user_deletion_schedules_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX "IDX_user_deletion_schedules_restoreTokenHash" ON public.user_deletion_schedules USING btree ("restoreTokenHash");
+CREATE INDEX "IDX_user_deletion_schedules_deleteAt" ON public.user_deletion_schedules USING btree ("deleteAt");
+
+--
+-- Name: asset_downscales; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.asset_downscales (
+    "assetId" uuid NOT NULL,
+    "originalWidth" integer NOT NULL,
+    "originalHeight" integer NOT NULL,
+    width integer NOT NULL,
+    height integer NOT NULL,
+    "originalKept" boolean NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_downscales_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_downscales_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+--
+-- Name: asset_thumbnail_ratios; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.asset_thumbnail_ratios (
+    "assetId" uuid NOT NULL,
+    ratio double precision NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_thumbnail_ratios_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_thumbnail_ratios_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "IDX_assets_owner_local_date_time_id" ON public.assets USING btree ("ownerId", "localDateTime" DESC, id DESC);
+
+--
+-- Name: webhook_subscriptions; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.webhook_subscriptions (
+    id uuid DEFAULT public.uuid_generate_v4() NOT NULL,
+    url text NOT NULL,
+    events text[] NOT NULL,
+    secret text NOT NULL,
+    enabled boolean DEFAULT true NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    "lastDeliveryAt" timestamp with time zone,
+    "lastSuccessAt" timestamp with time zone,
+    "lastStatusCode" integer,
+    "lastError" text,
+    "failureCount" integer DEFAULT 0 NOT NULL,
+    CONSTRAINT webhook_subscriptions_pkey PRIMARY KEY (id)
+);
+
+--
+-- Name: password_setup_tokens; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.password_setup_tokens (
+    "userId" uuid NOT NULL,
+    "tokenHash" character varying NOT NULL,
+    "expiresAt" timestamp with time zone NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT password_setup_tokens_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "password_setup_tokens_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX "IDX_password_setup_tokens_tokenHash" ON public.password_setup_tokens USING btree ("tokenHash");
+
+--
+-- Name: asset_user_shares; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.asset_user_shares (
+    "assetId" uuid NOT NULL,
+    "sharedById" uuid NOT NULL,
+    "sharedWithId" uuid NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_user_shares_pkey PRIMARY KEY ("assetId", "sharedWithId"),
+    CONSTRAINT "asset_user_shares_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE,
+    CONSTRAINT "asset_user_shares_sharedById_fkey" FOREIGN KEY ("sharedById") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT "asset_user_shares_sharedWithId_fkey" FOREIGN KEY ("sharedWithId") REFERENCES public.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "IDX_asset_user_shares_sharedWithId" ON public.asset_user_shares USING btree ("sharedWithId", "createdAt" DESC);
+CREATE INDEX "IDX_asset_user_shares_sharedById" ON public.asset_user_shares USING btree ("sharedById", "createdAt" DESC);
+
+--
+-- Name: user_limits; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.user_limits (
+    "userId" uuid NOT NULL,
+    "maxSharedLinks" integer,
+    "maxApiKeys" integer,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT user_limits_pkey PRIMARY KEY ("userId"),
+    CONSTRAINT "user_limits_userId_fkey" FOREIGN KEY ("userId") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT user_limits_max_shared_links_check CHECK ("maxSharedLinks" >= 0),
+    CONSTRAINT user_limits_max_api_keys_check CHECK ("maxApiKeys" >= 0)
+);
+
+--
+-- Name: asset_trash; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.asset_trash (
+    "assetId" uuid NOT NULL,
+    "trashedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT asset_trash_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "asset_trash_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "IDX_asset_trash_trashedAt" ON public.asset_trash USING btree ("trashedAt" DESC, "assetId" DESC);
+
+CREATE FUNCTION public.asset_trash_status() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+          BEGIN
+              IF new.status = 'trashed' AND new."deletedAt" IS NULL THEN
+                  INSERT INTO asset_trash ("assetId") VALUES (new.id) ON CONFLICT DO NOTHING;
+              ELSE
+                  DELETE FROM asset_trash WHERE "assetId" = new.id;
+              END IF;
+              RETURN NULL;
+          END;
+          $$;
+
+CREATE TRIGGER asset_trash_status AFTER INSERT OR UPDATE OF status, "deletedAt" ON public.assets FOR EACH ROW EXECUTE FUNCTION public.asset_trash_status();
+
+--
+-- Name: library_folder_album_settings; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.library_folder_album_settings (
+    "libraryId" uuid NOT NULL,
+    depth integer DEFAULT 0 NOT NULL,
+    naming character varying DEFAULT 'folder'::character varying NOT NULL,
+    "updatedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT library_folder_album_settings_pkey PRIMARY KEY ("libraryId"),
+    CONSTRAINT "library_folder_album_settings_libraryId_fkey" FOREIGN KEY ("libraryId") REFERENCES public.libraries(id) ON DELETE CASCADE,
+    CONSTRAINT library_folder_album_settings_depth_check CHECK (depth >= 0),
+    CONSTRAINT library_folder_album_settings_naming_check CHECK (naming IN ('folder', 'path'))
+);
+
+--
+-- Name: library_folder_albums; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.library_folder_albums (
+    "libraryId" uuid NOT NULL,
+    "folderPath" text NOT NULL,
+    "albumId" uuid NOT NULL,
+    CONSTRAINT library_folder_albums_pkey PRIMARY KEY ("libraryId", "folderPath"),
+    CONSTRAINT "library_folder_albums_libraryId_fkey" FOREIGN KEY ("libraryId") REFERENCES public.libraries(id) ON DELETE CASCADE,
+    CONSTRAINT "library_folder_albums_albumId_fkey" FOREIGN KEY ("albumId") REFERENCES public.albums(id) ON DELETE CASCADE
+);
+
+CREATE INDEX "IDX_library_folder_albums_albumId" ON public.library_folder_albums USING btree ("albumId");
+
+CREATE INDEX "IDX_assets_owner_id" ON public.assets USING btree ("ownerId", id);
+
+--
+-- Name: assets originalMimeType; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.assets
+    ADD COLUMN "originalMimeType" text;
+
+--
+-- Name: upload_sessions; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.upload_sessions (
+    "assetId" uuid NOT NULL,
+    "ownerId" uuid NOT NULL,
+    "totalSize" bigint NOT NULL,
+    checksum character varying NOT NULL,
+    "checksumAlgorithm" character varying NOT NULL,
+    "createdAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT upload_sessions_pkey PRIMARY KEY ("assetId"),
+    CONSTRAINT "upload_sessions_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.assets(id) ON DELETE CASCADE,
+    CONSTRAINT "upload_sessions_ownerId_fkey" FOREIGN KEY ("ownerId") REFERENCES public.users(id) ON DELETE CASCADE,
+    CONSTRAINT "upload_sessions_totalSize_check" CHECK ("totalSize" > 0)
+);
+
+--
+-- Name: upload_session_chunks; Type: TABLE; Schema: public; Owner: immich
+--
+
+CREATE TABLE public.upload_session_chunks (
+    "assetId" uuid NOT NULL,
+    start bigint NOT NULL,
+    size bigint NOT NULL,
+    "receivedAt" timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT upload_session_chunks_pkey PRIMARY KEY ("assetId", start),
+    CONSTRAINT "upload_session_chunks_assetId_fkey" FOREIGN KEY ("assetId") REFERENCES public.upload_sessions("assetId") ON DELETE CASCADE
+);
+
+--
+-- Name: asset_files width, height, sizeInBytes; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.asset_files
+    ADD COLUMN width integer,
+    ADD COLUMN height integer,
+    ADD COLUMN "sizeInBytes" bigint;
+
+--
+-- Name: assets blurhash; Type: COLUMN; Schema: public; Owner: immich
+--
+
+ALTER TABLE public.assets
+    ADD COLUMN blurhash text;